@@ -0,0 +1,367 @@
+package app
+
+import (
+	"context"
+	"log"
+	"os"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/config"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/controllers"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/utils"
+	"github.com/go-redis/redis/v8"
+	"gorm.io/gorm"
+)
+
+// Container アプリケーションの依存関係をまとめたもの。
+// リポジトリ・サービス・コントローラーはNewContainerで一度だけ生成し、ルーティングとバックグラウンド処理で共有する
+type Container struct {
+	Config      *config.Config
+	DB          *gorm.DB
+	RedisClient *redis.Client
+	Uploader    utils.Uploader
+
+	Repositories Repositories
+	Services     Services
+	Controllers  Controllers
+}
+
+// Repositories 生成済みのリポジトリ
+type Repositories struct {
+	TxManager     repositories.TxManager
+	User          repositories.UserRepository
+	Class         repositories.ClassRepository
+	ClassBoard    repositories.ClassBoardRepository
+	ClassCode     repositories.ClassCodeRepository
+	ClassSchedule repositories.ClassScheduleRepository
+	ClassUser     repositories.ClassUserRepository
+	Role          repositories.RoleRepository
+	Attendance    repositories.AttendanceRepository
+	GoogleAuth    repositories.GoogleAuthRepository
+	ChatSticker   repositories.ChatStickerRepository
+	AuditLog      repositories.AuditLogRepository
+	Subscription  repositories.AnnouncementSubscriptionRepository
+	Semester      repositories.ClassSemesterRepository
+	BoardComment  repositories.ClassBoardCommentRepository
+	Material      repositories.ScheduleMaterialRepository
+	Notification  repositories.NotificationRepository
+	Curriculum    repositories.CurriculumRepository
+	DeviceToken   repositories.DeviceTokenRepository
+	Webhook       repositories.WebhookRepository
+	Integration   repositories.ClassIntegrationRepository
+	CalendarSync  repositories.CalendarSyncRepository
+	Digest        repositories.DigestRepository
+}
+
+// Services 生成済みのサービス
+type Services struct {
+	JWT           services.JWTService
+	Notifier      services.Notifier
+	User          services.UserService
+	Class         services.ClassService
+	ClassBoard    services.ClassBoardService
+	ClassCode     services.ClassCodeService
+	ClassUser     services.ClassUserService
+	ClassSchedule services.ClassScheduleService
+	Attendance    services.AttendanceService
+	GoogleAuth    services.GoogleAuthService
+	LiveClass     services.LiveClassService
+	ChatSticker   services.ChatStickerService
+	Upload        services.UploadService
+	AuditLog      services.AuditLogService
+	Subscription  services.AnnouncementSubscriptionService
+	Semester      services.ClassSemesterService
+	BoardComment  services.ClassBoardCommentService
+	Material      services.ScheduleMaterialService
+	ClassAccess   services.ClassAccessService
+	ClassVersion  services.ClassVersionService
+	Maintenance   services.MaintenanceService
+	Unread        services.UnreadService
+	Translation   services.ChatTranslationService
+	Notification  services.NotificationService
+	Curriculum    services.CurriculumService
+	Mail          services.MailService
+	Invitation    services.ClassInvitationService
+	Realtime      services.RealtimeHub
+	Webhook       services.WebhookService
+	Integration   services.ClassIntegrationService
+	CalendarSync  services.CalendarSyncService
+	Events        services.EventBus
+	Digest        services.DigestService
+	EmailVerify   services.EmailVerificationService
+	ScheduleNotif services.ScheduleNotificationService
+	// VirusScan CLAMAV_ADDRESSが未設定の場合はnil
+	VirusScan services.AttachmentScanService
+	// ClassArchive 自動アーカイブが無効な場合はnil
+	ClassArchive services.ClassArchiveService
+	// ChatManager チャットルームを管理する。生成時にルームの処理を開始する
+	ChatManager *services.Manager
+}
+
+// Controllers 生成済みのコントローラー
+type Controllers struct {
+	User          *controllers.UserController
+	Class         *controllers.ClassController
+	ClassBoard    *controllers.ClassBoardController
+	ClassCode     *controllers.ClassCodeController
+	ClassSchedule *controllers.ClassScheduleController
+	ClassUser     *controllers.ClassUserController
+	Attendance    *controllers.AttendanceController
+	GoogleAuth    *controllers.GoogleAuthController
+	Chat          *controllers.ChatController
+	LiveClass     *controllers.LiveClassController
+	Upload        *controllers.UploadController
+	AuditLog      *controllers.AuditLogController
+	Subscription  *controllers.AnnouncementSubscriptionController
+	Semester      *controllers.ClassSemesterController
+	BoardComment  *controllers.ClassBoardCommentController
+	Material      *controllers.ScheduleMaterialController
+	ClassAccess   *controllers.ClassAccessController
+	Maintenance   *controllers.MaintenanceController
+	Unread        *controllers.UnreadController
+	Notification  *controllers.NotificationController
+	Curriculum    *controllers.CurriculumController
+	Invitation    *controllers.ClassInvitationController
+	Realtime      *controllers.RealtimeController
+	Webhook       *controllers.WebhookController
+	Integration   *controllers.ClassIntegrationController
+	CalendarSync  *controllers.CalendarSyncController
+	Events        *controllers.EventController
+	Digest        *controllers.DigestController
+	EmailVerify   *controllers.EmailVerificationController
+	Debug         *controllers.DebugController
+	ScheduleNotif *controllers.ScheduleNotificationController
+}
+
+// NewContainer 設定と外部への接続からリポジトリ・サービス・コントローラーを生成する
+func NewContainer(cfg *config.Config, db *gorm.DB, redisClient *redis.Client, uploader utils.Uploader, jwtService services.JWTService) *Container {
+	c := &Container{
+		Config:      cfg,
+		DB:          db,
+		RedisClient: redisClient,
+		Uploader:    uploader,
+	}
+	c.Repositories = newRepositories(db)
+	c.Services = newServices(cfg, c.Repositories, redisClient, uploader, jwtService)
+	c.Controllers = newControllers(cfg, c.Services, uploader)
+	return c
+}
+
+// newRepositories リポジトリを生成する
+func newRepositories(db *gorm.DB) Repositories {
+	return Repositories{
+		TxManager:     repositories.NewTxManager(db),
+		User:          repositories.NewUserRepository(db),
+		Class:         repositories.NewClassRepository(db),
+		ClassBoard:    repositories.NewClassBoardRepository(db),
+		ClassCode:     repositories.NewClassCodeRepository(db),
+		ClassSchedule: repositories.NewClassScheduleRepository(db),
+		ClassUser:     repositories.NewClassUserRepository(db),
+		Role:          repositories.NewRoleRepository(db),
+		Attendance:    repositories.NewAttendanceRepository(db),
+		GoogleAuth:    repositories.NewGoogleAuthRepository(db),
+		ChatSticker:   repositories.NewChatStickerRepository(db),
+		AuditLog:      repositories.NewAuditLogRepository(db),
+		Subscription:  repositories.NewAnnouncementSubscriptionRepository(db),
+		Semester:      repositories.NewClassSemesterRepository(db),
+		BoardComment:  repositories.NewClassBoardCommentRepository(db),
+		Material:      repositories.NewScheduleMaterialRepository(db),
+		Notification:  repositories.NewNotificationRepository(db),
+		Curriculum:    repositories.NewCurriculumRepository(db),
+		DeviceToken:   repositories.NewDeviceTokenRepository(db),
+		Webhook:       repositories.NewWebhookRepository(db),
+		Integration:   repositories.NewClassIntegrationRepository(db),
+		CalendarSync:  repositories.NewCalendarSyncRepository(db),
+		Digest:        repositories.NewDigestRepository(db),
+	}
+}
+
+// newServices サービスを生成する
+func newServices(cfg *config.Config, repos Repositories, redisClient *redis.Client, uploader utils.Uploader, jwtService services.JWTService) Services {
+	unread := services.NewUnreadService(repos.ClassUser, repos.ClassSchedule, redisClient)
+	realtime := services.NewRealtimeHub(repos.ClassUser, redisClient, services.RealtimeConfig{})
+	notification := services.NewNotificationService(repos.Notification, repos.DeviceToken, redisClient, services.PushConfig{Sender: pushSender(cfg.Push)}, realtime)
+	notifier := services.NewUnreadCountingNotifier(services.NewInAppNotifier(notification), unread)
+	scheduleNotif := services.NewScheduleNotificationService(notification, repos.Notification, repos.ClassSchedule, repos.ClassUser, unread, redisClient)
+	subscription := services.NewAnnouncementSubscriptionService(repos.Subscription, repos.ClassUser, repos.User, notificationSenders(cfg.Notification))
+	mail := services.NewMailService(services.MailConfig{Mailer: mailer(cfg.Notification), RatePerMinute: cfg.Notification.MailRatePerMinute})
+	webhook := services.NewWebhookService(repos.Webhook, repos.ClassUser, notifier, services.WebhookConfig{
+		Sender:       utils.NewHTTPWebhookSender(cfg.Webhook.AllowPrivateNetworks),
+		FailureLimit: cfg.Webhook.FailureLimit,
+	})
+	integration := services.NewClassIntegrationService(repos.Integration, repos.ClassUser, services.ClassIntegrationConfig{
+		Posters: map[models.IntegrationProvider]utils.ChatPoster{
+			models.SlackIntegration:   utils.NewSlackPoster(cfg.Webhook.AllowPrivateNetworks),
+			models.DiscordIntegration: utils.NewDiscordPoster(cfg.Webhook.AllowPrivateNetworks),
+		},
+		AppURL: cfg.AppURL,
+	})
+	events := services.NewEventBus(redisClient, services.EventBusConfig{
+		Enabled:      cfg.Events.Enabled,
+		StreamMaxLen: int64(cfg.Events.StreamMaxLen),
+	})
+	googleAuth := services.NewGoogleAuthService(repos.GoogleAuth, cfg.Google)
+	calendarSync := services.NewCalendarSyncService(repos.CalendarSync, repos.ClassSchedule, repos.ClassUser, notifier, services.CalendarSyncConfig{
+		Enabled: cfg.Google.CalendarSync,
+		OAuth:   googleAuth.OauthConfig(),
+		Client:  utils.NewGoogleCalendarClient(utils.GoogleCalendarEventsEndpoint),
+		AppURL:  cfg.AppURL,
+	})
+	var virusScan services.AttachmentScanService
+	if cfg.VirusScan.ClamAVAddress != "" {
+		virusScan = services.NewAttachmentScanService(repos.ClassBoard, uploader, notifier, services.AttachmentScanConfig{
+			Scanner: utils.NewClamAVScanner(cfg.VirusScan.ClamAVAddress),
+		})
+	}
+	// 出席トークンの署名の鍵はJWTの秘密鍵から導出するため、全てのインスタンスで同じ鍵になる
+	checkInTokens := services.CheckInTokenConfig{Secret: []byte(cfg.JWT.Secret), TTL: cfg.CheckInTokenTTL}
+	s := Services{
+		JWT:           jwtService,
+		Notifier:      notifier,
+		User:          services.NewCreateUserService(repos.User, redisClient),
+		Class:         services.NewCreateClassService(repos.TxManager, repos.Class, repos.ClassUser, repos.ClassCode, repos.User, repos.ClassSchedule, cfg.ClassInviteURL, redisClient, events),
+		ClassBoard:    services.NewClassBoardService(repos.ClassBoard, repos.ClassUser, uploader, redisClient, subscription, unread, cfg.AllowUnversionedUpdates, realtime, webhook, virusScan, integration),
+		ClassCode:     services.NewClassCodeService(repos.ClassCode),
+		ClassUser:     services.NewClassUserService(repos.TxManager, repos.ClassUser, repos.Role, repos.ClassSchedule, repos.ClassBoard, repos.User, redisClient, cfg.MaxActiveClassesPerUser, mail, webhook, events),
+		ClassSchedule: services.NewClassScheduleService(repos.ClassSchedule, repos.ClassUser, redisClient, scheduleNotif, cfg.AllowUnversionedUpdates, realtime, webhook, integration, calendarSync, checkInTokens, cfg.SuperAdminUIDs),
+		Attendance:    services.NewAttendanceService(repos.Attendance, repos.ClassUser, repos.ClassSchedule, repos.TxManager, cfg.AllowUnversionedUpdates, webhook, events),
+		GoogleAuth:    googleAuth,
+		LiveClass:     services.NewLiveClassService(repos.ClassUser, redisClient, realtime),
+		ChatSticker:   services.NewChatStickerService(repos.ChatSticker, repos.ClassUser, repos.ClassSchedule, uploader),
+		Upload:        services.NewUploadService(utils.NewAwsMultipartUploader(cfg.AWS), repos.ClassUser, redisClient),
+		AuditLog:      services.NewAuditLogService(repos.AuditLog, repos.ClassUser),
+		Subscription:  subscription,
+		Semester:      services.NewClassSemesterService(repos.Semester, repos.Attendance, repos.ClassUser),
+		BoardComment:  services.NewClassBoardCommentService(repos.BoardComment, repos.ClassBoard, repos.ClassUser),
+		Material:      services.NewScheduleMaterialService(repos.Material, repos.ClassSchedule, repos.ClassUser, notifier),
+		ClassAccess:   services.NewClassAccessService(repos.Class, repos.ClassUser),
+		ClassVersion:  services.NewClassVersionService(redisClient),
+		Maintenance:   services.NewMaintenanceService(redisClient),
+		Unread:        unread,
+		Translation:   services.NewChatTranslationService(redisClient, chatTranslator(cfg.Translation), repos.ClassSchedule, repos.ClassUser),
+		Notification:  notification,
+		Curriculum:    services.NewCurriculumService(repos.Curriculum, repos.ClassUser),
+		Mail:          mail,
+		Invitation:    services.NewClassInvitationService(repos.Class, repos.ClassCode, repos.ClassUser, repos.User, mail, redisClient, cfg.ClassInviteURL),
+		Realtime:      realtime,
+		Webhook:       webhook,
+		Integration:   integration,
+		CalendarSync:  calendarSync,
+		Events:        events,
+		Digest:        services.NewDigestService(repos.Digest, notification, mail, redisClient, services.DigestConfig{Hour: cfg.Digest.Hour, Email: cfg.Digest.Email}),
+		EmailVerify:   services.NewEmailVerificationService(repos.User, mail, redisClient, cfg.EmailVerifyURL),
+		VirusScan:     virusScan,
+		ChatManager:   services.NewRoomManager(redisClient, realtime),
+		ScheduleNotif: scheduleNotif,
+	}
+	if archiveConfig, ok := classArchiveConfig(cfg); ok {
+		s.ClassArchive = services.NewClassArchiveService(repos.Class, repos.ClassUser, notifier, archiveConfig)
+	}
+	return s
+}
+
+// newControllers コントローラーを生成する
+func newControllers(cfg *config.Config, s Services, uploader utils.Uploader) Controllers {
+	chatController := controllers.NewChatController(s.ChatManager, s.ChatSticker, s.ClassSchedule, s.User, s.Unread, s.Translation, cfg.ChatHistoryOnConnect)
+	classBoardController := controllers.NewClassBoardController(s.ClassBoard, uploader)
+	return Controllers{
+		User:          controllers.NewCreateUserController(s.User),
+		Class:         controllers.NewCreateClassController(s.Class, s.ClassSchedule, uploader),
+		ClassBoard:    classBoardController,
+		ClassCode:     controllers.NewClassCodeController(s.ClassCode, s.ClassUser),
+		ClassSchedule: controllers.NewClassScheduleController(s.ClassSchedule, s.JWT),
+		ClassUser:     controllers.NewClassUserController(s.ClassUser),
+		Attendance:    controllers.NewAttendanceController(s.Attendance, s.ClassSchedule),
+		GoogleAuth:    controllers.NewGoogleAuthController(s.GoogleAuth, s.JWT, s.CalendarSync),
+		Chat:          chatController,
+		LiveClass:     controllers.NewLiveClassController(s.LiveClass),
+		Upload:        controllers.NewUploadController(s.Upload),
+		AuditLog:      controllers.NewAuditLogController(s.AuditLog),
+		Subscription:  controllers.NewAnnouncementSubscriptionController(s.Subscription),
+		Semester:      controllers.NewClassSemesterController(s.Semester),
+		BoardComment:  controllers.NewClassBoardCommentController(s.BoardComment),
+		Material:      controllers.NewScheduleMaterialController(s.Material),
+		ClassAccess:   controllers.NewClassAccessController(s.ClassAccess),
+		Maintenance:   controllers.NewMaintenanceController(s.Maintenance),
+		Unread:        controllers.NewUnreadController(s.Unread),
+		Notification:  controllers.NewNotificationController(s.Notification),
+		Curriculum:    controllers.NewCurriculumController(s.Curriculum),
+		Invitation:    controllers.NewClassInvitationController(s.Invitation),
+		Realtime:      controllers.NewRealtimeController(s.Realtime, 0),
+		Webhook:       controllers.NewWebhookController(s.Webhook),
+		Integration:   controllers.NewClassIntegrationController(s.Integration),
+		CalendarSync:  controllers.NewCalendarSyncController(s.CalendarSync),
+		Events:        controllers.NewEventController(s.Events),
+		Digest:        controllers.NewDigestController(s.Digest),
+		EmailVerify:   controllers.NewEmailVerificationController(s.EmailVerify),
+		Debug:         controllers.NewDebugController(chatController, classBoardController),
+		ScheduleNotif: controllers.NewScheduleNotificationController(s.ScheduleNotif),
+	}
+}
+
+// chatTranslator チャットの翻訳に使うTranslatorを生成する。APIキーが未設定の場合はnilを返し、翻訳を利用できなくする
+func chatTranslator(cfg config.TranslationConfig) utils.Translator {
+	if cfg.APIKey == "" {
+		return nil
+	}
+	return utils.NewDeepLTranslator(cfg.APIURL, cfg.APIKey)
+}
+
+// pushSender プッシュ通知の送信処理を生成する。FCM_CREDENTIALS_FILEが未設定の場合と、認証情報を読み込めない場合はnilを返し、
+// プッシュ通知を送信しない
+func pushSender(cfg config.PushConfig) utils.PushSender {
+	if cfg.FCMCredentialsFile == "" {
+		return nil
+	}
+	credentials, err := os.ReadFile(cfg.FCMCredentialsFile)
+	if err != nil {
+		log.Printf("Push notifications are disabled: %v", err)
+		return nil
+	}
+	client, endpoint, err := utils.NewFCMClient(context.Background(), credentials, cfg.FCMProjectID)
+	if err != nil {
+		log.Printf("Push notifications are disabled: %v", err)
+		return nil
+	}
+	return utils.NewFCMSender(endpoint, client, cfg.DryRun)
+}
+
+// notificationSenders お知らせを配信する通知チャネルの送信処理を生成する
+// メールはSMTP_HOSTが設定されている場合のみ利用できる
+func notificationSenders(cfg config.NotificationConfig) map[models.NotificationChannel]utils.NotificationSender {
+	senders := map[models.NotificationChannel]utils.NotificationSender{}
+	if cfg.SMTPHost != "" {
+		senders[models.EmailChannel] = utils.NewSMTPMailSender(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUser, cfg.SMTPPassword, cfg.MailFrom)
+	}
+	return senders
+}
+
+// mailer 招待や承認のメールの送信処理を生成する。SMTP_HOSTが未設定の開発環境では送信せずにログに出力する
+func mailer(cfg config.NotificationConfig) utils.Mailer {
+	if cfg.SMTPHost == "" {
+		return utils.NewLogMailer()
+	}
+	return utils.NewSMTPMailer(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUser, cfg.SMTPPassword, cfg.MailFrom)
+}
+
+// classArchiveConfig クラスの自動アーカイブの設定を生成する
+// CLASS_AUTO_ARCHIVE_DAYSが0の場合は自動アーカイブを行わない
+func classArchiveConfig(cfg *config.Config) (services.ClassArchiveConfig, bool) {
+	graceDays, noticeDays := cfg.ClassAutoArchiveDays, cfg.ClassAutoArchiveNoticeDays
+	if graceDays == 0 {
+		return services.ClassArchiveConfig{}, false
+	}
+	// 通知はアーカイブ予定日より前にはできないため、猶予期間を上限とする
+	if noticeDays > graceDays {
+		noticeDays = graceDays
+	}
+
+	day := 24 * time.Hour
+	return services.ClassArchiveConfig{
+		GracePeriod: time.Duration(graceDays) * day,
+		NoticeLead:  time.Duration(noticeDays) * day,
+	}, true
+}
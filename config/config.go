@@ -0,0 +1,693 @@
+package config
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"net/mail"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/joho/godotenv"
+)
+
+// Config アプリケーションの設定。起動時に一度だけ読み込み、各コンストラクターに渡す
+type Config struct {
+	GinMode string
+	Port    int
+
+	Database DatabaseConfig
+	Redis    RedisConfig
+	JWT      JWTConfig
+	Google   GoogleConfig
+	AWS      AWSConfig
+	Debug    DebugConfig
+	// Notification お知らせを外部の通知チャネルに配信する設定
+	Notification NotificationConfig
+	// Translation チャットのメッセージを翻訳する外部APIの設定
+	Translation TranslationConfig
+	// Push モバイル端末へのプッシュ通知の設定
+	Push PushConfig
+	// Webhook クラスのイベントを外部のシステムに配信するWebhookの設定
+	Webhook WebhookConfig
+	// VirusScan 掲示の添付ファイルのウイルススキャンの設定
+	VirusScan VirusScanConfig
+	// Events 他のサービスに内部イベントを配信する設定
+	Events EventsConfig
+	// Digest 前日のクラスの活動をまとめた毎朝のダイジェストの設定
+	Digest DigestConfig
+
+	// ErrorReporterDSN エラー監視サービスの送信先。空の場合は送信しない
+	ErrorReporterDSN string
+
+	RequestTimeout     time.Duration
+	RequestTimeoutLong time.Duration
+
+	// ClassAutoArchiveDays 最後のスケジュールからクラスを自動アーカイブするまでの日数。0の場合は自動アーカイブを行わない
+	ClassAutoArchiveDays       int
+	ClassAutoArchiveNoticeDays int
+	// MaxActiveClassesPerUser 1ユーザーが同時に参加できるアクティブなクラス数の上限。0の場合は上限なし
+	MaxActiveClassesPerUser int
+	// ChatHistoryOnConnect チャットのストリーム接続時に送信する履歴の件数
+	ChatHistoryOnConnect int
+	// CheckInTokenTTL 講師画面のQRコードで発行する出席トークンの有効期間。QRコードを教室の外に送っても使えないよう短くする
+	CheckInTokenTTL time.Duration
+	// ClassInviteURL クラス参加用の招待ページのURL。設定した場合、配布用PDFのQRコードにクラスコード付きのリンクを格納する
+	ClassInviteURL string
+	// EmailVerifyURL メールアドレスの確認ページのURL。検証メールにtoken付きのリンクを載せる。空の場合はトークンのみ載せる
+	EmailVerifyURL string
+	// AppURL フロントエンドのURL。チャットサービスに投稿するメッセージに、/classes/{cid}から始まるページへのリンクを載せる。空の場合はリンクを載せない
+	AppURL string
+	// AllowUnversionedUpdates 楽観ロックのversionを指定しない掲示板・スケジュール・出席の更新を後勝ちで受け付ける。
+	// 全てのクライアントがversionを送信するようになった後にfalseにする(非推奨の移行用の設定)
+	AllowUnversionedUpdates bool
+	// TrustedProxies X-Forwarded-Forを信頼するプロキシのIPアドレスまたはCIDR。
+	// 空の場合はどのプロキシも信頼せず接続元のアドレスを使うため、ロードバランサーの背後ではそのレンジを指定する
+	TrustedProxies []string
+	// SuperAdminUIDs 運用の管理者のユーザーID。開始済みのスケジュールの強制削除など、クラスの管理者にも許可しない操作を行える
+	SuperAdminUIDs []uint
+}
+
+// DatabaseConfig PostgreSQLの接続設定
+type DatabaseConfig struct {
+	Host     string
+	Port     int
+	User     string
+	Password string
+	Name     string
+	// SSLMode 接続のSSLモード (disable, require, verify-ca, verify-full など)。マネージドのPostgreSQLではrequire以上を指定する
+	SSLMode string
+	// SlowQueryThreshold この時間以上かかったクエリを遅いクエリとしてログに出力する
+	SlowQueryThreshold time.Duration
+	// RunMigrations 起動時のマイグレーションの方法 (auto, versioned, off)
+	RunMigrations string
+	// RunSeed 起動時にロールを投入する。投入済みの場合は何もしない
+	RunSeed bool
+	// SeedDemo RunSeedと合わせてローカル開発用のデモデータも投入する。リリースモードでは指定できない
+	SeedDemo bool
+	// LogLevel クエリログの出力レベル (silent, error, warn, info)。
+	// infoは全てのクエリを出力するため調査には便利だが、本番では量が多くログの費用がかさむ。warnは遅いクエリとエラーのみ、silentは何も出力しない
+	LogLevel string
+	// PrepareStmt 実行したクエリのプリペアドステートメントを接続ごとにキャッシュし、同じクエリの解析を省く。
+	// キャッシュ分のメモリを使い、トランザクションモードのPgBouncerなど接続を共有するプーラーを経由する場合は動作しないため無効にする
+	PrepareStmt bool
+	// ReplicaHost 統計や検索などの重い読み取りを振り分けるリードレプリカ。空の場合は全てのクエリをプライマリで実行する。
+	// ユーザー、パスワード、データベース名、SSLモードはプライマリと同じものを使う
+	ReplicaHost string
+	// ReplicaPort リードレプリカのポート。0の場合はプライマリと同じポートを使う
+	ReplicaPort int
+	// MaxIdleConns コネクションプールに残す待機中の接続の上限
+	MaxIdleConns int
+	// MaxOpenConns 同時に開く接続の上限。PostgreSQLのmax_connectionsをアプリのインスタンス数で割った値以下にする
+	MaxOpenConns int
+	// ConnMaxLifetime 接続を使い回す最長の時間。フェイルオーバー後に古い接続が残らないようにする
+	ConnMaxLifetime time.Duration
+	// ConnMaxIdleTime 待機中の接続を閉じるまでの時間。0の場合は閉じない
+	ConnMaxIdleTime time.Duration
+}
+
+// 起動時のマイグレーションの方法
+const (
+	MigrationsAuto      = "auto"      // AutoMigrateでテーブルを作成・更新する
+	MigrationsVersioned = "versioned" // migration/sqlのSQLマイグレーションを順に適用する
+	MigrationsOff       = "off"       // マイグレーションを行わない
+)
+
+// maxCheckInTokenTTL 出席トークンの有効期間の上限。撮影したQRコードが授業中ずっと使えないようにする
+const maxCheckInTokenTTL = 10 * time.Minute
+
+// sslModes PostgreSQLが受け付けるSSLモード
+var sslModes = []string{"disable", "allow", "prefer", "require", "verify-ca", "verify-full"}
+
+// migrationModes RUN_MIGRATIONSに指定できる値
+var migrationModes = []string{MigrationsAuto, MigrationsVersioned, MigrationsOff}
+
+// logLevels LOG_LEVELに指定できる値
+var logLevels = []string{"silent", "error", "warn", "info"}
+
+// DSN 接続文字列を返す。パスワードなどに記号が含まれていても壊れないようURL形式で組み立てる
+func (c DatabaseConfig) DSN() string {
+	u := c.dsnURL()
+	return u.String()
+}
+
+// String パスワードを伏せた接続文字列を返す。ログやエラーメッセージにはDSNの代わりにこちらを使う
+func (c DatabaseConfig) String() string {
+	u := c.dsnURL()
+	return u.Redacted()
+}
+
+// dsnURL 接続文字列のURLを組み立てる
+func (c DatabaseConfig) dsnURL() url.URL {
+	return url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(c.User, c.Password),
+		Host:     net.JoinHostPort(c.Host, strconv.Itoa(c.Port)),
+		Path:     "/" + c.Name,
+		RawQuery: url.Values{"sslmode": {c.SSLMode}, "TimeZone": {"Asia/Tokyo"}}.Encode(),
+	}
+}
+
+// ReplicaDSN リードレプリカの接続文字列を返す
+func (c DatabaseConfig) ReplicaDSN() string {
+	replica := c
+	replica.Host = c.ReplicaHost
+	if c.ReplicaPort != 0 {
+		replica.Port = c.ReplicaPort
+	}
+	return replica.DSN()
+}
+
+// RedisConfig Redisの接続設定
+type RedisConfig struct {
+	Host     string
+	Port     int
+	Password string
+}
+
+// Addr host:port形式のアドレスを返す
+func (c RedisConfig) Addr() string {
+	return c.Host + ":" + strconv.Itoa(c.Port)
+}
+
+// JWTConfig JWTの署名設定
+type JWTConfig struct {
+	Secret string
+}
+
+// GoogleConfig Googleログインの設定
+type GoogleConfig struct {
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	// CalendarSync ログインでカレンダーの権限を許可したユーザーのGoogleカレンダーにスケジュールを同期する
+	CalendarSync bool
+}
+
+// AWSConfig 画像やファイルを保存するS3とCloudFrontの設定
+type AWSConfig struct {
+	Region          string
+	AccessKey       string
+	SecretAccessKey string
+	BucketName      string
+	// CloudFrontURL アップロードしたオブジェクトを配信するURL
+	CloudFrontURL string
+}
+
+// NotificationConfig お知らせを配信する外部の通知チャネルの設定
+type NotificationConfig struct {
+	// SMTPHost メールの送信に使うSMTPサーバー。空の場合はメールの通知チャネルを利用できず、招待や承認のメールはログに出力する
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUser     string
+	SMTPPassword string
+	// MailFrom 送信元のメールアドレス
+	MailFrom string
+	// MailRatePerMinute 招待や承認のメールを1分間に送信する最大の件数。0の場合は制限しない
+	MailRatePerMinute int
+}
+
+// TranslationConfig チャットのメッセージを翻訳するDeepL互換の翻訳APIの設定。APIキーが空の場合は翻訳できない
+type TranslationConfig struct {
+	APIURL string
+	APIKey string
+}
+
+// PushConfig FCMでモバイル端末にプッシュ通知を送信する設定。認証情報のファイルが空の場合はプッシュ通知を送信しない
+type PushConfig struct {
+	// FCMCredentialsFile FCMで送信するサービスアカウントの認証情報(JSON)のパス
+	FCMCredentialsFile string
+	// FCMProjectID FirebaseのプロジェクトID。空の場合は認証情報のプロジェクトIDを使う
+	FCMProjectID string
+	// DryRun FCMにメッセージの検証のみを依頼し、端末には届けない
+	DryRun bool
+}
+
+// WebhookConfig クラスの管理者が登録したWebhookにイベントを配信する設定
+type WebhookConfig struct {
+	// FailureLimit Webhookを自動的に無効にする、再送しても配信できなかったイベントの連続の件数
+	FailureLimit int
+	// AllowPrivateNetworks ループバックやプライベートネットワークのアドレスへの配信を許可する。
+	// 内部のサービスに送信されることを防ぐため、ローカル開発以外では有効にしない
+	AllowPrivateNetworks bool
+}
+
+// VirusScanConfig ClamAVで添付ファイルのウイルススキャンを行う設定。アドレスが空の場合はスキャンしない
+type VirusScanConfig struct {
+	// ClamAVAddress clamdのTCPの待ち受けアドレス (host:port)
+	ClamAVAddress string
+}
+
+// EventsConfig NestJSのサービスなど他のサービスにRedisで内部イベントを配信する設定
+type EventsConfig struct {
+	Enabled bool
+	// APIKey イベントの再取得APIを呼び出すサービスのAPIキー。空の場合は再取得APIを利用できない
+	APIKey string
+	// StreamMaxLen 再取得のためにRedisのストリームに残すイベントのおおよその上限
+	StreamMaxLen int
+}
+
+// DigestConfig 毎朝のダイジェストの設定。ダイジェストはユーザーが有効にした場合のみ送信する
+type DigestConfig struct {
+	// Hour ダイジェストを送信し始める時刻 (サーバーのタイムゾーンの0〜23時)
+	Hour int
+	// Email メールでの受け取りを設定したユーザーにメールでも送信する
+	Email bool
+}
+
+// DebugConfig pprofなどのデバッグ用エンドポイントの設定。トークンが空の場合は有効にしない
+type DebugConfig struct {
+	Enabled bool
+	Token   string
+}
+
+// ValidationError 設定の全ての問題をまとめたエラー
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid configuration:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+// Load .envファイルと環境変数から設定を読み込んで検証する。問題がある場合は全ての問題をまとめたValidationErrorを返す
+func Load() (*Config, error) {
+	if err := godotenv.Load(); err != nil {
+		log.Println("環境変数ファイルが読み込めませんでした。")
+	}
+	return FromEnv(os.Getenv)
+}
+
+// FromEnv getenvから設定を読み込んで検証する
+func FromEnv(getenv func(string) string) (*Config, error) {
+	r := &envReader{getenv: getenv}
+	ginMode := r.string("GIN_MODE", gin.ReleaseMode)
+	// 既定ではデバッグモードのみ全てのクエリを出力し、それ以外は遅いクエリとエラーのみ出力する
+	defaultLogLevel := "warn"
+	if ginMode == gin.DebugMode {
+		defaultLogLevel = "info"
+	}
+	cfg := &Config{
+		GinMode: ginMode,
+		Port:    r.int("PORT", 8080),
+		Database: DatabaseConfig{
+			Host:               r.required("POSTGRES_HOST"),
+			Port:               r.requiredInt("POSTGRES_PORT"),
+			User:               r.required("POSTGRES_USER"),
+			Password:           r.required("POSTGRES_PASSWORD"),
+			Name:               r.required("POSTGRES_DATABASE"),
+			SSLMode:            r.string("POSTGRES_SSLMODE", "disable"),
+			SlowQueryThreshold: r.duration("SLOW_QUERY_THRESHOLD", 200*time.Millisecond),
+			RunMigrations:      migrationMode(r.string("RUN_MIGRATIONS", MigrationsOff)),
+			RunSeed:            r.bool("RUN_SEED", false),
+			SeedDemo:           r.bool("SEED_DEMO", false),
+			LogLevel:           strings.ToLower(r.string("LOG_LEVEL", defaultLogLevel)),
+			PrepareStmt:        r.bool("POSTGRES_PREPARE_STMT", true),
+			ReplicaHost:        r.string("POSTGRES_REPLICA_HOST", ""),
+			ReplicaPort:        r.int("POSTGRES_REPLICA_PORT", 0),
+			MaxIdleConns:       r.int("POSTGRES_MAX_IDLE_CONNS", 10),
+			MaxOpenConns:       r.int("POSTGRES_MAX_OPEN_CONNS", 100),
+			ConnMaxLifetime:    r.duration("POSTGRES_CONN_MAX_LIFETIME", time.Hour),
+			ConnMaxIdleTime:    r.duration("POSTGRES_CONN_MAX_IDLE_TIME", 0),
+		},
+		Redis: RedisConfig{
+			Host:     r.required("REDIS_HOST"),
+			Port:     r.requiredInt("REDIS_PORT"),
+			Password: r.string("REDIS_PASSWORD", ""),
+		},
+		JWT: JWTConfig{Secret: r.required("JWT_SECRET")},
+		Google: GoogleConfig{
+			ClientID:     r.string("GOOGLE_CLIENT_ID", ""),
+			ClientSecret: r.string("GOOGLE_CLIENT_SECRET", ""),
+			RedirectURL:  r.string("GOOGLE_REDIRECT_URL", ""),
+			CalendarSync: r.bool("GOOGLE_CALENDAR_SYNC", false),
+		},
+		AWS: AWSConfig{
+			Region:          r.string("AWS_REGION", ""),
+			AccessKey:       r.string("AWS_S3_ACCESS_KEY", ""),
+			SecretAccessKey: r.string("AWS_S3_SECRET_ACCESS_KEY", ""),
+			BucketName:      r.string("AWS_S3_BUCKET_NAME", ""),
+			CloudFrontURL:   r.string("AWS_CLOUDFRONT", ""),
+		},
+		Notification: NotificationConfig{
+			SMTPHost:          r.string("SMTP_HOST", ""),
+			SMTPPort:          r.int("SMTP_PORT", 587),
+			SMTPUser:          r.string("SMTP_USER", ""),
+			SMTPPassword:      r.string("SMTP_PASSWORD", ""),
+			MailFrom:          r.string("MAIL_FROM", ""),
+			MailRatePerMinute: r.int("MAIL_RATE_PER_MINUTE", 60),
+		},
+		Translation: TranslationConfig{
+			APIURL: r.string("TRANSLATION_API_URL", "https://api-free.deepl.com/v2/translate"),
+			APIKey: r.string("TRANSLATION_API_KEY", ""),
+		},
+		Push: PushConfig{
+			FCMCredentialsFile: r.string("FCM_CREDENTIALS_FILE", ""),
+			FCMProjectID:       r.string("FCM_PROJECT_ID", ""),
+			DryRun:             r.bool("FCM_DRY_RUN", false),
+		},
+		Webhook: WebhookConfig{
+			FailureLimit:         r.int("WEBHOOK_FAILURE_LIMIT", 10),
+			AllowPrivateNetworks: r.bool("WEBHOOK_ALLOW_PRIVATE_NETWORKS", false),
+		},
+		VirusScan: VirusScanConfig{ClamAVAddress: r.string("CLAMAV_ADDRESS", "")},
+		Events: EventsConfig{
+			Enabled:      r.bool("EVENTS_ENABLED", false),
+			APIKey:       r.string("EVENTS_API_KEY", ""),
+			StreamMaxLen: r.int("EVENTS_STREAM_MAXLEN", 10000),
+		},
+		Digest: DigestConfig{
+			Hour:  r.int("DIGEST_HOUR", 7),
+			Email: r.bool("DIGEST_EMAIL_ENABLED", false),
+		},
+		Debug: DebugConfig{
+			Enabled: r.bool("DEBUG_ENDPOINTS_ENABLED", false),
+			Token:   r.string("DEBUG_ENDPOINTS_TOKEN", ""),
+		},
+		ErrorReporterDSN:           r.string("ERROR_REPORTER_DSN", ""),
+		RequestTimeout:             r.duration("REQUEST_TIMEOUT", 15*time.Second),
+		RequestTimeoutLong:         r.duration("REQUEST_TIMEOUT_LONG", 2*time.Minute),
+		ClassAutoArchiveDays:       r.int("CLASS_AUTO_ARCHIVE_DAYS", 90),
+		ClassAutoArchiveNoticeDays: r.int("CLASS_AUTO_ARCHIVE_NOTICE_DAYS", 7),
+		MaxActiveClassesPerUser:    r.int("MAX_ACTIVE_CLASSES_PER_USER", 0),
+		ChatHistoryOnConnect:       r.int("CHAT_HISTORY_ON_CONNECT", 50),
+		CheckInTokenTTL:            r.duration("CHECK_IN_TOKEN_TTL", 2*time.Minute),
+		ClassInviteURL:             r.string("CLASS_INVITE_URL", ""),
+		EmailVerifyURL:             r.string("EMAIL_VERIFY_URL", ""),
+		AppURL:                     r.string("APP_URL", ""),
+		AllowUnversionedUpdates:    r.bool("ALLOW_UNVERSIONED_UPDATES", true),
+		TrustedProxies:             r.list("TRUSTED_PROXIES"),
+		SuperAdminUIDs:             r.uintList("SUPER_ADMIN_UIDS"),
+	}
+
+	// 読み込めなかった値は範囲や形式の問題として重ねて報告しない
+	problems := r.problems
+	for _, problem := range cfg.problems() {
+		if !r.invalid[strings.SplitN(problem, " ", 2)[0]] {
+			problems = append(problems, problem)
+		}
+	}
+	if len(problems) > 0 {
+		return nil, &ValidationError{Problems: problems}
+	}
+	return cfg, nil
+}
+
+// LoadForTest 外部のサービスに接続しないテスト用の設定を返す
+func LoadForTest() *Config {
+	return &Config{
+		GinMode: gin.TestMode,
+		Port:    8080,
+		Database: DatabaseConfig{
+			Host:               "127.0.0.1",
+			Port:               5432,
+			User:               "test",
+			Password:           "test",
+			Name:               "test",
+			SSLMode:            "disable",
+			SlowQueryThreshold: 200 * time.Millisecond,
+			RunMigrations:      MigrationsOff,
+			LogLevel:           "warn",
+			PrepareStmt:        true,
+			MaxIdleConns:       10,
+			MaxOpenConns:       100,
+			ConnMaxLifetime:    time.Hour,
+		},
+		Redis:                      RedisConfig{Host: "127.0.0.1", Port: 6379},
+		JWT:                        JWTConfig{Secret: "test-secret"},
+		AWS:                        AWSConfig{CloudFrontURL: "https://example.com"},
+		Webhook:                    WebhookConfig{FailureLimit: 10},
+		Events:                     EventsConfig{StreamMaxLen: 10000},
+		Digest:                     DigestConfig{Hour: 7},
+		RequestTimeout:             15 * time.Second,
+		RequestTimeoutLong:         2 * time.Minute,
+		CheckInTokenTTL:            2 * time.Minute,
+		ClassAutoArchiveDays:       0,
+		ClassAutoArchiveNoticeDays: 7,
+		ChatHistoryOnConnect:       50,
+		AllowUnversionedUpdates:    true,
+	}
+}
+
+// Validate 設定の値を検証し、問題がある場合は全ての問題をまとめたValidationErrorを返す
+func (c *Config) Validate() error {
+	if problems := c.problems(); len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// problems 値の範囲や形式の問題を列挙する
+func (c *Config) problems() []string {
+	var problems []string
+	switch c.GinMode {
+	case gin.DebugMode, gin.ReleaseMode, gin.TestMode:
+	default:
+		problems = append(problems, fmt.Sprintf("GIN_MODE must be one of debug, release, test: got %q", c.GinMode))
+	}
+
+	problems = append(problems, checkPort("PORT", c.Port)...)
+	problems = append(problems, checkPort("POSTGRES_PORT", c.Database.Port)...)
+	problems = append(problems, checkPort("REDIS_PORT", c.Redis.Port)...)
+	problems = append(problems, checkURL("GOOGLE_REDIRECT_URL", c.Google.RedirectURL)...)
+	problems = append(problems, checkURL("AWS_CLOUDFRONT", c.AWS.CloudFrontURL)...)
+	problems = append(problems, checkURL("CLASS_INVITE_URL", c.ClassInviteURL)...)
+	problems = append(problems, checkURL("EMAIL_VERIFY_URL", c.EmailVerifyURL)...)
+	problems = append(problems, checkURL("APP_URL", c.AppURL)...)
+	problems = append(problems, checkURL("TRANSLATION_API_URL", c.Translation.APIURL)...)
+	if c.Notification.SMTPHost != "" {
+		problems = append(problems, checkPort("SMTP_PORT", c.Notification.SMTPPort)...)
+		if _, err := mail.ParseAddress(c.Notification.MailFrom); err != nil {
+			problems = append(problems, fmt.Sprintf("MAIL_FROM must be an email address when SMTP_HOST is set: got %q", c.Notification.MailFrom))
+		}
+	}
+
+	if c.Push.FCMCredentialsFile != "" {
+		if _, err := os.Stat(c.Push.FCMCredentialsFile); err != nil {
+			problems = append(problems, fmt.Sprintf("FCM_CREDENTIALS_FILE must be a readable file: %v", err))
+		}
+	}
+
+	if c.RequestTimeout <= 0 {
+		problems = append(problems, "REQUEST_TIMEOUT must be positive")
+	}
+	if c.RequestTimeoutLong <= 0 {
+		problems = append(problems, "REQUEST_TIMEOUT_LONG must be positive")
+	}
+	if c.CheckInTokenTTL <= 0 || c.CheckInTokenTTL > maxCheckInTokenTTL {
+		problems = append(problems, fmt.Sprintf("CHECK_IN_TOKEN_TTL must be positive and at most %s", maxCheckInTokenTTL))
+	}
+	for _, proxy := range c.TrustedProxies {
+		if net.ParseIP(proxy) == nil {
+			if _, _, err := net.ParseCIDR(proxy); err != nil {
+				problems = append(problems, fmt.Sprintf("TRUSTED_PROXIES must be IP addresses or CIDRs: got %q", proxy))
+			}
+		}
+	}
+	if !containsString(sslModes, c.Database.SSLMode) {
+		problems = append(problems, fmt.Sprintf("POSTGRES_SSLMODE must be one of %s: got %q", strings.Join(sslModes, ", "), c.Database.SSLMode))
+	}
+	if !containsString(migrationModes, c.Database.RunMigrations) {
+		problems = append(problems, fmt.Sprintf("RUN_MIGRATIONS must be one of %s: got %q", strings.Join(migrationModes, ", "), c.Database.RunMigrations))
+	}
+	if !containsString(logLevels, c.Database.LogLevel) {
+		problems = append(problems, fmt.Sprintf("LOG_LEVEL must be one of %s: got %q", strings.Join(logLevels, ", "), c.Database.LogLevel))
+	}
+	if c.Database.SeedDemo && c.GinMode == gin.ReleaseMode {
+		problems = append(problems, "SEED_DEMO must not be enabled when GIN_MODE is release")
+	}
+	if c.Database.SlowQueryThreshold <= 0 {
+		problems = append(problems, "SLOW_QUERY_THRESHOLD must be positive")
+	}
+	if c.Database.ReplicaHost != "" && c.Database.ReplicaPort != 0 {
+		problems = append(problems, checkPort("POSTGRES_REPLICA_PORT", c.Database.ReplicaPort)...)
+	}
+	if c.Database.MaxOpenConns <= 0 {
+		problems = append(problems, "POSTGRES_MAX_OPEN_CONNS must be positive")
+	}
+	if c.Database.ConnMaxLifetime < 0 {
+		problems = append(problems, "POSTGRES_CONN_MAX_LIFETIME must not be negative")
+	}
+	if c.Database.ConnMaxIdleTime < 0 {
+		problems = append(problems, "POSTGRES_CONN_MAX_IDLE_TIME must not be negative")
+	}
+	if c.Webhook.FailureLimit <= 0 {
+		problems = append(problems, "WEBHOOK_FAILURE_LIMIT must be positive")
+	}
+	if c.Events.StreamMaxLen <= 0 {
+		problems = append(problems, "EVENTS_STREAM_MAXLEN must be positive")
+	}
+	if c.Digest.Hour < 0 || c.Digest.Hour > 23 {
+		problems = append(problems, fmt.Sprintf("DIGEST_HOUR must be between 0 and 23: got %d", c.Digest.Hour))
+	}
+	if address := c.VirusScan.ClamAVAddress; address != "" {
+		if _, _, err := net.SplitHostPort(address); err != nil {
+			problems = append(problems, fmt.Sprintf("CLAMAV_ADDRESS must be host:port: got %q", address))
+		}
+	}
+	counts := []struct {
+		key   string
+		value int
+	}{
+		{"CLASS_AUTO_ARCHIVE_DAYS", c.ClassAutoArchiveDays},
+		{"CLASS_AUTO_ARCHIVE_NOTICE_DAYS", c.ClassAutoArchiveNoticeDays},
+		{"MAX_ACTIVE_CLASSES_PER_USER", c.MaxActiveClassesPerUser},
+		{"CHAT_HISTORY_ON_CONNECT", c.ChatHistoryOnConnect},
+		{"POSTGRES_MAX_IDLE_CONNS", c.Database.MaxIdleConns},
+		{"MAIL_RATE_PER_MINUTE", c.Notification.MailRatePerMinute},
+	}
+	for _, count := range counts {
+		if count.value < 0 {
+			problems = append(problems, fmt.Sprintf("%s must not be negative: got %d", count.key, count.value))
+		}
+	}
+	return problems
+}
+
+// containsString valuesにvalueが含まれるか確認する
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// checkPort ポート番号が1から65535の範囲か確認する
+func checkPort(key string, port int) []string {
+	if port < 1 || port > 65535 {
+		return []string{fmt.Sprintf("%s must be between 1 and 65535: got %d", key, port)}
+	}
+	return nil
+}
+
+// checkURL 値が設定されている場合、http(s)の絶対URLか確認する
+func checkURL(key, value string) []string {
+	if value == "" {
+		return nil
+	}
+	u, err := url.Parse(value)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return []string{fmt.Sprintf("%s must be an absolute http(s) URL: got %q", key, value)}
+	}
+	return nil
+}
+
+// envReader 環境変数を型に変換し、変換できない値を問題として記録する
+type envReader struct {
+	getenv   func(string) string
+	problems []string
+	invalid  map[string]bool
+}
+
+// migrationMode RUN_MIGRATIONSの値を返す。以前のtrue/falseの指定はauto/offとして扱う
+func migrationMode(value string) string {
+	if b, err := strconv.ParseBool(value); err == nil {
+		if b {
+			return MigrationsAuto
+		}
+		return MigrationsOff
+	}
+	return strings.ToLower(value)
+}
+
+// fail 環境変数の問題を記録する
+func (r *envReader) fail(key, problem string) {
+	if r.invalid == nil {
+		r.invalid = map[string]bool{}
+	}
+	r.invalid[key] = true
+	r.problems = append(r.problems, key+" "+problem)
+}
+
+func (r *envReader) string(key, defaultValue string) string {
+	if value := r.getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+func (r *envReader) required(key string) string {
+	value := r.getenv(key)
+	if value == "" {
+		r.fail(key, "is required")
+	}
+	return value
+}
+
+func (r *envReader) requiredInt(key string) int {
+	if r.getenv(key) == "" {
+		r.fail(key, "is required")
+		return 0
+	}
+	return r.int(key, 0)
+}
+
+func (r *envReader) int(key string, defaultValue int) int {
+	value := r.getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		r.fail(key, fmt.Sprintf("must be an integer: got %q", value))
+		return defaultValue
+	}
+	return n
+}
+
+func (r *envReader) bool(key string, defaultValue bool) bool {
+	value := r.getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		r.fail(key, fmt.Sprintf("must be a boolean: got %q", value))
+		return defaultValue
+	}
+	return b
+}
+
+// list カンマ区切りの値を空白を除いて返す
+func (r *envReader) list(key string) []string {
+	var values []string
+	for _, value := range strings.Split(r.getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// uintList カンマ区切りの正の整数を返す
+func (r *envReader) uintList(key string) []uint {
+	var values []uint
+	for _, value := range r.list(key) {
+		n, err := strconv.ParseUint(value, 10, 32)
+		if err != nil || n == 0 {
+			r.fail(key, fmt.Sprintf("must be comma-separated user IDs: got %q", value))
+			return nil
+		}
+		values = append(values, uint(n))
+	}
+	return values
+}
+
+func (r *envReader) duration(key string, defaultValue time.Duration) time.Duration {
+	value := r.getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		r.fail(key, fmt.Sprintf("must be a duration like 15s: got %q", value))
+		return defaultValue
+	}
+	return d
+}
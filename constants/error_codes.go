@@ -0,0 +1,66 @@
+package constants
+
+// エラーレスポンスの機械判読用コード
+// フロントエンドはメッセージではなくこのコードで処理を分岐してください。
+const (
+	ErrCodeInvalidRequest          = "invalid_request"           // 400 Bad Request
+	ErrCodeInvalidAttendanceStatus = "invalid_attendance_status" // 400 Bad Request
+	ErrCodeInvalidRoleName         = "invalid_role_name"         // 400 Bad Request
+	ErrCodeInvalidNotifyTarget     = "invalid_notify_target"     // 400 Bad Request
+	ErrCodeInvalidGradeScale       = "invalid_grade_scale"       // 400 Bad Request
+	ErrCodeInvalidAccessRule       = "invalid_access_rule"       // 400 Bad Request
+	ErrCodeNestedReply             = "nested_reply"              // 400 Bad Request
+	ErrCodeVersionRequired         = "version_required"          // 400 Bad Request
+	ErrCodeInvalidWebhookURL       = "invalid_webhook_url"       // 400 Bad Request
+	ErrCodeInvalidIntegrationURL   = "invalid_integration_url"   // 400 Bad Request
+	ErrCodeInvalidEventCursor      = "invalid_event_cursor"      // 400 Bad Request
+	ErrCodeInvalidEmailToken       = "invalid_email_token"       // 400 Bad Request
+	ErrCodeUnauthorized            = "unauthorized"              // 401 Unauthorized
+	ErrCodeForbidden               = "forbidden"                 // 403 Forbidden
+	ErrCodeAccessRestricted        = "access_restricted"         // 403 Forbidden
+	ErrCodeInvalidCheckInCode      = "invalid_check_in_code"     // 403 Forbidden
+	ErrCodeInvalidCheckInToken     = "invalid_check_in_token"    // 403 Forbidden
+	ErrCodeNotFound                = "not_found"                 // 404 Not Found
+	ErrCodeClassNotFound           = "class_not_found"           // 404 Not Found
+	ErrCodeUserNotFound            = "user_not_found"            // 404 Not Found
+	ErrCodeUserOrClassNotFound     = "user_or_class_not_found"   // 404 Not Found
+	ErrCodeAttendanceNotFound      = "attendance_not_found"      // 404 Not Found
+	ErrCodeConflict                = "conflict"                  // 409 Conflict
+	ErrCodeIdempotencyInFlight     = "idempotency_in_flight"     // 409 Conflict
+	ErrCodeStaleUpdate             = "stale_update"              // 409 Conflict
+	ErrCodeCalendarNotConnected    = "calendar_not_connected"    // 409 Conflict
+	ErrCodeEmailTaken              = "email_taken"               // 409 Conflict
+	ErrCodeRequestTooLarge         = "request_too_large"         // 413 Request Entity Too Large
+	ErrCodeValidation              = "validation_failed"         // 422 Unprocessable Entity
+	ErrCodePastSchedule            = "past_schedule"             // 422 Unprocessable Entity
+	ErrCodeScheduleTooOld          = "schedule_too_old"          // 422 Unprocessable Entity
+	ErrCodeCheckInTokenExpired     = "check_in_token_expired"    // 422 Unprocessable Entity
+	ErrCodeScheduleCancelled       = "schedule_cancelled"        // 422 Unprocessable Entity
+	ErrCodeCheckInMethodNotAllowed = "check_in_method_disabled"  // 422 Unprocessable Entity
+	ErrCodeNotEnrolled             = "not_enrolled"              // 422 Unprocessable Entity
+	ErrCodeActiveClassLimit        = "active_class_limit"        // 422 Unprocessable Entity
+	ErrCodeChannelUnavailable      = "channel_unavailable"       // 422 Unprocessable Entity
+	ErrCodeSurveyNotOpen           = "survey_not_open"           // 422 Unprocessable Entity
+	ErrCodeNotTranslatable         = "not_translatable"          // 422 Unprocessable Entity
+	ErrCodeIntegrationTestFailed   = "integration_test_failed"   // 422 Unprocessable Entity
+	ErrCodeCalendarClassNotSynced  = "calendar_class_not_synced" // 422 Unprocessable Entity
+	ErrCodeEmailNotSet             = "email_not_set"             // 422 Unprocessable Entity
+	ErrCodeEmailNotVerified        = "email_not_verified"        // 422 Unprocessable Entity
+	ErrCodeRealtimeTopicLimit      = "realtime_topic_limit"      // WebSocketのerrorイベント
+	ErrCodeInvitationLimit         = "invitation_limit"          // 429 Too Many Requests
+	ErrCodeVerificationLimit       = "email_verification_limit"  // 429 Too Many Requests
+	ErrCodeReminderCooldown        = "reminder_cooldown"         // 429 Too Many Requests
+	ErrCodePreviewSecretLimit      = "preview_secret_limit"      // 429 Too Many Requests
+	ErrCodeTranslationRateLimit    = "translation_rate_limit"    // 429 Too Many Requests
+	ErrCodeDatabaseError           = "database_error"            // 500 Internal Server Error
+	ErrCodeInternal                = "internal_error"            // 500 Internal Server Error
+	ErrCodeTranslationFailed       = "translation_failed"        // 502 Bad Gateway
+	ErrCodeMaintenance             = "maintenance"               // 503 Service Unavailable
+	ErrCodeTranslationUnavailable  = "translation_unavailable"   // 503 Service Unavailable
+	ErrCodeMailQueueFull           = "mail_queue_full"           // 503 Service Unavailable
+	ErrCodeCalendarSyncUnavailable = "calendar_sync_unavailable" // 503 Service Unavailable
+	ErrCodeEventsUnavailable       = "events_unavailable"        // 503 Service Unavailable
+	ErrCodeCheckInTokenUnavailable = "qr_check_in_unavailable"   // 503 Service Unavailable
+	ErrCodeVerifyUnavailable       = "email_verify_unavailable"  // 503 Service Unavailable
+	ErrCodeTimeout                 = "timeout"                   // 504 Gateway Timeout
+)
@@ -8,15 +8,79 @@ const (
 	ErrFileSizeJP        = "ファイルサイズが10MBを超えています" // 400 Bad Request
 	ErrMimeTypeJP        = "ファイルタイプが画像ではありません"   // 400 Bad Request
 	ErrNoDateJP          = "日付が提供されていません"        // 400 Bad Request
+	ErrInvalidDateJP     = "日付の形式が正しくありません"      // 400 Bad Request
 	ErrInvalidInput      = "無効な入力です"             // 400 Bad Request
 	ErrNoUserID          = "ユーザーIDが提供されていません"    // 400 Bad Request
 	RefreshTokenRequired = "refresh_tokenが必要です"  // 400 Bad Request
 	AuthCodeRequired     = "authCodeが必要です"       // 400 Bad Request
+	RequestTooLarge      = "リクエストのサイズが上限を超えています" // 413 Request Entity Too Large
+	ValidationFailed     = "入力値の検証に失敗しました"       // 422 Unprocessable Entity
+
+	// ContentTypeNotJSON Content-Typeがapplication/jsonではないリクエストのエラーメッセージ
+	ContentTypeNotJSON = "content type must be application/json" // 415 Unsupported Media Type
+
+	// InvalidAttendanceLimit 出席情報の一覧のlimitが範囲外のエラーメッセージ
+	InvalidAttendanceLimit = "limit must be an integer from 1 to 100" // 400 Bad Request
+)
+
+// 業務ルール関連のエラーメッセージ
+const (
+	PastScheduleDeletion    = "cannot delete a schedule that has already started" // 422 Unprocessable Entity
+	ScheduleCancelled       = "休講のスケジュールです"                                       // 409 Conflict
+	UploadIncomplete        = "アップロードされていないパートがあります"                              // 409 Conflict
+	IdempotencyInFlight     = "同じIdempotency-Keyのリクエストを処理中です"                     // 409 Conflict
+	AttendanceResetLimit    = "7日以上前のスケジュールの出席はリセットできません"                         // 422 Unprocessable Entity
+	NotEnrolledInClasses    = "参加していないクラスが含まれています"                                // 422 Unprocessable Entity
+	ActiveClassLimitReached = "参加できるクラス数の上限に達しています"                               // 422 Unprocessable Entity
+	PinUntilInPast          = "ピン留めの期限には現在より後の日時を指定してください"                        // 400 Bad Request
+	InvalidNotifyTarget     = "通知先のメールアドレスが正しくありません"                              // 400 Bad Request
+	ChannelUnavailable      = "この通知チャネルは現在利用できません"                                // 422 Unprocessable Entity
+	InvalidGradeScale       = "成績の基準は成績と下限が重複せず、下限0%の基準を含めてください"                  // 400 Bad Request
+	InvalidSemesterPeriod   = "学期の終了日は開始日以降の日付を指定してください"                          // 400 Bad Request
+	InvalidAttendanceMode   = "出席方式はIN_PERSON, ONLINE, HYBRIDのいずれかを指定してください"      // 400 Bad Request
+	DuplicateBoardLanguage  = "同じ言語の版が複数含まれています"                                  // 400 Bad Request
+	InvalidAccessRule       = "時間帯はHH:MM形式の開始と終了を、IPレンジはCIDR形式で指定してください"          // 400 Bad Request
+	AccessRestricted        = "このクラスには現在の時間帯または接続元からアクセスできません"                    // 403 Forbidden
+	NestedReply             = "リプライにはリプライできません"                                   // 400 Bad Request
+	SurveyNotOpen           = "振り返りアンケートには授業の終了後に回答できます"                          // 422 Unprocessable Entity
+	NotTranslatable         = "このメッセージには翻訳できる本文がありません"                            // 422 Unprocessable Entity
+	TranslationUnavailable  = "現在翻訳は利用できません"                                      // 503 Service Unavailable
+	TranslationRateLimit    = "翻訳の回数が多すぎます。しばらくしてから再度お試しください"                     // 429 Too Many Requests
+	TranslationFailed       = "翻訳に失敗しました。しばらくしてから再度お試しください"                       // 502 Bad Gateway
+	StaleUpdate             = "他のユーザーが先に更新しました。最新の内容を確認してください"                    // 409 Conflict
+	InvalidCheckInCode      = "確認コードが正しくありません。講師が伝えたコードを入力してください"                 // 403 Forbidden
+	InvalidCheckInToken     = "出席用のQRコードが正しくありません"                                // 403 Forbidden
+	CheckInTokenExpired     = "出席用のQRコードの有効期限が切れています。読み取り直してください"                 // 422 Unprocessable Entity
+	CheckInCancelled        = "このスケジュールは休講のためチェックインできません"                         // 422 Unprocessable Entity
+	CheckInMethodNotAllowed = "このスケジュールの出席方式ではこの方法でチェックインできません"                   // 422 Unprocessable Entity
+	CheckInTokenUnavailable = "現在QRコードによる出席は利用できません"                              // 503 Service Unavailable
+	VersionRequired         = "更新には読み込んだ時点のversionを指定してください"                      // 400 Bad Request
+	InvitationLimitReached  = "本日送信できる招待メールの上限に達しています"                            // 429 Too Many Requests
+	ReminderCooldown        = "再通知は前回の送信から10分経過してから送信できます"                        // 429 Too Many Requests
+	PreviewSecretLimit      = "シークレットの入力ミスが多すぎます。しばらくしてから再度お試しください"               // 429 Too Many Requests
+	MailQueueFull           = "現在メールを送信できません。しばらくしてから再度お試しください"                   // 503 Service Unavailable
+	ClassCodeAlreadyUsed    = "このクラスコードは既に使用されています。再参加はクラスの管理者に依頼してください"          // 409 Conflict
+	InvalidWebhookURL       = "Webhookの送信先にはhttpまたはhttpsのURLを指定してください"            // 400 Bad Request
+	RealtimeTopicLimit      = "1つの接続で購読できるクラス数の上限に達しています"                         // WebSocketのerrorイベント
+	InvalidIntegrationURL   = "SlackまたはDiscordが発行したWebhookのURLを指定してください"          // 400 Bad Request
+	IntegrationTestFailed   = "連携先にテストメッセージを投稿できませんでした。URLを確認してください"              // 422 Unprocessable Entity
+	CalendarSyncUnavailable = "現在Googleカレンダーとの同期は利用できません"                         // 503 Service Unavailable
+	CalendarNotConnected    = "Googleカレンダーの権限が許可されていません。ログインし直してください"             // 409 Conflict
+	CalendarClassNotSynced  = "このクラスはGoogleカレンダーと同期していません"                        // 422 Unprocessable Entity
+	EmailTaken              = "このメールアドレスは他のアカウントで検証済みです"                          // 409 Conflict
+	EmailNotSet             = "メールアドレスが登録されていません。検証するメールアドレスを指定してください"            // 422 Unprocessable Entity
+	EmailNotVerified        = "メールアドレスを検証してから登録してください"                            // 422 Unprocessable Entity
+	VerificationLimit       = "検証メールの送信が多すぎます。しばらくしてから再度お試しください"                  // 429 Too Many Requests
+	InvalidEmailToken       = "検証のリンクが正しくないか、有効期限が切れています。検証メールを再送してください"          // 400 Bad Request
+	VerifyUnavailable       = "現在メールアドレスの検証は利用できません"                              // 503 Service Unavailable
+	EventsUnavailable       = "現在イベントの配信は利用できません"                                 // 503 Service Unavailable
+	InvalidEventCursor      = "afterにはイベントのIDを指定してください"                           // 400 Bad Request
 )
 
 // 認証関連のエラーメッセージ
 const (
 	Unauthorized          = "認証に失敗しました"          // 401 Unauthorized
+	Forbidden             = "権限がありません"           // 403 Forbidden
 	SecretMismatch        = "シークレットが一致しません"      // 401 Unauthorized
 	CodeNotFound          = "コードが見つかりません"        // 404 Not Found
 	ClassNotFound         = "クラスが見つかりません"        // 404 Not Found
@@ -34,10 +98,14 @@ const (
 	ErrReadFileDataJP        = "ファイルデータの読み取りに失敗しました"          // 500 Internal Server Error
 	ErrLoadAWSConfigJP       = "AWS設定のロードに失敗しました"             // 500 Internal Server Error
 	ErrUploadToS3JP          = "S3へのアップロードに失敗しました"            // 500 Internal Server Error
+	ErrDeleteFromS3JP        = "S3からの削除に失敗しました"               // 500 Internal Server Error
 	ErrCloudFrontURLNotSetJP = "AWS_CLOUDFRONT環境変数が設定されていません" // 500 Internal Server Error
 	AssignError              = "ロールの割り当てに失敗しました"              // 500 Internal Server Error
 	ErrLoadMessage           = "メッセージの取得に失敗しました"              // 500 Internal Server Error
 	ErrSendMessage           = "メッセージの送信に失敗しました"              // 500 Internal Server Error
+	MaintenanceReadOnly      = "メンテナンス中のため、現在は閲覧のみ可能です"       // 503 Service Unavailable
+	MaintenanceInProgress    = "メンテナンス中です。しばらくしてから再度お試しください"  // 503 Service Unavailable
+	RequestTimeout           = "リクエストがタイムアウトしました"             // 504 Gateway Timeout
 )
 
 // 成功時のメッセージ
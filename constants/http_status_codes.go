@@ -22,6 +22,10 @@ const (
 	StatusNotFound         = 404 // Not Found
 	StatusMethodNotAllowed = 405 // Method Not Allowed
 	StatusConflict         = 409 // Conflict
+	StatusEntityTooLarge   = 413 // Request Entity Too Large
+	StatusUnsupportedMedia = 415 // Unsupported Media Type
+	StatusUnprocessable    = 422 // Unprocessable Entity
+	StatusTooManyRequests  = 429 // Too Many Requests
 
 	/*
 		サーバーエラー ステータスコード
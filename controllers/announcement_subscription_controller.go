@@ -0,0 +1,144 @@
+package controllers
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/constants"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// AnnouncementSubscriptionController お知らせの外部通知の購読のコントローラ
+type AnnouncementSubscriptionController struct {
+	subscriptionService services.AnnouncementSubscriptionService
+}
+
+// NewAnnouncementSubscriptionController AnnouncementSubscriptionControllerを生成
+func NewAnnouncementSubscriptionController(subscriptionService services.AnnouncementSubscriptionService) *AnnouncementSubscriptionController {
+	return &AnnouncementSubscriptionController{
+		subscriptionService: subscriptionService,
+	}
+}
+
+// GetSubscriptions godoc
+// @Summary お知らせの通知チャネル一覧
+// @Description ログインユーザーがクラスに登録した通知チャネルを取得します。
+// @Tags Class Subscription
+// @Produce json
+// @Param cid path int true "クラスID"
+// @Success 200 {array} dto.AnnouncementSubscriptionDTO "通知チャネル"
+// @Failure 400 {object} utils.ErrorResponse "無効なリクエスト"
+// @Failure 500 {object} utils.ErrorResponse "サーバーエラーが発生しました"
+// @Router /cl/subscriptions/{cid} [get]
+// @Security Bearer
+func (c *AnnouncementSubscriptionController) GetSubscriptions(ctx *gin.Context) {
+	cid, err := strconv.ParseUint(ctx.Param("cid"), 10, 32)
+	if err != nil {
+		abortWithError(ctx, utils.NewBadRequestError(constants.ErrCodeInvalidRequest, constants.InvalidRequest))
+		return
+	}
+
+	subscriptions, err := c.subscriptionService.ListSubscriptions(ctx.Request.Context(), ctx.GetUint("userID"), uint(cid))
+	if err != nil {
+		abortWithError(ctx, toAppError(err))
+		return
+	}
+
+	result := make([]dto.AnnouncementSubscriptionDTO, 0, len(subscriptions))
+	for _, subscription := range subscriptions {
+		result = append(result, toSubscriptionDTO(subscription))
+	}
+	respondWithSuccess(ctx, constants.StatusOK, result)
+}
+
+// Subscribe godoc
+// @Summary お知らせの通知チャネルを登録
+// @Description クラスのお知らせが作成されたときに通知を受け取るチャネルを登録します。EMAILはログインユーザーの検証済みのメールアドレスに送信し、配信時にはその時点の検証済みのアドレスを使います。登録済みの場合は停止していた配信を再開します。クラスの管理者・アシスタント・生徒のみ登録でき、クラスのメンバーではなくなると配信されません。
+// @Tags Class Subscription
+// @Produce json
+// @Param cid path int true "クラスID"
+// @Param channel path string true "通知チャネル" Enums(EMAIL)
+// @Success 200 {object} dto.AnnouncementSubscriptionDTO "登録した通知チャネル"
+// @Failure 400 {object} utils.ErrorResponse "無効なリクエスト"
+// @Failure 403 {object} utils.ErrorResponse "クラスのメンバーではありません"
+// @Failure 422 {object} utils.ErrorResponse "通知チャネルが利用できません, email_not_verified"
+// @Failure 500 {object} utils.ErrorResponse "サーバーエラーが発生しました"
+// @Router /cl/subscriptions/{cid}/{channel} [put]
+// @Security Bearer
+func (c *AnnouncementSubscriptionController) Subscribe(ctx *gin.Context) {
+	cid, channel, ok := parseSubscriptionParams(ctx)
+	if !ok {
+		return
+	}
+
+	subscription, err := c.subscriptionService.Subscribe(ctx.Request.Context(), ctx.GetUint("userID"), cid, channel)
+	if err != nil {
+		if errors.Is(err, services.ErrUnauthorized) {
+			abortWithError(ctx, utils.NewForbiddenError(constants.ErrCodeForbidden, constants.Forbidden))
+			return
+		}
+		abortWithError(ctx, toAppError(err))
+		return
+	}
+
+	respondWithSuccess(ctx, constants.StatusOK, toSubscriptionDTO(*subscription))
+}
+
+// Unsubscribe godoc
+// @Summary お知らせの通知チャネルを解除
+// @Description ログインユーザーがクラスに登録した通知チャネルを解除します。
+// @Tags Class Subscription
+// @Produce json
+// @Param cid path int true "クラスID"
+// @Param channel path string true "通知チャネル" Enums(EMAIL)
+// @Success 200 {object} string "削除に成功しました"
+// @Failure 400 {object} utils.ErrorResponse "無効なリクエスト"
+// @Failure 404 {object} utils.ErrorResponse "登録されていません"
+// @Failure 500 {object} utils.ErrorResponse "サーバーエラーが発生しました"
+// @Router /cl/subscriptions/{cid}/{channel} [delete]
+// @Security Bearer
+func (c *AnnouncementSubscriptionController) Unsubscribe(ctx *gin.Context) {
+	cid, channel, ok := parseSubscriptionParams(ctx)
+	if !ok {
+		return
+	}
+
+	if err := c.subscriptionService.Unsubscribe(ctx.Request.Context(), ctx.GetUint("userID"), cid, channel); err != nil {
+		abortWithError(ctx, toAppError(err))
+		return
+	}
+
+	respondWithSuccess(ctx, constants.StatusOK, constants.DeleteSuccess)
+}
+
+// parseSubscriptionParams パスのクラスIDと通知チャネルを解析する。不正な場合はエラーを登録してfalseを返す
+func parseSubscriptionParams(ctx *gin.Context) (uint, models.NotificationChannel, bool) {
+	cid, err := strconv.ParseUint(ctx.Param("cid"), 10, 32)
+	if err != nil {
+		abortWithError(ctx, utils.NewBadRequestError(constants.ErrCodeInvalidRequest, constants.InvalidRequest))
+		return 0, "", false
+	}
+	channel := models.NotificationChannel(strings.ToUpper(ctx.Param("channel")))
+	if channel != models.EmailChannel {
+		abortWithError(ctx, utils.NewBadRequestError(constants.ErrCodeInvalidRequest, constants.InvalidRequest))
+		return 0, "", false
+	}
+	return uint(cid), channel, true
+}
+
+// toSubscriptionDTO 購読をレスポンス用に変換する
+func toSubscriptionDTO(subscription models.AnnouncementSubscription) dto.AnnouncementSubscriptionDTO {
+	return dto.AnnouncementSubscriptionDTO{
+		Channel:      string(subscription.Channel),
+		Target:       subscription.Target,
+		FailureCount: subscription.FailureCount,
+		DisabledAt:   subscription.DisabledAt,
+		CreatedAt:    subscription.CreatedAt,
+		UpdatedAt:    subscription.UpdatedAt,
+	}
+}
@@ -1,65 +1,105 @@
 package controllers
 
 import (
-	"fmt"
+	"errors"
+
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/constants"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/middlewares"
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/utils"
 	"github.com/gin-gonic/gin"
 	"log"
 	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultAttendancePageSize 出席情報の一覧でlimitを指定しない場合の件数
+	defaultAttendancePageSize = 20
+	// maxAttendancePageSize 出席情報の一覧で1ページに返す最大の件数。超えるpageSizeはこの件数にする
+	maxAttendancePageSize = 100
 )
 
 // AttendanceController インタフェースを実装
 type AttendanceController struct {
-	attendanceService services.AttendanceService
+	attendanceService    services.AttendanceService
+	classScheduleService services.ClassScheduleService
 }
 
 type AttendanceInput struct {
-	UID    uint   `json:"uid"`
-	CID    uint   `json:"cid"`
-	CSID   uint   `json:"csid"`
-	Status string `json:"status"`
+	UID           uint    `json:"uid"`
+	CID           uint    `json:"cid"`
+	CSID          uint    `json:"csid"`
+	Status        string  `json:"status"`
+	Note          *string `json:"note"`
+	IsNoteVisible *bool   `json:"is_note_visible"`
+	// RecordedAt クライアントでの記録時刻。参考値として保存し、記録時刻にはサーバーの時刻を使用する
+	RecordedAt *time.Time `json:"recorded_at"`
+	// Version 読み込んだ出席情報のバージョン。既存の出席情報を更新する場合に指定する
+	Version uint `json:"version"`
+	// CheckInCode 講師が伝えた確認コード。確認コードを必須にしたスケジュールの自己チェックインで指定する
+	CheckInCode string `json:"check_in_code"`
 }
 
 // NewAttendanceController AttendanceControllerを生成
-func NewAttendanceController(service services.AttendanceService) *AttendanceController {
+func NewAttendanceController(service services.AttendanceService, classScheduleService services.ClassScheduleService) *AttendanceController {
 	return &AttendanceController{
-		attendanceService: service,
+		attendanceService:    service,
+		classScheduleService: classScheduleService,
 	}
 }
 
 // CreateOrUpdateAttendance godoc
 // @Summary 複数の出席情報を作成または更新
-// @Description 複数の出席情報を作成または更新します。'ATTENDANCE', 'TARDY', 'ABSENCE'のいずれかのステータスを持つことができます。
+// @Description 複数の出席情報を作成または更新します。'ATTENDANCE', 'TARDY', 'ABSENCE'のいずれかのステータスを持つことができます。講師コメント(note)と生徒への公開可否(is_note_visible)も指定できます。記録時刻はサーバーの時刻となり、recorded_atは参考値として保存されます。本人の出席はクラスの生徒のみ記録でき、記録元はSELFです。他のユーザーの出席はクラスの講師・アシスタントのみ記録でき、記録元はTEACHERです。既存の出席情報を更新する場合は読み込んだversionを指定し、他の更新が先に保存されていた場合は409と最新の出席情報を返します。確認コードを必須にしたスケジュールの自己チェックインでは、講師画面の確認コード(check_in_code)が必要です。出席方式が確認コードによるチェックイン(CODE)を受け付けないスケジュール(ONLINE)には、本人の出席を記録できません。
 // @Tags Attendance
 // @Accept json
 // @Produce json
 // @Param attendances body []AttendanceInput true "出席情報"
 // @Success 200 {string} string "作成または更新に成功しました"
-// @Failure 400 {string} string "無効なリクエスト"
-// @Failure 500 {string} string "サーバーエラーが発生しました"
+// @Failure 400 {object} utils.ErrorResponse "invalid_request, invalid_attendance_status, version_required"
+// @Failure 403 {object} utils.ErrorResponse "invalid_check_in_code, forbidden, access_restricted"
+// @Failure 404 {object} utils.ErrorResponse "not_found"
+// @Failure 409 {object} utils.ErrorResponse "stale_update"
+// @Failure 422 {object} utils.ErrorResponse "check_in_method_disabled"
+// @Failure 500 {object} utils.ErrorResponse "internal_error"
 // @Router /at [post]
+// @Router /v2/at [post]
 // @Security Bearer
 func (ac *AttendanceController) CreateOrUpdateAttendance(ctx *gin.Context) {
 	var attendances []AttendanceInput
 	if err := ctx.ShouldBindJSON(&attendances); err != nil {
 		log.Printf("Error binding JSON: %v", err)
-		respondWithError(ctx, constants.StatusBadRequest, constants.InvalidRequest)
+		abortWithError(ctx, utils.NewBadRequestError(constants.ErrCodeInvalidRequest, constants.InvalidRequest).Wrap(err))
 		return
 	}
 
+	actorUID := ctx.GetUint("userID")
 	for _, attendance := range attendances {
 		if attendance.Status != string(models.AttendanceStatus) && attendance.Status != string(models.TardyStatus) && attendance.Status != string(models.AbsenceStatus) {
 			log.Printf("Invalid attendance status: %s", attendance.Status)
-			respondWithError(ctx, constants.StatusBadRequest, constants.InvalidRequest)
+			abortWithError(ctx, utils.NewBadRequestError(constants.ErrCodeInvalidAttendanceStatus, constants.InvalidRequest).
+				WithDetails(map[string]interface{}{"status": attendance.Status}))
 			return
 		}
 
-		err := ac.attendanceService.CreateOrUpdateAttendance(attendance.CID, attendance.UID, attendance.CSID, attendance.Status)
+		if !middlewares.CheckClassAccess(ctx, attendance.CID) {
+			return
+		}
+		if attendance.UID == actorUID {
+			// 代理チェックインを防ぐため、教室で伝えた確認コードで本人の出席を確認する
+			if err := ac.classScheduleService.VerifyCheckInCode(ctx.Request.Context(), actorUID, attendance.CSID, attendance.CheckInCode); err != nil {
+				abortWithError(ctx, toAppError(err))
+				return
+			}
+		}
+		err := ac.attendanceService.CreateOrUpdateAttendance(ctx.Request.Context(), attendance.CID, attendance.UID, attendance.CSID, attendance.Status, attendance.Note, attendance.IsNoteVisible, actorUID, attendance.RecordedAt, attendance.Version)
 		if err != nil {
 			log.Printf("Error creating or updating attendance: %v", err)
-			handleServiceError(ctx, err)
+			abortWithError(ctx, toAppError(err))
 			return
 		}
 	}
@@ -67,17 +107,72 @@ func (ac *AttendanceController) CreateOrUpdateAttendance(ctx *gin.Context) {
 	respondWithSuccess(ctx, constants.StatusOK, constants.Success)
 }
 
+// CheckInWithToken godoc
+// @Summary 出席トークンで自己チェックイン
+// @Description 講師画面のQRコードから読み取った出席トークンで本人の出席を記録します。トークンの有効期限は短く、講師画面で表示中のQRコードを読み取ってすぐに送信する必要があります。生徒として参加しているメンバーのみチェックインでき、休講のスケジュールと、出席方式がQRコードによるチェックインを受け付けないスケジュール(ONLINE)にはチェックインできません。確認コードを必須にしたスケジュールでは、講師画面の確認コード(check_in_code)も必要です。接続の回復後に再送する場合は同じIdempotency-Keyを指定してください。既に出席情報がある場合は変更せず、recordedをfalseとして200を返します。
+// @Tags Attendance
+// @Accept json
+// @Produce json
+// @Param Idempotency-Key header string false "再送を識別するキー"
+// @Param request body dto.TokenCheckInRequest true "出席トークン"
+// @Success 201 {object} dto.TokenCheckInResultDTO "出席を記録しました"
+// @Success 200 {object} dto.TokenCheckInResultDTO "既に出席情報があります"
+// @Failure 400 {object} utils.ErrorResponse "invalid_request"
+// @Failure 401 {object} utils.ErrorResponse "unauthorized"
+// @Failure 403 {object} utils.ErrorResponse "invalid_check_in_token, invalid_check_in_code, access_restricted"
+// @Failure 404 {object} utils.ErrorResponse "not_found"
+// @Failure 422 {object} utils.ErrorResponse "check_in_token_expired, schedule_cancelled, check_in_method_disabled"
+// @Failure 503 {object} utils.ErrorResponse "qr_check_in_unavailable"
+// @Router /at/check-in [post]
+// @Router /v2/at/check-in [post]
+// @Security Bearer
+func (ac *AttendanceController) CheckInWithToken(ctx *gin.Context) {
+	var request dto.TokenCheckInRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		abortWithError(ctx, newBindingError(err))
+		return
+	}
+
+	uid := ctx.GetUint("userID")
+	schedule, err := ac.classScheduleService.CheckinWithToken(ctx.Request.Context(), uid, request.Token, request.CheckInCode)
+	if err != nil {
+		abortWithError(ctx, toAppError(err))
+		return
+	}
+	if !middlewares.CheckClassAccess(ctx, schedule.CID) {
+		return
+	}
+	recorded, err := ac.attendanceService.RecordTokenCheckIn(ctx.Request.Context(), *schedule, uid, request.RecordedAt)
+	if err != nil {
+		abortWithError(ctx, toAppError(err))
+		return
+	}
+
+	status := constants.StatusOK
+	if recorded {
+		status = constants.StatusCreated
+	}
+	respondWithSuccess(ctx, status, dto.TokenCheckInResultDTO{CID: schedule.CID, CSID: schedule.ID, Recorded: recorded})
+}
+
 // GetAllAttendances godoc
-// @Summary クラスの全ての出席情報を取得
-// @Description クラスの全ての出席情報を取得
+// @Summary クラスの出席情報を取得
+// @Description クラスの出席情報を記録した順に取得。statusとcsidで絞り込めます。v1は件数の上限なく一致するすべての出席情報を配列で返し、一致する出席情報がない場合は404を返します。v1は互換性のためだけに残しており、出席情報の多いクラスでは応答が大きくなるため、クライアントはv2に移行してください。v2はページごとに取得してdataに出席情報の配列を返し、metaのtotalに条件に一致する総数、next_pageに次のページの番号を返します。最後のページではnext_pageを含めません。v2では範囲外のページや一致する出席情報がない場合も空のdataを返します。limitは1から100までで、範囲外の場合は400を返します。pageSizeはlimitの旧名で、limitを指定しない場合に使われ、範囲外の値は400にせず100件までに収めます。非公開の講師コメントは講師・アシスタントにのみ返されます。
 // @Tags Attendance
 // @Accept json
 // @Produce json
 // @Param cid path int true "Class ID"
-// @Success 200 {array} models.Attendance "Attendance"
-// @Failure 400 {string} string "無効なリクエスト"
-// @Failure 500 {string} string "サーバーエラーが発生しました"
+// @Param page query int false "Page number (v2 only)" default(1)
+// @Param limit query int false "Number of items per page (1-100, v2 only)" default(20)
+// @Param pageSize query int false "Deprecated alias of limit (v2 only)"
+// @Param status query string false "Attendance status" Enums(ATTENDANCE, TARDY, ABSENCE)
+// @Param csid query int false "Class schedule ID"
+// @Success 200 {array} models.Attendance "Attendance (v1 returns all matching attendances without a limit)"
+// @Failure 400 {object} utils.ErrorResponse "invalid_request"
+// @Failure 404 {object} utils.ErrorResponse "attendance_not_found (v1 only)"
+// @Failure 500 {object} utils.ErrorResponse "internal_error"
 // @Router /at/{cid} [get]
+// @Router /v2/at/{cid} [get]
 // @Security Bearer
 func (ac *AttendanceController) GetAllAttendances(ctx *gin.Context) {
 	log.Println("GetAllAttendances: Request received")
@@ -85,53 +180,110 @@ func (ac *AttendanceController) GetAllAttendances(ctx *gin.Context) {
 	classID, err := strconv.ParseUint(ctx.Param("cid"), 10, 32)
 	if err != nil {
 		log.Printf("GetAllAttendances: Invalid classID: %v", err)
-		handleServiceError(ctx, fmt.Errorf(constants.InvalidRequest))
+		abortWithError(ctx, utils.NewBadRequestError(constants.ErrCodeInvalidRequest, constants.InvalidRequest).Wrap(err))
 		return
 	}
 	log.Printf("GetAllAttendances: Parsed classID: %d", classID)
 
-	attendances, serviceErr := ac.attendanceService.GetAllAttendancesByCID(uint(classID))
-	if serviceErr != nil {
-		log.Printf("GetAllAttendances: Error retrieving attendances: %v", serviceErr)
-		handleServiceError(ctx, serviceErr)
+	var filter dto.AttendanceListFilter
+	if status, ok := ctx.GetQuery("status"); ok {
+		filter.Status = models.AttendanceType(strings.ToUpper(status))
+		if filter.Status != models.AttendanceStatus && filter.Status != models.TardyStatus && filter.Status != models.AbsenceStatus {
+			abortWithError(ctx, utils.NewBadRequestError(constants.ErrCodeInvalidAttendanceStatus, constants.InvalidRequest).
+				WithDetails(map[string]interface{}{"status": status}))
+			return
+		}
+	}
+	if rawCSID, ok := ctx.GetQuery("csid"); ok {
+		csid, err := strconv.ParseUint(rawCSID, 10, 32)
+		if err != nil || csid == 0 {
+			abortWithError(ctx, utils.NewBadRequestError(constants.ErrCodeInvalidRequest, constants.InvalidRequest).
+				WithDetails(map[string]interface{}{"csid": rawCSID}))
+			return
+		}
+		filter.CSID = uint(csid)
+	}
+
+	// v1は従来どおり一致するすべての出席情報を配列で返し、ページングしない
+	if middlewares.GetAPIVersion(ctx) != middlewares.APIVersionV2 {
+		attendances, serviceErr := ac.attendanceService.ListAttendancesByCID(ctx.Request.Context(), uint(classID), ctx.GetUint("userID"), filter)
+		if serviceErr != nil {
+			log.Printf("GetAllAttendances: Error retrieving attendances: %v", serviceErr)
+			abortWithError(ctx, toAppError(serviceErr))
+			return
+		}
+		if len(attendances) == 0 {
+			log.Println("GetAllAttendances: No attendances found")
+			abortWithError(ctx, utils.NewNotFoundError(constants.ErrCodeAttendanceNotFound, "No attendance found"))
+			return
+		}
+		log.Printf("GetAllAttendances: Found %d attendances", len(attendances))
+		respondWithSuccess(ctx, constants.StatusOK, attendances)
 		return
 	}
 
-	if len(attendances) == 0 {
-		log.Println("GetAllAttendances: No attendances found")
-		respondWithError(ctx, constants.StatusNotFound, "No attendance found")
+	page, err := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	pageSize := defaultAttendancePageSize
+	if rawLimit, ok := ctx.GetQuery("limit"); ok {
+		pageSize, err = strconv.Atoi(rawLimit)
+		if err != nil || pageSize < 1 || pageSize > maxAttendancePageSize {
+			abortWithError(ctx, utils.NewBadRequestError(constants.ErrCodeInvalidRequest, constants.InvalidAttendanceLimit).
+				WithDetails(map[string]interface{}{"limit": rawLimit}))
+			return
+		}
+	} else if rawPageSize, ok := ctx.GetQuery("pageSize"); ok {
+		// 旧名のpageSizeは既存のクライアントのため従来どおり拒否せずに範囲内に収める
+		pageSize, err = strconv.Atoi(rawPageSize)
+		if err != nil || pageSize < 1 {
+			pageSize = defaultAttendancePageSize
+		}
+		if pageSize > maxAttendancePageSize {
+			pageSize = maxAttendancePageSize
+		}
+	}
+
+	// v2では範囲外のページや一致する出席情報がない場合も404ではなく総数と空のページを返す
+	result, serviceErr := ac.attendanceService.GetAllAttendancesByCID(ctx.Request.Context(), uint(classID), ctx.GetUint("userID"), filter, page, pageSize)
+	if serviceErr != nil {
+		log.Printf("GetAllAttendances: Error retrieving attendances: %v", serviceErr)
+		abortWithError(ctx, toAppError(serviceErr))
 		return
 	}
-	log.Printf("GetAllAttendances: Found %d attendances", len(attendances))
-	respondWithSuccess(ctx, constants.StatusOK, attendances)
+	log.Printf("GetAllAttendances: Found %d of %d attendances", len(result.Attendances), result.Total)
+	respondWithCountedPage(ctx, constants.StatusOK, result.Attendances, result.Page, result.Limit, result.Total, result.NextPage)
 }
 
 // GetAttendance godoc
 // @Summary 出席情報を取得
-// @Description 指定されたIDの出席情報を取得
+// @Description 指定されたIDの出席情報を取得。非公開の講師コメントは講師・アシスタントにのみ返されます。
 // @Tags Attendance
 // @Accept json
 // @Produce json
 // @Param id path int true "Attendance ID"
 // @Success 200 {array} models.Attendance "Attendance"
-// @Failure 400 {string} string "無効なリクエスト"
-// @Failure 500 {string} string "サーバーエラーが発生しました"
+// @Failure 400 {object} utils.ErrorResponse "invalid_request"
+// @Failure 404 {object} utils.ErrorResponse "attendance_not_found"
+// @Failure 500 {object} utils.ErrorResponse "internal_error"
 // @Router /at/attendance/{id} [get]
+// @Router /v2/at/attendance/{id} [get]
 // @Security Bearer
 func (ac *AttendanceController) GetAttendance(ctx *gin.Context) {
 	attendanceID, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
 	if err != nil {
-		handleServiceError(ctx, fmt.Errorf(constants.InvalidRequest))
+		abortWithError(ctx, utils.NewBadRequestError(constants.ErrCodeInvalidRequest, constants.InvalidRequest).Wrap(err))
 		return
 	}
 
-	attendances, err := ac.attendanceService.GetAttendanceByID(strconv.Itoa(int(attendanceID)))
+	attendances, err := ac.attendanceService.GetAttendanceByID(ctx.Request.Context(), strconv.Itoa(int(attendanceID)), ctx.GetUint("userID"))
 	if err != nil {
-		handleServiceError(ctx, err)
+		abortWithError(ctx, toAppError(err))
 		return
 	}
 	if attendances == nil {
-		respondWithError(ctx, constants.StatusNotFound, "Attendance not found")
+		abortWithError(ctx, utils.NewNotFoundError(constants.ErrCodeAttendanceNotFound, "Attendance not found"))
 		return
 	}
 	respondWithSuccess(ctx, constants.StatusOK, attendances)
@@ -145,21 +297,129 @@ func (ac *AttendanceController) GetAttendance(ctx *gin.Context) {
 // @Produce json
 // @Param id path int true "Attendance ID"
 // @Success 200 {string} string "削除に成功しました"
-// @Failure 400 {string} string "無効なリクエスト"
-// @Failure 500 {string} string "サーバーエラーが発生しました"
+// @Failure 400 {object} utils.ErrorResponse "invalid_request"
+// @Failure 500 {object} utils.ErrorResponse "internal_error"
 // @Router /at/attendance/{id} [delete]
+// @Router /v2/at/attendance/{id} [delete]
 // @Security Bearer
 func (ac *AttendanceController) DeleteAttendance(ctx *gin.Context) {
 	attendanceID, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
 	if err != nil {
-		handleServiceError(ctx, fmt.Errorf(constants.InvalidRequest))
+		abortWithError(ctx, utils.NewBadRequestError(constants.ErrCodeInvalidRequest, constants.InvalidRequest).Wrap(err))
 		return
 	}
 
-	if err := ac.attendanceService.DeleteAttendance(strconv.Itoa(int(attendanceID))); err != nil {
-		handleServiceError(ctx, err)
+	if err := ac.attendanceService.DeleteAttendance(ctx.Request.Context(), strconv.Itoa(int(attendanceID))); err != nil {
+		abortWithError(ctx, toAppError(err))
 		return
 	}
 
 	respondWithSuccess(ctx, constants.StatusOK, gin.H{"message": constants.DeleteSuccess})
 }
+
+// ResetScheduleAttendances godoc
+// @Summary スケジュールの出席情報をリセット
+// @Description 授業前のテストデータなど、指定したスケジュールの全ての出席情報を削除します。クラスの管理者のみ利用できます。開始から7日以上経過したスケジュールはリセットできません。
+// @Tags Attendance
+// @Produce json
+// @Param cid path int true "Class ID"
+// @Param csid path int true "Class Schedule ID"
+// @Success 200 {object} map[string]int64 "削除した件数(deleted_count)"
+// @Failure 400 {object} utils.ErrorResponse "invalid_request"
+// @Failure 403 {object} utils.ErrorResponse "forbidden"
+// @Failure 404 {object} utils.ErrorResponse "not_found"
+// @Failure 422 {object} utils.ErrorResponse "schedule_too_old"
+// @Failure 500 {object} utils.ErrorResponse "internal_error"
+// @Router /at/{cid}/schedule/{csid}/reset [delete]
+// @Router /v2/at/{cid}/schedule/{csid}/reset [delete]
+// @Security Bearer
+func (ac *AttendanceController) ResetScheduleAttendances(ctx *gin.Context) {
+	cid, cidErr := strconv.ParseUint(ctx.Param("cid"), 10, 32)
+	csid, csidErr := strconv.ParseUint(ctx.Param("csid"), 10, 32)
+	if cidErr != nil || csidErr != nil {
+		abortWithError(ctx, utils.NewBadRequestError(constants.ErrCodeInvalidRequest, constants.InvalidRequest))
+		return
+	}
+
+	uid := ctx.GetUint("userID")
+	deleted, err := ac.attendanceService.ResetScheduleAttendances(ctx.Request.Context(), uid, uint(cid), uint(csid))
+	if err != nil {
+		if errors.Is(err, services.ErrUnauthorized) {
+			abortWithError(ctx, utils.NewForbiddenError(constants.ErrCodeForbidden, constants.Forbidden))
+			return
+		}
+		abortWithError(ctx, toAppError(err))
+		return
+	}
+
+	log.Printf("[WARN] reset attendances: request_id=%s who=%d schedule_id=%d deleted_count=%d", middlewares.GetRequestID(ctx), uid, csid, deleted)
+	respondWithSuccess(ctx, constants.StatusOK, gin.H{"deleted_count": deleted})
+}
+
+// GetAttendanceStreak godoc
+// @Summary 連続出席(ストリーク)を取得
+// @Description 直近のスケジュールから遡って連続して出席したスケジュール数と、これまでの最長記録を返します。欠席や出席の記録がない終了済みのスケジュールで連続は途切れます。休講のスケジュールは数えません。本人とクラスの講師・アシスタントのみ取得できます。
+// @Tags Attendance
+// @Produce json
+// @Param cid path int true "Class ID"
+// @Param uid path int true "User ID"
+// @Param allow_tardy query bool false "遅刻を出席として数えるか" default(false)
+// @Success 200 {object} dto.AttendanceStreakDTO "ストリーク"
+// @Failure 400 {object} utils.ErrorResponse "invalid_request"
+// @Failure 403 {object} utils.ErrorResponse "forbidden"
+// @Failure 500 {object} utils.ErrorResponse "internal_error"
+// @Router /at/{cid}/streak/{uid} [get]
+// @Router /v2/at/{cid}/streak/{uid} [get]
+// @Security Bearer
+func (ac *AttendanceController) GetAttendanceStreak(ctx *gin.Context) {
+	cid, cidErr := strconv.ParseUint(ctx.Param("cid"), 10, 32)
+	uid, uidErr := strconv.ParseUint(ctx.Param("uid"), 10, 32)
+	allowTardy, tardyErr := strconv.ParseBool(ctx.DefaultQuery("allow_tardy", "false"))
+	if cidErr != nil || uidErr != nil || tardyErr != nil {
+		abortWithError(ctx, utils.NewBadRequestError(constants.ErrCodeInvalidRequest, constants.InvalidRequest))
+		return
+	}
+
+	streak, err := ac.attendanceService.GetAttendanceStreak(ctx.Request.Context(), ctx.GetUint("userID"), uint(uid), uint(cid), allowTardy)
+	if err != nil {
+		if errors.Is(err, services.ErrUnauthorized) {
+			abortWithError(ctx, utils.NewForbiddenError(constants.ErrCodeForbidden, constants.Forbidden))
+			return
+		}
+		abortWithError(ctx, toAppError(err))
+		return
+	}
+	respondWithSuccess(ctx, constants.StatusOK, streak)
+}
+
+// GetAttendanceSummaryByMode godoc
+// @Summary 出席方式ごとの出席状況を取得
+// @Description クラスの出席・遅刻・欠席の件数と出席率を、スケジュールの出席方式(IN_PERSON, ONLINE, HYBRID)ごとに返します。休講のスケジュールは数えません。クラスの講師・アシスタントのみ取得できます。
+// @Tags Attendance
+// @Produce json
+// @Param cid path int true "Class ID"
+// @Success 200 {array} dto.AttendanceModeSummaryDTO "出席方式ごとの集計"
+// @Failure 400 {object} utils.ErrorResponse "invalid_request"
+// @Failure 403 {object} utils.ErrorResponse "forbidden"
+// @Failure 500 {object} utils.ErrorResponse "internal_error"
+// @Router /at/{cid}/summary/by-mode [get]
+// @Router /v2/at/{cid}/summary/by-mode [get]
+// @Security Bearer
+func (ac *AttendanceController) GetAttendanceSummaryByMode(ctx *gin.Context) {
+	cid, err := strconv.ParseUint(ctx.Param("cid"), 10, 32)
+	if err != nil {
+		abortWithError(ctx, utils.NewBadRequestError(constants.ErrCodeInvalidRequest, constants.InvalidRequest))
+		return
+	}
+
+	summaries, err := ac.attendanceService.GetAttendanceSummaryByMode(ctx.Request.Context(), ctx.GetUint("userID"), uint(cid))
+	if err != nil {
+		if errors.Is(err, services.ErrUnauthorized) {
+			abortWithError(ctx, utils.NewForbiddenError(constants.ErrCodeForbidden, constants.Forbidden))
+			return
+		}
+		abortWithError(ctx, toAppError(err))
+		return
+	}
+	respondWithSuccess(ctx, constants.StatusOK, summaries)
+}
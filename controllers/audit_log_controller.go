@@ -0,0 +1,99 @@
+package controllers
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/constants"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// AuditLogController 監査ログのコントローラ
+type AuditLogController struct {
+	auditLogService services.AuditLogService
+}
+
+// NewAuditLogController AuditLogControllerを生成
+func NewAuditLogController(auditLogService services.AuditLogService) *AuditLogController {
+	return &AuditLogController{
+		auditLogService: auditLogService,
+	}
+}
+
+// GetClassAuditLogs godoc
+// @Summary クラスの監査ログを取得
+// @Description クラス内で行われた作成・更新・削除の操作履歴を新しい順に取得します。クラスの管理者のみ利用できます。期間はRFC3339または日付(YYYY-MM-DD)で指定し、省略時は保存期間(180日)の全てが対象です。
+// @Tags Admin
+// @Produce json
+// @Param cid query int true "クラスID"
+// @Param from query string false "開始日時"
+// @Param to query string false "終了日時"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Page size" default(50)
+// @Success 200 {array} models.AuditLog "監査ログ"
+// @Failure 400 {object} utils.ErrorResponse "無効なリクエスト"
+// @Failure 403 {object} utils.ErrorResponse "管理者権限がありません"
+// @Failure 500 {object} utils.ErrorResponse "サーバーエラーが発生しました"
+// @Router /admin/audit [get]
+// @Security Bearer
+func (c *AuditLogController) GetClassAuditLogs(ctx *gin.Context) {
+	cid, err := strconv.ParseUint(ctx.Query("cid"), 10, 32)
+	if err != nil {
+		abortWithError(ctx, utils.NewBadRequestError(constants.ErrCodeInvalidRequest, constants.InvalidRequest))
+		return
+	}
+
+	now := time.Now()
+	from, err := parseTimeQuery(ctx.Query("from"), now.Add(-services.AuditLogRetention), false)
+	if err != nil {
+		abortWithError(ctx, utils.NewBadRequestError(constants.ErrCodeInvalidRequest, constants.InvalidRequest).Wrap(err))
+		return
+	}
+	to, err := parseTimeQuery(ctx.Query("to"), now, true)
+	if err != nil {
+		abortWithError(ctx, utils.NewBadRequestError(constants.ErrCodeInvalidRequest, constants.InvalidRequest).Wrap(err))
+		return
+	}
+
+	page, err := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 || limit > 200 {
+		limit = 50
+	}
+
+	logs, err := c.auditLogService.GetClassAuditLogs(ctx.Request.Context(), ctx.GetUint("userID"), uint(cid), from, to, page, limit)
+	if err != nil {
+		if errors.Is(err, services.ErrUnauthorized) {
+			abortWithError(ctx, utils.NewForbiddenError(constants.ErrCodeForbidden, constants.Forbidden))
+			return
+		}
+		abortWithError(ctx, toAppError(err))
+		return
+	}
+
+	respondWithPage(ctx, constants.StatusOK, logs, page, limit)
+}
+
+// parseTimeQuery RFC3339または日付の文字列を解析する。日付のみの終了日時はその日の終わりとする
+func parseTimeQuery(value string, defaultValue time.Time, endOfDay bool) (time.Time, error) {
+	if value == "" {
+		return defaultValue, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if endOfDay {
+		t = t.Add(24*time.Hour - time.Nanosecond)
+	}
+	return t, nil
+}
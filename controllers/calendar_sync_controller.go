@@ -0,0 +1,110 @@
+package controllers
+
+import (
+	"errors"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/constants"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// CalendarSyncController Googleカレンダーへのスケジュールの同期のコントローラ
+type CalendarSyncController struct {
+	calendarSyncService services.CalendarSyncService
+}
+
+// NewCalendarSyncController CalendarSyncControllerを生成
+func NewCalendarSyncController(calendarSyncService services.CalendarSyncService) *CalendarSyncController {
+	return &CalendarSyncController{
+		calendarSyncService: calendarSyncService,
+	}
+}
+
+// GetSettings godoc
+// @Summary Googleカレンダーとの同期の設定
+// @Description ログインユーザーのGoogleカレンダーへのスケジュールの同期の設定を返します。connectedはGoogleのログインでカレンダーの権限を許可している場合にtrueです。トークンの更新に失敗して同期を停止した場合はdisabled_reasonに理由を返します。
+// @Tags Calendar Sync
+// @Produce json
+// @Success 200 {object} dto.CalendarSyncSettingsDTO "同期の設定"
+// @Failure 500 {object} utils.ErrorResponse "サーバーエラーが発生しました"
+// @Failure 503 {object} utils.ErrorResponse "同期は利用できません"
+// @Router /calendar-sync [get]
+// @Security Bearer
+func (c *CalendarSyncController) GetSettings(ctx *gin.Context) {
+	settings, err := c.calendarSyncService.GetSettings(ctx.Request.Context(), ctx.GetUint("userID"))
+	if err != nil {
+		abortWithError(ctx, toAppError(err))
+		return
+	}
+	respondWithSuccess(ctx, constants.StatusOK, settings)
+}
+
+// UpdateSettings godoc
+// @Summary Googleカレンダーとの同期の設定を変更
+// @Description スケジュールの作成・変更・休講・削除をログインユーザーのGoogleカレンダーに反映するかどうかを設定します。cidsを指定した場合は指定したクラスのみ同期し、ユーザーが管理者のクラスのみ指定できます。空の場合は参加している全てのクラスを同期します。有効にするには、先にGoogleのログインでカレンダーの権限を許可してください。
+// @Tags Calendar Sync
+// @Accept json
+// @Produce json
+// @Param request body dto.CalendarSyncSettingsRequest true "同期の設定"
+// @Success 200 {object} dto.CalendarSyncSettingsDTO "変更後の同期の設定"
+// @Failure 400 {object} utils.ErrorResponse "無効なリクエスト"
+// @Failure 403 {object} utils.ErrorResponse "管理者ではないクラスが含まれています"
+// @Failure 409 {object} utils.ErrorResponse "カレンダーの権限が許可されていません"
+// @Failure 500 {object} utils.ErrorResponse "サーバーエラーが発生しました"
+// @Failure 503 {object} utils.ErrorResponse "同期は利用できません"
+// @Router /calendar-sync [put]
+// @Security Bearer
+func (c *CalendarSyncController) UpdateSettings(ctx *gin.Context) {
+	var request dto.CalendarSyncSettingsRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		abortWithError(ctx, newBindingError(err))
+		return
+	}
+
+	settings, err := c.calendarSyncService.UpdateSettings(ctx.Request.Context(), ctx.GetUint("userID"), request)
+	if err != nil {
+		abortWithCalendarSyncError(ctx, err)
+		return
+	}
+	respondWithSuccess(ctx, constants.StatusOK, settings)
+}
+
+// SyncClassCalendar godoc
+// @Summary クラスのスケジュールをGoogleカレンダーに同期
+// @Description クラスの既存のスケジュールをログインユーザーのGoogleカレンダーに同期します。作成済みの予定は変更し、重複して作成しません。同期は非同期で行い、予約したスケジュールの数を返します。クラスのメンバーのみ利用できます。
+// @Tags Calendar Sync
+// @Produce json
+// @Param id path int true "Class ID"
+// @Success 202 {object} dto.CalendarSyncResultDTO "同期を予約したスケジュールの数"
+// @Failure 400 {object} utils.ErrorResponse "無効なリクエスト"
+// @Failure 403 {object} utils.ErrorResponse "クラスのメンバーではありません"
+// @Failure 409 {object} utils.ErrorResponse "同期が有効になっていません"
+// @Failure 422 {object} utils.ErrorResponse "同期するクラスに含まれていません"
+// @Failure 503 {object} utils.ErrorResponse "同期は利用できません"
+// @Router /cs/{id}/sync-calendar [post]
+// @Security Bearer
+func (c *CalendarSyncController) SyncClassCalendar(ctx *gin.Context) {
+	// スケジュールのルートとパラメーター名を揃えるため:idだが、クラスのIDを指定する
+	cid, ok := parseCommentParam(ctx, "id")
+	if !ok {
+		return
+	}
+
+	result, err := c.calendarSyncService.SyncClass(ctx.Request.Context(), ctx.GetUint("userID"), cid)
+	if err != nil {
+		abortWithCalendarSyncError(ctx, err)
+		return
+	}
+	respondWithSuccess(ctx, constants.StatusAccepted, result)
+}
+
+// abortWithCalendarSyncError 権限のエラーを403として登録し、それ以外はAppErrorに変換して登録する
+func abortWithCalendarSyncError(ctx *gin.Context, err error) {
+	if errors.Is(err, services.ErrUnauthorized) {
+		abortWithError(ctx, utils.NewForbiddenError(constants.ErrCodeForbidden, constants.Forbidden))
+		return
+	}
+	abortWithError(ctx, toAppError(err))
+}
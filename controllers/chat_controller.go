@@ -1,25 +1,42 @@
 package controllers
 
 import (
-	"context"
+	"errors"
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/constants"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/utils"
+	"github.com/gin-contrib/sse"
 	"github.com/gin-gonic/gin"
-	"github.com/go-redis/redis/v8"
+	"github.com/gin-gonic/gin/binding"
 	"io"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
 )
 
 // ChatController チャットコントローラ
 type ChatController struct {
-	chatManager *services.Manager
-	redisClient *redis.Client
+	chatManager     *services.Manager
+	stickerService  services.ChatStickerService
+	scheduleService services.ClassScheduleService
+	userService     services.UserService
+	unreadService   services.UnreadService
+	translation     services.ChatTranslationService
+	historyLimit    int
 }
 
-// NewChatController ChatControllerを生成
-func NewChatController(chatMgr *services.Manager, redisClient *redis.Client) *ChatController {
+// NewChatController ChatControllerを生成。historyLimitはストリーム接続時に送信する履歴の件数。unreadServiceがnilの場合は未読件数を記録しない
+func NewChatController(chatMgr *services.Manager, stickerService services.ChatStickerService, scheduleService services.ClassScheduleService, userService services.UserService, unreadService services.UnreadService, translation services.ChatTranslationService, historyLimit int) *ChatController {
 	return &ChatController{
-		chatManager: chatMgr,
-		redisClient: redisClient,
+		chatManager:     chatMgr,
+		stickerService:  stickerService,
+		scheduleService: scheduleService,
+		userService:     userService,
+		unreadService:   unreadService,
+		translation:     translation,
+		historyLimit:    historyLimit,
 	}
 }
 
@@ -53,37 +70,226 @@ func (c *ChatController) HandleChatRoom(ctx *gin.Context) {
 // @Param scheduleId path string true "スケジュールID"
 // @Success 200 {object} map[string]interface{} "Chat room created successfully."
 // @Failure 400 {object} map[string]interface{} "Failed to create chat room."
+// @Failure 409 {object} map[string]interface{} "休講のスケジュールです"
 // @Router /chat/create-room/{scheduleId} [post]
 // @Security Bearer
 func (c *ChatController) CreateChatRoom(ctx *gin.Context) {
 	scheduleId := ctx.Param("scheduleId")
+	// 休講のスケジュールにはチャットルームを作成しない
+	if id, err := strconv.ParseUint(scheduleId, 10, 32); err == nil {
+		if schedule, err := c.scheduleService.GetClassScheduleByID(ctx.Request.Context(), uint(id)); err == nil && schedule.IsCancelled {
+			respondWithError(ctx, constants.StatusConflict, constants.ScheduleCancelled)
+			return
+		}
+	}
 	c.chatManager.CreateRoom(scheduleId)
 	respondWithSuccess(ctx, constants.StatusOK, "Chat room created successfully.")
 }
 
 // PostToChatRoom godoc
 // @Summary チャットルームに投稿
-// @Description チャットルームにメッセージを投稿する。
+// @Description チャットルームにメッセージまたはスタンプを投稿する。sticker_idを指定した場合はスタンプを送信する。
 // @Tags Chat Room
 // @Accept multipart/form-data
 // @Produce json
 // @Param scheduleId path int true "スケジュールID"
 // @Param user formData string true "ユーザーID"
-// @Param message formData string true "メッセージ"
+// @Param message formData string false "メッセージ"
+// @Param sticker_id formData int false "スタンプID"
 // @Success 200 {object} map[string]interface{} "Message posted successfully."
+// @Failure 400 {object} map[string]interface{} "User and message or sticker must be provided."
+// @Failure 404 {object} map[string]interface{} "Sticker not found."
 // @Router /chat/room/{scheduleId} [post]
 // @Security Bearer
 func (c *ChatController) PostToChatRoom(ctx *gin.Context) {
-	user, message := ctx.PostForm("user"), ctx.PostForm("message")
-	if user == "" || message == "" {
-		respondWithError(ctx, constants.StatusBadRequest, "User and message must be provided.")
+	user, message, stickerParam := ctx.PostForm("user"), ctx.PostForm("message"), ctx.PostForm("sticker_id")
+	if user == "" || (message == "" && stickerParam == "") {
+		respondWithError(ctx, constants.StatusBadRequest, "User and message or sticker must be provided.")
 		return
 	}
 	scheduleId := ctx.Param("scheduleId")
-	c.chatManager.Submit(user, scheduleId, message)
+
+	if stickerParam == "" {
+		c.chatManager.Submit(ctx.Request.Context(), user, scheduleId, message)
+		c.recordUnread(ctx, user, scheduleId, message)
+		respondWithSuccess(ctx, constants.StatusOK, "Message posted successfully.")
+		return
+	}
+
+	stickerID, err := strconv.ParseUint(stickerParam, 10, 32)
+	if err != nil {
+		respondWithError(ctx, constants.StatusBadRequest, "Invalid sticker ID.")
+		return
+	}
+	sticker, err := c.stickerService.GetStickerForRoom(ctx.Request.Context(), uint(stickerID), scheduleId)
+	if err != nil {
+		if errors.Is(err, services.ErrNotFound) {
+			respondWithError(ctx, constants.StatusNotFound, "Sticker not found.")
+			return
+		}
+		handleServiceError(ctx, err)
+		return
+	}
+	c.chatManager.SubmitSticker(ctx.Request.Context(), user, scheduleId, *sticker)
+	c.recordUnread(ctx, user, scheduleId, "")
 	respondWithSuccess(ctx, constants.StatusOK, "Message posted successfully.")
 }
 
+// recordUnread 投稿をクラスのメンバーの未読チャットとメンションに記録する。失敗しても投稿は成功として扱う
+func (c *ChatController) recordUnread(ctx *gin.Context, user, scheduleId, message string) {
+	if c.unreadService == nil {
+		return
+	}
+	id, err := strconv.ParseUint(scheduleId, 10, 32)
+	if err != nil {
+		return
+	}
+	sender, _ := strconv.ParseUint(user, 10, 32)
+	if err := c.unreadService.RecordChatMessage(ctx.Request.Context(), uint(id), uint(sender), message); err != nil {
+		log.Printf("Failed to count unread chat message for room %s: %v", scheduleId, err)
+	}
+}
+
+// GetChatStickers godoc
+// @Summary スタンプ一覧を取得
+// @Description クラスのチャットで送信できるスタンプの一覧を取得する。
+// @Tags Chat Sticker
+// @Produce json
+// @Param cid path int true "クラスID"
+// @Success 200 {array} dto.ChatStickerDTO "スタンプ一覧"
+// @Failure 400 {object} map[string]interface{} "Invalid class ID."
+// @Router /chat/stickers/{cid} [get]
+// @Security Bearer
+func (c *ChatController) GetChatStickers(ctx *gin.Context) {
+	cid, err := strconv.ParseUint(ctx.Param("cid"), 10, 32)
+	if err != nil {
+		respondWithError(ctx, constants.StatusBadRequest, "Invalid class ID.")
+		return
+	}
+	stickers, err := c.stickerService.GetStickers(ctx.Request.Context(), uint(cid))
+	if err != nil {
+		handleServiceError(ctx, err)
+		return
+	}
+	respondWithSuccess(ctx, constants.StatusOK, stickers)
+}
+
+// CreateChatSticker godoc
+// @Summary スタンプを登録
+// @Description クラス独自のスタンプの画像をアップロードして登録する。クラスの管理者のみ登録できる。
+// @Tags Chat Sticker
+// @Accept multipart/form-data
+// @Produce json
+// @Param cid path int true "クラスID"
+// @Param name formData string true "スタンプ名"
+// @Param image formData file true "スタンプ画像"
+// @Success 200 {object} dto.ChatStickerDTO "登録したスタンプ"
+// @Failure 400 {object} map[string]interface{} "Name and image must be provided."
+// @Failure 403 {object} map[string]interface{} "権限がありません"
+// @Failure 500 {object} map[string]interface{} "Failed to create sticker."
+// @Router /chat/stickers/{cid} [post]
+// @Security Bearer
+func (c *ChatController) CreateChatSticker(ctx *gin.Context) {
+	cid, err := strconv.ParseUint(ctx.Param("cid"), 10, 32)
+	if err != nil {
+		respondWithError(ctx, constants.StatusBadRequest, "Invalid class ID.")
+		return
+	}
+	var req dto.ChatStickerCreateDTO
+	if err := ctx.ShouldBindWith(&req, binding.FormMultipart); err != nil {
+		respondWithError(ctx, constants.StatusBadRequest, "Name and image must be provided.")
+		return
+	}
+
+	sticker, err := c.stickerService.CreateSticker(ctx.Request.Context(), ctx.GetUint("userID"), uint(cid), req)
+	if err != nil {
+		if errors.Is(err, services.ErrUnauthorized) {
+			respondWithError(ctx, constants.StatusForbidden, constants.Forbidden)
+			return
+		}
+		log.Printf("Failed to create sticker for class %d: %v", cid, err)
+		respondWithError(ctx, constants.StatusInternalServerError, "Failed to create sticker.")
+		return
+	}
+	respondWithSuccess(ctx, constants.StatusOK, sticker)
+}
+
+// SaveDraft godoc
+// @Summary 下書きを保存
+// @Description チャットルームに書きかけのメッセージを下書きとして保存する。下書きはユーザー本人のみ取得でき、24時間保存されない場合やメッセージを送信した場合に削除される。
+// @Tags Chat Room
+// @Accept json
+// @Produce json
+// @Param scheduleId path string true "スケジュールID"
+// @Param draft body dto.ChatDraftSaveDTO true "下書き"
+// @Success 200 {object} dto.ChatDraftDTO "保存した下書き"
+// @Failure 400 {object} map[string]interface{} "Content must be provided."
+// @Failure 500 {object} map[string]interface{} "Failed to save draft."
+// @Router /chat/drafts/{scheduleId} [put]
+// @Security Bearer
+func (c *ChatController) SaveDraft(ctx *gin.Context) {
+	var req dto.ChatDraftSaveDTO
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		respondWithError(ctx, constants.StatusBadRequest, "Content must be provided.")
+		return
+	}
+
+	draft, err := c.chatManager.SaveDraft(ctx.Request.Context(), ctx.Param("scheduleId"), draftOwner(ctx), req.Content)
+	if err != nil {
+		log.Printf("Failed to save draft for room %s: %v", ctx.Param("scheduleId"), err)
+		respondWithError(ctx, constants.StatusInternalServerError, "Failed to save draft.")
+		return
+	}
+	respondWithSuccess(ctx, constants.StatusOK, draft)
+}
+
+// GetDraft godoc
+// @Summary 下書きを取得
+// @Description 自分がチャットルームに保存した下書きを取得する。
+// @Tags Chat Room
+// @Produce json
+// @Param scheduleId path string true "スケジュールID"
+// @Success 200 {object} dto.ChatDraftDTO "下書き"
+// @Failure 404 {object} map[string]interface{} "Draft not found."
+// @Failure 500 {object} map[string]interface{} "Failed to load draft."
+// @Router /chat/drafts/{scheduleId} [get]
+// @Security Bearer
+func (c *ChatController) GetDraft(ctx *gin.Context) {
+	draft, err := c.chatManager.GetDraft(ctx.Request.Context(), ctx.Param("scheduleId"), draftOwner(ctx))
+	if err != nil {
+		if errors.Is(err, services.ErrNotFound) {
+			respondWithError(ctx, constants.StatusNotFound, "Draft not found.")
+			return
+		}
+		respondWithError(ctx, constants.StatusInternalServerError, "Failed to load draft.")
+		return
+	}
+	respondWithSuccess(ctx, constants.StatusOK, draft)
+}
+
+// DeleteDraft godoc
+// @Summary 下書きを削除
+// @Description 自分がチャットルームに保存した下書きを削除する。
+// @Tags Chat Room
+// @Produce json
+// @Param scheduleId path string true "スケジュールID"
+// @Success 200 {object} string "Draft deleted successfully."
+// @Failure 500 {object} map[string]interface{} "Failed to delete draft."
+// @Router /chat/drafts/{scheduleId} [delete]
+// @Security Bearer
+func (c *ChatController) DeleteDraft(ctx *gin.Context) {
+	if err := c.chatManager.DeleteDraft(ctx.Request.Context(), ctx.Param("scheduleId"), draftOwner(ctx)); err != nil {
+		respondWithError(ctx, constants.StatusInternalServerError, "Failed to delete draft.")
+		return
+	}
+	respondWithSuccess(ctx, constants.StatusOK, "Draft deleted successfully.")
+}
+
+// draftOwner 下書きの持ち主。他のユーザーの下書きを扱えないよう、トークンのユーザーIDを使う
+func draftOwner(ctx *gin.Context) string {
+	return strconv.FormatUint(uint64(ctx.GetUint("userID")), 10)
+}
+
 // DeleteChatRoom godoc
 // @Summary チャットルームを削除
 // @Description チャットルームを削除する。
@@ -92,32 +298,71 @@ func (c *ChatController) PostToChatRoom(ctx *gin.Context) {
 // @Produce json
 // @Param scheduleId path string true "スケジュールID"
 // @Success 200 {object} string "Chat room deleted successfully."
+// @Failure 404 {object} string "Chat room not found."
+// @Failure 500 {object} string "Failed to delete chat room."
 // @Router /chat/room/{scheduleId} [delete]
 // @Security Bearer
 func (c *ChatController) DeleteChatRoom(ctx *gin.Context) {
 	scheduleId := ctx.Param("scheduleId")
-	c.chatManager.DeleteBroadcast(scheduleId)
+	if err := c.chatManager.DeleteBroadcast(scheduleId); err != nil {
+		if errors.Is(err, services.ErrRoomNotFound) {
+			respondWithError(ctx, constants.StatusNotFound, "Chat room not found.")
+			return
+		}
+		log.Printf("Failed to delete chat room %s: %v", scheduleId, err)
+		respondWithError(ctx, constants.StatusInternalServerError, "Failed to delete chat room.")
+		return
+	}
 	respondWithSuccess(ctx, constants.StatusOK, "Chat room deleted successfully.")
 }
 
 // StreamChat godoc
 // @Summary チャットをストリーム
-// @Description チャットをストリームする。
+// @Description チャットをストリームする。接続時に直近のメッセージ履歴を送信し、Last-Event-IDが指定された場合はそれ以降のメッセージを直近200件まで送信する。イベントのIDはルームごとに増加するメッセージIDで、履歴が期限切れになっても戻らない。
 // @Tags Chat Room
 // @Accept json
-// @Produce json
+// @Produce text/event-stream
 // @Param scheduleId path int true "スケジュールID"
+// @Param Last-Event-ID header int false "最後に受信したメッセージID"
 // @Router /chat/stream/{scheduleId} [get]
 // @Security Bearer
 func (c *ChatController) StreamChat(ctx *gin.Context) {
 	scheduleId := ctx.Param("scheduleId")
+	// 履歴の取得中に投稿されたメッセージを取りこぼさないよう、先にリスナーを開く
 	listener := c.chatManager.OpenListener(scheduleId)
 	defer c.chatManager.CloseListener(scheduleId, listener)
 
+	lastEventID, _ := strconv.ParseInt(ctx.GetHeader("Last-Event-ID"), 10, 64)
+	history, err := c.chatManager.GetRecentMessages(ctx.Request.Context(), scheduleId, c.historyLimit, lastEventID)
+	if err != nil {
+		log.Printf("Failed to load chat history for room %s: %v", scheduleId, err)
+	}
+
+	sent := make(map[int64]bool, len(history))
+	for _, event := range history {
+		writeChatEvent(ctx, event)
+		sent[event.ID] = true
+	}
+	ctx.Writer.Flush()
+
 	ctx.Stream(func(w io.Writer) bool {
 		select {
 		case message := <-listener:
-			ctx.SSEvent("message", message)
+			if systemEvent, ok := message.(services.SystemEvent); ok {
+				ctx.SSEvent("system", systemEvent)
+				return true
+			}
+			event, ok := message.(services.ChatEvent)
+			if !ok {
+				ctx.SSEvent("message", message)
+				return true
+			}
+			// 履歴として送信済みのメッセージは重複して送らない
+			if event.ID != 0 && sent[event.ID] {
+				delete(sent, event.ID)
+				return true
+			}
+			writeChatEvent(ctx, event)
 			return true
 		case <-ctx.Request.Context().Done():
 			return false
@@ -125,36 +370,126 @@ func (c *ChatController) StreamChat(ctx *gin.Context) {
 	})
 }
 
+// writeChatEvent メッセージIDを付けてSSEイベントを書き込む。スタンプはstickerイベントとしてJSONで送信する
+func writeChatEvent(ctx *gin.Context, event services.ChatEvent) {
+	sseEvent := sse.Event{Event: "message", Data: event.Text}
+	if event.Message.Type == dto.ChatMessageTypeSticker {
+		sseEvent = sse.Event{Event: "sticker", Data: event.Message}
+	}
+	if event.ID != 0 {
+		sseEvent.Id = strconv.FormatInt(event.ID, 10)
+	}
+	ctx.Render(-1, sseEvent)
+}
+
 // GetChatMessages godoc
 // @Summary チャットメッセージを取得
-// @Description チャットメッセージを取得する。
+// @Description チャットメッセージを取得する。各メッセージはtypeでテキスト(text)とスタンプ(sticker)を区別する。senderに送信者のID・名前・画像を含める。削除されたユーザーは名前が"Deleted User"、avatar_urlがnullになる。format=htmlを指定すると、テキストのMarkdown (コードブロック、引用、インラインコード、太字、斜体、取り消し線、リンク) をサニタイズ済みのHTMLに変換してhtmlに含める。コードブロックの言語はclassに"language-言語"として出力し、対応する言語のコードはPygments互換のclassを持つspanでシンタックスハイライトしてpreにclass="chroma"を付ける。
 // @Tags Chat Room
 // @Accept json
 // @Produce json
 // @Param roomid path string true "ルームID"
-// @Success 200 {object} string "success"
+// @Param format query string false "htmlを指定するとMarkdownを変換したHTMLを含める" Enums(html)
+// @Success 200 {array} dto.ChatMessageDTO "success"
 // @Failure 404 {object} string "Chat room not found"
 // @Router /chat/messages/{roomid} [get]
 // @Security Bearer
 func (c *ChatController) GetChatMessages(ctx *gin.Context) {
 	roomid := ctx.Param("roomid")
-	exists, err := c.redisClient.Exists(context.Background(), "chat:"+roomid).Result()
+	messages, err := c.chatManager.GetRoomMessages(ctx.Request.Context(), roomid)
 	if err != nil {
-		respondWithError(ctx, constants.StatusInternalServerError, "Error checking room existence.")
+		if errors.Is(err, services.ErrRoomNotFound) {
+			respondWithError(ctx, constants.StatusNotFound, "Chat room not found.")
+			return
+		}
+		respondWithError(ctx, constants.StatusInternalServerError, "Failed to load messages.")
 		return
 	}
-	if exists == 0 {
-		respondWithError(ctx, constants.StatusNotFound, "Chat room not found.")
+	if err := c.attachSenders(ctx, messages); err != nil {
+		respondWithError(ctx, constants.StatusInternalServerError, "Failed to load messages.")
 		return
 	}
-	messages, err := c.redisClient.LRange(context.Background(), "chat:"+roomid, 0, -1).Result()
+	if ctx.Query("format") == "html" {
+		renderMessageHTML(messages)
+	}
+	respondWithSuccess(ctx, constants.StatusOK, messages)
+}
+
+// renderMessageHTML テキストのメッセージのMarkdownをHTMLに変換する。保存した本文は変更しない
+func renderMessageHTML(messages []dto.ChatMessageDTO) {
+	for i := range messages {
+		if messages[i].Type == dto.ChatMessageTypeText && messages[i].Text != "" {
+			messages[i].HTML = utils.RenderMarkdown(messages[i].Text)
+		}
+	}
+}
+
+// attachSenders メッセージに送信者のプロフィールを付与する。送信者がユーザーIDでない以前のメッセージには付与しない
+func (c *ChatController) attachSenders(ctx *gin.Context, messages []dto.ChatMessageDTO) error {
+	senderIDs := make([]uint, 0, len(messages))
+	seen := make(map[uint]bool)
+	for _, message := range messages {
+		uid, err := strconv.ParseUint(message.User, 10, 32)
+		if err != nil || seen[uint(uid)] {
+			continue
+		}
+		seen[uint(uid)] = true
+		senderIDs = append(senderIDs, uint(uid))
+	}
+
+	profiles, err := c.userService.GetUserProfiles(ctx.Request.Context(), senderIDs)
 	if err != nil {
-		respondWithError(ctx, constants.StatusInternalServerError, "Failed to load messages.")
+		return err
+	}
+	for i := range messages {
+		uid, err := strconv.ParseUint(messages[i].User, 10, 32)
+		if err != nil {
+			continue
+		}
+		if profile, ok := profiles[uint(uid)]; ok {
+			messages[i].Sender = &profile
+		}
+	}
+	return nil
+}
+
+// TranslateMessage godoc
+// @Summary チャットメッセージを翻訳
+// @Description ルームのメッセージをtarget_langの言語に翻訳し、原文と対で返す。同じメッセージと言語の翻訳はキャッシュを返す。target_langはDeepLの言語コード (JA, EN-US, KO など)。ルームのクラスのメンバーのみ利用でき、翻訳APIで翻訳できるのは1人1分間に30件まで。
+// @Tags Chat Room
+// @Produce json
+// @Param roomid path string true "ルームID"
+// @Param messageId path int true "メッセージID"
+// @Param target_lang query string true "翻訳先の言語コード"
+// @Success 200 {object} dto.ChatTranslationDTO "翻訳"
+// @Failure 400 {object} utils.ErrorResponse "無効なリクエスト"
+// @Failure 403 {object} utils.ErrorResponse "ルームのクラスのメンバーではありません"
+// @Failure 404 {object} utils.ErrorResponse "メッセージが見つかりません"
+// @Failure 422 {object} utils.ErrorResponse "翻訳できる本文がありません"
+// @Failure 429 {object} utils.ErrorResponse "translation_rate_limit"
+// @Failure 502 {object} utils.ErrorResponse "翻訳APIが失敗しました"
+// @Failure 503 {object} utils.ErrorResponse "翻訳を利用できません"
+// @Router /chat/messages/{roomid}/{messageId}/translation [get]
+// @Security Bearer
+func (c *ChatController) TranslateMessage(ctx *gin.Context) {
+	messageID, err := strconv.ParseInt(ctx.Param("messageId"), 10, 64)
+	targetLang := strings.ToUpper(ctx.Query("target_lang"))
+	if err != nil || !languageCodePattern.MatchString(targetLang) {
+		abortWithError(ctx, utils.NewBadRequestError(constants.ErrCodeInvalidRequest, constants.InvalidRequest))
 		return
 	}
-	respondWithSuccess(ctx, constants.StatusOK, messages)
+
+	translation, err := c.translation.TranslateMessage(ctx.Request.Context(), ctx.GetUint("userID"), ctx.Param("roomid"), messageID, targetLang)
+	if err != nil {
+		abortWithError(ctx, toAppError(err))
+		return
+	}
+	respondWithSuccess(ctx, constants.StatusOK, translation)
 }
 
+// languageCodePattern 翻訳先に指定できる言語コード。EN-USやZH-HANSのように地域や文字の指定を含められる
+var languageCodePattern = regexp.MustCompile(`^[A-Z]{2}(-[A-Z]{2,4})?$`)
+
 // SendDirectMessage godoc
 // @Summary DMを送信
 // @Description 特定のユーザーにDMを送信
@@ -165,6 +500,7 @@ func (c *ChatController) GetChatMessages(ctx *gin.Context) {
 // @Param receiverId path string true "受信者ID"
 // @Param message formData string true "Message"
 // @Success 200 {object} string "Message sent successfully"
+// @Failure 403 {object} string "送信者IDが認証済みのユーザーと一致しません"
 // @Router /chat/dm/{senderId}/{receiverId} [post]
 // @Security Bearer
 func (c *ChatController) SendDirectMessage(ctx *gin.Context) {
@@ -173,7 +509,12 @@ func (c *ChatController) SendDirectMessage(ctx *gin.Context) {
 		respondWithError(ctx, constants.StatusBadRequest, "Sender, receiver and message must be provided and non-empty.")
 		return
 	}
-	if err := c.chatManager.SubmitDirectMessage(senderId, receiverId, message); err != nil {
+	// 受信者に配信する送信者のIDは認証済みのユーザーのものに限る
+	if senderId != strconv.FormatUint(uint64(ctx.GetUint("userID")), 10) {
+		respondWithError(ctx, constants.StatusForbidden, constants.Forbidden)
+		return
+	}
+	if err := c.chatManager.SubmitDirectMessage(ctx.Request.Context(), senderId, receiverId, message); err != nil {
 		respondWithError(ctx, constants.StatusInternalServerError, "Failed to send message.")
 		return
 	}
@@ -193,7 +534,7 @@ func (c *ChatController) SendDirectMessage(ctx *gin.Context) {
 // @Security Bearer
 func (c *ChatController) GetDirectMessages(ctx *gin.Context) {
 	senderId, receiverId := ctx.Param("senderId"), ctx.Param("receiverId")
-	messages, err := c.chatManager.GetDirectMessages(senderId, receiverId)
+	messages, err := c.chatManager.GetDirectMessages(ctx.Request.Context(), senderId, receiverId)
 	if err != nil {
 		respondWithError(ctx, constants.StatusInternalServerError, "Failed to fetch messages.")
 		return
@@ -214,9 +555,22 @@ func (c *ChatController) GetDirectMessages(ctx *gin.Context) {
 // @Security Bearer
 func (c *ChatController) DeleteDirectMessages(ctx *gin.Context) {
 	senderId, receiverId := ctx.Param("senderId"), ctx.Param("receiverId")
-	if err := c.chatManager.DeleteDirectMessages(senderId, receiverId); err != nil {
+	if err := c.chatManager.DeleteDirectMessages(ctx.Request.Context(), senderId, receiverId); err != nil {
 		respondWithError(ctx, constants.StatusInternalServerError, "Failed to delete messages.")
 		return
 	}
 	respondWithSuccess(ctx, constants.StatusOK, "Messages deleted successfully.")
 }
+
+// RuntimeStats チャットルームの数とストリームの接続数を返す
+func (c *ChatController) RuntimeStats() map[string]int64 {
+	return map[string]int64{
+		"chat_rooms":           c.chatManager.RoomCount(),
+		"chat_sse_connections": c.chatManager.ListenerCount(),
+	}
+}
+
+// BroadcastSystemEvent チャットのストリームに接続中の全てのクライアントにシステムからの通知を送る
+func (c *ChatController) BroadcastSystemEvent(event services.SystemEvent) {
+	c.chatManager.BroadcastSystemEvent(event)
+}
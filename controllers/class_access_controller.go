@@ -0,0 +1,91 @@
+package controllers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/constants"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// ClassAccessController クラスのアクセス制限のコントローラ
+type ClassAccessController struct {
+	accessService services.ClassAccessService
+}
+
+// NewClassAccessController ClassAccessControllerを生成
+func NewClassAccessController(accessService services.ClassAccessService) *ClassAccessController {
+	return &ClassAccessController{
+		accessService: accessService,
+	}
+}
+
+// GetAccessRestriction godoc
+// @Summary クラスのアクセス制限を取得
+// @Description クラスにアクセスできる時間帯とIPレンジを取得します。項目がnullまたは空の場合はその項目を制限しません。
+// @Tags Admin
+// @Produce json
+// @Param cid path int true "クラスID"
+// @Success 200 {object} dto.ClassAccessRestrictionDTO "アクセス制限"
+// @Failure 400 {object} utils.ErrorResponse "無効なリクエスト"
+// @Failure 404 {object} utils.ErrorResponse "クラスが見つかりません"
+// @Failure 500 {object} utils.ErrorResponse "サーバーエラーが発生しました"
+// @Router /admin/classes/{cid}/access-restriction [get]
+// @Security Bearer
+func (c *ClassAccessController) GetAccessRestriction(ctx *gin.Context) {
+	cid, err := strconv.ParseUint(ctx.Param("cid"), 10, 32)
+	if err != nil {
+		abortWithError(ctx, utils.NewBadRequestError(constants.ErrCodeInvalidRequest, constants.InvalidRequest))
+		return
+	}
+
+	restriction, err := c.accessService.GetRestriction(ctx.Request.Context(), uint(cid))
+	if err != nil {
+		abortWithError(ctx, toAppError(err))
+		return
+	}
+	respondWithSuccess(ctx, constants.StatusOK, restriction)
+}
+
+// SetAccessRestriction godoc
+// @Summary クラスのアクセス制限を設定
+// @Description クラスにアクセスできる時間帯(HH:MM、サーバーのタイムゾーン)とIPレンジ(CIDR)を置き換えます。制限外からのクラスへのアクセスは403になります。全ての項目を省略すると制限を解除します。クラスの管理者のみ利用でき、この操作はアクセス制限の対象外です。
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param cid path int true "クラスID"
+// @Param request body dto.ClassAccessRestrictionDTO true "アクセス制限"
+// @Success 200 {object} dto.ClassAccessRestrictionDTO "設定したアクセス制限"
+// @Failure 400 {object} utils.ErrorResponse "時間帯またはIPレンジの形式が正しくありません"
+// @Failure 403 {object} utils.ErrorResponse "管理者権限がありません"
+// @Failure 404 {object} utils.ErrorResponse "クラスが見つかりません"
+// @Failure 500 {object} utils.ErrorResponse "サーバーエラーが発生しました"
+// @Router /admin/classes/{cid}/access-restriction [put]
+// @Security Bearer
+func (c *ClassAccessController) SetAccessRestriction(ctx *gin.Context) {
+	cid, err := strconv.ParseUint(ctx.Param("cid"), 10, 32)
+	if err != nil {
+		abortWithError(ctx, utils.NewBadRequestError(constants.ErrCodeInvalidRequest, constants.InvalidRequest))
+		return
+	}
+
+	var request dto.ClassAccessRestrictionDTO
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		abortWithError(ctx, newBindingError(err))
+		return
+	}
+
+	restriction, err := c.accessService.SetRestriction(ctx.Request.Context(), ctx.GetUint("userID"), uint(cid), request)
+	if err != nil {
+		if errors.Is(err, services.ErrUnauthorized) {
+			abortWithError(ctx, utils.NewForbiddenError(constants.ErrCodeForbidden, constants.Forbidden))
+			return
+		}
+		abortWithError(ctx, toAppError(err))
+		return
+	}
+	respondWithSuccess(ctx, constants.StatusOK, restriction)
+}
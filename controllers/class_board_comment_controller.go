@@ -0,0 +1,223 @@
+package controllers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/constants"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// ClassBoardCommentController グループ掲示板のコメントのコントローラ
+type ClassBoardCommentController struct {
+	commentService services.ClassBoardCommentService
+}
+
+// NewClassBoardCommentController ClassBoardCommentControllerを生成
+func NewClassBoardCommentController(commentService services.ClassBoardCommentService) *ClassBoardCommentController {
+	return &ClassBoardCommentController{
+		commentService: commentService,
+	}
+}
+
+// GetComments godoc
+// @Summary グループ掲示板のコメント一覧
+// @Description 掲示板へのコメントを古い順に、返信数といいね数、自分がいいね済みかを含めて取得します。リプライは含みません。クラスのメンバーのみ利用できます。
+// @Tags Class Board
+// @Produce json
+// @Param id path int true "Class Board ID"
+// @Success 200 {array} dto.ClassBoardCommentDTO "コメント"
+// @Failure 400 {object} utils.ErrorResponse "無効なリクエスト"
+// @Failure 403 {object} utils.ErrorResponse "クラスのメンバーではありません"
+// @Failure 404 {object} utils.ErrorResponse "掲示板が見つかりません"
+// @Failure 500 {object} utils.ErrorResponse "サーバーエラーが発生しました"
+// @Router /cb/{id}/comments [get]
+// @Security Bearer
+func (c *ClassBoardCommentController) GetComments(ctx *gin.Context) {
+	bid, ok := parseCommentParam(ctx, "id")
+	if !ok {
+		return
+	}
+
+	comments, err := c.commentService.GetComments(ctx.Request.Context(), ctx.GetUint("userID"), bid)
+	if err != nil {
+		abortWithCommentError(ctx, err)
+		return
+	}
+	respondWithSuccess(ctx, constants.StatusOK, comments)
+}
+
+// GetReplies godoc
+// @Summary コメントへのリプライ一覧
+// @Description コメントへのリプライを古い順に、いいね数と自分がいいね済みかを含めて取得します。クラスのメンバーのみ利用できます。
+// @Tags Class Board
+// @Produce json
+// @Param id path int true "Class Board ID"
+// @Param commentID path int true "コメントID"
+// @Success 200 {array} dto.ClassBoardCommentDTO "リプライ"
+// @Failure 400 {object} utils.ErrorResponse "無効なリクエスト"
+// @Failure 403 {object} utils.ErrorResponse "クラスのメンバーではありません"
+// @Failure 404 {object} utils.ErrorResponse "掲示板またはコメントが見つかりません"
+// @Failure 500 {object} utils.ErrorResponse "サーバーエラーが発生しました"
+// @Router /cb/{id}/comments/{commentID}/replies [get]
+// @Security Bearer
+func (c *ClassBoardCommentController) GetReplies(ctx *gin.Context) {
+	bid, commentID, ok := parseCommentParams(ctx)
+	if !ok {
+		return
+	}
+
+	replies, err := c.commentService.GetReplies(ctx.Request.Context(), ctx.GetUint("userID"), bid, commentID)
+	if err != nil {
+		abortWithCommentError(ctx, err)
+		return
+	}
+	respondWithSuccess(ctx, constants.StatusOK, replies)
+}
+
+// CreateComment godoc
+// @Summary グループ掲示板にコメント
+// @Description 掲示板にコメントします。parent_comment_idを指定した場合はそのコメントへのリプライになります。リプライへのリプライはできません。クラスのメンバーのみ利用できます。
+// @Tags Class Board
+// @Accept json
+// @Produce json
+// @Param id path int true "Class Board ID"
+// @Param request body dto.ClassBoardCommentCreateDTO true "コメント"
+// @Success 201 {object} dto.ClassBoardCommentDTO "作成したコメント"
+// @Failure 400 {object} utils.ErrorResponse "無効なリクエスト、またはリプライへのリプライ"
+// @Failure 403 {object} utils.ErrorResponse "クラスのメンバーではありません"
+// @Failure 404 {object} utils.ErrorResponse "掲示板またはリプライ先のコメントが見つかりません"
+// @Failure 500 {object} utils.ErrorResponse "サーバーエラーが発生しました"
+// @Router /cb/{id}/comments [post]
+// @Security Bearer
+func (c *ClassBoardCommentController) CreateComment(ctx *gin.Context) {
+	bid, ok := parseCommentParam(ctx, "id")
+	if !ok {
+		return
+	}
+
+	var request dto.ClassBoardCommentCreateDTO
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		abortWithError(ctx, newBindingError(err))
+		return
+	}
+
+	comment, err := c.commentService.CreateComment(ctx.Request.Context(), ctx.GetUint("userID"), bid, request)
+	if err != nil {
+		abortWithCommentError(ctx, err)
+		return
+	}
+	respondWithSuccess(ctx, constants.StatusCreated, comment)
+}
+
+// DeleteComment godoc
+// @Summary コメントを削除
+// @Description コメントとそのリプライを削除します。投稿者とクラスの管理者、アシスタントのみ利用できます。
+// @Tags Class Board
+// @Param id path int true "Class Board ID"
+// @Param commentID path int true "コメントID"
+// @Success 200 {string} string "削除成功"
+// @Failure 400 {object} utils.ErrorResponse "無効なリクエスト"
+// @Failure 403 {object} utils.ErrorResponse "権限がありません"
+// @Failure 404 {object} utils.ErrorResponse "掲示板またはコメントが見つかりません"
+// @Failure 500 {object} utils.ErrorResponse "サーバーエラーが発生しました"
+// @Router /cb/{id}/comments/{commentID} [delete]
+// @Security Bearer
+func (c *ClassBoardCommentController) DeleteComment(ctx *gin.Context) {
+	bid, commentID, ok := parseCommentParams(ctx)
+	if !ok {
+		return
+	}
+
+	if err := c.commentService.DeleteComment(ctx.Request.Context(), ctx.GetUint("userID"), bid, commentID); err != nil {
+		abortWithCommentError(ctx, err)
+		return
+	}
+	respondWithSuccess(ctx, constants.StatusOK, constants.DeleteSuccess)
+}
+
+// LikeComment godoc
+// @Summary コメントにいいね
+// @Description コメントにいいねします。いいね済みの場合は何もしません。クラスのメンバーのみ利用できます。
+// @Tags Class Board
+// @Produce json
+// @Param id path int true "Class Board ID"
+// @Param commentID path int true "コメントID"
+// @Success 200 {object} dto.ClassBoardCommentDTO "いいね数を更新したコメント"
+// @Failure 400 {object} utils.ErrorResponse "無効なリクエスト"
+// @Failure 403 {object} utils.ErrorResponse "クラスのメンバーではありません"
+// @Failure 404 {object} utils.ErrorResponse "掲示板またはコメントが見つかりません"
+// @Failure 500 {object} utils.ErrorResponse "サーバーエラーが発生しました"
+// @Router /cb/{id}/comments/{commentID}/like [put]
+// @Security Bearer
+func (c *ClassBoardCommentController) LikeComment(ctx *gin.Context) {
+	c.setLike(ctx, true)
+}
+
+// UnlikeComment godoc
+// @Summary コメントのいいねを取り消し
+// @Description コメントのいいねを取り消します。いいねしていない場合は何もしません。クラスのメンバーのみ利用できます。
+// @Tags Class Board
+// @Produce json
+// @Param id path int true "Class Board ID"
+// @Param commentID path int true "コメントID"
+// @Success 200 {object} dto.ClassBoardCommentDTO "いいね数を更新したコメント"
+// @Failure 400 {object} utils.ErrorResponse "無効なリクエスト"
+// @Failure 403 {object} utils.ErrorResponse "クラスのメンバーではありません"
+// @Failure 404 {object} utils.ErrorResponse "掲示板またはコメントが見つかりません"
+// @Failure 500 {object} utils.ErrorResponse "サーバーエラーが発生しました"
+// @Router /cb/{id}/comments/{commentID}/like [delete]
+// @Security Bearer
+func (c *ClassBoardCommentController) UnlikeComment(ctx *gin.Context) {
+	c.setLike(ctx, false)
+}
+
+// setLike いいねを設定または取り消し、更新後のコメントを返す
+func (c *ClassBoardCommentController) setLike(ctx *gin.Context, liked bool) {
+	bid, commentID, ok := parseCommentParams(ctx)
+	if !ok {
+		return
+	}
+
+	comment, err := c.commentService.SetLike(ctx.Request.Context(), ctx.GetUint("userID"), bid, commentID, liked)
+	if err != nil {
+		abortWithCommentError(ctx, err)
+		return
+	}
+	respondWithSuccess(ctx, constants.StatusOK, comment)
+}
+
+// parseCommentParams パスの掲示板IDとコメントIDを解析する。不正な場合は400を登録してfalseを返す
+func parseCommentParams(ctx *gin.Context) (uint, uint, bool) {
+	bid, ok := parseCommentParam(ctx, "id")
+	if !ok {
+		return 0, 0, false
+	}
+	commentID, ok := parseCommentParam(ctx, "commentID")
+	if !ok {
+		return 0, 0, false
+	}
+	return bid, commentID, true
+}
+
+// parseCommentParam パスのIDを解析する。不正な場合は400を登録してfalseを返す
+func parseCommentParam(ctx *gin.Context, name string) (uint, bool) {
+	id, err := strconv.ParseUint(ctx.Param(name), 10, 32)
+	if err != nil {
+		abortWithError(ctx, utils.NewBadRequestError(constants.ErrCodeInvalidRequest, constants.InvalidRequest))
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// abortWithCommentError 権限のエラーを403として登録し、それ以外はAppErrorに変換して登録する
+func abortWithCommentError(ctx *gin.Context, err error) {
+	if errors.Is(err, services.ErrUnauthorized) {
+		abortWithError(ctx, utils.NewForbiddenError(constants.ErrCodeForbidden, constants.Forbidden))
+		return
+	}
+	abortWithError(ctx, toAppError(err))
+}
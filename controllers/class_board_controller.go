@@ -1,10 +1,12 @@
 package controllers
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/constants"
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/middlewares"
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/utils"
 	"github.com/gin-gonic/gin"
@@ -12,6 +14,7 @@ import (
 	"log"
 	"net/http"
 	"strconv"
+	"time"
 )
 
 // ClassBoardController インタフェースを実装
@@ -30,7 +33,7 @@ func NewClassBoardController(service services.ClassBoardService, uploader utils.
 
 // CreateClassBoard godoc
 // @Summary クラス掲示板を作成
-// @Description クラス掲示板を作成します。
+// @Description クラス掲示板を作成します。variantsに講師が用意した他の言語の版を指定すると、取得時に利用者の言語に最も近い版を返します。近い版がない場合はlanguageの版(タイトルと本文)を返します。ウイルススキャンが有効な場合、添付画像はScanStatusがpendingの状態で保存され、スキャンの完了後にcleanまたはinfectedになります。infectedの画像は削除され、投稿者に通知されます。
 // @Tags Class Board
 // @Security ApiKeyAuth
 // @CrossOrigin
@@ -42,6 +45,8 @@ func NewClassBoardController(service services.ClassBoardService, uploader utils.
 // @Param uid formData int true "User ID"
 // @Param is_announced formData boolean false "Is announced"
 // @Param image formData file false "Upload image file"
+// @Param language formData string false "タイトルと本文の言語 (例: ja)"
+// @Param variants formData string false "他の言語の版のJSONの配列 (例: [{\"language\":\"en\",\"title\":\"...\",\"content\":\"...\"}])。最大10件"
 // @Success 200 {object} models.ClassBoard "Class board created successfully"
 // @Failure 400 {string} string "Invalid request"
 // @Failure 401 {string} string "Unauthorized"
@@ -54,6 +59,16 @@ func (c *ClassBoardController) CreateClassBoard(ctx *gin.Context) {
 		respondWithError(ctx, constants.StatusBadRequest, constants.BadRequestMessage)
 		return
 	}
+	if createDTO.VariantsJSON != "" {
+		if err := json.Unmarshal([]byte(createDTO.VariantsJSON), &createDTO.Variants); err != nil {
+			respondWithError(ctx, constants.StatusBadRequest, constants.BadRequestMessage)
+			return
+		}
+		if err := binding.Validator.ValidateStruct(&createDTO); err != nil {
+			respondWithError(ctx, constants.StatusBadRequest, constants.BadRequestMessage)
+			return
+		}
+	}
 
 	cid, err := strconv.ParseUint(ctx.PostForm("cid"), 10, 64)
 	if err != nil {
@@ -68,7 +83,7 @@ func (c *ClassBoardController) CreateClassBoard(ctx *gin.Context) {
 	}
 	createDTO.ImageURL = imageUrl
 
-	result, err := c.classBoardService.CreateClassBoard(createDTO)
+	result, err := c.classBoardService.CreateClassBoard(ctx.Request.Context(), createDTO)
 	if err != nil {
 		handleServiceError(ctx, err)
 		return
@@ -82,13 +97,13 @@ func (c *ClassBoardController) CreateClassBoard(ctx *gin.Context) {
 
 // GetClassBoardByID godoc
 // @Summary IDでグループ掲示板を取得
-// @Description 指定されたIDのグループ掲示板の詳細を取得します。
+// @Description 指定されたIDのグループ掲示板の詳細を、投稿者の公開プロフィール(author)付きで取得します。他の言語の版がある場合、タイトルと本文はログインユーザーの言語に最も近い版になり、languageに表示している版の言語、languagesに表示できる言語を返します。投稿者のメールアドレスは含みません。ScanStatusがpendingの添付画像はウイルススキャン中のため、ダウンロード前に利用者に警告してください。
 // @Tags Class Board
 // @CrossOrigin
 // @Accept json
 // @Produce json
 // @Param id path int true "Class Board ID"
-// @Success 200 {object} models.ClassBoard "グループ掲示板が取得されました"
+// @Success 200 {object} dto.ClassBoardDetailDTO "グループ掲示板が取得されました"
 // @Failure 400 {object} string "無効なリクエストです"
 // @Failure 404 {object} string "コードが見つかりません"
 // @Failure 500 {object} string "サーバーエラーが発生しました"
@@ -101,18 +116,20 @@ func (c *ClassBoardController) GetClassBoardByID(ctx *gin.Context) {
 		return
 	}
 
-	result, err := c.classBoardService.GetClassBoardByID(uint(ID))
+	result, err := c.classBoardService.GetClassBoardByID(ctx.Request.Context(), uint(ID), ctx.GetUint("userID"))
 	if err != nil {
 		handleServiceError(ctx, err)
 		return
 	}
 
+	c.classBoardService.RecordView(uint(ID), result.CID, ctx.GetUint("userID"))
+
 	respondWithSuccess(ctx, constants.StatusOK, result)
 }
 
 // GetAllClassBoards godoc
 // @Summary 全てのグループ掲示板を取得
-// @Description cidに基づいて、グループの全ての掲示板を取得します。
+// @Description cidに基づいて、グループの全ての掲示板を取得します。他の言語の版がある掲示板は、ログインユーザーの言語に最も近い版のタイトルと本文を返します。
 // @Tags Class Board
 // @CrossOrigin
 // @Accept json
@@ -144,7 +161,7 @@ func (c *ClassBoardController) GetAllClassBoards(ctx *gin.Context) {
 		return
 	}
 
-	result, err := c.classBoardService.GetAllClassBoards(uint(cid), page, pageSize)
+	result, err := c.classBoardService.GetAllClassBoards(ctx.Request.Context(), uint(cid), ctx.GetUint("userID"), page, pageSize)
 	if err != nil {
 		handleServiceError(ctx, err)
 		return
@@ -154,7 +171,7 @@ func (c *ClassBoardController) GetAllClassBoards(ctx *gin.Context) {
 
 // GetAnnouncedClassBoards godoc
 // @Summary 公告されたグループ掲示板を取得
-// @Description cidに基づいて、公告されたグループの掲示板を取得します。
+// @Description cidに基づいて、公告されたグループの掲示板を取得します。他の言語の版がある掲示板は、ログインユーザーの言語に最も近い版のタイトルと本文を返します。
 // @Tags Class Board
 // @CrossOrigin
 // @Accept json
@@ -171,7 +188,7 @@ func (c *ClassBoardController) GetAnnouncedClassBoards(ctx *gin.Context) {
 		return
 	}
 
-	result, err := c.classBoardService.GetAnnouncedClassBoards(uint(cid))
+	result, err := c.classBoardService.GetAnnouncedClassBoards(ctx.Request.Context(), uint(cid), ctx.GetUint("userID"))
 	if err != nil {
 		handleServiceError(ctx, err)
 		return
@@ -181,7 +198,7 @@ func (c *ClassBoardController) GetAnnouncedClassBoards(ctx *gin.Context) {
 
 // UpdateClassBoard godoc
 // @Summary グループ掲示板を更新
-// @Description 指定されたIDのグループ掲示板の詳細を更新します。
+// @Description 指定されたIDのグループ掲示板の詳細を更新します。読み込んだ掲示板のversionを指定し、他の更新が先に保存されていた場合は409と最新の掲示板を返します。
 // @Tags Class Board
 // @CrossOrigin
 // @Accept json
@@ -194,6 +211,7 @@ func (c *ClassBoardController) GetAnnouncedClassBoards(ctx *gin.Context) {
 // @Failure 400 {object} string "リクエストが不正です"
 // @Failure 401 {string} string "Unauthorized"
 // @Failure 404 {object} string "コードが見つかりません"
+// @Failure 409 {object} utils.ErrorResponse "stale_update"
 // @Failure 500 {object} string "サーバーエラーが発生しました"
 // @Router /cb/{id}/{cid}/{uid} [patch]
 // @Security Bearer
@@ -224,7 +242,7 @@ func (c *ClassBoardController) UpdateClassBoard(ctx *gin.Context) {
 		}
 	}
 
-	result, err := c.classBoardService.UpdateClassBoard(uint(ID), updateDTO, imageUrl)
+	result, err := c.classBoardService.UpdateClassBoard(ctx.Request.Context(), uint(ID), updateDTO, imageUrl)
 	if err != nil {
 		log.Println("Error updating class board:", err)
 		handleServiceError(ctx, err)
@@ -261,7 +279,7 @@ func (c *ClassBoardController) DeleteClassBoard(ctx *gin.Context) {
 		respondWithError(ctx, constants.StatusBadRequest, constants.InvalidRequest)
 		return
 	}
-	err = c.classBoardService.DeleteClassBoard(uint(ID))
+	err = c.classBoardService.DeleteClassBoard(ctx.Request.Context(), uint(ID))
 	if err != nil {
 		handleServiceError(ctx, err)
 		return
@@ -269,6 +287,99 @@ func (c *ClassBoardController) DeleteClassBoard(ctx *gin.Context) {
 	respondWithSuccess(ctx, constants.StatusOK, constants.DeleteSuccess)
 }
 
+// BulkDeleteClassBoards godoc
+// @Summary グループ掲示板を一括削除
+// @Description 指定された日付より前に作成されたクラスのグループ掲示板と添付画像を一括削除します。クラスの管理者のみ実行できます。
+// @Tags Class Board
+// @CrossOrigin
+// @Security ApiKeyAuth
+// @Produce json
+// @Param cid query int true "Class ID"
+// @Param before query string true "この日時より前に作成された掲示板を削除 (YYYY-MM-DD または RFC3339)"
+// @Success 200 {object} map[string]int64 "削除件数"
+// @Failure 400 {string} string "無効なリクエストです"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "権限がありません"
+// @Failure 500 {object} string "サーバーエラーが発生しました"
+// @Router /cb [delete]
+// @Security Bearer
+func (c *ClassBoardController) BulkDeleteClassBoards(ctx *gin.Context) {
+	cid, err := strconv.ParseUint(ctx.Query("cid"), 10, 32)
+	if err != nil || ctx.Query("before") == "" {
+		respondWithError(ctx, constants.StatusBadRequest, constants.InvalidRequest)
+		return
+	}
+	before, err := parseTimeQuery(ctx.Query("before"), time.Time{}, false)
+	if err != nil {
+		respondWithError(ctx, constants.StatusBadRequest, constants.InvalidRequest)
+		return
+	}
+
+	uid := ctx.GetUint("userID")
+	deleted, err := c.classBoardService.BulkDeleteClassBoards(ctx.Request.Context(), uid, uint(cid), before)
+	if err != nil {
+		if errors.Is(err, services.ErrUnauthorized) {
+			respondWithError(ctx, constants.StatusForbidden, constants.Forbidden)
+			return
+		}
+		handleServiceError(ctx, err)
+		return
+	}
+
+	log.Printf("[WARN] bulk delete class boards: request_id=%s who=%d class_id=%d before=%s deleted_count=%d", middlewares.GetRequestID(ctx), uid, cid, before.Format(time.RFC3339), deleted)
+	respondWithSuccess(ctx, constants.StatusOK, gin.H{"deleted_count": deleted})
+}
+
+// PinClassBoard godoc
+// @Summary グループ掲示板のピン留めを設定
+// @Description 指定されたIDのグループ掲示板をピン留め、または解除します。pin_untilを指定した場合はその日時に自動的に解除されます。クラスの管理者のみ実行できます。
+// @Tags Class Board
+// @CrossOrigin
+// @Accept json
+// @Produce json
+// @Param id path int true "Class Board ID"
+// @Param body body dto.ClassBoardPinDTO true "ピン留めの設定"
+// @Success 200 {object} models.ClassBoard "ピン留めが更新されました"
+// @Failure 400 {string} string "無効なリクエストです"
+// @Failure 403 {string} string "権限がありません"
+// @Failure 404 {object} string "コードが見つかりません"
+// @Failure 500 {object} string "サーバーエラーが発生しました"
+// @Router /cb/{id}/pin [patch]
+// @Security Bearer
+func (c *ClassBoardController) PinClassBoard(ctx *gin.Context) {
+	ID, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		respondWithError(ctx, constants.StatusBadRequest, constants.InvalidRequest)
+		return
+	}
+
+	var pinDTO dto.ClassBoardPinDTO
+	if err := ctx.ShouldBindJSON(&pinDTO); err != nil {
+		respondWithError(ctx, constants.StatusBadRequest, constants.BadRequestMessage)
+		return
+	}
+	if pinDTO.Pinned && pinDTO.PinUntil != nil && !pinDTO.PinUntil.After(time.Now()) {
+		respondWithError(ctx, constants.StatusBadRequest, constants.PinUntilInPast)
+		return
+	}
+
+	result, err := c.classBoardService.PinClassBoard(ctx.Request.Context(), uint(ID), ctx.GetUint("userID"), pinDTO.Pinned, pinDTO.PinUntil)
+	if err != nil {
+		if errors.Is(err, services.ErrUnauthorized) {
+			respondWithError(ctx, constants.StatusForbidden, constants.Forbidden)
+			return
+		}
+		handleServiceError(ctx, err)
+		return
+	}
+	middlewares.SetAuditClassID(ctx, result.CID)
+
+	msg := fmt.Sprintf("data: %s\n\n", "Class board updated")
+	c.classBoardService.GetUpdateNotifier().Broadcast <- []byte(msg)
+
+	respondWithSuccess(ctx, constants.StatusOK, result)
+}
+
 // respondWithError エラーレスポンスを返す
 func (c *ClassBoardController) handleImageUpload(ctx *gin.Context, cid uint) (string, error) {
 	// Check if there's any file part
@@ -348,7 +459,7 @@ func (c *ClassBoardController) SearchClassBoards(ctx *gin.Context) {
 		return
 	}
 
-	result, err := c.classBoardService.SearchClassBoardsByTitle(title, uint(cid))
+	result, err := c.classBoardService.SearchClassBoardsByTitle(ctx.Request.Context(), title, uint(cid))
 	if err != nil {
 		handleServiceError(ctx, err)
 		return
@@ -360,3 +471,20 @@ func (c *ClassBoardController) SearchClassBoards(ctx *gin.Context) {
 
 	respondWithSuccess(ctx, constants.StatusOK, result)
 }
+
+// RuntimeStats 掲示板の更新を購読しているSSEの接続数を返す
+func (c *ClassBoardController) RuntimeStats() map[string]int64 {
+	return map[string]int64{
+		"class_board_sse_connections": int64(c.classBoardService.GetUpdateNotifier().ClientCount()),
+	}
+}
+
+// BroadcastSystemEvent 掲示板の更新を購読している全てのクライアントにシステムからの通知を送る
+func (c *ClassBoardController) BroadcastSystemEvent(event services.SystemEvent) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("Failed to encode system event: %v", err)
+		return
+	}
+	c.classBoardService.GetUpdateNotifier().Broadcast <- []byte(fmt.Sprintf("event: system\ndata: %s\n\n", data))
+}
@@ -1,9 +1,11 @@
 package controllers
 
 import (
+	"errors"
 	"strconv"
 
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/constants"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/middlewares"
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
 	"github.com/gin-gonic/gin"
 )
@@ -35,7 +37,7 @@ func NewClassCodeController(classCodeService services.ClassCodeService, classUse
 func (c *ClassCodeController) CheckSecretExists(ctx *gin.Context) {
 	code := ctx.Query("code")
 
-	secretExists, err := c.classCodeService.CheckSecretExists(code)
+	secretExists, err := c.classCodeService.CheckSecretExists(ctx.Request.Context(), code)
 	if err != nil {
 		// エラーメッセージに基づいて適切なHTTPステータスを返す
 		if err.Error() == services.ErrClassNotFound {
@@ -58,10 +60,13 @@ func (c *ClassCodeController) CheckSecretExists(ctx *gin.Context) {
 // @Param code query string true "Code to verify"
 // @Param secret query string false "Secret for the code"
 // @Param uid query int true "User ID to assign role"
-// @Success 200 {object} string "グループコードが検証されました"
+// @Success 201 {object} string "グループコードが検証されました"
+// @Success 200 {object} map[string]interface{} "既にメンバーの場合はロールを変更せずにalready_memberとroleを返す"
 // @Failure 400 {object} string "無効なリクエストです"
 // @Failure 401 {object} string "シークレットが一致しません"
 // @Failure 404 {object} string "コードが見つかりません"
+// @Failure 409 {object} string "このクラスコードは既に使用されています"
+// @Failure 422 {object} string "参加できるクラス数の上限に達しています"
 // @Router /cc/verifyClassCode [get]
 // @Security Bearer
 func (c *ClassCodeController) VerifyClassCode(ctx *gin.Context) {
@@ -74,7 +79,7 @@ func (c *ClassCodeController) VerifyClassCode(ctx *gin.Context) {
 		return
 	}
 
-	isValid, err := c.classCodeService.VerifyClassCode(code, secret)
+	isValid, err := c.classCodeService.VerifyClassCode(ctx.Request.Context(), code, secret)
 	if err != nil {
 		if err.Error() == services.ErrClassNotFound {
 			respondWithError(ctx, constants.StatusNotFound, constants.ClassNotFound)
@@ -89,15 +94,33 @@ func (c *ClassCodeController) VerifyClassCode(ctx *gin.Context) {
 		return
 	}
 
+	classCode, err := c.classCodeService.FindClassCode(ctx.Request.Context(), code)
+	if err != nil {
+		respondWithError(ctx, constants.StatusInternalServerError, constants.InternalServerError)
+		return
+	}
+
 	roleName := "APPLICANT"
-	cid := uint(uid)
-	err = c.classUserService.AssignRole(uint(uid), cid, roleName)
+	classUser, joined, err := c.classUserService.AssignRoleViaCode(ctx.Request.Context(), uint(uid), classCode.CID, roleName, classCode.ID)
 	if err != nil {
+		if errors.Is(err, services.ErrActiveClassLimit) {
+			respondWithError(ctx, constants.StatusUnprocessable, constants.ActiveClassLimitReached)
+			return
+		}
+		if errors.Is(err, services.ErrClassCodeUsed) {
+			respondWithError(ctx, constants.StatusConflict, constants.ClassCodeAlreadyUsed)
+			return
+		}
 		respondWithError(ctx, constants.StatusInternalServerError, constants.AssignError)
 		return
 	}
+	if !joined {
+		respondWithSuccess(ctx, constants.StatusOK, gin.H{"valid": true, "already_member": true, "role": classUser.Role})
+		return
+	}
+	middlewares.MarkClassChanged(ctx, classCode.CID)
 
-	respondWithSuccess(ctx, constants.StatusOK, gin.H{"valid": true, "message": constants.ClassMemberRegistration})
+	respondWithSuccess(ctx, constants.StatusCreated, gin.H{"valid": true, "message": constants.ClassMemberRegistration, "role": classUser.Role})
 }
 
 // VerifyAndRequestAccess godoc
@@ -109,10 +132,13 @@ func (c *ClassCodeController) VerifyClassCode(ctx *gin.Context) {
 // @Param code query string true "確認するクラスコード"
 // @Param secret query string false "必要な場合のクラスコードのシークレット"
 // @Param uid query int true "役割を割り当ててアクセスを要求するユーザーID"
-// @Success 200 {object} map[string]interface{} "Access request submitted successfully with validation result."
+// @Success 201 {object} map[string]interface{} "Access request submitted successfully with validation result."
+// @Success 200 {object} map[string]interface{} "Already a member; the existing role is returned unchanged."
 // @Failure 400 {string} string "Invalid request"
 // @Failure 401 {string} string "Invalid or missing secret"
 // @Failure 404 {string} string "Class code not found"
+// @Failure 409 {string} string "このクラスコードは既に使用されています"
+// @Failure 422 {string} string "参加できるクラス数の上限に達しています"
 // @Failure 500 {string} string "Internal server error or error assigning role"
 // @Router /cc/verifyAndRequestAccess [get]
 // @Security Bearer
@@ -126,7 +152,7 @@ func (c *ClassCodeController) VerifyAndRequestAccess(ctx *gin.Context) {
 		return
 	}
 
-	classCode, err := c.classCodeService.FindClassCode(code)
+	classCode, err := c.classCodeService.FindClassCode(ctx.Request.Context(), code)
 	if err != nil {
 		if err.Error() == services.ErrClassNotFound {
 			respondWithError(ctx, constants.StatusNotFound, constants.ClassNotFound)
@@ -145,15 +171,34 @@ func (c *ClassCodeController) VerifyAndRequestAccess(ctx *gin.Context) {
 
 	roleName := "APPLICANT"
 	cid := classCode.CID
-	err = c.classUserService.AssignRole(uint(uid), cid, roleName)
+	classUser, joined, err := c.classUserService.AssignRoleViaCode(ctx.Request.Context(), uint(uid), cid, roleName, classCode.ID)
 	if err != nil {
+		if errors.Is(err, services.ErrActiveClassLimit) {
+			respondWithError(ctx, constants.StatusUnprocessable, constants.ActiveClassLimitReached)
+			return
+		}
+		if errors.Is(err, services.ErrClassCodeUsed) {
+			respondWithError(ctx, constants.StatusConflict, constants.ClassCodeAlreadyUsed)
+			return
+		}
 		respondWithError(ctx, constants.StatusInternalServerError, "Error assigning role")
 		return
 	}
+	if !joined {
+		respondWithSuccess(ctx, constants.StatusOK, gin.H{
+			"valid":          true,
+			"already_member": true,
+			"cid":            cid,
+			"role":           classUser.Role,
+		})
+		return
+	}
+	middlewares.MarkClassChanged(ctx, cid)
 
-	respondWithSuccess(ctx, constants.StatusOK, gin.H{
+	respondWithSuccess(ctx, constants.StatusCreated, gin.H{
 		"valid":   true,
 		"message": "Access request submitted successfully.",
 		"cid":     cid,
+		"role":    classUser.Role,
 	})
 }
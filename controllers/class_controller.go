@@ -6,6 +6,8 @@ import (
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"gorm.io/gorm"
 
@@ -18,14 +20,16 @@ import (
 )
 
 type ClassController struct {
-	classService services.ClassService
-	uploader     utils.Uploader
+	classService         services.ClassService
+	classScheduleService services.ClassScheduleService
+	uploader             utils.Uploader
 }
 
-func NewCreateClassController(classService services.ClassService, uploader utils.Uploader) *ClassController {
+func NewCreateClassController(classService services.ClassService, classScheduleService services.ClassScheduleService, uploader utils.Uploader) *ClassController {
 	return &ClassController{
-		classService: classService,
-		uploader:     uploader,
+		classService:         classService,
+		classScheduleService: classScheduleService,
+		uploader:             uploader,
 	}
 }
 
@@ -49,7 +53,7 @@ func (cc *ClassController) GetClass(ctx *gin.Context) {
 		return
 	}
 
-	class, classCode, err := cc.classService.GetClassWithCode(uint(classID))
+	class, classCode, err := cc.classService.GetClassWithCode(ctx.Request.Context(), uint(classID))
 	if errors.Is(err, gorm.ErrRecordNotFound) {
 		respondWithError(ctx, constants.StatusNotFound, constants.ClassNotFound)
 		return
@@ -78,31 +82,347 @@ func (cc *ClassController) GetClass(ctx *gin.Context) {
 	respondWithSuccess(ctx, constants.StatusOK, response)
 }
 
+// GetClassPreview godoc
+// @Summary クラス参加前のプレビュー情報を取得します
+// @Description クラスの名前、説明、講師、メンバー数、次回スケジュールを取得します。シークレットが設定されたクラスは、X-Class-Secretヘッダーに正しいシークレットを指定しない場合は最小限の情報のみ返します。アクセスログに残らないよう、シークレットはクエリではなくヘッダーで指定します。シークレットの入力ミスが10分間に5回に達すると429を返します。
+// @Tags Class
+// @Accept  json
+// @Produce  json
+// @Param cid path int true "クラスID"
+// @Param X-Class-Secret header string false "クラス加入暗証番号"
+// @Success 200 {object} dto.ClassPreviewDTO "クラスのプレビュー情報"
+// @Failure 400 {object} map[string]interface{} "error: リクエストが不正です"
+// @Failure 404 {object} map[string]interface{} "error: クラスが見つかりません"
+// @Failure 429 {object} utils.ErrorResponse "preview_secret_limit"
+// @Failure 500 {object} map[string]interface{} "error: サーバーエラーが発生しました"
+// @Router /cl/{cid}/preview [get]
+// @Security Bearer
+func (cc *ClassController) GetClassPreview(ctx *gin.Context) {
+	classID, err := strconv.ParseUint(ctx.Param("cid"), 10, 32)
+	if err != nil {
+		respondWithError(ctx, constants.StatusBadRequest, constants.InvalidRequest)
+		return
+	}
+
+	preview, err := cc.classService.GetClassPreview(ctx.Request.Context(), ctx.GetUint("userID"), uint(classID), ctx.GetHeader("X-Class-Secret"))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		respondWithError(ctx, constants.StatusNotFound, constants.ClassNotFound)
+		return
+	}
+	if errors.Is(err, services.ErrPreviewSecretLimit) {
+		abortWithError(ctx, toAppError(err))
+		return
+	}
+	if err != nil {
+		respondWithError(ctx, constants.StatusInternalServerError, constants.InternalServerError)
+		return
+	}
+
+	respondWithSuccess(ctx, constants.StatusOK, preview)
+}
+
+// GetPublicClasses godoc
+// @Summary 公開クラスを検索します
+// @Description 公開に設定されたクラスを、メンバー数と今後のスケジュール数とともに検索します。認証は不要で、メンバーの個人情報は返しません。
+// @Tags Class
+// @Produce  json
+// @Param q query string false "クラス名または説明に含まれる文字列。%と_もそのまま検索します"
+// @Param language query string false "授業の言語 (例: ja)"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Page size" default(20)
+// @Success 200 {array} dto.PublicClassDTO "公開クラスの一覧"
+// @Failure 500 {object} map[string]interface{} "error: サーバーエラーが発生しました"
+// @Router /cl/public [get]
+func (cc *ClassController) GetPublicClasses(ctx *gin.Context) {
+	page, err := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", "20"))
+	if err != nil || limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	classes, err := cc.classService.GetPublicClasses(ctx.Request.Context(), strings.TrimSpace(ctx.Query("q")), ctx.Query("language"), page, limit)
+	if err != nil {
+		handleServiceError(ctx, err)
+		return
+	}
+
+	respondWithPage(ctx, constants.StatusOK, classes, page, limit)
+}
+
+// GetTodayClasses godoc
+// @Summary 今日授業があるクラスを取得します
+// @Description ログイン中のユーザーが参加しているクラスのうち、指定したタイムゾーンで今日授業があるクラスをその日のスケジュールとともに取得します。
+// @Tags Class
+// @Accept  json
+// @Produce  json
+// @Param tz query string false "IANAタイムゾーン名 (例: Asia/Tokyo)。省略時はサーバーのタイムゾーン"
+// @Success 200 {array} dto.TodayClassDTO "今日授業があるクラス"
+// @Failure 400 {object} map[string]interface{} "error: リクエストが不正です"
+// @Failure 500 {object} map[string]interface{} "error: サーバーエラーが発生しました"
+// @Router /cl/today [get]
+// @Security Bearer
+func (cc *ClassController) GetTodayClasses(ctx *gin.Context) {
+	loc := time.Local
+	if tz := ctx.Query("tz"); tz != "" {
+		var err error
+		if loc, err = time.LoadLocation(tz); err != nil {
+			respondWithError(ctx, constants.StatusBadRequest, constants.InvalidRequest)
+			return
+		}
+	}
+
+	classes, err := cc.classScheduleService.GetClassesWithSchedulesToday(ctx.Request.Context(), ctx.GetUint("userID"), loc)
+	if err != nil {
+		respondWithError(ctx, constants.StatusInternalServerError, constants.InternalServerError)
+		return
+	}
+
+	respondWithSuccess(ctx, constants.StatusOK, classes)
+}
+
+// RestoreClass godoc
+// @Summary 削除したクラスを復元します
+// @Description 削除したクラスを、クラスと同時に削除したスケジュールと掲示板とともに復元します。クラスの管理者のみ利用できます。
+// @Tags Admin
+// @Produce  json
+// @Param cid path int true "クラスID"
+// @Success 200 {string} string "成功"
+// @Failure 400 {object} map[string]interface{} "error: リクエストが不正です"
+// @Failure 403 {object} map[string]interface{} "error: 権限がありません"
+// @Failure 404 {object} map[string]interface{} "error: 削除したクラスが見つかりません"
+// @Failure 500 {object} map[string]interface{} "error: サーバーエラーが発生しました"
+// @Router /admin/classes/{cid}/restore [post]
+// @Security Bearer
+func (cc *ClassController) RestoreClass(ctx *gin.Context) {
+	classID, err := strconv.ParseUint(ctx.Param("cid"), 10, 32)
+	if err != nil {
+		respondWithError(ctx, constants.StatusBadRequest, constants.InvalidRequest)
+		return
+	}
+
+	err = cc.classService.RestoreClass(ctx.Request.Context(), uint(classID), ctx.GetUint("userID"))
+	switch {
+	case errors.Is(err, services.ErrUnauthorized):
+		respondWithError(ctx, constants.StatusForbidden, constants.Forbidden)
+	case errors.Is(err, services.ErrNotFound):
+		respondWithError(ctx, constants.StatusNotFound, constants.ClassNotFound)
+	case err != nil:
+		respondWithError(ctx, constants.StatusInternalServerError, constants.InternalServerError)
+	default:
+		respondWithSuccess(ctx, constants.StatusOK, constants.Success)
+	}
+}
+
+// SetArchiveExempt godoc
+// @Summary クラスを自動アーカイブの対象から除外します
+// @Description 最後のスケジュールから一定期間活動がないクラスは自動的にアーカイブされます。exemptをtrueにすると対象から除外します。クラスの管理者のみ利用できます。
+// @Tags Admin
+// @Accept  json
+// @Produce  json
+// @Param cid path int true "クラスID"
+// @Param request body dto.ArchiveExemptRequest true "除外するか"
+// @Success 200 {string} string "成功"
+// @Failure 400 {object} map[string]interface{} "error: リクエストが不正です"
+// @Failure 403 {object} map[string]interface{} "error: 権限がありません"
+// @Failure 404 {object} map[string]interface{} "error: クラスが見つかりません"
+// @Failure 500 {object} map[string]interface{} "error: サーバーエラーが発生しました"
+// @Router /admin/classes/{cid}/archive-exempt [patch]
+// @Security Bearer
+func (cc *ClassController) SetArchiveExempt(ctx *gin.Context) {
+	classID, err := strconv.ParseUint(ctx.Param("cid"), 10, 32)
+	if err != nil {
+		respondWithError(ctx, constants.StatusBadRequest, constants.InvalidRequest)
+		return
+	}
+
+	var request dto.ArchiveExemptRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		respondWithError(ctx, constants.StatusBadRequest, constants.InvalidRequest)
+		return
+	}
+
+	err = cc.classService.SetArchiveExempt(ctx.Request.Context(), uint(classID), ctx.GetUint("userID"), *request.Exempt)
+	switch {
+	case errors.Is(err, services.ErrUnauthorized):
+		respondWithError(ctx, constants.StatusForbidden, constants.Forbidden)
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		respondWithError(ctx, constants.StatusNotFound, constants.ClassNotFound)
+	case err != nil:
+		respondWithError(ctx, constants.StatusInternalServerError, constants.InternalServerError)
+	default:
+		respondWithSuccess(ctx, constants.StatusOK, constants.Success)
+	}
+}
+
+// GenerateClassFlyer godoc
+// @Summary クラス参加用の配布PDFを生成します
+// @Description クラス名と説明、参加用のQRコードとクラスコードを載せたA4のPDFをダウンロードします。招待ページのURLが設定されている場合、QRコードにはクラスコード付きの招待リンクを格納します。シークレットは印刷しません。クラスの管理者のみ利用できます。
+// @Tags Class
+// @Produce application/pdf
+// @Param cid path int true "クラスID"
+// @Success 200 {file} file "PDFファイル"
+// @Failure 400 {object} map[string]interface{} "error: リクエストが不正です"
+// @Failure 403 {object} map[string]interface{} "error: 権限がありません"
+// @Failure 404 {object} map[string]interface{} "error: クラスが見つかりません"
+// @Failure 500 {object} map[string]interface{} "error: サーバーエラーが発生しました"
+// @Router /cl/{cid}/flyer.pdf [get]
+// @Security Bearer
+func (cc *ClassController) GenerateClassFlyer(ctx *gin.Context) {
+	classID, err := strconv.ParseUint(ctx.Param("cid"), 10, 32)
+	if err != nil {
+		respondWithError(ctx, constants.StatusBadRequest, constants.InvalidRequest)
+		return
+	}
+
+	pdf, err := cc.classService.GenerateClassFlyer(ctx.Request.Context(), uint(classID), ctx.GetUint("userID"))
+	switch {
+	case errors.Is(err, services.ErrUnauthorized):
+		respondWithError(ctx, constants.StatusForbidden, constants.Forbidden)
+	case errors.Is(err, gorm.ErrRecordNotFound), errors.Is(err, services.ErrNotFound):
+		respondWithError(ctx, constants.StatusNotFound, constants.ClassNotFound)
+	case err != nil:
+		respondWithError(ctx, constants.StatusInternalServerError, constants.InternalServerError)
+	default:
+		ctx.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="class_%d_flyer.pdf"`, classID))
+		ctx.Data(constants.StatusOK, "application/pdf", pdf)
+	}
+}
+
+// GetClassStats godoc
+// @Summary クラスの統計を取得します
+// @Description メンバー数とロールごとの内訳、直近30日のアクティブなメンバー数、掲示板とスケジュールの件数、全体の出席率、発言中のチャットの数を返します。結果は2分間キャッシュされます。クラスの管理者のみ利用できます。
+// @Tags Class
+// @Produce  json
+// @Param cid path int true "クラスID"
+// @Success 200 {object} dto.ClassStatsDTO "クラスの統計"
+// @Failure 400 {object} map[string]interface{} "error: リクエストが不正です"
+// @Failure 403 {object} map[string]interface{} "error: 権限がありません"
+// @Failure 500 {object} map[string]interface{} "error: サーバーエラーが発生しました"
+// @Router /cl/{cid}/stats [get]
+// @Security Bearer
+func (cc *ClassController) GetClassStats(ctx *gin.Context) {
+	classID, err := strconv.ParseUint(ctx.Param("cid"), 10, 32)
+	if err != nil {
+		respondWithError(ctx, constants.StatusBadRequest, constants.InvalidRequest)
+		return
+	}
+
+	stats, err := cc.classService.GetClassStats(ctx.Request.Context(), uint(classID), ctx.GetUint("userID"))
+	switch {
+	case errors.Is(err, services.ErrUnauthorized):
+		respondWithError(ctx, constants.StatusForbidden, constants.Forbidden)
+	case err != nil:
+		respondWithError(ctx, constants.StatusInternalServerError, constants.InternalServerError)
+	default:
+		respondWithSuccess(ctx, constants.StatusOK, stats)
+	}
+}
+
+// GetClassTimeline godoc
+// @Summary クラスのタイムラインを取得します
+// @Description 掲示の投稿、スケジュールの追加、メンバーの参加、ロールの変更をクラスの活動履歴として新しい順に返します。ロールの変更は変更したユーザーと対象のメンバーのみを返し、変更後のロールは含みません。クラスから削除されたメンバーの参加とロールの変更は含みません。typeを指定すると出来事の種類で絞り込みます。クラスの講師・アシスタント・生徒のみ利用できます。
+// @Tags Class
+// @Produce  json
+// @Param cid path int true "クラスID"
+// @Param type query []string false "出来事の種類 (board_posted, schedule_added, member_joined, role_changed)。複数指定できます" collectionFormat(multi)
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Page size" default(20)
+// @Success 200 {array} dto.ClassTimelineEventDTO "クラスのタイムライン"
+// @Failure 400 {object} map[string]interface{} "error: リクエストが不正です"
+// @Failure 403 {object} map[string]interface{} "error: 権限がありません"
+// @Failure 500 {object} map[string]interface{} "error: サーバーエラーが発生しました"
+// @Router /cl/{cid}/timeline [get]
+// @Security Bearer
+func (cc *ClassController) GetClassTimeline(ctx *gin.Context) {
+	classID, err := strconv.ParseUint(ctx.Param("cid"), 10, 32)
+	if err != nil {
+		respondWithError(ctx, constants.StatusBadRequest, constants.InvalidRequest)
+		return
+	}
+	types, ok := parseTimelineTypeFilter(ctx.QueryArray("type"))
+	if !ok {
+		respondWithError(ctx, constants.StatusBadRequest, constants.InvalidRequest)
+		return
+	}
+	page, err := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", "20"))
+	if err != nil || limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	events, err := cc.classService.GetClassTimeline(ctx.Request.Context(), uint(classID), ctx.GetUint("userID"), types, page, limit)
+	switch {
+	case errors.Is(err, services.ErrUnauthorized):
+		respondWithError(ctx, constants.StatusForbidden, constants.Forbidden)
+	case err != nil:
+		respondWithError(ctx, constants.StatusInternalServerError, constants.InternalServerError)
+	default:
+		respondWithPage(ctx, constants.StatusOK, events, page, limit)
+	}
+}
+
+// parseTimelineTypeFilter タイムラインの出来事の種類の絞り込みを解析する。定義されていない種類を含む場合はfalseを返す
+func parseTimelineTypeFilter(values []string) ([]dto.ClassTimelineEventType, bool) {
+	var types []dto.ClassTimelineEventType
+	for _, value := range values {
+		for _, name := range strings.Split(value, ",") {
+			name = strings.ToLower(strings.TrimSpace(name))
+			if name == "" {
+				continue
+			}
+			eventType, ok := findTimelineEventType(name)
+			if !ok {
+				return nil, false
+			}
+			types = append(types, eventType)
+		}
+	}
+	return types, true
+}
+
+// findTimelineEventType 名前に一致するタイムラインの出来事の種類を返す
+func findTimelineEventType(name string) (dto.ClassTimelineEventType, bool) {
+	for _, eventType := range dto.ClassTimelineEventTypes {
+		if string(eventType) == name {
+			return eventType, true
+		}
+	}
+	return "", false
+}
+
 // CreateClass godoc
 // @Summary 新しいクラスを作成
 // @Description 名前、定員、説明、画像URL、作成者のUIDを持つ新しいクラスを作成します。画像はオプショナルです。
 // @Tags Class
 // @Accept multipart/form-data
 // @Produce json
-// @Param name formData string true "クラスの名前"
+// @Param name formData string true "クラスの名前 (30文字以内)"
 // @Param limitation formData int false "クラスの定員数"
-// @Param description formData string false "クラスの説明"
+// @Param description formData string false "クラスの説明 (255文字以内)"
 // @Param uid formData int true "クラスを作成するユーザーのUID"
 // @Param secret formData string false "クラス加入暗証番号"
+// @Param is_public formData boolean false "公開クラスの検索に表示する" default(false)
+// @Param language formData string false "授業の言語 (例: ja)"
 // @Param image formData file false "クラスの画像"
 // @Success 201 {object} map[string]interface{} "message: クラスが正常に作成されました"
-// @Failure 400 {object} map[string]interface{} "error: 不正なリクエストのエラーメッセージ"
+// @Failure 400 {object} utils.ErrorResponse "不正なリクエスト。入力値の検証に失敗した場合はdetailsに項目ごとのエラーメッセージを含みます"
 // @Failure 500 {object} map[string]interface{} "error: サーバー内部エラー"
 // @Router /cl/create [post]
 // @Security Bearer
 func (cc *ClassController) CreateClass(ctx *gin.Context) {
 	var createDTO dto.CreateClassRequest
 	if err := ctx.ShouldBindWith(&createDTO, binding.FormMultipart); err != nil {
-		respondWithError(ctx, constants.StatusBadRequest, constants.BadRequestMessage)
+		abortWithError(ctx, newBindingError(err))
 		return
 	}
 
-	classID, err := cc.classService.CreateClass(createDTO)
+	classID, err := cc.classService.CreateClass(ctx.Request.Context(), createDTO)
 	if err != nil {
 		handleServiceError(ctx, err)
 		return
@@ -119,7 +439,7 @@ func (cc *ClassController) CreateClass(ctx *gin.Context) {
 	}
 
 	if imageUrl != "" {
-		err = cc.classService.UpdateClassImage(classID, imageUrl)
+		err = cc.classService.UpdateClassImage(ctx.Request.Context(), classID, imageUrl)
 		if err != nil {
 			handleServiceError(ctx, err)
 			return
@@ -158,6 +478,8 @@ func (cc *ClassController) handleImageUpload(ctx *gin.Context) (string, error) {
 // @Param name formData string false "クラス名"
 // @Param limitation formData int false "参加制限人数"
 // @Param description formData string false "クラス説明"
+// @Param is_public formData boolean false "公開クラスの検索に表示する"
+// @Param language formData string false "授業の言語 (例: ja)"
 // @Param image formData file false "クラス画像"
 // @Success 200 {object} map[string]interface{} "message: クラスが正常に更新されました"
 // @Failure 400 {object} map[string]interface{} "error: 不正なリクエストのエラーメッセージ"
@@ -183,13 +505,13 @@ func (cc *ClassController) UpdateClass(ctx *gin.Context) {
 		}
 
 		// Call a separate method to update the image URL
-		if err := cc.classService.UpdateClassImage(uint(classID), imageUrl); err != nil {
+		if err := cc.classService.UpdateClassImage(ctx.Request.Context(), uint(classID), imageUrl); err != nil {
 			respondWithError(ctx, constants.StatusInternalServerError, "Failed to update class image: "+err.Error())
 			return
 		}
 	}
 
-	if err := cc.classService.UpdateClass(uint(classID), uint(userID), updateDTO); err != nil {
+	if err := cc.classService.UpdateClass(ctx.Request.Context(), uint(classID), uint(userID), updateDTO); err != nil {
 		respondWithError(ctx, constants.StatusInternalServerError, "Class update failed: "+err.Error())
 		return
 	}
@@ -214,7 +536,7 @@ func (cc *ClassController) DeleteClass(ctx *gin.Context) {
 	userID, _ := strconv.ParseUint(ctx.Param("uid"), 10, 32)
 	classID, _ := strconv.ParseUint(ctx.Param("cid"), 10, 32)
 
-	err := cc.classService.DeleteClass(uint(classID), uint(userID))
+	err := cc.classService.DeleteClass(ctx.Request.Context(), uint(classID), uint(userID))
 	if err != nil {
 		respondWithError(ctx, constants.StatusUnauthorized, fmt.Sprintf("Error: %v", err))
 		return
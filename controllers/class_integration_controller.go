@@ -0,0 +1,119 @@
+package controllers
+
+import (
+	"errors"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/constants"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// ClassIntegrationController クラスのチャットサービスとの連携のコントローラ
+type ClassIntegrationController struct {
+	integrationService services.ClassIntegrationService
+}
+
+// NewClassIntegrationController ClassIntegrationControllerを生成
+func NewClassIntegrationController(integrationService services.ClassIntegrationService) *ClassIntegrationController {
+	return &ClassIntegrationController{
+		integrationService: integrationService,
+	}
+}
+
+// GetIntegration godoc
+// @Summary クラスのチャットサービスとの連携
+// @Description クラスのSlackまたはDiscordとの連携と、投稿の状態を取得します。URLのトークンは伏せて返します。statusは最後の投稿に成功した場合はok、失敗した場合はfailing、投稿先が4xxを5回連続で返したため投稿を停止した場合はdisabledです。クラスの管理者のみ利用できます。
+// @Tags Class Integration
+// @Produce json
+// @Param cid path int true "Class ID"
+// @Success 200 {object} dto.ClassIntegrationDTO "連携"
+// @Failure 400 {object} utils.ErrorResponse "無効なリクエスト"
+// @Failure 403 {object} utils.ErrorResponse "権限がありません"
+// @Failure 404 {object} utils.ErrorResponse "連携が登録されていません"
+// @Failure 500 {object} utils.ErrorResponse "サーバーエラーが発生しました"
+// @Router /integrations/{cid} [get]
+// @Security Bearer
+func (c *ClassIntegrationController) GetIntegration(ctx *gin.Context) {
+	cid, ok := parseCommentParam(ctx, "cid")
+	if !ok {
+		return
+	}
+
+	integration, err := c.integrationService.GetIntegration(ctx.Request.Context(), ctx.GetUint("userID"), cid)
+	if err != nil {
+		abortWithIntegrationError(ctx, err)
+		return
+	}
+	respondWithSuccess(ctx, constants.StatusOK, integration)
+}
+
+// SaveIntegration godoc
+// @Summary クラスをチャットサービスと連携
+// @Description お知らせの掲示・スケジュールの変更・ライブ授業の開始を、SlackのIncoming WebhookまたはDiscordのWebhookに投稿する連携を登録します。登録済みの場合は置き換え、停止していた投稿を再開します。保存する前にテストメッセージを投稿し、投稿できない場合は保存しません。投稿は非同期で行い、失敗しても元の操作には影響せず、結果は連携のstatusとlast_errorに記録します。クラスの管理者のみ利用できます。
+// @Tags Class Integration
+// @Accept json
+// @Produce json
+// @Param cid path int true "Class ID"
+// @Param request body dto.ClassIntegrationRequest true "連携"
+// @Success 200 {object} dto.ClassIntegrationDTO "保存した連携"
+// @Failure 400 {object} utils.ErrorResponse "無効なリクエスト"
+// @Failure 403 {object} utils.ErrorResponse "権限がありません"
+// @Failure 422 {object} utils.ErrorResponse "テストメッセージを投稿できません"
+// @Failure 500 {object} utils.ErrorResponse "サーバーエラーが発生しました"
+// @Router /integrations/{cid} [put]
+// @Security Bearer
+func (c *ClassIntegrationController) SaveIntegration(ctx *gin.Context) {
+	cid, ok := parseCommentParam(ctx, "cid")
+	if !ok {
+		return
+	}
+
+	var request dto.ClassIntegrationRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		abortWithError(ctx, newBindingError(err))
+		return
+	}
+
+	integration, err := c.integrationService.SaveIntegration(ctx.Request.Context(), ctx.GetUint("userID"), cid, request)
+	if err != nil {
+		abortWithIntegrationError(ctx, err)
+		return
+	}
+	respondWithSuccess(ctx, constants.StatusOK, integration)
+}
+
+// DeleteIntegration godoc
+// @Summary チャットサービスとの連携を解除
+// @Description クラスのチャットサービスとの連携を削除します。投稿を待つイベントは投稿しません。クラスの管理者のみ利用できます。
+// @Tags Class Integration
+// @Param cid path int true "Class ID"
+// @Success 200 {string} string "削除成功"
+// @Failure 400 {object} utils.ErrorResponse "無効なリクエスト"
+// @Failure 403 {object} utils.ErrorResponse "権限がありません"
+// @Failure 404 {object} utils.ErrorResponse "連携が登録されていません"
+// @Failure 500 {object} utils.ErrorResponse "サーバーエラーが発生しました"
+// @Router /integrations/{cid} [delete]
+// @Security Bearer
+func (c *ClassIntegrationController) DeleteIntegration(ctx *gin.Context) {
+	cid, ok := parseCommentParam(ctx, "cid")
+	if !ok {
+		return
+	}
+
+	if err := c.integrationService.DeleteIntegration(ctx.Request.Context(), ctx.GetUint("userID"), cid); err != nil {
+		abortWithIntegrationError(ctx, err)
+		return
+	}
+	respondWithSuccess(ctx, constants.StatusOK, constants.DeleteSuccess)
+}
+
+// abortWithIntegrationError 権限のエラーを403として登録し、それ以外はAppErrorに変換して登録する
+func abortWithIntegrationError(ctx *gin.Context, err error) {
+	if errors.Is(err, services.ErrUnauthorized) {
+		abortWithError(ctx, utils.NewForbiddenError(constants.ErrCodeForbidden, constants.Forbidden))
+		return
+	}
+	abortWithError(ctx, toAppError(err))
+}
@@ -0,0 +1,68 @@
+package controllers
+
+import (
+	"errors"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/constants"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// ClassInvitationController メールでのクラスへの招待のコントローラ
+type ClassInvitationController struct {
+	invitationService services.ClassInvitationService
+}
+
+// NewClassInvitationController ClassInvitationControllerを生成
+func NewClassInvitationController(invitationService services.ClassInvitationService) *ClassInvitationController {
+	return &ClassInvitationController{
+		invitationService: invitationService,
+	}
+}
+
+// InviteByEmail godoc
+// @Summary メールでクラスに招待
+// @Description 指定したメールアドレスに、クラスコードと参加用のリンクを載せた招待メールを送信します。1回に50件まで指定できます。アカウントがあるアドレスにはユーザーの言語で、ないアドレスには指定した言語で送信し、既にメンバーのユーザーには送信しません。1人のユーザーが送信できる招待メールは、全てのクラスを合わせて1日200件までです。クラスの管理者のみ利用できます。
+// @Tags Class
+// @Accept json
+// @Produce json
+// @Param cid path int true "Class ID"
+// @Param request body dto.ClassInvitationRequest true "招待するメールアドレス"
+// @Success 202 {object} dto.ClassInvitationResultDTO "送信を予約した招待メール"
+// @Failure 400 {object} utils.ErrorResponse "無効なリクエスト"
+// @Failure 403 {object} utils.ErrorResponse "権限がありません"
+// @Failure 404 {object} utils.ErrorResponse "クラスまたはクラスコードが見つかりません"
+// @Failure 429 {object} utils.ErrorResponse "本日送信できる招待メールの上限に達しています"
+// @Failure 503 {object} utils.ErrorResponse "現在メールを送信できません"
+// @Router /cl/{cid}/invitations [post]
+// @Security Bearer
+func (c *ClassInvitationController) InviteByEmail(ctx *gin.Context) {
+	cid, ok := parseCommentParam(ctx, "cid")
+	if !ok {
+		return
+	}
+
+	var request dto.ClassInvitationRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		abortWithError(ctx, newBindingError(err))
+		return
+	}
+
+	result, err := c.invitationService.InviteByEmail(ctx.Request.Context(), ctx.GetUint("userID"), cid, request)
+	if err != nil {
+		abortWithInvitationError(ctx, err)
+		return
+	}
+	respondWithSuccess(ctx, constants.StatusAccepted, result)
+}
+
+// abortWithInvitationError サービスのエラーをレスポンスに変換する。管理者以外の招待は403にする
+func abortWithInvitationError(ctx *gin.Context, err error) {
+	if errors.Is(err, services.ErrUnauthorized) {
+		abortWithError(ctx, utils.NewForbiddenError(constants.ErrCodeForbidden, constants.Forbidden))
+		return
+	}
+	abortWithError(ctx, toAppError(err))
+}
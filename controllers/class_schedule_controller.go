@@ -1,30 +1,37 @@
 package controllers
 
 import (
+	"errors"
+	"fmt"
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/constants"
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/middlewares"
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
 	"github.com/gin-gonic/gin"
+	"log"
 	"net/http"
 	"strconv"
+	"time"
 )
 
 // ClassScheduleController インタフェースを実装
 type ClassScheduleController struct {
 	classScheduleService services.ClassScheduleService
+	jwtService           services.JWTService
 }
 
 // NewClassScheduleController ClassScheduleControllerを生成
-func NewClassScheduleController(service services.ClassScheduleService) *ClassScheduleController {
+func NewClassScheduleController(service services.ClassScheduleService, jwtService services.JWTService) *ClassScheduleController {
 	return &ClassScheduleController{
 		classScheduleService: service,
+		jwtService:           jwtService,
 	}
 }
 
 // CreateClassSchedule godoc
 // @Summary クラススケジュールを作成
-// @Description 新しいクラススケジュールを作成する。
+// @Description 新しいクラススケジュールを作成する。出席方式(attendance_mode)はIN_PERSON, ONLINE, HYBRIDのいずれかで、省略した場合はIN_PERSONになる。出席方式は場所の種類を兼ね、場所(location)には255文字以内で教室の名前やオンライン授業のURLを指定する。
 // @Tags Class Schedule
 // @Accept json
 // @Produce json
@@ -49,9 +56,15 @@ func (controller *ClassScheduleController) CreateClassSchedule(c *gin.Context) {
 		EndedAt:   dto.EndedAt,
 		CID:       dto.CID,
 		IsLive:    dto.IsLive,
+		// 方式の値はバインド時に検証済み
+		AttendanceMode: models.AttendanceMode(dto.AttendanceMode),
+		Location:       dto.Location,
+		// 確認コードを必須にする場合は講師画面に表示したコードを生徒に伝える
+		RequireCheckInCode: dto.RequireCheckInCode,
 	}
+	middlewares.SetAuditClassID(c, dto.CID)
 
-	createdClassSchedule, err := controller.classScheduleService.CreateClassSchedule(&classSchedule)
+	createdClassSchedule, err := controller.classScheduleService.CreateClassSchedule(c.Request.Context(), &classSchedule)
 	if err != nil {
 		handleServiceError(c, err)
 		return
@@ -61,12 +74,12 @@ func (controller *ClassScheduleController) CreateClassSchedule(c *gin.Context) {
 
 // GetClassScheduleByID godoc
 // @Summary IDでクラススケジュールを取得
-// @Description 指定されたIDのクラススケジュールを取得する。
+// @Description 指定されたIDのクラススケジュールを、クラスの名前と画像(class)付きで取得する。出席方式(attendance_mode)と、方式で有効なチェックイン手段(check_in_methods)も返す。対面はQRと位置情報、オンラインはライブ参加、ハイブリッドはその全てが有効。
 // @Tags Class Schedule
 // @Accept json
 // @Produce json
 // @Param id path int true "Class schedule ID"
-// @Success 200 {object} models.ClassSchedule "クラススケジュールが見つかりました"
+// @Success 200 {object} dto.ClassScheduleDetailDTO "クラススケジュールが見つかりました"
 // @Failure 400 {object} string "無効なID形式です"
 // @Failure 404 {object} string "クラススケジュールが見つかりません"
 // @Router /cs/{id} [get]
@@ -78,7 +91,7 @@ func (controller *ClassScheduleController) GetClassScheduleByID(c *gin.Context)
 		return
 	}
 
-	classSchedule, err := controller.classScheduleService.GetClassScheduleByID(uint(id))
+	classSchedule, err := controller.classScheduleService.GetClassScheduleByID(c.Request.Context(), uint(id))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": constants.ClassNotFound})
 		return
@@ -100,7 +113,7 @@ func (controller *ClassScheduleController) GetClassScheduleByID(c *gin.Context)
 // @Security Bearer
 func (controller *ClassScheduleController) GetAllClassSchedules(c *gin.Context) {
 	cid, _ := strconv.ParseUint(c.DefaultQuery("cid", "0"), 10, 32)
-	classSchedules, err := controller.classScheduleService.GetAllClassSchedules(uint(cid))
+	classSchedules, err := controller.classScheduleService.GetAllClassSchedules(c.Request.Context(), uint(cid))
 	if err != nil {
 		handleServiceError(c, err)
 		return
@@ -110,7 +123,7 @@ func (controller *ClassScheduleController) GetAllClassSchedules(c *gin.Context)
 
 // UpdateClassSchedule godoc
 // @Summary クラススケジュールを更新
-// @Description 指定されたIDのクラススケジュールを更新する。
+// @Description 指定されたIDのクラススケジュールを更新する。読み込んだスケジュールのversionを指定し、他の更新が先に保存されていた場合は409と最新のスケジュールを返す。
 // @Tags Class Schedule
 // @Accept json
 // @Produce json
@@ -120,6 +133,7 @@ func (controller *ClassScheduleController) GetAllClassSchedules(c *gin.Context)
 // @Param classSchedule body dto.UpdateClassScheduleDTO true "Class schedule to update"
 // @Success 200 {object} models.ClassSchedule "クラススケジュールが正常に更新されました"
 // @Failure 400 {object} string "リクエストが不正です"
+// @Failure 409 {object} utils.ErrorResponse "stale_update"
 // @Failure 500 {object} string "サーバーエラーが発生しました"
 // @Router /cs/{id} [patch]
 // @Security Bearer
@@ -136,7 +150,7 @@ func (controller *ClassScheduleController) UpdateClassSchedule(c *gin.Context) {
 		return
 	}
 
-	updatedClassSchedule, err := controller.classScheduleService.UpdateClassSchedule(uint(id), &dto)
+	updatedClassSchedule, err := controller.classScheduleService.UpdateClassSchedule(c.Request.Context(), uint(id), &dto)
 	if err != nil {
 		handleServiceError(c, err)
 		return
@@ -147,15 +161,20 @@ func (controller *ClassScheduleController) UpdateClassSchedule(c *gin.Context) {
 
 // DeleteClassSchedule godoc
 // @Summary クラススケジュールを削除
-// @Description 指定されたIDのクラススケジュールを削除する。
+// @Description 指定されたIDのクラススケジュールを削除する。開始済みのスケジュールは運用の管理者(SUPER_ADMIN_UIDS)がforce=trueを指定した場合のみ削除でき、クラスの管理者でも403を返す。強制削除した場合はWARNの監査ログを出力する。
 // @Tags Class Schedule
 // @Accept json
 // @Produce json
 // @Param id path int true "Class schedule ID"
 // @Param cid query int true "Class ID"
 // @Param uid query int true "User ID"
+// @Param force query bool false "開始済みのスケジュールを強制削除"
 // @Success 200 {object} string "クラススケジュールが正常に削除されました"
 // @Failure 400 {object} string "無効なID形式です"
+// @Failure 401 {object} string "認証に失敗しました"
+// @Failure 403 {object} string "開始済みのスケジュールを強制削除する権限がありません"
+// @Failure 404 {object} string "スケジュールが見つかりません"
+// @Failure 422 {object} string "cannot delete a schedule that has already started"
 // @Failure 500 {object} string "サーバーエラーが発生しました"
 // @Router /cs/{id} [delete]
 // @Security Bearer
@@ -166,14 +185,173 @@ func (controller *ClassScheduleController) DeleteClassSchedule(c *gin.Context) {
 		return
 	}
 
-	err = controller.classScheduleService.DeleteClassSchedule(uint(id))
+	uid := c.GetUint("userID")
+	force := c.Query("force") == "true"
+
+	forced, err := controller.classScheduleService.DeleteClassSchedule(c.Request.Context(), uint(id), uid, force)
 	if err != nil {
 		handleServiceError(c, err)
 		return
 	}
+
+	if forced {
+		log.Printf("[WARN] force-deleted class schedule: request_id=%s uid=%d schedule_id=%d", middlewares.GetRequestID(c), uid, id)
+	}
 	respondWithSuccess(c, constants.StatusOK, constants.DeleteSuccess)
 }
 
+// RestoreClassSchedule godoc
+// @Summary 削除したクラススケジュールを復元する
+// @Description 削除したクラススケジュールを復元する。クラス管理者のみ実行でき、クラスが削除されている場合は先にクラスを復元する必要がある。
+// @Tags Admin
+// @Produce json
+// @Param cid path int true "Class ID"
+// @Param id path int true "Class schedule ID"
+// @Success 200 {object} string "成功"
+// @Failure 400 {object} string "無効なID形式です"
+// @Failure 403 {object} string "権限がありません"
+// @Failure 404 {object} string "削除したクラススケジュールが見つかりません"
+// @Failure 500 {object} string "サーバーエラーが発生しました"
+// @Router /admin/classes/{cid}/schedules/{id}/restore [post]
+// @Security Bearer
+func (controller *ClassScheduleController) RestoreClassSchedule(c *gin.Context) {
+	cid, err := strconv.ParseUint(c.Param("cid"), 10, 32)
+	if err != nil {
+		respondWithError(c, constants.StatusBadRequest, constants.InvalidRequest)
+		return
+	}
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		respondWithError(c, constants.StatusBadRequest, constants.InvalidRequest)
+		return
+	}
+
+	err = controller.classScheduleService.RestoreClassSchedule(c.Request.Context(), uint(cid), uint(id), c.GetUint("userID"))
+	if errors.Is(err, services.ErrUnauthorized) {
+		respondWithError(c, constants.StatusForbidden, constants.Forbidden)
+		return
+	}
+	if err != nil {
+		handleServiceError(c, err)
+		return
+	}
+	respondWithSuccess(c, constants.StatusOK, constants.Success)
+}
+
+// CancelClassSchedule godoc
+// @Summary クラススケジュールを休講にする
+// @Description 指定されたIDのクラススケジュールを休講にし、クラスのメンバーに通知する。クラス管理者のみ実行できる。
+// @Tags Class Schedule
+// @Accept json
+// @Produce json
+// @Param id path int true "Class schedule ID"
+// @Success 200 {object} models.ClassSchedule "クラススケジュールが休講になりました"
+// @Failure 400 {object} string "無効なID形式です"
+// @Failure 401 {object} string "認証に失敗しました"
+// @Failure 403 {object} string "権限がありません"
+// @Failure 404 {object} string "コードが見つかりません"
+// @Failure 500 {object} string "サーバーエラーが発生しました"
+// @Router /cs/{id}/cancel [patch]
+// @Security Bearer
+func (controller *ClassScheduleController) CancelClassSchedule(c *gin.Context) {
+	controller.setClassScheduleCancelled(c, true)
+}
+
+// UncancelClassSchedule godoc
+// @Summary クラススケジュールの休講を取り消す
+// @Description 指定されたIDのクラススケジュールの休講を取り消す。クラス管理者のみ実行できる。
+// @Tags Class Schedule
+// @Accept json
+// @Produce json
+// @Param id path int true "Class schedule ID"
+// @Success 200 {object} models.ClassSchedule "クラススケジュールの休講が取り消されました"
+// @Failure 400 {object} string "無効なID形式です"
+// @Failure 401 {object} string "認証に失敗しました"
+// @Failure 403 {object} string "権限がありません"
+// @Failure 404 {object} string "コードが見つかりません"
+// @Failure 500 {object} string "サーバーエラーが発生しました"
+// @Router /cs/{id}/uncancel [patch]
+// @Security Bearer
+func (controller *ClassScheduleController) UncancelClassSchedule(c *gin.Context) {
+	controller.setClassScheduleCancelled(c, false)
+}
+
+// setClassScheduleCancelled パスのIDのクラススケジュールの休講状態を更新する
+func (controller *ClassScheduleController) setClassScheduleCancelled(c *gin.Context, cancelled bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		respondWithError(c, constants.StatusBadRequest, constants.InvalidRequest)
+		return
+	}
+
+	classSchedule, err := controller.classScheduleService.SetClassScheduleCancelled(c.Request.Context(), uint(id), c.GetUint("userID"), cancelled)
+	if err != nil {
+		handleServiceError(c, err)
+		return
+	}
+	middlewares.SetAuditClassID(c, classSchedule.CID)
+
+	respondWithSuccess(c, constants.StatusOK, classSchedule)
+}
+
+// GetCheckInCode godoc
+// @Summary 自己チェックインの確認コードを取得
+// @Description 講師画面に表示する自己チェックインの確認コード(4桁)を取得する。コードは1分ごとに更新されるため、expires_atを過ぎたら取得し直す。require_check_in_codeを有効にしたスケジュールでは、生徒はこのコードを入力して出席をチェックインする。クラスの講師・アシスタントのみ取得できる。
+// @Tags Class Schedule
+// @Produce json
+// @Param id path int true "Class schedule ID"
+// @Success 200 {object} dto.CheckInCodeDTO "確認コード"
+// @Failure 400 {object} string "無効なID形式です"
+// @Failure 401 {object} string "認証に失敗しました"
+// @Failure 404 {object} string "コードが見つかりません"
+// @Failure 500 {object} string "サーバーエラーが発生しました"
+// @Router /cs/{id}/check-in-code [get]
+// @Security Bearer
+func (controller *ClassScheduleController) GetCheckInCode(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		respondWithError(c, constants.StatusBadRequest, constants.InvalidRequest)
+		return
+	}
+
+	code, err := controller.classScheduleService.GetCheckInCode(c.Request.Context(), c.GetUint("userID"), uint(id))
+	if err != nil {
+		handleServiceError(c, err)
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	respondWithSuccess(c, constants.StatusOK, code)
+}
+
+// GetCheckInToken godoc
+// @Summary 出席トークンを発行
+// @Description 講師画面のQRコードに表示する署名付きの出席トークンを発行する。生徒はトークンを読み取り、POST /at/check-in に送信して出席をチェックインする。トークンはRedisを使わずに検証する。QRコードを撮影して教室の外に送っても使えないよう有効期限は短いため、講師画面はexpires_atまでに発行し直してQRコードを切り替える。クラスの講師・アシスタントのみ発行できる。
+// @Tags Class Schedule
+// @Produce json
+// @Param id path int true "Class schedule ID"
+// @Success 200 {object} dto.CheckInTokenDTO "出席トークン"
+// @Failure 400 {object} string "無効なID形式です"
+// @Failure 401 {object} string "認証に失敗しました"
+// @Failure 404 {object} string "スケジュールが見つかりません"
+// @Failure 503 {object} utils.ErrorResponse "QRコードによる出席は利用できません"
+// @Router /cs/{id}/check-in-token [get]
+// @Security Bearer
+func (controller *ClassScheduleController) GetCheckInToken(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		respondWithError(c, constants.StatusBadRequest, constants.InvalidRequest)
+		return
+	}
+
+	token, err := controller.classScheduleService.IssueCheckInToken(c.Request.Context(), c.GetUint("userID"), uint(id))
+	if err != nil {
+		handleServiceError(c, err)
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	respondWithSuccess(c, constants.StatusOK, token)
+}
+
 // GetLiveClassSchedules godoc
 // @Summary ライブ中のクラススケジュールを取得
 // @Description 指定されたクラスIDのライブ中のクラススケジュールを取得する。
@@ -187,7 +365,7 @@ func (controller *ClassScheduleController) DeleteClassSchedule(c *gin.Context) {
 // @Security Bearer
 func (controller *ClassScheduleController) GetLiveClassSchedules(c *gin.Context) {
 	cid, _ := strconv.ParseUint(c.Query("cid"), 10, 32)
-	classSchedules, err := controller.classScheduleService.GetLiveClassSchedules(uint(cid))
+	classSchedules, err := controller.classScheduleService.GetLiveClassSchedules(c.Request.Context(), uint(cid))
 	if err != nil {
 		handleServiceError(c, err)
 		return
@@ -197,30 +375,148 @@ func (controller *ClassScheduleController) GetLiveClassSchedules(c *gin.Context)
 
 // GetClassSchedulesByDate godoc
 // @Summary 日付でクラススケジュールを取得
-// @Description 指定されたクラスIDと日付のクラススケジュールを取得する。
+// @Description 指定されたクラスIDと日付に開始するクラススケジュールを、開始日時の順に取得する。日付はサーバーのタイムゾーンで区切る。attendance_modeを指定した場合はその出席方式のスケジュールのみ返す。
 // @Tags Class Schedule
 // @Accept json
 // @Produce json
 // @Param cid query uint true "Class ID"
-// @Param date query string true "Date"
+// @Param date query string true "Date (YYYY-MM-DD)"
+// @Param attendance_mode query string false "Attendance mode (IN_PERSON, ONLINE, HYBRID)"
 // @Success 200 {array} []models.ClassSchedule "指定された日付のクラススケジュールが見つかりました"
-// @Failure 400 {object} string "日付が必要です"
+// @Failure 400 {object} string "日付が必要です・日付の形式が正しくありません・出席方式が正しくありません"
 // @Failure 500 {object} string "サーバーエラーが発生しました"
 // @Router /cs/date [get]
 // @Security Bearer
 func (controller *ClassScheduleController) GetClassSchedulesByDate(c *gin.Context) {
 	cid, _ := strconv.ParseUint(c.Query("cid"), 10, 32)
-	date := c.Query("date") // Expecting date in the format 'YYYY-MM-DD'
-
-	if date == "" {
+	dateParam := c.Query("date")
+	if dateParam == "" {
 		respondWithError(c, constants.StatusBadRequest, constants.ErrNoDateJP)
 		return
 	}
+	date, err := time.ParseInLocation("2006-01-02", dateParam, time.Local)
+	if err != nil {
+		respondWithError(c, constants.StatusBadRequest, constants.ErrInvalidDateJP)
+		return
+	}
 
-	classSchedules, err := controller.classScheduleService.GetClassSchedulesByDate(uint(cid), date)
+	classSchedules, err := controller.classScheduleService.GetClassSchedulesByDate(c.Request.Context(), uint(cid), date, models.AttendanceMode(c.Query("attendance_mode")))
 	if err != nil {
 		handleServiceError(c, err)
 		return
 	}
 	respondWithSuccess(c, constants.StatusOK, classSchedules)
 }
+
+// ExportClassICal godoc
+// @Summary クラスのスケジュールをiCalendar形式でエクスポート
+// @Description 指定されたクラスのスケジュールをiCalendar(.ics)ファイルとして返す。場所はLOCATIONに、場所の種類として出席方式をX-MINORI-LOCATION-TYPEに出力する。クラスのメンバーのみ取得できる。
+// @Tags Class Schedule
+// @Produce text/calendar
+// @Param cid path int true "Class ID"
+// @Success 200 {file} file "iCalendarファイル"
+// @Failure 400 {object} string "リクエストが不正です"
+// @Failure 403 {object} string "権限がありません"
+// @Failure 500 {object} string "サーバーエラーが発生しました"
+// @Router /cs/export/class/{cid} [get]
+// @Security Bearer
+func (controller *ClassScheduleController) ExportClassICal(c *gin.Context) {
+	cid, err := strconv.ParseUint(c.Param("cid"), 10, 32)
+	if err != nil {
+		respondWithError(c, constants.StatusBadRequest, constants.InvalidRequest)
+		return
+	}
+
+	calendar, err := controller.classScheduleService.ExportClassICal(c.Request.Context(), c.GetUint("userID"), uint(cid))
+	if err != nil {
+		handleServiceError(c, err)
+		return
+	}
+
+	respondWithICal(c, fmt.Sprintf("class_%d.ics", cid), calendar)
+}
+
+// ExportUserICal godoc
+// @Summary ユーザーが参加している全クラスのスケジュールをiCalendar形式でエクスポート
+// @Description ユーザーが参加している全クラスのスケジュールを1つのiCalendar(.ics)ファイルにまとめて返す。各イベントのSUMMARYにはクラス名が含まれる。カレンダーアプリの購読用にtokenクエリでも認証できる。
+// @Tags Class Schedule
+// @Produce text/calendar
+// @Param uid path int true "User ID"
+// @Param token query string false "カレンダー購読用トークン"
+// @Success 200 {file} file "iCalendarファイル"
+// @Failure 400 {object} string "リクエストが不正です"
+// @Failure 401 {object} string "認証に失敗しました"
+// @Failure 403 {object} string "権限がありません"
+// @Failure 500 {object} string "サーバーエラーが発生しました"
+// @Router /cs/export/user/{uid} [get]
+// @Security Bearer
+func (controller *ClassScheduleController) ExportUserICal(c *gin.Context) {
+	uid, ok := parseOwnUserID(c)
+	if !ok {
+		return
+	}
+
+	calendar, err := controller.classScheduleService.ExportUserICal(c.Request.Context(), uid)
+	if err != nil {
+		handleServiceError(c, err)
+		return
+	}
+
+	respondWithICal(c, fmt.Sprintf("user_%d.ics", uid), calendar)
+}
+
+// GetCalendarSubscriptionURL godoc
+// @Summary カレンダー購読用URLを取得
+// @Description GoogleカレンダーなどにURLで登録できる、トークン付きのiCalendarエクスポートURLを返す。
+// @Tags Class Schedule
+// @Produce json
+// @Param uid path int true "User ID"
+// @Success 200 {object} map[string]string "購読用URL"
+// @Failure 400 {object} string "リクエストが不正です"
+// @Failure 401 {object} string "認証に失敗しました"
+// @Failure 403 {object} string "権限がありません"
+// @Failure 500 {object} string "サーバーエラーが発生しました"
+// @Router /cs/export/user/{uid}/subscription [get]
+// @Security Bearer
+func (controller *ClassScheduleController) GetCalendarSubscriptionURL(c *gin.Context) {
+	uid, ok := parseOwnUserID(c)
+	if !ok {
+		return
+	}
+
+	token, err := controller.jwtService.GenerateCalendarToken(uid)
+	if err != nil {
+		respondWithError(c, constants.StatusInternalServerError, constants.InternalServerError)
+		return
+	}
+
+	scheme := "https"
+	if proto := c.GetHeader("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	} else if c.Request.TLS == nil {
+		scheme = "http"
+	}
+	url := fmt.Sprintf("%s://%s/api/gin/cs/export/user/%d?token=%s", scheme, c.Request.Host, uid, token)
+
+	respondWithSuccess(c, constants.StatusOK, gin.H{"url": url})
+}
+
+// parseOwnUserID パスのuidを取得し、認証済みユーザー本人であることを確認する
+func parseOwnUserID(c *gin.Context) (uint, bool) {
+	uid, err := strconv.ParseUint(c.Param("uid"), 10, 32)
+	if err != nil {
+		respondWithError(c, constants.StatusBadRequest, constants.InvalidRequest)
+		return 0, false
+	}
+	if uint(uid) != c.GetUint("userID") {
+		respondWithError(c, constants.StatusForbidden, constants.Forbidden)
+		return 0, false
+	}
+	return uint(uid), true
+}
+
+// respondWithICal iCalendarファイルを返す
+func respondWithICal(c *gin.Context, filename string, calendar string) {
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Data(constants.StatusOK, "text/calendar; charset=utf-8", []byte(calendar))
+}
@@ -0,0 +1,250 @@
+package controllers
+
+import (
+	"errors"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/constants"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// maxSemesterNameLength 学期の名前の最大文字数
+const maxSemesterNameLength = 50
+
+// ClassSemesterController クラスの学期と出席成績のコントローラ
+type ClassSemesterController struct {
+	semesterService services.ClassSemesterService
+}
+
+// NewClassSemesterController ClassSemesterControllerを生成
+func NewClassSemesterController(semesterService services.ClassSemesterService) *ClassSemesterController {
+	return &ClassSemesterController{
+		semesterService: semesterService,
+	}
+}
+
+// GetSemesters godoc
+// @Summary クラスの学期一覧
+// @Description クラスに登録された学期を開始日時の順に取得します。
+// @Tags Attendance Grade
+// @Produce json
+// @Param cid path int true "クラスID"
+// @Success 200 {array} dto.SemesterDTO "学期"
+// @Failure 400 {object} utils.ErrorResponse "無効なリクエスト"
+// @Failure 500 {object} utils.ErrorResponse "サーバーエラーが発生しました"
+// @Router /at/{cid}/semesters [get]
+// @Security Bearer
+func (c *ClassSemesterController) GetSemesters(ctx *gin.Context) {
+	cid, err := strconv.ParseUint(ctx.Param("cid"), 10, 32)
+	if err != nil {
+		abortWithError(ctx, utils.NewBadRequestError(constants.ErrCodeInvalidRequest, constants.InvalidRequest))
+		return
+	}
+
+	semesters, err := c.semesterService.GetSemesters(ctx.Request.Context(), uint(cid))
+	if err != nil {
+		abortWithError(ctx, toAppError(err))
+		return
+	}
+	respondWithSuccess(ctx, constants.StatusOK, semesters)
+}
+
+// SaveSemester godoc
+// @Summary クラスの学期を登録
+// @Description 学期の開始日と終了日(YYYY-MM-DD)を登録します。同じ名前の学期がある場合は期間を置き換えます。クラスの管理者のみ利用できます。
+// @Tags Attendance Grade
+// @Accept json
+// @Produce json
+// @Param cid path int true "クラスID"
+// @Param semester path string true "学期の名前"
+// @Param request body dto.ClassSemesterDTO true "学期の期間"
+// @Success 200 {object} dto.SemesterDTO "登録した学期"
+// @Failure 400 {object} utils.ErrorResponse "無効なリクエスト"
+// @Failure 403 {object} utils.ErrorResponse "管理者権限がありません"
+// @Failure 500 {object} utils.ErrorResponse "サーバーエラーが発生しました"
+// @Router /at/{cid}/semesters/{semester} [put]
+// @Security Bearer
+func (c *ClassSemesterController) SaveSemester(ctx *gin.Context) {
+	cid, name, ok := parseSemesterParams(ctx)
+	if !ok {
+		return
+	}
+
+	var request dto.ClassSemesterDTO
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		abortWithError(ctx, newBindingError(err))
+		return
+	}
+	startsAt, err := parseTimeQuery(request.StartDate, time.Time{}, false)
+	if err != nil {
+		abortWithError(ctx, utils.NewBadRequestError(constants.ErrCodeInvalidRequest, constants.ErrInvalidDateJP).Wrap(err))
+		return
+	}
+	endsAt, err := parseTimeQuery(request.EndDate, time.Time{}, true)
+	if err != nil {
+		abortWithError(ctx, utils.NewBadRequestError(constants.ErrCodeInvalidRequest, constants.ErrInvalidDateJP).Wrap(err))
+		return
+	}
+	if endsAt.Before(startsAt) {
+		abortWithError(ctx, utils.NewBadRequestError(constants.ErrCodeInvalidRequest, constants.InvalidSemesterPeriod))
+		return
+	}
+
+	semester, err := c.semesterService.SaveSemester(ctx.Request.Context(), ctx.GetUint("userID"), cid, name, startsAt, endsAt)
+	if err != nil {
+		abortWithSemesterError(ctx, err)
+		return
+	}
+	respondWithSuccess(ctx, constants.StatusOK, semester)
+}
+
+// DeleteSemester godoc
+// @Summary クラスの学期を削除
+// @Description クラスの学期を削除します。出席の記録は削除されません。クラスの管理者のみ利用できます。
+// @Tags Attendance Grade
+// @Produce json
+// @Param cid path int true "クラスID"
+// @Param semester path string true "学期の名前"
+// @Success 200 {object} string "削除に成功しました"
+// @Failure 400 {object} utils.ErrorResponse "無効なリクエスト"
+// @Failure 403 {object} utils.ErrorResponse "管理者権限がありません"
+// @Failure 404 {object} utils.ErrorResponse "学期が見つかりません"
+// @Failure 500 {object} utils.ErrorResponse "サーバーエラーが発生しました"
+// @Router /at/{cid}/semesters/{semester} [delete]
+// @Security Bearer
+func (c *ClassSemesterController) DeleteSemester(ctx *gin.Context) {
+	cid, name, ok := parseSemesterParams(ctx)
+	if !ok {
+		return
+	}
+
+	if err := c.semesterService.DeleteSemester(ctx.Request.Context(), ctx.GetUint("userID"), cid, name); err != nil {
+		abortWithSemesterError(ctx, err)
+		return
+	}
+	respondWithSuccess(ctx, constants.StatusOK, constants.DeleteSuccess)
+}
+
+// GetGradeScale godoc
+// @Summary 出席成績の基準を取得
+// @Description 出席率を成績に変換する基準を出席率の下限が高い順に取得します。クラスで設定していない場合は既定の基準(A/B/C/F)を返します。
+// @Tags Attendance Grade
+// @Produce json
+// @Param cid path int true "クラスID"
+// @Success 200 {object} dto.GradeScaleDTO "成績の基準"
+// @Failure 400 {object} utils.ErrorResponse "無効なリクエスト"
+// @Failure 500 {object} utils.ErrorResponse "サーバーエラーが発生しました"
+// @Router /at/{cid}/grade-scale [get]
+// @Security Bearer
+func (c *ClassSemesterController) GetGradeScale(ctx *gin.Context) {
+	cid, err := strconv.ParseUint(ctx.Param("cid"), 10, 32)
+	if err != nil {
+		abortWithError(ctx, utils.NewBadRequestError(constants.ErrCodeInvalidRequest, constants.InvalidRequest))
+		return
+	}
+
+	scale, err := c.semesterService.GetGradeScale(ctx.Request.Context(), uint(cid))
+	if err != nil {
+		abortWithError(ctx, toAppError(err))
+		return
+	}
+	respondWithSuccess(ctx, constants.StatusOK, scale)
+}
+
+// SetGradeScale godoc
+// @Summary 出席成績の基準を設定
+// @Description クラスの出席率を成績に変換する基準を置き換えます。成績と出席率の下限は重複できず、下限0%の基準を必ず含めます。クラスの管理者のみ利用できます。
+// @Tags Attendance Grade
+// @Accept json
+// @Produce json
+// @Param cid path int true "クラスID"
+// @Param request body dto.GradeScaleDTO true "成績の基準"
+// @Success 200 {object} dto.GradeScaleDTO "設定した成績の基準"
+// @Failure 400 {object} utils.ErrorResponse "無効なリクエスト"
+// @Failure 403 {object} utils.ErrorResponse "管理者権限がありません"
+// @Failure 500 {object} utils.ErrorResponse "サーバーエラーが発生しました"
+// @Router /at/{cid}/grade-scale [put]
+// @Security Bearer
+func (c *ClassSemesterController) SetGradeScale(ctx *gin.Context) {
+	cid, err := strconv.ParseUint(ctx.Param("cid"), 10, 32)
+	if err != nil {
+		abortWithError(ctx, utils.NewBadRequestError(constants.ErrCodeInvalidRequest, constants.InvalidRequest))
+		return
+	}
+
+	var request dto.GradeScaleDTO
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		abortWithError(ctx, newBindingError(err))
+		return
+	}
+
+	scale, err := c.semesterService.SetGradeScale(ctx.Request.Context(), ctx.GetUint("userID"), uint(cid), request.Thresholds)
+	if err != nil {
+		abortWithSemesterError(ctx, err)
+		return
+	}
+	respondWithSuccess(ctx, constants.StatusOK, scale)
+}
+
+// GetSemesterAttendanceGrade godoc
+// @Summary 学期の出席成績を取得
+// @Description 学期中に終了したスケジュールの出席率を、クラスの成績の基準で成績と成績の点数(GPA用)に変換して返します。休講は含めず、出席の記録がないスケジュールは欠席として数えます。本人とクラスの講師・アシスタントのみ取得できます。
+// @Tags Attendance Grade
+// @Produce json
+// @Param cid path int true "クラスID"
+// @Param semester path string true "学期の名前"
+// @Param uid path int true "ユーザーID"
+// @Success 200 {object} dto.SemesterAttendanceGradeDTO "学期の出席成績"
+// @Failure 400 {object} utils.ErrorResponse "無効なリクエスト"
+// @Failure 403 {object} utils.ErrorResponse "権限がありません"
+// @Failure 404 {object} utils.ErrorResponse "学期が見つかりません"
+// @Failure 500 {object} utils.ErrorResponse "サーバーエラーが発生しました"
+// @Router /at/{cid}/semesters/{semester}/grades/{uid} [get]
+// @Security Bearer
+func (c *ClassSemesterController) GetSemesterAttendanceGrade(ctx *gin.Context) {
+	cid, name, ok := parseSemesterParams(ctx)
+	if !ok {
+		return
+	}
+	uid, err := strconv.ParseUint(ctx.Param("uid"), 10, 32)
+	if err != nil {
+		abortWithError(ctx, utils.NewBadRequestError(constants.ErrCodeInvalidRequest, constants.InvalidRequest))
+		return
+	}
+
+	grade, err := c.semesterService.GetSemesterAttendanceGrade(ctx.Request.Context(), ctx.GetUint("userID"), uint(uid), cid, name)
+	if err != nil {
+		abortWithSemesterError(ctx, err)
+		return
+	}
+	respondWithSuccess(ctx, constants.StatusOK, grade)
+}
+
+// parseSemesterParams パスのクラスIDと学期の名前を解析する。不正な場合はエラーを登録してfalseを返す
+func parseSemesterParams(ctx *gin.Context) (uint, string, bool) {
+	cid, err := strconv.ParseUint(ctx.Param("cid"), 10, 32)
+	if err != nil {
+		abortWithError(ctx, utils.NewBadRequestError(constants.ErrCodeInvalidRequest, constants.InvalidRequest))
+		return 0, "", false
+	}
+	name := ctx.Param("semester")
+	if name == "" || utf8.RuneCountInString(name) > maxSemesterNameLength {
+		abortWithError(ctx, utils.NewBadRequestError(constants.ErrCodeInvalidRequest, constants.InvalidRequest))
+		return 0, "", false
+	}
+	return uint(cid), name, true
+}
+
+// abortWithSemesterError 権限のエラーを403として登録し、それ以外はAppErrorに変換して登録する
+func abortWithSemesterError(ctx *gin.Context, err error) {
+	if errors.Is(err, services.ErrUnauthorized) {
+		abortWithError(ctx, utils.NewForbiddenError(constants.ErrCodeForbidden, constants.Forbidden))
+		return
+	}
+	abortWithError(ctx, toAppError(err))
+}
@@ -1,14 +1,20 @@
 package controllers
 
 import (
+	"bytes"
 	"errors"
+	"fmt"
+	"io"
 	"strconv"
+	"strings"
 
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
 	"gorm.io/gorm"
 
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/constants"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/middlewares"
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/utils"
 	"github.com/gin-gonic/gin"
 )
 
@@ -38,32 +44,32 @@ type UpdateUserNameRequest struct {
 // @Param uid path int true "ユーザーID"
 // @Param cid path int true "クラスID"
 // @Success 200 {object} dto.ClassMemberDTO "成功"
-// @Failure 400 {string} string "無効なリクエスト"
-// @Failure 404 {string} string "情報が見つかりません"
-// @Failure 500 {string} string "サーバーエラーが発生しました"
+// @Failure 400 {object} utils.ErrorResponse "無効なリクエスト"
+// @Failure 404 {object} utils.ErrorResponse "情報が見つかりません"
+// @Failure 500 {object} utils.ErrorResponse "サーバーエラーが発生しました"
 // @Router /cu/{uid}/{cid}/info [get]
+// @Router /v2/cu/{cid}/info [get]
 // @Security Bearer
 func (c *ClassUserController) GetUserClassUserInfo(ctx *gin.Context) {
-	uidStr := ctx.Param("uid")
-	uid, err := strconv.ParseUint(uidStr, 10, 32)
+	uid, err := resolveUserID(ctx)
 	if err != nil {
-		respondWithError(ctx, constants.StatusBadRequest, constants.InvalidRequest)
+		abortWithError(ctx, utils.NewBadRequestError(constants.ErrCodeInvalidRequest, constants.InvalidRequest))
 		return
 	}
 
 	cidStr := ctx.Param("cid")
 	cid, err := strconv.ParseUint(cidStr, 10, 32)
 	if err != nil {
-		respondWithError(ctx, constants.StatusBadRequest, constants.InvalidRequest)
+		abortWithError(ctx, utils.NewBadRequestError(constants.ErrCodeInvalidRequest, constants.InvalidRequest))
 		return
 	}
 
-	classUserInfo, err := c.classUserService.GetClassUserInfo(uint(uid), uint(cid))
+	classUserInfo, err := c.classUserService.GetClassUserInfo(ctx.Request.Context(), uid, uint(cid))
 	if err != nil {
 		if errors.Is(err, services.ErrNotFound) {
-			respondWithError(ctx, constants.StatusNotFound, constants.UserNotFound)
+			abortWithError(ctx, utils.NewNotFoundError(constants.ErrCodeUserNotFound, constants.UserNotFound))
 		} else {
-			respondWithError(ctx, constants.StatusInternalServerError, constants.InternalServerError)
+			abortWithError(ctx, err)
 		}
 		return
 	}
@@ -80,69 +86,77 @@ func (c *ClassUserController) GetUserClassUserInfo(ctx *gin.Context) {
 // @Param uid path int true "ユーザーID"
 // @Param page query int false "Page number" default(1)
 // @Param limit query int false "Page size" default(10)
+// @Param include_archived query bool false "アーカイブされたクラスも含める" default(false)
 // @Success 200 {array} models.Class "成功"
 // @Router /cu/{uid}/classes [get]
+// @Router /v2/cu/classes [get]
 // @Security Bearer
 func (c *ClassUserController) GetUserClasses(ctx *gin.Context) {
-	uidStr := ctx.Param("uid")
 	pageStr := ctx.DefaultQuery("page", "1")
 	limitStr := ctx.DefaultQuery("limit", "10")
 
-	uid, err := strconv.ParseUint(uidStr, 10, 32)
+	uid, err := resolveUserID(ctx)
 	if err != nil {
-		respondWithError(ctx, constants.StatusBadRequest, constants.InvalidRequest)
+		abortWithError(ctx, utils.NewBadRequestError(constants.ErrCodeInvalidRequest, constants.InvalidRequest))
 		return
 	}
 	page, _ := strconv.Atoi(pageStr)
 	limit, _ := strconv.Atoi(limitStr)
 
-	classes, err := c.classUserService.GetUserClasses(uint(uid), page, limit)
+	includeArchived, _ := strconv.ParseBool(ctx.DefaultQuery("include_archived", "false"))
+
+	classes, err := c.classUserService.GetUserClasses(ctx.Request.Context(), uid, page, limit, includeArchived)
 	if err != nil {
-		respondWithError(ctx, constants.StatusInternalServerError, constants.InternalServerError)
+		abortWithError(ctx, err)
 		return
 	}
 	//if err != nil {
 	//	if errors.Is(err, services.ErrNotFound) {
-	//		respondWithError(ctx, constants.StatusNotFound, constants.ClassNotFound)
+	//		abortWithError(ctx, utils.NewNotFoundError(constants.ErrCodeClassNotFound, constants.ClassNotFound))
 	//	} else {
-	//		respondWithError(ctx, constants.StatusInternalServerError, constants.InternalServerError)
+	//		abortWithError(ctx, err)
 	//	}
 	//	return
 	//}
 
 	if len(classes) == 0 {
-		respondWithError(ctx, constants.StatusNotFound, constants.ClassNotFound)
+		abortWithError(ctx, utils.NewNotFoundError(constants.ErrCodeClassNotFound, constants.ClassNotFound))
 		return
 	}
 
-	respondWithSuccess(ctx, constants.StatusOK, classes)
+	respondWithPage(ctx, constants.StatusOK, classes, page, limit)
 }
 
 // GetClassMembers godoc
 // @Summary クラスメンバーの情報を取得
-// @Description 指定されたcidのクラスに所属しているメンバーの情報を取得します。
+// @Description 指定されたcidのクラスに所属しているメンバーの情報を取得します。roleを複数指定した場合はいずれかのロールのメンバーを返し、省略した場合は全てのメンバーを返します。
 // @Tags Class User
 // @Accept  json
 // @Produce  json
 // @Param cid path int true "クラスID"
-// @Param role query string false "ロール名"
+// @Param role query []string false "ロール名 (例: role=ADMIN&role=ASSISTANT)" collectionFormat(multi)
 // @Success 200 {array} dto.ClassMemberDTO "成功時、クラスメンバーの情報を返します"
-// @Failure 400 {object} map[string]interface{} "無効なクラスIDが指定された場合のエラーメッセージ"
-// @Failure 500 {object} map[string]interface{} "サーバー内部エラー"
+// @Failure 400 {object} utils.ErrorResponse "無効なクラスIDまたはロール名が指定された場合のエラーメッセージ"
+// @Failure 500 {object} utils.ErrorResponse "サーバー内部エラー"
 // @Router /cu/class/{cid}/members [get]
+// @Router /v2/cu/class/{cid}/members [get]
 // @Security Bearer
 func (c *ClassUserController) GetClassMembers(ctx *gin.Context) {
 	cid, err := strconv.ParseUint(ctx.Param("cid"), 10, 32)
 	if err != nil {
-		respondWithError(ctx, constants.StatusBadRequest, constants.InvalidRequest)
+		abortWithError(ctx, utils.NewBadRequestError(constants.ErrCodeInvalidRequest, constants.InvalidRequest))
 		return
 	}
 
-	roleName := ctx.DefaultQuery("role", "")
+	roleNames, ok := parseRoleFilter(ctx.QueryArray("role"))
+	if !ok {
+		abortWithError(ctx, utils.NewBadRequestError(constants.ErrCodeInvalidRoleName, "Invalid Role Name"))
+		return
+	}
 
-	members, err := c.classUserService.GetClassMembers(uint(cid), roleName)
+	members, err := c.classUserService.GetClassMembers(ctx.Request.Context(), uint(cid), roleNames...)
 	if err != nil {
-		respondWithError(ctx, constants.StatusInternalServerError, constants.InternalServerError)
+		abortWithError(ctx, err)
 		return
 	}
 
@@ -164,16 +178,16 @@ func (c *ClassUserController) GetClassMembers(ctx *gin.Context) {
 // @Param page query int false "Page number" default(1)
 // @Param limit query int false "Page size" default(10)
 // @Success 200 {array} dto.UserClassInfoDTO "成功"
-// @Failure 400 {string} string "無効なリクエスト"
-// @Failure 404 {string} string "クラスが見つかりません"
-// @Failure 500 {string} string "サーバーエラーが発生しました"
+// @Failure 400 {object} utils.ErrorResponse "無効なリクエスト"
+// @Failure 404 {object} utils.ErrorResponse "クラスが見つかりません"
+// @Failure 500 {object} utils.ErrorResponse "サーバーエラーが発生しました"
 // @Router /cu/{uid}/favorite-classes [get]
+// @Router /v2/cu/favorite-classes [get]
 // @Security Bearer
 func (c *ClassUserController) GetFavoriteClasses(ctx *gin.Context) {
-	uidStr := ctx.Param("uid")
-	uid, err := strconv.ParseUint(uidStr, 10, 32)
+	uid, err := resolveUserID(ctx)
 	if err != nil {
-		respondWithError(ctx, constants.StatusBadRequest, constants.InvalidRequest)
+		abortWithError(ctx, utils.NewBadRequestError(constants.ErrCodeInvalidRequest, constants.InvalidRequest))
 		return
 	}
 
@@ -182,22 +196,22 @@ func (c *ClassUserController) GetFavoriteClasses(ctx *gin.Context) {
 	page, _ := strconv.Atoi(pageStr)
 	limit, _ := strconv.Atoi(limitStr)
 
-	favoriteClasses, err := c.classUserService.GetFavoriteClasses(uint(uid), page, limit)
+	favoriteClasses, err := c.classUserService.GetFavoriteClasses(ctx.Request.Context(), uid, page, limit)
 	if err != nil {
 		if errors.Is(err, services.ErrNotFound) {
-			respondWithError(ctx, constants.StatusNotFound, constants.ClassNotFound)
+			abortWithError(ctx, utils.NewNotFoundError(constants.ErrCodeClassNotFound, constants.ClassNotFound))
 		} else {
-			respondWithError(ctx, constants.StatusInternalServerError, constants.InternalServerError)
+			abortWithError(ctx, err)
 		}
 		return
 	}
 
 	if len(favoriteClasses) == 0 {
-		respondWithError(ctx, constants.StatusNotFound, constants.ClassNotFound)
+		abortWithError(ctx, utils.NewNotFoundError(constants.ErrCodeClassNotFound, constants.ClassNotFound))
 		return
 	}
 
-	respondWithSuccess(ctx, constants.StatusOK, favoriteClasses)
+	respondWithPage(ctx, constants.StatusOK, favoriteClasses, page, limit)
 }
 
 // GetUserClassesByRole godoc
@@ -211,18 +225,18 @@ func (c *ClassUserController) GetFavoriteClasses(ctx *gin.Context) {
 // @Param page query int false "Page number" default(1)
 // @Param limit query int false "Page size" default(10)
 // @Success 200 {array} dto.UserClassInfoDTO "成功"
-// @Failure 400 {string} string "無効なリクエスト"
-// @Failure 404 {string} string "クラスが見つかりません"
-// @Failure 500 {string} string "サーバーエラーが発生しました"
+// @Failure 400 {object} utils.ErrorResponse "無効なリクエスト"
+// @Failure 404 {object} utils.ErrorResponse "クラスが見つかりません"
+// @Failure 500 {object} utils.ErrorResponse "サーバーエラーが発生しました"
 // @Router /cu/{uid}/classes/by-role [get]
+// @Router /v2/cu/classes/by-role [get]
 // @Security Bearer
 func (c *ClassUserController) GetUserClassesByRole(ctx *gin.Context) {
-	uidStr := ctx.Param("uid")
 	roleName := ctx.Query("role")
 
-	uid, err := strconv.ParseUint(uidStr, 10, 32)
+	uid, err := resolveUserID(ctx)
 	if err != nil {
-		respondWithError(ctx, constants.StatusBadRequest, constants.InvalidRequest)
+		abortWithError(ctx, utils.NewBadRequestError(constants.ErrCodeInvalidRequest, constants.InvalidRequest))
 		return
 	}
 
@@ -231,27 +245,27 @@ func (c *ClassUserController) GetUserClassesByRole(ctx *gin.Context) {
 	page, _ := strconv.Atoi(pageStr)
 	limit, _ := strconv.Atoi(limitStr)
 
-	classes, err := c.classUserService.GetUserClassesByRole(uint(uid), roleName, page, limit)
+	classes, err := c.classUserService.GetUserClassesByRole(ctx.Request.Context(), uid, roleName, page, limit)
 	if err != nil {
 		if errors.Is(err, services.ErrNotFound) {
-			respondWithError(ctx, constants.StatusNotFound, constants.ClassNotFound)
+			abortWithError(ctx, utils.NewNotFoundError(constants.ErrCodeClassNotFound, constants.ClassNotFound))
 		} else {
-			respondWithError(ctx, constants.StatusInternalServerError, constants.InternalServerError)
+			abortWithError(ctx, err)
 		}
 		return
 	}
 
 	if len(classes) == 0 {
-		respondWithSuccess(ctx, constants.StatusOK, []dto.UserClassInfoDTO{})
+		respondWithPage(ctx, constants.StatusOK, []dto.UserClassInfoDTO{}, page, limit)
 		return
 	}
 
-	respondWithSuccess(ctx, constants.StatusOK, classes)
+	respondWithPage(ctx, constants.StatusOK, classes, page, limit)
 }
 
 // ChangeUserRole godoc
 // @Summary ユーザーのロールを変更
-// @Description 指定されたユーザーIDとクラスIDに基づいて、ユーザーのロールを変更します。
+// @Description 指定されたユーザーIDとクラスIDに基づいて、ユーザーのロールを変更します。クラスの管理者のみ利用できます。
 // @Tags Class User
 // @Accept json
 // @Produce json
@@ -259,9 +273,13 @@ func (c *ClassUserController) GetUserClassesByRole(ctx *gin.Context) {
 // @Param cid path int true "クラスID"
 // @Param roleName path string true "ロール名"
 // @Success 200 {string} string "Role updated successfully"
-// @Failure 400 {string} string "Invalid request"
-// @Failure 404 {string} string "User or class not found"
+// @Failure 400 {object} utils.ErrorResponse "Invalid request"
+// @Failure 403 {object} utils.ErrorResponse "管理者権限がありません"
+// @Failure 404 {object} utils.ErrorResponse "User or class not found"
+// @Failure 422 {object} utils.ErrorResponse "active_class_limit: 追加するユーザーのアクティブなクラス数が上限に達しています"
+// @Failure 500 {object} utils.ErrorResponse "サーバーエラーが発生しました"
 // @Router /cu/{uid}/{cid}/role/{roleName} [patch]
+// @Router /v2/cu/{cid}/members/{uid}/role/{roleName} [patch]
 // @Security Bearer
 func (c *ClassUserController) ChangeUserRole(ctx *gin.Context) {
 	uidStr := ctx.Param("uid")
@@ -270,30 +288,49 @@ func (c *ClassUserController) ChangeUserRole(ctx *gin.Context) {
 
 	uid, err := strconv.ParseUint(uidStr, 10, 32)
 	if err != nil {
-		respondWithError(ctx, constants.StatusBadRequest, "Invalid User ID")
+		abortWithError(ctx, utils.NewBadRequestError(constants.ErrCodeInvalidRequest, "Invalid User ID"))
 		return
 	}
 
 	cid, err := strconv.ParseUint(cidStr, 10, 32)
 	if err != nil {
-		respondWithError(ctx, constants.StatusBadRequest, "Invalid Class ID")
+		abortWithError(ctx, utils.NewBadRequestError(constants.ErrCodeInvalidRequest, "Invalid Class ID"))
 		return
 	}
 
 	if !isValidRoleName(roleName) {
-		respondWithError(ctx, constants.StatusBadRequest, "Invalid Role Name")
+		abortWithError(ctx, utils.NewBadRequestError(constants.ErrCodeInvalidRoleName, "Invalid Role Name"))
+		return
+	}
+	if !c.requireClassAdmin(ctx, uint(cid)) {
 		return
 	}
 
-	err = c.classUserService.AssignRole(uint(uid), uint(cid), roleName)
+	err = c.classUserService.AssignRole(ctx.Request.Context(), uint(uid), uint(cid), roleName)
 	if err != nil {
-		respondWithError(ctx, constants.StatusInternalServerError, "Error changing role")
+		abortWithError(ctx, toAppError(err))
 		return
 	}
 
 	respondWithSuccess(ctx, constants.StatusOK, "Role updated successfully")
 }
 
+// parseRoleFilter クエリで指定されたロール名を大文字に揃えて返す。無効なロール名が含まれる場合はfalseを返す
+func parseRoleFilter(values []string) ([]string, bool) {
+	var roleNames []string
+	for _, value := range values {
+		roleName := strings.ToUpper(strings.TrimSpace(value))
+		if roleName == "" {
+			continue
+		}
+		if !isValidRoleName(roleName) {
+			return nil, false
+		}
+		roleNames = append(roleNames, roleName)
+	}
+	return roleNames, true
+}
+
 func isValidRoleName(roleName string) bool {
 	validRoleNames := map[string]bool{
 		"USER":      true,
@@ -319,30 +356,31 @@ func isValidRoleName(roleName string) bool {
 // @Param cid path int true "Class ID"
 // @Param body body UpdateUserNameRequest true "新しいニックネーム"
 // @Success 200 {string} string "成功"
-// @Failure 500 {string} string "サーバーエラーが発生しました"
+// @Failure 500 {object} utils.ErrorResponse "サーバーエラーが発生しました"
 // @Router /cu/{uid}/{cid}/rename [put]
+// @Router /v2/cu/{cid}/rename [put]
 // @Security Bearer
 func (c *ClassUserController) UpdateUserName(ctx *gin.Context) {
-	uid, err := strconv.ParseUint(ctx.Param("uid"), 10, 32)
+	uid, err := resolveUserID(ctx)
 	if err != nil {
-		respondWithError(ctx, constants.StatusBadRequest, constants.InvalidRequest)
+		abortWithError(ctx, utils.NewBadRequestError(constants.ErrCodeInvalidRequest, constants.InvalidRequest))
 		return
 	}
 	cid, err := strconv.ParseUint(ctx.Param("cid"), 10, 32)
 	if err != nil {
-		respondWithError(ctx, constants.StatusBadRequest, constants.InvalidRequest)
+		abortWithError(ctx, utils.NewBadRequestError(constants.ErrCodeInvalidRequest, constants.InvalidRequest))
 		return
 	}
 
 	var requestBody UpdateUserNameRequest
 	if err := ctx.ShouldBindJSON(&requestBody); err != nil {
-		respondWithError(ctx, constants.StatusBadRequest, constants.InvalidRequest)
+		abortWithError(ctx, utils.NewBadRequestError(constants.ErrCodeInvalidRequest, constants.InvalidRequest))
 		return
 	}
 
-	err = c.classUserService.UpdateUserName(uint(uid), uint(cid), requestBody.NewName)
+	err = c.classUserService.UpdateUserName(ctx.Request.Context(), uid, uint(cid), requestBody.NewName)
 	if err != nil {
-		handleServiceError(ctx, err)
+		abortWithError(ctx, toAppError(err))
 		return
 	}
 
@@ -358,25 +396,26 @@ func (c *ClassUserController) UpdateUserName(ctx *gin.Context) {
 // @Param uid path int true "ユーザーID"
 // @Param cid path int true "クラスID"
 // @Success 200 {string} string "成功"
-// @Failure 400 {string} string "無効なリクエスト"
-// @Failure 404 {string} string "ユーザーまたはクラスが見つかりません"
-// @Failure 500 {string} string "サーバーエラーが発生しました"
+// @Failure 400 {object} utils.ErrorResponse "無効なリクエスト"
+// @Failure 404 {object} utils.ErrorResponse "ユーザーまたはクラスが見つかりません"
+// @Failure 500 {object} utils.ErrorResponse "サーバーエラーが発生しました"
 // @Router /cu/{uid}/{cid}/toggle-favorite [patch]
+// @Router /v2/cu/{cid}/toggle-favorite [patch]
 // @Security Bearer
 func (c *ClassUserController) ToggleFavorite(ctx *gin.Context) {
-	uid, uidErr := strconv.ParseUint(ctx.Param("uid"), 10, 32)
+	uid, uidErr := resolveUserID(ctx)
 	cid, cidErr := strconv.ParseUint(ctx.Param("cid"), 10, 32)
 	if uidErr != nil || cidErr != nil {
-		respondWithError(ctx, constants.StatusBadRequest, constants.InvalidRequest)
+		abortWithError(ctx, utils.NewBadRequestError(constants.ErrCodeInvalidRequest, constants.InvalidRequest))
 		return
 	}
 
-	err := c.classUserService.ToggleFavorite(uint(uid), uint(cid))
+	err := c.classUserService.ToggleFavorite(ctx.Request.Context(), uid, uint(cid))
 	if err != nil {
 		if errors.Is(err, services.ErrNotFound) {
-			respondWithError(ctx, constants.StatusNotFound, constants.UserNClassNotFound)
+			abortWithError(ctx, utils.NewNotFoundError(constants.ErrCodeUserOrClassNotFound, constants.UserNClassNotFound))
 		} else {
-			respondWithError(ctx, constants.StatusInternalServerError, constants.InternalServerError)
+			abortWithError(ctx, err)
 		}
 		return
 	}
@@ -384,41 +423,76 @@ func (c *ClassUserController) ToggleFavorite(ctx *gin.Context) {
 	respondWithSuccess(ctx, constants.StatusOK, constants.Success)
 }
 
+// BatchSetFavorite godoc
+// @Summary お気に入りのクラスを一括設定
+// @Description 指定した複数のクラスのお気に入りを一括で設定します。参加していないクラスが含まれる場合は何も更新せず、そのクラスIDを返します。
+// @Tags Class User
+// @Accept json
+// @Produce json
+// @Param uid path int true "ユーザーID"
+// @Param request body dto.BatchFavoriteRequest true "クラスIDとお気に入りの設定"
+// @Success 200 {string} string "成功"
+// @Failure 400 {object} utils.ErrorResponse "無効なリクエスト"
+// @Failure 422 {object} utils.ErrorResponse "not_enrolled"
+// @Failure 500 {object} utils.ErrorResponse "サーバーエラーが発生しました"
+// @Router /cu/{uid}/favorites [put]
+// @Router /v2/cu/favorites [put]
+// @Security Bearer
+func (c *ClassUserController) BatchSetFavorite(ctx *gin.Context) {
+	uid, err := resolveUserID(ctx)
+	if err != nil {
+		abortWithError(ctx, utils.NewBadRequestError(constants.ErrCodeInvalidRequest, constants.InvalidRequest))
+		return
+	}
+	var req dto.BatchFavoriteRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		abortWithError(ctx, utils.NewBadRequestError(constants.ErrCodeInvalidRequest, constants.InvalidRequest).Wrap(err))
+		return
+	}
+
+	if err := c.classUserService.BatchSetFavorite(ctx.Request.Context(), uid, req.ClassIDs, *req.IsFavorite); err != nil {
+		abortWithError(ctx, toAppError(err))
+		return
+	}
+	respondWithSuccess(ctx, constants.StatusOK, constants.Success)
+}
+
 // RemoveUserFromClass godoc
 // @Summary ユーザーをクラスから削除
-// @Description 指定したユーザーIDとクラスIDに基づいて、ユーザーをクラスから削除します。
+// @Description 指定したユーザーIDとクラスIDに基づいて、ユーザーをクラスから削除します。削除は論理削除で、参加日や参加に使用したコードは保持されます。
 // @Tags Class User
 // @Accept json
 // @Produce json
 // @Param uid path int true "ユーザーID"
 // @Param cid path int true "クラスID"
 // @Success 200 {string} string "成功"
-// @Failure 400 {string} string "無効なリクエスト"
-// @Failure 404 {string} string "ユーザーまたはクラスが見つかりません"
-// @Failure 500 {string} string "サーバーエラーが発生しました"
+// @Failure 400 {object} utils.ErrorResponse "無効なリクエスト"
+// @Failure 404 {object} utils.ErrorResponse "ユーザーまたはクラスが見つかりません"
+// @Failure 500 {object} utils.ErrorResponse "サーバーエラーが発生しました"
 // @Router /cu/{uid}/{cid}/remove [delete]
+// @Router /v2/cu/{cid}/members/{uid} [delete]
 // @Security Bearer
 func (c *ClassUserController) RemoveUserFromClass(ctx *gin.Context) {
 	uidStr := ctx.Param("uid")
 	uid, err := strconv.ParseUint(uidStr, 10, 32)
 	if err != nil {
-		respondWithError(ctx, constants.StatusBadRequest, constants.InvalidRequest)
+		abortWithError(ctx, utils.NewBadRequestError(constants.ErrCodeInvalidRequest, constants.InvalidRequest))
 		return
 	}
 
 	cidStr := ctx.Param("cid")
 	cid, err := strconv.ParseUint(cidStr, 10, 32)
 	if err != nil {
-		respondWithError(ctx, constants.StatusBadRequest, constants.InvalidRequest)
+		abortWithError(ctx, utils.NewBadRequestError(constants.ErrCodeInvalidRequest, constants.InvalidRequest))
 		return
 	}
 
-	err = c.classUserService.RemoveUserFromClass(uint(uid), uint(cid))
+	err = c.classUserService.RemoveUserFromClass(ctx.Request.Context(), uint(uid), uint(cid))
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			respondWithError(ctx, constants.StatusNotFound, constants.UserNotFound)
+			abortWithError(ctx, utils.NewNotFoundError(constants.ErrCodeUserNotFound, constants.UserNotFound))
 		} else {
-			respondWithError(ctx, constants.StatusInternalServerError, constants.InternalServerError)
+			abortWithError(ctx, err)
 		}
 		return
 	}
@@ -435,35 +509,319 @@ func (c *ClassUserController) RemoveUserFromClass(ctx *gin.Context) {
 // @Param uid path int true "ユーザーID"
 // @Param name query string true "クラス名"
 // @Success 200 {array} dto.UserClassInfoDTO "Successfully found classes"
-// @Failure 400 {object} string "Invalid Request"
-// @Failure 404 {object} string "No classes found"
-// @Failure 500 {object} string "Internal Server Error"
+// @Failure 400 {object} utils.ErrorResponse "Invalid Request"
+// @Failure 404 {object} utils.ErrorResponse "No classes found"
+// @Failure 500 {object} utils.ErrorResponse "Internal Server Error"
 // @Router /cu/{uid}/classes/search [get]
+// @Router /v2/cu/classes/search [get]
 // @Security Bearer
 func (c *ClassUserController) SearchUserClassesByName(ctx *gin.Context) {
-	uidStr := ctx.Param("uid")
 	className := ctx.Query("name")
 
 	if className == "" {
-		respondWithError(ctx, constants.StatusBadRequest, "Class name must not be empty")
+		abortWithError(ctx, utils.NewBadRequestError(constants.ErrCodeInvalidRequest, "Class name must not be empty"))
 		return
 	}
 
-	uid, err := strconv.ParseUint(uidStr, 10, 32)
+	uid, err := resolveUserID(ctx)
 	if err != nil {
-		respondWithError(ctx, constants.StatusBadRequest, "Invalid user ID")
+		abortWithError(ctx, utils.NewBadRequestError(constants.ErrCodeInvalidRequest, "Invalid user ID"))
 		return
 	}
 
-	classes, err := c.classUserService.SearchUserClassesByName(uint(uid), className)
+	classes, err := c.classUserService.SearchUserClassesByName(ctx.Request.Context(), uid, className)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			respondWithError(ctx, constants.StatusNotFound, "No classes found")
+			abortWithError(ctx, utils.NewNotFoundError(constants.ErrCodeClassNotFound, "No classes found"))
 			return
 		}
-		respondWithError(ctx, constants.StatusInternalServerError, "Internal Server Error")
+		abortWithError(ctx, err)
 		return
 	}
 
 	respondWithSuccess(ctx, constants.StatusOK, classes)
 }
+
+// ExportMembers godoc
+// @Summary クラス名簿をCSVでエクスポート
+// @Description クラスの全メンバーの名前、メールアドレス、ロール、参加日、出席率をCSV形式でダウンロードします。クラスの管理者のみ利用できます。
+// @Tags Class User
+// @Produce text/csv
+// @Param cid path int true "クラスID"
+// @Success 200 {file} file "CSVファイル"
+// @Failure 400 {object} utils.ErrorResponse "無効なリクエスト"
+// @Failure 403 {object} utils.ErrorResponse "管理者権限がありません"
+// @Failure 500 {object} utils.ErrorResponse "サーバーエラーが発生しました"
+// @Router /cl/{cid}/members/export.csv [get]
+// @Security Bearer
+func (c *ClassUserController) ExportMembers(ctx *gin.Context) {
+	cid, err := strconv.ParseUint(ctx.Param("cid"), 10, 32)
+	if err != nil {
+		abortWithError(ctx, utils.NewBadRequestError(constants.ErrCodeInvalidRequest, constants.InvalidRequest))
+		return
+	}
+
+	if !c.requireClassAdmin(ctx, uint(cid)) {
+		return
+	}
+
+	// 取得に失敗した場合にCSVのヘッダーでエラーを返さないよう、書き出してからヘッダーを設定する
+	var buf bytes.Buffer
+	if err := c.classUserService.ExportMembers(ctx.Request.Context(), uint(cid), &buf); err != nil {
+		abortWithError(ctx, err)
+		return
+	}
+
+	ctx.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="class_%d_members.csv"`, cid))
+	ctx.Data(constants.StatusOK, "text/csv; charset=utf-8", buf.Bytes())
+}
+
+// GetMemberActivityRanking godoc
+// @Summary クラスメンバーの活動度ランキングを取得
+// @Description 掲示板投稿数・出席率・チャット発言数を重み付けして集計した活動度ランキングを返します。講師・アシスタント以外には匿名化されたランキングを返します。
+// @Tags Class User
+// @Produce json
+// @Param cid path int true "クラスID"
+// @Param limit query int false "取得件数" default(10)
+// @Param board_weight query number false "掲示板投稿数の重み"
+// @Param attendance_weight query number false "出席率の重み"
+// @Param chat_weight query number false "チャット発言数の重み"
+// @Param anonymize query bool false "匿名化するか"
+// @Success 200 {array} dto.MemberActivityRankingDTO "成功"
+// @Failure 400 {object} utils.ErrorResponse "無効なリクエスト"
+// @Failure 403 {object} utils.ErrorResponse "クラスのメンバーではありません"
+// @Failure 500 {object} utils.ErrorResponse "サーバーエラーが発生しました"
+// @Router /cu/class/{cid}/activity-ranking [get]
+// @Security Bearer
+func (c *ClassUserController) GetMemberActivityRanking(ctx *gin.Context) {
+	cid, err := strconv.ParseUint(ctx.Param("cid"), 10, 32)
+	if err != nil {
+		abortWithError(ctx, utils.NewBadRequestError(constants.ErrCodeInvalidRequest, constants.InvalidRequest))
+		return
+	}
+
+	var weights dto.ActivityWeights
+	if err := ctx.ShouldBindQuery(&weights); err != nil {
+		abortWithError(ctx, utils.NewBadRequestError(constants.ErrCodeInvalidRequest, constants.InvalidRequest))
+		return
+	}
+	limit, _ := strconv.Atoi(ctx.DefaultQuery("limit", "10"))
+	anonymize := ctx.Query("anonymize") == "true"
+
+	role, err := c.classUserService.GetRole(ctx.Request.Context(), ctx.GetUint("userID"), uint(cid))
+	if err != nil {
+		abortWithError(ctx, utils.NewForbiddenError(constants.ErrCodeForbidden, constants.Forbidden))
+		return
+	}
+	if role != "ADMIN" && role != "ASSISTANT" {
+		anonymize = true
+	}
+
+	ranking, err := c.classUserService.GetMemberActivityRanking(ctx.Request.Context(), uint(cid), weights, limit, anonymize)
+	if err != nil {
+		abortWithError(ctx, toAppError(err))
+		return
+	}
+
+	respondWithSuccess(ctx, constants.StatusOK, ranking)
+}
+
+// GetRemovedMembers godoc
+// @Summary クラスから削除されたメンバーの一覧を取得
+// @Description 論理削除されたメンバーを削除日時とともに返します。クラスの管理者のみ利用できます。
+// @Tags Class User
+// @Produce json
+// @Param cid path int true "クラスID"
+// @Success 200 {array} dto.RemovedMemberDTO "成功"
+// @Failure 400 {object} utils.ErrorResponse "無効なリクエスト"
+// @Failure 403 {object} utils.ErrorResponse "管理者権限がありません"
+// @Failure 500 {object} utils.ErrorResponse "サーバーエラーが発生しました"
+// @Router /cu/class/{cid}/removed-members [get]
+// @Security Bearer
+func (c *ClassUserController) GetRemovedMembers(ctx *gin.Context) {
+	cid, err := strconv.ParseUint(ctx.Param("cid"), 10, 32)
+	if err != nil {
+		abortWithError(ctx, utils.NewBadRequestError(constants.ErrCodeInvalidRequest, constants.InvalidRequest))
+		return
+	}
+
+	if !c.requireClassAdmin(ctx, uint(cid)) {
+		return
+	}
+
+	members, err := c.classUserService.GetRemovedMembers(ctx.Request.Context(), uint(cid))
+	if err != nil {
+		abortWithError(ctx, err)
+		return
+	}
+
+	respondWithSuccess(ctx, constants.StatusOK, members)
+}
+
+// RestoreMember godoc
+// @Summary 削除されたメンバーを復元
+// @Description 論理削除されたメンバーをクラスに復元します。クラスの管理者のみ利用できます。
+// @Tags Class User
+// @Produce json
+// @Param cid path int true "クラスID"
+// @Param uid path int true "ユーザーID"
+// @Success 200 {string} string "成功"
+// @Failure 400 {object} utils.ErrorResponse "無効なリクエスト"
+// @Failure 403 {object} utils.ErrorResponse "管理者権限がありません"
+// @Failure 404 {object} utils.ErrorResponse "削除されたメンバーが見つかりません"
+// @Failure 500 {object} utils.ErrorResponse "サーバーエラーが発生しました"
+// @Router /cu/class/{cid}/members/{uid}/restore [post]
+// @Security Bearer
+func (c *ClassUserController) RestoreMember(ctx *gin.Context) {
+	cid, cidErr := strconv.ParseUint(ctx.Param("cid"), 10, 32)
+	uid, uidErr := strconv.ParseUint(ctx.Param("uid"), 10, 32)
+	if cidErr != nil || uidErr != nil {
+		abortWithError(ctx, utils.NewBadRequestError(constants.ErrCodeInvalidRequest, constants.InvalidRequest))
+		return
+	}
+
+	if !c.requireClassAdmin(ctx, uint(cid)) {
+		return
+	}
+
+	err := c.classUserService.RestoreMember(ctx.Request.Context(), uint(uid), uint(cid))
+	if err != nil {
+		if errors.Is(err, services.ErrNotFound) {
+			abortWithError(ctx, utils.NewNotFoundError(constants.ErrCodeUserNotFound, constants.UserNotFound))
+		} else {
+			abortWithError(ctx, err)
+		}
+		return
+	}
+
+	respondWithSuccess(ctx, constants.StatusOK, constants.Success)
+}
+
+// MoveMembers godoc
+// @Summary クラス間でメンバーを移動・コピー
+// @Description 移動元のクラスの指定したメンバーを移動先のクラスに指定したロールで追加します。copyがfalseの場合は移動元から削除し、trueの場合は移動元に残します。roleにADMINは指定できません。移動先で既にメンバーのユーザー、移動元のメンバーでないユーザー、移動元の管理者、移動先に追加するとアクティブなクラス数の上限を超えるユーザーはスキップし、メンバーごとの結果を返します。両方のクラスの管理者のみ利用できます。
+// @Tags Class User
+// @Accept json
+// @Produce json
+// @Param body body dto.MoveMembersRequest true "移動元と移動先のクラス、対象のユーザー、ロール"
+// @Success 200 {object} dto.MoveMembersResultDTO "成功"
+// @Failure 400 {object} utils.ErrorResponse "無効なリクエスト"
+// @Failure 403 {object} utils.ErrorResponse "管理者権限がありません"
+// @Failure 500 {object} utils.ErrorResponse "サーバーエラーが発生しました"
+// @Router /cu/members/move [post]
+// @Router /v2/cu/members/move [post]
+// @Security Bearer
+func (c *ClassUserController) MoveMembers(ctx *gin.Context) {
+	var request dto.MoveMembersRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		abortWithError(ctx, newBindingError(err))
+		return
+	}
+	if request.FromCID == request.ToCID {
+		abortWithError(ctx, utils.NewBadRequestError(constants.ErrCodeInvalidRequest, constants.InvalidRequest))
+		return
+	}
+	request.Role = strings.ToUpper(request.Role)
+	// 管理者は移動やコピーで追加せず、ロールの変更で任命する
+	if !isValidRoleName(request.Role) || request.Role == "ADMIN" {
+		abortWithError(ctx, utils.NewBadRequestError(constants.ErrCodeInvalidRoleName, "Invalid Role Name"))
+		return
+	}
+
+	result, err := c.classUserService.MoveMembers(ctx.Request.Context(), ctx.GetUint("userID"), request)
+	if err != nil {
+		if errors.Is(err, services.ErrUnauthorized) {
+			abortWithError(ctx, utils.NewForbiddenError(constants.ErrCodeForbidden, constants.Forbidden))
+		} else {
+			abortWithError(ctx, err)
+		}
+		return
+	}
+	middlewares.SetAuditClassID(ctx, request.FromCID)
+	middlewares.MarkClassChanged(ctx, request.FromCID, request.ToCID)
+
+	respondWithSuccess(ctx, constants.StatusOK, result)
+}
+
+// GetDashboardLayout godoc
+// @Summary ロール別のダッシュボードを取得
+// @Description ログイン後に表示するダッシュボードのウィジェットを表示順とデータを含めて返します。講師・アシスタントには出席統計とリスク生徒、生徒には次回授業と未読の掲示を優先して返します。
+// @Tags Class User
+// @Produce json
+// @Param cid path int true "クラスID"
+// @Success 200 {object} dto.DashboardLayoutDTO "成功"
+// @Failure 400 {object} utils.ErrorResponse "無効なリクエスト"
+// @Failure 403 {object} utils.ErrorResponse "クラスのメンバーではありません"
+// @Failure 500 {object} utils.ErrorResponse "サーバーエラーが発生しました"
+// @Router /v2/cu/{cid}/dashboard [get]
+// @Security Bearer
+func (c *ClassUserController) GetDashboardLayout(ctx *gin.Context) {
+	cid, err := strconv.ParseUint(ctx.Param("cid"), 10, 32)
+	if err != nil {
+		abortWithError(ctx, utils.NewBadRequestError(constants.ErrCodeInvalidRequest, constants.InvalidRequest))
+		return
+	}
+
+	layout, err := c.classUserService.GetDashboardLayout(ctx.Request.Context(), ctx.GetUint("userID"), uint(cid))
+	if err != nil {
+		if errors.Is(err, services.ErrUnauthorized) {
+			abortWithError(ctx, utils.NewForbiddenError(constants.ErrCodeForbidden, constants.Forbidden))
+			return
+		}
+		abortWithError(ctx, toAppError(err))
+		return
+	}
+
+	respondWithSuccess(ctx, constants.StatusOK, layout)
+}
+
+// requireClassAdmin リクエストしたユーザーがクラスの管理者か確認し、管理者でなければ403で中断する
+func (c *ClassUserController) requireClassAdmin(ctx *gin.Context, cid uint) bool {
+	role, err := c.classUserService.GetRole(ctx.Request.Context(), ctx.GetUint("userID"), cid)
+	if err != nil || role != "ADMIN" {
+		abortWithError(ctx, utils.NewForbiddenError(constants.ErrCodeForbidden, constants.Forbidden))
+		return false
+	}
+	return true
+}
+
+// StreamClassEvents godoc
+// @Summary クラスのイベントを購読
+// @Description メンバーのロール変更(role_changed)などのクラスのイベントをServer-Sent Eventsで配信します。イベント名はtypeと同じです。クラスの管理者のみ購読できます。
+// @Tags Class User
+// @Produce text/event-stream
+// @Param cid path int true "クラスID"
+// @Success 200 {object} dto.ClassEventDTO "イベント"
+// @Failure 400 {object} utils.ErrorResponse "invalid_request"
+// @Failure 403 {object} utils.ErrorResponse "forbidden"
+// @Failure 500 {object} utils.ErrorResponse "internal_error"
+// @Router /cl/{cid}/events/stream [get]
+// @Security Bearer
+func (c *ClassUserController) StreamClassEvents(ctx *gin.Context) {
+	cid, err := strconv.ParseUint(ctx.Param("cid"), 10, 32)
+	if err != nil {
+		abortWithError(ctx, utils.NewBadRequestError(constants.ErrCodeInvalidRequest, "Invalid Class ID"))
+		return
+	}
+
+	events, err := c.classUserService.SubscribeClassEvents(ctx.Request.Context(), ctx.GetUint("userID"), uint(cid))
+	if err != nil {
+		if errors.Is(err, services.ErrUnauthorized) {
+			abortWithError(ctx, utils.NewForbiddenError(constants.ErrCodeForbidden, constants.Forbidden))
+			return
+		}
+		abortWithError(ctx, utils.NewInternalError(err))
+		return
+	}
+
+	ctx.Header("Cache-Control", "no-cache")
+	ctx.Stream(func(w io.Writer) bool {
+		// クライアントが切断するとサービス側で購読を解除し、チャンネルが閉じられる
+		event, ok := <-events
+		if !ok {
+			return false
+		}
+		ctx.SSEvent(event.Type, event)
+		return true
+	})
+}
@@ -0,0 +1,276 @@
+package controllers
+
+import (
+	"errors"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/constants"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// CurriculumController カリキュラムと学習進捗のコントローラ
+type CurriculumController struct {
+	curriculumService services.CurriculumService
+}
+
+// NewCurriculumController CurriculumControllerを生成
+func NewCurriculumController(curriculumService services.CurriculumService) *CurriculumController {
+	return &CurriculumController{
+		curriculumService: curriculumService,
+	}
+}
+
+// GetItems godoc
+// @Summary カリキュラムの項目一覧
+// @Description クラスのカリキュラムの項目を表示順に取得します。ログインユーザーが完了した項目には完了日時が付きます。クラスのメンバーのみ利用できます。
+// @Tags Curriculum
+// @Produce json
+// @Param cid path int true "Class ID"
+// @Success 200 {array} dto.CurriculumItemDTO "カリキュラムの項目"
+// @Failure 400 {object} utils.ErrorResponse "無効なリクエスト"
+// @Failure 403 {object} utils.ErrorResponse "クラスのメンバーではありません"
+// @Failure 500 {object} utils.ErrorResponse "サーバーエラーが発生しました"
+// @Router /curriculum/{cid}/items [get]
+// @Security Bearer
+func (c *CurriculumController) GetItems(ctx *gin.Context) {
+	cid, ok := parseCommentParam(ctx, "cid")
+	if !ok {
+		return
+	}
+
+	items, err := c.curriculumService.GetItems(ctx.Request.Context(), ctx.GetUint("userID"), cid)
+	if err != nil {
+		abortWithCurriculumError(ctx, err)
+		return
+	}
+	respondWithSuccess(ctx, constants.StatusOK, items)
+}
+
+// CreateItem godoc
+// @Summary カリキュラムに項目を追加
+// @Description カリキュラムに項目を追加します。表示順を省略した場合は最後に追加します。クラスの管理者とアシスタントのみ利用できます。
+// @Tags Curriculum
+// @Accept json
+// @Produce json
+// @Param cid path int true "Class ID"
+// @Param request body dto.CurriculumItemRequest true "項目"
+// @Success 201 {object} dto.CurriculumItemDTO "作成した項目"
+// @Failure 400 {object} utils.ErrorResponse "無効なリクエスト"
+// @Failure 403 {object} utils.ErrorResponse "権限がありません"
+// @Failure 500 {object} utils.ErrorResponse "サーバーエラーが発生しました"
+// @Router /curriculum/{cid}/items [post]
+// @Security Bearer
+func (c *CurriculumController) CreateItem(ctx *gin.Context) {
+	cid, ok := parseCommentParam(ctx, "cid")
+	if !ok {
+		return
+	}
+
+	var request dto.CurriculumItemRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		abortWithError(ctx, newBindingError(err))
+		return
+	}
+
+	item, err := c.curriculumService.CreateItem(ctx.Request.Context(), ctx.GetUint("userID"), cid, request)
+	if err != nil {
+		abortWithCurriculumError(ctx, err)
+		return
+	}
+	respondWithSuccess(ctx, constants.StatusCreated, item)
+}
+
+// UpdateItem godoc
+// @Summary カリキュラムの項目を更新
+// @Description 項目の名前と表示順を更新します。表示順を省略した場合は変更しません。クラスの管理者とアシスタントのみ利用できます。
+// @Tags Curriculum
+// @Accept json
+// @Produce json
+// @Param cid path int true "Class ID"
+// @Param itemID path int true "項目ID"
+// @Param request body dto.CurriculumItemRequest true "項目"
+// @Success 200 {object} dto.CurriculumItemDTO "更新した項目"
+// @Failure 400 {object} utils.ErrorResponse "無効なリクエスト"
+// @Failure 403 {object} utils.ErrorResponse "権限がありません"
+// @Failure 404 {object} utils.ErrorResponse "項目が見つかりません"
+// @Failure 500 {object} utils.ErrorResponse "サーバーエラーが発生しました"
+// @Router /curriculum/{cid}/items/{itemID} [patch]
+// @Security Bearer
+func (c *CurriculumController) UpdateItem(ctx *gin.Context) {
+	cid, itemID, ok := parseCurriculumParams(ctx)
+	if !ok {
+		return
+	}
+
+	var request dto.CurriculumItemRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		abortWithError(ctx, newBindingError(err))
+		return
+	}
+
+	item, err := c.curriculumService.UpdateItem(ctx.Request.Context(), ctx.GetUint("userID"), cid, itemID, request)
+	if err != nil {
+		abortWithCurriculumError(ctx, err)
+		return
+	}
+	respondWithSuccess(ctx, constants.StatusOK, item)
+}
+
+// DeleteItem godoc
+// @Summary カリキュラムの項目を削除
+// @Description 項目を削除します。生徒の完了の記録も削除されます。クラスの管理者とアシスタントのみ利用できます。
+// @Tags Curriculum
+// @Param cid path int true "Class ID"
+// @Param itemID path int true "項目ID"
+// @Success 200 {string} string "削除成功"
+// @Failure 400 {object} utils.ErrorResponse "無効なリクエスト"
+// @Failure 403 {object} utils.ErrorResponse "権限がありません"
+// @Failure 404 {object} utils.ErrorResponse "項目が見つかりません"
+// @Failure 500 {object} utils.ErrorResponse "サーバーエラーが発生しました"
+// @Router /curriculum/{cid}/items/{itemID} [delete]
+// @Security Bearer
+func (c *CurriculumController) DeleteItem(ctx *gin.Context) {
+	cid, itemID, ok := parseCurriculumParams(ctx)
+	if !ok {
+		return
+	}
+
+	if err := c.curriculumService.DeleteItem(ctx.Request.Context(), ctx.GetUint("userID"), cid, itemID); err != nil {
+		abortWithCurriculumError(ctx, err)
+		return
+	}
+	respondWithSuccess(ctx, constants.StatusOK, constants.DeleteSuccess)
+}
+
+// CompleteItem godoc
+// @Summary カリキュラムの項目を完了にする
+// @Description ログインユーザーが項目を完了したことを記録します。完了済みの場合は最初に完了した日時のままにします。クラスの生徒のみ利用できます。
+// @Tags Curriculum
+// @Param cid path int true "Class ID"
+// @Param itemID path int true "項目ID"
+// @Success 200 {string} string "成功"
+// @Failure 400 {object} utils.ErrorResponse "無効なリクエスト"
+// @Failure 403 {object} utils.ErrorResponse "クラスの生徒ではありません"
+// @Failure 404 {object} utils.ErrorResponse "項目が見つかりません"
+// @Failure 500 {object} utils.ErrorResponse "サーバーエラーが発生しました"
+// @Router /curriculum/{cid}/items/{itemID}/complete [put]
+// @Security Bearer
+func (c *CurriculumController) CompleteItem(ctx *gin.Context) {
+	cid, itemID, ok := parseCurriculumParams(ctx)
+	if !ok {
+		return
+	}
+
+	if err := c.curriculumService.CompleteItem(ctx.Request.Context(), ctx.GetUint("userID"), cid, itemID); err != nil {
+		abortWithCurriculumError(ctx, err)
+		return
+	}
+	respondWithSuccess(ctx, constants.StatusOK, constants.Success)
+}
+
+// UncompleteItem godoc
+// @Summary カリキュラムの項目の完了を取り消す
+// @Description ログインユーザーの項目の完了の記録を削除します。クラスの生徒のみ利用できます。
+// @Tags Curriculum
+// @Param cid path int true "Class ID"
+// @Param itemID path int true "項目ID"
+// @Success 200 {string} string "成功"
+// @Failure 400 {object} utils.ErrorResponse "無効なリクエスト"
+// @Failure 403 {object} utils.ErrorResponse "クラスの生徒ではありません"
+// @Failure 404 {object} utils.ErrorResponse "項目が見つかりません"
+// @Failure 500 {object} utils.ErrorResponse "サーバーエラーが発生しました"
+// @Router /curriculum/{cid}/items/{itemID}/complete [delete]
+// @Security Bearer
+func (c *CurriculumController) UncompleteItem(ctx *gin.Context) {
+	cid, itemID, ok := parseCurriculumParams(ctx)
+	if !ok {
+		return
+	}
+
+	if err := c.curriculumService.UncompleteItem(ctx.Request.Context(), ctx.GetUint("userID"), cid, itemID); err != nil {
+		abortWithCurriculumError(ctx, err)
+		return
+	}
+	respondWithSuccess(ctx, constants.StatusOK, constants.Success)
+}
+
+// GetClassProgress godoc
+// @Summary クラス全体の学習進捗
+// @Description クラスの生徒ごとの進捗率と、項目ごとの完了率を取得します。クラスの管理者とアシスタントのみ利用できます。
+// @Tags Curriculum
+// @Produce json
+// @Param cid path int true "Class ID"
+// @Success 200 {object} dto.ClassCurriculumProgressDTO "クラス全体の進捗"
+// @Failure 400 {object} utils.ErrorResponse "無効なリクエスト"
+// @Failure 403 {object} utils.ErrorResponse "権限がありません"
+// @Failure 500 {object} utils.ErrorResponse "サーバーエラーが発生しました"
+// @Router /curriculum/{cid}/progress [get]
+// @Security Bearer
+func (c *CurriculumController) GetClassProgress(ctx *gin.Context) {
+	cid, ok := parseCommentParam(ctx, "cid")
+	if !ok {
+		return
+	}
+
+	progress, err := c.curriculumService.GetClassProgress(ctx.Request.Context(), ctx.GetUint("userID"), cid)
+	if err != nil {
+		abortWithCurriculumError(ctx, err)
+		return
+	}
+	respondWithSuccess(ctx, constants.StatusOK, progress)
+}
+
+// GetUserProgress godoc
+// @Summary 生徒の学習進捗
+// @Description 生徒の進捗率と完了した項目を取得します。生徒本人とクラスの管理者・アシスタントのみ利用できます。
+// @Tags Curriculum
+// @Produce json
+// @Param cid path int true "Class ID"
+// @Param uid path int true "User ID"
+// @Success 200 {object} dto.UserCurriculumProgressDTO "生徒の進捗"
+// @Failure 400 {object} utils.ErrorResponse "無効なリクエスト"
+// @Failure 403 {object} utils.ErrorResponse "権限がありません"
+// @Failure 500 {object} utils.ErrorResponse "サーバーエラーが発生しました"
+// @Router /curriculum/{cid}/progress/{uid} [get]
+// @Security Bearer
+func (c *CurriculumController) GetUserProgress(ctx *gin.Context) {
+	cid, ok := parseCommentParam(ctx, "cid")
+	if !ok {
+		return
+	}
+	uid, ok := parseCommentParam(ctx, "uid")
+	if !ok {
+		return
+	}
+
+	progress, err := c.curriculumService.GetUserProgress(ctx.Request.Context(), ctx.GetUint("userID"), cid, uid)
+	if err != nil {
+		abortWithCurriculumError(ctx, err)
+		return
+	}
+	respondWithSuccess(ctx, constants.StatusOK, progress)
+}
+
+// parseCurriculumParams パスのクラスIDと項目IDを解析する。不正な場合は400を登録してfalseを返す
+func parseCurriculumParams(ctx *gin.Context) (uint, uint, bool) {
+	cid, ok := parseCommentParam(ctx, "cid")
+	if !ok {
+		return 0, 0, false
+	}
+	itemID, ok := parseCommentParam(ctx, "itemID")
+	if !ok {
+		return 0, 0, false
+	}
+	return cid, itemID, true
+}
+
+// abortWithCurriculumError 権限のエラーを403として登録し、それ以外はAppErrorに変換して登録する
+func abortWithCurriculumError(ctx *gin.Context, err error) {
+	if errors.Is(err, services.ErrUnauthorized) {
+		abortWithError(ctx, utils.NewForbiddenError(constants.ErrCodeForbidden, constants.Forbidden))
+		return
+	}
+	abortWithError(ctx, toAppError(err))
+}
@@ -0,0 +1,48 @@
+package controllers
+
+import (
+	"runtime"
+	"strings"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/constants"
+	"github.com/gin-gonic/gin"
+)
+
+// RuntimeStatsSource 実行時の統計情報を提供する
+type RuntimeStatsSource interface {
+	RuntimeStats() map[string]int64
+}
+
+// DebugController 運用時の調査用に実行時の状態を返すコントローラ
+type DebugController struct {
+	sources []RuntimeStatsSource
+}
+
+// NewDebugController DebugControllerを生成
+func NewDebugController(sources ...RuntimeStatsSource) *DebugController {
+	return &DebugController{sources: sources}
+}
+
+// GetRuntimeVars ゴルーチン数・メモリ使用量・SSEの接続数・チャットルーム数を返す。
+// *_sse_connectionsの合計をsse_connectionsとして返す
+func (dc *DebugController) GetRuntimeVars(ctx *gin.Context) {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	vars := map[string]int64{
+		"goroutines":       int64(runtime.NumGoroutine()),
+		"heap_alloc_bytes": int64(mem.HeapAlloc),
+		"heap_objects":     int64(mem.HeapObjects),
+		"num_gc":           int64(mem.NumGC),
+		"sse_connections":  0,
+	}
+	for _, source := range dc.sources {
+		for name, value := range source.RuntimeStats() {
+			vars[name] = value
+			if strings.HasSuffix(name, "_sse_connections") {
+				vars["sse_connections"] += value
+			}
+		}
+	}
+	ctx.JSON(constants.StatusOK, vars)
+}
@@ -0,0 +1,83 @@
+package controllers
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/constants"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// DigestController 毎朝のダイジェストのコントローラ
+type DigestController struct {
+	digestService services.DigestService
+}
+
+// NewDigestController DigestControllerを生成
+func NewDigestController(digestService services.DigestService) *DigestController {
+	return &DigestController{digestService: digestService}
+}
+
+// GetSetting godoc
+// @Summary 毎朝のダイジェストの設定を取得
+// @Description ログインユーザーの毎朝のダイジェストの設定を返します。設定していない場合は無効です。
+// @Tags Notification
+// @Produce json
+// @Success 200 {object} dto.DigestSettingDTO "ダイジェストの設定"
+// @Failure 500 {object} utils.ErrorResponse "サーバーエラーが発生しました"
+// @Router /notifications/digest [get]
+// @Security Bearer
+func (c *DigestController) GetSetting(ctx *gin.Context) {
+	setting, err := c.digestService.GetSetting(ctx.Request.Context(), ctx.GetUint("userID"))
+	if err != nil {
+		abortWithError(ctx, toAppError(err))
+		return
+	}
+	respondWithSuccess(ctx, constants.StatusOK, setting)
+}
+
+// UpdateSetting godoc
+// @Summary 毎朝のダイジェストの設定を変更
+// @Description 前日のクラスの新しい掲示・スケジュールの変更・未読のチャットと今日の授業のまとめを、毎朝アプリ内通知で受け取るかどうかを設定します。emailをtrueにするとメールでも受け取ります。
+// @Tags Notification
+// @Accept json
+// @Produce json
+// @Param request body dto.DigestSettingDTO true "ダイジェストの設定"
+// @Success 200 {object} dto.DigestSettingDTO "変更後の設定"
+// @Failure 400 {object} utils.ErrorResponse "無効なリクエスト"
+// @Failure 500 {object} utils.ErrorResponse "サーバーエラーが発生しました"
+// @Router /notifications/digest [put]
+// @Security Bearer
+func (c *DigestController) UpdateSetting(ctx *gin.Context) {
+	var request dto.DigestSettingDTO
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		abortWithError(ctx, newBindingError(err))
+		return
+	}
+
+	setting, err := c.digestService.UpdateSetting(ctx.Request.Context(), ctx.GetUint("userID"), request)
+	if err != nil {
+		abortWithError(ctx, toAppError(err))
+		return
+	}
+	respondWithSuccess(ctx, constants.StatusOK, setting)
+}
+
+// DryRun 調査用に、指定したユーザーの今日のダイジェストを集計して返す。通知とメールは送信しない
+func (c *DigestController) DryRun(ctx *gin.Context) {
+	uid, err := strconv.ParseUint(ctx.Param("uid"), 10, 32)
+	if err != nil {
+		abortWithError(ctx, utils.NewBadRequestError(constants.ErrCodeInvalidRequest, constants.InvalidRequest))
+		return
+	}
+
+	result, err := c.digestService.DryRun(ctx.Request.Context(), uint(uid), time.Now())
+	if err != nil {
+		abortWithError(ctx, utils.NewInternalError(err))
+		return
+	}
+	ctx.JSON(constants.StatusOK, result)
+}
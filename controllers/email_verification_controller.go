@@ -0,0 +1,94 @@
+package controllers
+
+import (
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/constants"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"github.com/gin-gonic/gin"
+)
+
+// EmailVerificationController メールアドレスの検証のコントローラ
+type EmailVerificationController struct {
+	emailVerificationService services.EmailVerificationService
+}
+
+// NewEmailVerificationController EmailVerificationControllerを生成
+func NewEmailVerificationController(emailVerificationService services.EmailVerificationService) *EmailVerificationController {
+	return &EmailVerificationController{emailVerificationService: emailVerificationService}
+}
+
+// GetEmailStatus godoc
+// @Summary メールアドレスの検証の状態を取得
+// @Description ログインユーザーのメールアドレスと、検証済みかどうかを返します。未検証のアドレスにはメールの通知を送信しません。
+// @Tags User
+// @Produce json
+// @Success 200 {object} dto.EmailStatusDTO "メールアドレスと検証の状態"
+// @Failure 500 {object} utils.ErrorResponse "サーバーエラーが発生しました"
+// @Router /u/email [get]
+// @Security Bearer
+func (c *EmailVerificationController) GetEmailStatus(ctx *gin.Context) {
+	status, err := c.emailVerificationService.GetEmailStatus(ctx.Request.Context(), ctx.GetUint("userID"))
+	if err != nil {
+		abortWithError(ctx, toAppError(err))
+		return
+	}
+	respondWithSuccess(ctx, constants.StatusOK, status)
+}
+
+// SendVerification godoc
+// @Summary 検証メールを送信
+// @Description メールアドレスを確認するリンクを送信します。emailを指定した場合はそのアドレスに送信し、確認が完了した時点で登録するアドレスを変更します。リンクの有効期限は24時間で、再送すると以前のリンクは無効になります。再送は1分に1回、1日5回までです。
+// @Tags User
+// @Accept json
+// @Produce json
+// @Param request body dto.EmailVerificationRequestDTO false "検証するメールアドレス"
+// @Success 200 {object} map[string]interface{} "検証メールを送信しました"
+// @Failure 400 {object} utils.ErrorResponse "無効なリクエスト"
+// @Failure 409 {object} utils.ErrorResponse "このメールアドレスは他のアカウントで検証済みです"
+// @Failure 422 {object} utils.ErrorResponse "メールアドレスが登録されていません"
+// @Failure 429 {object} utils.ErrorResponse "検証メールの送信が多すぎます"
+// @Failure 503 {object} utils.ErrorResponse "現在メールアドレスの検証は利用できません"
+// @Router /u/email/verification [post]
+// @Security Bearer
+func (c *EmailVerificationController) SendVerification(ctx *gin.Context) {
+	var request dto.EmailVerificationRequestDTO
+	if ctx.Request.ContentLength != 0 {
+		if err := ctx.ShouldBindJSON(&request); err != nil {
+			abortWithError(ctx, newBindingError(err))
+			return
+		}
+	}
+
+	if err := c.emailVerificationService.SendVerification(ctx.Request.Context(), ctx.GetUint("userID"), request); err != nil {
+		abortWithError(ctx, toAppError(err))
+		return
+	}
+	respondWithSuccess(ctx, constants.StatusOK, constants.MessageSent)
+}
+
+// VerifyEmail godoc
+// @Summary メールアドレスを検証
+// @Description 検証メールのリンクに含まれるトークンでメールアドレスを検証します。リンクを別の端末で開く場合があるため、ログインは不要です。トークンは検証に成功すると無効になります。他のアカウントで検証済みのアドレスは検証できません。
+// @Tags User
+// @Accept json
+// @Produce json
+// @Param request body dto.EmailVerifyDTO true "検証のトークン"
+// @Success 200 {object} dto.EmailStatusDTO "検証後のメールアドレス"
+// @Failure 400 {object} utils.ErrorResponse "検証のリンクが正しくないか、有効期限が切れています"
+// @Failure 409 {object} utils.ErrorResponse "このメールアドレスは他のアカウントで検証済みです"
+// @Failure 503 {object} utils.ErrorResponse "現在メールアドレスの検証は利用できません"
+// @Router /u/email/verify [post]
+func (c *EmailVerificationController) VerifyEmail(ctx *gin.Context) {
+	var request dto.EmailVerifyDTO
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		abortWithError(ctx, newBindingError(err))
+		return
+	}
+
+	status, err := c.emailVerificationService.VerifyEmail(ctx.Request.Context(), request.Token)
+	if err != nil {
+		abortWithError(ctx, toAppError(err))
+		return
+	}
+	respondWithSuccess(ctx, constants.StatusOK, status)
+}
@@ -0,0 +1,49 @@
+package controllers
+
+import (
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/constants"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"github.com/gin-gonic/gin"
+)
+
+// EventController 他のサービス向けの内部イベントのコントローラ
+type EventController struct {
+	eventBus services.EventBus
+}
+
+// NewEventController EventControllerを生成
+func NewEventController(eventBus services.EventBus) *EventController {
+	return &EventController{
+		eventBus: eventBus,
+	}
+}
+
+// Replay godoc
+// @Summary 内部イベントの再取得
+// @Description Redisのチャンネルで受け取れなかった内部イベントを古い順に返します。afterには最後に受け取ったイベントのIDを指定し、レスポンスのnextを次のafterに指定すると続きを取得できます。ストリームには上限を超えた古いイベントは残りません。X-API-Keyヘッダーに他のサービス用のAPIキーを指定して呼び出します。
+// @Tags Events
+// @Produce json
+// @Param X-API-Key header string true "他のサービス用のAPIキー"
+// @Param after query string false "最後に受け取ったイベントのID"
+// @Param limit query int false "取得する件数 (最大1000、既定値100)"
+// @Success 200 {object} dto.EventReplayDTO "再取得したイベント"
+// @Failure 400 {object} utils.ErrorResponse "無効なリクエスト"
+// @Failure 401 {object} utils.ErrorResponse "APIキーが正しくありません"
+// @Failure 500 {object} utils.ErrorResponse "サーバーエラーが発生しました"
+// @Failure 503 {object} utils.ErrorResponse "イベントの配信は利用できません"
+// @Router /events/replay [get]
+func (c *EventController) Replay(ctx *gin.Context) {
+	var query dto.EventReplayQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		abortWithError(ctx, newBindingError(err))
+		return
+	}
+
+	result, err := c.eventBus.Replay(ctx.Request.Context(), query.After, query.Limit)
+	if err != nil {
+		abortWithError(ctx, toAppError(err))
+		return
+	}
+	respondWithSuccess(ctx, constants.StatusOK, result)
+}
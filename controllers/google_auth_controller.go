@@ -3,38 +3,45 @@ package controllers
 import (
 	"encoding/json"
 	"fmt"
+	"log"
 	"strings"
 
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/constants"
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 )
 
 type GoogleAuthController struct {
 	Service    services.GoogleAuthService
 	JWTService services.JWTService
+	// CalendarSync ログインで許可されたカレンダーの権限を保存する。nilの場合はカレンダーの権限を求めない
+	CalendarSync services.CalendarSyncService
 }
 
-func NewGoogleAuthController(service services.GoogleAuthService, jwtService services.JWTService) *GoogleAuthController {
+func NewGoogleAuthController(service services.GoogleAuthService, jwtService services.JWTService, calendarSync services.CalendarSyncService) *GoogleAuthController {
 	return &GoogleAuthController{
-		Service:    service,
-		JWTService: jwtService,
+		Service:      service,
+		JWTService:   jwtService,
+		CalendarSync: calendarSync,
 	}
 }
 
 // GoogleLoginHandler godoc
 // @Summary Googleのログインページへリダイレクト
-// @Description ユーザーをGoogleのログインページへリダイレクトして認証を行います。
+// @Description ユーザーをGoogleのログインページへリダイレクトして認証を行います。calendarがtrueでカレンダーの同期が有効な場合は、スケジュールを同期するためのカレンダーの権限も求めます。
 // @Tags GoogleAuth
 // @ID google-login-handler
 // @Produce html
+// @Param calendar query bool false "Googleカレンダーへのスケジュールの同期を許可する"
 // @Success 302 "Googleのログインページへのリダイレクト"
 // @Router /auth/google/login [get]
 func (controller *GoogleAuthController) GoogleLoginHandler(c *gin.Context) {
 	oauthStateString := controller.Service.GenerateStateOauthCookie(c.Writer)
 
-	url := controller.Service.OauthConfig().AuthCodeURL(oauthStateString)
+	withCalendar := c.Query("calendar") == "true" && controller.CalendarSync != nil && controller.CalendarSync.Enabled()
+	url := controller.Service.AuthCodeURL(oauthStateString, withCalendar)
 	respondWithSuccess(c, constants.StatusOK, gin.H{"url": url})
 }
 
@@ -60,7 +67,7 @@ func (controller *GoogleAuthController) ProcessAuthCode(c *gin.Context) {
 		return
 	}
 
-	userInfo, err := controller.Service.GetGoogleUserInfo(authCode)
+	userInfo, token, err := controller.Service.GetGoogleUserInfo(authCode)
 	if err != nil {
 		handleServiceError(c, err)
 		return
@@ -72,12 +79,19 @@ func (controller *GoogleAuthController) ProcessAuthCode(c *gin.Context) {
 		return
 	}
 
-	user, err := controller.Service.UpdateOrCreateUser(userInput)
+	user, err := controller.Service.UpdateOrCreateUser(c.Request.Context(), userInput)
 	if err != nil {
 		handleServiceError(c, err)
 		return
 	}
 
+	if controller.CalendarSync != nil {
+		// カレンダーの権限を保存できなくてもログインは続け、同期は再度ログインした際に有効にする
+		if err := controller.CalendarSync.Connect(c.Request.Context(), user.ID, token); err != nil {
+			log.Printf("Failed to save google calendar token of uid %d: %v", user.ID, err)
+		}
+	}
+
 	accessToken, err := controller.JWTService.GenerateToken(user.ID)
 	if err != nil {
 		handleServiceError(c, err)
@@ -111,22 +125,27 @@ func (controller *GoogleAuthController) ProcessAuthCode(c *gin.Context) {
 // @Success 200 {object} map[string]interface{} "アクセストークンと有効期限が返されます"
 // @Failure 400 {object} map[string]interface{} "JSON形式が不正、またはリフレッシュトークンが提供されていない場合のエラー"
 // @Failure 401 {object} map[string]interface{} "リフレッシュトークンが無効または期限切れの場合の認証エラー"
+// @Failure 415 {object} map[string]interface{} "Content-Typeがapplication/jsonではない場合のエラー"
 // @Failure 500 {object} map[string]interface{} "未処理のエラーによる内部サーバーエラー"
 // @Router /auth/google/refresh-token [post]
 func (controller *GoogleAuthController) RefreshAccessTokenHandler(c *gin.Context) {
-	var requestBody map[string]string
-	if err := c.BindJSON(&requestBody); err != nil {
-		handleServiceError(c, fmt.Errorf("Invalid JSON format or structure: %v", err))
+	// Content-Typeがない場合は本文を読まずに空のリクエストとして扱われるため、先に拒否する
+	if c.ContentType() != binding.MIMEJSON {
+		respondWithError(c, constants.StatusUnsupportedMedia, constants.ContentTypeNotJSON)
 		return
 	}
 
-	refreshToken, ok := requestBody["refresh_token"]
-	if !ok {
-		handleServiceError(c, fmt.Errorf("Refresh token is required"))
+	var requestBody map[string]string
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		respondWithError(c, constants.StatusBadRequest, constants.InvalidRequest)
 		return
 	}
 
-	refreshToken = strings.TrimSpace(strings.TrimPrefix(refreshToken, "Bearer "))
+	refreshToken := strings.TrimSpace(strings.TrimPrefix(requestBody["refresh_token"], "Bearer "))
+	if refreshToken == "" {
+		respondWithError(c, constants.StatusBadRequest, constants.RefreshTokenRequired)
+		return
+	}
 
 	tokenDetails, err := controller.JWTService.RefreshAccessToken(refreshToken)
 	if err != nil {
@@ -1,7 +1,12 @@
 package controllers
 
 import (
+	"context"
+	"errors"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/constants"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/utils"
 	"github.com/gin-gonic/gin"
 	"net/http"
 	"strconv"
@@ -43,7 +48,7 @@ func (ctrl *LiveClassController) StartScreenShare(c *gin.Context) {
 	cid, _ := strconv.ParseUint(c.Param("cid"), 10, 64)
 
 	// 스트리밍 서비스 API 호출을 통해 실제 스트리밍 URL 생성
-	streamURL, err := ctrl.liveClassService.StartStreamingSession(uint(cid))
+	streamURL, err := ctrl.liveClassService.StartStreamingSession(c.GetUint("userID"), uint(cid))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start streaming session: " + err.Error()})
 		return
@@ -63,3 +68,143 @@ func (ctrl *LiveClassController) StartScreenShare(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"message": "Screen sharing started successfully", "streamURL": streamURL})
 }
+
+// ReportQualityStats godoc
+// @Summary 接続品質の統計を送信
+// @Description 視聴者が遅延、パケットロス、ビットレートなどの接続品質の統計を定期的に送信します。統計はログイン中のユーザー本人のものとして集計します。クラスのメンバーのみ送信できます。
+// @Tags Live Class
+// @Accept  json
+// @Produce  json
+// @Param roomID path int true "ルームID(クラスID)"
+// @Param report body dto.ConnectionQualityReportDTO true "接続品質の統計"
+// @Success 200 {object} string "成功"
+// @Failure 400 {object} utils.ErrorResponse "無効なリクエストです"
+// @Failure 403 {object} utils.ErrorResponse "クラスのメンバーではありません"
+// @Router /live/quality/{roomID} [post]
+// @Security Bearer
+func (ctrl *LiveClassController) ReportQualityStats(c *gin.Context) {
+	cid, ok := parseCommentParam(c, "roomID")
+	if !ok {
+		return
+	}
+	var report dto.ConnectionQualityReportDTO
+	if err := c.ShouldBindJSON(&report); err != nil {
+		respondWithError(c, constants.StatusBadRequest, constants.InvalidRequest)
+		return
+	}
+
+	if err := ctrl.liveClassService.ReportQualityStats(c.Request.Context(), c.GetUint("userID"), cid, report); err != nil {
+		abortWithSpeakPermissionError(c, err)
+		return
+	}
+	respondWithSuccess(c, constants.StatusOK, constants.Success)
+}
+
+// GetRoomQualityStats godoc
+// @Summary ルームの接続品質の統計を取得
+// @Description 講師がルーム内の各視聴者の接続品質の統計を確認します。クラスの管理者とアシスタントのみ利用できます。
+// @Tags Live Class
+// @Accept  json
+// @Produce  json
+// @Param roomID path int true "ルームID(クラスID)"
+// @Success 200 {array} dto.ViewerQualityStatsDTO "視聴者ごとの接続品質の統計"
+// @Failure 400 {object} utils.ErrorResponse "無効なリクエストです"
+// @Failure 403 {object} utils.ErrorResponse "講師ではありません"
+// @Router /live/quality/{roomID} [get]
+// @Security Bearer
+func (ctrl *LiveClassController) GetRoomQualityStats(c *gin.Context) {
+	cid, ok := parseCommentParam(c, "roomID")
+	if !ok {
+		return
+	}
+	stats, err := ctrl.liveClassService.GetRoomQualityStats(c.Request.Context(), c.GetUint("userID"), cid)
+	if err != nil {
+		abortWithSpeakPermissionError(c, err)
+		return
+	}
+	respondWithSuccess(c, constants.StatusOK, stats)
+}
+
+// GetSpeakPermissions godoc
+// @Summary ライブ授業の発言権を取得
+// @Description ライブ授業で発言権を持つ生徒のIDを取得します。講師(管理者とアシスタント)は常に発言できるため含まれません。
+// @Tags Live Class
+// @Produce  json
+// @Param cid path int true "クラスID"
+// @Success 200 {object} dto.SpeakPermissionsDTO "発言権を持つ生徒"
+// @Failure 400 {object} utils.ErrorResponse "無効なリクエストです"
+// @Failure 403 {object} utils.ErrorResponse "クラスのメンバーではありません"
+// @Router /live/speakers/{cid} [get]
+// @Security Bearer
+func (ctrl *LiveClassController) GetSpeakPermissions(c *gin.Context) {
+	cid, ok := parseCommentParam(c, "cid")
+	if !ok {
+		return
+	}
+	permissions, err := ctrl.liveClassService.GetSpeakPermissions(c.Request.Context(), c.GetUint("userID"), cid)
+	if err != nil {
+		abortWithSpeakPermissionError(c, err)
+		return
+	}
+	respondWithSuccess(c, constants.StatusOK, permissions)
+}
+
+// GrantSpeakPermission godoc
+// @Summary 生徒に発言権を付与
+// @Description 講師が挙手した生徒を指名して発言権(音声)を付与します。変更はWebSocketでクラスを購読中の参加者にspeak_permissionとして配信されます。
+// @Tags Live Class
+// @Produce  json
+// @Param cid path int true "クラスID"
+// @Param uid path int true "発言権を付与する生徒のID"
+// @Success 200 {object} dto.SpeakPermissionsDTO "変更後の発言権を持つ生徒"
+// @Failure 400 {object} utils.ErrorResponse "無効なリクエストです"
+// @Failure 403 {object} utils.ErrorResponse "講師ではありません"
+// @Failure 404 {object} utils.ErrorResponse "生徒がクラスのメンバーではありません"
+// @Router /live/speakers/{cid}/{uid} [put]
+// @Security Bearer
+func (ctrl *LiveClassController) GrantSpeakPermission(c *gin.Context) {
+	ctrl.changeSpeakPermission(c, ctrl.liveClassService.GrantSpeakPermission)
+}
+
+// RevokeSpeakPermission godoc
+// @Summary 生徒の発言権を剥奪
+// @Description 講師が生徒の発言権(音声)を剥奪します。変更はWebSocketでクラスを購読中の参加者にspeak_permissionとして配信されます。
+// @Tags Live Class
+// @Produce  json
+// @Param cid path int true "クラスID"
+// @Param uid path int true "発言権を剥奪する生徒のID"
+// @Success 200 {object} dto.SpeakPermissionsDTO "変更後の発言権を持つ生徒"
+// @Failure 400 {object} utils.ErrorResponse "無効なリクエストです"
+// @Failure 403 {object} utils.ErrorResponse "講師ではありません"
+// @Router /live/speakers/{cid}/{uid} [delete]
+// @Security Bearer
+func (ctrl *LiveClassController) RevokeSpeakPermission(c *gin.Context) {
+	ctrl.changeSpeakPermission(c, ctrl.liveClassService.RevokeSpeakPermission)
+}
+
+// changeSpeakPermission パスのcidとuidで発言権を変更し、変更後の発言権を返す
+func (ctrl *LiveClassController) changeSpeakPermission(c *gin.Context, change func(ctx context.Context, actorUID uint, cid uint, uid uint) (*dto.SpeakPermissionsDTO, error)) {
+	cid, ok := parseCommentParam(c, "cid")
+	if !ok {
+		return
+	}
+	uid, ok := parseCommentParam(c, "uid")
+	if !ok {
+		return
+	}
+	permissions, err := change(c.Request.Context(), c.GetUint("userID"), cid, uid)
+	if err != nil {
+		abortWithSpeakPermissionError(c, err)
+		return
+	}
+	respondWithSuccess(c, constants.StatusOK, permissions)
+}
+
+// abortWithSpeakPermissionError 発言権や接続品質の統計の権限のエラーを403として登録し、それ以外はAppErrorに変換して登録する
+func abortWithSpeakPermissionError(c *gin.Context, err error) {
+	if errors.Is(err, services.ErrUnauthorized) {
+		abortWithError(c, utils.NewForbiddenError(constants.ErrCodeForbidden, constants.Forbidden))
+		return
+	}
+	abortWithError(c, toAppError(err))
+}
@@ -0,0 +1,58 @@
+package controllers
+
+import (
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/constants"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// SystemEventTarget SSEで接続中のクライアントにシステムからの通知を送る
+type SystemEventTarget interface {
+	BroadcastSystemEvent(event services.SystemEvent)
+}
+
+// MaintenanceController メンテナンスモードを確認・変更するコントローラ
+type MaintenanceController struct {
+	maintenanceService services.MaintenanceService
+}
+
+// NewMaintenanceController MaintenanceControllerを生成
+func NewMaintenanceController(maintenanceService services.MaintenanceService) *MaintenanceController {
+	return &MaintenanceController{maintenanceService: maintenanceService}
+}
+
+// GetMaintenance 現在のメンテナンスモードを返す
+func (mc *MaintenanceController) GetMaintenance(ctx *gin.Context) {
+	state, err := mc.maintenanceService.Refresh(ctx.Request.Context())
+	if err != nil {
+		abortWithError(ctx, utils.NewInternalError(err))
+		return
+	}
+	ctx.JSON(constants.StatusOK, state)
+}
+
+// SetMaintenance メンテナンスモードを変更する。他のインスタンスには数秒以内に反映される
+func (mc *MaintenanceController) SetMaintenance(ctx *gin.Context) {
+	var request dto.SetMaintenanceRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		abortWithError(ctx, newBindingError(err))
+		return
+	}
+	mode, ok := services.ParseMaintenanceMode(request.Mode)
+	if !ok {
+		abortWithError(ctx, utils.NewBadRequestError(constants.ErrCodeInvalidRequest, constants.InvalidRequest).
+			WithDetails(map[string]interface{}{"mode": request.Mode}))
+		return
+	}
+
+	state, err := mc.maintenanceService.SetMode(ctx.Request.Context(), mode, time.Duration(request.DurationSeconds)*time.Second)
+	if err != nil {
+		abortWithError(ctx, utils.NewInternalError(err))
+		return
+	}
+	ctx.JSON(constants.StatusOK, state)
+}
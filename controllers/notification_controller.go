@@ -0,0 +1,218 @@
+package controllers
+
+import (
+	"strconv"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/constants"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// NotificationController アプリ内通知のコントローラ
+type NotificationController struct {
+	notificationService services.NotificationService
+}
+
+// NewNotificationController NotificationControllerを生成
+func NewNotificationController(notificationService services.NotificationService) *NotificationController {
+	return &NotificationController{
+		notificationService: notificationService,
+	}
+}
+
+// GetNotifications godoc
+// @Summary 通知の一覧を取得
+// @Description ログインユーザーのアプリ内通知を新しい順に取得します。次のページはbeforeに前のページの最後の通知のIDを指定して取得します。
+// @Tags Notification
+// @Produce json
+// @Param unread query bool false "未読の通知のみ取得する" default(false)
+// @Param before query int false "このIDより前の通知を取得する"
+// @Param limit query int false "取得する件数" default(20)
+// @Success 200 {array} dto.NotificationDTO "通知の一覧"
+// @Failure 400 {object} utils.ErrorResponse "無効なリクエスト"
+// @Failure 500 {object} utils.ErrorResponse "サーバーエラーが発生しました"
+// @Router /notifications [get]
+// @Security Bearer
+func (c *NotificationController) GetNotifications(ctx *gin.Context) {
+	unreadOnly, err := strconv.ParseBool(ctx.DefaultQuery("unread", "false"))
+	if err != nil {
+		abortWithError(ctx, utils.NewBadRequestError(constants.ErrCodeInvalidRequest, constants.InvalidRequest))
+		return
+	}
+	before, err := strconv.ParseUint(ctx.DefaultQuery("before", "0"), 10, 32)
+	if err != nil {
+		abortWithError(ctx, utils.NewBadRequestError(constants.ErrCodeInvalidRequest, constants.InvalidRequest))
+		return
+	}
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", "20"))
+	if err != nil || limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	notifications, err := c.notificationService.GetNotifications(ctx.Request.Context(), ctx.GetUint("userID"), unreadOnly, uint(before), limit)
+	if err != nil {
+		abortWithError(ctx, toAppError(err))
+		return
+	}
+	respondWithSuccess(ctx, constants.StatusOK, notifications)
+}
+
+// GetUnreadCount godoc
+// @Summary 未読の通知の件数を取得
+// @Description ログインユーザーの未読のアプリ内通知の件数を返します。バッジの表示のための定期的な取得に使います。
+// @Tags Notification
+// @Produce json
+// @Success 200 {object} dto.NotificationUnreadCountDTO "未読の件数"
+// @Failure 500 {object} utils.ErrorResponse "サーバーエラーが発生しました"
+// @Router /notifications/unread-count [get]
+// @Security Bearer
+func (c *NotificationController) GetUnreadCount(ctx *gin.Context) {
+	count, err := c.notificationService.GetUnreadCount(ctx.Request.Context(), ctx.GetUint("userID"))
+	if err != nil {
+		abortWithError(ctx, toAppError(err))
+		return
+	}
+	respondWithSuccess(ctx, constants.StatusOK, dto.NotificationUnreadCountDTO{Unread: count})
+}
+
+// MarkRead godoc
+// @Summary 通知を既読にする
+// @Description ログインユーザーの通知を既読にします。既読の通知を指定した場合は何もしません。
+// @Tags Notification
+// @Produce json
+// @Param id path int true "通知ID"
+// @Success 200 {string} string "成功"
+// @Failure 400 {object} utils.ErrorResponse "無効なリクエスト"
+// @Failure 404 {object} utils.ErrorResponse "通知が見つかりません"
+// @Failure 500 {object} utils.ErrorResponse "サーバーエラーが発生しました"
+// @Router /notifications/{id}/read [patch]
+// @Security Bearer
+func (c *NotificationController) MarkRead(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		abortWithError(ctx, utils.NewBadRequestError(constants.ErrCodeInvalidRequest, constants.InvalidRequest))
+		return
+	}
+
+	if err := c.notificationService.MarkRead(ctx.Request.Context(), ctx.GetUint("userID"), uint(id)); err != nil {
+		abortWithError(ctx, toAppError(err))
+		return
+	}
+	respondWithSuccess(ctx, constants.StatusOK, constants.Success)
+}
+
+// MarkAllRead godoc
+// @Summary 全ての通知を既読にする
+// @Description ログインユーザーの未読の通知を全て既読にし、既読にした件数を返します。
+// @Tags Notification
+// @Produce json
+// @Success 200 {object} dto.NotificationReadAllDTO "既読にした件数"
+// @Failure 500 {object} utils.ErrorResponse "サーバーエラーが発生しました"
+// @Router /notifications/read-all [patch]
+// @Security Bearer
+func (c *NotificationController) MarkAllRead(ctx *gin.Context) {
+	updated, err := c.notificationService.MarkAllRead(ctx.Request.Context(), ctx.GetUint("userID"))
+	if err != nil {
+		abortWithError(ctx, toAppError(err))
+		return
+	}
+	respondWithSuccess(ctx, constants.StatusOK, dto.NotificationReadAllDTO{Updated: updated})
+}
+
+// RegisterDevice godoc
+// @Summary プッシュ通知を受け取る端末を登録
+// @Description ログインユーザーの端末のFCMの登録トークンを登録します。アプリの起動時とトークンの更新時に呼び出します。別のユーザーが登録したトークンはログインユーザーの端末として登録し直します。
+// @Tags Notification
+// @Accept json
+// @Produce json
+// @Param request body dto.DeviceTokenRequest true "端末"
+// @Success 200 {string} string "成功"
+// @Failure 400 {object} utils.ErrorResponse "無効なリクエスト"
+// @Failure 500 {object} utils.ErrorResponse "サーバーエラーが発生しました"
+// @Router /notifications/devices [post]
+// @Security Bearer
+func (c *NotificationController) RegisterDevice(ctx *gin.Context) {
+	var request dto.DeviceTokenRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		abortWithError(ctx, newBindingError(err))
+		return
+	}
+
+	if err := c.notificationService.RegisterDevice(ctx.Request.Context(), ctx.GetUint("userID"), request); err != nil {
+		abortWithError(ctx, toAppError(err))
+		return
+	}
+	respondWithSuccess(ctx, constants.StatusOK, constants.Success)
+}
+
+// UnregisterDevice godoc
+// @Summary 端末の登録を解除
+// @Description ログインユーザーの端末のFCMの登録トークンを削除し、プッシュ通知を送信しないようにします。ログアウト時に呼び出します。
+// @Tags Notification
+// @Accept json
+// @Produce json
+// @Param request body dto.DeviceTokenDeleteRequest true "端末"
+// @Success 200 {string} string "削除成功"
+// @Failure 400 {object} utils.ErrorResponse "無効なリクエスト"
+// @Failure 500 {object} utils.ErrorResponse "サーバーエラーが発生しました"
+// @Router /notifications/devices [delete]
+// @Security Bearer
+func (c *NotificationController) UnregisterDevice(ctx *gin.Context) {
+	var request dto.DeviceTokenDeleteRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		abortWithError(ctx, newBindingError(err))
+		return
+	}
+
+	if err := c.notificationService.UnregisterDevice(ctx.Request.Context(), ctx.GetUint("userID"), request.Token); err != nil {
+		abortWithError(ctx, toAppError(err))
+		return
+	}
+	respondWithSuccess(ctx, constants.StatusOK, constants.DeleteSuccess)
+}
+
+// GetPreferences godoc
+// @Summary 通知の設定を取得
+// @Description 全ての通知の種類について、ログインユーザーがプッシュ通知を受け取るかどうかを返します。設定していない種類は受け取ります。
+// @Tags Notification
+// @Produce json
+// @Success 200 {array} dto.NotificationPreferenceDTO "通知の設定"
+// @Failure 500 {object} utils.ErrorResponse "サーバーエラーが発生しました"
+// @Router /notifications/preferences [get]
+// @Security Bearer
+func (c *NotificationController) GetPreferences(ctx *gin.Context) {
+	preferences, err := c.notificationService.GetPreferences(ctx.Request.Context(), ctx.GetUint("userID"))
+	if err != nil {
+		abortWithError(ctx, toAppError(err))
+		return
+	}
+	respondWithSuccess(ctx, constants.StatusOK, preferences)
+}
+
+// UpdatePreference godoc
+// @Summary 通知の設定を変更
+// @Description 通知の種類ごとに、ログインユーザーがプッシュ通知を受け取るかどうかを設定します。アプリ内通知は設定に関わらず作成されます。
+// @Tags Notification
+// @Accept json
+// @Produce json
+// @Param request body dto.NotificationPreferenceDTO true "通知の設定"
+// @Success 200 {string} string "成功"
+// @Failure 400 {object} utils.ErrorResponse "無効なリクエスト"
+// @Failure 500 {object} utils.ErrorResponse "サーバーエラーが発生しました"
+// @Router /notifications/preferences [put]
+// @Security Bearer
+func (c *NotificationController) UpdatePreference(ctx *gin.Context) {
+	var request dto.NotificationPreferenceDTO
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		abortWithError(ctx, newBindingError(err))
+		return
+	}
+
+	if err := c.notificationService.UpdatePreference(ctx.Request.Context(), ctx.GetUint("userID"), request); err != nil {
+		abortWithError(ctx, toAppError(err))
+		return
+	}
+	respondWithSuccess(ctx, constants.StatusOK, constants.Success)
+}
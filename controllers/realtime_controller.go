@@ -0,0 +1,174 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/constants"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/utils"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/websocket"
+)
+
+const (
+	// defaultRealtimeHeartbeat pingを送信する間隔。2回分の間クライアントから何も受け取らない場合は切断する
+	defaultRealtimeHeartbeat = 30 * time.Second
+	// realtimeMessageLimit クライアントから受け取るメッセージの最大のサイズ
+	realtimeMessageLimit = 4 << 10
+)
+
+// RealtimeController WebSocketでイベントを配信するコントローラ
+type RealtimeController struct {
+	hub       services.RealtimeHub
+	heartbeat time.Duration
+}
+
+// NewRealtimeController RealtimeControllerを生成。heartbeatが0の場合は30秒ごとにpingを送信する
+func NewRealtimeController(hub services.RealtimeHub, heartbeat time.Duration) *RealtimeController {
+	if heartbeat == 0 {
+		heartbeat = defaultRealtimeHeartbeat
+	}
+	return &RealtimeController{
+		hub:       hub,
+		heartbeat: heartbeat,
+	}
+}
+
+// Connect godoc
+// @Summary WebSocketでイベントを受け取る
+// @Description 通知(notification)・ダイレクトメッセージ(dm)・購読中のクラスの掲示板の作成(board_created)・スケジュールの変更(schedule_changed)・ライブ授業の発言権の変更(speak_permission)を1つのWebSocketで配信します。ブラウザではtokenクエリにアクセストークンを指定します。クラスのイベントは`{"type":"subscribe","cid":1}`で購読し、`{"type":"unsubscribe","cid":1}`で解除します。購読中にクラスのメンバーでなくなった場合は購読を解除し、`{"type":"unsubscribed","cid":1}`を送ります。サーバーは30秒ごとに`{"type":"ping"}`を送信し、クライアントは`{"type":"pong"}`を返します。60秒間何も受け取らない場合は切断します。送信が追いつかない場合は古いイベントを破棄してresyncを送るため、表示中のデータを取得し直してください。
+// @Tags Realtime
+// @Param token query string false "アクセストークン。Authorizationヘッダーを設定できない場合に指定する"
+// @Success 101 {object} dto.RealtimeEventDTO "イベント"
+// @Failure 401 {object} string "認証に失敗しました"
+// @Failure 500 {object} utils.ErrorResponse "サーバーエラーが発生しました"
+// @Router /ws [get]
+// @Security Bearer
+func (c *RealtimeController) Connect(ctx *gin.Context) {
+	conn, err := c.hub.Connect(ctx.Request.Context(), ctx.GetUint("userID"))
+	if err != nil {
+		abortWithError(ctx, utils.NewInternalError(err))
+		return
+	}
+	defer conn.Close()
+
+	// 認証はトークンで行いCookieを使わないため、Originは確認しない
+	server := websocket.Server{Handler: func(ws *websocket.Conn) {
+		ws.MaxPayloadBytes = realtimeMessageLimit
+		c.serve(ws, conn)
+	}}
+	server.ServeHTTP(ctx.Writer, ctx.Request)
+}
+
+// serve 送信を待つイベントとpingを送信する。クライアントが切断するか、送信に失敗すると終了する
+func (c *RealtimeController) serve(ws *websocket.Conn, conn *services.RealtimeConnection) {
+	ctx, cancel := context.WithCancel(ws.Request().Context())
+	defer cancel()
+	go func() {
+		defer cancel()
+		c.receive(ctx, ws, conn)
+	}()
+
+	ticker := time.NewTicker(c.heartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			_ = ws.Close()
+			return
+		case <-conn.Ready():
+			for _, event := range conn.Drain() {
+				if !c.send(ws, event) {
+					return
+				}
+			}
+		case <-ticker.C:
+			if !c.send(ws, dto.RealtimeEventDTO{Type: dto.RealtimePing}) {
+				return
+			}
+		}
+	}
+}
+
+// send イベントを送信する。送信できない状態が続くクライアントは切断する
+func (c *RealtimeController) send(ws *websocket.Conn, event dto.RealtimeEventDTO) bool {
+	_ = ws.SetWriteDeadline(time.Now().Add(c.heartbeat))
+	if err := websocket.JSON.Send(ws, event); err != nil {
+		_ = ws.Close()
+		return false
+	}
+	return true
+}
+
+// receive クライアントのメッセージを処理する。pingの2回分の間何も受け取らない場合と、接続が閉じられた場合に終了する
+func (c *RealtimeController) receive(ctx context.Context, ws *websocket.Conn, conn *services.RealtimeConnection) {
+	for {
+		_ = ws.SetReadDeadline(time.Now().Add(2 * c.heartbeat))
+		var data []byte
+		if err := websocket.Message.Receive(ws, &data); err != nil {
+			if errors.Is(err, websocket.ErrFrameTooLarge) {
+				conn.Push(realtimeError(0, constants.ErrCodeInvalidRequest, constants.InvalidRequest))
+				continue
+			}
+			return
+		}
+		var message dto.RealtimeClientMessage
+		if err := json.Unmarshal(data, &message); err != nil {
+			conn.Push(realtimeError(0, constants.ErrCodeInvalidRequest, constants.InvalidRequest))
+			continue
+		}
+		c.handle(ctx, conn, message)
+	}
+}
+
+// handle クラスのイベントの購読と解除を行い、結果をクライアントに送る
+func (c *RealtimeController) handle(ctx context.Context, conn *services.RealtimeConnection, message dto.RealtimeClientMessage) {
+	switch message.Type {
+	case dto.RealtimePong:
+	case dto.RealtimeSubscribe, dto.RealtimeUnsubscribe:
+		if message.CID == 0 {
+			conn.Push(realtimeError(0, constants.ErrCodeInvalidRequest, constants.InvalidRequest))
+			return
+		}
+		cid := message.CID
+		if message.Type == dto.RealtimeUnsubscribe {
+			conn.Unsubscribe(cid)
+			conn.Push(dto.RealtimeEventDTO{Type: dto.RealtimeUnsubscribed, CID: &cid})
+			return
+		}
+		if err := conn.Subscribe(ctx, cid); err != nil {
+			switch {
+			case errors.Is(err, services.ErrUnauthorized):
+				conn.Push(realtimeError(cid, constants.ErrCodeForbidden, constants.Forbidden))
+			case errors.Is(err, services.ErrRealtimeTopicLimit):
+				conn.Push(realtimeError(cid, constants.ErrCodeRealtimeTopicLimit, constants.RealtimeTopicLimit))
+			default:
+				utils.ReportBackgroundError("realtime_subscribe", err)
+				conn.Push(realtimeError(cid, constants.ErrCodeInternal, constants.InternalServerError))
+			}
+			return
+		}
+		conn.Push(dto.RealtimeEventDTO{Type: dto.RealtimeSubscribed, CID: &cid})
+	default:
+		conn.Push(realtimeError(0, constants.ErrCodeInvalidRequest, constants.InvalidRequest))
+	}
+}
+
+// BroadcastSystemEvent WebSocketで接続中の全てのクライアントにシステムからの通知を送る
+func (c *RealtimeController) BroadcastSystemEvent(event services.SystemEvent) {
+	c.hub.BroadcastSystemEvent(event)
+}
+
+// realtimeError クライアントのメッセージを処理できなかったことを知らせるイベントを生成する。cidが0の場合はcidを含めない
+func realtimeError(cid uint, code string, message string) dto.RealtimeEventDTO {
+	data, _ := json.Marshal(dto.RealtimeErrorDTO{Code: code, Message: message})
+	event := dto.RealtimeEventDTO{Type: dto.RealtimeError, Data: data}
+	if cid != 0 {
+		event.CID = &cid
+	}
+	return event
+}
@@ -1,21 +1,41 @@
 package controllers
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/constants"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/middlewares"
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/utils"
 	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
 )
 
 // handleServiceError サービスによって返されたエラーを処理する
 func handleServiceError(ctx *gin.Context, err error) {
 	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		abortWithError(ctx, err)
 	case errors.Is(err, services.ErrNotFound):
 		respondWithError(ctx, constants.StatusNotFound, constants.CodeNotFound)
 	case errors.Is(err, services.ErrUnauthorized):
-		respondWithError(ctx, constants.StatusUnauthorized, constants.Unauthorized)
+		// 認証済みのユーザーに権限がない場合なので401ではなく403を返す
+		respondWithError(ctx, constants.StatusForbidden, constants.Forbidden)
 	case errors.Is(err, services.ErrDatabase):
 		respondWithError(ctx, constants.StatusInternalServerError, constants.DatabaseError)
+	case errors.Is(err, services.ErrPastSchedule):
+		respondWithError(ctx, constants.StatusUnprocessable, constants.PastScheduleDeletion)
+	case errors.Is(err, services.ErrInvalidAttendanceMode):
+		respondWithError(ctx, constants.StatusBadRequest, constants.InvalidAttendanceMode)
+	case errors.Is(err, services.ErrDuplicateBoardLanguage):
+		respondWithError(ctx, constants.StatusBadRequest, constants.DuplicateBoardLanguage)
+	case errors.Is(err, services.ErrStaleUpdate), errors.Is(err, services.ErrVersionRequired), errors.Is(err, services.ErrCheckInTokenUnavailable):
+		abortWithError(ctx, toAppError(err))
 	default:
 		respondWithError(ctx, constants.StatusInternalServerError, constants.InternalServerError)
 	}
@@ -26,7 +46,197 @@ func respondWithError(ctx *gin.Context, statusCode int, errMsg string) {
 	ctx.JSON(statusCode, gin.H{"error": errMsg})
 }
 
-// respondWithSuccess 成功時のレスポンスを返す
+// respondWithSuccess 成功時のレスポンスを返す。v2ではメタ情報を付与する
 func respondWithSuccess(ctx *gin.Context, statusCode int, data interface{}) {
+	if middlewares.GetAPIVersion(ctx) == middlewares.APIVersionV2 {
+		ctx.JSON(statusCode, dto.V2Response{Data: data, Meta: newResponseMeta(ctx)})
+		return
+	}
+	ctx.JSON(statusCode, gin.H{"data": data})
+}
+
+// respondWithPage ページングされた一覧を返す。v2ではページ情報をメタ情報に含める
+func respondWithPage(ctx *gin.Context, statusCode int, data interface{}, page, limit int) {
+	if middlewares.GetAPIVersion(ctx) == middlewares.APIVersionV2 {
+		ctx.JSON(statusCode, dto.V2Response{Data: data, Meta: newPageMeta(ctx, page, limit)})
+		return
+	}
+	ctx.JSON(statusCode, gin.H{"data": data})
+}
+
+// respondWithCountedPage 総数と次のページを含めてページングされた一覧を返す。v2ではページ情報をメタ情報に含める
+func respondWithCountedPage(ctx *gin.Context, statusCode int, data interface{}, page, limit int, total int64, nextPage *int) {
+	if middlewares.GetAPIVersion(ctx) == middlewares.APIVersionV2 {
+		meta := newPageMeta(ctx, page, limit)
+		meta.Total = &total
+		meta.NextPage = nextPage
+		ctx.JSON(statusCode, dto.V2Response{Data: data, Meta: meta})
+		return
+	}
 	ctx.JSON(statusCode, gin.H{"data": data})
 }
+
+// newResponseMeta v2レスポンスのメタ情報を生成する
+func newResponseMeta(ctx *gin.Context) dto.ResponseMeta {
+	return dto.ResponseMeta{
+		APIVersion: middlewares.APIVersionV2,
+		RequestID:  middlewares.GetRequestID(ctx),
+	}
+}
+
+// newPageMeta ページ情報を含むv2レスポンスのメタ情報を生成する
+func newPageMeta(ctx *gin.Context, page, limit int) dto.ResponseMeta {
+	meta := newResponseMeta(ctx)
+	meta.Page = page
+	meta.Limit = limit
+	return meta
+}
+
+// resolveUserID 操作対象のユーザーIDを取得する。v2ではJWTのユーザーID、v1ではパスパラメータのuidを使う
+func resolveUserID(ctx *gin.Context) (uint, error) {
+	if middlewares.GetAPIVersion(ctx) == middlewares.APIVersionV2 {
+		uid := ctx.GetUint("userID")
+		if uid == 0 {
+			return 0, utils.NewUnauthorizedError(constants.ErrCodeUnauthorized, constants.Unauthorized)
+		}
+		return uid, nil
+	}
+
+	uid, err := strconv.ParseUint(ctx.Param("uid"), 10, 32)
+	if err != nil {
+		return 0, err
+	}
+	return uint(uid), nil
+}
+
+// newBindingError リクエストのバインドに失敗したエラーを400のAppErrorに変換する。
+// 入力値の検証に失敗した場合はdetailsに項目ごとのエラーメッセージを含める
+func newBindingError(err error) error {
+	var validationErrs validator.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		return utils.NewBadRequestError(constants.ErrCodeInvalidRequest, constants.BadRequestMessage).Wrap(err)
+	}
+
+	fields := make(map[string]interface{}, len(validationErrs))
+	for _, fieldErr := range validationErrs {
+		field := strings.ToLower(fieldErr.Field())
+		switch fieldErr.Tag() {
+		case "required":
+			fields[field] = field + "は必須です"
+		case "max":
+			fields[field] = fmt.Sprintf("%sは%s文字以内で入力してください", field, fieldErr.Param())
+		default:
+			fields[field] = field + "の値が不正です"
+		}
+	}
+	return utils.NewBadRequestError(constants.ErrCodeInvalidRequest, constants.ValidationFailed).WithDetails(fields).Wrap(err)
+}
+
+// abortWithError エラーを登録して処理を中断し、レスポンスはglobalErrorHandlerに任せる
+func abortWithError(ctx *gin.Context, err error) {
+	_ = ctx.Error(err)
+	ctx.Abort()
+}
+
+// toAppError サービスのエラーをAppErrorに変換する。想定外のエラーはそのまま返す
+func toAppError(err error) error {
+	var appErr *utils.AppError
+	var notEnrolled *services.NotEnrolledError
+	var stale *services.StaleUpdateError
+	switch {
+	case errors.As(err, &appErr):
+		return appErr
+	case errors.As(err, &notEnrolled):
+		return utils.NewAppError(constants.StatusUnprocessable, constants.ErrCodeNotEnrolled, constants.NotEnrolledInClasses).
+			WithDetails(map[string]interface{}{"class_ids": notEnrolled.ClassIDs}).Wrap(err)
+	case errors.As(err, &stale):
+		return utils.NewConflictError(constants.ErrCodeStaleUpdate, constants.StaleUpdate).
+			WithDetails(map[string]interface{}{"current": stale.Current}).Wrap(err)
+	case errors.Is(err, services.ErrNotFound):
+		return utils.NewNotFoundError(constants.ErrCodeNotFound, constants.CodeNotFound).Wrap(err)
+	case errors.Is(err, services.ErrUnauthorized):
+		return utils.NewForbiddenError(constants.ErrCodeForbidden, constants.Forbidden).Wrap(err)
+	case errors.Is(err, services.ErrDatabase):
+		return utils.NewAppError(constants.StatusInternalServerError, constants.ErrCodeDatabaseError, constants.DatabaseError).Wrap(err)
+	case errors.Is(err, services.ErrPastSchedule):
+		return utils.NewAppError(constants.StatusUnprocessable, constants.ErrCodePastSchedule, constants.PastScheduleDeletion).Wrap(err)
+	case errors.Is(err, services.ErrActiveClassLimit):
+		return utils.NewAppError(constants.StatusUnprocessable, constants.ErrCodeActiveClassLimit, constants.ActiveClassLimitReached).Wrap(err)
+	case errors.Is(err, services.ErrChannelUnavailable):
+		return utils.NewAppError(constants.StatusUnprocessable, constants.ErrCodeChannelUnavailable, constants.ChannelUnavailable).Wrap(err)
+	case errors.Is(err, services.ErrSurveyNotOpen):
+		return utils.NewAppError(constants.StatusUnprocessable, constants.ErrCodeSurveyNotOpen, constants.SurveyNotOpen).Wrap(err)
+	case errors.Is(err, services.ErrNotTranslatable):
+		return utils.NewAppError(constants.StatusUnprocessable, constants.ErrCodeNotTranslatable, constants.NotTranslatable).Wrap(err)
+	case errors.Is(err, services.ErrIntegrationTestFailed):
+		return utils.NewAppError(constants.StatusUnprocessable, constants.ErrCodeIntegrationTestFailed, constants.IntegrationTestFailed).Wrap(err)
+	case errors.Is(err, services.ErrCalendarClassNotSynced):
+		return utils.NewAppError(constants.StatusUnprocessable, constants.ErrCodeCalendarClassNotSynced, constants.CalendarClassNotSynced).Wrap(err)
+	case errors.Is(err, services.ErrCalendarNotConnected):
+		return utils.NewConflictError(constants.ErrCodeCalendarNotConnected, constants.CalendarNotConnected).Wrap(err)
+	case errors.Is(err, services.ErrPreviewSecretLimit):
+		return utils.NewAppError(constants.StatusTooManyRequests, constants.ErrCodePreviewSecretLimit, constants.PreviewSecretLimit).Wrap(err)
+	case errors.Is(err, services.ErrTranslationRateLimit):
+		return utils.NewAppError(constants.StatusTooManyRequests, constants.ErrCodeTranslationRateLimit, constants.TranslationRateLimit).Wrap(err)
+	case errors.Is(err, services.ErrTranslationFailed):
+		return utils.NewAppError(constants.StatusBadGateway, constants.ErrCodeTranslationFailed, constants.TranslationFailed).Wrap(err)
+	case errors.Is(err, services.ErrInvitationLimit):
+		return utils.NewAppError(constants.StatusTooManyRequests, constants.ErrCodeInvitationLimit, constants.InvitationLimitReached).Wrap(err)
+	case errors.Is(err, services.ErrReminderCooldown):
+		return utils.NewAppError(constants.StatusTooManyRequests, constants.ErrCodeReminderCooldown, constants.ReminderCooldown).Wrap(err)
+	case errors.Is(err, services.ErrEmailVerificationLimit):
+		return utils.NewAppError(constants.StatusTooManyRequests, constants.ErrCodeVerificationLimit, constants.VerificationLimit).Wrap(err)
+	case errors.Is(err, services.ErrEmailNotSet):
+		return utils.NewAppError(constants.StatusUnprocessable, constants.ErrCodeEmailNotSet, constants.EmailNotSet).Wrap(err)
+	case errors.Is(err, services.ErrEmailNotVerified):
+		return utils.NewAppError(constants.StatusUnprocessable, constants.ErrCodeEmailNotVerified, constants.EmailNotVerified).Wrap(err)
+	case errors.Is(err, services.ErrEmailTaken):
+		return utils.NewConflictError(constants.ErrCodeEmailTaken, constants.EmailTaken).Wrap(err)
+	case errors.Is(err, services.ErrInvalidEmailToken):
+		return utils.NewBadRequestError(constants.ErrCodeInvalidEmailToken, constants.InvalidEmailToken).Wrap(err)
+	case errors.Is(err, services.ErrEmailVerificationUnavailable):
+		return utils.NewAppError(constants.StatusServiceUnavailable, constants.ErrCodeVerifyUnavailable, constants.VerifyUnavailable).Wrap(err)
+	case errors.Is(err, services.ErrMailQueueFull):
+		return utils.NewAppError(constants.StatusServiceUnavailable, constants.ErrCodeMailQueueFull, constants.MailQueueFull).Wrap(err)
+	case errors.Is(err, services.ErrCalendarSyncUnavailable):
+		return utils.NewAppError(constants.StatusServiceUnavailable, constants.ErrCodeCalendarSyncUnavailable, constants.CalendarSyncUnavailable).Wrap(err)
+	case errors.Is(err, services.ErrCheckInTokenExpired):
+		return utils.NewAppError(constants.StatusUnprocessable, constants.ErrCodeCheckInTokenExpired, constants.CheckInTokenExpired).Wrap(err)
+	case errors.Is(err, services.ErrScheduleCancelled):
+		return utils.NewAppError(constants.StatusUnprocessable, constants.ErrCodeScheduleCancelled, constants.CheckInCancelled).Wrap(err)
+	case errors.Is(err, services.ErrCheckInMethodNotAllowed):
+		return utils.NewAppError(constants.StatusUnprocessable, constants.ErrCodeCheckInMethodNotAllowed, constants.CheckInMethodNotAllowed).Wrap(err)
+	case errors.Is(err, services.ErrCheckInTokenUnavailable):
+		return utils.NewAppError(constants.StatusServiceUnavailable, constants.ErrCodeCheckInTokenUnavailable, constants.CheckInTokenUnavailable).Wrap(err)
+	case errors.Is(err, services.ErrEventsUnavailable):
+		return utils.NewAppError(constants.StatusServiceUnavailable, constants.ErrCodeEventsUnavailable, constants.EventsUnavailable).Wrap(err)
+	case errors.Is(err, services.ErrTranslationUnavailable):
+		return utils.NewAppError(constants.StatusServiceUnavailable, constants.ErrCodeTranslationUnavailable, constants.TranslationUnavailable).Wrap(err)
+	case errors.Is(err, utils.ErrInvalidNotificationTarget):
+		return utils.NewBadRequestError(constants.ErrCodeInvalidNotifyTarget, constants.InvalidNotifyTarget).Wrap(err)
+	case errors.Is(err, services.ErrInvalidGradeScale):
+		return utils.NewBadRequestError(constants.ErrCodeInvalidGradeScale, constants.InvalidGradeScale).Wrap(err)
+	case errors.Is(err, services.ErrInvalidAccessRestriction):
+		return utils.NewBadRequestError(constants.ErrCodeInvalidAccessRule, constants.InvalidAccessRule).Wrap(err)
+	case errors.Is(err, services.ErrInvalidWebhookURL):
+		return utils.NewBadRequestError(constants.ErrCodeInvalidWebhookURL, constants.InvalidWebhookURL).Wrap(err)
+	case errors.Is(err, services.ErrInvalidIntegrationURL):
+		return utils.NewBadRequestError(constants.ErrCodeInvalidIntegrationURL, constants.InvalidIntegrationURL).Wrap(err)
+	case errors.Is(err, services.ErrInvalidEventCursor):
+		return utils.NewBadRequestError(constants.ErrCodeInvalidEventCursor, constants.InvalidEventCursor).Wrap(err)
+	case errors.Is(err, services.ErrVersionRequired):
+		return utils.NewBadRequestError(constants.ErrCodeVersionRequired, constants.VersionRequired).Wrap(err)
+	case errors.Is(err, services.ErrNestedReply):
+		return utils.NewBadRequestError(constants.ErrCodeNestedReply, constants.NestedReply).Wrap(err)
+	case errors.Is(err, services.ErrInvalidCheckInCode):
+		return utils.NewForbiddenError(constants.ErrCodeInvalidCheckInCode, constants.InvalidCheckInCode).Wrap(err)
+	case errors.Is(err, services.ErrInvalidCheckInToken):
+		return utils.NewForbiddenError(constants.ErrCodeInvalidCheckInToken, constants.InvalidCheckInToken).Wrap(err)
+	case errors.Is(err, services.ErrAccessRestricted):
+		return utils.NewForbiddenError(constants.ErrCodeAccessRestricted, constants.AccessRestricted).Wrap(err)
+	case errors.Is(err, services.ErrScheduleTooOld):
+		return utils.NewAppError(constants.StatusUnprocessable, constants.ErrCodeScheduleTooOld, constants.AttendanceResetLimit).Wrap(err)
+	default:
+		return err
+	}
+}
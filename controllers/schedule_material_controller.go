@@ -0,0 +1,197 @@
+package controllers
+
+import (
+	"errors"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/constants"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// ScheduleMaterialController スケジュールの予習資料と振り返りアンケートのコントローラ
+type ScheduleMaterialController struct {
+	materialService services.ScheduleMaterialService
+}
+
+// NewScheduleMaterialController ScheduleMaterialControllerを生成
+func NewScheduleMaterialController(materialService services.ScheduleMaterialService) *ScheduleMaterialController {
+	return &ScheduleMaterialController{
+		materialService: materialService,
+	}
+}
+
+// GetMaterials godoc
+// @Summary スケジュールの資料一覧
+// @Description スケジュールの資料を授業前の予習資料、授業後の振り返りアンケートの順に取得します。生徒には授業が終了するまで振り返りアンケートを返しません。クラスのメンバーのみ利用できます。
+// @Tags Class Schedule
+// @Produce json
+// @Param id path int true "Class Schedule ID"
+// @Success 200 {array} dto.ScheduleMaterialDTO "資料"
+// @Failure 400 {object} utils.ErrorResponse "無効なリクエスト"
+// @Failure 403 {object} utils.ErrorResponse "クラスのメンバーではありません"
+// @Failure 404 {object} utils.ErrorResponse "スケジュールが見つかりません"
+// @Failure 500 {object} utils.ErrorResponse "サーバーエラーが発生しました"
+// @Router /cs/{id}/materials [get]
+// @Security Bearer
+func (c *ScheduleMaterialController) GetMaterials(ctx *gin.Context) {
+	csid, ok := parseCommentParam(ctx, "id")
+	if !ok {
+		return
+	}
+
+	materials, err := c.materialService.GetMaterials(ctx.Request.Context(), ctx.GetUint("userID"), csid)
+	if err != nil {
+		abortWithMaterialError(ctx, err)
+		return
+	}
+	respondWithSuccess(ctx, constants.StatusOK, materials)
+}
+
+// CreateMaterial godoc
+// @Summary スケジュールに資料を追加
+// @Description 授業前の予習資料(PRE)または授業後の振り返りアンケート(POST)をスケジュールに追加します。振り返りアンケートは授業の終了後に生徒へ通知されます。クラスの管理者とアシスタントのみ利用できます。
+// @Tags Class Schedule
+// @Accept json
+// @Produce json
+// @Param id path int true "Class Schedule ID"
+// @Param request body dto.ScheduleMaterialCreateDTO true "資料"
+// @Success 201 {object} dto.ScheduleMaterialDTO "作成した資料"
+// @Failure 400 {object} utils.ErrorResponse "無効なリクエスト"
+// @Failure 403 {object} utils.ErrorResponse "権限がありません"
+// @Failure 404 {object} utils.ErrorResponse "スケジュールが見つかりません"
+// @Failure 500 {object} utils.ErrorResponse "サーバーエラーが発生しました"
+// @Router /cs/{id}/materials [post]
+// @Security Bearer
+func (c *ScheduleMaterialController) CreateMaterial(ctx *gin.Context) {
+	csid, ok := parseCommentParam(ctx, "id")
+	if !ok {
+		return
+	}
+
+	var request dto.ScheduleMaterialCreateDTO
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		abortWithError(ctx, newBindingError(err))
+		return
+	}
+
+	material, err := c.materialService.CreateMaterial(ctx.Request.Context(), ctx.GetUint("userID"), csid, request)
+	if err != nil {
+		abortWithMaterialError(ctx, err)
+		return
+	}
+	respondWithSuccess(ctx, constants.StatusCreated, material)
+}
+
+// DeleteMaterial godoc
+// @Summary スケジュールの資料を削除
+// @Description 資料を削除します。振り返りアンケートの場合は回答も削除されます。クラスの管理者とアシスタントのみ利用できます。
+// @Tags Class Schedule
+// @Param id path int true "Class Schedule ID"
+// @Param materialID path int true "資料ID"
+// @Success 200 {string} string "削除成功"
+// @Failure 400 {object} utils.ErrorResponse "無効なリクエスト"
+// @Failure 403 {object} utils.ErrorResponse "権限がありません"
+// @Failure 404 {object} utils.ErrorResponse "スケジュールまたは資料が見つかりません"
+// @Failure 500 {object} utils.ErrorResponse "サーバーエラーが発生しました"
+// @Router /cs/{id}/materials/{materialID} [delete]
+// @Security Bearer
+func (c *ScheduleMaterialController) DeleteMaterial(ctx *gin.Context) {
+	csid, materialID, ok := parseMaterialParams(ctx)
+	if !ok {
+		return
+	}
+
+	if err := c.materialService.DeleteMaterial(ctx.Request.Context(), ctx.GetUint("userID"), csid, materialID); err != nil {
+		abortWithMaterialError(ctx, err)
+		return
+	}
+	respondWithSuccess(ctx, constants.StatusOK, constants.DeleteSuccess)
+}
+
+// SubmitSurveyResponse godoc
+// @Summary 振り返りアンケートに回答
+// @Description 授業の理解度(1から5)と振り返りを回答します。授業の終了後のみ回答でき、再回答すると以前の回答を上書きします。クラスの生徒のみ利用できます。
+// @Tags Class Schedule
+// @Accept json
+// @Produce json
+// @Param id path int true "Class Schedule ID"
+// @Param materialID path int true "資料ID"
+// @Param request body dto.SurveyResponseRequest true "回答"
+// @Success 200 {string} string "作成または更新成功"
+// @Failure 400 {object} utils.ErrorResponse "無効なリクエスト"
+// @Failure 403 {object} utils.ErrorResponse "クラスの生徒ではありません"
+// @Failure 404 {object} utils.ErrorResponse "スケジュールまたはアンケートが見つかりません"
+// @Failure 422 {object} utils.ErrorResponse "授業が終了していないか休講です"
+// @Failure 500 {object} utils.ErrorResponse "サーバーエラーが発生しました"
+// @Router /cs/{id}/materials/{materialID}/response [put]
+// @Security Bearer
+func (c *ScheduleMaterialController) SubmitSurveyResponse(ctx *gin.Context) {
+	csid, materialID, ok := parseMaterialParams(ctx)
+	if !ok {
+		return
+	}
+
+	var request dto.SurveyResponseRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		abortWithError(ctx, newBindingError(err))
+		return
+	}
+
+	if err := c.materialService.SubmitSurveyResponse(ctx.Request.Context(), ctx.GetUint("userID"), csid, materialID, request); err != nil {
+		abortWithMaterialError(ctx, err)
+		return
+	}
+	respondWithSuccess(ctx, constants.StatusOK, constants.CreateOrUpdateSuccess)
+}
+
+// GetSurveySummary godoc
+// @Summary 振り返りアンケートの集計
+// @Description 振り返りアンケートの回答数と回答率、理解度の平均と分布、振り返りの自由記述を取得します。クラスの管理者とアシスタントのみ利用できます。
+// @Tags Class Schedule
+// @Produce json
+// @Param id path int true "Class Schedule ID"
+// @Param materialID path int true "資料ID"
+// @Success 200 {object} dto.SurveySummaryDTO "集計"
+// @Failure 400 {object} utils.ErrorResponse "無効なリクエスト"
+// @Failure 403 {object} utils.ErrorResponse "権限がありません"
+// @Failure 404 {object} utils.ErrorResponse "スケジュールまたはアンケートが見つかりません"
+// @Failure 500 {object} utils.ErrorResponse "サーバーエラーが発生しました"
+// @Router /cs/{id}/materials/{materialID}/summary [get]
+// @Security Bearer
+func (c *ScheduleMaterialController) GetSurveySummary(ctx *gin.Context) {
+	csid, materialID, ok := parseMaterialParams(ctx)
+	if !ok {
+		return
+	}
+
+	summary, err := c.materialService.GetSurveySummary(ctx.Request.Context(), ctx.GetUint("userID"), csid, materialID)
+	if err != nil {
+		abortWithMaterialError(ctx, err)
+		return
+	}
+	respondWithSuccess(ctx, constants.StatusOK, summary)
+}
+
+// parseMaterialParams パスのスケジュールIDと資料IDを解析する。不正な場合は400を登録してfalseを返す
+func parseMaterialParams(ctx *gin.Context) (uint, uint, bool) {
+	csid, ok := parseCommentParam(ctx, "id")
+	if !ok {
+		return 0, 0, false
+	}
+	materialID, ok := parseCommentParam(ctx, "materialID")
+	if !ok {
+		return 0, 0, false
+	}
+	return csid, materialID, true
+}
+
+// abortWithMaterialError 権限のエラーを403として登録し、それ以外はAppErrorに変換して登録する
+func abortWithMaterialError(ctx *gin.Context, err error) {
+	if errors.Is(err, services.ErrUnauthorized) {
+		abortWithError(ctx, utils.NewForbiddenError(constants.ErrCodeForbidden, constants.Forbidden))
+		return
+	}
+	abortWithError(ctx, toAppError(err))
+}
@@ -0,0 +1,74 @@
+package controllers
+
+import (
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/constants"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"github.com/gin-gonic/gin"
+)
+
+// ScheduleNotificationController スケジュールに関する通知の既読状況と再通知のコントローラ
+type ScheduleNotificationController struct {
+	scheduleNotificationService services.ScheduleNotificationService
+}
+
+// NewScheduleNotificationController ScheduleNotificationControllerを生成
+func NewScheduleNotificationController(scheduleNotificationService services.ScheduleNotificationService) *ScheduleNotificationController {
+	return &ScheduleNotificationController{
+		scheduleNotificationService: scheduleNotificationService,
+	}
+}
+
+// GetScheduleNotificationStats godoc
+// @Summary スケジュールの通知の既読状況
+// @Description 休講などスケジュールに関するアプリ内通知を受け取ったメンバーの数と、そのうち1件以上を読んだメンバーの数を返します。既読の通知は保存期間を過ぎると削除されるため、保存期間内の通知が集計の対象です。クラスの管理者とアシスタントのみ利用できます。
+// @Tags Class Schedule
+// @Produce json
+// @Param id path int true "Class Schedule ID"
+// @Success 200 {object} dto.ScheduleNotificationStatsDTO "通知の既読状況"
+// @Failure 400 {object} utils.ErrorResponse "無効なリクエスト"
+// @Failure 403 {object} utils.ErrorResponse "権限がありません"
+// @Failure 404 {object} utils.ErrorResponse "スケジュールが見つかりません"
+// @Failure 500 {object} utils.ErrorResponse "サーバーエラーが発生しました"
+// @Router /cs/{id}/notifications/stats [get]
+// @Security Bearer
+func (c *ScheduleNotificationController) GetScheduleNotificationStats(ctx *gin.Context) {
+	csid, ok := parseCommentParam(ctx, "id")
+	if !ok {
+		return
+	}
+
+	stats, err := c.scheduleNotificationService.GetScheduleNotificationStats(ctx.Request.Context(), ctx.GetUint("userID"), csid)
+	if err != nil {
+		abortWithError(ctx, toAppError(err))
+		return
+	}
+	respondWithSuccess(ctx, constants.StatusOK, stats)
+}
+
+// RemindUnread godoc
+// @Summary スケジュールの通知を未読のメンバーに再通知
+// @Description スケジュールに関するアプリ内通知を1件も読んでいないクラスのメンバーに再通知し、再通知した人数を返します。同じスケジュールへの再通知は10分に1回までです。クラスの管理者とアシスタントのみ利用できます。
+// @Tags Class Schedule
+// @Produce json
+// @Param id path int true "Class Schedule ID"
+// @Success 200 {object} dto.ScheduleNotificationReminderDTO "再通知した人数"
+// @Failure 400 {object} utils.ErrorResponse "無効なリクエスト"
+// @Failure 403 {object} utils.ErrorResponse "権限がありません"
+// @Failure 404 {object} utils.ErrorResponse "スケジュールが見つかりません"
+// @Failure 429 {object} utils.ErrorResponse "reminder_cooldown"
+// @Failure 500 {object} utils.ErrorResponse "サーバーエラーが発生しました"
+// @Router /cs/{id}/notifications/remind [post]
+// @Security Bearer
+func (c *ScheduleNotificationController) RemindUnread(ctx *gin.Context) {
+	csid, ok := parseCommentParam(ctx, "id")
+	if !ok {
+		return
+	}
+
+	result, err := c.scheduleNotificationService.RemindUnread(ctx.Request.Context(), ctx.GetUint("userID"), csid)
+	if err != nil {
+		abortWithError(ctx, toAppError(err))
+		return
+	}
+	respondWithSuccess(ctx, constants.StatusOK, result)
+}
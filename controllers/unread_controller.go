@@ -0,0 +1,76 @@
+package controllers
+
+import (
+	"strconv"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/constants"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// UnreadController 未読件数のコントローラ
+type UnreadController struct {
+	unreadService services.UnreadService
+}
+
+// NewUnreadController UnreadControllerを生成
+func NewUnreadController(unreadService services.UnreadService) *UnreadController {
+	return &UnreadController{
+		unreadService: unreadService,
+	}
+}
+
+// GetUnreadSummary godoc
+// @Summary 参加している全クラスの未読サマリー
+// @Description ログインユーザーが参加するアーカイブされていない全てのクラスについて、未読の掲示、チャット、通知、メンションの件数と全体の合計を1回で返します。バッジ表示やホーム画面の通知集約に使います。
+// @Tags Unread
+// @Produce json
+// @Success 200 {object} dto.UnreadSummaryDTO "未読サマリー"
+// @Failure 500 {object} utils.ErrorResponse "サーバーエラーが発生しました"
+// @Router /unread [get]
+// @Security Bearer
+func (c *UnreadController) GetUnreadSummary(ctx *gin.Context) {
+	summary, err := c.unreadService.GetUnreadSummary(ctx.Request.Context(), ctx.GetUint("userID"))
+	if err != nil {
+		abortWithError(ctx, toAppError(err))
+		return
+	}
+	respondWithSuccess(ctx, constants.StatusOK, summary)
+}
+
+// MarkRead godoc
+// @Summary クラスの未読を既読にする
+// @Description ログインユーザーのクラスの未読件数を0にします。kindを省略した場合は全ての種類を既読にします。掲示は閲覧した時点で1件ずつ既読になります。
+// @Tags Unread
+// @Produce json
+// @Param cid path int true "クラスID"
+// @Param kind query string false "未読の種類" Enums(boards, chat, notifications, mentions)
+// @Success 200 {string} string "成功"
+// @Failure 400 {object} utils.ErrorResponse "無効なリクエスト"
+// @Failure 500 {object} utils.ErrorResponse "サーバーエラーが発生しました"
+// @Router /unread/{cid}/read [post]
+// @Security Bearer
+func (c *UnreadController) MarkRead(ctx *gin.Context) {
+	cid, err := strconv.ParseUint(ctx.Param("cid"), 10, 32)
+	if err != nil {
+		abortWithError(ctx, utils.NewBadRequestError(constants.ErrCodeInvalidRequest, constants.InvalidRequest))
+		return
+	}
+
+	var kinds []services.UnreadKind
+	if value := ctx.Query("kind"); value != "" {
+		kind, ok := services.ParseUnreadKind(value)
+		if !ok {
+			abortWithError(ctx, utils.NewBadRequestError(constants.ErrCodeInvalidRequest, constants.InvalidRequest))
+			return
+		}
+		kinds = append(kinds, kind)
+	}
+
+	if err := c.unreadService.MarkRead(ctx.Request.Context(), ctx.GetUint("userID"), uint(cid), kinds...); err != nil {
+		abortWithError(ctx, toAppError(err))
+		return
+	}
+	respondWithSuccess(ctx, constants.StatusOK, constants.Success)
+}
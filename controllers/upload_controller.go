@@ -0,0 +1,157 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/constants"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// UploadController 大容量ファイルのマルチパートアップロードのコントローラ
+type UploadController struct {
+	uploadService services.UploadService
+}
+
+// NewUploadController UploadControllerを生成
+func NewUploadController(uploadService services.UploadService) *UploadController {
+	return &UploadController{
+		uploadService: uploadService,
+	}
+}
+
+// StartUpload godoc
+// @Summary マルチパートアップロードを開始
+// @Description 録画データなどの大容量ファイルのアップロードを開始します。返されたpart_sizeごとにファイルを分割して送信してください。クラスの管理者・アシスタントのみ利用できます。
+// @Tags Upload
+// @Accept json
+// @Produce json
+// @Param request body dto.StartUploadRequest true "アップロードするファイルの情報"
+// @Success 201 {object} dto.UploadProgressDTO "アップロードの進捗"
+// @Failure 400 {object} utils.ErrorResponse "無効なリクエスト"
+// @Failure 403 {object} utils.ErrorResponse "権限がありません"
+// @Failure 500 {object} utils.ErrorResponse "サーバーエラーが発生しました"
+// @Router /uploads [post]
+// @Security Bearer
+func (c *UploadController) StartUpload(ctx *gin.Context) {
+	var request dto.StartUploadRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		abortWithError(ctx, utils.NewBadRequestError(constants.ErrCodeInvalidRequest, constants.InvalidRequest).Wrap(err))
+		return
+	}
+
+	progress, err := c.uploadService.StartUpload(ctx.Request.Context(), ctx.GetUint("userID"), request)
+	if err != nil {
+		abortWithError(ctx, toUploadAppError(err))
+		return
+	}
+	respondWithSuccess(ctx, constants.StatusCreated, progress)
+}
+
+// UploadPart godoc
+// @Summary パートをアップロード
+// @Description ファイルのパートをリクエストボディとして送信します。中断した場合は進捗のcompleted_partsに含まれないパートを送り直して再開できます。
+// @Tags Upload
+// @Accept application/octet-stream
+// @Produce json
+// @Param uploadId path string true "アップロードID"
+// @Param partNumber path int true "パート番号(1から)"
+// @Success 200 {object} dto.UploadProgressDTO "アップロードの進捗"
+// @Failure 400 {object} utils.ErrorResponse "パートのサイズまたは番号が不正です"
+// @Failure 404 {object} utils.ErrorResponse "アップロードが見つかりません"
+// @Failure 500 {object} utils.ErrorResponse "サーバーエラーが発生しました"
+// @Router /uploads/{uploadId}/parts/{partNumber} [put]
+// @Security Bearer
+func (c *UploadController) UploadPart(ctx *gin.Context) {
+	partNumber, err := strconv.ParseInt(ctx.Param("partNumber"), 10, 32)
+	if err != nil || ctx.Request.ContentLength <= 0 || ctx.Request.ContentLength > services.UploadPartSize {
+		abortWithError(ctx, utils.NewBadRequestError(constants.ErrCodeInvalidRequest, constants.InvalidRequest))
+		return
+	}
+
+	body := http.MaxBytesReader(ctx.Writer, ctx.Request.Body, services.UploadPartSize)
+	progress, err := c.uploadService.UploadPart(ctx.Request.Context(), ctx.GetUint("userID"), ctx.Param("uploadId"), int32(partNumber), body, ctx.Request.ContentLength)
+	if err != nil {
+		abortWithError(ctx, toUploadAppError(err))
+		return
+	}
+	respondWithSuccess(ctx, constants.StatusOK, progress)
+}
+
+// GetUploadProgress godoc
+// @Summary アップロードの進捗を取得
+// @Description アップロード済みのバイト数とパート番号を取得します。
+// @Tags Upload
+// @Produce json
+// @Param uploadId path string true "アップロードID"
+// @Success 200 {object} dto.UploadProgressDTO "アップロードの進捗"
+// @Failure 404 {object} utils.ErrorResponse "アップロードが見つかりません"
+// @Failure 500 {object} utils.ErrorResponse "サーバーエラーが発生しました"
+// @Router /uploads/{uploadId} [get]
+// @Security Bearer
+func (c *UploadController) GetUploadProgress(ctx *gin.Context) {
+	progress, err := c.uploadService.GetProgress(ctx.Request.Context(), ctx.GetUint("userID"), ctx.Param("uploadId"))
+	if err != nil {
+		abortWithError(ctx, toUploadAppError(err))
+		return
+	}
+	respondWithSuccess(ctx, constants.StatusOK, progress)
+}
+
+// CompleteUpload godoc
+// @Summary アップロードを完了
+// @Description 全てのパートを結合してアップロードを完了し、ファイルのURLを返します。
+// @Tags Upload
+// @Produce json
+// @Param uploadId path string true "アップロードID"
+// @Success 200 {object} dto.UploadCompleteDTO "アップロードしたファイル"
+// @Failure 404 {object} utils.ErrorResponse "アップロードが見つかりません"
+// @Failure 409 {object} utils.ErrorResponse "未アップロードのパートがあります"
+// @Failure 500 {object} utils.ErrorResponse "サーバーエラーが発生しました"
+// @Router /uploads/{uploadId}/complete [post]
+// @Security Bearer
+func (c *UploadController) CompleteUpload(ctx *gin.Context) {
+	result, err := c.uploadService.CompleteUpload(ctx.Request.Context(), ctx.GetUint("userID"), ctx.Param("uploadId"))
+	if err != nil {
+		abortWithError(ctx, toUploadAppError(err))
+		return
+	}
+	respondWithSuccess(ctx, constants.StatusOK, result)
+}
+
+// AbortUpload godoc
+// @Summary アップロードを中止
+// @Description アップロードを中止し、アップロード済みのパートを破棄します。
+// @Tags Upload
+// @Produce json
+// @Param uploadId path string true "アップロードID"
+// @Success 200 {string} string "削除に成功しました"
+// @Failure 404 {object} utils.ErrorResponse "アップロードが見つかりません"
+// @Failure 500 {object} utils.ErrorResponse "サーバーエラーが発生しました"
+// @Router /uploads/{uploadId} [delete]
+// @Security Bearer
+func (c *UploadController) AbortUpload(ctx *gin.Context) {
+	if err := c.uploadService.AbortUpload(ctx.Request.Context(), ctx.GetUint("userID"), ctx.Param("uploadId")); err != nil {
+		abortWithError(ctx, toUploadAppError(err))
+		return
+	}
+	respondWithSuccess(ctx, constants.StatusOK, constants.DeleteSuccess)
+}
+
+// toUploadAppError アップロード固有のエラーをAppErrorに変換する
+func toUploadAppError(err error) error {
+	switch {
+	case errors.Is(err, services.ErrInvalidUploadPart), errors.Is(err, services.ErrUploadTooLarge):
+		return utils.NewBadRequestError(constants.ErrCodeInvalidRequest, constants.InvalidRequest).Wrap(err)
+	case errors.Is(err, services.ErrUploadIncomplete):
+		return utils.NewConflictError(constants.ErrCodeConflict, constants.UploadIncomplete).Wrap(err)
+	case errors.Is(err, services.ErrUnauthorized):
+		return utils.NewForbiddenError(constants.ErrCodeForbidden, constants.Forbidden).Wrap(err)
+	default:
+		return toAppError(err)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"strconv"
 
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/constants"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
 	"github.com/gin-gonic/gin"
 )
@@ -38,7 +39,7 @@ func (uc *UserController) GetApplyingClasses(ctx *gin.Context) {
 		return
 	}
 
-	classes, err := uc.userService.GetApplyingClasses(uint(userID))
+	classes, err := uc.userService.GetApplyingClasses(ctx.Request.Context(), uint(userID))
 	if err != nil {
 		if err.Error() == services.ErrUserNotFound {
 			respondWithError(ctx, constants.StatusNotFound, constants.UserNotFound)
@@ -74,7 +75,7 @@ func (uc *UserController) SearchByName(ctx *gin.Context) {
 		return
 	}
 
-	users, err := uc.userService.SearchUsersByName(name)
+	users, err := uc.userService.SearchUsersByName(ctx.Request.Context(), name)
 	if err != nil {
 		respondWithError(ctx, constants.StatusInternalServerError, err.Error())
 		return
@@ -102,7 +103,7 @@ func (c *UserController) RemoveUserFromService(ctx *gin.Context) {
 		return
 	}
 
-	err = c.userService.RemoveUserFromService(uint(userID))
+	err = c.userService.RemoveUserFromService(ctx.Request.Context(), uint(userID))
 	if err != nil {
 		if err.Error() == services.ErrUserNotFound {
 			respondWithError(ctx, constants.StatusNotFound, constants.UserNotFound)
@@ -114,3 +115,48 @@ func (c *UserController) RemoveUserFromService(ctx *gin.Context) {
 
 	respondWithSuccess(ctx, constants.StatusOK, gin.H{"deletedUserID": userID})
 }
+
+// GetAccessibilitySettings godoc
+// @Summary アクセシビリティの設定を取得
+// @Description ログインユーザーの文字の大きさ、ハイコントラスト、モーション低減の設定を返します。保存していない場合はデフォルトの設定を返します。
+// @Tags User
+// @Produce json
+// @Success 200 {object} dto.AccessibilitySettingsDTO "アクセシビリティの設定"
+// @Failure 500 {object} utils.ErrorResponse "サーバーエラーが発生しました"
+// @Router /u/accessibility [get]
+// @Security Bearer
+func (c *UserController) GetAccessibilitySettings(ctx *gin.Context) {
+	settings, err := c.userService.GetAccessibilitySettings(ctx.Request.Context(), ctx.GetUint("userID"))
+	if err != nil {
+		abortWithError(ctx, toAppError(err))
+		return
+	}
+	respondWithSuccess(ctx, constants.StatusOK, settings)
+}
+
+// UpdateAccessibilitySettings godoc
+// @Summary アクセシビリティの設定を変更
+// @Description ログインユーザーのアクセシビリティの設定を変更します。省略した項目は変更しません。
+// @Tags User
+// @Accept json
+// @Produce json
+// @Param request body dto.AccessibilitySettingsUpdateDTO true "変更する設定"
+// @Success 200 {object} dto.AccessibilitySettingsDTO "変更後のアクセシビリティの設定"
+// @Failure 400 {object} utils.ErrorResponse "無効なリクエスト"
+// @Failure 500 {object} utils.ErrorResponse "サーバーエラーが発生しました"
+// @Router /u/accessibility [patch]
+// @Security Bearer
+func (c *UserController) UpdateAccessibilitySettings(ctx *gin.Context) {
+	var request dto.AccessibilitySettingsUpdateDTO
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		abortWithError(ctx, newBindingError(err))
+		return
+	}
+
+	settings, err := c.userService.UpdateAccessibilitySettings(ctx.Request.Context(), ctx.GetUint("userID"), request)
+	if err != nil {
+		abortWithError(ctx, toAppError(err))
+		return
+	}
+	respondWithSuccess(ctx, constants.StatusOK, settings)
+}
@@ -0,0 +1,195 @@
+package controllers
+
+import (
+	"errors"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/constants"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// WebhookController クラスのWebhookのコントローラ
+type WebhookController struct {
+	webhookService services.WebhookService
+}
+
+// NewWebhookController WebhookControllerを生成
+func NewWebhookController(webhookService services.WebhookService) *WebhookController {
+	return &WebhookController{
+		webhookService: webhookService,
+	}
+}
+
+// GetWebhooks godoc
+// @Summary クラスのWebhook一覧
+// @Description クラスに登録したWebhookを登録順に取得します。秘密鍵は含みません。クラスの管理者のみ利用できます。
+// @Tags Webhook
+// @Produce json
+// @Param cid path int true "Class ID"
+// @Success 200 {array} dto.WebhookDTO "Webhook"
+// @Failure 400 {object} utils.ErrorResponse "無効なリクエスト"
+// @Failure 403 {object} utils.ErrorResponse "権限がありません"
+// @Failure 500 {object} utils.ErrorResponse "サーバーエラーが発生しました"
+// @Router /webhooks/{cid} [get]
+// @Security Bearer
+func (c *WebhookController) GetWebhooks(ctx *gin.Context) {
+	cid, ok := parseCommentParam(ctx, "cid")
+	if !ok {
+		return
+	}
+
+	webhooks, err := c.webhookService.GetWebhooks(ctx.Request.Context(), ctx.GetUint("userID"), cid)
+	if err != nil {
+		abortWithWebhookError(ctx, err)
+		return
+	}
+	respondWithSuccess(ctx, constants.StatusOK, webhooks)
+}
+
+// CreateWebhook godoc
+// @Summary クラスにWebhookを登録
+// @Description 掲示板の作成・スケジュールの変更・出席の変更・メンバーの参加を、指定したURLにJSONのPOSTで配信するWebhookを登録します。クラスの管理者のみ利用できます。各リクエストにはX-Minori-Event(イベントの種類)、X-Minori-Delivery(イベントのID。再送でも同じ)、X-Minori-Webhook-Version(ペイロードのバージョン)、X-Minori-Timestamp(UNIX秒)、X-Minori-Signatureヘッダーが付きます。署名は「{X-Minori-Timestamp}.{本文}」のHMAC-SHA256を秘密鍵で計算し、「sha256=」に続けて16進数で表したものです。秘密鍵を省略した場合は生成し、レスポンスで1度だけ返します。2xx以外の応答と10秒以内に応答がない場合は失敗とし、間隔を空けて最大5回まで送信します。再送しても配信できなかったイベントが続いた場合はWebhookを無効にし、クラスの管理者に通知します。
+// @Tags Webhook
+// @Accept json
+// @Produce json
+// @Param cid path int true "Class ID"
+// @Param request body dto.WebhookRequest true "Webhook"
+// @Success 201 {object} dto.WebhookDTO "登録したWebhook"
+// @Failure 400 {object} utils.ErrorResponse "無効なリクエスト"
+// @Failure 403 {object} utils.ErrorResponse "権限がありません"
+// @Failure 500 {object} utils.ErrorResponse "サーバーエラーが発生しました"
+// @Router /webhooks/{cid} [post]
+// @Security Bearer
+func (c *WebhookController) CreateWebhook(ctx *gin.Context) {
+	cid, ok := parseCommentParam(ctx, "cid")
+	if !ok {
+		return
+	}
+
+	var request dto.WebhookRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		abortWithError(ctx, newBindingError(err))
+		return
+	}
+
+	webhook, err := c.webhookService.CreateWebhook(ctx.Request.Context(), ctx.GetUint("userID"), cid, request)
+	if err != nil {
+		abortWithWebhookError(ctx, err)
+		return
+	}
+	respondWithSuccess(ctx, constants.StatusCreated, webhook)
+}
+
+// UpdateWebhook godoc
+// @Summary Webhookを更新
+// @Description Webhookの送信先・イベント・秘密鍵・有効かどうかを更新します。秘密鍵を省略した場合は変更しません。無効になったWebhookを有効にすると連続の失敗の件数を0に戻します。クラスの管理者のみ利用できます。
+// @Tags Webhook
+// @Accept json
+// @Produce json
+// @Param cid path int true "Class ID"
+// @Param id path int true "Webhook ID"
+// @Param request body dto.WebhookRequest true "Webhook"
+// @Success 200 {object} dto.WebhookDTO "更新したWebhook"
+// @Failure 400 {object} utils.ErrorResponse "無効なリクエスト"
+// @Failure 403 {object} utils.ErrorResponse "権限がありません"
+// @Failure 404 {object} utils.ErrorResponse "Webhookが見つかりません"
+// @Failure 500 {object} utils.ErrorResponse "サーバーエラーが発生しました"
+// @Router /webhooks/{cid}/{id} [patch]
+// @Security Bearer
+func (c *WebhookController) UpdateWebhook(ctx *gin.Context) {
+	cid, id, ok := parseWebhookParams(ctx)
+	if !ok {
+		return
+	}
+
+	var request dto.WebhookRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		abortWithError(ctx, newBindingError(err))
+		return
+	}
+
+	webhook, err := c.webhookService.UpdateWebhook(ctx.Request.Context(), ctx.GetUint("userID"), cid, id, request)
+	if err != nil {
+		abortWithWebhookError(ctx, err)
+		return
+	}
+	respondWithSuccess(ctx, constants.StatusOK, webhook)
+}
+
+// DeleteWebhook godoc
+// @Summary Webhookを削除
+// @Description Webhookと送信の記録を削除します。再送を待つイベントは送信しません。クラスの管理者のみ利用できます。
+// @Tags Webhook
+// @Param cid path int true "Class ID"
+// @Param id path int true "Webhook ID"
+// @Success 200 {string} string "削除成功"
+// @Failure 400 {object} utils.ErrorResponse "無効なリクエスト"
+// @Failure 403 {object} utils.ErrorResponse "権限がありません"
+// @Failure 404 {object} utils.ErrorResponse "Webhookが見つかりません"
+// @Failure 500 {object} utils.ErrorResponse "サーバーエラーが発生しました"
+// @Router /webhooks/{cid}/{id} [delete]
+// @Security Bearer
+func (c *WebhookController) DeleteWebhook(ctx *gin.Context) {
+	cid, id, ok := parseWebhookParams(ctx)
+	if !ok {
+		return
+	}
+
+	if err := c.webhookService.DeleteWebhook(ctx.Request.Context(), ctx.GetUint("userID"), cid, id); err != nil {
+		abortWithWebhookError(ctx, err)
+		return
+	}
+	respondWithSuccess(ctx, constants.StatusOK, constants.DeleteSuccess)
+}
+
+// GetDeliveries godoc
+// @Summary Webhookの送信の記録
+// @Description Webhookの最近の送信の記録を新しい順に最大50件取得します。再送もそれぞれ1件として記録します。クラスの管理者のみ利用できます。payloadは送信した本文で、eventに応じてboard・schedule・attendance・memberのいずれか1つを含みます。形式はversionごとに固定し、後方互換性のない変更はversionを上げて行います。
+// @Tags Webhook
+// @Produce json
+// @Param cid path int true "Class ID"
+// @Param id path int true "Webhook ID"
+// @Success 200 {array} dto.WebhookDeliveryDTO "送信の記録"
+// @Failure 400 {object} utils.ErrorResponse "無効なリクエスト"
+// @Failure 403 {object} utils.ErrorResponse "権限がありません"
+// @Failure 404 {object} utils.ErrorResponse "Webhookが見つかりません"
+// @Failure 500 {object} utils.ErrorResponse "サーバーエラーが発生しました"
+// @Router /webhooks/{cid}/{id}/deliveries [get]
+// @Security Bearer
+func (c *WebhookController) GetDeliveries(ctx *gin.Context) {
+	cid, id, ok := parseWebhookParams(ctx)
+	if !ok {
+		return
+	}
+
+	deliveries, err := c.webhookService.GetDeliveries(ctx.Request.Context(), ctx.GetUint("userID"), cid, id)
+	if err != nil {
+		abortWithWebhookError(ctx, err)
+		return
+	}
+	respondWithSuccess(ctx, constants.StatusOK, deliveries)
+}
+
+// parseWebhookParams パスのクラスIDとWebhookのIDを取得する
+func parseWebhookParams(ctx *gin.Context) (uint, uint, bool) {
+	cid, ok := parseCommentParam(ctx, "cid")
+	if !ok {
+		return 0, 0, false
+	}
+	id, ok := parseCommentParam(ctx, "id")
+	if !ok {
+		return 0, 0, false
+	}
+	return cid, id, true
+}
+
+// abortWithWebhookError 権限のエラーを403として登録し、それ以外はAppErrorに変換して登録する
+func abortWithWebhookError(ctx *gin.Context, err error) {
+	if errors.Is(err, services.ErrUnauthorized) {
+		abortWithError(ctx, utils.NewForbiddenError(constants.ErrCodeForbidden, constants.Forbidden))
+		return
+	}
+	abortWithError(ctx, toAppError(err))
+}
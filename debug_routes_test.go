@@ -0,0 +1,50 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/config"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/controllers"
+	"github.com/gin-gonic/gin"
+)
+
+// TestDebugRoutes はフラグが無効な場合にデバッグ用のルートが登録されず、有効な場合はトークンが必要なことを確認するテストです。
+func TestDebugRoutes(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+
+	cases := []struct {
+		name       string
+		enabled    bool
+		token      string
+		authHeader string
+		wantStatus int
+	}{
+		{"Flag Off", false, "secret", "Bearer secret", http.StatusNotFound},
+		{"Flag On Without Token Config", true, "", "Bearer secret", http.StatusNotFound},
+		{"Missing Token", true, "secret", "", http.StatusUnauthorized},
+		{"Wrong Token", true, "secret", "Bearer wrong", http.StatusUnauthorized},
+		{"Valid Token", true, "secret", "Bearer secret", http.StatusOK},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			router := gin.New()
+			setupDebugRoutes(router, config.DebugConfig{Enabled: tc.enabled, Token: tc.token}, controllers.NewDebugController(), controllers.NewMaintenanceController(nil), controllers.NewDigestController(nil))
+
+			for _, path := range []string{"/debug/vars", "/debug/pprof/", "/debug/pprof/heap"} {
+				req, _ := http.NewRequest(http.MethodGet, path, nil)
+				if tc.authHeader != "" {
+					req.Header.Set("Authorization", tc.authHeader)
+				}
+				resp := httptest.NewRecorder()
+				router.ServeHTTP(resp, req)
+
+				if resp.Code != tc.wantStatus {
+					t.Errorf("GET %s status = %d, want %d", path, resp.Code, tc.wantStatus)
+				}
+			}
+		})
+	}
+}
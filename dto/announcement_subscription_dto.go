@@ -0,0 +1,13 @@
+package dto
+
+import "time"
+
+// AnnouncementSubscriptionDTO - 登録済みの通知チャネル
+type AnnouncementSubscriptionDTO struct {
+	Channel      string     `json:"channel" example:"EMAIL"`
+	Target       string     `json:"target" example:"student@example.com"`
+	FailureCount int        `json:"failure_count" example:"0"`
+	DisabledAt   *time.Time `json:"disabled_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
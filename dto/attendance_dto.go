@@ -0,0 +1,66 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+)
+
+// AttendanceStreakDTO 連続出席(ストリーク)の記録
+type AttendanceStreakDTO struct {
+	UID uint `json:"uid"`
+	CID uint `json:"cid"`
+	// CurrentStreak 直近のスケジュールから遡って連続して出席したスケジュール数
+	CurrentStreak int `json:"current_streak"`
+	// LongestStreak これまでの最長の連続出席数
+	LongestStreak int `json:"longest_streak"`
+	// AllowTardy 遅刻を出席として数えたか
+	AllowTardy bool `json:"allow_tardy"`
+}
+
+// AttendanceModeSummaryDTO 出席方式ごとの出席状況の集計
+type AttendanceModeSummaryDTO struct {
+	// Mode 出席方式 (IN_PERSON, ONLINE, HYBRID)
+	Mode       string `json:"mode" example:"ONLINE"`
+	Attendance int64  `json:"attendance"`
+	Tardy      int64  `json:"tardy"`
+	Absence    int64  `json:"absence"`
+	Total      int64  `json:"total"`
+	// AttendanceRate 出席の割合(%)。遅刻は含めない。出席の記録がない場合は0
+	AttendanceRate float64 `json:"attendance_rate" example:"87.5"`
+}
+
+// TokenCheckInRequest 出席トークンによる自己チェックイン
+type TokenCheckInRequest struct {
+	// Token 講師画面のQRコードから読み取った出席トークン
+	Token string `json:"token" binding:"required,max=200"`
+	// CheckInCode 講師が伝えた確認コード。確認コードを必須にしたスケジュールで指定する
+	CheckInCode string `json:"check_in_code" binding:"max=10"`
+	// RecordedAt トークンを読み取った端末の時刻。参考値として保存し、記録時刻にはサーバーの時刻を使用する
+	RecordedAt *time.Time `json:"recorded_at"`
+}
+
+// TokenCheckInResultDTO 出席トークンによる自己チェックインの結果
+type TokenCheckInResultDTO struct {
+	CID  uint `json:"cid" example:"1"`
+	CSID uint `json:"csid" example:"12"`
+	// Recorded 出席を記録した場合はtrue。既に出席情報があり変更しなかった場合はfalse
+	Recorded bool `json:"recorded"`
+}
+
+// AttendanceListFilter クラスの出席情報の一覧の絞り込み条件。ゼロ値の条件では絞り込まない
+type AttendanceListFilter struct {
+	Status models.AttendanceType
+	CSID   uint
+}
+
+// AttendancePage クラスの出席情報の1ページ。v2のレスポンスではAttendancesをdata、それ以外をmetaとして返す
+type AttendancePage struct {
+	Attendances []models.Attendance
+	// Total 絞り込み条件に一致する出席情報の総数
+	Total int64
+	Page  int
+	Limit int
+	// NextPage 次のページの番号。最後のページの場合はnil
+	NextPage *int
+}
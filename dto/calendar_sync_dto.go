@@ -0,0 +1,28 @@
+package dto
+
+import "time"
+
+// CalendarSyncSettingsDTO ユーザーのGoogleカレンダーへのスケジュールの同期の設定
+type CalendarSyncSettingsDTO struct {
+	// Connected Googleのログインでカレンダーの権限を許可している
+	Connected bool `json:"connected" example:"true"`
+	Enabled   bool `json:"enabled" example:"true"`
+	// CIDs 同期するクラス。空の場合は参加している全てのクラスを同期する
+	CIDs []uint `json:"cids"`
+	// DisabledReason トークンの更新に失敗して同期を停止した理由。停止していない場合は空
+	DisabledReason string     `json:"disabled_reason,omitempty"`
+	DisabledAt     *time.Time `json:"disabled_at,omitempty"`
+}
+
+// CalendarSyncSettingsRequest Googleカレンダーへの同期の設定を変更するためのDTO。
+// 同期するクラスを限定する場合は、ユーザーが管理者のクラスのみ指定できる
+type CalendarSyncSettingsRequest struct {
+	Enabled *bool  `json:"enabled" binding:"required" example:"true"`
+	CIDs    []uint `json:"cids" binding:"max=50"`
+}
+
+// CalendarSyncResultDTO クラスの既存のスケジュールの同期を予約した結果
+type CalendarSyncResultDTO struct {
+	// Queued 同期を予約したスケジュールの数
+	Queued int `json:"queued" example:"12"`
+}
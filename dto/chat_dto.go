@@ -0,0 +1,60 @@
+package dto
+
+import (
+	"mime/multipart"
+	"time"
+)
+
+// ChatMessageType チャットメッセージの種類
+const (
+	ChatMessageTypeText    = "text"
+	ChatMessageTypeSticker = "sticker"
+)
+
+// ChatStickerCreateDTO スタンプを登録するためのDTO
+type ChatStickerCreateDTO struct {
+	Name  string                `form:"name" binding:"required,max=50"`
+	Image *multipart.FileHeader `form:"image" binding:"required"`
+}
+
+// ChatStickerDTO チャットのスタンプ
+type ChatStickerDTO struct {
+	ID       uint   `json:"id"`
+	CID      uint   `json:"cid"`
+	Name     string `json:"name"`
+	ImageURL string `json:"image_url"`
+}
+
+// ChatMessageDTO チャットルームのメッセージ。typeがstickerの場合はstickerを持つ
+type ChatMessageDTO struct {
+	ID      int64           `json:"id"`
+	Type    string          `json:"type"`
+	User    string          `json:"user"`
+	Text    string          `json:"text,omitempty"`
+	Sticker *ChatStickerDTO `json:"sticker,omitempty"`
+	// HTML textのMarkdownをサニタイズ済みのHTMLに変換したもの。取得時にformat=htmlを指定した場合のみ付与する
+	HTML string `json:"html,omitempty"`
+	// Sender 送信者のプロフィール。メッセージの取得時にのみ付与する
+	Sender *UserProfileDTO `json:"sender,omitempty"`
+}
+
+// ChatTranslationDTO チャットのメッセージの翻訳。原文と対で返す
+type ChatTranslationDTO struct {
+	MessageID      int64  `json:"message_id"`
+	OriginalText   string `json:"original_text"`
+	TranslatedText string `json:"translated_text"`
+	// SourceLang 翻訳APIが判定した原文の言語
+	SourceLang string `json:"source_lang"`
+	TargetLang string `json:"target_lang"`
+}
+
+// ChatDraftSaveDTO 下書きを保存するためのDTO
+type ChatDraftSaveDTO struct {
+	Content string `json:"content" binding:"required,max=10000"`
+}
+
+// ChatDraftDTO チャットルームに書きかけのメッセージ
+type ChatDraftDTO struct {
+	Content   string    `json:"content"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
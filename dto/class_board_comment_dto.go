@@ -0,0 +1,26 @@
+package dto
+
+import "time"
+
+// ClassBoardCommentCreateDTO - グループ掲示板にコメントするためのDTO
+type ClassBoardCommentCreateDTO struct {
+	Content string `json:"content" binding:"required,max=2000" example:"資料ありがとうございます"`
+	// ParentCommentID リプライ先のコメントID。省略した場合は掲示板へのコメントになる
+	ParentCommentID *uint `json:"parent_comment_id" example:"1"`
+}
+
+// ClassBoardCommentDTO - 返信数といいね数を含むコメント
+type ClassBoardCommentDTO struct {
+	ID              uint                `json:"id" example:"1"`
+	BID             uint                `json:"bid" example:"1"`
+	ParentCommentID *uint               `json:"parent_comment_id"`
+	Content         string              `json:"content" example:"資料ありがとうございます"`
+	CreatedAt       time.Time           `json:"created_at"`
+	UpdatedAt       time.Time           `json:"updated_at"`
+	Author          ClassBoardAuthorDTO `json:"author"`
+	// ReplyCount リプライの数。リプライの場合は常に0
+	ReplyCount int64 `json:"reply_count" example:"2"`
+	LikeCount  int64 `json:"like_count" example:"5"`
+	// Liked 取得したユーザーがいいね済みか
+	Liked bool `json:"liked" example:"false"`
+}
@@ -1,6 +1,9 @@
 package dto
 
-import "mime/multipart"
+import (
+	"mime/multipart"
+	"time"
+)
 
 // ClassBoardCreateDTO - グループ掲示板を作成するためのDTO
 type ClassBoardCreateDTO struct {
@@ -11,6 +14,18 @@ type ClassBoardCreateDTO struct {
 	IsAnnounced bool `json:"is_announced" form:"is_announced" default:"false"`
 	CID         uint `json:"cid" form:"cid"  binding:"required"`
 	UID         uint `json:"uid" form:"uid"  binding:"required"`
+	// Language タイトルと本文の言語。利用者の言語の版がない場合はこの版を表示する
+	Language string `json:"language" form:"language" binding:"max=10" example:"ja"`
+	// VariantsJSON 他の言語の版のJSONの配列。multipart/form-dataでは配列を送れないため文字列で受け取り、コントローラでVariantsに読み込む
+	VariantsJSON string                 `form:"variants"`
+	Variants     []ClassBoardVariantDTO `form:"-" binding:"max=10,dive"`
+}
+
+// ClassBoardVariantDTO - グループ掲示板の他の言語の版
+type ClassBoardVariantDTO struct {
+	Language string `json:"language" binding:"required,max=10" example:"en"`
+	Title    string `json:"title" binding:"required,max=255" example:"Sample Title"`
+	Content  string `json:"content" binding:"required" example:"Sample Content"`
 }
 
 // ClassBoardUpdateDTO - グループ掲示板を更新するためのDTO
@@ -20,4 +35,42 @@ type ClassBoardUpdateDTO struct {
 	Content     string `json:"content" form:"content"`
 	Image       string `json:"image" form:"image"`
 	IsAnnounced bool   `json:"is_announced" form:"is_announced"`
+	// Version 読み込んだ掲示板のバージョン。他の更新が保存されていた場合は409を返す。省略した場合は後勝ちで更新する(非推奨)
+	Version uint `json:"version" form:"version" example:"3"`
+}
+
+// ClassBoardAuthorDTO - グループ掲示板の投稿者の公開プロフィール
+type ClassBoardAuthorDTO struct {
+	ID        uint   `json:"id" example:"1"`
+	Name      string `json:"name" example:"山田太郎"`
+	AvatarURL string `json:"avatar_url" example:"https://example.com/avatar.png"`
+}
+
+// ClassBoardDetailDTO - グループ掲示板の詳細。既存のクライアントのため、掲示板の項目名はモデルと同じにする
+type ClassBoardDetailDTO struct {
+	ID          uint
+	Title       string
+	Content     string
+	Image       string
+	ScanStatus  string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	IsAnnounced bool
+	ViewCount   uint
+	IsPinned    bool
+	PinnedUntil *time.Time
+	CID         uint
+	UID         uint
+	Author      ClassBoardAuthorDTO `json:"author"`
+	// Language 表示している版の言語。言語を指定していない掲示板は空
+	Language string `json:"language" example:"en"`
+	// Languages 掲示板を表示できる言語
+	Languages []string `json:"languages" example:"ja,en"`
+}
+
+// ClassBoardPinDTO - グループ掲示板のピン留めを設定するためのDTO
+type ClassBoardPinDTO struct {
+	Pinned bool `json:"pinned"`
+	// PinUntil ピン留めを自動的に解除する日時。省略した場合は期限なし
+	PinUntil *time.Time `json:"pin_until" example:"2026-04-01T00:00:00+09:00"`
 }
@@ -1,16 +1,93 @@
 package dto
 
+import "time"
+
 // CreateClassRequest クラス作成リクエストDTO
 type CreateClassRequest struct {
-	Name        string  `form:"name"`                   // クラス名
-	Limitation  *int    `form:"limitation"`             // 参加制限人数
-	Description *string `form:"description"`            // クラス説明
-	UID         uint    `form:"uid" binding:"required"` // ユーザID
+	Name        string  `form:"name" binding:"required,max=30"`          // クラス名
+	Limitation  *int    `form:"limitation"`                              // 参加制限人数
+	Description *string `form:"description" binding:"omitempty,max=255"` // クラス説明
+	UID         uint    `form:"uid" binding:"required"`                  // ユーザID
 	Secret      *string `form:"secret,omitempty"`
+	IsPublic    bool    `form:"is_public"`                           // 公開クラスの検索に表示する
+	Language    *string `form:"language" binding:"omitempty,max=10"` // 授業の言語
 }
 
 type UpdateClassRequest struct {
 	Name        string  `form:"name"`
 	Limitation  *int    `form:"limitation"`
 	Description *string `form:"description"`
+	IsPublic    *bool   `form:"is_public"`
+	Language    *string `form:"language" binding:"omitempty,max=10"`
+}
+
+// PublicClassDTO 公開クラスの検索結果。認証なしで返すため、メンバーの個人情報は含めない
+type PublicClassDTO struct {
+	ID                    uint    `json:"id"`
+	Name                  string  `json:"name"`
+	Description           *string `json:"description,omitempty"`
+	Image                 *string `json:"image,omitempty"`
+	Language              *string `json:"language,omitempty"`
+	MemberCount           int64   `json:"member_count"`
+	UpcomingScheduleCount int64   `json:"upcoming_schedule_count"`
+}
+
+// ClassPreviewDTO クラス参加前に表示する公開情報
+type ClassPreviewDTO struct {
+	ID             uint                     `json:"id"`
+	Name           string                   `json:"name"`
+	Description    *string                  `json:"description,omitempty"`
+	Image          *string                  `json:"image,omitempty"`
+	Limitation     *int                     `json:"limitation,omitempty"`
+	InstructorName string                   `json:"instructor_name,omitempty"`
+	MemberCount    int64                    `json:"member_count"`
+	NextSchedule   *ClassPreviewScheduleDTO `json:"next_schedule,omitempty" gorm:"-"`
+	IsPrivate      bool                     `json:"is_private" gorm:"-"`
+}
+
+// ClassPreviewScheduleDTO プレビューに表示する次回のスケジュール
+type ClassPreviewScheduleDTO struct {
+	Title       string    `json:"title"`
+	StartedAt   time.Time `json:"started_at"`
+	EndedAt     time.Time `json:"ended_at"`
+	IsCancelled bool      `json:"is_cancelled"`
+}
+
+// InactiveClassDTO 最後のスケジュールから活動がない自動アーカイブの候補
+type InactiveClassDTO struct {
+	ID                  uint       `json:"id"`
+	Name                string     `json:"name"`
+	LastEndedAt         time.Time  `json:"last_ended_at"`
+	ArchiveNoticeSentAt *time.Time `json:"archive_notice_sent_at"`
+}
+
+// ArchiveExemptRequest 自動アーカイブからの除外を設定するリクエスト
+type ArchiveExemptRequest struct {
+	Exempt *bool `json:"exempt" binding:"required"`
+}
+
+// ClassAccessRestrictionDTO クラスへのアクセスを許可する時間帯とIPレンジ。項目を省略した場合はその項目を制限しない
+type ClassAccessRestrictionDTO struct {
+	// StartTime 許可する時間帯の開始 (HH:MM)。EndTimeと合わせて指定し、サーバーのタイムゾーンで判定する
+	StartTime *string `json:"start_time" example:"09:00"`
+	// EndTime 許可する時間帯の終了 (HH:MM)。開始より前の場合は日をまたぐ
+	EndTime *string `json:"end_time" example:"18:00"`
+	// AllowedIPRanges 許可するIPレンジ (CIDRまたはIPアドレス)
+	AllowedIPRanges []string `json:"allowed_ip_ranges" binding:"max=50" example:"203.0.113.0/24"`
+}
+
+// ClassStatsDTO クラスの状況をまとめた統計。メンバー数は参加申請中とブロック済みを含めない
+type ClassStatsDTO struct {
+	TotalMembers int64 `json:"total_members"`
+	// ActiveMembers 直近30日に出席または掲示板に投稿したメンバー数
+	ActiveMembers         int64   `json:"active_members"`
+	AdminCount            int64   `json:"admin_count"`
+	AssistantCount        int64   `json:"assistant_count"`
+	StudentCount          int64   `json:"student_count"`
+	TotalBoards           int64   `json:"total_boards"`
+	TotalSchedules        int64   `json:"total_schedules"`
+	UpcomingScheduleCount int64   `json:"upcoming_schedule_count"`
+	OverallAttendanceRate float64 `json:"overall_attendance_rate"`
+	// ActiveChatRooms 直近1時間以内に発言があったスケジュールのチャットの数
+	ActiveChatRooms int64 `json:"active_chat_rooms"`
 }
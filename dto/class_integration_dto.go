@@ -0,0 +1,29 @@
+package dto
+
+import "time"
+
+// ClassIntegrationRequest - クラスのチャットサービスとの連携を登録するためのDTO
+type ClassIntegrationRequest struct {
+	Provider   string   `json:"provider" binding:"required,oneof=SLACK DISCORD" example:"SLACK"`
+	WebhookURL string   `json:"webhook_url" binding:"required,url,max=2048" example:"https://hooks.slack.com/services/T0000/B0000/XXXXXXXX"`
+	Events     []string `json:"events" binding:"required,min=1,dive,oneof=board.announced schedule.changed live.started" example:"board.announced,live.started"`
+}
+
+// ClassIntegrationDTO - クラスのチャットサービスとの連携
+type ClassIntegrationDTO struct {
+	CID      uint   `json:"cid" example:"1"`
+	Provider string `json:"provider" example:"SLACK"`
+	// WebhookURL 投稿先のURL。URLに含まれるトークンを隠すため、最後のパスを伏せて返す
+	WebhookURL string   `json:"webhook_url" example:"https://hooks.slack.com/services/T0000/B0000/****"`
+	Events     []string `json:"events" example:"board.announced,live.started"`
+	// Status 投稿の状態。ok(最後の投稿に成功)・failing(最後の投稿に失敗)・disabled(投稿先が拒否し続けたため停止)のいずれか
+	Status    string `json:"status" example:"ok"`
+	LastError string `json:"last_error,omitempty" example:"unexpected status 404: no_service"`
+	// ConsecutiveClientErrors 投稿先が4xxを返した連続の回数
+	ConsecutiveClientErrors int        `json:"consecutive_client_errors" example:"0"`
+	LastPostedAt            *time.Time `json:"last_posted_at"`
+	LastFailedAt            *time.Time `json:"last_failed_at"`
+	DisabledAt              *time.Time `json:"disabled_at"`
+	CreatedAt               time.Time  `json:"created_at"`
+	UpdatedAt               time.Time  `json:"updated_at"`
+}
@@ -0,0 +1,18 @@
+package dto
+
+// ClassInvitationRequest メールアドレスを指定してクラスに招待するリクエスト。1回に50件まで指定できる
+type ClassInvitationRequest struct {
+	Emails []string `json:"emails" binding:"required,min=1,max=50,dive,email"`
+	// Locale アカウントがない宛先に送信するメールの言語。省略した場合は日本語にする
+	Locale string `json:"locale" binding:"omitempty,oneof=ja en"`
+}
+
+// ClassInvitationResultDTO 招待メールの送信結果
+type ClassInvitationResultDTO struct {
+	// Invited 招待メールの送信を予約したアドレス
+	Invited []string `json:"invited"`
+	// Skipped 既にクラスのメンバーのため送信しなかったアドレス
+	Skipped []string `json:"skipped"`
+	// Remaining 今日送信できる残りの招待メールの件数。全てのクラスの招待を合わせて数える
+	Remaining int `json:"remaining"`
+}
@@ -11,6 +11,41 @@ type ClassScheduleDTO struct {
 	EndedAt   time.Time `json:"ended_at" binding:"required"`
 	CID       uint      `json:"cid" binding:"required"`
 	IsLive    bool      `json:"is_live"`
+	// AttendanceMode 出席方式。場所の種類を兼ねる。省略した場合はIN_PERSON
+	AttendanceMode string `json:"attendance_mode" binding:"omitempty,oneof=IN_PERSON ONLINE HYBRID" example:"IN_PERSON"`
+	// Location 教室の名前やオンライン授業のURL
+	Location string `json:"location" binding:"max=255" example:"本館301教室"`
+	// RequireCheckInCode 自己チェックインに講師画面の確認コードの入力を必須にする
+	RequireCheckInCode bool `json:"require_check_in_code"`
+}
+
+// ClassScheduleClassDTO スケジュールの表示に使うクラスの情報
+type ClassScheduleClassDTO struct {
+	ID       uint    `json:"id" example:"1"`
+	Name     string  `json:"name" example:"数学"`
+	ImageURL *string `json:"image_url" example:"https://example.com/class.png"`
+}
+
+// ClassScheduleDetailDTO クラススケジュールの詳細。既存のクライアントのため、スケジュールの項目名はモデルと同じにする
+type ClassScheduleDetailDTO struct {
+	ID          uint
+	Title       string
+	StartedAt   time.Time
+	EndedAt     time.Time
+	CID         uint
+	IsLive      bool
+	IsCancelled bool
+	// AttendanceMode 出席方式 (IN_PERSON, ONLINE, HYBRID)
+	AttendanceMode string `json:"attendance_mode" example:"HYBRID"`
+	// CheckInMethods 出席方式で有効なチェックイン手段 (QR, CODE, LOCATION, LIVE)
+	CheckInMethods []string `json:"check_in_methods"`
+	// Location 教室の名前やオンライン授業のURL
+	Location string                `json:"location" example:"本館301教室"`
+	Class    ClassScheduleClassDTO `json:"class"`
+	// RequireCheckInCode 自己チェックインに確認コードが必要
+	RequireCheckInCode bool `json:"require_check_in_code"`
+	// Version 楽観ロックのバージョン。更新時に送信する
+	Version uint `json:"version" example:"3"`
 }
 
 // UpdateClassScheduleDTO クラススケジュール更新DTO
@@ -19,4 +54,48 @@ type UpdateClassScheduleDTO struct {
 	StartedAt *time.Time `json:"started_at"`
 	EndedAt   *time.Time `json:"ended_at"`
 	IsLive    *bool      `json:"is_live"`
+	// AttendanceMode 出席方式
+	AttendanceMode *string `json:"attendance_mode" binding:"omitempty,oneof=IN_PERSON ONLINE HYBRID" example:"ONLINE"`
+	// Location 教室の名前やオンライン授業のURL
+	Location *string `json:"location" binding:"omitempty,max=255" example:"https://meet.example.com/abc"`
+	// RequireCheckInCode 自己チェックインに講師画面の確認コードの入力を必須にする
+	RequireCheckInCode *bool `json:"require_check_in_code"`
+	// Version 読み込んだスケジュールのバージョン。他の更新が保存されていた場合は409を返す。省略した場合は後勝ちで更新する(非推奨)
+	Version uint `json:"version" example:"3"`
+}
+
+// TodayClassDTO 当日に授業があるクラスとその日のスケジュール
+type TodayClassDTO struct {
+	ID        uint                    `json:"id"`
+	Name      string                  `json:"name"`
+	Image     *string                 `json:"image"`
+	Schedules []TodayClassScheduleDTO `json:"schedules"`
+}
+
+// TodayClassScheduleDTO 当日のスケジュール
+type TodayClassScheduleDTO struct {
+	ID          uint      `json:"id"`
+	Title       string    `json:"title"`
+	StartedAt   time.Time `json:"started_at"`
+	EndedAt     time.Time `json:"ended_at"`
+	IsLive      bool      `json:"is_live"`
+	IsCancelled bool      `json:"is_cancelled"`
+	Location    string    `json:"location"`
+	// AttendanceMode 出席方式 (IN_PERSON, ONLINE, HYBRID)
+	AttendanceMode string `json:"attendance_mode"`
+}
+
+// CheckInCodeDTO 講師画面に表示する自己チェックインの確認コード
+type CheckInCodeDTO struct {
+	Code string `json:"code" example:"4821"`
+	// ExpiresAt 確認コードが更新される日時。この日時を過ぎたら取得し直す
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// CheckInTokenDTO 講師画面のQRコードに表示する署名付きの出席トークン
+type CheckInTokenDTO struct {
+	Token string `json:"token" example:"12.1718000000.x3Jb0..."`
+	CSID  uint   `json:"csid" example:"12"`
+	// ExpiresAt トークンの有効期限。講師画面はこの日時までに発行し直してQRコードを切り替える
+	ExpiresAt time.Time `json:"expires_at"`
 }
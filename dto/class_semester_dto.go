@@ -0,0 +1,55 @@
+package dto
+
+import "time"
+
+// ClassSemesterDTO - クラスの学期を登録するためのDTO。日付はYYYY-MM-DDで指定し、終了日はその日の終わりまでを含む
+type ClassSemesterDTO struct {
+	StartDate string `json:"start_date" binding:"required" example:"2026-04-01"`
+	EndDate   string `json:"end_date" binding:"required" example:"2026-09-30"`
+}
+
+// SemesterDTO - 登録済みの学期
+type SemesterDTO struct {
+	Name     string    `json:"name" example:"2026-spring"`
+	StartsAt time.Time `json:"starts_at"`
+	EndsAt   time.Time `json:"ends_at"`
+}
+
+// GradeThresholdDTO - 出席率を成績に変換する基準
+type GradeThresholdDTO struct {
+	Grade string `json:"grade" binding:"required,max=5" example:"A"`
+	// MinRate 成績になる出席率(%)の下限
+	MinRate float64 `json:"min_rate" binding:"min=0,max=100" example:"90"`
+	// GradePoint GPAの計算に使う成績の点数
+	GradePoint float64 `json:"grade_point" binding:"min=0" example:"4"`
+}
+
+// GradeScaleDTO - クラスの成績の基準
+type GradeScaleDTO struct {
+	Thresholds []GradeThresholdDTO `json:"thresholds" binding:"required,min=1,max=20,dive"`
+	// IsDefault クラスで基準を設定していないため、既定の基準を使っているか
+	IsDefault bool `json:"is_default"`
+}
+
+// SemesterAttendanceGradeDTO - 学期の出席成績。成績管理システムへの連携に使う
+type SemesterAttendanceGradeDTO struct {
+	UID      uint      `json:"uid"`
+	CID      uint      `json:"cid"`
+	Semester string    `json:"semester" example:"2026-spring"`
+	StartsAt time.Time `json:"starts_at"`
+	EndsAt   time.Time `json:"ends_at"`
+	// Final 学期が終了し、成績が確定しているか
+	Final bool `json:"final"`
+	// TotalSessions 集計した終了済みのスケジュール数。休講は含めず、出席の記録がないスケジュールは欠席として数える
+	TotalSessions int `json:"total_sessions"`
+	Attendance    int `json:"attendance"`
+	Tardy         int `json:"tardy"`
+	Absence       int `json:"absence"`
+	// AttendanceRate 出席の割合(%)。遅刻は含めない
+	AttendanceRate float64 `json:"attendance_rate" example:"87.5"`
+	// Grade 出席率を成績の基準で変換した成績。集計するスケジュールがない場合はnull
+	Grade        *string       `json:"grade" example:"B"`
+	GradePoint   *float64      `json:"grade_point" example:"3"`
+	Scale        GradeScaleDTO `json:"scale"`
+	CalculatedAt time.Time     `json:"calculated_at"`
+}
@@ -0,0 +1,29 @@
+package dto
+
+import "time"
+
+// ClassTimelineEventType クラスのタイムラインの出来事の種類
+type ClassTimelineEventType string
+
+const (
+	TimelineBoardPosted   ClassTimelineEventType = "board_posted"   // 掲示の投稿
+	TimelineScheduleAdded ClassTimelineEventType = "schedule_added" // スケジュールの追加
+	TimelineMemberJoined  ClassTimelineEventType = "member_joined"  // メンバーの参加
+	TimelineRoleChanged   ClassTimelineEventType = "role_changed"   // メンバーのロールの変更
+)
+
+// ClassTimelineEventTypes 全てのタイムラインの出来事の種類
+var ClassTimelineEventTypes = []ClassTimelineEventType{TimelineBoardPosted, TimelineScheduleAdded, TimelineMemberJoined, TimelineRoleChanged}
+
+// ClassTimelineEventDTO - クラスのタイムラインの出来事
+type ClassTimelineEventDTO struct {
+	Type       ClassTimelineEventType `json:"type" example:"board_posted"`
+	OccurredAt time.Time              `json:"occurred_at"`
+	// ResourceID 掲示・スケジュールのID。メンバーの参加とロールの変更では対象のユーザーID
+	ResourceID uint `json:"resource_id" example:"12"`
+	// ActorUID 操作したユーザーID。掲示の投稿者、参加したメンバー、ロールを変更したユーザー。スケジュールの追加では記録していないため省略する
+	ActorUID *uint `json:"actor_uid,omitempty" example:"2"`
+	// Title 掲示・スケジュールのタイトル。メンバーの参加とロールの変更では対象のメンバーのクラスでの名前。
+	// ロールの変更では変更後のロールを含めない
+	Title string `json:"title" example:"第3回の課題について"`
+}
@@ -1,5 +1,7 @@
 package dto
 
+import "time"
+
 type UserClassInfoDTO struct {
 	ID          uint   `json:"id"`
 	Name        string `json:"name"`
@@ -8,11 +10,114 @@ type UserClassInfoDTO struct {
 	Image       string `json:"image"`
 	IsFavorite  bool   `json:"is_favorite"`
 	Role        string `json:"role"`
+	IsArchived  bool   `json:"is_archived"`
+}
+
+// BatchFavoriteRequest 複数のクラスのお気に入りを一括で設定するリクエスト
+type BatchFavoriteRequest struct {
+	ClassIDs   []uint `json:"class_ids" binding:"required,min=1,max=500,dive,gt=0"`
+	IsFavorite *bool  `json:"is_favorite" binding:"required"`
 }
 
 type ClassMemberDTO struct {
-	Uid      uint   `json:"uid"`
-	Nickname string `json:"nickname"`
-	Role     string `json:"role"`
-	Image    string `json:"image"`
+	Uid             uint   `json:"uid"`
+	Nickname        string `json:"nickname"`
+	Role            string `json:"role"`
+	Image           string `json:"image"`
+	JoinedViaCodeID *uint  `json:"joined_via_code_id,omitempty"`
+}
+
+// ClassMemberExportDTO クラス名簿のCSVエクスポート用の行
+type ClassMemberExportDTO struct {
+	UID            uint      `json:"uid"`
+	Name           string    `json:"name"`
+	Email          string    `json:"email"`
+	Role           string    `json:"role"`
+	JoinedAt       time.Time `json:"joined_at"`
+	AttendanceRate float64   `json:"attendance_rate"`
+}
+
+// MemberActivityStatsDTO メンバーごとの活動指標
+type MemberActivityStatsDTO struct {
+	UID            uint    `json:"uid"`
+	Nickname       string  `json:"nickname"`
+	BoardPosts     int64   `json:"board_posts"`
+	AttendanceRate float64 `json:"attendance_rate"`
+	ChatMessages   int64   `json:"chat_messages"`
+}
+
+// MemberActivityRankingDTO 活動度ランキングの1行
+type MemberActivityRankingDTO struct {
+	Rank           int     `json:"rank"`
+	UID            *uint   `json:"uid,omitempty"`
+	Nickname       string  `json:"nickname"`
+	BoardPosts     int64   `json:"board_posts"`
+	AttendanceRate float64 `json:"attendance_rate"`
+	ChatMessages   int64   `json:"chat_messages"`
+	Score          float64 `json:"score"`
+}
+
+// ActivityWeights 活動度スコアの各指標の重み
+type ActivityWeights struct {
+	Board      float64 `form:"board_weight"`
+	Attendance float64 `form:"attendance_weight"`
+	Chat       float64 `form:"chat_weight"`
+}
+
+// RemovedMemberDTO クラスから削除されたメンバー
+type RemovedMemberDTO struct {
+	Uid       uint      `json:"uid"`
+	Nickname  string    `json:"nickname"`
+	Role      string    `json:"role"`
+	Image     string    `json:"image"`
+	RemovedAt time.Time `json:"removed_at"`
+}
+
+// 移動・コピーしたメンバーごとの結果
+const (
+	MemberTransferMoved                = "moved"
+	MemberTransferCopied               = "copied"
+	MemberTransferSkippedAlreadyMember = "skipped_already_member"
+	MemberTransferSkippedNotMember     = "skipped_not_member"
+	MemberTransferSkippedAdmin         = "skipped_admin"
+	MemberTransferSkippedClassLimit    = "skipped_class_limit"
+)
+
+// MoveMembersRequest クラス間でメンバーを移動・コピーするリクエスト
+type MoveMembersRequest struct {
+	FromCID uint   `json:"from_cid" binding:"required" example:"1"`
+	ToCID   uint   `json:"to_cid" binding:"required" example:"2"`
+	UIDs    []uint `json:"uids" binding:"required,min=1,max=500"`
+	// Role 移動先で割り当てるロール。ADMINは指定できない
+	Role string `json:"role" binding:"required" example:"USER"`
+	// Copy trueの場合は移動元にメンバーを残す
+	Copy bool `json:"copy"`
+}
+
+// MemberTransferResultDTO メンバーごとの移動・コピーの結果
+type MemberTransferResultDTO struct {
+	UID    uint   `json:"uid"`
+	Status string `json:"status" example:"moved"`
+}
+
+// MoveMembersResultDTO クラス間のメンバーの移動・コピーの結果
+type MoveMembersResultDTO struct {
+	FromCID     uint                      `json:"from_cid"`
+	ToCID       uint                      `json:"to_cid"`
+	Copy        bool                      `json:"copy"`
+	Transferred int                       `json:"transferred"`
+	Skipped     int                       `json:"skipped"`
+	Results     []MemberTransferResultDTO `json:"results"`
+}
+
+// ClassEventRoleChanged メンバーのロールが変更されたイベント
+const ClassEventRoleChanged = "role_changed"
+
+// ClassEventDTO クラスの管理者に配信するイベント
+type ClassEventDTO struct {
+	Type    string `json:"type" example:"role_changed"`
+	CID     uint   `json:"cid"`
+	UID     uint   `json:"uid"`
+	OldRole string `json:"old_role,omitempty" example:"USER"`
+	NewRole string `json:"new_role,omitempty" example:"ASSISTANT"`
 }
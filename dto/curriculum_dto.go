@@ -0,0 +1,57 @@
+package dto
+
+import "time"
+
+// CurriculumItemRequest - カリキュラムの項目を作成・更新するためのDTO
+type CurriculumItemRequest struct {
+	Title string `json:"title" binding:"required,max=100" example:"第1章 変数と型"`
+	// Order 表示順。作成時に省略した場合は最後に追加し、更新時に省略した場合は変更しない
+	Order *int `json:"order" binding:"omitempty,min=0" example:"1"`
+}
+
+// CurriculumItemDTO - カリキュラムの項目
+type CurriculumItemDTO struct {
+	ID    uint   `json:"id" example:"1"`
+	CID   uint   `json:"cid" example:"1"`
+	Title string `json:"title" example:"第1章 変数と型"`
+	Order int    `json:"order" example:"1"`
+	// CompletedAt ログインユーザーが項目を完了した日時。未完了の場合はnull
+	CompletedAt *time.Time `json:"completed_at"`
+}
+
+// UserCurriculumProgressDTO - 生徒のカリキュラムの進捗
+type UserCurriculumProgressDTO struct {
+	UID            uint `json:"uid" example:"1"`
+	CompletedCount int  `json:"completed_count" example:"3"`
+	TotalCount     int  `json:"total_count" example:"10"`
+	// ProgressRate 完了した項目の割合(0から1)。項目が無い場合は0
+	ProgressRate     float64 `json:"progress_rate" example:"0.3"`
+	CompletedItemIDs []uint  `json:"completed_item_ids"`
+}
+
+// ClassCurriculumProgressDTO - クラス全体のカリキュラムの進捗
+type ClassCurriculumProgressDTO struct {
+	TotalCount   int `json:"total_count" example:"10"`
+	StudentCount int `json:"student_count" example:"20"`
+	// AverageProgressRate 生徒の進捗率の平均。生徒がいない場合は0
+	AverageProgressRate float64                        `json:"average_progress_rate" example:"0.45"`
+	Students            []StudentCurriculumProgressDTO `json:"students"`
+	Items               []CurriculumItemProgressDTO    `json:"items"`
+}
+
+// StudentCurriculumProgressDTO - クラス全体の進捗に含める生徒ごとの進捗
+type StudentCurriculumProgressDTO struct {
+	UID            uint    `json:"uid" example:"1"`
+	Nickname       string  `json:"nickname" example:"山田"`
+	CompletedCount int     `json:"completed_count" example:"3"`
+	ProgressRate   float64 `json:"progress_rate" example:"0.3"`
+}
+
+// CurriculumItemProgressDTO - クラス全体の進捗に含める項目ごとの完了状況
+type CurriculumItemProgressDTO struct {
+	ItemID         uint   `json:"item_id" example:"1"`
+	Title          string `json:"title" example:"第1章 変数と型"`
+	CompletedCount int    `json:"completed_count" example:"18"`
+	// CompletionRate 項目を完了した生徒の割合(0から1)
+	CompletionRate float64 `json:"completion_rate" example:"0.9"`
+}
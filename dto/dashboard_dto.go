@@ -0,0 +1,53 @@
+package dto
+
+import "time"
+
+// DashboardLayoutDTO ロールに応じたダッシュボードの構成
+type DashboardLayoutDTO struct {
+	CID     uint                 `json:"cid"`
+	Role    string               `json:"role"`
+	Widgets []DashboardWidgetDTO `json:"widgets"`
+}
+
+// DashboardWidgetDTO ダッシュボードのウィジェット。Orderの昇順に表示する
+type DashboardWidgetDTO struct {
+	Type  string      `json:"type"`
+	Order int         `json:"order"`
+	Data  interface{} `json:"data"`
+}
+
+// DashboardAttendanceSummaryDTO クラス全体の出席統計
+type DashboardAttendanceSummaryDTO struct {
+	MemberCount           int     `json:"member_count"`
+	AverageAttendanceRate float64 `json:"average_attendance_rate"`
+	AtRiskCount           int     `json:"at_risk_count"`
+}
+
+// DashboardAtRiskMemberDTO 出席率が低いメンバー
+type DashboardAtRiskMemberDTO struct {
+	UID            uint    `json:"uid"`
+	Nickname       string  `json:"nickname"`
+	AttendanceRate float64 `json:"attendance_rate"`
+}
+
+// DashboardScheduleDTO 次回の授業
+type DashboardScheduleDTO struct {
+	ID          uint      `json:"id"`
+	Title       string    `json:"title"`
+	StartedAt   time.Time `json:"started_at"`
+	EndedAt     time.Time `json:"ended_at"`
+	IsLive      bool      `json:"is_live"`
+	IsCancelled bool      `json:"is_cancelled"`
+}
+
+// DashboardBoardDTO ダッシュボードに表示する掲示
+type DashboardBoardDTO struct {
+	ID        uint      `json:"id"`
+	Title     string    `json:"title"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// DashboardMyAttendanceDTO 自分の出席状況
+type DashboardMyAttendanceDTO struct {
+	AttendanceRate float64 `json:"attendance_rate"`
+}
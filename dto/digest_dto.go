@@ -0,0 +1,58 @@
+package dto
+
+import "time"
+
+// DigestSettingDTO - 毎朝のダイジェストの設定
+type DigestSettingDTO struct {
+	// Enabled 前日のクラスの活動のまとめを毎朝アプリ内通知で受け取る
+	Enabled bool `json:"enabled" example:"true"`
+	// Email アプリ内通知に加えてメールでも受け取る。サーバーでメールのダイジェストを有効にしていない場合は送信しない
+	Email bool `json:"email" example:"false"`
+}
+
+// DigestScheduleDTO - ダイジェストに載せる今日の授業
+type DigestScheduleDTO struct {
+	ID        uint      `json:"id" example:"3"`
+	Title     string    `json:"title" example:"第3回 関数"`
+	StartedAt time.Time `json:"started_at"`
+	EndedAt   time.Time `json:"ended_at"`
+	Location  string    `json:"location,omitempty" example:"A棟301"`
+}
+
+// ClassDigestDTO - ダイジェストに載せるクラスごとの活動
+type ClassDigestDTO struct {
+	CID  uint   `json:"cid" example:"1"`
+	Name string `json:"name" example:"情報処理"`
+	// NewBoards 前日に投稿された掲示の件数
+	NewBoards int64 `json:"new_boards" example:"2"`
+	// ScheduleChanges 前日に作成・変更・休講・削除されたスケジュールの件数
+	ScheduleChanges int64 `json:"schedule_changes" example:"1"`
+	// UnreadChat 集計時点の未読のチャットの件数
+	UnreadChat     int64               `json:"unread_chat" example:"5"`
+	TodaySchedules []DigestScheduleDTO `json:"today_schedules"`
+}
+
+// DigestDTO - ユーザーに送信する毎朝のダイジェスト
+type DigestDTO struct {
+	UID uint `json:"uid" example:"2"`
+	// Date 活動を集計した前日の日付
+	Date    string           `json:"date" example:"2024-06-10"`
+	Classes []ClassDigestDTO `json:"classes"`
+	// Title アプリ内通知のタイトル
+	Title string `json:"title" example:"6/10のクラスのまとめ"`
+	// Body アプリ内通知の本文
+	Body string `json:"body" example:"情報処理: 新しい掲示 2件、今日の授業 1件"`
+}
+
+// DigestDryRunDTO - ダイジェストの試行の結果。通知とメールは送信しない
+type DigestDryRunDTO struct {
+	Digest DigestDTO `json:"digest"`
+	// Enabled ユーザーがダイジェストを有効にしている
+	Enabled bool `json:"enabled" example:"true"`
+	// Email 送信時にメールでも送信する
+	Email bool `json:"email" example:"false"`
+	// Empty 集計した活動がないため、送信時にはスキップする
+	Empty bool `json:"empty" example:"false"`
+	// AlreadySent 今日のダイジェストを送信済み
+	AlreadySent bool `json:"already_sent" example:"false"`
+}
@@ -0,0 +1,20 @@
+package dto
+
+// EmailStatusDTO - ユーザーのメールアドレスと検証の状態
+type EmailStatusDTO struct {
+	Email string `json:"email" example:"student@example.com"`
+	// Verified 検証済みのメールアドレス。未検証のアドレスにはメールの通知を送信しない
+	Verified bool `json:"verified" example:"true"`
+}
+
+// EmailVerificationRequestDTO - 検証メールの送信
+type EmailVerificationRequestDTO struct {
+	// Email 検証して登録するメールアドレス。省略した場合は登録済みのアドレスを検証する。検証が完了するまで登録済みのアドレスは変更しない
+	Email string `json:"email" binding:"omitempty,email,max=255" example:"student@example.com"`
+}
+
+// EmailVerifyDTO - トークンによるメールアドレスの検証
+type EmailVerifyDTO struct {
+	// Token 検証メールのリンクに含まれるトークン
+	Token string `json:"token" binding:"required,max=128"`
+}
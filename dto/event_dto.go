@@ -0,0 +1,78 @@
+package dto
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// EventEnvelopeVersion 内部イベントのエンベロープとペイロードの形式のバージョン。後方互換性のない変更を行う場合は上げ、
+// 購読するサービスが移行を終えるまで、古い形式のフィールドは削除しない
+const EventEnvelopeVersion = 1
+
+// 他のサービスに配信する内部イベント。名前はRedisのチャンネル名の末尾にも使う
+const (
+	EventClassCreated       = "class.created"
+	EventMemberApproved     = "member.approved"
+	EventAttendanceRecorded = "attendance.recorded"
+	EventAttendanceReset    = "attendance.reset"
+)
+
+// EventEnvelope - 他のサービスに配信する内部イベント。payloadの形式はeventで決まる
+type EventEnvelope struct {
+	// ID イベントのID。RedisのストリームのIDで、再取得のafterに指定する
+	ID         string    `json:"id" example:"1718000000000-0"`
+	Event      string    `json:"event" example:"class.created"`
+	Version    int       `json:"version" example:"1"`
+	OccurredAt time.Time `json:"occurred_at"`
+	// RequestID イベントを発生させたリクエストのX-Request-ID。リクエスト以外で発生した場合は空
+	RequestID string          `json:"request_id,omitempty" example:"5f2b8c1e9a7d4e3b"`
+	Payload   json.RawMessage `json:"payload" swaggertype:"object"`
+}
+
+// EventClassCreatedV1 - class.createdのペイロード
+type EventClassCreatedV1 struct {
+	CID      uint    `json:"cid" example:"1"`
+	UID      uint    `json:"uid" example:"1"`
+	Name     string  `json:"name" example:"情報処理"`
+	IsPublic bool    `json:"is_public" example:"false"`
+	Language *string `json:"language,omitempty" example:"ja"`
+}
+
+// EventMemberApprovedV1 - member.approvedのペイロード。参加申請を承認したメンバー
+type EventMemberApprovedV1 struct {
+	CID  uint   `json:"cid" example:"1"`
+	UID  uint   `json:"uid" example:"2"`
+	Role string `json:"role" example:"USER"`
+}
+
+// EventAttendanceRecordedV1 - attendance.recordedのペイロード。actionは記録の種類。講師コメントは含めない
+type EventAttendanceRecordedV1 struct {
+	ID         uint      `json:"id" example:"1"`
+	Action     string    `json:"action" example:"recorded" enums:"recorded,updated"`
+	CID        uint      `json:"cid" example:"1"`
+	CSID       uint      `json:"csid" example:"3"`
+	UID        uint      `json:"uid" example:"2"`
+	Status     string    `json:"status" example:"ATTENDANCE"`
+	RecordedAt time.Time `json:"recorded_at"`
+}
+
+// EventAttendanceResetV1 - attendance.resetのペイロード。スケジュールの全ての出席情報を削除した
+type EventAttendanceResetV1 struct {
+	CID     uint  `json:"cid" example:"1"`
+	CSID    uint  `json:"csid" example:"3"`
+	Deleted int64 `json:"deleted" example:"25"`
+}
+
+// EventReplayQuery - イベントの再取得の条件
+type EventReplayQuery struct {
+	// After 最後に受け取ったイベントのID。空の場合はストリームに残っている最初のイベントから返す
+	After string `form:"after"`
+	Limit int    `form:"limit" binding:"omitempty,min=1,max=1000"`
+}
+
+// EventReplayDTO - 再取得したイベント。nextを次のafterに指定すると続きを取得できる
+type EventReplayDTO struct {
+	Events []EventEnvelope `json:"events"`
+	// Next 最後に返したイベントのID。イベントがない場合はafterをそのまま返す
+	Next string `json:"next" example:"1718000000000-0"`
+}
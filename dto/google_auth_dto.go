@@ -4,4 +4,6 @@ type UserInput struct {
 	ID      string `json:"id"`
 	Picture string `json:"picture"`
 	Name    string `json:"name"`
+	Email   string `json:"email"`
+	Locale  string `json:"locale"`
 }
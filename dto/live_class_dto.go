@@ -0,0 +1,37 @@
+package dto
+
+import "time"
+
+// ConnectionQualityReportDTO 視聴者から定期的に送られる接続品質の統計。送信したユーザー本人の統計として集計する
+type ConnectionQualityReportDTO struct {
+	LatencyMs   float64 `json:"latency_ms"`
+	PacketLoss  float64 `json:"packet_loss"`
+	BitrateKbps float64 `json:"bitrate_kbps"`
+}
+
+// ViewerQualityStatsDTO 視聴者ごとに集計した接続品質の統計
+type ViewerQualityStatsDTO struct {
+	UID            uint      `json:"uid"`
+	Samples        int       `json:"samples"`
+	AvgLatencyMs   float64   `json:"avg_latency_ms"`
+	AvgPacketLoss  float64   `json:"avg_packet_loss"`
+	AvgBitrateKbps float64   `json:"avg_bitrate_kbps"`
+	LastLatencyMs  float64   `json:"last_latency_ms"`
+	LastPacketLoss float64   `json:"last_packet_loss"`
+	LastBitrate    float64   `json:"last_bitrate_kbps"`
+	LastReportedAt time.Time `json:"last_reported_at"`
+}
+
+// SpeakPermissionsDTO ライブ授業で発言権を持つ生徒。講師(管理者とアシスタント)は常に発言できるため含めない
+type SpeakPermissionsDTO struct {
+	CID      uint   `json:"cid"`
+	Speakers []uint `json:"speakers"`
+}
+
+// SpeakPermissionChangedDTO 発言権の付与・剥奪を全参加者に知らせるイベント
+type SpeakPermissionChangedDTO struct {
+	CID      uint   `json:"cid"`
+	UID      uint   `json:"uid"`
+	Granted  bool   `json:"granted"`
+	Speakers []uint `json:"speakers"`
+}
@@ -0,0 +1,9 @@
+package dto
+
+// SetMaintenanceRequest メンテナンスモードを設定するリクエスト
+type SetMaintenanceRequest struct {
+	// Mode off, read_only, fullのいずれか
+	Mode string `json:"mode" binding:"required"`
+	// DurationSeconds 自動的に解除するまでの秒数。0の場合は解除するまで継続する
+	DurationSeconds int `json:"duration_seconds" binding:"min=0"`
+}
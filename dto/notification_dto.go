@@ -0,0 +1,61 @@
+package dto
+
+import "time"
+
+// NotificationDTO - アプリ内通知
+type NotificationDTO struct {
+	ID    uint   `json:"id" example:"1"`
+	Type  string `json:"type" example:"SCHEDULE_CHANGED"`
+	Title string `json:"title" example:"休講のお知らせ"`
+	Body  string `json:"body" example:"第3回の授業は休講になりました"`
+	// ResourceType 通知の対象の種類。クリック時の遷移先に使う
+	ResourceType string     `json:"resource_type,omitempty" example:"class_schedule"`
+	ResourceID   *uint      `json:"resource_id,omitempty" example:"3"`
+	CID          *uint      `json:"cid,omitempty" example:"1"`
+	ReadAt       *time.Time `json:"read_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// NotificationUnreadCountDTO - 未読の通知の件数
+type NotificationUnreadCountDTO struct {
+	Unread int64 `json:"unread" example:"3"`
+}
+
+// NotificationReadAllDTO - 全ての通知を既読にした結果
+type NotificationReadAllDTO struct {
+	Updated int64 `json:"updated" example:"3"`
+}
+
+// DeviceTokenRequest - プッシュ通知を受け取る端末を登録するためのDTO
+type DeviceTokenRequest struct {
+	Platform string `json:"platform" binding:"required,oneof=ios android web" example:"ios"`
+	// Token FCMの登録トークン
+	Token string `json:"token" binding:"required,max=512" example:"fcm-registration-token"`
+}
+
+// DeviceTokenDeleteRequest - 端末の登録を解除するためのDTO
+type DeviceTokenDeleteRequest struct {
+	Token string `json:"token" binding:"required,max=512" example:"fcm-registration-token"`
+}
+
+// NotificationPreferenceDTO - 通知の種類ごとのプッシュ通知の設定
+type NotificationPreferenceDTO struct {
+	Type string `json:"type" binding:"required,oneof=MENTION APPLICATION_APPROVED SCHEDULE_CHANGED INVITATION CLASS DIGEST" example:"MENTION"`
+	// Push プッシュ通知を受け取る場合はtrue
+	Push bool `json:"push" example:"true"`
+}
+
+// ScheduleNotificationStatsDTO - スケジュールに関する通知を受け取った人数と読んだ人数
+type ScheduleNotificationStatsDTO struct {
+	CSID      uint  `json:"csid" example:"3"`
+	Delivered int64 `json:"delivered" example:"30"`
+	Read      int64 `json:"read" example:"24"`
+	Unread    int64 `json:"unread" example:"6"`
+	// OpenRate 読んだ人数の割合。通知を受け取った人がいない場合は0
+	OpenRate float64 `json:"open_rate" example:"0.8"`
+}
+
+// ScheduleNotificationReminderDTO - 未読のメンバーに再通知した結果
+type ScheduleNotificationReminderDTO struct {
+	Reminded int `json:"reminded" example:"6"`
+}
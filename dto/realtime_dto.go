@@ -0,0 +1,87 @@
+package dto
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// WebSocketで配信するイベントの種類
+const (
+	RealtimeNotification    = "notification"     // アプリ内通知が追加された
+	RealtimeBoardCreated    = "board_created"    // 購読中のクラスに掲示板が作成された
+	RealtimeScheduleChanged = "schedule_changed" // 購読中のクラスのスケジュールが変更された
+	RealtimeDirectMessage   = "dm"               // ダイレクトメッセージを受信した
+	RealtimeSpeakPermission = "speak_permission" // 購読中のクラスのライブ授業で発言権が付与・剥奪された
+	RealtimeSystem          = "system"           // メンテナンスなどのシステムからの通知
+	// RealtimeResync 送信が追いつかずにイベントを破棄した。クライアントは表示中のデータを取得し直す
+	RealtimeResync = "resync"
+	RealtimePing   = "ping"
+
+	RealtimeSubscribed   = "subscribed"
+	RealtimeUnsubscribed = "unsubscribed"
+	RealtimeError        = "error"
+)
+
+// クライアントから送信するメッセージの種類
+const (
+	RealtimeSubscribe   = "subscribe"
+	RealtimeUnsubscribe = "unsubscribe"
+	RealtimePong        = "pong"
+)
+
+// RealtimeEventDTO WebSocketで配信するイベント。クラスのイベントにはcidを含める
+type RealtimeEventDTO struct {
+	Type string          `json:"type" example:"board_created"`
+	CID  *uint           `json:"cid,omitempty"`
+	Data json.RawMessage `json:"data,omitempty" swaggertype:"object"`
+}
+
+// RealtimeClientMessage クライアントから送信するメッセージ。subscribeとunsubscribeではcidを指定する
+type RealtimeClientMessage struct {
+	Type string `json:"type" example:"subscribe"`
+	CID  uint   `json:"cid"`
+}
+
+// RealtimeErrorDTO クライアントのメッセージを処理できなかった理由
+type RealtimeErrorDTO struct {
+	Code    string `json:"code" example:"forbidden"`
+	Message string `json:"message"`
+}
+
+// RealtimeResyncDTO 破棄したイベントの件数
+type RealtimeResyncDTO struct {
+	Dropped int `json:"dropped"`
+}
+
+// RealtimeBoardDTO 作成された掲示板。本文はクライアントが権限を確認して取得する
+type RealtimeBoardDTO struct {
+	ID          uint      `json:"id"`
+	CID         uint      `json:"cid"`
+	UID         uint      `json:"uid"`
+	Title       string    `json:"title"`
+	IsAnnounced bool      `json:"is_announced"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// スケジュールの変更の種類
+const (
+	ScheduleCreated     = "created"
+	ScheduleUpdated     = "updated"
+	ScheduleDeleted     = "deleted"
+	ScheduleRestored    = "restored"
+	ScheduleCancelled   = "cancelled"
+	ScheduleUncancelled = "uncancelled"
+)
+
+// RealtimeScheduleDTO 変更されたスケジュール
+type RealtimeScheduleDTO struct {
+	ID     uint   `json:"id"`
+	CID    uint   `json:"cid"`
+	Action string `json:"action" example:"updated"`
+}
+
+// RealtimeDirectMessageDTO 受信したダイレクトメッセージ
+type RealtimeDirectMessageDTO struct {
+	SenderID string `json:"sender_id"`
+	Text     string `json:"text"`
+}
@@ -0,0 +1,19 @@
+package dto
+
+// ResponseMeta v2 APIのレスポンスに付与するメタ情報
+type ResponseMeta struct {
+	APIVersion string `json:"api_version"`
+	RequestID  string `json:"request_id,omitempty"`
+	Page       int    `json:"page,omitempty"`
+	Limit      int    `json:"limit,omitempty"`
+	// Total 総数を返す一覧で、条件に一致する件数の合計
+	Total *int64 `json:"total,omitempty" example:"120"`
+	// NextPage 総数を返す一覧で、次のページの番号。最後のページの場合は含めない
+	NextPage *int `json:"next_page,omitempty" example:"2"`
+}
+
+// V2Response v2 APIの共通レスポンス形式
+type V2Response struct {
+	Data interface{}  `json:"data"`
+	Meta ResponseMeta `json:"meta"`
+}
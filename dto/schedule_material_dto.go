@@ -0,0 +1,56 @@
+package dto
+
+import "time"
+
+// ScheduleMaterialCreateDTO - スケジュールに資料を紐付けるためのDTO
+type ScheduleMaterialCreateDTO struct {
+	// Phase 授業前の予習資料はPRE、授業後の振り返りアンケートはPOST
+	Phase   string  `json:"phase" binding:"required,oneof=PRE POST" example:"PRE"`
+	Title   string  `json:"title" binding:"required,max=100" example:"第3回 予習資料"`
+	Content *string `json:"content" binding:"omitempty,max=10000" example:"教科書の3章を読んでおいてください"`
+	URL     *string `json:"url" binding:"omitempty,url,max=2048" example:"https://example.com/slides.pdf"`
+}
+
+// ScheduleMaterialDTO - スケジュールの資料
+type ScheduleMaterialDTO struct {
+	ID      uint    `json:"id" example:"1"`
+	CSID    uint    `json:"csid" example:"1"`
+	Phase   string  `json:"phase" example:"PRE"`
+	Title   string  `json:"title" example:"第3回 予習資料"`
+	Content *string `json:"content"`
+	URL     *string `json:"url"`
+	// SurveyNotifiedAt 振り返りアンケートを生徒に通知した日時
+	SurveyNotifiedAt *time.Time `json:"survey_notified_at"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+}
+
+// SurveyResponseRequest - 振り返りアンケートに回答するためのDTO
+type SurveyResponseRequest struct {
+	// Understanding 授業の理解度 (1から5)
+	Understanding int     `json:"understanding" binding:"required,min=1,max=5" example:"4"`
+	Reflection    *string `json:"reflection" binding:"omitempty,max=2000" example:"例題をもう一度解き直したい"`
+}
+
+// SurveySummaryDTO - 振り返りアンケートの回答の集計
+type SurveySummaryDTO struct {
+	MaterialID    uint  `json:"material_id" example:"1"`
+	ResponseCount int64 `json:"response_count" example:"18"`
+	// StudentCount 回答の対象となるクラスの生徒数
+	StudentCount int64   `json:"student_count" example:"20"`
+	ResponseRate float64 `json:"response_rate" example:"0.9"`
+	// AverageUnderstanding 理解度の平均。回答がない場合は0
+	AverageUnderstanding float64 `json:"average_understanding" example:"3.8"`
+	// UnderstandingCounts 理解度ごとの回答数。キーは1から5
+	UnderstandingCounts map[int]int64         `json:"understanding_counts"`
+	Reflections         []SurveyReflectionDTO `json:"reflections"`
+}
+
+// SurveyReflectionDTO - 振り返りの自由記述
+type SurveyReflectionDTO struct {
+	UID           uint      `json:"uid" example:"1"`
+	Nickname      string    `json:"nickname" example:"たろう"`
+	Understanding int       `json:"understanding" example:"4"`
+	Reflection    string    `json:"reflection" example:"例題をもう一度解き直したい"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
@@ -0,0 +1,23 @@
+package dto
+
+// UnreadSummaryDTO ユーザーが参加する全てのクラスの未読件数と、その合計
+type UnreadSummaryDTO struct {
+	Classes []ClassUnreadDTO `json:"classes"`
+	Total   UnreadCountsDTO  `json:"total"`
+}
+
+// ClassUnreadDTO クラスごとの未読件数
+type ClassUnreadDTO struct {
+	CID    uint            `json:"cid"`
+	Name   string          `json:"name"`
+	Counts UnreadCountsDTO `json:"counts"`
+}
+
+// UnreadCountsDTO 種類ごとの未読件数。Totalは全ての種類の合計
+type UnreadCountsDTO struct {
+	Boards        int64 `json:"boards"`
+	Chat          int64 `json:"chat"`
+	Notifications int64 `json:"notifications"`
+	Mentions      int64 `json:"mentions"`
+	Total         int64 `json:"total"`
+}
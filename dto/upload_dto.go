@@ -0,0 +1,26 @@
+package dto
+
+// StartUploadRequest マルチパートアップロードの開始リクエスト
+type StartUploadRequest struct {
+	CID         uint   `json:"cid" binding:"required"`
+	FileName    string `json:"file_name" binding:"required"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size" binding:"required,gt=0"`
+}
+
+// UploadProgressDTO マルチパートアップロードの進捗。中断後はcompleted_partsに含まれないパートを送り直して再開する
+type UploadProgressDTO struct {
+	UploadID       string  `json:"upload_id"`
+	FileName       string  `json:"file_name"`
+	TotalBytes     int64   `json:"total_bytes"`
+	UploadedBytes  int64   `json:"uploaded_bytes"`
+	PartSize       int64   `json:"part_size"`
+	TotalParts     int32   `json:"total_parts"`
+	CompletedParts []int32 `json:"completed_parts"`
+}
+
+// UploadCompleteDTO 完了したアップロードのファイル情報
+type UploadCompleteDTO struct {
+	UploadID string `json:"upload_id"`
+	URL      string `json:"url"`
+}
@@ -1 +1,24 @@
 package dto
+
+// UserProfileDTO 他のユーザーにも公開するユーザーのプロフィール
+type UserProfileDTO struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+	// AvatarURL プロフィール画像のURL。削除されたユーザーの場合はnull
+	AvatarURL *string `json:"avatar_url"`
+}
+
+// AccessibilitySettingsDTO ユーザーのアクセシビリティの設定
+type AccessibilitySettingsDTO struct {
+	// FontSize 文字の大きさ (small, medium, large, x-large)
+	FontSize     string `json:"font_size" example:"medium"`
+	HighContrast bool   `json:"high_contrast" example:"false"`
+	ReduceMotion bool   `json:"reduce_motion" example:"false"`
+}
+
+// AccessibilitySettingsUpdateDTO アクセシビリティの設定を変更するためのDTO。省略した項目は変更しない
+type AccessibilitySettingsUpdateDTO struct {
+	FontSize     *string `json:"font_size" binding:"omitempty,oneof=small medium large x-large" example:"large"`
+	HighContrast *bool   `json:"high_contrast" example:"true"`
+	ReduceMotion *bool   `json:"reduce_motion" example:"true"`
+}
@@ -0,0 +1,106 @@
+package dto
+
+import "time"
+
+// WebhookPayloadVersion 送信するペイロードの形式のバージョン。後方互換性のない変更を行う場合は上げ、
+// 変更前のバージョンを受け取る送信先が移行を終えるまで、古い形式のフィールドは削除しない
+const WebhookPayloadVersion = 1
+
+// WebhookRequest - Webhookを登録・更新するためのDTO
+type WebhookRequest struct {
+	URL string `json:"url" binding:"required,url,max=2048" example:"https://example.com/hooks/minori"`
+	// Secret 署名に使う秘密鍵。登録時に省略した場合は生成し、更新時に省略した場合は変更しない
+	Secret string   `json:"secret" binding:"omitempty,min=16,max=128" example:"change-me-to-a-long-random-string"`
+	Events []string `json:"events" binding:"required,min=1,dive,oneof=board.created schedule.updated attendance.changed member.joined" example:"board.created,member.joined"`
+	// Enabled 省略した場合、登録時は有効にし、更新時は変更しない。有効にすると連続の失敗の件数を0に戻す
+	Enabled *bool `json:"enabled" example:"true"`
+}
+
+// WebhookDTO - クラスのWebhook
+type WebhookDTO struct {
+	ID     uint     `json:"id" example:"1"`
+	CID    uint     `json:"cid" example:"1"`
+	URL    string   `json:"url" example:"https://example.com/hooks/minori"`
+	Events []string `json:"events" example:"board.created,member.joined"`
+	// Secret 署名に使う秘密鍵。登録時と秘密鍵を変更した時のみ返す
+	Secret              string     `json:"secret,omitempty"`
+	Enabled             bool       `json:"enabled" example:"true"`
+	ConsecutiveFailures int        `json:"consecutive_failures" example:"0"`
+	DisabledAt          *time.Time `json:"disabled_at"`
+	CreatedAt           time.Time  `json:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at"`
+}
+
+// WebhookDeliveryDTO - Webhookの1回の送信の記録
+type WebhookDeliveryDTO struct {
+	ID         uint   `json:"id" example:"1"`
+	Event      string `json:"event" example:"board.created"`
+	DeliveryID string `json:"delivery_id" example:"4f9c2b7e1a0d4c8e9b3a6f5d2e1c0b9a"`
+	Attempt    int    `json:"attempt" example:"1"`
+	// StatusCode 送信先が返したHTTPのステータスコード。応答がなかった場合は0
+	StatusCode int    `json:"status_code" example:"200"`
+	Error      string `json:"error,omitempty" example:"unexpected status 500"`
+	DurationMS int64  `json:"duration_ms" example:"120"`
+	// Payload 送信したペイロード
+	Payload   WebhookPayloadDTO `json:"payload"`
+	CreatedAt time.Time         `json:"created_at"`
+}
+
+// WebhookPayloadDTO - Webhookで送信するペイロード。eventに応じて、board・schedule・attendance・memberのいずれか1つを含む。
+// 受信側はX-Minori-Signatureヘッダーの署名で送信元を確認する
+type WebhookPayloadDTO struct {
+	// ID イベントのID。再送でも同じIDを送るため、受信側は重複の排除に使う
+	ID         string    `json:"id" example:"4f9c2b7e1a0d4c8e9b3a6f5d2e1c0b9a"`
+	Event      string    `json:"event" example:"board.created"`
+	Version    int       `json:"version" example:"1"`
+	CID        uint      `json:"cid" example:"1"`
+	OccurredAt time.Time `json:"occurred_at"`
+
+	Board      *WebhookBoardV1      `json:"board,omitempty"`
+	Schedule   *WebhookScheduleV1   `json:"schedule,omitempty"`
+	Attendance *WebhookAttendanceV1 `json:"attendance,omitempty"`
+	Member     *WebhookMemberV1     `json:"member,omitempty"`
+}
+
+// WebhookBoardV1 - board.createdで送信する掲示板
+type WebhookBoardV1 struct {
+	ID          uint      `json:"id" example:"1"`
+	UID         uint      `json:"uid" example:"1"`
+	Title       string    `json:"title" example:"第3回の課題"`
+	IsAnnounced bool      `json:"is_announced" example:"false"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// WebhookScheduleV1 - schedule.updatedで送信するスケジュール。actionは変更の種類
+type WebhookScheduleV1 struct {
+	ID          uint      `json:"id" example:"1"`
+	Action      string    `json:"action" example:"updated" enums:"created,updated,deleted,restored,cancelled,uncancelled"`
+	Title       string    `json:"title" example:"第3回"`
+	StartedAt   time.Time `json:"started_at"`
+	EndedAt     time.Time `json:"ended_at"`
+	IsCancelled bool      `json:"is_cancelled" example:"false"`
+}
+
+// 出席の変更の種類
+const (
+	AttendanceRecorded = "recorded"
+	AttendanceUpdated  = "updated"
+	AttendanceDeleted  = "deleted"
+	AttendanceReset    = "reset"
+)
+
+// WebhookAttendanceV1 - attendance.changedで送信する出席。resetではスケジュールの全ての出席を削除したため、idとuidを含めない
+type WebhookAttendanceV1 struct {
+	ID         uint       `json:"id,omitempty" example:"1"`
+	Action     string     `json:"action" example:"recorded" enums:"recorded,updated,deleted,reset"`
+	CSID       uint       `json:"csid" example:"1"`
+	UID        uint       `json:"uid,omitempty" example:"2"`
+	Status     string     `json:"status,omitempty" example:"ATTENDANCE"`
+	RecordedAt *time.Time `json:"recorded_at,omitempty"`
+}
+
+// WebhookMemberV1 - member.joinedで送信するメンバー
+type WebhookMemberV1 struct {
+	UID  uint   `json:"uid" example:"2"`
+	Role string `json:"role" example:"USER"`
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+)
+
+// TestAttendanceFlow 出席情報の作成・取得・更新・削除をAPI経由で確認するテストです。
+func TestAttendanceFlow(t *testing.T) {
+	h := newTestHarness(t)
+	teacher := h.createUser("attendance-teacher")
+	student := h.createUser("attendance-student")
+	class := h.createClass(teacher, "attendance-class")
+	h.addMember(class, student, "USER")
+	schedule := h.createSchedule(class, "第1回", time.Now().Add(-time.Hour))
+
+	note := "積極的に発言していた"
+	input := []map[string]interface{}{{
+		"uid":             student.ID,
+		"cid":             class.ID,
+		"csid":            schedule.ID,
+		"status":          string(models.AttendanceStatus),
+		"note":            note,
+		"is_note_visible": false,
+	}}
+	h.expectStatus(h.request(http.MethodPost, "/api/gin/at", teacher, input), http.StatusOK, nil)
+
+	var attendances []models.Attendance
+	h.expectStatus(h.request(http.MethodGet, fmt.Sprintf("/api/gin/at/%d", class.ID), teacher, nil), http.StatusOK, &attendances)
+	if len(attendances) != 1 {
+		t.Fatalf("attendances = %d, want 1", len(attendances))
+	}
+	if attendances[0].IsAttendance != models.AttendanceStatus {
+		t.Errorf("status = %s, want %s", attendances[0].IsAttendance, models.AttendanceStatus)
+	}
+	if attendances[0].Note == nil || *attendances[0].Note != note {
+		t.Errorf("teacher should see the note, got %v", attendances[0].Note)
+	}
+
+	// 非公開の講師コメントは生徒には返さない
+	h.expectStatus(h.request(http.MethodGet, fmt.Sprintf("/api/gin/at/%d", class.ID), student, nil), http.StatusOK, &attendances)
+	if attendances[0].Note != nil {
+		t.Errorf("student should not see the hidden note, got %q", *attendances[0].Note)
+	}
+
+	input[0]["status"] = string(models.TardyStatus)
+	h.expectStatus(h.request(http.MethodPost, "/api/gin/at", teacher, input), http.StatusOK, nil)
+
+	h.expectStatus(h.request(http.MethodGet, fmt.Sprintf("/api/gin/at/attendance/%d", schedule.ID), teacher, nil), http.StatusOK, &attendances)
+	if len(attendances) != 1 || attendances[0].IsAttendance != models.TardyStatus {
+		t.Fatalf("attendances after update = %+v, want one %s record", attendances, models.TardyStatus)
+	}
+
+	h.expectStatus(h.request(http.MethodDelete, fmt.Sprintf("/api/gin/at/attendance/%d", attendances[0].ID), teacher, nil), http.StatusOK, nil)
+	h.expectStatus(h.request(http.MethodGet, fmt.Sprintf("/api/gin/at/%d", class.ID), teacher, nil), http.StatusNotFound, nil)
+}
+
+// TestAttendanceRejectsInvalidStatus 不正な出席ステータスが400になることを確認するテストです。
+func TestAttendanceRejectsInvalidStatus(t *testing.T) {
+	h := newTestHarness(t)
+	teacher := h.createUser("invalid-status-teacher")
+	class := h.createClass(teacher, "invalid-status-class")
+	schedule := h.createSchedule(class, "第1回", time.Now())
+
+	input := []map[string]interface{}{{"uid": teacher.ID, "cid": class.ID, "csid": schedule.ID, "status": "LATE"}}
+	h.expectStatus(h.request(http.MethodPost, "/api/gin/at", teacher, input), http.StatusBadRequest, nil)
+}
+
+// TestAttendanceResetBySchedule スケジュールの出席リセットが管理者のみ・7日以内のみ行えることを確認するテストです。
+func TestAttendanceResetBySchedule(t *testing.T) {
+	h := newTestHarness(t)
+	teacher := h.createUser("reset-teacher")
+	student := h.createUser("reset-student")
+	class := h.createClass(teacher, "reset-class")
+	h.addMember(class, student, "USER")
+	schedule := h.createSchedule(class, "第1回", time.Now().Add(time.Hour))
+	oldSchedule := h.createSchedule(class, "第0回", time.Now().Add(-8*24*time.Hour))
+
+	input := []map[string]interface{}{{"uid": student.ID, "cid": class.ID, "csid": schedule.ID, "status": string(models.AttendanceStatus)}}
+	h.expectStatus(h.request(http.MethodPost, "/api/gin/at", teacher, input), http.StatusOK, nil)
+
+	resetPath := fmt.Sprintf("/api/gin/at/%d/schedule/%d/reset", class.ID, schedule.ID)
+	h.expectStatus(h.request(http.MethodDelete, resetPath, student, nil), http.StatusForbidden, nil)
+	h.expectStatus(h.request(http.MethodDelete, fmt.Sprintf("/api/gin/at/%d/schedule/%d/reset", class.ID, oldSchedule.ID), teacher, nil), http.StatusUnprocessableEntity, nil)
+
+	var result struct {
+		DeletedCount int64 `json:"deleted_count"`
+	}
+	h.expectStatus(h.request(http.MethodDelete, resetPath, teacher, nil), http.StatusOK, &result)
+	if result.DeletedCount != 1 {
+		t.Errorf("deleted_count = %d, want 1", result.DeletedCount)
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+)
+
+// TestChatDraftFlow 下書きの保存・取得・削除と、本人以外が取得できず、送信すると削除されることをAPI経由で確認するテストです。
+func TestChatDraftFlow(t *testing.T) {
+	h := newTestHarness(t)
+	author := h.createUser("draft-author")
+	other := h.createUser("draft-other")
+	roomID := fmt.Sprintf("draft-test-%d", time.Now().UnixNano())
+	path := "/api/gin/chat/drafts/" + roomID
+	t.Cleanup(func() { h.redis.Del(context.Background(), "chat:"+roomID, "chat_stats:"+roomID) })
+
+	var draft dto.ChatDraftDTO
+	h.expectStatus(h.request(http.MethodPut, path, author, map[string]string{"content": "書きかけの"}), http.StatusOK, &draft)
+	h.expectStatus(h.request(http.MethodGet, path, author, nil), http.StatusOK, &draft)
+	if draft.Content != "書きかけの" {
+		t.Errorf("content = %q, want the saved draft", draft.Content)
+	}
+	h.expectStatus(h.request(http.MethodGet, path, other, nil), http.StatusNotFound, nil)
+	h.expectStatus(h.request(http.MethodPut, path, author, map[string]string{"content": ""}), http.StatusBadRequest, nil)
+
+	h.expectStatus(h.request(http.MethodDelete, path, author, nil), http.StatusOK, nil)
+	h.expectStatus(h.request(http.MethodGet, path, author, nil), http.StatusNotFound, nil)
+
+	// メッセージを送信すると下書きは削除される
+	h.expectStatus(h.request(http.MethodPut, path, author, map[string]string{"content": "送信する内容"}), http.StatusOK, nil)
+	form := url.Values{"user": {fmt.Sprint(author.ID)}, "message": {"送信する内容"}}
+	req, _ := http.NewRequest(http.MethodPost, "/api/gin/chat/room/"+roomID, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	token, err := h.jwtService.GenerateToken(author.ID)
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp := httptest.NewRecorder()
+	h.router.ServeHTTP(resp, req)
+	h.expectStatus(resp, http.StatusOK, nil)
+	h.expectStatus(h.request(http.MethodGet, path, author, nil), http.StatusNotFound, nil)
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+)
+
+// TestClassUserRoleFlow 参加申請者の承認、削除、復元までのロールの流れをAPI経由で確認するテストです。
+func TestClassUserRoleFlow(t *testing.T) {
+	h := newTestHarness(t)
+	admin := h.createUser("role-admin")
+	applicant := h.createUser("role-applicant")
+	class := h.createClass(admin, "role-class")
+	h.addMember(class, applicant, "APPLICANT")
+
+	infoPath := fmt.Sprintf("/api/gin/cu/%d/%d/info", applicant.ID, class.ID)
+	var info dto.ClassMemberDTO
+	h.expectStatus(h.request(http.MethodGet, infoPath, applicant, nil), http.StatusOK, &info)
+	if info.Role != "APPLICANT" {
+		t.Fatalf("role = %s, want APPLICANT", info.Role)
+	}
+
+	h.expectStatus(h.request(http.MethodPatch, fmt.Sprintf("/api/gin/cu/%d/%d/role/USER", applicant.ID, class.ID), admin, nil), http.StatusOK, nil)
+	h.expectStatus(h.request(http.MethodGet, infoPath, applicant, nil), http.StatusOK, &info)
+	if info.Role != "USER" {
+		t.Fatalf("role after approval = %s, want USER", info.Role)
+	}
+
+	h.expectStatus(h.request(http.MethodPatch, fmt.Sprintf("/api/gin/cu/%d/%d/role/OWNER", applicant.ID, class.ID), admin, nil), http.StatusBadRequest, nil)
+
+	h.expectStatus(h.request(http.MethodDelete, fmt.Sprintf("/api/gin/cu/%d/%d/remove", applicant.ID, class.ID), admin, nil), http.StatusOK, nil)
+	var members []dto.ClassMemberDTO
+	h.expectStatus(h.request(http.MethodGet, fmt.Sprintf("/api/gin/cu/class/%d/members", class.ID), admin, nil), http.StatusOK, &members)
+	for _, member := range members {
+		if member.Uid == applicant.ID {
+			t.Fatalf("removed member %d is still listed", applicant.ID)
+		}
+	}
+
+	var removed []dto.RemovedMemberDTO
+	h.expectStatus(h.request(http.MethodGet, fmt.Sprintf("/api/gin/cu/class/%d/removed-members", class.ID), admin, nil), http.StatusOK, &removed)
+	if len(removed) != 1 || removed[0].Uid != applicant.ID {
+		t.Fatalf("removed members = %+v, want only uid %d", removed, applicant.ID)
+	}
+
+	// 管理者以外は削除されたメンバーを復元できない
+	h.expectStatus(h.request(http.MethodPost, fmt.Sprintf("/api/gin/cu/class/%d/members/%d/restore", class.ID, applicant.ID), applicant, nil), http.StatusForbidden, nil)
+
+	h.expectStatus(h.request(http.MethodPost, fmt.Sprintf("/api/gin/cu/class/%d/members/%d/restore", class.ID, applicant.ID), admin, nil), http.StatusOK, nil)
+	h.expectStatus(h.request(http.MethodGet, infoPath, applicant, nil), http.StatusOK, &info)
+	if info.Role != "USER" {
+		t.Errorf("role after restore = %s, want USER", info.Role)
+	}
+}
+
+// TestBatchSetFavorite お気に入りを一括で設定し、参加していないクラスが含まれる場合は422で何も更新しないことを確認するテストです。
+func TestBatchSetFavorite(t *testing.T) {
+	h := newTestHarness(t)
+	owner := h.createUser("favorite-owner")
+	member := h.createUser("favorite-member")
+	joined := []models.Class{h.createClass(owner, "favorite-a"), h.createClass(owner, "favorite-b")}
+	other := h.createClass(owner, "favorite-other")
+	for _, class := range joined {
+		h.addMember(class, member, "USER")
+	}
+
+	path := fmt.Sprintf("/api/gin/cu/%d/favorites", member.ID)
+	isFavorite := true
+	h.expectStatus(h.request(http.MethodPut, path, member, dto.BatchFavoriteRequest{
+		ClassIDs: []uint{joined[0].ID, other.ID}, IsFavorite: &isFavorite,
+	}), http.StatusUnprocessableEntity, nil)
+
+	// お気に入りのクラスが1つもない場合、一覧は404を返す
+	h.expectStatus(h.request(http.MethodGet, fmt.Sprintf("/api/gin/cu/%d/favorite-classes", member.ID), member, nil), http.StatusNotFound, nil)
+
+	h.expectStatus(h.request(http.MethodPut, path, member, dto.BatchFavoriteRequest{
+		ClassIDs: []uint{joined[0].ID, joined[1].ID}, IsFavorite: &isFavorite,
+	}), http.StatusOK, nil)
+	var favorites []dto.UserClassInfoDTO
+	h.expectStatus(h.request(http.MethodGet, fmt.Sprintf("/api/gin/cu/%d/favorite-classes", member.ID), member, nil), http.StatusOK, &favorites)
+	if len(favorites) != len(joined) {
+		t.Errorf("favorites = %d, want %d", len(favorites), len(joined))
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+)
+
+// TestSoftDeleteAndRestoreClass 削除したクラスがスケジュール、掲示板とともに一覧から消え、管理者が復元すると個別に削除したスケジュール以外が戻ることを確認するテストです。
+func TestSoftDeleteAndRestoreClass(t *testing.T) {
+	h := newTestHarness(t)
+	admin := h.createUser("soft-delete-admin")
+	member := h.createUser("soft-delete-member")
+	class := h.createClass(admin, "soft-delete-class")
+	other := h.createClass(admin, "soft-delete-other")
+	h.addMember(class, member, "USER")
+	start := time.Now().Add(24 * time.Hour)
+	kept := h.createSchedule(class, "kept", start)
+	removed := h.createSchedule(class, "removed", start.Add(time.Hour))
+	board := models.ClassBoard{Title: "soft-delete-board", Content: "content", CID: class.ID, UID: admin.ID}
+	if err := h.db.Create(&board).Error; err != nil {
+		t.Fatalf("failed to create class board: %v", err)
+	}
+
+	schedulesPath := fmt.Sprintf("/api/gin/cs?cid=%d", class.ID)
+	h.expectStatus(h.request(http.MethodDelete, fmt.Sprintf("/api/gin/cs/%d", removed.ID), admin, nil), http.StatusOK, nil)
+	var schedules []models.ClassSchedule
+	h.expectStatus(h.request(http.MethodGet, schedulesPath, admin, nil), http.StatusOK, &schedules)
+	if len(schedules) != 1 || schedules[0].ID != kept.ID {
+		t.Fatalf("schedules after deleting one = %+v, want only %d", schedules, kept.ID)
+	}
+
+	h.expectStatus(h.request(http.MethodDelete, fmt.Sprintf("/api/gin/cl/%d/%d", admin.ID, class.ID), admin, nil), http.StatusOK, nil)
+	h.expectStatus(h.request(http.MethodGet, fmt.Sprintf("/api/gin/cl/%d", class.ID), admin, nil), http.StatusNotFound, nil)
+	var classes []dto.UserClassInfoDTO
+	h.expectStatus(h.request(http.MethodGet, fmt.Sprintf("/api/gin/cu/%d/classes", admin.ID), admin, nil), http.StatusOK, &classes)
+	if len(classes) != 1 || classes[0].ID != other.ID {
+		t.Fatalf("classes after delete = %+v, want only %d", classes, other.ID)
+	}
+	h.expectStatus(h.request(http.MethodGet, schedulesPath, admin, nil), http.StatusOK, &schedules)
+	if len(schedules) != 0 {
+		t.Fatalf("schedules of deleted class = %d, want 0", len(schedules))
+	}
+	var boards int64
+	if err := h.db.Model(&models.ClassBoard{}).Where("cid = ?", class.ID).Count(&boards).Error; err != nil {
+		t.Fatalf("failed to count class boards: %v", err)
+	}
+	if boards != 0 {
+		t.Fatalf("boards of deleted class = %d, want 0", boards)
+	}
+
+	// クラスを削除している間はスケジュールを復元できず、メンバーはクラスを復元できない
+	restoreSchedulePath := fmt.Sprintf("/api/gin/admin/classes/%d/schedules/%d/restore", class.ID, removed.ID)
+	h.expectStatus(h.request(http.MethodPost, restoreSchedulePath, admin, nil), http.StatusNotFound, nil)
+	restoreClassPath := fmt.Sprintf("/api/gin/admin/classes/%d/restore", class.ID)
+	h.expectStatus(h.request(http.MethodPost, restoreClassPath, member, nil), http.StatusForbidden, nil)
+
+	h.expectStatus(h.request(http.MethodPost, restoreClassPath, admin, nil), http.StatusOK, nil)
+	h.expectStatus(h.request(http.MethodPost, restoreClassPath, admin, nil), http.StatusNotFound, nil)
+	h.expectStatus(h.request(http.MethodGet, fmt.Sprintf("/api/gin/cl/%d", class.ID), admin, nil), http.StatusOK, nil)
+	h.expectStatus(h.request(http.MethodGet, schedulesPath, admin, nil), http.StatusOK, &schedules)
+	if len(schedules) != 1 || schedules[0].ID != kept.ID {
+		t.Fatalf("schedules after restoring class = %+v, want only %d", schedules, kept.ID)
+	}
+	if err := h.db.Model(&models.ClassBoard{}).Where("cid = ?", class.ID).Count(&boards).Error; err != nil {
+		t.Fatalf("failed to count class boards: %v", err)
+	}
+	if boards != 1 {
+		t.Fatalf("boards after restoring class = %d, want 1", boards)
+	}
+
+	h.expectStatus(h.request(http.MethodPost, restoreSchedulePath, admin, nil), http.StatusOK, nil)
+	h.expectStatus(h.request(http.MethodGet, schedulesPath, admin, nil), http.StatusOK, &schedules)
+	if len(schedules) != 2 {
+		t.Errorf("schedules after restoring schedule = %d, want 2", len(schedules))
+	}
+}
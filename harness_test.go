@@ -0,0 +1,219 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/app"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/config"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/migration"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// コントローラーを通したテスト用のハーネスです。
+// setupRouterがmainパッケージにあるため、testsディレクトリではなくここに置いています。
+// 外部のサービスを使わずに実行できるよう、DBはSQLiteのインメモリDB、Redisはminiredisを使います。
+// TEST_DATABASE_DSNを設定した場合はPostgreSQLで、TEST_REDIS_ADDRを設定した場合はそのRedisで実行します。
+
+// testHarness はテスト用のルーターとDBをまとめたものです。
+// DBへの書き込みは全てトランザクション内で行い、テスト終了時にロールバックします。
+type testHarness struct {
+	t          *testing.T
+	db         *gorm.DB
+	router     *gin.Engine
+	redis      *redis.Client
+	jwtService services.JWTService
+}
+
+// mockUploader はS3にアップロードせず固定のURLを返すUploaderです。
+type mockUploader struct{}
+
+func (mockUploader) UploadImage(file *multipart.FileHeader, classID uint, isLogo bool) (string, error) {
+	return fmt.Sprintf("https://example.com/%d/%s", classID, file.Filename), nil
+}
+
+func (mockUploader) DeleteObjects(ctx context.Context, urls []string) error {
+	return nil
+}
+
+func (mockUploader) OpenObject(ctx context.Context, url string) (io.ReadCloser, error) {
+	return io.NopCloser(strings.NewReader("")), nil
+}
+
+// harnessDBSeq はテストごとに別のインメモリDBを開くための連番です。
+var harnessDBSeq int64
+
+// openHarnessDB TEST_DATABASE_DSNのPostgreSQL、または新しいSQLiteのインメモリDBに接続します。
+func openHarnessDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dialector := sqlite.Open(fmt.Sprintf("file:harness%d?mode=memory&cache=shared&_busy_timeout=5000", atomic.AddInt64(&harnessDBSeq, 1)))
+	if dsn := os.Getenv("TEST_DATABASE_DSN"); dsn != "" {
+		dialector = postgres.Open(dsn)
+	}
+	db, err := gorm.Open(dialector, &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to connect to database: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("failed to get generic database: %v", err)
+	}
+	if db.Dialector.Name() == "sqlite" {
+		// インメモリDBのロックの競合を避けるため、接続を1本にする
+		sqlDB.SetMaxOpenConns(1)
+	}
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	return db
+}
+
+// openHarnessRedis TEST_REDIS_ADDRのRedis、またはテストごとに起動したminiredisに接続します。
+func openHarnessRedis(t *testing.T) *redis.Client {
+	t.Helper()
+	addr := os.Getenv("TEST_REDIS_ADDR")
+	if addr == "" {
+		server, err := miniredis.Run()
+		if err != nil {
+			t.Fatalf("failed to start miniredis: %v", err)
+		}
+		t.Cleanup(server.Close)
+		addr = server.Addr()
+	}
+	client := redis.NewClient(&redis.Options{Addr: addr})
+	t.Cleanup(func() { _ = client.Close() })
+	return client
+}
+
+// newTestHarness はマイグレーション済みのテスト用DBに接続し、ルーターを生成します。
+func newTestHarness(t *testing.T) *testHarness {
+	t.Helper()
+	cfg := config.LoadForTest()
+	gin.SetMode(cfg.GinMode)
+
+	db := openHarnessDB(t)
+	migration.Migrate(db)
+
+	tx := db.Begin()
+	if tx.Error != nil {
+		t.Fatalf("failed to begin transaction: %v", tx.Error)
+	}
+	t.Cleanup(func() { tx.Rollback() })
+
+	testRedis := openHarnessRedis(t)
+
+	jwtService := services.NewJWTService(cfg.JWT.Secret)
+	return &testHarness{
+		t:          t,
+		db:         tx,
+		router:     setupRouter(app.NewContainer(cfg, tx, testRedis, mockUploader{}, jwtService)),
+		redis:      testRedis,
+		jwtService: jwtService,
+	}
+}
+
+// createUser テスト用のユーザーを作成します。
+func (h *testHarness) createUser(name string) models.User {
+	h.t.Helper()
+	user := models.User{Name: name, PID: "test-" + name, Email: name + "@example.com"}
+	if err := h.db.Create(&user).Error; err != nil {
+		h.t.Fatalf("failed to create user: %v", err)
+	}
+	return user
+}
+
+// createClass ownerを管理者とするクラスを作成します。
+func (h *testHarness) createClass(owner models.User, name string) models.Class {
+	h.t.Helper()
+	class := models.Class{Name: name, UID: owner.ID}
+	if err := h.db.Create(&class).Error; err != nil {
+		h.t.Fatalf("failed to create class: %v", err)
+	}
+	h.addMember(class, owner, "ADMIN")
+	return class
+}
+
+// addMember ユーザーを指定したロールでクラスに追加します。
+func (h *testHarness) addMember(class models.Class, user models.User, role string) {
+	h.t.Helper()
+	classUser := models.ClassUser{CID: class.ID, UID: user.ID, Nickname: user.Name, Role: role}
+	if err := h.db.Create(&classUser).Error; err != nil {
+		h.t.Fatalf("failed to add class member: %v", err)
+	}
+}
+
+// createSchedule クラスのスケジュールを作成します。
+func (h *testHarness) createSchedule(class models.Class, title string, start time.Time) models.ClassSchedule {
+	h.t.Helper()
+	schedule := models.ClassSchedule{Title: title, CID: class.ID, StartedAt: start, EndedAt: start.Add(time.Hour)}
+	if err := h.db.Create(&schedule).Error; err != nil {
+		h.t.Fatalf("failed to create class schedule: %v", err)
+	}
+	return schedule
+}
+
+// request asユーザーのJWTを付けてリクエストを送信します。bodyはJSONに変換して送信します。
+func (h *testHarness) request(method, path string, as models.User, body interface{}) *httptest.ResponseRecorder {
+	h.t.Helper()
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			h.t.Fatalf("failed to encode request body: %v", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, path, reader)
+	if err != nil {
+		h.t.Fatalf("failed to build request: %v", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	token, err := h.jwtService.GenerateToken(as.ID)
+	if err != nil {
+		h.t.Fatalf("failed to generate token: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp := httptest.NewRecorder()
+	h.router.ServeHTTP(resp, req)
+	return resp
+}
+
+// expectStatus レスポンスのステータスコードを確認し、dataフィールドをvにデコードします。
+func (h *testHarness) expectStatus(resp *httptest.ResponseRecorder, status int, v interface{}) {
+	h.t.Helper()
+	if resp.Code != status {
+		h.t.Fatalf("status = %d, want %d, body = %s", resp.Code, status, resp.Body.String())
+	}
+	if v == nil {
+		return
+	}
+	envelope := struct {
+		Data json.RawMessage `json:"data"`
+	}{}
+	if err := json.Unmarshal(resp.Body.Bytes(), &envelope); err != nil {
+		h.t.Fatalf("failed to decode response: %v", err)
+	}
+	if err := json.Unmarshal(envelope.Data, v); err != nil {
+		h.t.Fatalf("failed to decode response data: %v, body = %s", err, resp.Body.String())
+	}
+}
@@ -2,30 +2,35 @@ package main
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"flag"
 	"fmt"
 	"log"
 	"net/http"
+	"net/http/pprof"
 	"os"
 	"os/signal"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
+	// 実行用のイメージにタイムゾーン情報が無いため、?tz=の解決用に埋め込む
+	_ "time/tzdata"
 
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/middlewares"
 	"github.com/go-redis/redis/v8"
 
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
 
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/app"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/config"
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/controllers"
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/docs"
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/migration"
-	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/utils"
 	"github.com/gin-gonic/gin"
-	"github.com/joho/godotenv"
 	swaggerfiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
 	"gorm.io/gorm"
@@ -34,84 +39,109 @@ import (
 var (
 	redisClient *redis.Client
 	addr        = flag.String("addr", ":8080", "http service address")
+	seed        = flag.Bool("seed", false, "seed roles on startup (same as RUN_SEED=true)")
+	seedDemo    = flag.Bool("seed-demo", false, "also seed demo data for local development (same as SEED_DEMO=true)")
 )
 
 func main() {
-	configureGinMode()
-	ensureEnvVariables()
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("設定の読み込みに失敗しました: %v", err)
+	}
+	gin.SetMode(cfg.GinMode)
 
-	db := initializeDatabase()
-	redisClient := initializeRedis()
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		runMigrateCommand(cfg, os.Args[2:])
+		return
+	}
+	// Parse the flags passed to program
+	flag.Parse()
 
-	jwtService := services.NewJWTService()
+	initializeErrorReporter(cfg)
 
-	services.NewRoomManager(redisClient)
+	db := initializeDatabase(cfg)
+	redisClient := initializeRedis(cfg)
 
-	router := setupRouter(db, jwtService)
-	startServer(router)
+	jwtService := services.NewJWTService(cfg.JWT.Secret)
 
-	// Parse the flags passed to program
-	flag.Parse()
+	container := app.NewContainer(cfg, db, redisClient, utils.NewAwsUploader(cfg.AWS), jwtService)
+	router := setupRouter(container)
+	startBackgroundJobs(container)
+	startServer(router, cfg.Port)
 
 	// start HTTP server
 	log.Fatal(http.ListenAndServe(*addr, nil))
 }
 
-// configureGinMode Ginのモードを設定する
-func configureGinMode() {
-	ginMode := getEnvOrDefault("GIN_MODE", gin.ReleaseMode)
-	gin.SetMode(ginMode)
-}
-
-// getEnvOrDefault 環境変数が設定されていない場合はデフォルト値を返す
-func getEnvOrDefault(key, defaultValue string) string {
-	value := os.Getenv(key)
-	if value == "" {
-		return defaultValue
+// initializeErrorReporter エラー監視サービスへの送信を設定する。ERROR_REPORTER_DSNが未設定の場合は送信しない
+func initializeErrorReporter(cfg *config.Config) {
+	reporter, err := utils.NewErrorReporter(cfg.ErrorReporterDSN, gin.Mode())
+	if err != nil {
+		log.Printf("エラー監視の初期化に失敗しました。送信せずに続行します: %v", err)
+		return
 	}
-	return value
+	utils.SetDefaultErrorReporter(reporter)
 }
 
-// ensureEnvVariables 環境変数が設定されているか確認する
-func ensureEnvVariables() {
-	if err := godotenv.Load(); err != nil {
-		log.Println("環境変数ファイルが読み込めませんでした。")
+// initializeDatabase データベースを初期化する
+func initializeDatabase(cfg *config.Config) *gorm.DB {
+	db, err := migration.InitDB(cfg.Database)
+	if err != nil {
+		log.Fatalf("データベースの初期化に失敗しました: %v", err)
 	}
-
-	requiredVars := []string{"POSTGRES_HOST", "POSTGRES_USER", "POSTGRES_PASSWORD", "POSTGRES_DATABASE", "POSTGRES_PORT"}
-
-	for _, varName := range requiredVars {
-		if value := os.Getenv(varName); value == "" {
-			log.Fatalf("環境変数 %s が設定されていません。", varName)
+	sqlDB, err := db.DB()
+	if err != nil {
+		log.Fatalf("データベースの初期化に失敗しました: %v", err)
+	}
+	switch cfg.Database.RunMigrations {
+	case config.MigrationsAuto:
+		migration.Migrate(db)
+	case config.MigrationsVersioned:
+		if err := migration.MigrateUp(db); err != nil {
+			log.Fatalf("マイグレーションの適用に失敗しました: %v", err)
+		}
+	}
+	// 途中で失敗したマイグレーションがあるとスキーマが中途半端なため、リクエストを受け付けない
+	if err := migration.CheckVersion(db); err != nil {
+		log.Fatalf("マイグレーションが完了していません: %v", err)
+	}
+	// テーブルが無いまま起動すると全てのリクエストが500になるため、起動時に確認する
+	if err := migration.CheckTables(db); err != nil {
+		log.Fatalf("データベースのテーブルが不足しています: %v", err)
+	}
+	if cfg.Database.RunSeed || *seed || *seedDemo {
+		opts := migration.SeedOptions{Demo: cfg.Database.SeedDemo || *seedDemo, GinMode: cfg.GinMode}
+		if err := migration.Seed(db, opts); err != nil {
+			log.Fatalf("初期データの投入に失敗しました: %v", err)
 		}
 	}
+	go monitorDatabasePool(sqlDB)
+	return db
 }
 
-// initializeDatabase データベースを初期化する
-func initializeDatabase() *gorm.DB {
-	db, err := migration.InitDB()
+// runMigrateCommand migrateサブコマンドを実行する。サーバーは起動しない
+func runMigrateCommand(cfg *config.Config, args []string) {
+	db, err := migration.InitDB(cfg.Database)
 	if err != nil {
 		log.Fatalf("データベースの初期化に失敗しました: %v", err)
 	}
-	return db
+	if err := migration.RunCommand(db, args, os.Stdout); err != nil {
+		log.Fatalf("migrate: %v", err)
+	}
 }
 
 // initializeRedis Redisを初期化する
-func initializeRedis() *redis.Client {
-	redisHost := os.Getenv("REDIS_HOST")
-	redisPort := os.Getenv("REDIS_PORT")
-	redisPassword := os.Getenv("REDIS_PASSWORD")
-
+func initializeRedis(cfg *config.Config) *redis.Client {
 	client := redis.NewClient(&redis.Options{
-		Addr: redisHost + ":" + redisPort,
-		//Password: redisPassword,
+		Addr: cfg.Redis.Addr(),
+		//Password: cfg.Redis.Password,
 		DB: 0,
 	})
 
 	_, err := client.Ping(context.Background()).Result()
 	if err != nil {
-		log.Fatalf("Redisの初期化に失敗しました： %v\nREDIS_HOST: %s\nREDIS_PORT: %s\nREDIS_PASSWORD: %s",
-			err, redisHost, redisPort, redisPassword)
+		log.Fatalf("Redisの初期化に失敗しました： %v\nREDIS_HOST: %s\nREDIS_PORT: %d",
+			err, cfg.Redis.Host, cfg.Redis.Port)
 	}
 
 	redisClient = client
@@ -119,8 +149,15 @@ func initializeRedis() *redis.Client {
 }
 
 // setupRouter ルーターをセットアップする
-func setupRouter(db *gorm.DB, jwtService services.JWTService) *gin.Engine {
-	router := gin.Default()
+func setupRouter(c *app.Container) *gin.Engine {
+	router := gin.New()
+	router.MaxMultipartMemory = middlewares.MultipartMemory
+	// 未設定の場合はどのプロキシも信頼せず、X-Forwarded-Forを無視して接続元のアドレスを使う
+	// ginの既定では全てのプロキシを信頼するため、ヘッダーを偽装してクラスのアクセス制限を回避できてしまう
+	if err := router.SetTrustedProxies(c.Config.TrustedProxies); err != nil {
+		log.Fatalf("TRUSTED_PROXIESの設定に失敗しました: %v", err)
+	}
+	router.Use(middlewares.AccessLogMiddleware())
 
 	allowedOrigins := []string{
 		"http://localhost:3000",
@@ -135,25 +172,143 @@ func setupRouter(db *gorm.DB, jwtService services.JWTService) *gin.Engine {
 		"/api/gin/swagger/",
 	}
 
-	router.Use(globalErrorHandler)
+	errorReporter := utils.DefaultErrorReporter()
+	router.Use(middlewares.RequestIDMiddleware())
+	router.Use(middlewares.RecoveryMiddleware(errorReporter))
+	router.Use(middlewares.GlobalErrorHandler(errorReporter))
+	router.Use(middlewares.TimeoutMiddleware(requestTimeoutConfig(c.Config)))
+	router.Use(middlewares.BodyLimitMiddleware(requestBodyLimitConfig()))
 	router.Use(CORS(allowedOrigins, ignoredPaths))
-	initializeSwagger(router)
-	userController, classBoardController, classCodeController, classScheduleController, classUserController, attendanceController, googleAuthController, createClassController, chatController := initializeControllers(db, redisClient)
+	router.Use(middlewares.MaintenanceMiddleware(c.Services.Maintenance, maintenanceExemptPaths...))
+	router.Use(middlewares.AuditMiddleware(c.Services.AuditLog))
+	router.Use(middlewares.ClassVersionMiddleware(c.Services.ClassVersion))
+	router.Use(middlewares.ClassAccessMiddleware(c.Services.ClassAccess, classAccessResources(&c.Repositories), classAccessExemptRoutes...))
 
-	setupRoutes(router, userController, classBoardController, classCodeController, classScheduleController, classUserController, attendanceController, googleAuthController, createClassController, chatController, jwtService)
+	initializeSwagger(router)
+	setupRoutes(router, c)
 	return router
 }
 
-func globalErrorHandler(c *gin.Context) {
-	c.Next()
+// startBackgroundJobs 定期的に実行するバックグラウンド処理を開始する
+func startBackgroundJobs(c *app.Container) {
+	go purgeExpiredAuditLogs(c.Services.AuditLog)
+	go purgeReadNotifications(c.Services.Notification)
+	go unpinExpiredClassBoards(c.Services.ClassBoard)
+	go refreshMemberActivityRankings(c.Services.ClassUser)
+	go manageChatRooms(c.DB, c.Services.ChatManager)
+	if c.Services.ClassArchive != nil {
+		go autoArchiveClasses(c.Services.ClassArchive)
+	}
+	go notifyScheduleSurveys(c.Services.Material)
+	if c.Services.VirusScan != nil {
+		go rescanPendingAttachments(c.Services.VirusScan)
+	}
+	go abortExpiredUploads(c.Services.Upload)
+	go sendDailyDigests(c.Services.Digest)
+	go watchMaintenanceMode(c.Services.Maintenance, c.Controllers.Chat, c.Controllers.ClassBoard, c.Controllers.Realtime)
+}
+
+// classAccessExemptRoutes クラスのアクセス制限を確認しないルート。管理者が制限外から設定を直せるようにする
+var classAccessExemptRoutes = []string{
+	"/api/gin/admin/classes/:cid/access-restriction",
+}
+
+// classAccessResources パスにcidを含まないルートで、リソースが属するクラスのアクセス制限を確認する設定。先に一致した設定を使う
+// 出席情報のGETはスケジュールID、DELETEは出席情報のIDを受け取る
+func classAccessResources(repos *app.Repositories) []middlewares.ClassResource {
+	board := func(ctx context.Context, id uint) (uint, error) {
+		classBoard, err := repos.ClassBoard.FindByID(ctx, id)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		return classBoard.CID, nil
+	}
+	schedule := func(ctx context.Context, id uint) (uint, error) {
+		classSchedule, err := repos.ClassSchedule.GetClassScheduleByID(ctx, id)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		return classSchedule.CID, nil
+	}
+	attendance := func(ctx context.Context, id uint) (uint, error) {
+		record, err := repos.Attendance.FindAttendance(ctx, strconv.FormatUint(uint64(id), 10))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		return record.CID, nil
+	}
+
+	// カレンダーの同期は:idにクラスのIDを受け取る
+	class := func(_ context.Context, id uint) (uint, error) { return id, nil }
+
+	return []middlewares.ClassResource{
+		{RoutePrefix: "/api/gin/cs/:id/sync-calendar", Param: "id", Resolve: class},
+		{RoutePrefix: "/api/gin/cb/:id", Param: "id", Resolve: board},
+		{RoutePrefix: "/api/gin/cs/:id", Param: "id", Resolve: schedule},
+		{RoutePrefix: "/api/gin/chat/", Param: "scheduleId", Resolve: schedule},
+		{RoutePrefix: "/api/gin/chat/messages/:roomid", Param: "roomid", Resolve: schedule},
+		{Method: http.MethodGet, RoutePrefix: "/api/gin/at/attendance/:id", Param: "id", Resolve: schedule},
+		{Method: http.MethodDelete, RoutePrefix: "/api/gin/at/attendance/:id", Param: "id", Resolve: attendance},
+		{Method: http.MethodGet, RoutePrefix: "/api/gin/v2/at/attendance/:id", Param: "id", Resolve: schedule},
+		{Method: http.MethodDelete, RoutePrefix: "/api/gin/v2/at/attendance/:id", Param: "id", Resolve: attendance},
+	}
+}
+
+// maintenanceExemptPaths メンテナンス中も受け付けるパス。トークンの更新とメンテナンスモードの操作は止めない
+var maintenanceExemptPaths = []string{
+	"/api/gin/auth/google/refresh-token",
+	"/api/gin/swagger/",
+	"/debug/",
+}
+
+// requestTimeoutConfig リクエストのタイムアウト設定を生成する
+// エクスポートやアップロードは長め、SSEのストリームとWebSocketには期限を設定しない
+func requestTimeoutConfig(cfg *config.Config) middlewares.TimeoutConfig {
+	defaultTimeout := cfg.RequestTimeout
+	longTimeout := cfg.RequestTimeoutLong
+
+	return middlewares.TimeoutConfig{
+		Default: defaultTimeout,
+		Overrides: map[string]time.Duration{
+			"GET /api/gin/cl/:cid/members/export.csv":          longTimeout,
+			"POST /api/gin/cb":                                 longTimeout,
+			"PATCH /api/gin/cb/:id/:cid/:uid":                  longTimeout,
+			"POST /api/gin/cl/create":                          longTimeout,
+			"PATCH /api/gin/cl/:uid/:cid":                      longTimeout,
+			"PUT /api/gin/uploads/:uploadId/parts/:partNumber": longTimeout,
+			"POST /api/gin/uploads/:uploadId/complete":         longTimeout,
+			"GET /api/gin/cb/subscribe":                        0,
+			"GET /api/gin/chat/stream/:scheduleId":             0,
+			"GET /api/gin/cl/:cid/events/stream":               0,
+			"GET /api/gin/ws":                                  0,
+			"GET /debug/pprof/profile":                         0,
+			"GET /debug/pprof/trace":                           0,
+		},
+	}
+}
 
-	if len(c.Errors) > 0 {
-		// You can log the errors here or send them to an external system
-		// Optionally process different types of errors differently
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Internal server error",
-			"details": c.Errors.String(),
-		})
+// requestBodyLimitConfig リクエストのボディサイズの上限を生成する
+// 画像を添付するAPIは長め、大容量ファイルのパートはパートのサイズまでとする
+func requestBodyLimitConfig() middlewares.BodyLimitConfig {
+	return middlewares.BodyLimitConfig{
+		Default: middlewares.DefaultBodyLimit,
+		Overrides: map[string]int64{
+			"POST /api/gin/cb":                                 middlewares.MultipartBodyLimit,
+			"PATCH /api/gin/cb/:id/:cid/:uid":                  middlewares.MultipartBodyLimit,
+			"POST /api/gin/cl/create":                          middlewares.MultipartBodyLimit,
+			"PATCH /api/gin/cl/:uid/:cid":                      middlewares.MultipartBodyLimit,
+			"POST /api/gin/chat/stickers/:cid":                 middlewares.MultipartBodyLimit,
+			"PUT /api/gin/uploads/:uploadId/parts/:partNumber": services.UploadPartSize,
+		},
 	}
 }
 
@@ -165,7 +320,74 @@ func globalErrorHandler(c *gin.Context) {
 func initializeSwagger(router *gin.Engine) {
 	docs.SwaggerInfo.BasePath = "/api/gin"
 	docs.SwaggerInfo.Title = "API Documentation"
-	docs.SwaggerInfo.Description = "This is minori gin server."
+	docs.SwaggerInfo.Description = "This is minori gin server.\n\n" +
+		"エラーレスポンスは `{\"error\": メッセージ, \"code\": コード, \"details\": 詳細, \"request_id\": リクエストID}` の形式です。\n\n" +
+		"| code | status |\n|---|---|\n" +
+		"| invalid_request | 400 |\n" +
+		"| invalid_attendance_status | 400 |\n" +
+		"| invalid_role_name | 400 |\n" +
+		"| invalid_notify_target | 400 |\n" +
+		"| invalid_grade_scale | 400 |\n" +
+		"| invalid_access_rule | 400 |\n" +
+		"| nested_reply | 400 |\n" +
+		"| version_required | 400 |\n" +
+		"| invalid_webhook_url | 400 |\n" +
+		"| invalid_integration_url | 400 |\n" +
+		"| invalid_event_cursor | 400 |\n" +
+		"| invalid_email_token | 400 |\n" +
+		"| unauthorized | 401 |\n" +
+		"| forbidden | 403 |\n" +
+		"| access_restricted | 403 |\n" +
+		"| invalid_check_in_code | 403 |\n" +
+		"| invalid_check_in_token | 403 |\n" +
+		"| not_found | 404 |\n" +
+		"| class_not_found | 404 |\n" +
+		"| user_not_found | 404 |\n" +
+		"| user_or_class_not_found | 404 |\n" +
+		"| attendance_not_found | 404 |\n" +
+		"| conflict | 409 |\n" +
+		"| idempotency_in_flight | 409 |\n" +
+		"| stale_update | 409 |\n" +
+		"| calendar_not_connected | 409 |\n" +
+		"| email_taken | 409 |\n" +
+		"| request_too_large | 413 |\n" +
+		"| validation_failed | 422 |\n" +
+		"| past_schedule | 422 |\n" +
+		"| schedule_too_old | 422 |\n" +
+		"| check_in_token_expired | 422 |\n" +
+		"| schedule_cancelled | 422 |\n" +
+		"| check_in_method_disabled | 422 |\n" +
+		"| not_enrolled | 422 |\n" +
+		"| active_class_limit | 422 |\n" +
+		"| channel_unavailable | 422 |\n" +
+		"| survey_not_open | 422 |\n" +
+		"| not_translatable | 422 |\n" +
+		"| integration_test_failed | 422 |\n" +
+		"| calendar_class_not_synced | 422 |\n" +
+		"| email_not_set | 422 |\n" +
+		"| email_not_verified | 422 |\n" +
+		"| realtime_topic_limit | WebSocketのerrorイベント |\n" +
+		"| invitation_limit | 429 |\n" +
+		"| email_verification_limit | 429 |\n" +
+		"| reminder_cooldown | 429 |\n" +
+		"| preview_secret_limit | 429 |\n" +
+		"| translation_rate_limit | 429 |\n" +
+		"| database_error | 500 |\n" +
+		"| internal_error | 500 |\n" +
+		"| translation_failed | 502 |\n" +
+		"| maintenance | 503 |\n" +
+		"| translation_unavailable | 503 |\n" +
+		"| mail_queue_full | 503 |\n" +
+		"| calendar_sync_unavailable | 503 |\n" +
+		"| events_unavailable | 503 |\n" +
+		"| qr_check_in_unavailable | 503 |\n" +
+		"| email_verify_unavailable | 503 |\n" +
+		"| timeout | 504 |\n\n" +
+		"`/v2` 配下のエンドポイントはJWTのユーザーIDを使用し、`{\"data\": データ, \"meta\": メタ情報}` の形式で返します。" +
+		"v2へ移行済みのv1エンドポイントには `Sunset` ヘッダーが付与されます。\n\n" +
+		"掲示板・クラスの作成、チャットの投稿、出席の一括登録、出席トークンによるチェックインは `Idempotency-Key` ヘッダーに対応し、同じキーで再送したリクエストには最初のレスポンスを返します。\n\n" +
+		"クラス情報・メンバー一覧・ダッシュボード・掲示板・スケジュールの取得は `ETag` を返し、`If-None-Match` が一致する場合は304を返します。\n\n" +
+		"通知・ダイレクトメッセージ・購読中のクラスの掲示板とスケジュールの更新は `/ws` のWebSocketでまとめて受け取れます。既存のSSEのエンドポイントも引き続き利用できます。"
 	docs.SwaggerInfo.Version = "1.0"
 	docs.SwaggerInfo.Schemes = []string{"http", "https"}
 
@@ -238,9 +460,9 @@ func CORS(allowedOrigins []string, ignoredPaths []string) gin.HandlerFunc {
 }
 
 // startServer サーバーを起動する
-func startServer(router *gin.Engine) {
+func startServer(router *gin.Engine, port int) {
 	srv := &http.Server{
-		Addr:    ":" + getEnvOrDefault("PORT", "8080"),
+		Addr:    ":" + strconv.Itoa(port),
 		Handler: router,
 	}
 
@@ -265,68 +487,54 @@ func startServer(router *gin.Engine) {
 	log.Println("Server exiting")
 }
 
-// initializeControllers コントローラーを初期化する
-func initializeControllers(db *gorm.DB, redisClient *redis.Client) (*controllers.UserController, *controllers.ClassBoardController, *controllers.ClassCodeController, *controllers.ClassScheduleController, *controllers.ClassUserController, *controllers.AttendanceController, *controllers.GoogleAuthController, *controllers.ClassController, *controllers.ChatController) {
-	userRepo := repositories.NewUserRepository(db)
-	classRepo := repositories.NewClassRepository(db)
-	classBoardRepo := repositories.NewClassBoardRepository(db)
-	classCodeRepo := repositories.NewClassCodeRepository(db)
-	classScheduleRepo := repositories.NewClassScheduleRepository(db)
-	classUserRepo := repositories.NewClassUserRepository(db)
-	roleRepo := repositories.NewRoleRepository(db)
-	attendanceRepo := repositories.NewAttendanceRepository(db)
-	googleAuthRepo := repositories.NewGoogleAuthRepository(db)
-
-	userService := services.NewCreateUserService(userRepo)
-	classBoardService := services.NewClassBoardService(classBoardRepo)
-	classCodeService := services.NewClassCodeService(classCodeRepo)
-	classUserService := services.NewClassUserService(classUserRepo, roleRepo)
-	classScheduleService := services.NewClassScheduleService(classScheduleRepo)
-	attendanceService := services.NewAttendanceService(attendanceRepo)
-	googleAuthService := services.NewGoogleAuthService(googleAuthRepo)
-	jwtService := services.NewJWTService()
-	chatManager := services.NewRoomManager(redisClient)
-	go manageChatRooms(db, chatManager)
-
-	createClassService := services.NewCreateClassService(classRepo, classUserRepo, classCodeRepo, userRepo)
-
-	uploader := utils.NewAwsUploader()
-	userController := controllers.NewCreateUserController(userService)
-	classBoardController := controllers.NewClassBoardController(classBoardService, uploader)
-	classCodeController := controllers.NewClassCodeController(classCodeService, classUserService)
-	classScheduleController := controllers.NewClassScheduleController(classScheduleService)
-	classUserController := controllers.NewClassUserController(classUserService)
-	attendanceController := controllers.NewAttendanceController(attendanceService)
-	googleAuthController := controllers.NewGoogleAuthController(googleAuthService, jwtService)
-	createClassController := controllers.NewCreateClassController(createClassService, uploader)
-	chatController := controllers.NewChatController(chatManager, redisClient)
-
-	return userController, classBoardController, classCodeController, classScheduleController, classUserController, attendanceController, googleAuthController, createClassController, chatController
-}
-
 // setupRoutes ルートをセットアップする
-func setupRoutes(router *gin.Engine, userController *controllers.UserController, classBoardController *controllers.ClassBoardController, classCodeController *controllers.ClassCodeController, classScheduleController *controllers.ClassScheduleController, classUserController *controllers.ClassUserController, attendanceController *controllers.AttendanceController, googleAuthController *controllers.GoogleAuthController, createClassController *controllers.ClassController, chatController *controllers.ChatController, jwtService services.JWTService) {
-	setupUserRoutes(router, userController, jwtService)
-	setupClassBoardRoutes(router, classBoardController, jwtService)
-	setupClassCodeRoutes(router, classCodeController, jwtService)
-	setupClassScheduleRoutes(router, classScheduleController, jwtService)
-	setupClassUserRoutes(router, classUserController, jwtService)
-	setupAttendanceRoutes(router, attendanceController, jwtService)
-	setupGoogleAuthRoutes(router, googleAuthController)
-	setupCreateClassRoutes(router, createClassController, jwtService)
-	setupChatRoutes(router, chatController, jwtService)
+func setupRoutes(router *gin.Engine, c *app.Container) {
+	ctrl := c.Controllers
+	jwtService := c.Services.JWT
+	idempotency := middlewares.IdempotencyMiddleware(middlewares.NewRedisIdempotencyStore(c.RedisClient))
+
+	setupUserRoutes(router, ctrl.User, ctrl.EmailVerify, jwtService)
+	setupClassBoardRoutes(router, ctrl.ClassBoard, ctrl.BoardComment, jwtService, idempotency)
+	setupClassCodeRoutes(router, ctrl.ClassCode, jwtService)
+	setupClassScheduleRoutes(router, ctrl.ClassSchedule, ctrl.Material, ctrl.CalendarSync, ctrl.ScheduleNotif, jwtService)
+	setupClassUserRoutes(router, ctrl.ClassUser, jwtService, c.Services.ClassVersion)
+	setupAttendanceRoutes(router, ctrl.Attendance, ctrl.Semester, jwtService, idempotency)
+	setupGoogleAuthRoutes(router, ctrl.GoogleAuth)
+	setupCreateClassRoutes(router, ctrl.Class, ctrl.ClassUser, ctrl.Subscription, ctrl.Invitation, jwtService, idempotency)
+	setupChatRoutes(router, ctrl.Chat, jwtService, idempotency)
+	setupLiveClassRoutes(router, ctrl.LiveClass, jwtService)
+	setupUploadRoutes(router, ctrl.Upload, jwtService)
+	setupUnreadRoutes(router, ctrl.Unread, jwtService)
+	setupNotificationRoutes(router, ctrl.Notification, ctrl.Digest, jwtService)
+	setupCurriculumRoutes(router, ctrl.Curriculum, jwtService)
+	setupWebhookRoutes(router, ctrl.Webhook, jwtService)
+	setupIntegrationRoutes(router, ctrl.Integration, jwtService)
+	setupCalendarSyncRoutes(router, ctrl.CalendarSync, jwtService)
+	setupRealtimeRoutes(router, ctrl.Realtime, jwtService)
+	setupEventRoutes(router, ctrl.Events, c.Config.Events.APIKey)
+
+	setupV2Routes(router, ctrl.ClassUser, ctrl.Attendance, ctrl.Semester, jwtService, idempotency, c.Services.ClassVersion)
+	setupAdminRoutes(router, ctrl.AuditLog, ctrl.Class, ctrl.ClassSchedule, ctrl.ClassAccess, jwtService)
+	setupDebugRoutes(router, c.Config.Debug, ctrl.Debug, ctrl.Maintenance, ctrl.Digest)
 }
 
 // @securityDefinitions.apikey Bearer
 // @in header
 // @name Authorization
 // @description Type "Bearer" followed by a space and JWT token.
-func setupUserRoutes(router *gin.Engine, controller *controllers.UserController, jwtService services.JWTService) {
+func setupUserRoutes(router *gin.Engine, controller *controllers.UserController, emailVerifyController *controllers.EmailVerificationController, jwtService services.JWTService) {
+	// 検証メールのリンクは別の端末で開く場合があるため、トークンのみで検証する
+	router.POST("/api/gin/u/email/verify", emailVerifyController.VerifyEmail)
+
 	u := router.Group("/api/gin/u")
 	u.Use(middlewares.TokenAuthMiddleware(jwtService))
 	{
 		u.GET(":userID/applying-classes", controller.GetApplyingClasses)
 		u.GET("search", controller.SearchByName)
+		u.GET("accessibility", controller.GetAccessibilitySettings)
+		u.PATCH("accessibility", controller.UpdateAccessibilitySettings)
+		u.GET("email", emailVerifyController.GetEmailStatus)
+		u.POST("email/verification", emailVerifyController.SendVerification)
 		u.DELETE(":userID/delete", controller.RemoveUserFromService)
 	}
 }
@@ -336,21 +544,31 @@ func setupUserRoutes(router *gin.Engine, controller *controllers.UserController,
 // @in header
 // @name Authorization
 // @description Type "Bearer" followed by a space and JWT token.
-func setupClassBoardRoutes(router *gin.Engine, controller *controllers.ClassBoardController, jwtService services.JWTService) {
+func setupClassBoardRoutes(router *gin.Engine, controller *controllers.ClassBoardController, commentController *controllers.ClassBoardCommentController, jwtService services.JWTService, idempotency gin.HandlerFunc) {
 	cb := router.Group("/api/gin/cb")
 	cb.Use(middlewares.TokenAuthMiddleware(jwtService))
+	etag := middlewares.ETagMiddleware(middlewares.ETagConfig{CacheControl: "private, no-cache"})
 	{
-		cb.GET("", controller.GetAllClassBoards)
-		cb.GET(":id", controller.GetClassBoardByID)
-		cb.GET("announced", controller.GetAnnouncedClassBoards)
+		cb.GET("", etag, controller.GetAllClassBoards)
+		cb.GET(":id", etag, controller.GetClassBoardByID)
+		cb.GET("announced", etag, controller.GetAnnouncedClassBoards)
 
 		// TODO: フロントエンド側の実装が完了したら、削除
-		cb.POST("", controller.CreateClassBoard)
+		cb.POST("", idempotency, controller.CreateClassBoard)
 		cb.PATCH(":id/:cid/:uid", controller.UpdateClassBoard)
+		cb.PATCH(":id/pin", controller.PinClassBoard)
 		cb.DELETE(":id", controller.DeleteClassBoard)
+		cb.DELETE("", controller.BulkDeleteClassBoards)
 
 		cb.GET("subscribe", controller.SubscribeClassBoardUpdates)
 		cb.GET("search", controller.SearchClassBoards)
+
+		cb.GET(":id/comments", commentController.GetComments)
+		cb.POST(":id/comments", commentController.CreateComment)
+		cb.DELETE(":id/comments/:commentID", commentController.DeleteComment)
+		cb.GET(":id/comments/:commentID/replies", commentController.GetReplies)
+		cb.PUT(":id/comments/:commentID/like", commentController.LikeComment)
+		cb.DELETE(":id/comments/:commentID/like", commentController.UnlikeComment)
 	}
 }
 
@@ -374,20 +592,43 @@ func setupClassCodeRoutes(router *gin.Engine, controller *controllers.ClassCodeC
 // @in header
 // @name Authorization
 // @description Type "Bearer" followed by a space and JWT token.
-func setupClassScheduleRoutes(router *gin.Engine, controller *controllers.ClassScheduleController, jwtService services.JWTService) {
+func setupClassScheduleRoutes(router *gin.Engine, controller *controllers.ClassScheduleController, materialController *controllers.ScheduleMaterialController, calendarSyncController *controllers.CalendarSyncController, notificationController *controllers.ScheduleNotificationController, jwtService services.JWTService) {
 	cs := router.Group("/api/gin/cs")
 	cs.Use(middlewares.TokenAuthMiddleware(jwtService))
+	// スケジュールは更新が少ないため、短時間はブラウザのキャッシュを使わせる
+	etag := middlewares.ETagMiddleware(middlewares.ETagConfig{CacheControl: "private, max-age=60"})
 	{
-		cs.GET("", controller.GetAllClassSchedules)
-		cs.GET(":id", controller.GetClassScheduleByID)
+		cs.GET("", etag, controller.GetAllClassSchedules)
+		cs.GET(":id", etag, controller.GetClassScheduleByID)
 
 		// TODO: フロントエンド側の実装が完了したら、削除
 		cs.POST("", controller.CreateClassSchedule)
 		cs.PATCH(":id", controller.UpdateClassSchedule)
 		cs.DELETE(":id", controller.DeleteClassSchedule)
+		cs.PATCH(":id/cancel", controller.CancelClassSchedule)
+		cs.PATCH(":id/uncancel", controller.UncancelClassSchedule)
+		cs.GET(":id/check-in-code", controller.GetCheckInCode)
+		cs.GET(":id/check-in-token", controller.GetCheckInToken)
 		cs.GET("live", controller.GetLiveClassSchedules)
 		cs.GET("date", controller.GetClassSchedulesByDate)
+		cs.GET("export/class/:cid", controller.ExportClassICal)
+		cs.GET("export/user/:uid/subscription", controller.GetCalendarSubscriptionURL)
+
+		cs.GET(":id/materials", materialController.GetMaterials)
+		cs.POST(":id/materials", materialController.CreateMaterial)
+		cs.DELETE(":id/materials/:materialID", materialController.DeleteMaterial)
+		cs.PUT(":id/materials/:materialID/response", materialController.SubmitSurveyResponse)
+		cs.GET(":id/materials/:materialID/summary", materialController.GetSurveySummary)
+
+		// 他のルートとパラメーター名を揃えるため:idだが、クラスのIDを指定する
+		cs.POST(":id/sync-calendar", calendarSyncController.SyncClassCalendar)
+
+		cs.GET(":id/notifications/stats", notificationController.GetScheduleNotificationStats)
+		cs.POST(":id/notifications/remind", notificationController.RemindUnread)
 	}
+
+	// カレンダーアプリからの購読はtokenクエリで認証する
+	router.GET("/api/gin/cs/export/user/:uid", middlewares.CalendarTokenMiddleware(jwtService), controller.ExportUserICal)
 }
 
 // setupGoogleAuthRoutes GoogleLoginのルートをセットアップする
@@ -405,14 +646,30 @@ func setupGoogleAuthRoutes(router *gin.Engine, controller *controllers.GoogleAut
 // @in header
 // @name Authorization
 // @description Type "Bearer" followed by a space and JWT token.
-func setupCreateClassRoutes(router *gin.Engine, controller *controllers.ClassController, jwtService services.JWTService) {
+func setupCreateClassRoutes(router *gin.Engine, controller *controllers.ClassController, classUserController *controllers.ClassUserController, subscriptionController *controllers.AnnouncementSubscriptionController, invitationController *controllers.ClassInvitationController, jwtService services.JWTService, idempotency gin.HandlerFunc) {
+	// 公開クラスの検索は参加前のユーザーも使うため認証しない
+	router.GET("/api/gin/cl/public", controller.GetPublicClasses)
+
 	cl := router.Group("/api/gin/cl")
 	cl.Use(middlewares.TokenAuthMiddleware(jwtService))
+	// クラス情報はアーカイブなどバージョンを更新しない変更もあるため、レスポンスのハッシュから生成する
+	etag := middlewares.ETagMiddleware(middlewares.ETagConfig{CacheControl: "private, no-cache"})
 	{
-		cl.GET(":cid", controller.GetClass)
-		cl.POST("create", controller.CreateClass)
+		cl.GET("today", controller.GetTodayClasses)
+		cl.GET(":cid", etag, controller.GetClass)
+		cl.GET(":cid/preview", etag, controller.GetClassPreview)
+		cl.POST("create", idempotency, controller.CreateClass)
 		cl.PATCH(":uid/:cid", controller.UpdateClass)
 		cl.DELETE(":uid/:cid", controller.DeleteClass)
+		cl.GET(":cid/members/export.csv", classUserController.ExportMembers)
+		cl.GET(":cid/flyer.pdf", controller.GenerateClassFlyer)
+		cl.GET(":cid/stats", etag, controller.GetClassStats)
+		cl.GET(":cid/timeline", controller.GetClassTimeline)
+		cl.POST(":cid/invitations", idempotency, invitationController.InviteByEmail)
+		cl.GET(":cid/events/stream", classUserController.StreamClassEvents)
+		cl.GET("subscriptions/:cid", subscriptionController.GetSubscriptions)
+		cl.PUT("subscriptions/:cid/:channel", subscriptionController.Subscribe)
+		cl.DELETE("subscriptions/:cid/:channel", subscriptionController.Unsubscribe)
 	}
 }
 
@@ -421,21 +678,28 @@ func setupCreateClassRoutes(router *gin.Engine, controller *controllers.ClassCon
 // @in header
 // @name Authorization
 // @description Type "Bearer" followed by a space and JWT token.
-func setupClassUserRoutes(router *gin.Engine, controller *controllers.ClassUserController, jwtService services.JWTService) {
+func setupClassUserRoutes(router *gin.Engine, controller *controllers.ClassUserController, jwtService services.JWTService, classVersionService services.ClassVersionService) {
 	cu := router.Group("/api/gin/cu")
-	cu.Use(middlewares.TokenAuthMiddleware(jwtService))
+	cu.Use(middlewares.TokenAuthMiddleware(jwtService), middlewares.SunsetMiddleware("/api/gin/v2/cu"))
+	membersETag := middlewares.ETagMiddleware(middlewares.ETagConfig{CacheControl: "private, no-cache", Version: middlewares.ClassVersion(classVersionService)})
+	infoETag := middlewares.ETagMiddleware(middlewares.ETagConfig{CacheControl: "private, no-cache"})
 	{
 		// TODO: フロントエンド側の実装が完了したら、削除
-		cu.GET("class/:cid/members", controller.GetClassMembers)
+		cu.GET("class/:cid/members", membersETag, controller.GetClassMembers)
+		cu.GET("class/:cid/activity-ranking", controller.GetMemberActivityRanking)
+		cu.GET("class/:cid/removed-members", controller.GetRemovedMembers)
+		cu.POST("class/:cid/members/:uid/restore", controller.RestoreMember)
+		cu.POST("members/move", controller.MoveMembers)
 
 		userRoutes := cu.Group(":uid")
 		{
-			userRoutes.GET(":cid/info", controller.GetUserClassUserInfo)
+			userRoutes.GET(":cid/info", infoETag, controller.GetUserClassUserInfo)
 			userRoutes.GET("classes", controller.GetUserClasses)
 			userRoutes.GET("favorite-classes", controller.GetFavoriteClasses)
 			userRoutes.GET("classes/by-role", controller.GetUserClassesByRole)
 			userRoutes.PATCH(":cid/role/:roleName", controller.ChangeUserRole)
 			userRoutes.PATCH(":cid/toggle-favorite", controller.ToggleFavorite)
+			userRoutes.PUT("favorites", controller.BatchSetFavorite)
 			userRoutes.PUT(":cid/:rename", controller.UpdateUserName)
 			userRoutes.DELETE(":cid/remove", controller.RemoveUserFromClass)
 			userRoutes.GET("classes/search", controller.SearchUserClassesByName)
@@ -448,14 +712,73 @@ func setupClassUserRoutes(router *gin.Engine, controller *controllers.ClassUserC
 // @in header
 // @name Authorization
 // @description Type "Bearer" followed by a space and JWT token.
-func setupAttendanceRoutes(router *gin.Engine, controller *controllers.AttendanceController, jwtService services.JWTService) {
+func setupAttendanceRoutes(router *gin.Engine, controller *controllers.AttendanceController, semesterController *controllers.ClassSemesterController, jwtService services.JWTService, idempotency gin.HandlerFunc) {
 	at := router.Group("/api/gin/at")
-	at.Use(middlewares.TokenAuthMiddleware(jwtService))
+	at.Use(middlewares.TokenAuthMiddleware(jwtService), middlewares.SunsetMiddleware("/api/gin/v2/at"))
 	{
-		at.POST("", controller.CreateOrUpdateAttendance)
+		at.POST("", idempotency, controller.CreateOrUpdateAttendance)
+		at.POST("check-in", idempotency, controller.CheckInWithToken)
 		at.GET(":cid", controller.GetAllAttendances)
 		at.GET("attendance/:id", controller.GetAttendance)
 		at.DELETE("attendance/:id", controller.DeleteAttendance)
+		at.DELETE(":cid/schedule/:csid/reset", controller.ResetScheduleAttendances)
+		at.GET(":cid/streak/:uid", controller.GetAttendanceStreak)
+		at.GET(":cid/summary/by-mode", controller.GetAttendanceSummaryByMode)
+		at.GET(":cid/semesters", semesterController.GetSemesters)
+		at.PUT(":cid/semesters/:semester", semesterController.SaveSemester)
+		at.DELETE(":cid/semesters/:semester", semesterController.DeleteSemester)
+		at.GET(":cid/semesters/:semester/grades/:uid", semesterController.GetSemesterAttendanceGrade)
+		at.GET(":cid/grade-scale", semesterController.GetGradeScale)
+		at.PUT(":cid/grade-scale", semesterController.SetGradeScale)
+	}
+}
+
+// setupV2Routes v2 APIのルートをセットアップする
+// v1とコントローラーを共有し、コンテキストのAPIバージョンでレスポンス形式を切り替える
+// @securityDefinitions.apikey Bearer
+// @in header
+// @name Authorization
+// @description Type "Bearer" followed by a space and JWT token.
+func setupV2Routes(router *gin.Engine, classUserController *controllers.ClassUserController, attendanceController *controllers.AttendanceController, semesterController *controllers.ClassSemesterController, jwtService services.JWTService, idempotency gin.HandlerFunc, classVersionService services.ClassVersionService) {
+	v2 := router.Group("/api/gin/v2")
+	v2.Use(middlewares.APIVersionMiddleware(middlewares.APIVersionV2), middlewares.TokenAuthMiddleware(jwtService))
+
+	cu := v2.Group("cu")
+	membersETag := middlewares.ETagMiddleware(middlewares.ETagConfig{CacheControl: "private, no-cache", Version: middlewares.ClassVersion(classVersionService)})
+	dashboardETag := middlewares.ETagMiddleware(middlewares.ETagConfig{CacheControl: "private, no-cache", Version: middlewares.DashboardVersion(classVersionService)})
+	infoETag := middlewares.ETagMiddleware(middlewares.ETagConfig{CacheControl: "private, no-cache"})
+	{
+		cu.GET("class/:cid/members", membersETag, classUserController.GetClassMembers)
+		cu.GET("classes", classUserController.GetUserClasses)
+		cu.GET("favorite-classes", classUserController.GetFavoriteClasses)
+		cu.GET("classes/by-role", classUserController.GetUserClassesByRole)
+		cu.GET("classes/search", classUserController.SearchUserClassesByName)
+		cu.GET(":cid/info", infoETag, classUserController.GetUserClassUserInfo)
+		cu.GET(":cid/dashboard", dashboardETag, classUserController.GetDashboardLayout)
+		cu.PATCH(":cid/toggle-favorite", classUserController.ToggleFavorite)
+		cu.PUT("favorites", classUserController.BatchSetFavorite)
+		cu.POST("members/move", classUserController.MoveMembers)
+		cu.PUT(":cid/rename", classUserController.UpdateUserName)
+		cu.PATCH(":cid/members/:uid/role/:roleName", classUserController.ChangeUserRole)
+		cu.DELETE(":cid/members/:uid", classUserController.RemoveUserFromClass)
+	}
+
+	at := v2.Group("at")
+	{
+		at.POST("", idempotency, attendanceController.CreateOrUpdateAttendance)
+		at.POST("check-in", idempotency, attendanceController.CheckInWithToken)
+		at.GET(":cid", attendanceController.GetAllAttendances)
+		at.GET("attendance/:id", attendanceController.GetAttendance)
+		at.DELETE("attendance/:id", attendanceController.DeleteAttendance)
+		at.DELETE(":cid/schedule/:csid/reset", attendanceController.ResetScheduleAttendances)
+		at.GET(":cid/streak/:uid", attendanceController.GetAttendanceStreak)
+		at.GET(":cid/summary/by-mode", attendanceController.GetAttendanceSummaryByMode)
+		at.GET(":cid/semesters", semesterController.GetSemesters)
+		at.PUT(":cid/semesters/:semester", semesterController.SaveSemester)
+		at.DELETE(":cid/semesters/:semester", semesterController.DeleteSemester)
+		at.GET(":cid/semesters/:semester/grades/:uid", semesterController.GetSemesterAttendanceGrade)
+		at.GET(":cid/grade-scale", semesterController.GetGradeScale)
+		at.PUT(":cid/grade-scale", semesterController.SetGradeScale)
 	}
 }
 
@@ -464,19 +787,403 @@ func setupAttendanceRoutes(router *gin.Engine, controller *controllers.Attendanc
 // @in header
 // @name Authorization
 // @description Type "Bearer" followed by a space and JWT token.
-func setupChatRoutes(router *gin.Engine, chatController *controllers.ChatController, jwtService services.JWTService) {
+func setupChatRoutes(router *gin.Engine, chatController *controllers.ChatController, jwtService services.JWTService, idempotency gin.HandlerFunc) {
 	chat := router.Group("/api/gin/chat")
 	chat.Use(middlewares.TokenAuthMiddleware(jwtService))
 	{
 		chat.POST("create-room/:scheduleId", chatController.CreateChatRoom)
 		chat.GET("room/:scheduleId/:userId", chatController.HandleChatRoom)
-		chat.POST("room/:scheduleId", chatController.PostToChatRoom)
+		chat.POST("room/:scheduleId", idempotency, chatController.PostToChatRoom)
 		chat.DELETE("room/:scheduleId", chatController.DeleteChatRoom)
 		chat.GET("stream/:scheduleId", chatController.StreamChat)
 		chat.GET("messages/:roomid", chatController.GetChatMessages)
+		chat.GET("messages/:roomid/:messageId/translation", chatController.TranslateMessage)
 		chat.POST("dm/:senderId/:receiverId", chatController.SendDirectMessage)
 		chat.GET("dm/:senderId/:receiverId", chatController.GetDirectMessages)
 		chat.DELETE("dm/:senderId/:receiverId", chatController.DeleteDirectMessages)
+		chat.GET("stickers/:cid", chatController.GetChatStickers)
+		chat.POST("stickers/:cid", chatController.CreateChatSticker)
+		chat.PUT("drafts/:scheduleId", chatController.SaveDraft)
+		chat.GET("drafts/:scheduleId", chatController.GetDraft)
+		chat.DELETE("drafts/:scheduleId", chatController.DeleteDraft)
+	}
+}
+
+// setupLiveClassRoutes LiveClassのルートをセットアップする
+// @securityDefinitions.apikey Bearer
+// @in header
+// @name Authorization
+// @description Type "Bearer" followed by a space and JWT token.
+func setupLiveClassRoutes(router *gin.Engine, controller *controllers.LiveClassController, jwtService services.JWTService) {
+	live := router.Group("/api/gin/live")
+	live.Use(middlewares.TokenAuthMiddleware(jwtService))
+	{
+		live.GET("screen_share/:uid/:cid", controller.GetScreenShareInfo)
+		live.POST("quality/:roomID", controller.ReportQualityStats)
+		live.GET("quality/:roomID", controller.GetRoomQualityStats)
+		live.GET("speakers/:cid", controller.GetSpeakPermissions)
+		live.PUT("speakers/:cid/:uid", controller.GrantSpeakPermission)
+		live.DELETE("speakers/:cid/:uid", controller.RevokeSpeakPermission)
+	}
+}
+
+// setupUploadRoutes 大容量ファイルのマルチパートアップロードのルートをセットアップする
+func setupUploadRoutes(router *gin.Engine, controller *controllers.UploadController, jwtService services.JWTService) {
+	up := router.Group("/api/gin/uploads")
+	up.Use(middlewares.TokenAuthMiddleware(jwtService))
+	{
+		up.POST("", controller.StartUpload)
+		up.GET(":uploadId", controller.GetUploadProgress)
+		up.PUT(":uploadId/parts/:partNumber", controller.UploadPart)
+		up.POST(":uploadId/complete", controller.CompleteUpload)
+		up.DELETE(":uploadId", controller.AbortUpload)
+	}
+}
+
+// setupUnreadRoutes 未読件数のルートをセットアップする
+func setupUnreadRoutes(router *gin.Engine, controller *controllers.UnreadController, jwtService services.JWTService) {
+	unread := router.Group("/api/gin/unread")
+	unread.Use(middlewares.TokenAuthMiddleware(jwtService))
+	{
+		unread.GET("", controller.GetUnreadSummary)
+		unread.POST(":cid/read", controller.MarkRead)
+	}
+}
+
+// setupNotificationRoutes アプリ内通知のルートをセットアップする
+func setupNotificationRoutes(router *gin.Engine, controller *controllers.NotificationController, digestController *controllers.DigestController, jwtService services.JWTService) {
+	notifications := router.Group("/api/gin/notifications")
+	notifications.Use(middlewares.TokenAuthMiddleware(jwtService))
+	{
+		notifications.GET("", controller.GetNotifications)
+		notifications.GET("unread-count", controller.GetUnreadCount)
+		notifications.PATCH("read-all", controller.MarkAllRead)
+		notifications.PATCH(":id/read", controller.MarkRead)
+		notifications.POST("devices", controller.RegisterDevice)
+		notifications.DELETE("devices", controller.UnregisterDevice)
+		notifications.GET("preferences", controller.GetPreferences)
+		notifications.PUT("preferences", controller.UpdatePreference)
+		notifications.GET("digest", digestController.GetSetting)
+		notifications.PUT("digest", digestController.UpdateSetting)
+	}
+}
+
+// setupCurriculumRoutes カリキュラムと学習進捗のルートをセットアップする
+func setupCurriculumRoutes(router *gin.Engine, controller *controllers.CurriculumController, jwtService services.JWTService) {
+	curriculum := router.Group("/api/gin/curriculum")
+	curriculum.Use(middlewares.TokenAuthMiddleware(jwtService))
+	{
+		curriculum.GET(":cid/items", controller.GetItems)
+		curriculum.POST(":cid/items", controller.CreateItem)
+		curriculum.PATCH(":cid/items/:itemID", controller.UpdateItem)
+		curriculum.DELETE(":cid/items/:itemID", controller.DeleteItem)
+		curriculum.PUT(":cid/items/:itemID/complete", controller.CompleteItem)
+		curriculum.DELETE(":cid/items/:itemID/complete", controller.UncompleteItem)
+		curriculum.GET(":cid/progress", controller.GetClassProgress)
+		curriculum.GET(":cid/progress/:uid", controller.GetUserProgress)
+	}
+}
+
+// setupWebhookRoutes クラスのWebhookのルートをセットアップする
+func setupWebhookRoutes(router *gin.Engine, controller *controllers.WebhookController, jwtService services.JWTService) {
+	webhooks := router.Group("/api/gin/webhooks")
+	webhooks.Use(middlewares.TokenAuthMiddleware(jwtService))
+	{
+		webhooks.GET(":cid", controller.GetWebhooks)
+		webhooks.POST(":cid", controller.CreateWebhook)
+		webhooks.PATCH(":cid/:id", controller.UpdateWebhook)
+		webhooks.DELETE(":cid/:id", controller.DeleteWebhook)
+		webhooks.GET(":cid/:id/deliveries", controller.GetDeliveries)
+	}
+}
+
+// setupIntegrationRoutes クラスのチャットサービスとの連携のルートをセットアップする
+func setupIntegrationRoutes(router *gin.Engine, controller *controllers.ClassIntegrationController, jwtService services.JWTService) {
+	integrations := router.Group("/api/gin/integrations")
+	integrations.Use(middlewares.TokenAuthMiddleware(jwtService))
+	{
+		integrations.GET(":cid", controller.GetIntegration)
+		integrations.PUT(":cid", controller.SaveIntegration)
+		integrations.DELETE(":cid", controller.DeleteIntegration)
+	}
+}
+
+// setupCalendarSyncRoutes Googleカレンダーへのスケジュールの同期のルートをセットアップする
+func setupCalendarSyncRoutes(router *gin.Engine, controller *controllers.CalendarSyncController, jwtService services.JWTService) {
+	calendarSync := router.Group("/api/gin/calendar-sync")
+	calendarSync.Use(middlewares.TokenAuthMiddleware(jwtService))
+	{
+		calendarSync.GET("", controller.GetSettings)
+		calendarSync.PUT("", controller.UpdateSettings)
+	}
+}
+
+// setupRealtimeRoutes WebSocketのルートをセットアップする
+func setupRealtimeRoutes(router *gin.Engine, controller *controllers.RealtimeController, jwtService services.JWTService) {
+	// ブラウザのWebSocketはヘッダーを設定できないため、tokenクエリでも認証する
+	router.GET("/api/gin/ws", middlewares.WebSocketTokenMiddleware(jwtService), controller.Connect)
+}
+
+// setupEventRoutes 他のサービス向けの内部イベントのルートをセットアップする。ユーザーのトークンではなくAPIキーで認証する
+func setupEventRoutes(router *gin.Engine, controller *controllers.EventController, apiKey string) {
+	events := router.Group("/api/gin/events")
+	events.Use(middlewares.ServiceKeyMiddleware(apiKey))
+	{
+		events.GET("replay", controller.Replay)
+	}
+}
+
+// setupAdminRoutes クラス管理者向けのルートをセットアップする
+func setupAdminRoutes(router *gin.Engine, auditLogController *controllers.AuditLogController, classController *controllers.ClassController, scheduleController *controllers.ClassScheduleController, classAccessController *controllers.ClassAccessController, jwtService services.JWTService) {
+	admin := router.Group("/api/gin/admin")
+	admin.Use(middlewares.TokenAuthMiddleware(jwtService))
+	{
+		admin.GET("audit", auditLogController.GetClassAuditLogs)
+		admin.PATCH("classes/:cid/archive-exempt", classController.SetArchiveExempt)
+		admin.POST("classes/:cid/restore", classController.RestoreClass)
+		admin.POST("classes/:cid/schedules/:id/restore", scheduleController.RestoreClassSchedule)
+		admin.GET("classes/:cid/access-restriction", classAccessController.GetAccessRestriction)
+		admin.PUT("classes/:cid/access-restriction", classAccessController.SetAccessRestriction)
+	}
+}
+
+// setupDebugRoutes pprofと実行時の状態を返すデバッグ用のルートをセットアップする。
+// DEBUG_ENDPOINTS_ENABLEDがtrueかつDEBUG_ENDPOINTS_TOKENが設定されている場合のみ登録する
+func setupDebugRoutes(router *gin.Engine, cfg config.DebugConfig, debugController *controllers.DebugController, maintenanceController *controllers.MaintenanceController, digestController *controllers.DigestController) {
+	if !cfg.Enabled {
+		return
+	}
+	token := cfg.Token
+	if token == "" {
+		log.Println("DEBUG_ENDPOINTS_TOKEN is not set; debug endpoints are disabled")
+		return
+	}
+
+	debug := router.Group("/debug")
+	debug.Use(middlewares.StaticTokenMiddleware(token))
+	{
+		debug.GET("vars", debugController.GetRuntimeVars)
+		debug.GET("maintenance", maintenanceController.GetMaintenance)
+		debug.PUT("maintenance", maintenanceController.SetMaintenance)
+		debug.POST("digests/:uid/dry-run", digestController.DryRun)
+		debug.GET("pprof/", gin.WrapF(pprof.Index))
+		debug.GET("pprof/cmdline", gin.WrapF(pprof.Cmdline))
+		debug.GET("pprof/profile", gin.WrapF(pprof.Profile))
+		debug.GET("pprof/symbol", gin.WrapF(pprof.Symbol))
+		debug.POST("pprof/symbol", gin.WrapF(pprof.Symbol))
+		debug.GET("pprof/trace", gin.WrapF(pprof.Trace))
+		// heap, goroutine, allocs などのプロファイルはpprof.Indexが名前から返す
+		debug.GET("pprof/:name", gin.WrapF(pprof.Index))
+	}
+}
+
+// watchMaintenanceMode メンテナンスモードを定期的に読み直し、変わった場合はSSEで接続中のクライアントに通知する
+func watchMaintenanceMode(maintenanceService services.MaintenanceService, targets ...controllers.SystemEventTarget) {
+	ticker := time.NewTicker(services.MaintenancePollInterval)
+	defer ticker.Stop()
+
+	last := services.MaintenanceOff
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), services.MaintenancePollInterval)
+		state, err := maintenanceService.Refresh(ctx)
+		cancel()
+		if err != nil {
+			utils.ReportBackgroundError("watch_maintenance_mode", fmt.Errorf("failed to refresh maintenance mode: %w", err))
+		} else if state.Mode != last {
+			log.Printf("Maintenance mode changed: %s -> %s", last, state.Mode)
+			last = state.Mode
+			for _, target := range targets {
+				target.BroadcastSystemEvent(services.NewMaintenanceEvent(state))
+			}
+		}
+		<-ticker.C
+	}
+}
+
+// purgeExpiredAuditLogs 保存期間を過ぎた監査ログを1日ごとに削除する
+func purgeExpiredAuditLogs(auditLogService services.AuditLogService) {
+	ticker := time.NewTicker(24 * time.Hour)
+	defer ticker.Stop()
+
+	for {
+		<-ticker.C
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		deleted, err := auditLogService.PurgeExpiredAuditLogs(ctx)
+		cancel()
+		if err != nil {
+			utils.ReportBackgroundError("purge_audit_logs", fmt.Errorf("failed to purge expired audit logs: %w", err))
+			continue
+		}
+		log.Printf("Purged %d expired audit logs", deleted)
+	}
+}
+
+// purgeReadNotifications 既読にしてから保存期間を過ぎた通知を1日ごとに削除する
+func purgeReadNotifications(notificationService services.NotificationService) {
+	ticker := time.NewTicker(24 * time.Hour)
+	defer ticker.Stop()
+
+	for {
+		<-ticker.C
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		deleted, err := notificationService.PurgeReadNotifications(ctx)
+		cancel()
+		if err != nil {
+			utils.ReportBackgroundError("purge_notifications", fmt.Errorf("failed to purge read notifications: %w", err))
+			continue
+		}
+		log.Printf("Purged %d read notifications", deleted)
+	}
+}
+
+// autoArchiveClasses 活動のないクラスの事前通知とアーカイブを1時間ごとに行う
+func autoArchiveClasses(classArchiveService services.ClassArchiveService) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		<-ticker.C
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		notified, archived, err := classArchiveService.RunAutoArchive(ctx)
+		cancel()
+		if err != nil {
+			utils.ReportBackgroundError("auto_archive_classes", fmt.Errorf("failed to find inactive classes: %w", err))
+			continue
+		}
+		if notified > 0 || archived > 0 {
+			log.Printf("Auto archive: notified %d classes, archived %d classes", notified, archived)
+		}
+	}
+}
+
+// notifyScheduleSurveys 授業が終了したスケジュールの振り返りアンケートを1分ごとに生徒へ通知する
+func notifyScheduleSurveys(materialService services.ScheduleMaterialService) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		<-ticker.C
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		notified, err := materialService.NotifyEndedSurveys(ctx)
+		cancel()
+		if err != nil {
+			utils.ReportBackgroundError("notify_schedule_surveys", fmt.Errorf("failed to find ended surveys: %w", err))
+			continue
+		}
+		if notified > 0 {
+			log.Printf("Schedule surveys: notified %d surveys", notified)
+		}
+	}
+}
+
+// abortExpiredUploads 完了も中止もされないまま期限切れになったマルチパートアップロードを1時間ごとにS3で中止する。
+// 中止しないとアップロード済みのパートがS3に残り続け、保存料金がかかる
+func abortExpiredUploads(uploadService services.UploadService) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		<-ticker.C
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+		aborted, err := uploadService.AbortExpiredUploads(ctx, time.Now())
+		cancel()
+		if err != nil {
+			utils.ReportBackgroundError("abort_expired_uploads", fmt.Errorf("failed to abort expired uploads: %w", err))
+			continue
+		}
+		if aborted > 0 {
+			log.Printf("Aborted %d expired multipart uploads", aborted)
+		}
+	}
+}
+
+// sendDailyDigests 設定した時刻を過ぎたら、前日のクラスの活動をまとめたダイジェストを10分ごとに送信する。
+// 送信済みの日とユーザーは記録しているため、送信が終わった日や他のインスタンスが送信中の場合は何もしない
+func sendDailyDigests(digestService services.DigestService) {
+	ticker := time.NewTicker(10 * time.Minute)
+	defer ticker.Stop()
+
+	for {
+		<-ticker.C
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Minute)
+		sent, err := digestService.RunDailyDigest(ctx, time.Now())
+		cancel()
+		if err != nil {
+			utils.ReportBackgroundError("daily_digest", fmt.Errorf("failed to send daily digests: %w", err))
+			continue
+		}
+		if sent > 0 {
+			log.Printf("Daily digest: sent %d digests", sent)
+		}
+	}
+}
+
+// monitorDatabasePool 接続の空きを待ったリクエストがあった場合、コネクションプールの状態を1分ごとにログに出力する
+func monitorDatabasePool(sqlDB *sql.DB) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	last := sqlDB.Stats()
+	for {
+		<-ticker.C
+		stats := sqlDB.Stats()
+		if waits := stats.WaitCount - last.WaitCount; waits > 0 {
+			log.Printf("[WARN] database pool saturated: waits=%d wait_duration=%s in_use=%d idle=%d max_open=%d",
+				waits, stats.WaitDuration-last.WaitDuration, stats.InUse, stats.Idle, stats.MaxOpenConnections)
+		}
+		last = stats
+	}
+}
+
+// unpinExpiredClassBoards 期限を過ぎたグループ掲示板のピン留めを定期的に解除する
+func unpinExpiredClassBoards(classBoardService services.ClassBoardService) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		<-ticker.C
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		unpinned, err := classBoardService.UnpinExpiredClassBoards(ctx)
+		cancel()
+		if err != nil {
+			utils.ReportBackgroundError("unpin_expired_class_boards", fmt.Errorf("failed to unpin expired class boards: %w", err))
+			continue
+		}
+		if unpinned > 0 {
+			log.Printf("Unpinned %d expired class boards", unpinned)
+			classBoardService.GetUpdateNotifier().Broadcast <- []byte(fmt.Sprintf("data: %s\n\n", "Class board updated"))
+		}
+	}
+}
+
+// rescanPendingAttachments スキャンされないままスキャン中になっている添付画像のスキャンを10分ごとに予約し直す
+func rescanPendingAttachments(scanService services.AttachmentScanService) {
+	ticker := time.NewTicker(10 * time.Minute)
+	defer ticker.Stop()
+
+	for {
+		<-ticker.C
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		scheduled, err := scanService.RescanStalePending(ctx, time.Now())
+		cancel()
+		if err != nil {
+			utils.ReportBackgroundError("rescan_pending_attachments", fmt.Errorf("failed to find pending attachment scans: %w", err))
+			continue
+		}
+		if scheduled > 0 {
+			log.Printf("Attachment scan: rescheduled %d pending images", scheduled)
+		}
+	}
+}
+
+// refreshMemberActivityRankings 活動度ランキングの指標を定期的に再計算する
+func refreshMemberActivityRankings(classUserService services.ClassUserService) {
+	ticker := time.NewTicker(10 * time.Minute)
+	defer ticker.Stop()
+
+	for {
+		<-ticker.C
+		classUserService.RefreshMemberActivityRankings()
 	}
 }
 
@@ -490,14 +1197,28 @@ func manageChatRooms(db *gorm.DB, chatManager *services.Manager) {
 		var schedules []models.ClassSchedule
 
 		// 수업 시작 5분 전과 수업 종료 10분 후에 채팅방 상태를 확인
-		db.Where("started_at <= ? AND started_at >= ?", now.Add(5*time.Minute), now).
-			Or("ended_at <= ? AND ended_at >= ?", now, now.Add(-10*time.Minute)).Find(&schedules)
+		// 休講のスケジュールは開始前でもチャットルームを残さない
+		err := db.Where("started_at <= ? AND started_at >= ?", now.Add(5*time.Minute), now).
+			Or("ended_at <= ? AND ended_at >= ?", now, now.Add(-10*time.Minute)).
+			Or("is_cancelled = true AND ended_at >= ?", now.Add(-10*time.Minute)).Find(&schedules).Error
+		if err != nil {
+			utils.ReportBackgroundError("manage_chat_rooms", fmt.Errorf("failed to load class schedules: %w", err))
+			continue
+		}
 
 		for _, schedule := range schedules {
 			roomID := fmt.Sprintf("class_%d", schedule.ID)
 			// 종료 10분 후 검사를 위해 ended_at에 10분을 더해 현재 시간과 비교
-			if now.After(schedule.EndedAt.Add(10 * time.Minute)) {
-				chatManager.DeleteBroadcast(roomID)
+			if schedule.IsCancelled || now.After(schedule.EndedAt.Add(10*time.Minute)) {
+				err := chatManager.DeleteBroadcast(roomID)
+				switch {
+				case errors.Is(err, services.ErrRoomNotFound) && schedule.IsCancelled:
+					// 休講のスケジュールは終了まで毎回確認するため、ルームが無いことは記録しない
+				case errors.Is(err, services.ErrRoomNotFound):
+					log.Printf("Chat room %s for schedule %d was not found at cleanup", roomID, schedule.ID)
+				case err != nil:
+					utils.ReportBackgroundError("manage_chat_rooms", fmt.Errorf("failed to delete chat room %s: %w", roomID, err))
+				}
 			}
 		}
 	}
@@ -0,0 +1,41 @@
+package middlewares
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	APIVersionKey = "apiVersion"
+	APIVersionV1  = "v1"
+	APIVersionV2  = "v2"
+
+	// APIv1Sunset v2へ移行済みのv1エンドポイントの提供終了予定日 (RFC 8594)
+	APIv1Sunset = "Thu, 31 Dec 2026 23:59:59 GMT"
+)
+
+// APIVersionMiddleware はルートグループのAPIバージョンをコンテキストに設定するミドルウェアです。
+// コントローラーはこの値を参照してレスポンスの形式を切り替えます。
+func APIVersionMiddleware(version string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(APIVersionKey, version)
+		c.Next()
+	}
+}
+
+// GetAPIVersion はコンテキストからAPIバージョンを取得します。未設定の場合はv1として扱います。
+func GetAPIVersion(c *gin.Context) string {
+	if version := c.GetString(APIVersionKey); version != "" {
+		return version
+	}
+	return APIVersionV1
+}
+
+// SunsetMiddleware はv2へ移行済みのv1エンドポイントに非推奨ヘッダーを付与するミドルウェアです。
+func SunsetMiddleware(successorPath string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Deprecation", "true")
+		c.Header("Sunset", APIv1Sunset)
+		c.Header("Link", "<"+successorPath+">; rel=\"successor-version\"")
+		c.Next()
+	}
+}
@@ -0,0 +1,185 @@
+package middlewares
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// AuditClassIDKey ハンドラーが監査ログのクラスIDを指定するためのコンテキストキー
+const AuditClassIDKey = "auditClassID"
+
+// maxAuditBodySize 監査ログに保存するリクエストボディの最大バイト数
+const maxAuditBodySize = 2048
+
+// auditResourceTypes ルートの先頭のセグメントとリソース種別の対応
+var auditResourceTypes = map[string]string{
+	"at":      "attendance",
+	"auth":    "auth",
+	"cb":      "class_board",
+	"cc":      "class_code",
+	"chat":    "chat",
+	"cl":      "class",
+	"cs":      "class_schedule",
+	"cu":      "class_user",
+	"live":    "live_class",
+	"u":       "user",
+	"uploads": "upload",
+}
+
+// auditResourceIDParams リソースIDとして扱うパスパラメータ。先に見つかったものを使う
+var auditResourceIDParams = []string{"id", "uploadId", "scheduleId", "roomID", "roomid", "uid", "userID"}
+
+// SetAuditClassID パスやクエリから特定できないクラスIDを監査ログに記録する
+func SetAuditClassID(c *gin.Context, cid uint) {
+	c.Set(AuditClassIDKey, cid)
+}
+
+// AuditMiddleware POST/PATCH/PUT/DELETEのリクエストを監査ログに記録する。
+// 書き込みはAuditLogServiceが非同期で行うため、レスポンスは待たせない
+func AuditMiddleware(auditLogService services.AuditLogService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !isMutatingMethod(c.Request.Method) {
+			c.Next()
+			return
+		}
+
+		// ハンドラーが読んだ分だけを記録し、ボディを余分に読み込まない
+		capture := &limitedBuffer{limit: maxAuditBodySize}
+		captureBody := shouldCaptureBody(c.ContentType())
+		if captureBody && c.Request.Body != nil {
+			c.Request.Body = &teeReadCloser{Reader: io.TeeReader(c.Request.Body, capture), Closer: c.Request.Body}
+		}
+
+		c.Next()
+
+		actorUID := c.GetUint("userID")
+		if actorUID == 0 || c.FullPath() == "" {
+			return
+		}
+
+		entry := models.AuditLog{
+			ActorUID:     actorUID,
+			Method:       c.Request.Method,
+			Route:        c.FullPath(),
+			ResourceType: auditResourceType(c.FullPath()),
+			ResourceID:   auditResourceID(c),
+			CID:          auditClassID(c),
+			StatusCode:   c.Writer.Status(),
+			RequestID:    GetRequestID(c),
+			CreatedAt:    time.Now(),
+		}
+		if captureBody {
+			entry.Body = redactBody(c.ContentType(), capture.String(), capture.truncated)
+		}
+		auditLogService.Record(entry)
+	}
+}
+
+// isMutatingMethod データを更新するメソッドか判定する
+func isMutatingMethod(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPatch, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// shouldCaptureBody テキストとして記録できるボディか判定する。ファイルやバイナリは記録しない
+func shouldCaptureBody(contentType string) bool {
+	return contentType == gin.MIMEJSON || contentType == gin.MIMEPOSTForm
+}
+
+// auditResourceType ルートからリソース種別を求める
+func auditResourceType(route string) string {
+	path := strings.TrimPrefix(route, "/api/gin/")
+	path = strings.TrimPrefix(path, "v2/")
+	segment := strings.SplitN(path, "/", 2)[0]
+	if resourceType, ok := auditResourceTypes[segment]; ok {
+		return resourceType
+	}
+	return segment
+}
+
+// auditResourceID パスパラメータからリソースIDを求める
+func auditResourceID(c *gin.Context) string {
+	for _, name := range auditResourceIDParams {
+		if value := c.Param(name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// auditClassID ハンドラーの指定、パス、クエリ、フォームの順にクラスIDを求める
+func auditClassID(c *gin.Context) *uint {
+	if value, ok := c.Get(AuditClassIDKey); ok {
+		if cid, ok := value.(uint); ok {
+			return &cid
+		}
+	}
+
+	candidates := []string{c.Param("cid"), c.Query("cid")}
+	if c.Request.PostForm != nil {
+		candidates = append(candidates, c.Request.PostForm.Get("cid"))
+	}
+	if c.Request.MultipartForm != nil && len(c.Request.MultipartForm.Value["cid"]) > 0 {
+		candidates = append(candidates, c.Request.MultipartForm.Value["cid"][0])
+	}
+
+	for _, candidate := range candidates {
+		if cid, err := strconv.ParseUint(candidate, 10, 32); err == nil {
+			value := uint(cid)
+			return &value
+		}
+	}
+	return nil
+}
+
+// redactBody 機密項目の値をマスクする。切り詰めた場合は末尾に印を付ける
+func redactBody(contentType, body string, truncated bool) string {
+	if contentType == gin.MIMEJSON {
+		body = utils.RedactJSON(body)
+	} else {
+		body = utils.RedactForm(body)
+	}
+	if truncated {
+		body += "...(truncated)"
+	}
+	return body
+}
+
+// limitedBuffer 先頭のlimitバイトまでを保持するWriter
+type limitedBuffer struct {
+	bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if remaining := b.limit - b.Len(); remaining > 0 {
+		if len(p) > remaining {
+			b.Buffer.Write(p[:remaining])
+			b.truncated = true
+		} else {
+			b.Buffer.Write(p)
+		}
+	} else if len(p) > 0 {
+		b.truncated = true
+	}
+	return len(p), nil
+}
+
+// teeReadCloser 読み込んだ内容を記録しつつ元のボディを閉じられるようにする
+type teeReadCloser struct {
+	io.Reader
+	io.Closer
+}
@@ -1,8 +1,10 @@
 package middlewares
 
 import (
+	"context"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/constants"
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
@@ -36,7 +38,7 @@ func ClassUserRoleMiddleware(roleService services.ClassUserService, requiredRole
 			return
 		}
 
-		roleName, err := roleService.GetRole(uid, cid)
+		roleName, err := roleService.GetRole(ctx.Request.Context(), uid, cid)
 		if err != nil {
 			ctx.AbortWithStatusJSON(constants.StatusUnauthorized, gin.H{"error": "Unauthorized: role check failed"})
 			return
@@ -53,12 +55,36 @@ func ClassUserRoleMiddleware(roleService services.ClassUserService, requiredRole
 
 // AdminMiddleware は管理者権限を持っているかどうかを確認するミドルウェアです。
 func AdminMiddleware(roleService services.ClassUserService) gin.HandlerFunc {
-	return ClassUserRoleMiddleware(roleService, AdminRole)
+	return classPrivilegeMiddleware(roleService.IsAdmin)
 }
 
-// AssistantMiddleware はアシスタント権限を持っているかどうかを確認するミドルウェアです。
+// AssistantMiddleware はアシスタント以上の権限(アシスタントまたは管理者)を持っているかどうかを確認するミドルウェアです。
 func AssistantMiddleware(roleService services.ClassUserService) gin.HandlerFunc {
-	return ClassUserRoleMiddleware(roleService, AssistantRole)
+	return classPrivilegeMiddleware(roleService.IsAdminOrAssistant)
+}
+
+// classPrivilegeMiddleware はクエリのuidとcidでhasPrivilegeを呼び、権限がない場合は403を返すミドルウェアです。
+func classPrivilegeMiddleware(hasPrivilege func(ctx context.Context, uid uint, cid uint) (bool, error)) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		uid, cid, err := getUserInfoFromPath(ctx)
+		if err != nil {
+			ctx.AbortWithStatusJSON(constants.StatusUnauthorized, gin.H{"error": "Unauthorized: invalid user or class ID"})
+			return
+		}
+
+		allowed, err := hasPrivilege(ctx.Request.Context(), uid, cid)
+		if err != nil {
+			ctx.AbortWithStatusJSON(constants.StatusUnauthorized, gin.H{"error": "Unauthorized: role check failed"})
+			return
+		}
+
+		if !allowed {
+			ctx.AbortWithStatusJSON(constants.StatusForbidden, gin.H{"error": "Forbidden: insufficient privileges"})
+			return
+		}
+
+		ctx.Next()
+	}
 }
 
 func AuthMiddleware(authenticate func(token string) bool) gin.HandlerFunc {
@@ -73,6 +99,50 @@ func AuthMiddleware(authenticate func(token string) bool) gin.HandlerFunc {
 	}
 }
 
+// CalendarTokenMiddleware はカレンダー購読URLのtokenクエリで認証するミドルウェアです。
+// tokenが無い場合は通常のBearerトークンで認証します。
+func CalendarTokenMiddleware(jwtService services.JWTService) gin.HandlerFunc {
+	tokenAuth := TokenAuthMiddleware(jwtService)
+	return func(c *gin.Context) {
+		tokenString := c.Query("token")
+		if tokenString == "" {
+			tokenAuth(c)
+			return
+		}
+
+		userID, err := jwtService.ValidateCalendarToken(tokenString)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid calendar token"})
+			return
+		}
+		c.Set("userID", userID)
+
+		c.Next()
+	}
+}
+
+// WebSocketTokenMiddleware はWebSocketの接続をtokenクエリのアクセストークンで認証するミドルウェアです。
+// ブラウザのWebSocketはAuthorizationヘッダーを設定できないため、tokenが無い場合のみ通常のBearerトークンで認証します。
+func WebSocketTokenMiddleware(jwtService services.JWTService) gin.HandlerFunc {
+	tokenAuth := TokenAuthMiddleware(jwtService)
+	return func(c *gin.Context) {
+		tokenString := c.Query("token")
+		if tokenString == "" {
+			tokenAuth(c)
+			return
+		}
+
+		userID, ok := accessTokenUserID(jwtService, tokenString)
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid API token"})
+			return
+		}
+		c.Set("userID", userID)
+
+		c.Next()
+	}
+}
+
 func TokenAuthMiddleware(jwtService services.JWTService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		const BearerSchema = "Bearer "
@@ -82,17 +152,35 @@ func TokenAuthMiddleware(jwtService services.JWTService) gin.HandlerFunc {
 			return
 		}
 
-		tokenString := header[len(BearerSchema):]
-		token, err := jwtService.ValidateToken(tokenString)
-		if err != nil || !token.Valid {
+		tokenString := strings.TrimPrefix(header, BearerSchema)
+		userID, ok := accessTokenUserID(jwtService, tokenString)
+		if !ok {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid API token"})
 			return
 		}
-
-		claims := token.Claims.(jwt.MapClaims)
-		userID := uint(claims["id"].(float64))
 		c.Set("userID", userID)
 
 		c.Next()
 	}
 }
+
+// accessTokenUserID アクセストークンを検証してユーザーIDを返す。
+// リフレッシュトークンやカレンダー購読用などtypeを持つトークンはAPIの認証に使えないため拒否する
+func accessTokenUserID(jwtService services.JWTService, tokenString string) (uint, bool) {
+	token, err := jwtService.ValidateToken(tokenString)
+	if err != nil || !token.Valid {
+		return 0, false
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return 0, false
+	}
+	if _, typed := claims["type"]; typed {
+		return 0, false
+	}
+	id, ok := claims["id"].(float64)
+	if !ok {
+		return 0, false
+	}
+	return uint(id), true
+}
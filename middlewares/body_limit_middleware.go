@@ -0,0 +1,128 @@
+package middlewares
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/constants"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/utils"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// DefaultBodyLimit JSONを受け付けるAPIのボディサイズの上限
+	DefaultBodyLimit int64 = 1 << 20
+	// MultipartBodyLimit 画像を添付するmultipart/form-dataのAPIのボディサイズの上限
+	MultipartBodyLimit int64 = 20 << 20
+	// MultipartMemory multipart/form-dataの解析でメモリに保持する上限。超えた分は一時ファイルに書き出す
+	MultipartMemory int64 = 8 << 20
+)
+
+// BodyLimitConfig はリクエストごとのボディサイズの上限です。
+// Overridesのキーは "METHOD /route/path" 形式で、0を指定したルートには上限を設定しません。
+type BodyLimitConfig struct {
+	Default   int64
+	Overrides map[string]int64
+}
+
+// BodyLimitMiddleware はリクエストのボディを上限までしか読み込まないようにするミドルウェアです。
+// Content-Lengthが上限を超える場合は読み込まずに413を返します。
+// Content-Lengthが無く読み込み中に上限を超えた場合も、コントローラーのレスポンスを413に置き換えます。
+// multipart/form-dataはコントローラーごとに解析のメモリの上限が変わらないよう、ここでMultipartMemoryを上限に解析します。
+func BodyLimitMiddleware(config BodyLimitConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit := config.Default
+		if override, ok := config.Overrides[c.Request.Method+" "+c.FullPath()]; ok {
+			limit = override
+		}
+		if limit <= 0 || c.Request.Body == nil || c.Request.Body == http.NoBody {
+			c.Next()
+			return
+		}
+
+		response := requestTooLargeResponse(c, limit)
+		if c.Request.ContentLength > limit {
+			c.Header("Connection", "close")
+			c.AbortWithStatusJSON(constants.StatusEntityTooLarge, response)
+			return
+		}
+
+		body := &limitedBody{ReadCloser: http.MaxBytesReader(c.Writer, c.Request.Body, limit)}
+		c.Request.Body = body
+		c.Writer = &limitedBodyWriter{ResponseWriter: c.Writer, body: body, response: response}
+
+		if mediaType, _, _ := mime.ParseMediaType(c.ContentType()); mediaType == "multipart/form-data" {
+			// 解析に失敗した場合の応答はコントローラーに任せる
+			_ = c.Request.ParseMultipartForm(MultipartMemory)
+			if body.exceeded {
+				c.AbortWithStatusJSON(constants.StatusEntityTooLarge, response)
+				return
+			}
+		}
+
+		c.Next()
+	}
+}
+
+// requestTooLargeResponse 413のレスポンスを生成する
+func requestTooLargeResponse(c *gin.Context, limit int64) utils.ErrorResponse {
+	return utils.NewAppError(constants.StatusEntityTooLarge, constants.ErrCodeRequestTooLarge, constants.RequestTooLarge).
+		WithDetails(map[string]interface{}{"limit_bytes": limit}).
+		Response(GetRequestID(c))
+}
+
+// limitedBody は上限を超えて読み込もうとしたかを記録するボディです。
+type limitedBody struct {
+	io.ReadCloser
+	exceeded bool
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		b.exceeded = true
+	}
+	return n, err
+}
+
+// limitedBodyWriter はボディが上限を超えた場合に、コントローラーのレスポンスを413に置き換えるResponseWriterです。
+type limitedBodyWriter struct {
+	gin.ResponseWriter
+	body     *limitedBody
+	response utils.ErrorResponse
+	replaced bool
+}
+
+func (w *limitedBodyWriter) WriteHeader(code int) {
+	if w.body.exceeded {
+		code = constants.StatusEntityTooLarge
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *limitedBodyWriter) Write(data []byte) (int, error) {
+	if !w.body.exceeded {
+		return w.ResponseWriter.Write(data)
+	}
+	if !w.replaced {
+		w.replaced = true
+		encoded, err := json.Marshal(w.response)
+		if err != nil {
+			return 0, err
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.ResponseWriter.WriteHeader(constants.StatusEntityTooLarge)
+		if _, err := w.ResponseWriter.Write(encoded); err != nil {
+			return 0, err
+		}
+	}
+	return len(data), nil
+}
+
+func (w *limitedBodyWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
@@ -0,0 +1,114 @@
+package middlewares
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/constants"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// classAccessServiceKey リクエストボディでクラスを指定するハンドラーがアクセス制限を確認できるよう、サービスを保存するコンテキストのキー
+const classAccessServiceKey = "classAccessService"
+
+// ClassIDResolver 掲示板やスケジュールなどのリソースのIDから、リソースが属するクラスのIDを求める。リソースが存在しない場合は0を返す
+type ClassIDResolver func(ctx context.Context, id uint) (uint, error)
+
+// ClassResource パスにcidを含まないルートで、パスのリソースIDからクラスを求める設定
+// RoutePrefixはc.FullPath()の前方一致で比較し、Methodが空の場合は全てのメソッドに適用する
+type ClassResource struct {
+	Method      string
+	RoutePrefix string
+	Param       string
+	Resolve     ClassIDResolver
+}
+
+// ClassAccessMiddleware パスまたはクエリのcid、パスのリソースが属するクラスのアクセス制限を確認し、制限外のリクエストに403を返すミドルウェア。
+// exemptRoutesのルートは確認しない。制限を設定していないクラスとクラスを指定しないリクエストはそのまま通す
+// リクエストボディでクラスを指定するハンドラーはCheckClassAccessで確認する
+func ClassAccessMiddleware(service services.ClassAccessService, resources []ClassResource, exemptRoutes ...string) gin.HandlerFunc {
+	exempt := make(map[string]bool, len(exemptRoutes))
+	for _, route := range exemptRoutes {
+		exempt[route] = true
+	}
+
+	return func(c *gin.Context) {
+		if exempt[c.FullPath()] {
+			c.Next()
+			return
+		}
+		c.Set(classAccessServiceKey, service)
+
+		cid, ok, err := requestClassID(c, resources)
+		if err != nil {
+			_ = c.Error(err)
+			c.Abort()
+			return
+		}
+		if ok && !checkClassAccess(c, service, cid) {
+			return
+		}
+		c.Next()
+	}
+}
+
+// CheckClassAccess リクエストボディなどで指定されたクラスのアクセス制限を確認する。
+// 制限外の場合は403を返してfalseを返す。ClassAccessMiddlewareを通っていないリクエストでは確認しない
+func CheckClassAccess(c *gin.Context, cid uint) bool {
+	service, ok := c.Value(classAccessServiceKey).(services.ClassAccessService)
+	if !ok {
+		return true
+	}
+	return checkClassAccess(c, service, cid)
+}
+
+// checkClassAccess クラスのアクセス制限を確認し、制限外またはエラーの場合はリクエストを中断してfalseを返す
+func checkClassAccess(c *gin.Context, service services.ClassAccessService, cid uint) bool {
+	err := service.CheckAccess(c.Request.Context(), cid, c.ClientIP(), time.Now())
+	if errors.Is(err, services.ErrAccessRestricted) {
+		c.AbortWithStatusJSON(constants.StatusForbidden,
+			utils.NewForbiddenError(constants.ErrCodeAccessRestricted, constants.AccessRestricted).Response(GetRequestID(c)))
+		return false
+	}
+	if err != nil {
+		_ = c.Error(err)
+		c.Abort()
+		return false
+	}
+	return true
+}
+
+// requestClassID パス、クエリ、パスのリソースの順にクラスIDを求める
+func requestClassID(c *gin.Context, resources []ClassResource) (uint, bool, error) {
+	for _, candidate := range []string{c.Param("cid"), c.Query("cid")} {
+		if cid, err := strconv.ParseUint(candidate, 10, 32); err == nil {
+			return uint(cid), true, nil
+		}
+	}
+
+	route := c.FullPath()
+	for _, resource := range resources {
+		if resource.Method != "" && resource.Method != c.Request.Method {
+			continue
+		}
+		if !strings.HasPrefix(route, resource.RoutePrefix) {
+			continue
+		}
+		id, err := strconv.ParseUint(c.Param(resource.Param), 10, 32)
+		if err != nil {
+			continue
+		}
+		// 存在しないリソースはハンドラーで404を返す
+		cid, err := resource.Resolve(c.Request.Context(), uint(id))
+		if err != nil || cid == 0 {
+			return 0, false, err
+		}
+		return cid, true, nil
+	}
+	return 0, false, nil
+}
@@ -0,0 +1,26 @@
+package middlewares
+
+import (
+	"crypto/subtle"
+	"log"
+	"strings"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/constants"
+	"github.com/gin-gonic/gin"
+)
+
+// StaticTokenMiddleware Authorizationヘッダーのベアラートークンが固定のトークンと一致する場合のみ通過させる。
+// デバッグ用のエンドポイントを保護するためのもので、アクセスはすべてログに残す
+func StaticTokenMiddleware(token string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		provided := strings.TrimPrefix(ctx.GetHeader("Authorization"), "Bearer ")
+		authorized := token != "" && subtle.ConstantTimeCompare([]byte(provided), []byte(token)) == 1
+		log.Printf("[DEBUG] access: request_id=%s method=%s path=%s remote=%s authorized=%t",
+			GetRequestID(ctx), ctx.Request.Method, ctx.Request.URL.Path, ctx.ClientIP(), authorized)
+		if !authorized {
+			ctx.AbortWithStatusJSON(constants.StatusUnauthorized, gin.H{"error": constants.Unauthorized})
+			return
+		}
+		ctx.Next()
+	}
+}
@@ -1,3 +1,71 @@
 package middlewares
 
-// 에러 발생 시 적절하게 처리하는 미들웨어를 포함
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/constants"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"gorm.io/gorm"
+)
+
+// GlobalErrorHandler はコントローラーがc.Errorで登録したエラーを統一された形式で返すミドルウェアです。
+// エラーの種類に応じてステータスを決定し、想定外のエラーはリクエストID付きの500で返します。
+// 500番台のエラーはエラー監視サービスにも送信します。
+func GlobalErrorHandler(reporter utils.ErrorReporter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 {
+			return
+		}
+
+		requestID := GetRequestID(c)
+		log.Printf("request %s failed: %v", requestID, c.Errors.String())
+
+		appErr := resolveAppError(c.Errors.Last().Err)
+		if appErr.Status >= http.StatusInternalServerError && appErr.Status != http.StatusGatewayTimeout {
+			reporter.Report(newErrorEvent(c, appErr))
+		}
+
+		// コントローラーが既にレスポンスを書き込んでいる場合は二重に書き込まない
+		if c.Writer.Written() {
+			return
+		}
+
+		response := appErr.Response(requestID)
+		if appErr.Code == constants.ErrCodeInternal && gin.Mode() == gin.DebugMode {
+			response.Details = map[string]interface{}{"errors": c.Errors.String()}
+		}
+
+		c.JSON(appErr.Status, response)
+	}
+}
+
+// resolveAppError はエラーを展開して対応するAppErrorに変換します。
+func resolveAppError(err error) *utils.AppError {
+	var appErr *utils.AppError
+	var validationErrs validator.ValidationErrors
+
+	switch {
+	case errors.As(err, &appErr):
+		return appErr
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return utils.NewNotFoundError(constants.ErrCodeNotFound, constants.CodeNotFound).Wrap(err)
+	case errors.As(err, &validationErrs):
+		fields := make(map[string]interface{}, len(validationErrs))
+		for _, fieldErr := range validationErrs {
+			fields[fieldErr.Field()] = fieldErr.Tag()
+		}
+		return utils.NewAppError(constants.StatusUnprocessable, constants.ErrCodeValidation, constants.ValidationFailed).
+			WithDetails(fields).Wrap(err)
+	case errors.Is(err, context.DeadlineExceeded):
+		return utils.NewAppError(constants.StatusGatewayTimeout, constants.ErrCodeTimeout, constants.RequestTimeout).Wrap(err)
+	default:
+		return utils.NewInternalError(err)
+	}
+}
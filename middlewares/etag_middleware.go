@@ -0,0 +1,243 @@
+package middlewares
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// ClassChangedKey GETなど書き込み以外のメソッドでクラスのデータを変更したハンドラーがクラスIDを指定するためのコンテキストキー
+	ClassChangedKey = "classVersionChanged"
+	// classVersionBumpTimeout 書き込み後にクラスのバージョンを更新する際のタイムアウト
+	classVersionBumpTimeout = 3 * time.Second
+)
+
+// ETagVersionFunc レスポンスの内容が変わると値が変わるバージョンを返す。取得できない場合はfalseを返す
+type ETagVersionFunc func(c *gin.Context) (string, bool)
+
+// ETagConfig ETagMiddlewareのルートグループごとの設定
+type ETagConfig struct {
+	// CacheControl 200と304のレスポンスに付与するCache-Controlヘッダー。空の場合は付与しない
+	CacheControl string
+	// Version 指定した場合はハンドラーを実行する前にIf-None-Matchと比較する。
+	// 取得できない場合はレスポンスボディのハッシュを使う
+	Version ETagVersionFunc
+}
+
+// ETagMiddleware GETのレスポンスにETagを付与し、If-None-Matchが一致する場合はボディを返さずに304を返す。
+// レスポンスをバッファするため、SSEやエクスポートなどのストリーミングするルートには適用しないこと。
+// TokenAuthMiddlewareの後に適用すること
+func ETagMiddleware(config ETagConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method != http.MethodGet {
+			c.Next()
+			return
+		}
+
+		// バージョンはハンドラーの実行前に取得する。実行中に更新された場合も次のリクエストで不一致になる
+		etag := ""
+		if config.Version != nil {
+			if version, ok := config.Version(c); ok {
+				etag = versionETag(c, version)
+				if etagMatches(c.GetHeader("If-None-Match"), etag) {
+					writeNotModified(c, etag, config.CacheControl)
+					c.Abort()
+					return
+				}
+			}
+		}
+
+		writer := &etagResponseWriter{ResponseWriter: c.Writer, status: http.StatusOK}
+		c.Writer = writer
+		c.Next()
+		c.Writer = writer.ResponseWriter
+
+		// c.Errorで登録されたエラーはGlobalErrorHandlerが後で書き込む
+		if !writer.written {
+			return
+		}
+		if writer.status != http.StatusOK || len(c.Errors) > 0 {
+			writer.flush()
+			return
+		}
+
+		if etag == "" {
+			sum := sha256.Sum256(writer.body.Bytes())
+			etag = `"` + hex.EncodeToString(sum[:]) + `"`
+		}
+		if etagMatches(c.GetHeader("If-None-Match"), etag) {
+			writeNotModified(c, etag, config.CacheControl)
+			return
+		}
+		setCacheHeaders(c, etag, config.CacheControl)
+		writer.flush()
+	}
+}
+
+// ClassVersion パスのcidのクラスのバージョンを返すETagVersionFunc
+func ClassVersion(versions services.ClassVersionService) ETagVersionFunc {
+	return func(c *gin.Context) (string, bool) {
+		cid, err := strconv.ParseUint(c.Param("cid"), 10, 32)
+		if versions == nil || err != nil {
+			return "", false
+		}
+		version, err := versions.ClassVersion(c.Request.Context(), uint(cid))
+		if err != nil {
+			log.Printf("etag: failed to get class version: request_id=%s err=%v", GetRequestID(c), err)
+			return "", false
+		}
+		return version, true
+	}
+}
+
+// DashboardVersion パスのcidのクラスとリクエストしたユーザーのダッシュボードのバージョンを返すETagVersionFunc
+func DashboardVersion(versions services.ClassVersionService) ETagVersionFunc {
+	return func(c *gin.Context) (string, bool) {
+		cid, err := strconv.ParseUint(c.Param("cid"), 10, 32)
+		if versions == nil || err != nil {
+			return "", false
+		}
+		version, err := versions.DashboardVersion(c.Request.Context(), c.GetUint("userID"), uint(cid))
+		if err != nil {
+			log.Printf("etag: failed to get dashboard version: request_id=%s err=%v", GetRequestID(c), err)
+			return "", false
+		}
+		return version, true
+	}
+}
+
+// MarkClassChanged 書き込み以外のメソッドのハンドラーでクラスのデータを変更したことを記録する。
+// 複数のクラスを変更した書き込みのハンドラーも、全てのクラスを指定するために使う
+func MarkClassChanged(c *gin.Context, cids ...uint) {
+	c.Set(ClassChangedKey, cids)
+}
+
+// ClassVersionMiddleware 成功したPOST/PATCH/PUT/DELETEのリクエストと、MarkClassChangedを呼んだリクエストのクラスのバージョンを更新する。
+// POST/PATCH/PUT/DELETEのクラスIDは監査ログと同じくハンドラーの指定、パス、クエリ、フォームの順に求める
+func ClassVersionMiddleware(versions services.ClassVersionService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if versions == nil || c.Writer.Status() >= http.StatusBadRequest || len(c.Errors) > 0 {
+			return
+		}
+		var cids []uint
+		if value, ok := c.Get(ClassChangedKey); ok {
+			cids, _ = value.([]uint)
+		} else if isMutatingMethod(c.Request.Method) {
+			if cid := auditClassID(c); cid != nil {
+				cids = []uint{*cid}
+			}
+		}
+		if len(cids) == 0 {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), classVersionBumpTimeout)
+		defer cancel()
+		for _, cid := range cids {
+			if err := versions.Bump(ctx, cid); err != nil {
+				log.Printf("etag: failed to bump class version: request_id=%s class_id=%d err=%v", GetRequestID(c), cid, err)
+			}
+		}
+	}
+}
+
+// versionETag バージョンとユーザー・URLからETagを求める。v1とv2やクエリの違いでレスポンスが異なるため、URL全体を含める
+func versionETag(c *gin.Context, version string) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%d|%s", version, c.GetUint("userID"), c.Request.URL.RequestURI())))
+	return `"v-` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// etagMatches If-None-Matchのいずれかのタグと一致するか判定する。If-None-Matchでは弱い比較を使う
+func etagMatches(header, etag string) bool {
+	if header == "" {
+		return false
+	}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// setCacheHeaders ETagとCache-Controlヘッダーを設定する
+func setCacheHeaders(c *gin.Context, etag, cacheControl string) {
+	c.Header("ETag", etag)
+	if cacheControl != "" {
+		c.Header("Cache-Control", cacheControl)
+	}
+}
+
+// writeNotModified ボディを含まない304を返す
+func writeNotModified(c *gin.Context, etag, cacheControl string) {
+	setCacheHeaders(c, etag, cacheControl)
+	c.Status(http.StatusNotModified)
+	c.Writer.WriteHeaderNow()
+}
+
+// etagResponseWriter ETagを計算するためにレスポンスをバッファするResponseWriter
+type etagResponseWriter struct {
+	gin.ResponseWriter
+	body    bytes.Buffer
+	status  int
+	written bool
+}
+
+func (w *etagResponseWriter) WriteHeader(code int) {
+	if code > 0 && !w.written {
+		w.status = code
+	}
+}
+
+// WriteHeaderNow ヘッダーの送信はflushまで遅らせる
+func (w *etagResponseWriter) WriteHeaderNow() {
+	w.written = true
+}
+
+func (w *etagResponseWriter) Write(data []byte) (int, error) {
+	w.WriteHeaderNow()
+	return w.body.Write(data)
+}
+
+func (w *etagResponseWriter) WriteString(s string) (int, error) {
+	w.WriteHeaderNow()
+	return w.body.WriteString(s)
+}
+
+func (w *etagResponseWriter) Status() int {
+	return w.status
+}
+
+func (w *etagResponseWriter) Size() int {
+	if !w.written {
+		return -1
+	}
+	return w.body.Len()
+}
+
+func (w *etagResponseWriter) Written() bool {
+	return w.written
+}
+
+// flush バッファしたステータスとボディを書き込む
+func (w *etagResponseWriter) flush() {
+	w.ResponseWriter.WriteHeader(w.status)
+	w.ResponseWriter.WriteHeaderNow()
+	if w.body.Len() > 0 {
+		_, _ = w.ResponseWriter.Write(w.body.Bytes())
+	}
+}
@@ -0,0 +1,218 @@
+package middlewares
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/constants"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	// IdempotencyKeyHeader クライアントが再送を識別するためのヘッダー
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotencyReplayedHeader 保存したレスポンスを返したことを示すヘッダー
+	IdempotencyReplayedHeader = "Idempotent-Replayed"
+	// IdempotencyTTL 最初のレスポンスを保存する期間
+	IdempotencyTTL = 24 * time.Hour
+	// idempotencyInFlightTTL 処理中の印の有効期限。処理中にプロセスが終了しても、この期間が過ぎれば再実行できる
+	idempotencyInFlightTTL = 5 * time.Minute
+	// idempotencyMaxBodySize 保存するレスポンスボディの上限。超えた場合は保存せず、ヘッダーが無いものとして扱う
+	idempotencyMaxBodySize = 64 << 10
+	// idempotencyStoreTimeout ハンドラーの実行後にレスポンスを保存する際のタイムアウト
+	idempotencyStoreTimeout = 3 * time.Second
+	idempotencyKeyFormat    = "idempotency:%d:%s %s:%s"
+	idempotencyMaxKeyLength = 255
+)
+
+// IdempotencyStore 冪等キーごとのレスポンスを保存する
+type IdempotencyStore interface {
+	// Reserve キーが未使用の場合は処理中として登録し、trueを返す
+	Reserve(ctx context.Context, key string, ttl time.Duration) (bool, error)
+	// Get 保存されたレスポンスを返す。処理中またはキーが無い場合はnilを返す
+	Get(ctx context.Context, key string) (*IdempotentResponse, error)
+	Save(ctx context.Context, key string, response IdempotentResponse, ttl time.Duration) error
+	Release(ctx context.Context, key string) error
+}
+
+// IdempotentResponse 再送時に返すレスポンス
+type IdempotentResponse struct {
+	Status      int    `json:"status"`
+	ContentType string `json:"content_type"`
+	Body        []byte `json:"body"`
+}
+
+// IdempotencyMiddleware Idempotency-Keyヘッダーが指定されたリクエストの最初のレスポンスを保存し、
+// 同じユーザー・ルート・キーで再送されたリクエストはハンドラーを実行せずに保存したレスポンスを返す。
+// 最初のリクエストの処理中に届いた再送には409を返す。
+// 5xxのレスポンスと上限を超えるレスポンスは保存せず、再送時にハンドラーを再実行する。
+// TokenAuthMiddlewareの後に適用すること
+func IdempotencyMiddleware(store IdempotencyStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		idempotencyKey := c.GetHeader(IdempotencyKeyHeader)
+		if store == nil || idempotencyKey == "" || len(idempotencyKey) > idempotencyMaxKeyLength {
+			c.Next()
+			return
+		}
+		key := fmt.Sprintf(idempotencyKeyFormat, c.GetUint("userID"), c.Request.Method, c.Request.URL.Path, idempotencyKey)
+
+		reserved, err := store.Reserve(c.Request.Context(), key, idempotencyInFlightTTL)
+		if err != nil {
+			log.Printf("idempotency: failed to reserve key: request_id=%s err=%v", GetRequestID(c), err)
+			c.Next()
+			return
+		}
+		if !reserved {
+			replayIdempotentResponse(c, store, key)
+			return
+		}
+
+		writer := &idempotencyResponseWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		completed := false
+		// ハンドラーがパニックした場合も処理中の印を残さない
+		defer func() {
+			if !completed {
+				releaseIdempotencyKey(c, store, key)
+			}
+		}()
+		c.Next()
+		completed = true
+		c.Writer = writer.ResponseWriter
+
+		// c.Errorで登録されたエラーはGlobalErrorHandlerが後で書き込むため、保存せずに再実行できるようにする
+		status := writer.Status()
+		if !writer.Written() || status >= http.StatusInternalServerError || writer.overflow {
+			releaseIdempotencyKey(c, store, key)
+			return
+		}
+
+		storeCtx, cancel := context.WithTimeout(context.Background(), idempotencyStoreTimeout)
+		defer cancel()
+		response := IdempotentResponse{
+			Status:      status,
+			ContentType: writer.Header().Get("Content-Type"),
+			Body:        writer.body.Bytes(),
+		}
+		if err := store.Save(storeCtx, key, response, IdempotencyTTL); err != nil {
+			log.Printf("idempotency: failed to save response: request_id=%s err=%v", GetRequestID(c), err)
+		}
+	}
+}
+
+// releaseIdempotencyKey 処理中の印を削除し、同じキーで再実行できるようにする
+func releaseIdempotencyKey(c *gin.Context, store IdempotencyStore, key string) {
+	ctx, cancel := context.WithTimeout(context.Background(), idempotencyStoreTimeout)
+	defer cancel()
+	if err := store.Release(ctx, key); err != nil {
+		log.Printf("idempotency: failed to release key: request_id=%s err=%v", GetRequestID(c), err)
+	}
+}
+
+// replayIdempotentResponse 保存されたレスポンスを返す。最初のリクエストが処理中の場合は409を返す
+func replayIdempotentResponse(c *gin.Context, store IdempotencyStore, key string) {
+	response, err := store.Get(c.Request.Context(), key)
+	if err != nil {
+		_ = c.Error(err)
+		c.Abort()
+		return
+	}
+	if response == nil {
+		c.AbortWithStatusJSON(constants.StatusConflict,
+			utils.NewConflictError(constants.ErrCodeIdempotencyInFlight, constants.IdempotencyInFlight).Response(GetRequestID(c)))
+		return
+	}
+
+	c.Header(IdempotencyReplayedHeader, "true")
+	c.Data(response.Status, response.ContentType, response.Body)
+	c.Abort()
+}
+
+// idempotencyResponseWriter 保存するためにレスポンスボディを上限まで複製するResponseWriter
+type idempotencyResponseWriter struct {
+	gin.ResponseWriter
+	body     bytes.Buffer
+	overflow bool
+}
+
+func (w *idempotencyResponseWriter) Write(data []byte) (int, error) {
+	w.capture(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *idempotencyResponseWriter) WriteString(s string) (int, error) {
+	w.capture([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *idempotencyResponseWriter) capture(data []byte) {
+	if w.overflow {
+		return
+	}
+	if w.body.Len()+len(data) > idempotencyMaxBodySize {
+		w.overflow = true
+		w.body.Reset()
+		return
+	}
+	w.body.Write(data)
+}
+
+// idempotencyInFlight 処理中を表す値。保存したレスポンスと区別するため、JSONとして解析できない値とする
+const idempotencyInFlight = "in-flight"
+
+// redisIdempotencyStore Redisを使うIdempotencyStore
+type redisIdempotencyStore struct {
+	redisClient *redis.Client
+}
+
+// NewRedisIdempotencyStore Redisを使うIdempotencyStoreを生成する。redisClientがnilの場合はnilを返し、冪等キーを無視する
+func NewRedisIdempotencyStore(redisClient *redis.Client) IdempotencyStore {
+	if redisClient == nil {
+		return nil
+	}
+	return &redisIdempotencyStore{redisClient: redisClient}
+}
+
+// Reserve キーが存在しない場合のみ処理中の値を設定する
+func (s *redisIdempotencyStore) Reserve(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	return s.redisClient.SetNX(ctx, key, idempotencyInFlight, ttl).Result()
+}
+
+// Get 保存されたレスポンスを取得する
+func (s *redisIdempotencyStore) Get(ctx context.Context, key string) (*IdempotentResponse, error) {
+	value, err := s.redisClient.Get(ctx, key).Result()
+	if errors.Is(err, redis.Nil) || value == idempotencyInFlight {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var response IdempotentResponse
+	if err := json.Unmarshal([]byte(value), &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// Save レスポンスを保存し、処理中の値を置き換える
+func (s *redisIdempotencyStore) Save(ctx context.Context, key string, response IdempotentResponse, ttl time.Duration) error {
+	data, err := json.Marshal(response)
+	if err != nil {
+		return err
+	}
+	return s.redisClient.Set(ctx, key, data, ttl).Err()
+}
+
+// Release 処理中の値を削除し、同じキーで再実行できるようにする
+func (s *redisIdempotencyStore) Release(ctx context.Context, key string) error {
+	return s.redisClient.Del(ctx, key).Err()
+}
@@ -1 +1,102 @@
 package middlewares
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	RequestIDHeader = "X-Request-ID"
+	RequestIDKey    = "requestID"
+)
+
+// RequestIDMiddleware はリクエストごとにIDを割り当てるミドルウェアです。
+// クライアントからX-Request-IDが送られた場合はその値を引き継ぎます。
+// クエリのログに出力できるよう、リクエストのcontextにもIDを設定します。
+func RequestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(RequestIDHeader)
+		if requestID == "" {
+			requestID = generateRequestID()
+		}
+
+		c.Set(RequestIDKey, requestID)
+		c.Request = c.Request.WithContext(utils.ContextWithRequestID(c.Request.Context(), requestID))
+		c.Writer.Header().Set(RequestIDHeader, requestID)
+		c.Next()
+	}
+}
+
+// GetRequestID はコンテキストからリクエストIDを取得します。
+func GetRequestID(c *gin.Context) string {
+	return c.GetString(RequestIDKey)
+}
+
+// generateRequestID はランダムなリクエストIDを生成します。
+func generateRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
+
+// redactedQueryParams アクセスログに値を出力しないクエリパラメータ。
+// カレンダー購読やWebSocketではトークンをクエリで受け取るため、ログから漏れないようにする
+var redactedQueryParams = []string{"token"}
+
+// AccessLogMiddleware はginの既定と同じ形式でアクセスログを出力するミドルウェアです。
+// redactedQueryParamsに含まれるクエリの値は伏せて出力します。
+func AccessLogMiddleware() gin.HandlerFunc {
+	return gin.LoggerWithFormatter(func(param gin.LogFormatterParams) string {
+		var statusColor, methodColor, resetColor string
+		if param.IsOutputColor() {
+			statusColor = param.StatusCodeColor()
+			methodColor = param.MethodColor()
+			resetColor = param.ResetColor()
+		}
+		if param.Latency > time.Minute {
+			param.Latency = param.Latency.Truncate(time.Second)
+		}
+		return fmt.Sprintf("[GIN] %v |%s %3d %s| %13v | %15s |%s %-7s %s %#v\n%s",
+			param.TimeStamp.Format("2006/01/02 - 15:04:05"),
+			statusColor, param.StatusCode, resetColor,
+			param.Latency,
+			param.ClientIP,
+			methodColor, param.Method, resetColor,
+			RedactPath(param.Path),
+			param.ErrorMessage,
+		)
+	})
+}
+
+// RedactPath クエリ付きのパスからredactedQueryParamsの値を伏せたパスを返します。
+func RedactPath(path string) string {
+	i := strings.IndexByte(path, '?')
+	if i < 0 {
+		return path
+	}
+	query, err := url.ParseQuery(path[i+1:])
+	if err != nil {
+		return path[:i]
+	}
+	redacted := false
+	for _, key := range redactedQueryParams {
+		if _, ok := query[key]; ok {
+			query.Set(key, "REDACTED")
+			redacted = true
+		}
+	}
+	if !redacted {
+		return path
+	}
+	return path[:i+1] + query.Encode()
+}
@@ -0,0 +1,65 @@
+package middlewares
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/constants"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// MaintenanceRetryAfter 終了予定が無いメンテナンス中に返すRetry-Afterの秒数
+const MaintenanceRetryAfter = 60 * time.Second
+
+// MaintenanceMiddleware メンテナンス中のリクエストに503を返すミドルウェア。
+// read_onlyでは更新系のメソッドのみ、fullではexemptPathsで始まるパス以外の全てのリクエストを拒否する。
+// 状態はリクエストごとにRedisを参照せず、定期的に読み込んだものを使う
+func MaintenanceMiddleware(service services.MaintenanceService, exemptPaths ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		state := service.State()
+		if state.Mode == services.MaintenanceOff || isMaintenanceExempt(c.Request.URL.Path, exemptPaths) {
+			c.Next()
+			return
+		}
+
+		message := constants.MaintenanceInProgress
+		if state.Mode == services.MaintenanceReadOnly {
+			if !isMutatingMethod(c.Request.Method) {
+				c.Next()
+				return
+			}
+			message = constants.MaintenanceReadOnly
+		}
+
+		c.Header("Retry-After", strconv.Itoa(maintenanceRetryAfterSeconds(state)))
+		c.AbortWithStatusJSON(constants.StatusServiceUnavailable,
+			utils.NewAppError(constants.StatusServiceUnavailable, constants.ErrCodeMaintenance, message).
+				WithDetails(map[string]interface{}{"mode": state.Mode}).
+				Response(GetRequestID(c)))
+	}
+}
+
+// isMaintenanceExempt メンテナンス中も受け付けるパスか判定する
+func isMaintenanceExempt(path string, exemptPaths []string) bool {
+	for _, exempt := range exemptPaths {
+		if strings.HasPrefix(path, exempt) {
+			return true
+		}
+	}
+	return false
+}
+
+// maintenanceRetryAfterSeconds 終了予定までの秒数を返す。終了予定が無い場合は既定値を返す
+func maintenanceRetryAfterSeconds(state services.MaintenanceState) int {
+	if state.Until == nil {
+		return int(MaintenanceRetryAfter / time.Second)
+	}
+	seconds := int(time.Until(*state.Until).Seconds())
+	if seconds < 1 {
+		return 1
+	}
+	return seconds
+}
@@ -0,0 +1,79 @@
+package middlewares
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// RecoveryMiddleware ハンドラーのパニックを回復し、リクエストID・ルート・ユーザーIDとともにエラー監視サービスへ送信する。
+// レスポンスは他のエラーと同じ形式の500で返す
+func RecoveryMiddleware(reporter utils.ErrorReporter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+			// クライアントへの書き込みを中断するためのパニックはそのまま伝える
+			if recovered == http.ErrAbortHandler {
+				panic(recovered)
+			}
+
+			err, ok := recovered.(error)
+			if !ok {
+				err = fmt.Errorf("panic: %v", recovered)
+			}
+			stack := debug.Stack()
+			log.Printf("request %s panicked: %v\n%s", GetRequestID(c), err, stack)
+
+			event := newErrorEvent(c, err)
+			event.Stack = stack
+			reporter.Report(event)
+
+			if c.Writer.Written() {
+				c.Abort()
+				return
+			}
+			c.AbortWithStatusJSON(http.StatusInternalServerError, utils.NewInternalError(err).Response(GetRequestID(c)))
+		}()
+
+		c.Next()
+	}
+}
+
+// newErrorEvent リクエストの情報からErrorEventを生成する。認証情報やパスワードはマスクする
+func newErrorEvent(c *gin.Context, err error) utils.ErrorEvent {
+	headers := make(map[string]string, len(c.Request.Header))
+	for name, values := range c.Request.Header {
+		if len(values) == 0 {
+			continue
+		}
+		if utils.IsSensitiveHeader(name) {
+			headers[name] = utils.RedactedValue
+		} else {
+			headers[name] = values[0]
+		}
+	}
+
+	requestURL := c.Request.URL.Path
+	if c.Request.URL.RawQuery != "" {
+		requestURL += "?" + utils.RedactForm(c.Request.URL.RawQuery)
+	}
+
+	return utils.ErrorEvent{
+		Err:       err,
+		RequestID: GetRequestID(c),
+		Method:    c.Request.Method,
+		Route:     c.FullPath(),
+		URL:       requestURL,
+		UID:       c.GetUint("userID"),
+		Headers:   headers,
+		Timestamp: time.Now(),
+	}
+}
@@ -0,0 +1,24 @@
+package middlewares
+
+import (
+	"crypto/subtle"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/constants"
+	"github.com/gin-gonic/gin"
+)
+
+// ServiceAPIKeyHeader 他のサービスがAPIキーを指定するヘッダー
+const ServiceAPIKeyHeader = "X-API-Key"
+
+// ServiceKeyMiddleware X-API-Keyヘッダーが他のサービス用のAPIキーと一致する場合のみ通過させる。
+// ユーザーのトークンでは呼び出せない、サービス間のエンドポイントを保護するためのもので、キーが空の場合は全て拒否する
+func ServiceKeyMiddleware(key string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		provided := ctx.GetHeader(ServiceAPIKeyHeader)
+		if key == "" || subtle.ConstantTimeCompare([]byte(provided), []byte(key)) != 1 {
+			ctx.AbortWithStatusJSON(constants.StatusUnauthorized, gin.H{"error": constants.Unauthorized})
+			return
+		}
+		ctx.Next()
+	}
+}
@@ -0,0 +1,41 @@
+package middlewares
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TimeoutConfig はリクエストごとのタイムアウト設定です。
+// Overridesのキーは "METHOD /route/path" 形式で、0を指定したルートには期限を設定しません。
+type TimeoutConfig struct {
+	Default   time.Duration
+	Overrides map[string]time.Duration
+}
+
+// TimeoutMiddleware はリクエストのコンテキストに期限を設定するミドルウェアです。
+// 期限を過ぎた場合はcontext.DeadlineExceededを登録し、GlobalErrorHandlerで504として返します。
+func TimeoutMiddleware(config TimeoutConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		timeout := config.Default
+		if override, ok := config.Overrides[c.Request.Method+" "+c.FullPath()]; ok {
+			timeout = override
+		}
+		if timeout <= 0 {
+			c.Next()
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) && len(c.Errors) == 0 {
+			_ = c.Error(context.DeadlineExceeded)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package migration
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+
+	"gorm.io/gorm"
+)
+
+// commandUsage migrateサブコマンドの使い方
+const commandUsage = `usage: migrate <command>
+  up           未適用のマイグレーションを全て適用する
+  down [N]     適用済みのマイグレーションを新しい順にN件 (省略時は1件) 取り消す
+  version      適用済みのバージョンを表示する
+  baseline     AutoMigrateで作成した既存のデータベースにベースラインを適用済みとして記録する
+  force V      途中で失敗したマイグレーションを手動で直した後、バージョンVを記録し直す`
+
+// RunCommand migrateサブコマンドを実行する。argsはサブコマンド名より後の引数
+func RunCommand(db *gorm.DB, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%s", commandUsage)
+	}
+
+	switch args[0] {
+	case "up":
+		if err := MigrateUp(db); err != nil {
+			return err
+		}
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				return fmt.Errorf("down: N must be a positive integer: got %q", args[1])
+			}
+			steps = n
+		}
+		if err := MigrateDown(db, steps); err != nil {
+			return err
+		}
+	case "baseline":
+		if err := Baseline(db); err != nil {
+			return err
+		}
+	case "force":
+		if len(args) < 2 {
+			return fmt.Errorf("force: version is required")
+		}
+		version, err := strconv.ParseUint(args[1], 10, 32)
+		if err != nil {
+			return fmt.Errorf("force: version must be a non-negative integer: got %q", args[1])
+		}
+		if err := Force(db, uint(version)); err != nil {
+			return err
+		}
+	case "version":
+	default:
+		return fmt.Errorf("unknown command %q\n%s", args[0], commandUsage)
+	}
+
+	version, dirty, err := Version(db)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "version: %d (dirty: %t)\n", version, dirty)
+	return nil
+}
@@ -1,51 +1,107 @@
 package migration
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
 	"log"
-	"os"
-	"strconv"
+	"strings"
 	"time"
 
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/config"
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/utils"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
 )
 
-func InitDB() (*gorm.DB, error) {
-	user := os.Getenv("POSTGRES_USER")
-	pass := os.Getenv("POSTGRES_PASSWORD")
-	dbName := os.Getenv("POSTGRES_DATABASE")
-	host := os.Getenv("POSTGRES_HOST")
-	port := os.Getenv("POSTGRES_PORT")
+// logLevels LOG_LEVELの値に対応するGORMのログレベル
+var logLevels = map[string]logger.LogLevel{
+	"silent": logger.Silent,
+	"error":  logger.Error,
+	"warn":   logger.Warn,
+	"info":   logger.Info,
+}
 
-	// ポート番号が数値でない場合はデフォルトの3306を使用
-	portInt, err := strconv.Atoi(port)
-	if err != nil {
-		log.Printf("Invalid port number. Using default port 5432. Error: %v", err)
-		portInt = 5432
-	}
+// connectTimeout 起動時にデータベースへの疎通を確認する時間の上限
+const connectTimeout = 10 * time.Second
 
-	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%d sslmode=disable TimeZone=Asia/Tokyo", host, user, pass, dbName, portInt)
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+// InitDB 検証済みの設定でデータベースに接続する。クエリはLOG_LEVELに応じてアプリケーションのログに出力する。
+// 接続できない場合は終了せずにエラーを返し、終了するかどうかは呼び出し側が決める
+func InitDB(cfg config.DatabaseConfig) (*gorm.DB, error) {
+	level, ok := logLevels[cfg.LogLevel]
+	if !ok {
+		level = logger.Warn
+	}
+	log.Printf("connecting to database %s", cfg)
+	db, err := gorm.Open(postgres.Open(cfg.DSN()), &gorm.Config{
+		Logger:      utils.NewQueryLogger(log.Default(), cfg.SlowQueryThreshold, false).LogMode(level),
+		PrepareStmt: cfg.PrepareStmt,
+	})
 	if err != nil {
-		log.Fatalf("failed to connect to database: %v", err)
+		return nil, fmt.Errorf("failed to connect to database %s: %w", cfg, err)
 	}
 
 	sqlDB, err := db.DB()
 	if err != nil {
-		log.Fatalf("failed to get gennric database: %v", err)
+		return nil, fmt.Errorf("failed to get generic database: %w", err)
+	}
+	configurePool(sqlDB, cfg)
+	// PrepareStmtが有効な場合GORMは接続時に疎通を確認しないため、ここで確認する
+	ctx, cancel := context.WithTimeout(context.Background(), connectTimeout)
+	defer cancel()
+	if err := sqlDB.PingContext(ctx); err != nil {
+		_ = sqlDB.Close()
+		return nil, fmt.Errorf("failed to connect to database %s: %w", cfg, err)
 	}
 
-	sqlDB.SetMaxIdleConns(10)
-	sqlDB.SetMaxOpenConns(100)
-	sqlDB.SetConnMaxLifetime(time.Hour)
+	if cfg.ReplicaHost != "" {
+		if err := useReadReplica(db, cfg); err != nil {
+			_ = sqlDB.Close()
+			return nil, fmt.Errorf("failed to set up read replica: %w", err)
+		}
+	}
 
 	return db, nil
 }
 
-func Migrate(db *gorm.DB) {
-	err := db.AutoMigrate(
+// configurePool 設定に従ってコネクションプールの上限と接続を使い回す時間を設定する
+func configurePool(sqlDB *sql.DB, cfg config.DatabaseConfig) {
+	sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+}
+
+// replicaHealthCheckInterval リードレプリカのヘルスチェックの間隔
+const replicaHealthCheckInterval = 10 * time.Second
+
+// useReadReplica リードレプリカに接続し、読み取り専用のクエリを振り分ける。コネクションプールはプライマリと同じ設定にする。
+// 起動時にレプリカへ接続できない場合もプライマリで動作を続け、ヘルスチェックが成功した時点から振り分ける
+func useReadReplica(db *gorm.DB, cfg config.DatabaseConfig) error {
+	replicaDB, err := sql.Open("pgx", cfg.ReplicaDSN())
+	if err != nil {
+		return err
+	}
+	configurePool(replicaDB, cfg)
+
+	replica := repositories.NewReadReplica(replicaDB, replicaDB.PingContext)
+	ctx, cancel := context.WithTimeout(context.Background(), replicaHealthCheckInterval)
+	defer cancel()
+	_ = replica.CheckHealth(ctx)
+	if err := db.Use(replica); err != nil {
+		_ = replicaDB.Close()
+		return err
+	}
+	replica.StartHealthCheck(context.Background(), replicaHealthCheckInterval)
+	return nil
+}
+
+// tableModels リポジトリが使用するテーブルのモデル。マイグレーションと起動時のテーブルの確認の対象になる
+func tableModels() []interface{} {
+	return []interface{}{
 		&models.User{},
 		&models.Class{},
 		&models.ClassUser{},
@@ -53,8 +109,84 @@ func Migrate(db *gorm.DB) {
 		&models.ClassCode{},
 		&models.ClassSchedule{},
 		&models.Attendance{},
-	)
-	if err != nil {
+		&models.AuditLog{},
+		&models.ChatSticker{},
+		&models.AnnouncementSubscription{},
+		&models.ClassSemester{},
+		&models.ClassGradeThreshold{},
+		&models.ClassBoardComment{},
+		&models.ClassBoardCommentLike{},
+		&models.ScheduleMaterial{},
+		&models.ScheduleSurveyResponse{},
+		&models.Notification{},
+		&models.CurriculumItem{},
+		&models.UserProgress{},
+		&models.DeviceToken{},
+		&models.NotificationPreference{},
+		&models.Webhook{},
+		&models.WebhookDelivery{},
+		&models.ClassIntegration{},
+		&models.AccessibilitySetting{},
+		&models.GoogleCalendarSync{},
+		&models.CalendarSyncClass{},
+		&models.CalendarEventLink{},
+		&models.ClassBoardVariant{},
+		&models.DigestSetting{},
+	}
+}
+
+// roleEnumSQL class_users.roleの列挙型を作成する。作成済みの場合は何もしない
+const roleEnumSQL = `DO $$ BEGIN
+	CREATE TYPE role AS ENUM ('ADMIN', 'ASSISTANT', 'USER', 'APPLICANT', 'BLACKLIST');
+EXCEPTION
+	WHEN duplicate_object THEN NULL;
+END $$;`
+
+// dedupAttendancesSQL 同じユーザーとスケジュールの出席が重複している場合に、最後に記録したものだけを残す。
+// (uid, csid)の一意インデックスを作成する前に実行する。テスト用のSQLiteでも実行できるようにウィンドウ関数で書く
+const dedupAttendancesSQL = `DELETE FROM attendances WHERE id IN (
+	SELECT id FROM (
+		SELECT id, ROW_NUMBER() OVER (PARTITION BY uid, csid ORDER BY recorded_at DESC, id DESC) AS rn FROM attendances
+	) ranked WHERE rn > 1
+);`
+
+// isPostgres PostgreSQLに接続しているか。列挙型やアドバイザリロックはPostgreSQLの場合のみ使い、テスト用のSQLiteでは省く
+func isPostgres(db *gorm.DB) bool {
+	return db.Dialector.Name() == "postgres"
+}
+
+// Migrate リポジトリが使用するテーブルを作成・更新する
+func Migrate(db *gorm.DB) {
+	// AutoMigrateは列挙型を作成しないため、テーブルより先に作成する
+	if isPostgres(db) {
+		if err := db.Exec(roleEnumSQL).Error; err != nil {
+			log.Fatalf("failed to create enum types: %v", err)
+		}
+	}
+	if db.Migrator().HasTable(&models.Attendance{}) {
+		if err := db.Exec(dedupAttendancesSQL).Error; err != nil {
+			log.Fatalf("failed to remove duplicate attendances: %v", err)
+		}
+	}
+	if err := db.AutoMigrate(tableModels()...); err != nil {
 		log.Fatalf("failed to migrate database: %v", err)
 	}
 }
+
+// CheckTables リポジトリが使用するテーブルが全て存在するかを確認する。存在しないテーブルがある場合は、その一覧を含むエラーを返す
+func CheckTables(db *gorm.DB) error {
+	var missing []string
+	for _, model := range tableModels() {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			return fmt.Errorf("failed to parse model %T: %w", model, err)
+		}
+		if !db.Migrator().HasTable(stmt.Schema.Table) {
+			missing = append(missing, stmt.Schema.Table)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("missing tables: %s (set RUN_MIGRATIONS=auto or versioned to create them)", strings.Join(missing, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package migration
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Roles class_users.roleに指定できるロール。クラスの管理者、アシスタント、メンバー、参加申請中、ブロック済みの順
+var Roles = []string{"ADMIN", "ASSISTANT", "USER", "APPLICANT", "BLACKLIST"}
+
+// ErrDemoSeedInRelease リリースモードでデモデータを投入しようとした
+var ErrDemoSeedInRelease = errors.New("demo data must not be seeded when GIN_MODE is release")
+
+// デモデータを識別する値。2回目以降の投入では同じ値の行を再利用する
+const (
+	demoUserPID   = "demo-user"
+	demoClassName = "デモクラス"
+	demoClassCode = "DEMO01"
+)
+
+// SeedOptions 投入するデータの設定
+type SeedOptions struct {
+	// Demo ローカル開発用のデモデータも投入する
+	Demo bool
+	// GinMode リリースモードの場合はデモデータを投入しない
+	GinMode string
+}
+
+// Seed ロールと、指定した場合はデモデータを投入する。投入済みのデータは変更しないため、何度実行してもよい
+func Seed(db *gorm.DB, opts SeedOptions) error {
+	if opts.Demo && opts.GinMode == gin.ReleaseMode {
+		return ErrDemoSeedInRelease
+	}
+	if err := seedRoles(db); err != nil {
+		return fmt.Errorf("failed to seed roles: %w", err)
+	}
+	if !opts.Demo {
+		return nil
+	}
+	if err := seedDemo(db); err != nil {
+		return fmt.Errorf("failed to seed demo data: %w", err)
+	}
+	return nil
+}
+
+// seedRoles ロールの列挙型を作成し、不足しているロールを追加する。
+// ロールはテーブルではなく列挙型のため、列挙型を作成する前のデータベースやロールを追加する前のデータベースでも揃える
+func seedRoles(db *gorm.DB) error {
+	if err := db.Exec(roleEnumSQL).Error; err != nil {
+		return err
+	}
+	for _, role := range Roles {
+		// ADD VALUEはプレースホルダーを使えないため、定数のロール名を埋め込む
+		if err := db.Exec(fmt.Sprintf("ALTER TYPE role ADD VALUE IF NOT EXISTS '%s'", role)).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// seedDemo デモ用のユーザー、クラスコード付きのクラス、スケジュールと掲示板を1つのトランザクションで投入する
+func seedDemo(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		user := models.User{}
+		if err := tx.Where(models.User{PID: demoUserPID}).
+			Attrs(models.User{Name: "デモユーザー", Email: "demo@example.com"}).
+			FirstOrCreate(&user).Error; err != nil {
+			return err
+		}
+
+		description := "ローカル開発用のデモクラスです"
+		class := models.Class{}
+		if err := tx.Where(models.Class{UID: user.ID, Name: demoClassName}).
+			Attrs(models.Class{Description: &description}).
+			FirstOrCreate(&class).Error; err != nil {
+			return err
+		}
+
+		code := models.ClassCode{}
+		if err := tx.Where(models.ClassCode{CID: class.ID}).
+			Attrs(models.ClassCode{Code: demoClassCode, UID: user.ID}).
+			FirstOrCreate(&code).Error; err != nil {
+			return err
+		}
+		// クラスから削除済みの場合も主キーが重複するため、既存の行は変更しない
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.ClassUser{
+			CID:      class.ID,
+			UID:      user.ID,
+			Nickname: user.Name,
+			Role:     "ADMIN",
+			CodeID:   &code.ID,
+		}).Error; err != nil {
+			return err
+		}
+
+		start := time.Now().Truncate(time.Hour)
+		for i := 0; i < 3; i++ {
+			startedAt := start.AddDate(0, 0, 7*(i-1))
+			schedule := models.ClassSchedule{}
+			if err := tx.Where(models.ClassSchedule{CID: class.ID, Title: fmt.Sprintf("第%d回", i+1)}).
+				Attrs(models.ClassSchedule{StartedAt: startedAt, EndedAt: startedAt.Add(90 * time.Minute), AttendanceMode: models.InPersonMode}).
+				FirstOrCreate(&schedule).Error; err != nil {
+				return err
+			}
+		}
+
+		boards := []models.ClassBoard{
+			{Title: "ようこそ", Content: "デモクラスへようこそ。", IsAnnounced: true},
+			{Title: "第1回の資料", Content: "授業の資料はこちらに掲載します。"},
+			{Title: "質問はこちら", Content: "授業の質問があれば書き込んでください。"},
+		}
+		for _, board := range boards {
+			if err := tx.Where(models.ClassBoard{CID: class.ID, Title: board.Title}).
+				Attrs(models.ClassBoard{UID: user.ID, Content: board.Content, IsAnnounced: board.IsAnnounced}).
+				FirstOrCreate(&models.ClassBoard{}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
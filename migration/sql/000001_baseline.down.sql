@@ -0,0 +1,11 @@
+DROP TABLE IF EXISTS announcement_subscriptions;
+DROP TABLE IF EXISTS chat_stickers;
+DROP TABLE IF EXISTS audit_logs;
+DROP TABLE IF EXISTS attendances;
+DROP TABLE IF EXISTS class_schedules;
+DROP TABLE IF EXISTS class_boards;
+DROP TABLE IF EXISTS class_users;
+DROP TABLE IF EXISTS class_codes;
+DROP TABLE IF EXISTS classes;
+DROP TABLE IF EXISTS users;
+DROP TYPE IF EXISTS role;
@@ -0,0 +1,155 @@
+-- AutoMigrateで作成していたスキーマ。既存のデータベースは `migrate baseline` でこのバージョンを記録して適用済みとする
+DO $$ BEGIN
+	CREATE TYPE role AS ENUM ('ADMIN', 'ASSISTANT', 'USER', 'APPLICANT', 'BLACKLIST');
+EXCEPTION
+	WHEN duplicate_object THEN NULL;
+END $$;
+
+CREATE TABLE users (
+	id bigserial,
+	name varchar(50) NOT NULL,
+	image varchar(255) NOT NULL,
+	p_id varchar(255) NOT NULL,
+	email varchar(255) NOT NULL DEFAULT '',
+	created_at timestamptz NOT NULL,
+	max_active_classes bigint,
+	PRIMARY KEY (id)
+);
+
+CREATE TABLE classes (
+	id bigserial,
+	name varchar(30) NOT NULL,
+	limitation bigint NOT NULL DEFAULT 30,
+	description varchar(255),
+	image varchar(255),
+	uid bigint NOT NULL,
+	is_archived boolean NOT NULL DEFAULT false,
+	archived_at timestamptz,
+	archive_exempt boolean NOT NULL DEFAULT false,
+	archive_notice_sent_at timestamptz,
+	is_public boolean NOT NULL DEFAULT false,
+	language varchar(10),
+	PRIMARY KEY (id)
+);
+
+CREATE TABLE class_codes (
+	id bigserial,
+	code varchar(10) NOT NULL,
+	secret varchar(20),
+	cid bigint NOT NULL,
+	uid bigint NOT NULL,
+	PRIMARY KEY (id),
+	CONSTRAINT fk_class_codes_class FOREIGN KEY (cid) REFERENCES classes(id) ON DELETE CASCADE,
+	CONSTRAINT fk_class_codes_user FOREIGN KEY (uid) REFERENCES users(id)
+);
+
+CREATE TABLE class_users (
+	cid bigint,
+	uid bigint,
+	nickname varchar(50) NOT NULL,
+	is_favorite boolean NOT NULL DEFAULT false,
+	role role NOT NULL,
+	code_id bigint,
+	joined_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	deleted_at timestamptz,
+	PRIMARY KEY (cid, uid),
+	CONSTRAINT fk_class_users_class FOREIGN KEY (cid) REFERENCES classes(id) ON DELETE CASCADE,
+	CONSTRAINT fk_class_users_user FOREIGN KEY (uid) REFERENCES users(id),
+	CONSTRAINT fk_class_users_class_code FOREIGN KEY (code_id) REFERENCES class_codes(id) ON DELETE SET NULL
+);
+CREATE INDEX idx_class_users_deleted_at ON class_users (deleted_at);
+
+CREATE TABLE class_boards (
+	id bigserial,
+	title varchar(255) NOT NULL,
+	content text NOT NULL,
+	image varchar(255),
+	created_at timestamptz NOT NULL,
+	updated_at timestamptz NOT NULL,
+	is_announced boolean NOT NULL DEFAULT false,
+	view_count bigint NOT NULL DEFAULT 0,
+	is_pinned boolean NOT NULL DEFAULT false,
+	pinned_until timestamptz,
+	cid bigint NOT NULL,
+	uid bigint NOT NULL,
+	PRIMARY KEY (id),
+	CONSTRAINT fk_class_boards_class FOREIGN KEY (cid) REFERENCES classes(id) ON DELETE CASCADE,
+	CONSTRAINT fk_class_boards_user FOREIGN KEY (uid) REFERENCES users(id)
+);
+
+CREATE TABLE class_schedules (
+	id bigserial,
+	title varchar(255) NOT NULL,
+	started_at timestamptz NOT NULL,
+	ended_at timestamptz NOT NULL,
+	cid bigint NOT NULL,
+	is_live boolean NOT NULL DEFAULT false,
+	is_cancelled boolean NOT NULL DEFAULT false,
+	attendance_mode varchar(10) NOT NULL DEFAULT 'IN_PERSON',
+	PRIMARY KEY (id),
+	CONSTRAINT fk_class_schedules_class FOREIGN KEY (cid) REFERENCES classes(id) ON DELETE CASCADE
+);
+
+CREATE TABLE attendances (
+	id bigserial,
+	cid bigint NOT NULL,
+	uid bigint NOT NULL,
+	csid bigint NOT NULL,
+	is_attendance varchar(10) NOT NULL DEFAULT 'ABSENCE',
+	note text,
+	is_note_visible boolean NOT NULL DEFAULT false,
+	recorded_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	client_recorded_at timestamptz DEFAULT null,
+	source varchar(10) NOT NULL DEFAULT 'TEACHER',
+	PRIMARY KEY (id),
+	CONSTRAINT fk_attendances_class_user FOREIGN KEY (cid, uid) REFERENCES class_users(cid, uid),
+	CONSTRAINT fk_attendances_class_schedule FOREIGN KEY (csid) REFERENCES class_schedules(id)
+);
+
+CREATE TABLE audit_logs (
+	id bigserial,
+	actor_uid bigint NOT NULL,
+	method varchar(10) NOT NULL,
+	route varchar(255) NOT NULL,
+	resource_type varchar(50),
+	resource_id varchar(100),
+	cid bigint,
+	status_code bigint NOT NULL,
+	request_id varchar(64),
+	body text,
+	created_at timestamptz NOT NULL,
+	PRIMARY KEY (id)
+);
+CREATE INDEX idx_audit_logs_actor_uid ON audit_logs (actor_uid);
+CREATE INDEX idx_audit_logs_cid ON audit_logs (cid);
+CREATE INDEX idx_audit_logs_created_at ON audit_logs (created_at);
+
+CREATE TABLE chat_stickers (
+	id bigserial,
+	name varchar(50) NOT NULL,
+	image_url varchar(255) NOT NULL,
+	created_at timestamptz NOT NULL,
+	cid bigint NOT NULL,
+	uid bigint NOT NULL,
+	PRIMARY KEY (id),
+	CONSTRAINT fk_chat_stickers_class FOREIGN KEY (cid) REFERENCES classes(id) ON DELETE CASCADE,
+	CONSTRAINT fk_chat_stickers_user FOREIGN KEY (uid) REFERENCES users(id)
+);
+CREATE INDEX idx_chat_stickers_cid ON chat_stickers (cid);
+
+CREATE TABLE announcement_subscriptions (
+	id bigserial,
+	uid bigint NOT NULL,
+	cid bigint NOT NULL,
+	channel varchar(10) NOT NULL,
+	target varchar(255) NOT NULL,
+	failure_count bigint NOT NULL DEFAULT 0,
+	disabled_at timestamptz,
+	created_at timestamptz NOT NULL,
+	updated_at timestamptz NOT NULL,
+	PRIMARY KEY (id),
+	CONSTRAINT fk_announcement_subscriptions_class FOREIGN KEY (cid) REFERENCES classes(id) ON DELETE CASCADE,
+	CONSTRAINT fk_announcement_subscriptions_user FOREIGN KEY (uid) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX idx_announcement_subscriptions_target ON announcement_subscriptions (uid, cid, channel);
+CREATE INDEX idx_announcement_subscriptions_cid ON announcement_subscriptions (cid);
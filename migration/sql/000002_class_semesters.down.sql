@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS class_grade_thresholds;
+DROP TABLE IF EXISTS class_semesters;
@@ -0,0 +1,24 @@
+-- RUN_MIGRATIONS=autoで作成済みのデータベースにもベースラインの記録後に適用できるよう、作成済みの場合は何もしない
+CREATE TABLE IF NOT EXISTS class_semesters (
+	id bigserial,
+	cid bigint NOT NULL,
+	name varchar(50) NOT NULL,
+	starts_at timestamptz NOT NULL,
+	ends_at timestamptz NOT NULL,
+	created_at timestamptz NOT NULL,
+	updated_at timestamptz NOT NULL,
+	PRIMARY KEY (id),
+	CONSTRAINT fk_class_semesters_class FOREIGN KEY (cid) REFERENCES classes(id) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_class_semesters_name ON class_semesters (cid, name);
+
+CREATE TABLE IF NOT EXISTS class_grade_thresholds (
+	id bigserial,
+	cid bigint NOT NULL,
+	grade varchar(5) NOT NULL,
+	min_rate decimal NOT NULL,
+	grade_point decimal NOT NULL DEFAULT 0,
+	PRIMARY KEY (id),
+	CONSTRAINT fk_class_grade_thresholds_class FOREIGN KEY (cid) REFERENCES classes(id) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_class_grade_thresholds_grade ON class_grade_thresholds (cid, grade);
@@ -0,0 +1,2 @@
+ALTER TABLE class_schedules DROP COLUMN IF EXISTS location_type;
+ALTER TABLE class_schedules DROP COLUMN IF EXISTS location;
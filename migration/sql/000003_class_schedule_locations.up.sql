@@ -0,0 +1,3 @@
+-- RUN_MIGRATIONS=autoで追加済みの列がある場合は何もしない
+ALTER TABLE class_schedules ADD COLUMN IF NOT EXISTS location varchar(255) NOT NULL DEFAULT '';
+ALTER TABLE class_schedules ADD COLUMN IF NOT EXISTS location_type varchar(10) NOT NULL DEFAULT '';
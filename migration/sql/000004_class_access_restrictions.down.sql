@@ -0,0 +1,3 @@
+ALTER TABLE classes DROP COLUMN IF EXISTS allowed_ip_ranges;
+ALTER TABLE classes DROP COLUMN IF EXISTS access_end_time;
+ALTER TABLE classes DROP COLUMN IF EXISTS access_start_time;
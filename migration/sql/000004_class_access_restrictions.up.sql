@@ -0,0 +1,4 @@
+-- RUN_MIGRATIONS=autoで追加済みの列がある場合は何もしない
+ALTER TABLE classes ADD COLUMN IF NOT EXISTS access_start_time varchar(5);
+ALTER TABLE classes ADD COLUMN IF NOT EXISTS access_end_time varchar(5);
+ALTER TABLE classes ADD COLUMN IF NOT EXISTS allowed_ip_ranges text;
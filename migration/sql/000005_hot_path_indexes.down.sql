@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_class_boards_cid_created_at;
+DROP INDEX IF EXISTS idx_class_schedules_cid_started_at;
+DROP INDEX IF EXISTS idx_class_users_uid;
+DROP INDEX IF EXISTS idx_class_users_cid_role;
+DROP INDEX IF EXISTS idx_attendances_uid_csid;
+DROP INDEX IF EXISTS idx_attendances_cid;
@@ -0,0 +1,11 @@
+-- 一意インデックスを作成できるよう、同じユーザーとスケジュールの出席が重複している場合は最後に記録したものだけを残す
+DELETE FROM attendances a USING attendances b
+WHERE a.uid = b.uid AND a.csid = b.csid AND (a.recorded_at, a.id) < (b.recorded_at, b.id);
+
+-- RUN_MIGRATIONS=autoで作成済みのインデックスがある場合は何もしない
+CREATE INDEX IF NOT EXISTS idx_attendances_cid ON attendances (cid);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_attendances_uid_csid ON attendances (uid, csid);
+CREATE INDEX IF NOT EXISTS idx_class_users_cid_role ON class_users (cid, role);
+CREATE INDEX IF NOT EXISTS idx_class_users_uid ON class_users (uid);
+CREATE INDEX IF NOT EXISTS idx_class_schedules_cid_started_at ON class_schedules (cid, started_at);
+CREATE INDEX IF NOT EXISTS idx_class_boards_cid_created_at ON class_boards (cid, created_at);
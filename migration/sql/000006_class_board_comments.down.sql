@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS class_board_comment_likes;
+DROP TABLE IF EXISTS class_board_comments;
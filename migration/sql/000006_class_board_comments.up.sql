@@ -0,0 +1,24 @@
+-- RUN_MIGRATIONS=autoで作成済みのデータベースにもベースラインの記録後に適用できるよう、作成済みの場合は何もしない
+CREATE TABLE IF NOT EXISTS class_board_comments (
+	id bigserial,
+	bid bigint NOT NULL,
+	uid bigint NOT NULL,
+	parent_comment_id bigint,
+	content text NOT NULL,
+	created_at timestamptz NOT NULL,
+	updated_at timestamptz NOT NULL,
+	PRIMARY KEY (id),
+	CONSTRAINT fk_class_board_comments_class_board FOREIGN KEY (bid) REFERENCES class_boards(id) ON DELETE CASCADE,
+	CONSTRAINT fk_class_board_comments_user FOREIGN KEY (uid) REFERENCES users(id),
+	CONSTRAINT fk_class_board_comments_parent_comment FOREIGN KEY (parent_comment_id) REFERENCES class_board_comments(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_class_board_comments_bid_parent ON class_board_comments (bid, parent_comment_id);
+
+CREATE TABLE IF NOT EXISTS class_board_comment_likes (
+	comment_id bigint,
+	uid bigint,
+	created_at timestamptz NOT NULL,
+	PRIMARY KEY (comment_id, uid),
+	CONSTRAINT fk_class_board_comment_likes_comment FOREIGN KEY (comment_id) REFERENCES class_board_comments(id) ON DELETE CASCADE,
+	CONSTRAINT fk_class_board_comment_likes_user FOREIGN KEY (uid) REFERENCES users(id)
+);
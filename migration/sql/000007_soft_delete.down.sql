@@ -0,0 +1,9 @@
+-- 論理削除した行は取り消し後に表示されるため、先に物理削除する
+DELETE FROM class_boards WHERE deleted_at IS NOT NULL;
+DELETE FROM attendances WHERE csid IN (SELECT id FROM class_schedules WHERE deleted_at IS NOT NULL);
+DELETE FROM class_schedules WHERE deleted_at IS NOT NULL;
+DELETE FROM classes WHERE deleted_at IS NOT NULL;
+
+ALTER TABLE class_boards DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE class_schedules DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE classes DROP COLUMN IF EXISTS deleted_at;
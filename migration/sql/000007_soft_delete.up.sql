@@ -0,0 +1,9 @@
+-- RUN_MIGRATIONS=autoで追加済みの列とインデックスがある場合は何もしない。
+-- 既定値のない列として追加するため、既存の行のdeleted_atはNULL(削除されていない)になる。class_usersは以前から論理削除しているため対象にしない
+ALTER TABLE classes ADD COLUMN IF NOT EXISTS deleted_at timestamptz;
+ALTER TABLE class_schedules ADD COLUMN IF NOT EXISTS deleted_at timestamptz;
+ALTER TABLE class_boards ADD COLUMN IF NOT EXISTS deleted_at timestamptz;
+
+CREATE INDEX IF NOT EXISTS idx_classes_deleted_at ON classes (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_class_schedules_deleted_at ON class_schedules (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_class_boards_deleted_at ON class_boards (deleted_at);
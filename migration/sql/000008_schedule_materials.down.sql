@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS schedule_survey_responses;
+DROP TABLE IF EXISTS schedule_materials;
@@ -0,0 +1,31 @@
+-- RUN_MIGRATIONS=autoで作成済みのデータベースにもベースラインの記録後に適用できるよう、作成済みの場合は何もしない
+CREATE TABLE IF NOT EXISTS schedule_materials (
+	id bigserial,
+	csid bigint NOT NULL,
+	uid bigint NOT NULL,
+	phase varchar(4) NOT NULL,
+	title varchar(100) NOT NULL,
+	content text,
+	url varchar(2048),
+	survey_notified_at timestamptz,
+	created_at timestamptz NOT NULL,
+	updated_at timestamptz NOT NULL,
+	PRIMARY KEY (id),
+	CONSTRAINT fk_schedule_materials_class_schedule FOREIGN KEY (csid) REFERENCES class_schedules(id) ON DELETE CASCADE,
+	CONSTRAINT fk_schedule_materials_user FOREIGN KEY (uid) REFERENCES users(id)
+);
+CREATE INDEX IF NOT EXISTS idx_schedule_materials_csid ON schedule_materials (csid);
+
+CREATE TABLE IF NOT EXISTS schedule_survey_responses (
+	id bigserial,
+	material_id bigint NOT NULL,
+	uid bigint NOT NULL,
+	understanding bigint NOT NULL,
+	reflection text,
+	created_at timestamptz NOT NULL,
+	updated_at timestamptz NOT NULL,
+	PRIMARY KEY (id),
+	CONSTRAINT fk_schedule_survey_responses_material FOREIGN KEY (material_id) REFERENCES schedule_materials(id) ON DELETE CASCADE,
+	CONSTRAINT fk_schedule_survey_responses_user FOREIGN KEY (uid) REFERENCES users(id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_schedule_survey_responses_material_uid ON schedule_survey_responses (material_id, uid);
@@ -0,0 +1,3 @@
+ALTER TABLE attendances DROP COLUMN IF EXISTS version;
+ALTER TABLE class_schedules DROP COLUMN IF EXISTS version;
+ALTER TABLE class_boards DROP COLUMN IF EXISTS version;
@@ -0,0 +1,4 @@
+-- RUN_MIGRATIONS=autoで追加済みの列がある場合は何もしない。既存の行はバージョン1から始める
+ALTER TABLE class_boards ADD COLUMN IF NOT EXISTS version bigint NOT NULL DEFAULT 1;
+ALTER TABLE class_schedules ADD COLUMN IF NOT EXISTS version bigint NOT NULL DEFAULT 1;
+ALTER TABLE attendances ADD COLUMN IF NOT EXISTS version bigint NOT NULL DEFAULT 1;
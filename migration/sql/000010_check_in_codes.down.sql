@@ -0,0 +1 @@
+ALTER TABLE class_schedules DROP COLUMN IF EXISTS require_check_in_code;
@@ -0,0 +1,2 @@
+-- RUN_MIGRATIONS=autoで追加済みの列がある場合は何もしない。既存のスケジュールは確認コードなしでチェックインできる
+ALTER TABLE class_schedules ADD COLUMN IF NOT EXISTS require_check_in_code boolean NOT NULL DEFAULT false;
@@ -0,0 +1,17 @@
+-- RUN_MIGRATIONS=autoで作成済みのデータベースにもベースラインの記録後に適用できるよう、作成済みの場合は何もしない
+CREATE TABLE IF NOT EXISTS notifications (
+	id bigserial,
+	uid bigint NOT NULL,
+	type varchar(30) NOT NULL,
+	title varchar(255) NOT NULL,
+	body text,
+	resource_type varchar(50),
+	resource_id bigint,
+	cid bigint,
+	read_at timestamptz,
+	created_at timestamptz NOT NULL,
+	PRIMARY KEY (id),
+	CONSTRAINT fk_notifications_user FOREIGN KEY (uid) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_notifications_uid_id ON notifications (uid, id);
+CREATE INDEX IF NOT EXISTS idx_notifications_read_at ON notifications (read_at);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS user_progresses;
+DROP TABLE IF EXISTS curriculum_items;
@@ -0,0 +1,23 @@
+-- RUN_MIGRATIONS=autoで作成済みのデータベースにもベースラインの記録後に適用できるよう、作成済みの場合は何もしない
+CREATE TABLE IF NOT EXISTS curriculum_items (
+	id bigserial,
+	cid bigint NOT NULL,
+	title varchar(100) NOT NULL,
+	sort_order bigint NOT NULL DEFAULT 0,
+	created_at timestamptz NOT NULL,
+	updated_at timestamptz NOT NULL,
+	PRIMARY KEY (id),
+	CONSTRAINT fk_curriculum_items_class FOREIGN KEY (cid) REFERENCES classes(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_curriculum_items_cid ON curriculum_items (cid);
+
+CREATE TABLE IF NOT EXISTS user_progresses (
+	id bigserial,
+	uid bigint NOT NULL,
+	item_id bigint NOT NULL,
+	completed_at timestamptz NOT NULL,
+	PRIMARY KEY (id),
+	CONSTRAINT fk_user_progresses_item FOREIGN KEY (item_id) REFERENCES curriculum_items(id) ON DELETE CASCADE,
+	CONSTRAINT fk_user_progresses_user FOREIGN KEY (uid) REFERENCES users(id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_progresses_item_uid ON user_progresses (uid, item_id);
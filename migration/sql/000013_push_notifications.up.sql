@@ -0,0 +1,22 @@
+-- RUN_MIGRATIONS=autoで作成済みのデータベースにもベースラインの記録後に適用できるよう、作成済みの場合は何もしない
+CREATE TABLE IF NOT EXISTS device_tokens (
+	id bigserial,
+	uid bigint NOT NULL,
+	platform varchar(10) NOT NULL,
+	token varchar(512) NOT NULL,
+	last_seen_at timestamptz NOT NULL,
+	created_at timestamptz NOT NULL,
+	PRIMARY KEY (id),
+	CONSTRAINT fk_device_tokens_user FOREIGN KEY (uid) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_device_tokens_uid ON device_tokens (uid);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_device_tokens_token ON device_tokens (token);
+
+CREATE TABLE IF NOT EXISTS notification_preferences (
+	uid bigint NOT NULL,
+	type varchar(30) NOT NULL,
+	push_enabled boolean NOT NULL,
+	updated_at timestamptz NOT NULL,
+	PRIMARY KEY (uid, type),
+	CONSTRAINT fk_notification_preferences_user FOREIGN KEY (uid) REFERENCES users(id) ON DELETE CASCADE
+);
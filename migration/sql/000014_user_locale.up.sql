@@ -0,0 +1,2 @@
+-- RUN_MIGRATIONS=autoで追加済みの列がある場合は何もしない。既存のユーザーは次回ログイン時にGoogleアカウントの言語を記録する
+ALTER TABLE users ADD COLUMN IF NOT EXISTS locale varchar(35) NOT NULL DEFAULT '';
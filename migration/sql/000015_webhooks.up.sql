@@ -0,0 +1,32 @@
+-- RUN_MIGRATIONS=autoで作成済みのデータベースにもベースラインの記録後に適用できるよう、作成済みの場合は何もしない
+CREATE TABLE IF NOT EXISTS webhooks (
+	id bigserial,
+	cid bigint NOT NULL,
+	url varchar(2048) NOT NULL,
+	secret varchar(128) NOT NULL,
+	events varchar(255) NOT NULL,
+	enabled boolean NOT NULL DEFAULT true,
+	consecutive_failures bigint NOT NULL DEFAULT 0,
+	disabled_at timestamptz,
+	created_at timestamptz NOT NULL,
+	updated_at timestamptz NOT NULL,
+	PRIMARY KEY (id),
+	CONSTRAINT fk_webhooks_class FOREIGN KEY (cid) REFERENCES classes(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_webhooks_cid ON webhooks (cid);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+	id bigserial,
+	webhook_id bigint NOT NULL,
+	event varchar(30) NOT NULL,
+	delivery_id varchar(32) NOT NULL,
+	attempt bigint NOT NULL,
+	status_code bigint NOT NULL DEFAULT 0,
+	error varchar(500),
+	duration_ms bigint NOT NULL,
+	payload text NOT NULL,
+	created_at timestamptz NOT NULL,
+	PRIMARY KEY (id),
+	CONSTRAINT fk_webhook_deliveries_webhook FOREIGN KEY (webhook_id) REFERENCES webhooks(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook_id_id ON webhook_deliveries (webhook_id, id);
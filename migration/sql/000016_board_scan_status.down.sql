@@ -0,0 +1 @@
+ALTER TABLE class_boards DROP COLUMN IF EXISTS scan_status;
@@ -0,0 +1,2 @@
+-- RUN_MIGRATIONS=autoで追加済みの列がある場合は何もしない。既存の画像はスキャンしていないため空にする
+ALTER TABLE class_boards ADD COLUMN IF NOT EXISTS scan_status varchar(8) NOT NULL DEFAULT '';
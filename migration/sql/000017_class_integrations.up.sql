@@ -0,0 +1,19 @@
+-- RUN_MIGRATIONS=autoで作成済みのデータベースにもベースラインの記録後に適用できるよう、作成済みの場合は何もしない
+CREATE TABLE IF NOT EXISTS class_integrations (
+	id bigserial,
+	cid bigint NOT NULL,
+	provider varchar(10) NOT NULL,
+	webhook_url varchar(2048) NOT NULL,
+	events varchar(255) NOT NULL,
+	status varchar(10) NOT NULL DEFAULT 'ok',
+	last_error varchar(500),
+	consecutive_client_errors bigint NOT NULL DEFAULT 0,
+	last_posted_at timestamptz,
+	last_failed_at timestamptz,
+	disabled_at timestamptz,
+	created_at timestamptz NOT NULL,
+	updated_at timestamptz NOT NULL,
+	PRIMARY KEY (id),
+	CONSTRAINT fk_class_integrations_class FOREIGN KEY (cid) REFERENCES classes(id) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_class_integrations_cid ON class_integrations (cid);
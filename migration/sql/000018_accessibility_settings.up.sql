@@ -0,0 +1,10 @@
+-- RUN_MIGRATIONS=autoで作成済みのデータベースにもベースラインの記録後に適用できるよう、作成済みの場合は何もしない
+CREATE TABLE IF NOT EXISTS accessibility_settings (
+	uid bigint NOT NULL,
+	font_size varchar(10) NOT NULL DEFAULT 'medium',
+	high_contrast boolean NOT NULL DEFAULT false,
+	reduce_motion boolean NOT NULL DEFAULT false,
+	updated_at timestamptz NOT NULL,
+	PRIMARY KEY (uid),
+	CONSTRAINT fk_accessibility_settings_user FOREIGN KEY (uid) REFERENCES users(id) ON DELETE CASCADE
+);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS calendar_event_links;
+DROP TABLE IF EXISTS calendar_sync_classes;
+DROP TABLE IF EXISTS google_calendar_syncs;
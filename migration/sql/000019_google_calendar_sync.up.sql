@@ -0,0 +1,31 @@
+-- RUN_MIGRATIONS=autoで作成済みのデータベースにもベースラインの記録後に適用できるよう、作成済みの場合は何もしない
+CREATE TABLE IF NOT EXISTS google_calendar_syncs (
+	uid bigint NOT NULL,
+	enabled boolean NOT NULL DEFAULT false,
+	access_token varchar(2048) NOT NULL DEFAULT '',
+	refresh_token varchar(512) NOT NULL DEFAULT '',
+	token_expiry timestamptz,
+	disabled_reason varchar(500) NOT NULL DEFAULT '',
+	disabled_at timestamptz,
+	created_at timestamptz NOT NULL,
+	updated_at timestamptz NOT NULL,
+	PRIMARY KEY (uid),
+	CONSTRAINT fk_google_calendar_syncs_user FOREIGN KEY (uid) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE TABLE IF NOT EXISTS calendar_sync_classes (
+	uid bigint NOT NULL,
+	cid bigint NOT NULL,
+	created_at timestamptz NOT NULL,
+	PRIMARY KEY (uid, cid),
+	CONSTRAINT fk_calendar_sync_classes_user FOREIGN KEY (uid) REFERENCES users(id) ON DELETE CASCADE,
+	CONSTRAINT fk_calendar_sync_classes_class FOREIGN KEY (cid) REFERENCES classes(id) ON DELETE CASCADE
+);
+CREATE TABLE IF NOT EXISTS calendar_event_links (
+	uid bigint NOT NULL,
+	csid bigint NOT NULL,
+	event_id varchar(1024) NOT NULL,
+	updated_at timestamptz NOT NULL,
+	PRIMARY KEY (uid, csid),
+	CONSTRAINT fk_calendar_event_links_user FOREIGN KEY (uid) REFERENCES users(id) ON DELETE CASCADE,
+	CONSTRAINT fk_calendar_event_links_class_schedule FOREIGN KEY (csid) REFERENCES class_schedules(id) ON DELETE CASCADE
+);
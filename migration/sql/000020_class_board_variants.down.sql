@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS class_board_variants;
+ALTER TABLE class_boards DROP COLUMN IF EXISTS language;
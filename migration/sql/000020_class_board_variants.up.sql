@@ -0,0 +1,14 @@
+-- RUN_MIGRATIONS=autoで作成済みのデータベースにもベースラインの記録後に適用できるよう、作成済みの場合は何もしない。既存の掲示板の言語は空にする
+ALTER TABLE class_boards ADD COLUMN IF NOT EXISTS language varchar(10) NOT NULL DEFAULT '';
+CREATE TABLE IF NOT EXISTS class_board_variants (
+	id bigserial,
+	bid bigint NOT NULL,
+	language varchar(10) NOT NULL,
+	title varchar(255) NOT NULL,
+	content text NOT NULL,
+	created_at timestamptz NOT NULL,
+	updated_at timestamptz NOT NULL,
+	PRIMARY KEY (id),
+	CONSTRAINT fk_class_boards_variants FOREIGN KEY (bid) REFERENCES class_boards(id) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_class_board_variants_bid_language ON class_board_variants (bid, language);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS digest_settings;
+ALTER TABLE class_schedules DROP COLUMN IF EXISTS updated_at;
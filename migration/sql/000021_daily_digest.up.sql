@@ -0,0 +1,12 @@
+-- RUN_MIGRATIONS=autoで作成済みのデータベースにもベースラインの記録後に適用できるよう、作成済みの場合は何もしない。
+-- 既存のスケジュールの更新日時は記録していないためNULLにし、ダイジェストの変更には含めない
+ALTER TABLE class_schedules ADD COLUMN IF NOT EXISTS updated_at timestamptz;
+CREATE TABLE IF NOT EXISTS digest_settings (
+	uid bigint NOT NULL,
+	enabled boolean NOT NULL DEFAULT false,
+	email_enabled boolean NOT NULL DEFAULT false,
+	updated_at timestamptz NOT NULL,
+	PRIMARY KEY (uid),
+	CONSTRAINT fk_digest_settings_user FOREIGN KEY (uid) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_digest_settings_enabled ON digest_settings (enabled);
@@ -0,0 +1,3 @@
+-- RUN_MIGRATIONS=autoで作成済みのデータベースにもベースラインの記録後に適用できるよう、作成済みの場合は何もしない。
+-- 既存のスケジュールの作成日時は記録していないためNULLにし、クラスのタイムラインには含めない
+ALTER TABLE class_schedules ADD COLUMN IF NOT EXISTS created_at timestamptz;
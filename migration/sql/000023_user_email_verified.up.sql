@@ -0,0 +1,3 @@
+-- RUN_MIGRATIONS=autoで作成済みのデータベースにもベースラインの記録後に適用できるよう、作成済みの場合は何もしない。
+-- 既存のユーザーのメールアドレスは検証していないため未検証にし、検証メールで確認するまでメールの通知を送信しない
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified boolean NOT NULL DEFAULT false;
@@ -0,0 +1,2 @@
+-- スケジュールなどの対象ごとに通知の既読状況を集計するためのインデックス
+CREATE INDEX IF NOT EXISTS idx_notifications_resource ON notifications (resource_type, resource_id);
@@ -0,0 +1,2 @@
+-- 削除したLINEのアクセストークンは復元できないため何もしない
+SELECT 1;
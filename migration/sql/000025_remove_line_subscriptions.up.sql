@@ -0,0 +1,2 @@
+-- LINE Notifyの提供終了に伴いLINEの通知チャネルを廃止したため、登録済みの購読を削除する
+DELETE FROM announcement_subscriptions WHERE channel = 'LINE';
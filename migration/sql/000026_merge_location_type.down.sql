@@ -0,0 +1,3 @@
+-- 場所の種類は出席方式から復元する
+ALTER TABLE class_schedules ADD COLUMN IF NOT EXISTS location_type varchar(10) NOT NULL DEFAULT '';
+UPDATE class_schedules SET location_type = LOWER(attendance_mode);
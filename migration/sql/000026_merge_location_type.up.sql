@@ -0,0 +1,5 @@
+-- 場所の種類は出席方式と同じ区分のため出席方式にまとめる。
+-- 出席方式が既定の対面のまま場所の種類だけを設定したスケジュールは、場所の種類を出席方式として引き継ぐ
+UPDATE class_schedules SET attendance_mode = UPPER(location_type)
+WHERE location_type IN ('online', 'hybrid') AND attendance_mode = 'IN_PERSON';
+ALTER TABLE class_schedules DROP COLUMN IF EXISTS location_type;
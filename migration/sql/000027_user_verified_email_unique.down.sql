@@ -0,0 +1,2 @@
+-- 未検証に戻したアドレスは復元できないため、インデックスのみ削除する
+DROP INDEX IF EXISTS idx_users_verified_email;
@@ -0,0 +1,7 @@
+-- 検証済みのメールアドレスは1人のユーザーのみが持てるようにする。
+-- 既に複数のユーザーが同じアドレスを検証済みの場合は最初に登録したユーザー以外を未検証に戻し、検証し直すまでメールの通知を送信しない
+UPDATE users SET email_verified = false
+WHERE email_verified = true AND id NOT IN (
+    SELECT MIN(id) FROM users WHERE email_verified = true GROUP BY LOWER(email)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_verified_email ON users (LOWER(email)) WHERE email_verified = true;
@@ -0,0 +1,298 @@
+package migration
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"gorm.io/gorm"
+)
+
+// sqlFiles バージョン付きのSQLマイグレーション。golang-migrateと同じ {バージョン}_{名前}.up.sql / .down.sql の形式で置く
+//
+//go:embed sql/*.sql
+var sqlFiles embed.FS
+
+// schemaMigrationsSQL 適用済みのバージョンを記録するテーブル。golang-migrateのpostgresドライバと同じ形式にする
+const schemaMigrationsSQL = `CREATE TABLE IF NOT EXISTS schema_migrations (version bigint NOT NULL PRIMARY KEY, dirty boolean NOT NULL)`
+
+// migrationLockID マイグレーションを同時に実行しないためのアドバイザリロックのID
+const migrationLockID = 72647001
+
+// baselineVersion AutoMigrateで作成していたスキーマに相当するバージョン
+const baselineVersion = 1
+
+var sqlFileName = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+// ErrNoBaseline schema_migrationsが無いまま既存のテーブルにマイグレーションを適用しようとした
+var ErrNoBaseline = errors.New("tables exist but no migration version is recorded (run `migrate baseline` to adopt the current schema)")
+
+// DirtyError 途中で失敗したマイグレーションがある。スキーマを確認して手動で直し、`migrate force` でバージョンを記録し直す
+type DirtyError struct {
+	Version uint
+}
+
+func (e *DirtyError) Error() string {
+	return fmt.Sprintf("migration %d is dirty: fix the schema by hand and run `migrate force <version>`", e.Version)
+}
+
+// sqlMigration 1つのバージョンのマイグレーション
+type sqlMigration struct {
+	Version uint
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Runner SQLのマイグレーションを適用する。sourceのsqlディレクトリにあるSQLファイルを使う
+type Runner struct {
+	source fs.FS
+}
+
+// NewRunner sourceのsqlディレクトリにあるマイグレーションを適用するRunnerを生成する
+func NewRunner(source fs.FS) *Runner {
+	return &Runner{source: source}
+}
+
+// defaultRunner 埋め込んだSQLファイルを適用するRunner
+var defaultRunner = NewRunner(sqlFiles)
+
+// loadMigrations SQLファイルをバージョン順に読み込む
+func (r *Runner) loadMigrations() ([]sqlMigration, error) {
+	entries, err := fs.ReadDir(r.source, "sql")
+	if err != nil {
+		return nil, err
+	}
+	byVersion := map[uint]*sqlMigration{}
+	for _, entry := range entries {
+		match := sqlFileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("unexpected migration file name: %s", entry.Name())
+		}
+		version, err := strconv.ParseUint(match[1], 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version: %s", entry.Name())
+		}
+		body, err := fs.ReadFile(r.source, "sql/"+entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[uint(version)]
+		if !ok {
+			m = &sqlMigration{Version: uint(version), Name: match[2]}
+			byVersion[uint(version)] = m
+		}
+		if match[3] == "up" {
+			m.Up = string(body)
+		} else {
+			m.Down = string(body)
+		}
+	}
+
+	migrations := make([]sqlMigration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s must have both up and down files", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// LatestVersion 埋め込んだマイグレーションの最新のバージョンを返す
+func LatestVersion() (uint, error) {
+	return defaultRunner.LatestVersion()
+}
+
+// LatestVersion マイグレーションの最新のバージョンを返す
+func (r *Runner) LatestVersion() (uint, error) {
+	migrations, err := r.loadMigrations()
+	if err != nil || len(migrations) == 0 {
+		return 0, err
+	}
+	return migrations[len(migrations)-1].Version, nil
+}
+
+// Version 適用済みのバージョンと、途中で失敗したかを返す。未適用の場合は0を返す
+func Version(db *gorm.DB) (uint, bool, error) {
+	if !db.Migrator().HasTable("schema_migrations") {
+		return 0, false, nil
+	}
+	var row struct {
+		Version uint
+		Dirty   bool
+	}
+	result := db.Raw("SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&row)
+	if result.Error != nil {
+		return 0, false, result.Error
+	}
+	return row.Version, row.Dirty, nil
+}
+
+// CheckVersion 途中で失敗したマイグレーションが無いかを確認する。ある場合はDirtyErrorを返す
+func CheckVersion(db *gorm.DB) error {
+	version, dirty, err := Version(db)
+	if err != nil {
+		return err
+	}
+	if dirty {
+		return &DirtyError{Version: version}
+	}
+	return nil
+}
+
+// MigrateUp 埋め込んだマイグレーションのうち未適用のものを順に適用する
+func MigrateUp(db *gorm.DB) error {
+	return defaultRunner.Up(db)
+}
+
+// Up 未適用のマイグレーションを順に適用する
+func (r *Runner) Up(db *gorm.DB) error {
+	return withLock(db, func(conn *gorm.DB) error {
+		migrations, err := r.loadMigrations()
+		if err != nil {
+			return err
+		}
+		current, err := currentVersion(conn)
+		if err != nil {
+			return err
+		}
+		if current == 0 && conn.Migrator().HasTable("users") {
+			return ErrNoBaseline
+		}
+		for _, m := range migrations {
+			if m.Version <= current {
+				continue
+			}
+			if err := apply(conn, m.Version, m.Up, m.Version); err != nil {
+				return fmt.Errorf("migration %d_%s failed: %w", m.Version, m.Name, err)
+			}
+		}
+		return nil
+	})
+}
+
+// MigrateDown 埋め込んだマイグレーションのうち適用済みのものを新しい順にsteps件取り消す
+func MigrateDown(db *gorm.DB, steps int) error {
+	return defaultRunner.Down(db, steps)
+}
+
+// Down 適用済みのマイグレーションを新しい順にsteps件取り消す
+func (r *Runner) Down(db *gorm.DB, steps int) error {
+	return withLock(db, func(conn *gorm.DB) error {
+		migrations, err := r.loadMigrations()
+		if err != nil {
+			return err
+		}
+		current, err := currentVersion(conn)
+		if err != nil {
+			return err
+		}
+		for i := len(migrations) - 1; i >= 0 && steps > 0; i-- {
+			m := migrations[i]
+			if m.Version > current {
+				continue
+			}
+			var previous uint
+			if i > 0 {
+				previous = migrations[i-1].Version
+			}
+			if err := apply(conn, m.Version, m.Down, previous); err != nil {
+				return fmt.Errorf("rollback of %d_%s failed: %w", m.Version, m.Name, err)
+			}
+			steps--
+		}
+		return nil
+	})
+}
+
+// Baseline AutoMigrateで作成した既存のデータベースに、ベースラインを適用済みとして記録する。SQLは実行しない
+func Baseline(db *gorm.DB) error {
+	return withLock(db, func(conn *gorm.DB) error {
+		current, err := currentVersion(conn)
+		if err != nil {
+			return err
+		}
+		if current != 0 {
+			return fmt.Errorf("migration version %d is already recorded", current)
+		}
+		if !conn.Migrator().HasTable("users") {
+			return errors.New("there is no existing schema to adopt (run `migrate up` instead)")
+		}
+		return setVersion(conn, baselineVersion, false)
+	})
+}
+
+// Force 途中で失敗したマイグレーションを手動で直した後に、バージョンを記録し直す。SQLは実行しない
+func Force(db *gorm.DB, version uint) error {
+	return withLock(db, func(conn *gorm.DB) error {
+		if err := conn.Exec(schemaMigrationsSQL).Error; err != nil {
+			return err
+		}
+		return setVersion(conn, version, false)
+	})
+}
+
+// withLock 1つの接続でアドバイザリロックを取得してから処理する。複数のインスタンスが同時に起動しても1つずつ適用する。
+// アドバイザリロックはPostgreSQLの場合のみ取得し、テスト用のSQLiteではロックせずに処理する。
+// db.Connectionの接続はPrepareStmtのキャッシュを通らないため、複数の文を含むSQLも実行できる
+func withLock(db *gorm.DB, fn func(conn *gorm.DB) error) error {
+	return db.Connection(func(conn *gorm.DB) error {
+		if !isPostgres(conn) {
+			return fn(conn)
+		}
+		if err := conn.Exec("SELECT pg_advisory_lock(?)", migrationLockID).Error; err != nil {
+			return fmt.Errorf("failed to acquire migration lock: %w", err)
+		}
+		defer conn.Exec("SELECT pg_advisory_unlock(?)", migrationLockID)
+		return fn(conn)
+	})
+}
+
+// currentVersion 適用済みのバージョンを返す。途中で失敗したマイグレーションがある場合はDirtyErrorを返す
+func currentVersion(conn *gorm.DB) (uint, error) {
+	if err := conn.Exec(schemaMigrationsSQL).Error; err != nil {
+		return 0, err
+	}
+	version, dirty, err := Version(conn)
+	if err != nil {
+		return 0, err
+	}
+	if dirty {
+		return 0, &DirtyError{Version: version}
+	}
+	return version, nil
+}
+
+// apply SQLを1つのトランザクションで実行し、成功したらtoVersionを記録する。
+// 実行前にdirtyを記録するため、失敗した場合や途中でプロセスが終了した場合はdirtyのまま残る
+func apply(conn *gorm.DB, version uint, script string, toVersion uint) error {
+	if err := setVersion(conn, version, true); err != nil {
+		return err
+	}
+	if err := conn.Transaction(func(tx *gorm.DB) error {
+		return tx.Exec(script).Error
+	}); err != nil {
+		return err
+	}
+	return setVersion(conn, toVersion, false)
+}
+
+// setVersion schema_migrationsの1行をバージョンで置き換える。バージョン0は行を削除する
+func setVersion(conn *gorm.DB, version uint, dirty bool) error {
+	return conn.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM schema_migrations").Error; err != nil {
+			return err
+		}
+		if version == 0 && !dirty {
+			return nil
+		}
+		return tx.Exec("INSERT INTO schema_migrations (version, dirty) VALUES (?, ?)", version, dirty).Error
+	})
+}
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// NotificationChannel お知らせを配信する外部の通知チャネル
+type NotificationChannel string
+
+const (
+	EmailChannel NotificationChannel = "EMAIL" // メール
+)
+
+// AnnouncementSubscription ユーザーがクラスのお知らせを受け取る外部の通知チャネル。ユーザー・クラス・チャネルごとに1件
+type AnnouncementSubscription struct {
+	ID      uint                `gorm:"primaryKey"`
+	UID     uint                `gorm:"column:uid;not null;uniqueIndex:idx_announcement_subscriptions_target"`
+	CID     uint                `gorm:"column:cid;not null;uniqueIndex:idx_announcement_subscriptions_target;index"`
+	Channel NotificationChannel `gorm:"type:varchar(10);not null;uniqueIndex:idx_announcement_subscriptions_target"`
+	// Target 登録した時点のユーザーの検証済みのメールアドレス。配信にはユーザーの現在の検証済みのアドレスを使う
+	Target string `gorm:"size:255;not null"`
+	// FailureCount 連続して配信に失敗した回数
+	FailureCount int `gorm:"not null;default:0"`
+	// DisabledAt 配信に失敗し続けたため配信を停止した日時。再登録すると解除される
+	DisabledAt *time.Time
+	CreatedAt  time.Time `gorm:"not null;"`
+	UpdatedAt  time.Time `gorm:"not null;"`
+	Class      Class     `gorm:"foreignKey:CID;constraint:OnDelete:CASCADE"`
+	User       User      `gorm:"foreignKey:UID;constraint:OnDelete:CASCADE"`
+}
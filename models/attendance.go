@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 type AttendanceType string
 
 const (
@@ -8,12 +10,27 @@ const (
 	AbsenceStatus    AttendanceType = "ABSENCE"
 )
 
+// AttendanceSource 出席を記録した経路
+type AttendanceSource string
+
+const (
+	SelfSource    AttendanceSource = "SELF"    // 生徒本人によるチェックイン
+	TeacherSource AttendanceSource = "TEACHER" // 講師による記録
+	AutoSource    AttendanceSource = "AUTO"    // システムによる自動記録
+)
+
 type Attendance struct {
-	ID            uint           `gorm:"primaryKey;size:255;autoIncrement;"`
-	CID           uint           `gorm:"column:cid;not null"`                                                    // Class ID
-	UID           uint           `gorm:"column:uid;not null"`                                                    // User ID
-	CSID          uint           `gorm:"column:csid;not null"`                                                   // Class Schedule ID
-	IsAttendance  AttendanceType `gorm:"type:enum('ATTENDANCE', 'TARDY', 'ABSENCE');default:'ABSENCE';not null"` // 出席, 遅刻, 欠席
-	ClassUser     ClassUser      `gorm:"foreignKey:CID,UID"`
-	ClassSchedule ClassSchedule  `gorm:"foreignKey:CSID"`
+	ID               uint             `gorm:"primaryKey;size:255;autoIncrement;"`
+	CID              uint             `gorm:"column:cid;not null;index"`                                 // Class ID
+	UID              uint             `gorm:"column:uid;not null;uniqueIndex:idx_attendances_uid_csid"`  // User ID
+	CSID             uint             `gorm:"column:csid;not null;uniqueIndex:idx_attendances_uid_csid"` // Class Schedule ID
+	IsAttendance     AttendanceType   `gorm:"type:varchar(10);default:'ABSENCE';not null"`               // 出席, 遅刻, 欠席
+	Note             *string          `gorm:"type:text"`                                                 // 講師コメント
+	IsNoteVisible    bool             `gorm:"not null;default:false"`                                    // 生徒本人にコメントを公開するか
+	RecordedAt       time.Time        `gorm:"not null;default:CURRENT_TIMESTAMP"`                        // サーバーで記録した時刻
+	ClientRecordedAt *time.Time       `gorm:"default:null"`                                              // クライアントから送られた時刻。参考値として保存する
+	Source           AttendanceSource `gorm:"type:varchar(10);not null;default:'TEACHER'"`               // 記録元
+	Version          uint             `gorm:"not null;default:1"`                                        // 楽観ロックのバージョン。更新のたびに1増える
+	ClassUser        ClassUser        `gorm:"foreignKey:CID,UID"`
+	ClassSchedule    ClassSchedule    `gorm:"foreignKey:CSID"`
 }
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// AuditLog 変更系APIの操作履歴
+type AuditLog struct {
+	ID           uint      `gorm:"primaryKey"`
+	ActorUID     uint      `gorm:"column:actor_uid;not null;index"` // 操作したユーザーID
+	Method       string    `gorm:"size:10;not null"`
+	Route        string    `gorm:"size:255;not null"` // パラメータを含まないルートのパス
+	ResourceType string    `gorm:"size:50"`
+	ResourceID   string    `gorm:"size:100"`
+	CID          *uint     `gorm:"column:cid;index"` // 特定できた場合のクラスID
+	StatusCode   int       `gorm:"not null"`
+	RequestID    string    `gorm:"size:64"`
+	Body         string    `gorm:"type:text"` // 機密項目をマスクし、切り詰めたリクエストボディ
+	CreatedAt    time.Time `gorm:"not null;index"`
+}
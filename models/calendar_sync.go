@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+// GoogleCalendarSync ユーザーのGoogleカレンダーへのスケジュールの同期の設定と、同期に使うGoogleのOAuthのトークン。
+// Googleのログインでカレンダーの権限を許可したユーザーのみ作成する
+type GoogleCalendarSync struct {
+	UID uint `gorm:"column:uid;primaryKey"`
+	// Enabled スケジュールを同期する。トークンの更新に失敗した場合はfalseにする
+	Enabled      bool       `gorm:"not null;default:false"`
+	AccessToken  string     `gorm:"size:2048;not null;default:''"`
+	RefreshToken string     `gorm:"size:512;not null;default:''"`
+	TokenExpiry  *time.Time `gorm:"column:token_expiry"`
+	// DisabledReason トークンの更新に失敗して同期を停止した理由。停止していない場合は空
+	DisabledReason string     `gorm:"size:500;not null;default:''"`
+	DisabledAt     *time.Time `gorm:"column:disabled_at"`
+	CreatedAt      time.Time  `gorm:"not null;"`
+	UpdatedAt      time.Time  `gorm:"not null;"`
+	User           User       `gorm:"foreignKey:UID;constraint:OnDelete:CASCADE"`
+}
+
+// Connected トークンを更新して同期を続けるためのリフレッシュトークンを保存しているか
+func (s GoogleCalendarSync) Connected() bool {
+	return s.RefreshToken != ""
+}
+
+// CalendarSyncClass 管理者が同期するクラスを限定する場合の、同期するクラス。ユーザーに1件もない場合は参加している全てのクラスを同期する
+type CalendarSyncClass struct {
+	UID       uint      `gorm:"column:uid;primaryKey"`
+	CID       uint      `gorm:"column:cid;primaryKey"`
+	CreatedAt time.Time `gorm:"not null;"`
+	User      User      `gorm:"foreignKey:UID;constraint:OnDelete:CASCADE"`
+	Class     Class     `gorm:"foreignKey:CID;constraint:OnDelete:CASCADE"`
+}
+
+// CalendarEventLink スケジュールと、ユーザーのGoogleカレンダーに作成した予定の対応。スケジュールの変更と休講は同じ予定に反映する
+type CalendarEventLink struct {
+	UID           uint          `gorm:"column:uid;primaryKey"`
+	CSID          uint          `gorm:"column:csid;primaryKey"`
+	EventID       string        `gorm:"size:1024;not null"`
+	UpdatedAt     time.Time     `gorm:"not null;"`
+	User          User          `gorm:"foreignKey:UID;constraint:OnDelete:CASCADE"`
+	ClassSchedule ClassSchedule `gorm:"foreignKey:CSID;constraint:OnDelete:CASCADE"`
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// ChatSticker チャットで送信できるクラス独自のスタンプ
+type ChatSticker struct {
+	ID        uint      `gorm:"primaryKey"`
+	Name      string    `gorm:"size:50;not null"`
+	ImageURL  string    `gorm:"size:255;not null"`
+	CreatedAt time.Time `gorm:"not null;"`
+	CID       uint      `gorm:"column:cid;not null;index"`
+	UID       uint      `gorm:"column:uid;not null"` // 登録したユーザーのID
+	Class     Class     `gorm:"foreignKey:CID;constraint:OnDelete:CASCADE"`
+	User      User      `gorm:"foreignKey:UID"`
+}
@@ -1,10 +1,27 @@
 package models
 
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
 type Class struct {
-	ID          uint    `gorm:"primaryKey"`
-	Name        string  `gorm:"size:30;not null"`
-	Limitation  *int    `gorm:"not null;default:30"`
-	Description *string `gorm:"size:255"`
-	Image       *string `gorm:"size:255"`
-	UID         uint    `gorm:"not null"`
+	ID                  uint       `gorm:"primaryKey"`
+	Name                string     `gorm:"size:30;not null"`
+	Limitation          *int       `gorm:"not null;default:30"`
+	Description         *string    `gorm:"size:255"`
+	Image               *string    `gorm:"size:255"`
+	UID                 uint       `gorm:"not null"`
+	IsArchived          bool       `gorm:"not null;default:false"`
+	ArchivedAt          *time.Time // アーカイブされた日時
+	ArchiveExempt       bool       `gorm:"not null;default:false"` // 自動アーカイブの対象から除外する
+	ArchiveNoticeSentAt *time.Time // 自動アーカイブの事前通知を送った日時
+	IsPublic            bool       `gorm:"not null;default:false"` // 公開クラスの検索に表示する
+	Language            *string    `gorm:"size:10"`                // 授業の言語 (例: ja, en)
+	AccessStartTime     *string    `gorm:"size:5"`                 // アクセスを許可する時間帯の開始 (HH:MM)。nilの場合は時間帯を制限しない
+	AccessEndTime       *string    `gorm:"size:5"`                 // アクセスを許可する時間帯の終了 (HH:MM)。開始より前の場合は日をまたぐ
+	AllowedIPRanges     *string    `gorm:"type:text"`              // アクセスを許可するIPレンジ (CIDR) のカンマ区切り。nilの場合はIPを制限しない
+	// DeletedAt 削除された日時。クラスのスケジュールと掲示板も同じ日時で削除し、復元する
+	DeletedAt gorm.DeletedAt `gorm:"index"`
 }
@@ -1,17 +1,54 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// AttachmentScanStatus 添付ファイルのウイルススキャンの状態
+type AttachmentScanStatus string
+
+const (
+	ScanPending  AttachmentScanStatus = "pending"  // スキャン中。ダウンロード前に利用者に警告する
+	ScanClean    AttachmentScanStatus = "clean"    // ウイルスは検出されなかった
+	ScanInfected AttachmentScanStatus = "infected" // ウイルスが検出されたため、添付画像を削除した
+)
 
 type ClassBoard struct {
-	ID          uint      `gorm:"primaryKey"`
-	Title       string    `gorm:"size:255;not null"`
-	Content     string    `gorm:"type:text;not null"`
-	Image       string    `gorm:"size:255"`
-	CreatedAt   time.Time `gorm:"not null;"`
-	UpdatedAt   time.Time `gorm:"not null;"`
-	IsAnnounced bool      `gorm:"not null;default:false"`
-	CID         uint      `gorm:"column:cid;not null;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
-	UID         uint      `gorm:"column:uid;not null"` // User ID
-	Class       Class     `gorm:"foreignKey:CID;constraint:OnDelete:CASCADE"`
-	User        User      `gorm:"foreignKey:UID"`
+	ID          uint       `gorm:"primaryKey"`
+	Title       string     `gorm:"size:255;not null"`
+	Content     string     `gorm:"type:text;not null"`
+	Image       string     `gorm:"size:255"`
+	CreatedAt   time.Time  `gorm:"not null;index:idx_class_boards_cid_created_at,priority:2"`
+	UpdatedAt   time.Time  `gorm:"not null;"`
+	IsAnnounced bool       `gorm:"not null;default:false"`
+	ViewCount   uint       `gorm:"not null;default:0"`
+	IsPinned    bool       `gorm:"not null;default:false"`
+	PinnedUntil *time.Time // ピン留めを自動的に解除する日時。nilの場合は期限なし
+	CID         uint       `gorm:"column:cid;not null;index:idx_class_boards_cid_created_at,priority:1;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
+	UID         uint       `gorm:"column:uid;not null"` // User ID
+	Class       Class      `gorm:"foreignKey:CID;constraint:OnDelete:CASCADE"`
+	User        User       `gorm:"foreignKey:UID"`
+	// Version 楽観ロックのバージョン。更新のたびに1増える
+	Version uint `gorm:"not null;default:1"`
+	// DeletedAt 削除された日時
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+	// ScanStatus 添付画像のウイルススキャンの状態。画像がない場合とスキャンを導入する前の画像は空
+	ScanStatus AttachmentScanStatus `gorm:"column:scan_status;type:varchar(8);not null;default:''"`
+	// Language タイトルと本文の言語 (例: ja, en)。利用者の言語の版がない場合はこの版を表示する。空の場合は言語を指定していない
+	Language string `gorm:"size:10;not null;default:''"`
+	// Variants 講師が用意した他の言語の版。作成時のみ保存し、一覧などでは読み込まない
+	Variants []ClassBoardVariant `gorm:"foreignKey:BID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// ClassBoardVariant グループ掲示板の他の言語の版
+type ClassBoardVariant struct {
+	ID        uint      `gorm:"primaryKey"`
+	BID       uint      `gorm:"column:bid;not null;uniqueIndex:idx_class_board_variants_bid_language,priority:1"`
+	Language  string    `gorm:"size:10;not null;uniqueIndex:idx_class_board_variants_bid_language,priority:2"`
+	Title     string    `gorm:"size:255;not null"`
+	Content   string    `gorm:"type:text;not null"`
+	CreatedAt time.Time `gorm:"not null"`
+	UpdatedAt time.Time `gorm:"not null"`
 }
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// ClassBoardComment グループ掲示板へのコメント。ParentCommentIDがある場合はそのコメントへのリプライで、リプライへのリプライはできない
+type ClassBoardComment struct {
+	ID  uint `gorm:"primaryKey"`
+	BID uint `gorm:"column:bid;not null;index:idx_class_board_comments_bid_parent"` // Class Board ID
+	UID uint `gorm:"column:uid;not null"`                                           // User ID
+	// ParentCommentID リプライ先のコメントID。掲示板へのコメントの場合はnil
+	ParentCommentID *uint              `gorm:"column:parent_comment_id;index:idx_class_board_comments_bid_parent"`
+	Content         string             `gorm:"type:text;not null"`
+	CreatedAt       time.Time          `gorm:"not null;"`
+	UpdatedAt       time.Time          `gorm:"not null;"`
+	ClassBoard      ClassBoard         `gorm:"foreignKey:BID;constraint:OnDelete:CASCADE"`
+	User            User               `gorm:"foreignKey:UID"`
+	ParentComment   *ClassBoardComment `gorm:"foreignKey:ParentCommentID;constraint:OnDelete:CASCADE"`
+}
+
+// ClassBoardCommentLike コメントへのいいね。1人のユーザーは1つのコメントに1回だけいいねできる
+type ClassBoardCommentLike struct {
+	CommentID uint              `gorm:"column:comment_id;primaryKey"`
+	UID       uint              `gorm:"column:uid;primaryKey"`
+	CreatedAt time.Time         `gorm:"not null;"`
+	Comment   ClassBoardComment `gorm:"foreignKey:CommentID;constraint:OnDelete:CASCADE"`
+	User      User              `gorm:"foreignKey:UID"`
+}
@@ -0,0 +1,79 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// IntegrationProvider クラスのイベントを投稿するチャットサービス
+type IntegrationProvider string
+
+const (
+	SlackIntegration   IntegrationProvider = "SLACK"   // SlackのIncoming Webhook
+	DiscordIntegration IntegrationProvider = "DISCORD" // DiscordのWebhook
+)
+
+// IntegrationEvent チャットサービスに投稿するイベントの種類
+type IntegrationEvent string
+
+const (
+	BoardAnnouncedIntegration  IntegrationEvent = "board.announced"  // お知らせの掲示
+	ScheduleChangedIntegration IntegrationEvent = "schedule.changed" // スケジュールの作成・変更・削除・休講
+	LiveStartedIntegration     IntegrationEvent = "live.started"     // ライブ授業の開始
+)
+
+// IntegrationStatus チャットサービスへの投稿の状態
+type IntegrationStatus string
+
+const (
+	IntegrationOK       IntegrationStatus = "ok"       // 最後の投稿に成功した
+	IntegrationFailing  IntegrationStatus = "failing"  // 最後の投稿に失敗した
+	IntegrationDisabled IntegrationStatus = "disabled" // 投稿先が拒否し続けたため投稿を停止した
+)
+
+// ClassIntegration クラスのイベントをSlackまたはDiscordに投稿する連携の設定。クラスの管理者がクラスごとに1件登録する。
+// 投稿先が4xxを連続して返した回数が上限に達すると投稿を停止し、再登録すると再開する
+type ClassIntegration struct {
+	ID         uint                `gorm:"primaryKey"`
+	CID        uint                `gorm:"column:cid;not null;uniqueIndex"`
+	Provider   IntegrationProvider `gorm:"type:varchar(10);not null"`
+	WebhookURL string              `gorm:"size:2048;not null"`
+	// Events 投稿するイベントの種類をカンマ区切りで保存する
+	Events    string            `gorm:"size:255;not null"`
+	Status    IntegrationStatus `gorm:"type:varchar(10);not null;default:'ok'"`
+	LastError string            `gorm:"size:500"`
+	// ConsecutiveClientErrors 投稿先が4xxを返した連続の回数。投稿に成功すると0に戻す
+	ConsecutiveClientErrors int        `gorm:"not null;default:0"`
+	LastPostedAt            *time.Time // 最後に投稿に成功した日時
+	LastFailedAt            *time.Time // 最後に投稿に失敗した日時
+	DisabledAt              *time.Time // 投稿を停止した日時
+	CreatedAt               time.Time  `gorm:"not null;"`
+	UpdatedAt               time.Time  `gorm:"not null;"`
+	Class                   Class      `gorm:"foreignKey:CID;constraint:OnDelete:CASCADE"`
+}
+
+// EventList 投稿するイベントの種類を返す
+func (i ClassIntegration) EventList() []IntegrationEvent {
+	if i.Events == "" {
+		return nil
+	}
+	names := strings.Split(i.Events, ",")
+	events := make([]IntegrationEvent, 0, len(names))
+	for _, name := range names {
+		events = append(events, IntegrationEvent(name))
+	}
+	return events
+}
+
+// Subscribes イベントの種類を投稿するかどうか。投稿を停止している場合は投稿しない
+func (i ClassIntegration) Subscribes(event IntegrationEvent) bool {
+	if i.Status == IntegrationDisabled {
+		return false
+	}
+	for _, e := range i.EventList() {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
@@ -1,13 +1,77 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// AttendanceMode スケジュールの出席方式
+type AttendanceMode string
+
+const (
+	InPersonMode AttendanceMode = "IN_PERSON" // 対面
+	OnlineMode   AttendanceMode = "ONLINE"    // オンライン
+	HybridMode   AttendanceMode = "HYBRID"    // ハイブリッド
+)
+
+// AttendanceModes 全ての出席方式
+var AttendanceModes = []AttendanceMode{InPersonMode, OnlineMode, HybridMode}
+
+// Valid 出席方式が定義済みの値かどうか
+func (m AttendanceMode) Valid() bool {
+	for _, mode := range AttendanceModes {
+		if m == mode {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckInMethod 生徒が出席をチェックインする手段
+type CheckInMethod string
+
+const (
+	QRCheckIn       CheckInMethod = "QR"       // 教室に掲示したQRコードの読み取り
+	CodeCheckIn     CheckInMethod = "CODE"     // 教室で講師が伝えた確認コードによる自己チェックイン
+	LocationCheckIn CheckInMethod = "LOCATION" // 位置情報による教室内の確認
+	LiveCheckIn     CheckInMethod = "LIVE"     // ライブ授業への参加
+)
+
+// CheckInMethods 出席方式で有効なチェックイン手段を返す
+func (m AttendanceMode) CheckInMethods() []CheckInMethod {
+	switch m {
+	case OnlineMode:
+		return []CheckInMethod{LiveCheckIn}
+	case HybridMode:
+		return []CheckInMethod{QRCheckIn, CodeCheckIn, LocationCheckIn, LiveCheckIn}
+	default:
+		return []CheckInMethod{QRCheckIn, CodeCheckIn, LocationCheckIn}
+	}
+}
 
 type ClassSchedule struct {
 	ID        uint      `gorm:"primaryKey"`
 	Title     string    `gorm:"size:255;not null"`
-	StartedAt time.Time `gorm:"not null"`
+	StartedAt time.Time `gorm:"not null;index:idx_class_schedules_cid_started_at,priority:2"`
 	EndedAt   time.Time `gorm:"not null"`
-	CID       uint      `gorm:"column:cid;not null;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
+	CID       uint      `gorm:"column:cid;not null;index:idx_class_schedules_cid_started_at,priority:1;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
 	IsLive    bool      `gorm:"not null;default:false"`
-	Class     Class     `gorm:"foreignKey:CID;constraint:OnDelete:CASCADE"`
+	// IsCancelled 作成後に休講となったスケジュール
+	IsCancelled bool `gorm:"not null;default:false"`
+	// AttendanceMode 出席方式。授業を行う場所の種類を兼ね、方式によって有効なチェックイン手段が変わる
+	AttendanceMode AttendanceMode `gorm:"type:varchar(10);not null;default:'IN_PERSON'"`
+	// Location 教室の名前やオンライン授業のURL
+	Location string `gorm:"size:255;not null;default:''"`
+	Class    Class  `gorm:"foreignKey:CID;constraint:OnDelete:CASCADE"`
+	// RequireCheckInCode 生徒の自己チェックインに、講師が口頭で伝える確認コードの入力を必須にする
+	RequireCheckInCode bool `gorm:"not null;default:false"`
+	// Version 楽観ロックのバージョン。更新のたびに1増える
+	Version uint `gorm:"not null;default:1"`
+	// CreatedAt 作成した日時。クラスのタイムラインに表示する。記録する前に作成したスケジュールはnil
+	CreatedAt *time.Time
+	// UpdatedAt 作成・変更した日時。ダイジェストでスケジュールの変更を集計する。記録する前に作成したスケジュールはnil
+	UpdatedAt *time.Time
+	// DeletedAt 削除された日時
+	DeletedAt gorm.DeletedAt `gorm:"index"`
 }
@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// ClassSemester クラスの学期。出席の成績はこの期間に開始したスケジュールで集計する
+type ClassSemester struct {
+	ID  uint `gorm:"primaryKey"`
+	CID uint `gorm:"column:cid;not null;uniqueIndex:idx_class_semesters_name"`
+	// Name 学期の名前 (例: 2026-spring)。クラス内で一意
+	Name string `gorm:"size:50;not null;uniqueIndex:idx_class_semesters_name"`
+	// StartsAt 学期の開始日時。EndsAtは学期の最終日の終わり
+	StartsAt  time.Time `gorm:"not null"`
+	EndsAt    time.Time `gorm:"not null"`
+	CreatedAt time.Time `gorm:"not null;"`
+	UpdatedAt time.Time `gorm:"not null;"`
+	Class     Class     `gorm:"foreignKey:CID;constraint:OnDelete:CASCADE"`
+}
+
+// ClassGradeThreshold 出席率を成績に変換する基準。出席率がMinRate以上の基準のうち、最も高いものの成績になる
+type ClassGradeThreshold struct {
+	ID    uint   `gorm:"primaryKey"`
+	CID   uint   `gorm:"column:cid;not null;uniqueIndex:idx_class_grade_thresholds_grade"`
+	Grade string `gorm:"size:5;not null;uniqueIndex:idx_class_grade_thresholds_grade"`
+	// MinRate 成績になる出席率(%)の下限
+	MinRate float64 `gorm:"not null"`
+	// GradePoint GPAの計算に使う成績の点数
+	GradePoint float64 `gorm:"not null;default:0"`
+	Class      Class   `gorm:"foreignKey:CID;constraint:OnDelete:CASCADE"`
+}
@@ -1,11 +1,21 @@
 package models
 
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
 type ClassUser struct {
-	CID        uint   `gorm:"column:cid;primaryKey"`
-	UID        uint   `gorm:"column:uid;primaryKey"`
-	Nickname   string `gorm:"size:50;not null"`
-	IsFavorite bool   `gorm:"not null;default:false"`
-	Role       string `gorm:"type:Role;not null"`
-	Class      Class  `gorm:"foreignKey:CID;constraint:OnDelete:CASCADE"`
-	User       User   `gorm:"foreignKey:UID"`
+	CID        uint           `gorm:"column:cid;primaryKey;index:idx_class_users_cid_role"`
+	UID        uint           `gorm:"column:uid;primaryKey;index"`
+	Nickname   string         `gorm:"size:50;not null"`
+	IsFavorite bool           `gorm:"not null;default:false"`
+	Role       string         `gorm:"type:Role;not null;index:idx_class_users_cid_role"`
+	CodeID     *uint          `gorm:"column:code_id"` // 参加時に使用したクラスコードID
+	JoinedAt   time.Time      `gorm:"autoCreateTime;not null;default:CURRENT_TIMESTAMP"`
+	DeletedAt  gorm.DeletedAt `gorm:"index"` // クラスから削除された日時
+	Class      Class          `gorm:"foreignKey:CID;constraint:OnDelete:CASCADE"`
+	User       User           `gorm:"foreignKey:UID"`
+	ClassCode  *ClassCode     `gorm:"foreignKey:CodeID;constraint:OnDelete:SET NULL"`
 }
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// CurriculumItem コース型クラスのカリキュラムの項目。生徒は項目ごとに完了を記録する
+type CurriculumItem struct {
+	ID        uint      `gorm:"primaryKey"`
+	CID       uint      `gorm:"column:cid;not null;index"`
+	Title     string    `gorm:"size:100;not null"`
+	Order     int       `gorm:"column:sort_order;not null;default:0"` // 表示順。小さいほど先に表示する
+	CreatedAt time.Time `gorm:"not null;"`
+	UpdatedAt time.Time `gorm:"not null;"`
+	Class     Class     `gorm:"foreignKey:CID;constraint:OnDelete:CASCADE"`
+}
+
+// UserProgress 生徒がカリキュラムの項目を完了した記録。完了を取り消した場合は削除する
+type UserProgress struct {
+	ID          uint           `gorm:"primaryKey"`
+	UID         uint           `gorm:"column:uid;not null;uniqueIndex:idx_user_progresses_item_uid"`
+	ItemID      uint           `gorm:"column:item_id;not null;uniqueIndex:idx_user_progresses_item_uid"`
+	CompletedAt time.Time      `gorm:"not null"`
+	Item        CurriculumItem `gorm:"foreignKey:ItemID;constraint:OnDelete:CASCADE"`
+	User        User           `gorm:"foreignKey:UID"`
+}
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// DevicePlatform プッシュ通知を受け取る端末の種類
+type DevicePlatform string
+
+const (
+	IOSPlatform     DevicePlatform = "ios"
+	AndroidPlatform DevicePlatform = "android"
+	WebPlatform     DevicePlatform = "web"
+)
+
+// DeviceToken プッシュ通知を送信する端末のFCMの登録トークン。同じトークンは1人のユーザーにのみ登録する
+type DeviceToken struct {
+	ID       uint           `gorm:"primaryKey"`
+	UID      uint           `gorm:"column:uid;not null;index"`
+	Platform DevicePlatform `gorm:"type:varchar(10);not null"`
+	Token    string         `gorm:"size:512;not null;uniqueIndex"`
+	// LastSeenAt アプリが最後にトークンを登録した日時。アプリは起動するたびに登録する
+	LastSeenAt time.Time `gorm:"not null"`
+	CreatedAt  time.Time `gorm:"not null;"`
+	User       User      `gorm:"foreignKey:UID;constraint:OnDelete:CASCADE"`
+}
@@ -0,0 +1,55 @@
+package models
+
+import "time"
+
+// NotificationType アプリ内通知の種類
+type NotificationType string
+
+const (
+	MentionNotification             NotificationType = "MENTION"              // チャットや掲示板でのメンション
+	ApplicationApprovedNotification NotificationType = "APPLICATION_APPROVED" // クラスへの参加申請の承認
+	ScheduleChangedNotification     NotificationType = "SCHEDULE_CHANGED"     // スケジュールの変更・休講
+	InvitationNotification          NotificationType = "INVITATION"           // クラスへの招待
+	ClassNotification               NotificationType = "CLASS"                // その他のクラスに関するお知らせ
+	DigestNotification              NotificationType = "DIGEST"               // 前日のクラスの活動をまとめた毎朝のダイジェスト
+)
+
+// NotificationTypes 全てのアプリ内通知の種類
+var NotificationTypes = []NotificationType{
+	MentionNotification, ApplicationApprovedNotification, ScheduleChangedNotification, InvitationNotification, ClassNotification,
+	DigestNotification,
+}
+
+// Notification ユーザーへのアプリ内通知。既読にした日時を記録し、既読の通知は保存期間を過ぎると削除する
+type Notification struct {
+	ID           uint             `gorm:"primaryKey;index:idx_notifications_uid_id,priority:2"`
+	UID          uint             `gorm:"column:uid;not null;index:idx_notifications_uid_id,priority:1"`
+	Type         NotificationType `gorm:"type:varchar(30);not null"`
+	Title        string           `gorm:"size:255;not null"`
+	Body         string           `gorm:"type:text"`
+	ResourceType string           `gorm:"size:50;index:idx_notifications_resource,priority:1"` // 通知の対象の種類 (例: class_schedule)
+	ResourceID   *uint            `gorm:"index:idx_notifications_resource,priority:2"`         // 通知の対象のID
+	CID          *uint            `gorm:"column:cid"`                                          // 通知に関係するクラスのID
+	ReadAt       *time.Time       `gorm:"index"`
+	CreatedAt    time.Time        `gorm:"not null;"`
+	User         User             `gorm:"foreignKey:UID;constraint:OnDelete:CASCADE"`
+}
+
+// NotificationPreference ユーザーの通知の種類ごとの設定。設定していない種類はプッシュ通知を送信する
+type NotificationPreference struct {
+	UID         uint             `gorm:"column:uid;primaryKey"`
+	Type        NotificationType `gorm:"type:varchar(30);primaryKey"`
+	PushEnabled bool             `gorm:"not null"`
+	UpdatedAt   time.Time        `gorm:"not null;"`
+	User        User             `gorm:"foreignKey:UID;constraint:OnDelete:CASCADE"`
+}
+
+// DigestSetting ユーザーの毎朝のダイジェストの設定。設定していないユーザーにはダイジェストを送信しない
+type DigestSetting struct {
+	UID     uint `gorm:"column:uid;primaryKey"`
+	Enabled bool `gorm:"not null;default:false;index"`
+	// EmailEnabled アプリ内通知に加えてメールでも受け取る
+	EmailEnabled bool      `gorm:"not null;default:false"`
+	UpdatedAt    time.Time `gorm:"not null;"`
+	User         User      `gorm:"foreignKey:UID;constraint:OnDelete:CASCADE"`
+}
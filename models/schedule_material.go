@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// MaterialPhase スケジュールの資料を配布する時期
+type MaterialPhase string
+
+const (
+	PreClassPhase  MaterialPhase = "PRE"  // 授業前に配布する予習資料
+	PostClassPhase MaterialPhase = "POST" // 授業後に実施する振り返りアンケート
+)
+
+// ScheduleMaterial スケジュールに紐付けた資料。授業後の資料は振り返りアンケートとして生徒が回答する
+type ScheduleMaterial struct {
+	ID      uint          `gorm:"primaryKey"`
+	CSID    uint          `gorm:"column:csid;not null;index"` // Class Schedule ID
+	UID     uint          `gorm:"column:uid;not null"`        // 作成したユーザーのID
+	Phase   MaterialPhase `gorm:"type:varchar(4);not null"`
+	Title   string        `gorm:"size:100;not null"`
+	Content *string       `gorm:"type:text"`
+	URL     *string       `gorm:"size:2048"`
+	// SurveyNotifiedAt 授業後のアンケートを生徒に通知した日時。授業前の資料と未通知のアンケートはnil
+	SurveyNotifiedAt *time.Time
+	CreatedAt        time.Time     `gorm:"not null;"`
+	UpdatedAt        time.Time     `gorm:"not null;"`
+	ClassSchedule    ClassSchedule `gorm:"foreignKey:CSID;constraint:OnDelete:CASCADE"`
+	User             User          `gorm:"foreignKey:UID"`
+}
+
+// ScheduleSurveyResponse 振り返りアンケートへの生徒の回答。1人の生徒は1つのアンケートに1回だけ回答し、再回答すると上書きする
+type ScheduleSurveyResponse struct {
+	ID         uint `gorm:"primaryKey"`
+	MaterialID uint `gorm:"column:material_id;not null;uniqueIndex:idx_schedule_survey_responses_material_uid"`
+	UID        uint `gorm:"column:uid;not null;uniqueIndex:idx_schedule_survey_responses_material_uid"`
+	// Understanding 授業の理解度 (1から5)
+	Understanding int              `gorm:"not null"`
+	Reflection    *string          `gorm:"type:text"` // 振り返りの自由記述
+	CreatedAt     time.Time        `gorm:"not null;"`
+	UpdatedAt     time.Time        `gorm:"not null;"`
+	Material      ScheduleMaterial `gorm:"foreignKey:MaterialID;constraint:OnDelete:CASCADE"`
+	User          User             `gorm:"foreignKey:UID"`
+}
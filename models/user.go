@@ -5,9 +5,42 @@ import (
 )
 
 type User struct {
-	ID        uint      `gorm:"primaryKey"`
-	Name      string    `gorm:"size:50;not null"`
-	Image     string    `gorm:"size:255;not null;"`
-	PID       string    `gorm:"size:255;not null"`
+	ID    uint   `gorm:"primaryKey"`
+	Name  string `gorm:"size:50;not null"`
+	Image string `gorm:"size:255;not null;"`
+	PID   string `gorm:"size:255;not null"`
+	// Email メールアドレス。検証済みのアドレスは大文字と小文字を区別せずに1人のユーザーのみが持てる
+	Email     string    `gorm:"size:255;not null;default:'';uniqueIndex:idx_users_verified_email,expression:lower(email),where:email_verified = true"`
 	CreatedAt time.Time `gorm:"not null;"`
+	// EmailVerified メールアドレスを検証メールで確認済み。未検証のアドレスにはメールの通知を送信しない
+	EmailVerified bool `gorm:"not null;default:false"`
+	// MaxActiveClasses 同時に参加できるアクティブなクラス数の上限。nilの場合は全体の設定を使い、0は上限なし
+	MaxActiveClasses *int `gorm:"column:max_active_classes"`
+	// Locale Googleアカウントの言語 (例: ja, en-GB)。メールのテンプレートの選択に使い、空の場合は日本語にする
+	Locale string `gorm:"size:35;not null;default:''"`
+}
+
+// FontSize 画面の文字の大きさ
+type FontSize string
+
+const (
+	FontSizeSmall      FontSize = "small"
+	FontSizeMedium     FontSize = "medium"
+	FontSizeLarge      FontSize = "large"
+	FontSizeExtraLarge FontSize = "x-large"
+)
+
+// AccessibilitySetting ユーザーのアクセシビリティの設定。フロントエンドが画面の表示の調整に使う
+type AccessibilitySetting struct {
+	UID          uint      `gorm:"column:uid;primaryKey"`
+	FontSize     FontSize  `gorm:"type:varchar(10);not null;default:'medium'"`
+	HighContrast bool      `gorm:"not null;default:false"`
+	ReduceMotion bool      `gorm:"not null;default:false"`
+	UpdatedAt    time.Time `gorm:"not null;"`
+	User         User      `gorm:"foreignKey:UID;constraint:OnDelete:CASCADE"`
+}
+
+// DefaultAccessibilitySetting 設定を保存していないユーザーのアクセシビリティの設定。標準の文字の大きさで、ハイコントラストとモーション低減は無効
+func DefaultAccessibilitySetting(uid uint) AccessibilitySetting {
+	return AccessibilitySetting{UID: uid, FontSize: FontSizeMedium}
 }
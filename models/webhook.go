@@ -0,0 +1,79 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// WebhookEvent Webhookで外部のシステムに配信するイベントの種類
+type WebhookEvent string
+
+const (
+	BoardCreatedWebhook      WebhookEvent = "board.created"      // 掲示板の作成
+	ScheduleUpdatedWebhook   WebhookEvent = "schedule.updated"   // スケジュールの作成・変更・削除・休講
+	AttendanceChangedWebhook WebhookEvent = "attendance.changed" // 出席の記録・変更・削除
+	MemberJoinedWebhook      WebhookEvent = "member.joined"      // クラスへのメンバーの参加
+)
+
+// WebhookEvents 全てのWebhookのイベントの種類
+var WebhookEvents = []WebhookEvent{
+	BoardCreatedWebhook, ScheduleUpdatedWebhook, AttendanceChangedWebhook, MemberJoinedWebhook,
+}
+
+// Webhook クラスのイベントを外部のシステムに配信する送信先。クラスの管理者が登録する。
+// 連続して配信に失敗した回数が上限に達すると無効にする
+type Webhook struct {
+	ID     uint   `gorm:"primaryKey"`
+	CID    uint   `gorm:"column:cid;not null;index"`
+	URL    string `gorm:"size:2048;not null"`
+	Secret string `gorm:"size:128;not null"` // 署名に使う共有の秘密鍵
+	// Events 配信するイベントの種類をカンマ区切りで保存する
+	Events  string `gorm:"size:255;not null"`
+	Enabled bool   `gorm:"not null;default:true"`
+	// ConsecutiveFailures 再送しても配信できなかったイベントの連続の件数。配信に成功すると0に戻す
+	ConsecutiveFailures int        `gorm:"not null;default:0"`
+	DisabledAt          *time.Time // 連続の失敗で自動的に無効にした日時
+	CreatedAt           time.Time  `gorm:"not null;"`
+	UpdatedAt           time.Time  `gorm:"not null;"`
+	Class               Class      `gorm:"foreignKey:CID;constraint:OnDelete:CASCADE"`
+}
+
+// EventList 配信するイベントの種類を返す
+func (w Webhook) EventList() []WebhookEvent {
+	if w.Events == "" {
+		return nil
+	}
+	names := strings.Split(w.Events, ",")
+	events := make([]WebhookEvent, 0, len(names))
+	for _, name := range names {
+		events = append(events, WebhookEvent(name))
+	}
+	return events
+}
+
+// Subscribes イベントの種類を配信するかどうか
+func (w Webhook) Subscribes(event WebhookEvent) bool {
+	for _, e := range w.EventList() {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDelivery Webhookの1回の送信の記録。再送もそれぞれ記録し、送信先ごとに新しいものから一定の件数のみ残す
+type WebhookDelivery struct {
+	ID        uint         `gorm:"primaryKey;index:idx_webhook_deliveries_webhook_id_id,priority:2"`
+	WebhookID uint         `gorm:"column:webhook_id;not null;index:idx_webhook_deliveries_webhook_id_id,priority:1"`
+	Event     WebhookEvent `gorm:"type:varchar(30);not null"`
+	// DeliveryID 配信するイベントのID。再送でも同じIDを送る
+	DeliveryID string `gorm:"size:32;not null"`
+	Attempt    int    `gorm:"not null"`
+	// StatusCode 送信先が返したHTTPのステータスコード。応答がなかった場合は0
+	StatusCode int       `gorm:"not null;default:0"`
+	Error      string    `gorm:"size:500"`
+	DurationMS int64     `gorm:"column:duration_ms;not null"`
+	Payload    string    `gorm:"type:text;not null"`
+	CreatedAt  time.Time `gorm:"not null;"`
+	Webhook    Webhook   `gorm:"foreignKey:WebhookID;constraint:OnDelete:CASCADE"`
+}
@@ -0,0 +1,75 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// AnnouncementSubscriptionRepository お知らせの外部通知の購読のリポジトリ
+type AnnouncementSubscriptionRepository interface {
+	Upsert(ctx context.Context, subscription *models.AnnouncementSubscription) error
+	Delete(ctx context.Context, uid uint, cid uint, channel models.NotificationChannel) (int64, error)
+	FindByUserAndClass(ctx context.Context, uid uint, cid uint) ([]models.AnnouncementSubscription, error)
+	FindActiveByClass(ctx context.Context, cid uint) ([]models.AnnouncementSubscription, error)
+	UpdateDeliveryState(ctx context.Context, id uint, failureCount int, disabledAt *time.Time) error
+}
+
+// announcementSubscriptionRepository AnnouncementSubscriptionRepositoryを実装
+type announcementSubscriptionRepository struct {
+	db *gorm.DB
+}
+
+// NewAnnouncementSubscriptionRepository AnnouncementSubscriptionRepositoryを生成
+func NewAnnouncementSubscriptionRepository(db *gorm.DB) AnnouncementSubscriptionRepository {
+	return &announcementSubscriptionRepository{db: db}
+}
+
+// Upsert 購読を登録する。同じチャネルを登録済みの場合は送信先を置き換え、失敗回数と停止を解除する
+func (r *announcementSubscriptionRepository) Upsert(ctx context.Context, subscription *models.AnnouncementSubscription) error {
+	subscription.FailureCount = 0
+	subscription.DisabledAt = nil
+	return r.db.WithContext(ctx).Omit(clause.Associations).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "uid"}, {Name: "cid"}, {Name: "channel"}},
+		DoUpdates: clause.AssignmentColumns([]string{"target", "failure_count", "disabled_at", "updated_at"}),
+	}).Create(subscription).Error
+}
+
+// Delete 購読を解除し、削除した件数を返す
+func (r *announcementSubscriptionRepository) Delete(ctx context.Context, uid uint, cid uint, channel models.NotificationChannel) (int64, error) {
+	result := r.db.WithContext(ctx).Where("uid = ? AND cid = ? AND channel = ?", uid, cid, channel).Delete(&models.AnnouncementSubscription{})
+	return result.RowsAffected, result.Error
+}
+
+// FindByUserAndClass ユーザーがクラスに登録した購読を取得
+func (r *announcementSubscriptionRepository) FindByUserAndClass(ctx context.Context, uid uint, cid uint) ([]models.AnnouncementSubscription, error) {
+	var subscriptions []models.AnnouncementSubscription
+	err := r.db.WithContext(ctx).Where("uid = ? AND cid = ?", uid, cid).Order("channel ASC").Find(&subscriptions).Error
+	return subscriptions, err
+}
+
+// FindActiveByClass クラスの配信を停止していない購読を取得する。クラスの管理者・アシスタント・生徒ではなくなったユーザーと、
+// メールアドレスが検証済みでないユーザーの購読は含まない。Targetにはユーザーの現在のメールアドレスを入れる
+func (r *announcementSubscriptionRepository) FindActiveByClass(ctx context.Context, cid uint) ([]models.AnnouncementSubscription, error) {
+	var subscriptions []models.AnnouncementSubscription
+	err := r.db.WithContext(ctx).Model(&models.AnnouncementSubscription{}).
+		Select("announcement_subscriptions.id, announcement_subscriptions.uid, announcement_subscriptions.cid, announcement_subscriptions.channel, "+
+			"users.email AS target, announcement_subscriptions.failure_count, announcement_subscriptions.disabled_at, "+
+			"announcement_subscriptions.created_at, announcement_subscriptions.updated_at").
+		Joins("JOIN class_users ON class_users.uid = announcement_subscriptions.uid AND class_users.cid = announcement_subscriptions.cid AND class_users.deleted_at IS NULL").
+		Joins("JOIN users ON users.id = announcement_subscriptions.uid").
+		Where("announcement_subscriptions.cid = ? AND announcement_subscriptions.disabled_at IS NULL", cid).
+		Where("class_users.role IN ?", []string{"ADMIN", "ASSISTANT", "USER"}).
+		Where("users.email_verified = ? AND users.email <> ''", true).
+		Find(&subscriptions).Error
+	return subscriptions, err
+}
+
+// UpdateDeliveryState 配信の失敗回数と停止日時を更新
+func (r *announcementSubscriptionRepository) UpdateDeliveryState(ctx context.Context, id uint, failureCount int, disabledAt *time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.AnnouncementSubscription{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"failure_count": failureCount, "disabled_at": disabledAt}).Error
+}
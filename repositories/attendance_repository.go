@@ -1,18 +1,45 @@
 package repositories
 
 import (
+	"context"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // AttendanceRepository インタフェース
 type AttendanceRepository interface {
-	CreateAttendance(attendance *models.Attendance) error
-	GetAttendanceByUIDAndCID(uid uint, cid uint) (*models.Attendance, error)
-	GetAllAttendancesByCID(cid uint) ([]models.Attendance, error)
-	GetAttendanceByID(id string) ([]models.Attendance, error)
-	UpdateAttendance(attendance *models.Attendance) error
-	DeleteAttendance(id string) error
+	CreateAttendance(ctx context.Context, attendance *models.Attendance) error
+	CreateAttendanceIfAbsent(ctx context.Context, attendance *models.Attendance) (bool, error)
+	GetAttendanceByUIDAndCSID(ctx context.Context, uid uint, csid uint) (*models.Attendance, error)
+	GetAllAttendancesByCID(ctx context.Context, cid uint, filter dto.AttendanceListFilter, limit int, offset int) ([]models.Attendance, int64, error)
+	GetAttendanceByID(ctx context.Context, id string) ([]models.Attendance, error)
+	UpdateAttendance(ctx context.Context, attendance *models.Attendance, expectedVersion uint) error
+	FindAttendance(ctx context.Context, id string) (*models.Attendance, error)
+	DeleteAttendance(ctx context.Context, id string) error
+	GetAttendanceRateForPeriod(ctx context.Context, cid, uid uint, from, to time.Time) (float64, error)
+	DeleteAllBySchedule(ctx context.Context, csid uint) (int64, error)
+	GetAttendanceTimeline(ctx context.Context, cid, uid uint, until time.Time) ([]AttendanceTimelineEntry, error)
+	GetAttendanceTimelineBetween(ctx context.Context, cid, uid uint, from, until time.Time) ([]AttendanceTimelineEntry, error)
+	CountByAttendanceMode(ctx context.Context, cid uint) ([]AttendanceModeCount, error)
+}
+
+// AttendanceTimelineEntry スケジュールごとのユーザーの出席状況。出席が記録されていない場合、Statusはnil
+type AttendanceTimelineEntry struct {
+	CSID      uint `gorm:"column:csid"`
+	StartedAt time.Time
+	EndedAt   time.Time
+	Status    *models.AttendanceType
+}
+
+// AttendanceModeCount スケジュールの出席方式と出席状況ごとの出席の件数
+type AttendanceModeCount struct {
+	Mode   models.AttendanceMode
+	Status models.AttendanceType
+	Count  int64
 }
 
 // attendanceConnection グループ掲示板リポジトリ
@@ -26,40 +53,131 @@ func NewAttendanceRepository(db *gorm.DB) AttendanceRepository {
 }
 
 // CreateAttendance 出席情報を作成
-func (repo *attendanceRepository) CreateAttendance(attendance *models.Attendance) error {
-	return repo.db.Create(attendance).Error
+func (repo *attendanceRepository) CreateAttendance(ctx context.Context, attendance *models.Attendance) error {
+	return repo.db.WithContext(ctx).Create(attendance).Error
+}
+
+// CreateAttendanceIfAbsent ユーザーのスケジュールの出席情報がない場合のみ作成し、作成したかどうかを返す。
+// 既にある場合は変更しないため、同じチェックインを再送しても重複して登録しない
+func (repo *attendanceRepository) CreateAttendanceIfAbsent(ctx context.Context, attendance *models.Attendance) (bool, error) {
+	result := repo.db.WithContext(ctx).Omit(clause.Associations).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "uid"}, {Name: "csid"}},
+		DoNothing: true,
+	}).Create(attendance)
+	return result.RowsAffected > 0, result.Error
 }
 
-// GetAttendanceByUIDAndCID UIDとCIDによって出席情報を取得
-func (repo *attendanceRepository) GetAttendanceByUIDAndCID(uid uint, cid uint) (*models.Attendance, error) {
+// GetAttendanceByUIDAndCSID UIDとスケジュールのIDによって出席情報を取得。idx_attendances_uid_csidで1件に決まる
+func (repo *attendanceRepository) GetAttendanceByUIDAndCSID(ctx context.Context, uid uint, csid uint) (*models.Attendance, error) {
 	var attendance models.Attendance
-	err := repo.db.Where("uid = ? AND cid = ?", uid, cid).First(&attendance).Error
+	err := repo.db.WithContext(ctx).Where("uid = ? AND csid = ?", uid, csid).First(&attendance).Error
 	return &attendance, err
 }
 
-// GetAllAttendancesByCID CIDによって全ての出席情報を取得
-func (repo *attendanceRepository) GetAllAttendancesByCID(cid uint) ([]models.Attendance, error) {
+// GetAllAttendancesByCID CIDによって絞り込み条件に一致する出席情報を記録した順にlimit件取得し、条件に一致する総数と合わせて返す。limitが負の場合はすべて取得する
+func (repo *attendanceRepository) GetAllAttendancesByCID(ctx context.Context, cid uint, filter dto.AttendanceListFilter, limit int, offset int) ([]models.Attendance, int64, error) {
+	scope := func(db *gorm.DB) *gorm.DB {
+		db = db.Where("cid = ?", cid)
+		if filter.Status != "" {
+			db = db.Where("is_attendance = ?", filter.Status)
+		}
+		if filter.CSID != 0 {
+			db = db.Where("csid = ?", filter.CSID)
+		}
+		return db
+	}
+	var total int64
+	if err := repo.db.WithContext(ctx).Model(&models.Attendance{}).Scopes(scope).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
 	var attendances []models.Attendance
-	err := repo.db.Where("cid = ?", cid).Find(&attendances).Error
+	err := repo.db.WithContext(ctx).Scopes(scope).Order("id").Limit(limit).Offset(offset).Find(&attendances).Error
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
-	return attendances, err
+	return attendances, total, nil
 }
 
 // GetAttendanceByID IDによって出席情報を取得
-func (repo *attendanceRepository) GetAttendanceByID(id string) ([]models.Attendance, error) {
+func (repo *attendanceRepository) GetAttendanceByID(ctx context.Context, id string) ([]models.Attendance, error) {
 	var attendances []models.Attendance
-	err := repo.db.Preload("ClassUser").Preload("ClassUser.User").Preload("ClassUser.Class").Where("csid = ?", id).Find(&attendances).Error
+	err := repo.db.WithContext(ctx).Preload("ClassUser", unscopedPreload).Preload("ClassUser.User").Preload("ClassUser.Class").Where("csid = ?", id).Find(&attendances).Error
 	return attendances, err
 }
 
-// UpdateAttendance 出席情報を更新
-func (repo *attendanceRepository) UpdateAttendance(attendance *models.Attendance) error {
-	return repo.db.Save(attendance).Error
+// UpdateAttendance 出席情報を更新。expectedVersionが0以外で他の更新が保存されていた場合はErrStaleUpdateを返す
+func (repo *attendanceRepository) UpdateAttendance(ctx context.Context, attendance *models.Attendance, expectedVersion uint) error {
+	return saveWithVersion(repo.db.WithContext(ctx), attendance, attendance.ID, &attendance.Version, expectedVersion)
+}
+
+// FindAttendance 主キーで出席情報を取得
+func (repo *attendanceRepository) FindAttendance(ctx context.Context, id string) (*models.Attendance, error) {
+	var attendance models.Attendance
+	if err := repo.db.WithContext(ctx).First(&attendance, id).Error; err != nil {
+		return nil, err
+	}
+	return &attendance, nil
 }
 
 // DeleteAttendance 出席情報を削除
-func (repo *attendanceRepository) DeleteAttendance(id string) error {
-	return repo.db.Delete(&models.Attendance{}, id).Error
+func (repo *attendanceRepository) DeleteAttendance(ctx context.Context, id string) error {
+	return repo.db.WithContext(ctx).Delete(&models.Attendance{}, id).Error
+}
+
+// GetAttendanceRateForPeriod 期間内の出席率をSQLで集計して取得
+func (repo *attendanceRepository) GetAttendanceRateForPeriod(ctx context.Context, cid, uid uint, from, to time.Time) (float64, error) {
+	var rate float64
+	err := readOnly(repo.db.WithContext(ctx)).Table("attendances").
+		Select("COALESCE(COUNT(CASE WHEN attendances.is_attendance = ? THEN 1 END) * 1.0 / NULLIF(COUNT(*), 0), 0)", models.AttendanceStatus).
+		Joins("JOIN class_schedules ON class_schedules.id = attendances.csid AND class_schedules.deleted_at IS NULL").
+		Where("attendances.cid = ? AND attendances.uid = ? AND class_schedules.started_at BETWEEN ? AND ?", cid, uid, from, to).
+		Scan(&rate).Error
+	return rate, err
+}
+
+// unscopedPreload 削除済みメンバーの出席履歴も表示できるように論理削除を無視して読み込む
+func unscopedPreload(db *gorm.DB) *gorm.DB {
+	return db.Unscoped()
+}
+
+// DeleteAllBySchedule スケジュールの全ての出席情報を削除し、削除した件数を返す
+func (repo *attendanceRepository) DeleteAllBySchedule(ctx context.Context, csid uint) (int64, error) {
+	result := repo.db.WithContext(ctx).Where("csid = ?", csid).Delete(&models.Attendance{})
+	return result.RowsAffected, result.Error
+}
+
+// GetAttendanceTimeline untilまでに開始したクラスのスケジュールを古い順に、ユーザーの出席状況と合わせて取得する。休講のスケジュールは含めない
+func (repo *attendanceRepository) GetAttendanceTimeline(ctx context.Context, cid, uid uint, until time.Time) ([]AttendanceTimelineEntry, error) {
+	var entries []AttendanceTimelineEntry
+	err := readOnly(repo.db.WithContext(ctx)).Table("class_schedules").
+		Select("class_schedules.id AS csid, class_schedules.started_at, class_schedules.ended_at, attendances.is_attendance AS status").
+		Joins("LEFT JOIN attendances ON attendances.csid = class_schedules.id AND attendances.uid = ?", uid).
+		Where("class_schedules.cid = ? AND class_schedules.started_at <= ? AND class_schedules.is_cancelled = ? AND class_schedules.deleted_at IS NULL", cid, until, false).
+		Order("class_schedules.started_at ASC, class_schedules.id ASC").
+		Scan(&entries).Error
+	return entries, err
+}
+
+// GetAttendanceTimelineBetween fromからuntilまでに開始したクラスのスケジュールを古い順に、ユーザーの出席状況と合わせて取得する。休講のスケジュールは含めない
+func (repo *attendanceRepository) GetAttendanceTimelineBetween(ctx context.Context, cid, uid uint, from, until time.Time) ([]AttendanceTimelineEntry, error) {
+	var entries []AttendanceTimelineEntry
+	err := readOnly(repo.db.WithContext(ctx)).Table("class_schedules").
+		Select("class_schedules.id AS csid, class_schedules.started_at, class_schedules.ended_at, attendances.is_attendance AS status").
+		Joins("LEFT JOIN attendances ON attendances.csid = class_schedules.id AND attendances.uid = ?", uid).
+		Where("class_schedules.cid = ? AND class_schedules.started_at >= ? AND class_schedules.started_at <= ? AND class_schedules.is_cancelled = ? AND class_schedules.deleted_at IS NULL", cid, from, until, false).
+		Order("class_schedules.started_at ASC, class_schedules.id ASC").
+		Scan(&entries).Error
+	return entries, err
+}
+
+// CountByAttendanceMode クラスの出席をスケジュールの出席方式と出席状況ごとに数える。休講のスケジュールは含めない
+func (repo *attendanceRepository) CountByAttendanceMode(ctx context.Context, cid uint) ([]AttendanceModeCount, error) {
+	var counts []AttendanceModeCount
+	err := readOnly(repo.db.WithContext(ctx)).Table("attendances").
+		Select("class_schedules.attendance_mode AS mode, attendances.is_attendance AS status, COUNT(*) AS count").
+		Joins("JOIN class_schedules ON class_schedules.id = attendances.csid AND class_schedules.deleted_at IS NULL").
+		Where("attendances.cid = ? AND class_schedules.is_cancelled = ?", cid, false).
+		Group("class_schedules.attendance_mode, attendances.is_attendance").
+		Scan(&counts).Error
+	return counts, err
 }
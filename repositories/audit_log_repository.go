@@ -0,0 +1,52 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"gorm.io/gorm"
+)
+
+// auditLogBatchSize 一度のINSERTで保存する監査ログの件数
+const auditLogBatchSize = 100
+
+// AuditLogRepository インタフェース
+type AuditLogRepository interface {
+	CreateAuditLogs(ctx context.Context, logs []models.AuditLog) error
+	FindAuditLogsByClass(ctx context.Context, cid uint, from, to time.Time, page, limit int) ([]models.AuditLog, error)
+	DeleteAuditLogsBefore(ctx context.Context, before time.Time) (int64, error)
+}
+
+// auditLogRepository 監査ログリポジトリ
+type auditLogRepository struct {
+	db *gorm.DB
+}
+
+// NewAuditLogRepository 監査ログリポジトリを生成
+func NewAuditLogRepository(db *gorm.DB) AuditLogRepository {
+	return &auditLogRepository{db: db}
+}
+
+// CreateAuditLogs 監査ログをまとめて保存
+func (repo *auditLogRepository) CreateAuditLogs(ctx context.Context, logs []models.AuditLog) error {
+	return repo.db.WithContext(ctx).CreateInBatches(logs, auditLogBatchSize).Error
+}
+
+// FindAuditLogsByClass クラスの監査ログを期間で絞り込み、新しい順に取得
+func (repo *auditLogRepository) FindAuditLogsByClass(ctx context.Context, cid uint, from, to time.Time, page, limit int) ([]models.AuditLog, error) {
+	var logs []models.AuditLog
+	err := repo.db.WithContext(ctx).
+		Where("cid = ? AND created_at BETWEEN ? AND ?", cid, from, to).
+		Order("created_at DESC, id DESC").
+		Offset((page - 1) * limit).
+		Limit(limit).
+		Find(&logs).Error
+	return logs, err
+}
+
+// DeleteAuditLogsBefore 指定日時より前の監査ログを削除し、削除件数を返す
+func (repo *auditLogRepository) DeleteAuditLogsBefore(ctx context.Context, before time.Time) (int64, error) {
+	result := repo.db.WithContext(ctx).Where("created_at < ?", before).Delete(&models.AuditLog{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,135 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CalendarSyncRepository Googleカレンダーへのスケジュールの同期のリポジトリ
+type CalendarSyncRepository interface {
+	FindSync(ctx context.Context, uid uint) (*models.GoogleCalendarSync, error)
+	SaveToken(ctx context.Context, sync *models.GoogleCalendarSync) error
+	UpdateAccessToken(ctx context.Context, uid uint, accessToken string, expiry time.Time) error
+	UpdateSettings(ctx context.Context, uid uint, enabled bool, cids []uint) error
+	DisableSync(ctx context.Context, uid uint, reason string, at time.Time) error
+	FindSyncClassIDs(ctx context.Context, uid uint) ([]uint, error)
+	FindSyncTargets(ctx context.Context, cid uint) ([]models.GoogleCalendarSync, error)
+	FindEventLink(ctx context.Context, uid uint, csid uint) (*models.CalendarEventLink, error)
+	SaveEventLink(ctx context.Context, link *models.CalendarEventLink) error
+	DeleteEventLink(ctx context.Context, uid uint, csid uint) error
+}
+
+// calendarSyncRepository CalendarSyncRepositoryを実装
+type calendarSyncRepository struct {
+	db *gorm.DB
+}
+
+// NewCalendarSyncRepository CalendarSyncRepositoryを生成
+func NewCalendarSyncRepository(db *gorm.DB) CalendarSyncRepository {
+	return &calendarSyncRepository{db: db}
+}
+
+// FindSync ユーザーの同期の設定を取得
+func (r *calendarSyncRepository) FindSync(ctx context.Context, uid uint) (*models.GoogleCalendarSync, error) {
+	var sync models.GoogleCalendarSync
+	if err := r.db.WithContext(ctx).Where("uid = ?", uid).First(&sync).Error; err != nil {
+		return nil, err
+	}
+	return &sync, nil
+}
+
+// SaveToken Googleのログインで取得したトークンを保存し、同期を有効にする。停止していた同期も再開する。
+// Googleは同意画面を表示しない場合にリフレッシュトークンを返さないため、リフレッシュトークンが空の場合は保存済みのものを残す
+func (r *calendarSyncRepository) SaveToken(ctx context.Context, sync *models.GoogleCalendarSync) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "uid"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"enabled":         true,
+			"access_token":    gorm.Expr("EXCLUDED.access_token"),
+			"refresh_token":   gorm.Expr("COALESCE(NULLIF(EXCLUDED.refresh_token, ''), google_calendar_syncs.refresh_token)"),
+			"token_expiry":    gorm.Expr("EXCLUDED.token_expiry"),
+			"disabled_reason": "",
+			"disabled_at":     nil,
+			"updated_at":      gorm.Expr("EXCLUDED.updated_at"),
+		}),
+	}).Create(sync).Error
+}
+
+// UpdateAccessToken 同期中に更新したアクセストークンを保存する
+func (r *calendarSyncRepository) UpdateAccessToken(ctx context.Context, uid uint, accessToken string, expiry time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.GoogleCalendarSync{}).Where("uid = ?", uid).
+		Updates(map[string]interface{}{"access_token": accessToken, "token_expiry": expiry}).Error
+}
+
+// UpdateSettings 同期を有効にするかどうかと同期するクラスを保存する。cidsが空の場合は参加している全てのクラスを同期する
+func (r *calendarSyncRepository) UpdateSettings(ctx context.Context, uid uint, enabled bool, cids []uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.GoogleCalendarSync{}).Where("uid = ?", uid).Update("enabled", enabled).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("uid = ?", uid).Delete(&models.CalendarSyncClass{}).Error; err != nil {
+			return err
+		}
+		if len(cids) == 0 {
+			return nil
+		}
+		classes := make([]models.CalendarSyncClass, 0, len(cids))
+		for _, cid := range cids {
+			classes = append(classes, models.CalendarSyncClass{UID: uid, CID: cid})
+		}
+		return tx.Omit(clause.Associations).Clauses(clause.OnConflict{DoNothing: true}).Create(&classes).Error
+	})
+}
+
+// DisableSync 同期を停止し、理由を記録する
+func (r *calendarSyncRepository) DisableSync(ctx context.Context, uid uint, reason string, at time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.GoogleCalendarSync{}).Where("uid = ?", uid).
+		Updates(map[string]interface{}{"enabled": false, "disabled_reason": reason, "disabled_at": at}).Error
+}
+
+// FindSyncClassIDs ユーザーが同期するクラスを限定している場合のクラスのIDを取得する。限定していない場合は空
+func (r *calendarSyncRepository) FindSyncClassIDs(ctx context.Context, uid uint) ([]uint, error) {
+	var cids []uint
+	err := r.db.WithContext(ctx).Model(&models.CalendarSyncClass{}).Where("uid = ?", uid).Order("cid").Pluck("cid", &cids).Error
+	return cids, err
+}
+
+// FindSyncTargets クラスのスケジュールを同期するユーザーの設定を取得する。
+// 同期が有効で、クラスのメンバーであり、同期するクラスを限定していないかこのクラスを含めているユーザーが対象
+func (r *calendarSyncRepository) FindSyncTargets(ctx context.Context, cid uint) ([]models.GoogleCalendarSync, error) {
+	var syncs []models.GoogleCalendarSync
+	err := r.db.WithContext(ctx).
+		Joins("JOIN class_users ON class_users.uid = google_calendar_syncs.uid AND class_users.cid = ? AND class_users.deleted_at IS NULL AND class_users.role IN ?",
+			cid, []string{"ADMIN", "ASSISTANT", "USER"}).
+		Where("google_calendar_syncs.enabled AND google_calendar_syncs.refresh_token <> ''").
+		Where("(NOT EXISTS (SELECT 1 FROM calendar_sync_classes WHERE calendar_sync_classes.uid = google_calendar_syncs.uid) "+
+			"OR EXISTS (SELECT 1 FROM calendar_sync_classes WHERE calendar_sync_classes.uid = google_calendar_syncs.uid AND calendar_sync_classes.cid = ?))", cid).
+		Find(&syncs).Error
+	return syncs, err
+}
+
+// FindEventLink スケジュールに対応するユーザーの予定を取得
+func (r *calendarSyncRepository) FindEventLink(ctx context.Context, uid uint, csid uint) (*models.CalendarEventLink, error) {
+	var link models.CalendarEventLink
+	if err := r.db.WithContext(ctx).Where("uid = ? AND csid = ?", uid, csid).First(&link).Error; err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+// SaveEventLink スケジュールに対応するユーザーの予定を保存する。保存済みの場合は予定のIDを置き換える
+func (r *calendarSyncRepository) SaveEventLink(ctx context.Context, link *models.CalendarEventLink) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "uid"}, {Name: "csid"}},
+		DoUpdates: clause.AssignmentColumns([]string{"event_id", "updated_at"}),
+	}).Create(link).Error
+}
+
+// DeleteEventLink スケジュールに対応するユーザーの予定の記録を削除
+func (r *calendarSyncRepository) DeleteEventLink(ctx context.Context, uid uint, csid uint) error {
+	return r.db.WithContext(ctx).Where("uid = ? AND csid = ?", uid, csid).Delete(&models.CalendarEventLink{}).Error
+}
@@ -0,0 +1,46 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"gorm.io/gorm"
+)
+
+// ChatStickerRepository チャットのスタンプのリポジトリ
+type ChatStickerRepository interface {
+	InsertChatSticker(ctx context.Context, sticker *models.ChatSticker) error
+	FindByID(ctx context.Context, id uint) (*models.ChatSticker, error)
+	FindByClass(ctx context.Context, cid uint) ([]models.ChatSticker, error)
+}
+
+// chatStickerRepository ChatStickerRepositoryを実装
+type chatStickerRepository struct {
+	db *gorm.DB
+}
+
+// NewChatStickerRepository ChatStickerRepositoryを生成
+func NewChatStickerRepository(db *gorm.DB) ChatStickerRepository {
+	return &chatStickerRepository{db: db}
+}
+
+// InsertChatSticker スタンプを登録
+func (r *chatStickerRepository) InsertChatSticker(ctx context.Context, sticker *models.ChatSticker) error {
+	return r.db.WithContext(ctx).Create(sticker).Error
+}
+
+// FindByID IDでスタンプを取得
+func (r *chatStickerRepository) FindByID(ctx context.Context, id uint) (*models.ChatSticker, error) {
+	var sticker models.ChatSticker
+	if err := r.db.WithContext(ctx).First(&sticker, id).Error; err != nil {
+		return nil, err
+	}
+	return &sticker, nil
+}
+
+// FindByClass クラスのスタンプを登録順に取得
+func (r *chatStickerRepository) FindByClass(ctx context.Context, cid uint) ([]models.ChatSticker, error) {
+	var stickers []models.ChatSticker
+	err := r.db.WithContext(ctx).Where("cid = ?", cid).Order("id").Find(&stickers).Error
+	return stickers, err
+}
@@ -0,0 +1,116 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ClassBoardCommentRow 集計した返信数といいね数、投稿者の公開プロフィールを含むコメント
+type ClassBoardCommentRow struct {
+	ID              uint
+	BID             uint `gorm:"column:bid"`
+	UID             uint `gorm:"column:uid"`
+	ParentCommentID *uint
+	Content         string
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	AuthorName      string
+	AuthorImage     string
+	ReplyCount      int64
+	LikeCount       int64
+	Liked           bool
+}
+
+// ClassBoardCommentRepository グループ掲示板のコメントといいねのリポジトリ
+type ClassBoardCommentRepository interface {
+	Create(ctx context.Context, comment *models.ClassBoardComment) error
+	FindByID(ctx context.Context, id uint) (*models.ClassBoardComment, error)
+	FindRow(ctx context.Context, id uint, viewerUID uint) (*ClassBoardCommentRow, error)
+	FindRows(ctx context.Context, bid uint, parentCommentID *uint, viewerUID uint) ([]ClassBoardCommentRow, error)
+	Delete(ctx context.Context, id uint) error
+	Like(ctx context.Context, commentID uint, uid uint) error
+	Unlike(ctx context.Context, commentID uint, uid uint) error
+}
+
+// classBoardCommentRepository ClassBoardCommentRepositoryを実装
+type classBoardCommentRepository struct {
+	db *gorm.DB
+}
+
+// NewClassBoardCommentRepository ClassBoardCommentRepositoryを生成
+func NewClassBoardCommentRepository(db *gorm.DB) ClassBoardCommentRepository {
+	return &classBoardCommentRepository{db: db}
+}
+
+// Create コメントを作成
+func (r *classBoardCommentRepository) Create(ctx context.Context, comment *models.ClassBoardComment) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Create(comment).Error
+}
+
+// FindByID IDでコメントを取得
+func (r *classBoardCommentRepository) FindByID(ctx context.Context, id uint) (*models.ClassBoardComment, error) {
+	var comment models.ClassBoardComment
+	if err := r.db.WithContext(ctx).First(&comment, id).Error; err != nil {
+		return nil, err
+	}
+	return &comment, nil
+}
+
+// FindRow IDでコメントを返信数といいね数付きで取得
+func (r *classBoardCommentRepository) FindRow(ctx context.Context, id uint, viewerUID uint) (*ClassBoardCommentRow, error) {
+	var row ClassBoardCommentRow
+	result := r.rows(ctx, viewerUID).Where("class_board_comments.id = ?", id).Limit(1).Scan(&row)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &row, nil
+}
+
+// FindRows 掲示板のコメントを古い順に返信数といいね数付きで取得する。parentCommentIDを指定した場合はそのコメントへのリプライを取得する
+func (r *classBoardCommentRepository) FindRows(ctx context.Context, bid uint, parentCommentID *uint, viewerUID uint) ([]ClassBoardCommentRow, error) {
+	query := r.rows(ctx, viewerUID).Where("class_board_comments.bid = ?", bid)
+	if parentCommentID == nil {
+		query = query.Where("class_board_comments.parent_comment_id IS NULL")
+	} else {
+		query = query.Where("class_board_comments.parent_comment_id = ?", *parentCommentID)
+	}
+
+	var rows []ClassBoardCommentRow
+	err := query.Order("class_board_comments.created_at ASC, class_board_comments.id ASC").Scan(&rows).Error
+	return rows, err
+}
+
+// rows 返信数といいね数、viewerUIDのユーザーがいいね済みかを集計するクエリ
+func (r *classBoardCommentRepository) rows(ctx context.Context, viewerUID uint) *gorm.DB {
+	return r.db.WithContext(ctx).Table("class_board_comments").
+		Select(`class_board_comments.id, class_board_comments.bid, class_board_comments.uid, class_board_comments.parent_comment_id,
+			class_board_comments.content, class_board_comments.created_at, class_board_comments.updated_at,
+			users.name AS author_name, users.image AS author_image,
+			(SELECT COUNT(*) FROM class_board_comments replies WHERE replies.parent_comment_id = class_board_comments.id) AS reply_count,
+			(SELECT COUNT(*) FROM class_board_comment_likes likes WHERE likes.comment_id = class_board_comments.id) AS like_count,
+			EXISTS (SELECT 1 FROM class_board_comment_likes likes WHERE likes.comment_id = class_board_comments.id AND likes.uid = ?) AS liked`, viewerUID).
+		Joins("LEFT JOIN users ON users.id = class_board_comments.uid")
+}
+
+// Delete コメントを削除する。リプライといいねは外部キーで削除される
+func (r *classBoardCommentRepository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&models.ClassBoardComment{}, id).Error
+}
+
+// Like コメントにいいねする。いいね済みの場合は何もしない
+func (r *classBoardCommentRepository) Like(ctx context.Context, commentID uint, uid uint) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&models.ClassBoardCommentLike{CommentID: commentID, UID: uid}).Error
+}
+
+// Unlike コメントのいいねを取り消す。いいねしていない場合は何もしない
+func (r *classBoardCommentRepository) Unlike(ctx context.Context, commentID uint, uid uint) error {
+	return r.db.WithContext(ctx).Where("comment_id = ? AND uid = ?", commentID, uid).Delete(&models.ClassBoardCommentLike{}).Error
+}
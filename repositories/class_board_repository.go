@@ -1,21 +1,35 @@
 package repositories
 
 import (
+	"context"
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
 )
 
 // ClassBoardRepository インタフェース
 type ClassBoardRepository interface {
-	InsertClassBoard(b *models.ClassBoard) (*models.ClassBoard, error)
-	FindByID(id uint) (*models.ClassBoard, error)
-	FindAllPaged(cid uint, limit int, offset int) ([]models.ClassBoard, error)
-	FindAnnounced(isAnnounced bool, cid uint) ([]models.ClassBoard, error)
-	UpdateClassBoard(b *models.ClassBoard) error
-	DeleteClassBoard(id uint) error
-	SearchByTitle(title string, cid uint) ([]models.ClassBoard, error)
+	InsertClassBoard(ctx context.Context, b *models.ClassBoard) (*models.ClassBoard, error)
+	FindByID(ctx context.Context, id uint) (*models.ClassBoard, error)
+	FindAllPaged(ctx context.Context, cid uint, limit int, offset int) ([]models.ClassBoard, error)
+	FindAnnounced(ctx context.Context, isAnnounced bool, cid uint) ([]models.ClassBoard, error)
+	UpdateClassBoard(ctx context.Context, b *models.ClassBoard, expectedVersion uint) error
+	DeleteClassBoard(ctx context.Context, id uint) error
+	SearchByTitle(ctx context.Context, title string, cid uint) ([]models.ClassBoard, error)
+	IncrementViewCount(ctx context.Context, id uint) error
+	DeleteByClassCreatedBefore(ctx context.Context, cid uint, before time.Time) ([]models.ClassBoard, error)
+	UnpinExpired(ctx context.Context, now time.Time) (int64, error)
+	UpdateScanStatus(ctx context.Context, id uint, image string, status models.AttachmentScanStatus) (bool, error)
+	FindStalePendingScans(ctx context.Context, updatedBefore time.Time, limit int) ([]models.ClassBoard, error)
+	FindVariants(ctx context.Context, bids []uint) ([]models.ClassBoardVariant, error)
+	FindUserLocale(ctx context.Context, uid uint) (string, error)
 }
 
+// pinnedFirstOrder 期限内のピン留めを先頭に、残りを新しい順に並べる。
+// 定期的な解除の前でも期限切れのピン留めが上部に残らないよう、期限もここで判定する
+const pinnedFirstOrder = "(is_pinned AND (pinned_until IS NULL OR pinned_until > now())) DESC, created_at DESC, id DESC"
+
 // classBoardConnection グループ掲示板リポジトリ
 type classBoardRepository struct {
 	db *gorm.DB
@@ -27,44 +41,112 @@ func NewClassBoardRepository(db *gorm.DB) ClassBoardRepository {
 }
 
 // InsertClassBoard グループ掲示板を作成
-func (repo *classBoardRepository) InsertClassBoard(b *models.ClassBoard) (*models.ClassBoard, error) {
-	result := repo.db.Create(b)
+func (repo *classBoardRepository) InsertClassBoard(ctx context.Context, b *models.ClassBoard) (*models.ClassBoard, error) {
+	result := repo.db.WithContext(ctx).Create(b)
 	return b, result.Error
 }
 
-// FindByID IDでグループ掲示板を取得
-func (repo *classBoardRepository) FindByID(id uint) (*models.ClassBoard, error) {
+// FindByID IDでグループ掲示板を取得。投稿者は公開プロフィールの項目のみ読み込み、メールアドレスなどは含めない
+func (repo *classBoardRepository) FindByID(ctx context.Context, id uint) (*models.ClassBoard, error) {
 	var classBoard models.ClassBoard
-	err := repo.db.First(&classBoard, id).Error
+	err := repo.db.WithContext(ctx).
+		Preload("User", func(db *gorm.DB) *gorm.DB { return db.Select("id, name, image") }).
+		First(&classBoard, id).Error
 	return &classBoard, err
 }
 
 // FindAllPaged 全てのグループ掲示板を取得
-func (repo *classBoardRepository) FindAllPaged(cid uint, limit int, offset int) ([]models.ClassBoard, error) {
+func (repo *classBoardRepository) FindAllPaged(ctx context.Context, cid uint, limit int, offset int) ([]models.ClassBoard, error) {
 	var classBoards []models.ClassBoard
-	err := repo.db.Where("cid = ?", cid).Offset(offset).Limit(limit).Find(&classBoards).Error
+	err := repo.db.WithContext(ctx).Where("cid = ?", cid).Order(pinnedFirstOrder).Offset(offset).Limit(limit).Find(&classBoards).Error
 	return classBoards, err
 }
 
 // FindAnnounced 公開されたグループ掲示板を取得
-func (repo *classBoardRepository) FindAnnounced(isAnnounced bool, cid uint) ([]models.ClassBoard, error) {
+func (repo *classBoardRepository) FindAnnounced(ctx context.Context, isAnnounced bool, cid uint) ([]models.ClassBoard, error) {
 	var classBoards []models.ClassBoard
-	err := repo.db.Where("is_announced = ? AND cid = ?", isAnnounced, cid).Find(&classBoards).Error
+	err := repo.db.WithContext(ctx).Where("is_announced = ? AND cid = ?", isAnnounced, cid).Order(pinnedFirstOrder).Find(&classBoards).Error
 	return classBoards, err
 }
 
-// UpdateClassBoard グループ掲示板を更新
-func (repo *classBoardRepository) UpdateClassBoard(b *models.ClassBoard) error {
-	return repo.db.Save(b).Error
+// UpdateClassBoard グループ掲示板を更新。読み込んだ投稿者などの関連は保存しない。
+// expectedVersionが0以外で他の更新が保存されていた場合はErrStaleUpdateを返す
+func (repo *classBoardRepository) UpdateClassBoard(ctx context.Context, b *models.ClassBoard, expectedVersion uint) error {
+	return saveWithVersion(repo.db.WithContext(ctx), b, b.ID, &b.Version, expectedVersion)
 }
 
 // DeleteClassBoard グループ掲示板を削除
-func (repo *classBoardRepository) DeleteClassBoard(id uint) error {
-	return repo.db.Delete(&models.ClassBoard{}, id).Error
+func (repo *classBoardRepository) DeleteClassBoard(ctx context.Context, id uint) error {
+	return repo.db.WithContext(ctx).Delete(&models.ClassBoard{}, id).Error
 }
 
-func (repo *classBoardRepository) SearchByTitle(title string, cid uint) ([]models.ClassBoard, error) {
+func (repo *classBoardRepository) SearchByTitle(ctx context.Context, title string, cid uint) ([]models.ClassBoard, error) {
 	var classBoards []models.ClassBoard
-	err := repo.db.Where("title LIKE ? AND cid = ?", "%"+title+"%", cid).Find(&classBoards).Error
+	err := readOnly(repo.db.WithContext(ctx)).Where("title LIKE ? AND cid = ?", "%"+title+"%", cid).Find(&classBoards).Error
 	return classBoards, err
 }
+
+// IncrementViewCount グループ掲示板の閲覧数を加算
+func (repo *classBoardRepository) IncrementViewCount(ctx context.Context, id uint) error {
+	return repo.db.WithContext(ctx).Model(&models.ClassBoard{}).Where("id = ?", id).UpdateColumn("view_count", gorm.Expr("view_count + ?", 1)).Error
+}
+
+// DeleteByClassCreatedBefore 指定日時より前に作成されたクラスのグループ掲示板を削除し、削除した掲示板を返す。
+// 呼び出し側が画像もストレージから削除するため、論理削除した掲示板を含めて物理削除する
+func (repo *classBoardRepository) DeleteByClassCreatedBefore(ctx context.Context, cid uint, before time.Time) ([]models.ClassBoard, error) {
+	var deleted []models.ClassBoard
+	err := repo.db.WithContext(ctx).Unscoped().
+		Clauses(clause.Returning{Columns: []clause.Column{{Name: "id"}, {Name: "image"}}}).
+		Where("cid = ? AND created_at < ?", cid, before).
+		Delete(&deleted).Error
+	return deleted, err
+}
+
+// UnpinExpired 期限を過ぎたピン留めを解除し、解除した件数を返す
+func (repo *classBoardRepository) UnpinExpired(ctx context.Context, now time.Time) (int64, error) {
+	result := repo.db.WithContext(ctx).Model(&models.ClassBoard{}).
+		Where("is_pinned = ? AND pinned_until IS NOT NULL AND pinned_until <= ?", true, now).
+		Updates(map[string]interface{}{"is_pinned": false, "pinned_until": nil})
+	return result.RowsAffected, result.Error
+}
+
+// UpdateScanStatus 添付画像のスキャンの結果を保存し、保存した場合はtrueを返す。スキャン中に画像が差し替えられた場合は保存しない。
+// ウイルスが検出された画像は掲示板から外す。利用者の更新ではないため、バージョンと更新日時は変えない
+func (repo *classBoardRepository) UpdateScanStatus(ctx context.Context, id uint, image string, status models.AttachmentScanStatus) (bool, error) {
+	columns := map[string]interface{}{"scan_status": status}
+	if status == models.ScanInfected {
+		columns["image"] = ""
+	}
+	result := repo.db.WithContext(ctx).Model(&models.ClassBoard{}).
+		Where("id = ? AND image = ?", id, image).
+		UpdateColumns(columns)
+	return result.RowsAffected > 0, result.Error
+}
+
+// FindStalePendingScans updatedBeforeまでに画像を添付し、まだスキャン中の掲示を古い順にlimit件まで取得
+func (repo *classBoardRepository) FindStalePendingScans(ctx context.Context, updatedBefore time.Time, limit int) ([]models.ClassBoard, error) {
+	var boards []models.ClassBoard
+	err := repo.db.WithContext(ctx).
+		Where("scan_status = ? AND image <> '' AND updated_at <= ?", models.ScanPending, updatedBefore).
+		Order("updated_at ASC, id ASC").
+		Limit(limit).
+		Find(&boards).Error
+	return boards, err
+}
+
+// FindVariants グループ掲示板の他の言語の版を取得
+func (repo *classBoardRepository) FindVariants(ctx context.Context, bids []uint) ([]models.ClassBoardVariant, error) {
+	var variants []models.ClassBoardVariant
+	if len(bids) == 0 {
+		return variants, nil
+	}
+	err := repo.db.WithContext(ctx).Where("bid IN ?", bids).Order("bid, id").Find(&variants).Error
+	return variants, err
+}
+
+// FindUserLocale 掲示板を表示するユーザーの言語を取得。表示する言語の版の選択に使う
+func (repo *classBoardRepository) FindUserLocale(ctx context.Context, uid uint) (string, error) {
+	var locale string
+	err := repo.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", uid).Pluck("locale", &locale).Error
+	return locale, err
+}
@@ -1,6 +1,7 @@
 package repositories
 
 import (
+	"context"
 	"errors"
 	"log"
 	"strconv"
@@ -10,9 +11,9 @@ import (
 )
 
 type ClassCodeRepository interface {
-	FindByCode(code string) (*models.ClassCode, error)
-	FindByClassID(cid uint) (*models.ClassCode, error)
-	SaveClassCode(classCode *models.ClassCode) error
+	FindByCode(ctx context.Context, code string) (*models.ClassCode, error)
+	FindByClassID(ctx context.Context, cid uint) (*models.ClassCode, error)
+	SaveClassCode(ctx context.Context, classCode *models.ClassCode) error
 }
 
 // ClassCodeRepository はグループコードのリポジトリです。
@@ -25,10 +26,13 @@ func NewClassCodeRepository(db *gorm.DB) ClassCodeRepository {
 	return &classCodeRepository{db: db}
 }
 
-// FindByCode は指定されたコードのグループコードを取得します。
-func (r *classCodeRepository) FindByCode(code string) (*models.ClassCode, error) {
+// FindByCode は指定されたコードのグループコードを取得します。削除されたクラスのコードは見つからないものとし、同じコードを再利用できるようにします。
+func (r *classCodeRepository) FindByCode(ctx context.Context, code string) (*models.ClassCode, error) {
 	var classCode models.ClassCode
-	result := r.db.Where("code = ?", code).First(&classCode)
+	result := r.db.WithContext(ctx).
+		Joins("JOIN classes ON classes.id = class_codes.cid AND classes.deleted_at IS NULL").
+		Where("class_codes.code = ?", code).
+		First(&classCode)
 	if result.Error != nil {
 		// レコードが見つからない場合、nilを返します。
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
@@ -41,9 +45,9 @@ func (r *classCodeRepository) FindByCode(code string) (*models.ClassCode, error)
 }
 
 // FindByClassID は指定されたクラスIDのクラスコードを取得します。
-func (r *classCodeRepository) FindByClassID(cid uint) (*models.ClassCode, error) {
+func (r *classCodeRepository) FindByClassID(ctx context.Context, cid uint) (*models.ClassCode, error) {
 	var classCode models.ClassCode
-	result := r.db.Where("cid = ?", cid).First(&classCode)
+	result := r.db.WithContext(ctx).Where("cid = ?", cid).First(&classCode)
 	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
 		log.Printf("ClassCode not found for ClassID: %d", cid)
 		return nil, nil
@@ -58,16 +62,16 @@ func (r *classCodeRepository) FindByClassID(cid uint) (*models.ClassCode, error)
 	return &classCode, nil
 }
 
-func (r *classCodeRepository) SaveClassCode(classCode *models.ClassCode) error {
+func (r *classCodeRepository) SaveClassCode(ctx context.Context, classCode *models.ClassCode) error {
 	var class models.Class
-	if err := r.db.First(&class, "id = ?", classCode.CID).Error; err != nil {
+	if err := r.db.WithContext(ctx).First(&class, "id = ?", classCode.CID).Error; err != nil {
 		return errors.New("invalid class ID: " + strconv.Itoa(int(classCode.CID)))
 	}
 
 	var user models.User
-	if err := r.db.First(&user, "id = ?", classCode.UID).Error; err != nil {
+	if err := r.db.WithContext(ctx).First(&user, "id = ?", classCode.UID).Error; err != nil {
 		return errors.New("invalid user ID: " + strconv.Itoa(int(classCode.UID)))
 	}
 
-	return r.db.Create(classCode).Error
+	return r.db.WithContext(ctx).Create(classCode).Error
 }
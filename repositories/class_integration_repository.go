@@ -0,0 +1,103 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ClassIntegrationRepository クラスのチャットサービスとの連携のリポジトリ
+type ClassIntegrationRepository interface {
+	FindIntegration(ctx context.Context, cid uint) (*models.ClassIntegration, error)
+	SaveIntegration(ctx context.Context, integration *models.ClassIntegration) error
+	DeleteIntegration(ctx context.Context, cid uint) (bool, error)
+	RecordPosted(ctx context.Context, id uint, at time.Time) error
+	RecordFailure(ctx context.Context, id uint, message string, at time.Time) error
+	RecordClientError(ctx context.Context, id uint, message string, at time.Time, limit int) (bool, error)
+}
+
+// classIntegrationRepository ClassIntegrationRepositoryを実装
+type classIntegrationRepository struct {
+	db *gorm.DB
+}
+
+// NewClassIntegrationRepository ClassIntegrationRepositoryを生成
+func NewClassIntegrationRepository(db *gorm.DB) ClassIntegrationRepository {
+	return &classIntegrationRepository{db: db}
+}
+
+// FindIntegration クラスの連携を取得
+func (r *classIntegrationRepository) FindIntegration(ctx context.Context, cid uint) (*models.ClassIntegration, error) {
+	var integration models.ClassIntegration
+	if err := r.db.WithContext(ctx).Where("cid = ?", cid).First(&integration).Error; err != nil {
+		return nil, err
+	}
+	return &integration, nil
+}
+
+// SaveIntegration クラスの連携を登録する。登録済みの場合は投稿先・イベント・状態を置き換える
+func (r *classIntegrationRepository) SaveIntegration(ctx context.Context, integration *models.ClassIntegration) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "cid"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"provider", "webhook_url", "events", "status", "last_error", "consecutive_client_errors",
+			"last_posted_at", "last_failed_at", "disabled_at", "updated_at",
+		}),
+	}).Create(integration).Error
+}
+
+// DeleteIntegration クラスの連携を削除する。削除した場合はtrueを返す
+func (r *classIntegrationRepository) DeleteIntegration(ctx context.Context, cid uint) (bool, error) {
+	result := r.db.WithContext(ctx).Where("cid = ?", cid).Delete(&models.ClassIntegration{})
+	return result.RowsAffected > 0, result.Error
+}
+
+// RecordPosted 投稿に成功したことを記録し、4xxの連続の回数を0に戻す。投稿を停止した連携は変更しない
+func (r *classIntegrationRepository) RecordPosted(ctx context.Context, id uint, at time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.ClassIntegration{}).
+		Where("id = ? AND status <> ?", id, models.IntegrationDisabled).
+		Updates(map[string]interface{}{
+			"status":                    models.IntegrationOK,
+			"last_error":                "",
+			"consecutive_client_errors": 0,
+			"last_posted_at":            at,
+		}).Error
+}
+
+// RecordFailure 再送しても投稿できなかったことを記録する。投稿を停止した連携は変更しない
+func (r *classIntegrationRepository) RecordFailure(ctx context.Context, id uint, message string, at time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.ClassIntegration{}).
+		Where("id = ? AND status <> ?", id, models.IntegrationDisabled).
+		Updates(map[string]interface{}{
+			"status":         models.IntegrationFailing,
+			"last_error":     message,
+			"last_failed_at": at,
+		}).Error
+}
+
+// RecordClientError 投稿先が4xxを返したことを記録し、連続の回数がlimitに達した場合は投稿を停止する。停止した場合はtrueを返す
+func (r *classIntegrationRepository) RecordClientError(ctx context.Context, id uint, message string, at time.Time, limit int) (bool, error) {
+	var disabled bool
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.ClassIntegration{}).
+			Where("id = ? AND status <> ?", id, models.IntegrationDisabled).
+			Updates(map[string]interface{}{
+				"status":                    models.IntegrationFailing,
+				"last_error":                message,
+				"last_failed_at":            at,
+				"consecutive_client_errors": gorm.Expr("consecutive_client_errors + 1"),
+			})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		result = tx.Model(&models.ClassIntegration{}).
+			Where("id = ? AND consecutive_client_errors >= ?", id, limit).
+			Updates(map[string]interface{}{"status": models.IntegrationDisabled, "disabled_at": at})
+		disabled = result.RowsAffected > 0
+		return result.Error
+	})
+	return disabled, err
+}
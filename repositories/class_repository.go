@@ -1,18 +1,38 @@
 package repositories
 
 import (
+	"context"
 	"errors"
+	"strings"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/utils"
 	"gorm.io/gorm"
 )
 
 type ClassRepository interface {
-	GetByID(classID uint) (*models.Class, error)
-	Create(class *models.Class) error
-	Save(class *models.Class) (uint, error)
-	UpdateClassImage(classID uint, imageUrl string) error
-	Update(class *models.Class) error
-	Delete(classID uint) error
+	GetByID(ctx context.Context, classID uint) (*models.Class, error)
+	Create(ctx context.Context, class *models.Class) error
+	Save(ctx context.Context, class *models.Class) (uint, error)
+	UpdateClassImage(ctx context.Context, classID uint, imageUrl string) error
+	Update(ctx context.Context, class *models.Class) error
+	Delete(ctx context.Context, classID uint) error
+	Restore(ctx context.Context, classID uint) error
+	GetClassPreview(ctx context.Context, classID uint) (*dto.ClassPreviewDTO, error)
+	FindInactiveClasses(ctx context.Context, endedBefore time.Time) ([]dto.InactiveClassDTO, error)
+	MarkArchiveNoticeSent(ctx context.Context, classID uint, sentAt time.Time) error
+	Archive(ctx context.Context, classID uint, archivedAt time.Time) error
+	SetArchiveExempt(ctx context.Context, classID uint, exempt bool) error
+	SetAccessRestriction(ctx context.Context, classID uint, startTime, endTime, allowedIPRanges *string) error
+	FindPublicClasses(ctx context.Context, query string, language string, now time.Time, limit int, offset int) ([]dto.PublicClassDTO, error)
+	CountMembersByRole(ctx context.Context, classID uint) (map[string]int64, error)
+	CountActiveMembers(ctx context.Context, classID uint, since time.Time) (int64, error)
+	CountBoards(ctx context.Context, classID uint) (int64, error)
+	CountSchedules(ctx context.Context, classID uint, now time.Time) (total int64, upcoming int64, err error)
+	GetAttendanceRate(ctx context.Context, classID uint) (float64, error)
+	FindTimelineEvents(ctx context.Context, classID uint, types []dto.ClassTimelineEventType, limit int, offset int) ([]dto.ClassTimelineEventDTO, error)
 }
 
 type classRepository struct {
@@ -23,9 +43,9 @@ func NewClassRepository(db *gorm.DB) ClassRepository {
 	return &classRepository{db: db}
 }
 
-func (r *classRepository) GetByID(classID uint) (*models.Class, error) {
+func (r *classRepository) GetByID(ctx context.Context, classID uint) (*models.Class, error) {
 	var class models.Class
-	result := r.db.First(&class, classID)
+	result := r.db.WithContext(ctx).First(&class, classID)
 	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
 		return nil, result.Error
 	}
@@ -35,25 +55,269 @@ func (r *classRepository) GetByID(classID uint) (*models.Class, error) {
 	return &class, nil
 }
 
-func (r *classRepository) Create(class *models.Class) error {
-	return r.db.Create(class).Error
+func (r *classRepository) Create(ctx context.Context, class *models.Class) error {
+	return r.db.WithContext(ctx).Create(class).Error
 }
 
-func (r *classRepository) Save(class *models.Class) (uint, error) {
-	if err := r.db.Create(&class).Error; err != nil {
+func (r *classRepository) Save(ctx context.Context, class *models.Class) (uint, error) {
+	if err := r.db.WithContext(ctx).Create(&class).Error; err != nil {
 		return 0, err
 	}
 	return class.ID, nil
 }
 
-func (r *classRepository) UpdateClassImage(classID uint, imageUrl string) error {
-	return r.db.Model(&models.Class{}).Where("id = ?", classID).Update("image", imageUrl).Error
+func (r *classRepository) UpdateClassImage(ctx context.Context, classID uint, imageUrl string) error {
+	return r.db.WithContext(ctx).Model(&models.Class{}).Where("id = ?", classID).Update("image", imageUrl).Error
+}
+
+func (r *classRepository) Update(ctx context.Context, class *models.Class) error {
+	return r.db.WithContext(ctx).Save(class).Error
+}
+
+// Delete クラスと、そのスケジュールと掲示板を同じ時刻で論理削除する
+func (r *classRepository) Delete(ctx context.Context, classID uint) error {
+	deletedAt := time.Now()
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Class{}).Where("id = ?", classID).Update("deleted_at", deletedAt)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		if err := tx.Model(&models.ClassSchedule{}).Where("cid = ?", classID).Update("deleted_at", deletedAt).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.ClassBoard{}).Where("cid = ?", classID).Update("deleted_at", deletedAt).Error
+	})
+}
+
+// Restore 論理削除したクラスを復元する。クラスと同時に削除したスケジュールと掲示板も復元し、個別に削除したものは削除したままにする
+func (r *classRepository) Restore(ctx context.Context, classID uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var class models.Class
+		if err := tx.Unscoped().Where("id = ? AND deleted_at IS NOT NULL", classID).First(&class).Error; err != nil {
+			return err
+		}
+		deletedAt := class.DeletedAt.Time
+		if err := tx.Unscoped().Model(&models.Class{}).Where("id = ?", classID).Update("deleted_at", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Model(&models.ClassSchedule{}).Where("cid = ? AND deleted_at = ?", classID, deletedAt).Update("deleted_at", nil).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Model(&models.ClassBoard{}).Where("cid = ? AND deleted_at = ?", classID, deletedAt).Update("deleted_at", nil).Error
+	})
+}
+
+// GetClassPreview クラスの公開情報を講師名と参加メンバー数とともに取得する
+func (r *classRepository) GetClassPreview(ctx context.Context, classID uint) (*dto.ClassPreviewDTO, error) {
+	var preview dto.ClassPreviewDTO
+	memberCount := r.db.Model(&models.ClassUser{}).
+		Select("COUNT(*)").
+		Where("class_users.cid = classes.id AND class_users.role IN ?", []string{"ADMIN", "ASSISTANT", "USER"})
+
+	result := r.db.WithContext(ctx).Model(&models.Class{}).
+		Select("classes.id, classes.name, classes.description, classes.image, classes.limitation, users.name AS instructor_name, (?) AS member_count", memberCount).
+		Joins("LEFT JOIN users ON users.id = classes.uid").
+		Where("classes.id = ?", classID).
+		Take(&preview)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &preview, nil
+}
+
+// FindInactiveClasses 最後のスケジュールの終了日時が指定日時より前で、アーカイブも除外もされていないクラスを取得する
+// スケジュールが1件もないクラスは対象にしない
+func (r *classRepository) FindInactiveClasses(ctx context.Context, endedBefore time.Time) ([]dto.InactiveClassDTO, error) {
+	var classes []dto.InactiveClassDTO
+	err := r.db.WithContext(ctx).Model(&models.Class{}).
+		Select("classes.id, classes.name, MAX(class_schedules.ended_at) AS last_ended_at, classes.archive_notice_sent_at").
+		Joins("JOIN class_schedules ON class_schedules.cid = classes.id AND class_schedules.deleted_at IS NULL").
+		Where("classes.is_archived = ? AND classes.archive_exempt = ?", false, false).
+		Group("classes.id, classes.name, classes.archive_notice_sent_at").
+		Having("MAX(class_schedules.ended_at) < ?", endedBefore).
+		Scan(&classes).Error
+	return classes, err
+}
+
+// MarkArchiveNoticeSent 自動アーカイブの事前通知を送った日時を記録する
+func (r *classRepository) MarkArchiveNoticeSent(ctx context.Context, classID uint, sentAt time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.Class{}).Where("id = ?", classID).Update("archive_notice_sent_at", sentAt).Error
+}
+
+// Archive クラスをアーカイブする
+func (r *classRepository) Archive(ctx context.Context, classID uint, archivedAt time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.Class{}).Where("id = ?", classID).
+		Updates(map[string]interface{}{"is_archived": true, "archived_at": archivedAt}).Error
+}
+
+// SetAccessRestriction アクセスを許可する時間帯とIPレンジを設定する。nilの項目は制限を解除する
+func (r *classRepository) SetAccessRestriction(ctx context.Context, classID uint, startTime, endTime, allowedIPRanges *string) error {
+	result := r.db.WithContext(ctx).Model(&models.Class{}).Where("id = ?", classID).Updates(map[string]interface{}{
+		"access_start_time": startTime,
+		"access_end_time":   endTime,
+		"allowed_ip_ranges": allowedIPRanges,
+	})
+	if result.Error == nil && result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return result.Error
+}
+
+// SetArchiveExempt 自動アーカイブの対象から除外するかを設定する
+func (r *classRepository) SetArchiveExempt(ctx context.Context, classID uint, exempt bool) error {
+	result := r.db.WithContext(ctx).Model(&models.Class{}).Where("id = ?", classID).Update("archive_exempt", exempt)
+	if result.Error == nil && result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return result.Error
+}
+
+// FindPublicClasses アーカイブされていない公開クラスを、メンバー数と今後のスケジュール数とともに取得する。
+// queryはクラス名と説明の部分一致で、%と_も文字として検索する。languageは言語の完全一致で絞り込み、空の場合は絞り込まない
+func (r *classRepository) FindPublicClasses(ctx context.Context, query string, language string, now time.Time, limit int, offset int) ([]dto.PublicClassDTO, error) {
+	memberCount := r.db.Model(&models.ClassUser{}).
+		Select("COUNT(*)").
+		Where("class_users.cid = classes.id AND class_users.role IN ?", []string{"ADMIN", "ASSISTANT", "USER"})
+	upcomingScheduleCount := r.db.Model(&models.ClassSchedule{}).
+		Select("COUNT(*)").
+		Where("class_schedules.cid = classes.id AND class_schedules.started_at > ? AND class_schedules.is_cancelled = ?", now, false)
+
+	db := readOnly(r.db.WithContext(ctx)).Model(&models.Class{}).
+		Select("classes.id, classes.name, classes.description, classes.image, classes.language, (?) AS member_count, (?) AS upcoming_schedule_count", memberCount, upcomingScheduleCount).
+		Where("classes.is_public = ? AND classes.is_archived = ?", true, false)
+	if query != "" {
+		pattern := utils.ContainsPattern(query)
+		db = db.Where(`classes.name ILIKE ? ESCAPE '\' OR classes.description ILIKE ? ESCAPE '\'`, pattern, pattern)
+	}
+	if language != "" {
+		db = db.Where("classes.language = ?", language)
+	}
+
+	var classes []dto.PublicClassDTO
+	err := db.Order("classes.id DESC").Offset(offset).Limit(limit).Scan(&classes).Error
+	return classes, err
+}
+
+// CountMembersByRole クラスのメンバー数をロールごとに数える
+func (r *classRepository) CountMembersByRole(ctx context.Context, classID uint) (map[string]int64, error) {
+	var rows []struct {
+		Role  string
+		Count int64
+	}
+	err := readOnly(r.db.WithContext(ctx)).Model(&models.ClassUser{}).
+		Select("role, COUNT(*) AS count").
+		Where("cid = ?", classID).
+		Group("role").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		counts[row.Role] = row.Count
+	}
+	return counts, nil
+}
+
+// CountActiveMembers since以降に開始したスケジュールに出席または遅刻したか、掲示板に投稿したメンバーを数える
+func (r *classRepository) CountActiveMembers(ctx context.Context, classID uint, since time.Time) (int64, error) {
+	attended := r.db.Table("attendances").
+		Select("1").
+		Joins("JOIN class_schedules ON class_schedules.id = attendances.csid AND class_schedules.deleted_at IS NULL").
+		Where("attendances.cid = class_users.cid AND attendances.uid = class_users.uid AND attendances.is_attendance IN ? AND class_schedules.started_at >= ?",
+			[]models.AttendanceType{models.AttendanceStatus, models.TardyStatus}, since)
+	posted := r.db.Table("class_boards").
+		Select("1").
+		Where("class_boards.cid = class_users.cid AND class_boards.uid = class_users.uid AND class_boards.created_at >= ? AND class_boards.deleted_at IS NULL", since)
+
+	var count int64
+	err := readOnly(r.db.WithContext(ctx)).Model(&models.ClassUser{}).
+		Where("class_users.cid = ? AND class_users.role IN ?", classID, []string{"ADMIN", "ASSISTANT", "USER"}).
+		Where("EXISTS (?) OR EXISTS (?)", attended, posted).
+		Count(&count).Error
+	return count, err
+}
+
+// CountBoards クラスの掲示板の投稿数を数える
+func (r *classRepository) CountBoards(ctx context.Context, classID uint) (int64, error) {
+	var count int64
+	err := readOnly(r.db.WithContext(ctx)).Model(&models.ClassBoard{}).Where("cid = ?", classID).Count(&count).Error
+	return count, err
+}
+
+// CountSchedules クラスのスケジュール数と、休講を除くnow以降に開始するスケジュール数を数える
+func (r *classRepository) CountSchedules(ctx context.Context, classID uint, now time.Time) (int64, int64, error) {
+	var counts struct {
+		Total    int64
+		Upcoming int64
+	}
+	err := readOnly(r.db.WithContext(ctx)).Model(&models.ClassSchedule{}).
+		Select("COUNT(*) AS total, COUNT(CASE WHEN started_at > ? AND is_cancelled = ? THEN 1 END) AS upcoming", now, false).
+		Where("cid = ?", classID).
+		Scan(&counts).Error
+	return counts.Total, counts.Upcoming, err
+}
+
+// GetAttendanceRate クラス全体の出席率を集計する。記録がない場合は0
+func (r *classRepository) GetAttendanceRate(ctx context.Context, classID uint) (float64, error) {
+	var rate float64
+	err := readOnly(r.db.WithContext(ctx)).Table("attendances").
+		Select("COALESCE(COUNT(CASE WHEN attendances.is_attendance = ? THEN 1 END) * 1.0 / NULLIF(COUNT(*), 0), 0)", models.AttendanceStatus).
+		Joins("JOIN class_schedules ON class_schedules.id = attendances.csid AND class_schedules.deleted_at IS NULL").
+		Where("attendances.cid = ?", classID).
+		Scan(&rate).Error
+	return rate, err
 }
 
-func (r *classRepository) Update(class *models.Class) error {
-	return r.db.Save(class).Error
+// timelineEventQueries タイムラインの出来事の種類ごとの取得条件。いずれもクラスIDを1つ目の引数にとる
+var timelineEventQueries = map[dto.ClassTimelineEventType]string{
+	dto.TimelineBoardPosted: "SELECT 'board_posted' AS type, created_at AS occurred_at, id AS resource_id, uid AS actor_uid, title " +
+		"FROM class_boards WHERE cid = ? AND deleted_at IS NULL",
+	// 作成日時を記録する前に作成したスケジュールは含めない
+	dto.TimelineScheduleAdded: "SELECT 'schedule_added' AS type, created_at AS occurred_at, id AS resource_id, CAST(NULL AS bigint) AS actor_uid, title " +
+		"FROM class_schedules WHERE cid = ? AND deleted_at IS NULL AND created_at IS NOT NULL",
+	dto.TimelineMemberJoined: "SELECT 'member_joined' AS type, joined_at AS occurred_at, uid AS resource_id, uid AS actor_uid, nickname AS title " +
+		"FROM class_users WHERE cid = ? AND role IN ('ADMIN', 'ASSISTANT', 'USER') AND deleted_at IS NULL",
+	// v1とv2のロールの変更のAPIのうち成功した操作の監査ログ。対象のユーザーIDはパスパラメータのuidから記録している。
+	// 変更後のロールはパスパラメータのため監査ログに残らず、取得できない。削除済みのメンバーはメンバーの参加と同じく含めない
+	dto.TimelineRoleChanged: "SELECT 'role_changed' AS type, audit_logs.created_at AS occurred_at, target.uid AS resource_id, audit_logs.actor_uid, target.nickname AS title " +
+		"FROM audit_logs INNER JOIN class_users AS target ON target.cid = audit_logs.cid AND CAST(target.uid AS text) = audit_logs.resource_id AND target.deleted_at IS NULL " +
+		"WHERE audit_logs.cid = ? AND audit_logs.method = 'PATCH' AND audit_logs.route LIKE '%/role/:roleName' AND audit_logs.status_code < 400",
 }
 
-func (r *classRepository) Delete(classID uint) error {
-	return r.db.Delete(&models.Class{}, classID).Error
+// FindTimelineEvents クラスの掲示の投稿、スケジュールの追加、メンバーの参加、ロールの変更を新しい順に取得する。
+// typesで出来事の種類を絞り込み、空の場合は全ての種類を取得する
+func (r *classRepository) FindTimelineEvents(ctx context.Context, classID uint, types []dto.ClassTimelineEventType, limit int, offset int) ([]dto.ClassTimelineEventDTO, error) {
+	if len(types) == 0 {
+		types = dto.ClassTimelineEventTypes
+	}
+	queries := make([]string, 0, len(types))
+	args := make([]interface{}, 0, len(types)+2)
+	for _, eventType := range dto.ClassTimelineEventTypes {
+		if !containsTimelineEventType(types, eventType) {
+			continue
+		}
+		queries = append(queries, timelineEventQueries[eventType])
+		args = append(args, classID)
+	}
+	args = append(args, limit, offset)
+
+	events := []dto.ClassTimelineEventDTO{}
+	err := readOnly(r.db.WithContext(ctx)).
+		Raw("SELECT * FROM ("+strings.Join(queries, " UNION ALL ")+") AS timeline ORDER BY occurred_at DESC, type, resource_id DESC LIMIT ? OFFSET ?", args...).
+		Scan(&events).Error
+	return events, err
+}
+
+// containsTimelineEventType typesにeventTypeが含まれるか判定する
+func containsTimelineEventType(types []dto.ClassTimelineEventType, eventType dto.ClassTimelineEventType) bool {
+	for _, t := range types {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
 }
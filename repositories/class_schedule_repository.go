@@ -1,19 +1,27 @@
 package repositories
 
 import (
+	"context"
+	"time"
+
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
 	"gorm.io/gorm"
 )
 
 // ClassScheduleRepository インタフェース
 type ClassScheduleRepository interface {
-	GetClassScheduleByID(id uint) (*models.ClassSchedule, error)
-	GetAllClassSchedules(cid uint) ([]models.ClassSchedule, error)
-	CreateClassSchedule(classSchedule *models.ClassSchedule) error
-	UpdateClassSchedule(classSchedule *models.ClassSchedule) error
-	DeleteClassSchedule(id uint) error
-	FindLiveClassSchedules(cid uint) ([]models.ClassSchedule, error)
-	FindClassSchedulesByDate(cid uint, date string) ([]models.ClassSchedule, error)
+	GetClassScheduleByID(ctx context.Context, id uint) (*models.ClassSchedule, error)
+	GetAllClassSchedules(ctx context.Context, cid uint) ([]models.ClassSchedule, error)
+	CreateClassSchedule(ctx context.Context, classSchedule *models.ClassSchedule) error
+	UpdateClassSchedule(ctx context.Context, classSchedule *models.ClassSchedule, expectedVersion uint) error
+	DeleteClassSchedule(ctx context.Context, id uint) error
+	FindDeletedClassSchedule(ctx context.Context, id uint) (*models.ClassSchedule, error)
+	RestoreClassSchedule(ctx context.Context, id uint) error
+	FindLiveClassSchedules(ctx context.Context, cid uint) ([]models.ClassSchedule, error)
+	FindClassSchedulesByDate(ctx context.Context, cid uint, date time.Time, mode models.AttendanceMode) ([]models.ClassSchedule, error)
+	FindClassSchedulesByUser(ctx context.Context, uid uint) ([]models.ClassSchedule, error)
+	FindNextClassSchedule(ctx context.Context, cid uint, after time.Time) (*models.ClassSchedule, error)
+	FindClassSchedulesByUserBetween(ctx context.Context, uid uint, from, to time.Time) ([]models.ClassSchedule, error)
 }
 
 // classScheduleConnection クラススケジュールリポジトリ
@@ -26,45 +34,113 @@ func NewClassScheduleRepository(db *gorm.DB) ClassScheduleRepository {
 	return &classScheduleRepository{db: db}
 }
 
-// GetClassScheduleByID クラススケジュールを取得
-func (repo *classScheduleRepository) GetClassScheduleByID(id uint) (*models.ClassSchedule, error) {
+// GetClassScheduleByID クラススケジュールを取得。クラスは表示に使う項目のみ読み込む
+func (repo *classScheduleRepository) GetClassScheduleByID(ctx context.Context, id uint) (*models.ClassSchedule, error) {
 	var classSchedule models.ClassSchedule
-	err := repo.db.First(&classSchedule, id).Error
+	err := repo.db.WithContext(ctx).
+		Preload("Class", func(db *gorm.DB) *gorm.DB { return db.Select("id, name, image") }).
+		First(&classSchedule, id).Error
 	return &classSchedule, err
 }
 
 // GetAllClassSchedules 全てのクラススケジュールを取得
-func (repo *classScheduleRepository) GetAllClassSchedules(cid uint) ([]models.ClassSchedule, error) {
+func (repo *classScheduleRepository) GetAllClassSchedules(ctx context.Context, cid uint) ([]models.ClassSchedule, error) {
 	var classSchedules []models.ClassSchedule
-	err := repo.db.Where("cid = ?", cid).Find(&classSchedules).Error
+	err := repo.db.WithContext(ctx).Where("cid = ?", cid).Find(&classSchedules).Error
 	return classSchedules, err
 }
 
 // CreateClassSchedule 新しいクラススケジュールを作成
-func (repo *classScheduleRepository) CreateClassSchedule(classSchedule *models.ClassSchedule) error {
-	return repo.db.Create(classSchedule).Error
+func (repo *classScheduleRepository) CreateClassSchedule(ctx context.Context, classSchedule *models.ClassSchedule) error {
+	return repo.db.WithContext(ctx).Create(classSchedule).Error
 }
 
-// UpdateClassSchedule クラススケジュールを更新
-func (repo *classScheduleRepository) UpdateClassSchedule(classSchedule *models.ClassSchedule) error {
-	return repo.db.Save(classSchedule).Error
+// UpdateClassSchedule クラススケジュールを更新。読み込んだクラスなどの関連は保存しない。
+// expectedVersionが0以外で他の更新が保存されていた場合はErrStaleUpdateを返す
+func (repo *classScheduleRepository) UpdateClassSchedule(ctx context.Context, classSchedule *models.ClassSchedule, expectedVersion uint) error {
+	return saveWithVersion(repo.db.WithContext(ctx), classSchedule, classSchedule.ID, &classSchedule.Version, expectedVersion)
 }
 
 // DeleteClassSchedule クラススケジュールを削除
-func (repo *classScheduleRepository) DeleteClassSchedule(id uint) error {
-	return repo.db.Delete(&models.ClassSchedule{}, id).Error
+func (repo *classScheduleRepository) DeleteClassSchedule(ctx context.Context, id uint) error {
+	return repo.db.WithContext(ctx).Delete(&models.ClassSchedule{}, id).Error
+}
+
+// FindDeletedClassSchedule 論理削除したクラススケジュールを取得
+func (repo *classScheduleRepository) FindDeletedClassSchedule(ctx context.Context, id uint) (*models.ClassSchedule, error) {
+	var classSchedule models.ClassSchedule
+	err := repo.db.WithContext(ctx).Unscoped().Where("id = ? AND deleted_at IS NOT NULL", id).First(&classSchedule).Error
+	return &classSchedule, err
+}
+
+// RestoreClassSchedule 論理削除したクラススケジュールを復元。クラスが削除されている場合は復元せずErrRecordNotFoundを返す
+func (repo *classScheduleRepository) RestoreClassSchedule(ctx context.Context, id uint) error {
+	result := repo.db.WithContext(ctx).Unscoped().Model(&models.ClassSchedule{}).
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		Where("EXISTS (SELECT 1 FROM classes WHERE classes.id = class_schedules.cid AND classes.deleted_at IS NULL)").
+		Update("deleted_at", nil)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// FindLiveClassSchedules ライブ中のクラススケジュールを取得。休講のスケジュールは含めない
+func (repo *classScheduleRepository) FindLiveClassSchedules(ctx context.Context, cid uint) ([]models.ClassSchedule, error) {
+	var classSchedules []models.ClassSchedule
+	err := repo.db.WithContext(ctx).Where("cid = ? AND is_live = ? AND is_cancelled = ? AND ended_at > ?", cid, true, false, time.Now()).Find(&classSchedules).Error
+	return classSchedules, err
 }
 
-// FindLiveClassSchedules ライブ中のクラススケジュールを取得
-func (repo *classScheduleRepository) FindLiveClassSchedules(cid uint) ([]models.ClassSchedule, error) {
+// FindClassSchedulesByDate dateの日に開始するクラススケジュールを取得。日の区切りはdateのタイムゾーンに従う。
+// modeを指定した場合はその出席方式のスケジュールのみ取得する
+func (repo *classScheduleRepository) FindClassSchedulesByDate(ctx context.Context, cid uint, date time.Time, mode models.AttendanceMode) ([]models.ClassSchedule, error) {
 	var classSchedules []models.ClassSchedule
-	err := repo.db.Where("cid = ? AND is_live = true AND end_time > NOW()", cid).Find(&classSchedules).Error
+	start := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
+	query := repo.db.WithContext(ctx).Where("cid = ? AND started_at >= ? AND started_at < ?", cid, start, start.AddDate(0, 0, 1))
+	if mode != "" {
+		query = query.Where("attendance_mode = ?", mode)
+	}
+	err := query.Order("started_at ASC").Find(&classSchedules).Error
 	return classSchedules, err
 }
 
-// FindClassSchedulesByDate 日付でクラススケジュールを取得
-func (repo *classScheduleRepository) FindClassSchedulesByDate(cid uint, date string) ([]models.ClassSchedule, error) {
+// FindClassSchedulesByUser ユーザーが参加している全クラスのスケジュールをクラス情報とともに取得
+func (repo *classScheduleRepository) FindClassSchedulesByUser(ctx context.Context, uid uint) ([]models.ClassSchedule, error) {
+	var classSchedules []models.ClassSchedule
+	err := repo.db.WithContext(ctx).Preload("Class").
+		Joins("JOIN class_users ON class_users.cid = class_schedules.cid AND class_users.deleted_at IS NULL").
+		Where("class_users.uid = ? AND class_users.role IN ?", uid, []string{"ADMIN", "ASSISTANT", "USER"}).
+		Order("class_schedules.started_at").
+		Find(&classSchedules).Error
+	return classSchedules, err
+}
+
+// FindNextClassSchedule 指定日時より後に始まる最初のクラススケジュールを取得。存在しない場合はnilを返す
+func (repo *classScheduleRepository) FindNextClassSchedule(ctx context.Context, cid uint, after time.Time) (*models.ClassSchedule, error) {
+	var classSchedules []models.ClassSchedule
+	err := repo.db.WithContext(ctx).
+		Where("cid = ? AND started_at > ?", cid, after).
+		Order("started_at").
+		Limit(1).
+		Find(&classSchedules).Error
+	if err != nil || len(classSchedules) == 0 {
+		return nil, err
+	}
+	return &classSchedules[0], nil
+}
+
+// FindClassSchedulesByUserBetween ユーザーが参加しているクラスのうち、期間内に始まるスケジュールをクラス情報とともに取得
+func (repo *classScheduleRepository) FindClassSchedulesByUserBetween(ctx context.Context, uid uint, from, to time.Time) ([]models.ClassSchedule, error) {
 	var classSchedules []models.ClassSchedule
-	err := repo.db.Where("cid = ? AND DATE(start_time) = ?", cid, date).Find(&classSchedules).Error
+	err := repo.db.WithContext(ctx).Preload("Class").
+		Joins("JOIN class_users ON class_users.cid = class_schedules.cid AND class_users.deleted_at IS NULL").
+		Where("class_users.uid = ? AND class_users.role IN ?", uid, []string{"ADMIN", "ASSISTANT", "USER"}).
+		Where("class_schedules.started_at >= ? AND class_schedules.started_at < ?", from, to).
+		Order("class_schedules.started_at").
+		Find(&classSchedules).Error
 	return classSchedules, err
 }
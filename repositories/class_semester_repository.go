@@ -0,0 +1,83 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ClassSemesterRepository クラスの学期と成績の基準のリポジトリ
+type ClassSemesterRepository interface {
+	UpsertSemester(ctx context.Context, semester *models.ClassSemester) error
+	FindSemester(ctx context.Context, cid uint, name string) (*models.ClassSemester, error)
+	FindSemesters(ctx context.Context, cid uint) ([]models.ClassSemester, error)
+	DeleteSemester(ctx context.Context, cid uint, name string) (int64, error)
+	FindGradeScale(ctx context.Context, cid uint) ([]models.ClassGradeThreshold, error)
+	ReplaceGradeScale(ctx context.Context, cid uint, thresholds []models.ClassGradeThreshold) error
+}
+
+// classSemesterRepository ClassSemesterRepositoryを実装
+type classSemesterRepository struct {
+	db *gorm.DB
+}
+
+// NewClassSemesterRepository ClassSemesterRepositoryを生成
+func NewClassSemesterRepository(db *gorm.DB) ClassSemesterRepository {
+	return &classSemesterRepository{db: db}
+}
+
+// UpsertSemester 学期を登録する。同じ名前の学期がある場合は期間を置き換える
+func (r *classSemesterRepository) UpsertSemester(ctx context.Context, semester *models.ClassSemester) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "cid"}, {Name: "name"}},
+		DoUpdates: clause.AssignmentColumns([]string{"starts_at", "ends_at", "updated_at"}),
+	}).Create(semester).Error
+}
+
+// FindSemester 名前で学期を取得
+func (r *classSemesterRepository) FindSemester(ctx context.Context, cid uint, name string) (*models.ClassSemester, error) {
+	var semester models.ClassSemester
+	if err := r.db.WithContext(ctx).Where("cid = ? AND name = ?", cid, name).First(&semester).Error; err != nil {
+		return nil, err
+	}
+	return &semester, nil
+}
+
+// FindSemesters クラスの学期を開始日時の順に取得
+func (r *classSemesterRepository) FindSemesters(ctx context.Context, cid uint) ([]models.ClassSemester, error) {
+	var semesters []models.ClassSemester
+	err := r.db.WithContext(ctx).Where("cid = ?", cid).Order("starts_at ASC, id ASC").Find(&semesters).Error
+	return semesters, err
+}
+
+// DeleteSemester 学期を削除し、削除した件数を返す
+func (r *classSemesterRepository) DeleteSemester(ctx context.Context, cid uint, name string) (int64, error) {
+	result := r.db.WithContext(ctx).Where("cid = ? AND name = ?", cid, name).Delete(&models.ClassSemester{})
+	return result.RowsAffected, result.Error
+}
+
+// FindGradeScale クラスの成績の基準を出席率の下限が高い順に取得
+func (r *classSemesterRepository) FindGradeScale(ctx context.Context, cid uint) ([]models.ClassGradeThreshold, error) {
+	var thresholds []models.ClassGradeThreshold
+	err := r.db.WithContext(ctx).Where("cid = ?", cid).Order("min_rate DESC").Find(&thresholds).Error
+	return thresholds, err
+}
+
+// ReplaceGradeScale クラスの成績の基準を全て置き換える。空の場合は基準を削除する
+func (r *classSemesterRepository) ReplaceGradeScale(ctx context.Context, cid uint, thresholds []models.ClassGradeThreshold) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("cid = ?", cid).Delete(&models.ClassGradeThreshold{}).Error; err != nil {
+			return err
+		}
+		if len(thresholds) == 0 {
+			return nil
+		}
+		for i := range thresholds {
+			thresholds[i].ID = 0
+			thresholds[i].CID = cid
+		}
+		return tx.Omit(clause.Associations).Create(&thresholds).Error
+	})
+}
@@ -1,6 +1,7 @@
 package repositories
 
 import (
+	"context"
 	"errors"
 
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/constants"
@@ -8,25 +9,44 @@ import (
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type ClassUserRepository interface {
-	GetClassMembers(cid uint, roles ...string) ([]dto.ClassMemberDTO, error)
-	GetClassUserInfo(uid uint, cid uint) (dto.ClassMemberDTO, error)
-	GetUserClasses(uid uint, page int, limit int) ([]dto.UserClassInfoDTO, error)
-	GetUserClassesByRole(uid uint, role string, page int, limit int) ([]dto.UserClassInfoDTO, error)
-	GetRole(uid uint, cid uint) (string, error)
-	UpdateUserRole(uid uint, cid uint, newRole string) error
-	UpdateUserName(uid uint, cid uint, newName string) error
-	ToggleFavorite(uid uint, cid uint) error
-	DeleteClassUser(uid uint, cid uint) error
-	Save(classUser *models.ClassUser) error
-	GetFavoriteClasses(uid uint, page int, limit int) ([]dto.UserClassInfoDTO, error)
-	IsAdmin(uid uint, cid uint) (bool, error)
-	IsMember(uid uint, cid uint) (bool, error)
-	SearchUserClassesByName(uid uint, name string) ([]dto.UserClassInfoDTO, error)
-	RoleExists(uid uint, cid uint) (bool, error)
-	CreateUserRole(uid uint, cid uint, role string) error
+	GetClassMembers(ctx context.Context, cid uint, roles ...string) ([]dto.ClassMemberDTO, error)
+	GetClassUserInfo(ctx context.Context, uid uint, cid uint) (dto.ClassMemberDTO, error)
+	GetUserClasses(ctx context.Context, uid uint, page int, limit int, opts ClassUserQueryOptions) ([]dto.UserClassInfoDTO, error)
+	GetUserClassesByRole(ctx context.Context, uid uint, role string, page int, limit int) ([]dto.UserClassInfoDTO, error)
+	GetRole(ctx context.Context, uid uint, cid uint) (string, error)
+	UpdateUserRole(ctx context.Context, uid uint, cid uint, newRole string) error
+	UpdateUserName(ctx context.Context, uid uint, cid uint, newName string) error
+	ToggleFavorite(ctx context.Context, uid uint, cid uint) error
+	FindEnrolledClassIDs(ctx context.Context, uid uint, cids []uint) ([]uint, error)
+	BatchSetFavorite(ctx context.Context, uid uint, cids []uint, isFavorite bool) error
+	DeleteClassUser(ctx context.Context, uid uint, cid uint) error
+	Save(ctx context.Context, classUser *models.ClassUser) error
+	GetFavoriteClasses(ctx context.Context, uid uint, page int, limit int) ([]dto.UserClassInfoDTO, error)
+	IsAdmin(ctx context.Context, uid uint, cid uint) (bool, error)
+	IsMember(ctx context.Context, uid uint, cid uint) (bool, error)
+	CountActiveClasses(ctx context.Context, uid uint) (int64, error)
+	SearchUserClassesByName(ctx context.Context, uid uint, name string) ([]dto.UserClassInfoDTO, error)
+	RoleExists(ctx context.Context, uid uint, cid uint) (bool, error)
+	CreateUserRole(ctx context.Context, uid uint, cid uint, role string) (*models.ClassUser, bool, error)
+	UpdateJoinedViaCode(ctx context.Context, uid uint, cid uint, codeID uint) error
+	GetClassUsersByCodeID(ctx context.Context, codeID uint) ([]models.ClassUser, error)
+	GetClassMembersForExport(ctx context.Context, cid uint) ([]dto.ClassMemberExportDTO, error)
+	GetMemberActivityStats(ctx context.Context, cid uint) ([]dto.MemberActivityStatsDTO, error)
+	GetRemovedMembers(ctx context.Context, cid uint) ([]dto.RemovedMemberDTO, error)
+	RestoreClassUser(ctx context.Context, uid uint, cid uint) error
+	FindMembersByUIDs(ctx context.Context, cid uint, uids []uint) ([]models.ClassUser, error)
+	FindRemovedMember(ctx context.Context, uid uint, cid uint) (*models.ClassUser, error)
+	AddMember(ctx context.Context, classUser *models.ClassUser) error
+}
+
+// ClassUserQueryOptions ユーザーのクラス一覧を取得する際のオプション
+type ClassUserQueryOptions struct {
+	// IncludeArchived アーカイブされたクラスも含める
+	IncludeArchived bool
 }
 
 type classUserRepository struct {
@@ -38,9 +58,9 @@ func NewClassUserRepository(db *gorm.DB) ClassUserRepository {
 }
 
 // GetClassUserInfo はユーザーのクラスユーザー情報を取得します。
-func (r *classUserRepository) GetClassUserInfo(uid uint, cid uint) (dto.ClassMemberDTO, error) {
+func (r *classUserRepository) GetClassUserInfo(ctx context.Context, uid uint, cid uint) (dto.ClassMemberDTO, error) {
 	var classUser models.ClassUser
-	err := r.db.Joins("User").Where("class_users.uid = ? AND class_users.cid = ?", uid, cid).First(&classUser).Error
+	err := r.db.WithContext(ctx).Joins("User").Where("class_users.uid = ? AND class_users.cid = ?", uid, cid).First(&classUser).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return dto.ClassMemberDTO{}, errors.New(constants.UserNotFound)
@@ -50,14 +70,20 @@ func (r *classUserRepository) GetClassUserInfo(uid uint, cid uint) (dto.ClassMem
 	return toClassMemberDTO(classUser), nil
 }
 
-func (r *classUserRepository) GetUserClasses(uid uint, page int, limit int) ([]dto.UserClassInfoDTO, error) {
+// GetUserClasses はユーザーが参加しているクラスを取得します。アーカイブされたクラスはオプションで指定した場合のみ含めます。
+func (r *classUserRepository) GetUserClasses(ctx context.Context, uid uint, page int, limit int, opts ClassUserQueryOptions) ([]dto.UserClassInfoDTO, error) {
 	var userClassesInfo []dto.UserClassInfoDTO
 	offset := (page - 1) * limit
 
-	err := r.db.Table("classes").
-		Select("classes.id, classes.name, classes.limitation, classes.description, classes.image, class_users.is_favorite, class_users.role").
-		Joins("INNER JOIN class_users ON classes.id = class_users.cid").
-		Where("class_users.uid = ?", uid).
+	query := r.db.WithContext(ctx).Table("classes").
+		Select("classes.id, classes.name, classes.limitation, classes.description, classes.image, classes.is_archived, class_users.is_favorite, class_users.role").
+		Joins("INNER JOIN class_users ON classes.id = class_users.cid AND class_users.deleted_at IS NULL").
+		Where("class_users.uid = ? AND classes.deleted_at IS NULL", uid)
+	if !opts.IncludeArchived {
+		query = query.Where("classes.is_archived = ?", false)
+	}
+
+	err := query.
 		Offset(offset).
 		Limit(limit).
 		Scan(&userClassesInfo).Error
@@ -69,17 +95,17 @@ func (r *classUserRepository) GetUserClasses(uid uint, page int, limit int) ([]d
 	return userClassesInfo, nil
 }
 
-// GetClassMembers はクラスのメンバー情報を取得します。
-func (r *classUserRepository) GetClassMembers(cid uint, roles ...string) ([]dto.ClassMemberDTO, error) {
+// GetClassMembers はクラスのメンバー情報を取得します。rolesを指定した場合はいずれかのロールのメンバーのみを返します。
+func (r *classUserRepository) GetClassMembers(ctx context.Context, cid uint, roles ...string) ([]dto.ClassMemberDTO, error) {
 	var members []dto.ClassMemberDTO
 
-	query := r.db.Table("class_users").
+	query := r.db.WithContext(ctx).Model(&models.ClassUser{}).
 		Select("class_users.uid, class_users.nickname, class_users.role, users.image").
 		Joins("join users on class_users.uid = users.id").
 		Where("class_users.cid = ?", cid)
 
 	if len(roles) > 0 {
-		query = query.Where("class_users.role = ?", roles[0])
+		query = query.Where("class_users.role IN ?", roles)
 	}
 
 	if err := query.Scan(&members).Error; err != nil {
@@ -92,13 +118,13 @@ func (r *classUserRepository) GetClassMembers(cid uint, roles ...string) ([]dto.
 	return members, nil
 }
 
-func (r *classUserRepository) GetUserClassesByRole(uid uint, role string, page int, limit int) ([]dto.UserClassInfoDTO, error) {
+func (r *classUserRepository) GetUserClassesByRole(ctx context.Context, uid uint, role string, page int, limit int) ([]dto.UserClassInfoDTO, error) {
 	var userClassesInfo []dto.UserClassInfoDTO
 	offset := (page - 1) * limit
-	err := r.db.Table("classes").
+	err := r.db.WithContext(ctx).Table("classes").
 		Select("classes.id, classes.name, classes.limitation, classes.description, classes.image, class_users.is_favorite, class_users.role").
-		Joins("INNER JOIN class_users ON classes.id = class_users.cid").
-		Where("class_users.uid = ? AND class_users.role = ?", uid, role).
+		Joins("INNER JOIN class_users ON classes.id = class_users.cid AND class_users.deleted_at IS NULL").
+		Where("class_users.uid = ? AND class_users.role = ? AND classes.deleted_at IS NULL", uid, role).
 		Offset(offset).
 		Limit(limit).
 		Scan(&userClassesInfo).Error
@@ -111,9 +137,9 @@ func (r *classUserRepository) GetUserClassesByRole(uid uint, role string, page i
 }
 
 // GetRole はユーザーのロールを取得します。
-func (r *classUserRepository) GetRole(uid uint, cid uint) (string, error) {
+func (r *classUserRepository) GetRole(ctx context.Context, uid uint, cid uint) (string, error) {
 	var classUser models.ClassUser
-	result := r.db.Select("role").First(&classUser, "uid = ? AND cid = ?", uid, cid)
+	result := r.db.WithContext(ctx).Select("role").First(&classUser, "uid = ? AND cid = ?", uid, cid)
 
 	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
 		return "", result.Error
@@ -125,14 +151,14 @@ func (r *classUserRepository) GetRole(uid uint, cid uint) (string, error) {
 }
 
 // UpdateUserRole はユーザーのロールを更新します。
-func (r *classUserRepository) UpdateUserRole(uid uint, cid uint, newRole string) error {
-	return r.db.Model(&models.ClassUser{}).Where("uid = ? AND cid = ?", uid, cid).Update("role", newRole).Error
+func (r *classUserRepository) UpdateUserRole(ctx context.Context, uid uint, cid uint, newRole string) error {
+	return r.db.WithContext(ctx).Model(&models.ClassUser{}).Where("uid = ? AND cid = ?", uid, cid).Update("role", newRole).Error
 }
 
 // UpdateUserName はユーザーの名前を更新します。
-func (r *classUserRepository) UpdateUserName(uid uint, cid uint, newName string) error {
+func (r *classUserRepository) UpdateUserName(ctx context.Context, uid uint, cid uint, newName string) error {
 	var classUser models.ClassUser
-	result := r.db.First(&classUser, "uid = ? AND cid = ?", uid, cid)
+	result := r.db.WithContext(ctx).First(&classUser, "uid = ? AND cid = ?", uid, cid)
 
 	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
 		return result.Error
@@ -140,40 +166,57 @@ func (r *classUserRepository) UpdateUserName(uid uint, cid uint, newName string)
 		return result.Error
 	}
 
-	return r.db.Model(&classUser).Update("nickname", newName).Error
+	return r.db.WithContext(ctx).Model(&classUser).Update("nickname", newName).Error
 }
 
 func toClassMemberDTO(classUser models.ClassUser) dto.ClassMemberDTO {
 	return dto.ClassMemberDTO{
-		Uid:      classUser.UID,
-		Nickname: classUser.Nickname,
-		Role:     classUser.Role,
-		Image:    classUser.User.Image,
+		Uid:             classUser.UID,
+		Nickname:        classUser.Nickname,
+		Role:            classUser.Role,
+		Image:           classUser.User.Image,
+		JoinedViaCodeID: classUser.CodeID,
 	}
 }
 
-func (r *classUserRepository) ToggleFavorite(uid uint, cid uint) error {
+func (r *classUserRepository) ToggleFavorite(ctx context.Context, uid uint, cid uint) error {
 	var classUser models.ClassUser
-	err := r.db.Model(&classUser).Where("uid = ? AND cid = ?", uid, cid).UpdateColumn("is_favorite", gorm.Expr("NOT is_favorite")).Error
+	err := r.db.WithContext(ctx).Model(&classUser).Where("uid = ? AND cid = ?", uid, cid).UpdateColumn("is_favorite", gorm.Expr("NOT is_favorite")).Error
 	return err
 }
 
-func (r *classUserRepository) DeleteClassUser(uid uint, cid uint) error {
-	return r.db.Where("uid = ? AND cid = ?", uid, cid).Delete(&models.ClassUser{}).Error
+// FindEnrolledClassIDs 指定したクラスのうち、ユーザーが参加しているクラスのIDを返す
+func (r *classUserRepository) FindEnrolledClassIDs(ctx context.Context, uid uint, cids []uint) ([]uint, error) {
+	var enrolled []uint
+	err := r.db.WithContext(ctx).Model(&models.ClassUser{}).
+		Where("uid = ? AND cid IN ?", uid, cids).
+		Pluck("cid", &enrolled).Error
+	return enrolled, err
+}
+
+// BatchSetFavorite 指定したクラスのお気に入りを1つのUPDATE文で設定する
+func (r *classUserRepository) BatchSetFavorite(ctx context.Context, uid uint, cids []uint, isFavorite bool) error {
+	return r.db.WithContext(ctx).Model(&models.ClassUser{}).
+		Where("uid = ? AND cid IN ?", uid, cids).
+		UpdateColumn("is_favorite", isFavorite).Error
+}
+
+func (r *classUserRepository) DeleteClassUser(ctx context.Context, uid uint, cid uint) error {
+	return r.db.WithContext(ctx).Where("uid = ? AND cid = ?", uid, cid).Delete(&models.ClassUser{}).Error
 }
 
-func (r *classUserRepository) Save(classUser *models.ClassUser) error {
-	return r.db.Create(classUser).Error
+func (r *classUserRepository) Save(ctx context.Context, classUser *models.ClassUser) error {
+	return r.db.WithContext(ctx).Create(classUser).Error
 }
 
-func (r *classUserRepository) GetFavoriteClasses(uid uint, page int, limit int) ([]dto.UserClassInfoDTO, error) {
+func (r *classUserRepository) GetFavoriteClasses(ctx context.Context, uid uint, page int, limit int) ([]dto.UserClassInfoDTO, error) {
 	var favoriteClasses []dto.UserClassInfoDTO
 	offset := (page - 1) * limit
 
-	query := r.db.Table("classes").
+	query := r.db.WithContext(ctx).Table("classes").
 		Select("classes.id, classes.name, classes.description, classes.image, class_users.is_favorite").
-		Joins("join class_users on classes.id = class_users.cid").
-		Where("class_users.uid = ? AND class_users.is_favorite = ?", uid, true).
+		Joins("join class_users on classes.id = class_users.cid AND class_users.deleted_at IS NULL").
+		Where("class_users.uid = ? AND class_users.is_favorite = ? AND classes.deleted_at IS NULL", uid, true).
 		Offset(offset).
 		Limit(limit).
 		Scan(&favoriteClasses)
@@ -185,26 +228,39 @@ func (r *classUserRepository) GetFavoriteClasses(uid uint, page int, limit int)
 }
 
 // IsAdmin はユーザーが管理者かどうかを確認します。
-func (r *classUserRepository) IsAdmin(uid uint, cid uint) (bool, error) {
-	role, err := r.GetRole(uid, cid)
+func (r *classUserRepository) IsAdmin(ctx context.Context, uid uint, cid uint) (bool, error) {
+	role, err := r.GetRole(ctx, uid, cid)
 	if err != nil {
 		return false, err
 	}
 	return role == "ADMIN", nil
 }
 
-func (r *classUserRepository) IsMember(uid uint, cid uint) (bool, error) {
+// IsMember はユーザーがクラスの管理者・アシスタント・生徒のいずれかであるかを返します。申請中やブラックリストのユーザーは含みません。
+func (r *classUserRepository) IsMember(ctx context.Context, uid uint, cid uint) (bool, error) {
 	var count int64
-	r.db.Model(&models.ClassUser{}).Where("uid = ? AND cid = ?", uid, cid).Count(&count)
-	return count > 0, nil
+	err := r.db.WithContext(ctx).Model(&models.ClassUser{}).
+		Where("uid = ? AND cid = ? AND role IN ?", uid, cid, []string{"ADMIN", "ASSISTANT", "USER"}).
+		Count(&count).Error
+	return count > 0, err
 }
 
-func (r *classUserRepository) SearchUserClassesByName(uid uint, name string) ([]dto.UserClassInfoDTO, error) {
+// CountActiveClasses はユーザーが参加しているアーカイブされていないクラスの数を返します。
+func (r *classUserRepository) CountActiveClasses(ctx context.Context, uid uint) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.ClassUser{}).
+		Joins("join classes on classes.id = class_users.cid AND classes.deleted_at IS NULL").
+		Where("class_users.uid = ? AND classes.is_archived = ?", uid, false).
+		Count(&count).Error
+	return count, err
+}
+
+func (r *classUserRepository) SearchUserClassesByName(ctx context.Context, uid uint, name string) ([]dto.UserClassInfoDTO, error) {
 	var classes []dto.UserClassInfoDTO
-	err := r.db.Table("classes").
+	err := readOnly(r.db.WithContext(ctx)).Table("classes").
 		Select("classes.id, classes.name, class_users.role, class_users.is_favorite").
-		Joins("join class_users on classes.id = class_users.cid").
-		Where("class_users.uid = ? AND classes.name LIKE ?", uid, "%"+name+"%").
+		Joins("join class_users on classes.id = class_users.cid AND class_users.deleted_at IS NULL").
+		Where("class_users.uid = ? AND classes.name LIKE ? AND classes.deleted_at IS NULL", uid, "%"+name+"%").
 		Scan(&classes).Error
 
 	if err != nil {
@@ -213,17 +269,171 @@ func (r *classUserRepository) SearchUserClassesByName(uid uint, name string) ([]
 	return classes, nil
 }
 
-func (r *classUserRepository) RoleExists(uid uint, cid uint) (bool, error) {
+func (r *classUserRepository) RoleExists(ctx context.Context, uid uint, cid uint) (bool, error) {
 	var count int64
-	err := r.db.Model(&models.ClassUser{}).Where("uid = ? AND cid = ?", uid, cid).Count(&count).Error
+	err := r.db.WithContext(ctx).Model(&models.ClassUser{}).Where("uid = ? AND cid = ?", uid, cid).Count(&count).Error
 	return count > 0, err
 }
 
-func (r *classUserRepository) CreateUserRole(uid uint, cid uint, role string) error {
-	newUserRole := models.ClassUser{
-		UID:  uid,
-		CID:  cid,
-		Role: role,
+// CreateUserRole はユーザーをクラスに追加し、追加した場合はtrueを返します。
+// 既にメンバーの場合は既存の行をそのまま返し、同時に追加された場合も重複した行を作りません。
+func (r *classUserRepository) CreateUserRole(ctx context.Context, uid uint, cid uint, role string) (*models.ClassUser, bool, error) {
+	// 削除済みのメンバーが再参加する場合は論理削除された行を復元する
+	var removed models.ClassUser
+	err := r.db.WithContext(ctx).Unscoped().Where("uid = ? AND cid = ? AND deleted_at IS NOT NULL", uid, cid).First(&removed).Error
+	if err == nil {
+		if err := r.db.WithContext(ctx).Unscoped().Model(&removed).Updates(map[string]interface{}{"role": role, "deleted_at": nil}).Error; err != nil {
+			return nil, false, err
+		}
+		return &removed, true, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, false, err
+	}
+
+	var classUser models.ClassUser
+	err = r.db.WithContext(ctx).Where("uid = ? AND cid = ?", uid, cid).First(&classUser).Error
+	if err == nil {
+		return &classUser, false, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, false, err
+	}
+
+	classUser = models.ClassUser{UID: uid, CID: cid, Role: role}
+	result := r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&classUser)
+	if result.Error != nil {
+		return nil, false, result.Error
+	}
+	if result.RowsAffected > 0 {
+		return &classUser, true, nil
+	}
+	// 検索と作成の間に他のリクエストが追加した場合は、その行を読み直す
+	if err := r.db.WithContext(ctx).Where("uid = ? AND cid = ?", uid, cid).First(&classUser).Error; err != nil {
+		return nil, false, err
+	}
+	return &classUser, false, nil
+}
+
+// UpdateJoinedViaCode は参加時に使用したクラスコードを記録します。
+func (r *classUserRepository) UpdateJoinedViaCode(ctx context.Context, uid uint, cid uint, codeID uint) error {
+	return r.db.WithContext(ctx).Model(&models.ClassUser{}).Where("uid = ? AND cid = ?", uid, cid).Update("code_id", codeID).Error
+}
+
+// GetClassUsersByCodeID は指定されたクラスコードで参加したユーザーを取得します。
+func (r *classUserRepository) GetClassUsersByCodeID(ctx context.Context, codeID uint) ([]models.ClassUser, error) {
+	var classUsers []models.ClassUser
+	err := r.db.WithContext(ctx).Preload("User").Where("code_id = ?", codeID).Find(&classUsers).Error
+	return classUsers, err
+}
+
+// GetClassMembersForExport は名簿エクスポート用にメンバー情報と出席率を1回のクエリで取得します。
+func (r *classUserRepository) GetClassMembersForExport(ctx context.Context, cid uint) ([]dto.ClassMemberExportDTO, error) {
+	var members []dto.ClassMemberExportDTO
+
+	attendanceStats := r.db.WithContext(ctx).Table("attendances").
+		Select("uid, COUNT(CASE WHEN is_attendance = ? THEN 1 END) * 1.0 / NULLIF(COUNT(*), 0) AS attendance_rate", models.AttendanceStatus).
+		Where("cid = ?", cid).
+		Group("uid")
+
+	err := readOnly(r.db.WithContext(ctx)).Model(&models.ClassUser{}).
+		Select("class_users.uid, users.name, users.email, class_users.role, class_users.joined_at, COALESCE(attendance_stats.attendance_rate, 0) AS attendance_rate").
+		Joins("JOIN users ON users.id = class_users.uid").
+		Joins("LEFT JOIN (?) AS attendance_stats ON attendance_stats.uid = class_users.uid", attendanceStats).
+		Where("class_users.cid = ?", cid).
+		Order("class_users.uid").
+		Scan(&members).Error
+
+	if err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+// GetMemberActivityStats はメンバーごとの掲示板投稿数と出席率を取得します。
+func (r *classUserRepository) GetMemberActivityStats(ctx context.Context, cid uint) ([]dto.MemberActivityStatsDTO, error) {
+	var stats []dto.MemberActivityStatsDTO
+
+	boardStats := r.db.WithContext(ctx).Table("class_boards").
+		Select("uid, COUNT(*) AS board_posts").
+		Where("cid = ? AND deleted_at IS NULL", cid).
+		Group("uid")
+
+	attendanceStats := r.db.WithContext(ctx).Table("attendances").
+		Select("uid, COUNT(CASE WHEN is_attendance = ? THEN 1 END) * 1.0 / NULLIF(COUNT(*), 0) AS attendance_rate", models.AttendanceStatus).
+		Where("cid = ?", cid).
+		Group("uid")
+
+	err := readOnly(r.db.WithContext(ctx)).Model(&models.ClassUser{}).
+		Select("class_users.uid, class_users.nickname, COALESCE(board_stats.board_posts, 0) AS board_posts, COALESCE(attendance_stats.attendance_rate, 0) AS attendance_rate").
+		Joins("LEFT JOIN (?) AS board_stats ON board_stats.uid = class_users.uid", boardStats).
+		Joins("LEFT JOIN (?) AS attendance_stats ON attendance_stats.uid = class_users.uid", attendanceStats).
+		Where("class_users.cid = ? AND class_users.role IN ?", cid, []string{"USER", "ASSISTANT"}).
+		Scan(&stats).Error
+
+	if err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// GetRemovedMembers はクラスから削除されたメンバーを削除日時とともに取得します。
+func (r *classUserRepository) GetRemovedMembers(ctx context.Context, cid uint) ([]dto.RemovedMemberDTO, error) {
+	var members []dto.RemovedMemberDTO
+	err := r.db.WithContext(ctx).Unscoped().Model(&models.ClassUser{}).
+		Select("class_users.uid, class_users.nickname, class_users.role, users.image, class_users.deleted_at AS removed_at").
+		Joins("join users on class_users.uid = users.id").
+		Where("class_users.cid = ? AND class_users.deleted_at IS NOT NULL", cid).
+		Order("class_users.deleted_at DESC").
+		Scan(&members).Error
+
+	if err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+// RestoreClassUser は削除されたメンバーをクラスに復元します。
+func (r *classUserRepository) RestoreClassUser(ctx context.Context, uid uint, cid uint) error {
+	result := r.db.WithContext(ctx).Unscoped().Model(&models.ClassUser{}).
+		Where("uid = ? AND cid = ? AND deleted_at IS NOT NULL", uid, cid).
+		Update("deleted_at", nil)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// FindMembersByUIDs は指定されたユーザーのうち、クラスに所属しているメンバーを取得します。
+func (r *classUserRepository) FindMembersByUIDs(ctx context.Context, cid uint, uids []uint) ([]models.ClassUser, error) {
+	var classUsers []models.ClassUser
+	err := r.db.WithContext(ctx).Where("cid = ? AND uid IN ?", cid, uids).Find(&classUsers).Error
+	return classUsers, err
+}
+
+// FindRemovedMember はクラスから削除されたメンバーを取得します。削除されていない場合はgorm.ErrRecordNotFoundを返します。
+func (r *classUserRepository) FindRemovedMember(ctx context.Context, uid uint, cid uint) (*models.ClassUser, error) {
+	var classUser models.ClassUser
+	err := r.db.WithContext(ctx).Unscoped().Where("uid = ? AND cid = ? AND deleted_at IS NOT NULL", uid, cid).First(&classUser).Error
+	if err != nil {
+		return nil, err
+	}
+	return &classUser, nil
+}
+
+// AddMember はメンバーをクラスに追加します。削除済みのメンバーの場合は論理削除された行をロールとニックネームを更新して復元します。
+func (r *classUserRepository) AddMember(ctx context.Context, classUser *models.ClassUser) error {
+	result := r.db.WithContext(ctx).Unscoped().Model(&models.ClassUser{}).
+		Where("uid = ? AND cid = ? AND deleted_at IS NOT NULL", classUser.UID, classUser.CID).
+		Updates(map[string]interface{}{"role": classUser.Role, "nickname": classUser.Nickname, "deleted_at": nil})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		return nil
 	}
-	return r.db.Create(&newUserRole).Error
+	return r.db.WithContext(ctx).Create(classUser).Error
 }
@@ -0,0 +1,107 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CurriculumRepository カリキュラムの項目と生徒の進捗のリポジトリ
+type CurriculumRepository interface {
+	FindItemsByClass(ctx context.Context, cid uint) ([]models.CurriculumItem, error)
+	FindItemByID(ctx context.Context, id uint) (*models.CurriculumItem, error)
+	NextItemOrder(ctx context.Context, cid uint) (int, error)
+	CreateItem(ctx context.Context, item *models.CurriculumItem) error
+	UpdateItem(ctx context.Context, item *models.CurriculumItem) error
+	DeleteItem(ctx context.Context, id uint) error
+	SaveProgress(ctx context.Context, progress *models.UserProgress) error
+	DeleteProgress(ctx context.Context, uid uint, itemID uint) error
+	FindProgressByUser(ctx context.Context, cid uint, uid uint) ([]models.UserProgress, error)
+	FindProgressByClass(ctx context.Context, cid uint) ([]models.UserProgress, error)
+}
+
+// curriculumRepository CurriculumRepositoryを実装
+type curriculumRepository struct {
+	db *gorm.DB
+}
+
+// NewCurriculumRepository CurriculumRepositoryを生成
+func NewCurriculumRepository(db *gorm.DB) CurriculumRepository {
+	return &curriculumRepository{db: db}
+}
+
+// FindItemsByClass クラスのカリキュラムの項目を表示順に取得
+func (r *curriculumRepository) FindItemsByClass(ctx context.Context, cid uint) ([]models.CurriculumItem, error) {
+	var items []models.CurriculumItem
+	err := r.db.WithContext(ctx).Where("cid = ?", cid).Order("sort_order ASC, id ASC").Find(&items).Error
+	return items, err
+}
+
+// FindItemByID IDでカリキュラムの項目を取得
+func (r *curriculumRepository) FindItemByID(ctx context.Context, id uint) (*models.CurriculumItem, error) {
+	var item models.CurriculumItem
+	if err := r.db.WithContext(ctx).First(&item, id).Error; err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// NextItemOrder クラスの最後の項目の次の表示順を返す。項目が無い場合は1
+func (r *curriculumRepository) NextItemOrder(ctx context.Context, cid uint) (int, error) {
+	var order int
+	err := r.db.WithContext(ctx).Model(&models.CurriculumItem{}).
+		Select("COALESCE(MAX(sort_order), 0) + 1").
+		Where("cid = ?", cid).
+		Scan(&order).Error
+	return order, err
+}
+
+// CreateItem カリキュラムの項目を作成
+func (r *curriculumRepository) CreateItem(ctx context.Context, item *models.CurriculumItem) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Create(item).Error
+}
+
+// UpdateItem カリキュラムの項目を更新
+func (r *curriculumRepository) UpdateItem(ctx context.Context, item *models.CurriculumItem) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Save(item).Error
+}
+
+// DeleteItem カリキュラムの項目を削除する。生徒の進捗は外部キーで削除される
+func (r *curriculumRepository) DeleteItem(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&models.CurriculumItem{}, id).Error
+}
+
+// SaveProgress 項目の完了を記録する。完了済みの場合は最初に完了した日時を変更しない
+func (r *curriculumRepository) SaveProgress(ctx context.Context, progress *models.UserProgress) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "uid"}, {Name: "item_id"}},
+		DoNothing: true,
+	}).Create(progress).Error
+}
+
+// DeleteProgress 項目の完了の記録を削除
+func (r *curriculumRepository) DeleteProgress(ctx context.Context, uid uint, itemID uint) error {
+	return r.db.WithContext(ctx).Where("uid = ? AND item_id = ?", uid, itemID).Delete(&models.UserProgress{}).Error
+}
+
+// FindProgressByUser クラスの項目のうち、ユーザーが完了した記録を取得
+func (r *curriculumRepository) FindProgressByUser(ctx context.Context, cid uint, uid uint) ([]models.UserProgress, error) {
+	var progress []models.UserProgress
+	err := r.db.WithContext(ctx).
+		Joins("JOIN curriculum_items ON curriculum_items.id = user_progresses.item_id").
+		Where("curriculum_items.cid = ? AND user_progresses.uid = ?", cid, uid).
+		Find(&progress).Error
+	return progress, err
+}
+
+// FindProgressByClass クラスの項目の全てのユーザーの完了の記録を取得
+func (r *curriculumRepository) FindProgressByClass(ctx context.Context, cid uint) ([]models.UserProgress, error) {
+	var progress []models.UserProgress
+	err := r.db.WithContext(ctx).
+		Joins("JOIN curriculum_items ON curriculum_items.id = user_progresses.item_id").
+		Where("curriculum_items.cid = ?", cid).
+		Find(&progress).Error
+	return progress, err
+}
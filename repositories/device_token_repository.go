@@ -0,0 +1,55 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DeviceTokenRepository プッシュ通知を送信する端末のトークンのリポジトリ
+type DeviceTokenRepository interface {
+	SaveDeviceToken(ctx context.Context, token *models.DeviceToken) error
+	DeleteDeviceToken(ctx context.Context, uid uint, token string) error
+	FindDeviceTokensByUser(ctx context.Context, uid uint) ([]models.DeviceToken, error)
+	DeleteDeviceTokens(ctx context.Context, tokens []string) error
+}
+
+// deviceTokenRepository DeviceTokenRepositoryを実装
+type deviceTokenRepository struct {
+	db *gorm.DB
+}
+
+// NewDeviceTokenRepository DeviceTokenRepositoryを生成
+func NewDeviceTokenRepository(db *gorm.DB) DeviceTokenRepository {
+	return &deviceTokenRepository{db: db}
+}
+
+// SaveDeviceToken トークンを登録する。登録済みの場合は、別のユーザーのトークンでもユーザーと端末の種類、最終登録日時を上書きする
+func (r *deviceTokenRepository) SaveDeviceToken(ctx context.Context, token *models.DeviceToken) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "token"}},
+		DoUpdates: clause.AssignmentColumns([]string{"uid", "platform", "last_seen_at"}),
+	}).Create(token).Error
+}
+
+// DeleteDeviceToken ユーザーのトークンを削除する。他のユーザーのトークンは削除しない
+func (r *deviceTokenRepository) DeleteDeviceToken(ctx context.Context, uid uint, token string) error {
+	return r.db.WithContext(ctx).Where("uid = ? AND token = ?", uid, token).Delete(&models.DeviceToken{}).Error
+}
+
+// FindDeviceTokensByUser ユーザーのトークンを取得
+func (r *deviceTokenRepository) FindDeviceTokensByUser(ctx context.Context, uid uint) ([]models.DeviceToken, error) {
+	var tokens []models.DeviceToken
+	err := r.db.WithContext(ctx).Where("uid = ?", uid).Order("id").Find(&tokens).Error
+	return tokens, err
+}
+
+// DeleteDeviceTokens 無効になったトークンをまとめて削除
+func (r *deviceTokenRepository) DeleteDeviceTokens(ctx context.Context, tokens []string) error {
+	if len(tokens) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Where("token IN ?", tokens).Delete(&models.DeviceToken{}).Error
+}
@@ -0,0 +1,138 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DigestRepository 毎朝のダイジェストの設定と、ダイジェストに載せるクラスの活動の集計のリポジトリ。
+// 集計は受信者ごとではなく、受信者の参加するクラスをまとめて行う
+type DigestRepository interface {
+	FindDigestSetting(ctx context.Context, uid uint) (*models.DigestSetting, error)
+	SaveDigestSetting(ctx context.Context, setting *models.DigestSetting) error
+	FindDigestRecipients(ctx context.Context, afterUID uint, limit int) ([]models.DigestSetting, error)
+	FindDigestMemberships(ctx context.Context, uids []uint) ([]DigestMembership, error)
+	CountBoardsCreatedBetween(ctx context.Context, cids []uint, from, to time.Time) (map[uint]int64, error)
+	CountSchedulesChangedBetween(ctx context.Context, cids []uint, from, to time.Time) (map[uint]int64, error)
+	FindSchedulesStartingBetween(ctx context.Context, cids []uint, from, to time.Time) ([]models.ClassSchedule, error)
+}
+
+// DigestMembership ダイジェストの受信者が参加しているアーカイブされていないクラス
+type DigestMembership struct {
+	UID       uint
+	CID       uint
+	ClassName string
+}
+
+// digestClassCount クラスごとの件数の集計結果
+type digestClassCount struct {
+	CID   uint
+	Count int64
+}
+
+// digestRepository DigestRepositoryを実装
+type digestRepository struct {
+	db *gorm.DB
+}
+
+// NewDigestRepository DigestRepositoryを生成
+func NewDigestRepository(db *gorm.DB) DigestRepository {
+	return &digestRepository{db: db}
+}
+
+// FindDigestSetting ユーザーのダイジェストの設定を宛先のユーザーと合わせて取得する。保存していない場合はgorm.ErrRecordNotFoundを返す
+func (r *digestRepository) FindDigestSetting(ctx context.Context, uid uint) (*models.DigestSetting, error) {
+	var setting models.DigestSetting
+	if err := r.db.WithContext(ctx).Joins("User").Where("digest_settings.uid = ?", uid).First(&setting).Error; err != nil {
+		return nil, err
+	}
+	return &setting, nil
+}
+
+// SaveDigestSetting ユーザーのダイジェストの設定を保存する。保存済みの場合は上書きする
+func (r *digestRepository) SaveDigestSetting(ctx context.Context, setting *models.DigestSetting) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "uid"}},
+		DoUpdates: clause.AssignmentColumns([]string{"enabled", "email_enabled", "updated_at"}),
+	}).Create(setting).Error
+}
+
+// FindDigestRecipients ダイジェストを有効にしたユーザーを、afterUIDより大きいユーザーIDの順にlimit件取得する
+func (r *digestRepository) FindDigestRecipients(ctx context.Context, afterUID uint, limit int) ([]models.DigestSetting, error) {
+	var settings []models.DigestSetting
+	err := r.db.WithContext(ctx).Joins("User").
+		Where("digest_settings.enabled = ? AND digest_settings.uid > ?", true, afterUID).
+		Order("digest_settings.uid").Limit(limit).Find(&settings).Error
+	return settings, err
+}
+
+// FindDigestMemberships ユーザーが講師・アシスタント・生徒として参加している、アーカイブされていないクラスを取得する
+func (r *digestRepository) FindDigestMemberships(ctx context.Context, uids []uint) ([]DigestMembership, error) {
+	var memberships []DigestMembership
+	if len(uids) == 0 {
+		return memberships, nil
+	}
+	err := r.db.WithContext(ctx).Table("class_users").
+		Select("class_users.uid, class_users.cid, classes.name AS class_name").
+		Joins("INNER JOIN classes ON classes.id = class_users.cid AND classes.deleted_at IS NULL").
+		Where("class_users.uid IN ? AND class_users.role IN ? AND class_users.deleted_at IS NULL AND classes.is_archived = ?",
+			uids, []string{"ADMIN", "ASSISTANT", "USER"}, false).
+		Order("class_users.uid, class_users.cid").
+		Scan(&memberships).Error
+	return memberships, err
+}
+
+// CountBoardsCreatedBetween クラスごとに、期間内に投稿された掲示の件数を返す。投稿のないクラスは含まない
+func (r *digestRepository) CountBoardsCreatedBetween(ctx context.Context, cids []uint, from, to time.Time) (map[uint]int64, error) {
+	if len(cids) == 0 {
+		return map[uint]int64{}, nil
+	}
+	var counts []digestClassCount
+	err := r.db.WithContext(ctx).Model(&models.ClassBoard{}).
+		Select("cid, COUNT(*) AS count").
+		Where("cid IN ? AND created_at >= ? AND created_at < ?", cids, from, to).
+		Group("cid").
+		Scan(&counts).Error
+	return toDigestCountMap(counts), err
+}
+
+// CountSchedulesChangedBetween クラスごとに、期間内に作成・変更・休講・削除されたスケジュールの件数を返す。変更のないクラスは含まない
+func (r *digestRepository) CountSchedulesChangedBetween(ctx context.Context, cids []uint, from, to time.Time) (map[uint]int64, error) {
+	if len(cids) == 0 {
+		return map[uint]int64{}, nil
+	}
+	var counts []digestClassCount
+	err := r.db.WithContext(ctx).Unscoped().Model(&models.ClassSchedule{}).
+		Select("cid, COUNT(*) AS count").
+		Where("cid IN ?", cids).
+		Where(r.db.Where("updated_at >= ? AND updated_at < ?", from, to).Or("deleted_at >= ? AND deleted_at < ?", from, to)).
+		Group("cid").
+		Scan(&counts).Error
+	return toDigestCountMap(counts), err
+}
+
+// FindSchedulesStartingBetween 期間内に始まる休講ではないスケジュールを開始日時の順に取得する
+func (r *digestRepository) FindSchedulesStartingBetween(ctx context.Context, cids []uint, from, to time.Time) ([]models.ClassSchedule, error) {
+	var schedules []models.ClassSchedule
+	if len(cids) == 0 {
+		return schedules, nil
+	}
+	err := r.db.WithContext(ctx).
+		Where("cid IN ? AND started_at >= ? AND started_at < ? AND is_cancelled = ?", cids, from, to, false).
+		Order("started_at, id").
+		Find(&schedules).Error
+	return schedules, err
+}
+
+// toDigestCountMap 集計結果をクラスIDと件数の対応に変換する
+func toDigestCountMap(counts []digestClassCount) map[uint]int64 {
+	result := make(map[uint]int64, len(counts))
+	for _, count := range counts {
+		result[count.CID] = count.Count
+	}
+	return result
+}
@@ -1,6 +1,7 @@
 package repositories
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
@@ -9,8 +10,8 @@ import (
 )
 
 type GoogleAuthRepository interface {
-	UpdateOrCreateUser(userInput dto.UserInput) (models.User, error)
-	GetUserByID(id uint) (models.User, error)
+	UpdateOrCreateUser(ctx context.Context, userInput dto.UserInput) (models.User, error)
+	GetUserByID(ctx context.Context, id uint) (models.User, error)
 }
 
 type googleAuthRepository struct {
@@ -21,26 +22,37 @@ func NewGoogleAuthRepository(db *gorm.DB) GoogleAuthRepository {
 	return &googleAuthRepository{db: db}
 }
 
-func (repo *googleAuthRepository) UpdateOrCreateUser(userInput dto.UserInput) (models.User, error) {
+func (repo *googleAuthRepository) UpdateOrCreateUser(ctx context.Context, userInput dto.UserInput) (models.User, error) {
 	var user models.User
-	result := repo.db.Where("p_id = ?", fmt.Sprint(userInput.ID)).First(&user)
+	result := repo.db.WithContext(ctx).Where("p_id = ?", fmt.Sprint(userInput.ID)).First(&user)
 	if result.Error != nil && result.Error == gorm.ErrRecordNotFound {
 
 		pidPrefix := userInput.ID[:4]
 		uniqueName := fmt.Sprintf("%s#%s", userInput.Name, pidPrefix)
 
 		user = models.User{
-			PID:   fmt.Sprint(userInput.ID),
-			Name:  uniqueName,
-			Image: userInput.Picture,
+			PID:    fmt.Sprint(userInput.ID),
+			Name:   uniqueName,
+			Image:  userInput.Picture,
+			Email:  userInput.Email,
+			Locale: userInput.Locale,
 		}
-		result = repo.db.Create(&user)
+		result = repo.db.WithContext(ctx).Create(&user)
+	} else if result.Error == nil && user.Email == "" && userInput.Email != "" {
+		// メールアドレス取得前に登録されたユーザーは次回ログイン時に補完する
+		user.Email = userInput.Email
+		result = repo.db.WithContext(ctx).Model(&user).Update("email", userInput.Email)
+	}
+	if result.Error == nil && userInput.Locale != "" && user.Locale != userInput.Locale {
+		// Googleアカウントの言語を変更した場合はメールの言語も合わせる
+		user.Locale = userInput.Locale
+		result = repo.db.WithContext(ctx).Model(&user).Update("locale", userInput.Locale)
 	}
 	return user, result.Error
 }
 
-func (repo *googleAuthRepository) GetUserByID(id uint) (models.User, error) {
+func (repo *googleAuthRepository) GetUserByID(ctx context.Context, id uint) (models.User, error) {
 	var user models.User
-	result := repo.db.First(&user, id)
+	result := repo.db.WithContext(ctx).First(&user, id)
 	return user, result.Error
 }
@@ -0,0 +1,130 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// NotificationRepository インタフェース
+type NotificationRepository interface {
+	CreateNotification(ctx context.Context, notification *models.Notification) error
+	FindNotificationsByUser(ctx context.Context, uid uint, unreadOnly bool, beforeID uint, limit int) ([]models.Notification, error)
+	CountUnreadNotifications(ctx context.Context, uid uint) (int64, error)
+	MarkNotificationRead(ctx context.Context, uid uint, id uint, readAt time.Time) error
+	MarkAllNotificationsRead(ctx context.Context, uid uint, readAt time.Time) (int64, error)
+	DeleteReadNotificationsBefore(ctx context.Context, before time.Time) (int64, error)
+	CountResourceRecipients(ctx context.Context, resourceType string, resourceID uint) (int64, int64, error)
+	FindUnreadResourceRecipients(ctx context.Context, resourceType string, resourceID uint) ([]uint, error)
+	FindNotificationPreferences(ctx context.Context, uid uint) ([]models.NotificationPreference, error)
+	SaveNotificationPreference(ctx context.Context, preference *models.NotificationPreference) error
+}
+
+// notificationRepository アプリ内通知リポジトリ
+type notificationRepository struct {
+	db *gorm.DB
+}
+
+// NewNotificationRepository アプリ内通知リポジトリを生成
+func NewNotificationRepository(db *gorm.DB) NotificationRepository {
+	return &notificationRepository{db: db}
+}
+
+// CreateNotification 通知を保存
+func (repo *notificationRepository) CreateNotification(ctx context.Context, notification *models.Notification) error {
+	return repo.db.WithContext(ctx).Omit("User").Create(notification).Error
+}
+
+// FindNotificationsByUser ユーザーの通知を新しい順に取得する。beforeIDが0以外の場合はそのIDより前の通知のみ取得する
+func (repo *notificationRepository) FindNotificationsByUser(ctx context.Context, uid uint, unreadOnly bool, beforeID uint, limit int) ([]models.Notification, error) {
+	query := repo.db.WithContext(ctx).Where("uid = ?", uid)
+	if unreadOnly {
+		query = query.Where("read_at IS NULL")
+	}
+	if beforeID != 0 {
+		query = query.Where("id < ?", beforeID)
+	}
+
+	var notifications []models.Notification
+	err := query.Order("id DESC").Limit(limit).Find(&notifications).Error
+	return notifications, err
+}
+
+// CountUnreadNotifications ユーザーの未読の通知の件数を取得
+func (repo *notificationRepository) CountUnreadNotifications(ctx context.Context, uid uint) (int64, error) {
+	var count int64
+	err := repo.db.WithContext(ctx).Model(&models.Notification{}).Where("uid = ? AND read_at IS NULL", uid).Count(&count).Error
+	return count, err
+}
+
+// MarkNotificationRead ユーザーの通知を既読にする。既読の通知は既読にした日時を変更しない。
+// ユーザーの通知が無い場合はgorm.ErrRecordNotFoundを返す
+func (repo *notificationRepository) MarkNotificationRead(ctx context.Context, uid uint, id uint, readAt time.Time) error {
+	return repo.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var notification models.Notification
+		if err := tx.Where("id = ? AND uid = ?", id, uid).First(&notification).Error; err != nil {
+			return err
+		}
+		if notification.ReadAt != nil {
+			return nil
+		}
+		return tx.Model(&notification).Update("read_at", readAt).Error
+	})
+}
+
+// MarkAllNotificationsRead ユーザーの未読の通知を全て既読にし、既読にした件数を返す
+func (repo *notificationRepository) MarkAllNotificationsRead(ctx context.Context, uid uint, readAt time.Time) (int64, error) {
+	result := repo.db.WithContext(ctx).Model(&models.Notification{}).
+		Where("uid = ? AND read_at IS NULL", uid).
+		Update("read_at", readAt)
+	return result.RowsAffected, result.Error
+}
+
+// DeleteReadNotificationsBefore 指定日時より前に既読にした通知を削除し、削除件数を返す
+func (repo *notificationRepository) DeleteReadNotificationsBefore(ctx context.Context, before time.Time) (int64, error) {
+	result := repo.db.WithContext(ctx).Where("read_at < ?", before).Delete(&models.Notification{})
+	return result.RowsAffected, result.Error
+}
+
+// CountResourceRecipients 対象への通知を受け取ったユーザーの数と、そのうち1件以上を既読にしたユーザーの数を返す
+func (repo *notificationRepository) CountResourceRecipients(ctx context.Context, resourceType string, resourceID uint) (int64, int64, error) {
+	var counts struct {
+		Delivered int64
+		ReadCount int64
+	}
+	err := repo.db.WithContext(ctx).Model(&models.Notification{}).
+		Select("COUNT(DISTINCT uid) AS delivered, COUNT(DISTINCT CASE WHEN read_at IS NOT NULL THEN uid END) AS read_count").
+		Where("resource_type = ? AND resource_id = ?", resourceType, resourceID).
+		Scan(&counts).Error
+	return counts.Delivered, counts.ReadCount, err
+}
+
+// FindUnreadResourceRecipients 対象への通知を受け取り、1件も既読にしていないユーザーのIDを取得
+func (repo *notificationRepository) FindUnreadResourceRecipients(ctx context.Context, resourceType string, resourceID uint) ([]uint, error) {
+	var uids []uint
+	err := repo.db.WithContext(ctx).Model(&models.Notification{}).
+		Where("resource_type = ? AND resource_id = ?", resourceType, resourceID).
+		Group("uid").
+		Having("COUNT(read_at) = 0").
+		Order("uid").
+		Pluck("uid", &uids).Error
+	return uids, err
+}
+
+// FindNotificationPreferences ユーザーの通知の設定を取得する。設定していない種類は含まない
+func (repo *notificationRepository) FindNotificationPreferences(ctx context.Context, uid uint) ([]models.NotificationPreference, error) {
+	var preferences []models.NotificationPreference
+	err := repo.db.WithContext(ctx).Where("uid = ?", uid).Find(&preferences).Error
+	return preferences, err
+}
+
+// SaveNotificationPreference ユーザーの通知の種類の設定を保存する。設定済みの場合は上書きする
+func (repo *notificationRepository) SaveNotificationPreference(ctx context.Context, preference *models.NotificationPreference) error {
+	return repo.db.WithContext(ctx).Omit(clause.Associations).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "uid"}, {Name: "type"}},
+		DoUpdates: clause.AssignmentColumns([]string{"push_enabled", "updated_at"}),
+	}).Create(preference).Error
+}
@@ -0,0 +1,41 @@
+package repositories
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrStaleUpdate 楽観ロックで、読み込んだ後に他の更新が保存されていたレコードの更新
+var ErrStaleUpdate = errors.New("record has been updated by another request")
+
+// lockVersion 更新するレコードの行をロックし、現在のバージョンを返す。レコードが無い場合はgorm.ErrRecordNotFoundを返す
+func lockVersion(tx *gorm.DB, model interface{}, id uint) (uint, error) {
+	var versions []uint
+	err := tx.Model(model).Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).Pluck("version", &versions).Error
+	if err != nil {
+		return 0, err
+	}
+	if len(versions) == 0 {
+		return 0, gorm.ErrRecordNotFound
+	}
+	return versions[0], nil
+}
+
+// saveWithVersion 楽観ロックでレコードの全ての列を保存し、versionに新しいバージョンを設定する。
+// expectedが0以外で現在のバージョンと異なる場合は保存せずにErrStaleUpdateを返す。0の場合は従来どおり後勝ちで保存する。
+// 確認から保存までは行をロックするため、同時に同じバージョンで保存した場合も片方のみ成功する
+func saveWithVersion(db *gorm.DB, model interface{}, id uint, version *uint, expected uint) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		current, err := lockVersion(tx, model, id)
+		if err != nil {
+			return err
+		}
+		if expected != 0 && current != expected {
+			return ErrStaleUpdate
+		}
+		*version = current + 1
+		return tx.Omit(clause.Associations).Save(model).Error
+	})
+}
@@ -0,0 +1,110 @@
+package repositories
+
+import (
+	"context"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// readReplicaSetting 読み取り専用のクエリであることを示すステートメントの設定
+const readReplicaSetting = "read_replica:read_only"
+
+// primaryContextKey プライマリへの読み取りを強制するコンテキストのキー
+type primaryContextKey struct{}
+
+// WithPrimary 読み取り専用のクエリもプライマリで実行するコンテキストを返す。書き込み直後に同じデータを読む場合に使う
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryContextKey{}, true)
+}
+
+// readOnly クエリを読み取り専用とし、リードレプリカが使える場合はレプリカで実行させる。
+// 統計、エクスポート、検索など、書き込み直後に読まれない重いクエリにのみ指定する
+func readOnly(db *gorm.DB) *gorm.DB {
+	return db.Set(readReplicaSetting, true)
+}
+
+// ReadReplica readOnlyを指定したクエリをリードレプリカに振り分けるGORMのプラグイン。
+// 書き込み、トランザクション内のクエリ、WithPrimaryを指定したクエリはプライマリで実行し、
+// レプリカのヘルスチェックに失敗している間は全てのクエリをプライマリで実行する
+type ReadReplica struct {
+	replica gorm.ConnPool
+	ping    func(ctx context.Context) error
+	healthy atomic.Bool
+}
+
+// NewReadReplica replicaの接続にクエリを振り分けるReadReplicaを生成する。pingはヘルスチェックに使い、最初の確認までは正常とみなす
+func NewReadReplica(replica gorm.ConnPool, ping func(ctx context.Context) error) *ReadReplica {
+	r := &ReadReplica{replica: replica, ping: ping}
+	r.healthy.Store(true)
+	return r
+}
+
+// Name プラグイン名
+func (r *ReadReplica) Name() string {
+	return "read_replica"
+}
+
+// Initialize 読み取りのコールバックの前に接続を振り分ける処理を登録する
+func (r *ReadReplica) Initialize(db *gorm.DB) error {
+	if err := db.Callback().Query().Before("gorm:query").Register("read_replica:route_query", r.route); err != nil {
+		return err
+	}
+	return db.Callback().Row().Before("gorm:row").Register("read_replica:route_row", r.route)
+}
+
+// Healthy レプリカに振り分けてよい状態か
+func (r *ReadReplica) Healthy() bool {
+	return r.healthy.Load()
+}
+
+// CheckHealth レプリカに接続できるか確認し、結果を振り分けに反映する
+func (r *ReadReplica) CheckHealth(ctx context.Context) error {
+	err := r.ping(ctx)
+	if was := r.healthy.Swap(err == nil); was != (err == nil) {
+		if err != nil {
+			log.Printf("read replica is unhealthy, falling back to the primary: %v", err)
+		} else {
+			log.Println("read replica is healthy again")
+		}
+	}
+	return err
+}
+
+// StartHealthCheck ctxが終了するまでinterval毎にレプリカのヘルスチェックを行う
+func (r *ReadReplica) StartHealthCheck(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				pingCtx, cancel := context.WithTimeout(ctx, interval)
+				_ = r.CheckHealth(pingCtx)
+				cancel()
+			}
+		}
+	}()
+}
+
+// route readOnlyを指定したクエリの接続をレプリカに置き換える
+func (r *ReadReplica) route(db *gorm.DB) {
+	if readOnly, ok := db.Get(readReplicaSetting); !ok || readOnly != true {
+		return
+	}
+	if forced, _ := db.Statement.Context.Value(primaryContextKey{}).(bool); forced {
+		return
+	}
+	// トランザクション内では書き込みと同じ接続で読む
+	if _, inTx := db.Statement.ConnPool.(gorm.TxCommitter); inTx {
+		return
+	}
+	if !r.healthy.Load() {
+		return
+	}
+	db.Statement.ConnPool = r.replica
+}
@@ -1,12 +1,15 @@
 package repositories
 
 import (
+	"context"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
 	"gorm.io/gorm"
 )
 
 // RoleRepository はロールのリポジトリです。
 type RoleRepository interface {
-	FindByRoleName(roleName string) (string, error) // 변경된 메서드 시그니처
+	FindByRoleName(ctx context.Context, roleName string) (string, error) // 변경된 메서드 시그니처
 }
 
 // roleRepository はRoleRepositoryの実装です。
@@ -19,9 +22,9 @@ func NewRoleRepository(db *gorm.DB) RoleRepository {
 	return &roleRepository{db: db}
 }
 
-func (r *roleRepository) FindByRoleName(roleName string) (string, error) {
+func (r *roleRepository) FindByRoleName(ctx context.Context, roleName string) (string, error) {
 	var role string
-	result := r.db.Table("class_users").Select("role").Where("role = ?", roleName).Limit(1).Scan(&role)
+	result := r.db.WithContext(ctx).Model(&models.ClassUser{}).Select("role").Where("role = ?", roleName).Limit(1).Scan(&role)
 	if result.Error != nil {
 		return "", result.Error
 	}
@@ -0,0 +1,142 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// PendingSurvey 授業が終了し、まだ生徒に通知していない振り返りアンケート
+type PendingSurvey struct {
+	MaterialID    uint
+	CID           uint `gorm:"column:cid"`
+	Title         string
+	ScheduleTitle string
+}
+
+// SurveyReflectionRow 振り返りの自由記述と回答した生徒のニックネーム
+type SurveyReflectionRow struct {
+	UID           uint `gorm:"column:uid"`
+	Nickname      string
+	Understanding int
+	Reflection    string
+	UpdatedAt     time.Time
+}
+
+// ScheduleMaterialRepository スケジュールの資料と振り返りアンケートの回答のリポジトリ
+type ScheduleMaterialRepository interface {
+	Create(ctx context.Context, material *models.ScheduleMaterial) error
+	FindByID(ctx context.Context, id uint) (*models.ScheduleMaterial, error)
+	FindBySchedule(ctx context.Context, csid uint) ([]models.ScheduleMaterial, error)
+	Delete(ctx context.Context, id uint) error
+	FindPendingSurveys(ctx context.Context, endedBefore time.Time) ([]PendingSurvey, error)
+	ClaimSurveyNotification(ctx context.Context, id uint, notifiedAt time.Time) (bool, error)
+	SaveResponse(ctx context.Context, response *models.ScheduleSurveyResponse) error
+	CountResponsesByUnderstanding(ctx context.Context, materialID uint) (map[int]int64, error)
+	FindReflections(ctx context.Context, materialID uint) ([]SurveyReflectionRow, error)
+}
+
+// scheduleMaterialRepository ScheduleMaterialRepositoryを実装
+type scheduleMaterialRepository struct {
+	db *gorm.DB
+}
+
+// NewScheduleMaterialRepository ScheduleMaterialRepositoryを生成
+func NewScheduleMaterialRepository(db *gorm.DB) ScheduleMaterialRepository {
+	return &scheduleMaterialRepository{db: db}
+}
+
+// Create 資料を作成
+func (r *scheduleMaterialRepository) Create(ctx context.Context, material *models.ScheduleMaterial) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Create(material).Error
+}
+
+// FindByID IDで資料を取得
+func (r *scheduleMaterialRepository) FindByID(ctx context.Context, id uint) (*models.ScheduleMaterial, error) {
+	var material models.ScheduleMaterial
+	if err := r.db.WithContext(ctx).First(&material, id).Error; err != nil {
+		return nil, err
+	}
+	return &material, nil
+}
+
+// FindBySchedule スケジュールの資料を授業前、授業後の順に作成順で取得
+func (r *scheduleMaterialRepository) FindBySchedule(ctx context.Context, csid uint) ([]models.ScheduleMaterial, error) {
+	var materials []models.ScheduleMaterial
+	// PREはPOSTより辞書順で後になるため、降順で授業前の資料が先になる
+	err := r.db.WithContext(ctx).Where("csid = ?", csid).Order("phase DESC, id ASC").Find(&materials).Error
+	return materials, err
+}
+
+// Delete 資料を削除する。アンケートの回答は外部キーで削除される
+func (r *scheduleMaterialRepository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&models.ScheduleMaterial{}, id).Error
+}
+
+// FindPendingSurveys endedBeforeまでに終了した休講でないスケジュールの、生徒に通知していない振り返りアンケートを取得
+func (r *scheduleMaterialRepository) FindPendingSurveys(ctx context.Context, endedBefore time.Time) ([]PendingSurvey, error) {
+	var surveys []PendingSurvey
+	err := r.db.WithContext(ctx).Table("schedule_materials").
+		Select("schedule_materials.id AS material_id, class_schedules.cid, schedule_materials.title, class_schedules.title AS schedule_title").
+		Joins("JOIN class_schedules ON class_schedules.id = schedule_materials.csid AND class_schedules.deleted_at IS NULL").
+		Where("schedule_materials.phase = ? AND schedule_materials.survey_notified_at IS NULL", models.PostClassPhase).
+		Where("class_schedules.ended_at <= ? AND class_schedules.is_cancelled = ?", endedBefore, false).
+		Order("schedule_materials.id").
+		Scan(&surveys).Error
+	return surveys, err
+}
+
+// ClaimSurveyNotification 未通知のアンケートに通知した日時を記録し、記録できたかどうかを返す。
+// 通知済みの場合は更新しないため、複数のインスタンスが同じアンケートを取得しても1つだけが記録できる
+func (r *scheduleMaterialRepository) ClaimSurveyNotification(ctx context.Context, id uint, notifiedAt time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&models.ScheduleMaterial{}).
+		Where("id = ? AND survey_notified_at IS NULL", id).
+		Update("survey_notified_at", notifiedAt)
+	return result.RowsAffected == 1, result.Error
+}
+
+// SaveResponse アンケートの回答を保存する。回答済みの場合は理解度と振り返りを上書きする
+func (r *scheduleMaterialRepository) SaveResponse(ctx context.Context, response *models.ScheduleSurveyResponse) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "material_id"}, {Name: "uid"}},
+		DoUpdates: clause.AssignmentColumns([]string{"understanding", "reflection", "updated_at"}),
+	}).Create(response).Error
+}
+
+// CountResponsesByUnderstanding アンケートの回答数を理解度ごとに数える
+func (r *scheduleMaterialRepository) CountResponsesByUnderstanding(ctx context.Context, materialID uint) (map[int]int64, error) {
+	var rows []struct {
+		Understanding int
+		Count         int64
+	}
+	err := r.db.WithContext(ctx).Model(&models.ScheduleSurveyResponse{}).
+		Select("understanding, COUNT(*) AS count").
+		Where("material_id = ?", materialID).
+		Group("understanding").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[int]int64, len(rows))
+	for _, row := range rows {
+		counts[row.Understanding] = row.Count
+	}
+	return counts, nil
+}
+
+// FindReflections 振り返りを記入した回答を新しい順に、クラスでのニックネームとともに取得
+func (r *scheduleMaterialRepository) FindReflections(ctx context.Context, materialID uint) ([]SurveyReflectionRow, error) {
+	var rows []SurveyReflectionRow
+	err := r.db.WithContext(ctx).Table("schedule_survey_responses").
+		Select("schedule_survey_responses.uid, COALESCE(class_users.nickname, '') AS nickname, schedule_survey_responses.understanding, schedule_survey_responses.reflection, schedule_survey_responses.updated_at").
+		Joins("JOIN schedule_materials ON schedule_materials.id = schedule_survey_responses.material_id").
+		Joins("JOIN class_schedules ON class_schedules.id = schedule_materials.csid").
+		Joins("LEFT JOIN class_users ON class_users.cid = class_schedules.cid AND class_users.uid = schedule_survey_responses.uid").
+		Where("schedule_survey_responses.material_id = ? AND schedule_survey_responses.reflection <> ''", materialID).
+		Order("schedule_survey_responses.updated_at DESC, schedule_survey_responses.id DESC").
+		Scan(&rows).Error
+	return rows, err
+}
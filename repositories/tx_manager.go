@@ -0,0 +1,92 @@
+package repositories
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// RepositorySet はトランザクション内で使用するリポジトリの集合です。
+type RepositorySet struct {
+	Attendance    AttendanceRepository
+	Class         ClassRepository
+	ClassBoard    ClassBoardRepository
+	ClassCode     ClassCodeRepository
+	ClassSchedule ClassScheduleRepository
+	ClassUser     ClassUserRepository
+	User          UserRepository
+
+	// TxManager はこのトランザクションに紐づいたTxManagerです。入れ子の呼び出しは外側のトランザクションを再利用します。
+	TxManager TxManager
+	// AfterCommit は外側のトランザクションのコミット後に実行する処理を登録します。ロールバックした場合は実行しません。
+	// nilの場合はトランザクションの外のため、RunAfterCommitはすぐに実行します。
+	AfterCommit func(fn func())
+}
+
+// RunAfterCommit はfnをトランザクションのコミット後に実行します。トランザクションの外ではすぐに実行します。
+func (r RepositorySet) RunAfterCommit(fn func()) {
+	if r.AfterCommit == nil {
+		fn()
+		return
+	}
+	r.AfterCommit(fn)
+}
+
+// TxManager は複数のリポジトリにまたがる処理を1つのトランザクションで実行します。
+type TxManager interface {
+	WithinTransaction(ctx context.Context, fn func(repos RepositorySet) error) error
+}
+
+type txManager struct {
+	db *gorm.DB
+}
+
+// NewTxManager はTxManagerを生成します。
+func NewTxManager(db *gorm.DB) TxManager {
+	return &txManager{db: db}
+}
+
+// WithinTransaction はトランザクション用のリポジトリを生成してfnを実行します。
+// fnがエラーを返した場合はロールバックし、それ以外はコミットしてからAfterCommitで登録された処理を登録順に実行します。
+func (m *txManager) WithinTransaction(ctx context.Context, fn func(repos RepositorySet) error) error {
+	var committed []func()
+	err := m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(newRepositorySet(tx, func(hook func()) { committed = append(committed, hook) }))
+	})
+	if err != nil {
+		return err
+	}
+	for _, hook := range committed {
+		hook()
+	}
+	return nil
+}
+
+// nestedTxManager は既に開始されたトランザクションを再利用するTxManagerです。
+type nestedTxManager struct {
+	repos RepositorySet
+}
+
+// WithinTransaction は外側のトランザクションのリポジトリでfnを実行します。
+// コミットとロールバックは外側のトランザクションに任せます。
+func (m *nestedTxManager) WithinTransaction(_ context.Context, fn func(repos RepositorySet) error) error {
+	return fn(m.repos)
+}
+
+// newRepositorySet は指定されたDB接続を共有するリポジトリの集合を生成します。
+func newRepositorySet(tx *gorm.DB, afterCommit func(fn func())) RepositorySet {
+	repos := RepositorySet{
+		Attendance:    NewAttendanceRepository(tx),
+		Class:         NewClassRepository(tx),
+		ClassBoard:    NewClassBoardRepository(tx),
+		ClassCode:     NewClassCodeRepository(tx),
+		ClassSchedule: NewClassScheduleRepository(tx),
+		ClassUser:     NewClassUserRepository(tx),
+		User:          NewUserRepository(tx),
+		AfterCommit:   afterCommit,
+	}
+	nested := &nestedTxManager{}
+	repos.TxManager = nested
+	nested.repos = repos
+	return repos
+}
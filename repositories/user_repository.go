@@ -1,16 +1,27 @@
 package repositories
 
 import (
+	"context"
+	"strings"
+
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type UserRepository interface {
-	GetApplyingClasses(userID uint) ([]models.ClassUser, error)
-	UserExists(userID uint) (bool, error)
-	FindByName(name string) ([]models.User, error)
-	DeleteUser(userID uint) error
-	FindByID(userID uint) (*models.User, error)
+	GetApplyingClasses(ctx context.Context, userID uint) ([]models.ClassUser, error)
+	UserExists(ctx context.Context, userID uint) (bool, error)
+	FindByName(ctx context.Context, name string) ([]models.User, error)
+	DeleteUser(ctx context.Context, userID uint) error
+	FindByID(ctx context.Context, userID uint) (*models.User, error)
+	LockByID(ctx context.Context, userID uint) (*models.User, error)
+	GetUsersByIDs(ctx context.Context, userIDs []uint) ([]models.User, error)
+	FindByEmails(ctx context.Context, emails []string) ([]models.User, error)
+	FindAccessibilitySetting(ctx context.Context, userID uint) (*models.AccessibilitySetting, error)
+	SaveAccessibilitySetting(ctx context.Context, setting *models.AccessibilitySetting) error
+	IsEmailVerifiedByOther(ctx context.Context, userID uint, email string) (bool, error)
+	MarkEmailVerified(ctx context.Context, userID uint, email string) (bool, error)
 }
 
 type userRepository struct {
@@ -22,35 +33,117 @@ func NewUserRepository(db *gorm.DB) UserRepository {
 }
 
 // GetApplyingClasses はユーザーが申請中のクラスを取得します。
-func (r *userRepository) GetApplyingClasses(userID uint) ([]models.ClassUser, error) {
+func (r *userRepository) GetApplyingClasses(ctx context.Context, userID uint) ([]models.ClassUser, error) {
 	var classUsers []models.ClassUser
-	err := r.db.Preload("Class").Preload("User").Where("uid = ? AND role = ?", userID, "APPLICANT").Find(&classUsers).Error
+	err := r.db.WithContext(ctx).Preload("Class").Preload("User").Where("uid = ? AND role = ?", userID, "APPLICANT").Find(&classUsers).Error
 	return classUsers, err
 }
 
 // UserExists はユーザーが存在するかを確認します。
-func (r *userRepository) UserExists(userID uint) (bool, error) {
+func (r *userRepository) UserExists(ctx context.Context, userID uint) (bool, error) {
 	var count int64
-	err := r.db.Model(&models.User{}).Where("id = ?", userID).Count(&count).Error
+	err := r.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", userID).Count(&count).Error
 	return count > 0, err
 }
 
-func (r *userRepository) FindByName(name string) ([]models.User, error) {
+func (r *userRepository) FindByName(ctx context.Context, name string) ([]models.User, error) {
 	var users []models.User
-	err := r.db.Where("name LIKE ?", "%"+name+"%").Find(&users).Error
+	err := readOnly(r.db.WithContext(ctx)).Where("name LIKE ?", "%"+name+"%").Find(&users).Error
 	return users, err
 }
 
-func (r *userRepository) DeleteUser(userID uint) error {
-	err := r.db.Model(&models.User{}).Where("id = ?", userID).Delete(&models.User{}).Error
+func (r *userRepository) DeleteUser(ctx context.Context, userID uint) error {
+	err := r.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", userID).Delete(&models.User{}).Error
 	return err
 }
 
-func (r *userRepository) FindByID(userID uint) (*models.User, error) {
+func (r *userRepository) FindByID(ctx context.Context, userID uint) (*models.User, error) {
 	var user models.User
-	err := r.db.First(&user, userID).Error
+	err := r.db.WithContext(ctx).First(&user, userID).Error
 	if err != nil {
 		return nil, err
 	}
 	return &user, nil
 }
+
+// LockByID ユーザーの行をトランザクションの終了までロックして取得する。同じユーザーに対する確認と書き込みを順番に実行するために使う
+func (r *userRepository) LockByID(ctx context.Context, userID uint) (*models.User, error) {
+	var user models.User
+	err := r.db.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, userID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// GetUsersByIDs は指定したIDのユーザーの公開プロフィールの項目をまとめて取得します。存在しないIDは結果に含まれません。
+func (r *userRepository) GetUsersByIDs(ctx context.Context, userIDs []uint) ([]models.User, error) {
+	var users []models.User
+	if len(userIDs) == 0 {
+		return users, nil
+	}
+	err := r.db.WithContext(ctx).Select("id, name, image").Where("id IN ?", userIDs).Find(&users).Error
+	return users, err
+}
+
+// FindByEmails はメールアドレスが一致するユーザーを取得します。大文字と小文字は区別しません。
+func (r *userRepository) FindByEmails(ctx context.Context, emails []string) ([]models.User, error) {
+	var users []models.User
+	if len(emails) == 0 {
+		return users, nil
+	}
+	lowered := make([]string, 0, len(emails))
+	for _, email := range emails {
+		lowered = append(lowered, strings.ToLower(email))
+	}
+	err := r.db.WithContext(ctx).Where("LOWER(email) IN ?", lowered).Find(&users).Error
+	return users, err
+}
+
+// FindAccessibilitySetting はユーザーのアクセシビリティの設定を取得します。保存していない場合はgorm.ErrRecordNotFoundを返します。
+func (r *userRepository) FindAccessibilitySetting(ctx context.Context, userID uint) (*models.AccessibilitySetting, error) {
+	var setting models.AccessibilitySetting
+	if err := r.db.WithContext(ctx).Where("uid = ?", userID).First(&setting).Error; err != nil {
+		return nil, err
+	}
+	return &setting, nil
+}
+
+// SaveAccessibilitySetting はユーザーのアクセシビリティの設定を保存します。保存済みの場合は上書きします。
+func (r *userRepository) SaveAccessibilitySetting(ctx context.Context, setting *models.AccessibilitySetting) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "uid"}},
+		DoUpdates: clause.AssignmentColumns([]string{"font_size", "high_contrast", "reduce_motion", "updated_at"}),
+	}).Create(setting).Error
+}
+
+// IsEmailVerifiedByOther 他のユーザーがメールアドレスを検証済みかどうか。大文字と小文字は区別しない
+func (r *userRepository) IsEmailVerifiedByOther(ctx context.Context, userID uint, email string) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.User{}).
+		Where("LOWER(email) = LOWER(?) AND email_verified = ? AND id <> ?", email, true, userID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// MarkEmailVerified ユーザーのメールアドレスを検証済みのアドレスに変更し、変更した場合はtrueを返す。
+// 他のユーザーが検証済みのアドレスの場合は変更せずにfalseを返す。同時に検証した場合はユニークインデックスで1人のみが変更できる
+func (r *userRepository) MarkEmailVerified(ctx context.Context, userID uint, email string) (bool, error) {
+	taken, err := r.IsEmailVerifiedByOther(ctx, userID, email)
+	if err != nil || taken {
+		return false, err
+	}
+	result := r.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", userID).
+		Updates(map[string]interface{}{"email": email, "email_verified": true})
+	if result.Error != nil {
+		// ユニークインデックスの違反はドライバーごとにエラーが異なるため、検証済みのユーザーを確認し直す
+		if taken, err := r.IsEmailVerifiedByOther(ctx, userID, email); err == nil && taken {
+			return false, nil
+		}
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, gorm.ErrRecordNotFound
+	}
+	return true, nil
+}
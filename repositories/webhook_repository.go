@@ -0,0 +1,127 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// WebhookRepository クラスのWebhookと送信の記録のリポジトリ
+type WebhookRepository interface {
+	CreateWebhook(ctx context.Context, webhook *models.Webhook) error
+	UpdateWebhook(ctx context.Context, webhook *models.Webhook) error
+	DeleteWebhook(ctx context.Context, id uint) error
+	FindWebhook(ctx context.Context, id uint) (*models.Webhook, error)
+	FindWebhooksByClass(ctx context.Context, cid uint) ([]models.Webhook, error)
+	FindEnabledWebhooks(ctx context.Context, cid uint) ([]models.Webhook, error)
+	ResetWebhookFailures(ctx context.Context, id uint) error
+	IncrementWebhookFailures(ctx context.Context, id uint) (int, error)
+	DisableWebhook(ctx context.Context, id uint, at time.Time) (bool, error)
+	CreateDelivery(ctx context.Context, delivery *models.WebhookDelivery, keep int) error
+	FindDeliveries(ctx context.Context, webhookID uint, limit int) ([]models.WebhookDelivery, error)
+}
+
+// webhookRepository WebhookRepositoryを実装
+type webhookRepository struct {
+	db *gorm.DB
+}
+
+// NewWebhookRepository WebhookRepositoryを生成
+func NewWebhookRepository(db *gorm.DB) WebhookRepository {
+	return &webhookRepository{db: db}
+}
+
+// CreateWebhook Webhookを登録
+func (r *webhookRepository) CreateWebhook(ctx context.Context, webhook *models.Webhook) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Create(webhook).Error
+}
+
+// UpdateWebhook Webhookの送信先・秘密鍵・イベント・有効かどうかと失敗の件数を保存
+func (r *webhookRepository) UpdateWebhook(ctx context.Context, webhook *models.Webhook) error {
+	return r.db.WithContext(ctx).Model(webhook).
+		Select("URL", "Secret", "Events", "Enabled", "ConsecutiveFailures", "DisabledAt", "UpdatedAt").
+		Updates(webhook).Error
+}
+
+// DeleteWebhook Webhookを削除する。送信の記録も削除される
+func (r *webhookRepository) DeleteWebhook(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&models.Webhook{}, id).Error
+}
+
+// FindWebhook IDでWebhookを取得
+func (r *webhookRepository) FindWebhook(ctx context.Context, id uint) (*models.Webhook, error) {
+	var webhook models.Webhook
+	if err := r.db.WithContext(ctx).First(&webhook, id).Error; err != nil {
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+// FindWebhooksByClass クラスのWebhookを登録順に取得
+func (r *webhookRepository) FindWebhooksByClass(ctx context.Context, cid uint) ([]models.Webhook, error) {
+	var webhooks []models.Webhook
+	err := r.db.WithContext(ctx).Where("cid = ?", cid).Order("id").Find(&webhooks).Error
+	return webhooks, err
+}
+
+// FindEnabledWebhooks クラスの有効なWebhookを取得
+func (r *webhookRepository) FindEnabledWebhooks(ctx context.Context, cid uint) ([]models.Webhook, error) {
+	var webhooks []models.Webhook
+	err := r.db.WithContext(ctx).Where("cid = ? AND enabled = ?", cid, true).Order("id").Find(&webhooks).Error
+	return webhooks, err
+}
+
+// ResetWebhookFailures 連続の失敗の件数を0に戻す
+func (r *webhookRepository) ResetWebhookFailures(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Model(&models.Webhook{}).
+		Where("id = ? AND consecutive_failures <> 0", id).
+		Update("consecutive_failures", 0).Error
+}
+
+// IncrementWebhookFailures 連続の失敗の件数を1増やし、増やした後の件数を返す
+func (r *webhookRepository) IncrementWebhookFailures(ctx context.Context, id uint) (int, error) {
+	var failures int
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Webhook{}).Where("id = ?", id).
+			Update("consecutive_failures", gorm.Expr("consecutive_failures + 1"))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return tx.Model(&models.Webhook{}).Where("id = ?", id).Pluck("consecutive_failures", &failures).Error
+	})
+	return failures, err
+}
+
+// DisableWebhook 有効なWebhookを無効にする。無効にした場合はtrueを返し、既に無効の場合はfalseを返す
+func (r *webhookRepository) DisableWebhook(ctx context.Context, id uint, at time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&models.Webhook{}).
+		Where("id = ? AND enabled = ?", id, true).
+		Updates(map[string]interface{}{"enabled": false, "disabled_at": at})
+	return result.RowsAffected > 0, result.Error
+}
+
+// CreateDelivery 送信の記録を保存し、Webhookごとに新しいものからkeep件を超える古い記録を削除する
+func (r *webhookRepository) CreateDelivery(ctx context.Context, delivery *models.WebhookDelivery, keep int) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Create(delivery).Error; err != nil {
+			return err
+		}
+		recent := tx.Model(&models.WebhookDelivery{}).Select("id").
+			Where("webhook_id = ?", delivery.WebhookID).Order("id DESC").Limit(keep)
+		return tx.Where("webhook_id = ? AND id NOT IN (?)", delivery.WebhookID, recent).
+			Delete(&models.WebhookDelivery{}).Error
+	})
+}
+
+// FindDeliveries Webhookの送信の記録を新しい順に取得
+func (r *webhookRepository) FindDeliveries(ctx context.Context, webhookID uint, limit int) ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+	err := r.db.WithContext(ctx).Where("webhook_id = ?", webhookID).Order("id DESC").Limit(limit).Find(&deliveries).Error
+	return deliveries, err
+}
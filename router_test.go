@@ -0,0 +1,261 @@
+package main
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/app"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/config"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// expectedRoutes 登録されるべき全てのルート
+var expectedRoutes = []gin.RouteInfo{
+	{Method: "DELETE", Path: "/api/gin/at/:cid/schedule/:csid/reset"},
+	{Method: "DELETE", Path: "/api/gin/at/attendance/:id"},
+	{Method: "DELETE", Path: "/api/gin/cb"},
+	{Method: "DELETE", Path: "/api/gin/cb/:id"},
+	{Method: "DELETE", Path: "/api/gin/chat/dm/:senderId/:receiverId"},
+	{Method: "DELETE", Path: "/api/gin/chat/room/:scheduleId"},
+	{Method: "DELETE", Path: "/api/gin/cl/:uid/:cid"},
+	{Method: "DELETE", Path: "/api/gin/cs/:id"},
+	{Method: "DELETE", Path: "/api/gin/cu/:uid/:cid/remove"},
+	{Method: "DELETE", Path: "/api/gin/u/:userID/delete"},
+	{Method: "DELETE", Path: "/api/gin/uploads/:uploadId"},
+	{Method: "DELETE", Path: "/api/gin/v2/at/:cid/schedule/:csid/reset"},
+	{Method: "DELETE", Path: "/api/gin/v2/at/attendance/:id"},
+	{Method: "DELETE", Path: "/api/gin/v2/cu/:cid/members/:uid"},
+	{Method: "GET", Path: "/api/gin/admin/audit"},
+	{Method: "GET", Path: "/api/gin/at/:cid"},
+	{Method: "GET", Path: "/api/gin/at/:cid/streak/:uid"},
+	{Method: "GET", Path: "/api/gin/at/:cid/summary/by-mode"},
+	{Method: "GET", Path: "/api/gin/at/:cid/semesters"},
+	{Method: "PUT", Path: "/api/gin/at/:cid/semesters/:semester"},
+	{Method: "DELETE", Path: "/api/gin/at/:cid/semesters/:semester"},
+	{Method: "GET", Path: "/api/gin/at/:cid/semesters/:semester/grades/:uid"},
+	{Method: "GET", Path: "/api/gin/at/:cid/grade-scale"},
+	{Method: "PUT", Path: "/api/gin/at/:cid/grade-scale"},
+	{Method: "GET", Path: "/api/gin/at/attendance/:id"},
+	{Method: "GET", Path: "/api/gin/auth/google/login"},
+	{Method: "GET", Path: "/api/gin/calendar-sync"},
+	{Method: "PUT", Path: "/api/gin/calendar-sync"},
+	{Method: "GET", Path: "/api/gin/cb"},
+	{Method: "GET", Path: "/api/gin/cb/:id"},
+	{Method: "GET", Path: "/api/gin/cb/announced"},
+	{Method: "GET", Path: "/api/gin/cb/search"},
+	{Method: "GET", Path: "/api/gin/cb/subscribe"},
+	{Method: "GET", Path: "/api/gin/cb/:id/comments"},
+	{Method: "POST", Path: "/api/gin/cb/:id/comments"},
+	{Method: "DELETE", Path: "/api/gin/cb/:id/comments/:commentID"},
+	{Method: "GET", Path: "/api/gin/cb/:id/comments/:commentID/replies"},
+	{Method: "PUT", Path: "/api/gin/cb/:id/comments/:commentID/like"},
+	{Method: "DELETE", Path: "/api/gin/cb/:id/comments/:commentID/like"},
+	{Method: "GET", Path: "/api/gin/cc/checkSecretExists"},
+	{Method: "GET", Path: "/api/gin/cc/verifyAndRequestAccess"},
+	{Method: "GET", Path: "/api/gin/cc/verifyClassCode"},
+	{Method: "GET", Path: "/api/gin/chat/dm/:senderId/:receiverId"},
+	{Method: "GET", Path: "/api/gin/chat/messages/:roomid"},
+	{Method: "GET", Path: "/api/gin/chat/messages/:roomid/:messageId/translation"},
+	{Method: "GET", Path: "/api/gin/chat/room/:scheduleId/:userId"},
+	{Method: "GET", Path: "/api/gin/chat/stickers/:cid"},
+	{Method: "GET", Path: "/api/gin/chat/stream/:scheduleId"},
+	{Method: "GET", Path: "/api/gin/cl/:cid"},
+	{Method: "GET", Path: "/api/gin/cl/:cid/flyer.pdf"},
+	{Method: "GET", Path: "/api/gin/cl/:cid/stats"},
+	{Method: "GET", Path: "/api/gin/cl/:cid/timeline"},
+	{Method: "POST", Path: "/api/gin/cl/:cid/invitations"},
+	{Method: "GET", Path: "/api/gin/cl/:cid/events/stream"},
+	{Method: "GET", Path: "/api/gin/cl/subscriptions/:cid"},
+	{Method: "PUT", Path: "/api/gin/cl/subscriptions/:cid/:channel"},
+	{Method: "DELETE", Path: "/api/gin/cl/subscriptions/:cid/:channel"},
+	{Method: "GET", Path: "/api/gin/cl/:cid/members/export.csv"},
+	{Method: "GET", Path: "/api/gin/cl/:cid/preview"},
+	{Method: "GET", Path: "/api/gin/cl/public"},
+	{Method: "GET", Path: "/api/gin/cl/today"},
+	{Method: "GET", Path: "/api/gin/cs"},
+	{Method: "GET", Path: "/api/gin/cs/:id"},
+	{Method: "GET", Path: "/api/gin/cs/date"},
+	{Method: "GET", Path: "/api/gin/cs/export/class/:cid"},
+	{Method: "GET", Path: "/api/gin/cs/export/user/:uid"},
+	{Method: "GET", Path: "/api/gin/cs/export/user/:uid/subscription"},
+	{Method: "GET", Path: "/api/gin/cs/live"},
+	{Method: "GET", Path: "/api/gin/cs/:id/check-in-code"},
+	{Method: "GET", Path: "/api/gin/cs/:id/check-in-token"},
+	{Method: "GET", Path: "/api/gin/cs/:id/materials"},
+	{Method: "POST", Path: "/api/gin/cs/:id/materials"},
+	{Method: "DELETE", Path: "/api/gin/cs/:id/materials/:materialID"},
+	{Method: "PUT", Path: "/api/gin/cs/:id/materials/:materialID/response"},
+	{Method: "GET", Path: "/api/gin/cs/:id/materials/:materialID/summary"},
+	{Method: "POST", Path: "/api/gin/cs/:id/sync-calendar"},
+	{Method: "GET", Path: "/api/gin/cs/:id/notifications/stats"},
+	{Method: "POST", Path: "/api/gin/cs/:id/notifications/remind"},
+	{Method: "GET", Path: "/api/gin/cu/:uid/:cid/info"},
+	{Method: "GET", Path: "/api/gin/cu/:uid/classes"},
+	{Method: "GET", Path: "/api/gin/cu/:uid/classes/by-role"},
+	{Method: "GET", Path: "/api/gin/cu/:uid/classes/search"},
+	{Method: "GET", Path: "/api/gin/cu/:uid/favorite-classes"},
+	{Method: "GET", Path: "/api/gin/cu/class/:cid/activity-ranking"},
+	{Method: "GET", Path: "/api/gin/cu/class/:cid/members"},
+	{Method: "GET", Path: "/api/gin/cu/class/:cid/removed-members"},
+	{Method: "GET", Path: "/api/gin/events/replay"},
+	{Method: "GET", Path: "/api/gin/live/quality/:roomID"},
+	{Method: "GET", Path: "/api/gin/live/screen_share/:uid/:cid"},
+	{Method: "GET", Path: "/api/gin/live/speakers/:cid"},
+	{Method: "PUT", Path: "/api/gin/live/speakers/:cid/:uid"},
+	{Method: "DELETE", Path: "/api/gin/live/speakers/:cid/:uid"},
+	{Method: "GET", Path: "/api/gin/swagger/*any"},
+	{Method: "GET", Path: "/api/gin/u/:userID/applying-classes"},
+	{Method: "GET", Path: "/api/gin/u/accessibility"},
+	{Method: "GET", Path: "/api/gin/u/email"},
+	{Method: "POST", Path: "/api/gin/u/email/verification"},
+	{Method: "POST", Path: "/api/gin/u/email/verify"},
+	{Method: "GET", Path: "/api/gin/u/search"},
+	{Method: "GET", Path: "/api/gin/uploads/:uploadId"},
+	{Method: "GET", Path: "/api/gin/v2/at/:cid"},
+	{Method: "GET", Path: "/api/gin/v2/at/:cid/streak/:uid"},
+	{Method: "GET", Path: "/api/gin/v2/at/:cid/summary/by-mode"},
+	{Method: "GET", Path: "/api/gin/v2/at/:cid/semesters"},
+	{Method: "PUT", Path: "/api/gin/v2/at/:cid/semesters/:semester"},
+	{Method: "DELETE", Path: "/api/gin/v2/at/:cid/semesters/:semester"},
+	{Method: "GET", Path: "/api/gin/v2/at/:cid/semesters/:semester/grades/:uid"},
+	{Method: "GET", Path: "/api/gin/v2/at/:cid/grade-scale"},
+	{Method: "PUT", Path: "/api/gin/v2/at/:cid/grade-scale"},
+	{Method: "GET", Path: "/api/gin/v2/at/attendance/:id"},
+	{Method: "GET", Path: "/api/gin/v2/cu/:cid/dashboard"},
+	{Method: "GET", Path: "/api/gin/v2/cu/:cid/info"},
+	{Method: "GET", Path: "/api/gin/v2/cu/class/:cid/members"},
+	{Method: "GET", Path: "/api/gin/v2/cu/classes"},
+	{Method: "GET", Path: "/api/gin/v2/cu/classes/by-role"},
+	{Method: "GET", Path: "/api/gin/v2/cu/classes/search"},
+	{Method: "GET", Path: "/api/gin/v2/cu/favorite-classes"},
+	{Method: "GET", Path: "/debug/maintenance"},
+	{Method: "GET", Path: "/debug/pprof/"},
+	{Method: "GET", Path: "/debug/pprof/:name"},
+	{Method: "GET", Path: "/debug/pprof/cmdline"},
+	{Method: "GET", Path: "/debug/pprof/profile"},
+	{Method: "GET", Path: "/debug/pprof/symbol"},
+	{Method: "GET", Path: "/debug/pprof/trace"},
+	{Method: "GET", Path: "/debug/vars"},
+	{Method: "GET", Path: "/api/gin/unread"},
+	{Method: "POST", Path: "/api/gin/unread/:cid/read"},
+	{Method: "GET", Path: "/api/gin/notifications"},
+	{Method: "GET", Path: "/api/gin/notifications/unread-count"},
+	{Method: "PATCH", Path: "/api/gin/notifications/read-all"},
+	{Method: "PATCH", Path: "/api/gin/notifications/:id/read"},
+	{Method: "POST", Path: "/api/gin/notifications/devices"},
+	{Method: "DELETE", Path: "/api/gin/notifications/devices"},
+	{Method: "GET", Path: "/api/gin/notifications/preferences"},
+	{Method: "PUT", Path: "/api/gin/notifications/preferences"},
+	{Method: "GET", Path: "/api/gin/notifications/digest"},
+	{Method: "PUT", Path: "/api/gin/notifications/digest"},
+	{Method: "GET", Path: "/api/gin/curriculum/:cid/items"},
+	{Method: "POST", Path: "/api/gin/curriculum/:cid/items"},
+	{Method: "PATCH", Path: "/api/gin/curriculum/:cid/items/:itemID"},
+	{Method: "DELETE", Path: "/api/gin/curriculum/:cid/items/:itemID"},
+	{Method: "PUT", Path: "/api/gin/curriculum/:cid/items/:itemID/complete"},
+	{Method: "DELETE", Path: "/api/gin/curriculum/:cid/items/:itemID/complete"},
+	{Method: "GET", Path: "/api/gin/curriculum/:cid/progress"},
+	{Method: "GET", Path: "/api/gin/curriculum/:cid/progress/:uid"},
+	{Method: "GET", Path: "/api/gin/webhooks/:cid"},
+	{Method: "POST", Path: "/api/gin/webhooks/:cid"},
+	{Method: "PATCH", Path: "/api/gin/webhooks/:cid/:id"},
+	{Method: "DELETE", Path: "/api/gin/webhooks/:cid/:id"},
+	{Method: "GET", Path: "/api/gin/webhooks/:cid/:id/deliveries"},
+	{Method: "GET", Path: "/api/gin/integrations/:cid"},
+	{Method: "PUT", Path: "/api/gin/integrations/:cid"},
+	{Method: "DELETE", Path: "/api/gin/integrations/:cid"},
+	{Method: "GET", Path: "/api/gin/ws"},
+	{Method: "PATCH", Path: "/api/gin/admin/classes/:cid/archive-exempt"},
+	{Method: "POST", Path: "/api/gin/admin/classes/:cid/restore"},
+	{Method: "POST", Path: "/api/gin/admin/classes/:cid/schedules/:id/restore"},
+	{Method: "GET", Path: "/api/gin/admin/classes/:cid/access-restriction"},
+	{Method: "PUT", Path: "/api/gin/admin/classes/:cid/access-restriction"},
+	{Method: "PATCH", Path: "/api/gin/cb/:id/:cid/:uid"},
+	{Method: "PATCH", Path: "/api/gin/cb/:id/pin"},
+	{Method: "PATCH", Path: "/api/gin/cl/:uid/:cid"},
+	{Method: "PATCH", Path: "/api/gin/cs/:id"},
+	{Method: "PATCH", Path: "/api/gin/cs/:id/cancel"},
+	{Method: "PATCH", Path: "/api/gin/cs/:id/uncancel"},
+	{Method: "PATCH", Path: "/api/gin/cu/:uid/:cid/role/:roleName"},
+	{Method: "PATCH", Path: "/api/gin/cu/:uid/:cid/toggle-favorite"},
+	{Method: "PATCH", Path: "/api/gin/u/accessibility"},
+	{Method: "PATCH", Path: "/api/gin/v2/cu/:cid/members/:uid/role/:roleName"},
+	{Method: "PATCH", Path: "/api/gin/v2/cu/:cid/toggle-favorite"},
+	{Method: "POST", Path: "/api/gin/at"},
+	{Method: "POST", Path: "/api/gin/at/check-in"},
+	{Method: "POST", Path: "/api/gin/auth/google/process"},
+	{Method: "POST", Path: "/api/gin/auth/google/refresh-token"},
+	{Method: "POST", Path: "/api/gin/cb"},
+	{Method: "POST", Path: "/api/gin/chat/create-room/:scheduleId"},
+	{Method: "POST", Path: "/api/gin/chat/dm/:senderId/:receiverId"},
+	{Method: "POST", Path: "/api/gin/chat/room/:scheduleId"},
+	{Method: "POST", Path: "/api/gin/chat/stickers/:cid"},
+	{Method: "PUT", Path: "/api/gin/chat/drafts/:scheduleId"},
+	{Method: "GET", Path: "/api/gin/chat/drafts/:scheduleId"},
+	{Method: "DELETE", Path: "/api/gin/chat/drafts/:scheduleId"},
+	{Method: "POST", Path: "/api/gin/cl/create"},
+	{Method: "POST", Path: "/api/gin/cs"},
+	{Method: "POST", Path: "/api/gin/cu/class/:cid/members/:uid/restore"},
+	{Method: "POST", Path: "/api/gin/cu/members/move"},
+	{Method: "POST", Path: "/api/gin/live/quality/:roomID"},
+	{Method: "POST", Path: "/api/gin/uploads"},
+	{Method: "POST", Path: "/api/gin/uploads/:uploadId/complete"},
+	{Method: "POST", Path: "/api/gin/v2/at"},
+	{Method: "POST", Path: "/api/gin/v2/at/check-in"},
+	{Method: "POST", Path: "/api/gin/v2/cu/members/move"},
+	{Method: "POST", Path: "/debug/pprof/symbol"},
+	{Method: "PUT", Path: "/api/gin/cu/:uid/:cid/:rename"},
+	{Method: "PUT", Path: "/api/gin/cu/:uid/favorites"},
+	{Method: "PUT", Path: "/api/gin/uploads/:uploadId/parts/:partNumber"},
+	{Method: "PUT", Path: "/api/gin/v2/cu/:cid/rename"},
+	{Method: "PUT", Path: "/api/gin/v2/cu/favorites"},
+	{Method: "PUT", Path: "/debug/maintenance"},
+	{Method: "POST", Path: "/debug/digests/:uid/dry-run"},
+}
+
+// TestSetupRouter はコンテナからルーターを組み立てられ、期待する全てのルートだけが登録されることを確認するテストです。
+// 接続を確認しないDBとRedisを使うため、外部のサービスは不要です。
+func TestSetupRouter(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+
+	cfg := config.LoadForTest()
+	cfg.Debug = config.DebugConfig{Enabled: true, Token: "secret"}
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=127.0.0.1 port=1 sslmode=disable"}), &gorm.Config{DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	redisClient := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1"})
+	t.Cleanup(func() { _ = redisClient.Close() })
+
+	container := app.NewContainer(cfg, db, redisClient, mockUploader{}, services.NewJWTService(cfg.JWT.Secret))
+	router := setupRouter(container)
+
+	registered := map[string]bool{}
+	for _, route := range router.Routes() {
+		registered[route.Method+" "+route.Path] = true
+	}
+
+	want := map[string]bool{}
+	for _, route := range expectedRoutes {
+		key := route.Method + " " + route.Path
+		want[key] = true
+		if !registered[key] {
+			t.Errorf("route %s is not registered", key)
+		}
+	}
+
+	var unexpected []string
+	for key := range registered {
+		if !want[key] {
+			unexpected = append(unexpected, key)
+		}
+	}
+	sort.Strings(unexpected)
+	for _, key := range unexpected {
+		t.Errorf("route %s is registered but not expected", key)
+	}
+}
@@ -0,0 +1,135 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/utils"
+)
+
+// maxDeliveryFailures 連続して配信に失敗した場合に購読の配信を停止する回数
+// announcementDeliveryTimeout お知らせ1件を全ての購読者に配信する処理の上限時間
+const (
+	maxDeliveryFailures         = 5
+	announcementDeliveryTimeout = 2 * time.Minute
+)
+
+// AnnouncementSubscriptionService インタフェース
+type AnnouncementSubscriptionService interface {
+	Subscribe(ctx context.Context, uid uint, cid uint, channel models.NotificationChannel) (*models.AnnouncementSubscription, error)
+	Unsubscribe(ctx context.Context, uid uint, cid uint, channel models.NotificationChannel) error
+	ListSubscriptions(ctx context.Context, uid uint, cid uint) ([]models.AnnouncementSubscription, error)
+	DeliverAnnouncement(ctx context.Context, board models.ClassBoard)
+}
+
+// announcementSubscriptionService インタフェースを実装
+type announcementSubscriptionService struct {
+	repo          repositories.AnnouncementSubscriptionRepository
+	classUserRepo repositories.ClassUserRepository
+	userRepo      repositories.UserRepository
+	senders       map[models.NotificationChannel]utils.NotificationSender
+}
+
+// NewAnnouncementSubscriptionService AnnouncementSubscriptionServiceを生成。sendersに含まれないチャネルは登録できない
+func NewAnnouncementSubscriptionService(repo repositories.AnnouncementSubscriptionRepository, classUserRepo repositories.ClassUserRepository, userRepo repositories.UserRepository, senders map[models.NotificationChannel]utils.NotificationSender) AnnouncementSubscriptionService {
+	return &announcementSubscriptionService{
+		repo:          repo,
+		classUserRepo: classUserRepo,
+		userRepo:      userRepo,
+		senders:       senders,
+	}
+}
+
+// Subscribe クラスのお知らせを受け取る通知チャネルを登録する。登録済みの場合は送信先を置き換え、停止していた配信を再開する。
+// メールの送信先は指定させず、ユーザーの検証済みのメールアドレスにする。未検証の場合はErrEmailNotVerifiedを返す
+func (s *announcementSubscriptionService) Subscribe(ctx context.Context, uid uint, cid uint, channel models.NotificationChannel) (*models.AnnouncementSubscription, error) {
+	isMember, err := s.classUserRepo.IsMember(ctx, uid, cid)
+	if err != nil {
+		return nil, err
+	}
+	if !isMember {
+		return nil, ErrUnauthorized
+	}
+	if s.senders[channel] == nil {
+		return nil, ErrChannelUnavailable
+	}
+
+	user, err := s.userRepo.FindByID(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	if user.Email == "" || !user.EmailVerified {
+		return nil, ErrEmailNotVerified
+	}
+
+	subscription := &models.AnnouncementSubscription{UID: uid, CID: cid, Channel: channel, Target: user.Email}
+	if err := s.repo.Upsert(ctx, subscription); err != nil {
+		return nil, err
+	}
+	return subscription, nil
+}
+
+// Unsubscribe 通知チャネルの登録を解除する
+func (s *announcementSubscriptionService) Unsubscribe(ctx context.Context, uid uint, cid uint, channel models.NotificationChannel) error {
+	deleted, err := s.repo.Delete(ctx, uid, cid, channel)
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// ListSubscriptions ユーザーがクラスに登録した通知チャネルを取得
+func (s *announcementSubscriptionService) ListSubscriptions(ctx context.Context, uid uint, cid uint) ([]models.AnnouncementSubscription, error) {
+	return s.repo.FindByUserAndClass(ctx, uid, cid)
+}
+
+// DeliverAnnouncement お知らせをクラスの購読者に配信する。投稿者と、クラスのメンバーではなくなったユーザー、メールアドレスが未検証のユーザーには送信しない。
+// 失敗した購読は失敗回数を加算し、送信先が無効な場合や連続して失敗した場合は配信を停止する
+func (s *announcementSubscriptionService) DeliverAnnouncement(ctx context.Context, board models.ClassBoard) {
+	subscriptions, err := s.repo.FindActiveByClass(ctx, board.CID)
+	if err != nil {
+		utils.ReportBackgroundError("deliver_announcement", fmt.Errorf("failed to find subscriptions for class %d: %w", board.CID, err))
+		return
+	}
+
+	for _, subscription := range subscriptions {
+		if subscription.UID == board.UID {
+			continue
+		}
+		sender := s.senders[subscription.Channel]
+		if sender == nil {
+			continue
+		}
+
+		sendErr := sender.Send(ctx, subscription.Target, board.Title, board.Content)
+		if sendErr == nil {
+			if subscription.FailureCount > 0 {
+				s.updateDeliveryState(ctx, subscription, 0, nil)
+			}
+			continue
+		}
+
+		failureCount := subscription.FailureCount + 1
+		var disabledAt *time.Time
+		if errors.Is(sendErr, utils.ErrInvalidNotificationTarget) || failureCount >= maxDeliveryFailures {
+			now := time.Now()
+			disabledAt = &now
+		}
+		utils.ReportBackgroundError("deliver_announcement", fmt.Errorf("failed to deliver class board %d to subscription %d (%s): %w", board.ID, subscription.ID, subscription.Channel, sendErr))
+		s.updateDeliveryState(ctx, subscription, failureCount, disabledAt)
+	}
+}
+
+// updateDeliveryState 購読の配信状態を保存する
+func (s *announcementSubscriptionService) updateDeliveryState(ctx context.Context, subscription models.AnnouncementSubscription, failureCount int, disabledAt *time.Time) {
+	if err := s.repo.UpdateDeliveryState(ctx, subscription.ID, failureCount, disabledAt); err != nil {
+		utils.ReportBackgroundError("deliver_announcement", fmt.Errorf("failed to update delivery state of subscription %d: %w", subscription.ID, err))
+	}
+}
@@ -0,0 +1,211 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/utils"
+)
+
+const (
+	attachmentScanQueueSize   = 256
+	attachmentScanWorkerCount = 2
+	// attachmentScanMaxAttempts スキャンサービスに接続できない場合などに1つの添付画像をスキャンする最大の回数
+	attachmentScanMaxAttempts   = 3
+	attachmentScanStepTimeout   = 3 * time.Minute
+	defaultAttachmentRetryDelay = 30 * time.Second
+	// attachmentScanStaleAfter 添付してからこの時間が過ぎてもスキャン中の画像は、キューからの破棄や再起動でスキャンされなかったものとして予約し直す
+	attachmentScanStaleAfter = 15 * time.Minute
+	// attachmentScanSweepBatchSize 1回の見直しで予約し直す添付画像の上限
+	attachmentScanSweepBatchSize = 100
+)
+
+// AttachmentScanConfig 添付ファイルのウイルススキャンの設定
+type AttachmentScanConfig struct {
+	Scanner utils.VirusScanner
+	// RetryDelay スキャンに失敗した添付画像を最初に再スキャンするまでの時間。再スキャンするたびに2倍にする。0の場合は30秒
+	RetryDelay time.Duration
+}
+
+// AttachmentScanService アップロードされた掲示の添付画像を非同期でウイルススキャンする。
+// ウイルスを検出した画像は掲示から外してストレージから削除し、投稿者に通知する
+type AttachmentScanService interface {
+	ScanBoardImage(board models.ClassBoard)
+	RescanStalePending(ctx context.Context, now time.Time) (int, error)
+}
+
+// attachmentScanService インタフェースを実装
+type attachmentScanService struct {
+	repo     repositories.ClassBoardRepository
+	uploader utils.Uploader
+	notifier Notifier
+	config   AttachmentScanConfig
+	jobs     chan attachmentScanJob
+	// queued 予約済みまたはスキャン中の添付画像。見直しで同じ画像を重ねて予約しない
+	queued   map[attachmentScanKey]bool
+	queuedMu sync.Mutex
+}
+
+// attachmentScanKey スキャンする添付画像。画像を差し替えた場合は別の画像としてスキャンする
+type attachmentScanKey struct {
+	id    uint
+	image string
+}
+
+// attachmentScanJob 1つの添付画像の1回のスキャン
+type attachmentScanJob struct {
+	board   models.ClassBoard
+	attempt int
+}
+
+// NewAttachmentScanService AttachmentScanServiceを生成し、スキャンを行うワーカーを開始する。notifierがnilの場合は投稿者に通知しない
+func NewAttachmentScanService(repo repositories.ClassBoardRepository, uploader utils.Uploader, notifier Notifier, config AttachmentScanConfig) AttachmentScanService {
+	if config.RetryDelay == 0 {
+		config.RetryDelay = defaultAttachmentRetryDelay
+	}
+	s := &attachmentScanService{
+		repo:     repo,
+		uploader: uploader,
+		notifier: notifier,
+		config:   config,
+		jobs:     make(chan attachmentScanJob, attachmentScanQueueSize),
+		queued:   make(map[attachmentScanKey]bool),
+	}
+	for i := 0; i < attachmentScanWorkerCount; i++ {
+		go s.runWorker()
+	}
+	return s
+}
+
+// ScanBoardImage 掲示の添付画像のスキャンを予約する。キューが一杯の場合は破棄し、画像はスキャン中のままにする
+func (s *attachmentScanService) ScanBoardImage(board models.ClassBoard) {
+	s.schedule(board)
+}
+
+// RescanStalePending 添付してから時間が過ぎてもスキャン中の画像のスキャンを予約し直し、予約した数を返す
+func (s *attachmentScanService) RescanStalePending(ctx context.Context, now time.Time) (int, error) {
+	boards, err := s.repo.FindStalePendingScans(ctx, now.Add(-attachmentScanStaleAfter), attachmentScanSweepBatchSize)
+	if err != nil {
+		return 0, err
+	}
+	scheduled := 0
+	for _, board := range boards {
+		if s.schedule(board) {
+			scheduled++
+		}
+	}
+	return scheduled, nil
+}
+
+// schedule 予約済みでない添付画像のスキャンを予約し、予約したかどうかを返す
+func (s *attachmentScanService) schedule(board models.ClassBoard) bool {
+	if board.Image == "" {
+		return false
+	}
+	key := attachmentScanKey{id: board.ID, image: board.Image}
+	s.queuedMu.Lock()
+	if s.queued[key] {
+		s.queuedMu.Unlock()
+		return false
+	}
+	s.queued[key] = true
+	s.queuedMu.Unlock()
+	return s.enqueue(attachmentScanJob{board: board, attempt: 1})
+}
+
+// enqueue スキャンを予約する。キューが一杯の場合は破棄し、見直しで予約し直せるようにする
+func (s *attachmentScanService) enqueue(job attachmentScanJob) bool {
+	select {
+	case s.jobs <- job:
+		return true
+	default:
+		log.Printf("Attachment scan queue is full. Dropped image of class board %d", job.board.ID)
+		s.finish(job.board)
+		return false
+	}
+}
+
+// finish 添付画像のスキャンを終え、再び予約できるようにする
+func (s *attachmentScanService) finish(board models.ClassBoard) {
+	s.queuedMu.Lock()
+	delete(s.queued, attachmentScanKey{id: board.ID, image: board.Image})
+	s.queuedMu.Unlock()
+}
+
+// runWorker 予約されたスキャンを順に行う
+func (s *attachmentScanService) runWorker() {
+	for job := range s.jobs {
+		s.scan(job)
+	}
+}
+
+// scan ストレージから添付画像を読み込んでスキャンし、結果を保存する。失敗した場合は間隔を空けて再スキャンを予約する
+func (s *attachmentScanService) scan(job attachmentScanJob) {
+	board := job.board
+	var result utils.ScanResult
+	err := withAttachmentScanTimeout(func(ctx context.Context) error {
+		content, err := s.uploader.OpenObject(ctx, board.Image)
+		if err != nil {
+			return err
+		}
+		defer content.Close()
+		result, err = s.config.Scanner.Scan(ctx, content)
+		return err
+	})
+	if err != nil {
+		if job.attempt < attachmentScanMaxAttempts {
+			delay := s.config.RetryDelay << (job.attempt - 1)
+			job.attempt++
+			time.AfterFunc(delay, func() { s.enqueue(job) })
+			return
+		}
+		s.finish(board)
+		utils.ReportBackgroundError("scan_attachment", fmt.Errorf("failed to scan image of class board %d: %w", board.ID, err))
+		return
+	}
+
+	defer s.finish(board)
+	status := models.ScanClean
+	if result.Infected {
+		status = models.ScanInfected
+	}
+	err = withAttachmentScanTimeout(func(ctx context.Context) error {
+		updated, err := s.repo.UpdateScanStatus(ctx, board.ID, board.Image, status)
+		if err != nil || !updated || !result.Infected {
+			return err
+		}
+		log.Printf("Virus %s was found in image of class board %d", result.Signature, board.ID)
+		if err := s.uploader.DeleteObjects(ctx, []string{board.Image}); err != nil {
+			utils.ReportBackgroundError("scan_attachment", fmt.Errorf("failed to delete infected image of class board %d: %w", board.ID, err))
+		}
+		s.notifyInfected(ctx, board, result.Signature)
+		return nil
+	})
+	if err != nil {
+		utils.ReportBackgroundError("scan_attachment", fmt.Errorf("failed to save scan result of class board %d: %w", board.ID, err))
+	}
+}
+
+// notifyInfected 添付画像からウイルスが検出されたことを投稿者に知らせる
+func (s *attachmentScanService) notifyInfected(ctx context.Context, board models.ClassBoard, signature string) {
+	if s.notifier == nil {
+		return
+	}
+	title := "添付画像を削除しました"
+	body := fmt.Sprintf("掲示「%s」の添付画像からウイルス(%s)が検出されたため、画像を削除しました。端末のウイルス対策を確認してから、別のファイルを添付してください。", board.Title, signature)
+	if err := s.notifier.Notify(ctx, board.UID, board.CID, title, body); err != nil {
+		utils.ReportBackgroundError("scan_attachment", fmt.Errorf("failed to notify uid %d: %w", board.UID, err))
+	}
+}
+
+// withAttachmentScanTimeout スキャンの1つの処理にタイムアウトを設けて実行する
+func withAttachmentScanTimeout(fn func(ctx context.Context) error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), attachmentScanStepTimeout)
+	defer cancel()
+	return fn(ctx)
+}
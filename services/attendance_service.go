@@ -1,7 +1,11 @@
 package services
 
 import (
+	"context"
 	"errors"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
 	"gorm.io/gorm"
@@ -9,64 +13,390 @@ import (
 
 // AttendanceService インタフェース
 type AttendanceService interface {
-	CreateOrUpdateAttendance(cid uint, uid uint, csid uint, status string) error
-	GetAllAttendancesByCID(cid uint) ([]models.Attendance, error)
-	GetAttendanceByID(id string) ([]models.Attendance, error)
-	DeleteAttendance(id string) error
+	CreateOrUpdateAttendance(ctx context.Context, cid uint, uid uint, csid uint, status string, note *string, isNoteVisible *bool, actorUID uint, clientRecordedAt *time.Time, expectedVersion uint) error
+	RecordTokenCheckIn(ctx context.Context, schedule models.ClassSchedule, uid uint, clientRecordedAt *time.Time) (bool, error)
+	GetAllAttendancesByCID(ctx context.Context, cid uint, viewerUID uint, filter dto.AttendanceListFilter, page int, pageSize int) (*dto.AttendancePage, error)
+	ListAttendancesByCID(ctx context.Context, cid uint, viewerUID uint, filter dto.AttendanceListFilter) ([]models.Attendance, error)
+	GetAttendanceByID(ctx context.Context, id string, viewerUID uint) ([]models.Attendance, error)
+	DeleteAttendance(ctx context.Context, id string) error
+	ResetScheduleAttendances(ctx context.Context, uid uint, cid uint, csid uint) (int64, error)
+	GetAttendanceStreak(ctx context.Context, viewerUID uint, uid uint, cid uint, allowTardy bool) (*dto.AttendanceStreakDTO, error)
+	GetAttendanceSummaryByMode(ctx context.Context, viewerUID uint, cid uint) ([]dto.AttendanceModeSummaryDTO, error)
 }
 
+// AttendanceResetWindow 出席をリセットできるスケジュールの開始からの期間
+const AttendanceResetWindow = 7 * 24 * time.Hour
+
 // attendanceService インタフェースを実装
 type attendanceService struct {
-	repo repositories.AttendanceRepository
+	repo          repositories.AttendanceRepository
+	classUserRepo repositories.ClassUserRepository
+	scheduleRepo  repositories.ClassScheduleRepository
+	txManager     repositories.TxManager
+	// allowUnversioned バージョンを指定しない更新を後勝ちで受け付ける
+	allowUnversioned bool
+	// webhooks 出席の変更をクラスのWebhookに配信する。nilの場合は配信しない
+	webhooks WebhookPublisher
+	// events 出席の記録とリセットを他のサービスに配信する。nilの場合は配信しない
+	events EventPublisher
 }
 
-// NewAttendanceService AttendanceServiceを生成
-func NewAttendanceService(repo repositories.AttendanceRepository) AttendanceService {
+// NewAttendanceService AttendanceServiceを生成。allowUnversionedがfalseの場合、バージョンを指定しない既存の出席情報の更新はErrVersionRequiredとする
+func NewAttendanceService(repo repositories.AttendanceRepository, classUserRepo repositories.ClassUserRepository, scheduleRepo repositories.ClassScheduleRepository, txManager repositories.TxManager, allowUnversioned bool, webhooks WebhookPublisher, events EventPublisher) AttendanceService {
 	return &attendanceService{
-		repo: repo,
+		repo:             repo,
+		classUserRepo:    classUserRepo,
+		scheduleRepo:     scheduleRepo,
+		txManager:        txManager,
+		allowUnversioned: allowUnversioned,
+		webhooks:         webhooks,
+		events:           events,
 	}
 }
 
-// CreateOrUpdateAttendance 出席情報を作成または更新
-func (s *attendanceService) CreateOrUpdateAttendance(cid uint, uid uint, csid uint, status string) error {
-	attendance, err := s.repo.GetAttendanceByUIDAndCID(uid, cid)
+// CreateOrUpdateAttendance ユーザーのスケジュールの出席情報を作成または更新。記録時刻はサーバーの時刻とし、クライアントの時刻は参考値として保存する。
+// 記録元は操作したユーザー(actorUID)のロールから決め、記録できないユーザーの場合はErrUnauthorizedを返す。
+// 本人の出席は出席方式で確認コードによるチェックインが有効なスケジュールのみ記録でき、それ以外はErrCheckInMethodNotAllowedを返す。
+// expectedVersionを指定した場合、読み込んだ後に他の更新が保存されていれば最新の出席情報を持つStaleUpdateErrorを返す
+func (s *attendanceService) CreateOrUpdateAttendance(ctx context.Context, cid uint, uid uint, csid uint, status string, note *string, isNoteVisible *bool, actorUID uint, clientRecordedAt *time.Time, expectedVersion uint) error {
+	source, err := s.attendanceSource(ctx, actorUID, uid, cid)
+	if err != nil {
+		return err
+	}
+	if source == models.SelfSource {
+		if err := s.requireCheckInMethod(ctx, csid, models.CodeCheckIn); err != nil {
+			return err
+		}
+	}
+	recordedAt := time.Now()
+	attendance, err := s.repo.GetAttendanceByUIDAndCSID(ctx, uid, csid)
 	if err != nil {
 		// レコードが見つからない場合は新規作成
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			newAttendance := models.Attendance{
-				CID:          cid,
-				UID:          uid,
-				CSID:         csid,
-				IsAttendance: models.AttendanceType(status),
+				CID:              cid,
+				UID:              uid,
+				CSID:             csid,
+				IsAttendance:     models.AttendanceType(status),
+				Note:             note,
+				RecordedAt:       recordedAt,
+				ClientRecordedAt: clientRecordedAt,
+				Source:           source,
+			}
+			if isNoteVisible != nil {
+				newAttendance.IsNoteVisible = *isNoteVisible
+			}
+			if err := s.repo.CreateAttendance(ctx, &newAttendance); err != nil {
+				return err
 			}
-			return s.repo.CreateAttendance(&newAttendance)
+			s.publishAttendanceChanged(ctx, newAttendance, dto.AttendanceRecorded)
+			return nil
 		}
 		return err
 	}
 
 	// レコードが見つかった場合は更新
+	if expectedVersion == 0 && !s.allowUnversioned {
+		return ErrVersionRequired
+	}
 	attendance.IsAttendance = models.AttendanceType(status)
-	return s.repo.UpdateAttendance(attendance)
+	attendance.RecordedAt = recordedAt
+	attendance.ClientRecordedAt = clientRecordedAt
+	attendance.Source = source
+	if note != nil {
+		attendance.Note = note
+	}
+	if isNoteVisible != nil {
+		attendance.IsNoteVisible = *isNoteVisible
+	}
+	if err := s.repo.UpdateAttendance(ctx, attendance, expectedVersion); err != nil {
+		return staleUpdate(err, func() (interface{}, error) { return s.repo.GetAttendanceByUIDAndCSID(ctx, uid, csid) })
+	}
+	s.publishAttendanceChanged(ctx, *attendance, dto.AttendanceUpdated)
+	return nil
+}
+
+// attendanceSource 操作したユーザーのクラスでのロールから出席の記録元を決める。
+// 本人の出席は生徒のみSELFとして、他のユーザーの出席は講師とアシスタントのみTEACHERとして記録できる
+func (s *attendanceService) attendanceSource(ctx context.Context, actorUID uint, uid uint, cid uint) (models.AttendanceSource, error) {
+	role, err := s.classUserRepo.GetRole(ctx, actorUID, cid)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", ErrUnauthorized
+	} else if err != nil {
+		return "", err
+	}
+	switch {
+	case actorUID == uid && role == "USER":
+		return models.SelfSource, nil
+	case actorUID != uid && (role == "ADMIN" || role == "ASSISTANT"):
+		return models.TeacherSource, nil
+	}
+	return "", ErrUnauthorized
+}
+
+// requireCheckInMethod スケジュールの出席方式でチェックイン手段が有効か確認する。
+// スケジュールが無い場合はErrNotFound、手段が無効な場合はErrCheckInMethodNotAllowedを返す
+func (s *attendanceService) requireCheckInMethod(ctx context.Context, csid uint, method models.CheckInMethod) error {
+	schedule, err := s.scheduleRepo.GetClassScheduleByID(ctx, csid)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotFound
+	} else if err != nil {
+		return err
+	}
+	if !allowsCheckInMethod(attendanceModeOrDefault(schedule.AttendanceMode), method) {
+		return ErrCheckInMethodNotAllowed
+	}
+	return nil
+}
+
+// RecordTokenCheckIn 出席トークンで検証したスケジュールに本人の出席を記録し、記録したかどうかを返す。
+// 出席方式でQRコードによるチェックインが無効なスケジュールはErrCheckInMethodNotAllowedを返す。
+// 接続の回復後に再送されても重複して登録しないよう、既に出席情報がある場合は変更せずにfalseを返す
+func (s *attendanceService) RecordTokenCheckIn(ctx context.Context, schedule models.ClassSchedule, uid uint, clientRecordedAt *time.Time) (bool, error) {
+	if !allowsCheckInMethod(attendanceModeOrDefault(schedule.AttendanceMode), models.QRCheckIn) {
+		return false, ErrCheckInMethodNotAllowed
+	}
+	attendance := models.Attendance{
+		CID:              schedule.CID,
+		UID:              uid,
+		CSID:             schedule.ID,
+		IsAttendance:     models.AttendanceStatus,
+		RecordedAt:       time.Now(),
+		ClientRecordedAt: clientRecordedAt,
+		Source:           models.SelfSource,
+	}
+	created, err := s.repo.CreateAttendanceIfAbsent(ctx, &attendance)
+	if err != nil || !created {
+		return false, err
+	}
+	s.publishAttendanceChanged(ctx, attendance, dto.AttendanceRecorded)
+	return true, nil
+}
+
+// publishAttendanceChanged 出席の変更をクラスのWebhookに配信し、記録と更新は他のサービスにも配信する。講師コメントは含めない
+func (s *attendanceService) publishAttendanceChanged(ctx context.Context, attendance models.Attendance, action string) {
+	if s.events != nil && action != dto.AttendanceDeleted {
+		s.events.PublishEvent(ctx, dto.EventAttendanceRecorded, dto.EventAttendanceRecordedV1{
+			ID:         attendance.ID,
+			Action:     action,
+			CID:        attendance.CID,
+			CSID:       attendance.CSID,
+			UID:        attendance.UID,
+			Status:     string(attendance.IsAttendance),
+			RecordedAt: attendance.RecordedAt,
+		})
+	}
+	if s.webhooks == nil {
+		return
+	}
+	recordedAt := attendance.RecordedAt
+	s.webhooks.PublishWebhook(ctx, attendance.CID, models.AttendanceChangedWebhook, dto.WebhookPayloadDTO{Attendance: &dto.WebhookAttendanceV1{
+		ID:         attendance.ID,
+		Action:     action,
+		CSID:       attendance.CSID,
+		UID:        attendance.UID,
+		Status:     string(attendance.IsAttendance),
+		RecordedAt: &recordedAt,
+	}})
+}
+
+// GetAllAttendancesByCID CIDによって絞り込み条件に一致する出席情報をページごとに取得
+func (s *attendanceService) GetAllAttendancesByCID(ctx context.Context, cid uint, viewerUID uint, filter dto.AttendanceListFilter, page int, pageSize int) (*dto.AttendancePage, error) {
+	offset := (page - 1) * pageSize
+	attendances, total, err := s.repo.GetAllAttendancesByCID(ctx, cid, filter, pageSize, offset)
+	if err != nil {
+		return nil, err
+	}
+	s.hideInvisibleNotes(ctx, attendances, viewerUID)
+	if attendances == nil {
+		attendances = []models.Attendance{}
+	}
+
+	response := &dto.AttendancePage{Attendances: attendances, Total: total, Page: page, Limit: pageSize}
+	if int64(offset+len(attendances)) < total {
+		next := page + 1
+		response.NextPage = &next
+	}
+	return response, nil
 }
 
-// GetAllAttendancesByCID CIDによって全ての出席情報を取得
-func (s *attendanceService) GetAllAttendancesByCID(cid uint) ([]models.Attendance, error) {
-	return s.repo.GetAllAttendancesByCID(cid)
+// ListAttendancesByCID CIDによって絞り込み条件に一致するすべての出席情報を取得
+func (s *attendanceService) ListAttendancesByCID(ctx context.Context, cid uint, viewerUID uint, filter dto.AttendanceListFilter) ([]models.Attendance, error) {
+	attendances, _, err := s.repo.GetAllAttendancesByCID(ctx, cid, filter, -1, 0)
+	if err != nil {
+		return nil, err
+	}
+	s.hideInvisibleNotes(ctx, attendances, viewerUID)
+	return attendances, nil
 }
 
 // GetAttendanceByID IDによって出席情報を取得
-func (s *attendanceService) GetAttendanceByID(id string) ([]models.Attendance, error) {
-	attendances, err := s.repo.GetAttendanceByID(id)
+func (s *attendanceService) GetAttendanceByID(ctx context.Context, id string, viewerUID uint) ([]models.Attendance, error) {
+	attendances, err := s.repo.GetAttendanceByID(ctx, id)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, nil
 		}
 		return nil, err
 	}
+	s.hideInvisibleNotes(ctx, attendances, viewerUID)
 	return attendances, nil
 }
 
+// hideInvisibleNotes 講師・アシスタント以外には非公開の講師コメントを隠す
+func (s *attendanceService) hideInvisibleNotes(ctx context.Context, attendances []models.Attendance, viewerUID uint) {
+	canViewAll := make(map[uint]bool)
+	for i := range attendances {
+		if attendances[i].Note == nil || attendances[i].IsNoteVisible {
+			continue
+		}
+
+		cid := attendances[i].CID
+		allowed, checked := canViewAll[cid]
+		if !checked {
+			role, err := s.classUserRepo.GetRole(ctx, viewerUID, cid)
+			allowed = err == nil && (role == "ADMIN" || role == "ASSISTANT")
+			canViewAll[cid] = allowed
+		}
+		if !allowed {
+			attendances[i].Note = nil
+		}
+	}
+}
+
 // DeleteAttendance 出席情報を削除
-func (s *attendanceService) DeleteAttendance(id string) error {
-	return s.repo.DeleteAttendance(id)
+func (s *attendanceService) DeleteAttendance(ctx context.Context, id string) error {
+	// Webhookに配信するクラスとスケジュールは削除の前に取得する
+	var deleted *models.Attendance
+	if s.webhooks != nil {
+		deleted, _ = s.repo.FindAttendance(ctx, id)
+	}
+	if err := s.repo.DeleteAttendance(ctx, id); err != nil {
+		return err
+	}
+	if deleted != nil {
+		s.publishAttendanceChanged(ctx, *deleted, dto.AttendanceDeleted)
+	}
+	return nil
+}
+
+// ResetScheduleAttendances クラスの管理者がスケジュールの全ての出席情報を削除する
+// 出席記録を守るため、開始から7日以上経過したスケジュールはリセットできない
+func (s *attendanceService) ResetScheduleAttendances(ctx context.Context, uid uint, cid uint, csid uint) (int64, error) {
+	isAdmin, err := s.classUserRepo.IsAdmin(ctx, uid, cid)
+	if err != nil || !isAdmin {
+		return 0, ErrUnauthorized
+	}
+
+	var deleted int64
+	err = s.txManager.WithinTransaction(ctx, func(repos repositories.RepositorySet) error {
+		schedule, err := repos.ClassSchedule.GetClassScheduleByID(ctx, csid)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrNotFound
+			}
+			return err
+		}
+		if schedule.CID != cid {
+			return ErrNotFound
+		}
+		if schedule.StartedAt.Before(time.Now().Add(-AttendanceResetWindow)) {
+			return ErrScheduleTooOld
+		}
+
+		deleted, err = repos.Attendance.DeleteAllBySchedule(ctx, csid)
+		if err != nil {
+			return err
+		}
+		if deleted > 0 {
+			publishEventAfterCommit(ctx, repos, s.events, dto.EventAttendanceReset, dto.EventAttendanceResetV1{CID: cid, CSID: csid, Deleted: deleted})
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	if s.webhooks != nil && deleted > 0 {
+		s.webhooks.PublishWebhook(ctx, cid, models.AttendanceChangedWebhook, dto.WebhookPayloadDTO{Attendance: &dto.WebhookAttendanceV1{
+			Action: dto.AttendanceReset,
+			CSID:   csid,
+		}})
+	}
+	return deleted, nil
+}
+
+// GetAttendanceStreak ユーザーの現在と最長の連続出席数を返す。本人とクラスの講師・アシスタントのみ取得できる。
+// 欠席と出席の記録がない終了済みのスケジュールで連続は途切れ、allowTardyがtrueの場合は遅刻も出席として数える
+func (s *attendanceService) GetAttendanceStreak(ctx context.Context, viewerUID uint, uid uint, cid uint, allowTardy bool) (*dto.AttendanceStreakDTO, error) {
+	if viewerUID != uid {
+		role, err := s.classUserRepo.GetRole(ctx, viewerUID, cid)
+		if err != nil || (role != "ADMIN" && role != "ASSISTANT") {
+			return nil, ErrUnauthorized
+		}
+	}
+
+	now := time.Now()
+	timeline, err := s.repo.GetAttendanceTimeline(ctx, cid, uid, now)
+	if err != nil {
+		return nil, err
+	}
+
+	streak := &dto.AttendanceStreakDTO{UID: uid, CID: cid, AllowTardy: allowTardy}
+	for _, entry := range timeline {
+		if entry.Status == nil && entry.EndedAt.After(now) {
+			// 授業中でまだ出席が記録されていないスケジュールでは途切れさせない
+			continue
+		}
+		if entry.Status != nil && (*entry.Status == models.AttendanceStatus || (allowTardy && *entry.Status == models.TardyStatus)) {
+			streak.CurrentStreak++
+			if streak.CurrentStreak > streak.LongestStreak {
+				streak.LongestStreak = streak.CurrentStreak
+			}
+			continue
+		}
+		streak.CurrentStreak = 0
+	}
+	return streak, nil
+}
+
+// GetAttendanceSummaryByMode クラスの出席状況をスケジュールの出席方式ごとに集計する。クラスの講師・アシスタントのみ取得できる。
+// 記録がない方式も件数0で返す
+func (s *attendanceService) GetAttendanceSummaryByMode(ctx context.Context, viewerUID uint, cid uint) ([]dto.AttendanceModeSummaryDTO, error) {
+	role, err := s.classUserRepo.GetRole(ctx, viewerUID, cid)
+	if err != nil || (role != "ADMIN" && role != "ASSISTANT") {
+		return nil, ErrUnauthorized
+	}
+
+	counts, err := s.repo.CountByAttendanceMode(ctx, cid)
+	if err != nil {
+		return nil, err
+	}
+
+	summaries := make([]dto.AttendanceModeSummaryDTO, len(models.AttendanceModes))
+	index := make(map[models.AttendanceMode]int, len(models.AttendanceModes))
+	for i, mode := range models.AttendanceModes {
+		summaries[i].Mode = string(mode)
+		index[mode] = i
+	}
+	for _, count := range counts {
+		i, ok := index[count.Mode]
+		if !ok {
+			continue
+		}
+		switch count.Status {
+		case models.AttendanceStatus:
+			summaries[i].Attendance += count.Count
+		case models.TardyStatus:
+			summaries[i].Tardy += count.Count
+		case models.AbsenceStatus:
+			summaries[i].Absence += count.Count
+		}
+		summaries[i].Total += count.Count
+	}
+	for i := range summaries {
+		if summaries[i].Total > 0 {
+			summaries[i].AttendanceRate = float64(summaries[i].Attendance) / float64(summaries[i].Total) * 100
+		}
+	}
+	return summaries, nil
 }
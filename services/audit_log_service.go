@@ -0,0 +1,102 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/utils"
+)
+
+const (
+	// AuditLogRetention 監査ログの保存期間
+	AuditLogRetention = 180 * 24 * time.Hour
+
+	auditLogBufferSize    = 1024
+	auditLogFlushSize     = 100
+	auditLogFlushInterval = 2 * time.Second
+	auditLogWriteTimeout  = 10 * time.Second
+)
+
+// AuditLogService 監査ログの記録と取得を行う
+type AuditLogService interface {
+	Record(entry models.AuditLog)
+	GetClassAuditLogs(ctx context.Context, uid uint, cid uint, from, to time.Time, page, limit int) ([]models.AuditLog, error)
+	PurgeExpiredAuditLogs(ctx context.Context) (int64, error)
+}
+
+// auditLogService インタフェースを実装
+type auditLogService struct {
+	repo          repositories.AuditLogRepository
+	classUserRepo repositories.ClassUserRepository
+	entries       chan models.AuditLog
+}
+
+// NewAuditLogService AuditLogServiceを生成し、バックグラウンドで書き込みを開始する
+func NewAuditLogService(repo repositories.AuditLogRepository, classUserRepo repositories.ClassUserRepository) AuditLogService {
+	s := &auditLogService{
+		repo:          repo,
+		classUserRepo: classUserRepo,
+		entries:       make(chan models.AuditLog, auditLogBufferSize),
+	}
+	go s.run()
+	return s
+}
+
+// Record 監査ログを非同期で記録する。バッファが一杯の場合はリクエストを待たせずに破棄する
+func (s *auditLogService) Record(entry models.AuditLog) {
+	select {
+	case s.entries <- entry:
+	default:
+		log.Printf("Audit log buffer is full. Dropped entry: %s %s by uid %d", entry.Method, entry.Route, entry.ActorUID)
+	}
+}
+
+// run バッファされた監査ログを一定件数または一定間隔ごとにまとめて保存する
+func (s *auditLogService) run() {
+	ticker := time.NewTicker(auditLogFlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]models.AuditLog, 0, auditLogFlushSize)
+	for {
+		select {
+		case entry := <-s.entries:
+			batch = append(batch, entry)
+			if len(batch) >= auditLogFlushSize {
+				batch = s.flush(batch)
+			}
+		case <-ticker.C:
+			if len(batch) > 0 {
+				batch = s.flush(batch)
+			}
+		}
+	}
+}
+
+// flush 監査ログを保存し、再利用できる空のバッチを返す
+func (s *auditLogService) flush(batch []models.AuditLog) []models.AuditLog {
+	ctx, cancel := context.WithTimeout(context.Background(), auditLogWriteTimeout)
+	defer cancel()
+
+	if err := s.repo.CreateAuditLogs(ctx, batch); err != nil {
+		utils.ReportBackgroundError("write_audit_logs", fmt.Errorf("failed to write %d audit logs: %w", len(batch), err))
+	}
+	return batch[:0]
+}
+
+// GetClassAuditLogs クラスの管理者がクラスの監査ログを取得する
+func (s *auditLogService) GetClassAuditLogs(ctx context.Context, uid uint, cid uint, from, to time.Time, page, limit int) ([]models.AuditLog, error) {
+	role, err := s.classUserRepo.GetRole(ctx, uid, cid)
+	if err != nil || role != "ADMIN" {
+		return nil, ErrUnauthorized
+	}
+	return s.repo.FindAuditLogsByClass(ctx, cid, from, to, page, limit)
+}
+
+// PurgeExpiredAuditLogs 保存期間を過ぎた監査ログを削除する
+func (s *auditLogService) PurgeExpiredAuditLogs(ctx context.Context) (int64, error) {
+	return s.repo.DeleteAuditLogsBefore(ctx, time.Now().Add(-AuditLogRetention))
+}
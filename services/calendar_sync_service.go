@@ -0,0 +1,426 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/utils"
+	"golang.org/x/oauth2"
+	"gorm.io/gorm"
+)
+
+const (
+	calendarSyncQueueSize = 1024
+	// calendarSyncWorkerCount 同じスケジュールの作成と変更を並行して同期し、予定を重複して作成しないよう1つのワーカーで順に同期する
+	calendarSyncWorkerCount = 1
+	// calendarSyncMaxAttempts Googleに接続できない場合や5xx・429を返した場合に1つのスケジュールを同期する最大の回数
+	calendarSyncMaxAttempts       = 3
+	calendarSyncStepTimeout       = 30 * time.Second
+	defaultCalendarSyncRetryDelay = 30 * time.Second
+	// cancelledEventPrefix 休講のスケジュールの予定のタイトルの先頭に付ける文言
+	cancelledEventPrefix = "【休講】"
+)
+
+// CalendarSyncConfig Googleカレンダーへのスケジュールの同期の設定
+type CalendarSyncConfig struct {
+	// Enabled 同期を利用できる。falseの場合はログインでカレンダーの権限を求めず、同期もしない
+	Enabled bool
+	// OAuth アクセストークンの更新に使うGoogleのOAuthの設定
+	OAuth  *oauth2.Config
+	Client utils.GoogleCalendarClient
+	// AppURL 予定の説明に載せるリンクのアプリのURL。空の場合はリンクを載せない
+	AppURL string
+	// RetryDelay 同期に失敗したスケジュールを最初に再同期するまでの時間。再同期するたびに2倍にする。0の場合は30秒
+	RetryDelay time.Duration
+}
+
+// CalendarSyncPublisher スケジュールの変更を、クラスのスケジュールを同期しているユーザーのGoogleカレンダーに反映する。
+// 反映は非同期で行い、失敗してもスケジュールの変更は失敗させない
+type CalendarSyncPublisher interface {
+	SyncSchedule(cid uint, csid uint)
+}
+
+// CalendarSyncService ユーザーのGoogleカレンダーへのスケジュールの同期の設定と同期を行うサービス
+type CalendarSyncService interface {
+	CalendarSyncPublisher
+	Enabled() bool
+	Connect(ctx context.Context, uid uint, token *oauth2.Token) error
+	GetSettings(ctx context.Context, uid uint) (*dto.CalendarSyncSettingsDTO, error)
+	UpdateSettings(ctx context.Context, uid uint, request dto.CalendarSyncSettingsRequest) (*dto.CalendarSyncSettingsDTO, error)
+	SyncClass(ctx context.Context, uid uint, cid uint) (*dto.CalendarSyncResultDTO, error)
+}
+
+// calendarSyncService インタフェースを実装
+type calendarSyncService struct {
+	repo          repositories.CalendarSyncRepository
+	scheduleRepo  repositories.ClassScheduleRepository
+	classUserRepo repositories.ClassUserRepository
+	notifier      Notifier
+	config        CalendarSyncConfig
+	jobs          chan calendarSyncJob
+}
+
+// calendarSyncJob 1つのスケジュールの1回の同期
+type calendarSyncJob struct {
+	// uid 同期するユーザー。0の場合はクラスのスケジュールを同期している全てのユーザーの同期を予約する
+	uid     uint
+	cid     uint
+	csid    uint
+	attempt int
+}
+
+// NewCalendarSyncService CalendarSyncServiceを生成し、同期が有効な場合は同期を行うワーカーを開始する。
+// notifierがnilの場合は同期を停止したことをユーザーに通知しない
+func NewCalendarSyncService(repo repositories.CalendarSyncRepository, scheduleRepo repositories.ClassScheduleRepository, classUserRepo repositories.ClassUserRepository, notifier Notifier, config CalendarSyncConfig) CalendarSyncService {
+	if config.RetryDelay == 0 {
+		config.RetryDelay = defaultCalendarSyncRetryDelay
+	}
+	s := &calendarSyncService{
+		repo:          repo,
+		scheduleRepo:  scheduleRepo,
+		classUserRepo: classUserRepo,
+		notifier:      notifier,
+		config:        config,
+		jobs:          make(chan calendarSyncJob, calendarSyncQueueSize),
+	}
+	if config.Enabled {
+		for i := 0; i < calendarSyncWorkerCount; i++ {
+			go s.runWorker()
+		}
+	}
+	return s
+}
+
+// Enabled 同期を利用できるか。利用できない場合はログインでカレンダーの権限を求めない
+func (s *calendarSyncService) Enabled() bool {
+	return s.config.Enabled
+}
+
+// Connect Googleのログインで取得したトークンを保存し、同期を有効にする。カレンダーの権限を許可していない場合は何もしない
+func (s *calendarSyncService) Connect(ctx context.Context, uid uint, token *oauth2.Token) error {
+	if !s.config.Enabled || !utils.HasCalendarScope(token) {
+		return nil
+	}
+	sync := models.GoogleCalendarSync{
+		UID:          uid,
+		Enabled:      true,
+		AccessToken:  token.AccessToken,
+		RefreshToken: token.RefreshToken,
+	}
+	if !token.Expiry.IsZero() {
+		expiry := token.Expiry
+		sync.TokenExpiry = &expiry
+	}
+	return s.repo.SaveToken(ctx, &sync)
+}
+
+// GetSettings ユーザーの同期の設定を取得する。カレンダーの権限を許可していない場合は未接続の設定を返す
+func (s *calendarSyncService) GetSettings(ctx context.Context, uid uint) (*dto.CalendarSyncSettingsDTO, error) {
+	if !s.config.Enabled {
+		return nil, ErrCalendarSyncUnavailable
+	}
+	sync, err := s.repo.FindSync(ctx, uid)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &dto.CalendarSyncSettingsDTO{CIDs: []uint{}}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	cids, err := s.repo.FindSyncClassIDs(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	if cids == nil {
+		cids = []uint{}
+	}
+	return &dto.CalendarSyncSettingsDTO{
+		Connected:      sync.Connected(),
+		Enabled:        sync.Enabled,
+		CIDs:           cids,
+		DisabledReason: sync.DisabledReason,
+		DisabledAt:     sync.DisabledAt,
+	}, nil
+}
+
+// UpdateSettings 同期を有効にするかどうかと、同期するクラスを変更する。同期するクラスはユーザーが管理者のクラスのみ指定できる。
+// トークンの更新に失敗して停止した同期は、Googleに再度ログインするまで有効にできない
+func (s *calendarSyncService) UpdateSettings(ctx context.Context, uid uint, request dto.CalendarSyncSettingsRequest) (*dto.CalendarSyncSettingsDTO, error) {
+	if !s.config.Enabled {
+		return nil, ErrCalendarSyncUnavailable
+	}
+	enabled := request.Enabled != nil && *request.Enabled
+	sync, err := s.repo.FindSync(ctx, uid)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		if enabled {
+			return nil, ErrCalendarNotConnected
+		}
+		return s.GetSettings(ctx, uid)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if enabled && (!sync.Connected() || sync.DisabledReason != "") {
+		return nil, ErrCalendarNotConnected
+	}
+
+	cids := make([]uint, 0, len(request.CIDs))
+	seen := make(map[uint]bool, len(request.CIDs))
+	for _, cid := range request.CIDs {
+		if seen[cid] {
+			continue
+		}
+		seen[cid] = true
+		isAdmin, err := s.classUserRepo.IsAdmin(ctx, uid, cid)
+		if err != nil || !isAdmin {
+			return nil, ErrUnauthorized
+		}
+		cids = append(cids, cid)
+	}
+	if err := s.repo.UpdateSettings(ctx, uid, enabled, cids); err != nil {
+		return nil, err
+	}
+	return s.GetSettings(ctx, uid)
+}
+
+// SyncClass クラスの既存のスケジュールをユーザーのGoogleカレンダーに同期する。同期は非同期で行い、予約したスケジュールの数を返す
+func (s *calendarSyncService) SyncClass(ctx context.Context, uid uint, cid uint) (*dto.CalendarSyncResultDTO, error) {
+	if !s.config.Enabled {
+		return nil, ErrCalendarSyncUnavailable
+	}
+	role, err := s.classUserRepo.GetRole(ctx, uid, cid)
+	if err != nil || (role != "ADMIN" && role != "ASSISTANT" && role != "USER") {
+		return nil, ErrUnauthorized
+	}
+	sync, err := s.repo.FindSync(ctx, uid)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrCalendarNotConnected
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !sync.Enabled || !sync.Connected() {
+		return nil, ErrCalendarNotConnected
+	}
+	cids, err := s.repo.FindSyncClassIDs(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	synced := len(cids) == 0
+	for _, syncedCID := range cids {
+		synced = synced || syncedCID == cid
+	}
+	if !synced {
+		return nil, ErrCalendarClassNotSynced
+	}
+
+	schedules, err := s.scheduleRepo.GetAllClassSchedules(ctx, cid)
+	if err != nil {
+		return nil, err
+	}
+	result := &dto.CalendarSyncResultDTO{}
+	for _, schedule := range schedules {
+		if s.enqueue(calendarSyncJob{uid: uid, cid: cid, csid: schedule.ID, attempt: 1}) {
+			result.Queued++
+		}
+	}
+	return result, nil
+}
+
+// SyncSchedule スケジュールの同期を予約する。同期するユーザーの確認と同期は非同期で行い、キューが一杯の場合はリクエストを待たせずに破棄する
+func (s *calendarSyncService) SyncSchedule(cid uint, csid uint) {
+	if !s.config.Enabled {
+		return
+	}
+	s.enqueue(calendarSyncJob{cid: cid, csid: csid, attempt: 1})
+}
+
+// enqueue 同期を予約する。キューが一杯の場合は破棄してfalseを返す
+func (s *calendarSyncService) enqueue(job calendarSyncJob) bool {
+	select {
+	case s.jobs <- job:
+		return true
+	default:
+		log.Printf("Calendar sync queue is full. Dropped class schedule %d", job.csid)
+		return false
+	}
+}
+
+// runWorker 予約された同期を順に行う
+func (s *calendarSyncService) runWorker() {
+	for job := range s.jobs {
+		if job.uid == 0 {
+			s.dispatch(job)
+			continue
+		}
+		s.sync(job)
+	}
+}
+
+// dispatch クラスのスケジュールを同期している全てのユーザーの同期を予約する
+func (s *calendarSyncService) dispatch(job calendarSyncJob) {
+	var targets []models.GoogleCalendarSync
+	err := withCalendarSyncTimeout(func(ctx context.Context) (err error) {
+		targets, err = s.repo.FindSyncTargets(ctx, job.cid)
+		return err
+	})
+	if err != nil {
+		utils.ReportBackgroundError("sync_calendar", fmt.Errorf("failed to find calendar sync targets of class %d: %w", job.cid, err))
+		return
+	}
+	for _, target := range targets {
+		s.enqueue(calendarSyncJob{uid: target.UID, cid: job.cid, csid: job.csid, attempt: 1})
+	}
+}
+
+// sync スケジュールをユーザーのGoogleカレンダーの予定に1回反映する。トークンを更新できない場合は同期を停止してユーザーに通知し、
+// Googleに接続できない場合や5xx・429の場合は間隔を空けて再同期を予約する
+func (s *calendarSyncService) sync(job calendarSyncJob) {
+	var sync *models.GoogleCalendarSync
+	err := withCalendarSyncTimeout(func(ctx context.Context) (err error) {
+		sync, err = s.repo.FindSync(ctx, job.uid)
+		return err
+	})
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.ReportBackgroundError("sync_calendar", fmt.Errorf("failed to find calendar sync of uid %d: %w", job.uid, err))
+		}
+		return
+	}
+	if !sync.Enabled || !sync.Connected() {
+		return
+	}
+
+	token := &oauth2.Token{AccessToken: sync.AccessToken, RefreshToken: sync.RefreshToken}
+	if sync.TokenExpiry != nil {
+		token.Expiry = *sync.TokenExpiry
+	}
+	err = withCalendarSyncTimeout(func(ctx context.Context) error {
+		tokens := s.config.OAuth.TokenSource(ctx, token)
+		if err := s.syncEvent(ctx, oauth2.NewClient(ctx, tokens), job); err != nil {
+			return err
+		}
+		s.saveRefreshedToken(ctx, job.uid, token, tokens)
+		return nil
+	})
+
+	var retrieveErr *oauth2.RetrieveError
+	var apiErr *utils.CalendarAPIError
+	switch {
+	case err == nil:
+	case errors.As(err, &retrieveErr) && retrieveErr.Response != nil &&
+		(retrieveErr.Response.StatusCode == http.StatusBadRequest || retrieveErr.Response.StatusCode == http.StatusUnauthorized):
+		s.disable(job, err)
+	case errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusUnauthorized:
+		s.disable(job, err)
+	case errors.As(err, &apiErr) && !apiErr.Retryable():
+		utils.ReportBackgroundError("sync_calendar", fmt.Errorf("failed to sync class schedule %d to calendar of uid %d: %w", job.csid, job.uid, err))
+	case job.attempt < calendarSyncMaxAttempts:
+		delay := s.config.RetryDelay << (job.attempt - 1)
+		job.attempt++
+		time.AfterFunc(delay, func() { s.enqueue(job) })
+	default:
+		utils.ReportBackgroundError("sync_calendar", fmt.Errorf("failed to sync class schedule %d to calendar of uid %d: %w", job.csid, job.uid, err))
+	}
+}
+
+// syncEvent スケジュールに対応する予定を作成・変更する。削除されたスケジュールの場合は予定を削除する
+func (s *calendarSyncService) syncEvent(ctx context.Context, client *http.Client, job calendarSyncJob) error {
+	schedule, err := s.scheduleRepo.GetClassScheduleByID(ctx, job.csid)
+	deleted := errors.Is(err, gorm.ErrRecordNotFound)
+	if err != nil && !deleted {
+		return err
+	}
+	var eventID string
+	link, err := s.repo.FindEventLink(ctx, job.uid, job.csid)
+	if err == nil {
+		eventID = link.EventID
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	if deleted {
+		if eventID == "" {
+			return nil
+		}
+		if err := s.config.Client.DeleteEvent(ctx, client, eventID); err != nil {
+			return err
+		}
+		return s.repo.DeleteEventLink(ctx, job.uid, job.csid)
+	}
+	syncedID, err := s.config.Client.UpsertEvent(ctx, client, eventID, s.calendarEvent(schedule))
+	if err != nil {
+		return err
+	}
+	if syncedID == eventID {
+		return nil
+	}
+	return s.repo.SaveEventLink(ctx, &models.CalendarEventLink{UID: job.uid, CSID: job.csid, EventID: syncedID})
+}
+
+// calendarEvent スケジュールをGoogleカレンダーの予定に変換する。休講の場合はタイトルで知らせ、空き時間として扱う
+func (s *calendarSyncService) calendarEvent(schedule *models.ClassSchedule) utils.CalendarEvent {
+	summary := schedule.Title
+	if schedule.Class.Name != "" {
+		summary = fmt.Sprintf("[%s] %s", schedule.Class.Name, schedule.Title)
+	}
+	if schedule.IsCancelled {
+		summary = cancelledEventPrefix + summary
+	}
+	var description string
+	if s.config.AppURL != "" {
+		description = strings.TrimRight(s.config.AppURL, "/") + fmt.Sprintf("/classes/%d/schedules/%d", schedule.CID, schedule.ID)
+	}
+	return utils.CalendarEvent{
+		Summary:     summary,
+		Description: description,
+		Location:    schedule.Location,
+		Start:       schedule.StartedAt,
+		End:         schedule.EndedAt,
+		Transparent: schedule.IsCancelled,
+	}
+}
+
+// saveRefreshedToken 同期の途中でアクセストークンを更新した場合に保存する。保存できなくても次回の同期で再度更新する
+func (s *calendarSyncService) saveRefreshedToken(ctx context.Context, uid uint, before *oauth2.Token, tokens oauth2.TokenSource) {
+	current, err := tokens.Token()
+	if err != nil || current.AccessToken == before.AccessToken {
+		return
+	}
+	if err := s.repo.UpdateAccessToken(ctx, uid, current.AccessToken, current.Expiry); err != nil {
+		log.Printf("Failed to save refreshed google token of uid %d: %v", uid, err)
+	}
+}
+
+// disable トークンを更新できないユーザーの同期を停止し、再度ログインするよう通知する
+func (s *calendarSyncService) disable(job calendarSyncJob, cause error) {
+	log.Printf("Calendar sync of uid %d was disabled: %v", job.uid, cause)
+	err := withCalendarSyncTimeout(func(ctx context.Context) error {
+		if err := s.repo.DisableSync(ctx, job.uid, truncateWebhookError(cause.Error()), time.Now()); err != nil {
+			return err
+		}
+		if s.notifier == nil {
+			return nil
+		}
+		title := "Googleカレンダーの同期を停止しました"
+		body := "Googleカレンダーへのアクセスが取り消されたか期限が切れたため、スケジュールの同期を停止しました。再開するには、カレンダーの権限を許可してGoogleに再度ログインしてください。"
+		return s.notifier.Notify(ctx, job.uid, job.cid, title, body)
+	})
+	if err != nil {
+		utils.ReportBackgroundError("sync_calendar", fmt.Errorf("failed to disable calendar sync of uid %d: %w", job.uid, err))
+	}
+}
+
+// withCalendarSyncTimeout 同期の1つの処理にタイムアウトを設けて実行する
+func withCalendarSyncTimeout(fn func(ctx context.Context) error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), calendarSyncStepTimeout)
+	defer cancel()
+	return fn(ctx)
+}
@@ -4,12 +4,29 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
 	"github.com/dustin/go-broadcast"
 	"github.com/go-redis/redis/v8"
 	"log"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
 )
 
+const (
+	// chatDraftKey ルームとユーザーごとの下書きのキー
+	chatDraftKey = "chat_draft:%s:%s"
+	// chatDraftTTL 下書きを保持する期間。保存するたびに延長する
+	chatDraftTTL = 24 * time.Hour
+	// chatSequenceKey ルームごとのメッセージIDの採番のキー。履歴を削除してもIDが戻らないよう履歴とは別に保持する
+	chatSequenceKey = "chat_seq:%s"
+	// chatSequenceTTL 採番のキーを保持する期間。投稿するたびに延長し、使われなくなったルームのキーを残さない
+	chatSequenceTTL = 7 * 24 * time.Hour
+	// chatReplayLimit Last-Event-IDによる再接続時に再送するメッセージの上限
+	chatReplayLimit = 200
+)
+
 // Message ユーザーとルームの識別子を持つチャットメッセージを表す
 type Message struct {
 	UserId     string
@@ -17,6 +34,17 @@ type Message struct {
 	ReceiverId string // もしIsDMがtrueならば、ReceiverIdはnullになれない
 	Text       string
 	IsDM       bool
+	ID         int64               `json:"-"` // ルームごとに採番したメッセージID。DMでは使用しない
+	Type       string              `json:",omitempty"`
+	Sticker    *dto.ChatStickerDTO `json:",omitempty"`
+}
+
+// ChatEvent SSEで配信するチャットメッセージ。IDはLast-Event-IDによる再接続に使う。
+// Textは「ユーザーID: 本文」の形式で、スタンプの場合はMessageのStickerを参照する
+type ChatEvent struct {
+	ID      int64
+	Text    string
+	Message dto.ChatMessageDTO
 }
 
 // Listener 特定のルームの着信チャットメッセージを処理
@@ -32,18 +60,26 @@ type Manager struct {
 	close        chan *Listener
 	delete       chan string
 	messages     chan *Message
+	system       chan SystemEvent
 	redisClient  *redis.Client
+	// realtime ダイレクトメッセージを受信者のWebSocketに配信する。nilの場合は配信しない
+	realtime RealtimePublisher
+	// roomCount, listenerCount roomChannelsはrun以外から参照できないため、件数を別に保持する
+	roomCount     atomic.Int64
+	listenerCount atomic.Int64
 }
 
 // NewRoomManager function マネージャーを作成
-func NewRoomManager(redisClient *redis.Client) *Manager {
+func NewRoomManager(redisClient *redis.Client, realtime RealtimePublisher) *Manager {
 	manager := &Manager{
 		roomChannels: make(map[string]broadcast.Broadcaster),
 		open:         make(chan *Listener, 100),
 		close:        make(chan *Listener, 100),
 		delete:       make(chan string, 100),
 		messages:     make(chan *Message, 100),
+		system:       make(chan SystemEvent, 10),
 		redisClient:  redisClient,
+		realtime:     realtime,
 	}
 
 	go manager.run()
@@ -61,7 +97,17 @@ func (m *Manager) run() {
 		case roomid := <-m.delete:
 			m.deleteBroadcast(roomid)
 		case message := <-m.messages:
-			m.room(message.RoomId).Submit(message.UserId + ": " + message.Text)
+			m.room(message.RoomId).Submit(newChatEvent(dto.ChatMessageDTO{
+				ID:      message.ID,
+				Type:    message.Type,
+				User:    message.UserId,
+				Text:    message.Text,
+				Sticker: message.Sticker,
+			}))
+		case event := <-m.system:
+			for _, b := range m.roomChannels {
+				b.Submit(event)
+			}
 		}
 	}
 }
@@ -69,12 +115,14 @@ func (m *Manager) run() {
 // register リスナーを登録
 func (m *Manager) register(listener *Listener) {
 	m.room(listener.RoomId).Register(listener.Chan)
+	m.listenerCount.Add(1)
 }
 
 // deregister リスナーを登録解除
 func (m *Manager) deregister(listener *Listener) {
 	m.room(listener.RoomId).Unregister(listener.Chan)
 	close(listener.Chan)
+	m.listenerCount.Add(-1)
 }
 
 // deleteBroadcast ブロードキャストを削除
@@ -86,6 +134,7 @@ func (m *Manager) deleteBroadcast(roomid string) {
 			return
 		}
 		delete(m.roomChannels, roomid)
+		m.roomCount.Add(-1)
 	}
 }
 
@@ -95,10 +144,26 @@ func (m *Manager) room(roomid string) broadcast.Broadcaster {
 	if !ok {
 		b = broadcast.NewBroadcaster(10)
 		m.roomChannels[roomid] = b
+		m.roomCount.Add(1)
 	}
 	return b
 }
 
+// RoomCount 開いているチャットルームの数を返す
+func (m *Manager) RoomCount() int64 {
+	return m.roomCount.Load()
+}
+
+// ListenerCount チャットのストリームに接続しているリスナーの数を返す
+func (m *Manager) ListenerCount() int64 {
+	return m.listenerCount.Load()
+}
+
+// BroadcastSystemEvent 全てのルームのリスナーにシステムからの通知を送る
+func (m *Manager) BroadcastSystemEvent(event SystemEvent) {
+	m.system <- event
+}
+
 // OpenListener リスナーを開く
 func (m *Manager) OpenListener(roomid string) chan interface{} {
 	listener := make(chan interface{})
@@ -118,30 +183,188 @@ func (m *Manager) CloseListener(roomid string, channel chan interface{}) {
 }
 
 // Submit メッセージを送信
-func (m *Manager) Submit(userid, roomid, text string) {
-	msg := &Message{
-		UserId: userid,
-		RoomId: roomid,
-		Text:   text,
+func (m *Manager) Submit(ctx context.Context, userid, roomid, text string) {
+	m.submit(ctx, roomid, dto.ChatMessageDTO{Type: dto.ChatMessageTypeText, User: userid, Text: text})
+	// 送信した内容の下書きは不要になる
+	if err := m.DeleteDraft(ctx, roomid, userid); err != nil {
+		log.Printf("Redis error: %v", err)
 	}
-	m.messages <- msg
+}
 
-	// Redisにメッセージを保存
+// SubmitSticker スタンプを送信
+func (m *Manager) SubmitSticker(ctx context.Context, userid, roomid string, sticker dto.ChatStickerDTO) {
+	m.submit(ctx, roomid, dto.ChatMessageDTO{Type: dto.ChatMessageTypeSticker, User: userid, Sticker: &sticker})
+}
+
+// submit メッセージにIDを採番してRedisに保存し、ルームのリスナーに配信する。
+// IDは履歴のリスト内の位置ではなくルームごとの連番で、履歴が期限切れになっても戻らない。採番に失敗した場合はIDなしで配信する
+func (m *Manager) submit(ctx context.Context, roomid string, msg dto.ChatMessageDTO) {
 	key := "chat:" + roomid
-	err := m.redisClient.RPush(context.Background(), "chat:"+roomid, fmt.Sprintf("%s: %s", userid, text)).Err()
+	sequenceKey := fmt.Sprintf(chatSequenceKey, roomid)
+	id, err := m.redisClient.Incr(ctx, sequenceKey).Result()
 	if err != nil {
 		log.Printf("Redis error: %v", err)
+	} else if err := m.redisClient.Expire(ctx, sequenceKey, chatSequenceTTL).Err(); err != nil {
+		log.Printf("Redis error: %v", err)
+	}
+	msg.ID = id
+	data, _ := json.Marshal(msg)
+	if err := m.redisClient.RPush(ctx, key, data).Err(); err != nil {
+		log.Printf("Redis error: %v", err)
+	}
+
+	m.messages <- &Message{
+		UserId:  msg.User,
+		RoomId:  roomid,
+		Text:    msg.Text,
+		ID:      id,
+		Type:    msg.Type,
+		Sticker: msg.Sticker,
+	}
+
+	// 活動度ランキング用にユーザーごとの発言数を記録
+	if err := m.redisClient.HIncrBy(ctx, chatStatsKey+roomid, msg.User, 1).Err(); err != nil {
+		log.Printf("Redis error: %v", err)
+	} else if err := m.redisClient.Expire(ctx, chatStatsKey+roomid, chatStatsTTL).Err(); err != nil {
+		log.Printf("Redis error: %v", err)
 	}
 
 	// メッセージの有効期限を設定(e.g. , 1時間)
-	msgErr := m.redisClient.Expire(context.Background(), key, time.Hour).Err()
+	msgErr := m.redisClient.Expire(ctx, key, time.Hour).Err()
 	if msgErr != nil {
 		return
 	}
 }
 
+// SaveDraft ユーザーの書きかけのメッセージをルームごとに保存する
+func (m *Manager) SaveDraft(ctx context.Context, roomid, userid, content string) (*dto.ChatDraftDTO, error) {
+	draft := &dto.ChatDraftDTO{Content: content, UpdatedAt: time.Now()}
+	data, err := json.Marshal(draft)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.redisClient.Set(ctx, fmt.Sprintf(chatDraftKey, roomid, userid), data, chatDraftTTL).Err(); err != nil {
+		return nil, err
+	}
+	return draft, nil
+}
+
+// GetDraft ユーザーの下書きを取得する。下書きが無い場合はErrNotFoundを返す
+func (m *Manager) GetDraft(ctx context.Context, roomid, userid string) (*dto.ChatDraftDTO, error) {
+	data, err := m.redisClient.Get(ctx, fmt.Sprintf(chatDraftKey, roomid, userid)).Bytes()
+	if err == redis.Nil {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var draft dto.ChatDraftDTO
+	if err := json.Unmarshal(data, &draft); err != nil {
+		return nil, err
+	}
+	return &draft, nil
+}
+
+// DeleteDraft ユーザーの下書きを削除する
+func (m *Manager) DeleteDraft(ctx context.Context, roomid, userid string) error {
+	return m.redisClient.Del(ctx, fmt.Sprintf(chatDraftKey, roomid, userid)).Err()
+}
+
+// GetRecentMessages ルームのメッセージ履歴を取得する。
+// afterIDが指定された場合はIDがそれより大きいメッセージを直近chatReplayLimit件まで、それ以外は直近limit件を返す
+func (m *Manager) GetRecentMessages(ctx context.Context, roomid string, limit int, afterID int64) ([]ChatEvent, error) {
+	if afterID > 0 {
+		limit = chatReplayLimit
+	}
+	if limit <= 0 {
+		return nil, nil
+	}
+	key := "chat:" + roomid
+	length, err := m.redisClient.LLen(ctx, key).Result()
+	if err != nil {
+		return nil, err
+	}
+	start := length - int64(limit)
+	if start < 0 {
+		start = 0
+	}
+
+	texts, err := m.redisClient.LRange(ctx, key, start, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]ChatEvent, 0, len(texts))
+	for i, text := range texts {
+		msg := decodeChatMessage(start+int64(i)+1, text)
+		if msg.ID <= afterID {
+			continue
+		}
+		events = append(events, newChatEvent(msg))
+	}
+	return events, nil
+}
+
+// GetRoomMessages ルームのメッセージ履歴をすべて取得する。ルームが存在しない場合はErrRoomNotFoundを返す
+func (m *Manager) GetRoomMessages(ctx context.Context, roomid string) ([]dto.ChatMessageDTO, error) {
+	key := "chat:" + roomid
+	exists, err := m.redisClient.Exists(ctx, key).Result()
+	if err != nil {
+		return nil, err
+	}
+	if exists == 0 {
+		return nil, ErrRoomNotFound
+	}
+
+	texts, err := m.redisClient.LRange(ctx, key, 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	messages := make([]dto.ChatMessageDTO, 0, len(texts))
+	for i, text := range texts {
+		messages = append(messages, decodeChatMessage(int64(i)+1, text))
+	}
+	return messages, nil
+}
+
+// decodeChatMessage Redisに保存したメッセージを復元する。IDを保存していない以前のメッセージは履歴内の位置positionをIDにする。
+// JSONでない場合は「ユーザーID: 本文」の形式で保存された以前のテキストメッセージとして扱う
+func decodeChatMessage(position int64, raw string) dto.ChatMessageDTO {
+	var msg dto.ChatMessageDTO
+	if err := json.Unmarshal([]byte(raw), &msg); err != nil || msg.Type == "" {
+		msg = dto.ChatMessageDTO{Type: dto.ChatMessageTypeText, Text: raw}
+		if user, text, found := strings.Cut(raw, ": "); found {
+			msg.User, msg.Text = user, text
+		}
+	}
+	if msg.ID <= 0 {
+		msg.ID = position
+	}
+	return msg
+}
+
+// findChatMessage ルームの履歴からIDが一致するメッセージを探す。見つからない場合はErrNotFoundを返す
+func findChatMessage(ctx context.Context, redisClient *redis.Client, roomid string, id int64) (dto.ChatMessageDTO, error) {
+	texts, err := redisClient.LRange(ctx, "chat:"+roomid, 0, -1).Result()
+	if err != nil {
+		return dto.ChatMessageDTO{}, err
+	}
+	for i, text := range texts {
+		if msg := decodeChatMessage(int64(i)+1, text); msg.ID == id {
+			return msg, nil
+		}
+	}
+	return dto.ChatMessageDTO{}, ErrNotFound
+}
+
+// newChatEvent メッセージからSSEで配信するイベントを生成する
+func newChatEvent(msg dto.ChatMessageDTO) ChatEvent {
+	return ChatEvent{ID: msg.ID, Text: msg.User + ": " + msg.Text, Message: msg}
+}
+
 // SubmitDirectMessage ダイレクトメッセージを送信
-func (m *Manager) SubmitDirectMessage(senderId, receiverId, text string) error {
+func (m *Manager) SubmitDirectMessage(ctx context.Context, senderId, receiverId, text string) error {
 	msg := &Message{
 		UserId:     senderId,
 		ReceiverId: receiverId,
@@ -151,26 +374,39 @@ func (m *Manager) SubmitDirectMessage(senderId, receiverId, text string) error {
 
 	messageJSON, _ := json.Marshal(msg)
 	key := "dm:" + senderId + ":" + receiverId
-	if err := m.pushToRedis(key, messageJSON); err != nil {
+	if err := m.pushToRedis(ctx, key, messageJSON); err != nil {
 		log.Printf("Redis error: %v", err)
 		return err
 	}
 
 	// メッセージの有効期限を設定(e.g. , 1時間)
-	err := m.redisClient.Expire(context.Background(), key, time.Hour).Err()
+	err := m.redisClient.Expire(ctx, key, time.Hour).Err()
 	if err != nil {
 		return err
 	}
+	m.publishDirectMessage(ctx, senderId, receiverId, text)
 	return nil
 }
 
-func (m *Manager) pushToRedis(key string, data []byte) error {
-	if err := m.redisClient.RPush(context.Background(), key, data).Err(); err != nil {
+// publishDirectMessage ダイレクトメッセージを受信者のWebSocketに配信する。受信者のIDが数値でない場合は配信しない
+func (m *Manager) publishDirectMessage(ctx context.Context, senderId, receiverId, text string) {
+	if m.realtime == nil {
+		return
+	}
+	receiver, err := strconv.ParseUint(receiverId, 10, 32)
+	if err != nil {
+		return
+	}
+	m.realtime.PublishToUser(ctx, uint(receiver), dto.RealtimeDirectMessage, dto.RealtimeDirectMessageDTO{SenderID: senderId, Text: text})
+}
+
+func (m *Manager) pushToRedis(ctx context.Context, key string, data []byte) error {
+	if err := m.redisClient.RPush(ctx, key, data).Err(); err != nil {
 		return err
 	}
 
 	// Set the expiration of the message to 1 hour
-	if err := m.redisClient.Expire(context.Background(), key, time.Hour).Err(); err != nil {
+	if err := m.redisClient.Expire(ctx, key, time.Hour).Err(); err != nil {
 		return err
 	}
 
@@ -178,9 +414,9 @@ func (m *Manager) pushToRedis(key string, data []byte) error {
 }
 
 // GetDirectMessages ダイレクトメッセージを取得
-func (m *Manager) GetDirectMessages(senderId, receiverId string) ([]Message, error) {
+func (m *Manager) GetDirectMessages(ctx context.Context, senderId, receiverId string) ([]Message, error) {
 	key := "dm:" + senderId + ":" + receiverId // e.g. dm:1:2
-	messagesJSON, err := m.redisClient.LRange(context.Background(), key, 0, -1).Result()
+	messagesJSON, err := m.redisClient.LRange(ctx, key, 0, -1).Result()
 	if err != nil {
 		return nil, err
 	}
@@ -205,28 +441,28 @@ func (m *Manager) CreateRoom(roomID string) {
 	}
 }
 
-func (m *Manager) DeleteBroadcast(roomID string) {
+// DeleteBroadcast チャットルームを削除する。ルームが存在しない場合はErrRoomNotFoundを返す
+func (m *Manager) DeleteBroadcast(roomID string) error {
 	b, ok := m.roomChannels[roomID]
-	if ok {
-		err := b.Close()
-		if err != nil {
-			log.Printf("Error closing broadcaster for room %s: %v", roomID, err)
-			return
-		}
-		delete(m.roomChannels, roomID)
-		delErr := m.redisClient.Del(context.Background(), "chat:"+roomID).Err()
-		if delErr != nil {
-			log.Printf("Error deleting Redis key for room %s: %v", roomID, delErr)
-		}
-		log.Printf("Chat room deleted: %s", roomID)
-	} else {
-		log.Printf("Attempted to delete a non-existing room: %s", roomID)
+	if !ok {
+		return ErrRoomNotFound
+	}
+
+	if err := b.Close(); err != nil {
+		return fmt.Errorf("closing broadcaster for room %s: %w", roomID, err)
 	}
+	delete(m.roomChannels, roomID)
+
+	if err := m.redisClient.Del(context.Background(), "chat:"+roomID).Err(); err != nil {
+		return fmt.Errorf("deleting messages for room %s: %w", roomID, err)
+	}
+	log.Printf("Chat room deleted: %s", roomID)
+	return nil
 }
 
-func (m *Manager) DeleteDirectMessages(senderId, receiverId string) error {
+func (m *Manager) DeleteDirectMessages(ctx context.Context, senderId, receiverId string) error {
 	key := "dm:" + senderId + ":" + receiverId
-	if err := m.redisClient.Del(context.Background(), key).Err(); err != nil {
+	if err := m.redisClient.Del(ctx, key).Err(); err != nil {
 		log.Printf("Error deleting DMs from Redis: %v", err)
 		return err
 	}
@@ -0,0 +1,110 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/utils"
+	"gorm.io/gorm"
+)
+
+// ChatStickerService チャットのスタンプを管理する
+type ChatStickerService interface {
+	CreateSticker(ctx context.Context, uid uint, cid uint, req dto.ChatStickerCreateDTO) (*dto.ChatStickerDTO, error)
+	GetStickers(ctx context.Context, cid uint) ([]dto.ChatStickerDTO, error)
+	GetStickerForRoom(ctx context.Context, stickerID uint, roomID string) (*dto.ChatStickerDTO, error)
+}
+
+// chatStickerService ChatStickerServiceを実装
+type chatStickerService struct {
+	stickerRepo       repositories.ChatStickerRepository
+	classUserRepo     repositories.ClassUserRepository
+	classScheduleRepo repositories.ClassScheduleRepository
+	uploader          utils.Uploader
+}
+
+// NewChatStickerService ChatStickerServiceを生成
+func NewChatStickerService(stickerRepo repositories.ChatStickerRepository, classUserRepo repositories.ClassUserRepository, classScheduleRepo repositories.ClassScheduleRepository, uploader utils.Uploader) ChatStickerService {
+	return &chatStickerService{
+		stickerRepo:       stickerRepo,
+		classUserRepo:     classUserRepo,
+		classScheduleRepo: classScheduleRepo,
+		uploader:          uploader,
+	}
+}
+
+// CreateSticker スタンプの画像をS3にアップロードして登録する。クラスの管理者のみ登録できる
+func (s *chatStickerService) CreateSticker(ctx context.Context, uid uint, cid uint, req dto.ChatStickerCreateDTO) (*dto.ChatStickerDTO, error) {
+	isAdmin, err := s.classUserRepo.IsAdmin(ctx, uid, cid)
+	if err != nil || !isAdmin {
+		return nil, ErrUnauthorized
+	}
+
+	imageURL, err := s.uploader.UploadImage(req.Image, cid, false)
+	if err != nil {
+		return nil, fmt.Errorf("uploading sticker image: %w", err)
+	}
+
+	sticker := &models.ChatSticker{Name: req.Name, ImageURL: imageURL, CID: cid, UID: uid}
+	if err := s.stickerRepo.InsertChatSticker(ctx, sticker); err != nil {
+		return nil, err
+	}
+	return toChatStickerDTO(sticker), nil
+}
+
+// GetStickers クラスのスタンプ一覧を取得する
+func (s *chatStickerService) GetStickers(ctx context.Context, cid uint) ([]dto.ChatStickerDTO, error) {
+	stickers, err := s.stickerRepo.FindByClass(ctx, cid)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]dto.ChatStickerDTO, 0, len(stickers))
+	for i := range stickers {
+		result = append(result, *toChatStickerDTO(&stickers[i]))
+	}
+	return result, nil
+}
+
+// GetStickerForRoom チャットルームで送信するスタンプを取得する。
+// ルームはスケジュールIDで識別し、スケジュールと異なるクラスのスタンプはErrNotFoundとする
+func (s *chatStickerService) GetStickerForRoom(ctx context.Context, stickerID uint, roomID string) (*dto.ChatStickerDTO, error) {
+	scheduleID, err := strconv.ParseUint(roomID, 10, 32)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+
+	sticker, err := s.stickerRepo.FindByID(ctx, stickerID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	schedule, err := s.classScheduleRepo.GetClassScheduleByID(ctx, uint(scheduleID))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if schedule.CID != sticker.CID {
+		return nil, ErrNotFound
+	}
+	return toChatStickerDTO(sticker), nil
+}
+
+// toChatStickerDTO スタンプのモデルをDTOに変換する
+func toChatStickerDTO(sticker *models.ChatSticker) *dto.ChatStickerDTO {
+	return &dto.ChatStickerDTO{
+		ID:       sticker.ID,
+		CID:      sticker.CID,
+		Name:     sticker.Name,
+		ImageURL: sticker.ImageURL,
+	}
+}
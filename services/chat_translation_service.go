@@ -0,0 +1,139 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/utils"
+	"github.com/go-redis/redis/v8"
+	"gorm.io/gorm"
+)
+
+const (
+	// chatTranslationKey ルームのメッセージと翻訳先の言語ごとの翻訳のキー
+	chatTranslationKey = "chat_translation:%s:%d:%s"
+	// chatTranslationTTL 翻訳を保持する期間。チャットの履歴は最後の発言から1時間で消えるため、それに合わせる
+	chatTranslationTTL = time.Hour
+	// chatTranslationRateKey ユーザーごとの翻訳APIの呼び出し回数のキー
+	chatTranslationRateKey = "chat_translation_rate:%d"
+	// chatTranslationRateWindow 翻訳APIの呼び出し回数を数え直す間隔
+	chatTranslationRateWindow = time.Minute
+	// ChatTranslationRateLimit 1人のユーザーが1分間に翻訳APIで翻訳できるメッセージ数。キャッシュした翻訳は数えない
+	ChatTranslationRateLimit = 30
+)
+
+// ChatTranslationService チャットのメッセージを受信者の言語に翻訳するサービス
+type ChatTranslationService interface {
+	TranslateMessage(ctx context.Context, uid uint, roomid string, messageID int64, targetLang string) (*dto.ChatTranslationDTO, error)
+}
+
+// chatTranslationService ChatTranslationServiceを実装
+type chatTranslationService struct {
+	redisClient       *redis.Client
+	translator        utils.Translator
+	classScheduleRepo repositories.ClassScheduleRepository
+	classUserRepo     repositories.ClassUserRepository
+}
+
+// NewChatTranslationService ChatTranslationServiceを生成する。translatorがnilの場合は翻訳できない
+func NewChatTranslationService(redisClient *redis.Client, translator utils.Translator, classScheduleRepo repositories.ClassScheduleRepository, classUserRepo repositories.ClassUserRepository) ChatTranslationService {
+	return &chatTranslationService{redisClient: redisClient, translator: translator, classScheduleRepo: classScheduleRepo, classUserRepo: classUserRepo}
+}
+
+// cachedTranslation キャッシュする翻訳。原文と対にして保存し、ルームの作り直しでIDが同じ別のメッセージになった場合は使わない
+type cachedTranslation struct {
+	Original   string `json:"original"`
+	Text       string `json:"text"`
+	SourceLang string `json:"source_lang"`
+}
+
+// TranslateMessage ルームのメッセージをtargetLangに翻訳する。原文はそのまま残し、同じメッセージと言語の翻訳はキャッシュを返す。
+// ルームのクラスのメンバー以外はErrUnauthorized、メッセージが無い場合はErrNotFound、スタンプなど本文が無い場合はErrNotTranslatable、
+// 翻訳APIの呼び出しが上限に達した場合はErrTranslationRateLimit、翻訳APIが失敗した場合はErrTranslationFailedを返す
+func (s *chatTranslationService) TranslateMessage(ctx context.Context, uid uint, roomid string, messageID int64, targetLang string) (*dto.ChatTranslationDTO, error) {
+	if s.translator == nil {
+		return nil, ErrTranslationUnavailable
+	}
+	if err := s.authorizeRoom(ctx, uid, roomid); err != nil {
+		return nil, err
+	}
+	if messageID <= 0 {
+		return nil, ErrNotFound
+	}
+	message, err := findChatMessage(ctx, s.redisClient, roomid, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if message.Type != dto.ChatMessageTypeText || message.Text == "" {
+		return nil, ErrNotTranslatable
+	}
+
+	result := &dto.ChatTranslationDTO{MessageID: messageID, OriginalText: message.Text, TargetLang: targetLang}
+	key := fmt.Sprintf(chatTranslationKey, roomid, messageID, targetLang)
+	if data, err := s.redisClient.Get(ctx, key).Bytes(); err == nil {
+		var cached cachedTranslation
+		if err := json.Unmarshal(data, &cached); err == nil && cached.Original == message.Text {
+			result.TranslatedText, result.SourceLang = cached.Text, cached.SourceLang
+			return result, nil
+		}
+	}
+
+	if err := s.reserveTranslation(ctx, uid); err != nil {
+		return nil, err
+	}
+	translation, err := s.translator.Translate(ctx, message.Text, targetLang)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrTranslationFailed, err)
+	}
+	result.TranslatedText, result.SourceLang = translation.Text, translation.SourceLang
+	if data, err := json.Marshal(cachedTranslation{Original: message.Text, Text: translation.Text, SourceLang: translation.SourceLang}); err == nil {
+		s.redisClient.Set(ctx, key, data, chatTranslationTTL)
+	}
+	return result, nil
+}
+
+// authorizeRoom ルームのスケジュールのクラスに、ユーザーがメンバーとして参加しているか確認する。
+// ルームはスケジュールIDで識別し、存在しないスケジュールのルームはErrNotFoundとする
+func (s *chatTranslationService) authorizeRoom(ctx context.Context, uid uint, roomid string) error {
+	scheduleID, err := strconv.ParseUint(roomid, 10, 32)
+	if err != nil {
+		return ErrNotFound
+	}
+	schedule, err := s.classScheduleRepo.GetClassScheduleByID(ctx, uint(scheduleID))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrNotFound
+		}
+		return err
+	}
+	role, err := s.classUserRepo.GetRole(ctx, uid, schedule.CID)
+	if err != nil || (role != "ADMIN" && role != "ASSISTANT" && role != "USER") {
+		return ErrUnauthorized
+	}
+	return nil
+}
+
+// reserveTranslation ユーザーの翻訳APIの呼び出し回数を1つ加える。上限を超える場合はErrTranslationRateLimitを返す。
+// 呼び出し回数は最初に翻訳してから1分で数え直す
+func (s *chatTranslationService) reserveTranslation(ctx context.Context, uid uint) error {
+	key := fmt.Sprintf(chatTranslationRateKey, uid)
+	count, err := s.redisClient.Incr(ctx, key).Result()
+	if err != nil {
+		return err
+	}
+	if count == 1 {
+		if err := s.redisClient.Expire(ctx, key, chatTranslationRateWindow).Err(); err != nil {
+			return err
+		}
+	}
+	if count > ChatTranslationRateLimit {
+		return ErrTranslationRateLimit
+	}
+	return nil
+}
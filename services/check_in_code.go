@@ -0,0 +1,135 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/go-redis/redis/v8"
+	"gorm.io/gorm"
+)
+
+const (
+	// checkInCodeKey スケジュールごとの現在の確認コードのキー
+	checkInCodeKey = "check_in_code:%d"
+	// checkInAttemptsKey スケジュールとユーザーごとの確認コードの入力ミスの回数のキー
+	checkInAttemptsKey = "check_in_code_attempts:%d:%d"
+	// checkInCodeTTL 確認コードを更新する間隔。教室の外に伝わっても使えないよう短くする
+	checkInCodeTTL = time.Minute
+	// checkInCodeDigits 講師が口頭で伝えられる桁数
+	checkInCodeDigits = 4
+	// maxCheckInAttempts 確認コードの更新までに入力ミスできる回数。総当たりで当てられないようにする
+	maxCheckInAttempts = 5
+)
+
+// GetCheckInCode 講師画面に表示する自己チェックインの確認コードを返す。コードがない場合は生成し、1分ごとに更新する。
+// クラスの講師・アシスタントのみ取得できる
+func (s *classScheduleService) GetCheckInCode(ctx context.Context, uid uint, csid uint) (*dto.CheckInCodeDTO, error) {
+	classSchedule, err := s.repo.GetClassScheduleByID(ctx, csid)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	role, err := s.classUserRepo.GetRole(ctx, uid, classSchedule.CID)
+	if err != nil || (role != "ADMIN" && role != "ASSISTANT") {
+		return nil, ErrUnauthorized
+	}
+
+	key := fmt.Sprintf(checkInCodeKey, csid)
+	code, err := s.redisClient.Get(ctx, key).Result()
+	if err == redis.Nil {
+		code, err = s.issueCheckInCode(ctx, key)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ttl, err := s.redisClient.PTTL(ctx, key).Result()
+	if err != nil {
+		return nil, err
+	}
+	return &dto.CheckInCodeDTO{Code: code, ExpiresAt: time.Now().Add(ttl)}, nil
+}
+
+// VerifyCheckInCode 生徒の自己チェックインの確認コードを検証する。確認コードを必須にしていないスケジュールは常に成功する。
+// コードが一致しない場合と、コードの更新までに入力ミスが上限に達した場合はErrInvalidCheckInCodeを返す
+func (s *classScheduleService) VerifyCheckInCode(ctx context.Context, uid uint, csid uint, code string) error {
+	classSchedule, err := s.repo.GetClassScheduleByID(ctx, csid)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrNotFound
+		}
+		return err
+	}
+	return s.verifyCheckInCode(ctx, classSchedule, uid, code)
+}
+
+// verifyCheckInCode 取得済みのスケジュールで生徒の自己チェックインの確認コードを検証する。
+// 確認コードを必須にしていないスケジュールではRedisに問い合わせずに成功する
+func (s *classScheduleService) verifyCheckInCode(ctx context.Context, classSchedule *models.ClassSchedule, uid uint, code string) error {
+	if !classSchedule.RequireCheckInCode {
+		return nil
+	}
+	csid := classSchedule.ID
+
+	attemptsKey := fmt.Sprintf(checkInAttemptsKey, csid, uid)
+	attempts, err := s.redisClient.Get(ctx, attemptsKey).Int()
+	if err != nil && err != redis.Nil {
+		return err
+	}
+	if attempts >= maxCheckInAttempts {
+		return ErrInvalidCheckInCode
+	}
+
+	current, err := s.redisClient.Get(ctx, fmt.Sprintf(checkInCodeKey, csid)).Result()
+	if err != nil && err != redis.Nil {
+		return err
+	}
+	if current == "" || subtle.ConstantTimeCompare([]byte(current), []byte(code)) != 1 {
+		pipe := s.redisClient.TxPipeline()
+		pipe.Incr(ctx, attemptsKey)
+		pipe.Expire(ctx, attemptsKey, checkInCodeTTL)
+		if _, err := pipe.Exec(ctx); err != nil {
+			return err
+		}
+		return ErrInvalidCheckInCode
+	}
+	return nil
+}
+
+// issueCheckInCode 新しい確認コードを保存して返す。同時に生成した場合は先に保存したコードを返す
+func (s *classScheduleService) issueCheckInCode(ctx context.Context, key string) (string, error) {
+	code, err := newCheckInCode()
+	if err != nil {
+		return "", err
+	}
+	stored, err := s.redisClient.SetNX(ctx, key, code, checkInCodeTTL).Result()
+	if err != nil {
+		return "", err
+	}
+	if !stored {
+		return s.redisClient.Get(ctx, key).Result()
+	}
+	return code, nil
+}
+
+// newCheckInCode ランダムな数字の確認コードを生成する
+func newCheckInCode() (string, error) {
+	max := big.NewInt(1)
+	for i := 0; i < checkInCodeDigits; i++ {
+		max.Mul(max, big.NewInt(10))
+	}
+	n, err := rand.Int(rand.Reader, max)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%0*d", checkInCodeDigits, n), nil
+}
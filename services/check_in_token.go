@@ -0,0 +1,159 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"gorm.io/gorm"
+)
+
+// defaultCheckInTokenTTL 設定がない場合の出席トークンの有効期間。QRコードを撮影して教室の外に送っても使えないよう短くし、講師画面で有効期限までに発行し直す
+const defaultCheckInTokenTTL = 2 * time.Minute
+
+// checkInTokenKeyLabel 署名の鍵を導出するラベル。JWTの秘密鍵と同じ鍵で署名しないようにする
+const checkInTokenKeyLabel = "minori:check-in-token:v1"
+
+// CheckInTokenConfig Redisに問い合わせずに検証できる署名付きの出席トークンの設定。Secretが空の場合は発行しない
+type CheckInTokenConfig struct {
+	// Secret 署名の鍵を導出する秘密鍵。全てのインスタンスで同じ値を指定する
+	Secret []byte
+	// TTL 発行したトークンの有効期間。0以下の場合は既定値
+	TTL time.Duration
+}
+
+// checkInTokenSigner 出席トークンの署名と検証を行う。検証はRedisに問い合わせずに行う
+type checkInTokenSigner struct {
+	key []byte
+	ttl time.Duration
+}
+
+// newCheckInTokenSigner 設定から署名の鍵を導出する。秘密鍵が空の場合はnilを返す
+func newCheckInTokenSigner(config CheckInTokenConfig) *checkInTokenSigner {
+	if len(config.Secret) == 0 {
+		return nil
+	}
+	mac := hmac.New(sha256.New, config.Secret)
+	mac.Write([]byte(checkInTokenKeyLabel))
+	ttl := config.TTL
+	if ttl <= 0 {
+		ttl = defaultCheckInTokenTTL
+	}
+	return &checkInTokenSigner{key: mac.Sum(nil), ttl: ttl}
+}
+
+// sign スケジュールIDと有効期限を「スケジュールID.有効期限のUNIX時間.署名」の形式のトークンにする
+func (s *checkInTokenSigner) sign(csid uint, expiresAt time.Time) string {
+	claims := fmt.Sprintf("%d.%d", csid, expiresAt.Unix())
+	return claims + "." + s.signature(claims)
+}
+
+// verify トークンの署名を検証し、スケジュールIDを返す。署名が一致しない場合はErrInvalidCheckInToken、
+// 有効期限を過ぎた場合はErrCheckInTokenExpiredを返す
+func (s *checkInTokenSigner) verify(token string, now time.Time) (uint, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return 0, ErrInvalidCheckInToken
+	}
+	claims := parts[0] + "." + parts[1]
+	if !hmac.Equal([]byte(parts[2]), []byte(s.signature(claims))) {
+		return 0, ErrInvalidCheckInToken
+	}
+	csid, err := strconv.ParseUint(parts[0], 10, 32)
+	if err != nil {
+		return 0, ErrInvalidCheckInToken
+	}
+	expiresAt, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return 0, ErrInvalidCheckInToken
+	}
+	if now.After(time.Unix(expiresAt, 0)) {
+		return 0, ErrCheckInTokenExpired
+	}
+	return uint(csid), nil
+}
+
+// signature クレームのHMAC-SHA256の署名をURLで使える形式で返す
+func (s *checkInTokenSigner) signature(claims string) string {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(claims))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// IssueCheckInToken 講師画面のQRコードに表示する署名付きの出席トークンを発行する。
+// 有効期限は短いため、講師画面は有効期限までに発行し直してQRコードを切り替える。
+// クラスの講師・アシスタントのみ発行できる
+func (s *classScheduleService) IssueCheckInToken(ctx context.Context, uid uint, csid uint) (*dto.CheckInTokenDTO, error) {
+	if s.checkInTokens == nil {
+		return nil, ErrCheckInTokenUnavailable
+	}
+	classSchedule, err := s.repo.GetClassScheduleByID(ctx, csid)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	role, err := s.classUserRepo.GetRole(ctx, uid, classSchedule.CID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if role != "ADMIN" && role != "ASSISTANT" {
+		return nil, ErrUnauthorized
+	}
+
+	// 秒単位で署名するため、返す有効期限もトークンに含めた値に揃える
+	expiresAt := time.Now().Add(s.checkInTokens.ttl).Truncate(time.Second)
+	return &dto.CheckInTokenDTO{
+		Token:     s.checkInTokens.sign(csid, expiresAt),
+		CSID:      csid,
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// CheckinWithToken 生徒が送信した出席トークンの署名と有効期限を検証し、チェックインするスケジュールを返す。
+// トークンの検証はRedisに問い合わせずに行うため、確認コードを必須にしていないスケジュールではRedisに障害があってもチェックインできる。
+// 確認コードを必須にしたスケジュールでは、代理チェックインを防ぐためトークンに加えて確認コードも検証する。
+// 休講のスケジュールはErrScheduleCancelled、出席方式がQRコードによるチェックインを受け付けない場合はErrCheckInMethodNotAllowedを返す。
+// 生徒として参加しているメンバーのみチェックインでき、講師・アシスタントとメンバーではないユーザーはErrUnauthorizedを返す
+func (s *classScheduleService) CheckinWithToken(ctx context.Context, uid uint, token string, code string) (*models.ClassSchedule, error) {
+	if s.checkInTokens == nil {
+		return nil, ErrCheckInTokenUnavailable
+	}
+	csid, err := s.checkInTokens.verify(token, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	classSchedule, err := s.repo.GetClassScheduleByID(ctx, csid)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	role, err := s.classUserRepo.GetRole(ctx, uid, classSchedule.CID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if role != "USER" {
+		return nil, ErrUnauthorized
+	}
+	if classSchedule.IsCancelled {
+		return nil, ErrScheduleCancelled
+	}
+	if !allowsCheckInMethod(attendanceModeOrDefault(classSchedule.AttendanceMode), models.QRCheckIn) {
+		return nil, ErrCheckInMethodNotAllowed
+	}
+	if err := s.verifyCheckInCode(ctx, classSchedule, uid, code); err != nil {
+		return nil, err
+	}
+	return classSchedule, nil
+}
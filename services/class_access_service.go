@@ -0,0 +1,236 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+	"gorm.io/gorm"
+)
+
+// classAccessCacheTTL クラスのアクセス制限をキャッシュする時間。変更は他のインスタンスにこの時間内に反映される
+const classAccessCacheTTL = 30 * time.Second
+
+// accessTimeLayout アクセスを許可する時間帯の形式
+const accessTimeLayout = "15:04"
+
+// ClassAccessService クラスへのアクセスを時間帯と接続元のIPで制限するサービス
+type ClassAccessService interface {
+	GetRestriction(ctx context.Context, cid uint) (*dto.ClassAccessRestrictionDTO, error)
+	SetRestriction(ctx context.Context, viewerUID uint, cid uint, restriction dto.ClassAccessRestrictionDTO) (*dto.ClassAccessRestrictionDTO, error)
+	CheckAccess(ctx context.Context, cid uint, clientIP string, now time.Time) error
+}
+
+// classAccessPolicy 解析済みのアクセス制限
+type classAccessPolicy struct {
+	// hasWindow 時間帯を制限する。startとendは0時からの分
+	hasWindow  bool
+	start, end int
+	networks   []*net.IPNet
+}
+
+// cachedAccessPolicy キャッシュしたアクセス制限と有効期限
+type cachedAccessPolicy struct {
+	policy    classAccessPolicy
+	expiresAt time.Time
+}
+
+// classAccessService インタフェースを実装
+type classAccessService struct {
+	classRepo     repositories.ClassRepository
+	classUserRepo repositories.ClassUserRepository
+
+	mu    sync.Mutex
+	cache map[uint]cachedAccessPolicy
+}
+
+// NewClassAccessService ClassAccessServiceを生成
+func NewClassAccessService(classRepo repositories.ClassRepository, classUserRepo repositories.ClassUserRepository) ClassAccessService {
+	return &classAccessService{
+		classRepo:     classRepo,
+		classUserRepo: classUserRepo,
+		cache:         make(map[uint]cachedAccessPolicy),
+	}
+}
+
+// GetRestriction クラスのアクセス制限を取得する
+func (s *classAccessService) GetRestriction(ctx context.Context, cid uint) (*dto.ClassAccessRestrictionDTO, error) {
+	class, err := s.classRepo.GetByID(ctx, cid)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return toAccessRestrictionDTO(class), nil
+}
+
+// SetRestriction クラスのアクセス制限を置き換える。クラスの管理者のみ設定できる。全ての項目を省略すると制限を解除する
+func (s *classAccessService) SetRestriction(ctx context.Context, viewerUID uint, cid uint, restriction dto.ClassAccessRestrictionDTO) (*dto.ClassAccessRestrictionDTO, error) {
+	isAdmin, err := s.classUserRepo.IsAdmin(ctx, viewerUID, cid)
+	if err != nil || !isAdmin {
+		return nil, ErrUnauthorized
+	}
+
+	class := &models.Class{ID: cid, AccessStartTime: restriction.StartTime, AccessEndTime: restriction.EndTime}
+	if len(restriction.AllowedIPRanges) > 0 {
+		ranges := strings.Join(restriction.AllowedIPRanges, ",")
+		class.AllowedIPRanges = &ranges
+	}
+	policy, err := parseAccessPolicy(class)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.classRepo.SetAccessRestriction(ctx, cid, class.AccessStartTime, class.AccessEndTime, class.AllowedIPRanges); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	s.storePolicy(cid, policy, time.Now())
+	return toAccessRestrictionDTO(class), nil
+}
+
+// CheckAccess 現在の時刻と接続元のIPでクラスにアクセスできるか確認する。制限外の場合はErrAccessRestrictedを返す。
+// 存在しないクラスは制限しない
+func (s *classAccessService) CheckAccess(ctx context.Context, cid uint, clientIP string, now time.Time) error {
+	policy, err := s.loadPolicy(ctx, cid, now)
+	if err != nil {
+		return err
+	}
+	if !policy.allowsTime(now) || !policy.allowsIP(net.ParseIP(clientIP)) {
+		return ErrAccessRestricted
+	}
+	return nil
+}
+
+// loadPolicy キャッシュが有効な場合はキャッシュから、それ以外はデータベースからアクセス制限を読み込む
+func (s *classAccessService) loadPolicy(ctx context.Context, cid uint, now time.Time) (classAccessPolicy, error) {
+	s.mu.Lock()
+	cached, ok := s.cache[cid]
+	s.mu.Unlock()
+	if ok && now.Before(cached.expiresAt) {
+		return cached.policy, nil
+	}
+
+	class, err := s.classRepo.GetByID(ctx, cid)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return classAccessPolicy{}, nil
+	}
+	if err != nil {
+		return classAccessPolicy{}, err
+	}
+	policy, err := parseAccessPolicy(class)
+	if err != nil {
+		// 保存時に検証しているため、データベースを直接書き換えた場合のみ到達する。制限を緩めないよう拒否する
+		return classAccessPolicy{}, ErrAccessRestricted
+	}
+	s.storePolicy(cid, policy, now)
+	return policy, nil
+}
+
+// storePolicy アクセス制限をキャッシュする
+func (s *classAccessService) storePolicy(cid uint, policy classAccessPolicy, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cache[cid] = cachedAccessPolicy{policy: policy, expiresAt: now.Add(classAccessCacheTTL)}
+}
+
+// parseAccessPolicy クラスのアクセス制限を解析する。IPアドレスのみの指定は1つのアドレスのレンジとし、正規化した値をclassに書き戻す
+func parseAccessPolicy(class *models.Class) (classAccessPolicy, error) {
+	var policy classAccessPolicy
+	if (class.AccessStartTime == nil) != (class.AccessEndTime == nil) {
+		return policy, ErrInvalidAccessRestriction
+	}
+	if class.AccessStartTime != nil {
+		start, err := time.Parse(accessTimeLayout, *class.AccessStartTime)
+		if err != nil {
+			return policy, ErrInvalidAccessRestriction
+		}
+		end, err := time.Parse(accessTimeLayout, *class.AccessEndTime)
+		if err != nil || start.Equal(end) {
+			return policy, ErrInvalidAccessRestriction
+		}
+		policy.hasWindow = true
+		policy.start = start.Hour()*60 + start.Minute()
+		policy.end = end.Hour()*60 + end.Minute()
+	}
+
+	if class.AllowedIPRanges == nil {
+		return policy, nil
+	}
+	ranges := strings.Split(*class.AllowedIPRanges, ",")
+	normalized := make([]string, 0, len(ranges))
+	for _, value := range ranges {
+		value = strings.TrimSpace(value)
+		if !strings.Contains(value, "/") {
+			ip := net.ParseIP(value)
+			if ip == nil {
+				return policy, ErrInvalidAccessRestriction
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				bits = 8 * net.IPv4len
+			}
+			value = (&net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}).String()
+		}
+		_, network, err := net.ParseCIDR(value)
+		if err != nil {
+			return policy, ErrInvalidAccessRestriction
+		}
+		policy.networks = append(policy.networks, network)
+		normalized = append(normalized, network.String())
+	}
+	joined := strings.Join(normalized, ",")
+	class.AllowedIPRanges = &joined
+	return policy, nil
+}
+
+// allowsTime 時刻が許可する時間帯に含まれるか。時間帯を制限しない場合は常にtrue
+func (p classAccessPolicy) allowsTime(now time.Time) bool {
+	if !p.hasWindow {
+		return true
+	}
+	minute := now.Hour()*60 + now.Minute()
+	if p.start < p.end {
+		return p.start <= minute && minute < p.end
+	}
+	// 22:00-06:00のように日をまたぐ時間帯
+	return minute >= p.start || minute < p.end
+}
+
+// allowsIP IPアドレスが許可するIPレンジに含まれるか。IPを制限しない場合は常にtrue
+func (p classAccessPolicy) allowsIP(ip net.IP) bool {
+	if p.networks == nil {
+		return true
+	}
+	if ip == nil {
+		return false
+	}
+	for _, network := range p.networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// toAccessRestrictionDTO クラスのアクセス制限をDTOに変換する
+func toAccessRestrictionDTO(class *models.Class) *dto.ClassAccessRestrictionDTO {
+	restriction := &dto.ClassAccessRestrictionDTO{
+		StartTime:       class.AccessStartTime,
+		EndTime:         class.AccessEndTime,
+		AllowedIPRanges: []string{},
+	}
+	if class.AllowedIPRanges != nil {
+		restriction.AllowedIPRanges = strings.Split(*class.AllowedIPRanges, ",")
+	}
+	return restriction
+}
@@ -0,0 +1,101 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/utils"
+)
+
+// ClassArchiveConfig クラスの自動アーカイブの設定
+type ClassArchiveConfig struct {
+	// GracePeriod 最後のスケジュールの終了からアーカイブするまでの期間
+	GracePeriod time.Duration
+	// NoticeLead アーカイブの何日前に講師へ通知するか。通知からこの期間が経過するまではアーカイブしない
+	NoticeLead time.Duration
+}
+
+// ClassArchiveService 活動のないクラスを自動的にアーカイブする
+type ClassArchiveService interface {
+	RunAutoArchive(ctx context.Context) (notified int, archived int, err error)
+}
+
+// classArchiveService インタフェースを実装
+type classArchiveService struct {
+	classRepo     repositories.ClassRepository
+	classUserRepo repositories.ClassUserRepository
+	notifier      Notifier
+	config        ClassArchiveConfig
+}
+
+// NewClassArchiveService ClassArchiveServiceを生成
+func NewClassArchiveService(classRepo repositories.ClassRepository, classUserRepo repositories.ClassUserRepository, notifier Notifier, config ClassArchiveConfig) ClassArchiveService {
+	return &classArchiveService{
+		classRepo:     classRepo,
+		classUserRepo: classUserRepo,
+		notifier:      notifier,
+		config:        config,
+	}
+}
+
+// RunAutoArchive 最後のスケジュールから猶予期間が経過したクラスをアーカイブする。
+// アーカイブ予定日のNoticeLead前になったクラスの講師には事前に通知し、通知していないクラスはアーカイブしない
+func (s *classArchiveService) RunAutoArchive(ctx context.Context) (int, int, error) {
+	now := time.Now()
+	candidates, err := s.classRepo.FindInactiveClasses(ctx, now.Add(-(s.config.GracePeriod - s.config.NoticeLead)))
+	if err != nil {
+		return 0, 0, err
+	}
+
+	notified, archived := 0, 0
+	for _, class := range candidates {
+		archiveAt := class.LastEndedAt.Add(s.config.GracePeriod)
+
+		// 通知後に新しいスケジュールが追加された場合は、以前の通知を無効とする
+		if class.ArchiveNoticeSentAt == nil || class.ArchiveNoticeSentAt.Before(class.LastEndedAt) {
+			if earliest := now.Add(s.config.NoticeLead); earliest.After(archiveAt) {
+				archiveAt = earliest
+			}
+			if err := s.sendArchiveNotice(ctx, class, archiveAt); err != nil {
+				utils.ReportBackgroundError("auto_archive_classes", fmt.Errorf("failed to notify archive of class %d: %w", class.ID, err))
+				continue
+			}
+			notified++
+			continue
+		}
+
+		if noticeDeadline := class.ArchiveNoticeSentAt.Add(s.config.NoticeLead); noticeDeadline.After(archiveAt) {
+			archiveAt = noticeDeadline
+		}
+		if now.Before(archiveAt) {
+			continue
+		}
+		if err := s.classRepo.Archive(ctx, class.ID, now); err != nil {
+			utils.ReportBackgroundError("auto_archive_classes", fmt.Errorf("failed to archive class %d: %w", class.ID, err))
+			continue
+		}
+		archived++
+	}
+	return notified, archived, nil
+}
+
+// sendArchiveNotice クラスの管理者にアーカイブの予定を通知し、通知日時を記録する
+func (s *classArchiveService) sendArchiveNotice(ctx context.Context, class dto.InactiveClassDTO, archiveAt time.Time) error {
+	admins, err := s.classUserRepo.GetClassMembers(ctx, class.ID, "ADMIN")
+	if err != nil {
+		return err
+	}
+
+	title := "クラスの自動アーカイブのお知らせ"
+	body := fmt.Sprintf("クラス「%s」は最後の授業から活動がないため、%sに自動的にアーカイブされます。引き続き利用する場合は、自動アーカイブの対象から除外してください。",
+		class.Name, archiveAt.Format("2006-01-02"))
+	for _, admin := range admins {
+		if err := s.notifier.Notify(ctx, admin.Uid, class.ID, title, body); err != nil {
+			return err
+		}
+	}
+	return s.classRepo.MarkArchiveNoticeSent(ctx, class.ID, time.Now())
+}
@@ -0,0 +1,198 @@
+package services
+
+import (
+	"context"
+	"errors"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+	"gorm.io/gorm"
+)
+
+// ClassBoardCommentService グループ掲示板のコメント、リプライといいねのサービス。クラスのメンバーのみ利用できる
+type ClassBoardCommentService interface {
+	GetComments(ctx context.Context, viewerUID uint, bid uint) ([]dto.ClassBoardCommentDTO, error)
+	GetReplies(ctx context.Context, viewerUID uint, bid uint, commentID uint) ([]dto.ClassBoardCommentDTO, error)
+	CreateComment(ctx context.Context, viewerUID uint, bid uint, request dto.ClassBoardCommentCreateDTO) (*dto.ClassBoardCommentDTO, error)
+	DeleteComment(ctx context.Context, viewerUID uint, bid uint, commentID uint) error
+	SetLike(ctx context.Context, viewerUID uint, bid uint, commentID uint, liked bool) (*dto.ClassBoardCommentDTO, error)
+}
+
+// classBoardCommentService インタフェースを実装
+type classBoardCommentService struct {
+	repo          repositories.ClassBoardCommentRepository
+	boardRepo     repositories.ClassBoardRepository
+	classUserRepo repositories.ClassUserRepository
+}
+
+// NewClassBoardCommentService ClassBoardCommentServiceを生成
+func NewClassBoardCommentService(repo repositories.ClassBoardCommentRepository, boardRepo repositories.ClassBoardRepository, classUserRepo repositories.ClassUserRepository) ClassBoardCommentService {
+	return &classBoardCommentService{
+		repo:          repo,
+		boardRepo:     boardRepo,
+		classUserRepo: classUserRepo,
+	}
+}
+
+// GetComments 掲示板へのコメントを古い順に返信数といいね数付きで取得する。リプライは含めない
+func (s *classBoardCommentService) GetComments(ctx context.Context, viewerUID uint, bid uint) ([]dto.ClassBoardCommentDTO, error) {
+	if _, err := s.authorize(ctx, viewerUID, bid); err != nil {
+		return nil, err
+	}
+	rows, err := s.repo.FindRows(ctx, bid, nil, viewerUID)
+	if err != nil {
+		return nil, err
+	}
+	return toCommentDTOs(rows), nil
+}
+
+// GetReplies コメントへのリプライを古い順にいいね数付きで取得する
+func (s *classBoardCommentService) GetReplies(ctx context.Context, viewerUID uint, bid uint, commentID uint) ([]dto.ClassBoardCommentDTO, error) {
+	if _, err := s.authorize(ctx, viewerUID, bid); err != nil {
+		return nil, err
+	}
+	if _, err := s.findComment(ctx, bid, commentID); err != nil {
+		return nil, err
+	}
+	rows, err := s.repo.FindRows(ctx, bid, &commentID, viewerUID)
+	if err != nil {
+		return nil, err
+	}
+	return toCommentDTOs(rows), nil
+}
+
+// CreateComment 掲示板にコメントする。リプライ先は同じ掲示板へのコメントに限り、リプライへのリプライはErrNestedReplyを返す
+func (s *classBoardCommentService) CreateComment(ctx context.Context, viewerUID uint, bid uint, request dto.ClassBoardCommentCreateDTO) (*dto.ClassBoardCommentDTO, error) {
+	if _, err := s.authorize(ctx, viewerUID, bid); err != nil {
+		return nil, err
+	}
+	if request.ParentCommentID != nil {
+		parent, err := s.findComment(ctx, bid, *request.ParentCommentID)
+		if err != nil {
+			return nil, err
+		}
+		if parent.ParentCommentID != nil {
+			return nil, ErrNestedReply
+		}
+	}
+
+	comment := models.ClassBoardComment{
+		BID:             bid,
+		UID:             viewerUID,
+		ParentCommentID: request.ParentCommentID,
+		Content:         request.Content,
+	}
+	if err := s.repo.Create(ctx, &comment); err != nil {
+		return nil, err
+	}
+	return s.findRow(ctx, comment.ID, viewerUID)
+}
+
+// DeleteComment コメントを削除する。投稿者とクラスの管理者、アシスタントのみ削除でき、コメントへのリプライも削除される
+func (s *classBoardCommentService) DeleteComment(ctx context.Context, viewerUID uint, bid uint, commentID uint) error {
+	role, err := s.authorize(ctx, viewerUID, bid)
+	if err != nil {
+		return err
+	}
+	comment, err := s.findComment(ctx, bid, commentID)
+	if err != nil {
+		return err
+	}
+	if comment.UID != viewerUID && role != "ADMIN" && role != "ASSISTANT" {
+		return ErrUnauthorized
+	}
+	return s.repo.Delete(ctx, commentID)
+}
+
+// SetLike コメントのいいねを設定または取り消し、更新後のいいね数を含むコメントを返す
+func (s *classBoardCommentService) SetLike(ctx context.Context, viewerUID uint, bid uint, commentID uint, liked bool) (*dto.ClassBoardCommentDTO, error) {
+	if _, err := s.authorize(ctx, viewerUID, bid); err != nil {
+		return nil, err
+	}
+	if _, err := s.findComment(ctx, bid, commentID); err != nil {
+		return nil, err
+	}
+
+	like := s.repo.Unlike
+	if liked {
+		like = s.repo.Like
+	}
+	if err := like(ctx, commentID, viewerUID); err != nil {
+		return nil, err
+	}
+	return s.findRow(ctx, commentID, viewerUID)
+}
+
+// authorize 掲示板のクラスのメンバーであることを確認し、ロールを返す。参加申請中とブロック済みのユーザーはメンバーとしない
+func (s *classBoardCommentService) authorize(ctx context.Context, viewerUID uint, bid uint) (string, error) {
+	board, err := s.boardRepo.FindByID(ctx, bid)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", ErrNotFound
+		}
+		return "", err
+	}
+	role, err := s.classUserRepo.GetRole(ctx, viewerUID, board.CID)
+	if err != nil || (role != "ADMIN" && role != "ASSISTANT" && role != "USER") {
+		return "", ErrUnauthorized
+	}
+	return role, nil
+}
+
+// findComment 掲示板のコメントを取得する。別の掲示板のコメントはErrNotFoundとする
+func (s *classBoardCommentService) findComment(ctx context.Context, bid uint, commentID uint) (*models.ClassBoardComment, error) {
+	comment, err := s.repo.FindByID(ctx, commentID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if comment.BID != bid {
+		return nil, ErrNotFound
+	}
+	return comment, nil
+}
+
+// findRow 返信数といいね数付きでコメントを取得してDTOに変換する
+func (s *classBoardCommentService) findRow(ctx context.Context, commentID uint, viewerUID uint) (*dto.ClassBoardCommentDTO, error) {
+	row, err := s.repo.FindRow(ctx, commentID, viewerUID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	comment := toCommentDTO(*row)
+	return &comment, nil
+}
+
+// toCommentDTOs コメントの一覧をDTOに変換する
+func toCommentDTOs(rows []repositories.ClassBoardCommentRow) []dto.ClassBoardCommentDTO {
+	comments := make([]dto.ClassBoardCommentDTO, 0, len(rows))
+	for _, row := range rows {
+		comments = append(comments, toCommentDTO(row))
+	}
+	return comments
+}
+
+// toCommentDTO コメントをDTOに変換する
+func toCommentDTO(row repositories.ClassBoardCommentRow) dto.ClassBoardCommentDTO {
+	return dto.ClassBoardCommentDTO{
+		ID:              row.ID,
+		BID:             row.BID,
+		ParentCommentID: row.ParentCommentID,
+		Content:         row.Content,
+		CreatedAt:       row.CreatedAt,
+		UpdatedAt:       row.UpdatedAt,
+		Author: dto.ClassBoardAuthorDTO{
+			ID:        row.UID,
+			Name:      row.AuthorName,
+			AvatarURL: row.AuthorImage,
+		},
+		ReplyCount: row.ReplyCount,
+		LikeCount:  row.LikeCount,
+		Liked:      row.Liked,
+	}
+}
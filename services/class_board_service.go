@@ -1,47 +1,95 @@
 package services
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/utils"
+	"github.com/go-redis/redis/v8"
+	"gorm.io/gorm"
+	"log"
 	"net/http"
 	"sync"
+	"time"
+)
+
+// viewDedupTTL 同一ユーザーの連続閲覧を重複カウントしない期間
+// boardReadKey ユーザーが閲覧した掲示板IDの集合。ダッシュボードの未読判定に使う
+const (
+	viewDedupTTL      = 10 * time.Minute
+	viewRecordTimeout = 5 * time.Second
+	boardReadKey      = "cb_read:%d"
 )
 
 // ClassBoardService インタフェース
 type ClassBoardService interface {
-	CreateClassBoard(b dto.ClassBoardCreateDTO) (*models.ClassBoard, error)
-	GetAllClassBoards(cid uint, page int, pageSize int) ([]models.ClassBoard, error)
-	GetClassBoardByID(id uint) (*models.ClassBoard, error)
-	GetAnnouncedClassBoards(cid uint) ([]models.ClassBoard, error)
-	UpdateClassBoard(id uint, b dto.ClassBoardUpdateDTO, imageUrl string) (*models.ClassBoard, error) // Added imageUrl parameter
-	DeleteClassBoard(id uint) error
+	CreateClassBoard(ctx context.Context, b dto.ClassBoardCreateDTO) (*models.ClassBoard, error)
+	GetAllClassBoards(ctx context.Context, cid uint, uid uint, page int, pageSize int) ([]models.ClassBoard, error)
+	GetClassBoardByID(ctx context.Context, id uint, uid uint) (*dto.ClassBoardDetailDTO, error)
+	GetAnnouncedClassBoards(ctx context.Context, cid uint, uid uint) ([]models.ClassBoard, error)
+	UpdateClassBoard(ctx context.Context, id uint, b dto.ClassBoardUpdateDTO, imageUrl string) (*models.ClassBoard, error) // Added imageUrl parameter
+	DeleteClassBoard(ctx context.Context, id uint) error
 	GetUpdateNotifier() *UpdateNotifier
-	SearchClassBoardsByTitle(title string, cid uint) ([]models.ClassBoard, error)
+	SearchClassBoardsByTitle(ctx context.Context, title string, cid uint) ([]models.ClassBoard, error)
+	RecordView(id uint, cid uint, uid uint)
+	BulkDeleteClassBoards(ctx context.Context, uid uint, cid uint, before time.Time) (int64, error)
+	PinClassBoard(ctx context.Context, id uint, uid uint, pinned bool, until *time.Time) (*models.ClassBoard, error)
+	UnpinExpiredClassBoards(ctx context.Context) (int64, error)
 }
 
 // classBoardService インタフェースを実装
 type classBoardService struct {
-	repo     repositories.ClassBoardRepository
-	uploader utils.Uploader
-	notifier *UpdateNotifier
+	repo          repositories.ClassBoardRepository
+	classUserRepo repositories.ClassUserRepository
+	uploader      utils.Uploader
+	notifier      *UpdateNotifier
+	redisClient   *redis.Client
+	subscriptions AnnouncementSubscriptionService
+	unread        UnreadService
+	// allowUnversioned バージョンを指定しない更新を後勝ちで受け付ける
+	allowUnversioned bool
+	realtime         RealtimePublisher
+	// webhooks 作成した掲示板をクラスのWebhookに配信する。nilの場合は配信しない
+	webhooks WebhookPublisher
+	// scanner 添付画像のウイルススキャンを予約する。nilの場合はスキャンしない
+	scanner AttachmentScanService
+	// integrations お知らせをクラスが連携したチャットサービスに投稿する。nilの場合は投稿しない
+	integrations IntegrationPublisher
 }
 
-// NewClassBoardService ClassClassServiceを生成
-func NewClassBoardService(repo repositories.ClassBoardRepository) ClassBoardService {
+// NewClassBoardService ClassClassServiceを生成。subscriptionsがnilの場合はお知らせを外部に配信せず、unreadがnilの場合は未読件数を記録しない。
+// allowUnversionedがfalseの場合、バージョンを指定しない更新はErrVersionRequiredとする。realtimeがnilの場合は作成した掲示板をWebSocketで配信せず、webhooksがnilの場合はWebhookで配信しない。
+// scannerがnilの場合は添付画像のウイルススキャンを行わず、integrationsがnilの場合はお知らせをチャットサービスに投稿しない
+func NewClassBoardService(repo repositories.ClassBoardRepository, classUserRepo repositories.ClassUserRepository, uploader utils.Uploader, redisClient *redis.Client, subscriptions AnnouncementSubscriptionService, unread UnreadService, allowUnversioned bool, realtime RealtimePublisher, webhooks WebhookPublisher, scanner AttachmentScanService, integrations IntegrationPublisher) ClassBoardService {
 	notifier := NewUpdateNotifier()
 	return &classBoardService{
-		repo:     repo,
-		uploader: utils.NewAwsUploader(),
-		notifier: notifier,
+		repo:             repo,
+		classUserRepo:    classUserRepo,
+		uploader:         uploader,
+		notifier:         notifier,
+		redisClient:      redisClient,
+		subscriptions:    subscriptions,
+		unread:           unread,
+		allowUnversioned: allowUnversioned,
+		realtime:         realtime,
+		webhooks:         webhooks,
+		scanner:          scanner,
+		integrations:     integrations,
 	}
 }
 
-// CreateClassBoard 新しいグループ掲示板を作成
-func (s *classBoardService) CreateClassBoard(b dto.ClassBoardCreateDTO) (*models.ClassBoard, error) {
+// CreateClassBoard 新しいグループ掲示板を他の言語の版と共に作成。添付画像はスキャン中として保存し、作成後にウイルススキャンを予約する。
+// 同じ言語の版が複数ある場合はErrDuplicateBoardLanguageを返す
+func (s *classBoardService) CreateClassBoard(ctx context.Context, b dto.ClassBoardCreateDTO) (*models.ClassBoard, error) {
+	variants, err := newBoardVariants(b.Language, b.Variants)
+	if err != nil {
+		return nil, err
+	}
+
 	var imageUrl string
-	var err error
 	if b.Image != nil {
 		imageUrl, err = s.uploader.UploadImage(b.Image, b.CID, false)
 		if err != nil {
@@ -56,35 +104,187 @@ func (s *classBoardService) CreateClassBoard(b dto.ClassBoardCreateDTO) (*models
 		IsAnnounced: b.IsAnnounced,
 		CID:         b.CID,
 		UID:         b.UID,
+		Language:    normalizeBoardLanguage(b.Language),
+		Variants:    variants,
+	}
+	classBoard.ScanStatus = s.imageScanStatus(imageUrl)
+	created, err := s.repo.InsertClassBoard(ctx, &classBoard)
+	if err != nil {
+		return nil, err
+	}
+	s.scanImage(*created)
+	if created.IsAnnounced {
+		s.deliverAnnouncement(*created)
+	}
+	if s.unread != nil {
+		if err := s.unread.RecordBoardPost(ctx, *created); err != nil {
+			log.Printf("Redis error while counting unread class board %d: %v", created.ID, err)
+		}
+	}
+	if s.realtime != nil {
+		s.realtime.PublishToClass(ctx, created.CID, dto.RealtimeBoardCreated, dto.RealtimeBoardDTO{
+			ID:          created.ID,
+			CID:         created.CID,
+			UID:         created.UID,
+			Title:       created.Title,
+			IsAnnounced: created.IsAnnounced,
+			CreatedAt:   created.CreatedAt,
+		})
+	}
+	if s.webhooks != nil {
+		s.webhooks.PublishWebhook(ctx, created.CID, models.BoardCreatedWebhook, dto.WebhookPayloadDTO{Board: &dto.WebhookBoardV1{
+			ID:          created.ID,
+			UID:         created.UID,
+			Title:       created.Title,
+			IsAnnounced: created.IsAnnounced,
+			CreatedAt:   created.CreatedAt,
+		}})
+	}
+	return created, nil
+}
+
+// imageScanStatus 新しく添付した画像のスキャンの状態。スキャンを行わない場合は空にする
+func (s *classBoardService) imageScanStatus(image string) models.AttachmentScanStatus {
+	if image == "" || s.scanner == nil {
+		return ""
 	}
-	return s.repo.InsertClassBoard(&classBoard)
+	return models.ScanPending
 }
 
-// GetAllClassBoards 全てのグループ掲示板を取得
-func (s *classBoardService) GetAllClassBoards(cid uint, page int, pageSize int) ([]models.ClassBoard, error) {
+// scanImage スキャン中の添付画像のウイルススキャンを予約する
+func (s *classBoardService) scanImage(board models.ClassBoard) {
+	if s.scanner != nil && board.ScanStatus == models.ScanPending {
+		s.scanner.ScanBoardImage(board)
+	}
+}
+
+// deliverAnnouncement お知らせを外部の通知チャネルの購読者とクラスが連携したチャットサービスに非同期で配信
+func (s *classBoardService) deliverAnnouncement(board models.ClassBoard) {
+	if s.integrations != nil {
+		s.integrations.PostToIntegration(board.CID, models.BoardAnnouncedIntegration, IntegrationPost{
+			Title:     board.Title,
+			AuthorUID: board.UID,
+			Body:      board.Content,
+			Path:      fmt.Sprintf("/classes/%d/boards/%d", board.CID, board.ID),
+		})
+	}
+	if s.subscriptions == nil {
+		return
+	}
+	go func() {
+		// リクエスト終了後も配信するため、リクエストのコンテキストとは切り離す
+		ctx, cancel := context.WithTimeout(context.Background(), announcementDeliveryTimeout)
+		defer cancel()
+		s.subscriptions.DeliverAnnouncement(ctx, board)
+	}()
+}
+
+// GetAllClassBoards 全てのグループ掲示板を、uidのユーザーの言語に最も近い版で取得
+func (s *classBoardService) GetAllClassBoards(ctx context.Context, cid uint, uid uint, page int, pageSize int) ([]models.ClassBoard, error) {
 	offset := (page - 1) * pageSize
-	return s.repo.FindAllPaged(cid, pageSize, offset)
+	boards, err := s.repo.FindAllPaged(ctx, cid, pageSize, offset)
+	if err != nil {
+		return nil, err
+	}
+	_, err = s.localizeClassBoards(ctx, uid, boards)
+	return boards, err
 }
 
-// GetClassBoardByID IDでグループ掲示板を取得
-func (s *classBoardService) GetClassBoardByID(id uint) (*models.ClassBoard, error) {
-	return s.repo.FindByID(id)
+// GetClassBoardByID IDでグループ掲示板を投稿者の公開プロフィール付きで取得。タイトルと本文はuidのユーザーの言語に最も近い版にする
+func (s *classBoardService) GetClassBoardByID(ctx context.Context, id uint, uid uint) (*dto.ClassBoardDetailDTO, error) {
+	b, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	boards := []models.ClassBoard{*b}
+	variants, err := s.localizeClassBoards(ctx, uid, boards)
+	if err != nil {
+		return nil, err
+	}
+	languages := boardLanguages(*b, variants[b.ID])
+	b = &boards[0]
+	return &dto.ClassBoardDetailDTO{
+		ID:          b.ID,
+		Title:       b.Title,
+		Content:     b.Content,
+		Image:       b.Image,
+		ScanStatus:  string(b.ScanStatus),
+		CreatedAt:   b.CreatedAt,
+		UpdatedAt:   b.UpdatedAt,
+		IsAnnounced: b.IsAnnounced,
+		ViewCount:   b.ViewCount,
+		IsPinned:    b.IsPinned,
+		PinnedUntil: b.PinnedUntil,
+		CID:         b.CID,
+		UID:         b.UID,
+		Author: dto.ClassBoardAuthorDTO{
+			ID:        b.User.ID,
+			Name:      b.User.Name,
+			AvatarURL: b.User.Image,
+		},
+		Language:  b.Language,
+		Languages: languages,
+	}, nil
+}
+
+// RecordView 閲覧数を非同期で加算。同一ユーザーの短時間の連続閲覧はRedisで重複を除外。
+// 初めて閲覧した掲示板はクラスの未読掲示から除く
+func (s *classBoardService) RecordView(id uint, cid uint, uid uint) {
+	go func() {
+		// リクエスト終了後も処理するため、リクエストのコンテキストとは切り離す
+		ctx, cancel := context.WithTimeout(context.Background(), viewRecordTimeout)
+		defer cancel()
+
+		added, err := s.redisClient.SAdd(ctx, fmt.Sprintf(boardReadKey, uid), id).Result()
+		if err != nil {
+			log.Printf("Redis error while marking class board %d as read: %v", id, err)
+		}
+		if added > 0 && s.unread != nil {
+			if err := s.unread.RecordBoardRead(ctx, uid, cid); err != nil {
+				log.Printf("Redis error while counting read class board %d: %v", id, err)
+			}
+		}
+
+		key := fmt.Sprintf("cb_view:%d:%d", id, uid)
+		first, err := s.redisClient.SetNX(ctx, key, 1, viewDedupTTL).Result()
+		if err != nil {
+			log.Printf("Redis error while recording view for class board %d: %v", id, err)
+			return
+		}
+		if !first {
+			return
+		}
+
+		if err := s.repo.IncrementViewCount(ctx, id); err != nil {
+			utils.ReportBackgroundError("record_board_view", fmt.Errorf("failed to increment view count for class board %d: %w", id, err))
+		}
+	}()
 }
 
-// GetAnnouncedClassBoards 公開されたグループ掲示板を取得
-func (s *classBoardService) GetAnnouncedClassBoards(cid uint) ([]models.ClassBoard, error) {
-	return s.repo.FindAnnounced(true, cid)
+// GetAnnouncedClassBoards 公開されたグループ掲示板を、uidのユーザーの言語に最も近い版で取得
+func (s *classBoardService) GetAnnouncedClassBoards(ctx context.Context, cid uint, uid uint) ([]models.ClassBoard, error) {
+	boards, err := s.repo.FindAnnounced(ctx, true, cid)
+	if err != nil {
+		return nil, err
+	}
+	_, err = s.localizeClassBoards(ctx, uid, boards)
+	return boards, err
 }
 
-// UpdateClassBoard 更新
-func (s *classBoardService) UpdateClassBoard(id uint, b dto.ClassBoardUpdateDTO, imageUrl string) (*models.ClassBoard, error) {
-	classBoard, err := s.GetClassBoardByID(id)
+// UpdateClassBoard 更新。bのVersionを指定した場合、読み込んだ後に他の更新が保存されていれば最新の掲示板を持つStaleUpdateErrorを返す。
+// 画像を差し替えた場合は新しい画像のウイルススキャンを予約する
+func (s *classBoardService) UpdateClassBoard(ctx context.Context, id uint, b dto.ClassBoardUpdateDTO, imageUrl string) (*models.ClassBoard, error) {
+	if b.Version == 0 && !s.allowUnversioned {
+		return nil, ErrVersionRequired
+	}
+	classBoard, err := s.repo.FindByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
 
 	if imageUrl != "" {
 		classBoard.Image = imageUrl
+		classBoard.ScanStatus = s.imageScanStatus(imageUrl)
 	}
 	if b.Title != "" {
 		classBoard.Title = b.Title
@@ -93,19 +293,83 @@ func (s *classBoardService) UpdateClassBoard(id uint, b dto.ClassBoardUpdateDTO,
 		classBoard.Content = b.Content
 	}
 
+	wasAnnounced := classBoard.IsAnnounced
 	classBoard.IsAnnounced = b.IsAnnounced
 
-	err = s.repo.UpdateClassBoard(classBoard)
+	err = s.repo.UpdateClassBoard(ctx, classBoard, b.Version)
 	if err != nil {
-		return nil, err
+		return nil, staleUpdate(err, func() (interface{}, error) { return s.repo.FindByID(ctx, id) })
+	}
+	if imageUrl != "" {
+		s.scanImage(*classBoard)
+	}
+	if classBoard.IsAnnounced && !wasAnnounced {
+		s.deliverAnnouncement(*classBoard)
 	}
 
 	return classBoard, nil
 }
 
 // DeleteClassBoard 削除
-func (s *classBoardService) DeleteClassBoard(id uint) error {
-	return s.repo.DeleteClassBoard(id)
+func (s *classBoardService) DeleteClassBoard(ctx context.Context, id uint) error {
+	return s.repo.DeleteClassBoard(ctx, id)
+}
+
+// BulkDeleteClassBoards 指定日時より前に作成されたクラスのグループ掲示板を一括削除し、削除件数を返す。
+// 添付画像は掲示板の削除後にS3から削除し、失敗した場合はエラー監視に送信する
+func (s *classBoardService) BulkDeleteClassBoards(ctx context.Context, uid uint, cid uint, before time.Time) (int64, error) {
+	isAdmin, err := s.classUserRepo.IsAdmin(ctx, uid, cid)
+	if err != nil || !isAdmin {
+		return 0, ErrUnauthorized
+	}
+
+	deleted, err := s.repo.DeleteByClassCreatedBefore(ctx, cid, before)
+	if err != nil {
+		return 0, err
+	}
+
+	images := make([]string, 0, len(deleted))
+	for _, board := range deleted {
+		if board.Image != "" {
+			images = append(images, board.Image)
+		}
+	}
+	if err := s.uploader.DeleteObjects(ctx, images); err != nil {
+		utils.ReportBackgroundError("bulk_delete_class_boards", fmt.Errorf("failed to delete images of class %d: %w", cid, err))
+	}
+	return int64(len(deleted)), nil
+}
+
+// PinClassBoard グループ掲示板のピン留めを設定する。untilを指定した場合はその日時に自動的に解除する。
+// クラスの管理者のみ実行できる
+func (s *classBoardService) PinClassBoard(ctx context.Context, id uint, uid uint, pinned bool, until *time.Time) (*models.ClassBoard, error) {
+	classBoard, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	isAdmin, err := s.classUserRepo.IsAdmin(ctx, uid, classBoard.CID)
+	if err != nil || !isAdmin {
+		return nil, ErrUnauthorized
+	}
+
+	classBoard.IsPinned = pinned
+	classBoard.PinnedUntil = nil
+	if pinned {
+		classBoard.PinnedUntil = until
+	}
+	if err := s.repo.UpdateClassBoard(ctx, classBoard, 0); err != nil {
+		return nil, err
+	}
+	return classBoard, nil
+}
+
+// UnpinExpiredClassBoards 期限を過ぎたピン留めを解除し、解除した件数を返す
+func (s *classBoardService) UnpinExpiredClassBoards(ctx context.Context) (int64, error) {
+	return s.repo.UnpinExpired(ctx, time.Now())
 }
 
 type UpdateNotifier struct {
@@ -127,6 +391,13 @@ func NewUpdateNotifier() *UpdateNotifier {
 	return notifier
 }
 
+// ClientCount 更新を購読しているSSEクライアントの数を返す
+func (u *UpdateNotifier) ClientCount() int {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return len(u.clients)
+}
+
 func (u *UpdateNotifier) run() {
 	for {
 		select {
@@ -155,6 +426,6 @@ func (s *classBoardService) GetUpdateNotifier() *UpdateNotifier {
 	return s.notifier
 }
 
-func (s *classBoardService) SearchClassBoardsByTitle(title string, cid uint) ([]models.ClassBoard, error) {
-	return s.repo.SearchByTitle(title, cid)
+func (s *classBoardService) SearchClassBoardsByTitle(ctx context.Context, title string, cid uint) ([]models.ClassBoard, error) {
+	return s.repo.SearchByTitle(ctx, title, cid)
 }
@@ -0,0 +1,119 @@
+package services
+
+import (
+	"context"
+	"strings"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+)
+
+// newBoardVariants 作成するグループ掲示板の他の言語の版を作成する。掲示板の言語を含め、同じ言語が複数ある場合はErrDuplicateBoardLanguageを返す
+func newBoardVariants(language string, requests []dto.ClassBoardVariantDTO) ([]models.ClassBoardVariant, error) {
+	seen := make(map[string]bool, len(requests)+1)
+	if language = normalizeBoardLanguage(language); language != "" {
+		seen[strings.ToLower(language)] = true
+	}
+	variants := make([]models.ClassBoardVariant, 0, len(requests))
+	for _, request := range requests {
+		variantLanguage := normalizeBoardLanguage(request.Language)
+		if seen[strings.ToLower(variantLanguage)] {
+			return nil, ErrDuplicateBoardLanguage
+		}
+		seen[strings.ToLower(variantLanguage)] = true
+		variants = append(variants, models.ClassBoardVariant{
+			Language: variantLanguage,
+			Title:    request.Title,
+			Content:  request.Content,
+		})
+	}
+	return variants, nil
+}
+
+// normalizeBoardLanguage 言語の区切りをハイフンに揃える (例: en_GB → en-GB)
+func normalizeBoardLanguage(language string) string {
+	return strings.ReplaceAll(strings.TrimSpace(language), "_", "-")
+}
+
+// localizeClassBoards 掲示板のタイトルと本文を、uidのユーザーの言語に最も近い版に置き換える。読み込んだ他の言語の版を掲示板のIDごとに返す
+func (s *classBoardService) localizeClassBoards(ctx context.Context, uid uint, boards []models.ClassBoard) (map[uint][]models.ClassBoardVariant, error) {
+	if len(boards) == 0 {
+		return nil, nil
+	}
+	bids := make([]uint, 0, len(boards))
+	for _, board := range boards {
+		bids = append(bids, board.ID)
+	}
+	found, err := s.repo.FindVariants(ctx, bids)
+	if err != nil {
+		return nil, err
+	}
+	variants := make(map[uint][]models.ClassBoardVariant, len(boards))
+	for _, variant := range found {
+		variants[variant.BID] = append(variants[variant.BID], variant)
+	}
+	if len(variants) == 0 {
+		return variants, nil
+	}
+
+	locale, err := s.repo.FindUserLocale(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	for i := range boards {
+		if variant := selectBoardVariant(boards[i].Language, variants[boards[i].ID], locale); variant != nil {
+			boards[i].Title = variant.Title
+			boards[i].Content = variant.Content
+			boards[i].Language = variant.Language
+		}
+	}
+	return variants, nil
+}
+
+// selectBoardVariant ユーザーの言語 (例: en-GB) に最も近い版を選ぶ。言語が一致する版を優先し、なければ主言語 (例: en) が一致する版を選ぶ。
+// 掲示板の言語の方が近いか、近い版がない場合はnilを返し、掲示板のタイトルと本文を表示する
+func selectBoardVariant(language string, variants []models.ClassBoardVariant, locale string) *models.ClassBoardVariant {
+	locale = normalizeBoardLanguage(locale)
+	var selected *models.ClassBoardVariant
+	best := boardLanguageMatch(language, locale)
+	for i := range variants {
+		if match := boardLanguageMatch(variants[i].Language, locale); match > best {
+			selected = &variants[i]
+			best = match
+		}
+	}
+	return selected
+}
+
+// boardLanguageMatch 版の言語とユーザーの言語の近さ。一致する場合は2、主言語のみ一致する場合は1、それ以外は0
+func boardLanguageMatch(language string, locale string) int {
+	switch {
+	case language == "" || locale == "":
+		return 0
+	case strings.EqualFold(language, locale):
+		return 2
+	case strings.EqualFold(primaryBoardLanguage(language), primaryBoardLanguage(locale)):
+		return 1
+	}
+	return 0
+}
+
+// primaryBoardLanguage 言語の主言語 (例: en-GB → en)
+func primaryBoardLanguage(language string) string {
+	if i := strings.Index(language, "-"); i >= 0 {
+		return language[:i]
+	}
+	return language
+}
+
+// boardLanguages 掲示板を表示できる言語。掲示板の言語を指定していない場合は他の言語の版の言語のみ
+func boardLanguages(board models.ClassBoard, variants []models.ClassBoardVariant) []string {
+	languages := make([]string, 0, len(variants)+1)
+	if board.Language != "" {
+		languages = append(languages, board.Language)
+	}
+	for _, variant := range variants {
+		languages = append(languages, variant.Language)
+	}
+	return languages
+}
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"errors"
 
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
@@ -11,9 +12,9 @@ const ErrClassNotFound = "class not found"
 
 // ClassCodeService はグループコードのサービスです。
 type ClassCodeService interface {
-	CheckSecretExists(code string) (bool, error)
-	VerifyClassCode(code, secret string) (bool, error)
-	FindClassCode(code string) (*models.ClassCode, error)
+	CheckSecretExists(ctx context.Context, code string) (bool, error)
+	VerifyClassCode(ctx context.Context, code, secret string) (bool, error)
+	FindClassCode(ctx context.Context, code string) (*models.ClassCode, error)
 }
 
 // classCodeServiceImpl はClassCodeServiceの実装です。
@@ -27,8 +28,8 @@ func NewClassCodeService(repo repositories.ClassCodeRepository) ClassCodeService
 }
 
 // FindClassCode findClassCode は指定されたグループコードを取得します。
-func (s *classCodeServiceImpl) FindClassCode(code string) (*models.ClassCode, error) {
-	classCode, err := s.repo.FindByCode(code)
+func (s *classCodeServiceImpl) FindClassCode(ctx context.Context, code string) (*models.ClassCode, error) {
+	classCode, err := s.repo.FindByCode(ctx, code)
 	if err != nil {
 		return nil, err
 	}
@@ -39,8 +40,8 @@ func (s *classCodeServiceImpl) FindClassCode(code string) (*models.ClassCode, er
 }
 
 // CheckSecretExists は指定されたグループコードにシークレットがあるかどうかをチェックします。
-func (s *classCodeServiceImpl) CheckSecretExists(code string) (bool, error) {
-	classCode, err := s.FindClassCode(code)
+func (s *classCodeServiceImpl) CheckSecretExists(ctx context.Context, code string) (bool, error) {
+	classCode, err := s.FindClassCode(ctx, code)
 	if err != nil {
 		return false, err
 	}
@@ -55,8 +56,8 @@ func (s *classCodeServiceImpl) CheckSecretExists(code string) (bool, error) {
 }
 
 // VerifyClassCode はグループコードと、該当する場合はそのシークレットを確認します。
-func (s *classCodeServiceImpl) VerifyClassCode(code string, secret string) (bool, error) {
-	classCode, err := s.FindClassCode(code)
+func (s *classCodeServiceImpl) VerifyClassCode(ctx context.Context, code string, secret string) (bool, error) {
+	classCode, err := s.FindClassCode(ctx, code)
 	if err != nil {
 		return false, err
 	}
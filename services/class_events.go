@@ -0,0 +1,67 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+)
+
+// classEventsChannel クラスのイベントを配信するRedisのチャンネル
+const classEventsChannel = "class:events:%d"
+
+// publishClassEvent クラスのイベントを配信する。配信に失敗しても元の操作は失敗させない
+func (s *classUserServiceImpl) publishClassEvent(ctx context.Context, event dto.ClassEventDTO) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("failed to encode class event: %v", err)
+		return
+	}
+	if err := s.redisClient.Publish(ctx, fmt.Sprintf(classEventsChannel, event.CID), data).Err(); err != nil {
+		log.Printf("failed to publish %s event for class %d: %v", event.Type, event.CID, err)
+	}
+}
+
+// SubscribeClassEvents クラスのイベントを購読する。クラスの管理者のみ購読でき、ctxが終了すると購読を解除してチャンネルを閉じる
+func (s *classUserServiceImpl) SubscribeClassEvents(ctx context.Context, viewerUID uint, cid uint) (<-chan dto.ClassEventDTO, error) {
+	isAdmin, err := s.classUserRepo.IsAdmin(ctx, viewerUID, cid)
+	if err != nil || !isAdmin {
+		return nil, ErrUnauthorized
+	}
+
+	pubsub := s.redisClient.Subscribe(ctx, fmt.Sprintf(classEventsChannel, cid))
+	// 購読の完了を待ち、接続できない場合はストリームを開始しない
+	if _, err := pubsub.Receive(ctx); err != nil {
+		_ = pubsub.Close()
+		return nil, err
+	}
+
+	events := make(chan dto.ClassEventDTO)
+	go func() {
+		defer close(events)
+		defer pubsub.Close()
+		messages := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case message, ok := <-messages:
+				if !ok {
+					return
+				}
+				var event dto.ClassEventDTO
+				if err := json.Unmarshal([]byte(message.Payload), &event); err != nil {
+					continue
+				}
+				select {
+				case events <- event:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return events, nil
+}
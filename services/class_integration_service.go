@@ -0,0 +1,363 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/utils"
+	"gorm.io/gorm"
+)
+
+const (
+	integrationQueueSize   = 256
+	integrationWorkerCount = 2
+	// integrationMaxAttempts 接続できない場合や投稿先が5xx・429を返した場合に1つのイベントを投稿する最大の回数
+	integrationMaxAttempts             = 3
+	integrationStepTimeout             = 30 * time.Second
+	defaultIntegrationRetryDelay       = 10 * time.Second
+	defaultIntegrationClientErrorLimit = 5
+	integrationMaskedPathSegment       = "****"
+)
+
+// integrationEventNames 連携のイベントの種類の表示名
+var integrationEventNames = map[models.IntegrationEvent]string{
+	models.BoardAnnouncedIntegration:  "お知らせ",
+	models.ScheduleChangedIntegration: "スケジュールの変更",
+	models.LiveStartedIntegration:     "ライブ授業の開始",
+}
+
+// ClassIntegrationConfig クラスのイベントをチャットサービスに投稿する設定
+type ClassIntegrationConfig struct {
+	// Posters チャットサービスごとの投稿処理。投稿処理がないサービスとは連携できない
+	Posters map[models.IntegrationProvider]utils.ChatPoster
+	// AppURL メッセージに載せるリンクのアプリのURL。空の場合はリンクを載せない
+	AppURL string
+	// RetryDelay 投稿に失敗したイベントを最初に再投稿するまでの時間。再投稿するたびに2倍にする。0の場合は10秒
+	RetryDelay time.Duration
+	// ClientErrorLimit 投稿を停止する、投稿先が4xxを返した連続の回数。0の場合は5回
+	ClientErrorLimit int
+}
+
+// IntegrationPost チャットサービスに投稿するクラスのイベント
+type IntegrationPost struct {
+	Title string
+	// AuthorUID 投稿者。0の場合は投稿者を載せない
+	AuthorUID uint
+	Body      string
+	// Path アプリで詳細を表示するページのパス。AppURLに続けてリンクにする
+	Path string
+}
+
+// IntegrationPublisher クラスのイベントをクラスが連携したチャットサービスに投稿する。
+// 投稿は非同期で行い、失敗しても元の操作は失敗させず、結果は連携の状態に記録する
+type IntegrationPublisher interface {
+	PostToIntegration(cid uint, event models.IntegrationEvent, post IntegrationPost)
+}
+
+// ClassIntegrationService クラスのチャットサービスとの連携の管理と投稿を行うサービス。連携の管理はクラスの管理者のみ利用できる
+type ClassIntegrationService interface {
+	IntegrationPublisher
+	GetIntegration(ctx context.Context, viewerUID uint, cid uint) (*dto.ClassIntegrationDTO, error)
+	SaveIntegration(ctx context.Context, viewerUID uint, cid uint, request dto.ClassIntegrationRequest) (*dto.ClassIntegrationDTO, error)
+	DeleteIntegration(ctx context.Context, viewerUID uint, cid uint) error
+}
+
+// classIntegrationService インタフェースを実装
+type classIntegrationService struct {
+	repo          repositories.ClassIntegrationRepository
+	classUserRepo repositories.ClassUserRepository
+	config        ClassIntegrationConfig
+	jobs          chan integrationJob
+}
+
+// integrationJob 1つのイベントの1回の投稿
+type integrationJob struct {
+	cid     uint
+	event   models.IntegrationEvent
+	post    IntegrationPost
+	attempt int
+}
+
+// NewClassIntegrationService ClassIntegrationServiceを生成し、投稿を行うワーカーを開始する
+func NewClassIntegrationService(repo repositories.ClassIntegrationRepository, classUserRepo repositories.ClassUserRepository, config ClassIntegrationConfig) ClassIntegrationService {
+	if config.RetryDelay == 0 {
+		config.RetryDelay = defaultIntegrationRetryDelay
+	}
+	if config.ClientErrorLimit == 0 {
+		config.ClientErrorLimit = defaultIntegrationClientErrorLimit
+	}
+	s := &classIntegrationService{
+		repo:          repo,
+		classUserRepo: classUserRepo,
+		config:        config,
+		jobs:          make(chan integrationJob, integrationQueueSize),
+	}
+	for i := 0; i < integrationWorkerCount; i++ {
+		go s.runWorker()
+	}
+	return s
+}
+
+// GetIntegration クラスの連携を取得する。登録していない場合はErrNotFoundを返す
+func (s *classIntegrationService) GetIntegration(ctx context.Context, viewerUID uint, cid uint) (*dto.ClassIntegrationDTO, error) {
+	if err := s.authorize(ctx, viewerUID, cid); err != nil {
+		return nil, err
+	}
+	return s.findIntegration(ctx, cid)
+}
+
+// SaveIntegration 連携先にテストメッセージを投稿し、投稿できた場合のみクラスの連携を登録する。
+// 登録済みの場合は置き換え、停止していた投稿を再開する
+func (s *classIntegrationService) SaveIntegration(ctx context.Context, viewerUID uint, cid uint, request dto.ClassIntegrationRequest) (*dto.ClassIntegrationDTO, error) {
+	if err := s.authorize(ctx, viewerUID, cid); err != nil {
+		return nil, err
+	}
+	provider := models.IntegrationProvider(request.Provider)
+	poster, ok := s.config.Posters[provider]
+	if !ok {
+		return nil, ErrChannelUnavailable
+	}
+	if err := validateIntegrationURL(provider, request.WebhookURL); err != nil {
+		return nil, err
+	}
+
+	integration := models.ClassIntegration{
+		CID:        cid,
+		Provider:   provider,
+		WebhookURL: request.WebhookURL,
+		Events:     joinWebhookEvents(request.Events),
+		Status:     models.IntegrationOK,
+	}
+	if _, err := poster.Post(ctx, integration.WebhookURL, s.testMessage(integration)); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrIntegrationTestFailed, err)
+	}
+	now := time.Now()
+	integration.LastPostedAt = &now
+	if err := s.repo.SaveIntegration(ctx, &integration); err != nil {
+		return nil, err
+	}
+	return s.findIntegration(ctx, cid)
+}
+
+// DeleteIntegration クラスの連携を削除する。投稿を待つイベントは投稿しない
+func (s *classIntegrationService) DeleteIntegration(ctx context.Context, viewerUID uint, cid uint) error {
+	if err := s.authorize(ctx, viewerUID, cid); err != nil {
+		return err
+	}
+	deleted, err := s.repo.DeleteIntegration(ctx, cid)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// PostToIntegration イベントの投稿を予約する。連携の確認と投稿は非同期で行い、キューが一杯の場合はリクエストを待たせずに破棄する
+func (s *classIntegrationService) PostToIntegration(cid uint, event models.IntegrationEvent, post IntegrationPost) {
+	s.enqueue(integrationJob{cid: cid, event: event, post: post, attempt: 1})
+}
+
+// enqueue 投稿を予約する。キューが一杯の場合は破棄する
+func (s *classIntegrationService) enqueue(job integrationJob) {
+	select {
+	case s.jobs <- job:
+	default:
+		log.Printf("Integration queue is full. Dropped %s event of class %d", job.event, job.cid)
+	}
+}
+
+// runWorker 予約された投稿を順に行う
+func (s *classIntegrationService) runWorker() {
+	for job := range s.jobs {
+		s.deliver(job)
+	}
+}
+
+// deliver クラスの連携がイベントを投稿する場合に1回投稿し、結果を連携の状態に記録する。
+// 4xxは再投稿しても成功しないため再投稿せず、それ以外の失敗は間隔を空けて再投稿を予約する
+func (s *classIntegrationService) deliver(job integrationJob) {
+	var integration *models.ClassIntegration
+	err := withIntegrationTimeout(func(ctx context.Context) (err error) {
+		integration, err = s.repo.FindIntegration(ctx, job.cid)
+		return err
+	})
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.ReportBackgroundError("post_integration", fmt.Errorf("failed to find integration of class %d: %w", job.cid, err))
+		}
+		return
+	}
+	poster := s.config.Posters[integration.Provider]
+	if poster == nil || !integration.Subscribes(job.event) {
+		return
+	}
+
+	var status int
+	postErr := withIntegrationTimeout(func(ctx context.Context) (err error) {
+		status, err = poster.Post(ctx, integration.WebhookURL, s.buildMessage(ctx, job))
+		return err
+	})
+	now := time.Now()
+	switch {
+	case postErr == nil:
+		err = withIntegrationTimeout(func(ctx context.Context) error {
+			return s.repo.RecordPosted(ctx, integration.ID, now)
+		})
+	case status >= 400 && status < 500 && status != 429:
+		message := truncateWebhookError(postErr.Error())
+		err = withIntegrationTimeout(func(ctx context.Context) error {
+			disabled, err := s.repo.RecordClientError(ctx, integration.ID, message, now, s.config.ClientErrorLimit)
+			if disabled {
+				log.Printf("Integration of class %d was disabled after %d client errors", job.cid, s.config.ClientErrorLimit)
+			}
+			return err
+		})
+	case job.attempt < integrationMaxAttempts:
+		delay := s.config.RetryDelay << (job.attempt - 1)
+		job.attempt++
+		time.AfterFunc(delay, func() { s.enqueue(job) })
+		return
+	default:
+		message := truncateWebhookError(postErr.Error())
+		err = withIntegrationTimeout(func(ctx context.Context) error {
+			return s.repo.RecordFailure(ctx, integration.ID, message, now)
+		})
+	}
+	if err != nil {
+		utils.ReportBackgroundError("post_integration", fmt.Errorf("failed to record result of integration %d: %w", integration.ID, err))
+	}
+}
+
+// buildMessage イベントを投稿するメッセージに変換する。投稿者のニックネームを取得できない場合は投稿者を載せない
+func (s *classIntegrationService) buildMessage(ctx context.Context, job integrationJob) utils.ChatMessage {
+	message := utils.ChatMessage{
+		Title: job.post.Title,
+		Body:  job.post.Body,
+		Link:  s.appLink(job.post.Path),
+	}
+	if job.post.AuthorUID != 0 {
+		member, err := s.classUserRepo.GetClassUserInfo(ctx, job.post.AuthorUID, job.cid)
+		if err != nil {
+			log.Printf("Failed to load nickname of uid %d in class %d: %v", job.post.AuthorUID, job.cid, err)
+		}
+		message.Author = member.Nickname
+	}
+	return message
+}
+
+// testMessage 連携を保存する前に投稿する、投稿するイベントを知らせるメッセージ
+func (s *classIntegrationService) testMessage(integration models.ClassIntegration) utils.ChatMessage {
+	names := make([]string, 0, len(integrationEventNames))
+	for _, event := range integration.EventList() {
+		names = append(names, integrationEventNames[event])
+	}
+	return utils.ChatMessage{
+		Title: "Minoriとの連携を設定しました",
+		Body:  fmt.Sprintf("このチャンネルにクラスの%sを投稿します。", strings.Join(names, "・")),
+		Link:  s.appLink(fmt.Sprintf("/classes/%d", integration.CID)),
+	}
+}
+
+// appLink アプリのページのURLを返す。AppURLがない場合は空にする
+func (s *classIntegrationService) appLink(path string) string {
+	if s.config.AppURL == "" || path == "" {
+		return ""
+	}
+	return strings.TrimRight(s.config.AppURL, "/") + path
+}
+
+// authorize ユーザーがクラスの管理者か確認する
+func (s *classIntegrationService) authorize(ctx context.Context, uid uint, cid uint) error {
+	isAdmin, err := s.classUserRepo.IsAdmin(ctx, uid, cid)
+	if err != nil || !isAdmin {
+		return ErrUnauthorized
+	}
+	return nil
+}
+
+// findIntegration クラスの連携をDTOで取得する。登録していない場合はErrNotFoundにする
+func (s *classIntegrationService) findIntegration(ctx context.Context, cid uint) (*dto.ClassIntegrationDTO, error) {
+	integration, err := s.repo.FindIntegration(ctx, cid)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	result := toClassIntegrationDTO(*integration)
+	return &result, nil
+}
+
+// validateIntegrationURL URLが連携先のサービスが発行したWebhookのURLか確認する。
+// 投稿先を限定し、連携の設定から任意のURLに送信されることを防ぐ
+func validateIntegrationURL(provider models.IntegrationProvider, rawURL string) error {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Scheme != "https" || parsed.User != nil || parsed.Port() != "" {
+		return ErrInvalidIntegrationURL
+	}
+	host := strings.ToLower(parsed.Hostname())
+	switch provider {
+	case models.SlackIntegration:
+		if host == "hooks.slack.com" && strings.HasPrefix(parsed.Path, "/services/") {
+			return nil
+		}
+	case models.DiscordIntegration:
+		if (host == "discord.com" || host == "discordapp.com") && strings.HasPrefix(parsed.Path, "/api/webhooks/") {
+			return nil
+		}
+	}
+	return ErrInvalidIntegrationURL
+}
+
+// maskIntegrationURL URLに含まれるトークンを返さないよう、最後のパスを伏せる
+func maskIntegrationURL(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	parsed.RawQuery = ""
+	if i := strings.LastIndex(parsed.Path, "/"); i >= 0 {
+		parsed.Path = parsed.Path[:i+1] + integrationMaskedPathSegment
+	}
+	parsed.RawPath = ""
+	return parsed.String()
+}
+
+// withIntegrationTimeout 連携の投稿の1つの処理にタイムアウトを設けて実行する
+func withIntegrationTimeout(fn func(ctx context.Context) error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), integrationStepTimeout)
+	defer cancel()
+	return fn(ctx)
+}
+
+// toClassIntegrationDTO 連携をDTOに変換する
+func toClassIntegrationDTO(integration models.ClassIntegration) dto.ClassIntegrationDTO {
+	events := make([]string, 0)
+	for _, event := range integration.EventList() {
+		events = append(events, string(event))
+	}
+	return dto.ClassIntegrationDTO{
+		CID:                     integration.CID,
+		Provider:                string(integration.Provider),
+		WebhookURL:              maskIntegrationURL(integration.WebhookURL),
+		Events:                  events,
+		Status:                  string(integration.Status),
+		LastError:               integration.LastError,
+		ConsecutiveClientErrors: integration.ConsecutiveClientErrors,
+		LastPostedAt:            integration.LastPostedAt,
+		LastFailedAt:            integration.LastFailedAt,
+		DisabledAt:              integration.DisabledAt,
+		CreatedAt:               integration.CreatedAt,
+		UpdatedAt:               integration.UpdatedAt,
+	}
+}
@@ -0,0 +1,201 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+	"github.com/go-redis/redis/v8"
+	"gorm.io/gorm"
+)
+
+const (
+	// ClassInvitationDailyLimit 1人のユーザーが1日に送信できる招待メールの件数。全てのクラスの招待を合わせて数える。
+	// 存在しないアドレスへの大量の送信で送信元の評価が下がらないよう、クラスを作り直しても上限が戻らないようにする
+	ClassInvitationDailyLimit = 200
+
+	// classInvitationKey 招待したユーザーごとの招待メールの送信数のキー
+	classInvitationKey    = "class_invitations:user:%d"
+	classInvitationWindow = 24 * time.Hour
+)
+
+// ClassInvitationService メールでクラスに招待するサービス
+type ClassInvitationService interface {
+	InviteByEmail(ctx context.Context, uid uint, cid uint, request dto.ClassInvitationRequest) (*dto.ClassInvitationResultDTO, error)
+}
+
+// classInvitationService インタフェースを実装
+type classInvitationService struct {
+	classRepo     repositories.ClassRepository
+	classCodeRepo repositories.ClassCodeRepository
+	classUserRepo repositories.ClassUserRepository
+	userRepo      repositories.UserRepository
+	mail          MailService
+	// redisClient 招待メールの送信数の記録に使う。nilの場合は送信数を制限しない
+	redisClient *redis.Client
+	// inviteURL 招待メールに載せる参加用のリンク。空の場合はクラスコードのみ載せる
+	inviteURL string
+}
+
+// NewClassInvitationService ClassInvitationServiceを生成
+func NewClassInvitationService(classRepo repositories.ClassRepository, classCodeRepo repositories.ClassCodeRepository, classUserRepo repositories.ClassUserRepository, userRepo repositories.UserRepository, mail MailService, redisClient *redis.Client, inviteURL string) ClassInvitationService {
+	return &classInvitationService{
+		classRepo:     classRepo,
+		classCodeRepo: classCodeRepo,
+		classUserRepo: classUserRepo,
+		userRepo:      userRepo,
+		mail:          mail,
+		redisClient:   redisClient,
+		inviteURL:     inviteURL,
+	}
+}
+
+// InviteByEmail クラスの管理者が、指定したメールアドレスにクラスコードを載せた招待メールを送信する。
+// アカウントがあるアドレスはユーザーの言語で、ないアドレスはリクエストの言語で送信し、既にメンバーのユーザーには送信しない。
+// 招待したユーザーの1日の送信数が上限を超える場合は1件も送信せずにErrInvitationLimitを返す
+func (s *classInvitationService) InviteByEmail(ctx context.Context, uid uint, cid uint, request dto.ClassInvitationRequest) (*dto.ClassInvitationResultDTO, error) {
+	isAdmin, err := s.classUserRepo.IsAdmin(ctx, uid, cid)
+	if err != nil || !isAdmin {
+		return nil, ErrUnauthorized
+	}
+	class, err := s.classRepo.GetByID(ctx, cid)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	classCode, err := s.classCodeRepo.FindByClassID(ctx, cid)
+	if err != nil {
+		return nil, err
+	}
+	if classCode == nil {
+		return nil, ErrNotFound
+	}
+	inviter, err := s.userRepo.FindByID(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	emails := uniqueEmails(request.Emails)
+	users, err := s.findUsersByEmail(ctx, cid, emails)
+	if err != nil {
+		return nil, err
+	}
+	result := &dto.ClassInvitationResultDTO{Invited: []string{}, Skipped: []string{}}
+	var requests []MailRequest
+	for _, email := range emails {
+		user, ok := users[strings.ToLower(email)]
+		if ok && user == nil {
+			result.Skipped = append(result.Skipped, email)
+			continue
+		}
+		mailRequest := MailRequest{To: email, Locale: request.Locale, Template: ClassInvitationMail}
+		if user != nil {
+			mailRequest.Data.Name = user.Name
+			if user.Locale != "" {
+				mailRequest.Locale = user.Locale
+			}
+		}
+		requests = append(requests, mailRequest)
+		result.Invited = append(result.Invited, email)
+	}
+
+	result.Remaining, err = s.reserveInvitations(ctx, uid, len(requests))
+	if err != nil {
+		return nil, err
+	}
+	joinURL, err := classJoinURL(s.inviteURL, classCode.Code)
+	if err != nil {
+		return nil, err
+	}
+	for _, mailRequest := range requests {
+		mailRequest.Data.ClassName = class.Name
+		mailRequest.Data.InviterName = inviter.Name
+		mailRequest.Data.JoinCode = classCode.Code
+		mailRequest.Data.JoinURL = joinURL
+		mailRequest.Data.SecretRequired = classCode.Secret != nil && *classCode.Secret != ""
+		if err := s.mail.Send(ctx, mailRequest); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// findUsersByEmail メールアドレスが一致するユーザーを小文字のアドレスごとに返す。既にクラスのメンバーのユーザーはnilにする
+func (s *classInvitationService) findUsersByEmail(ctx context.Context, cid uint, emails []string) (map[string]*models.User, error) {
+	users, err := s.userRepo.FindByEmails(ctx, emails)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]*models.User, len(users))
+	uids := make([]uint, 0, len(users))
+	for i := range users {
+		result[strings.ToLower(users[i].Email)] = &users[i]
+		uids = append(uids, users[i].ID)
+	}
+	if len(uids) == 0 {
+		return result, nil
+	}
+
+	members, err := s.classUserRepo.FindMembersByUIDs(ctx, cid, uids)
+	if err != nil {
+		return nil, err
+	}
+	isMember := make(map[uint]bool, len(members))
+	for _, member := range members {
+		isMember[member.UID] = true
+	}
+	for email, user := range result {
+		if isMember[user.ID] {
+			result[email] = nil
+		}
+	}
+	return result, nil
+}
+
+// reserveInvitations 招待したユーザーの今日の招待メールの送信数にn件を加え、残りの件数を返す。
+// 上限を超える場合は加えずにErrInvitationLimitを返す。送信数は最初に送信してから24時間で数え直す
+func (s *classInvitationService) reserveInvitations(ctx context.Context, uid uint, n int) (int, error) {
+	if s.redisClient == nil {
+		return ClassInvitationDailyLimit - n, nil
+	}
+	key := fmt.Sprintf(classInvitationKey, uid)
+	count, err := s.redisClient.IncrBy(ctx, key, int64(n)).Result()
+	if err != nil {
+		return 0, err
+	}
+	if count == int64(n) {
+		if err := s.redisClient.Expire(ctx, key, classInvitationWindow).Err(); err != nil {
+			return 0, err
+		}
+	}
+	if count > ClassInvitationDailyLimit {
+		if err := s.redisClient.DecrBy(ctx, key, int64(n)).Err(); err != nil {
+			return 0, err
+		}
+		return 0, ErrInvitationLimit
+	}
+	return ClassInvitationDailyLimit - int(count), nil
+}
+
+// uniqueEmails 前後の空白を除き、大文字と小文字の違いのみのアドレスを最初の1件にまとめる
+func uniqueEmails(emails []string) []string {
+	seen := make(map[string]bool, len(emails))
+	result := make([]string, 0, len(emails))
+	for _, email := range emails {
+		email = strings.TrimSpace(email)
+		key := strings.ToLower(email)
+		if email == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		result = append(result, email)
+	}
+	return result
+}
@@ -1,89 +1,469 @@
 package services
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/utils"
+	"github.com/go-redis/redis/v8"
+	"gorm.io/gorm"
 )
 
 // ClassScheduleService インタフェース
 type ClassScheduleService interface {
-	CreateClassSchedule(classSchedule *models.ClassSchedule) (*models.ClassSchedule, error)
-	GetClassScheduleByID(cid uint) (*models.ClassSchedule, error)
-	GetAllClassSchedules(cid uint) ([]models.ClassSchedule, error)
-	UpdateClassSchedule(id uint, dto *dto.UpdateClassScheduleDTO) (*models.ClassSchedule, error)
-	DeleteClassSchedule(id uint) error
-	GetLiveClassSchedules(cid uint) ([]models.ClassSchedule, error)
-	GetClassSchedulesByDate(cid uint, date string) ([]models.ClassSchedule, error)
+	CreateClassSchedule(ctx context.Context, classSchedule *models.ClassSchedule) (*models.ClassSchedule, error)
+	GetClassScheduleByID(ctx context.Context, cid uint) (*dto.ClassScheduleDetailDTO, error)
+	GetAllClassSchedules(ctx context.Context, cid uint) ([]models.ClassSchedule, error)
+	UpdateClassSchedule(ctx context.Context, id uint, dto *dto.UpdateClassScheduleDTO) (*models.ClassSchedule, error)
+	DeleteClassSchedule(ctx context.Context, id uint, uid uint, force bool) (bool, error)
+	RestoreClassSchedule(ctx context.Context, cid uint, id uint, uid uint) error
+	SetClassScheduleCancelled(ctx context.Context, id uint, uid uint, cancelled bool) (*models.ClassSchedule, error)
+	GetLiveClassSchedules(ctx context.Context, cid uint) ([]models.ClassSchedule, error)
+	GetClassSchedulesByDate(ctx context.Context, cid uint, date time.Time, mode models.AttendanceMode) ([]models.ClassSchedule, error)
+	ExportClassICal(ctx context.Context, uid uint, cid uint) (string, error)
+	ExportUserICal(ctx context.Context, uid uint) (string, error)
+	GetClassesWithSchedulesToday(ctx context.Context, uid uint, loc *time.Location) ([]dto.TodayClassDTO, error)
+	GetCheckInCode(ctx context.Context, uid uint, csid uint) (*dto.CheckInCodeDTO, error)
+	VerifyCheckInCode(ctx context.Context, uid uint, csid uint, code string) error
+	IssueCheckInToken(ctx context.Context, uid uint, csid uint) (*dto.CheckInTokenDTO, error)
+	CheckinWithToken(ctx context.Context, uid uint, token string, code string) (*models.ClassSchedule, error)
 }
 
 // classScheduleService インタフェースを実装
 type classScheduleService struct {
-	repo repositories.ClassScheduleRepository
+	repo          repositories.ClassScheduleRepository
+	classUserRepo repositories.ClassUserRepository
+	redisClient   *redis.Client
+	notifier      ScheduleNotifier
+	// allowUnversioned バージョンを指定しない更新を後勝ちで受け付ける
+	allowUnversioned bool
+	realtime         RealtimePublisher
+	// webhooks スケジュールの変更をクラスのWebhookに配信する。nilの場合は配信しない
+	webhooks WebhookPublisher
+	// integrations スケジュールの変更とライブ授業の開始をクラスが連携したチャットサービスに投稿する。nilの場合は投稿しない
+	integrations IntegrationPublisher
+	// calendar スケジュールの変更をメンバーのGoogleカレンダーに反映する。nilの場合は反映しない
+	calendar CalendarSyncPublisher
+	// checkInTokens 出席トークンの署名に使う。秘密鍵が設定されていない場合はnil
+	checkInTokens *checkInTokenSigner
+	// superAdmins 開始済みのスケジュールを強制削除できる運用の管理者のユーザーID
+	superAdmins map[uint]bool
+}
+
+// scheduleChangeTexts スケジュールの変更の種類ごとの、チャットサービスに投稿する見出しの文言
+var scheduleChangeTexts = map[string]string{
+	dto.ScheduleCreated:     "追加されました",
+	dto.ScheduleUpdated:     "変更されました",
+	dto.ScheduleDeleted:     "削除されました",
+	dto.ScheduleRestored:    "復元されました",
+	dto.ScheduleCancelled:   "休講になりました",
+	dto.ScheduleUncancelled: "休講が取り消されました",
 }
 
-// NewClassScheduleService ClassScheduleServiceを生成
-func NewClassScheduleService(repo repositories.ClassScheduleRepository) ClassScheduleService {
+// NewClassScheduleService ClassScheduleServiceを生成。redisClientは自己チェックインの確認コードの保存に使う。allowUnversionedがfalseの場合、バージョンを指定しない更新はErrVersionRequiredとする。
+// realtimeがnilの場合はスケジュールの変更をWebSocketで配信せず、webhooksがnilの場合はWebhookで配信せず、integrationsがnilの場合はチャットサービスに投稿せず、
+// calendarがnilの場合はGoogleカレンダーに反映しない。checkInTokensの秘密鍵が空の場合は出席トークンを発行しない
+func NewClassScheduleService(repo repositories.ClassScheduleRepository, classUserRepo repositories.ClassUserRepository, redisClient *redis.Client, notifier ScheduleNotifier, allowUnversioned bool, realtime RealtimePublisher, webhooks WebhookPublisher, integrations IntegrationPublisher, calendar CalendarSyncPublisher, checkInTokens CheckInTokenConfig, superAdmins []uint) ClassScheduleService {
+	admins := make(map[uint]bool, len(superAdmins))
+	for _, uid := range superAdmins {
+		admins[uid] = true
+	}
 	return &classScheduleService{
-		repo: repo,
+		repo:             repo,
+		classUserRepo:    classUserRepo,
+		redisClient:      redisClient,
+		notifier:         notifier,
+		allowUnversioned: allowUnversioned,
+		realtime:         realtime,
+		webhooks:         webhooks,
+		integrations:     integrations,
+		calendar:         calendar,
+		checkInTokens:    newCheckInTokenSigner(checkInTokens),
+		superAdmins:      admins,
 	}
 }
 
-// GetClassScheduleByID クラススケジュールを取得
-func (s *classScheduleService) GetClassScheduleByID(cid uint) (*models.ClassSchedule, error) {
-	return s.repo.GetClassScheduleByID(cid)
+// GetClassScheduleByID クラススケジュールをクラスの名前と画像付きで取得
+func (s *classScheduleService) GetClassScheduleByID(ctx context.Context, cid uint) (*dto.ClassScheduleDetailDTO, error) {
+	classSchedule, err := s.repo.GetClassScheduleByID(ctx, cid)
+	if err != nil {
+		return nil, err
+	}
+	return &dto.ClassScheduleDetailDTO{
+		ID:          classSchedule.ID,
+		Title:       classSchedule.Title,
+		StartedAt:   classSchedule.StartedAt,
+		EndedAt:     classSchedule.EndedAt,
+		CID:         classSchedule.CID,
+		IsLive:      classSchedule.IsLive,
+		IsCancelled: classSchedule.IsCancelled,
+		// 出席方式の追加前に作成したスケジュールは対面とする
+		AttendanceMode: string(attendanceModeOrDefault(classSchedule.AttendanceMode)),
+		CheckInMethods: checkInMethodNames(attendanceModeOrDefault(classSchedule.AttendanceMode)),
+		Location:       classSchedule.Location,
+		Class: dto.ClassScheduleClassDTO{
+			ID:       classSchedule.Class.ID,
+			Name:     classSchedule.Class.Name,
+			ImageURL: classSchedule.Class.Image,
+		},
+		RequireCheckInCode: classSchedule.RequireCheckInCode,
+		Version:            classSchedule.Version,
+	}, nil
 }
 
 // GetAllClassSchedules 全てのクラススケジュールを取得
-func (s *classScheduleService) GetAllClassSchedules(cid uint) ([]models.ClassSchedule, error) {
-	return s.repo.GetAllClassSchedules(cid)
+func (s *classScheduleService) GetAllClassSchedules(ctx context.Context, cid uint) ([]models.ClassSchedule, error) {
+	return s.repo.GetAllClassSchedules(ctx, cid)
 }
 
 // CreateClassSchedule 新しいクラススケジュールを作成
-func (s *classScheduleService) CreateClassSchedule(classSchedule *models.ClassSchedule) (*models.ClassSchedule, error) {
-	err := s.repo.CreateClassSchedule(classSchedule)
+func (s *classScheduleService) CreateClassSchedule(ctx context.Context, classSchedule *models.ClassSchedule) (*models.ClassSchedule, error) {
+	classSchedule.AttendanceMode = attendanceModeOrDefault(classSchedule.AttendanceMode)
+	if err := validateAttendanceMode(classSchedule.AttendanceMode); err != nil {
+		return nil, err
+	}
+	err := s.repo.CreateClassSchedule(ctx, classSchedule)
+	if err == nil {
+		s.publishScheduleChanged(ctx, classSchedule, dto.ScheduleCreated, 0)
+		if classSchedule.IsLive {
+			s.postLiveStarted(classSchedule)
+		}
+	}
 	return classSchedule, err
 }
 
-// UpdateClassSchedule クラススケジュールを更新
-func (s *classScheduleService) UpdateClassSchedule(id uint, dto *dto.UpdateClassScheduleDTO) (*models.ClassSchedule, error) {
-	classSchedule, err := s.repo.GetClassScheduleByID(id)
+// UpdateClassSchedule クラススケジュールを更新。updateのVersionを指定した場合、読み込んだ後に他の更新が保存されていれば最新のスケジュールを持つStaleUpdateErrorを返す
+func (s *classScheduleService) UpdateClassSchedule(ctx context.Context, id uint, update *dto.UpdateClassScheduleDTO) (*models.ClassSchedule, error) {
+	if update.Version == 0 && !s.allowUnversioned {
+		return nil, ErrVersionRequired
+	}
+	if update.AttendanceMode != nil && !models.AttendanceMode(*update.AttendanceMode).Valid() {
+		return nil, ErrInvalidAttendanceMode
+	}
+	classSchedule, err := s.repo.GetClassScheduleByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
 
-	if dto.Title != nil {
-		classSchedule.Title = *dto.Title
+	wasLive := classSchedule.IsLive
+	if update.Title != nil {
+		classSchedule.Title = *update.Title
+	}
+	if update.StartedAt != nil {
+		classSchedule.StartedAt = *update.StartedAt
+	}
+	if update.EndedAt != nil {
+		classSchedule.EndedAt = *update.EndedAt
+	}
+	if update.IsLive != nil {
+		classSchedule.IsLive = *update.IsLive
+	}
+	if update.AttendanceMode != nil {
+		classSchedule.AttendanceMode = models.AttendanceMode(*update.AttendanceMode)
+	}
+	if update.Location != nil {
+		classSchedule.Location = *update.Location
+	}
+	if update.RequireCheckInCode != nil {
+		classSchedule.RequireCheckInCode = *update.RequireCheckInCode
+	}
+
+	err = s.repo.UpdateClassSchedule(ctx, classSchedule, update.Version)
+	if err != nil {
+		return nil, staleUpdate(err, func() (interface{}, error) { return s.repo.GetClassScheduleByID(ctx, id) })
+	}
+	s.publishScheduleChanged(ctx, classSchedule, dto.ScheduleUpdated, 0)
+	if !wasLive && classSchedule.IsLive {
+		s.postLiveStarted(classSchedule)
+	}
+
+	return classSchedule, nil
+}
+
+// DeleteClassSchedule クラススケジュールを削除し、開始済みのスケジュールを強制削除したかどうかを返す。
+// 開始済みのスケジュールは出席記録を守るため、運用の管理者(SUPER_ADMIN_UIDS)がforceを指定した場合のみ削除でき、
+// クラスの管理者でもErrUnauthorizedを返す
+func (s *classScheduleService) DeleteClassSchedule(ctx context.Context, id uint, uid uint, force bool) (bool, error) {
+	classSchedule, err := s.repo.GetClassScheduleByID(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, ErrNotFound
 	}
-	if dto.StartedAt != nil {
-		classSchedule.StartedAt = *dto.StartedAt
+	if err != nil {
+		return false, err
 	}
-	if dto.EndedAt != nil {
-		classSchedule.EndedAt = *dto.EndedAt
+
+	forced := classSchedule.StartedAt.Before(time.Now())
+	if forced {
+		if !force {
+			return false, ErrPastSchedule
+		}
+		if !s.superAdmins[uid] {
+			return false, ErrUnauthorized
+		}
 	}
-	if dto.IsLive != nil {
-		classSchedule.IsLive = *dto.IsLive
+
+	if err := s.repo.DeleteClassSchedule(ctx, id); err != nil {
+		return false, err
 	}
+	s.publishScheduleChanged(ctx, classSchedule, dto.ScheduleDeleted, uid)
+	return forced, nil
+}
 
-	err = s.repo.UpdateClassSchedule(classSchedule)
+// RestoreClassSchedule クラス管理者が論理削除したクラススケジュールを復元する。クラスが削除されている場合は復元できない
+func (s *classScheduleService) RestoreClassSchedule(ctx context.Context, cid uint, id uint, uid uint) error {
+	isAdmin, err := s.classUserRepo.IsAdmin(ctx, uid, cid)
+	if err != nil || !isAdmin {
+		return ErrUnauthorized
+	}
+	classSchedule, err := s.repo.FindDeletedClassSchedule(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && classSchedule.CID != cid) {
+		return ErrNotFound
+	}
 	if err != nil {
+		return err
+	}
+	if err := s.repo.RestoreClassSchedule(ctx, id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrNotFound
+		}
+		return err
+	}
+	s.publishScheduleChanged(ctx, classSchedule, dto.ScheduleRestored, uid)
+	return nil
+}
+
+// SetClassScheduleCancelled クラス管理者がスケジュールを休講にする、または休講を取り消す
+// 休講にした場合はクラスのメンバーに通知する
+func (s *classScheduleService) SetClassScheduleCancelled(ctx context.Context, id uint, uid uint, cancelled bool) (*models.ClassSchedule, error) {
+	classSchedule, err := s.repo.GetClassScheduleByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
 		return nil, err
 	}
 
+	isAdmin, err := s.classUserRepo.IsAdmin(ctx, uid, classSchedule.CID)
+	if err != nil || !isAdmin {
+		return nil, ErrUnauthorized
+	}
+	if classSchedule.IsCancelled == cancelled {
+		return classSchedule, nil
+	}
+
+	classSchedule.IsCancelled = cancelled
+	if err := s.repo.UpdateClassSchedule(ctx, classSchedule, 0); err != nil {
+		return nil, err
+	}
+	if cancelled {
+		s.notifyScheduleCancelled(ctx, classSchedule, uid)
+		s.publishScheduleChanged(ctx, classSchedule, dto.ScheduleCancelled, uid)
+	} else {
+		s.publishScheduleChanged(ctx, classSchedule, dto.ScheduleUncancelled, uid)
+	}
 	return classSchedule, nil
 }
 
-// DeleteClassSchedule クラススケジュールを削除
-func (s *classScheduleService) DeleteClassSchedule(id uint) error {
-	return s.repo.DeleteClassSchedule(id)
+// publishScheduleChanged スケジュールの変更をクラスを購読中のクライアント・クラスのWebhook・クラスが連携したチャットサービス・メンバーのGoogleカレンダーに配信する。
+// actorUIDは変更したユーザーで、0の場合はチャットサービスに変更したユーザーを載せない
+func (s *classScheduleService) publishScheduleChanged(ctx context.Context, classSchedule *models.ClassSchedule, action string, actorUID uint) {
+	if s.realtime != nil {
+		s.realtime.PublishToClass(ctx, classSchedule.CID, dto.RealtimeScheduleChanged, dto.RealtimeScheduleDTO{
+			ID:     classSchedule.ID,
+			CID:    classSchedule.CID,
+			Action: action,
+		})
+	}
+	if s.webhooks != nil {
+		s.webhooks.PublishWebhook(ctx, classSchedule.CID, models.ScheduleUpdatedWebhook, dto.WebhookPayloadDTO{Schedule: &dto.WebhookScheduleV1{
+			ID:          classSchedule.ID,
+			Action:      action,
+			Title:       classSchedule.Title,
+			StartedAt:   classSchedule.StartedAt,
+			EndedAt:     classSchedule.EndedAt,
+			IsCancelled: classSchedule.IsCancelled,
+		}})
+	}
+	if s.integrations != nil {
+		path := fmt.Sprintf("/classes/%d/schedules/%d", classSchedule.CID, classSchedule.ID)
+		if action == dto.ScheduleDeleted {
+			path = fmt.Sprintf("/classes/%d/schedules", classSchedule.CID)
+		}
+		s.integrations.PostToIntegration(classSchedule.CID, models.ScheduleChangedIntegration, IntegrationPost{
+			Title:     fmt.Sprintf("スケジュール「%s」が%s", classSchedule.Title, scheduleChangeTexts[action]),
+			AuthorUID: actorUID,
+			Body:      fmt.Sprintf("日時: %s〜%s", classSchedule.StartedAt.Format("2006-01-02 15:04"), classSchedule.EndedAt.Format("15:04")),
+			Path:      path,
+		})
+	}
+	if s.calendar != nil {
+		s.calendar.SyncSchedule(classSchedule.CID, classSchedule.ID)
+	}
+}
+
+// postLiveStarted ライブ授業の開始をクラスが連携したチャットサービスに投稿する
+func (s *classScheduleService) postLiveStarted(classSchedule *models.ClassSchedule) {
+	if s.integrations == nil {
+		return
+	}
+	s.integrations.PostToIntegration(classSchedule.CID, models.LiveStartedIntegration, IntegrationPost{
+		Title: fmt.Sprintf("ライブ授業「%s」が始まりました", classSchedule.Title),
+		Path:  fmt.Sprintf("/classes/%d/live", classSchedule.CID),
+	})
+}
+
+// notifyScheduleCancelled 休講を操作した管理者以外のクラスのメンバーに通知する。
+// 休講は保存済みのため、通知に失敗してもエラーを返さずに報告する
+func (s *classScheduleService) notifyScheduleCancelled(ctx context.Context, classSchedule *models.ClassSchedule, actorUID uint) {
+	members, err := s.classUserRepo.GetClassMembers(ctx, classSchedule.CID, "ADMIN", "ASSISTANT", "USER")
+	if err != nil {
+		utils.ReportBackgroundError("notify_schedule_cancelled", fmt.Errorf("failed to load members of class %d: %w", classSchedule.CID, err))
+		return
+	}
+
+	title := "休講のお知らせ"
+	body := fmt.Sprintf("%sの授業「%s」は休講になりました。", classSchedule.StartedAt.Format("2006-01-02 15:04"), classSchedule.Title)
+	for _, member := range members {
+		if member.Uid == actorUID {
+			continue
+		}
+		if err := s.notifier.NotifySchedule(ctx, member.Uid, classSchedule, title, body); err != nil {
+			utils.ReportBackgroundError("notify_schedule_cancelled", fmt.Errorf("failed to notify uid %d of cancelled schedule %d: %w", member.Uid, classSchedule.ID, err))
+		}
+	}
 }
 
 // GetLiveClassSchedules ライブ中のクラススケジュールを取得
-func (s *classScheduleService) GetLiveClassSchedules(cid uint) ([]models.ClassSchedule, error) {
-	return s.repo.FindLiveClassSchedules(cid)
+func (s *classScheduleService) GetLiveClassSchedules(ctx context.Context, cid uint) ([]models.ClassSchedule, error) {
+	return s.repo.FindLiveClassSchedules(ctx, cid)
+}
+
+// GetClassSchedulesByDate 日付でクラススケジュールを取得。modeを指定した場合はその出席方式のスケジュールのみ取得する
+func (s *classScheduleService) GetClassSchedulesByDate(ctx context.Context, cid uint, date time.Time, mode models.AttendanceMode) ([]models.ClassSchedule, error) {
+	if err := validateAttendanceMode(mode); err != nil {
+		return nil, err
+	}
+	return s.repo.FindClassSchedulesByDate(ctx, cid, date, mode)
+}
+
+// ExportClassICal クラスのスケジュールをiCalendar形式で出力。クラスのメンバーのみ出力できる
+func (s *classScheduleService) ExportClassICal(ctx context.Context, uid uint, cid uint) (string, error) {
+	role, err := s.classUserRepo.GetRole(ctx, uid, cid)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", ErrUnauthorized
+	} else if err != nil {
+		return "", err
+	}
+	if role != "ADMIN" && role != "ASSISTANT" && role != "USER" {
+		return "", ErrUnauthorized
+	}
+
+	classSchedules, err := s.repo.GetAllClassSchedules(ctx, cid)
+	if err != nil {
+		return "", err
+	}
+	return utils.BuildICalendar(fmt.Sprintf("class_%d", cid), toICalEvents(classSchedules, false)), nil
+}
+
+// ExportUserICal ユーザーが参加している全クラスのスケジュールを1つのiCalendarにまとめて出力
+func (s *classScheduleService) ExportUserICal(ctx context.Context, uid uint) (string, error) {
+	classSchedules, err := s.repo.FindClassSchedulesByUser(ctx, uid)
+	if err != nil {
+		return "", err
+	}
+	return utils.BuildICalendar("minori", toICalEvents(classSchedules, true)), nil
+}
+
+// GetClassesWithSchedulesToday ユーザーのタイムゾーンで今日授業があるクラスを、その日のスケジュールとともに取得
+// クラスは最初の授業の開始時刻順に並べる
+func (s *classScheduleService) GetClassesWithSchedulesToday(ctx context.Context, uid uint, loc *time.Location) ([]dto.TodayClassDTO, error) {
+	now := time.Now().In(loc)
+	from := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	classSchedules, err := s.repo.FindClassSchedulesByUserBetween(ctx, uid, from, from.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, err
+	}
+
+	classes := make([]dto.TodayClassDTO, 0)
+	indexes := make(map[uint]int)
+	for _, schedule := range classSchedules {
+		i, ok := indexes[schedule.CID]
+		if !ok {
+			i = len(classes)
+			indexes[schedule.CID] = i
+			classes = append(classes, dto.TodayClassDTO{ID: schedule.CID, Name: schedule.Class.Name, Image: schedule.Class.Image})
+		}
+		classes[i].Schedules = append(classes[i].Schedules, dto.TodayClassScheduleDTO{
+			ID:             schedule.ID,
+			Title:          schedule.Title,
+			StartedAt:      schedule.StartedAt,
+			EndedAt:        schedule.EndedAt,
+			IsLive:         schedule.IsLive,
+			IsCancelled:    schedule.IsCancelled,
+			Location:       schedule.Location,
+			AttendanceMode: string(attendanceModeOrDefault(schedule.AttendanceMode)),
+		})
+	}
+	return classes, nil
 }
 
-// GetClassSchedulesByDate 日付でクラススケジュールを取得
-func (s *classScheduleService) GetClassSchedulesByDate(cid uint, date string) ([]models.ClassSchedule, error) {
-	return s.repo.FindClassSchedulesByDate(cid, date)
+// toICalEvents スケジュールをiCalendarのイベントに変換。withClassNameの場合はSUMMARYにクラス名を含める
+func toICalEvents(classSchedules []models.ClassSchedule, withClassName bool) []utils.ICalEvent {
+	events := make([]utils.ICalEvent, 0, len(classSchedules))
+	for _, schedule := range classSchedules {
+		summary := schedule.Title
+		if withClassName && schedule.Class.Name != "" {
+			summary = fmt.Sprintf("[%s] %s", schedule.Class.Name, schedule.Title)
+		}
+		events = append(events, utils.ICalEvent{
+			UID:          fmt.Sprintf("class-schedule-%d@minori", schedule.ID),
+			Summary:      summary,
+			Start:        schedule.StartedAt,
+			End:          schedule.EndedAt,
+			Location:     schedule.Location,
+			LocationType: string(attendanceModeOrDefault(schedule.AttendanceMode)),
+		})
+	}
+	return events
+}
+
+// validateAttendanceMode 出席方式が未設定か定義済みの値であることを確認する
+func validateAttendanceMode(mode models.AttendanceMode) error {
+	if mode != "" && !mode.Valid() {
+		return ErrInvalidAttendanceMode
+	}
+	return nil
+}
+
+// attendanceModeOrDefault 出席方式が未設定の場合は対面を返す
+func attendanceModeOrDefault(mode models.AttendanceMode) models.AttendanceMode {
+	if mode == "" {
+		return models.InPersonMode
+	}
+	return mode
+}
+
+// allowsCheckInMethod 出席方式でチェックイン手段が有効かどうか
+func allowsCheckInMethod(mode models.AttendanceMode, method models.CheckInMethod) bool {
+	for _, allowed := range mode.CheckInMethods() {
+		if allowed == method {
+			return true
+		}
+	}
+	return false
+}
+
+// checkInMethodNames 出席方式で有効なチェックイン手段の名前を返す
+func checkInMethodNames(mode models.AttendanceMode) []string {
+	methods := mode.CheckInMethods()
+	names := make([]string, len(methods))
+	for i, method := range methods {
+		names[i] = string(method)
+	}
+	return names
 }
@@ -0,0 +1,220 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+	"gorm.io/gorm"
+)
+
+// DefaultGradeScale クラスで成績の基準を設定していない場合に使う基準
+var DefaultGradeScale = []dto.GradeThresholdDTO{
+	{Grade: "A", MinRate: 90, GradePoint: 4},
+	{Grade: "B", MinRate: 80, GradePoint: 3},
+	{Grade: "C", MinRate: 70, GradePoint: 2},
+	{Grade: "F", MinRate: 0, GradePoint: 0},
+}
+
+// ClassSemesterService インタフェース
+type ClassSemesterService interface {
+	SaveSemester(ctx context.Context, viewerUID uint, cid uint, name string, startsAt, endsAt time.Time) (*dto.SemesterDTO, error)
+	GetSemesters(ctx context.Context, cid uint) ([]dto.SemesterDTO, error)
+	DeleteSemester(ctx context.Context, viewerUID uint, cid uint, name string) error
+	GetGradeScale(ctx context.Context, cid uint) (*dto.GradeScaleDTO, error)
+	SetGradeScale(ctx context.Context, viewerUID uint, cid uint, thresholds []dto.GradeThresholdDTO) (*dto.GradeScaleDTO, error)
+	GetSemesterAttendanceGrade(ctx context.Context, viewerUID uint, uid uint, cid uint, semester string) (*dto.SemesterAttendanceGradeDTO, error)
+}
+
+// classSemesterService インタフェースを実装
+type classSemesterService struct {
+	repo           repositories.ClassSemesterRepository
+	attendanceRepo repositories.AttendanceRepository
+	classUserRepo  repositories.ClassUserRepository
+}
+
+// NewClassSemesterService ClassSemesterServiceを生成
+func NewClassSemesterService(repo repositories.ClassSemesterRepository, attendanceRepo repositories.AttendanceRepository, classUserRepo repositories.ClassUserRepository) ClassSemesterService {
+	return &classSemesterService{
+		repo:           repo,
+		attendanceRepo: attendanceRepo,
+		classUserRepo:  classUserRepo,
+	}
+}
+
+// SaveSemester 学期を登録する。同じ名前の学期がある場合は期間を置き換える。クラスの管理者のみ登録できる
+func (s *classSemesterService) SaveSemester(ctx context.Context, viewerUID uint, cid uint, name string, startsAt, endsAt time.Time) (*dto.SemesterDTO, error) {
+	isAdmin, err := s.classUserRepo.IsAdmin(ctx, viewerUID, cid)
+	if err != nil || !isAdmin {
+		return nil, ErrUnauthorized
+	}
+
+	semester := models.ClassSemester{CID: cid, Name: name, StartsAt: startsAt, EndsAt: endsAt}
+	if err := s.repo.UpsertSemester(ctx, &semester); err != nil {
+		return nil, err
+	}
+	return &dto.SemesterDTO{Name: semester.Name, StartsAt: semester.StartsAt, EndsAt: semester.EndsAt}, nil
+}
+
+// GetSemesters クラスの学期を開始日時の順に取得
+func (s *classSemesterService) GetSemesters(ctx context.Context, cid uint) ([]dto.SemesterDTO, error) {
+	semesters, err := s.repo.FindSemesters(ctx, cid)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]dto.SemesterDTO, 0, len(semesters))
+	for _, semester := range semesters {
+		result = append(result, dto.SemesterDTO{Name: semester.Name, StartsAt: semester.StartsAt, EndsAt: semester.EndsAt})
+	}
+	return result, nil
+}
+
+// DeleteSemester 学期を削除する。クラスの管理者のみ削除できる
+func (s *classSemesterService) DeleteSemester(ctx context.Context, viewerUID uint, cid uint, name string) error {
+	isAdmin, err := s.classUserRepo.IsAdmin(ctx, viewerUID, cid)
+	if err != nil || !isAdmin {
+		return ErrUnauthorized
+	}
+
+	deleted, err := s.repo.DeleteSemester(ctx, cid, name)
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// GetGradeScale クラスの成績の基準を出席率の下限が高い順に取得する。設定していない場合は既定の基準を返す
+func (s *classSemesterService) GetGradeScale(ctx context.Context, cid uint) (*dto.GradeScaleDTO, error) {
+	thresholds, err := s.repo.FindGradeScale(ctx, cid)
+	if err != nil {
+		return nil, err
+	}
+	if len(thresholds) == 0 {
+		return &dto.GradeScaleDTO{Thresholds: append([]dto.GradeThresholdDTO(nil), DefaultGradeScale...), IsDefault: true}, nil
+	}
+
+	scale := &dto.GradeScaleDTO{Thresholds: make([]dto.GradeThresholdDTO, 0, len(thresholds))}
+	for _, threshold := range thresholds {
+		scale.Thresholds = append(scale.Thresholds, dto.GradeThresholdDTO{Grade: threshold.Grade, MinRate: threshold.MinRate, GradePoint: threshold.GradePoint})
+	}
+	return scale, nil
+}
+
+// SetGradeScale クラスの成績の基準を置き換える。クラスの管理者のみ設定できる。
+// 全ての出席率が成績に変換されるよう、出席率0%の基準を必ず含める
+func (s *classSemesterService) SetGradeScale(ctx context.Context, viewerUID uint, cid uint, thresholds []dto.GradeThresholdDTO) (*dto.GradeScaleDTO, error) {
+	isAdmin, err := s.classUserRepo.IsAdmin(ctx, viewerUID, cid)
+	if err != nil || !isAdmin {
+		return nil, ErrUnauthorized
+	}
+
+	grades := make(map[string]bool, len(thresholds))
+	rates := make(map[float64]bool, len(thresholds))
+	for _, threshold := range thresholds {
+		if threshold.Grade == "" || grades[threshold.Grade] || rates[threshold.MinRate] || threshold.MinRate < 0 || threshold.MinRate > 100 {
+			return nil, ErrInvalidGradeScale
+		}
+		grades[threshold.Grade] = true
+		rates[threshold.MinRate] = true
+	}
+	if !rates[0] {
+		return nil, ErrInvalidGradeScale
+	}
+
+	records := make([]models.ClassGradeThreshold, 0, len(thresholds))
+	for _, threshold := range thresholds {
+		records = append(records, models.ClassGradeThreshold{CID: cid, Grade: threshold.Grade, MinRate: threshold.MinRate, GradePoint: threshold.GradePoint})
+	}
+	if err := s.repo.ReplaceGradeScale(ctx, cid, records); err != nil {
+		return nil, err
+	}
+	return s.GetGradeScale(ctx, cid)
+}
+
+// GetSemesterAttendanceGrade 学期の出席率を成績の基準で成績に変換して返す。本人とクラスの講師・アシスタントのみ取得できる。
+// 学期中に開始して終了したスケジュールを集計し、出席の記録がないスケジュールは欠席として数える
+func (s *classSemesterService) GetSemesterAttendanceGrade(ctx context.Context, viewerUID uint, uid uint, cid uint, semesterName string) (*dto.SemesterAttendanceGradeDTO, error) {
+	if viewerUID != uid {
+		role, err := s.classUserRepo.GetRole(ctx, viewerUID, cid)
+		if err != nil || (role != "ADMIN" && role != "ASSISTANT") {
+			return nil, ErrUnauthorized
+		}
+	}
+
+	semester, err := s.repo.FindSemester(ctx, cid, semesterName)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	scale, err := s.GetGradeScale(ctx, cid)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	until := semester.EndsAt
+	if now.Before(until) {
+		until = now
+	}
+	timeline, err := s.attendanceRepo.GetAttendanceTimelineBetween(ctx, cid, uid, semester.StartsAt, until)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &dto.SemesterAttendanceGradeDTO{
+		UID:          uid,
+		CID:          cid,
+		Semester:     semester.Name,
+		StartsAt:     semester.StartsAt,
+		EndsAt:       semester.EndsAt,
+		Final:        now.After(semester.EndsAt),
+		Scale:        *scale,
+		CalculatedAt: now,
+	}
+	for _, entry := range timeline {
+		if entry.Status == nil && entry.EndedAt.After(now) {
+			// 授業中でまだ出席が記録されていないスケジュールは集計しない
+			continue
+		}
+		result.TotalSessions++
+		switch {
+		case entry.Status != nil && *entry.Status == models.AttendanceStatus:
+			result.Attendance++
+		case entry.Status != nil && *entry.Status == models.TardyStatus:
+			result.Tardy++
+		default:
+			result.Absence++
+		}
+	}
+	if result.TotalSessions == 0 {
+		return result, nil
+	}
+
+	result.AttendanceRate = float64(result.Attendance) / float64(result.TotalSessions) * 100
+	if threshold, ok := gradeFor(scale.Thresholds, result.AttendanceRate); ok {
+		result.Grade = &threshold.Grade
+		result.GradePoint = &threshold.GradePoint
+	}
+	return result, nil
+}
+
+// gradeFor 出席率が下限以上の基準のうち、下限が最も高い基準を返す
+func gradeFor(thresholds []dto.GradeThresholdDTO, rate float64) (dto.GradeThresholdDTO, bool) {
+	sorted := append([]dto.GradeThresholdDTO(nil), thresholds...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].MinRate > sorted[j].MinRate })
+	for _, threshold := range sorted {
+		if rate >= threshold.MinRate {
+			return threshold, true
+		}
+	}
+	return dto.GradeThresholdDTO{}, false
+}
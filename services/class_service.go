@@ -1,45 +1,76 @@
 package services
 
 import (
+	"context"
+	"crypto/subtle"
 	"errors"
 	"fmt"
 	"log"
 	"math/rand"
+	"net/url"
 	"time"
 
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/utils"
+	"github.com/go-redis/redis/v8"
+	"gorm.io/gorm"
 )
 
 type ClassService interface {
-	GetClass(classID uint) (*models.Class, error)
-	GetClassWithCode(classID uint) (*models.Class, *models.ClassCode, error)
-	CreateClass(request dto.CreateClassRequest) (uint, error)
-	UpdateClassImage(classID uint, imageUrl string) error
-	UpdateClass(classID uint, userID uint, request dto.UpdateClassRequest) error
-	DeleteClass(classID uint, userID uint) error
-	GenerateClassCode() (string, error)
+	GetClass(ctx context.Context, classID uint) (*models.Class, error)
+	GetClassWithCode(ctx context.Context, classID uint) (*models.Class, *models.ClassCode, error)
+	CreateClass(ctx context.Context, request dto.CreateClassRequest) (uint, error)
+	UpdateClassImage(ctx context.Context, classID uint, imageUrl string) error
+	UpdateClass(ctx context.Context, classID uint, userID uint, request dto.UpdateClassRequest) error
+	DeleteClass(ctx context.Context, classID uint, userID uint) error
+	RestoreClass(ctx context.Context, classID uint, userID uint) error
+	GenerateClassCode(ctx context.Context) (string, error)
+	GetClassPreview(ctx context.Context, uid uint, classID uint, secret string) (*dto.ClassPreviewDTO, error)
+	SetArchiveExempt(ctx context.Context, classID uint, userID uint, exempt bool) error
+	GetPublicClasses(ctx context.Context, query string, language string, page int, limit int) ([]dto.PublicClassDTO, error)
+	GenerateClassFlyer(ctx context.Context, classID uint, userID uint) ([]byte, error)
+	GetClassStats(ctx context.Context, classID uint, userID uint) (*dto.ClassStatsDTO, error)
+	GetClassTimeline(ctx context.Context, classID uint, userID uint, types []dto.ClassTimelineEventType, page int, limit int) ([]dto.ClassTimelineEventDTO, error)
 }
 
 type classServiceImpl struct {
+	txManager     repositories.TxManager
 	classRepo     repositories.ClassRepository
 	classUserRepo repositories.ClassUserRepository
 	classCodeRepo repositories.ClassCodeRepository
 	userRepo      repositories.UserRepository
+	scheduleRepo  repositories.ClassScheduleRepository
+	// inviteURL 配布用PDFのQRコードに格納する招待リンク。空の場合はクラスコードを格納する
+	inviteURL string
+	// redisClient クラスの統計のキャッシュと発言中のチャットの確認に使う。nilの場合はキャッシュしない
+	redisClient *redis.Client
+	// events クラスの作成を他のサービスに配信する。nilの場合は配信しない
+	events EventPublisher
 }
 
 func NewCreateClassService(
+	txManager repositories.TxManager,
 	classRepo repositories.ClassRepository,
 	classUserRepo repositories.ClassUserRepository,
 	classCodeRepo repositories.ClassCodeRepository,
 	userRepo repositories.UserRepository,
+	scheduleRepo repositories.ClassScheduleRepository,
+	inviteURL string,
+	redisClient *redis.Client,
+	events EventPublisher,
 ) ClassService {
 	return &classServiceImpl{
+		txManager:     txManager,
 		classRepo:     classRepo,
 		classUserRepo: classUserRepo,
 		classCodeRepo: classCodeRepo,
 		userRepo:      userRepo,
+		scheduleRepo:  scheduleRepo,
+		inviteURL:     inviteURL,
+		redisClient:   redisClient,
+		events:        events,
 	}
 }
 
@@ -49,12 +80,12 @@ func init() {
 	rand.Seed(time.Now().UnixNano())
 }
 
-func (s *classServiceImpl) GetClass(classID uint) (*models.Class, error) {
-	return s.classRepo.GetByID(classID)
+func (s *classServiceImpl) GetClass(ctx context.Context, classID uint) (*models.Class, error) {
+	return s.classRepo.GetByID(ctx, classID)
 }
 
-func (s *classServiceImpl) GetClassWithCode(classID uint) (*models.Class, *models.ClassCode, error) {
-	class, err := s.classRepo.GetByID(classID)
+func (s *classServiceImpl) GetClassWithCode(ctx context.Context, classID uint) (*models.Class, *models.ClassCode, error) {
+	class, err := s.classRepo.GetByID(ctx, classID)
 	if err != nil {
 		log.Printf("Error retrieving Class for ClassID %d: %v", classID, err)
 		return nil, nil, err
@@ -62,7 +93,7 @@ func (s *classServiceImpl) GetClassWithCode(classID uint) (*models.Class, *model
 
 	log.Printf("Class retrieved for ClassID %d: %+v", classID, class)
 
-	classCode, err := s.classCodeRepo.FindByClassID(classID)
+	classCode, err := s.classCodeRepo.FindByClassID(ctx, classID)
 	if err != nil {
 		log.Printf("Error retrieving ClassCode for ClassID %d: %v", classID, err)
 		return class, nil, err
@@ -77,66 +108,177 @@ func (s *classServiceImpl) GetClassWithCode(classID uint) (*models.Class, *model
 	return class, classCode, nil
 }
 
-func (s *classServiceImpl) CreateClass(request dto.CreateClassRequest) (uint, error) {
+// CreateClass クラス、管理者のメンバー登録、クラスコードを1つのトランザクションで作成する
+func (s *classServiceImpl) CreateClass(ctx context.Context, request dto.CreateClassRequest) (uint, error) {
+	var classID uint
+	err := s.txManager.WithinTransaction(ctx, func(repos repositories.RepositorySet) error {
+		user, err := repos.User.FindByID(ctx, request.UID)
+		if err != nil {
+			return err
+		}
 
-	var user *models.User
-	var err error
-	if user, err = s.userRepo.FindByID(request.UID); err != nil {
-		return 0, err
-	}
+		class := models.Class{
+			Name:        request.Name,
+			Limitation:  request.Limitation,
+			Description: request.Description,
+			UID:         request.UID,
+			IsPublic:    request.IsPublic,
+			Language:    request.Language,
+		}
 
-	class := models.Class{
-		Name:        request.Name,
-		Limitation:  request.Limitation,
-		Description: request.Description,
-		UID:         request.UID,
-	}
+		classID, err = repos.Class.Save(ctx, &class)
+		if err != nil {
+			return err
+		}
+
+		classUser := models.ClassUser{
+			CID:        classID,
+			UID:        request.UID,
+			Nickname:   user.Name,
+			IsFavorite: false,
+			Role:       "ADMIN",
+		}
+		if err := repos.ClassUser.Save(ctx, &classUser); err != nil {
+			return err
+		}
+
+		code, err := generateClassCode(ctx, repos.ClassCode)
+		if err != nil {
+			return err
+		}
+		classCode := models.ClassCode{
+			Code:   code,
+			CID:    classID,
+			UID:    request.UID,
+			Secret: request.Secret,
+		}
+		if err := repos.ClassCode.SaveClassCode(ctx, &classCode); err != nil {
+			return err
+		}
 
-	classID, err := s.classRepo.Save(&class)
+		publishEventAfterCommit(ctx, repos, s.events, dto.EventClassCreated, dto.EventClassCreatedV1{
+			CID:      classID,
+			UID:      request.UID,
+			Name:     class.Name,
+			IsPublic: class.IsPublic,
+			Language: class.Language,
+		})
+		return nil
+	})
 	if err != nil {
 		return 0, err
 	}
 
-	classUser := models.ClassUser{
-		CID:        classID,
-		UID:        request.UID,
-		Nickname:   user.Name,
-		IsFavorite: false,
-		Role:       "ADMIN",
+	return classID, nil
+}
+
+const (
+	// classPreviewAttemptsKey クラスとユーザーごとのプレビューのシークレットの入力ミスの回数のキー
+	classPreviewAttemptsKey = "class_preview_attempts:%d:%d"
+	// classPreviewAttemptWindow 入力ミスの回数を数える期間
+	classPreviewAttemptWindow = 10 * time.Minute
+	// maxClassPreviewAttempts 期間内に入力ミスできる回数。総当たりでシークレットを当てられないようにする
+	maxClassPreviewAttempts = 5
+)
+
+// GetClassPreview 参加前のユーザー向けにクラスの公開情報を取得する。
+// シークレットが設定されたクラスは、正しいシークレットが指定された場合のみ詳細を返す。
+// シークレットの入力ミスが10分間に5回に達したユーザーには、正しいシークレットでもErrPreviewSecretLimitを返す
+func (s *classServiceImpl) GetClassPreview(ctx context.Context, uid uint, classID uint, secret string) (*dto.ClassPreviewDTO, error) {
+	preview, err := s.classRepo.GetClassPreview(ctx, classID)
+	if err != nil {
+		return nil, err
 	}
-	err = s.classUserRepo.Save(&classUser)
+
+	classCode, err := s.classCodeRepo.FindByClassID(ctx, classID)
 	if err != nil {
-		return 0, err
+		return nil, err
+	}
+	if classCode != nil && classCode.Secret != nil && *classCode.Secret != "" {
+		preview.IsPrivate = true
+		matched, err := s.checkPreviewSecret(ctx, uid, classID, *classCode.Secret, secret)
+		if err != nil {
+			return nil, err
+		}
+		if !matched {
+			return &dto.ClassPreviewDTO{
+				ID:        preview.ID,
+				Name:      preview.Name,
+				Image:     preview.Image,
+				IsPrivate: true,
+			}, nil
+		}
 	}
 
-	code, err := s.GenerateClassCode()
+	next, err := s.scheduleRepo.FindNextClassSchedule(ctx, classID, time.Now())
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
-	classCode := models.ClassCode{
-		Code:   code,
-		CID:    classID,
-		UID:    request.UID,
-		Secret: request.Secret,
+	if next != nil {
+		preview.NextSchedule = &dto.ClassPreviewScheduleDTO{
+			Title:       next.Title,
+			StartedAt:   next.StartedAt,
+			EndedAt:     next.EndedAt,
+			IsCancelled: next.IsCancelled,
+		}
 	}
-	if err := s.classCodeRepo.SaveClassCode(&classCode); err != nil {
-		return 0, err
+
+	return preview, nil
+}
+
+// checkPreviewSecret プレビューで指定されたシークレットを定数時間で比較し、入力ミスを数える。
+// シークレットを指定しない場合は入力ミスに数えない。Redisがない場合は回数を制限しない
+func (s *classServiceImpl) checkPreviewSecret(ctx context.Context, uid uint, classID uint, want string, secret string) (bool, error) {
+	if secret == "" {
+		return false, nil
+	}
+	key := fmt.Sprintf(classPreviewAttemptsKey, classID, uid)
+	if s.redisClient != nil {
+		attempts, err := s.redisClient.Get(ctx, key).Int()
+		if err != nil && err != redis.Nil {
+			return false, err
+		}
+		if attempts >= maxClassPreviewAttempts {
+			return false, ErrPreviewSecretLimit
+		}
+	}
+	if subtle.ConstantTimeCompare([]byte(want), []byte(secret)) == 1 {
+		return true, nil
+	}
+	if s.redisClient != nil {
+		pipe := s.redisClient.TxPipeline()
+		pipe.Incr(ctx, key)
+		pipe.Expire(ctx, key, classPreviewAttemptWindow)
+		if _, err := pipe.Exec(ctx); err != nil {
+			return false, err
+		}
 	}
+	return false, nil
+}
 
-	return classID, nil
+// GetPublicClasses 参加前のユーザー向けに公開クラスを検索する
+func (s *classServiceImpl) GetPublicClasses(ctx context.Context, query string, language string, page int, limit int) ([]dto.PublicClassDTO, error) {
+	classes, err := s.classRepo.FindPublicClasses(ctx, query, language, time.Now(), limit, (page-1)*limit)
+	if err != nil {
+		return nil, err
+	}
+	if classes == nil {
+		classes = []dto.PublicClassDTO{}
+	}
+	return classes, nil
 }
 
-func (s *classServiceImpl) UpdateClassImage(classID uint, imageUrl string) error {
-	return s.classRepo.UpdateClassImage(classID, imageUrl)
+func (s *classServiceImpl) UpdateClassImage(ctx context.Context, classID uint, imageUrl string) error {
+	return s.classRepo.UpdateClassImage(ctx, classID, imageUrl)
 }
 
-func (s *classServiceImpl) UpdateClass(classID uint, userID uint, request dto.UpdateClassRequest) error {
-	isAdmin, err := s.IsAdmin(userID, classID)
+func (s *classServiceImpl) UpdateClass(ctx context.Context, classID uint, userID uint, request dto.UpdateClassRequest) error {
+	isAdmin, err := s.IsAdmin(ctx, userID, classID)
 	if err != nil || !isAdmin {
 		return errors.New("unauthorized: user is not an admin")
 	}
 
-	class, err := s.GetClass(classID)
+	class, err := s.GetClass(ctx, classID)
 	if err != nil {
 		return err
 	}
@@ -150,20 +292,26 @@ func (s *classServiceImpl) UpdateClass(classID uint, userID uint, request dto.Up
 	if request.Description != nil {
 		class.Description = request.Description
 	}
+	if request.IsPublic != nil {
+		class.IsPublic = *request.IsPublic
+	}
+	if request.Language != nil {
+		class.Language = request.Language
+	}
 
-	return s.classRepo.Update(class)
+	return s.classRepo.Update(ctx, class)
 }
 
-func (s *classServiceImpl) IsAdmin(userID uint, classID uint) (bool, error) {
-	role, err := s.classUserRepo.GetRole(userID, classID)
+func (s *classServiceImpl) IsAdmin(ctx context.Context, userID uint, classID uint) (bool, error) {
+	role, err := s.classUserRepo.GetRole(ctx, userID, classID)
 	if err != nil {
 		return false, err
 	}
 	return role == "ADMIN", nil
 }
 
-func (s *classServiceImpl) DeleteClass(classID uint, userID uint) error {
-	role, err := s.classUserRepo.GetRole(userID, classID)
+func (s *classServiceImpl) DeleteClass(ctx context.Context, classID uint, userID uint) error {
+	role, err := s.classUserRepo.GetRole(ctx, userID, classID)
 	if err != nil {
 		return err
 	}
@@ -172,16 +320,91 @@ func (s *classServiceImpl) DeleteClass(classID uint, userID uint) error {
 		return errors.New(fmt.Sprintf("unauthorized access: role %s", role))
 	}
 
-	return s.classRepo.Delete(classID)
+	return s.classRepo.Delete(ctx, classID)
+}
+
+// RestoreClass クラスの管理者が論理削除したクラスを、同時に削除したスケジュールと掲示板とともに復元する
+func (s *classServiceImpl) RestoreClass(ctx context.Context, classID uint, userID uint) error {
+	isAdmin, err := s.IsAdmin(ctx, userID, classID)
+	if err != nil || !isAdmin {
+		return ErrUnauthorized
+	}
+	if err := s.classRepo.Restore(ctx, classID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// SetArchiveExempt クラスの管理者がクラスを自動アーカイブの対象から除外するかを設定する
+func (s *classServiceImpl) SetArchiveExempt(ctx context.Context, classID uint, userID uint, exempt bool) error {
+	isAdmin, err := s.IsAdmin(ctx, userID, classID)
+	if err != nil || !isAdmin {
+		return ErrUnauthorized
+	}
+	return s.classRepo.SetArchiveExempt(ctx, classID, exempt)
+}
+
+// GenerateClassFlyer クラスの管理者向けに、クラス名・説明・参加用QRコードを載せた配布用PDFを生成する
+func (s *classServiceImpl) GenerateClassFlyer(ctx context.Context, classID uint, userID uint) ([]byte, error) {
+	isAdmin, err := s.IsAdmin(ctx, userID, classID)
+	if err != nil || !isAdmin {
+		return nil, ErrUnauthorized
+	}
+
+	class, classCode, err := s.GetClassWithCode(ctx, classID)
+	if err != nil {
+		return nil, err
+	}
+	if classCode == nil {
+		return nil, ErrNotFound
+	}
+
+	flyer := utils.ClassFlyer{
+		ClassName:      class.Name,
+		JoinCode:       classCode.Code,
+		SecretRequired: classCode.Secret != nil && *classCode.Secret != "",
+	}
+	if class.Description != nil {
+		flyer.Description = *class.Description
+	}
+	joinURL, err := classJoinURL(s.inviteURL, classCode.Code)
+	if err != nil {
+		return nil, err
+	}
+	flyer.JoinURL = joinURL
+	return utils.BuildClassFlyerPDF(flyer)
+}
+
+// classJoinURL 招待リンクにクラスコードを付けた参加用のURLを返す。招待リンクが空の場合は空を返す
+func classJoinURL(inviteURL string, code string) (string, error) {
+	if inviteURL == "" {
+		return "", nil
+	}
+	joinURL, err := url.Parse(inviteURL)
+	if err != nil {
+		return "", err
+	}
+	query := joinURL.Query()
+	query.Set("code", code)
+	joinURL.RawQuery = query.Encode()
+	return joinURL.String(), nil
+}
+
+func (s *classServiceImpl) GenerateClassCode(ctx context.Context) (string, error) {
+	return generateClassCode(ctx, s.classCodeRepo)
 }
 
-func (s *classServiceImpl) GenerateClassCode() (string, error) {
+// generateClassCode 指定されたリポジトリで重複しないクラスコードを生成する
+func generateClassCode(ctx context.Context, classCodeRepo repositories.ClassCodeRepository) (string, error) {
 	for {
 		code := make([]byte, 6)
 		for i := range code {
 			code[i] = letters[rand.Intn(len(letters))]
 		}
-		existingCode, err := s.classCodeRepo.FindByCode(string(code))
+		existingCode, err := classCodeRepo.FindByCode(ctx, string(code))
 		if err != nil {
 			return "", err
 		}
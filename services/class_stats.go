@@ -0,0 +1,142 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"gorm.io/gorm"
+)
+
+const (
+	classStatsCacheKey = "classstats:%d"
+	classStatsCacheTTL = 2 * time.Minute
+	// classStatsActivePeriod この期間内に出席または投稿したメンバーをアクティブとする
+	classStatsActivePeriod = 30 * 24 * time.Hour
+)
+
+// GetClassStats クラスの管理者向けに、メンバー数、掲示板とスケジュールの件数、出席率、発言中のチャットの数をまとめて返す。
+// 集計は並行して行い、結果をRedisに2分間キャッシュする。クラスのメンバーでない場合もErrUnauthorizedを返し、ロールの取得に失敗した場合はそのエラーを返す
+func (s *classServiceImpl) GetClassStats(ctx context.Context, classID uint, userID uint) (*dto.ClassStatsDTO, error) {
+	isAdmin, err := s.IsAdmin(ctx, userID, classID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrUnauthorized
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !isAdmin {
+		return nil, ErrUnauthorized
+	}
+
+	key := fmt.Sprintf(classStatsCacheKey, classID)
+	if s.redisClient != nil {
+		if cached, err := s.redisClient.Get(ctx, key).Bytes(); err == nil {
+			var stats dto.ClassStatsDTO
+			if err := json.Unmarshal(cached, &stats); err == nil {
+				return &stats, nil
+			}
+		}
+	}
+
+	stats, err := s.computeClassStats(ctx, classID)
+	if err != nil {
+		return nil, err
+	}
+	if s.redisClient != nil {
+		if data, err := json.Marshal(stats); err == nil {
+			s.redisClient.Set(ctx, key, data, classStatsCacheTTL)
+		}
+	}
+	return stats, nil
+}
+
+// computeClassStats クラスの統計をDBとRedisから並行して集計する
+func (s *classServiceImpl) computeClassStats(ctx context.Context, classID uint) (*dto.ClassStatsDTO, error) {
+	now := time.Now()
+	stats := &dto.ClassStatsDTO{}
+	err := runConcurrently(ctx,
+		func(ctx context.Context) error {
+			counts, err := s.classRepo.CountMembersByRole(ctx, classID)
+			if err != nil {
+				return err
+			}
+			stats.AdminCount = counts["ADMIN"]
+			stats.AssistantCount = counts["ASSISTANT"]
+			stats.StudentCount = counts["USER"]
+			stats.TotalMembers = stats.AdminCount + stats.AssistantCount + stats.StudentCount
+			return nil
+		},
+		func(ctx context.Context) (err error) {
+			stats.ActiveMembers, err = s.classRepo.CountActiveMembers(ctx, classID, now.Add(-classStatsActivePeriod))
+			return err
+		},
+		func(ctx context.Context) (err error) {
+			stats.TotalBoards, err = s.classRepo.CountBoards(ctx, classID)
+			return err
+		},
+		func(ctx context.Context) (err error) {
+			stats.TotalSchedules, stats.UpcomingScheduleCount, err = s.classRepo.CountSchedules(ctx, classID, now)
+			return err
+		},
+		func(ctx context.Context) (err error) {
+			stats.OverallAttendanceRate, err = s.classRepo.GetAttendanceRate(ctx, classID)
+			return err
+		},
+		func(ctx context.Context) (err error) {
+			stats.ActiveChatRooms, err = s.countActiveChatRooms(ctx, classID)
+			return err
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// countActiveChatRooms 履歴が残っているスケジュールのチャットを数える。チャットの履歴は最後の発言から1時間で消える
+func (s *classServiceImpl) countActiveChatRooms(ctx context.Context, classID uint) (int64, error) {
+	if s.redisClient == nil {
+		return 0, nil
+	}
+	schedules, err := s.scheduleRepo.GetAllClassSchedules(ctx, classID)
+	if err != nil || len(schedules) == 0 {
+		return 0, err
+	}
+	keys := make([]string, 0, len(schedules))
+	for _, schedule := range schedules {
+		keys = append(keys, "chat:"+strconv.FormatUint(uint64(schedule.ID), 10))
+	}
+	return s.redisClient.Exists(ctx, keys...).Result()
+}
+
+// runConcurrently fnsを並行して実行し、最初に発生したエラーを返す。エラーが発生した時点で他の処理のコンテキストをキャンセルする
+func runConcurrently(ctx context.Context, fns ...func(ctx context.Context) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+	for _, fn := range fns {
+		wg.Add(1)
+		go func(fn func(ctx context.Context) error) {
+			defer wg.Done()
+			if err := fn(ctx); err != nil {
+				once.Do(func() {
+					firstErr = err
+					cancel()
+				})
+			}
+		}(fn)
+	}
+	wg.Wait()
+	return firstErr
+}
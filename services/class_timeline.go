@@ -0,0 +1,17 @@
+package services
+
+import (
+	"context"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+)
+
+// GetClassTimeline クラスの掲示の投稿、スケジュールの追加、メンバーの参加、ロールの変更を1つのフィードとして新しい順に返す。
+// 講師・アシスタント・生徒のみ取得でき、typesが空の場合は全ての種類を返す
+func (s *classServiceImpl) GetClassTimeline(ctx context.Context, classID uint, userID uint, types []dto.ClassTimelineEventType, page int, limit int) ([]dto.ClassTimelineEventDTO, error) {
+	role, err := s.classUserRepo.GetRole(ctx, userID, classID)
+	if err != nil || (role != "ADMIN" && role != "ASSISTANT" && role != "USER") {
+		return nil, ErrUnauthorized
+	}
+	return s.classRepo.FindTimelineEvents(ctx, classID, types, limit, (page-1)*limit)
+}
@@ -1,104 +1,512 @@
 package services
 
 import (
+	"context"
+	"encoding/csv"
 	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/utils"
+	"github.com/go-redis/redis/v8"
 	"gorm.io/gorm"
 )
 
 // ClassUserService はグループコードのサービスです。
 type ClassUserService interface {
-	GetClassMembers(cid uint, roleNames ...string) ([]dto.ClassMemberDTO, error)
-	GetClassUserInfo(uid uint, cid uint) (dto.ClassMemberDTO, error)
-	GetUserClasses(uid uint, page int, limit int) ([]dto.UserClassInfoDTO, error)
-	GetRole(uid uint, cid uint) (string, error)
-	GetFavoriteClasses(uid uint, page int, limit int) ([]dto.UserClassInfoDTO, error)
-	GetUserClassesByRole(uid uint, roleName string, page int, limit int) ([]dto.UserClassInfoDTO, error)
-	AssignRole(uid uint, cid uint, roleName string) error
-	UpdateUserName(uid uint, cid uint, newName string) error
-	ToggleFavorite(uid uint, cid uint) error
-	RemoveUserFromClass(uid uint, cid uint) error
-	SearchUserClassesByName(uid uint, name string) ([]dto.UserClassInfoDTO, error)
+	GetClassMembers(ctx context.Context, cid uint, roleNames ...string) ([]dto.ClassMemberDTO, error)
+	GetClassUserInfo(ctx context.Context, uid uint, cid uint) (dto.ClassMemberDTO, error)
+	GetUserClasses(ctx context.Context, uid uint, page int, limit int, includeArchived bool) ([]dto.UserClassInfoDTO, error)
+	GetRole(ctx context.Context, uid uint, cid uint) (string, error)
+	IsAdmin(ctx context.Context, uid uint, cid uint) (bool, error)
+	IsAdminOrAssistant(ctx context.Context, uid uint, cid uint) (bool, error)
+	GetFavoriteClasses(ctx context.Context, uid uint, page int, limit int) ([]dto.UserClassInfoDTO, error)
+	GetUserClassesByRole(ctx context.Context, uid uint, roleName string, page int, limit int) ([]dto.UserClassInfoDTO, error)
+	AssignRole(ctx context.Context, uid uint, cid uint, roleName string) error
+	AssignRoleViaCode(ctx context.Context, uid uint, cid uint, roleName string, codeID uint) (*models.ClassUser, bool, error)
+	GetClassUsersByCodeID(ctx context.Context, codeID uint) ([]models.ClassUser, error)
+	UpdateUserName(ctx context.Context, uid uint, cid uint, newName string) error
+	ToggleFavorite(ctx context.Context, uid uint, cid uint) error
+	BatchSetFavorite(ctx context.Context, uid uint, classIDs []uint, isFavorite bool) error
+	RemoveUserFromClass(ctx context.Context, uid uint, cid uint) error
+	SearchUserClassesByName(ctx context.Context, uid uint, name string) ([]dto.UserClassInfoDTO, error)
+	ExportMembers(ctx context.Context, cid uint, w io.Writer) error
+	GetMemberActivityRanking(ctx context.Context, cid uint, weights dto.ActivityWeights, limit int, anonymize bool) ([]dto.MemberActivityRankingDTO, error)
+	RefreshMemberActivityRankings()
+	GetRemovedMembers(ctx context.Context, cid uint) ([]dto.RemovedMemberDTO, error)
+	RestoreMember(ctx context.Context, uid uint, cid uint) error
+	GetDashboardLayout(ctx context.Context, uid uint, cid uint) (*dto.DashboardLayoutDTO, error)
+	MoveMembers(ctx context.Context, uid uint, request dto.MoveMembersRequest) (*dto.MoveMembersResultDTO, error)
+	SubscribeClassEvents(ctx context.Context, viewerUID uint, cid uint) (<-chan dto.ClassEventDTO, error)
 }
 
 // classUserServiceImpl はClassCodeServiceの実装です。
 type classUserServiceImpl struct {
-	roleRepo      repositories.RoleRepository
-	classUserRepo repositories.ClassUserRepository
+	txManager         repositories.TxManager
+	roleRepo          repositories.RoleRepository
+	classUserRepo     repositories.ClassUserRepository
+	classScheduleRepo repositories.ClassScheduleRepository
+	classBoardRepo    repositories.ClassBoardRepository
+	userRepo          repositories.UserRepository
+	redisClient       *redis.Client
+	activeClassLimit  int
+	// mail 参加申請の承認をメールで知らせる。nilの場合は送信しない
+	mail MailService
+	// webhooks メンバーの参加をクラスのWebhookに配信する。nilの場合は配信しない
+	webhooks WebhookPublisher
+	// events 参加申請の承認を他のサービスに配信する。nilの場合は配信しない
+	events EventPublisher
 }
 
-func NewClassUserService(classUserRepo repositories.ClassUserRepository, roleRepo repositories.RoleRepository) ClassUserService {
+// NewClassUserService ClassUserServiceを生成する。
+// activeClassLimitは1ユーザーが同時に参加できるアクティブなクラス数の上限で、0の場合は上限なし。ユーザーごとの設定があればそちらを優先する
+func NewClassUserService(txManager repositories.TxManager, classUserRepo repositories.ClassUserRepository, roleRepo repositories.RoleRepository, classScheduleRepo repositories.ClassScheduleRepository, classBoardRepo repositories.ClassBoardRepository, userRepo repositories.UserRepository, redisClient *redis.Client, activeClassLimit int, mail MailService, webhooks WebhookPublisher, events EventPublisher) ClassUserService {
 	return &classUserServiceImpl{
-		classUserRepo: classUserRepo,
-		roleRepo:      roleRepo,
+		txManager:         txManager,
+		classUserRepo:     classUserRepo,
+		roleRepo:          roleRepo,
+		classScheduleRepo: classScheduleRepo,
+		classBoardRepo:    classBoardRepo,
+		userRepo:          userRepo,
+		redisClient:       redisClient,
+		activeClassLimit:  activeClassLimit,
+		mail:              mail,
+		webhooks:          webhooks,
+		events:            events,
 	}
 }
 
-func (s *classUserServiceImpl) GetClassUserInfo(uid uint, cid uint) (dto.ClassMemberDTO, error) {
-	return s.classUserRepo.GetClassUserInfo(uid, cid)
+func (s *classUserServiceImpl) GetClassUserInfo(ctx context.Context, uid uint, cid uint) (dto.ClassMemberDTO, error) {
+	return s.classUserRepo.GetClassUserInfo(ctx, uid, cid)
 }
 
-func (s *classUserServiceImpl) GetUserClasses(uid uint, page int, limit int) ([]dto.UserClassInfoDTO, error) {
-	return s.classUserRepo.GetUserClasses(uid, page, limit)
+func (s *classUserServiceImpl) GetUserClasses(ctx context.Context, uid uint, page int, limit int, includeArchived bool) ([]dto.UserClassInfoDTO, error) {
+	return s.classUserRepo.GetUserClasses(ctx, uid, page, limit, repositories.ClassUserQueryOptions{IncludeArchived: includeArchived})
 }
 
-func (s *classUserServiceImpl) GetClassMembers(cid uint, roleNames ...string) ([]dto.ClassMemberDTO, error) {
-	if len(roleNames) > 0 {
-		return s.classUserRepo.GetClassMembers(cid, roleNames[0])
-	}
-	return s.classUserRepo.GetClassMembers(cid)
+func (s *classUserServiceImpl) GetClassMembers(ctx context.Context, cid uint, roleNames ...string) ([]dto.ClassMemberDTO, error) {
+	return s.classUserRepo.GetClassMembers(ctx, cid, roleNames...)
 }
 
-func (s *classUserServiceImpl) GetFavoriteClasses(uid uint, page int, limit int) ([]dto.UserClassInfoDTO, error) {
-	return s.classUserRepo.GetFavoriteClasses(uid, page, limit)
+func (s *classUserServiceImpl) GetFavoriteClasses(ctx context.Context, uid uint, page int, limit int) ([]dto.UserClassInfoDTO, error) {
+	return s.classUserRepo.GetFavoriteClasses(ctx, uid, page, limit)
 }
 
-func (s *classUserServiceImpl) GetUserClassesByRole(uid uint, roleName string, page int, limit int) ([]dto.UserClassInfoDTO, error) {
-	return s.classUserRepo.GetUserClassesByRole(uid, roleName, page, limit)
+func (s *classUserServiceImpl) GetUserClassesByRole(ctx context.Context, uid uint, roleName string, page int, limit int) ([]dto.UserClassInfoDTO, error) {
+	return s.classUserRepo.GetUserClassesByRole(ctx, uid, roleName, page, limit)
 }
 
-func (s *classUserServiceImpl) GetRole(uid uint, cid uint) (string, error) {
-	roleName, err := s.classUserRepo.GetRole(uid, cid)
+func (s *classUserServiceImpl) GetRole(ctx context.Context, uid uint, cid uint) (string, error) {
+	roleName, err := s.classUserRepo.GetRole(ctx, uid, cid)
 	if err != nil {
 		return "", err
 	}
 	return roleName, nil
 }
 
-func (s *classUserServiceImpl) AssignRole(uid uint, cid uint, roleName string) error {
-	exists, err := s.classUserRepo.RoleExists(uid, cid)
+// IsAdmin ユーザーがクラスの管理者か確認する
+func (s *classUserServiceImpl) IsAdmin(ctx context.Context, uid uint, cid uint) (bool, error) {
+	return s.classUserRepo.IsAdmin(ctx, uid, cid)
+}
+
+// IsAdminOrAssistant ユーザーがクラスの管理者またはアシスタントか確認する。ロールの上下関係を変える場合はここを変更する
+func (s *classUserServiceImpl) IsAdminOrAssistant(ctx context.Context, uid uint, cid uint) (bool, error) {
+	roleName, err := s.classUserRepo.GetRole(ctx, uid, cid)
+	if err != nil {
+		return false, err
+	}
+	return roleName == "ADMIN" || roleName == "ASSISTANT", nil
+}
+
+// AssignRole ユーザーにロールを割り当てる。既存のメンバーのロールを変更した場合は、クラスの管理者にrole_changedイベントを配信する。
+// 申請中のユーザーを生徒にした場合は、参加申請の承認をメールで知らせる。申請中または未参加のユーザーをメンバーにした場合はmember.joinedを配信する。
+// 未参加のユーザーを追加する場合はクラスコードでの参加と同じく、ユーザーの行をロックしてアクティブなクラス数の上限を確認し、上限に達していればErrActiveClassLimitを返す
+func (s *classUserServiceImpl) AssignRole(ctx context.Context, uid uint, cid uint, roleName string) error {
+	exists, err := s.classUserRepo.RoleExists(ctx, uid, cid)
 	if err != nil {
 		return err
 	}
 	if exists {
-		return s.classUserRepo.UpdateUserRole(uid, cid, roleName)
-	} else {
-		return s.classUserRepo.CreateUserRole(uid, cid, roleName)
+		oldRole, err := s.classUserRepo.GetRole(ctx, uid, cid)
+		if err != nil {
+			return err
+		}
+		var approval *MailRequest
+		if oldRole == "APPLICANT" && roleName == "USER" {
+			approval = s.approvalMail(ctx, uid, cid)
+		}
+		if err := s.classUserRepo.UpdateUserRole(ctx, uid, cid, roleName); err != nil {
+			return err
+		}
+		if oldRole != roleName {
+			s.publishClassEvent(ctx, dto.ClassEventDTO{Type: dto.ClassEventRoleChanged, CID: cid, UID: uid, OldRole: oldRole, NewRole: roleName})
+		}
+		if oldRole == "APPLICANT" {
+			s.publishMemberJoined(ctx, uid, cid, roleName)
+			s.publishMemberApproved(ctx, uid, cid, roleName)
+		}
+		if approval != nil {
+			// メールを送信できなくてもロールの変更は取り消さない
+			if err := s.mail.Send(ctx, *approval); err != nil {
+				utils.ReportBackgroundError("send_mail", fmt.Errorf("failed to queue approval mail to uid %d for class %d: %w", uid, cid, err))
+			}
+		}
+		return nil
+	}
+	var created bool
+	err = s.txManager.WithinTransaction(ctx, func(repos repositories.RepositorySet) error {
+		if err := s.checkActiveClassLimit(ctx, repos, uid); err != nil {
+			return err
+		}
+		_, isNew, err := repos.ClassUser.CreateUserRole(ctx, uid, cid, roleName)
+		created = isNew
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if created {
+		s.publishMemberJoined(ctx, uid, cid, roleName)
 	}
+	return nil
 }
 
-func (s *classUserServiceImpl) UpdateUserName(uid uint, cid uint, newName string) error {
-	return s.classUserRepo.UpdateUserName(uid, cid, newName)
+// publishMemberJoined ユーザーがメンバーとして参加したことをクラスのWebhookに配信する。申請者は参加に含めない
+func (s *classUserServiceImpl) publishMemberJoined(ctx context.Context, uid uint, cid uint, roleName string) {
+	if s.webhooks == nil || roleName == "APPLICANT" {
+		return
+	}
+	s.webhooks.PublishWebhook(ctx, cid, models.MemberJoinedWebhook, dto.WebhookPayloadDTO{
+		Member: &dto.WebhookMemberV1{UID: uid, Role: roleName},
+	})
 }
 
-func (s *classUserServiceImpl) ToggleFavorite(uid uint, cid uint) error {
-	err := s.classUserRepo.ToggleFavorite(uid, cid)
+// publishMemberApproved 参加申請の承認を他のサービスに配信する。申請中のままの場合とブラックリストに登録した場合は承認に含めない
+func (s *classUserServiceImpl) publishMemberApproved(ctx context.Context, uid uint, cid uint, roleName string) {
+	if s.events == nil || roleName == "APPLICANT" || roleName == "BLACKLIST" {
+		return
+	}
+	s.events.PublishEvent(ctx, dto.EventMemberApproved, dto.EventMemberApprovedV1{CID: cid, UID: uid, Role: roleName})
+}
+
+// approvalMail 参加申請の承認を知らせるメールを作成する。承認の前に申請中のクラスから宛先とクラス名を取得し、
+// メールを送信しない設定の場合とメールアドレスが未登録の場合はnilを返す
+func (s *classUserServiceImpl) approvalMail(ctx context.Context, uid uint, cid uint) *MailRequest {
+	if s.mail == nil {
+		return nil
+	}
+	applications, err := s.userRepo.GetApplyingClasses(ctx, uid)
+	if err != nil {
+		utils.ReportBackgroundError("send_mail", fmt.Errorf("failed to find applications of uid %d: %w", uid, err))
+		return nil
+	}
+	for _, application := range applications {
+		if application.CID != cid || application.User.Email == "" || !application.User.EmailVerified {
+			continue
+		}
+		return &MailRequest{
+			To:       application.User.Email,
+			Locale:   application.User.Locale,
+			Template: ApplicationApprovedMail,
+			Data:     MailData{Name: application.Nickname, ClassName: application.Class.Name},
+		}
+	}
+	return nil
+}
+
+// AssignRoleViaCode はクラスコード経由でクラスに参加させ、使用したコードを記録します。参加させた場合はtrueを返します。
+// クラスコードは1ユーザーにつき1回のみ使用できます。既にメンバーの場合はロールを変更せずに既存のメンバー情報を返し、
+// 同じコードで参加した後に退出・削除されたユーザーはErrClassCodeUsedを返します。再参加はクラスの管理者が削除済みのメンバーから復元します。
+// コードを使わずに追加された後に削除されたユーザーは、申請者として再参加できます。
+// 新しく参加する場合、アクティブなクラス数が上限に達していればErrActiveClassLimitを返します。申請者以外として参加させた場合はmember.joinedを配信します。
+// 上限の確認から参加までは1つのトランザクションでユーザーの行をロックし、同じユーザーが同時に参加しても上限を超えないようにします。
+func (s *classUserServiceImpl) AssignRoleViaCode(ctx context.Context, uid uint, cid uint, roleName string, codeID uint) (*models.ClassUser, bool, error) {
+	var classUser *models.ClassUser
+	var joined bool
+	err := s.txManager.WithinTransaction(ctx, func(repos repositories.RepositorySet) error {
+		members, err := repos.ClassUser.FindMembersByUIDs(ctx, cid, []uint{uid})
+		if err != nil {
+			return err
+		}
+		if len(members) > 0 {
+			classUser = &members[0]
+			return nil
+		}
+		removed, err := repos.ClassUser.FindRemovedMember(ctx, uid, cid)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if removed != nil && removed.CodeID != nil && *removed.CodeID == codeID {
+			return ErrClassCodeUsed
+		}
+		if err := s.checkActiveClassLimit(ctx, repos, uid); err != nil {
+			return err
+		}
+
+		created, isNew, err := repos.ClassUser.CreateUserRole(ctx, uid, cid, roleName)
+		if err != nil || !isNew {
+			classUser = created
+			return err
+		}
+		if err := repos.ClassUser.UpdateJoinedViaCode(ctx, uid, cid, codeID); err != nil {
+			return err
+		}
+		created.CodeID = &codeID
+		classUser, joined = created, true
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	if joined {
+		s.publishMemberJoined(ctx, uid, cid, roleName)
+	}
+	return classUser, joined, nil
+}
+
+// checkActiveClassLimit はユーザーのアクティブなクラス数が上限に達していないかを確認します。
+// アーカイブされたクラスは数えません。トランザクションの中で呼び出し、ユーザーの行をトランザクションの終了までロックします。
+// 同じユーザーの参加はロックを待ってから数えるため、数えてから参加するまでの間に他の参加が割り込みません。
+func (s *classUserServiceImpl) checkActiveClassLimit(ctx context.Context, repos repositories.RepositorySet, uid uint) error {
+	limit := s.activeClassLimit
+	user, err := repos.User.LockByID(ctx, uid)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return ErrNotFound
 		}
+		return err
+	}
+	if user.MaxActiveClasses != nil {
+		limit = *user.MaxActiveClasses
+	}
+	if limit <= 0 {
+		return nil
+	}
 
+	count, err := repos.ClassUser.CountActiveClasses(ctx, uid)
+	if err != nil {
 		return err
 	}
+	if count >= int64(limit) {
+		return ErrActiveClassLimit
+	}
 	return nil
 }
 
-func (s *classUserServiceImpl) RemoveUserFromClass(uid uint, cid uint) error {
-	return s.classUserRepo.DeleteClassUser(uid, cid)
+// GetClassUsersByCodeID は指定されたクラスコードで参加したユーザーを取得します。
+func (s *classUserServiceImpl) GetClassUsersByCodeID(ctx context.Context, codeID uint) ([]models.ClassUser, error) {
+	return s.classUserRepo.GetClassUsersByCodeID(ctx, codeID)
 }
 
-func (s *classUserServiceImpl) SearchUserClassesByName(uid uint, name string) ([]dto.UserClassInfoDTO, error) {
-	return s.classUserRepo.SearchUserClassesByName(uid, name)
+func (s *classUserServiceImpl) UpdateUserName(ctx context.Context, uid uint, cid uint, newName string) error {
+	return s.classUserRepo.UpdateUserName(ctx, uid, cid, newName)
+}
+
+// BatchSetFavorite 複数のクラスのお気に入りを一括で設定する。
+// 参加していないクラスが含まれる場合は更新せず、そのクラスIDを持つNotEnrolledErrorを返す
+func (s *classUserServiceImpl) BatchSetFavorite(ctx context.Context, uid uint, classIDs []uint, isFavorite bool) error {
+	uniqueIDs := make([]uint, 0, len(classIDs))
+	seen := make(map[uint]bool, len(classIDs))
+	for _, cid := range classIDs {
+		if !seen[cid] {
+			seen[cid] = true
+			uniqueIDs = append(uniqueIDs, cid)
+		}
+	}
+
+	enrolledIDs, err := s.classUserRepo.FindEnrolledClassIDs(ctx, uid, uniqueIDs)
+	if err != nil {
+		return err
+	}
+	enrolled := make(map[uint]bool, len(enrolledIDs))
+	for _, cid := range enrolledIDs {
+		enrolled[cid] = true
+	}
+	var notEnrolled []uint
+	for _, cid := range uniqueIDs {
+		if !enrolled[cid] {
+			notEnrolled = append(notEnrolled, cid)
+		}
+	}
+	if len(notEnrolled) > 0 {
+		return &NotEnrolledError{ClassIDs: notEnrolled}
+	}
+
+	return s.classUserRepo.BatchSetFavorite(ctx, uid, uniqueIDs, isFavorite)
+}
+
+func (s *classUserServiceImpl) ToggleFavorite(ctx context.Context, uid uint, cid uint) error {
+	err := s.classUserRepo.ToggleFavorite(ctx, uid, cid)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrNotFound
+		}
+
+		return err
+	}
+	return nil
+}
+
+// RemoveUserFromClass はメンバーをクラスから論理削除します。参加日や参加に使用したコードは保持されます。
+func (s *classUserServiceImpl) RemoveUserFromClass(ctx context.Context, uid uint, cid uint) error {
+	return s.classUserRepo.DeleteClassUser(ctx, uid, cid)
+}
+
+// GetRemovedMembers はクラスから削除されたメンバーを取得します。
+func (s *classUserServiceImpl) GetRemovedMembers(ctx context.Context, cid uint) ([]dto.RemovedMemberDTO, error) {
+	return s.classUserRepo.GetRemovedMembers(ctx, cid)
+}
+
+// RestoreMember は削除されたメンバーをクラスに復元します。
+func (s *classUserServiceImpl) RestoreMember(ctx context.Context, uid uint, cid uint) error {
+	err := s.classUserRepo.RestoreClassUser(ctx, uid, cid)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// MoveMembers は移動元のクラスのメンバーを移動先のクラスに移動します。request.Copyがtrueの場合は移動元に残します。
+// 両方のクラスの管理者のみ実行でき、移動先で既にメンバーのユーザーと移動元のメンバーでないユーザーはスキップします。
+// 移動元の管理者は移動先のロールに関わらずスキップし、移動元のクラスから管理者がいなくならないようにします。
+// 移動先に追加するとアクティブなクラス数が増え、上限に達しているユーザーもスキップします。
+// 全てのメンバーを1つのトランザクションで処理し、いずれかの書き込みに失敗した場合は全て取り消します。
+func (s *classUserServiceImpl) MoveMembers(ctx context.Context, uid uint, request dto.MoveMembersRequest) (*dto.MoveMembersResultDTO, error) {
+	for _, cid := range []uint{request.FromCID, request.ToCID} {
+		isAdmin, err := s.classUserRepo.IsAdmin(ctx, uid, cid)
+		if err != nil || !isAdmin {
+			return nil, ErrUnauthorized
+		}
+	}
+
+	uids := make([]uint, 0, len(request.UIDs))
+	seen := make(map[uint]bool, len(request.UIDs))
+	for _, memberUID := range request.UIDs {
+		if !seen[memberUID] {
+			seen[memberUID] = true
+			uids = append(uids, memberUID)
+		}
+	}
+
+	result := &dto.MoveMembersResultDTO{FromCID: request.FromCID, ToCID: request.ToCID, Copy: request.Copy}
+	err := s.txManager.WithinTransaction(ctx, func(repos repositories.RepositorySet) error {
+		sources, err := repos.ClassUser.FindMembersByUIDs(ctx, request.FromCID, uids)
+		if err != nil {
+			return err
+		}
+		destinations, err := repos.ClassUser.FindMembersByUIDs(ctx, request.ToCID, uids)
+		if err != nil {
+			return err
+		}
+		sourceByUID := make(map[uint]models.ClassUser, len(sources))
+		for _, source := range sources {
+			sourceByUID[source.UID] = source
+		}
+		alreadyMember := make(map[uint]bool, len(destinations))
+		for _, destination := range destinations {
+			alreadyMember[destination.UID] = true
+		}
+		// アクティブなクラスからアクティブなクラスへの移動ではクラス数が変わらないため、上限を確認しない
+		fromClass, err := repos.Class.GetByID(ctx, request.FromCID)
+		if err != nil {
+			return err
+		}
+		toClass, err := repos.Class.GetByID(ctx, request.ToCID)
+		if err != nil {
+			return err
+		}
+		addsActiveClass := !toClass.IsArchived && (request.Copy || fromClass.IsArchived)
+
+		result.Results = make([]dto.MemberTransferResultDTO, 0, len(uids))
+		for _, memberUID := range uids {
+			source, isMember := sourceByUID[memberUID]
+			status := dto.MemberTransferCopied
+			switch {
+			case !isMember:
+				status = dto.MemberTransferSkippedNotMember
+			case source.Role == "ADMIN":
+				status = dto.MemberTransferSkippedAdmin
+			case alreadyMember[memberUID]:
+				status = dto.MemberTransferSkippedAlreadyMember
+			default:
+				if addsActiveClass {
+					err := s.checkActiveClassLimit(ctx, repos, memberUID)
+					if errors.Is(err, ErrActiveClassLimit) {
+						status = dto.MemberTransferSkippedClassLimit
+						break
+					}
+					if err != nil {
+						return err
+					}
+				}
+				if err := repos.ClassUser.AddMember(ctx, &models.ClassUser{CID: request.ToCID, UID: memberUID, Nickname: source.Nickname, Role: request.Role}); err != nil {
+					return err
+				}
+				if !request.Copy {
+					if err := repos.ClassUser.DeleteClassUser(ctx, memberUID, request.FromCID); err != nil {
+						return err
+					}
+					status = dto.MemberTransferMoved
+				}
+			}
+
+			if status == dto.MemberTransferMoved || status == dto.MemberTransferCopied {
+				result.Transferred++
+			} else {
+				result.Skipped++
+			}
+			result.Results = append(result.Results, dto.MemberTransferResultDTO{UID: memberUID, Status: status})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (s *classUserServiceImpl) SearchUserClassesByName(ctx context.Context, uid uint, name string) ([]dto.UserClassInfoDTO, error) {
+	return s.classUserRepo.SearchUserClassesByName(ctx, uid, name)
+}
+
+// ExportMembers クラス名簿をCSV形式でwに書き出す。名前とメールアドレスは表計算ソフトで数式として実行されないよう無害化する
+func (s *classUserServiceImpl) ExportMembers(ctx context.Context, cid uint, w io.Writer) error {
+	members, err := s.classUserRepo.GetClassMembersForExport(ctx, cid)
+	if err != nil {
+		return err
+	}
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"uid", "name", "email", "role", "joined_at", "attendance_rate"}); err != nil {
+		return err
+	}
+	for _, member := range members {
+		record := []string{
+			strconv.FormatUint(uint64(member.UID), 10),
+			csvSafe(member.Name),
+			csvSafe(member.Email),
+			member.Role,
+			member.JoinedAt.Format(time.RFC3339),
+			strconv.FormatFloat(member.AttendanceRate, 'f', 4, 64),
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// csvSafe 表計算ソフトが数式として解釈する文字で始まるセルの先頭に'を付ける
+func csvSafe(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
 }
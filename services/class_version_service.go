@@ -0,0 +1,78 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	classVersionKey = "class_version:%d"
+	// classVersionTTL バージョンの有効期限。書き込みで更新されないデータ(次回授業や活動指標など)も、この期間が過ぎれば新しいバージョンになる
+	classVersionTTL = 5 * time.Minute
+)
+
+// ClassVersionService クラスのデータが更新されるたびに変わるバージョンを管理する。
+// ETagの計算に使い、変更が無い場合はレスポンスを組み立てずに304を返せるようにする
+type ClassVersionService interface {
+	// ClassVersion クラスの現在のバージョンを返す。未設定の場合は新しいバージョンを発行する
+	ClassVersion(ctx context.Context, cid uint) (string, error)
+	// DashboardVersion クラスのバージョンにユーザーの既読状態を加えたダッシュボードのバージョンを返す
+	DashboardVersion(ctx context.Context, uid uint, cid uint) (string, error)
+	// Bump クラスのバージョンを破棄し、次の参照で新しいバージョンを発行させる
+	Bump(ctx context.Context, cid uint) error
+}
+
+// classVersionService インタフェースを実装
+type classVersionService struct {
+	redisClient *redis.Client
+}
+
+// NewClassVersionService ClassVersionServiceを生成する
+func NewClassVersionService(redisClient *redis.Client) ClassVersionService {
+	return &classVersionService{redisClient: redisClient}
+}
+
+// ClassVersion Redisに保存したバージョンを返す。他のリクエストが同時に発行した場合はそちらを使う
+func (s *classVersionService) ClassVersion(ctx context.Context, cid uint) (string, error) {
+	key := fmt.Sprintf(classVersionKey, cid)
+	version, err := s.redisClient.Get(ctx, key).Result()
+	if err == nil {
+		return version, nil
+	}
+	if !errors.Is(err, redis.Nil) {
+		return "", err
+	}
+
+	version = strconv.FormatInt(time.Now().UnixNano(), 36)
+	created, err := s.redisClient.SetNX(ctx, key, version, classVersionTTL).Result()
+	if err != nil {
+		return "", err
+	}
+	if !created {
+		return s.redisClient.Get(ctx, key).Result()
+	}
+	return version, nil
+}
+
+// DashboardVersion 既読の集合は追加のみのため、件数で既読状態の変化を判定する
+func (s *classVersionService) DashboardVersion(ctx context.Context, uid uint, cid uint) (string, error) {
+	version, err := s.ClassVersion(ctx, cid)
+	if err != nil {
+		return "", err
+	}
+	readCount, err := s.redisClient.SCard(ctx, fmt.Sprintf(boardReadKey, uid)).Result()
+	if err != nil {
+		return "", err
+	}
+	return version + "." + strconv.FormatInt(readCount, 10), nil
+}
+
+// Bump バージョンを削除する
+func (s *classVersionService) Bump(ctx context.Context, cid uint) error {
+	return s.redisClient.Del(ctx, fmt.Sprintf(classVersionKey, cid)).Err()
+}
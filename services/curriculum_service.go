@@ -0,0 +1,303 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+	"gorm.io/gorm"
+)
+
+// CurriculumService コース型クラスのカリキュラムと生徒の進捗のサービス。
+// 項目の管理とクラス全体の進捗の閲覧はクラスの管理者とアシスタント、項目の完了の記録は生徒のみ利用できる
+type CurriculumService interface {
+	GetItems(ctx context.Context, viewerUID uint, cid uint) ([]dto.CurriculumItemDTO, error)
+	CreateItem(ctx context.Context, viewerUID uint, cid uint, request dto.CurriculumItemRequest) (*dto.CurriculumItemDTO, error)
+	UpdateItem(ctx context.Context, viewerUID uint, cid uint, itemID uint, request dto.CurriculumItemRequest) (*dto.CurriculumItemDTO, error)
+	DeleteItem(ctx context.Context, viewerUID uint, cid uint, itemID uint) error
+	CompleteItem(ctx context.Context, viewerUID uint, cid uint, itemID uint) error
+	UncompleteItem(ctx context.Context, viewerUID uint, cid uint, itemID uint) error
+	GetUserProgress(ctx context.Context, viewerUID uint, cid uint, uid uint) (*dto.UserCurriculumProgressDTO, error)
+	GetClassProgress(ctx context.Context, viewerUID uint, cid uint) (*dto.ClassCurriculumProgressDTO, error)
+}
+
+// curriculumService インタフェースを実装
+type curriculumService struct {
+	repo          repositories.CurriculumRepository
+	classUserRepo repositories.ClassUserRepository
+}
+
+// NewCurriculumService CurriculumServiceを生成
+func NewCurriculumService(repo repositories.CurriculumRepository, classUserRepo repositories.ClassUserRepository) CurriculumService {
+	return &curriculumService{
+		repo:          repo,
+		classUserRepo: classUserRepo,
+	}
+}
+
+// GetItems クラスのカリキュラムの項目を表示順に取得し、ログインユーザーが完了した日時を付ける
+func (s *curriculumService) GetItems(ctx context.Context, viewerUID uint, cid uint) ([]dto.CurriculumItemDTO, error) {
+	if _, err := s.authorize(ctx, viewerUID, cid); err != nil {
+		return nil, err
+	}
+	items, err := s.repo.FindItemsByClass(ctx, cid)
+	if err != nil {
+		return nil, err
+	}
+	progress, err := s.repo.FindProgressByUser(ctx, cid, viewerUID)
+	if err != nil {
+		return nil, err
+	}
+
+	completedAt := make(map[uint]time.Time, len(progress))
+	for _, p := range progress {
+		completedAt[p.ItemID] = p.CompletedAt
+	}
+	result := make([]dto.CurriculumItemDTO, 0, len(items))
+	for _, item := range items {
+		itemDTO := toCurriculumItemDTO(item)
+		if at, ok := completedAt[item.ID]; ok {
+			itemDTO.CompletedAt = &at
+		}
+		result = append(result, itemDTO)
+	}
+	return result, nil
+}
+
+// CreateItem カリキュラムに項目を追加する。表示順を省略した場合は最後に追加する
+func (s *curriculumService) CreateItem(ctx context.Context, viewerUID uint, cid uint, request dto.CurriculumItemRequest) (*dto.CurriculumItemDTO, error) {
+	if err := s.authorizeStaff(ctx, viewerUID, cid); err != nil {
+		return nil, err
+	}
+
+	item := models.CurriculumItem{CID: cid, Title: request.Title}
+	if request.Order != nil {
+		item.Order = *request.Order
+	} else {
+		order, err := s.repo.NextItemOrder(ctx, cid)
+		if err != nil {
+			return nil, err
+		}
+		item.Order = order
+	}
+	if err := s.repo.CreateItem(ctx, &item); err != nil {
+		return nil, err
+	}
+	result := toCurriculumItemDTO(item)
+	return &result, nil
+}
+
+// UpdateItem カリキュラムの項目の名前と表示順を更新する。表示順を省略した場合は変更しない
+func (s *curriculumService) UpdateItem(ctx context.Context, viewerUID uint, cid uint, itemID uint, request dto.CurriculumItemRequest) (*dto.CurriculumItemDTO, error) {
+	if err := s.authorizeStaff(ctx, viewerUID, cid); err != nil {
+		return nil, err
+	}
+	item, err := s.findItem(ctx, cid, itemID)
+	if err != nil {
+		return nil, err
+	}
+
+	item.Title = request.Title
+	if request.Order != nil {
+		item.Order = *request.Order
+	}
+	if err := s.repo.UpdateItem(ctx, item); err != nil {
+		return nil, err
+	}
+	result := toCurriculumItemDTO(*item)
+	return &result, nil
+}
+
+// DeleteItem カリキュラムの項目を削除する。生徒の完了の記録も削除される
+func (s *curriculumService) DeleteItem(ctx context.Context, viewerUID uint, cid uint, itemID uint) error {
+	if err := s.authorizeStaff(ctx, viewerUID, cid); err != nil {
+		return err
+	}
+	if _, err := s.findItem(ctx, cid, itemID); err != nil {
+		return err
+	}
+	return s.repo.DeleteItem(ctx, itemID)
+}
+
+// CompleteItem 生徒が項目を完了したことを記録する。完了済みの場合は何もしない
+func (s *curriculumService) CompleteItem(ctx context.Context, viewerUID uint, cid uint, itemID uint) error {
+	if err := s.authorizeStudent(ctx, viewerUID, cid, itemID); err != nil {
+		return err
+	}
+	return s.repo.SaveProgress(ctx, &models.UserProgress{UID: viewerUID, ItemID: itemID, CompletedAt: time.Now()})
+}
+
+// UncompleteItem 生徒の項目の完了を取り消す。未完了の場合は何もしない
+func (s *curriculumService) UncompleteItem(ctx context.Context, viewerUID uint, cid uint, itemID uint) error {
+	if err := s.authorizeStudent(ctx, viewerUID, cid, itemID); err != nil {
+		return err
+	}
+	return s.repo.DeleteProgress(ctx, viewerUID, itemID)
+}
+
+// GetUserProgress 生徒の進捗率と完了した項目を返す。生徒本人とクラスの管理者・アシスタントのみ取得できる
+func (s *curriculumService) GetUserProgress(ctx context.Context, viewerUID uint, cid uint, uid uint) (*dto.UserCurriculumProgressDTO, error) {
+	role, err := s.authorize(ctx, viewerUID, cid)
+	if err != nil {
+		return nil, err
+	}
+	if viewerUID != uid && role != "ADMIN" && role != "ASSISTANT" {
+		return nil, ErrUnauthorized
+	}
+
+	items, err := s.repo.FindItemsByClass(ctx, cid)
+	if err != nil {
+		return nil, err
+	}
+	progress, err := s.repo.FindProgressByUser(ctx, cid, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	completed := make(map[uint]bool, len(progress))
+	for _, p := range progress {
+		completed[p.ItemID] = true
+	}
+	result := &dto.UserCurriculumProgressDTO{UID: uid, TotalCount: len(items), CompletedItemIDs: []uint{}}
+	for _, item := range items {
+		if completed[item.ID] {
+			result.CompletedItemIDs = append(result.CompletedItemIDs, item.ID)
+		}
+	}
+	result.CompletedCount = len(result.CompletedItemIDs)
+	result.ProgressRate = progressRate(result.CompletedCount, result.TotalCount)
+	return result, nil
+}
+
+// GetClassProgress クラスの生徒ごとの進捗率と項目ごとの完了率を返す。退会した生徒の記録は集計に含めない
+func (s *curriculumService) GetClassProgress(ctx context.Context, viewerUID uint, cid uint) (*dto.ClassCurriculumProgressDTO, error) {
+	if err := s.authorizeStaff(ctx, viewerUID, cid); err != nil {
+		return nil, err
+	}
+	items, err := s.repo.FindItemsByClass(ctx, cid)
+	if err != nil {
+		return nil, err
+	}
+	students, err := s.classUserRepo.GetClassMembers(ctx, cid, "USER")
+	if err != nil {
+		return nil, err
+	}
+	progress, err := s.repo.FindProgressByClass(ctx, cid)
+	if err != nil {
+		return nil, err
+	}
+
+	isStudent := make(map[uint]bool, len(students))
+	for _, student := range students {
+		isStudent[student.Uid] = true
+	}
+	byStudent := make(map[uint]int, len(students))
+	byItem := make(map[uint]int, len(items))
+	for _, p := range progress {
+		if !isStudent[p.UID] {
+			continue
+		}
+		byStudent[p.UID]++
+		byItem[p.ItemID]++
+	}
+
+	result := &dto.ClassCurriculumProgressDTO{
+		TotalCount:   len(items),
+		StudentCount: len(students),
+		Students:     make([]dto.StudentCurriculumProgressDTO, 0, len(students)),
+		Items:        make([]dto.CurriculumItemProgressDTO, 0, len(items)),
+	}
+	var totalRate float64
+	for _, student := range students {
+		rate := progressRate(byStudent[student.Uid], len(items))
+		totalRate += rate
+		result.Students = append(result.Students, dto.StudentCurriculumProgressDTO{
+			UID:            student.Uid,
+			Nickname:       student.Nickname,
+			CompletedCount: byStudent[student.Uid],
+			ProgressRate:   rate,
+		})
+	}
+	if len(students) > 0 {
+		result.AverageProgressRate = totalRate / float64(len(students))
+	}
+	for _, item := range items {
+		result.Items = append(result.Items, dto.CurriculumItemProgressDTO{
+			ItemID:         item.ID,
+			Title:          item.Title,
+			CompletedCount: byItem[item.ID],
+			CompletionRate: progressRate(byItem[item.ID], len(students)),
+		})
+	}
+	return result, nil
+}
+
+// authorize クラスのメンバーであることを確認し、ロールを返す。参加申請中とブロック済みのユーザーはメンバーとしない
+func (s *curriculumService) authorize(ctx context.Context, viewerUID uint, cid uint) (string, error) {
+	role, err := s.classUserRepo.GetRole(ctx, viewerUID, cid)
+	if err != nil || !isActiveMemberRole(role) {
+		return "", ErrUnauthorized
+	}
+	return role, nil
+}
+
+// authorizeStaff クラスの管理者またはアシスタントであることを確認する
+func (s *curriculumService) authorizeStaff(ctx context.Context, viewerUID uint, cid uint) error {
+	role, err := s.authorize(ctx, viewerUID, cid)
+	if err != nil {
+		return err
+	}
+	if role != "ADMIN" && role != "ASSISTANT" {
+		return ErrUnauthorized
+	}
+	return nil
+}
+
+// authorizeStudent クラスの生徒であり、項目がクラスのカリキュラムにあることを確認する
+func (s *curriculumService) authorizeStudent(ctx context.Context, viewerUID uint, cid uint, itemID uint) error {
+	role, err := s.authorize(ctx, viewerUID, cid)
+	if err != nil {
+		return err
+	}
+	if role != "USER" {
+		return ErrUnauthorized
+	}
+	_, err = s.findItem(ctx, cid, itemID)
+	return err
+}
+
+// findItem クラスのカリキュラムの項目を取得する。別のクラスの項目はErrNotFoundとする
+func (s *curriculumService) findItem(ctx context.Context, cid uint, itemID uint) (*models.CurriculumItem, error) {
+	item, err := s.repo.FindItemByID(ctx, itemID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if item.CID != cid {
+		return nil, ErrNotFound
+	}
+	return item, nil
+}
+
+// progressRate completedのtotalに対する割合を返す。totalが0の場合は0
+func progressRate(completed, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(completed) / float64(total)
+}
+
+// toCurriculumItemDTO カリキュラムの項目をDTOに変換する
+func toCurriculumItemDTO(item models.CurriculumItem) dto.CurriculumItemDTO {
+	return dto.CurriculumItemDTO{
+		ID:    item.ID,
+		CID:   item.CID,
+		Title: item.Title,
+		Order: item.Order,
+	}
+}
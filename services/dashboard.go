@@ -0,0 +1,187 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+)
+
+const (
+	// dashboardAtRiskAttendanceRate この出席率を下回るメンバーをリスク生徒とする
+	dashboardAtRiskAttendanceRate = 0.7
+	dashboardBoardLimit           = 5
+	dashboardAtRiskLimit          = 10
+)
+
+// ダッシュボードのウィジェット種別
+const (
+	WidgetAttendanceSummary   = "attendance_summary"
+	WidgetAtRiskStudents      = "at_risk_students"
+	WidgetNextSchedule        = "next_schedule"
+	WidgetRecentAnnouncements = "recent_announcements"
+	WidgetUnreadAnnouncements = "unread_announcements"
+	WidgetMyAttendance        = "my_attendance"
+)
+
+// dashboardLayouts ロールごとのウィジェットの表示順
+var dashboardLayouts = map[string][]string{
+	"ADMIN":     {WidgetAttendanceSummary, WidgetAtRiskStudents, WidgetNextSchedule, WidgetRecentAnnouncements},
+	"ASSISTANT": {WidgetNextSchedule, WidgetAttendanceSummary, WidgetAtRiskStudents, WidgetRecentAnnouncements},
+	"USER":      {WidgetNextSchedule, WidgetUnreadAnnouncements, WidgetMyAttendance},
+}
+
+// GetDashboardLayout ユーザーのロールに応じたダッシュボードの構成と各ウィジェットのデータを返す
+// 講師・アシスタントには出席統計とリスク生徒、生徒には次回授業と未読の掲示を優先して返す
+func (s *classUserServiceImpl) GetDashboardLayout(ctx context.Context, uid uint, cid uint) (*dto.DashboardLayoutDTO, error) {
+	role, err := s.classUserRepo.GetRole(ctx, uid, cid)
+	if err != nil {
+		return nil, ErrUnauthorized
+	}
+	widgetTypes, ok := dashboardLayouts[role]
+	if !ok {
+		return nil, ErrUnauthorized
+	}
+
+	var stats []dto.MemberActivityStatsDTO
+	if needsActivityStats(widgetTypes) {
+		if stats, err = s.getCachedActivityStats(ctx, cid); err != nil {
+			return nil, err
+		}
+	}
+
+	layout := &dto.DashboardLayoutDTO{CID: cid, Role: role, Widgets: make([]dto.DashboardWidgetDTO, 0, len(widgetTypes))}
+	for i, widgetType := range widgetTypes {
+		data, err := s.dashboardWidgetData(ctx, widgetType, uid, cid, stats)
+		if err != nil {
+			return nil, err
+		}
+		layout.Widgets = append(layout.Widgets, dto.DashboardWidgetDTO{Type: widgetType, Order: i + 1, Data: data})
+	}
+	return layout, nil
+}
+
+// needsActivityStats メンバーの活動指標を使うウィジェットが含まれるか判定する
+func needsActivityStats(widgetTypes []string) bool {
+	for _, widgetType := range widgetTypes {
+		switch widgetType {
+		case WidgetAttendanceSummary, WidgetAtRiskStudents, WidgetMyAttendance:
+			return true
+		}
+	}
+	return false
+}
+
+// dashboardWidgetData ウィジェットに表示するデータを取得する
+func (s *classUserServiceImpl) dashboardWidgetData(ctx context.Context, widgetType string, uid uint, cid uint, stats []dto.MemberActivityStatsDTO) (interface{}, error) {
+	switch widgetType {
+	case WidgetAttendanceSummary:
+		return summarizeAttendance(stats), nil
+	case WidgetAtRiskStudents:
+		return atRiskMembers(stats, dashboardAtRiskLimit), nil
+	case WidgetNextSchedule:
+		return s.dashboardNextSchedule(ctx, cid)
+	case WidgetRecentAnnouncements:
+		return s.dashboardAnnouncements(ctx, cid, 0)
+	case WidgetUnreadAnnouncements:
+		return s.dashboardAnnouncements(ctx, cid, uid)
+	case WidgetMyAttendance:
+		for _, stat := range stats {
+			if stat.UID == uid {
+				return dto.DashboardMyAttendanceDTO{AttendanceRate: stat.AttendanceRate}, nil
+			}
+		}
+		return dto.DashboardMyAttendanceDTO{}, nil
+	}
+	return nil, fmt.Errorf("unknown dashboard widget: %s", widgetType)
+}
+
+// summarizeAttendance メンバーの出席率からクラス全体の出席統計を求める
+func summarizeAttendance(stats []dto.MemberActivityStatsDTO) dto.DashboardAttendanceSummaryDTO {
+	summary := dto.DashboardAttendanceSummaryDTO{MemberCount: len(stats)}
+	if len(stats) == 0 {
+		return summary
+	}
+
+	var total float64
+	for _, stat := range stats {
+		total += stat.AttendanceRate
+		if stat.AttendanceRate < dashboardAtRiskAttendanceRate {
+			summary.AtRiskCount++
+		}
+	}
+	summary.AverageAttendanceRate = total / float64(len(stats))
+	return summary
+}
+
+// atRiskMembers 出席率が基準を下回るメンバーを出席率の低い順に返す
+func atRiskMembers(stats []dto.MemberActivityStatsDTO, limit int) []dto.DashboardAtRiskMemberDTO {
+	members := make([]dto.DashboardAtRiskMemberDTO, 0)
+	for _, stat := range stats {
+		if stat.AttendanceRate < dashboardAtRiskAttendanceRate {
+			members = append(members, dto.DashboardAtRiskMemberDTO{UID: stat.UID, Nickname: stat.Nickname, AttendanceRate: stat.AttendanceRate})
+		}
+	}
+
+	sort.SliceStable(members, func(i, j int) bool {
+		return members[i].AttendanceRate < members[j].AttendanceRate
+	})
+	if len(members) > limit {
+		members = members[:limit]
+	}
+	return members
+}
+
+// dashboardNextSchedule 次回の授業を取得する。予定が無い場合はnilを返す
+func (s *classUserServiceImpl) dashboardNextSchedule(ctx context.Context, cid uint) (*dto.DashboardScheduleDTO, error) {
+	schedule, err := s.classScheduleRepo.FindNextClassSchedule(ctx, cid, time.Now())
+	if err != nil || schedule == nil {
+		return nil, err
+	}
+	return &dto.DashboardScheduleDTO{
+		ID:          schedule.ID,
+		Title:       schedule.Title,
+		StartedAt:   schedule.StartedAt,
+		EndedAt:     schedule.EndedAt,
+		IsLive:      schedule.IsLive,
+		IsCancelled: schedule.IsCancelled,
+	}, nil
+}
+
+// dashboardAnnouncements 公開された掲示を新しい順に取得する。uidを指定した場合はそのユーザーの未読のみを返す
+func (s *classUserServiceImpl) dashboardAnnouncements(ctx context.Context, cid uint, uid uint) ([]dto.DashboardBoardDTO, error) {
+	boards, err := s.classBoardRepo.FindAnnounced(ctx, true, cid)
+	if err != nil {
+		return nil, err
+	}
+
+	read := map[string]bool{}
+	if uid != 0 {
+		ids, err := s.redisClient.SMembers(ctx, fmt.Sprintf(boardReadKey, uid)).Result()
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range ids {
+			read[id] = true
+		}
+	}
+
+	sort.SliceStable(boards, func(i, j int) bool {
+		return boards[i].CreatedAt.After(boards[j].CreatedAt)
+	})
+
+	result := make([]dto.DashboardBoardDTO, 0, dashboardBoardLimit)
+	for _, board := range boards {
+		if read[strconv.FormatUint(uint64(board.ID), 10)] {
+			continue
+		}
+		result = append(result, dto.DashboardBoardDTO{ID: board.ID, Title: board.Title, CreatedAt: board.CreatedAt})
+		if len(result) == dashboardBoardLimit {
+			break
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,407 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/utils"
+	"github.com/go-redis/redis/v8"
+	"gorm.io/gorm"
+)
+
+const (
+	// digestLockKey ダイジェストを送信するインスタンスを1つにするロック
+	digestLockKey = "digest:lock"
+	// digestLockTTL ロックを保持する上限。ロックを持ったインスタンスが停止しても、この時間が過ぎれば他のインスタンスが続きを送信する
+	digestLockTTL = 30 * time.Minute
+	// digestSentKey 日付ごとの送信済みのユーザーIDの集合。途中で停止した場合も、送信済みのユーザーには再送しない
+	digestSentKey = "digest:sent:%s"
+	// digestDoneKey 日付ごとの送信の完了の記録。完了した日は受信者を読み込み直さない
+	digestDoneKey = "digest:done:%s"
+	// digestKeyTTL 送信の記録を残す期間
+	digestKeyTTL = 48 * time.Hour
+	// digestBatchSize クラスの活動をまとめて集計する受信者の数
+	digestBatchSize = 200
+	// digestDateLayout 送信の記録とダイジェストに使う日付の形式
+	digestDateLayout = "2006-01-02"
+)
+
+// releaseDigestLockScript 自分が取得したロックのみ解放する。期限切れの後に他のインスタンスが取得したロックは残す
+var releaseDigestLockScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// DigestConfig 毎朝のダイジェストの設定
+type DigestConfig struct {
+	// Hour ダイジェストを送信し始める時刻 (サーバーのタイムゾーンの0〜23時)
+	Hour int
+	// Email メールでの受け取りを設定したユーザーにメールでも送信する
+	Email bool
+}
+
+// DigestService 前日のクラスの活動をまとめたダイジェストを毎朝送信するサービス
+type DigestService interface {
+	GetSetting(ctx context.Context, uid uint) (*dto.DigestSettingDTO, error)
+	UpdateSetting(ctx context.Context, uid uint, setting dto.DigestSettingDTO) (*dto.DigestSettingDTO, error)
+	RunDailyDigest(ctx context.Context, now time.Time) (int, error)
+	DryRun(ctx context.Context, uid uint, now time.Time) (*dto.DigestDryRunDTO, error)
+}
+
+// digestService インタフェースを実装
+type digestService struct {
+	repo          repositories.DigestRepository
+	notifications NotificationService
+	// mail ダイジェストをメールで送信する。nilの場合はメールを送信しない
+	mail        MailService
+	redisClient *redis.Client
+	config      DigestConfig
+}
+
+// NewDigestService DigestServiceを生成
+func NewDigestService(repo repositories.DigestRepository, notifications NotificationService, mail MailService, redisClient *redis.Client, config DigestConfig) DigestService {
+	return &digestService{
+		repo:          repo,
+		notifications: notifications,
+		mail:          mail,
+		redisClient:   redisClient,
+		config:        config,
+	}
+}
+
+// GetSetting ユーザーのダイジェストの設定を返す。保存していない場合は無効
+func (s *digestService) GetSetting(ctx context.Context, uid uint) (*dto.DigestSettingDTO, error) {
+	setting, err := s.findSetting(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	return &dto.DigestSettingDTO{Enabled: setting.Enabled, Email: setting.EmailEnabled}, nil
+}
+
+// UpdateSetting ユーザーのダイジェストの設定を保存する
+func (s *digestService) UpdateSetting(ctx context.Context, uid uint, setting dto.DigestSettingDTO) (*dto.DigestSettingDTO, error) {
+	err := s.repo.SaveDigestSetting(ctx, &models.DigestSetting{
+		UID:          uid,
+		Enabled:      setting.Enabled,
+		EmailEnabled: setting.Email,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &setting, nil
+}
+
+// RunDailyDigest 設定した時刻を過ぎていれば、ダイジェストを有効にしたユーザーに前日のクラスの活動のまとめを送信し、送信した件数を返す。
+// Redisのロックで同時に送信するインスタンスを1つにし、送信したユーザーは日付ごとに記録するため、
+// 途中で停止した場合や同じ日に何度呼び出した場合も同じユーザーに2回送信しない。集計する活動がないユーザーには送信しない
+func (s *digestService) RunDailyDigest(ctx context.Context, now time.Time) (int, error) {
+	if now.Hour() < s.config.Hour {
+		return 0, nil
+	}
+	today := startOfDigestDay(now)
+	date := today.Format(digestDateLayout)
+	doneKey := fmt.Sprintf(digestDoneKey, date)
+	if done, err := s.redisClient.Exists(ctx, doneKey).Result(); err != nil || done > 0 {
+		return 0, err
+	}
+
+	token, err := generateDigestLockToken()
+	if err != nil {
+		return 0, err
+	}
+	locked, err := s.redisClient.SetNX(ctx, digestLockKey, token, digestLockTTL).Result()
+	if err != nil || !locked {
+		return 0, err
+	}
+	defer releaseDigestLockScript.Run(context.Background(), s.redisClient, []string{digestLockKey}, token)
+
+	sentKey := fmt.Sprintf(digestSentKey, date)
+	sent, failed := 0, false
+	for afterUID := uint(0); ; {
+		recipients, err := s.repo.FindDigestRecipients(ctx, afterUID, digestBatchSize)
+		if err != nil {
+			return sent, err
+		}
+		if len(recipients) == 0 {
+			break
+		}
+		afterUID = recipients[len(recipients)-1].UID
+
+		pending, err := s.unsentRecipients(ctx, sentKey, recipients)
+		if err != nil {
+			return sent, err
+		}
+		digests, err := s.buildDigests(ctx, pending, today)
+		if err != nil {
+			return sent, err
+		}
+		for _, recipient := range pending {
+			digest := digests[recipient.UID]
+			if len(digest.Classes) == 0 {
+				continue
+			}
+			delivered, err := s.deliver(ctx, sentKey, recipient, digest)
+			if err != nil {
+				failed = true
+				utils.ReportBackgroundError("daily_digest", fmt.Errorf("failed to send digest to uid %d: %w", recipient.UID, err))
+				continue
+			}
+			if delivered {
+				sent++
+			}
+		}
+	}
+
+	// 送信できなかったユーザーがいる日は、次の実行で送信し直す
+	if !failed {
+		if err := s.redisClient.Set(ctx, doneKey, 1, digestKeyTTL).Err(); err != nil {
+			return sent, err
+		}
+	}
+	return sent, nil
+}
+
+// DryRun ユーザーの今日のダイジェストを集計して返す。通知とメールは送信せず、送信の記録も変更しない。
+// ダイジェストを有効にしていないユーザーも集計する
+func (s *digestService) DryRun(ctx context.Context, uid uint, now time.Time) (*dto.DigestDryRunDTO, error) {
+	setting, err := s.findSetting(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	today := startOfDigestDay(now)
+	digests, err := s.buildDigests(ctx, []models.DigestSetting{*setting}, today)
+	if err != nil {
+		return nil, err
+	}
+
+	digest := digests[uid]
+	result := &dto.DigestDryRunDTO{
+		Digest:  digest,
+		Enabled: setting.Enabled,
+		Email:   s.sendsEmail(*setting),
+		Empty:   len(digest.Classes) == 0,
+	}
+	if s.redisClient != nil {
+		result.AlreadySent, err = s.redisClient.SIsMember(ctx, fmt.Sprintf(digestSentKey, today.Format(digestDateLayout)), uid).Result()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// findSetting ユーザーのダイジェストの設定を取得する。保存していない場合は無効の設定を返す
+func (s *digestService) findSetting(ctx context.Context, uid uint) (*models.DigestSetting, error) {
+	setting, err := s.repo.FindDigestSetting(ctx, uid)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &models.DigestSetting{UID: uid}, nil
+	}
+	return setting, err
+}
+
+// unsentRecipients 今日のダイジェストを送信していない受信者を返す
+func (s *digestService) unsentRecipients(ctx context.Context, sentKey string, recipients []models.DigestSetting) ([]models.DigestSetting, error) {
+	pipe := s.redisClient.Pipeline()
+	results := make([]*redis.BoolCmd, 0, len(recipients))
+	for _, recipient := range recipients {
+		results = append(results, pipe.SIsMember(ctx, sentKey, recipient.UID))
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	pending := make([]models.DigestSetting, 0, len(recipients))
+	for i, recipient := range recipients {
+		if !results[i].Val() {
+			pending = append(pending, recipient)
+		}
+	}
+	return pending, nil
+}
+
+// buildDigests 受信者のダイジェストを集計する。掲示・スケジュールは受信者ごとではなく、受信者の参加するクラスをまとめて集計し、
+// 未読のチャットは受信者の未読件数のハッシュをまとめて読み込む
+func (s *digestService) buildDigests(ctx context.Context, recipients []models.DigestSetting, today time.Time) (map[uint]dto.DigestDTO, error) {
+	uids := make([]uint, 0, len(recipients))
+	for _, recipient := range recipients {
+		uids = append(uids, recipient.UID)
+	}
+	memberships, err := s.repo.FindDigestMemberships(ctx, uids)
+	if err != nil {
+		return nil, err
+	}
+	cids := make([]uint, 0, len(memberships))
+	seen := make(map[uint]bool, len(memberships))
+	for _, membership := range memberships {
+		if !seen[membership.CID] {
+			seen[membership.CID] = true
+			cids = append(cids, membership.CID)
+		}
+	}
+
+	yesterday, tomorrow := today.AddDate(0, 0, -1), today.AddDate(0, 0, 1)
+	boards, err := s.repo.CountBoardsCreatedBetween(ctx, cids, yesterday, today)
+	if err != nil {
+		return nil, err
+	}
+	changes, err := s.repo.CountSchedulesChangedBetween(ctx, cids, yesterday, today)
+	if err != nil {
+		return nil, err
+	}
+	schedules, err := s.repo.FindSchedulesStartingBetween(ctx, cids, today, tomorrow)
+	if err != nil {
+		return nil, err
+	}
+	upcoming := make(map[uint][]dto.DigestScheduleDTO)
+	for _, schedule := range schedules {
+		upcoming[schedule.CID] = append(upcoming[schedule.CID], dto.DigestScheduleDTO{
+			ID:        schedule.ID,
+			Title:     schedule.Title,
+			StartedAt: schedule.StartedAt,
+			EndedAt:   schedule.EndedAt,
+			Location:  schedule.Location,
+		})
+	}
+	unread, err := s.unreadCounts(ctx, uids)
+	if err != nil {
+		return nil, err
+	}
+
+	digests := make(map[uint]dto.DigestDTO, len(uids))
+	for _, uid := range uids {
+		digests[uid] = dto.DigestDTO{UID: uid, Date: yesterday.Format(digestDateLayout), Classes: []dto.ClassDigestDTO{}}
+	}
+	for _, membership := range memberships {
+		class := dto.ClassDigestDTO{
+			CID:             membership.CID,
+			Name:            membership.ClassName,
+			NewBoards:       boards[membership.CID],
+			ScheduleChanges: changes[membership.CID],
+			UnreadChat:      unreadCount(unread[membership.UID], membership.CID, UnreadChat),
+			TodaySchedules:  upcoming[membership.CID],
+		}
+		if class.NewBoards == 0 && class.ScheduleChanges == 0 && class.UnreadChat == 0 && len(class.TodaySchedules) == 0 {
+			continue
+		}
+		if class.TodaySchedules == nil {
+			class.TodaySchedules = []dto.DigestScheduleDTO{}
+		}
+		digest := digests[membership.UID]
+		digest.Classes = append(digest.Classes, class)
+		digests[membership.UID] = digest
+	}
+	for uid, digest := range digests {
+		digest.Title, digest.Body = digestMessage(digest, yesterday)
+		digests[uid] = digest
+	}
+	return digests, nil
+}
+
+// unreadCounts ユーザーごとの未読件数のハッシュをまとめて読み込む。Redisがない場合は空にする
+func (s *digestService) unreadCounts(ctx context.Context, uids []uint) (map[uint]map[string]string, error) {
+	counts := make(map[uint]map[string]string, len(uids))
+	if s.redisClient == nil || len(uids) == 0 {
+		return counts, nil
+	}
+	pipe := s.redisClient.Pipeline()
+	results := make([]*redis.StringStringMapCmd, 0, len(uids))
+	for _, uid := range uids {
+		results = append(results, pipe.HGetAll(ctx, fmt.Sprintf(unreadKey, uid)))
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+	for i, uid := range uids {
+		counts[uid] = results[i].Val()
+	}
+	return counts, nil
+}
+
+// deliver 送信済みとして記録してから、ダイジェストをアプリ内通知とメールで送信する。記録済みの場合は送信せずにfalseを返す。
+// 送信の前に記録するため、送信の途中で停止しても同じ日に2回届くことはない。通知を保存できなかった場合は記録を取り消す
+func (s *digestService) deliver(ctx context.Context, sentKey string, recipient models.DigestSetting, digest dto.DigestDTO) (bool, error) {
+	claimed, err := s.redisClient.SAdd(ctx, sentKey, recipient.UID).Result()
+	if err != nil || claimed == 0 {
+		return false, err
+	}
+	s.redisClient.Expire(ctx, sentKey, digestKeyTTL)
+
+	err = s.notifications.Publish(ctx, models.Notification{
+		UID:          recipient.UID,
+		Type:         models.DigestNotification,
+		Title:        digest.Title,
+		Body:         digest.Body,
+		ResourceType: "digest",
+	})
+	if err != nil {
+		s.redisClient.SRem(ctx, sentKey, recipient.UID)
+		return false, err
+	}
+
+	// 通知は届いているため、メールを予約できなくても送信済みとする
+	if s.sendsEmail(recipient) {
+		err := s.mail.Send(ctx, MailRequest{
+			To:       recipient.User.Email,
+			Locale:   recipient.User.Locale,
+			Template: DailyDigestMail,
+			Data:     MailData{Name: recipient.User.Name, Date: digest.Date, Classes: digest.Classes},
+		})
+		if err != nil {
+			utils.ReportBackgroundError("daily_digest", fmt.Errorf("failed to queue digest mail to uid %d: %w", recipient.UID, err))
+		}
+	}
+	return true, nil
+}
+
+// sendsEmail ダイジェストをメールでも送信するか。サーバーの設定とユーザーの設定が有効で、検証済みのメールアドレスがある場合のみ送信する
+func (s *digestService) sendsEmail(setting models.DigestSetting) bool {
+	return s.config.Email && s.mail != nil && setting.EmailEnabled && setting.User.Email != "" && setting.User.EmailVerified
+}
+
+// digestMessage ダイジェストのアプリ内通知のタイトルと本文を作成する。本文はクラスごとに1行にする
+func digestMessage(digest dto.DigestDTO, date time.Time) (string, string) {
+	title := fmt.Sprintf("%d/%dのクラスのまとめ", date.Month(), date.Day())
+	lines := make([]string, 0, len(digest.Classes))
+	for _, class := range digest.Classes {
+		var items []string
+		if class.NewBoards > 0 {
+			items = append(items, fmt.Sprintf("新しい掲示 %d件", class.NewBoards))
+		}
+		if class.ScheduleChanges > 0 {
+			items = append(items, fmt.Sprintf("スケジュールの変更 %d件", class.ScheduleChanges))
+		}
+		if class.UnreadChat > 0 {
+			items = append(items, fmt.Sprintf("未読のチャット %d件", class.UnreadChat))
+		}
+		for _, schedule := range class.TodaySchedules {
+			items = append(items, fmt.Sprintf("今日の授業 %s「%s」", schedule.StartedAt.In(date.Location()).Format("15:04"), schedule.Title))
+		}
+		lines = append(lines, class.Name+": "+strings.Join(items, "、"))
+	}
+	return title, strings.Join(lines, "\n")
+}
+
+// startOfDigestDay 日時の日の0時を返す
+func startOfDigestDay(now time.Time) time.Time {
+	year, month, day := now.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, now.Location())
+}
+
+// generateDigestLockToken ロックを取得したインスタンスを区別するランダムな値を生成する
+func generateDigestLockToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
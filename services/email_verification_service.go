@@ -0,0 +1,241 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	// EmailVerificationTTL 検証メールのリンクの有効期限
+	EmailVerificationTTL = 24 * time.Hour
+	// EmailVerificationCooldown 検証メールを再送できるまでの間隔
+	EmailVerificationCooldown = time.Minute
+	// EmailVerificationDailyLimit 1人のユーザーが1日に送信できる検証メールの件数
+	EmailVerificationDailyLimit = 5
+
+	// emailVerificationKey トークンのハッシュから検証するユーザーとアドレスを引くキー
+	emailVerificationKey = "email_verification:%s"
+	// emailVerificationUserKey ユーザーの最新のトークンのハッシュのキー。再送した場合は以前のトークンを無効にする
+	emailVerificationUserKey     = "email_verification_user:%d"
+	emailVerificationCooldownKey = "email_verification_cooldown:%d"
+	emailVerificationCountKey    = "email_verification_count:%d"
+	emailVerificationCountWindow = 24 * time.Hour
+)
+
+// EmailVerificationService ユーザーのメールアドレスを検証メールで確認するサービス
+type EmailVerificationService interface {
+	GetEmailStatus(ctx context.Context, uid uint) (dto.EmailStatusDTO, error)
+	SendVerification(ctx context.Context, uid uint, request dto.EmailVerificationRequestDTO) error
+	VerifyEmail(ctx context.Context, token string) (dto.EmailStatusDTO, error)
+}
+
+// emailVerification Redisに保存する検証中のアドレス
+type emailVerification struct {
+	UID   uint   `json:"uid"`
+	Email string `json:"email"`
+}
+
+// emailVerificationService インタフェースを実装
+type emailVerificationService struct {
+	userRepo repositories.UserRepository
+	mail     MailService
+	// redisClient トークンと送信数の記録に使う。nilの場合は検証メールを送信できない
+	redisClient *redis.Client
+	// verifyURL 検証メールに載せる確認ページのURL。空の場合はトークンのみ載せる
+	verifyURL string
+}
+
+// NewEmailVerificationService EmailVerificationServiceを生成
+func NewEmailVerificationService(userRepo repositories.UserRepository, mail MailService, redisClient *redis.Client, verifyURL string) EmailVerificationService {
+	return &emailVerificationService{
+		userRepo:    userRepo,
+		mail:        mail,
+		redisClient: redisClient,
+		verifyURL:   verifyURL,
+	}
+}
+
+// GetEmailStatus ユーザーのメールアドレスと検証の状態を返す
+func (s *emailVerificationService) GetEmailStatus(ctx context.Context, uid uint) (dto.EmailStatusDTO, error) {
+	user, err := s.userRepo.FindByID(ctx, uid)
+	if err != nil {
+		return dto.EmailStatusDTO{}, err
+	}
+	return dto.EmailStatusDTO{Email: user.Email, Verified: user.EmailVerified}, nil
+}
+
+// SendVerification 検証メールを送信する。アドレスを指定しない場合は登録済みのアドレスに送信する。
+// 他のユーザーが検証済みのアドレスの場合はErrEmailTakenを、前回の送信から1分以内の場合と1日の送信数が上限に達している場合はErrEmailVerificationLimitを返す
+func (s *emailVerificationService) SendVerification(ctx context.Context, uid uint, request dto.EmailVerificationRequestDTO) error {
+	if s.redisClient == nil || s.mail == nil {
+		return ErrEmailVerificationUnavailable
+	}
+	user, err := s.userRepo.FindByID(ctx, uid)
+	if err != nil {
+		return err
+	}
+	email := strings.TrimSpace(request.Email)
+	if email == "" {
+		email = user.Email
+	}
+	if email == "" {
+		return ErrEmailNotSet
+	}
+	if user.EmailVerified && strings.EqualFold(email, user.Email) {
+		return nil
+	}
+	taken, err := s.userRepo.IsEmailVerifiedByOther(ctx, uid, email)
+	if err != nil {
+		return err
+	}
+	if taken {
+		return ErrEmailTaken
+	}
+
+	if err := s.reserveVerification(ctx, uid); err != nil {
+		return err
+	}
+	token, err := generateEmailVerificationToken()
+	if err != nil {
+		return err
+	}
+	if err := s.saveVerification(ctx, token, emailVerification{UID: uid, Email: email}); err != nil {
+		return err
+	}
+	verifyURL, err := emailVerificationURL(s.verifyURL, token)
+	if err != nil {
+		return err
+	}
+	return s.mail.Send(ctx, MailRequest{
+		To:       email,
+		Locale:   user.Locale,
+		Template: EmailVerificationMail,
+		Data:     MailData{Name: user.Name, VerifyURL: verifyURL, VerifyToken: token},
+	})
+}
+
+// reserveVerification 再送の間隔と1日の送信数を確認し、送信を記録する
+func (s *emailVerificationService) reserveVerification(ctx context.Context, uid uint) error {
+	reserved, err := s.redisClient.SetNX(ctx, fmt.Sprintf(emailVerificationCooldownKey, uid), 1, EmailVerificationCooldown).Result()
+	if err != nil {
+		return err
+	}
+	if !reserved {
+		return ErrEmailVerificationLimit
+	}
+	countKey := fmt.Sprintf(emailVerificationCountKey, uid)
+	count, err := s.redisClient.Incr(ctx, countKey).Result()
+	if err != nil {
+		return err
+	}
+	if count == 1 {
+		s.redisClient.Expire(ctx, countKey, emailVerificationCountWindow)
+	}
+	if count > EmailVerificationDailyLimit {
+		return ErrEmailVerificationLimit
+	}
+	return nil
+}
+
+// saveVerification トークンのハッシュを保存し、ユーザーの以前のトークンを無効にする
+func (s *emailVerificationService) saveVerification(ctx context.Context, token string, verification emailVerification) error {
+	data, err := json.Marshal(verification)
+	if err != nil {
+		return err
+	}
+	userKey := fmt.Sprintf(emailVerificationUserKey, verification.UID)
+	previous, err := s.redisClient.Get(ctx, userKey).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return err
+	}
+	hash := hashEmailVerificationToken(token)
+	_, err = s.redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		if previous != "" {
+			pipe.Del(ctx, fmt.Sprintf(emailVerificationKey, previous))
+		}
+		pipe.Set(ctx, fmt.Sprintf(emailVerificationKey, hash), data, EmailVerificationTTL)
+		pipe.Set(ctx, userKey, hash, EmailVerificationTTL)
+		return nil
+	})
+	return err
+}
+
+// VerifyEmail トークンを確認し、ユーザーのメールアドレスを検証済みのアドレスに変更する。トークンは検証に成功した後に削除し、1回のみ使える。
+// トークンが存在しないか有効期限を過ぎている場合はErrInvalidEmailTokenを、他のユーザーが先に同じアドレスを検証した場合はErrEmailTakenを返す。
+// 保存に失敗した場合はトークンを残し、同じリンクで検証し直せるようにする
+func (s *emailVerificationService) VerifyEmail(ctx context.Context, token string) (dto.EmailStatusDTO, error) {
+	if s.redisClient == nil {
+		return dto.EmailStatusDTO{}, ErrEmailVerificationUnavailable
+	}
+	hash := hashEmailVerificationToken(token)
+	key := fmt.Sprintf(emailVerificationKey, hash)
+	data, err := s.redisClient.Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return dto.EmailStatusDTO{}, ErrInvalidEmailToken
+	}
+	if err != nil {
+		return dto.EmailStatusDTO{}, err
+	}
+	var verification emailVerification
+	if err := json.Unmarshal(data, &verification); err != nil {
+		return dto.EmailStatusDTO{}, ErrInvalidEmailToken
+	}
+
+	verified, err := s.userRepo.MarkEmailVerified(ctx, verification.UID, verification.Email)
+	if err != nil {
+		return dto.EmailStatusDTO{}, err
+	}
+	if !verified {
+		return dto.EmailStatusDTO{}, ErrEmailTaken
+	}
+	userKey := fmt.Sprintf(emailVerificationUserKey, verification.UID)
+	if err := s.redisClient.Del(ctx, key).Err(); err != nil {
+		return dto.EmailStatusDTO{}, err
+	}
+	if current, err := s.redisClient.Get(ctx, userKey).Result(); err == nil && current == hash {
+		s.redisClient.Del(ctx, userKey)
+	}
+	return dto.EmailStatusDTO{Email: verification.Email, Verified: true}, nil
+}
+
+// emailVerificationURL 確認ページのURLにトークンを付ける。確認ページのURLが空の場合は空を返す
+func emailVerificationURL(verifyURL string, token string) (string, error) {
+	if verifyURL == "" {
+		return "", nil
+	}
+	u, err := url.Parse(verifyURL)
+	if err != nil {
+		return "", err
+	}
+	query := u.Query()
+	query.Set("token", token)
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+// generateEmailVerificationToken 推測できない検証のトークンを生成する
+func generateEmailVerificationToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// hashEmailVerificationToken Redisに保存するトークンのハッシュ。Redisの内容からリンクを復元できないようにする
+func hashEmailVerificationToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
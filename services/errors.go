@@ -1,9 +1,141 @@
 package services
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+)
 
 var (
-	ErrNotFound     = errors.New("not found")
-	ErrUnauthorized = errors.New("unauthorized")
-	ErrDatabase     = errors.New("database error")
+	ErrNotFound       = errors.New("not found")
+	ErrUnauthorized   = errors.New("unauthorized")
+	ErrDatabase       = errors.New("database error")
+	ErrPastSchedule   = errors.New("cannot delete a schedule that has already started")
+	ErrRoomNotFound   = errors.New("chat room not found")
+	ErrScheduleTooOld = errors.New("schedule is too old to reset attendances")
+	ErrNotEnrolled    = errors.New("user is not enrolled in the class")
+	// ErrActiveClassLimit ユーザーが参加できるアクティブなクラス数の上限に達している
+	ErrActiveClassLimit = errors.New("active class limit reached")
+	// ErrRealtimeTopicLimit 1つのWebSocketの接続で購読できるクラスの上限に達している
+	ErrRealtimeTopicLimit = errors.New("realtime topic limit reached")
+	// ErrClassCodeUsed 同じクラスコードで参加した後に退出・削除されたユーザーによるコードの再使用
+	ErrClassCodeUsed = errors.New("class code has already been used by the user")
+	// ErrChannelUnavailable サーバーに通知チャネルの送信設定がない
+	ErrChannelUnavailable = errors.New("notification channel is not available")
+	// ErrInvalidGradeScale 成績の基準に同じ成績や出席率の下限が重複しているか、出席率0%の基準がない
+	ErrInvalidGradeScale = errors.New("invalid grade scale")
+	// ErrInvalidAttendanceMode スケジュールの出席方式が定義済みの値ではない
+	ErrInvalidAttendanceMode = errors.New("invalid attendance mode")
+	// ErrDuplicateBoardLanguage グループ掲示板の言語の版に同じ言語が複数含まれている
+	ErrDuplicateBoardLanguage = errors.New("duplicate class board language")
+	// ErrAccessRestricted クラスのアクセス制限で許可していない時間帯または接続元からのアクセス
+	ErrAccessRestricted = errors.New("class access is restricted")
+	// ErrInvalidAccessRestriction アクセス制限の時間帯またはIPレンジの形式が正しくない
+	ErrInvalidAccessRestriction = errors.New("invalid access restriction")
+	// ErrNestedReply リプライへのリプライ。コメントのスレッドは1階層までとする
+	ErrNestedReply = errors.New("replies cannot be nested")
+	// ErrSurveyNotOpen 授業が終了していないか休講のスケジュールの振り返りアンケートへの回答
+	ErrSurveyNotOpen = errors.New("survey is not open")
+	// ErrTranslationUnavailable サーバーに翻訳APIの設定がない
+	ErrTranslationUnavailable = errors.New("translation is not available")
+	// ErrNotTranslatable スタンプなど翻訳する本文がないメッセージ
+	ErrNotTranslatable = errors.New("message has no text to translate")
+	// ErrTranslationRateLimit ユーザーが1分間に翻訳APIで翻訳できるメッセージ数の上限に達している
+	ErrTranslationRateLimit = errors.New("translation rate limit reached")
+	// ErrTranslationFailed 翻訳APIがエラーを返したか、応答しなかった
+	ErrTranslationFailed = errors.New("translation api failed")
+	// ErrStaleUpdate 読み込んだ後に他の更新が保存されたレコードの更新
+	ErrStaleUpdate = errors.New("stale update")
+	// ErrVersionRequired 後勝ちの更新を受け付けない設定で、楽観ロックのバージョンを指定しない更新
+	ErrVersionRequired = errors.New("version is required to update the record")
+	// ErrInvalidCheckInCode 自己チェックインの確認コードが一致しないか、入力ミスの回数が上限に達している
+	ErrInvalidCheckInCode = errors.New("invalid check-in code")
+	// ErrUnknownMailTemplate テンプレートが定義されていない種類のメール
+	ErrUnknownMailTemplate = errors.New("unknown mail template")
+	// ErrMailQueueFull メールの送信キューが一杯で、送信を予約できない
+	ErrMailQueueFull = errors.New("mail queue is full")
+	// ErrInvalidWebhookURL Webhookの送信先のURLがhttpまたはhttpsではない
+	ErrInvalidWebhookURL = errors.New("webhook url must use http or https")
+	// ErrInvalidIntegrationURL 連携先のURLが連携先のサービスが発行したWebhookのURLではない
+	ErrInvalidIntegrationURL = errors.New("integration url is not a webhook url of the provider")
+	// ErrIntegrationTestFailed 連携を保存する前のテストメッセージの投稿に失敗した
+	ErrIntegrationTestFailed = errors.New("failed to post test message to integration")
+	// ErrCalendarSyncUnavailable サーバーでGoogleカレンダーとの同期が有効になっていない
+	ErrCalendarSyncUnavailable = errors.New("google calendar sync is not available")
+	// ErrCalendarNotConnected ユーザーがGoogleのログインでカレンダーの権限を許可していないか、トークンの更新に失敗して同期が停止している
+	ErrCalendarNotConnected = errors.New("google calendar is not connected")
+	// ErrCalendarClassNotSynced ユーザーが同期するクラスを限定していて、クラスが含まれていない
+	ErrCalendarClassNotSynced = errors.New("class is not synced to google calendar")
+	// ErrEventsUnavailable サーバーで内部イベントの配信が有効になっていない
+	ErrEventsUnavailable = errors.New("internal events are not available")
+	// ErrInvalidEventCursor 再取得するイベントの位置がストリームのIDの形式ではない
+	ErrInvalidEventCursor = errors.New("invalid event cursor")
+	// ErrInvalidCheckInToken 出席トークンの形式が正しくないか、署名が一致しない
+	ErrInvalidCheckInToken = errors.New("invalid check-in token")
+	// ErrCheckInTokenExpired 出席トークンの有効期限を過ぎている
+	ErrCheckInTokenExpired = errors.New("check-in token expired")
+	// ErrCheckInTokenUnavailable サーバーに出席トークンの署名の鍵が設定されていない
+	ErrCheckInTokenUnavailable = errors.New("check-in token is not available")
+	// ErrScheduleCancelled 休講になったスケジュールにはチェックインできない
+	ErrScheduleCancelled = errors.New("schedule is cancelled")
+	// ErrCheckInMethodNotAllowed スケジュールの出席方式がチェックインの手段を受け付けない
+	ErrCheckInMethodNotAllowed = errors.New("check-in method is not allowed for the attendance mode")
+	// ErrEmailNotSet 検証するメールアドレスが登録も指定もされていない
+	ErrEmailNotSet = errors.New("email is not set")
+	// ErrEmailNotVerified メールアドレスを検証していないため、メールの通知を登録できない
+	ErrEmailNotVerified = errors.New("email is not verified")
+	// ErrEmailVerificationLimit 検証メールの再送の間隔が短いか、1日の送信数の上限に達している
+	ErrEmailVerificationLimit = errors.New("email verification limit reached")
+	// ErrEmailTaken 他のユーザーが同じメールアドレスを検証済み
+	ErrEmailTaken = errors.New("email is verified by another user")
+	// ErrInvalidEmailToken 検証のトークンが存在しないか、有効期限を過ぎている
+	ErrInvalidEmailToken = errors.New("invalid email verification token")
+	// ErrEmailVerificationUnavailable サーバーにRedisまたはメールの送信設定がない
+	ErrEmailVerificationUnavailable = errors.New("email verification is not available")
+	// ErrInvitationLimit クラスから1日に送信できる招待メールの上限に達している
+	ErrInvitationLimit = errors.New("class invitation limit reached")
+	// ErrReminderCooldown 同じスケジュールの未読のメンバーへの再通知の間隔が短すぎる
+	ErrReminderCooldown = errors.New("schedule reminder cooldown")
+	// ErrPreviewSecretLimit クラスのプレビューでシークレットを間違えた回数が上限に達している
+	ErrPreviewSecretLimit = errors.New("class preview secret attempts exceeded")
 )
+
+// NotEnrolledError ユーザーが参加していないクラスのIDを持つエラー。errors.IsでErrNotEnrolledと判定できる
+type NotEnrolledError struct {
+	ClassIDs []uint
+}
+
+func (e *NotEnrolledError) Error() string {
+	return fmt.Sprintf("%s: %v", ErrNotEnrolled, e.ClassIDs)
+}
+
+func (e *NotEnrolledError) Is(target error) bool {
+	return target == ErrNotEnrolled
+}
+
+// StaleUpdateError 楽観ロックで他の更新と競合したエラー。Currentに最新のレコードを持ち、errors.IsでErrStaleUpdateと判定できる
+type StaleUpdateError struct {
+	Current interface{}
+}
+
+func (e *StaleUpdateError) Error() string {
+	return ErrStaleUpdate.Error()
+}
+
+func (e *StaleUpdateError) Is(target error) bool {
+	return target == ErrStaleUpdate
+}
+
+// staleUpdate リポジトリの楽観ロックの競合を、reloadで読み直した最新のレコードを持つStaleUpdateErrorに変換する。
+// 競合以外のエラーはそのまま返す
+func staleUpdate(err error, reload func() (interface{}, error)) error {
+	if !errors.Is(err, repositories.ErrStaleUpdate) {
+		return err
+	}
+	current, reloadErr := reload()
+	if reloadErr != nil {
+		return reloadErr
+	}
+	return &StaleUpdateError{Current: current}
+}
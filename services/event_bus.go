@@ -0,0 +1,227 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"regexp"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/utils"
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	// EventChannelPrefix 内部イベントを配信するRedisのチャンネルの接頭辞。チャンネル名は接頭辞とイベント名をつなげたもの
+	EventChannelPrefix = "minori:events:"
+	// EventStreamKey 再取得のために内部イベントを残すRedisのストリーム
+	EventStreamKey = "minori:events"
+
+	// defaultEventStreamMaxLen 設定がない場合にストリームに残すイベントのおおよその上限
+	defaultEventStreamMaxLen = 10000
+	// eventPublishTimeout 1件のイベントの配信を待つ上限
+	eventPublishTimeout = 3 * time.Second
+	// defaultEventReplayLimit 再取得で件数を指定しない場合に返すイベントの数
+	defaultEventReplayLimit = 100
+	// MaxEventReplayLimit 1回の再取得で返すイベントの上限
+	MaxEventReplayLimit = 1000
+	// eventEnvelopeField ストリームのエントリでエンベロープを保存するフィールド
+	eventEnvelopeField = "envelope"
+)
+
+// eventIDPattern ストリームのIDの形式。ミリ秒のみの指定も受け付ける
+var eventIDPattern = regexp.MustCompile(`^\d+(-\d+)?$`)
+
+// EventBusConfig 内部イベントの配信の設定
+type EventBusConfig struct {
+	Enabled bool
+	// StreamMaxLen ストリームに残すイベントのおおよその上限。0以下の場合は既定値
+	StreamMaxLen int64
+}
+
+// EventPublisher 他のサービスに内部イベントを配信する。配信に失敗しても呼び出し元の処理は失敗させない
+type EventPublisher interface {
+	PublishEvent(ctx context.Context, event string, payload interface{})
+}
+
+// EventBus 内部イベントの配信と、取りこぼしたイベントの再取得を行う
+type EventBus interface {
+	EventPublisher
+	Replay(ctx context.Context, after string, limit int) (*dto.EventReplayDTO, error)
+}
+
+// eventBus EventBusを実装
+type eventBus struct {
+	redisClient *redis.Client
+	config      EventBusConfig
+}
+
+// NewEventBus EventBusを生成
+func NewEventBus(redisClient *redis.Client, config EventBusConfig) EventBus {
+	if config.StreamMaxLen <= 0 {
+		config.StreamMaxLen = defaultEventStreamMaxLen
+	}
+	return &eventBus{redisClient: redisClient, config: config}
+}
+
+// NewEventEnvelope ペイロードを現在のバージョンのエンベロープに包む。IDはストリームに追加したときに決まる
+func NewEventEnvelope(ctx context.Context, event string, payload interface{}) (dto.EventEnvelope, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return dto.EventEnvelope{}, err
+	}
+	return dto.EventEnvelope{
+		Event:      event,
+		Version:    dto.EventEnvelopeVersion,
+		OccurredAt: time.Now().UTC(),
+		RequestID:  utils.RequestIDFromContext(ctx),
+		Payload:    data,
+	}, nil
+}
+
+// DecodeEventPayload エンベロープのペイロードをvに読み込む
+func DecodeEventPayload(envelope dto.EventEnvelope, v interface{}) error {
+	return json.Unmarshal(envelope.Payload, v)
+}
+
+// PublishEvent イベントをストリームに追加してからチャンネルに配信する。
+// リクエストの終了で配信が中断されないよう、ctxからはリクエストIDのみを使う
+func (b *eventBus) PublishEvent(ctx context.Context, event string, payload interface{}) {
+	if !b.config.Enabled {
+		return
+	}
+	envelope, err := NewEventEnvelope(ctx, event, payload)
+	if err != nil {
+		log.Printf("failed to encode %s event: %v", event, err)
+		return
+	}
+
+	publishCtx, cancel := context.WithTimeout(context.Background(), eventPublishTimeout)
+	defer cancel()
+	data, err := json.Marshal(envelope)
+	if err != nil {
+		log.Printf("failed to encode %s event: %v", event, err)
+		return
+	}
+	id, err := b.redisClient.XAdd(publishCtx, &redis.XAddArgs{
+		Stream: EventStreamKey,
+		MaxLen: b.config.StreamMaxLen,
+		Approx: true,
+		Values: map[string]interface{}{eventEnvelopeField: data},
+	}).Result()
+	if err != nil {
+		log.Printf("failed to append %s event to stream: %v", event, err)
+		return
+	}
+
+	envelope.ID = id
+	if data, err = json.Marshal(envelope); err != nil {
+		log.Printf("failed to encode %s event: %v", event, err)
+		return
+	}
+	if err := b.redisClient.Publish(publishCtx, EventChannelPrefix+event, data).Err(); err != nil {
+		log.Printf("failed to publish %s event %s: %v", event, id, err)
+	}
+}
+
+// Replay afterより後にストリームに追加されたイベントを古い順に返す。afterが空の場合はストリームの先頭から返す
+func (b *eventBus) Replay(ctx context.Context, after string, limit int) (*dto.EventReplayDTO, error) {
+	if !b.config.Enabled {
+		return nil, ErrEventsUnavailable
+	}
+	if after == "" {
+		after = "0"
+	}
+	if !eventIDPattern.MatchString(after) {
+		return nil, ErrInvalidEventCursor
+	}
+	if limit <= 0 {
+		limit = defaultEventReplayLimit
+	}
+	if limit > MaxEventReplayLimit {
+		limit = MaxEventReplayLimit
+	}
+
+	result := &dto.EventReplayDTO{Events: []dto.EventEnvelope{}, Next: after}
+	// Blockに負の値を指定すると待たずに返す
+	streams, err := b.redisClient.XRead(ctx, &redis.XReadArgs{
+		Streams: []string{EventStreamKey, after},
+		Count:   int64(limit),
+		Block:   -1,
+	}).Result()
+	if errors.Is(err, redis.Nil) {
+		return result, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, stream := range streams {
+		for _, message := range stream.Messages {
+			result.Next = message.ID
+			data, ok := message.Values[eventEnvelopeField].(string)
+			if !ok {
+				continue
+			}
+			var envelope dto.EventEnvelope
+			if err := json.Unmarshal([]byte(data), &envelope); err != nil {
+				log.Printf("skipping malformed event %s: %v", message.ID, err)
+				continue
+			}
+			envelope.ID = message.ID
+			result.Events = append(result.Events, envelope)
+		}
+	}
+	return result, nil
+}
+
+// ConsumeEvents 内部イベントを購読し、受け取ったイベントを順にhandleに渡す。eventsが空の場合は全てのイベントを購読する。
+// 購読を開始できない場合はエラーを返し、それ以外はctxが終了するまで戻らない。
+// Pub/Subは購読していない間のイベントを届けないため、取りこぼしは再取得APIで補う
+func ConsumeEvents(ctx context.Context, redisClient *redis.Client, handle func(dto.EventEnvelope), events ...string) error {
+	var pubsub *redis.PubSub
+	if len(events) == 0 {
+		pubsub = redisClient.PSubscribe(ctx, EventChannelPrefix+"*")
+	} else {
+		channels := make([]string, 0, len(events))
+		for _, event := range events {
+			channels = append(channels, EventChannelPrefix+event)
+		}
+		pubsub = redisClient.Subscribe(ctx, channels...)
+	}
+	defer pubsub.Close()
+	if _, err := pubsub.Receive(ctx); err != nil {
+		return err
+	}
+
+	messages := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case message, ok := <-messages:
+			if !ok {
+				return nil
+			}
+			var envelope dto.EventEnvelope
+			if err := json.Unmarshal([]byte(message.Payload), &envelope); err != nil {
+				log.Printf("skipping malformed event on %s: %v", message.Channel, err)
+				continue
+			}
+			handle(envelope)
+		}
+	}
+}
+
+// publishEventAfterCommit トランザクションのコミット後にイベントを配信する。eventsがnilの場合は何もしない
+func publishEventAfterCommit(ctx context.Context, repos repositories.RepositorySet, events EventPublisher, event string, payload interface{}) {
+	if events == nil {
+		return
+	}
+	repos.RunAfterCommit(func() {
+		events.PublishEvent(ctx, event, payload)
+	})
+}
@@ -2,17 +2,21 @@ package services
 
 import (
 	"context"
+	"crypto/hmac"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
 	"fmt"
 	"io"
 	"net/http"
-	"os"
+	"strings"
 	"time"
 
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/config"
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/utils"
 	"github.com/dgrijalva/jwt-go"
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/google"
@@ -23,19 +27,28 @@ type JWTService interface {
 	GenerateRefreshToken(userID uint) (string, error)
 	ValidateToken(tokenString string) (*jwt.Token, error)
 	RefreshAccessToken(refreshToken string) (*jwt.Token, error)
+	GenerateCalendarToken(userID uint) (string, error)
+	ValidateCalendarToken(tokenString string) (uint, error)
 }
 
 type JWTServiceImpl struct {
-	secretKey []byte
+	secretKey   []byte
+	calendarKey []byte
 }
 
-func NewJWTService() *JWTServiceImpl {
-	secret := os.Getenv("JWT_SECRET")
+// calendarKeyLabel カレンダー購読用の署名鍵をJWTの秘密鍵から導出するときのラベル
+const calendarKeyLabel = "calendar-feed"
+
+func NewJWTService(secret string) *JWTServiceImpl {
 	if secret == "" {
 		panic("JWT secret is not set")
 	}
+	// カレンダー購読用のトークンはURLに含まれて外部に渡るため、アクセストークンとは別の鍵で署名する
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(calendarKeyLabel))
 	return &JWTServiceImpl{
-		secretKey: []byte(secret),
+		secretKey:   []byte(secret),
+		calendarKey: mac.Sum(nil),
 	}
 }
 
@@ -89,13 +102,48 @@ func (s *JWTServiceImpl) RefreshAccessToken(refreshToken string) (*jwt.Token, er
 	return nil, fmt.Errorf("invalid refresh token")
 }
 
+// GenerateCalendarToken カレンダー購読URL用のトークンを生成
+// カレンダーアプリはAuthorizationヘッダーを送れないため、URLに含める長期トークンを発行する。
+// アクセストークンとは別の鍵で署名するため、APIの認証には使えない
+func (s *JWTServiceImpl) GenerateCalendarToken(userID uint) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"id":   userID,
+		"exp":  time.Now().Add(365 * 24 * time.Hour).Unix(),
+		"type": "calendar",
+	})
+	return token.SignedString(s.calendarKey)
+}
+
+// ValidateCalendarToken カレンダー購読URL用のトークンを検証してユーザーIDを返す
+func (s *JWTServiceImpl) ValidateCalendarToken(tokenString string) (uint, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return s.calendarKey, nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid || claims["type"] != "calendar" {
+		return 0, fmt.Errorf("invalid calendar token")
+	}
+	id, ok := claims["id"].(float64)
+	if !ok {
+		return 0, fmt.Errorf("invalid calendar token")
+	}
+	return uint(id), nil
+}
+
 // GoogleAuthServiceはGoogle認証サービスのインターフェース
 type GoogleAuthService interface {
 	GenerateStateOauthCookie(w http.ResponseWriter) string
-	GetGoogleUserInfo(code string) ([]byte, error)
+	AuthCodeURL(state string, withCalendar bool) string
+	GetGoogleUserInfo(code string) ([]byte, *oauth2.Token, error)
 	OauthConfig() *oauth2.Config
-	UpdateOrCreateUser(userInput dto.UserInput) (models.User, error)
-	GetUserByID(userID uint) (models.User, error)
+	UpdateOrCreateUser(ctx context.Context, userInput dto.UserInput) (models.User, error)
+	GetUserByID(ctx context.Context, userID uint) (models.User, error)
 }
 
 // GoogleAuthServiceImplはGoogle認証サービスの実装
@@ -105,12 +153,12 @@ type GoogleAuthServiceImpl struct {
 	repo        repositories.GoogleAuthRepository
 }
 
-func (s *GoogleAuthServiceImpl) GetUserByID(id uint) (models.User, error) {
-	return s.repo.GetUserByID(id)
+func (s *GoogleAuthServiceImpl) GetUserByID(ctx context.Context, id uint) (models.User, error) {
+	return s.repo.GetUserByID(ctx, id)
 }
 
-func (s *GoogleAuthServiceImpl) UpdateOrCreateUser(userInput dto.UserInput) (models.User, error) {
-	return s.repo.UpdateOrCreateUser(userInput)
+func (s *GoogleAuthServiceImpl) UpdateOrCreateUser(ctx context.Context, userInput dto.UserInput) (models.User, error) {
+	return s.repo.UpdateOrCreateUser(ctx, userInput)
 }
 
 // OauthConfigはOAuth設定を返す
@@ -118,14 +166,26 @@ func (s *GoogleAuthServiceImpl) OauthConfig() *oauth2.Config {
 	return s.oauthConfig
 }
 
+// AuthCodeURLはGoogleのログインページのURLを返す。withCalendarの場合はカレンダーの予定を操作する権限も求め、
+// スケジュールの同期でアクセストークンを更新できるよう、同意画面を表示してリフレッシュトークンを取得する
+func (s *GoogleAuthServiceImpl) AuthCodeURL(state string, withCalendar bool) string {
+	if !withCalendar {
+		return s.oauthConfig.AuthCodeURL(state)
+	}
+	scopes := append(append([]string(nil), s.oauthConfig.Scopes...), utils.GoogleCalendarEventsScope)
+	return s.oauthConfig.AuthCodeURL(state, oauth2.AccessTypeOffline, oauth2.ApprovalForce,
+		oauth2.SetAuthURLParam("scope", strings.Join(scopes, " ")),
+		oauth2.SetAuthURLParam("include_granted_scopes", "true"))
+}
+
 // NewGoogleAuthServiceはGoogle認証サービスの新しいインスタンスを作成
-func NewGoogleAuthService(repo repositories.GoogleAuthRepository) GoogleAuthService {
+func NewGoogleAuthService(repo repositories.GoogleAuthRepository, cfg config.GoogleConfig) GoogleAuthService {
 	return &GoogleAuthServiceImpl{
 		oauthConfig: &oauth2.Config{
-			RedirectURL:  os.Getenv("GOOGLE_REDIRECT_URL"),
-			ClientID:     os.Getenv("GOOGLE_CLIENT_ID"),
-			ClientSecret: os.Getenv("GOOGLE_CLIENT_SECRET"),
-			Scopes:       []string{"https://www.googleapis.com/auth/userinfo.profile"},
+			RedirectURL:  cfg.RedirectURL,
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			Scopes:       []string{"https://www.googleapis.com/auth/userinfo.profile", "https://www.googleapis.com/auth/userinfo.email"},
 			Endpoint:     google.Endpoint,
 		},
 		UrlAPI: "https://www.googleapis.com/oauth2/v2/userinfo?access_token=",
@@ -145,23 +205,23 @@ func (s *GoogleAuthServiceImpl) GenerateStateOauthCookie(w http.ResponseWriter)
 	return state
 }
 
-// GetGoogleUserInfoはGoogleのユーザー情報を取得
-func (s *GoogleAuthServiceImpl) GetGoogleUserInfo(code string) ([]byte, error) {
+// GetGoogleUserInfoはGoogleのユーザー情報と、認可コードと交換したトークンを取得
+func (s *GoogleAuthServiceImpl) GetGoogleUserInfo(code string) ([]byte, *oauth2.Token, error) {
 	token, err := s.oauthConfig.Exchange(context.Background(), code)
 	if err != nil {
-		return nil, fmt.Errorf("Failed to Exchange %s\n", err.Error())
+		return nil, nil, fmt.Errorf("Failed to Exchange %s\n", err.Error())
 	}
 
 	resp, err := http.Get(s.UrlAPI + token.AccessToken)
 	if err != nil {
-		return nil, fmt.Errorf("Failed to Get UserInfo %s\n", err.Error())
+		return nil, nil, fmt.Errorf("Failed to Get UserInfo %s\n", err.Error())
 	}
 	defer resp.Body.Close()
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("Failed to read response body: %s\n", err.Error())
+		return nil, nil, fmt.Errorf("Failed to read response body: %s\n", err.Error())
 	}
 
-	return body, nil
+	return body, token, nil
 }
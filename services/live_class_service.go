@@ -5,29 +5,52 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
 	"github.com/go-redis/redis/v8"
 	"io/ioutil"
 	"log"
 	"net/http"
+	"sort"
+	"strconv"
+	"sync"
 	"time"
 )
 
+const (
+	// liveSpeakersKey ライブ授業で発言権を持つ生徒のuidの集合
+	liveSpeakersKey = "live_speakers:%d"
+	// liveSpeakersTTL 発言権を保持する期間。付与するたびに延長し、授業が終わった後に残らないようにする
+	liveSpeakersTTL = 2 * time.Hour
+)
+
 type LiveClassService interface {
 	GetScreenShareInfo(ctx context.Context, cid uint) (interface{}, error)
 	SaveScreenShareInfo(ctx context.Context, cid uint, info map[string]interface{}) error
-	StartStreamingSession(cid uint) (string, error)
+	StartStreamingSession(uid uint, cid uint) (string, error)
+	ReportQualityStats(ctx context.Context, uid uint, cid uint, report dto.ConnectionQualityReportDTO) error
+	GetRoomQualityStats(ctx context.Context, uid uint, cid uint) ([]dto.ViewerQualityStatsDTO, error)
+	ClearRoomQualityStats(cid uint)
+	GetSpeakPermissions(ctx context.Context, viewerUID uint, cid uint) (*dto.SpeakPermissionsDTO, error)
+	GrantSpeakPermission(ctx context.Context, actorUID uint, cid uint, uid uint) (*dto.SpeakPermissionsDTO, error)
+	RevokeSpeakPermission(ctx context.Context, actorUID uint, cid uint, uid uint) (*dto.SpeakPermissionsDTO, error)
 }
 
 type liveClassServiceImpl struct {
 	classUserRepository repositories.ClassUserRepository
 	redisClient         *redis.Client
+	qualityStats        map[uint]map[uint]*dto.ViewerQualityStatsDTO
+	qualityMu           sync.RWMutex
+	// realtime 発言権の変更をクラスの参加者に配信する。nilの場合は配信しない
+	realtime RealtimePublisher
 }
 
-func NewLiveClassService(classUserRepo repositories.ClassUserRepository, redisClient *redis.Client) LiveClassService {
+func NewLiveClassService(classUserRepo repositories.ClassUserRepository, redisClient *redis.Client, realtime RealtimePublisher) LiveClassService {
 	return &liveClassServiceImpl{
 		classUserRepository: classUserRepo,
 		redisClient:         redisClient,
+		qualityStats:        make(map[uint]map[uint]*dto.ViewerQualityStatsDTO),
+		realtime:            realtime,
 	}
 }
 
@@ -56,7 +79,14 @@ func makeRedisKey(cid uint) string {
 	return fmt.Sprintf("screen_share:%d", cid)
 }
 
-func (service *liveClassServiceImpl) StartStreamingSession(cid uint) (string, error) {
+// StartStreamingSession uidのユーザーがクラスのライブ授業の配信を開始する。
+// ライブ授業の開始はスケジュールをライブ中にした時にチャットサービスへ投稿する
+func (service *liveClassServiceImpl) StartStreamingSession(uid uint, cid uint) (string, error) {
+	return service.startStream(cid)
+}
+
+// startStream ストリーミングサーバーにセッションの開始を依頼し、配信のURLを返す
+func (service *liveClassServiceImpl) startStream(cid uint) (string, error) {
 	// API 호출 로직 구현 (예시: HTTP 요청)
 	// 예를 들어, 스트리밍 서버로 POST 요청을 보내고 응답에서 URL을 추출
 	response, err := http.Post(fmt.Sprintf("https://minoriedu.com/start/%d", cid), "application/json", nil)
@@ -99,7 +129,7 @@ func (service *liveClassServiceImpl) MonitorStream(cid uint) {
 
 		if active, ok := status["active"].(bool); ok && !active {
 			log.Println("Stream has stopped unexpectedly, attempting to restart...")
-			service.StartStreamingSession(cid)
+			service.startStream(cid)
 		}
 	}
 }
@@ -118,5 +148,153 @@ func (service *liveClassServiceImpl) StopStreamingSession(cid uint) error {
 		return fmt.Errorf("failed to stop streaming session with status: %s", response.Status)
 	}
 
+	// ルーム終了時に接続品質の統計を破棄
+	service.ClearRoomQualityStats(cid)
+	return nil
+}
+
+// ReportQualityStats 視聴者の接続品質の統計をクラスのルームごとに集計する。
+// 統計は送信したユーザー本人のものとして集計し、クラスのメンバー以外はErrUnauthorizedを返す
+func (service *liveClassServiceImpl) ReportQualityStats(ctx context.Context, uid uint, cid uint, report dto.ConnectionQualityReportDTO) error {
+	role, err := service.classUserRepository.GetRole(ctx, uid, cid)
+	if err != nil || (role != "ADMIN" && role != "ASSISTANT" && role != "USER") {
+		return ErrUnauthorized
+	}
+
+	service.qualityMu.Lock()
+	defer service.qualityMu.Unlock()
+
+	room, ok := service.qualityStats[cid]
+	if !ok {
+		room = make(map[uint]*dto.ViewerQualityStatsDTO)
+		service.qualityStats[cid] = room
+	}
+
+	stats, ok := room[uid]
+	if !ok {
+		stats = &dto.ViewerQualityStatsDTO{UID: uid}
+		room[uid] = stats
+	}
+
+	// 移動平均で集計し、最新の値も保持する
+	n := float64(stats.Samples)
+	stats.AvgLatencyMs = (stats.AvgLatencyMs*n + report.LatencyMs) / (n + 1)
+	stats.AvgPacketLoss = (stats.AvgPacketLoss*n + report.PacketLoss) / (n + 1)
+	stats.AvgBitrateKbps = (stats.AvgBitrateKbps*n + report.BitrateKbps) / (n + 1)
+	stats.Samples++
+	stats.LastLatencyMs = report.LatencyMs
+	stats.LastPacketLoss = report.PacketLoss
+	stats.LastBitrate = report.BitrateKbps
+	stats.LastReportedAt = time.Now()
 	return nil
 }
+
+// GetRoomQualityStats クラスのルーム内の全視聴者の接続品質の統計をuidの昇順で取得する。講師(管理者とアシスタント)以外はErrUnauthorizedを返す
+func (service *liveClassServiceImpl) GetRoomQualityStats(ctx context.Context, uid uint, cid uint) ([]dto.ViewerQualityStatsDTO, error) {
+	if err := service.requireInstructor(ctx, uid, cid); err != nil {
+		return nil, err
+	}
+
+	service.qualityMu.RLock()
+	defer service.qualityMu.RUnlock()
+
+	result := make([]dto.ViewerQualityStatsDTO, 0, len(service.qualityStats[cid]))
+	for _, stats := range service.qualityStats[cid] {
+		result = append(result, *stats)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].UID < result[j].UID })
+	return result, nil
+}
+
+// ClearRoomQualityStats クラスのルームの接続品質の統計を破棄
+func (service *liveClassServiceImpl) ClearRoomQualityStats(cid uint) {
+	service.qualityMu.Lock()
+	defer service.qualityMu.Unlock()
+	delete(service.qualityStats, cid)
+}
+
+// GetSpeakPermissions ライブ授業で発言権を持つ生徒を取得する。クラスのメンバー以外はErrUnauthorizedを返す
+func (service *liveClassServiceImpl) GetSpeakPermissions(ctx context.Context, viewerUID uint, cid uint) (*dto.SpeakPermissionsDTO, error) {
+	role, err := service.classUserRepository.GetRole(ctx, viewerUID, cid)
+	if err != nil || (role != "ADMIN" && role != "ASSISTANT" && role != "USER") {
+		return nil, ErrUnauthorized
+	}
+	return service.speakPermissions(ctx, cid)
+}
+
+// GrantSpeakPermission 講師が挙手した生徒を指名して発言権を付与し、全参加者に配信する。
+// 講師(管理者とアシスタント)以外はErrUnauthorized、生徒がクラスのメンバーでない場合はErrNotFoundを返す。講師は常に発言できるため付与しない
+func (service *liveClassServiceImpl) GrantSpeakPermission(ctx context.Context, actorUID uint, cid uint, uid uint) (*dto.SpeakPermissionsDTO, error) {
+	if err := service.requireInstructor(ctx, actorUID, cid); err != nil {
+		return nil, err
+	}
+	role, err := service.classUserRepository.GetRole(ctx, uid, cid)
+	if err != nil || (role != "ADMIN" && role != "ASSISTANT" && role != "USER") {
+		return nil, ErrNotFound
+	}
+	if role == "USER" {
+		key := fmt.Sprintf(liveSpeakersKey, cid)
+		if err := service.redisClient.SAdd(ctx, key, uid).Err(); err != nil {
+			return nil, err
+		}
+		if err := service.redisClient.Expire(ctx, key, liveSpeakersTTL).Err(); err != nil {
+			return nil, err
+		}
+	}
+	return service.publishSpeakPermissions(ctx, cid, uid, true)
+}
+
+// RevokeSpeakPermission 講師が生徒の発言権を剥奪し、全参加者に配信する。講師(管理者とアシスタント)以外はErrUnauthorizedを返す
+func (service *liveClassServiceImpl) RevokeSpeakPermission(ctx context.Context, actorUID uint, cid uint, uid uint) (*dto.SpeakPermissionsDTO, error) {
+	if err := service.requireInstructor(ctx, actorUID, cid); err != nil {
+		return nil, err
+	}
+	if err := service.redisClient.SRem(ctx, fmt.Sprintf(liveSpeakersKey, cid), uid).Err(); err != nil {
+		return nil, err
+	}
+	return service.publishSpeakPermissions(ctx, cid, uid, false)
+}
+
+// requireInstructor ユーザーがクラスの管理者またはアシスタントか確認する
+func (service *liveClassServiceImpl) requireInstructor(ctx context.Context, uid uint, cid uint) error {
+	role, err := service.classUserRepository.GetRole(ctx, uid, cid)
+	if err != nil || (role != "ADMIN" && role != "ASSISTANT") {
+		return ErrUnauthorized
+	}
+	return nil
+}
+
+// speakPermissions 発言権を持つ生徒をuidの昇順で取得する
+func (service *liveClassServiceImpl) speakPermissions(ctx context.Context, cid uint) (*dto.SpeakPermissionsDTO, error) {
+	members, err := service.redisClient.SMembers(ctx, fmt.Sprintf(liveSpeakersKey, cid)).Result()
+	if err != nil {
+		return nil, err
+	}
+	speakers := make([]uint, 0, len(members))
+	for _, member := range members {
+		uid, err := strconv.ParseUint(member, 10, 32)
+		if err != nil {
+			continue
+		}
+		speakers = append(speakers, uint(uid))
+	}
+	sort.Slice(speakers, func(i, j int) bool { return speakers[i] < speakers[j] })
+	return &dto.SpeakPermissionsDTO{CID: cid, Speakers: speakers}, nil
+}
+
+// publishSpeakPermissions 変更後の発言権を取得し、クラスを購読中の参加者に配信する
+func (service *liveClassServiceImpl) publishSpeakPermissions(ctx context.Context, cid uint, uid uint, granted bool) (*dto.SpeakPermissionsDTO, error) {
+	permissions, err := service.speakPermissions(ctx, cid)
+	if err != nil {
+		return nil, err
+	}
+	if service.realtime != nil {
+		service.realtime.PublishToClass(ctx, cid, dto.RealtimeSpeakPermission, dto.SpeakPermissionChangedDTO{
+			CID:      cid,
+			UID:      uid,
+			Granted:  granted,
+			Speakers: permissions.Speakers,
+		})
+	}
+	return permissions, nil
+}
@@ -0,0 +1,128 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/utils"
+)
+
+const (
+	mailQueueSize   = 1024
+	mailWorkerCount = 2
+	// mailMaxAttempts 一時的なエラーで送信できなかったメールを送信する最大の回数。超えた場合はデッドレターとして記録する
+	mailMaxAttempts       = 3
+	mailSendTimeout       = 30 * time.Second
+	defaultMailRetryDelay = 30 * time.Second
+)
+
+// MailConfig メールの送信設定
+type MailConfig struct {
+	// Mailer メールの送信処理
+	Mailer utils.Mailer
+	// RatePerMinute 1分間に送信する最大の件数。送信元の評価を下げないよう、一括の招待などでも送信の間隔を空ける。0の場合は制限しない
+	RatePerMinute int
+	// RetryDelay 送信できなかったメールを最初に再送するまでの時間。再送するたびに2倍にする。0の場合は30秒
+	RetryDelay time.Duration
+}
+
+// MailRequest 送信を予約するメール
+type MailRequest struct {
+	To string
+	// Locale 宛先の言語。テンプレートがない言語は日本語にする
+	Locale   string
+	Template MailTemplate
+	Data     MailData
+}
+
+// MailService テンプレートからメールを作成し、非同期で送信するサービス
+type MailService interface {
+	Send(ctx context.Context, request MailRequest) error
+}
+
+// mailService インタフェースを実装
+type mailService struct {
+	config   MailConfig
+	jobs     chan utils.Mail
+	throttle <-chan time.Time
+}
+
+// NewMailService MailServiceを生成し、送信を行うワーカーを開始する
+func NewMailService(config MailConfig) MailService {
+	if config.RetryDelay == 0 {
+		config.RetryDelay = defaultMailRetryDelay
+	}
+	s := &mailService{
+		config: config,
+		jobs:   make(chan utils.Mail, mailQueueSize),
+	}
+	if config.RatePerMinute > 0 {
+		s.throttle = time.NewTicker(time.Minute / time.Duration(config.RatePerMinute)).C
+	}
+	for i := 0; i < mailWorkerCount; i++ {
+		go s.runWorker()
+	}
+	return s
+}
+
+// Send 宛先の言語のテンプレートでメールを作成し、送信を予約する。送信の結果は待たない。
+// キューが一杯の場合はErrMailQueueFullを返す
+func (s *mailService) Send(_ context.Context, request MailRequest) error {
+	mail, err := renderMail(request)
+	if err != nil {
+		return err
+	}
+	select {
+	case s.jobs <- mail:
+		return nil
+	default:
+		return ErrMailQueueFull
+	}
+}
+
+// runWorker 予約されたメールを順に送信する
+func (s *mailService) runWorker() {
+	for mail := range s.jobs {
+		attempts, err := s.deliver(mail)
+		if err != nil {
+			// 再送しても届かないメールは、宛先を確認して手動で送り直せるよう記録する
+			log.Printf("[DEAD-LETTER] mail to %s (%q) was not sent after %d attempts: %v", maskMailAddress(mail.To), mail.Subject, attempts, err)
+			utils.ReportBackgroundError("send_mail", fmt.Errorf("failed to send mail after %d attempts: %w", attempts, err))
+		}
+	}
+}
+
+// deliver メールを送信し、送信を試みた回数を返す。一時的なエラーの場合は間隔を空けて再送する。
+// 宛先が無効な場合は再送しない
+func (s *mailService) deliver(mail utils.Mail) (int, error) {
+	delay := s.config.RetryDelay
+	for attempt := 1; ; attempt++ {
+		if s.throttle != nil {
+			<-s.throttle
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), mailSendTimeout)
+		err := s.config.Mailer.Send(ctx, mail)
+		cancel()
+		if err == nil {
+			return attempt, nil
+		}
+		if errors.Is(err, utils.ErrInvalidNotificationTarget) || attempt >= mailMaxAttempts {
+			return attempt, err
+		}
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// maskMailAddress ログに残すメールアドレスのローカル部を先頭の1文字以外伏せる
+func maskMailAddress(address string) string {
+	at := strings.LastIndex(address, "@")
+	if at <= 0 {
+		return utils.RedactedValue
+	}
+	return string([]rune(address)[0]) + "***" + address[at:]
+}
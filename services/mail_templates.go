@@ -0,0 +1,257 @@
+package services
+
+import (
+	"bytes"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/utils"
+)
+
+// MailTemplate 送信するメールの種類
+type MailTemplate string
+
+const (
+	// ApplicationApprovedMail クラスへの参加申請の承認
+	ApplicationApprovedMail MailTemplate = "application_approved"
+	// ClassInvitationMail メールアドレスを指定したクラスへの招待
+	ClassInvitationMail MailTemplate = "class_invitation"
+	// DailyDigestMail 前日のクラスの活動をまとめた毎朝のダイジェスト
+	DailyDigestMail MailTemplate = "daily_digest"
+	// EmailVerificationMail メールアドレスの検証
+	EmailVerificationMail MailTemplate = "email_verification"
+)
+
+// defaultMailLocale テンプレートがない言語の宛先に送信する言語
+const defaultMailLocale = "ja"
+
+// MailData メールのテンプレートに埋め込む値。テンプレートで使わない値は空でよい
+type MailData struct {
+	// Name 宛先のユーザー名。アカウントがない宛先は空にする
+	Name           string
+	ClassName      string
+	InviterName    string
+	JoinCode       string
+	JoinURL        string
+	SecretRequired bool
+	// Date ダイジェストで活動を集計した日 (例: 2024-06-10)
+	Date string
+	// Classes ダイジェストに載せるクラスごとの活動
+	Classes []dto.ClassDigestDTO
+	// VerifyURL メールアドレスの確認ページのリンク。確認ページを設定していない場合は空
+	VerifyURL string
+	// VerifyToken メールアドレスの検証のトークン。確認ページがない場合にアプリに入力する
+	VerifyToken string
+}
+
+// mailLayout 1つの言語のメールの件名・テキスト・HTMLのテンプレート
+type mailLayout struct {
+	subject *texttemplate.Template
+	text    *texttemplate.Template
+	html    *htmltemplate.Template
+}
+
+// newMailLayout テンプレートを解析する。HTMLはbodyをlangの共通のレイアウトに埋め込む
+func newMailLayout(lang, subject, text, body string) mailLayout {
+	html := `<!DOCTYPE html>
+<html lang="` + lang + `">
+<body style="font-family: sans-serif; line-height: 1.6; color: #222;">
+` + body + `
+</body>
+</html>`
+	return mailLayout{
+		subject: texttemplate.Must(texttemplate.New("subject").Parse(subject)),
+		text:    texttemplate.Must(texttemplate.New("text").Parse(text)),
+		html:    htmltemplate.Must(htmltemplate.New("html").Parse(html)),
+	}
+}
+
+// mailLayouts メールの種類と言語ごとのテンプレート
+var mailLayouts = map[MailTemplate]map[string]mailLayout{
+	ApplicationApprovedMail: {
+		"ja": newMailLayout("ja",
+			`「{{.ClassName}}」への参加が承認されました`,
+			`{{.Name}} さん
+
+クラス「{{.ClassName}}」への参加申請が承認されました。
+アプリからクラスの掲示板やスケジュールを確認できます。
+`,
+			`<p>{{.Name}} さん</p>
+<p>クラス「<strong>{{.ClassName}}</strong>」への参加申請が承認されました。<br>
+アプリからクラスの掲示板やスケジュールを確認できます。</p>`),
+		"en": newMailLayout("en",
+			`Your request to join "{{.ClassName}}" was approved`,
+			`Hi {{.Name}},
+
+Your request to join the class "{{.ClassName}}" has been approved.
+You can now see the class board and schedule in the app.
+`,
+			`<p>Hi {{.Name}},</p>
+<p>Your request to join the class <strong>{{.ClassName}}</strong> has been approved.<br>
+You can now see the class board and schedule in the app.</p>`),
+	},
+	ClassInvitationMail: {
+		"ja": newMailLayout("ja",
+			`{{.InviterName}} さんから「{{.ClassName}}」に招待されました`,
+			`{{with .Name}}{{.}} さん
+
+{{end}}{{.InviterName}} さんからクラス「{{.ClassName}}」に招待されました。
+{{if .JoinURL}}
+次のリンクから参加を申請できます。
+{{.JoinURL}}
+{{end}}
+参加コード: {{.JoinCode}}
+{{if .SecretRequired}}参加にはクラスのシークレットが必要です。招待した講師に確認してください。
+{{end}}
+心当たりがない場合は、このメールを破棄してください。
+`,
+			`{{with .Name}}<p>{{.}} さん</p>
+{{end}}<p>{{.InviterName}} さんからクラス「<strong>{{.ClassName}}</strong>」に招待されました。</p>
+{{if .JoinURL}}<p><a href="{{.JoinURL}}">クラスへの参加を申請する</a></p>
+{{end}}<p>参加コード: <strong>{{.JoinCode}}</strong></p>
+{{if .SecretRequired}}<p>参加にはクラスのシークレットが必要です。招待した講師に確認してください。</p>
+{{end}}<p style="color: #666;">心当たりがない場合は、このメールを破棄してください。</p>`),
+		"en": newMailLayout("en",
+			`{{.InviterName}} invited you to "{{.ClassName}}"`,
+			`{{with .Name}}Hi {{.}},
+
+{{end}}{{.InviterName}} invited you to the class "{{.ClassName}}".
+{{if .JoinURL}}
+You can request to join from the following link.
+{{.JoinURL}}
+{{end}}
+Join code: {{.JoinCode}}
+{{if .SecretRequired}}The class secret is also required to join. Please ask the teacher who invited you.
+{{end}}
+If you were not expecting this invitation, you can ignore this email.
+`,
+			`{{with .Name}}<p>Hi {{.}},</p>
+{{end}}<p>{{.InviterName}} invited you to the class <strong>{{.ClassName}}</strong>.</p>
+{{if .JoinURL}}<p><a href="{{.JoinURL}}">Request to join the class</a></p>
+{{end}}<p>Join code: <strong>{{.JoinCode}}</strong></p>
+{{if .SecretRequired}}<p>The class secret is also required to join. Please ask the teacher who invited you.</p>
+{{end}}<p style="color: #666;">If you were not expecting this invitation, you can ignore this email.</p>`),
+	},
+	DailyDigestMail: {
+		"ja": newMailLayout("ja",
+			`{{.Date}}のクラスのまとめ`,
+			`{{.Name}} さん
+
+{{.Date}}のクラスの活動と今日の授業をお知らせします。
+{{range .Classes}}
+■ {{.Name}}
+{{if .NewBoards}}新しい掲示: {{.NewBoards}}件
+{{end}}{{if .ScheduleChanges}}スケジュールの変更: {{.ScheduleChanges}}件
+{{end}}{{if .UnreadChat}}未読のチャット: {{.UnreadChat}}件
+{{end}}{{range .TodaySchedules}}今日の授業: {{.StartedAt.Local.Format "15:04"}} {{.Title}}
+{{end}}{{end}}
+通知の設定から、ダイジェストの受け取りを停止できます。
+`,
+			`<p>{{.Name}} さん</p>
+<p>{{.Date}}のクラスの活動と今日の授業をお知らせします。</p>
+{{range .Classes}}<h3>{{.Name}}</h3>
+<ul>
+{{if .NewBoards}}<li>新しい掲示: {{.NewBoards}}件</li>
+{{end}}{{if .ScheduleChanges}}<li>スケジュールの変更: {{.ScheduleChanges}}件</li>
+{{end}}{{if .UnreadChat}}<li>未読のチャット: {{.UnreadChat}}件</li>
+{{end}}{{range .TodaySchedules}}<li>今日の授業: {{.StartedAt.Local.Format "15:04"}} {{.Title}}</li>
+{{end}}</ul>
+{{end}}<p style="color: #666;">通知の設定から、ダイジェストの受け取りを停止できます。</p>`),
+		"en": newMailLayout("en",
+			`Your class summary for {{.Date}}`,
+			`Hi {{.Name}},
+
+Here is what happened in your classes on {{.Date}} and your lessons for today.
+{{range .Classes}}
+■ {{.Name}}
+{{if .NewBoards}}New posts: {{.NewBoards}}
+{{end}}{{if .ScheduleChanges}}Schedule changes: {{.ScheduleChanges}}
+{{end}}{{if .UnreadChat}}Unread chat messages: {{.UnreadChat}}
+{{end}}{{range .TodaySchedules}}Today: {{.StartedAt.Local.Format "15:04"}} {{.Title}}
+{{end}}{{end}}
+You can stop receiving this summary in your notification settings.
+`,
+			`<p>Hi {{.Name}},</p>
+<p>Here is what happened in your classes on {{.Date}} and your lessons for today.</p>
+{{range .Classes}}<h3>{{.Name}}</h3>
+<ul>
+{{if .NewBoards}}<li>New posts: {{.NewBoards}}</li>
+{{end}}{{if .ScheduleChanges}}<li>Schedule changes: {{.ScheduleChanges}}</li>
+{{end}}{{if .UnreadChat}}<li>Unread chat messages: {{.UnreadChat}}</li>
+{{end}}{{range .TodaySchedules}}<li>Today: {{.StartedAt.Local.Format "15:04"}} {{.Title}}</li>
+{{end}}</ul>
+{{end}}<p style="color: #666;">You can stop receiving this summary in your notification settings.</p>`),
+	},
+	EmailVerificationMail: {
+		"ja": newMailLayout("ja",
+			`メールアドレスの確認`,
+			`{{.Name}} さん
+
+このメールアドレスで通知を受け取るには、24時間以内に次のリンクからメールアドレスを確認してください。
+{{if .VerifyURL}}{{.VerifyURL}}{{else}}確認コード: {{.VerifyToken}}{{end}}
+
+心当たりがない場合は、このメールを破棄してください。
+`,
+			`<p>{{.Name}} さん</p>
+<p>このメールアドレスで通知を受け取るには、24時間以内にメールアドレスを確認してください。</p>
+{{if .VerifyURL}}<p><a href="{{.VerifyURL}}">メールアドレスを確認する</a></p>
+{{else}}<p>確認コード: <strong>{{.VerifyToken}}</strong></p>
+{{end}}<p style="color: #666;">心当たりがない場合は、このメールを破棄してください。</p>`),
+		"en": newMailLayout("en",
+			`Confirm your email address`,
+			`Hi {{.Name}},
+
+To receive notifications at this address, please confirm it within 24 hours.
+{{if .VerifyURL}}{{.VerifyURL}}{{else}}Verification code: {{.VerifyToken}}{{end}}
+
+If you did not request this, you can ignore this email.
+`,
+			`<p>Hi {{.Name}},</p>
+<p>To receive notifications at this address, please confirm it within 24 hours.</p>
+{{if .VerifyURL}}<p><a href="{{.VerifyURL}}">Confirm my email address</a></p>
+{{else}}<p>Verification code: <strong>{{.VerifyToken}}</strong></p>
+{{end}}<p style="color: #666;">If you did not request this, you can ignore this email.</p>`),
+	},
+}
+
+// mailLocale ユーザーの言語 (例: en-GB) からテンプレートの言語を選ぶ。テンプレートがない言語は日本語にする
+func mailLocale(template MailTemplate, locale string) string {
+	lang := strings.ToLower(locale)
+	if i := strings.IndexAny(lang, "-_"); i >= 0 {
+		lang = lang[:i]
+	}
+	if _, ok := mailLayouts[template][lang]; ok {
+		return lang
+	}
+	return defaultMailLocale
+}
+
+// renderMail 宛先の言語のテンプレートでメールを作成する
+func renderMail(request MailRequest) (utils.Mail, error) {
+	layouts, ok := mailLayouts[request.Template]
+	if !ok {
+		return utils.Mail{}, ErrUnknownMailTemplate
+	}
+	layout := layouts[mailLocale(request.Template, request.Locale)]
+
+	var subject, text, html bytes.Buffer
+	if err := layout.subject.Execute(&subject, request.Data); err != nil {
+		return utils.Mail{}, err
+	}
+	if err := layout.text.Execute(&text, request.Data); err != nil {
+		return utils.Mail{}, err
+	}
+	if err := layout.html.Execute(&html, request.Data); err != nil {
+		return utils.Mail{}, err
+	}
+	return utils.Mail{
+		To: request.To,
+		// クラス名などの改行で件名のヘッダーが壊れないようにする
+		Subject:  strings.Join(strings.Fields(subject.String()), " "),
+		Text:     text.String(),
+		HTML:     html.String(),
+		Template: string(request.Template),
+	}, nil
+}
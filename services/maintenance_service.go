@@ -0,0 +1,138 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	// maintenanceModeKey メンテナンスモードを保存するキー。全てのインスタンスがこのキーを参照する。
+	// CLIからは redis-cli SET maintenance:mode read_only EX 600 のように設定できる
+	maintenanceModeKey = "maintenance:mode"
+	// MaintenancePollInterval 各インスタンスがメンテナンスモードを読み直す間隔
+	MaintenancePollInterval = 3 * time.Second
+)
+
+// MaintenanceMode メンテナンスモードの種類
+type MaintenanceMode string
+
+const (
+	MaintenanceOff      MaintenanceMode = "off"       // 通常どおり
+	MaintenanceReadOnly MaintenanceMode = "read_only" // 更新系のリクエストを受け付けない
+	MaintenanceFull     MaintenanceMode = "full"      // ヘルスチェックとトークンの更新以外を受け付けない
+)
+
+// ParseMaintenanceMode 文字列をメンテナンスモードに変換する。未知の値の場合はfalseを返す
+func ParseMaintenanceMode(value string) (MaintenanceMode, bool) {
+	switch mode := MaintenanceMode(value); mode {
+	case MaintenanceOff, MaintenanceReadOnly, MaintenanceFull:
+		return mode, true
+	}
+	return MaintenanceOff, false
+}
+
+// MaintenanceState メンテナンスモードと終了予定時刻。終了予定が無い場合、Untilはnilになる
+type MaintenanceState struct {
+	Mode  MaintenanceMode `json:"mode"`
+	Until *time.Time      `json:"until,omitempty"`
+}
+
+// SystemEvent SSEで接続中のクライアントに送るシステムからの通知。
+// メンテナンスの開始を受け取ったクライアントは、終了まで更新や再接続を控える
+type SystemEvent struct {
+	Type  string          `json:"type"`
+	Mode  MaintenanceMode `json:"mode"`
+	Until *time.Time      `json:"until,omitempty"`
+}
+
+// NewMaintenanceEvent メンテナンスモードの変更を知らせるSystemEventを生成する
+func NewMaintenanceEvent(state MaintenanceState) SystemEvent {
+	return SystemEvent{Type: "maintenance", Mode: state.Mode, Until: state.Until}
+}
+
+// MaintenanceService メンテナンスモードを管理する。
+// リクエストごとにRedisを参照しないよう、Refreshで読み込んだ状態を保持する
+type MaintenanceService interface {
+	// State 最後に読み込んだ状態を返す
+	State() MaintenanceState
+	// Refresh Redisから状態を読み直す
+	Refresh(ctx context.Context) (MaintenanceState, error)
+	// SetMode メンテナンスモードを設定する。ttlが0より大きい場合は期間が過ぎると自動的に解除される
+	SetMode(ctx context.Context, mode MaintenanceMode, ttl time.Duration) (MaintenanceState, error)
+}
+
+// maintenanceService インタフェースを実装
+type maintenanceService struct {
+	redisClient *redis.Client
+	mu          sync.RWMutex
+	state       MaintenanceState
+}
+
+// NewMaintenanceService MaintenanceServiceを生成する
+func NewMaintenanceService(redisClient *redis.Client) MaintenanceService {
+	return &maintenanceService{redisClient: redisClient, state: MaintenanceState{Mode: MaintenanceOff}}
+}
+
+// State 保持している状態を返す
+func (s *maintenanceService) State() MaintenanceState {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.state
+}
+
+// Refresh キーの値と残りの有効期限から状態を組み立てる。キーが無い場合や未知の値の場合は通常どおりとする
+func (s *maintenanceService) Refresh(ctx context.Context) (MaintenanceState, error) {
+	pipe := s.redisClient.Pipeline()
+	get := pipe.Get(ctx, maintenanceModeKey)
+	ttl := pipe.PTTL(ctx, maintenanceModeKey)
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return s.State(), err
+	}
+
+	state := MaintenanceState{Mode: MaintenanceOff}
+	if mode, ok := ParseMaintenanceMode(get.Val()); ok {
+		state.Mode = mode
+	}
+	if state.Mode != MaintenanceOff && ttl.Val() > 0 {
+		until := time.Now().Add(ttl.Val()).Truncate(time.Second)
+		state.Until = &until
+	}
+	s.setState(state)
+	return state, nil
+}
+
+// SetMode 通常どおりに戻す場合はキーを削除する
+func (s *maintenanceService) SetMode(ctx context.Context, mode MaintenanceMode, ttl time.Duration) (MaintenanceState, error) {
+	if mode == MaintenanceOff {
+		if err := s.redisClient.Del(ctx, maintenanceModeKey).Err(); err != nil {
+			return s.State(), err
+		}
+		state := MaintenanceState{Mode: MaintenanceOff}
+		s.setState(state)
+		return state, nil
+	}
+
+	if ttl < 0 {
+		ttl = 0
+	}
+	if err := s.redisClient.Set(ctx, maintenanceModeKey, string(mode), ttl).Err(); err != nil {
+		return s.State(), err
+	}
+	state := MaintenanceState{Mode: mode}
+	if ttl > 0 {
+		until := time.Now().Add(ttl).Truncate(time.Second)
+		state.Until = &until
+	}
+	s.setState(state)
+	return state, nil
+}
+
+func (s *maintenanceService) setState(state MaintenanceState) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.state = state
+}
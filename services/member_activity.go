@@ -0,0 +1,155 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/utils"
+)
+
+const (
+	activityCacheTTL       = 30 * time.Minute
+	activityCacheKey       = "activity_ranking:%d"
+	activityTrackedClasses = "activity_ranking:classes"
+	chatStatsKey           = "chat_stats:"
+	// chatStatsTTL スケジュールごとの発言数を保持する期間。発言するたびに延長し、学期中のランキングに使えるようにする
+	chatStatsTTL = 180 * 24 * time.Hour
+)
+
+// DefaultActivityWeights 重みが指定されなかった場合の既定値
+var DefaultActivityWeights = dto.ActivityWeights{Board: 1, Attendance: 1, Chat: 1}
+
+// GetMemberActivityRanking 掲示板投稿数・出席率・チャット発言数を重み付けした活動度ランキングを返す
+// 指標はRedisにキャッシュし、重みはキャッシュ取得後に適用する
+func (s *classUserServiceImpl) GetMemberActivityRanking(ctx context.Context, cid uint, weights dto.ActivityWeights, limit int, anonymize bool) ([]dto.MemberActivityRankingDTO, error) {
+	stats, err := s.getCachedActivityStats(ctx, cid)
+	if err != nil {
+		return nil, err
+	}
+	if weights == (dto.ActivityWeights{}) {
+		weights = DefaultActivityWeights
+	}
+
+	return rankMemberActivity(stats, weights, limit, anonymize), nil
+}
+
+// RefreshMemberActivityRankings ランキングが参照されたクラスの指標を再計算してキャッシュする
+func (s *classUserServiceImpl) RefreshMemberActivityRankings() {
+	ctx := context.Background()
+	classIDs, err := s.redisClient.SMembers(ctx, activityTrackedClasses).Result()
+	if err != nil {
+		utils.ReportBackgroundError("refresh_activity_rankings", fmt.Errorf("failed to load tracked classes for activity ranking: %w", err))
+		return
+	}
+
+	for _, cidStr := range classIDs {
+		cid, err := strconv.ParseUint(cidStr, 10, 32)
+		if err != nil {
+			continue
+		}
+		if _, err := s.computeActivityStats(ctx, uint(cid)); err != nil {
+			utils.ReportBackgroundError("refresh_activity_rankings", fmt.Errorf("failed to refresh activity ranking for class %d: %w", cid, err))
+		}
+	}
+}
+
+// getCachedActivityStats キャッシュ済みの指標を返し、無ければ集計する
+func (s *classUserServiceImpl) getCachedActivityStats(ctx context.Context, cid uint) ([]dto.MemberActivityStatsDTO, error) {
+	cached, err := s.redisClient.Get(ctx, fmt.Sprintf(activityCacheKey, cid)).Bytes()
+	if err == nil {
+		var stats []dto.MemberActivityStatsDTO
+		if err := json.Unmarshal(cached, &stats); err == nil {
+			return stats, nil
+		}
+	}
+	return s.computeActivityStats(ctx, cid)
+}
+
+// computeActivityStats DBとRedisから指標を集計してキャッシュする
+func (s *classUserServiceImpl) computeActivityStats(ctx context.Context, cid uint) ([]dto.MemberActivityStatsDTO, error) {
+	stats, err := s.classUserRepo.GetMemberActivityStats(ctx, cid)
+	if err != nil {
+		return nil, err
+	}
+
+	schedules, err := s.classScheduleRepo.GetAllClassSchedules(ctx, cid)
+	if err != nil {
+		return nil, err
+	}
+
+	chatCounts := make(map[string]int64)
+	for _, schedule := range schedules {
+		counts, err := s.redisClient.HGetAll(ctx, chatStatsKey+strconv.FormatUint(uint64(schedule.ID), 10)).Result()
+		if err != nil {
+			continue
+		}
+		for uid, count := range counts {
+			n, _ := strconv.ParseInt(count, 10, 64)
+			chatCounts[uid] += n
+		}
+	}
+	for i := range stats {
+		stats[i].ChatMessages = chatCounts[strconv.FormatUint(uint64(stats[i].UID), 10)]
+	}
+
+	if data, err := json.Marshal(stats); err == nil {
+		s.redisClient.Set(ctx, fmt.Sprintf(activityCacheKey, cid), data, activityCacheTTL)
+		s.redisClient.SAdd(ctx, activityTrackedClasses, cid)
+	}
+	return stats, nil
+}
+
+// rankMemberActivity 指標を正規化して重み付けし、スコア順に並べる
+func rankMemberActivity(stats []dto.MemberActivityStatsDTO, weights dto.ActivityWeights, limit int, anonymize bool) []dto.MemberActivityRankingDTO {
+	var maxBoard, maxChat int64
+	for _, stat := range stats {
+		if stat.BoardPosts > maxBoard {
+			maxBoard = stat.BoardPosts
+		}
+		if stat.ChatMessages > maxChat {
+			maxChat = stat.ChatMessages
+		}
+	}
+
+	ranking := make([]dto.MemberActivityRankingDTO, 0, len(stats))
+	for _, stat := range stats {
+		score := weights.Attendance * stat.AttendanceRate
+		if maxBoard > 0 {
+			score += weights.Board * float64(stat.BoardPosts) / float64(maxBoard)
+		}
+		if maxChat > 0 {
+			score += weights.Chat * float64(stat.ChatMessages) / float64(maxChat)
+		}
+
+		uid := stat.UID
+		ranking = append(ranking, dto.MemberActivityRankingDTO{
+			UID:            &uid,
+			Nickname:       stat.Nickname,
+			BoardPosts:     stat.BoardPosts,
+			AttendanceRate: stat.AttendanceRate,
+			ChatMessages:   stat.ChatMessages,
+			Score:          score,
+		})
+	}
+
+	sort.SliceStable(ranking, func(i, j int) bool {
+		return ranking[i].Score > ranking[j].Score
+	})
+	if limit > 0 && len(ranking) > limit {
+		ranking = ranking[:limit]
+	}
+
+	for i := range ranking {
+		ranking[i].Rank = i + 1
+		if anonymize {
+			ranking[i].UID = nil
+			ranking[i].Nickname = fmt.Sprintf("メンバー%d", i+1)
+		}
+	}
+	return ranking
+}
@@ -0,0 +1,362 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/utils"
+	"github.com/go-redis/redis/v8"
+	"gorm.io/gorm"
+)
+
+const (
+	// NotificationRetention 既読の通知の保存期間。未読の通知は削除しない
+	NotificationRetention = 90 * 24 * time.Hour
+
+	// notificationUnreadKey ユーザーごとの未読の通知の件数のキャッシュ
+	notificationUnreadKey = "notification_unread:%d"
+	// notificationUnreadTTL 未読件数のキャッシュの有効期間。通知の追加と既読では削除するため、同時に更新した場合のずれのみこの期間残る
+	notificationUnreadTTL = 5 * time.Minute
+
+	pushQueueSize   = 1024
+	pushWorkerCount = 4
+	// pushMaxAttempts 一時的なエラーで送信できなかったトークンに送信する最大の回数
+	pushMaxAttempts       = 3
+	pushStepTimeout       = 30 * time.Second
+	defaultPushRetryDelay = 5 * time.Second
+)
+
+// PushConfig アプリ内通知をモバイル端末にプッシュ通知として配信する設定
+type PushConfig struct {
+	// Sender プッシュ通知の送信処理。nilの場合はプッシュ通知を配信しない
+	Sender utils.PushSender
+	// RetryDelay 一時的なエラーで送信できなかったトークンに最初に再送するまでの時間。再送するたびに2倍にする。0の場合は5秒
+	RetryDelay time.Duration
+}
+
+// NotificationService アプリ内通知の作成・取得・既読を行うサービス
+type NotificationService interface {
+	Publish(ctx context.Context, notification models.Notification) error
+	GetNotifications(ctx context.Context, uid uint, unreadOnly bool, beforeID uint, limit int) ([]dto.NotificationDTO, error)
+	GetUnreadCount(ctx context.Context, uid uint) (int64, error)
+	MarkRead(ctx context.Context, uid uint, id uint) error
+	MarkAllRead(ctx context.Context, uid uint) (int64, error)
+	PurgeReadNotifications(ctx context.Context) (int64, error)
+	RegisterDevice(ctx context.Context, uid uint, request dto.DeviceTokenRequest) error
+	UnregisterDevice(ctx context.Context, uid uint, token string) error
+	GetPreferences(ctx context.Context, uid uint) ([]dto.NotificationPreferenceDTO, error)
+	UpdatePreference(ctx context.Context, uid uint, preference dto.NotificationPreferenceDTO) error
+}
+
+// notificationService インタフェースを実装
+type notificationService struct {
+	repo            repositories.NotificationRepository
+	deviceTokenRepo repositories.DeviceTokenRepository
+	redisClient     *redis.Client
+	push            PushConfig
+	pushJobs        chan models.Notification
+	// realtime 接続中のクライアントに通知を配信する。nilの場合は配信しない
+	realtime RealtimePublisher
+}
+
+// NewNotificationService NotificationServiceを生成する。プッシュ通知の送信処理がある場合は、配信を行うワーカーを開始する
+func NewNotificationService(repo repositories.NotificationRepository, deviceTokenRepo repositories.DeviceTokenRepository, redisClient *redis.Client, push PushConfig, realtime RealtimePublisher) NotificationService {
+	if push.RetryDelay == 0 {
+		push.RetryDelay = defaultPushRetryDelay
+	}
+	s := &notificationService{
+		repo:            repo,
+		deviceTokenRepo: deviceTokenRepo,
+		redisClient:     redisClient,
+		push:            push,
+		realtime:        realtime,
+	}
+	if push.Sender != nil {
+		s.pushJobs = make(chan models.Notification, pushQueueSize)
+		for i := 0; i < pushWorkerCount; i++ {
+			go s.runPushWorker()
+		}
+	}
+	return s
+}
+
+// Publish ユーザーにアプリ内通知を追加し、接続中のクライアントへの配信とプッシュ通知の配信の予約を行う。他のサービスが通知を作成するときに呼び出す。
+// プッシュ通知は非同期で配信するため、配信の結果を待たない
+func (s *notificationService) Publish(ctx context.Context, notification models.Notification) error {
+	notification.ID = 0
+	notification.ReadAt = nil
+	if notification.Type == "" {
+		notification.Type = models.ClassNotification
+	}
+	if err := s.repo.CreateNotification(ctx, &notification); err != nil {
+		return err
+	}
+	s.invalidateUnreadCount(ctx, notification.UID)
+	if s.realtime != nil {
+		s.realtime.PublishToUser(ctx, notification.UID, dto.RealtimeNotification, toNotificationDTO(notification))
+	}
+	s.enqueuePush(notification)
+	return nil
+}
+
+// GetNotifications ユーザーの通知を新しい順に取得する。beforeIDには前のページの最後の通知のIDを指定する
+func (s *notificationService) GetNotifications(ctx context.Context, uid uint, unreadOnly bool, beforeID uint, limit int) ([]dto.NotificationDTO, error) {
+	notifications, err := s.repo.FindNotificationsByUser(ctx, uid, unreadOnly, beforeID, limit)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]dto.NotificationDTO, 0, len(notifications))
+	for _, notification := range notifications {
+		result = append(result, toNotificationDTO(notification))
+	}
+	return result, nil
+}
+
+// GetUnreadCount ユーザーの未読の通知の件数を返す。バッジの表示で頻繁に呼ばれるため、件数をRedisにキャッシュする
+func (s *notificationService) GetUnreadCount(ctx context.Context, uid uint) (int64, error) {
+	key := fmt.Sprintf(notificationUnreadKey, uid)
+	if s.redisClient != nil {
+		if count, err := s.redisClient.Get(ctx, key).Int64(); err == nil {
+			return count, nil
+		}
+	}
+
+	count, err := s.repo.CountUnreadNotifications(ctx, uid)
+	if err != nil {
+		return 0, err
+	}
+	if s.redisClient != nil {
+		s.redisClient.Set(ctx, key, count, notificationUnreadTTL)
+	}
+	return count, nil
+}
+
+// MarkRead ユーザーの通知を既読にする。他のユーザーの通知はErrNotFoundにする
+func (s *notificationService) MarkRead(ctx context.Context, uid uint, id uint) error {
+	if err := s.repo.MarkNotificationRead(ctx, uid, id, time.Now()); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrNotFound
+		}
+		return err
+	}
+	s.invalidateUnreadCount(ctx, uid)
+	return nil
+}
+
+// MarkAllRead ユーザーの未読の通知を全て既読にし、既読にした件数を返す
+func (s *notificationService) MarkAllRead(ctx context.Context, uid uint) (int64, error) {
+	updated, err := s.repo.MarkAllNotificationsRead(ctx, uid, time.Now())
+	if err != nil {
+		return 0, err
+	}
+	s.invalidateUnreadCount(ctx, uid)
+	return updated, nil
+}
+
+// PurgeReadNotifications 既読にしてから保存期間を過ぎた通知を削除する
+func (s *notificationService) PurgeReadNotifications(ctx context.Context) (int64, error) {
+	return s.repo.DeleteReadNotificationsBefore(ctx, time.Now().Add(-NotificationRetention))
+}
+
+// RegisterDevice 端末のトークンを登録する。アプリは起動するたびに登録し、最終登録日時を更新する
+func (s *notificationService) RegisterDevice(ctx context.Context, uid uint, request dto.DeviceTokenRequest) error {
+	return s.deviceTokenRepo.SaveDeviceToken(ctx, &models.DeviceToken{
+		UID:        uid,
+		Platform:   models.DevicePlatform(request.Platform),
+		Token:      request.Token,
+		LastSeenAt: time.Now(),
+	})
+}
+
+// UnregisterDevice ユーザーの端末のトークンを削除する。ログアウトした端末にプッシュ通知を送信しないようにする
+func (s *notificationService) UnregisterDevice(ctx context.Context, uid uint, token string) error {
+	return s.deviceTokenRepo.DeleteDeviceToken(ctx, uid, token)
+}
+
+// GetPreferences 全ての通知の種類について、プッシュ通知を受け取るかどうかを返す
+func (s *notificationService) GetPreferences(ctx context.Context, uid uint) ([]dto.NotificationPreferenceDTO, error) {
+	enabled, err := s.pushPreferences(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]dto.NotificationPreferenceDTO, 0, len(models.NotificationTypes))
+	for _, notificationType := range models.NotificationTypes {
+		result = append(result, dto.NotificationPreferenceDTO{Type: string(notificationType), Push: enabled(notificationType)})
+	}
+	return result, nil
+}
+
+// UpdatePreference 通知の種類のプッシュ通知を受け取るかどうかを設定する
+func (s *notificationService) UpdatePreference(ctx context.Context, uid uint, preference dto.NotificationPreferenceDTO) error {
+	return s.repo.SaveNotificationPreference(ctx, &models.NotificationPreference{
+		UID:         uid,
+		Type:        models.NotificationType(preference.Type),
+		PushEnabled: preference.Push,
+	})
+}
+
+// pushPreferences ユーザーが通知の種類のプッシュ通知を受け取るかどうかを判定する関数を返す。設定していない種類は受け取る
+func (s *notificationService) pushPreferences(ctx context.Context, uid uint) (func(models.NotificationType) bool, error) {
+	preferences, err := s.repo.FindNotificationPreferences(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	disabled := make(map[models.NotificationType]bool, len(preferences))
+	for _, preference := range preferences {
+		disabled[preference.Type] = !preference.PushEnabled
+	}
+	return func(notificationType models.NotificationType) bool {
+		return !disabled[notificationType]
+	}, nil
+}
+
+// enqueuePush プッシュ通知の配信を予約する。キューが一杯の場合はリクエストを待たせずに破棄する
+func (s *notificationService) enqueuePush(notification models.Notification) {
+	if s.pushJobs == nil {
+		return
+	}
+	select {
+	case s.pushJobs <- notification:
+	default:
+		log.Printf("Push notification queue is full. Dropped notification %d to uid %d", notification.ID, notification.UID)
+	}
+}
+
+// runPushWorker 予約されたプッシュ通知を順に配信する
+func (s *notificationService) runPushWorker() {
+	for notification := range s.pushJobs {
+		if err := s.deliverPush(notification); err != nil {
+			utils.ReportBackgroundError("deliver_push_notifications", fmt.Errorf("failed to push notification %d to uid %d: %w", notification.ID, notification.UID, err))
+		}
+	}
+}
+
+// deliverPush ユーザーがプッシュ通知を受け取る種類の通知を、ユーザーの全ての端末にまとめて送信する。
+// 無効になったトークンは削除し、一時的なエラーで送信できなかったトークンには間隔を空けて再送する
+func (s *notificationService) deliverPush(notification models.Notification) error {
+	var tokens []models.DeviceToken
+	err := withPushTimeout(func(ctx context.Context) error {
+		enabled, err := s.pushPreferences(ctx, notification.UID)
+		if err != nil || !enabled(notification.Type) {
+			return err
+		}
+		tokens, err = s.deviceTokenRepo.FindDeviceTokensByUser(ctx, notification.UID)
+		return err
+	})
+	if err != nil || len(tokens) == 0 {
+		return err
+	}
+
+	pending := make([]string, 0, len(tokens))
+	for _, token := range tokens {
+		pending = append(pending, token.Token)
+	}
+	message := toPushMessage(notification)
+	delay := s.push.RetryDelay
+	for attempt := 1; ; attempt++ {
+		var result *utils.PushResult
+		err := withPushTimeout(func(ctx context.Context) (err error) {
+			result, err = s.push.Sender.Send(ctx, pending, message)
+			if result != nil && len(result.InvalidTokens) > 0 {
+				if pruneErr := s.deviceTokenRepo.DeleteDeviceTokens(ctx, result.InvalidTokens); pruneErr != nil {
+					log.Printf("Failed to delete %d invalid device tokens: %v", len(result.InvalidTokens), pruneErr)
+				}
+			}
+			return err
+		})
+		if result != nil {
+			pending = result.RetryTokens
+		}
+		if len(pending) == 0 {
+			return nil
+		}
+		if attempt >= pushMaxAttempts {
+			if err == nil {
+				err = fmt.Errorf("%d device tokens were not reachable", len(pending))
+			}
+			return fmt.Errorf("gave up after %d attempts: %w", attempt, err)
+		}
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// withPushTimeout プッシュ通知の配信の1つの処理にタイムアウトを設けて実行する
+func withPushTimeout(fn func(ctx context.Context) error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), pushStepTimeout)
+	defer cancel()
+	return fn(ctx)
+}
+
+// toPushMessage 通知をプッシュ通知に変換する。アプリが通知を既読にして遷移できるよう、通知のIDと対象を含める
+func toPushMessage(notification models.Notification) utils.PushMessage {
+	data := map[string]string{
+		"notification_id": strconv.FormatUint(uint64(notification.ID), 10),
+		"type":            string(notification.Type),
+	}
+	if notification.ResourceType != "" {
+		data["resource_type"] = notification.ResourceType
+	}
+	if notification.ResourceID != nil {
+		data["resource_id"] = strconv.FormatUint(uint64(*notification.ResourceID), 10)
+	}
+	if notification.CID != nil {
+		data["cid"] = strconv.FormatUint(uint64(*notification.CID), 10)
+	}
+	return utils.PushMessage{Title: notification.Title, Body: notification.Body, Data: data}
+}
+
+// invalidateUnreadCount 未読件数のキャッシュを削除する。削除に失敗しても有効期間が過ぎれば正しい件数に戻るため、エラーにしない
+func (s *notificationService) invalidateUnreadCount(ctx context.Context, uid uint) {
+	if s.redisClient == nil {
+		return
+	}
+	if err := s.redisClient.Del(ctx, fmt.Sprintf(notificationUnreadKey, uid)).Err(); err != nil {
+		log.Printf("Redis error while invalidating unread notification count for uid %d: %v", uid, err)
+	}
+}
+
+// toNotificationDTO 通知をDTOに変換する
+func toNotificationDTO(notification models.Notification) dto.NotificationDTO {
+	return dto.NotificationDTO{
+		ID:           notification.ID,
+		Type:         string(notification.Type),
+		Title:        notification.Title,
+		Body:         notification.Body,
+		ResourceType: notification.ResourceType,
+		ResourceID:   notification.ResourceID,
+		CID:          notification.CID,
+		ReadAt:       notification.ReadAt,
+		CreatedAt:    notification.CreatedAt,
+	}
+}
+
+// inAppNotifier 通知をアプリ内通知として保存するNotifier
+type inAppNotifier struct {
+	notifications NotificationService
+}
+
+// NewInAppNotifier Notifierで送信する通知をnotificationsにクラスの通知として保存するNotifierを生成
+func NewInAppNotifier(notifications NotificationService) Notifier {
+	return &inAppNotifier{notifications: notifications}
+}
+
+// Notify 通知をクラスに関するアプリ内通知として保存する
+func (n *inAppNotifier) Notify(ctx context.Context, uid uint, cid uint, title string, body string) error {
+	return n.notifications.Publish(ctx, models.Notification{
+		UID:          uid,
+		Type:         models.ClassNotification,
+		Title:        title,
+		Body:         body,
+		ResourceType: "class",
+		ResourceID:   &cid,
+		CID:          &cid,
+	})
+}
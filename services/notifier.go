@@ -0,0 +1,47 @@
+package services
+
+import (
+	"context"
+	"log"
+)
+
+// Notifier ユーザーへのクラスに関する通知を送信する
+type Notifier interface {
+	Notify(ctx context.Context, uid uint, cid uint, title string, body string) error
+}
+
+// logNotifier 通知をログに出力するNotifier。通知の配信手段が用意されるまでの既定の実装
+type logNotifier struct{}
+
+// NewLogNotifier logNotifierを生成
+func NewLogNotifier() Notifier {
+	return logNotifier{}
+}
+
+// Notify 通知の内容をログに出力する
+func (logNotifier) Notify(_ context.Context, uid uint, cid uint, title string, body string) error {
+	log.Printf("Notification to uid %d about class %d: %s: %s", uid, cid, title, body)
+	return nil
+}
+
+// unreadCountingNotifier 通知を送信し、ユーザーのクラスの未読通知を1件増やすNotifier
+type unreadCountingNotifier struct {
+	next   Notifier
+	unread UnreadService
+}
+
+// NewUnreadCountingNotifier nextで通知を送信し、未読通知をunreadに記録するNotifierを生成
+func NewUnreadCountingNotifier(next Notifier, unread UnreadService) Notifier {
+	return &unreadCountingNotifier{next: next, unread: unread}
+}
+
+// Notify 通知を送信する。未読件数の記録に失敗しても通知は失敗にしない
+func (n *unreadCountingNotifier) Notify(ctx context.Context, uid uint, cid uint, title string, body string) error {
+	if err := n.next.Notify(ctx, uid, cid, title, body); err != nil {
+		return err
+	}
+	if err := n.unread.RecordNotification(ctx, uid, cid); err != nil {
+		log.Printf("Redis error while counting unread notification for uid %d: %v", uid, err)
+	}
+	return nil
+}
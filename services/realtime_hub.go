@@ -0,0 +1,406 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	// realtimeUserChannel, realtimeClassChannel ユーザー・クラスごとのイベントを配信するRedisのチャンネル
+	realtimeUserChannel  = "realtime:user:%d"
+	realtimeClassChannel = "realtime:class:%d"
+
+	defaultRealtimeSendBuffer = 64
+	defaultRealtimeMaxTopics  = 50
+
+	// realtimeMemberCheckTimeout クラスのイベントを配信する前にメンバーを確認するときのタイムアウト
+	realtimeMemberCheckTimeout = 5 * time.Second
+)
+
+// RealtimeConfig WebSocketでのイベントの配信設定
+type RealtimeConfig struct {
+	// SendBuffer 接続ごとに送信を待つイベントの上限。超えた場合は古いイベントから破棄する。0の場合は64件
+	SendBuffer int
+	// MaxTopics 1つの接続で購読できるクラスの上限。0の場合は50件
+	MaxTopics int
+}
+
+// RealtimePublisher WebSocketで接続中のクライアントにイベントを配信する。
+// 配信に失敗しても元の操作は失敗させないため、エラーはログに記録するのみとする
+type RealtimePublisher interface {
+	PublishToUser(ctx context.Context, uid uint, eventType string, data interface{})
+	PublishToClass(ctx context.Context, cid uint, eventType string, data interface{})
+}
+
+// RealtimeHub ユーザーごとのWebSocketの接続に、ユーザー宛てと購読中のクラスのイベントをまとめて配信する
+type RealtimeHub interface {
+	RealtimePublisher
+	Connect(ctx context.Context, uid uint) (*RealtimeConnection, error)
+	BroadcastSystemEvent(event SystemEvent)
+}
+
+// realtimeHub インタフェースを実装
+type realtimeHub struct {
+	classUserRepo repositories.ClassUserRepository
+	config        RealtimeConfig
+	// pubsub 全ての接続で共有するRedisの購読。nilの場合はこのインスタンスの接続にのみ配信する
+	pubsub      *redis.PubSub
+	redisClient *redis.Client
+
+	mu          sync.Mutex
+	connections map[*RealtimeConnection]struct{}
+	// channels チャンネルごとの購読中の接続。最初の接続でRedisのチャンネルを購読し、最後の接続が離れると解除する
+	channels map[string]map[*RealtimeConnection]struct{}
+
+	// subMu Redisのチャンネルの購読と解除を直列にする。Redisとの通信中に配信を止めないよう、muとは別に取得する
+	subMu sync.Mutex
+	// subscribed Redisで購読中のチャンネル。subMuで保護する
+	subscribed map[string]bool
+}
+
+// NewRealtimeHub RealtimeHubを生成する。redisClientがある場合は、他のインスタンスで配信したイベントも受け取る
+func NewRealtimeHub(classUserRepo repositories.ClassUserRepository, redisClient *redis.Client, config RealtimeConfig) RealtimeHub {
+	if config.SendBuffer == 0 {
+		config.SendBuffer = defaultRealtimeSendBuffer
+	}
+	if config.MaxTopics == 0 {
+		config.MaxTopics = defaultRealtimeMaxTopics
+	}
+	h := &realtimeHub{
+		classUserRepo: classUserRepo,
+		config:        config,
+		redisClient:   redisClient,
+		connections:   make(map[*RealtimeConnection]struct{}),
+		channels:      make(map[string]map[*RealtimeConnection]struct{}),
+		subscribed:    make(map[string]bool),
+	}
+	if redisClient != nil {
+		h.pubsub = redisClient.Subscribe(context.Background())
+		go h.run()
+	}
+	return h
+}
+
+// Connect ユーザーの接続を登録し、ユーザー宛てのイベントの配信を開始する。接続を終えたらCloseを呼び出す
+func (h *realtimeHub) Connect(ctx context.Context, uid uint) (*RealtimeConnection, error) {
+	conn := &RealtimeConnection{
+		hub:      h,
+		uid:      uid,
+		ready:    make(chan struct{}, 1),
+		channels: make(map[string]bool),
+	}
+	channel := fmt.Sprintf(realtimeUserChannel, uid)
+	h.mu.Lock()
+	h.join(conn, channel)
+	h.connections[conn] = struct{}{}
+	h.mu.Unlock()
+	if err := h.syncSubscription(ctx, channel); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// PublishToUser ユーザーの全ての接続にイベントを配信する
+func (h *realtimeHub) PublishToUser(ctx context.Context, uid uint, eventType string, data interface{}) {
+	h.publish(ctx, fmt.Sprintf(realtimeUserChannel, uid), eventType, nil, data)
+}
+
+// PublishToClass クラスを購読中の全ての接続にイベントを配信する
+func (h *realtimeHub) PublishToClass(ctx context.Context, cid uint, eventType string, data interface{}) {
+	h.publish(ctx, fmt.Sprintf(realtimeClassChannel, cid), eventType, &cid, data)
+}
+
+// BroadcastSystemEvent このインスタンスの全ての接続にシステムからの通知を送る。通知は各インスタンスで行うため、Redisには配信しない
+func (h *realtimeHub) BroadcastSystemEvent(event SystemEvent) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("failed to encode system event: %v", err)
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for conn := range h.connections {
+		conn.Push(dto.RealtimeEventDTO{Type: dto.RealtimeSystem, Data: data})
+	}
+}
+
+// publish イベントをチャンネルに配信する。Redisがない場合はこのインスタンスの接続に直接配信する
+func (h *realtimeHub) publish(ctx context.Context, channel string, eventType string, cid *uint, data interface{}) {
+	event := dto.RealtimeEventDTO{Type: eventType, CID: cid}
+	if data != nil {
+		encoded, err := json.Marshal(data)
+		if err != nil {
+			log.Printf("failed to encode %s event: %v", eventType, err)
+			return
+		}
+		event.Data = encoded
+	}
+	if h.redisClient == nil {
+		h.dispatch(channel, event)
+		return
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("failed to encode %s event: %v", eventType, err)
+		return
+	}
+	if err := h.redisClient.Publish(ctx, channel, payload).Err(); err != nil {
+		log.Printf("failed to publish %s event to %s: %v", eventType, channel, err)
+	}
+}
+
+// run Redisから受け取ったイベントを購読中の接続に配信する
+func (h *realtimeHub) run() {
+	for message := range h.pubsub.Channel() {
+		var event dto.RealtimeEventDTO
+		if err := json.Unmarshal([]byte(message.Payload), &event); err != nil {
+			continue
+		}
+		h.dispatch(message.Channel, event)
+	}
+}
+
+// dispatch チャンネルを購読中のこのインスタンスの接続にイベントを配信する。
+// クラスのイベントは配信する時点のメンバーにのみ送り、メンバーでなくなった接続の購読は解除する
+func (h *realtimeHub) dispatch(channel string, event dto.RealtimeEventDTO) {
+	h.mu.Lock()
+	subscribers := make([]*RealtimeConnection, 0, len(h.channels[channel]))
+	for conn := range h.channels[channel] {
+		subscribers = append(subscribers, conn)
+	}
+	h.mu.Unlock()
+	if len(subscribers) == 0 {
+		return
+	}
+	if event.CID != nil && channel == fmt.Sprintf(realtimeClassChannel, *event.CID) {
+		subscribers = h.removeNonMembers(channel, *event.CID, subscribers)
+	}
+	for _, conn := range subscribers {
+		conn.Push(event)
+	}
+}
+
+// removeNonMembers クラスのメンバーでなくなったユーザーの接続の購読を解除してunsubscribedを送り、メンバーの接続のみを返す。
+// メンバーを確認できない場合は、メンバーでないユーザーに配信しないよう全ての接続に配信しない
+func (h *realtimeHub) removeNonMembers(channel string, cid uint, subscribers []*RealtimeConnection) []*RealtimeConnection {
+	ctx, cancel := context.WithTimeout(context.Background(), realtimeMemberCheckTimeout)
+	defer cancel()
+	members, err := h.classUserRepo.GetClassMembers(ctx, cid, "ADMIN", "ASSISTANT", "USER")
+	if err != nil {
+		log.Printf("failed to load members of class %d: %v", cid, err)
+		return nil
+	}
+	isMember := make(map[uint]bool, len(members))
+	for _, member := range members {
+		isMember[member.Uid] = true
+	}
+
+	var allowed, revoked []*RealtimeConnection
+	for _, conn := range subscribers {
+		if isMember[conn.uid] {
+			allowed = append(allowed, conn)
+		} else {
+			revoked = append(revoked, conn)
+		}
+	}
+	if len(revoked) == 0 {
+		return allowed
+	}
+	h.mu.Lock()
+	for _, conn := range revoked {
+		h.leave(conn, channel)
+	}
+	h.mu.Unlock()
+	if err := h.syncSubscription(ctx, channel); err != nil {
+		log.Printf("failed to unsubscribe from %s: %v", channel, err)
+	}
+	for _, conn := range revoked {
+		conn.Push(dto.RealtimeEventDTO{Type: dto.RealtimeUnsubscribed, CID: &cid})
+	}
+	return allowed
+}
+
+// join 接続にチャンネルを購読させる。h.muを取得して呼び出し、h.muを解放した後にsyncSubscriptionを呼び出す
+func (h *realtimeHub) join(conn *RealtimeConnection, channel string) {
+	if conn.channels[channel] {
+		return
+	}
+	subscribers := h.channels[channel]
+	if subscribers == nil {
+		subscribers = make(map[*RealtimeConnection]struct{})
+		h.channels[channel] = subscribers
+	}
+	subscribers[conn] = struct{}{}
+	conn.channels[channel] = true
+}
+
+// leave 接続のチャンネルの購読を解除する。h.muを取得して呼び出し、h.muを解放した後にsyncSubscriptionを呼び出す
+func (h *realtimeHub) leave(conn *RealtimeConnection, channel string) {
+	if !conn.channels[channel] {
+		return
+	}
+	delete(conn.channels, channel)
+	subscribers := h.channels[channel]
+	delete(subscribers, conn)
+	if len(subscribers) == 0 {
+		delete(h.channels, channel)
+	}
+}
+
+// syncSubscription チャンネルを購読中の接続の有無に合わせて、Redisのチャンネルを購読または解除する。
+// Redisとの通信中もイベントを配信できるよう、h.muを持たずに呼び出す
+func (h *realtimeHub) syncSubscription(ctx context.Context, channel string) error {
+	if h.pubsub == nil {
+		return nil
+	}
+	h.subMu.Lock()
+	defer h.subMu.Unlock()
+	h.mu.Lock()
+	want := len(h.channels[channel]) > 0
+	h.mu.Unlock()
+	switch {
+	case want && !h.subscribed[channel]:
+		if err := h.pubsub.Subscribe(ctx, channel); err != nil {
+			return err
+		}
+		h.subscribed[channel] = true
+	case !want && h.subscribed[channel]:
+		if err := h.pubsub.Unsubscribe(ctx, channel); err != nil {
+			return err
+		}
+		delete(h.subscribed, channel)
+	}
+	return nil
+}
+
+// RealtimeConnection ユーザーの1つのWebSocketの接続。送信を待つイベントを保持し、購読中のクラスのイベントを受け取る
+type RealtimeConnection struct {
+	hub *realtimeHub
+	uid uint
+	// ready 送信を待つイベントが追加されたことを知らせる
+	ready chan struct{}
+	// channels 購読中のチャンネル。hub.muで保護する
+	channels map[string]bool
+
+	mu      sync.Mutex
+	pending []dto.RealtimeEventDTO
+	dropped int
+	closed  bool
+}
+
+// UID 接続したユーザーのID
+func (c *RealtimeConnection) UID() uint {
+	return c.uid
+}
+
+// Subscribe クラスのイベントを購読する。申請者を含むメンバー以外はErrUnauthorized、購読数が上限に達している場合はErrRealtimeTopicLimitを返す。
+// 購読中にメンバーでなくなった場合は、次にクラスのイベントを配信するときに購読を解除してunsubscribedを送る
+func (c *RealtimeConnection) Subscribe(ctx context.Context, cid uint) error {
+	role, err := c.hub.classUserRepo.GetRole(ctx, c.uid, cid)
+	if err != nil || (role != "ADMIN" && role != "ASSISTANT" && role != "USER") {
+		return ErrUnauthorized
+	}
+	channel := fmt.Sprintf(realtimeClassChannel, cid)
+	c.hub.mu.Lock()
+	if _, ok := c.hub.connections[c]; !ok {
+		c.hub.mu.Unlock()
+		return nil
+	}
+	// channelsにはユーザー宛てのチャンネルも含まれる
+	if !c.channels[channel] && len(c.channels)-1 >= c.hub.config.MaxTopics {
+		c.hub.mu.Unlock()
+		return ErrRealtimeTopicLimit
+	}
+	c.hub.join(c, channel)
+	c.hub.mu.Unlock()
+
+	if err := c.hub.syncSubscription(ctx, channel); err != nil {
+		c.hub.mu.Lock()
+		c.hub.leave(c, channel)
+		c.hub.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// Unsubscribe クラスのイベントの購読を解除する
+func (c *RealtimeConnection) Unsubscribe(cid uint) {
+	channel := fmt.Sprintf(realtimeClassChannel, cid)
+	c.hub.mu.Lock()
+	c.hub.leave(c, channel)
+	c.hub.mu.Unlock()
+	if err := c.hub.syncSubscription(context.Background(), channel); err != nil {
+		log.Printf("failed to unsubscribe from %s: %v", channel, err)
+	}
+}
+
+// Push イベントの送信を予約する。送信を待つイベントが上限に達している場合は最も古いイベントを破棄し、
+// 次に取り出すときにクライアントに取得し直すよう伝える
+func (c *RealtimeConnection) Push(event dto.RealtimeEventDTO) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return
+	}
+	if len(c.pending) >= c.hub.config.SendBuffer {
+		c.pending = c.pending[1:]
+		c.dropped++
+	}
+	c.pending = append(c.pending, event)
+	select {
+	case c.ready <- struct{}{}:
+	default:
+	}
+}
+
+// Ready 送信を待つイベントが追加されると値を送るチャンネル
+func (c *RealtimeConnection) Ready() <-chan struct{} {
+	return c.ready
+}
+
+// Drain 送信を待つイベントを全て取り出す。イベントを破棄していた場合は、先頭に破棄した件数を持つresyncのイベントを含める
+func (c *RealtimeConnection) Drain() []dto.RealtimeEventDTO {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	events := make([]dto.RealtimeEventDTO, 0, len(c.pending)+1)
+	if c.dropped > 0 {
+		data, _ := json.Marshal(dto.RealtimeResyncDTO{Dropped: c.dropped})
+		events = append(events, dto.RealtimeEventDTO{Type: dto.RealtimeResync, Data: data})
+		c.dropped = 0
+	}
+	events = append(events, c.pending...)
+	c.pending = nil
+	return events
+}
+
+// Close 全ての購読を解除し、接続の登録を削除する
+func (c *RealtimeConnection) Close() {
+	c.mu.Lock()
+	c.closed = true
+	c.pending = nil
+	c.mu.Unlock()
+
+	c.hub.mu.Lock()
+	channels := make([]string, 0, len(c.channels))
+	for channel := range c.channels {
+		channels = append(channels, channel)
+		c.hub.leave(c, channel)
+	}
+	delete(c.hub.connections, c)
+	c.hub.mu.Unlock()
+
+	for _, channel := range channels {
+		if err := c.hub.syncSubscription(context.Background(), channel); err != nil {
+			log.Printf("failed to unsubscribe from %s: %v", channel, err)
+		}
+	}
+}
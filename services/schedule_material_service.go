@@ -0,0 +1,277 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/utils"
+	"gorm.io/gorm"
+)
+
+// ScheduleMaterialService スケジュールの予習資料と振り返りアンケートのサービス。
+// 資料の作成と削除、アンケートの集計はクラスの管理者とアシスタント、アンケートへの回答は生徒のみ利用できる
+type ScheduleMaterialService interface {
+	GetMaterials(ctx context.Context, viewerUID uint, csid uint) ([]dto.ScheduleMaterialDTO, error)
+	CreateMaterial(ctx context.Context, viewerUID uint, csid uint, request dto.ScheduleMaterialCreateDTO) (*dto.ScheduleMaterialDTO, error)
+	DeleteMaterial(ctx context.Context, viewerUID uint, csid uint, materialID uint) error
+	SubmitSurveyResponse(ctx context.Context, viewerUID uint, csid uint, materialID uint, request dto.SurveyResponseRequest) error
+	GetSurveySummary(ctx context.Context, viewerUID uint, csid uint, materialID uint) (*dto.SurveySummaryDTO, error)
+	NotifyEndedSurveys(ctx context.Context) (int, error)
+}
+
+// scheduleMaterialService インタフェースを実装
+type scheduleMaterialService struct {
+	repo          repositories.ScheduleMaterialRepository
+	scheduleRepo  repositories.ClassScheduleRepository
+	classUserRepo repositories.ClassUserRepository
+	notifier      Notifier
+}
+
+// NewScheduleMaterialService ScheduleMaterialServiceを生成
+func NewScheduleMaterialService(repo repositories.ScheduleMaterialRepository, scheduleRepo repositories.ClassScheduleRepository, classUserRepo repositories.ClassUserRepository, notifier Notifier) ScheduleMaterialService {
+	return &scheduleMaterialService{
+		repo:          repo,
+		scheduleRepo:  scheduleRepo,
+		classUserRepo: classUserRepo,
+		notifier:      notifier,
+	}
+}
+
+// GetMaterials スケジュールの資料を授業前、授業後の順に取得する。生徒には授業が終了するまで振り返りアンケートを表示しない
+func (s *scheduleMaterialService) GetMaterials(ctx context.Context, viewerUID uint, csid uint) ([]dto.ScheduleMaterialDTO, error) {
+	schedule, role, err := s.authorize(ctx, viewerUID, csid)
+	if err != nil {
+		return nil, err
+	}
+	materials, err := s.repo.FindBySchedule(ctx, csid)
+	if err != nil {
+		return nil, err
+	}
+
+	ended := !schedule.EndedAt.After(time.Now())
+	result := make([]dto.ScheduleMaterialDTO, 0, len(materials))
+	for _, material := range materials {
+		if material.Phase == models.PostClassPhase && role == "USER" && !ended {
+			continue
+		}
+		result = append(result, toMaterialDTO(material))
+	}
+	return result, nil
+}
+
+// CreateMaterial スケジュールに資料を紐付ける。授業後の資料は授業の終了後に生徒へ振り返りアンケートとして通知する
+func (s *scheduleMaterialService) CreateMaterial(ctx context.Context, viewerUID uint, csid uint, request dto.ScheduleMaterialCreateDTO) (*dto.ScheduleMaterialDTO, error) {
+	if _, err := s.authorizeStaff(ctx, viewerUID, csid); err != nil {
+		return nil, err
+	}
+
+	material := models.ScheduleMaterial{
+		CSID:    csid,
+		UID:     viewerUID,
+		Phase:   models.MaterialPhase(request.Phase),
+		Title:   request.Title,
+		Content: request.Content,
+		URL:     request.URL,
+	}
+	if err := s.repo.Create(ctx, &material); err != nil {
+		return nil, err
+	}
+	result := toMaterialDTO(material)
+	return &result, nil
+}
+
+// DeleteMaterial 資料を削除する。振り返りアンケートの場合は回答も削除される
+func (s *scheduleMaterialService) DeleteMaterial(ctx context.Context, viewerUID uint, csid uint, materialID uint) error {
+	if _, err := s.authorizeStaff(ctx, viewerUID, csid); err != nil {
+		return err
+	}
+	if _, err := s.findMaterial(ctx, csid, materialID); err != nil {
+		return err
+	}
+	return s.repo.Delete(ctx, materialID)
+}
+
+// SubmitSurveyResponse 振り返りアンケートに回答する。授業の終了後のみ回答でき、再回答すると以前の回答を上書きする
+func (s *scheduleMaterialService) SubmitSurveyResponse(ctx context.Context, viewerUID uint, csid uint, materialID uint, request dto.SurveyResponseRequest) error {
+	schedule, role, err := s.authorize(ctx, viewerUID, csid)
+	if err != nil {
+		return err
+	}
+	if role != "USER" {
+		return ErrUnauthorized
+	}
+	material, err := s.findMaterial(ctx, csid, materialID)
+	if err != nil {
+		return err
+	}
+	if material.Phase != models.PostClassPhase {
+		return ErrNotFound
+	}
+	if schedule.IsCancelled || schedule.EndedAt.After(time.Now()) {
+		return ErrSurveyNotOpen
+	}
+
+	return s.repo.SaveResponse(ctx, &models.ScheduleSurveyResponse{
+		MaterialID:    materialID,
+		UID:           viewerUID,
+		Understanding: request.Understanding,
+		Reflection:    request.Reflection,
+	})
+}
+
+// GetSurveySummary 振り返りアンケートの回答数と回答率、理解度の平均と分布、振り返りの自由記述を返す
+func (s *scheduleMaterialService) GetSurveySummary(ctx context.Context, viewerUID uint, csid uint, materialID uint) (*dto.SurveySummaryDTO, error) {
+	schedule, err := s.authorizeStaff(ctx, viewerUID, csid)
+	if err != nil {
+		return nil, err
+	}
+	material, err := s.findMaterial(ctx, csid, materialID)
+	if err != nil {
+		return nil, err
+	}
+	if material.Phase != models.PostClassPhase {
+		return nil, ErrNotFound
+	}
+
+	counts, err := s.repo.CountResponsesByUnderstanding(ctx, materialID)
+	if err != nil {
+		return nil, err
+	}
+	reflections, err := s.repo.FindReflections(ctx, materialID)
+	if err != nil {
+		return nil, err
+	}
+	students, err := s.classUserRepo.GetClassMembers(ctx, schedule.CID, "USER")
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &dto.SurveySummaryDTO{
+		MaterialID:          materialID,
+		StudentCount:        int64(len(students)),
+		UnderstandingCounts: make(map[int]int64, 5),
+		Reflections:         make([]dto.SurveyReflectionDTO, 0, len(reflections)),
+	}
+	var total int64
+	for understanding := 1; understanding <= 5; understanding++ {
+		summary.UnderstandingCounts[understanding] = counts[understanding]
+		summary.ResponseCount += counts[understanding]
+		total += int64(understanding) * counts[understanding]
+	}
+	if summary.ResponseCount > 0 {
+		summary.AverageUnderstanding = float64(total) / float64(summary.ResponseCount)
+	}
+	if summary.StudentCount > 0 {
+		summary.ResponseRate = float64(summary.ResponseCount) / float64(summary.StudentCount)
+	}
+	for _, row := range reflections {
+		summary.Reflections = append(summary.Reflections, dto.SurveyReflectionDTO{
+			UID:           row.UID,
+			Nickname:      row.Nickname,
+			Understanding: row.Understanding,
+			Reflection:    row.Reflection,
+			UpdatedAt:     row.UpdatedAt,
+		})
+	}
+	return summary, nil
+}
+
+// NotifyEndedSurveys 授業が終了したスケジュールの振り返りアンケートをクラスの生徒に通知し、通知したアンケートの数を返す。
+// 複数のインスタンスで実行しても同じアンケートを重複して通知しないよう、通知の前に通知済みとして記録できたアンケートのみ通知する。
+// そのため一部の生徒への通知に失敗しても同じアンケートは繰り返し通知しない
+func (s *scheduleMaterialService) NotifyEndedSurveys(ctx context.Context) (int, error) {
+	now := time.Now()
+	surveys, err := s.repo.FindPendingSurveys(ctx, now)
+	if err != nil {
+		return 0, err
+	}
+
+	notified := 0
+	for _, survey := range surveys {
+		students, err := s.classUserRepo.GetClassMembers(ctx, survey.CID, "USER")
+		if err != nil {
+			utils.ReportBackgroundError("notify_schedule_surveys", fmt.Errorf("failed to load students of class %d: %w", survey.CID, err))
+			continue
+		}
+		claimed, err := s.repo.ClaimSurveyNotification(ctx, survey.MaterialID, now)
+		if err != nil {
+			utils.ReportBackgroundError("notify_schedule_surveys", fmt.Errorf("failed to mark survey %d as notified: %w", survey.MaterialID, err))
+			continue
+		}
+		if !claimed {
+			// 他のインスタンスが通知済み
+			continue
+		}
+		body := fmt.Sprintf("「%s」の振り返りアンケート「%s」に回答してください。", survey.ScheduleTitle, survey.Title)
+		for _, student := range students {
+			if err := s.notifier.Notify(ctx, student.Uid, survey.CID, "振り返りアンケート", body); err != nil {
+				utils.ReportBackgroundError("notify_schedule_surveys", fmt.Errorf("failed to notify survey %d to uid %d: %w", survey.MaterialID, student.Uid, err))
+			}
+		}
+		notified++
+	}
+	return notified, nil
+}
+
+// authorize スケジュールのクラスのメンバーであることを確認し、スケジュールとロールを返す。参加申請中とブロック済みのユーザーはメンバーとしない
+func (s *scheduleMaterialService) authorize(ctx context.Context, viewerUID uint, csid uint) (*models.ClassSchedule, string, error) {
+	schedule, err := s.scheduleRepo.GetClassScheduleByID(ctx, csid)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, "", ErrNotFound
+		}
+		return nil, "", err
+	}
+	role, err := s.classUserRepo.GetRole(ctx, viewerUID, schedule.CID)
+	if err != nil || !isActiveMemberRole(role) {
+		return nil, "", ErrUnauthorized
+	}
+	return schedule, role, nil
+}
+
+// authorizeStaff スケジュールのクラスの管理者またはアシスタントであることを確認し、スケジュールを返す
+func (s *scheduleMaterialService) authorizeStaff(ctx context.Context, viewerUID uint, csid uint) (*models.ClassSchedule, error) {
+	schedule, role, err := s.authorize(ctx, viewerUID, csid)
+	if err != nil {
+		return nil, err
+	}
+	if role != "ADMIN" && role != "ASSISTANT" {
+		return nil, ErrUnauthorized
+	}
+	return schedule, nil
+}
+
+// findMaterial スケジュールの資料を取得する。別のスケジュールの資料はErrNotFoundとする
+func (s *scheduleMaterialService) findMaterial(ctx context.Context, csid uint, materialID uint) (*models.ScheduleMaterial, error) {
+	material, err := s.repo.FindByID(ctx, materialID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if material.CSID != csid {
+		return nil, ErrNotFound
+	}
+	return material, nil
+}
+
+// toMaterialDTO 資料をDTOに変換する
+func toMaterialDTO(material models.ScheduleMaterial) dto.ScheduleMaterialDTO {
+	return dto.ScheduleMaterialDTO{
+		ID:               material.ID,
+		CSID:             material.CSID,
+		Phase:            string(material.Phase),
+		Title:            material.Title,
+		Content:          material.Content,
+		URL:              material.URL,
+		SurveyNotifiedAt: material.SurveyNotifiedAt,
+		CreatedAt:        material.CreatedAt,
+		UpdatedAt:        material.UpdatedAt,
+	}
+}
@@ -0,0 +1,167 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+	"github.com/go-redis/redis/v8"
+	"gorm.io/gorm"
+)
+
+// scheduleNotificationResource スケジュールに関するアプリ内通知の対象の種類
+const scheduleNotificationResource = "class_schedule"
+
+// scheduleReminderCooldown 同じスケジュールの未読のメンバーに再通知できる間隔
+const scheduleReminderCooldown = 10 * time.Minute
+
+// ScheduleNotifier スケジュールに関する通知を送信する
+type ScheduleNotifier interface {
+	NotifySchedule(ctx context.Context, uid uint, classSchedule *models.ClassSchedule, title string, body string) error
+}
+
+// ScheduleNotificationService スケジュールに関する通知を送信し、通知を読んだメンバーの数を集計するサービス
+type ScheduleNotificationService interface {
+	ScheduleNotifier
+	GetScheduleNotificationStats(ctx context.Context, uid uint, csid uint) (*dto.ScheduleNotificationStatsDTO, error)
+	RemindUnread(ctx context.Context, uid uint, csid uint) (*dto.ScheduleNotificationReminderDTO, error)
+}
+
+// scheduleNotificationService インタフェースを実装
+type scheduleNotificationService struct {
+	notifications NotificationService
+	repo          repositories.NotificationRepository
+	scheduleRepo  repositories.ClassScheduleRepository
+	classUserRepo repositories.ClassUserRepository
+	// unread クラスの未読通知の件数を記録する。nilの場合は記録しない
+	unread UnreadService
+	// redisClient 再通知の間隔を制限する。nilの場合は制限しない
+	redisClient *redis.Client
+}
+
+// NewScheduleNotificationService ScheduleNotificationServiceを生成
+func NewScheduleNotificationService(notifications NotificationService, repo repositories.NotificationRepository, scheduleRepo repositories.ClassScheduleRepository, classUserRepo repositories.ClassUserRepository, unread UnreadService, redisClient *redis.Client) ScheduleNotificationService {
+	return &scheduleNotificationService{
+		notifications: notifications,
+		repo:          repo,
+		scheduleRepo:  scheduleRepo,
+		classUserRepo: classUserRepo,
+		unread:        unread,
+		redisClient:   redisClient,
+	}
+}
+
+// NotifySchedule スケジュールを対象にしたアプリ内通知を保存し、クラスの未読通知を1件増やす。未読件数の記録に失敗しても通知は失敗にしない
+func (s *scheduleNotificationService) NotifySchedule(ctx context.Context, uid uint, classSchedule *models.ClassSchedule, title string, body string) error {
+	csid, cid := classSchedule.ID, classSchedule.CID
+	if err := s.notifications.Publish(ctx, models.Notification{
+		UID:          uid,
+		Type:         models.ScheduleChangedNotification,
+		Title:        title,
+		Body:         body,
+		ResourceType: scheduleNotificationResource,
+		ResourceID:   &csid,
+		CID:          &cid,
+	}); err != nil {
+		return err
+	}
+	if s.unread != nil {
+		if err := s.unread.RecordNotification(ctx, uid, cid); err != nil {
+			log.Printf("Redis error while counting unread notification for uid %d: %v", uid, err)
+		}
+	}
+	return nil
+}
+
+// GetScheduleNotificationStats スケジュールに関する通知を受け取ったメンバーと読んだメンバーの数を返す。クラスの講師・アシスタントのみ取得できる。
+// 既読の通知は保存期間を過ぎると削除されるため、集計は保存期間内の通知が対象になる
+func (s *scheduleNotificationService) GetScheduleNotificationStats(ctx context.Context, uid uint, csid uint) (*dto.ScheduleNotificationStatsDTO, error) {
+	if _, err := s.authorizeInstructor(ctx, uid, csid); err != nil {
+		return nil, err
+	}
+
+	delivered, read, err := s.repo.CountResourceRecipients(ctx, scheduleNotificationResource, csid)
+	if err != nil {
+		return nil, err
+	}
+	stats := &dto.ScheduleNotificationStatsDTO{CSID: csid, Delivered: delivered, Read: read, Unread: delivered - read}
+	if delivered > 0 {
+		stats.OpenRate = float64(read) / float64(delivered)
+	}
+	return stats, nil
+}
+
+// RemindUnread スケジュールに関する通知を1件も読んでいないクラスのメンバーに再通知し、再通知した人数を返す。
+// クラスの講師・アシスタントのみ送信でき、同じスケジュールへの再通知は10分に1回までにする
+func (s *scheduleNotificationService) RemindUnread(ctx context.Context, uid uint, csid uint) (*dto.ScheduleNotificationReminderDTO, error) {
+	classSchedule, err := s.authorizeInstructor(ctx, uid, csid)
+	if err != nil {
+		return nil, err
+	}
+
+	uids, err := s.repo.FindUnreadResourceRecipients(ctx, scheduleNotificationResource, csid)
+	if err != nil {
+		return nil, err
+	}
+	// クラスを退出したメンバーには再通知しない
+	members, err := s.classUserRepo.GetClassMembers(ctx, classSchedule.CID, "ADMIN", "ASSISTANT", "USER")
+	if err != nil {
+		return nil, err
+	}
+	active := make(map[uint]bool, len(members))
+	for _, member := range members {
+		active[member.Uid] = true
+	}
+
+	if s.redisClient != nil {
+		ok, err := s.redisClient.SetNX(ctx, fmt.Sprintf("schedule_notification_reminder:%d", csid), uid, scheduleReminderCooldown).Result()
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, ErrReminderCooldown
+		}
+	}
+
+	title := "未読のお知らせがあります"
+	body := fmt.Sprintf("%sの授業「%s」に関するお知らせをご確認ください。", classSchedule.StartedAt.Format("2006-01-02 15:04"), classSchedule.Title)
+	result := &dto.ScheduleNotificationReminderDTO{}
+	for _, recipient := range uids {
+		if recipient == uid || !active[recipient] {
+			continue
+		}
+		if err := s.NotifySchedule(ctx, recipient, classSchedule, title, body); err != nil {
+			return result, err
+		}
+		result.Reminded++
+	}
+	return result, nil
+}
+
+// authorizeInstructor スケジュールを取得し、ユーザーがスケジュールのクラスの講師・アシスタントであることを確認する
+func (s *scheduleNotificationService) authorizeInstructor(ctx context.Context, uid uint, csid uint) (*models.ClassSchedule, error) {
+	classSchedule, err := s.scheduleRepo.GetClassScheduleByID(ctx, csid)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	role, err := s.classUserRepo.GetRole(ctx, uid, classSchedule.CID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrUnauthorized
+	}
+	if err != nil {
+		return nil, err
+	}
+	if role != "ADMIN" && role != "ASSISTANT" {
+		return nil, ErrUnauthorized
+	}
+	return classSchedule, nil
+}
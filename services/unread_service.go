@@ -0,0 +1,194 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+	"github.com/go-redis/redis/v8"
+)
+
+// unreadKey ユーザーごとの未読件数のハッシュ。フィールドは「クラスID:種類」
+const unreadKey = "unread:%d"
+
+// UnreadKind 未読件数の種類
+type UnreadKind string
+
+const (
+	UnreadBoards        UnreadKind = "boards"
+	UnreadChat          UnreadKind = "chat"
+	UnreadNotifications UnreadKind = "notifications"
+	UnreadMentions      UnreadKind = "mentions"
+)
+
+// UnreadKinds 全ての未読件数の種類
+var UnreadKinds = []UnreadKind{UnreadBoards, UnreadChat, UnreadNotifications, UnreadMentions}
+
+// decrementUnreadScript 未読件数を1減らす。0以下にはせず、0になったフィールドは削除する
+var decrementUnreadScript = redis.NewScript(`
+local count = tonumber(redis.call('HGET', KEYS[1], ARGV[1]) or '0')
+if count <= 1 then
+	redis.call('HDEL', KEYS[1], ARGV[1])
+	return 0
+end
+return redis.call('HINCRBY', KEYS[1], ARGV[1], -1)
+`)
+
+// UnreadService ユーザーが参加するクラスの未読件数をRedisのカウンタで管理するサービス
+type UnreadService interface {
+	GetUnreadSummary(ctx context.Context, uid uint) (*dto.UnreadSummaryDTO, error)
+	RecordBoardPost(ctx context.Context, board models.ClassBoard) error
+	RecordBoardRead(ctx context.Context, uid uint, cid uint) error
+	RecordChatMessage(ctx context.Context, scheduleID uint, senderUID uint, text string) error
+	RecordNotification(ctx context.Context, uid uint, cid uint) error
+	MarkRead(ctx context.Context, uid uint, cid uint, kinds ...UnreadKind) error
+}
+
+// unreadService インタフェースを実装
+type unreadService struct {
+	classUserRepo repositories.ClassUserRepository
+	scheduleRepo  repositories.ClassScheduleRepository
+	redisClient   *redis.Client
+}
+
+// NewUnreadService UnreadServiceを生成
+func NewUnreadService(classUserRepo repositories.ClassUserRepository, scheduleRepo repositories.ClassScheduleRepository, redisClient *redis.Client) UnreadService {
+	return &unreadService{
+		classUserRepo: classUserRepo,
+		scheduleRepo:  scheduleRepo,
+		redisClient:   redisClient,
+	}
+}
+
+// GetUnreadSummary ユーザーが参加する全てのアーカイブされていないクラスの未読件数と合計を返す。
+// 未読件数は1回のHGETALLで取得し、退会したクラスの件数は含めない
+func (s *unreadService) GetUnreadSummary(ctx context.Context, uid uint) (*dto.UnreadSummaryDTO, error) {
+	// limitに-1を指定すると件数を制限しない
+	classes, err := s.classUserRepo.GetUserClasses(ctx, uid, 1, -1, repositories.ClassUserQueryOptions{})
+	if err != nil {
+		return nil, err
+	}
+	counts, err := s.redisClient.HGetAll(ctx, fmt.Sprintf(unreadKey, uid)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &dto.UnreadSummaryDTO{Classes: []dto.ClassUnreadDTO{}}
+	for _, class := range classes {
+		if !isActiveMemberRole(class.Role) {
+			continue
+		}
+		classCounts := dto.UnreadCountsDTO{
+			Boards:        unreadCount(counts, class.ID, UnreadBoards),
+			Chat:          unreadCount(counts, class.ID, UnreadChat),
+			Notifications: unreadCount(counts, class.ID, UnreadNotifications),
+			Mentions:      unreadCount(counts, class.ID, UnreadMentions),
+		}
+		classCounts.Total = classCounts.Boards + classCounts.Chat + classCounts.Notifications + classCounts.Mentions
+		summary.Classes = append(summary.Classes, dto.ClassUnreadDTO{CID: class.ID, Name: class.Name, Counts: classCounts})
+
+		summary.Total.Boards += classCounts.Boards
+		summary.Total.Chat += classCounts.Chat
+		summary.Total.Notifications += classCounts.Notifications
+		summary.Total.Mentions += classCounts.Mentions
+		summary.Total.Total += classCounts.Total
+	}
+	return summary, nil
+}
+
+// RecordBoardPost 投稿者以外のクラスのメンバーの未読掲示を1件増やす
+func (s *unreadService) RecordBoardPost(ctx context.Context, board models.ClassBoard) error {
+	members, err := s.classUserRepo.GetClassMembers(ctx, board.CID, "ADMIN", "ASSISTANT", "USER")
+	if err != nil {
+		return err
+	}
+	pipe := s.redisClient.Pipeline()
+	for _, member := range members {
+		if member.Uid != board.UID {
+			pipe.HIncrBy(ctx, fmt.Sprintf(unreadKey, member.Uid), unreadField(board.CID, UnreadBoards), 1)
+		}
+	}
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// RecordBoardRead ユーザーが掲示板を初めて閲覧した際に未読掲示を1件減らす
+func (s *unreadService) RecordBoardRead(ctx context.Context, uid uint, cid uint) error {
+	return decrementUnreadScript.Run(ctx, s.redisClient, []string{fmt.Sprintf(unreadKey, uid)}, unreadField(cid, UnreadBoards)).Err()
+}
+
+// RecordChatMessage スケジュールのチャットへの投稿を送信者以外のメンバーの未読チャットに加える。
+// 本文に「@ニックネーム」を含むメンバーはメンションも1件増やす
+func (s *unreadService) RecordChatMessage(ctx context.Context, scheduleID uint, senderUID uint, text string) error {
+	schedule, err := s.scheduleRepo.GetClassScheduleByID(ctx, scheduleID)
+	if err != nil {
+		return err
+	}
+	members, err := s.classUserRepo.GetClassMembers(ctx, schedule.CID, "ADMIN", "ASSISTANT", "USER")
+	if err != nil {
+		return err
+	}
+	pipe := s.redisClient.Pipeline()
+	for _, member := range members {
+		if member.Uid == senderUID {
+			continue
+		}
+		key := fmt.Sprintf(unreadKey, member.Uid)
+		pipe.HIncrBy(ctx, key, unreadField(schedule.CID, UnreadChat), 1)
+		if member.Nickname != "" && strings.Contains(text, "@"+member.Nickname) {
+			pipe.HIncrBy(ctx, key, unreadField(schedule.CID, UnreadMentions), 1)
+		}
+	}
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// RecordNotification ユーザーのクラスの未読通知を1件増やす
+func (s *unreadService) RecordNotification(ctx context.Context, uid uint, cid uint) error {
+	return s.redisClient.HIncrBy(ctx, fmt.Sprintf(unreadKey, uid), unreadField(cid, UnreadNotifications), 1).Err()
+}
+
+// MarkRead クラスの指定した種類の未読件数を0にする。種類を省略した場合は全ての種類を0にする
+func (s *unreadService) MarkRead(ctx context.Context, uid uint, cid uint, kinds ...UnreadKind) error {
+	if len(kinds) == 0 {
+		kinds = UnreadKinds
+	}
+	fields := make([]string, 0, len(kinds))
+	for _, kind := range kinds {
+		fields = append(fields, unreadField(cid, kind))
+	}
+	return s.redisClient.HDel(ctx, fmt.Sprintf(unreadKey, uid), fields...).Err()
+}
+
+// ParseUnreadKind 未読件数の種類を解析する
+func ParseUnreadKind(value string) (UnreadKind, bool) {
+	for _, kind := range UnreadKinds {
+		if string(kind) == value {
+			return kind, true
+		}
+	}
+	return "", false
+}
+
+// unreadField 未読件数のハッシュのフィールド名
+func unreadField(cid uint, kind UnreadKind) string {
+	return fmt.Sprintf("%d:%s", cid, kind)
+}
+
+// unreadCount ハッシュから未読件数を読み取る。未設定の場合は0
+func unreadCount(counts map[string]string, cid uint, kind UnreadKind) int64 {
+	count, err := strconv.ParseInt(counts[unreadField(cid, kind)], 10, 64)
+	if err != nil || count < 0 {
+		return 0
+	}
+	return count
+}
+
+// isActiveMemberRole 参加申請中とブロック済みを除くクラスのメンバーのロールか
+func isActiveMemberRole(role string) bool {
+	return role == "ADMIN" || role == "ASSISTANT" || role == "USER"
+}
@@ -0,0 +1,353 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/utils"
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	// UploadPartSize 最後のパート以外のパートのサイズ。S3の最小パートサイズ(5MB)以上にする
+	UploadPartSize int64 = 8 << 20
+	// maxUploadSize アップロードできるファイルの最大サイズ
+	maxUploadSize int64 = 5 << 30
+
+	uploadSessionTTL = 24 * time.Hour
+	uploadSessionKey = "upload_session:%s"
+	uploadPartsKey   = "upload_parts:%s"
+	// uploadExpiryKey 完了も中止もされていないアップロードのIDを、アップロードの状態の有効期限をスコアにして保持するソート済みセット
+	uploadExpiryKey = "upload_expiry"
+	// uploadTargetsKey アップロードのIDごとのS3のキーとアップロードID。アップロードの状態が期限切れになった後に中止するために使う
+	uploadTargetsKey = "upload_targets"
+	// expiredUploadBatchSize 1回の処理で中止する期限切れのアップロードの最大数
+	expiredUploadBatchSize = 100
+)
+
+var (
+	ErrInvalidUploadPart = errors.New("invalid upload part")
+	ErrUploadTooLarge    = errors.New("upload exceeds the maximum size")
+	ErrUploadIncomplete  = errors.New("upload has missing parts")
+)
+
+// UploadService 大容量ファイルのマルチパートアップロードと進捗を管理する
+type UploadService interface {
+	StartUpload(ctx context.Context, uid uint, request dto.StartUploadRequest) (*dto.UploadProgressDTO, error)
+	UploadPart(ctx context.Context, uid uint, uploadID string, partNumber int32, body io.Reader, size int64) (*dto.UploadProgressDTO, error)
+	GetProgress(ctx context.Context, uid uint, uploadID string) (*dto.UploadProgressDTO, error)
+	CompleteUpload(ctx context.Context, uid uint, uploadID string) (*dto.UploadCompleteDTO, error)
+	AbortUpload(ctx context.Context, uid uint, uploadID string) error
+	AbortExpiredUploads(ctx context.Context, now time.Time) (int, error)
+}
+
+// uploadTarget 期限切れのアップロードを中止するためのS3のキーとアップロードID
+type uploadTarget struct {
+	Key        string `json:"key"`
+	S3UploadID string `json:"s3_upload_id"`
+}
+
+// uploadSession Redisに保存するアップロードの状態
+type uploadSession struct {
+	ID          string `json:"id"`
+	UID         uint   `json:"uid"`
+	CID         uint   `json:"cid"`
+	Key         string `json:"key"`
+	S3UploadID  string `json:"s3_upload_id"`
+	FileName    string `json:"file_name"`
+	ContentType string `json:"content_type"`
+	TotalBytes  int64  `json:"total_bytes"`
+	PartSize    int64  `json:"part_size"`
+}
+
+// totalParts ファイル全体のパート数
+func (s *uploadSession) totalParts() int32 {
+	return int32((s.TotalBytes + s.PartSize - 1) / s.PartSize)
+}
+
+// expectedPartSize 指定されたパート番号のパートのサイズ。最後のパートのみ残りのバイト数になる
+func (s *uploadSession) expectedPartSize(partNumber int32) int64 {
+	if partNumber == s.totalParts() {
+		return s.TotalBytes - int64(partNumber-1)*s.PartSize
+	}
+	return s.PartSize
+}
+
+type uploadService struct {
+	uploader      utils.MultipartUploader
+	classUserRepo repositories.ClassUserRepository
+	redisClient   *redis.Client
+}
+
+// NewUploadService UploadServiceを生成
+func NewUploadService(uploader utils.MultipartUploader, classUserRepo repositories.ClassUserRepository, redisClient *redis.Client) UploadService {
+	return &uploadService{
+		uploader:      uploader,
+		classUserRepo: classUserRepo,
+		redisClient:   redisClient,
+	}
+}
+
+// StartUpload クラスの管理者・アシスタントがマルチパートアップロードを開始する
+func (s *uploadService) StartUpload(ctx context.Context, uid uint, request dto.StartUploadRequest) (*dto.UploadProgressDTO, error) {
+	if request.Size > maxUploadSize {
+		return nil, ErrUploadTooLarge
+	}
+
+	role, err := s.classUserRepo.GetRole(ctx, uid, request.CID)
+	if err != nil || (role != "ADMIN" && role != "ASSISTANT") {
+		return nil, ErrUnauthorized
+	}
+
+	id, err := generateUploadID()
+	if err != nil {
+		return nil, err
+	}
+
+	extension := filepath.Ext(request.FileName)
+	key := fmt.Sprintf("files/%d/%s-%d%s", request.CID, strings.TrimSuffix(filepath.Base(request.FileName), extension), time.Now().Unix(), extension)
+	s3UploadID, err := s.uploader.CreateMultipartUpload(ctx, key, request.ContentType)
+	if err != nil {
+		return nil, err
+	}
+
+	session := &uploadSession{
+		ID:          id,
+		UID:         uid,
+		CID:         request.CID,
+		Key:         key,
+		S3UploadID:  s3UploadID,
+		FileName:    request.FileName,
+		ContentType: request.ContentType,
+		TotalBytes:  request.Size,
+		PartSize:    UploadPartSize,
+	}
+	data, err := json.Marshal(session)
+	if err != nil {
+		return nil, err
+	}
+	target, err := json.Marshal(uploadTarget{Key: key, S3UploadID: s3UploadID})
+	if err != nil {
+		return nil, err
+	}
+	pipe := s.redisClient.TxPipeline()
+	pipe.Set(ctx, fmt.Sprintf(uploadSessionKey, id), data, uploadSessionTTL)
+	pipe.HSet(ctx, uploadTargetsKey, id, target)
+	pipe.ZAdd(ctx, uploadExpiryKey, &redis.Z{Score: float64(time.Now().Add(uploadSessionTTL).Unix()), Member: id})
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	return toUploadProgressDTO(session, nil), nil
+}
+
+// UploadPart パートをアップロードし、進捗を記録する。同じパート番号を再送した場合は上書きする
+func (s *uploadService) UploadPart(ctx context.Context, uid uint, uploadID string, partNumber int32, body io.Reader, size int64) (*dto.UploadProgressDTO, error) {
+	session, err := s.getSession(ctx, uid, uploadID)
+	if err != nil {
+		return nil, err
+	}
+	if partNumber < 1 || partNumber > session.totalParts() || size != session.expectedPartSize(partNumber) {
+		return nil, ErrInvalidUploadPart
+	}
+
+	etag, err := s.uploader.UploadPart(ctx, session.Key, session.S3UploadID, partNumber, body, size)
+	if err != nil {
+		return nil, err
+	}
+
+	part, err := json.Marshal(utils.UploadedPart{PartNumber: partNumber, ETag: etag, Size: size})
+	if err != nil {
+		return nil, err
+	}
+	partsKey := fmt.Sprintf(uploadPartsKey, uploadID)
+	if err := s.redisClient.HSet(ctx, partsKey, strconv.Itoa(int(partNumber)), part).Err(); err != nil {
+		return nil, err
+	}
+	s.redisClient.Expire(ctx, partsKey, uploadSessionTTL)
+
+	parts, err := s.getParts(ctx, uploadID)
+	if err != nil {
+		return nil, err
+	}
+	return toUploadProgressDTO(session, parts), nil
+}
+
+// GetProgress アップロード済みのバイト数とパートを取得する
+func (s *uploadService) GetProgress(ctx context.Context, uid uint, uploadID string) (*dto.UploadProgressDTO, error) {
+	session, err := s.getSession(ctx, uid, uploadID)
+	if err != nil {
+		return nil, err
+	}
+	parts, err := s.getParts(ctx, uploadID)
+	if err != nil {
+		return nil, err
+	}
+	return toUploadProgressDTO(session, parts), nil
+}
+
+// CompleteUpload 全てのパートが揃っている場合にアップロードを完了する
+func (s *uploadService) CompleteUpload(ctx context.Context, uid uint, uploadID string) (*dto.UploadCompleteDTO, error) {
+	session, err := s.getSession(ctx, uid, uploadID)
+	if err != nil {
+		return nil, err
+	}
+	parts, err := s.getParts(ctx, uploadID)
+	if err != nil {
+		return nil, err
+	}
+	if int32(len(parts)) != session.totalParts() {
+		return nil, ErrUploadIncomplete
+	}
+
+	url, err := s.uploader.CompleteMultipartUpload(ctx, session.Key, session.S3UploadID, parts)
+	if err != nil {
+		return nil, err
+	}
+	s.deleteSession(ctx, uploadID)
+
+	return &dto.UploadCompleteDTO{UploadID: uploadID, URL: url}, nil
+}
+
+// AbortUpload アップロードを中止し、アップロード済みのパートを破棄する
+func (s *uploadService) AbortUpload(ctx context.Context, uid uint, uploadID string) error {
+	session, err := s.getSession(ctx, uid, uploadID)
+	if err != nil {
+		return err
+	}
+	if err := s.uploader.AbortMultipartUpload(ctx, session.Key, session.S3UploadID); err != nil {
+		return err
+	}
+	s.deleteSession(ctx, uploadID)
+	return nil
+}
+
+// AbortExpiredUploads アップロードの状態の有効期限を過ぎたアップロードをS3で中止し、アップロード済みのパートを破棄する。
+// 中止したアップロード数を返す。複数のインスタンスで実行しても、ソート済みセットから削除できたインスタンスのみが中止する
+func (s *uploadService) AbortExpiredUploads(ctx context.Context, now time.Time) (int, error) {
+	ids, err := s.redisClient.ZRangeByScore(ctx, uploadExpiryKey, &redis.ZRangeBy{
+		Min:   "-inf",
+		Max:   strconv.FormatInt(now.Unix(), 10),
+		Count: expiredUploadBatchSize,
+	}).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	aborted := 0
+	for _, id := range ids {
+		claimed, err := s.redisClient.ZRem(ctx, uploadExpiryKey, id).Result()
+		if err != nil {
+			return aborted, err
+		}
+		if claimed == 0 {
+			continue
+		}
+		data, err := s.redisClient.HGet(ctx, uploadTargetsKey, id).Bytes()
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		if err != nil {
+			return aborted, err
+		}
+		var target uploadTarget
+		if err := json.Unmarshal(data, &target); err != nil {
+			s.redisClient.HDel(ctx, uploadTargetsKey, id)
+			continue
+		}
+		if err := s.uploader.AbortMultipartUpload(ctx, target.Key, target.S3UploadID); err != nil {
+			// 次回の処理で中止し直す
+			s.redisClient.ZAdd(ctx, uploadExpiryKey, &redis.Z{Score: float64(now.Unix()), Member: id})
+			return aborted, fmt.Errorf("aborting upload %s: %w", id, err)
+		}
+		s.deleteSession(ctx, id)
+		aborted++
+	}
+	return aborted, nil
+}
+
+// getSession アップロードの状態を取得する。他のユーザーのアップロードは存在しないものとして扱う
+func (s *uploadService) getSession(ctx context.Context, uid uint, uploadID string) (*uploadSession, error) {
+	data, err := s.redisClient.Get(ctx, fmt.Sprintf(uploadSessionKey, uploadID)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var session uploadSession
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, err
+	}
+	if session.UID != uid {
+		return nil, ErrNotFound
+	}
+	return &session, nil
+}
+
+// getParts アップロード済みのパートをパート番号順に取得する
+func (s *uploadService) getParts(ctx context.Context, uploadID string) ([]utils.UploadedPart, error) {
+	values, err := s.redisClient.HVals(ctx, fmt.Sprintf(uploadPartsKey, uploadID)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	parts := make([]utils.UploadedPart, 0, len(values))
+	for _, value := range values {
+		var part utils.UploadedPart
+		if err := json.Unmarshal([]byte(value), &part); err != nil {
+			return nil, err
+		}
+		parts = append(parts, part)
+	}
+	sort.Slice(parts, func(i, j int) bool { return parts[i].PartNumber < parts[j].PartNumber })
+	return parts, nil
+}
+
+// deleteSession アップロードの状態と、期限切れのアップロードを中止するための記録を削除する
+func (s *uploadService) deleteSession(ctx context.Context, uploadID string) {
+	pipe := s.redisClient.TxPipeline()
+	pipe.Del(ctx, fmt.Sprintf(uploadSessionKey, uploadID), fmt.Sprintf(uploadPartsKey, uploadID))
+	pipe.HDel(ctx, uploadTargetsKey, uploadID)
+	pipe.ZRem(ctx, uploadExpiryKey, uploadID)
+	_, _ = pipe.Exec(ctx)
+}
+
+// toUploadProgressDTO アップロードの状態を進捗DTOに変換する
+func toUploadProgressDTO(session *uploadSession, parts []utils.UploadedPart) *dto.UploadProgressDTO {
+	progress := &dto.UploadProgressDTO{
+		UploadID:       session.ID,
+		FileName:       session.FileName,
+		TotalBytes:     session.TotalBytes,
+		PartSize:       session.PartSize,
+		TotalParts:     session.totalParts(),
+		CompletedParts: make([]int32, 0, len(parts)),
+	}
+	for _, part := range parts {
+		progress.UploadedBytes += part.Size
+		progress.CompletedParts = append(progress.CompletedParts, part.PartNumber)
+	}
+	return progress
+}
+
+// generateUploadID ランダムなアップロードIDを生成する
+func generateUploadID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
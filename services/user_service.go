@@ -1,32 +1,51 @@
 package services
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"time"
 
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+	"github.com/go-redis/redis/v8"
+	"gorm.io/gorm"
 )
 
 const ErrUserNotFound = "user not found"
 
+const (
+	userProfileCacheKey = "user:profile:%d"
+	userProfileCacheTTL = 5 * time.Minute
+	// DeletedUserName 削除されたユーザーのプロフィールに表示する名前
+	DeletedUserName = "Deleted User"
+)
+
 type UserService interface {
-	GetApplyingClasses(userID uint) ([]models.ClassUser, error)
-	SearchUsersByName(name string) ([]models.User, error)
-	RemoveUserFromService(userID uint) error
+	GetApplyingClasses(ctx context.Context, userID uint) ([]models.ClassUser, error)
+	SearchUsersByName(ctx context.Context, name string) ([]models.User, error)
+	RemoveUserFromService(ctx context.Context, userID uint) error
+	GetUserProfiles(ctx context.Context, userIDs []uint) (map[uint]dto.UserProfileDTO, error)
+	GetAccessibilitySettings(ctx context.Context, userID uint) (dto.AccessibilitySettingsDTO, error)
+	UpdateAccessibilitySettings(ctx context.Context, userID uint, request dto.AccessibilitySettingsUpdateDTO) (dto.AccessibilitySettingsDTO, error)
 }
 
 type userServiceImpl struct {
-	userRepo repositories.UserRepository
+	userRepo    repositories.UserRepository
+	redisClient *redis.Client
 }
 
-func NewCreateUserService(userRepo repositories.UserRepository) UserService {
+func NewCreateUserService(userRepo repositories.UserRepository, redisClient *redis.Client) UserService {
 	return &userServiceImpl{
-		userRepo: userRepo,
+		userRepo:    userRepo,
+		redisClient: redisClient,
 	}
 }
 
-func (s *userServiceImpl) GetApplyingClasses(userID uint) ([]models.ClassUser, error) {
-	exists, err := s.userRepo.UserExists(userID)
+func (s *userServiceImpl) GetApplyingClasses(ctx context.Context, userID uint) ([]models.ClassUser, error) {
+	exists, err := s.userRepo.UserExists(ctx, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -34,13 +53,123 @@ func (s *userServiceImpl) GetApplyingClasses(userID uint) ([]models.ClassUser, e
 		return nil, errors.New(ErrUserNotFound)
 	}
 
-	return s.userRepo.GetApplyingClasses(userID)
+	return s.userRepo.GetApplyingClasses(ctx, userID)
+}
+
+func (s *userServiceImpl) SearchUsersByName(ctx context.Context, name string) ([]models.User, error) {
+	return s.userRepo.FindByName(ctx, name)
+}
+
+func (s *userServiceImpl) RemoveUserFromService(ctx context.Context, userID uint) error {
+	if err := s.userRepo.DeleteUser(ctx, userID); err != nil {
+		return err
+	}
+	// 削除後もキャッシュ済みのプロフィールが表示され続けないようにする
+	s.redisClient.Del(ctx, fmt.Sprintf(userProfileCacheKey, userID))
+	return nil
 }
 
-func (s *userServiceImpl) SearchUsersByName(name string) ([]models.User, error) {
-	return s.userRepo.FindByName(name)
+// GetUserProfiles ユーザーの公開プロフィールをまとめて取得する。プロフィールはRedisにキャッシュし、キャッシュに無いユーザーのみDBから取得する。
+// 削除されたユーザーは名前をDeletedUserName、画像をnilとして返す
+func (s *userServiceImpl) GetUserProfiles(ctx context.Context, userIDs []uint) (map[uint]dto.UserProfileDTO, error) {
+	profiles := make(map[uint]dto.UserProfileDTO, len(userIDs))
+	if len(userIDs) == 0 {
+		return profiles, nil
+	}
+
+	keys := make([]string, len(userIDs))
+	for i, uid := range userIDs {
+		keys[i] = fmt.Sprintf(userProfileCacheKey, uid)
+	}
+	// Redisに接続できない場合は全てDBから取得する
+	cached, _ := s.redisClient.MGet(ctx, keys...).Result()
+	var missing []uint
+	for i, uid := range userIDs {
+		var profile dto.UserProfileDTO
+		if i < len(cached) {
+			if data, ok := cached[i].(string); ok && json.Unmarshal([]byte(data), &profile) == nil {
+				profiles[uid] = profile
+				continue
+			}
+		}
+		missing = append(missing, uid)
+	}
+	if len(missing) == 0 {
+		return profiles, nil
+	}
+
+	users, err := s.userRepo.GetUsersByIDs(ctx, missing)
+	if err != nil {
+		return nil, err
+	}
+	for _, user := range users {
+		image := user.Image
+		profiles[user.ID] = dto.UserProfileDTO{ID: user.ID, Name: user.Name, AvatarURL: &image}
+	}
+
+	pipe := s.redisClient.Pipeline()
+	for _, uid := range missing {
+		profile, ok := profiles[uid]
+		if !ok {
+			profile = dto.UserProfileDTO{ID: uid, Name: DeletedUserName}
+			profiles[uid] = profile
+		}
+		if data, err := json.Marshal(profile); err == nil {
+			pipe.Set(ctx, fmt.Sprintf(userProfileCacheKey, uid), data, userProfileCacheTTL)
+		}
+	}
+	_, _ = pipe.Exec(ctx)
+	return profiles, nil
 }
 
-func (s *userServiceImpl) RemoveUserFromService(userID uint) error {
-	return s.userRepo.DeleteUser(userID)
+// GetAccessibilitySettings ユーザーのアクセシビリティの設定を取得する。保存していない場合はデフォルトの設定を返す
+func (s *userServiceImpl) GetAccessibilitySettings(ctx context.Context, userID uint) (dto.AccessibilitySettingsDTO, error) {
+	setting, err := s.findAccessibilitySetting(ctx, userID)
+	if err != nil {
+		return dto.AccessibilitySettingsDTO{}, err
+	}
+	return toAccessibilitySettingsDTO(setting), nil
+}
+
+// UpdateAccessibilitySettings ユーザーのアクセシビリティの設定を変更する。省略した項目は現在の設定のままにする
+func (s *userServiceImpl) UpdateAccessibilitySettings(ctx context.Context, userID uint, request dto.AccessibilitySettingsUpdateDTO) (dto.AccessibilitySettingsDTO, error) {
+	setting, err := s.findAccessibilitySetting(ctx, userID)
+	if err != nil {
+		return dto.AccessibilitySettingsDTO{}, err
+	}
+	if request.FontSize != nil {
+		setting.FontSize = models.FontSize(*request.FontSize)
+	}
+	if request.HighContrast != nil {
+		setting.HighContrast = *request.HighContrast
+	}
+	if request.ReduceMotion != nil {
+		setting.ReduceMotion = *request.ReduceMotion
+	}
+	setting.UpdatedAt = time.Now()
+	if err := s.userRepo.SaveAccessibilitySetting(ctx, &setting); err != nil {
+		return dto.AccessibilitySettingsDTO{}, err
+	}
+	return toAccessibilitySettingsDTO(setting), nil
+}
+
+// findAccessibilitySetting 保存済みのアクセシビリティの設定を取得する。保存していない場合はデフォルトの設定を返す
+func (s *userServiceImpl) findAccessibilitySetting(ctx context.Context, userID uint) (models.AccessibilitySetting, error) {
+	setting, err := s.userRepo.FindAccessibilitySetting(ctx, userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return models.DefaultAccessibilitySetting(userID), nil
+	}
+	if err != nil {
+		return models.AccessibilitySetting{}, err
+	}
+	return *setting, nil
+}
+
+// toAccessibilitySettingsDTO アクセシビリティの設定をDTOに変換する
+func toAccessibilitySettingsDTO(setting models.AccessibilitySetting) dto.AccessibilitySettingsDTO {
+	return dto.AccessibilitySettingsDTO{
+		FontSize:     string(setting.FontSize),
+		HighContrast: setting.HighContrast,
+		ReduceMotion: setting.ReduceMotion,
+	}
 }
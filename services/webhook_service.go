@@ -0,0 +1,511 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/utils"
+	"gorm.io/gorm"
+)
+
+const (
+	// WebhookDeliveryLogSize Webhookごとに残す送信の記録の件数
+	WebhookDeliveryLogSize = 50
+
+	webhookQueueSize   = 1024
+	webhookWorkerCount = 4
+	// webhookMaxAttempts 1つのイベントを送信する最大の回数。全て失敗すると連続の失敗の件数を増やす
+	webhookMaxAttempts         = 5
+	webhookStepTimeout         = 30 * time.Second
+	defaultWebhookRetryDelay   = 10 * time.Second
+	defaultWebhookFailureLimit = 10
+	webhookSecretBytes         = 32
+	webhookErrorLimit          = 500
+)
+
+// WebhookConfig クラスのイベントをWebhookで配信する設定
+type WebhookConfig struct {
+	// Sender Webhookの送信処理。nilの場合は配信しない
+	Sender utils.WebhookSender
+	// RetryDelay 送信に失敗したイベントを最初に再送するまでの時間。再送するたびに2倍にする。0の場合は10秒
+	RetryDelay time.Duration
+	// FailureLimit Webhookを無効にする、再送しても配信できなかったイベントの連続の件数。0の場合は10件
+	FailureLimit int
+}
+
+// WebhookPublisher クラスのイベントをクラスのWebhookに配信する。
+// 配信は非同期で行い、失敗しても元の操作は失敗させないため、エラーは報告するのみとする
+type WebhookPublisher interface {
+	PublishWebhook(ctx context.Context, cid uint, event models.WebhookEvent, payload dto.WebhookPayloadDTO)
+}
+
+// WebhookService クラスのWebhookの管理と配信を行うサービス。Webhookの管理と送信の記録の閲覧はクラスの管理者のみ利用できる
+type WebhookService interface {
+	WebhookPublisher
+	GetWebhooks(ctx context.Context, viewerUID uint, cid uint) ([]dto.WebhookDTO, error)
+	CreateWebhook(ctx context.Context, viewerUID uint, cid uint, request dto.WebhookRequest) (*dto.WebhookDTO, error)
+	UpdateWebhook(ctx context.Context, viewerUID uint, cid uint, id uint, request dto.WebhookRequest) (*dto.WebhookDTO, error)
+	DeleteWebhook(ctx context.Context, viewerUID uint, cid uint, id uint) error
+	GetDeliveries(ctx context.Context, viewerUID uint, cid uint, id uint) ([]dto.WebhookDeliveryDTO, error)
+}
+
+// webhookService インタフェースを実装
+type webhookService struct {
+	repo          repositories.WebhookRepository
+	classUserRepo repositories.ClassUserRepository
+	// notifier 連続の失敗でWebhookを無効にしたことをクラスの管理者に知らせる
+	notifier Notifier
+	config   WebhookConfig
+	jobs     chan webhookJob
+}
+
+// webhookJob 1つのWebhookへの1回の送信
+type webhookJob struct {
+	webhook    models.Webhook
+	event      models.WebhookEvent
+	deliveryID string
+	body       []byte
+	attempt    int
+}
+
+// NewWebhookService WebhookServiceを生成する。送信処理がある場合は、配信を行うワーカーを開始する
+func NewWebhookService(repo repositories.WebhookRepository, classUserRepo repositories.ClassUserRepository, notifier Notifier, config WebhookConfig) WebhookService {
+	if config.RetryDelay == 0 {
+		config.RetryDelay = defaultWebhookRetryDelay
+	}
+	if config.FailureLimit == 0 {
+		config.FailureLimit = defaultWebhookFailureLimit
+	}
+	s := &webhookService{
+		repo:          repo,
+		classUserRepo: classUserRepo,
+		notifier:      notifier,
+		config:        config,
+	}
+	if config.Sender != nil {
+		s.jobs = make(chan webhookJob, webhookQueueSize)
+		for i := 0; i < webhookWorkerCount; i++ {
+			go s.runWorker()
+		}
+	}
+	return s
+}
+
+// GetWebhooks クラスのWebhookを登録順に取得する。秘密鍵は含めない
+func (s *webhookService) GetWebhooks(ctx context.Context, viewerUID uint, cid uint) ([]dto.WebhookDTO, error) {
+	if err := s.authorize(ctx, viewerUID, cid); err != nil {
+		return nil, err
+	}
+	webhooks, err := s.repo.FindWebhooksByClass(ctx, cid)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]dto.WebhookDTO, 0, len(webhooks))
+	for _, webhook := range webhooks {
+		result = append(result, toWebhookDTO(webhook, false))
+	}
+	return result, nil
+}
+
+// CreateWebhook クラスにWebhookを登録する。秘密鍵を省略した場合は生成し、登録したWebhookと共に1度だけ返す
+func (s *webhookService) CreateWebhook(ctx context.Context, viewerUID uint, cid uint, request dto.WebhookRequest) (*dto.WebhookDTO, error) {
+	if err := s.authorize(ctx, viewerUID, cid); err != nil {
+		return nil, err
+	}
+	if err := validateWebhookURL(request.URL); err != nil {
+		return nil, err
+	}
+	secret := request.Secret
+	if secret == "" {
+		generated, err := generateWebhookSecret()
+		if err != nil {
+			return nil, err
+		}
+		secret = generated
+	}
+
+	webhook := models.Webhook{
+		CID:     cid,
+		URL:     request.URL,
+		Secret:  secret,
+		Events:  joinWebhookEvents(request.Events),
+		Enabled: request.Enabled == nil || *request.Enabled,
+	}
+	if err := s.repo.CreateWebhook(ctx, &webhook); err != nil {
+		return nil, err
+	}
+	result := toWebhookDTO(webhook, true)
+	return &result, nil
+}
+
+// UpdateWebhook Webhookの送信先とイベントを更新する。秘密鍵を指定した場合は変更して返し、
+// 無効のWebhookを有効にした場合は連続の失敗の件数を0に戻す
+func (s *webhookService) UpdateWebhook(ctx context.Context, viewerUID uint, cid uint, id uint, request dto.WebhookRequest) (*dto.WebhookDTO, error) {
+	if err := s.authorize(ctx, viewerUID, cid); err != nil {
+		return nil, err
+	}
+	if err := validateWebhookURL(request.URL); err != nil {
+		return nil, err
+	}
+	webhook, err := s.findWebhook(ctx, cid, id)
+	if err != nil {
+		return nil, err
+	}
+
+	webhook.URL = request.URL
+	webhook.Events = joinWebhookEvents(request.Events)
+	if request.Secret != "" {
+		webhook.Secret = request.Secret
+	}
+	if request.Enabled != nil {
+		if *request.Enabled && !webhook.Enabled {
+			webhook.ConsecutiveFailures = 0
+			webhook.DisabledAt = nil
+		}
+		webhook.Enabled = *request.Enabled
+	}
+	if err := s.repo.UpdateWebhook(ctx, webhook); err != nil {
+		return nil, err
+	}
+	result := toWebhookDTO(*webhook, request.Secret != "")
+	return &result, nil
+}
+
+// DeleteWebhook Webhookを削除する。送信の記録も削除され、再送を待つイベントは送信しない
+func (s *webhookService) DeleteWebhook(ctx context.Context, viewerUID uint, cid uint, id uint) error {
+	if err := s.authorize(ctx, viewerUID, cid); err != nil {
+		return err
+	}
+	if _, err := s.findWebhook(ctx, cid, id); err != nil {
+		return err
+	}
+	return s.repo.DeleteWebhook(ctx, id)
+}
+
+// GetDeliveries Webhookの最近の送信の記録を新しい順に取得する。再送もそれぞれ1件として記録する
+func (s *webhookService) GetDeliveries(ctx context.Context, viewerUID uint, cid uint, id uint) ([]dto.WebhookDeliveryDTO, error) {
+	if err := s.authorize(ctx, viewerUID, cid); err != nil {
+		return nil, err
+	}
+	if _, err := s.findWebhook(ctx, cid, id); err != nil {
+		return nil, err
+	}
+	deliveries, err := s.repo.FindDeliveries(ctx, id, WebhookDeliveryLogSize)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]dto.WebhookDeliveryDTO, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		result = append(result, toWebhookDeliveryDTO(delivery))
+	}
+	return result, nil
+}
+
+// PublishWebhook イベントを配信するクラスの有効なWebhookを取得し、送信を予約する。
+// 送信は非同期で行うため、送信の結果を待たない。キューが一杯の場合はリクエストを待たせずに破棄する
+func (s *webhookService) PublishWebhook(ctx context.Context, cid uint, event models.WebhookEvent, payload dto.WebhookPayloadDTO) {
+	if s.jobs == nil {
+		return
+	}
+	webhooks, err := s.repo.FindEnabledWebhooks(ctx, cid)
+	if err != nil {
+		utils.ReportBackgroundError("publish_webhook", fmt.Errorf("failed to find webhooks of class %d: %w", cid, err))
+		return
+	}
+	var targets []models.Webhook
+	for _, webhook := range webhooks {
+		if webhook.Subscribes(event) {
+			targets = append(targets, webhook)
+		}
+	}
+	if len(targets) == 0 {
+		return
+	}
+
+	deliveryID, err := generateWebhookDeliveryID()
+	if err != nil {
+		utils.ReportBackgroundError("publish_webhook", err)
+		return
+	}
+	payload.ID = deliveryID
+	payload.Event = string(event)
+	payload.Version = dto.WebhookPayloadVersion
+	payload.CID = cid
+	payload.OccurredAt = time.Now()
+	body, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("failed to encode %s webhook payload: %v", event, err)
+		return
+	}
+	for _, webhook := range targets {
+		s.enqueue(webhookJob{webhook: webhook, event: event, deliveryID: deliveryID, body: body, attempt: 1})
+	}
+}
+
+// enqueue 送信を予約する。キューが一杯の場合は破棄する
+func (s *webhookService) enqueue(job webhookJob) {
+	select {
+	case s.jobs <- job:
+	default:
+		log.Printf("Webhook queue is full. Dropped %s event %s to webhook %d", job.event, job.deliveryID, job.webhook.ID)
+	}
+}
+
+// runWorker 予約された送信を順に行う
+func (s *webhookService) runWorker() {
+	for job := range s.jobs {
+		s.deliver(job)
+	}
+}
+
+// deliver Webhookに1回送信して記録する。失敗した場合は間隔を空けて再送を予約し、
+// 最大の回数まで失敗した場合は連続の失敗の件数を増やす。再送を待つ間はワーカーを占有しない
+func (s *webhookService) deliver(job webhookJob) {
+	started := time.Now()
+	var status int
+	err := withWebhookTimeout(func(ctx context.Context) (err error) {
+		status, err = s.config.Sender.Send(ctx, utils.WebhookMessage{
+			URL:        job.webhook.URL,
+			Secret:     job.webhook.Secret,
+			Event:      string(job.event),
+			DeliveryID: job.deliveryID,
+			Version:    dto.WebhookPayloadVersion,
+			Body:       job.body,
+		})
+		return err
+	})
+	s.recordDelivery(job, status, err, time.Since(started))
+
+	if err == nil {
+		if job.webhook.ConsecutiveFailures > 0 || job.attempt > 1 {
+			if resetErr := withWebhookTimeout(func(ctx context.Context) error {
+				return s.repo.ResetWebhookFailures(ctx, job.webhook.ID)
+			}); resetErr != nil {
+				log.Printf("Failed to reset failures of webhook %d: %v", job.webhook.ID, resetErr)
+			}
+		}
+		return
+	}
+	if job.attempt < webhookMaxAttempts {
+		delay := s.config.RetryDelay << (job.attempt - 1)
+		job.attempt++
+		time.AfterFunc(delay, func() { s.retry(job) })
+		return
+	}
+	s.recordFailure(job)
+}
+
+// retry Webhookを読み直して再送を予約する。削除または無効にされたWebhookには再送しない
+func (s *webhookService) retry(job webhookJob) {
+	var webhook *models.Webhook
+	err := withWebhookTimeout(func(ctx context.Context) (err error) {
+		webhook, err = s.repo.FindWebhook(ctx, job.webhook.ID)
+		return err
+	})
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.ReportBackgroundError("deliver_webhook", fmt.Errorf("failed to reload webhook %d: %w", job.webhook.ID, err))
+		}
+		return
+	}
+	if !webhook.Enabled {
+		return
+	}
+	job.webhook = *webhook
+	s.enqueue(job)
+}
+
+// recordDelivery 送信の結果を記録する。記録に失敗しても配信は続ける
+func (s *webhookService) recordDelivery(job webhookJob, status int, sendErr error, duration time.Duration) {
+	delivery := models.WebhookDelivery{
+		WebhookID:  job.webhook.ID,
+		Event:      job.event,
+		DeliveryID: job.deliveryID,
+		Attempt:    job.attempt,
+		StatusCode: status,
+		DurationMS: duration.Milliseconds(),
+		Payload:    string(job.body),
+	}
+	if sendErr != nil {
+		delivery.Error = truncateWebhookError(sendErr.Error())
+	}
+	if err := withWebhookTimeout(func(ctx context.Context) error {
+		return s.repo.CreateDelivery(ctx, &delivery, WebhookDeliveryLogSize)
+	}); err != nil {
+		log.Printf("Failed to record delivery %s to webhook %d: %v", job.deliveryID, job.webhook.ID, err)
+	}
+}
+
+// recordFailure 連続の失敗の件数を増やし、上限に達した場合はWebhookを無効にしてクラスの管理者に知らせる
+func (s *webhookService) recordFailure(job webhookJob) {
+	err := withWebhookTimeout(func(ctx context.Context) error {
+		failures, err := s.repo.IncrementWebhookFailures(ctx, job.webhook.ID)
+		if err != nil || failures < s.config.FailureLimit {
+			return err
+		}
+		disabled, err := s.repo.DisableWebhook(ctx, job.webhook.ID, time.Now())
+		if err != nil || !disabled {
+			return err
+		}
+		s.notifyDisabled(ctx, job.webhook, failures)
+		return nil
+	})
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		utils.ReportBackgroundError("deliver_webhook", fmt.Errorf("failed to record failure of webhook %d: %w", job.webhook.ID, err))
+	}
+}
+
+// notifyDisabled Webhookを無効にしたことをクラスの管理者に知らせる
+func (s *webhookService) notifyDisabled(ctx context.Context, webhook models.Webhook, failures int) {
+	if s.notifier == nil {
+		return
+	}
+	admins, err := s.classUserRepo.GetClassMembers(ctx, webhook.CID, "ADMIN")
+	if err != nil {
+		utils.ReportBackgroundError("notify_webhook_disabled", fmt.Errorf("failed to load admins of class %d: %w", webhook.CID, err))
+		return
+	}
+	title := "Webhookを無効にしました"
+	body := fmt.Sprintf("%sへの配信に%d回連続で失敗したため、Webhookを無効にしました。送信の記録を確認し、送信先を修正してから有効にしてください。", webhook.URL, failures)
+	for _, admin := range admins {
+		if err := s.notifier.Notify(ctx, admin.Uid, webhook.CID, title, body); err != nil {
+			utils.ReportBackgroundError("notify_webhook_disabled", fmt.Errorf("failed to notify uid %d: %w", admin.Uid, err))
+		}
+	}
+}
+
+// authorize ユーザーがクラスの管理者か確認する
+func (s *webhookService) authorize(ctx context.Context, uid uint, cid uint) error {
+	isAdmin, err := s.classUserRepo.IsAdmin(ctx, uid, cid)
+	if err != nil || !isAdmin {
+		return ErrUnauthorized
+	}
+	return nil
+}
+
+// findWebhook クラスのWebhookを取得する。他のクラスのWebhookはErrNotFoundにする
+func (s *webhookService) findWebhook(ctx context.Context, cid uint, id uint) (*models.Webhook, error) {
+	webhook, err := s.repo.FindWebhook(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if webhook.CID != cid {
+		return nil, ErrNotFound
+	}
+	return webhook, nil
+}
+
+// validateWebhookURL 送信先がhttpまたはhttpsのURLか確認する
+func validateWebhookURL(rawURL string) error {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return ErrInvalidWebhookURL
+	}
+	return nil
+}
+
+// joinWebhookEvents イベントの種類を重複を除いてカンマ区切りにする
+func joinWebhookEvents(events []string) string {
+	seen := make(map[string]bool, len(events))
+	unique := make([]string, 0, len(events))
+	for _, event := range events {
+		if !seen[event] {
+			seen[event] = true
+			unique = append(unique, event)
+		}
+	}
+	return strings.Join(unique, ",")
+}
+
+// generateWebhookSecret ランダムな署名の秘密鍵を生成する
+func generateWebhookSecret() (string, error) {
+	b := make([]byte, webhookSecretBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// generateWebhookDeliveryID ランダムなイベントのIDを生成する
+func generateWebhookDeliveryID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// truncateWebhookError 送信の記録に保存できる長さにエラーを切り詰める
+func truncateWebhookError(message string) string {
+	if len(message) <= webhookErrorLimit {
+		return message
+	}
+	end := webhookErrorLimit
+	for end > 0 && !utf8.RuneStart(message[end]) {
+		end--
+	}
+	return message[:end]
+}
+
+// withWebhookTimeout Webhookの配信の1つの処理にタイムアウトを設けて実行する
+func withWebhookTimeout(fn func(ctx context.Context) error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), webhookStepTimeout)
+	defer cancel()
+	return fn(ctx)
+}
+
+// toWebhookDTO WebhookをDTOに変換する。includeSecretの場合のみ秘密鍵を含める
+func toWebhookDTO(webhook models.Webhook, includeSecret bool) dto.WebhookDTO {
+	events := make([]string, 0)
+	for _, event := range webhook.EventList() {
+		events = append(events, string(event))
+	}
+	result := dto.WebhookDTO{
+		ID:                  webhook.ID,
+		CID:                 webhook.CID,
+		URL:                 webhook.URL,
+		Events:              events,
+		Enabled:             webhook.Enabled,
+		ConsecutiveFailures: webhook.ConsecutiveFailures,
+		DisabledAt:          webhook.DisabledAt,
+		CreatedAt:           webhook.CreatedAt,
+		UpdatedAt:           webhook.UpdatedAt,
+	}
+	if includeSecret {
+		result.Secret = webhook.Secret
+	}
+	return result
+}
+
+// toWebhookDeliveryDTO 送信の記録をDTOに変換する
+func toWebhookDeliveryDTO(delivery models.WebhookDelivery) dto.WebhookDeliveryDTO {
+	result := dto.WebhookDeliveryDTO{
+		ID:         delivery.ID,
+		Event:      string(delivery.Event),
+		DeliveryID: delivery.DeliveryID,
+		Attempt:    delivery.Attempt,
+		StatusCode: delivery.StatusCode,
+		Error:      delivery.Error,
+		DurationMS: delivery.DurationMS,
+		CreatedAt:  delivery.CreatedAt,
+	}
+	if err := json.Unmarshal([]byte(delivery.Payload), &result.Payload); err != nil {
+		log.Printf("Failed to decode payload of webhook delivery %d: %v", delivery.ID, err)
+	}
+	return result
+}
@@ -0,0 +1,81 @@
+package tests
+
+import (
+	"context"
+	"testing"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"gorm.io/gorm"
+)
+
+// accessibilityUserRepo はユーザーごとのアクセシビリティの設定をメモリに保存するUserRepositoryです。
+type accessibilityUserRepo struct {
+	repositories.UserRepository
+	settings map[uint]models.AccessibilitySetting
+}
+
+func (r *accessibilityUserRepo) FindAccessibilitySetting(_ context.Context, userID uint) (*models.AccessibilitySetting, error) {
+	setting, ok := r.settings[userID]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &setting, nil
+}
+
+func (r *accessibilityUserRepo) SaveAccessibilitySetting(_ context.Context, setting *models.AccessibilitySetting) error {
+	r.settings[setting.UID] = *setting
+	return nil
+}
+
+// TestAccessibilitySettings は保存していないユーザーにはデフォルトの設定を返し、変更した項目のみ上書きすることを確認するテストです。
+func TestAccessibilitySettings(t *testing.T) {
+	large, on, off := "large", true, false
+	saved := map[uint]models.AccessibilitySetting{
+		2: {UID: 2, FontSize: models.FontSizeExtraLarge, HighContrast: true},
+	}
+
+	cases := []struct {
+		name    string
+		uid     uint
+		request *dto.AccessibilitySettingsUpdateDTO
+		want    dto.AccessibilitySettingsDTO
+	}{
+		{"Default", 1, nil, dto.AccessibilitySettingsDTO{FontSize: "medium"}},
+		{"Saved", 2, nil, dto.AccessibilitySettingsDTO{FontSize: "x-large", HighContrast: true}},
+		{"Update unsaved", 1, &dto.AccessibilitySettingsUpdateDTO{ReduceMotion: &on}, dto.AccessibilitySettingsDTO{FontSize: "medium", ReduceMotion: true}},
+		{"Update only given fields", 2, &dto.AccessibilitySettingsUpdateDTO{FontSize: &large, HighContrast: &off}, dto.AccessibilitySettingsDTO{FontSize: "large"}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := &accessibilityUserRepo{settings: map[uint]models.AccessibilitySetting{}}
+			for uid, setting := range saved {
+				repo.settings[uid] = setting
+			}
+			service := services.NewCreateUserService(repo, nil)
+
+			var got dto.AccessibilitySettingsDTO
+			var err error
+			if tc.request == nil {
+				got, err = service.GetAccessibilitySettings(context.Background(), tc.uid)
+			} else {
+				got, err = service.UpdateAccessibilitySettings(context.Background(), tc.uid, *tc.request)
+			}
+			if err != nil {
+				t.Fatalf("err = %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("settings = %+v, want %+v", got, tc.want)
+			}
+			if tc.request == nil {
+				return
+			}
+			if reloaded, _ := service.GetAccessibilitySettings(context.Background(), tc.uid); reloaded != tc.want {
+				t.Errorf("reloaded settings = %+v, want %+v", reloaded, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,228 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/migration"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"gorm.io/gorm"
+)
+
+// limitClassUserRepo はアクティブなクラス数と削除済みのメンバーを固定で返し、参加を記録するClassUserRepositoryです。
+type limitClassUserRepo struct {
+	repositories.ClassUserRepository
+	member      bool
+	removed     *models.ClassUser
+	activeCount int64
+	joined      bool
+}
+
+func (r *limitClassUserRepo) FindMembersByUIDs(_ context.Context, cid uint, uids []uint) ([]models.ClassUser, error) {
+	if !r.member {
+		return nil, nil
+	}
+	return []models.ClassUser{{CID: cid, UID: uids[0], Role: "USER"}}, nil
+}
+
+func (r *limitClassUserRepo) RoleExists(context.Context, uint, uint) (bool, error) {
+	return r.member, nil
+}
+
+func (r *limitClassUserRepo) FindRemovedMember(context.Context, uint, uint) (*models.ClassUser, error) {
+	if r.removed == nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return r.removed, nil
+}
+
+func (r *limitClassUserRepo) CountActiveClasses(context.Context, uint) (int64, error) {
+	return r.activeCount, nil
+}
+
+func (r *limitClassUserRepo) CreateUserRole(_ context.Context, uid uint, cid uint, role string) (*models.ClassUser, bool, error) {
+	r.joined = true
+	return &models.ClassUser{CID: cid, UID: uid, Role: role}, true, nil
+}
+
+func (r *limitClassUserRepo) UpdateJoinedViaCode(context.Context, uint, uint, uint) error {
+	return nil
+}
+
+// limitUserRepo はユーザーごとの上限を設定したユーザーを返し、行をロックしたかを記録するUserRepositoryです。
+type limitUserRepo struct {
+	repositories.UserRepository
+	maxActiveClasses *int
+	locked           bool
+}
+
+func (r *limitUserRepo) FindByID(_ context.Context, userID uint) (*models.User, error) {
+	return &models.User{ID: userID, MaxActiveClasses: r.maxActiveClasses}, nil
+}
+
+func (r *limitUserRepo) LockByID(ctx context.Context, userID uint) (*models.User, error) {
+	r.locked = true
+	return r.FindByID(ctx, userID)
+}
+
+// limitTxManager はlimitClassUserRepoとlimitUserRepoでfnを実行するTxManagerです。
+type limitTxManager struct {
+	classUserRepo *limitClassUserRepo
+	userRepo      *limitUserRepo
+}
+
+func (m *limitTxManager) WithinTransaction(_ context.Context, fn func(repos repositories.RepositorySet) error) error {
+	return fn(repositories.RepositorySet{ClassUser: m.classUserRepo, User: m.userRepo, TxManager: m})
+}
+
+// TestAssignRoleViaCodeActiveClassLimit はアクティブなクラス数が上限に達したユーザーの新規参加が拒否され、
+// 既にメンバーの場合はロールを変更せずに既存のメンバー情報を返し、新規参加ではユーザーの行をロックしてから数えることを確認するテストです。
+func TestAssignRoleViaCodeActiveClassLimit(t *testing.T) {
+	intPtr := func(v int) *int { return &v }
+
+	cases := []struct {
+		name        string
+		globalLimit int
+		userLimit   *int
+		member      bool
+		activeCount int64
+		wantErr     error
+		wantJoined  bool
+	}{
+		{"Under Global Limit", 3, nil, false, 2, nil, true},
+		{"Global Limit Reached", 3, nil, false, 3, services.ErrActiveClassLimit, false},
+		{"No Global Limit", 0, nil, false, 100, nil, true},
+		{"User Limit Overrides Global", 3, intPtr(10), false, 5, nil, true},
+		{"User Limit Reached", 10, intPtr(2), false, 2, services.ErrActiveClassLimit, false},
+		{"User Unlimited", 3, intPtr(0), false, 50, nil, true},
+		{"Already Member", 3, nil, true, 3, nil, false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			classUserRepo := &limitClassUserRepo{member: tc.member, activeCount: tc.activeCount}
+			userRepo := &limitUserRepo{maxActiveClasses: tc.userLimit}
+			txManager := &limitTxManager{classUserRepo: classUserRepo, userRepo: userRepo}
+			service := services.NewClassUserService(txManager, classUserRepo, nil, nil, nil, userRepo, nil, tc.globalLimit, nil, nil, nil)
+
+			classUser, joined, err := service.AssignRoleViaCode(context.Background(), 1, 2, "APPLICANT", 3)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tc.wantErr)
+			}
+			if joined != tc.wantJoined || classUserRepo.joined != tc.wantJoined {
+				t.Errorf("joined = %v (repo %v), want %v", joined, classUserRepo.joined, tc.wantJoined)
+			}
+			if tc.member && (classUser == nil || classUser.Role != "USER") {
+				t.Errorf("classUser = %+v, want existing member with role USER", classUser)
+			}
+			if userRepo.locked == tc.member {
+				t.Errorf("locked = %v, want %v", userRepo.locked, !tc.member)
+			}
+		})
+	}
+}
+
+// TestAssignRoleActiveClassLimit は管理者が未参加のユーザーを追加する場合も、ユーザーの行をロックして
+// アクティブなクラス数の上限を確認し、上限に達したユーザーを追加しないことを確認するテストです。
+func TestAssignRoleActiveClassLimit(t *testing.T) {
+	intPtr := func(v int) *int { return &v }
+
+	cases := []struct {
+		name        string
+		globalLimit int
+		userLimit   *int
+		activeCount int64
+		wantErr     error
+	}{
+		{"Under Global Limit", 3, nil, 2, nil},
+		{"Global Limit Reached", 3, nil, 3, services.ErrActiveClassLimit},
+		{"User Limit Reached", 10, intPtr(2), 2, services.ErrActiveClassLimit},
+		{"User Unlimited", 3, intPtr(0), 50, nil},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			classUserRepo := &limitClassUserRepo{activeCount: tc.activeCount}
+			userRepo := &limitUserRepo{maxActiveClasses: tc.userLimit}
+			txManager := &limitTxManager{classUserRepo: classUserRepo, userRepo: userRepo}
+			service := services.NewClassUserService(txManager, classUserRepo, nil, nil, nil, userRepo, nil, tc.globalLimit, nil, nil, nil)
+
+			err := service.AssignRole(context.Background(), 1, 2, "USER")
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tc.wantErr)
+			}
+			if classUserRepo.joined != (tc.wantErr == nil) || !userRepo.locked {
+				t.Errorf("joined = %v, locked = %v, want joined %v after locking", classUserRepo.joined, userRepo.locked, tc.wantErr == nil)
+			}
+		})
+	}
+}
+
+// TestAssignRoleViaCodeConcurrentOnDatabase は同じユーザーが複数のクラスに同時に参加しても、
+// アクティブなクラス数の上限を超えて参加しないことを確認するテストです。
+func TestAssignRoleViaCodeConcurrentOnDatabase(t *testing.T) {
+	db := openTestDB(t)
+	migration.Migrate(db)
+	ctx := context.Background()
+
+	limit := 1
+	owner := models.User{PID: "active-class-race-owner", Name: "active-class-race-owner"}
+	user := models.User{PID: "active-class-race-test", Name: "active-class-race-test", MaxActiveClasses: &limit}
+	for _, u := range []*models.User{&owner, &user} {
+		if err := db.Create(u).Error; err != nil {
+			t.Fatalf("failed to create user: %v", err)
+		}
+	}
+	const classCount = 5
+	classes := make([]models.Class, classCount)
+	codes := make([]models.ClassCode, classCount)
+	for i := range classes {
+		classes[i] = models.Class{Name: "active-class-race-test", UID: owner.ID}
+		if err := db.Create(&classes[i]).Error; err != nil {
+			t.Fatalf("failed to create class: %v", err)
+		}
+		codes[i] = models.ClassCode{Code: "RACE", CID: classes[i].ID, UID: owner.ID}
+		if err := db.Create(&codes[i]).Error; err != nil {
+			t.Fatalf("failed to create class code: %v", err)
+		}
+	}
+	t.Cleanup(func() {
+		db.Unscoped().Where("uid = ?", user.ID).Delete(&models.ClassUser{})
+		db.Delete(&codes)
+		db.Unscoped().Delete(&classes)
+		db.Delete(&[]models.User{owner, user})
+	})
+
+	service := services.NewClassUserService(repositories.NewTxManager(db), repositories.NewClassUserRepository(db), nil, nil, nil, repositories.NewUserRepository(db), nil, 0, nil, nil, nil)
+	var wg sync.WaitGroup
+	errs := make([]error, classCount)
+	for i := range classes {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, _, errs[i] = service.AssignRoleViaCode(ctx, user.ID, classes[i].ID, "USER", codes[i].ID)
+		}(i)
+	}
+	wg.Wait()
+
+	joined := 0
+	for _, err := range errs {
+		switch {
+		case err == nil:
+			joined++
+		case !errors.Is(err, services.ErrActiveClassLimit):
+			t.Errorf("err = %v, want nil or %v", err, services.ErrActiveClassLimit)
+		}
+	}
+	count, err := repositories.NewClassUserRepository(db).CountActiveClasses(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("err = %v", err)
+	}
+	if joined != 1 || count != 1 {
+		t.Errorf("joined = %d, active classes = %d, want 1 and 1", joined, count)
+	}
+}
@@ -0,0 +1,268 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/migration"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/utils"
+)
+
+// memberClassUserRepo はメンバーかどうかのみを返すClassUserRepositoryです。
+type memberClassUserRepo struct {
+	repositories.ClassUserRepository
+	member bool
+}
+
+func (r *memberClassUserRepo) IsMember(context.Context, uint, uint) (bool, error) {
+	return r.member, nil
+}
+
+// subscriberUserRepo は指定したメールアドレスと検証状態のユーザーを返すUserRepositoryです。
+type subscriberUserRepo struct {
+	repositories.UserRepository
+	email    string
+	verified bool
+}
+
+func (r *subscriberUserRepo) FindByID(_ context.Context, uid uint) (*models.User, error) {
+	return &models.User{ID: uid, Email: r.email, EmailVerified: r.verified}, nil
+}
+
+// memorySubscriptionRepo は購読をメモリに保持するAnnouncementSubscriptionRepositoryです。
+type memorySubscriptionRepo struct {
+	repositories.AnnouncementSubscriptionRepository
+	subscriptions []models.AnnouncementSubscription
+	upserted      []models.AnnouncementSubscription
+}
+
+func (r *memorySubscriptionRepo) Upsert(_ context.Context, subscription *models.AnnouncementSubscription) error {
+	r.upserted = append(r.upserted, *subscription)
+	return nil
+}
+
+func (r *memorySubscriptionRepo) FindActiveByClass(_ context.Context, cid uint) ([]models.AnnouncementSubscription, error) {
+	var active []models.AnnouncementSubscription
+	for _, subscription := range r.subscriptions {
+		if subscription.CID == cid && subscription.DisabledAt == nil {
+			active = append(active, subscription)
+		}
+	}
+	return active, nil
+}
+
+func (r *memorySubscriptionRepo) UpdateDeliveryState(_ context.Context, id uint, failureCount int, disabledAt *time.Time) error {
+	for i := range r.subscriptions {
+		if r.subscriptions[i].ID == id {
+			r.subscriptions[i].FailureCount = failureCount
+			r.subscriptions[i].DisabledAt = disabledAt
+		}
+	}
+	return nil
+}
+
+// scriptedSender は送信先ごとに決められたエラーを返し、送信先を記録するNotificationSenderです。
+type scriptedSender struct {
+	errs map[string]error
+	sent []string
+}
+
+func (s *scriptedSender) Send(_ context.Context, target string, _ string, _ string) error {
+	s.sent = append(s.sent, target)
+	return s.errs[target]
+}
+
+// TestSubscribeAnnouncements は通知チャネルの登録時にメンバーとチャネルを検証し、
+// メールの送信先をユーザーの検証済みのメールアドレスにすることを確認するテストです。
+func TestSubscribeAnnouncements(t *testing.T) {
+	cases := []struct {
+		name     string
+		member   bool
+		channel  models.NotificationChannel
+		email    string
+		verified bool
+		wantErr  error
+	}{
+		{name: "Verified Email", member: true, channel: models.EmailChannel, email: "student@example.com", verified: true},
+		{name: "Unverified Email", member: true, channel: models.EmailChannel, email: "student@example.com", verified: false, wantErr: services.ErrEmailNotVerified},
+		{name: "No Email", member: true, channel: models.EmailChannel, email: "", verified: true, wantErr: services.ErrEmailNotVerified},
+		{name: "Not a member", member: false, channel: models.EmailChannel, email: "student@example.com", verified: true, wantErr: services.ErrUnauthorized},
+		{name: "Channel not configured", member: true, channel: models.NotificationChannel("SMS"), email: "student@example.com", verified: true, wantErr: services.ErrChannelUnavailable},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := &memorySubscriptionRepo{}
+			senders := map[models.NotificationChannel]utils.NotificationSender{
+				models.EmailChannel: &scriptedSender{},
+			}
+			service := services.NewAnnouncementSubscriptionService(repo, &memberClassUserRepo{member: tc.member}, &subscriberUserRepo{email: tc.email, verified: tc.verified}, senders)
+
+			_, err := service.Subscribe(context.Background(), 1, 10, tc.channel)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tc.wantErr)
+			}
+			if wantUpserts := map[bool]int{true: 0, false: 1}[tc.wantErr != nil]; len(repo.upserted) != wantUpserts {
+				t.Fatalf("upserted %d subscriptions, want %d", len(repo.upserted), wantUpserts)
+			}
+			if tc.wantErr == nil && repo.upserted[0].Target != tc.email {
+				t.Errorf("target = %q, want %q", repo.upserted[0].Target, tc.email)
+			}
+		})
+	}
+}
+
+// TestDeliverAnnouncement は投稿者以外に配信し、失敗した購読の失敗回数を加算して、送信先が無効な場合や失敗が続いた場合に配信を停止することを確認するテストです。
+func TestDeliverAnnouncement(t *testing.T) {
+	repo := &memorySubscriptionRepo{subscriptions: []models.AnnouncementSubscription{
+		{ID: 1, UID: 1, CID: 10, Channel: models.EmailChannel, Target: "author"},
+		{ID: 2, UID: 2, CID: 10, Channel: models.EmailChannel, Target: "ok", FailureCount: 2},
+		{ID: 3, UID: 3, CID: 10, Channel: models.EmailChannel, Target: "revoked"},
+		{ID: 4, UID: 4, CID: 10, Channel: models.EmailChannel, Target: "flaky"},
+		{ID: 5, UID: 5, CID: 10, Channel: models.EmailChannel, Target: "down", FailureCount: 4},
+		{ID: 6, UID: 6, CID: 20, Channel: models.EmailChannel, Target: "other class"},
+	}}
+	sender := &scriptedSender{errs: map[string]error{
+		"revoked": utils.ErrInvalidNotificationTarget,
+		"flaky":   errors.New("timeout"),
+		"down":    errors.New("timeout"),
+	}}
+	service := services.NewAnnouncementSubscriptionService(repo, nil, nil, map[models.NotificationChannel]utils.NotificationSender{models.EmailChannel: sender})
+
+	service.DeliverAnnouncement(context.Background(), models.ClassBoard{ID: 100, Title: "休講", Content: "明日は休講です", CID: 10, UID: 1})
+
+	if want := []string{"ok", "revoked", "flaky", "down"}; len(sender.sent) != len(want) {
+		t.Fatalf("sent to %v, want %v", sender.sent, want)
+	}
+	want := map[uint]struct {
+		failures int
+		disabled bool
+	}{
+		2: {failures: 0, disabled: false},
+		3: {failures: 1, disabled: true},
+		4: {failures: 1, disabled: false},
+		5: {failures: 5, disabled: true},
+	}
+	for _, subscription := range repo.subscriptions {
+		w, ok := want[subscription.ID]
+		if !ok {
+			continue
+		}
+		if subscription.FailureCount != w.failures || (subscription.DisabledAt != nil) != w.disabled {
+			t.Errorf("subscription %d: failures = %d, disabled = %v, want %d, %v", subscription.ID, subscription.FailureCount, subscription.DisabledAt != nil, w.failures, w.disabled)
+		}
+	}
+}
+
+// TestFindActiveSubscriptions は配信対象の購読に、クラスの管理者・アシスタント・生徒ではなくなったユーザーと
+// メールアドレスが未検証のユーザーを含めず、送信先をユーザーの現在のメールアドレスにすることを確認するテストです。
+func TestFindActiveSubscriptions(t *testing.T) {
+	db := openTestDB(t)
+	migration.Migrate(db)
+	ctx := context.Background()
+
+	owner := &models.User{Name: "管理者", PID: fmt.Sprintf("subscription-owner-%d", time.Now().UnixNano())}
+	if err := db.Create(owner).Error; err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	class := &models.Class{Name: "数学", UID: owner.ID}
+	if err := db.Create(class).Error; err != nil {
+		t.Fatalf("failed to create class: %v", err)
+	}
+
+	members := []struct {
+		name     string
+		role     string
+		verified bool
+		removed  bool
+		want     bool
+	}{
+		{"student", "USER", true, false, true},
+		{"assistant", "ASSISTANT", true, false, true},
+		{"unverified", "USER", false, false, false},
+		{"applicant", "APPLICANT", true, false, false},
+		{"blacklisted", "BLACKLIST", true, false, false},
+		{"removed", "USER", true, true, false},
+	}
+	want := map[uint]string{}
+	classUserRepo := repositories.NewClassUserRepository(db)
+	for i, member := range members {
+		user := &models.User{Name: member.name, PID: fmt.Sprintf("subscription-%d-%d", i, time.Now().UnixNano()), Email: member.name + "@example.com", EmailVerified: member.verified}
+		if err := db.Create(user).Error; err != nil {
+			t.Fatalf("failed to create user: %v", err)
+		}
+		classUser := &models.ClassUser{CID: class.ID, UID: user.ID, Nickname: member.name, Role: member.role}
+		if err := db.Create(classUser).Error; err != nil {
+			t.Fatalf("failed to create class user: %v", err)
+		}
+		if member.removed {
+			db.Where("uid = ? AND cid = ?", user.ID, class.ID).Delete(&models.ClassUser{})
+		}
+		// 購読した後にメールアドレスを変更しても、現在のアドレスに配信する
+		if err := db.Create(&models.AnnouncementSubscription{UID: user.ID, CID: class.ID, Channel: models.EmailChannel, Target: "old@example.com"}).Error; err != nil {
+			t.Fatalf("failed to create subscription: %v", err)
+		}
+		if member.want {
+			want[user.ID] = user.Email
+		}
+		if isMember, err := classUserRepo.IsMember(ctx, user.ID, class.ID); err != nil || isMember != (member.want || member.name == "unverified") {
+			t.Errorf("%s: IsMember = %v, err = %v", member.name, isMember, err)
+		}
+	}
+
+	subscriptions, err := repositories.NewAnnouncementSubscriptionRepository(db).FindActiveByClass(ctx, class.ID)
+	if err != nil {
+		t.Fatalf("err = %v", err)
+	}
+	got := map[uint]string{}
+	for _, subscription := range subscriptions {
+		got[subscription.UID] = subscription.Target
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("subscriptions = %v, want %v", got, want)
+	}
+}
+
+// TestSMTPMailerContext はSMTPサーバーが応答しない場合に、ctxの期限で送信を中断することを確認するテストです。
+func TestSMTPMailerContext(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	// 接続を受け付けるだけで挨拶を返さない
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		if conn, err := listener.Accept(); err == nil {
+			accepted <- conn
+		}
+	}()
+	t.Cleanup(func() {
+		listener.Close()
+		select {
+		case conn := <-accepted:
+			conn.Close()
+		default:
+		}
+	})
+
+	addr := listener.Addr().(*net.TCPAddr)
+	mailer := utils.NewSMTPMailer("127.0.0.1", addr.Port, "", "", "noreply@example.com")
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err = mailer.Send(ctx, utils.Mail{To: "student@example.com", Subject: "休講", Text: "本文"})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Send took %v after the deadline", elapsed)
+	}
+}
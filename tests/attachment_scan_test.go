@@ -0,0 +1,350 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/utils"
+)
+
+// startFakeClamd はINSTREAMで受け取った内容をreplyに渡し、その応答を返すclamdを起動します。
+func startFakeClamd(t *testing.T, reply func(content []byte) string) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				command := make([]byte, len("zINSTREAM\x00"))
+				if _, err := io.ReadFull(conn, command); err != nil || string(command) != "zINSTREAM\x00" {
+					return
+				}
+				var content bytes.Buffer
+				size := make([]byte, 4)
+				for {
+					if _, err := io.ReadFull(conn, size); err != nil {
+						return
+					}
+					n := binary.BigEndian.Uint32(size)
+					if n == 0 {
+						break
+					}
+					if _, err := io.CopyN(&content, conn, int64(n)); err != nil {
+						return
+					}
+				}
+				conn.Write([]byte(reply(content.Bytes()) + "\x00"))
+			}(conn)
+		}
+	}()
+	return listener.Addr().String()
+}
+
+// TestClamAVScanner はclamdに内容を分割して送信し、応答からウイルスの検出を判定することを確認するテストです。
+func TestClamAVScanner(t *testing.T) {
+	address := startFakeClamd(t, func(content []byte) string {
+		switch {
+		case bytes.Contains(content, []byte("EICAR")):
+			return "stream: Eicar-Test-Signature FOUND"
+		case len(content) == 0:
+			return "INSTREAM size limit exceeded. ERROR"
+		default:
+			return "stream: OK"
+		}
+	})
+
+	cases := []struct {
+		name          string
+		content       string
+		wantInfected  bool
+		wantSignature string
+		wantErr       bool
+	}{
+		{"Clean", strings.Repeat("a", 200<<10), false, "", false},
+		{"Infected", "X5O!P%@AP[4\\PZX54(P^)7CC)7}$EICAR-STANDARD-ANTIVIRUS-TEST-FILE!$H+H*", true, "Eicar-Test-Signature", false},
+		{"Error", "", false, "", true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := utils.NewClamAVScanner(address).Scan(context.Background(), strings.NewReader(tc.content))
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Scan() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if result.Infected != tc.wantInfected || result.Signature != tc.wantSignature {
+				t.Errorf("Scan() = %+v, want infected %v with %q", result, tc.wantInfected, tc.wantSignature)
+			}
+		})
+	}
+}
+
+// scanBoardRepo はスキャンの結果を記録するClassBoardRepositoryです。replacedの場合は画像が差し替えられたものとして保存しません。
+type scanBoardRepo struct {
+	repositories.ClassBoardRepository
+	mu       sync.Mutex
+	replaced bool
+	statuses []models.AttachmentScanStatus
+	// stale スキャン中のまま時間が過ぎた掲示として返す掲示
+	stale       []models.ClassBoard
+	staleBefore time.Time
+}
+
+func (r *scanBoardRepo) UpdateScanStatus(_ context.Context, _ uint, _ string, status models.AttachmentScanStatus) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.replaced {
+		return false, nil
+	}
+	r.statuses = append(r.statuses, status)
+	return true, nil
+}
+
+func (r *scanBoardRepo) saved() []models.AttachmentScanStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]models.AttachmentScanStatus(nil), r.statuses...)
+}
+
+func (r *scanBoardRepo) FindStalePendingScans(_ context.Context, updatedBefore time.Time, _ int) ([]models.ClassBoard, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.staleBefore = updatedBefore
+	return r.stale, nil
+}
+
+// scanUploader は画像の内容として固定の文字列を返し、削除を依頼されたURLを記録するUploaderです。
+type scanUploader struct {
+	recordingUploader
+	mu      sync.Mutex
+	content string
+	removed []string
+}
+
+func (u *scanUploader) OpenObject(context.Context, string) (io.ReadCloser, error) {
+	return io.NopCloser(strings.NewReader(u.content)), nil
+}
+
+func (u *scanUploader) DeleteObjects(_ context.Context, urls []string) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.removed = append(u.removed, urls...)
+	return nil
+}
+
+func (u *scanUploader) deleted() []string {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return append([]string(nil), u.removed...)
+}
+
+// flakyScanner は指定した回数だけ失敗した後、内容に「virus」を含む場合にウイルスを検出するVirusScannerです。
+type flakyScanner struct {
+	mu       sync.Mutex
+	failures int
+	calls    int
+}
+
+func (s *flakyScanner) Scan(_ context.Context, content io.Reader) (utils.ScanResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls++
+	if s.failures > 0 {
+		s.failures--
+		return utils.ScanResult{}, errors.New("connection refused")
+	}
+	data, _ := io.ReadAll(content)
+	if strings.Contains(string(data), "virus") {
+		return utils.ScanResult{Infected: true, Signature: "Test-Signature"}, nil
+	}
+	return utils.ScanResult{}, nil
+}
+
+// TestAttachmentScan はスキャンの結果を保存し、ウイルスを検出した画像のみ削除して投稿者に通知することを確認するテストです。
+func TestAttachmentScan(t *testing.T) {
+	const image = "https://cdn.example.com/images/5/a-1.png"
+
+	cases := []struct {
+		name        string
+		content     string
+		failures    int
+		replaced    bool
+		wantStatus  models.AttachmentScanStatus
+		wantRemoved bool
+	}{
+		{"Clean", "image", 0, false, models.ScanClean, false},
+		{"Infected", "virus", 0, false, models.ScanInfected, true},
+		{"Retried after scanner error", "virus", 2, false, models.ScanInfected, true},
+		{"Image replaced while scanning", "virus", 0, true, "", false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := &scanBoardRepo{replaced: tc.replaced}
+			uploader := &scanUploader{content: tc.content}
+			scanner := &flakyScanner{failures: tc.failures}
+			notifier := &lockedNotifier{}
+			service := services.NewAttachmentScanService(repo, uploader, notifier, services.AttachmentScanConfig{Scanner: scanner, RetryDelay: 1})
+
+			service.ScanBoardImage(models.ClassBoard{ID: 1, CID: 5, UID: 9, Title: "課題", Image: image, ScanStatus: models.ScanPending})
+
+			waitFor(t, "the scan", func() bool {
+				scanner.mu.Lock()
+				defer scanner.mu.Unlock()
+				return scanner.calls == tc.failures+1
+			})
+			if tc.wantStatus != "" {
+				waitFor(t, "the result", func() bool { return len(repo.saved()) == 1 })
+				if got := repo.saved()[0]; got != tc.wantStatus {
+					t.Errorf("status = %q, want %q", got, tc.wantStatus)
+				}
+			}
+			if tc.wantRemoved {
+				waitFor(t, "the notification", func() bool { return len(notifier.notified()) == 1 })
+				if got := notifier.notified()[0]; got != 9 {
+					t.Errorf("notified uid %d, want the poster 9", got)
+				}
+				if got := uploader.deleted(); len(got) != 1 || got[0] != image {
+					t.Errorf("deleted = %v, want [%s]", got, image)
+				}
+				return
+			}
+			if len(uploader.deleted()) != 0 || len(notifier.notified()) != 0 {
+				t.Errorf("deleted %v and notified %v, want neither", uploader.deleted(), notifier.notified())
+			}
+		})
+	}
+}
+
+// recordingScanService はスキャンを予約された掲示板を記録するAttachmentScanServiceです。
+type recordingScanService struct {
+	boards []models.ClassBoard
+}
+
+func (s *recordingScanService) ScanBoardImage(board models.ClassBoard) {
+	s.boards = append(s.boards, board)
+}
+
+func (s *recordingScanService) RescanStalePending(context.Context, time.Time) (int, error) {
+	return 0, nil
+}
+
+// TestUpdateClassBoardScansImage は画像を差し替えた場合のみスキャン中にしてスキャンを予約し、スキャンが無効な場合は状態を空にすることを確認するテストです。
+func TestUpdateClassBoardScansImage(t *testing.T) {
+	const oldImage = "https://cdn.example.com/images/5/old.png"
+	const newImage = "https://cdn.example.com/images/5/new.png"
+
+	cases := []struct {
+		name       string
+		scanning   bool
+		imageURL   string
+		wantStatus models.AttachmentScanStatus
+		wantQueued bool
+	}{
+		{"New image", true, newImage, models.ScanPending, true},
+		{"Image unchanged", true, "", models.ScanClean, false},
+		{"Scanning disabled", false, newImage, "", false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := &pinBoardRepo{board: models.ClassBoard{ID: 1, CID: 5, Image: oldImage, ScanStatus: models.ScanClean}}
+			scanner := &recordingScanService{}
+			var scan services.AttachmentScanService
+			if tc.scanning {
+				scan = scanner
+			}
+			service := services.NewClassBoardService(repo, &adminClassUserRepo{}, &recordingUploader{}, nil, nil, nil, true, nil, nil, scan, nil)
+
+			board, err := service.UpdateClassBoard(context.Background(), 1, dto.ClassBoardUpdateDTO{ID: 1, Title: "更新"}, tc.imageURL)
+			if err != nil {
+				t.Fatalf("UpdateClassBoard() error = %v", err)
+			}
+			if board.ScanStatus != tc.wantStatus {
+				t.Errorf("ScanStatus = %q, want %q", board.ScanStatus, tc.wantStatus)
+			}
+			if queued := len(scanner.boards) == 1; queued != tc.wantQueued {
+				t.Fatalf("queued = %v, want %v", queued, tc.wantQueued)
+			}
+			if tc.wantQueued && scanner.boards[0].Image != newImage {
+				t.Errorf("queued image %q, want %q", scanner.boards[0].Image, newImage)
+			}
+		})
+	}
+}
+
+// blockingScanner はreleaseが閉じられるまでスキャンを終えず、スキャンした回数を数えるVirusScannerです。
+type blockingScanner struct {
+	release chan struct{}
+	mu      sync.Mutex
+	calls   int
+}
+
+func (s *blockingScanner) Scan(context.Context, io.Reader) (utils.ScanResult, error) {
+	s.mu.Lock()
+	s.calls++
+	s.mu.Unlock()
+	<-s.release
+	return utils.ScanResult{}, nil
+}
+
+func (s *blockingScanner) scanned() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls
+}
+
+// TestRescanStalePending は添付してから時間が過ぎてもスキャン中の画像のスキャンを予約し直し、
+// 予約済みやスキャン中の画像は重ねて予約せず、スキャンを終えた画像は再び予約できることを確認するテストです。
+func TestRescanStalePending(t *testing.T) {
+	const image = "https://cdn.example.com/images/5/a-1.png"
+	now := time.Date(2026, 4, 10, 9, 0, 0, 0, time.UTC)
+	repo := &scanBoardRepo{stale: []models.ClassBoard{{ID: 1, CID: 5, UID: 9, Image: image, ScanStatus: models.ScanPending}}}
+	scanner := &blockingScanner{release: make(chan struct{})}
+	service := services.NewAttachmentScanService(repo, &scanUploader{content: "image"}, nil, services.AttachmentScanConfig{Scanner: scanner, RetryDelay: 1})
+
+	scheduled, err := service.RescanStalePending(context.Background(), now)
+	if err != nil || scheduled != 1 {
+		t.Fatalf("RescanStalePending() = %d, %v, want 1", scheduled, err)
+	}
+	if want := now.Add(-15 * time.Minute); !repo.staleBefore.Equal(want) {
+		t.Errorf("updated before %v, want %v", repo.staleBefore, want)
+	}
+	waitFor(t, "the scan", func() bool { return scanner.scanned() == 1 })
+
+	// スキャン中の画像は予約しない
+	if scheduled, err := service.RescanStalePending(context.Background(), now); err != nil || scheduled != 0 {
+		t.Errorf("RescanStalePending() while scanning = %d, %v, want 0", scheduled, err)
+	}
+	service.ScanBoardImage(repo.stale[0])
+
+	close(scanner.release)
+	waitFor(t, "the result", func() bool { return len(repo.saved()) == 1 })
+	if scanner.scanned() != 1 {
+		t.Errorf("scanned %d times, want once", scanner.scanned())
+	}
+	waitFor(t, "the rescan", func() bool {
+		scheduled, err := service.RescanStalePending(context.Background(), now)
+		return err == nil && scheduled == 1
+	})
+}
@@ -0,0 +1,161 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+)
+
+// TestClassScheduleCheckInMethods はスケジュールの出席方式ごとに有効なチェックイン手段を返し、未設定の方式を対面として扱うことを確認するテストです。
+func TestClassScheduleCheckInMethods(t *testing.T) {
+	cases := []struct {
+		mode        models.AttendanceMode
+		wantMode    string
+		wantMethods []string
+	}{
+		{"", "IN_PERSON", []string{"QR", "CODE", "LOCATION"}},
+		{models.InPersonMode, "IN_PERSON", []string{"QR", "CODE", "LOCATION"}},
+		{models.OnlineMode, "ONLINE", []string{"LIVE"}},
+		{models.HybridMode, "HYBRID", []string{"QR", "CODE", "LOCATION", "LIVE"}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.wantMode, func(t *testing.T) {
+			repo := &cancelScheduleRepo{schedule: models.ClassSchedule{ID: 3, CID: 5, AttendanceMode: tc.mode}}
+			service := services.NewClassScheduleService(repo, &cancelClassUserRepo{}, nil, nil, true, nil, nil, nil, nil, services.CheckInTokenConfig{}, nil)
+
+			schedule, err := service.GetClassScheduleByID(context.Background(), 3)
+			if err != nil {
+				t.Fatalf("err = %v", err)
+			}
+			if schedule.AttendanceMode != tc.wantMode || !reflect.DeepEqual(schedule.CheckInMethods, tc.wantMethods) {
+				t.Errorf("mode = %q methods = %v, want %q and %v", schedule.AttendanceMode, schedule.CheckInMethods, tc.wantMode, tc.wantMethods)
+			}
+		})
+	}
+
+	t.Run("Update", func(t *testing.T) {
+		repo := &cancelScheduleRepo{schedule: models.ClassSchedule{ID: 3, CID: 5, AttendanceMode: models.InPersonMode}}
+		service := services.NewClassScheduleService(repo, &cancelClassUserRepo{}, nil, nil, true, nil, nil, nil, nil, services.CheckInTokenConfig{}, nil)
+
+		online := "ONLINE"
+		if _, err := service.UpdateClassSchedule(context.Background(), 3, &dto.UpdateClassScheduleDTO{AttendanceMode: &online}); err != nil {
+			t.Fatalf("err = %v", err)
+		}
+		if repo.schedule.AttendanceMode != models.OnlineMode {
+			t.Errorf("mode = %q, want %q", repo.schedule.AttendanceMode, models.OnlineMode)
+		}
+	})
+}
+
+// modeCountAttendanceRepo は固定の出席方式ごとの件数を返すAttendanceRepositoryです。
+type modeCountAttendanceRepo struct {
+	repositories.AttendanceRepository
+	counts []repositories.AttendanceModeCount
+}
+
+func (r *modeCountAttendanceRepo) CountByAttendanceMode(context.Context, uint) ([]repositories.AttendanceModeCount, error) {
+	return r.counts, nil
+}
+
+// TestGetAttendanceSummaryByMode は出席方式ごとに件数と出席率を集計し、記録のない方式も返し、講師・アシスタント以外には返さないことを確認するテストです。
+func TestGetAttendanceSummaryByMode(t *testing.T) {
+	repo := &modeCountAttendanceRepo{counts: []repositories.AttendanceModeCount{
+		{Mode: models.InPersonMode, Status: models.AttendanceStatus, Count: 6},
+		{Mode: models.InPersonMode, Status: models.TardyStatus, Count: 1},
+		{Mode: models.InPersonMode, Status: models.AbsenceStatus, Count: 1},
+		{Mode: models.OnlineMode, Status: models.AbsenceStatus, Count: 2},
+	}}
+
+	cases := []struct {
+		name    string
+		role    string
+		wantErr error
+	}{
+		{"Teacher", "ADMIN", nil},
+		{"Assistant", "ASSISTANT", nil},
+		{"Student", "USER", services.ErrUnauthorized},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			service := services.NewAttendanceService(repo, &streakClassUserRepo{role: tc.role}, nil, nil, true, nil, nil)
+
+			summaries, err := service.GetAttendanceSummaryByMode(context.Background(), 1, 10)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tc.wantErr)
+			}
+			if tc.wantErr != nil {
+				return
+			}
+			want := []dto.AttendanceModeSummaryDTO{
+				{Mode: "IN_PERSON", Attendance: 6, Tardy: 1, Absence: 1, Total: 8, AttendanceRate: 75},
+				{Mode: "ONLINE", Absence: 2, Total: 2},
+				{Mode: "HYBRID"},
+			}
+			if !reflect.DeepEqual(summaries, want) {
+				t.Errorf("summaries = %+v, want %+v", summaries, want)
+			}
+		})
+	}
+}
+
+// TestCheckInAttendanceMode は本人の出席を確認コードで記録する場合と出席トークンで記録する場合の両方で、
+// スケジュールの出席方式で無効なチェックイン手段を拒否し、講師による記録は出席方式に関わらず受け付けることを確認するテストです。
+func TestCheckInAttendanceMode(t *testing.T) {
+	cases := []struct {
+		name     string
+		mode     models.AttendanceMode
+		actorUID uint
+		want     error
+	}{
+		{"Code In Person", models.InPersonMode, 2, nil},
+		{"Code Hybrid", models.HybridMode, 2, nil},
+		{"Code Online", models.OnlineMode, 2, services.ErrCheckInMethodNotAllowed},
+		{"Teacher Online", models.OnlineMode, 1, nil},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := &timestampAttendanceRepo{}
+			scheduleRepo := &materialScheduleRepo{schedule: models.ClassSchedule{ID: 3, CID: 1, AttendanceMode: tc.mode}}
+			service := services.NewAttendanceService(repo, &roleClassUserRepo{roles: attendanceRoles}, scheduleRepo, nil, true, nil, nil)
+
+			err := service.CreateOrUpdateAttendance(context.Background(), 1, 2, 3, string(models.AttendanceStatus), nil, nil, tc.actorUID, nil, 0)
+			if !errors.Is(err, tc.want) {
+				t.Fatalf("err = %v, want %v", err, tc.want)
+			}
+			if saved := repo.saved != nil; saved != (tc.want == nil) {
+				t.Errorf("saved = %+v, want saved %v", repo.saved, tc.want == nil)
+			}
+		})
+	}
+
+	tokenCases := []struct {
+		mode models.AttendanceMode
+		want error
+	}{
+		{models.InPersonMode, nil},
+		{models.HybridMode, nil},
+		{models.OnlineMode, services.ErrCheckInMethodNotAllowed},
+	}
+	for _, tc := range tokenCases {
+		t.Run("Token "+string(tc.mode), func(t *testing.T) {
+			repo := &checkInAttendanceRepo{saved: map[[2]uint]models.Attendance{}}
+			service := services.NewAttendanceService(repo, nil, nil, nil, true, nil, nil)
+
+			_, err := service.RecordTokenCheckIn(context.Background(), models.ClassSchedule{ID: 3, CID: 1, AttendanceMode: tc.mode}, 2, nil)
+			if !errors.Is(err, tc.want) {
+				t.Fatalf("err = %v, want %v", err, tc.want)
+			}
+			if saved := len(repo.saved) == 1; saved != (tc.want == nil) {
+				t.Errorf("saved = %+v, want saved %v", repo.saved, tc.want == nil)
+			}
+		})
+	}
+}
@@ -0,0 +1,244 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/controllers"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/middlewares"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"github.com/gin-gonic/gin"
+)
+
+// pagedAttendanceRepo は総数がtotal件の出席情報から指定したページを返し、取得条件を記録するAttendanceRepositoryです。
+type pagedAttendanceRepo struct {
+	repositories.AttendanceRepository
+	total  int
+	filter dto.AttendanceListFilter
+	limit  int
+	offset int
+}
+
+func (r *pagedAttendanceRepo) GetAllAttendancesByCID(_ context.Context, cid uint, filter dto.AttendanceListFilter, limit int, offset int) ([]models.Attendance, int64, error) {
+	r.filter, r.limit, r.offset = filter, limit, offset
+	var attendances []models.Attendance
+	for i := offset; i < r.total && i < offset+limit; i++ {
+		attendances = append(attendances, models.Attendance{ID: uint(i + 1), CID: cid})
+	}
+	return attendances, int64(r.total), nil
+}
+
+// TestGetAllAttendancesByCIDPage はページ番号から取得位置を求めて絞り込み条件と合わせてリポジトリに渡し、
+// 最後のページではnext_pageをnullにすることを確認するテストです。
+func TestGetAllAttendancesByCIDPage(t *testing.T) {
+	second := 2
+	cases := []struct {
+		name       string
+		total      int
+		page       int
+		pageSize   int
+		wantOffset int
+		wantLen    int
+		wantNext   *int
+	}{
+		{"First Page", 45, 1, 20, 0, 20, &second},
+		{"Last Page", 45, 3, 20, 40, 5, nil},
+		{"Exact Last Page", 40, 2, 20, 20, 20, nil},
+		{"Beyond Last Page", 45, 4, 20, 60, 0, nil},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := &pagedAttendanceRepo{total: tc.total}
+			service := services.NewAttendanceService(repo, nil, nil, nil, true, nil, nil)
+
+			filter := dto.AttendanceListFilter{Status: models.TardyStatus, CSID: 3}
+
+			result, err := service.GetAllAttendancesByCID(context.Background(), 1, 2, filter, tc.page, tc.pageSize)
+			if err != nil {
+				t.Fatalf("err = %v", err)
+			}
+			if repo.filter != filter {
+				t.Errorf("filter = %+v, want %+v", repo.filter, filter)
+			}
+			if repo.limit != tc.pageSize || repo.offset != tc.wantOffset {
+				t.Errorf("limit = %d offset = %d, want %d and %d", repo.limit, repo.offset, tc.pageSize, tc.wantOffset)
+			}
+			if result.Attendances == nil || len(result.Attendances) != tc.wantLen || result.Total != int64(tc.total) {
+				t.Errorf("got %d attendances of %d, want %d of %d", len(result.Attendances), result.Total, tc.wantLen, tc.total)
+			}
+			if result.Page != tc.page || result.Limit != tc.pageSize {
+				t.Errorf("page = %d limit = %d, want %d and %d", result.Page, result.Limit, tc.page, tc.pageSize)
+			}
+			if (result.NextPage == nil) != (tc.wantNext == nil) || (tc.wantNext != nil && *result.NextPage != *tc.wantNext) {
+				t.Errorf("next_page = %v, want %v", result.NextPage, tc.wantNext)
+			}
+		})
+	}
+}
+
+// pagedAttendanceService は出席情報の一覧の取得条件を記録するAttendanceServiceです。
+type pagedAttendanceService struct {
+	services.AttendanceService
+	total    int64
+	all      []models.Attendance
+	called   bool
+	listed   bool
+	filter   dto.AttendanceListFilter
+	page     int
+	pageSize int
+}
+
+func (s *pagedAttendanceService) GetAllAttendancesByCID(_ context.Context, _ uint, _ uint, filter dto.AttendanceListFilter, page int, pageSize int) (*dto.AttendancePage, error) {
+	s.called, s.filter, s.page, s.pageSize = true, filter, page, pageSize
+	result := &dto.AttendancePage{Attendances: []models.Attendance{}, Total: s.total, Page: page, Limit: pageSize}
+	if int64(page*pageSize) < s.total {
+		next := page + 1
+		result.NextPage = &next
+	}
+	return result, nil
+}
+
+func (s *pagedAttendanceService) ListAttendancesByCID(_ context.Context, _ uint, _ uint, filter dto.AttendanceListFilter) ([]models.Attendance, error) {
+	s.listed, s.filter = true, filter
+	return s.all, nil
+}
+
+// TestGetAllAttendancesPagination はv2でpage・limit・status・csidを取得条件としてサービスに渡し、
+// 範囲外のlimitや定義されていないstatusを400で拒否する一方で旧名のpageSizeは100件までに収め、
+// 出席情報がない場合も404ではなく空のdataを返し、総数と次のページをmetaに含めることを確認するテストです。
+func TestGetAllAttendancesPagination(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+
+	cases := []struct {
+		name         string
+		query        string
+		total        int64
+		wantStatus   int
+		wantFilter   dto.AttendanceListFilter
+		wantPage     int
+		wantPageSize int
+	}{
+		{"Defaults", "", 45, http.StatusOK, dto.AttendanceListFilter{}, 1, 20},
+		{"Limit", "?page=3&limit=10", 45, http.StatusOK, dto.AttendanceListFilter{}, 3, 10},
+		{"Page Size Alias", "?pageSize=100", 45, http.StatusOK, dto.AttendanceListFilter{}, 1, 100},
+		{"Invalid Page", "?page=0", 45, http.StatusOK, dto.AttendanceListFilter{}, 1, 20},
+		{"Filter", "?status=tardy&csid=12", 3, http.StatusOK, dto.AttendanceListFilter{Status: models.TardyStatus, CSID: 12}, 1, 20},
+		{"Empty Page", "?page=9", 45, http.StatusOK, dto.AttendanceListFilter{}, 9, 20},
+		{"No Attendance", "?status=ABSENCE", 0, http.StatusOK, dto.AttendanceListFilter{Status: models.AbsenceStatus}, 1, 20},
+		{"Limit Too Large", "?limit=101", 45, http.StatusBadRequest, dto.AttendanceListFilter{}, 0, 0},
+		{"Limit Zero", "?limit=0", 45, http.StatusBadRequest, dto.AttendanceListFilter{}, 0, 0},
+		{"Limit Not Integer", "?limit=abc", 45, http.StatusBadRequest, dto.AttendanceListFilter{}, 0, 0},
+		{"Page Size Clamped", "?pageSize=500", 45, http.StatusOK, dto.AttendanceListFilter{}, 1, 100},
+		{"Page Size Not Integer", "?pageSize=abc", 45, http.StatusOK, dto.AttendanceListFilter{}, 1, 20},
+		{"Limit Wins Over Page Size", "?limit=101&pageSize=10", 45, http.StatusBadRequest, dto.AttendanceListFilter{}, 0, 0},
+		{"Unknown Status", "?status=LATE", 45, http.StatusBadRequest, dto.AttendanceListFilter{}, 0, 0},
+		{"Invalid Schedule", "?csid=x", 45, http.StatusBadRequest, dto.AttendanceListFilter{}, 0, 0},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			service := &pagedAttendanceService{total: tc.total}
+			r := gin.New()
+			r.Use(middlewares.GlobalErrorHandler(&recordingErrorReporter{}))
+			r.GET("/api/gin/v2/at/:cid", middlewares.APIVersionMiddleware(middlewares.APIVersionV2), controllers.NewAttendanceController(service, nil).GetAllAttendances)
+
+			req, _ := http.NewRequest(http.MethodGet, "/api/gin/v2/at/1"+tc.query, nil)
+			resp := httptest.NewRecorder()
+			r.ServeHTTP(resp, req)
+
+			if resp.Code != tc.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", resp.Code, tc.wantStatus, resp.Body.String())
+			}
+			if tc.wantStatus != http.StatusOK {
+				if service.called {
+					t.Error("service was called for an invalid request")
+				}
+				return
+			}
+			if service.filter != tc.wantFilter || service.page != tc.wantPage || service.pageSize != tc.wantPageSize {
+				t.Errorf("got filter=%+v page=%d limit=%d, want filter=%+v page=%d limit=%d",
+					service.filter, service.page, service.pageSize, tc.wantFilter, tc.wantPage, tc.wantPageSize)
+			}
+			var body struct {
+				Data []models.Attendance `json:"data"`
+				Meta dto.ResponseMeta    `json:"meta"`
+			}
+			if err := json.Unmarshal(resp.Body.Bytes(), &body); err != nil {
+				t.Fatalf("body = %s: %v", resp.Body.String(), err)
+			}
+			if !reflect.DeepEqual(body.Data, []models.Attendance{}) {
+				t.Errorf("data = %v, want an empty array", body.Data)
+			}
+			meta := body.Meta
+			if meta.Total == nil || *meta.Total != tc.total || meta.Page != tc.wantPage || meta.Limit != tc.wantPageSize {
+				t.Errorf("meta = %s", resp.Body.String())
+			}
+			if hasNext := int64(tc.wantPage*tc.wantPageSize) < tc.total; (meta.NextPage != nil) != hasNext || (hasNext && *meta.NextPage != tc.wantPage+1) {
+				t.Errorf("next_page = %v, want next page %v", meta.NextPage, hasNext)
+			}
+		})
+	}
+}
+
+// TestGetAllAttendancesV1 はv1ではpageやlimitを無視して一致するすべての出席情報を配列で返し、
+// 一致する出席情報がない場合は従来どおり404を返すことを確認するテストです。
+func TestGetAllAttendancesV1(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+
+	all := make([]models.Attendance, 150)
+	for i := range all {
+		all[i] = models.Attendance{ID: uint(i + 1), CID: 1}
+	}
+	cases := []struct {
+		name       string
+		query      string
+		all        []models.Attendance
+		wantStatus int
+		wantFilter dto.AttendanceListFilter
+	}{
+		{"All", "", all, http.StatusOK, dto.AttendanceListFilter{}},
+		{"Page Ignored", "?page=2&limit=10", all, http.StatusOK, dto.AttendanceListFilter{}},
+		{"Filter", "?status=tardy&csid=12", all[:3], http.StatusOK, dto.AttendanceListFilter{Status: models.TardyStatus, CSID: 12}},
+		{"No Attendance", "", nil, http.StatusNotFound, dto.AttendanceListFilter{}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			service := &pagedAttendanceService{all: tc.all}
+			r := gin.New()
+			r.Use(middlewares.GlobalErrorHandler(&recordingErrorReporter{}))
+			r.GET("/api/gin/at/:cid", controllers.NewAttendanceController(service, nil).GetAllAttendances)
+
+			req, _ := http.NewRequest(http.MethodGet, "/api/gin/at/1"+tc.query, nil)
+			resp := httptest.NewRecorder()
+			r.ServeHTTP(resp, req)
+
+			if resp.Code != tc.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", resp.Code, tc.wantStatus, resp.Body.String())
+			}
+			if service.called || !service.listed || service.filter != tc.wantFilter {
+				t.Errorf("called=%v listed=%v filter=%+v, want the full list with filter=%+v", service.called, service.listed, service.filter, tc.wantFilter)
+			}
+			if tc.wantStatus != http.StatusOK {
+				return
+			}
+			var body struct {
+				Data []models.Attendance `json:"data"`
+			}
+			if err := json.Unmarshal(resp.Body.Bytes(), &body); err != nil {
+				t.Fatalf("body = %s: %v", resp.Body.String(), err)
+			}
+			if len(body.Data) != len(tc.all) {
+				t.Errorf("got %d attendances, want %d", len(body.Data), len(tc.all))
+			}
+		})
+	}
+}
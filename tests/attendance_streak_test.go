@@ -0,0 +1,94 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+)
+
+// streakAttendanceRepo は固定の出席の時系列を返すAttendanceRepositoryです。
+type streakAttendanceRepo struct {
+	repositories.AttendanceRepository
+	timeline []repositories.AttendanceTimelineEntry
+}
+
+func (r *streakAttendanceRepo) GetAttendanceTimeline(context.Context, uint, uint, time.Time) ([]repositories.AttendanceTimelineEntry, error) {
+	return r.timeline, nil
+}
+
+// streakClassUserRepo は閲覧者のロールを返すClassUserRepositoryです。
+type streakClassUserRepo struct {
+	repositories.ClassUserRepository
+	role string
+}
+
+func (r *streakClassUserRepo) GetRole(context.Context, uint, uint) (string, error) {
+	return r.role, nil
+}
+
+// streakTimeline ステータスの並びから1日おきのスケジュールの時系列を作ります。空文字は出席の記録なしです。
+func streakTimeline(now time.Time, statuses ...models.AttendanceType) []repositories.AttendanceTimelineEntry {
+	entries := make([]repositories.AttendanceTimelineEntry, len(statuses))
+	for i, status := range statuses {
+		started := now.AddDate(0, 0, i-len(statuses))
+		entries[i] = repositories.AttendanceTimelineEntry{CSID: uint(i + 1), StartedAt: started, EndedAt: started.Add(time.Hour)}
+		if status != "" {
+			s := status
+			entries[i].Status = &s
+		}
+	}
+	return entries
+}
+
+// TestGetAttendanceStreak は欠席と記録のないスケジュールで連続が途切れ、遅刻の扱いを選べ、最長記録を返すことを確認するテストです。
+func TestGetAttendanceStreak(t *testing.T) {
+	now := time.Now()
+	present, tardy, absent := models.AttendanceStatus, models.TardyStatus, models.AbsenceStatus
+	inProgress := repositories.AttendanceTimelineEntry{CSID: 99, StartedAt: now.Add(-time.Minute), EndedAt: now.Add(time.Hour)}
+
+	cases := []struct {
+		name        string
+		viewer      uint
+		role        string
+		timeline    []repositories.AttendanceTimelineEntry
+		allowTardy  bool
+		wantErr     error
+		wantCurrent int
+		wantLongest int
+	}{
+		{"No Schedules", 1, "", nil, false, nil, 0, 0},
+		{"Absence Resets", 1, "", streakTimeline(now, present, present, present, absent, present), false, nil, 1, 3},
+		{"Missing Record Resets", 1, "", streakTimeline(now, present, present, "", present), false, nil, 1, 2},
+		{"Tardy Breaks By Default", 1, "", streakTimeline(now, present, tardy, present, present), false, nil, 2, 2},
+		{"Tardy Allowed", 1, "", streakTimeline(now, present, tardy, present, present), true, nil, 4, 4},
+		{"In Progress Without Record Keeps Streak", 1, "", append(streakTimeline(now, present, present), inProgress), false, nil, 2, 2},
+		{"Teacher Views Student", 2, "ADMIN", streakTimeline(now, present), false, nil, 1, 1},
+		{"Assistant Views Student", 2, "ASSISTANT", streakTimeline(now, present), false, nil, 1, 1},
+		{"Other Student", 2, "USER", streakTimeline(now, present), false, services.ErrUnauthorized, 0, 0},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			service := services.NewAttendanceService(&streakAttendanceRepo{timeline: tc.timeline}, &streakClassUserRepo{role: tc.role}, nil, nil, true, nil, nil)
+
+			streak, err := service.GetAttendanceStreak(context.Background(), tc.viewer, 1, 10, tc.allowTardy)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tc.wantErr)
+			}
+			if tc.wantErr != nil {
+				return
+			}
+			if streak.CurrentStreak != tc.wantCurrent || streak.LongestStreak != tc.wantLongest {
+				t.Errorf("current = %d longest = %d, want %d and %d", streak.CurrentStreak, streak.LongestStreak, tc.wantCurrent, tc.wantLongest)
+			}
+			if streak.AllowTardy != tc.allowTardy || streak.UID != 1 || streak.CID != 10 {
+				t.Errorf("streak = %+v", streak)
+			}
+		})
+	}
+}
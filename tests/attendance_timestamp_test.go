@@ -0,0 +1,126 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"gorm.io/gorm"
+)
+
+// timestampAttendanceRepo は保存された出席情報を記録するAttendanceRepositoryです。
+type timestampAttendanceRepo struct {
+	repositories.AttendanceRepository
+	existing *models.Attendance
+	saved    *models.Attendance
+}
+
+func (r *timestampAttendanceRepo) GetAttendanceByUIDAndCSID(context.Context, uint, uint) (*models.Attendance, error) {
+	if r.existing == nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return r.existing, nil
+}
+
+func (r *timestampAttendanceRepo) CreateAttendance(_ context.Context, attendance *models.Attendance) error {
+	r.saved = attendance
+	return nil
+}
+
+func (r *timestampAttendanceRepo) UpdateAttendance(_ context.Context, attendance *models.Attendance, _ uint) error {
+	r.saved = attendance
+	return nil
+}
+
+// TestCreateOrUpdateAttendanceTimestamp はクライアントの時刻に関わらずサーバーの時刻で記録し、クライアントの時刻と記録元を別に保存することを確認するテストです。
+func TestCreateOrUpdateAttendanceTimestamp(t *testing.T) {
+	forged := time.Date(2020, 1, 1, 9, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		name     string
+		existing *models.Attendance
+		actorUID uint
+		client   *time.Time
+		want     models.AttendanceSource
+	}{
+		{"Self Check-in With Forged Time", nil, 2, &forged, models.SelfSource},
+		{"Teacher Without Client Time", nil, 1, nil, models.TeacherSource},
+		{"Update Existing", &models.Attendance{ID: 1, RecordedAt: forged, Source: models.SelfSource}, 1, &forged, models.TeacherSource},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := &timestampAttendanceRepo{existing: tc.existing}
+			service := services.NewAttendanceService(repo, &roleClassUserRepo{roles: attendanceRoles}, attendanceScheduleRepo, nil, true, nil, nil)
+
+			before := time.Now()
+			if err := service.CreateOrUpdateAttendance(context.Background(), 1, 2, 3, string(models.AttendanceStatus), nil, nil, tc.actorUID, tc.client, 0); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if repo.saved == nil {
+				t.Fatal("attendance was not saved")
+			}
+			if repo.saved.RecordedAt.Before(before) || repo.saved.RecordedAt.After(time.Now()) {
+				t.Errorf("recorded at = %v, want server time after %v", repo.saved.RecordedAt, before)
+			}
+			if repo.saved.ClientRecordedAt != tc.client {
+				t.Errorf("client recorded at = %v, want %v", repo.saved.ClientRecordedAt, tc.client)
+			}
+			if repo.saved.Source != tc.want {
+				t.Errorf("source = %q, want %q", repo.saved.Source, tc.want)
+			}
+		})
+	}
+}
+
+// attendanceRoles は講師(1)、生徒(2, 3)、アシスタント(4)、申請者(5)のロールです。
+var attendanceRoles = map[uint]string{1: "ADMIN", 2: "USER", 3: "USER", 4: "ASSISTANT", 5: "APPLICANT"}
+
+// attendanceScheduleRepo は対面のスケジュール3を返すClassScheduleRepositoryです。
+var attendanceScheduleRepo = &materialScheduleRepo{schedule: models.ClassSchedule{ID: 3, CID: 1, AttendanceMode: models.InPersonMode}}
+
+// TestCreateOrUpdateAttendanceActor は他のユーザーの出席を記録できるのは講師とアシスタントのみで、
+// 本人の出席を記録できるのは生徒のみであり、記録元が操作したユーザーのロールから決まることを確認するテストです。
+func TestCreateOrUpdateAttendanceActor(t *testing.T) {
+	cases := []struct {
+		name     string
+		actorUID uint
+		uid      uint
+		want     models.AttendanceSource
+		wantErr  error
+	}{
+		{"Teacher Records Student", 1, 2, models.TeacherSource, nil},
+		{"Assistant Records Student", 4, 2, models.TeacherSource, nil},
+		{"Student Records Self", 2, 2, models.SelfSource, nil},
+		{"Student Records Another Student", 3, 2, "", services.ErrUnauthorized},
+		{"Teacher Records Self", 1, 1, "", services.ErrUnauthorized},
+		{"Applicant Records Self", 5, 5, "", services.ErrUnauthorized},
+		{"Applicant Records Student", 5, 2, "", services.ErrUnauthorized},
+		{"Not A Member", 6, 2, "", services.ErrUnauthorized},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := &timestampAttendanceRepo{}
+			service := services.NewAttendanceService(repo, &roleClassUserRepo{roles: attendanceRoles}, attendanceScheduleRepo, nil, true, nil, nil)
+
+			err := service.CreateOrUpdateAttendance(context.Background(), 1, tc.uid, 3, string(models.AttendanceStatus), nil, nil, tc.actorUID, nil, 0)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tc.wantErr)
+			}
+			if tc.wantErr != nil {
+				if repo.saved != nil {
+					t.Errorf("saved = %+v, want nothing saved", repo.saved)
+				}
+				return
+			}
+			if repo.saved == nil || repo.saved.Source != tc.want {
+				t.Errorf("saved = %+v, want source %q", repo.saved, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/migration"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+)
+
+// TestCreateOrUpdateAttendancePerSchedule は同じクラスの別のスケジュールの出席を記録しても前のスケジュールの出席を上書きせず、
+// スケジュールごとに1件の出席情報を作成・更新することを確認するテストです。
+func TestCreateOrUpdateAttendancePerSchedule(t *testing.T) {
+	db := openTestDB(t)
+	migration.Migrate(db)
+	tx := db.Begin()
+	if tx.Error != nil {
+		t.Fatalf("failed to begin transaction: %v", tx.Error)
+	}
+	t.Cleanup(func() { tx.Rollback() })
+	ctx := context.Background()
+
+	user := &models.User{Name: "山田", Image: "https://example.com/u.png", PID: "attendance-upsert-test"}
+	if err := tx.Create(user).Error; err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	teacher := &models.User{Name: "佐藤", Image: "https://example.com/t.png", PID: "attendance-upsert-teacher"}
+	if err := tx.Create(teacher).Error; err != nil {
+		t.Fatalf("failed to create teacher: %v", err)
+	}
+	class := &models.Class{Name: "数学", UID: teacher.ID}
+	if err := tx.Create(class).Error; err != nil {
+		t.Fatalf("failed to create class: %v", err)
+	}
+	if err := tx.Create(&models.ClassUser{CID: class.ID, UID: teacher.ID, Nickname: "佐藤", Role: "ADMIN"}).Error; err != nil {
+		t.Fatalf("failed to create class user: %v", err)
+	}
+	start := time.Now().Add(-48 * time.Hour)
+	schedules := []models.ClassSchedule{
+		{Title: "第1回", CID: class.ID, StartedAt: start, EndedAt: start.Add(time.Hour)},
+		{Title: "第2回", CID: class.ID, StartedAt: start.Add(24 * time.Hour), EndedAt: start.Add(25 * time.Hour)},
+	}
+	if err := tx.Create(&schedules).Error; err != nil {
+		t.Fatalf("failed to create schedules: %v", err)
+	}
+
+	service := services.NewAttendanceService(repositories.NewAttendanceRepository(tx), repositories.NewClassUserRepository(tx), nil, nil, true, nil, nil)
+	record := func(csid uint, status models.AttendanceType) {
+		t.Helper()
+		if err := service.CreateOrUpdateAttendance(ctx, class.ID, user.ID, csid, string(status), nil, nil, teacher.ID, nil, 0); err != nil {
+			t.Fatalf("CreateOrUpdateAttendance(csid=%d) = %v", csid, err)
+		}
+	}
+	record(schedules[0].ID, models.AttendanceStatus)
+	record(schedules[1].ID, models.TardyStatus)
+	record(schedules[0].ID, models.AbsenceStatus)
+
+	var attendances []models.Attendance
+	if err := tx.Where("uid = ?", user.ID).Order("csid").Find(&attendances).Error; err != nil {
+		t.Fatalf("failed to load attendances: %v", err)
+	}
+	if len(attendances) != 2 {
+		t.Fatalf("got %d attendances, want one per schedule", len(attendances))
+	}
+	want := map[uint]models.AttendanceType{schedules[0].ID: models.AbsenceStatus, schedules[1].ID: models.TardyStatus}
+	for _, attendance := range attendances {
+		if attendance.IsAttendance != want[attendance.CSID] {
+			t.Errorf("schedule %d status = %s, want %s", attendance.CSID, attendance.IsAttendance, want[attendance.CSID])
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package tests
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/middlewares"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/gin-gonic/gin"
+)
+
+// recordingAuditLogService は記録された監査ログを保持するAuditLogServiceです。
+type recordingAuditLogService struct {
+	entries []models.AuditLog
+}
+
+func (s *recordingAuditLogService) Record(entry models.AuditLog) {
+	s.entries = append(s.entries, entry)
+}
+
+func (s *recordingAuditLogService) GetClassAuditLogs(context.Context, uint, uint, time.Time, time.Time, int, int) ([]models.AuditLog, error) {
+	return nil, nil
+}
+
+func (s *recordingAuditLogService) PurgeExpiredAuditLogs(context.Context) (int64, error) {
+	return 0, nil
+}
+
+// setUpAuditRouter は監査ミドルウェアを適用し、ユーザーIDを設定するルーターを生成します。
+func setUpAuditRouter(service *recordingAuditLogService, handler gin.HandlerFunc) *gin.Engine {
+	r := gin.New()
+	r.Use(middlewares.RequestIDMiddleware())
+	r.Use(middlewares.AuditMiddleware(service))
+	authenticated := func(c *gin.Context) { c.Set("userID", uint(7)) }
+	r.GET("/api/gin/cs/:id", authenticated, handler)
+	r.POST("/api/gin/cs", authenticated, handler)
+	r.DELETE("/api/gin/cs/:id", authenticated, handler)
+	r.POST("/api/gin/auth/google/process", handler)
+	return r
+}
+
+// TestAuditMiddlewareRecordsMutatingRequests は変更系のリクエストのみが記録されることを確認するテストです。
+func TestAuditMiddlewareRecordsMutatingRequests(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+
+	cases := []struct {
+		name       string
+		method     string
+		path       string
+		wantRecord bool
+		wantID     string
+		wantCID    uint
+	}{
+		{"GET is not recorded", http.MethodGet, "/api/gin/cs/3?cid=5", false, "", 0},
+		{"DELETE is recorded", http.MethodDelete, "/api/gin/cs/3?cid=5", true, "3", 5},
+		{"Unauthenticated request is not recorded", http.MethodPost, "/api/gin/auth/google/process", false, "", 0},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			service := &recordingAuditLogService{}
+			r := setUpAuditRouter(service, func(c *gin.Context) { c.Status(http.StatusOK) })
+
+			req, _ := http.NewRequest(tc.method, tc.path, nil)
+			r.ServeHTTP(httptest.NewRecorder(), req)
+
+			if got := len(service.entries) == 1; got != tc.wantRecord {
+				t.Fatalf("recorded = %v, want %v", got, tc.wantRecord)
+			}
+			if !tc.wantRecord {
+				return
+			}
+
+			entry := service.entries[0]
+			if entry.ActorUID != 7 || entry.Route != "/api/gin/cs/:id" || entry.ResourceType != "class_schedule" {
+				t.Errorf("entry = %+v, want actor 7 on class_schedule route", entry)
+			}
+			if entry.ResourceID != tc.wantID {
+				t.Errorf("resource id = %q, want %q", entry.ResourceID, tc.wantID)
+			}
+			if entry.CID == nil || *entry.CID != tc.wantCID {
+				t.Errorf("cid = %v, want %d", entry.CID, tc.wantCID)
+			}
+			if entry.RequestID == "" {
+				t.Error("request id should not be empty")
+			}
+		})
+	}
+}
+
+// TestAuditMiddlewareRedactsBody は機密項目がマスクされ、ハンドラーが元のボディを読めることを確認するテストです。
+func TestAuditMiddlewareRedactsBody(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+
+	cases := []struct {
+		name        string
+		contentType string
+		body        string
+		wantBody    string
+	}{
+		{"JSON", "application/json", `{"cid":5,"title":"第1回","secret":"1234","refresh_token":"abc"}`, `{"cid":5,"title":"第1回","secret":"[REDACTED]","refresh_token":"[REDACTED]"}`},
+		{"Form", "application/x-www-form-urlencoded", "cid=5&password=hunter2&title=a", "cid=5&password=[REDACTED]&title=a"},
+		{"Multipart is not captured", "multipart/form-data; boundary=x", "--x--", ""},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			service := &recordingAuditLogService{}
+			var handlerBody string
+			r := setUpAuditRouter(service, func(c *gin.Context) {
+				data, _ := io.ReadAll(c.Request.Body)
+				handlerBody = string(data)
+				c.Status(http.StatusCreated)
+			})
+
+			req, _ := http.NewRequest(http.MethodPost, "/api/gin/cs", strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", tc.contentType)
+			r.ServeHTTP(httptest.NewRecorder(), req)
+
+			if handlerBody != tc.body {
+				t.Errorf("handler body = %q, want %q", handlerBody, tc.body)
+			}
+			if len(service.entries) != 1 {
+				t.Fatalf("entries = %d, want 1", len(service.entries))
+			}
+			if got := service.entries[0].Body; got != tc.wantBody {
+				t.Errorf("audit body = %q, want %q", got, tc.wantBody)
+			}
+			if got := service.entries[0].StatusCode; got != http.StatusCreated {
+				t.Errorf("status = %d, want %d", got, http.StatusCreated)
+			}
+		})
+	}
+}
+
+// TestAuditMiddlewareTruncatesBody は長いボディが切り詰められ、途中の機密項目もマスクされることを確認するテストです。
+func TestAuditMiddlewareTruncatesBody(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	service := &recordingAuditLogService{}
+	r := setUpAuditRouter(service, func(c *gin.Context) {
+		_, _ = io.ReadAll(c.Request.Body)
+	})
+
+	body := `{"content":"` + strings.Repeat("a", 2030) + `","password":"` + strings.Repeat("p", 100) + `"}`
+	req, _ := http.NewRequest(http.MethodPost, "/api/gin/cs", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	got := service.entries[0].Body
+	if !strings.HasSuffix(got, "...(truncated)") {
+		t.Errorf("audit body should be marked as truncated: %q", got[len(got)-40:])
+	}
+	if strings.Contains(got, "ppp") {
+		t.Error("truncated password value should be redacted")
+	}
+}
@@ -0,0 +1,120 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/constants"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/middlewares"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// newBodyLimitRouter はJSONのルートとmultipartのルートを持つ、ボディサイズの上限付きのルーターを生成します。
+func newBodyLimitRouter() *gin.Engine {
+	r := gin.New()
+	r.Use(middlewares.BodyLimitMiddleware(middlewares.BodyLimitConfig{
+		Default:   1024,
+		Overrides: map[string]int64{"POST /api/gin/cb": 8 * 1024},
+	}))
+	r.POST("/api/gin/chat/room/:scheduleId", func(c *gin.Context) {
+		var body map[string]string
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": constants.InvalidRequest})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"data": len(body["message"])})
+	})
+	r.POST("/api/gin/cb", func(c *gin.Context) {
+		fileHeader, err := c.FormFile("image")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": constants.InvalidRequest})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"data": fileHeader.Size})
+	})
+	return r
+}
+
+// multipartImageBody はsizeバイトの画像を添付したmultipart/form-dataのボディを生成します。
+func multipartImageBody(t *testing.T, size int) (*bytes.Buffer, string) {
+	t.Helper()
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, err := writer.CreateFormFile("image", "board.png")
+	if err != nil {
+		t.Fatalf("failed to create form file: %v", err)
+	}
+	_, _ = part.Write(bytes.Repeat([]byte{0x89}, size))
+	_ = writer.Close()
+	return body, writer.FormDataContentType()
+}
+
+// TestBodyLimitMiddleware はJSONとアップロードのルートで上限を超えるボディに、Content-Lengthの有無に関わらず413を返すことを確認するテストです。
+func TestBodyLimitMiddleware(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+
+	jsonBody := func(size int) ([]byte, string) {
+		data, _ := json.Marshal(map[string]string{"message": strings.Repeat("a", size)})
+		return data, "application/json"
+	}
+	imageBody := func(size int) ([]byte, string) {
+		body, contentType := multipartImageBody(t, size)
+		return body.Bytes(), contentType
+	}
+
+	cases := []struct {
+		name       string
+		path       string
+		body       func(int) ([]byte, string)
+		size       int
+		chunked    bool
+		wantStatus int
+	}{
+		{"JSON Within Limit", "/api/gin/chat/room/1", jsonBody, 100, false, http.StatusOK},
+		{"JSON Too Large", "/api/gin/chat/room/1", jsonBody, 2048, false, http.StatusRequestEntityTooLarge},
+		{"Chunked JSON Too Large", "/api/gin/chat/room/1", jsonBody, 2048, true, http.StatusRequestEntityTooLarge},
+		{"Upload Above Default Within Route Limit", "/api/gin/cb", imageBody, 4096, false, http.StatusOK},
+		{"Upload Too Large", "/api/gin/cb", imageBody, 16 * 1024, false, http.StatusRequestEntityTooLarge},
+		{"Chunked Upload Too Large", "/api/gin/cb", imageBody, 16 * 1024, true, http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			data, contentType := tc.body(tc.size)
+			var reader io.Reader = bytes.NewReader(data)
+			if tc.chunked {
+				// Content-Lengthを持たないボディにする
+				reader = io.MultiReader(reader)
+			}
+			req, _ := http.NewRequest(http.MethodPost, tc.path, reader)
+			req.Header.Set("Content-Type", contentType)
+			if tc.chunked {
+				req.ContentLength = -1
+			}
+			resp := httptest.NewRecorder()
+			newBodyLimitRouter().ServeHTTP(resp, req)
+
+			if resp.Code != tc.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", resp.Code, tc.wantStatus, resp.Body.String())
+			}
+			if tc.wantStatus != http.StatusRequestEntityTooLarge {
+				return
+			}
+
+			var errResp utils.ErrorResponse
+			if err := json.Unmarshal(resp.Body.Bytes(), &errResp); err != nil {
+				t.Fatalf("failed to decode response %q: %v", resp.Body.String(), err)
+			}
+			if errResp.Code != constants.ErrCodeRequestTooLarge || errResp.Error != constants.RequestTooLarge {
+				t.Errorf("response = %+v, want code %q", errResp, constants.ErrCodeRequestTooLarge)
+			}
+		})
+	}
+}
@@ -0,0 +1,459 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/utils"
+	"golang.org/x/oauth2"
+	"gorm.io/gorm"
+)
+
+// memoryCalendarSyncRepo は同期の設定と予定の対応をメモリに保存するCalendarSyncRepositoryです。
+// FindSyncTargetsはクラスのメンバーかどうかを確認せず、同期が有効な全てのユーザーを返します。
+type memoryCalendarSyncRepo struct {
+	repositories.CalendarSyncRepository
+	mu      sync.Mutex
+	syncs   map[uint]*models.GoogleCalendarSync
+	classes map[uint][]uint
+	links   map[[2]uint]string
+}
+
+func newMemoryCalendarSyncRepo(syncs ...models.GoogleCalendarSync) *memoryCalendarSyncRepo {
+	r := &memoryCalendarSyncRepo{
+		syncs:   make(map[uint]*models.GoogleCalendarSync),
+		classes: make(map[uint][]uint),
+		links:   make(map[[2]uint]string),
+	}
+	for i := range syncs {
+		sync := syncs[i]
+		r.syncs[sync.UID] = &sync
+	}
+	return r
+}
+
+func (r *memoryCalendarSyncRepo) FindSync(_ context.Context, uid uint) (*models.GoogleCalendarSync, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	sync, ok := r.syncs[uid]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copied := *sync
+	return &copied, nil
+}
+
+func (r *memoryCalendarSyncRepo) UpdateAccessToken(_ context.Context, uid uint, accessToken string, expiry time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.syncs[uid].AccessToken = accessToken
+	r.syncs[uid].TokenExpiry = &expiry
+	return nil
+}
+
+func (r *memoryCalendarSyncRepo) UpdateSettings(_ context.Context, uid uint, enabled bool, cids []uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.syncs[uid].Enabled = enabled
+	r.classes[uid] = cids
+	return nil
+}
+
+func (r *memoryCalendarSyncRepo) DisableSync(_ context.Context, uid uint, reason string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.syncs[uid].Enabled = false
+	r.syncs[uid].DisabledReason = reason
+	r.syncs[uid].DisabledAt = &at
+	return nil
+}
+
+func (r *memoryCalendarSyncRepo) FindSyncClassIDs(_ context.Context, uid uint) ([]uint, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]uint(nil), r.classes[uid]...), nil
+}
+
+func (r *memoryCalendarSyncRepo) FindSyncTargets(_ context.Context, _ uint) ([]models.GoogleCalendarSync, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var targets []models.GoogleCalendarSync
+	for _, sync := range r.syncs {
+		if sync.Enabled && sync.Connected() {
+			targets = append(targets, *sync)
+		}
+	}
+	return targets, nil
+}
+
+func (r *memoryCalendarSyncRepo) FindEventLink(_ context.Context, uid uint, csid uint) (*models.CalendarEventLink, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	eventID, ok := r.links[[2]uint{uid, csid}]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &models.CalendarEventLink{UID: uid, CSID: csid, EventID: eventID}, nil
+}
+
+func (r *memoryCalendarSyncRepo) SaveEventLink(_ context.Context, link *models.CalendarEventLink) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.links[[2]uint{link.UID, link.CSID}] = link.EventID
+	return nil
+}
+
+func (r *memoryCalendarSyncRepo) DeleteEventLink(_ context.Context, uid uint, csid uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.links, [2]uint{uid, csid})
+	return nil
+}
+
+func (r *memoryCalendarSyncRepo) eventLink(uid uint, csid uint) (string, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	eventID, ok := r.links[[2]uint{uid, csid}]
+	return eventID, ok
+}
+
+func (r *memoryCalendarSyncRepo) current(uid uint) models.GoogleCalendarSync {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return *r.syncs[uid]
+}
+
+// calendarScheduleRepo はスケジュールをメモリに保存するClassScheduleRepositoryです。
+type calendarScheduleRepo struct {
+	repositories.ClassScheduleRepository
+	mu        sync.Mutex
+	schedules map[uint]models.ClassSchedule
+}
+
+func (r *calendarScheduleRepo) GetClassScheduleByID(_ context.Context, id uint) (*models.ClassSchedule, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	schedule, ok := r.schedules[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &schedule, nil
+}
+
+func (r *calendarScheduleRepo) GetAllClassSchedules(_ context.Context, cid uint) ([]models.ClassSchedule, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var schedules []models.ClassSchedule
+	for _, schedule := range r.schedules {
+		if schedule.CID == cid {
+			schedules = append(schedules, schedule)
+		}
+	}
+	return schedules, nil
+}
+
+func (r *calendarScheduleRepo) remove(id uint) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.schedules, id)
+}
+
+// calendarClassUserRepo はuid 1をクラス1の管理者、uid 2をメンバーとするClassUserRepositoryです。
+type calendarClassUserRepo struct {
+	repositories.ClassUserRepository
+}
+
+func (r *calendarClassUserRepo) IsAdmin(_ context.Context, uid uint, cid uint) (bool, error) {
+	return uid == 1 && cid == 1, nil
+}
+
+func (r *calendarClassUserRepo) GetRole(_ context.Context, uid uint, cid uint) (string, error) {
+	switch {
+	case cid != 1:
+		return "", gorm.ErrRecordNotFound
+	case uid == 1:
+		return "ADMIN", nil
+	case uid == 2:
+		return "USER", nil
+	}
+	return "APPLICANT", nil
+}
+
+// calendarServer はGoogle Calendar APIの予定の作成・変更・削除を記録するテスト用のサーバーです。
+type calendarServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	requests []string
+	events   map[string]map[string]interface{}
+}
+
+func newCalendarServer(t *testing.T) *calendarServer {
+	s := &calendarServer{events: make(map[string]map[string]interface{})}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.requests = append(s.requests, r.Method+" "+r.URL.Path+" "+r.Header.Get("Authorization"))
+		eventID := strings.TrimPrefix(r.URL.Path, "/events/")
+		var event map[string]interface{}
+		if r.Method != http.MethodDelete {
+			body, _ := io.ReadAll(r.Body)
+			if err := json.Unmarshal(body, &event); err != nil {
+				t.Errorf("body is not JSON: %s", body)
+			}
+		}
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/events":
+			eventID = fmt.Sprintf("event-%d", len(s.events)+1)
+		case r.Method == http.MethodPatch && s.events[eventID] != nil:
+		case r.Method == http.MethodDelete && s.events[eventID] != nil:
+			delete(s.events, eventID)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		s.events[eventID] = event
+		json.NewEncoder(w).Encode(map[string]string{"id": eventID})
+	}))
+	return s
+}
+
+func (s *calendarServer) received() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.requests...)
+}
+
+func (s *calendarServer) event(eventID string) map[string]interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.events[eventID]
+}
+
+// TestGoogleCalendarClient は見つからない予定の変更を作成に切り替え、削除済みの予定の削除を成功として扱うことを確認するテストです。
+func TestGoogleCalendarClient(t *testing.T) {
+	server := newCalendarServer(t)
+	defer server.Close()
+	client := utils.NewGoogleCalendarClient(server.URL + "/events/")
+	event := utils.CalendarEvent{
+		Summary: "第1回",
+		Start:   time.Date(2024, 4, 8, 9, 0, 0, 0, time.UTC),
+		End:     time.Date(2024, 4, 8, 10, 30, 0, 0, time.UTC),
+	}
+
+	eventID, err := client.UpsertEvent(context.Background(), http.DefaultClient, "removed", event)
+	if err != nil || eventID != "event-1" {
+		t.Fatalf("UpsertEvent() = %q, %v, want event-1", eventID, err)
+	}
+	if got := server.event("event-1"); got["start"].(map[string]interface{})["dateTime"] != "2024-04-08T09:00:00Z" || got["status"] != "confirmed" {
+		t.Errorf("event = %v, want start 2024-04-08T09:00:00Z and status confirmed", got)
+	}
+	if eventID, err := client.UpsertEvent(context.Background(), http.DefaultClient, "event-1", event); err != nil || eventID != "event-1" {
+		t.Errorf("UpsertEvent() = %q, %v, want event-1 to be updated", eventID, err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := client.DeleteEvent(context.Background(), http.DefaultClient, "event-1"); err != nil {
+			t.Errorf("DeleteEvent() #%d = %v, want nil", i+1, err)
+		}
+	}
+
+	want := []string{"PATCH /events/removed ", "POST /events ", "PATCH /events/event-1 ", "DELETE /events/event-1 ", "DELETE /events/event-1 "}
+	if got := server.received(); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("requests = %q, want %q", got, want)
+	}
+}
+
+// TestCalendarSyncSchedule はスケジュールの変更を同期しているユーザーの予定に反映し、削除されたスケジュールの予定を削除することを確認するテストです。
+func TestCalendarSyncSchedule(t *testing.T) {
+	server := newCalendarServer(t)
+	defer server.Close()
+	expiry := time.Now().Add(time.Hour)
+	repo := newMemoryCalendarSyncRepo(models.GoogleCalendarSync{UID: 2, Enabled: true, AccessToken: "access", RefreshToken: "refresh", TokenExpiry: &expiry})
+	schedules := &calendarScheduleRepo{schedules: map[uint]models.ClassSchedule{
+		10: {ID: 10, CID: 1, Title: "第1回", Location: "3号館301", IsCancelled: true, Class: models.Class{Name: "情報処理"},
+			StartedAt: time.Date(2024, 4, 8, 9, 0, 0, 0, time.UTC), EndedAt: time.Date(2024, 4, 8, 10, 30, 0, 0, time.UTC)},
+	}}
+	service := services.NewCalendarSyncService(repo, schedules, &calendarClassUserRepo{}, nil, services.CalendarSyncConfig{
+		Enabled: true,
+		OAuth:   &oauth2.Config{},
+		Client:  utils.NewGoogleCalendarClient(server.URL + "/events"),
+		AppURL:  "https://minoriedu.com/",
+	})
+
+	service.SyncSchedule(1, 10)
+	waitFor(t, "event link", func() bool {
+		_, ok := repo.eventLink(2, 10)
+		return ok
+	})
+	eventID, _ := repo.eventLink(2, 10)
+	event := server.event(eventID)
+	if event["summary"] != "【休講】[情報処理] 第1回" || event["transparency"] != "transparent" || event["location"] != "3号館301" {
+		t.Errorf("event = %v, want the cancelled schedule as a transparent event", event)
+	}
+	if event["description"] != "https://minoriedu.com/classes/1/schedules/10" {
+		t.Errorf("description = %v, want the link to the schedule", event["description"])
+	}
+
+	schedules.remove(10)
+	service.SyncSchedule(1, 10)
+	waitFor(t, "deleted event link", func() bool {
+		_, ok := repo.eventLink(2, 10)
+		return !ok
+	})
+	if server.event(eventID) != nil {
+		t.Errorf("event %s was not deleted", eventID)
+	}
+	for _, request := range server.received() {
+		if !strings.HasSuffix(request, "Bearer access") {
+			t.Errorf("request %q was not authorized with the saved access token", request)
+		}
+	}
+}
+
+// TestCalendarSyncRevokedToken はトークンを更新できない場合に同期を停止し、ユーザーに通知することを確認するテストです。
+func TestCalendarSyncRevokedToken(t *testing.T) {
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		io.WriteString(w, `{"error":"invalid_grant","error_description":"Token has been expired or revoked."}`)
+	}))
+	defer tokenServer.Close()
+	server := newCalendarServer(t)
+	defer server.Close()
+
+	expired := time.Now().Add(-time.Hour)
+	repo := newMemoryCalendarSyncRepo(models.GoogleCalendarSync{UID: 2, Enabled: true, AccessToken: "access", RefreshToken: "revoked", TokenExpiry: &expired})
+	schedules := &calendarScheduleRepo{schedules: map[uint]models.ClassSchedule{10: {ID: 10, CID: 1, Title: "第1回"}}}
+	notifier := &lockedNotifier{}
+	service := services.NewCalendarSyncService(repo, schedules, &calendarClassUserRepo{}, notifier, services.CalendarSyncConfig{
+		Enabled: true,
+		OAuth:   &oauth2.Config{Endpoint: oauth2.Endpoint{TokenURL: tokenServer.URL, AuthStyle: oauth2.AuthStyleInParams}},
+		Client:  utils.NewGoogleCalendarClient(server.URL + "/events"),
+	})
+
+	service.SyncSchedule(1, 10)
+	waitFor(t, "notification", func() bool { return len(notifier.notified()) == 1 })
+	sync := repo.current(2)
+	if sync.Enabled || !strings.Contains(sync.DisabledReason, "invalid_grant") || sync.DisabledAt == nil {
+		t.Errorf("sync = %+v, want it to be disabled with the reason", sync)
+	}
+	if got := notifier.notified(); got[0] != 2 {
+		t.Errorf("notified = %v, want [2]", got)
+	}
+	if got := server.received(); len(got) != 0 {
+		t.Errorf("requests = %q, want no request to the calendar", got)
+	}
+}
+
+// TestCalendarSyncSettings は同期の設定の変更とクラスの同期で、権限・接続・同期するクラスを確認することを確認するテストです。
+func TestCalendarSyncSettings(t *testing.T) {
+	enabled, disabled := true, false
+	connected := models.GoogleCalendarSync{UID: 1, AccessToken: "access", RefreshToken: "refresh"}
+	revoked := models.GoogleCalendarSync{UID: 1, RefreshToken: "refresh", DisabledReason: "invalid_grant"}
+
+	cases := []struct {
+		name    string
+		syncs   []models.GoogleCalendarSync
+		uid     uint
+		request dto.CalendarSyncSettingsRequest
+		want    error
+	}{
+		{"enable", []models.GoogleCalendarSync{connected}, 1, dto.CalendarSyncSettingsRequest{Enabled: &enabled, CIDs: []uint{1, 1}}, nil},
+		{"disable without connection", nil, 1, dto.CalendarSyncSettingsRequest{Enabled: &disabled}, nil},
+		{"enable without connection", nil, 1, dto.CalendarSyncSettingsRequest{Enabled: &enabled}, services.ErrCalendarNotConnected},
+		{"enable after revoked", []models.GoogleCalendarSync{revoked}, 1, dto.CalendarSyncSettingsRequest{Enabled: &enabled}, services.ErrCalendarNotConnected},
+		{"class of others", []models.GoogleCalendarSync{connected}, 1, dto.CalendarSyncSettingsRequest{Enabled: &enabled, CIDs: []uint{2}}, services.ErrUnauthorized},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := newMemoryCalendarSyncRepo(tc.syncs...)
+			service := services.NewCalendarSyncService(repo, &calendarScheduleRepo{}, &calendarClassUserRepo{}, nil, services.CalendarSyncConfig{Enabled: true})
+			settings, err := service.UpdateSettings(context.Background(), tc.uid, tc.request)
+			if !errors.Is(err, tc.want) {
+				t.Fatalf("UpdateSettings() error = %v, want %v", err, tc.want)
+			}
+			if err == nil && (settings.Enabled != *tc.request.Enabled || settings.Connected != (len(tc.syncs) > 0) || len(settings.CIDs) != len(repo.classes[tc.uid])) {
+				t.Errorf("settings = %+v, want enabled %v", settings, *tc.request.Enabled)
+			}
+		})
+	}
+
+	t.Run("unavailable", func(t *testing.T) {
+		service := services.NewCalendarSyncService(newMemoryCalendarSyncRepo(), &calendarScheduleRepo{}, &calendarClassUserRepo{}, nil, services.CalendarSyncConfig{})
+		if _, err := service.GetSettings(context.Background(), 1); !errors.Is(err, services.ErrCalendarSyncUnavailable) {
+			t.Errorf("GetSettings() error = %v, want ErrCalendarSyncUnavailable", err)
+		}
+	})
+}
+
+// TestCalendarSyncClass はクラスのメンバーが同期しているクラスの既存のスケジュールを全て同期に予約できることを確認するテストです。
+func TestCalendarSyncClass(t *testing.T) {
+	sync := models.GoogleCalendarSync{UID: 2, Enabled: true, AccessToken: "access", RefreshToken: "refresh"}
+	disabledSync := models.GoogleCalendarSync{UID: 2, AccessToken: "access", RefreshToken: "refresh"}
+
+	cases := []struct {
+		name    string
+		syncs   []models.GoogleCalendarSync
+		classes []uint
+		uid     uint
+		cid     uint
+		want    error
+		queued  int
+	}{
+		{"member", []models.GoogleCalendarSync{sync}, nil, 2, 1, nil, 2},
+		{"restricted to the class", []models.GoogleCalendarSync{sync}, []uint{1}, 2, 1, nil, 2},
+		{"restricted to other classes", []models.GoogleCalendarSync{sync}, []uint{3}, 2, 1, services.ErrCalendarClassNotSynced, 0},
+		{"not a member", []models.GoogleCalendarSync{sync}, nil, 2, 2, services.ErrUnauthorized, 0},
+		{"applicant", nil, nil, 3, 1, services.ErrUnauthorized, 0},
+		{"sync disabled", []models.GoogleCalendarSync{disabledSync}, nil, 2, 1, services.ErrCalendarNotConnected, 0},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := newMemoryCalendarSyncRepo(tc.syncs...)
+			repo.classes[tc.uid] = tc.classes
+			schedules := &calendarScheduleRepo{schedules: map[uint]models.ClassSchedule{
+				10: {ID: 10, CID: 1, Title: "第1回"},
+				11: {ID: 11, CID: 1, Title: "第2回"},
+				20: {ID: 20, CID: 2, Title: "第1回"},
+			}}
+			server := newCalendarServer(t)
+			defer server.Close()
+			service := services.NewCalendarSyncService(repo, schedules, &calendarClassUserRepo{}, nil, services.CalendarSyncConfig{
+				Enabled: true,
+				OAuth:   &oauth2.Config{},
+				Client:  utils.NewGoogleCalendarClient(server.URL + "/events"),
+			})
+			result, err := service.SyncClass(context.Background(), tc.uid, tc.cid)
+			if !errors.Is(err, tc.want) {
+				t.Fatalf("SyncClass() error = %v, want %v", err, tc.want)
+			}
+			if err != nil {
+				return
+			}
+			if result.Queued != tc.queued {
+				t.Errorf("queued = %d, want %d", result.Queued, tc.queued)
+			}
+			waitFor(t, "event links", func() bool {
+				_, first := repo.eventLink(tc.uid, 10)
+				_, second := repo.eventLink(tc.uid, 11)
+				return first && second
+			})
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/controllers"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/middlewares"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"github.com/gin-gonic/gin"
+)
+
+// TestCalendarTokenScope はカレンダー購読用のトークンをAPIの認証に使えず、
+// アクセストークンやリフレッシュトークンをカレンダー購読用のトークンとして使えないことを確認するテストです。
+func TestCalendarTokenScope(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	jwtService := services.NewJWTService("test-secret")
+	access, _ := jwtService.GenerateToken(7)
+	refresh, _ := jwtService.GenerateRefreshToken(7)
+	calendar, _ := jwtService.GenerateCalendarToken(7)
+
+	r := gin.New()
+	r.GET("/api", middlewares.TokenAuthMiddleware(jwtService), func(c *gin.Context) { c.String(http.StatusOK, "%d", c.GetUint("userID")) })
+	r.GET("/feed", middlewares.CalendarTokenMiddleware(jwtService), func(c *gin.Context) { c.String(http.StatusOK, "%d", c.GetUint("userID")) })
+
+	cases := []struct {
+		name       string
+		path       string
+		header     string
+		wantStatus int
+	}{
+		{"Access Token", "/api", "Bearer " + access, http.StatusOK},
+		{"Refresh Token", "/api", "Bearer " + refresh, http.StatusUnauthorized},
+		{"Calendar Token As Bearer", "/api", "Bearer " + calendar, http.StatusUnauthorized},
+		{"Calendar Token", "/feed?token=" + calendar, "", http.StatusOK},
+		{"Access Token As Calendar Token", "/feed?token=" + access, "", http.StatusUnauthorized},
+		{"Refresh Token As Calendar Token", "/feed?token=" + refresh, "", http.StatusUnauthorized},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, tc.path, nil)
+			if tc.header != "" {
+				req.Header.Set("Authorization", tc.header)
+			}
+			resp := httptest.NewRecorder()
+			r.ServeHTTP(resp, req)
+
+			if resp.Code != tc.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", resp.Code, tc.wantStatus, resp.Body.String())
+			}
+			if tc.wantStatus == http.StatusOK && resp.Body.String() != "7" {
+				t.Errorf("userID = %s, want 7", resp.Body.String())
+			}
+		})
+	}
+}
+
+// icalScheduleRepo はクラスのスケジュールを1件返すClassScheduleRepositoryです。
+type icalScheduleRepo struct {
+	repositories.ClassScheduleRepository
+}
+
+func (r *icalScheduleRepo) GetAllClassSchedules(_ context.Context, cid uint) ([]models.ClassSchedule, error) {
+	return []models.ClassSchedule{{ID: 1, CID: cid, Title: "第1回"}}, nil
+}
+
+// TestExportICalPermission はクラスのiCalendarをクラスのメンバーのみが取得でき、
+// ユーザーのiCalendarや購読URLを本人以外が取得しようとした場合に403を返すことを確認するテストです。
+func TestExportICalPermission(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+
+	cases := []struct {
+		name       string
+		path       string
+		userID     uint
+		wantStatus int
+	}{
+		{"Class Member", "/cs/export/class/10", 1, http.StatusOK},
+		{"Class Assistant", "/cs/export/class/10", 2, http.StatusOK},
+		{"Applicant", "/cs/export/class/10", 3, http.StatusForbidden},
+		{"Blacklisted", "/cs/export/class/10", 4, http.StatusForbidden},
+		{"Not A Member", "/cs/export/class/10", 5, http.StatusForbidden},
+		{"Other User", "/cs/export/user/1", 5, http.StatusForbidden},
+		{"Other User Subscription", "/cs/export/user/1/subscription", 5, http.StatusForbidden},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			classUserRepo := &roleClassUserRepo{roles: map[uint]string{1: "USER", 2: "ASSISTANT", 3: "APPLICANT", 4: "BLACKLIST"}}
+			service := services.NewClassScheduleService(&icalScheduleRepo{}, classUserRepo, nil, nil, true, nil, nil, nil, nil, services.CheckInTokenConfig{}, nil)
+			controller := controllers.NewClassScheduleController(service, services.NewJWTService("test-secret"))
+
+			r := gin.New()
+			r.Use(func(c *gin.Context) { c.Set("userID", tc.userID) })
+			r.GET("/cs/export/class/:cid", controller.ExportClassICal)
+			r.GET("/cs/export/user/:uid", controller.ExportUserICal)
+			r.GET("/cs/export/user/:uid/subscription", controller.GetCalendarSubscriptionURL)
+
+			req, _ := http.NewRequest(http.MethodGet, tc.path, nil)
+			resp := httptest.NewRecorder()
+			r.ServeHTTP(resp, req)
+
+			if resp.Code != tc.wantStatus {
+				t.Errorf("status = %d, want %d: %s", resp.Code, tc.wantStatus, resp.Body.String())
+			}
+		})
+	}
+}
+
+// TestRedactPath はアクセスログに出力するパスからtokenクエリの値だけを伏せることを確認するテストです。
+func TestRedactPath(t *testing.T) {
+	cases := []struct {
+		path string
+		want string
+	}{
+		{"/api/gin/cs/export/user/1", "/api/gin/cs/export/user/1"},
+		{"/api/gin/cs/export/user/1?token=secret", "/api/gin/cs/export/user/1?token=REDACTED"},
+		{"/api/gin/ws?room=3&token=secret", "/api/gin/ws?room=3&token=REDACTED"},
+		{"/api/gin/cs/1?date=2024-04-01", "/api/gin/cs/1?date=2024-04-01"},
+	}
+	for _, tc := range cases {
+		if got := middlewares.RedactPath(tc.path); got != tc.want {
+			t.Errorf("RedactPath(%q) = %q, want %q", tc.path, got, tc.want)
+		}
+	}
+}
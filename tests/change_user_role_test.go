@@ -0,0 +1,76 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/controllers"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/middlewares"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"github.com/gin-gonic/gin"
+)
+
+// roleChangeClassUserService はログイン中のユーザーのロールを返し、ロールの変更を記録するClassUserServiceです。
+type roleChangeClassUserService struct {
+	services.ClassUserService
+	callerRole string
+	assignErr  error
+	assigned   bool
+}
+
+func (s *roleChangeClassUserService) GetRole(context.Context, uint, uint) (string, error) {
+	return s.callerRole, nil
+}
+
+func (s *roleChangeClassUserService) AssignRole(context.Context, uint, uint, string) error {
+	s.assigned = true
+	return s.assignErr
+}
+
+// TestChangeUserRole はクラスの管理者のみロールを変更でき、サービスのエラーをエラーの種類に応じたステータスで返すことを確認するテストです。
+func TestChangeUserRole(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+
+	cases := []struct {
+		name         string
+		callerRole   string
+		assignErr    error
+		wantStatus   int
+		wantAssigned bool
+	}{
+		{"Admin", "ADMIN", nil, http.StatusOK, true},
+		{"Student Promotes Self", "USER", nil, http.StatusForbidden, false},
+		{"Applicant", "APPLICANT", nil, http.StatusForbidden, false},
+		{"Not Found", "ADMIN", services.ErrNotFound, http.StatusNotFound, true},
+		{"Active Class Limit", "ADMIN", services.ErrActiveClassLimit, http.StatusUnprocessableEntity, true},
+		{"Unexpected Error", "ADMIN", errors.New("connection refused"), http.StatusInternalServerError, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			service := &roleChangeClassUserService{callerRole: tc.callerRole, assignErr: tc.assignErr}
+			controller := controllers.NewClassUserController(service)
+			r := gin.New()
+			r.Use(middlewares.GlobalErrorHandler(&recordingErrorReporter{}))
+			r.Use(func(c *gin.Context) { c.Set("userID", uint(2)) })
+			r.PATCH("/cu/:uid/:cid/role/:roleName", controller.ChangeUserRole)
+			r.PATCH("/v2/cu/:cid/members/:uid/role/:roleName", controller.ChangeUserRole)
+
+			for _, path := range []string{"/cu/2/1/role/ADMIN", "/v2/cu/1/members/2/role/ADMIN"} {
+				service.assigned = false
+				req, _ := http.NewRequest(http.MethodPatch, path, nil)
+				resp := httptest.NewRecorder()
+				r.ServeHTTP(resp, req)
+
+				if resp.Code != tc.wantStatus {
+					t.Fatalf("%s: status = %d, want %d: %s", path, resp.Code, tc.wantStatus, resp.Body.String())
+				}
+				if service.assigned != tc.wantAssigned {
+					t.Errorf("%s: assigned = %v, want %v", path, service.assigned, tc.wantAssigned)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"mime/multipart"
+	"testing"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"gorm.io/gorm"
+)
+
+// memoryStickerRepo はメモリ上でスタンプを保持するChatStickerRepositoryです。
+type memoryStickerRepo struct {
+	repositories.ChatStickerRepository
+	stickers map[uint]models.ChatSticker
+}
+
+func (r *memoryStickerRepo) InsertChatSticker(_ context.Context, sticker *models.ChatSticker) error {
+	sticker.ID = uint(len(r.stickers) + 1)
+	r.stickers[sticker.ID] = *sticker
+	return nil
+}
+
+func (r *memoryStickerRepo) FindByID(_ context.Context, id uint) (*models.ChatSticker, error) {
+	sticker, ok := r.stickers[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &sticker, nil
+}
+
+// stickerScheduleRepo はスケジュールIDの10倍をクラスIDとして返すClassScheduleRepositoryです。
+type stickerScheduleRepo struct {
+	repositories.ClassScheduleRepository
+}
+
+func (r *stickerScheduleRepo) GetClassScheduleByID(_ context.Context, id uint) (*models.ClassSchedule, error) {
+	return &models.ClassSchedule{ID: id, CID: id * 10}, nil
+}
+
+// TestCreateSticker はクラスの管理者のみがスタンプを登録でき、画像がアップロードされることを確認するテストです。
+func TestCreateSticker(t *testing.T) {
+	cases := []struct {
+		name       string
+		admin      bool
+		wantErr    error
+		wantUpload bool
+	}{
+		{"Admin", true, nil, true},
+		{"Not Admin", false, services.ErrUnauthorized, false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := &memoryStickerRepo{stickers: map[uint]models.ChatSticker{}}
+			uploader := &stickerUploader{}
+			service := services.NewChatStickerService(repo, &adminClassUserRepo{admin: tc.admin}, &stickerScheduleRepo{}, uploader)
+
+			sticker, err := service.CreateSticker(context.Background(), 1, 10, dto.ChatStickerCreateDTO{Name: "good", Image: &multipart.FileHeader{Filename: "good.png"}})
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tc.wantErr)
+			}
+			if uploader.uploaded != tc.wantUpload {
+				t.Errorf("uploaded = %v, want %v", uploader.uploaded, tc.wantUpload)
+			}
+			if tc.wantErr == nil && (sticker.CID != 10 || sticker.ImageURL != stickerImageURL) {
+				t.Errorf("sticker = %+v, want class 10 with uploaded image", sticker)
+			}
+		})
+	}
+}
+
+// TestGetStickerForRoom はチャットルームのクラスに登録されたスタンプのみ送信できることを確認するテストです。
+func TestGetStickerForRoom(t *testing.T) {
+	repo := &memoryStickerRepo{stickers: map[uint]models.ChatSticker{
+		1: {ID: 1, Name: "good", CID: 10},
+		2: {ID: 2, Name: "other", CID: 20},
+	}}
+	service := services.NewChatStickerService(repo, &adminClassUserRepo{}, &stickerScheduleRepo{}, &stickerUploader{})
+
+	cases := []struct {
+		name      string
+		stickerID uint
+		roomID    string
+		wantErr   error
+	}{
+		{"Same Class", 1, "1", nil},
+		{"Other Class", 2, "1", services.ErrNotFound},
+		{"Unknown Sticker", 3, "1", services.ErrNotFound},
+		{"Invalid Room", 1, "abc", services.ErrNotFound},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			sticker, err := service.GetStickerForRoom(context.Background(), tc.stickerID, tc.roomID)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tc.wantErr)
+			}
+			if tc.wantErr == nil && sticker.ID != tc.stickerID {
+				t.Errorf("sticker id = %d, want %d", sticker.ID, tc.stickerID)
+			}
+		})
+	}
+}
+
+const stickerImageURL = "https://cdn.example.com/images/10/good.png"
+
+// stickerUploader はアップロードされたかを記録し、固定のURLを返すUploaderです。
+type stickerUploader struct {
+	recordingUploader
+	uploaded bool
+}
+
+func (u *stickerUploader) UploadImage(*multipart.FileHeader, uint, bool) (string, error) {
+	u.uploaded = true
+	return stickerImageURL, nil
+}
@@ -0,0 +1,104 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+)
+
+// TestChatMessageIDs はメッセージIDをルームごとの連番で採番し、履歴が期限切れで削除された後も
+// IDが戻らず、Last-Event-IDより後のメッセージのみを再送することを確認するテストです。
+func TestChatMessageIDs(t *testing.T) {
+	redisClient := openTestRedis(t)
+	manager := services.NewRoomManager(redisClient, nil)
+	ctx := context.Background()
+	const room = "940001"
+
+	for i := 1; i <= 3; i++ {
+		manager.Submit(ctx, "1", room, fmt.Sprintf("message %d", i))
+	}
+	// 履歴は1時間で期限切れになる
+	redisClient.Del(ctx, "chat:"+room)
+	manager.Submit(ctx, "1", room, "message 4")
+	manager.Submit(ctx, "2", room, "message 5")
+
+	cases := []struct {
+		name    string
+		limit   int
+		afterID int64
+		wantIDs []int64
+	}{
+		{"Recent", 10, 0, []int64{4, 5}},
+		{"Recent Limit", 1, 0, []int64{5}},
+		{"After Expired Messages", 10, 3, []int64{4, 5}},
+		{"After Last Event", 10, 4, []int64{5}},
+		{"Up To Date", 10, 5, []int64{}},
+		{"No History", 0, 0, []int64{}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			events, err := manager.GetRecentMessages(ctx, room, tc.limit, tc.afterID)
+			if err != nil {
+				t.Fatalf("err = %v", err)
+			}
+			ids := make([]int64, 0, len(events))
+			for _, event := range events {
+				ids = append(ids, event.ID)
+			}
+			if fmt.Sprint(ids) != fmt.Sprint(tc.wantIDs) {
+				t.Errorf("ids = %v, want %v", ids, tc.wantIDs)
+			}
+		})
+	}
+
+	messages, err := manager.GetRoomMessages(ctx, room)
+	if err != nil || len(messages) != 2 || messages[0].ID != 4 || messages[0].Text != "message 4" {
+		t.Errorf("messages = %+v, err = %v, want messages 4 and 5", messages, err)
+	}
+}
+
+// TestChatReplayLimit はLast-Event-IDによる再送を直近200件までに制限することを確認するテストです。
+func TestChatReplayLimit(t *testing.T) {
+	redisClient := openTestRedis(t)
+	manager := services.NewRoomManager(redisClient, nil)
+	ctx := context.Background()
+	const room = "940002"
+
+	for i := 1; i <= 250; i++ {
+		manager.Submit(ctx, "1", room, fmt.Sprintf("message %d", i))
+	}
+
+	events, err := manager.GetRecentMessages(ctx, room, 50, 1)
+	if err != nil {
+		t.Fatalf("err = %v", err)
+	}
+	if len(events) != 200 || events[0].ID != 51 || events[199].ID != 250 {
+		t.Errorf("got %d events from %d, want 200 events from 51", len(events), events[0].ID)
+	}
+}
+
+// TestChatStatsTTL は活動度ランキング用の発言数に有効期限を設定し、発言するたびに延長することを確認するテストです。
+func TestChatStatsTTL(t *testing.T) {
+	redisClient := openTestRedis(t)
+	manager := services.NewRoomManager(redisClient, nil)
+	ctx := context.Background()
+	const room = "940003"
+
+	manager.Submit(ctx, "1", room, "message 1")
+	redisClient.Expire(ctx, "chat_stats:"+room, time.Hour)
+	manager.Submit(ctx, "2", room, "message 2")
+
+	ttl, err := redisClient.TTL(ctx, "chat_stats:"+room).Result()
+	if err != nil {
+		t.Fatalf("err = %v", err)
+	}
+	if ttl <= 24*time.Hour {
+		t.Errorf("ttl = %v, want it extended past a day", ttl)
+	}
+	if counts := redisClient.HGetAll(ctx, "chat_stats:"+room).Val(); counts["1"] != "1" || counts["2"] != "1" {
+		t.Errorf("counts = %v, want one message each", counts)
+	}
+}
@@ -0,0 +1,176 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/utils"
+	"github.com/go-redis/redis/v8"
+)
+
+// TestDeepLTranslator はAPIキーと翻訳先の言語を送信して翻訳と原文の言語を返し、エラーのステータスをエラーにすることを確認するテストです。
+func TestDeepLTranslator(t *testing.T) {
+	cases := []struct {
+		name    string
+		status  int
+		want    utils.Translation
+		wantErr bool
+	}{
+		{"Translated", http.StatusOK, utils.Translation{Text: "こんにちは", SourceLang: "EN"}, false},
+		{"Quota Exceeded", 456, utils.Translation{}, true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if got := r.Header.Get("Authorization"); got != "DeepL-Auth-Key key" {
+					t.Errorf("Authorization = %q", got)
+				}
+				if r.PostFormValue("text") != "Hello" || r.PostFormValue("target_lang") != "JA" {
+					t.Errorf("form = %v", r.PostForm)
+				}
+				w.WriteHeader(tc.status)
+				_ = json.NewEncoder(w).Encode(map[string]interface{}{
+					"translations": []map[string]string{{"detected_source_language": "EN", "text": "こんにちは"}},
+				})
+			}))
+			defer server.Close()
+
+			translation, err := utils.NewDeepLTranslator(server.URL, "key").Translate(context.Background(), "Hello", "JA")
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tc.wantErr)
+			}
+			if err == nil && *translation != tc.want {
+				t.Errorf("translation = %+v, want %+v", *translation, tc.want)
+			}
+		})
+	}
+}
+
+// countingTranslator は原文の前に言語コードを付けて返し、呼び出し回数を数えるTranslatorです。
+type countingTranslator struct {
+	calls int
+}
+
+func (t *countingTranslator) Translate(_ context.Context, text string, targetLang string) (*utils.Translation, error) {
+	t.calls++
+	return &utils.Translation{Text: targetLang + ":" + text, SourceLang: "EN"}, nil
+}
+
+// failingTranslator は常にエラーを返すTranslatorです。
+type failingTranslator struct{}
+
+func (failingTranslator) Translate(context.Context, string, string) (*utils.Translation, error) {
+	return nil, errors.New("translation api responded with status 456")
+}
+
+// newChatTranslationService はルームのスケジュールがクラス1のもので、speakRolesのロールを持つChatTranslationServiceを生成します。
+func newChatTranslationService(redisClient *redis.Client, translator utils.Translator) services.ChatTranslationService {
+	scheduleRepo := &materialScheduleRepo{schedule: models.ClassSchedule{ID: 1, CID: 1}}
+	return services.NewChatTranslationService(redisClient, translator, scheduleRepo, &speakRoleClassUserRepo{roles: speakRoles})
+}
+
+// TestTranslateMessageUnavailable は翻訳APIが設定されていない場合にErrTranslationUnavailableを返すことを確認するテストです。
+func TestTranslateMessageUnavailable(t *testing.T) {
+	_, err := services.NewChatTranslationService(nil, nil, nil, nil).TranslateMessage(context.Background(), 3, "1", 1, "JA")
+	if !errors.Is(err, services.ErrTranslationUnavailable) {
+		t.Errorf("err = %v, want %v", err, services.ErrTranslationUnavailable)
+	}
+}
+
+// TestTranslateMessage は同じメッセージと言語の翻訳をキャッシュから返し、原文が変わった場合は翻訳し直し、
+// スタンプと存在しないメッセージを翻訳しないことを確認するテストです。
+func TestTranslateMessage(t *testing.T) {
+	redisClient := openTestRedis(t)
+	ctx := context.Background()
+	const room = "930001"
+	keys := []string{"chat:" + room, "chat_translation:" + room + ":1:JA"}
+	redisClient.Del(ctx, keys...)
+	t.Cleanup(func() {
+		redisClient.Del(ctx, keys...)
+	})
+	push := func(message dto.ChatMessageDTO) string {
+		data, _ := json.Marshal(message)
+		return string(data)
+	}
+	redisClient.RPush(ctx, keys[0],
+		push(dto.ChatMessageDTO{Type: dto.ChatMessageTypeText, User: "1", Text: "Hello"}),
+		push(dto.ChatMessageDTO{Type: dto.ChatMessageTypeSticker, User: "1", Sticker: &dto.ChatStickerDTO{ID: 1}}),
+	)
+
+	translator := &countingTranslator{}
+	service := newChatTranslationService(redisClient, translator)
+	for i := 0; i < 2; i++ {
+		translation, err := service.TranslateMessage(ctx, 3, room, 1, "JA")
+		if err != nil {
+			t.Fatalf("err = %v", err)
+		}
+		want := dto.ChatTranslationDTO{MessageID: 1, OriginalText: "Hello", TranslatedText: "JA:Hello", SourceLang: "EN", TargetLang: "JA"}
+		if *translation != want {
+			t.Errorf("translation = %+v, want %+v", *translation, want)
+		}
+	}
+	if translator.calls != 1 {
+		t.Errorf("translator was called %d times, want 1", translator.calls)
+	}
+
+	// ルームを作り直して同じIDが別のメッセージになった場合
+	redisClient.LSet(ctx, keys[0], 0, push(dto.ChatMessageDTO{Type: dto.ChatMessageTypeText, User: "2", Text: "Bye"}))
+	if translation, err := service.TranslateMessage(ctx, 3, room, 1, "JA"); err != nil || translation.TranslatedText != "JA:Bye" {
+		t.Errorf("translation = %+v, err = %v, want JA:Bye", translation, err)
+	}
+
+	if _, err := service.TranslateMessage(ctx, 3, room, 2, "JA"); !errors.Is(err, services.ErrNotTranslatable) {
+		t.Errorf("sticker err = %v, want %v", err, services.ErrNotTranslatable)
+	}
+	if _, err := service.TranslateMessage(ctx, 3, room, 3, "JA"); !errors.Is(err, services.ErrNotFound) {
+		t.Errorf("missing message err = %v, want %v", err, services.ErrNotFound)
+	}
+}
+
+// TestTranslateMessageAccess はルームのクラスのメンバー以外の翻訳を拒否し、翻訳APIの呼び出しをユーザーごとに1分間30件までに制限し、
+// キャッシュした翻訳は数えず、翻訳APIの失敗をErrTranslationFailedとすることを確認するテストです。
+func TestTranslateMessageAccess(t *testing.T) {
+	redisClient := openTestRedis(t)
+	ctx := context.Background()
+	const room = "930002"
+	data, _ := json.Marshal(dto.ChatMessageDTO{Type: dto.ChatMessageTypeText, User: "1", Text: "Hello"})
+	redisClient.RPush(ctx, "chat:"+room, string(data))
+
+	service := newChatTranslationService(redisClient, &countingTranslator{})
+	for i := 0; i < services.ChatTranslationRateLimit; i++ {
+		if _, err := service.TranslateMessage(ctx, 4, room, 1, fmt.Sprintf("L%d", i)); err != nil {
+			t.Fatalf("translation %d err = %v", i, err)
+		}
+	}
+
+	cases := []struct {
+		name    string
+		service services.ChatTranslationService
+		uid     uint
+		lang    string
+		want    error
+	}{
+		{"Applicant", service, 5, "JA", services.ErrUnauthorized},
+		{"Not A Member", service, 9, "JA", services.ErrUnauthorized},
+		{"Rate Limited", service, 4, "JA", services.ErrTranslationRateLimit},
+		{"Cached While Rate Limited", service, 4, "L0", nil},
+		{"Other User", service, 3, "JA", nil},
+		{"Translator Failed", newChatTranslationService(redisClient, failingTranslator{}), 2, "KO", services.ErrTranslationFailed},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := tc.service.TranslateMessage(ctx, tc.uid, room, 1, tc.lang); !errors.Is(err, tc.want) {
+				t.Errorf("err = %v, want %v", err, tc.want)
+			}
+		})
+	}
+}
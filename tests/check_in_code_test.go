@@ -0,0 +1,114 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"github.com/go-redis/redis/v8"
+)
+
+// newCheckInService はrequiredで確認コードの要否を指定したスケジュール(ID 1)を扱うClassScheduleServiceを生成します。
+func newCheckInService(redisClient *redis.Client, role string, required bool) services.ClassScheduleService {
+	repo := &materialScheduleRepo{schedule: models.ClassSchedule{ID: 1, CID: 1, RequireCheckInCode: required}}
+	return services.NewClassScheduleService(repo, &materialClassUserRepo{role: role}, redisClient, nil, true, nil, nil, nil, nil, services.CheckInTokenConfig{}, nil)
+}
+
+// TestGetCheckInCodeUnauthorized は講師・アシスタント以外は確認コードを取得できないことを確認するテストです。
+func TestGetCheckInCodeUnauthorized(t *testing.T) {
+	for _, role := range []string{"USER", "APPLICANT"} {
+		t.Run(role, func(t *testing.T) {
+			if _, err := newCheckInService(nil, role, true).GetCheckInCode(context.Background(), 11, 1); !errors.Is(err, services.ErrUnauthorized) {
+				t.Errorf("err = %v, want %v", err, services.ErrUnauthorized)
+			}
+		})
+	}
+}
+
+// TestVerifyCheckInCodeNotRequired は確認コードを必須にしていないスケジュールでは、コードなしでチェックインできることを確認するテストです。
+func TestVerifyCheckInCodeNotRequired(t *testing.T) {
+	if err := newCheckInService(nil, "USER", false).VerifyCheckInCode(context.Background(), 11, 1, ""); err != nil {
+		t.Errorf("err = %v, want nil", err)
+	}
+}
+
+// TestCheckInCode は更新されるまで同じ確認コードを返し、一致するコードのみ受け付け、
+// 入力ミスが上限に達した後は正しいコードも受け付けないことを確認するテストです。
+func TestCheckInCode(t *testing.T) {
+	redisClient := openTestRedis(t)
+	ctx := context.Background()
+	keys := []string{"check_in_code:1", "check_in_code_attempts:1:11", "check_in_code_attempts:1:12"}
+	redisClient.Del(ctx, keys...)
+	t.Cleanup(func() {
+		redisClient.Del(ctx, keys...)
+	})
+
+	teacher := newCheckInService(redisClient, "ADMIN", true)
+	code, err := teacher.GetCheckInCode(ctx, 1, 1)
+	if err != nil {
+		t.Fatalf("err = %v", err)
+	}
+	if !regexp.MustCompile(`^[0-9]{4}$`).MatchString(code.Code) {
+		t.Errorf("code = %q, want 4 digits", code.Code)
+	}
+	if again, err := teacher.GetCheckInCode(ctx, 1, 1); err != nil || again.Code != code.Code {
+		t.Errorf("code = %+v, err = %v, want %q until it expires", again, err, code.Code)
+	}
+
+	n, _ := strconv.Atoi(code.Code)
+	wrong := fmt.Sprintf("%04d", (n+1)%10000)
+	student := newCheckInService(redisClient, "USER", true)
+	if err := student.VerifyCheckInCode(ctx, 11, 1, wrong); !errors.Is(err, services.ErrInvalidCheckInCode) {
+		t.Errorf("wrong code err = %v, want %v", err, services.ErrInvalidCheckInCode)
+	}
+	if err := student.VerifyCheckInCode(ctx, 11, 1, code.Code); err != nil {
+		t.Errorf("correct code err = %v, want nil", err)
+	}
+
+	// 総当たりを防ぐため、入力ミスが上限に達したユーザーは正しいコードでもチェックインできない
+	for i := 0; i < 5; i++ {
+		_ = student.VerifyCheckInCode(ctx, 12, 1, wrong)
+	}
+	if err := student.VerifyCheckInCode(ctx, 12, 1, code.Code); !errors.Is(err, services.ErrInvalidCheckInCode) {
+		t.Errorf("locked out err = %v, want %v", err, services.ErrInvalidCheckInCode)
+	}
+}
+
+// TestCheckinWithTokenRequiresCode は確認コードを必須にしたスケジュールでは、出席トークンがあっても確認コードなしではチェックインできないことを確認するテストです。
+func TestCheckinWithTokenRequiresCode(t *testing.T) {
+	redisClient := openTestRedis(t)
+	ctx := context.Background()
+	keys := []string{"check_in_code:1", "check_in_code_attempts:1:11"}
+	redisClient.Del(ctx, keys...)
+	t.Cleanup(func() {
+		redisClient.Del(ctx, keys...)
+	})
+
+	config := services.CheckInTokenConfig{Secret: []byte("secret")}
+	newService := func(role string) services.ClassScheduleService {
+		repo := &materialScheduleRepo{schedule: models.ClassSchedule{ID: 1, CID: 1, RequireCheckInCode: true}}
+		return services.NewClassScheduleService(repo, &materialClassUserRepo{role: role}, redisClient, nil, true, nil, nil, nil, nil, config, nil)
+	}
+	teacher := newService("ADMIN")
+	issued, err := teacher.IssueCheckInToken(ctx, 1, 1)
+	if err != nil {
+		t.Fatalf("err = %v", err)
+	}
+	code, err := teacher.GetCheckInCode(ctx, 1, 1)
+	if err != nil {
+		t.Fatalf("err = %v", err)
+	}
+
+	student := newService("USER")
+	if _, err := student.CheckinWithToken(ctx, 11, issued.Token, ""); !errors.Is(err, services.ErrInvalidCheckInCode) {
+		t.Errorf("without code err = %v, want %v", err, services.ErrInvalidCheckInCode)
+	}
+	if schedule, err := student.CheckinWithToken(ctx, 11, issued.Token, code.Code); err != nil || schedule.ID != 1 {
+		t.Errorf("schedule = %+v, err = %v, want schedule 1", schedule, err)
+	}
+}
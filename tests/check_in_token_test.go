@@ -0,0 +1,182 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"gorm.io/gorm"
+)
+
+// newCheckInTokenService はスケジュール(ID 12, クラス 1)の出席トークンを扱うClassScheduleServiceを生成します。
+// Redisのクライアントは渡さず、検証にRedisを使わないことも確認します。
+func newCheckInTokenService(role string, config services.CheckInTokenConfig) services.ClassScheduleService {
+	return newCheckInTokenServiceFor(models.ClassSchedule{ID: 12, CID: 1}, role, config)
+}
+
+// newCheckInTokenServiceFor は指定したスケジュールの出席トークンを扱うClassScheduleServiceを生成します。
+func newCheckInTokenServiceFor(schedule models.ClassSchedule, role string, config services.CheckInTokenConfig) services.ClassScheduleService {
+	return services.NewClassScheduleService(&materialScheduleRepo{schedule: schedule}, &materialClassUserRepo{role: role}, nil, nil, true, nil, nil, nil, nil, config, nil)
+}
+
+// TestCheckInToken は講師が発行した出席トークンで生徒がチェックインでき、改ざんしたトークン、別の鍵で署名したトークン、
+// 有効期限を過ぎたトークン、講師・アシスタント自身のチェックイン、休講のスケジュールとQRコードを受け付けない出席方式のスケジュールへのチェックインを
+// 受け付けないことを確認するテストです。
+func TestCheckInToken(t *testing.T) {
+	config := services.CheckInTokenConfig{Secret: []byte("secret"), TTL: time.Hour}
+	issued, err := newCheckInTokenService("ADMIN", config).IssueCheckInToken(context.Background(), 1, 12)
+	if err != nil {
+		t.Fatalf("err = %v", err)
+	}
+	if issued.CSID != 12 || !issued.ExpiresAt.After(time.Now().Add(59*time.Minute)) {
+		t.Fatalf("issued = %+v", issued)
+	}
+	expired, err := newCheckInTokenService("ADMIN", services.CheckInTokenConfig{Secret: []byte("secret"), TTL: time.Nanosecond}).IssueCheckInToken(context.Background(), 1, 12)
+	if err != nil {
+		t.Fatalf("err = %v", err)
+	}
+	parts := strings.Split(issued.Token, ".")
+	schedule := models.ClassSchedule{ID: 12, CID: 1}
+
+	cases := []struct {
+		name     string
+		schedule models.ClassSchedule
+		role     string
+		config   services.CheckInTokenConfig
+		token    string
+		wantErr  error
+	}{
+		{"Valid", schedule, "USER", config, issued.Token, nil},
+		{"Hybrid", models.ClassSchedule{ID: 12, CID: 1, AttendanceMode: models.HybridMode}, "USER", config, issued.Token, nil},
+		{"Tampered Schedule", schedule, "USER", config, "13." + parts[1] + "." + parts[2], services.ErrInvalidCheckInToken},
+		{"Extended Expiry", schedule, "USER", config, parts[0] + ".9999999999." + parts[2], services.ErrInvalidCheckInToken},
+		{"Malformed", schedule, "USER", config, "not-a-token", services.ErrInvalidCheckInToken},
+		{"Other Secret", schedule, "USER", services.CheckInTokenConfig{Secret: []byte("other")}, issued.Token, services.ErrInvalidCheckInToken},
+		{"Expired", schedule, "USER", config, expired.Token, services.ErrCheckInTokenExpired},
+		{"Not Member", schedule, "APPLICANT", config, issued.Token, services.ErrUnauthorized},
+		{"Admin", schedule, "ADMIN", config, issued.Token, services.ErrUnauthorized},
+		{"Assistant", schedule, "ASSISTANT", config, issued.Token, services.ErrUnauthorized},
+		{"Cancelled", models.ClassSchedule{ID: 12, CID: 1, IsCancelled: true}, "USER", config, issued.Token, services.ErrScheduleCancelled},
+		{"Online", models.ClassSchedule{ID: 12, CID: 1, AttendanceMode: models.OnlineMode}, "USER", config, issued.Token, services.ErrCheckInMethodNotAllowed},
+		{"Not Configured", schedule, "USER", services.CheckInTokenConfig{}, issued.Token, services.ErrCheckInTokenUnavailable},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			schedule, err := newCheckInTokenServiceFor(tc.schedule, tc.role, tc.config).CheckinWithToken(context.Background(), 11, tc.token, "")
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tc.wantErr)
+			}
+			if tc.wantErr == nil && (schedule.ID != 12 || schedule.CID != 1) {
+				t.Fatalf("schedule = %+v", schedule)
+			}
+		})
+	}
+}
+
+// TestCheckInTokenDefaultTTL は有効期間を設定しない場合、出席トークンの有効期限を数分以内にすることを確認するテストです。
+func TestCheckInTokenDefaultTTL(t *testing.T) {
+	issued, err := newCheckInTokenService("ADMIN", services.CheckInTokenConfig{Secret: []byte("secret")}).IssueCheckInToken(context.Background(), 1, 12)
+	if err != nil {
+		t.Fatalf("err = %v", err)
+	}
+	if issued.ExpiresAt.After(time.Now().Add(2 * time.Minute)) {
+		t.Errorf("expires_at = %v, want within 2 minutes", issued.ExpiresAt)
+	}
+}
+
+// TestIssueCheckInTokenUnauthorized は講師・アシスタント以外は出席トークンを発行できないことを確認するテストです。
+func TestIssueCheckInTokenUnauthorized(t *testing.T) {
+	config := services.CheckInTokenConfig{Secret: []byte("secret")}
+	for _, role := range []string{"USER", "APPLICANT"} {
+		t.Run(role, func(t *testing.T) {
+			if _, err := newCheckInTokenService(role, config).IssueCheckInToken(context.Background(), 11, 12); !errors.Is(err, services.ErrUnauthorized) {
+				t.Errorf("err = %v, want %v", err, services.ErrUnauthorized)
+			}
+		})
+	}
+}
+
+// TestCheckInTokenRoleError はメンバーではないユーザーにはErrUnauthorizedを返し、
+// ロールの取得に失敗した場合はErrUnauthorizedにせずにそのエラーを返すことを確認するテストです。
+func TestCheckInTokenRoleError(t *testing.T) {
+	config := services.CheckInTokenConfig{Secret: []byte("secret"), TTL: time.Hour}
+	issued, err := newCheckInTokenService("ADMIN", config).IssueCheckInToken(context.Background(), 1, 12)
+	if err != nil {
+		t.Fatalf("err = %v", err)
+	}
+	dbErr := errors.New("connection refused")
+
+	cases := []struct {
+		name    string
+		err     error
+		wantErr error
+	}{
+		{"Not Member", gorm.ErrRecordNotFound, services.ErrUnauthorized},
+		{"Database Error", dbErr, dbErr},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			service := services.NewClassScheduleService(&materialScheduleRepo{schedule: models.ClassSchedule{ID: 12, CID: 1}}, &statsClassUserRepo{err: tc.err}, nil, nil, true, nil, nil, nil, nil, config, nil)
+			if _, err := service.IssueCheckInToken(context.Background(), 1, 12); !errors.Is(err, tc.wantErr) {
+				t.Errorf("issue err = %v, want %v", err, tc.wantErr)
+			}
+			if _, err := service.CheckinWithToken(context.Background(), 11, issued.Token, ""); !errors.Is(err, tc.wantErr) {
+				t.Errorf("check-in err = %v, want %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+// checkInAttendanceRepo はユーザーとスケジュールごとに1件の出席情報のみ保存するAttendanceRepositoryです。
+type checkInAttendanceRepo struct {
+	repositories.AttendanceRepository
+	saved map[[2]uint]models.Attendance
+}
+
+func (r *checkInAttendanceRepo) CreateAttendanceIfAbsent(_ context.Context, attendance *models.Attendance) (bool, error) {
+	key := [2]uint{attendance.UID, attendance.CSID}
+	if _, ok := r.saved[key]; ok {
+		return false, nil
+	}
+	r.saved[key] = *attendance
+	return true, nil
+}
+
+// TestRecordTokenCheckInIdempotent は接続の回復後に同じチェックインを再送しても、出席情報とイベントを重複して登録しないことを確認するテストです。
+func TestRecordTokenCheckInIdempotent(t *testing.T) {
+	repo := &checkInAttendanceRepo{saved: map[[2]uint]models.Attendance{}}
+	events := &recordingEventPublisher{}
+	service := services.NewAttendanceService(repo, nil, nil, nil, false, nil, events)
+	schedule := models.ClassSchedule{ID: 12, CID: 1}
+	scannedAt := time.Now().Add(-10 * time.Minute)
+
+	for i, want := range []bool{true, false} {
+		recorded, err := service.RecordTokenCheckIn(context.Background(), schedule, 11, &scannedAt)
+		if err != nil {
+			t.Fatalf("attempt %d: err = %v", i, err)
+		}
+		if recorded != want {
+			t.Fatalf("attempt %d: recorded = %v, want %v", i, recorded, want)
+		}
+	}
+
+	saved := repo.saved[[2]uint{11, 12}]
+	if len(repo.saved) != 1 || saved.CID != 1 || saved.IsAttendance != models.AttendanceStatus || saved.Source != models.SelfSource {
+		t.Fatalf("saved = %+v", repo.saved)
+	}
+	// 記録時刻はサーバーの時刻で、読み取った時刻は参考値として保存する
+	if !saved.RecordedAt.After(scannedAt) || saved.ClientRecordedAt == nil || !saved.ClientRecordedAt.Equal(scannedAt) {
+		t.Fatalf("recorded_at = %v, client_recorded_at = %v", saved.RecordedAt, saved.ClientRecordedAt)
+	}
+	if len(events.events) != 1 || events.events[0].payload.(dto.EventAttendanceRecordedV1).Action != dto.AttendanceRecorded {
+		t.Fatalf("events = %+v", events.events)
+	}
+}
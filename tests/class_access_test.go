@@ -0,0 +1,201 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/constants"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/middlewares"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// accessClassRepo は1つのクラスのアクセス制限を保持するClassRepositoryです。
+type accessClassRepo struct {
+	repositories.ClassRepository
+	class *models.Class
+	saved bool
+}
+
+func (r *accessClassRepo) GetByID(_ context.Context, classID uint) (*models.Class, error) {
+	if r.class == nil || r.class.ID != classID {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return r.class, nil
+}
+
+func (r *accessClassRepo) SetAccessRestriction(_ context.Context, classID uint, startTime, endTime, allowedIPRanges *string) error {
+	if r.class == nil || r.class.ID != classID {
+		return gorm.ErrRecordNotFound
+	}
+	r.class.AccessStartTime, r.class.AccessEndTime, r.class.AllowedIPRanges = startTime, endTime, allowedIPRanges
+	r.saved = true
+	return nil
+}
+
+// TestSetAccessRestriction はアクセス制限の形式を検証し、IPアドレスのみの指定をレンジに正規化して保存することを確認するテストです。
+func TestSetAccessRestriction(t *testing.T) {
+	start, end, invalid := "09:00", "17:00", "25:00"
+	cases := []struct {
+		name        string
+		admin       bool
+		restriction dto.ClassAccessRestrictionDTO
+		wantErr     error
+		wantRanges  []string
+	}{
+		{"Time Window And Ranges", true, dto.ClassAccessRestrictionDTO{StartTime: &start, EndTime: &end, AllowedIPRanges: []string{"10.0.0.0/16", "192.168.1.5", "2001:db8::1"}}, nil, []string{"10.0.0.0/16", "192.168.1.5/32", "2001:db8::1/128"}},
+		{"Clear", true, dto.ClassAccessRestrictionDTO{}, nil, []string{}},
+		{"Start Without End", true, dto.ClassAccessRestrictionDTO{StartTime: &start}, services.ErrInvalidAccessRestriction, nil},
+		{"Invalid Time", true, dto.ClassAccessRestrictionDTO{StartTime: &start, EndTime: &invalid}, services.ErrInvalidAccessRestriction, nil},
+		{"Empty Window", true, dto.ClassAccessRestrictionDTO{StartTime: &start, EndTime: &start}, services.ErrInvalidAccessRestriction, nil},
+		{"Invalid Range", true, dto.ClassAccessRestrictionDTO{AllowedIPRanges: []string{"10.0.0.0/33"}}, services.ErrInvalidAccessRestriction, nil},
+		{"Not Admin", false, dto.ClassAccessRestrictionDTO{StartTime: &start, EndTime: &end}, services.ErrUnauthorized, nil},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := &accessClassRepo{class: &models.Class{ID: 5}}
+			service := services.NewClassAccessService(repo, &adminClassUserRepo{admin: tc.admin})
+
+			restriction, err := service.SetRestriction(context.Background(), 1, 5, tc.restriction)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tc.wantErr)
+			}
+			if repo.saved != (tc.wantErr == nil) {
+				t.Errorf("saved = %v, want %v", repo.saved, tc.wantErr == nil)
+			}
+			if err != nil {
+				return
+			}
+			if len(restriction.AllowedIPRanges) != len(tc.wantRanges) {
+				t.Fatalf("allowed_ip_ranges = %v, want %v", restriction.AllowedIPRanges, tc.wantRanges)
+			}
+			for i, want := range tc.wantRanges {
+				if restriction.AllowedIPRanges[i] != want {
+					t.Errorf("allowed_ip_ranges[%d] = %q, want %q", i, restriction.AllowedIPRanges[i], want)
+				}
+			}
+		})
+	}
+}
+
+// TestCheckAccess は時間帯(日をまたぐ場合を含む)とIPレンジでアクセスを判定し、制限のないクラスと存在しないクラスは許可することを確認するテストです。
+func TestCheckAccess(t *testing.T) {
+	day, night, morning := "09:00", "22:00", "06:00"
+	ranges := "10.0.0.0/16,192.168.1.5/32"
+	at := func(hour, minute int) time.Time {
+		return time.Date(2026, 4, 10, hour, minute, 0, 0, time.Local)
+	}
+
+	cases := []struct {
+		name    string
+		class   models.Class
+		cid     uint
+		ip      string
+		now     time.Time
+		wantErr error
+	}{
+		{"Unrestricted", models.Class{ID: 5}, 5, "203.0.113.1", at(3, 0), nil},
+		{"Unknown Class", models.Class{ID: 5, AccessStartTime: &day, AccessEndTime: &night}, 6, "203.0.113.1", at(3, 0), nil},
+		{"Inside Window", models.Class{ID: 5, AccessStartTime: &day, AccessEndTime: &night}, 5, "203.0.113.1", at(9, 0), nil},
+		{"Window End Is Exclusive", models.Class{ID: 5, AccessStartTime: &day, AccessEndTime: &night}, 5, "203.0.113.1", at(22, 0), services.ErrAccessRestricted},
+		{"Overnight Late", models.Class{ID: 5, AccessStartTime: &night, AccessEndTime: &morning}, 5, "203.0.113.1", at(23, 30), nil},
+		{"Overnight Early", models.Class{ID: 5, AccessStartTime: &night, AccessEndTime: &morning}, 5, "203.0.113.1", at(5, 59), nil},
+		{"Overnight Daytime", models.Class{ID: 5, AccessStartTime: &night, AccessEndTime: &morning}, 5, "203.0.113.1", at(12, 0), services.ErrAccessRestricted},
+		{"Inside Range", models.Class{ID: 5, AllowedIPRanges: &ranges}, 5, "10.0.200.3", at(12, 0), nil},
+		{"Single Address", models.Class{ID: 5, AllowedIPRanges: &ranges}, 5, "192.168.1.5", at(12, 0), nil},
+		{"Outside Range", models.Class{ID: 5, AllowedIPRanges: &ranges}, 5, "192.168.1.6", at(12, 0), services.ErrAccessRestricted},
+		{"Unparsable Client IP", models.Class{ID: 5, AllowedIPRanges: &ranges}, 5, "", at(12, 0), services.ErrAccessRestricted},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			class := tc.class
+			service := services.NewClassAccessService(&accessClassRepo{class: &class}, &adminClassUserRepo{})
+
+			if err := service.CheckAccess(context.Background(), tc.cid, tc.ip, tc.now); !errors.Is(err, tc.wantErr) {
+				t.Errorf("err = %v, want %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+// TestClassAccessMiddleware は制限外のクラスへのリクエストを403で拒否し、除外したルートとクラスを指定しないリクエストは通すことを確認するテストです。
+// パスにcidを含まないルートではリソースが属するクラスを、リクエストボディで指定したクラスはCheckClassAccessで確認します。
+func TestClassAccessMiddleware(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	ranges := "10.0.0.0/16"
+	service := services.NewClassAccessService(&accessClassRepo{class: &models.Class{ID: 5, AllowedIPRanges: &ranges}}, &adminClassUserRepo{})
+
+	// 掲示板1はクラス5、掲示板2はクラス6に属し、それ以外は存在しない
+	boards := map[uint]uint{1: 5, 2: 6}
+	resolveBoard := func(_ context.Context, id uint) (uint, error) { return boards[id], nil }
+	resources := []middlewares.ClassResource{
+		{RoutePrefix: "/boards/:id", Param: "id", Resolve: resolveBoard},
+		{Method: http.MethodDelete, RoutePrefix: "/schedules/:id", Param: "id", Resolve: resolveBoard},
+	}
+
+	r := gin.New()
+	r.Use(middlewares.ClassAccessMiddleware(service, resources, "/admin/classes/:cid/access-restriction"))
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	r.GET("/classes/:cid", ok)
+	r.GET("/boards", ok)
+	r.GET("/boards/:id", ok)
+	r.GET("/boards/:id/comments", ok)
+	r.GET("/schedules/:id", ok)
+	r.DELETE("/schedules/:id", ok)
+	r.GET("/admin/classes/:cid/access-restriction", ok)
+	r.POST("/check-in/:class", func(c *gin.Context) {
+		cid, _ := strconv.ParseUint(c.Param("class"), 10, 32)
+		if !middlewares.CheckClassAccess(c, uint(cid)) {
+			return
+		}
+		c.Status(http.StatusOK)
+	})
+
+	cases := []struct {
+		name       string
+		method     string
+		path       string
+		remoteAddr string
+		wantStatus int
+	}{
+		{"Allowed Address", http.MethodGet, "/classes/5", "10.0.1.1:1234", http.StatusOK},
+		{"Restricted Path", http.MethodGet, "/classes/5", "203.0.113.1:1234", constants.StatusForbidden},
+		{"Restricted Query", http.MethodGet, "/boards?cid=5", "203.0.113.1:1234", constants.StatusForbidden},
+		{"Other Class", http.MethodGet, "/classes/6", "203.0.113.1:1234", http.StatusOK},
+		{"No Class", http.MethodGet, "/boards", "203.0.113.1:1234", http.StatusOK},
+		{"Exempt Route", http.MethodGet, "/admin/classes/5/access-restriction", "203.0.113.1:1234", http.StatusOK},
+		{"Restricted Resource", http.MethodGet, "/boards/1", "203.0.113.1:1234", constants.StatusForbidden},
+		{"Restricted Nested Resource", http.MethodGet, "/boards/1/comments", "203.0.113.1:1234", constants.StatusForbidden},
+		{"Allowed Resource", http.MethodGet, "/boards/1", "10.0.1.1:1234", http.StatusOK},
+		{"Other Class Resource", http.MethodGet, "/boards/2", "203.0.113.1:1234", http.StatusOK},
+		{"Missing Resource", http.MethodGet, "/boards/9", "203.0.113.1:1234", http.StatusOK},
+		{"Resource Method", http.MethodDelete, "/schedules/1", "203.0.113.1:1234", constants.StatusForbidden},
+		{"Other Method", http.MethodGet, "/schedules/1", "203.0.113.1:1234", http.StatusOK},
+		{"Restricted Body Class", http.MethodPost, "/check-in/5", "203.0.113.1:1234", constants.StatusForbidden},
+		{"Allowed Body Class", http.MethodPost, "/check-in/6", "203.0.113.1:1234", http.StatusOK},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			resp := httptest.NewRecorder()
+			req, _ := http.NewRequest(tc.method, tc.path, nil)
+			req.RemoteAddr = tc.remoteAddr
+			r.ServeHTTP(resp, req)
+
+			if resp.Code != tc.wantStatus {
+				t.Errorf("status = %d, want %d", resp.Code, tc.wantStatus)
+			}
+		})
+	}
+}
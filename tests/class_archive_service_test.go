@@ -0,0 +1,108 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+)
+
+// archiveClassRepo は自動アーカイブの候補を返し、通知とアーカイブを記録するClassRepositoryです。
+type archiveClassRepo struct {
+	repositories.ClassRepository
+	candidates []dto.InactiveClassDTO
+	noticed    []uint
+	archived   []uint
+}
+
+func (r *archiveClassRepo) FindInactiveClasses(context.Context, time.Time) ([]dto.InactiveClassDTO, error) {
+	return r.candidates, nil
+}
+
+func (r *archiveClassRepo) MarkArchiveNoticeSent(_ context.Context, classID uint, _ time.Time) error {
+	r.noticed = append(r.noticed, classID)
+	return nil
+}
+
+func (r *archiveClassRepo) Archive(_ context.Context, classID uint, _ time.Time) error {
+	r.archived = append(r.archived, classID)
+	return nil
+}
+
+// archiveClassUserRepo は各クラスの管理者を1人返すClassUserRepositoryです。
+type archiveClassUserRepo struct {
+	repositories.ClassUserRepository
+}
+
+func (r *archiveClassUserRepo) GetClassMembers(_ context.Context, cid uint, _ ...string) ([]dto.ClassMemberDTO, error) {
+	return []dto.ClassMemberDTO{{Uid: cid * 10, Role: "ADMIN"}}, nil
+}
+
+// recordingNotifier は通知先のユーザーIDを記録するNotifierです。
+type recordingNotifier struct {
+	uids []uint
+}
+
+func (n *recordingNotifier) Notify(_ context.Context, uid uint, _ uint, _ string, _ string) error {
+	n.uids = append(n.uids, uid)
+	return nil
+}
+
+func (n *recordingNotifier) NotifySchedule(_ context.Context, uid uint, _ *models.ClassSchedule, _ string, _ string) error {
+	n.uids = append(n.uids, uid)
+	return nil
+}
+
+// TestRunAutoArchive は事前通知の後、猶予期間と通知期間の両方が経過したクラスのみアーカイブされることを確認するテストです。
+func TestRunAutoArchive(t *testing.T) {
+	day := 24 * time.Hour
+	now := time.Now()
+	ago := func(d time.Duration) *time.Time {
+		at := now.Add(-d)
+		return &at
+	}
+	config := services.ClassArchiveConfig{GracePeriod: 90 * day, NoticeLead: 7 * day}
+
+	cases := []struct {
+		name         string
+		class        dto.InactiveClassDTO
+		wantNotified bool
+		wantArchived bool
+	}{
+		{"First notice", dto.InactiveClassDTO{ID: 1, LastEndedAt: *ago(85 * day)}, true, false},
+		{"Archived after notice", dto.InactiveClassDTO{ID: 2, LastEndedAt: *ago(91 * day), ArchiveNoticeSentAt: ago(8 * day)}, false, true},
+		{"Waits for notice lead", dto.InactiveClassDTO{ID: 3, LastEndedAt: *ago(120 * day), ArchiveNoticeSentAt: ago(1 * day)}, false, false},
+		{"Waits for grace period", dto.InactiveClassDTO{ID: 4, LastEndedAt: *ago(85 * day), ArchiveNoticeSentAt: ago(8 * day)}, false, false},
+		{"Notice before last schedule", dto.InactiveClassDTO{ID: 5, LastEndedAt: *ago(91 * day), ArchiveNoticeSentAt: ago(200 * day)}, true, false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			classRepo := &archiveClassRepo{candidates: []dto.InactiveClassDTO{tc.class}}
+			notifier := &recordingNotifier{}
+			service := services.NewClassArchiveService(classRepo, &archiveClassUserRepo{}, notifier, config)
+
+			notified, archived, err := service.RunAutoArchive(context.Background())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got := notified == 1; got != tc.wantNotified {
+				t.Errorf("notified = %d, want notified %v", notified, tc.wantNotified)
+			}
+			if got := archived == 1; got != tc.wantArchived {
+				t.Errorf("archived = %d, want archived %v", archived, tc.wantArchived)
+			}
+			if tc.wantNotified && (len(notifier.uids) != 1 || notifier.uids[0] != tc.class.ID*10 || len(classRepo.noticed) != 1) {
+				t.Errorf("admin should be notified once, got uids %v and noticed %v", notifier.uids, classRepo.noticed)
+			}
+			if !tc.wantNotified && len(notifier.uids) != 0 {
+				t.Errorf("no notice expected, got %v", notifier.uids)
+			}
+		})
+	}
+}
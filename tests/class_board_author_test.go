@@ -0,0 +1,43 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+)
+
+// TestGetClassBoardByIDAuthor は掲示板の詳細に投稿者の公開プロフィールを含め、メールアドレスなどを含めないことを確認するテストです。
+func TestGetClassBoardByIDAuthor(t *testing.T) {
+	repo := &pinBoardRepo{board: models.ClassBoard{
+		ID:    1,
+		Title: "お知らせ",
+		CID:   5,
+		UID:   7,
+		User:  models.User{ID: 7, Name: "山田", Image: "https://example.com/7.png", PID: "google-7", Email: "yamada@example.com"},
+	}}
+	service := services.NewClassBoardService(repo, &adminClassUserRepo{}, &recordingUploader{}, nil, nil, nil, true, nil, nil, nil, nil)
+
+	board, err := service.GetClassBoardByID(context.Background(), 1, 7)
+	if err != nil {
+		t.Fatalf("err = %v", err)
+	}
+	want := dto.ClassBoardAuthorDTO{ID: 7, Name: "山田", AvatarURL: "https://example.com/7.png"}
+	if board.Author != want {
+		t.Errorf("author = %+v, want %+v", board.Author, want)
+	}
+
+	body, err := json.Marshal(board)
+	if err != nil {
+		t.Fatalf("failed to encode board: %v", err)
+	}
+	for _, secret := range []string{"yamada@example.com", "google-7"} {
+		if strings.Contains(string(body), secret) {
+			t.Errorf("response %s must not contain %q", body, secret)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"io"
+	"mime/multipart"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+)
+
+// bulkDeleteBoardRepo は削除対象の掲示板を返し、削除が呼ばれたかを記録するClassBoardRepositoryです。
+type bulkDeleteBoardRepo struct {
+	repositories.ClassBoardRepository
+	boards []models.ClassBoard
+	called bool
+}
+
+func (r *bulkDeleteBoardRepo) DeleteByClassCreatedBefore(context.Context, uint, time.Time) ([]models.ClassBoard, error) {
+	r.called = true
+	return r.boards, nil
+}
+
+// adminClassUserRepo はadminがtrueの場合のみ管理者と判定するClassUserRepositoryです。
+type adminClassUserRepo struct {
+	repositories.ClassUserRepository
+	admin bool
+}
+
+func (r *adminClassUserRepo) IsAdmin(context.Context, uint, uint) (bool, error) {
+	return r.admin, nil
+}
+
+// recordingUploader は削除を依頼されたURLを記録するUploaderです。
+type recordingUploader struct {
+	deleted []string
+}
+
+func (u *recordingUploader) UploadImage(*multipart.FileHeader, uint, bool) (string, error) {
+	return "", nil
+}
+
+func (u *recordingUploader) DeleteObjects(_ context.Context, urls []string) error {
+	u.deleted = append(u.deleted, urls...)
+	return nil
+}
+
+func (u *recordingUploader) OpenObject(context.Context, string) (io.ReadCloser, error) {
+	return io.NopCloser(strings.NewReader("")), nil
+}
+
+// TestBulkDeleteClassBoards は管理者のみが一括削除でき、添付画像もS3から削除されることを確認するテストです。
+func TestBulkDeleteClassBoards(t *testing.T) {
+	boards := []models.ClassBoard{
+		{ID: 1, Image: "https://cdn.example.com/images/5/a-1.png"},
+		{ID: 2},
+		{ID: 3, Image: "https://cdn.example.com/images/5/b-2.png"},
+	}
+
+	cases := []struct {
+		name        string
+		admin       bool
+		wantErr     error
+		wantCount   int64
+		wantDeleted int
+	}{
+		{"Admin", true, nil, 3, 2},
+		{"Not Admin", false, services.ErrUnauthorized, 0, 0},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			boardRepo := &bulkDeleteBoardRepo{boards: boards}
+			uploader := &recordingUploader{}
+			service := services.NewClassBoardService(boardRepo, &adminClassUserRepo{admin: tc.admin}, uploader, nil, nil, nil, true, nil, nil, nil, nil)
+
+			count, err := service.BulkDeleteClassBoards(context.Background(), 1, 5, time.Now())
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tc.wantErr)
+			}
+			if count != tc.wantCount {
+				t.Errorf("count = %d, want %d", count, tc.wantCount)
+			}
+			if boardRepo.called != tc.admin {
+				t.Errorf("repository called = %v, want %v", boardRepo.called, tc.admin)
+			}
+			if len(uploader.deleted) != tc.wantDeleted {
+				t.Errorf("deleted images = %v, want %d", uploader.deleted, tc.wantDeleted)
+			}
+		})
+	}
+}
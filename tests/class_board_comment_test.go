@@ -0,0 +1,236 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"gorm.io/gorm"
+)
+
+// commentLike はmemoryCommentRepoが保持するいいねです。
+type commentLike struct {
+	commentID, uid uint
+}
+
+// memoryCommentRepo はコメントといいねをメモリに保持し、返信数といいね数を集計するClassBoardCommentRepositoryです。
+type memoryCommentRepo struct {
+	comments []models.ClassBoardComment
+	likes    map[commentLike]bool
+}
+
+func (r *memoryCommentRepo) Create(_ context.Context, comment *models.ClassBoardComment) error {
+	comment.ID = uint(len(r.comments) + 1)
+	r.comments = append(r.comments, *comment)
+	return nil
+}
+
+func (r *memoryCommentRepo) FindByID(_ context.Context, id uint) (*models.ClassBoardComment, error) {
+	for _, comment := range r.comments {
+		if comment.ID == id {
+			return &comment, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *memoryCommentRepo) FindRow(_ context.Context, id uint, viewerUID uint) (*repositories.ClassBoardCommentRow, error) {
+	for _, comment := range r.comments {
+		if comment.ID == id {
+			row := r.row(comment, viewerUID)
+			return &row, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *memoryCommentRepo) FindRows(_ context.Context, bid uint, parentCommentID *uint, viewerUID uint) ([]repositories.ClassBoardCommentRow, error) {
+	var rows []repositories.ClassBoardCommentRow
+	for _, comment := range r.comments {
+		sameParent := (comment.ParentCommentID == nil && parentCommentID == nil) ||
+			(comment.ParentCommentID != nil && parentCommentID != nil && *comment.ParentCommentID == *parentCommentID)
+		if comment.BID == bid && sameParent {
+			rows = append(rows, r.row(comment, viewerUID))
+		}
+	}
+	return rows, nil
+}
+
+func (r *memoryCommentRepo) row(comment models.ClassBoardComment, viewerUID uint) repositories.ClassBoardCommentRow {
+	row := repositories.ClassBoardCommentRow{ID: comment.ID, BID: comment.BID, UID: comment.UID, ParentCommentID: comment.ParentCommentID, Content: comment.Content}
+	for _, reply := range r.comments {
+		if reply.ParentCommentID != nil && *reply.ParentCommentID == comment.ID {
+			row.ReplyCount++
+		}
+	}
+	for like := range r.likes {
+		if like.commentID == comment.ID {
+			row.LikeCount++
+		}
+	}
+	row.Liked = r.likes[commentLike{comment.ID, viewerUID}]
+	return row
+}
+
+func (r *memoryCommentRepo) Delete(_ context.Context, id uint) error {
+	kept := r.comments[:0]
+	for _, comment := range r.comments {
+		if comment.ID != id && (comment.ParentCommentID == nil || *comment.ParentCommentID != id) {
+			kept = append(kept, comment)
+		}
+	}
+	r.comments = kept
+	return nil
+}
+
+func (r *memoryCommentRepo) Like(_ context.Context, commentID uint, uid uint) error {
+	r.likes[commentLike{commentID, uid}] = true
+	return nil
+}
+
+func (r *memoryCommentRepo) Unlike(_ context.Context, commentID uint, uid uint) error {
+	delete(r.likes, commentLike{commentID, uid})
+	return nil
+}
+
+// commentBoardRepo は掲示板1と2をクラス5の掲示板として返すClassBoardRepositoryです。
+type commentBoardRepo struct {
+	repositories.ClassBoardRepository
+}
+
+func (r *commentBoardRepo) FindByID(_ context.Context, id uint) (*models.ClassBoard, error) {
+	if id > 2 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &models.ClassBoard{ID: id, CID: 5}, nil
+}
+
+// newCommentService はユーザー1と2がコメントした掲示板1と、ユーザー1のコメントへのリプライを持つサービスを生成します。
+func newCommentService(t *testing.T, role string) (services.ClassBoardCommentService, *memoryCommentRepo) {
+	t.Helper()
+	repo := &memoryCommentRepo{likes: map[commentLike]bool{}}
+	setup := services.NewClassBoardCommentService(repo, &commentBoardRepo{}, &streakClassUserRepo{role: "USER"})
+	ctx := context.Background()
+	first, err := setup.CreateComment(ctx, 1, 1, dto.ClassBoardCommentCreateDTO{Content: "質問です"})
+	if err != nil {
+		t.Fatalf("CreateComment() = %v", err)
+	}
+	if _, err := setup.CreateComment(ctx, 2, 1, dto.ClassBoardCommentCreateDTO{Content: "回答です", ParentCommentID: &first.ID}); err != nil {
+		t.Fatalf("CreateComment() reply = %v", err)
+	}
+	if _, err := setup.CreateComment(ctx, 2, 2, dto.ClassBoardCommentCreateDTO{Content: "別の掲示板"}); err != nil {
+		t.Fatalf("CreateComment() = %v", err)
+	}
+	return services.NewClassBoardCommentService(repo, &commentBoardRepo{}, &streakClassUserRepo{role: role}), repo
+}
+
+// TestCreateCommentReplyDepth はコメントにはリプライでき、リプライへのリプライと別の掲示板のコメントへのリプライを拒否することを確認するテストです。
+func TestCreateCommentReplyDepth(t *testing.T) {
+	top, reply, otherBoard := uint(1), uint(2), uint(3)
+	cases := []struct {
+		name     string
+		role     string
+		bid      uint
+		parentID *uint
+		wantErr  error
+	}{
+		{"Comment", "USER", 1, nil, nil},
+		{"Reply", "USER", 1, &top, nil},
+		{"Reply To Reply", "USER", 1, &reply, services.ErrNestedReply},
+		{"Reply Across Boards", "USER", 1, &otherBoard, services.ErrNotFound},
+		{"Unknown Board", "USER", 9, nil, services.ErrNotFound},
+		{"Applicant", "APPLICANT", 1, nil, services.ErrUnauthorized},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			service, repo := newCommentService(t, tc.role)
+			before := len(repo.comments)
+
+			_, err := service.CreateComment(context.Background(), 3, tc.bid, dto.ClassBoardCommentCreateDTO{Content: "コメント", ParentCommentID: tc.parentID})
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tc.wantErr)
+			}
+			if created := len(repo.comments) > before; created != (tc.wantErr == nil) {
+				t.Errorf("created = %v, want %v", created, tc.wantErr == nil)
+			}
+		})
+	}
+}
+
+// TestCommentCountsAndLikes はコメントの取得で返信数といいね数、自分がいいね済みかを返し、いいねを重複して数えないことを確認するテストです。
+func TestCommentCountsAndLikes(t *testing.T) {
+	service, _ := newCommentService(t, "USER")
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if _, err := service.SetLike(ctx, 3, 1, 1, true); err != nil {
+			t.Fatalf("SetLike() = %v", err)
+		}
+	}
+	if _, err := service.SetLike(ctx, 4, 1, 1, true); err != nil {
+		t.Fatalf("SetLike() = %v", err)
+	}
+	comment, err := service.SetLike(ctx, 4, 1, 1, false)
+	if err != nil {
+		t.Fatalf("SetLike() = %v", err)
+	}
+	if comment.LikeCount != 1 || comment.Liked {
+		t.Errorf("after unlike: like_count = %d, liked = %v, want 1, false", comment.LikeCount, comment.Liked)
+	}
+
+	comments, err := service.GetComments(ctx, 3, 1)
+	if err != nil {
+		t.Fatalf("GetComments() = %v", err)
+	}
+	if len(comments) != 1 || comments[0].ReplyCount != 1 || comments[0].LikeCount != 1 || !comments[0].Liked {
+		t.Fatalf("comments = %+v, want one comment with 1 reply, 1 like, liked by the viewer", comments)
+	}
+
+	replies, err := service.GetReplies(ctx, 3, 1, 1)
+	if err != nil {
+		t.Fatalf("GetReplies() = %v", err)
+	}
+	if len(replies) != 1 || replies[0].ParentCommentID == nil || *replies[0].ParentCommentID != 1 {
+		t.Errorf("replies = %+v, want the reply to comment 1", replies)
+	}
+	if _, err := service.SetLike(ctx, 3, 2, 1, true); !errors.Is(err, services.ErrNotFound) {
+		t.Errorf("SetLike() on another board = %v, want %v", err, services.ErrNotFound)
+	}
+}
+
+// TestDeleteComment は投稿者とアシスタント以上のみコメントを削除でき、リプライも削除されることを確認するテストです。
+func TestDeleteComment(t *testing.T) {
+	cases := []struct {
+		name    string
+		role    string
+		uid     uint
+		wantErr error
+	}{
+		{"Author", "USER", 1, nil},
+		{"Assistant", "ASSISTANT", 3, nil},
+		{"Other Member", "USER", 2, services.ErrUnauthorized},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			service, repo := newCommentService(t, tc.role)
+
+			err := service.DeleteComment(context.Background(), tc.uid, 1, 1)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tc.wantErr)
+			}
+			wantRemaining := 3
+			if tc.wantErr == nil {
+				wantRemaining = 1
+			}
+			if len(repo.comments) != wantRemaining {
+				t.Errorf("remaining comments = %d, want %d", len(repo.comments), wantRemaining)
+			}
+		})
+	}
+}
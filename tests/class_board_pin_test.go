@@ -0,0 +1,79 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+)
+
+// pinBoardRepo は1件の掲示板を保持し、更新を記録するClassBoardRepositoryです。
+type pinBoardRepo struct {
+	repositories.ClassBoardRepository
+	board   models.ClassBoard
+	updated bool
+}
+
+func (r *pinBoardRepo) FindByID(context.Context, uint) (*models.ClassBoard, error) {
+	board := r.board
+	return &board, nil
+}
+
+func (r *pinBoardRepo) FindVariants(context.Context, []uint) ([]models.ClassBoardVariant, error) {
+	return nil, nil
+}
+
+func (r *pinBoardRepo) UpdateClassBoard(_ context.Context, b *models.ClassBoard, _ uint) error {
+	r.board = *b
+	r.updated = true
+	return nil
+}
+
+// TestPinClassBoard は管理者のみがピン留めでき、解除した場合は期限も消えることを確認するテストです。
+func TestPinClassBoard(t *testing.T) {
+	until := time.Now().Add(24 * time.Hour)
+
+	cases := []struct {
+		name         string
+		admin        bool
+		current      *time.Time
+		pinned       bool
+		until        *time.Time
+		wantErr      error
+		wantPinned   bool
+		wantHasUntil bool
+	}{
+		{"Pin Without Deadline", true, nil, true, nil, nil, true, false},
+		{"Pin With Deadline", true, nil, true, &until, nil, true, true},
+		{"Unpin Clears Deadline", true, &until, false, &until, nil, false, false},
+		{"Not Admin", false, nil, true, nil, services.ErrUnauthorized, false, false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := &pinBoardRepo{board: models.ClassBoard{ID: 1, CID: 5, IsPinned: tc.current != nil, PinnedUntil: tc.current}}
+			service := services.NewClassBoardService(repo, &adminClassUserRepo{admin: tc.admin}, &recordingUploader{}, nil, nil, nil, true, nil, nil, nil, nil)
+
+			board, err := service.PinClassBoard(context.Background(), 1, 1, tc.pinned, tc.until)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tc.wantErr)
+			}
+			if tc.wantErr != nil {
+				if repo.updated {
+					t.Error("board was updated, want unchanged")
+				}
+				return
+			}
+			if board.IsPinned != tc.wantPinned || (board.PinnedUntil != nil) != tc.wantHasUntil {
+				t.Errorf("pinned = %v until = %v, want pinned %v has until %v", board.IsPinned, board.PinnedUntil, tc.wantPinned, tc.wantHasUntil)
+			}
+			if repo.board.IsPinned != tc.wantPinned {
+				t.Errorf("saved pinned = %v, want %v", repo.board.IsPinned, tc.wantPinned)
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+)
+
+// variantBoardRepo は掲示板と他の言語の版、ユーザーの言語を保持するClassBoardRepositoryです。
+type variantBoardRepo struct {
+	repositories.ClassBoardRepository
+	boards   []models.ClassBoard
+	variants []models.ClassBoardVariant
+	locales  map[uint]string
+	inserted *models.ClassBoard
+}
+
+func (r *variantBoardRepo) InsertClassBoard(_ context.Context, b *models.ClassBoard) (*models.ClassBoard, error) {
+	b.ID = 1
+	r.inserted = b
+	return b, nil
+}
+
+func (r *variantBoardRepo) FindByID(_ context.Context, id uint) (*models.ClassBoard, error) {
+	for _, board := range r.boards {
+		if board.ID == id {
+			return &board, nil
+		}
+	}
+	return nil, services.ErrNotFound
+}
+
+func (r *variantBoardRepo) FindAnnounced(context.Context, bool, uint) ([]models.ClassBoard, error) {
+	return append([]models.ClassBoard(nil), r.boards...), nil
+}
+
+func (r *variantBoardRepo) FindVariants(_ context.Context, bids []uint) ([]models.ClassBoardVariant, error) {
+	var variants []models.ClassBoardVariant
+	for _, variant := range r.variants {
+		for _, bid := range bids {
+			if variant.BID == bid {
+				variants = append(variants, variant)
+			}
+		}
+	}
+	return variants, nil
+}
+
+func (r *variantBoardRepo) FindUserLocale(_ context.Context, uid uint) (string, error) {
+	return r.locales[uid], nil
+}
+
+// TestCreateClassBoardVariants は他の言語の版を掲示板と共に保存し、同じ言語の版が複数ある場合は作成しないことを確認するテストです。
+func TestCreateClassBoardVariants(t *testing.T) {
+	cases := []struct {
+		name      string
+		language  string
+		variants  []dto.ClassBoardVariantDTO
+		wantErr   error
+		languages []string
+	}{
+		{"Variants", "ja", []dto.ClassBoardVariantDTO{{Language: "en_GB", Title: "Notice", Content: "Body"}, {Language: "ko", Title: "공지", Content: "본문"}}, nil, []string{"en-GB", "ko"}},
+		{"Without Variants", "", nil, nil, []string{}},
+		{"Same As Board Language", "ja", []dto.ClassBoardVariantDTO{{Language: "JA", Title: "お知らせ", Content: "本文"}}, services.ErrDuplicateBoardLanguage, nil},
+		{"Duplicate Variants", "", []dto.ClassBoardVariantDTO{{Language: "en-US", Title: "Notice", Content: "Body"}, {Language: "en_us", Title: "Notice", Content: "Body"}}, services.ErrDuplicateBoardLanguage, nil},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := &variantBoardRepo{}
+			service := services.NewClassBoardService(repo, &adminClassUserRepo{}, &recordingUploader{}, nil, nil, nil, true, nil, nil, nil, nil)
+			_, err := service.CreateClassBoard(context.Background(), dto.ClassBoardCreateDTO{Title: "お知らせ", Content: "本文", CID: 1, UID: 1, Language: tc.language, Variants: tc.variants})
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tc.wantErr)
+			}
+			if tc.wantErr != nil {
+				if repo.inserted != nil {
+					t.Errorf("board was created despite the error")
+				}
+				return
+			}
+			languages := []string{}
+			for _, variant := range repo.inserted.Variants {
+				languages = append(languages, variant.Language)
+			}
+			if strings.Join(languages, ",") != strings.Join(tc.languages, ",") || repo.inserted.Language != tc.language {
+				t.Errorf("saved language = %q, variants = %v, want %q, %v", repo.inserted.Language, languages, tc.language, tc.languages)
+			}
+		})
+	}
+}
+
+// TestClassBoardVariantSelection はユーザーの言語と一致する版、主言語が一致する版の順に選び、近い版がない場合は掲示板の言語の版を返すことを確認するテストです。
+func TestClassBoardVariantSelection(t *testing.T) {
+	variants := []models.ClassBoardVariant{
+		{BID: 1, Language: "en-US", Title: "Notice (US)"},
+		{BID: 1, Language: "en-GB", Title: "Notice (UK)"},
+		{BID: 1, Language: "zh-TW", Title: "公告"},
+	}
+
+	cases := []struct {
+		name         string
+		language     string
+		locale       string
+		wantTitle    string
+		wantLanguage string
+	}{
+		{"Exact Match", "ja", "en-GB", "Notice (UK)", "en-GB"},
+		{"Case Insensitive", "ja", "en_gb", "Notice (UK)", "en-GB"},
+		{"Primary Language", "ja", "en-AU", "Notice (US)", "en-US"},
+		{"Primary Language Only", "ja", "zh", "公告", "zh-TW"},
+		{"Board Language", "ja", "ja-JP", "お知らせ", "ja"},
+		{"Fallback To Board", "ja", "ko", "お知らせ", "ja"},
+		{"No Locale", "ja", "", "お知らせ", "ja"},
+		{"Board Without Language", "", "en", "Notice (US)", "en-US"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := &variantBoardRepo{
+				boards:   []models.ClassBoard{{ID: 1, Title: "お知らせ", Language: tc.language, CID: 5}, {ID: 2, Title: "時間割", Language: "ja", CID: 5}},
+				variants: variants,
+				locales:  map[uint]string{7: tc.locale},
+			}
+			service := services.NewClassBoardService(repo, &adminClassUserRepo{}, &recordingUploader{}, nil, nil, nil, true, nil, nil, nil, nil)
+
+			board, err := service.GetClassBoardByID(context.Background(), 1, 7)
+			if err != nil {
+				t.Fatalf("err = %v", err)
+			}
+			if board.Title != tc.wantTitle || board.Language != tc.wantLanguage {
+				t.Errorf("board = %q (%q), want %q (%q)", board.Title, board.Language, tc.wantTitle, tc.wantLanguage)
+			}
+			wantLanguages := "en-US,en-GB,zh-TW"
+			if tc.language != "" {
+				wantLanguages = tc.language + "," + wantLanguages
+			}
+			if got := strings.Join(board.Languages, ","); got != wantLanguages {
+				t.Errorf("languages = %q, want %q", got, wantLanguages)
+			}
+
+			boards, err := service.GetAnnouncedClassBoards(context.Background(), 5, 7)
+			if err != nil {
+				t.Fatalf("err = %v", err)
+			}
+			if boards[0].Title != tc.wantTitle || boards[1].Title != "時間割" {
+				t.Errorf("announced = %q, %q, want %q, 時間割", boards[0].Title, boards[1].Title, tc.wantTitle)
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+)
+
+// TestAssignRoleViaCodeRejoin は同じクラスコードで参加した後に削除されたユーザーがコードを再使用できず、
+// コードを使わずに追加された後に削除されたユーザーは再参加できることを確認するテストです。
+func TestAssignRoleViaCodeRejoin(t *testing.T) {
+	uintPtr := func(v uint) *uint { return &v }
+
+	cases := []struct {
+		name       string
+		removed    *models.ClassUser
+		wantErr    error
+		wantJoined bool
+	}{
+		{"Never Joined", nil, nil, true},
+		{"Removed After Joining With Same Code", &models.ClassUser{UID: 1, CID: 2, CodeID: uintPtr(3)}, services.ErrClassCodeUsed, false},
+		{"Removed After Joining With Other Code", &models.ClassUser{UID: 1, CID: 2, CodeID: uintPtr(4)}, nil, true},
+		{"Removed After Added Without Code", &models.ClassUser{UID: 1, CID: 2}, nil, true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			classUserRepo := &limitClassUserRepo{removed: tc.removed}
+			userRepo := &limitUserRepo{}
+			txManager := &limitTxManager{classUserRepo: classUserRepo, userRepo: userRepo}
+			service := services.NewClassUserService(txManager, classUserRepo, nil, nil, nil, userRepo, nil, 0, nil, nil, nil)
+
+			_, joined, err := service.AssignRoleViaCode(context.Background(), 1, 2, "APPLICANT", 3)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tc.wantErr)
+			}
+			if joined != tc.wantJoined || classUserRepo.joined != tc.wantJoined {
+				t.Errorf("joined = %v (repo %v), want %v", joined, classUserRepo.joined, tc.wantJoined)
+			}
+		})
+	}
+}
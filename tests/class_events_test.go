@@ -0,0 +1,89 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"github.com/go-redis/redis/v8"
+)
+
+// roleChangeClassUserRepo は1人のメンバーのロールを保持するClassUserRepositoryです。
+type roleChangeClassUserRepo struct {
+	adminClassUserRepo
+	role string
+}
+
+func (r *roleChangeClassUserRepo) RoleExists(context.Context, uint, uint) (bool, error) {
+	return r.role != "", nil
+}
+
+func (r *roleChangeClassUserRepo) GetRole(context.Context, uint, uint) (string, error) {
+	return r.role, nil
+}
+
+func (r *roleChangeClassUserRepo) UpdateUserRole(_ context.Context, _ uint, _ uint, role string) error {
+	r.role = role
+	return nil
+}
+
+// TestSubscribeClassEventsRequiresAdmin はクラスの管理者以外がイベントを購読できないことを確認するテストです。
+func TestSubscribeClassEventsRequiresAdmin(t *testing.T) {
+	service := services.NewClassUserService(nil, &roleChangeClassUserRepo{role: "USER"}, nil, nil, nil, nil, nil, 0, nil, nil, nil)
+
+	if _, err := service.SubscribeClassEvents(context.Background(), 2, 10); !errors.Is(err, services.ErrUnauthorized) {
+		t.Fatalf("err = %v, want %v", err, services.ErrUnauthorized)
+	}
+}
+
+// TestAssignRoleWithoutRedis はイベントを配信できない場合もロールの変更は成功することを確認するテストです。
+func TestAssignRoleWithoutRedis(t *testing.T) {
+	redisClient := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", DialTimeout: 50 * time.Millisecond, MaxRetries: -1})
+	repo := &roleChangeClassUserRepo{role: "USER"}
+	service := services.NewClassUserService(nil, repo, nil, nil, nil, nil, redisClient, 0, nil, nil, nil)
+
+	if err := service.AssignRole(context.Background(), 3, 10, "ASSISTANT"); err != nil {
+		t.Fatalf("err = %v", err)
+	}
+	if repo.role != "ASSISTANT" {
+		t.Errorf("role = %q, want ASSISTANT", repo.role)
+	}
+}
+
+// TestRoleChangedEvent はロールの変更がクラスのチャンネルに配信され、ロールが変わらない場合は配信されないことを確認するテストです。
+func TestRoleChangedEvent(t *testing.T) {
+	redisClient := openTestRedis(t)
+	repo := &roleChangeClassUserRepo{adminClassUserRepo: adminClassUserRepo{admin: true}, role: "USER"}
+	service := services.NewClassUserService(nil, repo, nil, nil, nil, nil, redisClient, 0, nil, nil, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	events, err := service.SubscribeClassEvents(ctx, 1, 10)
+	if err != nil {
+		t.Fatalf("err = %v", err)
+	}
+
+	if err := service.AssignRole(ctx, 3, 10, "USER"); err != nil {
+		t.Fatalf("err = %v", err)
+	}
+	if err := service.AssignRole(ctx, 3, 10, "ASSISTANT"); err != nil {
+		t.Fatalf("err = %v", err)
+	}
+
+	want := dto.ClassEventDTO{Type: dto.ClassEventRoleChanged, CID: 10, UID: 3, OldRole: "USER", NewRole: "ASSISTANT"}
+	select {
+	case event := <-events:
+		if event != want {
+			t.Errorf("event = %+v, want %+v", event, want)
+		}
+	case <-ctx.Done():
+		t.Fatal("no event was received")
+	}
+
+	cancel()
+	for range events {
+	}
+}
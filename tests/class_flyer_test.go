@@ -0,0 +1,207 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/utils"
+	"github.com/makiuchi-d/gozxing"
+	"github.com/makiuchi-d/gozxing/qrcode"
+)
+
+// TestEncodeQRCode は文字数に応じた型番の大きさでQRコードを生成し、格納できない長さをエラーにすることを確認するテストです。
+func TestEncodeQRCode(t *testing.T) {
+	cases := []struct {
+		name     string
+		length   int
+		wantSize int
+		wantErr  error
+	}{
+		{"Version 1", 1, 21, nil},
+		{"Version 2", 15, 25, nil},
+		{"Version 10", 213, 57, nil},
+		{"Too Long", 214, 0, utils.ErrQRCodeTooLong},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			qr, err := utils.EncodeQRCode(strings.Repeat("a", tc.length))
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tc.wantErr)
+			}
+			if tc.wantErr != nil {
+				return
+			}
+			if qr.Size != tc.wantSize || len(qr.Modules) != tc.wantSize {
+				t.Fatalf("size = %d, want %d", qr.Size, tc.wantSize)
+			}
+			// 3つの角の位置検出パターンは外周が黒、その内側が白になる
+			for _, corner := range [][2]int{{0, 0}, {0, qr.Size - 7}, {qr.Size - 7, 0}} {
+				row, col := corner[0], corner[1]
+				if !qr.Modules[row][col] || !qr.Modules[row+6][col+6] || qr.Modules[row+1][col+1] || !qr.Modules[row+3][col+3] {
+					t.Errorf("finder pattern at (%d, %d) is broken", row, col)
+				}
+			}
+		})
+	}
+}
+
+// TestEncodeQRCodeDecode は生成したQRコードをZXingのデコーダで読み取り、格納したデータに戻ることを確認するテストです。
+// 型番ごとにブロック構成と位置合わせパターンが異なるため、型番1〜10のそれぞれで確認する
+func TestEncodeQRCodeDecode(t *testing.T) {
+	lengths := []int{1, 15, 30, 50, 70, 100, 120, 150, 180, 213}
+	for version, length := range lengths {
+		data := "https://minori.example.com/join?code=ABC123&ref=" + strings.Repeat("x", length)
+		data = data[len(data)-length:]
+		t.Run(fmt.Sprintf("Version %d", version+1), func(t *testing.T) {
+			qr, err := utils.EncodeQRCode(data)
+			if err != nil {
+				t.Fatalf("err = %v", err)
+			}
+			if want := 17 + 4*(version+1); qr.Size != want {
+				t.Fatalf("size = %d, want %d", qr.Size, want)
+			}
+			bitmap, err := gozxing.NewBinaryBitmapFromImage(renderQRCode(qr, 4))
+			if err != nil {
+				t.Fatalf("err = %v", err)
+			}
+			result, err := qrcode.NewQRCodeReader().Decode(bitmap, map[gozxing.DecodeHintType]interface{}{gozxing.DecodeHintType_PURE_BARCODE: true})
+			if err != nil {
+				t.Fatalf("decode err = %v", err)
+			}
+			if result.GetText() != data {
+				t.Errorf("decoded %q, want %q", result.GetText(), data)
+			}
+		})
+	}
+}
+
+// renderQRCode はQRコードを周囲に4モジュールの余白を付けて、1モジュールscaleピクセルの画像にします。
+func renderQRCode(qr *utils.QRCode, scale int) image.Image {
+	size := (qr.Size + 8) * scale
+	img := image.NewGray(image.Rect(0, 0, size, size))
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			row, col := y/scale-4, x/scale-4
+			if row >= 0 && row < qr.Size && col >= 0 && col < qr.Size && qr.Modules[row][col] {
+				img.SetGray(x, y, color.Gray{})
+			} else {
+				img.SetGray(x, y, color.Gray{Y: 255})
+			}
+		}
+	}
+	return img
+}
+
+// flyerClassRepo は1件のクラスを返すClassRepositoryです。
+type flyerClassRepo struct {
+	repositories.ClassRepository
+	class *models.Class
+}
+
+func (r *flyerClassRepo) GetByID(context.Context, uint) (*models.Class, error) {
+	return r.class, nil
+}
+
+// flyerClassCodeRepo は1件のクラスコードを返すClassCodeRepositoryです。
+type flyerClassCodeRepo struct {
+	repositories.ClassCodeRepository
+	code *models.ClassCode
+}
+
+func (r *flyerClassCodeRepo) FindByClassID(context.Context, uint) (*models.ClassCode, error) {
+	return r.code, nil
+}
+
+// flyerClassUserRepo は固定のロールを返すClassUserRepositoryです。
+type flyerClassUserRepo struct {
+	repositories.ClassUserRepository
+	role string
+}
+
+func (r *flyerClassUserRepo) GetRole(context.Context, uint, uint) (string, error) {
+	return r.role, nil
+}
+
+// TestGenerateClassFlyer は管理者のみ配布用PDFを生成でき、招待リンクにクラスコードを付け、シークレットを印刷しないことを確認するテストです。
+func TestGenerateClassFlyer(t *testing.T) {
+	description := "月曜1限の授業です"
+	secret := "s3cret"
+
+	cases := []struct {
+		name      string
+		role      string
+		inviteURL string
+		wantErr   error
+		wantFlyer utils.ClassFlyer
+	}{
+		{"Not Admin", "USER", "", services.ErrUnauthorized, utils.ClassFlyer{}},
+		{
+			name: "Class Code Only",
+			role: "ADMIN",
+			wantFlyer: utils.ClassFlyer{
+				ClassName: "数学", Description: description, JoinCode: "ABC123", SecretRequired: true,
+			},
+		},
+		{
+			name:      "Invite Link",
+			role:      "ADMIN",
+			inviteURL: "https://minori.example.com/join?ref=flyer",
+			wantFlyer: utils.ClassFlyer{
+				ClassName: "数学", Description: description, JoinCode: "ABC123", SecretRequired: true,
+				JoinURL: "https://minori.example.com/join?code=ABC123&ref=flyer",
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			service := services.NewCreateClassService(
+				nil,
+				&flyerClassRepo{class: &models.Class{ID: 1, Name: "数学", Description: &description}},
+				&flyerClassUserRepo{role: tc.role},
+				&flyerClassCodeRepo{code: &models.ClassCode{CID: 1, Code: "ABC123", Secret: &secret}},
+				nil,
+				nil,
+				tc.inviteURL,
+				nil,
+				nil,
+			)
+
+			pdf, err := service.GenerateClassFlyer(context.Background(), 1, 1)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tc.wantErr)
+			}
+			if tc.wantErr != nil {
+				return
+			}
+
+			want, err := utils.BuildClassFlyerPDF(tc.wantFlyer)
+			if err != nil {
+				t.Fatalf("failed to build expected flyer: %v", err)
+			}
+			if !bytes.Equal(pdf, want) {
+				t.Errorf("flyer differs from %+v", tc.wantFlyer)
+			}
+			if !bytes.HasPrefix(pdf, []byte("%PDF-")) || !bytes.HasSuffix(pdf, []byte("%%EOF\n")) {
+				t.Errorf("output is not a PDF document")
+			}
+			// 日本語のフォントはPDFに埋め込む
+			if !bytes.Contains(pdf, []byte("/FontFile2")) {
+				t.Errorf("font is not embedded")
+			}
+			if bytes.Contains(pdf, []byte(secret)) {
+				t.Errorf("secret must not be printed")
+			}
+		})
+	}
+}
@@ -0,0 +1,442 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/utils"
+	"gorm.io/gorm"
+)
+
+// TestChatPoster はSlackとDiscordの形式で見出し・投稿者・リンクを投稿し、2xx以外の応答をステータスコード付きの失敗にすることを確認するテストです。
+func TestChatPoster(t *testing.T) {
+	message := utils.ChatMessage{Title: "期末試験 <重要>", Author: "山田先生", Body: "@everyone 範囲は第5章まで", Link: "https://minoriedu.com/classes/1/boards/2"}
+
+	cases := []struct {
+		name   string
+		poster utils.ChatPoster
+		field  string
+		want   []string
+	}{
+		{"Slack", utils.NewSlackPoster(true), "text", []string{"*期末試験 &lt;重要&gt;*", "投稿者: 山田先生", "<https://minoriedu.com/classes/1/boards/2|アプリで開く>"}},
+		{"Discord", utils.NewDiscordPoster(true), "content", []string{"**期末試験 <重要>**", "投稿者: 山田先生", "<https://minoriedu.com/classes/1/boards/2>"}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var received map[string]interface{}
+			status := http.StatusNoContent
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				if err := json.Unmarshal(body, &received); err != nil {
+					t.Errorf("body is not JSON: %s", body)
+				}
+				w.WriteHeader(status)
+				io.WriteString(w, "invalid_token")
+			}))
+			defer server.Close()
+
+			if code, err := tc.poster.Post(context.Background(), server.URL, message); err != nil || code != http.StatusNoContent {
+				t.Fatalf("Post() = %d, %v, want 204 without error", code, err)
+			}
+			text, _ := received[tc.field].(string)
+			for _, want := range tc.want {
+				if !strings.Contains(text, want) {
+					t.Errorf("%s = %q, want it to contain %q", tc.field, text, want)
+				}
+			}
+
+			status = http.StatusForbidden
+			code, err := tc.poster.Post(context.Background(), server.URL, message)
+			if err == nil || code != http.StatusForbidden || !strings.Contains(err.Error(), "invalid_token") {
+				t.Errorf("Post() = %d, %v, want 403 with the response body", code, err)
+			}
+		})
+	}
+}
+
+// memoryIntegrationRepo はクラスの連携をメモリに保存するClassIntegrationRepositoryです。
+type memoryIntegrationRepo struct {
+	repositories.ClassIntegrationRepository
+	mu           sync.Mutex
+	integrations map[uint]*models.ClassIntegration
+}
+
+func newMemoryIntegrationRepo(integrations ...models.ClassIntegration) *memoryIntegrationRepo {
+	r := &memoryIntegrationRepo{integrations: make(map[uint]*models.ClassIntegration)}
+	for i := range integrations {
+		integration := integrations[i]
+		r.integrations[integration.CID] = &integration
+	}
+	return r
+}
+
+func (r *memoryIntegrationRepo) FindIntegration(_ context.Context, cid uint) (*models.ClassIntegration, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	integration, ok := r.integrations[cid]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copied := *integration
+	return &copied, nil
+}
+
+func (r *memoryIntegrationRepo) SaveIntegration(_ context.Context, integration *models.ClassIntegration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	integration.ID = integration.CID
+	saved := *integration
+	r.integrations[integration.CID] = &saved
+	return nil
+}
+
+func (r *memoryIntegrationRepo) DeleteIntegration(_ context.Context, cid uint) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, ok := r.integrations[cid]
+	delete(r.integrations, cid)
+	return ok, nil
+}
+
+func (r *memoryIntegrationRepo) RecordPosted(_ context.Context, id uint, at time.Time) error {
+	r.update(id, func(integration *models.ClassIntegration) {
+		integration.Status = models.IntegrationOK
+		integration.LastError = ""
+		integration.ConsecutiveClientErrors = 0
+		integration.LastPostedAt = &at
+	})
+	return nil
+}
+
+func (r *memoryIntegrationRepo) RecordFailure(_ context.Context, id uint, message string, at time.Time) error {
+	r.update(id, func(integration *models.ClassIntegration) {
+		integration.Status = models.IntegrationFailing
+		integration.LastError = message
+		integration.LastFailedAt = &at
+	})
+	return nil
+}
+
+func (r *memoryIntegrationRepo) RecordClientError(_ context.Context, id uint, message string, at time.Time, limit int) (bool, error) {
+	var disabled bool
+	r.update(id, func(integration *models.ClassIntegration) {
+		integration.Status = models.IntegrationFailing
+		integration.LastError = message
+		integration.LastFailedAt = &at
+		integration.ConsecutiveClientErrors++
+		if integration.ConsecutiveClientErrors >= limit {
+			integration.Status = models.IntegrationDisabled
+			integration.DisabledAt = &at
+			disabled = true
+		}
+	})
+	return disabled, nil
+}
+
+// update 投稿を停止していない連携のみ変更する
+func (r *memoryIntegrationRepo) update(id uint, change func(integration *models.ClassIntegration)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, integration := range r.integrations {
+		if integration.ID == id && integration.Status != models.IntegrationDisabled {
+			change(integration)
+		}
+	}
+}
+
+func (r *memoryIntegrationRepo) current(cid uint) models.ClassIntegration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return *r.integrations[cid]
+}
+
+// integrationClassUserRepo はuid 1のみを管理者とし、メンバーのニックネームを返すClassUserRepositoryです。
+type integrationClassUserRepo struct {
+	repositories.ClassUserRepository
+}
+
+func (r *integrationClassUserRepo) IsAdmin(_ context.Context, uid uint, _ uint) (bool, error) {
+	return uid == 1, nil
+}
+
+func (r *integrationClassUserRepo) GetClassUserInfo(_ context.Context, uid uint, _ uint) (dto.ClassMemberDTO, error) {
+	return dto.ClassMemberDTO{Uid: uid, Nickname: fmt.Sprintf("member%d", uid)}, nil
+}
+
+// scriptedChatPoster は指定したステータスコードを順に返すChatPosterです。指定した分を使い切った後は200を返します。
+type scriptedChatPoster struct {
+	mu       sync.Mutex
+	statuses []int
+	messages []utils.ChatMessage
+}
+
+func (p *scriptedChatPoster) Post(_ context.Context, _ string, message utils.ChatMessage) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.messages = append(p.messages, message)
+	status := http.StatusOK
+	if len(p.statuses) > 0 {
+		status, p.statuses = p.statuses[0], p.statuses[1:]
+	}
+	if status == 0 {
+		return 0, errors.New("connection refused")
+	}
+	if status >= 300 {
+		return status, fmt.Errorf("unexpected status %d", status)
+	}
+	return status, nil
+}
+
+func (p *scriptedChatPoster) posted() []utils.ChatMessage {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]utils.ChatMessage(nil), p.messages...)
+}
+
+// TestSaveIntegration は連携先のサービスが発行したURLに限り、テストメッセージを投稿できた場合のみ連携を保存することを確認するテストです。
+func TestSaveIntegration(t *testing.T) {
+	cases := []struct {
+		name       string
+		viewer     uint
+		provider   string
+		url        string
+		testStatus int
+		wantErr    error
+	}{
+		{"Slack", 1, "SLACK", "https://hooks.slack.com/services/T0/B0/secret", http.StatusOK, nil},
+		{"Discord", 1, "DISCORD", "https://discord.com/api/webhooks/1/secret", http.StatusNoContent, nil},
+		{"Not an admin", 2, "SLACK", "https://hooks.slack.com/services/T0/B0/secret", http.StatusOK, services.ErrUnauthorized},
+		{"Other host", 1, "SLACK", "https://example.com/services/T0/B0/secret", http.StatusOK, services.ErrInvalidIntegrationURL},
+		{"Plain http", 1, "DISCORD", "http://discord.com/api/webhooks/1/secret", http.StatusOK, services.ErrInvalidIntegrationURL},
+		{"URL of other provider", 1, "DISCORD", "https://hooks.slack.com/services/T0/B0/secret", http.StatusOK, services.ErrInvalidIntegrationURL},
+		{"Test message rejected", 1, "SLACK", "https://hooks.slack.com/services/T0/B0/revoked", http.StatusNotFound, services.ErrIntegrationTestFailed},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := newMemoryIntegrationRepo()
+			poster := &scriptedChatPoster{statuses: []int{tc.testStatus}}
+			service := services.NewClassIntegrationService(repo, &integrationClassUserRepo{}, services.ClassIntegrationConfig{
+				Posters: map[models.IntegrationProvider]utils.ChatPoster{models.SlackIntegration: poster, models.DiscordIntegration: poster},
+				AppURL:  "https://minoriedu.com/",
+			})
+
+			request := dto.ClassIntegrationRequest{Provider: tc.provider, WebhookURL: tc.url, Events: []string{"board.announced", "live.started"}}
+			integration, err := service.SaveIntegration(context.Background(), tc.viewer, 5, request)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("SaveIntegration() error = %v, want %v", err, tc.wantErr)
+			}
+			if tc.wantErr != nil {
+				if _, err := repo.FindIntegration(context.Background(), 5); !errors.Is(err, gorm.ErrRecordNotFound) {
+					t.Errorf("integration was saved despite error %v", tc.wantErr)
+				}
+				return
+			}
+			if integration.Status != "ok" || strings.Contains(integration.WebhookURL, "secret") {
+				t.Errorf("integration = %+v, want status ok with the token masked", integration)
+			}
+			messages := poster.posted()
+			if len(messages) != 1 || messages[0].Link != "https://minoriedu.com/classes/5" || !strings.Contains(messages[0].Body, "お知らせ・ライブ授業の開始") {
+				t.Errorf("test messages = %+v, want one listing the events with a link to the class", messages)
+			}
+		})
+	}
+}
+
+// TestIntegrationDelivery は投稿の結果を連携の状態に記録し、4xxは再投稿せずに連続の回数が上限に達すると投稿を停止することを確認するテストです。
+func TestIntegrationDelivery(t *testing.T) {
+	cases := []struct {
+		name        string
+		statuses    []int
+		wantPosts   int
+		wantStatus  models.IntegrationStatus
+		wantClient  int
+		wantMessage string
+	}{
+		{"Posted", nil, 1, models.IntegrationOK, 0, ""},
+		{"Retried after server error", []int{500, 0}, 3, models.IntegrationOK, 0, ""},
+		{"Retried after rate limit", []int{http.StatusTooManyRequests}, 2, models.IntegrationOK, 0, ""},
+		{"Failed after retries", []int{500, 503, 0}, 3, models.IntegrationFailing, 0, "connection refused"},
+		{"Client error is not retried", []int{http.StatusNotFound}, 1, models.IntegrationFailing, 1, "unexpected status 404"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := newMemoryIntegrationRepo(models.ClassIntegration{
+				ID: 5, CID: 5, Provider: models.SlackIntegration, WebhookURL: "https://hooks.slack.com/services/T0/B0/secret",
+				Events: "board.announced,schedule.changed", Status: models.IntegrationOK,
+			})
+			poster := &scriptedChatPoster{statuses: tc.statuses}
+			service := services.NewClassIntegrationService(repo, &integrationClassUserRepo{}, services.ClassIntegrationConfig{
+				Posters:          map[models.IntegrationProvider]utils.ChatPoster{models.SlackIntegration: poster},
+				AppURL:           "https://minoriedu.com",
+				RetryDelay:       time.Millisecond,
+				ClientErrorLimit: 3,
+			})
+
+			service.PostToIntegration(5, models.BoardAnnouncedIntegration, services.IntegrationPost{
+				Title: "期末試験", AuthorUID: 9, Body: "範囲は第5章まで", Path: "/classes/5/boards/2",
+			})
+			waitFor(t, "the posts", func() bool { return len(poster.posted()) == tc.wantPosts })
+			waitFor(t, "the status", func() bool {
+				integration := repo.current(5)
+				return integration.Status == tc.wantStatus && (tc.wantStatus == models.IntegrationOK) == (integration.LastPostedAt != nil)
+			})
+
+			integration := repo.current(5)
+			if integration.ConsecutiveClientErrors != tc.wantClient || integration.LastError != tc.wantMessage {
+				t.Errorf("client errors = %d with %q, want %d with %q", integration.ConsecutiveClientErrors, integration.LastError, tc.wantClient, tc.wantMessage)
+			}
+			message := poster.posted()[0]
+			if message.Author != "member9" || message.Link != "https://minoriedu.com/classes/5/boards/2" {
+				t.Errorf("message = %+v, want the author nickname and a link to the board", message)
+			}
+		})
+	}
+}
+
+// TestIntegrationAutoDisable は投稿先が4xxを返し続けると投稿を停止し、その後のイベントを投稿しないことを確認するテストです。
+func TestIntegrationAutoDisable(t *testing.T) {
+	repo := newMemoryIntegrationRepo(models.ClassIntegration{
+		ID: 5, CID: 5, Provider: models.SlackIntegration, WebhookURL: "https://hooks.slack.com/services/T0/B0/revoked",
+		Events: "live.started", Status: models.IntegrationOK,
+	})
+	poster := &scriptedChatPoster{statuses: []int{http.StatusForbidden, http.StatusNotFound, http.StatusGone}}
+	service := services.NewClassIntegrationService(repo, &integrationClassUserRepo{}, services.ClassIntegrationConfig{
+		Posters:          map[models.IntegrationProvider]utils.ChatPoster{models.SlackIntegration: poster},
+		ClientErrorLimit: 3,
+	})
+
+	for i := 1; i <= 3; i++ {
+		service.PostToIntegration(5, models.LiveStartedIntegration, services.IntegrationPost{Title: "ライブ授業が始まりました"})
+		waitFor(t, "the client error", func() bool { return repo.current(5).ConsecutiveClientErrors == i })
+	}
+	if integration := repo.current(5); integration.Status != models.IntegrationDisabled || integration.DisabledAt == nil {
+		t.Fatalf("integration = %+v, want disabled", integration)
+	}
+
+	service.PostToIntegration(5, models.LiveStartedIntegration, services.IntegrationPost{Title: "ライブ授業が始まりました"})
+	time.Sleep(20 * time.Millisecond)
+	if posted := len(poster.posted()); posted != 3 {
+		t.Errorf("posted %d messages, want 3 before the integration was disabled", posted)
+	}
+}
+
+// TestIntegrationSkipsUnsubscribedEvent は連携が選択していないイベントを投稿しないことを確認するテストです。
+func TestIntegrationSkipsUnsubscribedEvent(t *testing.T) {
+	repo := newMemoryIntegrationRepo(models.ClassIntegration{
+		ID: 5, CID: 5, Provider: models.DiscordIntegration, WebhookURL: "https://discord.com/api/webhooks/1/secret",
+		Events: "schedule.changed", Status: models.IntegrationOK,
+	})
+	poster := &scriptedChatPoster{}
+	service := services.NewClassIntegrationService(repo, &integrationClassUserRepo{}, services.ClassIntegrationConfig{
+		Posters: map[models.IntegrationProvider]utils.ChatPoster{models.DiscordIntegration: poster},
+	})
+
+	service.PostToIntegration(5, models.LiveStartedIntegration, services.IntegrationPost{Title: "ライブ授業が始まりました"})
+	service.PostToIntegration(5, models.ScheduleChangedIntegration, services.IntegrationPost{Title: "スケジュール「第1回」が変更されました"})
+
+	waitFor(t, "the schedule post", func() bool { return len(poster.posted()) == 1 })
+	time.Sleep(20 * time.Millisecond)
+	if messages := poster.posted(); len(messages) != 1 || messages[0].Title != "スケジュール「第1回」が変更されました" {
+		t.Errorf("posted %+v, want only the schedule change", messages)
+	}
+}
+
+// recordingIntegrations は投稿を予約したイベントを記録するIntegrationPublisherです。
+type recordingIntegrations struct {
+	events []models.IntegrationEvent
+	posts  []services.IntegrationPost
+}
+
+func (p *recordingIntegrations) PostToIntegration(_ uint, event models.IntegrationEvent, post services.IntegrationPost) {
+	p.events = append(p.events, event)
+	p.posts = append(p.posts, post)
+}
+
+// TestUpdateClassBoardPostsAnnouncement は掲示をお知らせにした場合のみ連携に投稿することを確認するテストです。
+func TestUpdateClassBoardPostsAnnouncement(t *testing.T) {
+	cases := []struct {
+		name         string
+		wasAnnounced bool
+		announced    bool
+		wantPosted   bool
+	}{
+		{"Announced", false, true, true},
+		{"Already announced", true, true, false},
+		{"Not announced", false, false, false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := &pinBoardRepo{board: models.ClassBoard{ID: 2, CID: 5, UID: 9, Title: "期末試験", IsAnnounced: tc.wasAnnounced}}
+			integrations := &recordingIntegrations{}
+			service := services.NewClassBoardService(repo, &adminClassUserRepo{}, &recordingUploader{}, nil, nil, nil, true, nil, nil, nil, integrations)
+
+			if _, err := service.UpdateClassBoard(context.Background(), 2, dto.ClassBoardUpdateDTO{ID: 2, IsAnnounced: tc.announced}, ""); err != nil {
+				t.Fatalf("UpdateClassBoard() error = %v", err)
+			}
+			if posted := len(integrations.events) == 1; posted != tc.wantPosted {
+				t.Fatalf("posted = %v, want %v", posted, tc.wantPosted)
+			}
+			if tc.wantPosted {
+				post := integrations.posts[0]
+				if integrations.events[0] != models.BoardAnnouncedIntegration || post.AuthorUID != 9 || post.Path != "/classes/5/boards/2" {
+					t.Errorf("posted %s %+v, want the announcement by uid 9 linking to the board", integrations.events[0], post)
+				}
+			}
+		})
+	}
+}
+
+// TestUpdateClassSchedulePostsLiveStarted はスケジュールをライブ中にした場合のみライブ授業の開始を連携に投稿することを確認するテストです。
+func TestUpdateClassSchedulePostsLiveStarted(t *testing.T) {
+	cases := []struct {
+		name       string
+		wasLive    bool
+		isLive     bool
+		wantPosted bool
+	}{
+		{"Started", false, true, true},
+		{"Already live", true, true, false},
+		{"Ended", true, false, false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := &cancelScheduleRepo{schedule: models.ClassSchedule{ID: 3, CID: 5, Title: "第1回", IsLive: tc.wasLive}}
+			integrations := &recordingIntegrations{}
+			service := services.NewClassScheduleService(repo, &cancelClassUserRepo{}, nil, nil, true, nil, nil, integrations, nil, services.CheckInTokenConfig{}, nil)
+
+			if _, err := service.UpdateClassSchedule(context.Background(), 3, &dto.UpdateClassScheduleDTO{IsLive: &tc.isLive}); err != nil {
+				t.Fatalf("UpdateClassSchedule() error = %v", err)
+			}
+			var live []services.IntegrationPost
+			for i, event := range integrations.events {
+				if event == models.LiveStartedIntegration {
+					live = append(live, integrations.posts[i])
+				}
+			}
+			if posted := len(live) == 1; posted != tc.wantPosted {
+				t.Fatalf("live posts = %+v, want posted %v", live, tc.wantPosted)
+			}
+			if tc.wantPosted && live[0].Path != "/classes/5/live" {
+				t.Errorf("posted %+v, want a link to the live class", live[0])
+			}
+		})
+	}
+}
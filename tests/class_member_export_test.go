@@ -0,0 +1,96 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/controllers"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/middlewares"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"github.com/gin-gonic/gin"
+)
+
+// exportClassUserRepo は管理者のロールと、名簿エクスポート用のメンバーまたはエラーを返すClassUserRepositoryです。
+type exportClassUserRepo struct {
+	repositories.ClassUserRepository
+	members []dto.ClassMemberExportDTO
+	err     error
+}
+
+func (r *exportClassUserRepo) GetRole(context.Context, uint, uint) (string, error) {
+	return "ADMIN", nil
+}
+
+func (r *exportClassUserRepo) GetClassMembersForExport(context.Context, uint) ([]dto.ClassMemberExportDTO, error) {
+	return r.members, r.err
+}
+
+// TestExportMembers は数式として解釈される文字で始まる名前とメールアドレスの先頭に'を付けてCSVを返し、
+// 名簿の取得に失敗した場合はCSVのヘッダーを付けずにエラーを返すことを確認するテストです。
+func TestExportMembers(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	joinedAt := time.Date(2024, 4, 1, 9, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		name            string
+		members         []dto.ClassMemberExportDTO
+		err             error
+		wantStatus      int
+		wantBody        string
+		wantDisposition string
+	}{
+		{
+			"Formula Injection",
+			[]dto.ClassMemberExportDTO{
+				{UID: 1, Name: "=HYPERLINK(\"http://example.com\")", Email: "@sum@example.com", Role: "USER", JoinedAt: joinedAt, AttendanceRate: 0.5},
+				{UID: 2, Name: "+81", Email: "-a@example.com", Role: "USER", JoinedAt: joinedAt},
+				{UID: 3, Name: "山田", Email: "yamada@example.com", Role: "ADMIN", JoinedAt: joinedAt, AttendanceRate: 1},
+			},
+			nil,
+			http.StatusOK,
+			"uid,name,email,role,joined_at,attendance_rate\n" +
+				"1,\"'=HYPERLINK(\"\"http://example.com\"\")\",'@sum@example.com,USER,2024-04-01T09:00:00Z,0.5000\n" +
+				"2,'+81,'-a@example.com,USER,2024-04-01T09:00:00Z,0.0000\n" +
+				"3,山田,yamada@example.com,ADMIN,2024-04-01T09:00:00Z,1.0000\n",
+			`attachment; filename="class_10_members.csv"`,
+		},
+		{"Repository Error", nil, errors.New("connection refused"), http.StatusInternalServerError, "", ""},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := &exportClassUserRepo{members: tc.members, err: tc.err}
+			service := services.NewClassUserService(nil, repo, nil, nil, nil, nil, nil, 0, nil, nil, nil)
+			r := gin.New()
+			r.Use(middlewares.GlobalErrorHandler(&recordingErrorReporter{}))
+			r.Use(func(c *gin.Context) { c.Set("userID", uint(1)) })
+			r.GET("/cl/:cid/members/export.csv", controllers.NewClassUserController(service).ExportMembers)
+
+			req, _ := http.NewRequest(http.MethodGet, "/cl/10/members/export.csv", nil)
+			resp := httptest.NewRecorder()
+			r.ServeHTTP(resp, req)
+
+			if resp.Code != tc.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", resp.Code, tc.wantStatus, resp.Body.String())
+			}
+			if got := resp.Header().Get("Content-Disposition"); got != tc.wantDisposition {
+				t.Errorf("Content-Disposition = %q, want %q", got, tc.wantDisposition)
+			}
+			if tc.wantStatus != http.StatusOK {
+				if got := resp.Header().Get("Content-Type"); got == "text/csv; charset=utf-8" {
+					t.Errorf("Content-Type = %q on an error", got)
+				}
+				return
+			}
+			if resp.Body.String() != tc.wantBody {
+				t.Errorf("body = %q, want %q", resp.Body.String(), tc.wantBody)
+			}
+		})
+	}
+}
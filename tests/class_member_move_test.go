@@ -0,0 +1,280 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/controllers"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/middlewares"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"github.com/gin-gonic/gin"
+)
+
+// transferMembers はクラスIDごとのメンバーのロールを保持します。
+type transferMembers map[uint]map[uint]string
+
+func (m transferMembers) clone() transferMembers {
+	copied := transferMembers{}
+	for cid, members := range m {
+		copied[cid] = map[uint]string{}
+		for uid, role := range members {
+			copied[cid][uid] = role
+		}
+	}
+	return copied
+}
+
+// transferClassUserRepo はメンバーをメモリ上で管理するClassUserRepositoryです。
+// archivedのクラスはアクティブなクラス数に数えません。
+type transferClassUserRepo struct {
+	repositories.ClassUserRepository
+	members       transferMembers
+	admins        map[uint]bool
+	archived      map[uint]bool
+	failAddMember bool
+}
+
+func (r *transferClassUserRepo) CountActiveClasses(_ context.Context, uid uint) (int64, error) {
+	var count int64
+	for cid, members := range r.members {
+		if _, ok := members[uid]; ok && !r.archived[cid] {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *transferClassUserRepo) IsAdmin(_ context.Context, _ uint, cid uint) (bool, error) {
+	return r.admins[cid], nil
+}
+
+func (r *transferClassUserRepo) FindMembersByUIDs(_ context.Context, cid uint, uids []uint) ([]models.ClassUser, error) {
+	var classUsers []models.ClassUser
+	for _, uid := range uids {
+		if role, ok := r.members[cid][uid]; ok {
+			classUsers = append(classUsers, models.ClassUser{CID: cid, UID: uid, Role: role})
+		}
+	}
+	return classUsers, nil
+}
+
+func (r *transferClassUserRepo) AddMember(_ context.Context, classUser *models.ClassUser) error {
+	if r.failAddMember {
+		return errInduced
+	}
+	r.members[classUser.CID][classUser.UID] = classUser.Role
+	return nil
+}
+
+func (r *transferClassUserRepo) DeleteClassUser(_ context.Context, uid uint, cid uint) error {
+	delete(r.members[cid], uid)
+	return nil
+}
+
+// transferClassRepo はtransferClassUserRepoのarchivedに従ってクラスを返すClassRepositoryです。
+type transferClassRepo struct {
+	repositories.ClassRepository
+	repo *transferClassUserRepo
+}
+
+func (r *transferClassRepo) GetByID(_ context.Context, classID uint) (*models.Class, error) {
+	return &models.Class{ID: classID, IsArchived: r.repo.archived[classID]}, nil
+}
+
+// transferTxManager はfnがエラーを返した場合にメンバーを元に戻すTxManagerです。
+type transferTxManager struct {
+	repo *transferClassUserRepo
+}
+
+func (m *transferTxManager) WithinTransaction(_ context.Context, fn func(repos repositories.RepositorySet) error) error {
+	snapshot := m.repo.members.clone()
+	if err := fn(repositories.RepositorySet{Class: &transferClassRepo{repo: m.repo}, ClassUser: m.repo, User: &limitUserRepo{}, TxManager: m}); err != nil {
+		m.repo.members = snapshot
+		return err
+	}
+	return nil
+}
+
+// TestMoveMembers は移動とコピーでメンバーを移動先に追加し、既存のメンバー、移動元のメンバーでないユーザー、移動元の管理者をスキップし、
+// アクティブなクラス数が増える場合のみ上限に達したユーザーをスキップすることを確認するテストです。
+func TestMoveMembers(t *testing.T) {
+	cases := []struct {
+		name          string
+		copy          bool
+		admins        map[uint]bool
+		archived      map[uint]bool
+		classLimit    int
+		failAddMember bool
+		wantErr       error
+		wantResults   []dto.MemberTransferResultDTO
+		wantMembers   transferMembers
+	}{
+		{
+			name:   "Move",
+			admins: map[uint]bool{1: true, 2: true},
+			wantResults: []dto.MemberTransferResultDTO{
+				{UID: 10, Status: dto.MemberTransferMoved},
+				{UID: 11, Status: dto.MemberTransferSkippedAlreadyMember},
+				{UID: 12, Status: dto.MemberTransferSkippedNotMember},
+				{UID: 1, Status: dto.MemberTransferSkippedAdmin},
+			},
+			wantMembers: transferMembers{1: {1: "ADMIN", 11: "USER"}, 2: {1: "ADMIN", 10: "USER", 11: "USER"}},
+		},
+		{
+			name:   "Copy",
+			copy:   true,
+			admins: map[uint]bool{1: true, 2: true},
+			wantResults: []dto.MemberTransferResultDTO{
+				{UID: 10, Status: dto.MemberTransferCopied},
+				{UID: 11, Status: dto.MemberTransferSkippedAlreadyMember},
+				{UID: 12, Status: dto.MemberTransferSkippedNotMember},
+				{UID: 1, Status: dto.MemberTransferSkippedAdmin},
+			},
+			wantMembers: transferMembers{1: {1: "ADMIN", 10: "USER", 11: "USER"}, 2: {1: "ADMIN", 10: "USER", 11: "USER"}},
+		},
+		{
+			name:       "Move Between Active Classes At Limit",
+			admins:     map[uint]bool{1: true, 2: true},
+			classLimit: 1,
+			wantResults: []dto.MemberTransferResultDTO{
+				{UID: 10, Status: dto.MemberTransferMoved},
+				{UID: 11, Status: dto.MemberTransferSkippedAlreadyMember},
+				{UID: 12, Status: dto.MemberTransferSkippedNotMember},
+				{UID: 1, Status: dto.MemberTransferSkippedAdmin},
+			},
+			wantMembers: transferMembers{1: {1: "ADMIN", 11: "USER"}, 2: {1: "ADMIN", 10: "USER", 11: "USER"}},
+		},
+		{
+			name:       "Copy At Limit",
+			copy:       true,
+			admins:     map[uint]bool{1: true, 2: true},
+			classLimit: 1,
+			wantResults: []dto.MemberTransferResultDTO{
+				{UID: 10, Status: dto.MemberTransferSkippedClassLimit},
+				{UID: 11, Status: dto.MemberTransferSkippedAlreadyMember},
+				{UID: 12, Status: dto.MemberTransferSkippedNotMember},
+				{UID: 1, Status: dto.MemberTransferSkippedAdmin},
+			},
+			wantMembers: transferMembers{1: {1: "ADMIN", 10: "USER", 11: "USER"}, 2: {1: "ADMIN", 11: "USER"}},
+		},
+		{
+			name:       "Move From Archived Class Under Limit",
+			admins:     map[uint]bool{1: true, 2: true},
+			archived:   map[uint]bool{1: true},
+			classLimit: 1,
+			wantResults: []dto.MemberTransferResultDTO{
+				{UID: 10, Status: dto.MemberTransferMoved},
+				{UID: 11, Status: dto.MemberTransferSkippedAlreadyMember},
+				{UID: 12, Status: dto.MemberTransferSkippedNotMember},
+				{UID: 1, Status: dto.MemberTransferSkippedAdmin},
+			},
+			wantMembers: transferMembers{1: {1: "ADMIN", 11: "USER"}, 2: {1: "ADMIN", 10: "USER", 11: "USER"}},
+		},
+		{
+			name:        "Not Admin Of Destination",
+			admins:      map[uint]bool{1: true},
+			wantErr:     services.ErrUnauthorized,
+			wantMembers: transferMembers{1: {1: "ADMIN", 10: "USER", 11: "USER"}, 2: {1: "ADMIN", 11: "USER"}},
+		},
+		{
+			name:          "Rolls Back On Failure",
+			admins:        map[uint]bool{1: true, 2: true},
+			failAddMember: true,
+			wantErr:       errInduced,
+			wantMembers:   transferMembers{1: {1: "ADMIN", 10: "USER", 11: "USER"}, 2: {1: "ADMIN", 11: "USER"}},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := &transferClassUserRepo{
+				members:       transferMembers{1: {1: "ADMIN", 10: "USER", 11: "USER"}, 2: {1: "ADMIN", 11: "USER"}},
+				admins:        tc.admins,
+				archived:      tc.archived,
+				failAddMember: tc.failAddMember,
+			}
+			service := services.NewClassUserService(&transferTxManager{repo: repo}, repo, nil, nil, nil, &limitUserRepo{}, nil, tc.classLimit, nil, nil, nil)
+
+			request := dto.MoveMembersRequest{FromCID: 1, ToCID: 2, UIDs: []uint{10, 11, 12, 1, 10}, Role: "USER", Copy: tc.copy}
+			result, err := service.MoveMembers(context.Background(), 1, request)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tc.wantErr)
+			}
+			if !reflect.DeepEqual(repo.members, tc.wantMembers) {
+				t.Errorf("members = %v, want %v", repo.members, tc.wantMembers)
+			}
+			if tc.wantErr != nil {
+				return
+			}
+			if !reflect.DeepEqual(result.Results, tc.wantResults) {
+				t.Errorf("results = %v, want %v", result.Results, tc.wantResults)
+			}
+			wantTransferred := 0
+			for _, want := range tc.wantResults {
+				if want.Status == dto.MemberTransferMoved || want.Status == dto.MemberTransferCopied {
+					wantTransferred++
+				}
+			}
+			if result.Transferred != wantTransferred || result.Skipped != len(tc.wantResults)-wantTransferred {
+				t.Errorf("transferred = %d skipped = %d, want %d and %d", result.Transferred, result.Skipped, wantTransferred, len(tc.wantResults)-wantTransferred)
+			}
+		})
+	}
+}
+
+// moveClassUserService はメンバーの移動を呼び出したかを記録するClassUserServiceです。
+type moveClassUserService struct {
+	services.ClassUserService
+	called bool
+}
+
+func (s *moveClassUserService) MoveMembers(_ context.Context, _ uint, request dto.MoveMembersRequest) (*dto.MoveMembersResultDTO, error) {
+	s.called = true
+	return &dto.MoveMembersResultDTO{FromCID: request.FromCID, ToCID: request.ToCID}, nil
+}
+
+// TestMoveMembersRole は移動先のロールにADMINを指定したリクエストを400で拒否することを確認するテストです。
+func TestMoveMembersRole(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+
+	cases := []struct {
+		name       string
+		role       string
+		wantStatus int
+	}{
+		{"User", "user", http.StatusOK},
+		{"Assistant", "ASSISTANT", http.StatusOK},
+		{"Admin", "admin", http.StatusBadRequest},
+		{"Unknown", "OWNER", http.StatusBadRequest},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			service := &moveClassUserService{}
+			r := gin.New()
+			r.Use(middlewares.GlobalErrorHandler(&recordingErrorReporter{}))
+			r.Use(func(c *gin.Context) { c.Set("userID", uint(1)) })
+			r.POST("/cu/members/move", controllers.NewClassUserController(service).MoveMembers)
+
+			body := `{"from_cid":1,"to_cid":2,"uids":[10],"role":"` + tc.role + `"}`
+			req, _ := http.NewRequest(http.MethodPost, "/cu/members/move", strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+			r.ServeHTTP(resp, req)
+
+			if resp.Code != tc.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", resp.Code, tc.wantStatus, resp.Body.String())
+			}
+			if service.called != (tc.wantStatus == http.StatusOK) {
+				t.Errorf("called = %v, want %v", service.called, tc.wantStatus == http.StatusOK)
+			}
+		})
+	}
+}
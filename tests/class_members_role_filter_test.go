@@ -0,0 +1,69 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/controllers"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/middlewares"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"github.com/gin-gonic/gin"
+)
+
+// roleFilterClassUserService は受け取ったロール名を記録するClassUserServiceです。
+type roleFilterClassUserService struct {
+	services.ClassUserService
+	called    bool
+	roleNames []string
+}
+
+func (s *roleFilterClassUserService) GetClassMembers(_ context.Context, _ uint, roleNames ...string) ([]dto.ClassMemberDTO, error) {
+	s.called = true
+	s.roleNames = roleNames
+	return []dto.ClassMemberDTO{}, nil
+}
+
+// TestGetClassMembersRoleFilter は複数のroleを大文字に揃えてサービスに渡し、無効なロール名には400を返すことを確認するテストです。
+func TestGetClassMembersRoleFilter(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+
+	cases := []struct {
+		name       string
+		query      string
+		wantStatus int
+		wantRoles  []string
+	}{
+		{"All Members", "", http.StatusOK, nil},
+		{"Single Role", "?role=ADMIN", http.StatusOK, []string{"ADMIN"}},
+		{"Multiple Roles", "?role=Admin&role=assistant", http.StatusOK, []string{"ADMIN", "ASSISTANT"}},
+		{"Empty Role", "?role=", http.StatusOK, nil},
+		{"Invalid Role", "?role=ADMIN&role=OWNER", http.StatusBadRequest, nil},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			service := &roleFilterClassUserService{}
+			r := gin.New()
+			r.Use(middlewares.GlobalErrorHandler(&recordingErrorReporter{}))
+			r.GET("/api/gin/v2/cu/class/:cid/members", controllers.NewClassUserController(service).GetClassMembers)
+
+			req, _ := http.NewRequest(http.MethodGet, "/api/gin/v2/cu/class/1/members"+tc.query, nil)
+			resp := httptest.NewRecorder()
+			r.ServeHTTP(resp, req)
+
+			if resp.Code != tc.wantStatus {
+				t.Fatalf("status = %d, want %d", resp.Code, tc.wantStatus)
+			}
+			if service.called != (tc.wantStatus == http.StatusOK) {
+				t.Errorf("service called = %v, want %v", service.called, tc.wantStatus == http.StatusOK)
+			}
+			if !reflect.DeepEqual(service.roleNames, tc.wantRoles) {
+				t.Errorf("roleNames = %q, want %q", service.roleNames, tc.wantRoles)
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/controllers"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/middlewares"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"github.com/gin-gonic/gin"
+)
+
+// previewClassRepo は説明付きのクラスのプレビューを返すClassRepositoryです。
+type previewClassRepo struct {
+	repositories.ClassRepository
+}
+
+func (r *previewClassRepo) GetClassPreview(_ context.Context, classID uint) (*dto.ClassPreviewDTO, error) {
+	description := "月曜1限の授業です"
+	return &dto.ClassPreviewDTO{ID: classID, Name: "数学", Description: &description, MemberCount: 12}, nil
+}
+
+// previewScheduleRepo は次回のスケジュールがないClassScheduleRepositoryです。
+type previewScheduleRepo struct {
+	repositories.ClassScheduleRepository
+}
+
+func (r *previewScheduleRepo) FindNextClassSchedule(context.Context, uint, time.Time) (*models.ClassSchedule, error) {
+	return nil, nil
+}
+
+// TestGetClassPreviewSecret はシークレットをX-Class-Secretヘッダーで受け取ってクエリでは受け付けず、
+// 入力ミスが上限に達したユーザーには正しいシークレットでも429を返し、他のユーザーは制限しないことを確認するテストです。
+func TestGetClassPreviewSecret(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	secret := "s3cret"
+	service := services.NewCreateClassService(
+		nil,
+		&previewClassRepo{},
+		nil,
+		&flyerClassCodeRepo{code: &models.ClassCode{CID: 1, Code: "ABC123", Secret: &secret}},
+		nil,
+		&previewScheduleRepo{},
+		"",
+		openTestRedis(t),
+		nil,
+	)
+	controller := controllers.NewCreateClassController(service, nil, nil)
+
+	cases := []struct {
+		name         string
+		userID       uint
+		path         string
+		secret       string
+		repeat       int
+		wantStatus   int
+		wantDetailed bool
+	}{
+		{"Correct Secret", 1, "/cl/1/preview", secret, 1, http.StatusOK, true},
+		{"Secret In Query", 1, "/cl/1/preview?secret=" + secret, "", 1, http.StatusOK, false},
+		{"No Secret", 1, "/cl/1/preview", "", 10, http.StatusOK, false},
+		{"Wrong Secret", 2, "/cl/1/preview", "guess", 5, http.StatusOK, false},
+		{"Limit Reached", 2, "/cl/1/preview", secret, 1, http.StatusTooManyRequests, false},
+		{"Other User", 3, "/cl/1/preview", secret, 1, http.StatusOK, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := gin.New()
+			r.Use(middlewares.GlobalErrorHandler(&recordingErrorReporter{}))
+			r.Use(func(c *gin.Context) { c.Set("userID", tc.userID) })
+			r.GET("/cl/:cid/preview", controller.GetClassPreview)
+
+			var resp *httptest.ResponseRecorder
+			for i := 0; i < tc.repeat; i++ {
+				req, _ := http.NewRequest(http.MethodGet, tc.path, nil)
+				if tc.secret != "" {
+					req.Header.Set("X-Class-Secret", tc.secret)
+				}
+				resp = httptest.NewRecorder()
+				r.ServeHTTP(resp, req)
+			}
+
+			if resp.Code != tc.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", resp.Code, tc.wantStatus, resp.Body.String())
+			}
+			if tc.wantStatus != http.StatusOK {
+				return
+			}
+			var body struct {
+				Data dto.ClassPreviewDTO `json:"data"`
+			}
+			if err := json.Unmarshal(resp.Body.Bytes(), &body); err != nil {
+				t.Fatalf("body = %s: %v", resp.Body.String(), err)
+			}
+			if detailed := body.Data.Description != nil; detailed != tc.wantDetailed || !body.Data.IsPrivate {
+				t.Errorf("preview = %+v, want detailed = %v", body.Data, tc.wantDetailed)
+			}
+		})
+	}
+}
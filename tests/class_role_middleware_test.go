@@ -0,0 +1,74 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/constants"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/middlewares"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// roleClassUserRepo はuidごとのロールを返すClassUserRepositoryです。ロールがないuidはメンバーではありません。
+type roleClassUserRepo struct {
+	repositories.ClassUserRepository
+	roles map[uint]string
+}
+
+func (r *roleClassUserRepo) GetRole(_ context.Context, uid uint, _ uint) (string, error) {
+	role, ok := r.roles[uid]
+	if !ok {
+		return "", gorm.ErrRecordNotFound
+	}
+	return role, nil
+}
+
+func (r *roleClassUserRepo) IsAdmin(ctx context.Context, uid uint, cid uint) (bool, error) {
+	role, err := r.GetRole(ctx, uid, cid)
+	return role == "ADMIN", err
+}
+
+// TestClassRoleMiddleware はAdminMiddlewareが管理者のみ、AssistantMiddlewareが管理者とアシスタントを通し、メンバーでないユーザーを401にすることを確認するテストです。
+func TestClassRoleMiddleware(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	repo := &roleClassUserRepo{roles: map[uint]string{1: "ADMIN", 2: "ASSISTANT", 3: "USER", 4: "APPLICANT"}}
+	service := services.NewClassUserService(nil, repo, nil, nil, nil, nil, nil, 0, nil, nil, nil)
+
+	r := gin.New()
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	r.GET("/admin", middlewares.AdminMiddleware(service), ok)
+	r.GET("/assistant", middlewares.AssistantMiddleware(service), ok)
+
+	cases := []struct {
+		name       string
+		path       string
+		uid        uint
+		wantStatus int
+	}{
+		{"Admin On Admin Route", "/admin", 1, http.StatusOK},
+		{"Assistant On Admin Route", "/admin", 2, constants.StatusForbidden},
+		{"Admin On Assistant Route", "/assistant", 1, http.StatusOK},
+		{"Assistant On Assistant Route", "/assistant", 2, http.StatusOK},
+		{"Member On Assistant Route", "/assistant", 3, constants.StatusForbidden},
+		{"Applicant On Assistant Route", "/assistant", 4, constants.StatusForbidden},
+		{"Not A Member", "/assistant", 5, constants.StatusUnauthorized},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			resp := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("%s?uid=%d&cid=9", tc.path, tc.uid), nil)
+			r.ServeHTTP(resp, req)
+
+			if resp.Code != tc.wantStatus {
+				t.Errorf("status = %d, want %d", resp.Code, tc.wantStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/controllers"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"github.com/gin-gonic/gin"
+)
+
+// cancelScheduleRepo は1件のスケジュールを保持し、更新を記録するClassScheduleRepositoryです。
+type cancelScheduleRepo struct {
+	repositories.ClassScheduleRepository
+	schedule models.ClassSchedule
+	updated  bool
+}
+
+func (r *cancelScheduleRepo) GetClassScheduleByID(context.Context, uint) (*models.ClassSchedule, error) {
+	schedule := r.schedule
+	return &schedule, nil
+}
+
+func (r *cancelScheduleRepo) UpdateClassSchedule(_ context.Context, schedule *models.ClassSchedule, _ uint) error {
+	r.schedule = *schedule
+	r.updated = true
+	return nil
+}
+
+// cancelClassUserRepo は管理者の判定とクラスのメンバーを固定で返すClassUserRepositoryです。
+type cancelClassUserRepo struct {
+	adminClassUserRepo
+}
+
+func (r *cancelClassUserRepo) GetClassMembers(context.Context, uint, ...string) ([]dto.ClassMemberDTO, error) {
+	return []dto.ClassMemberDTO{{Uid: 1, Role: "ADMIN"}, {Uid: 2, Role: "USER"}, {Uid: 3, Role: "USER"}}, nil
+}
+
+// TestSetClassScheduleCancelled は管理者のみが休講を設定でき、休講にした場合のみ操作者以外のメンバーに通知されることを確認するテストです。
+func TestSetClassScheduleCancelled(t *testing.T) {
+	cases := []struct {
+		name        string
+		admin       bool
+		current     bool
+		cancelled   bool
+		wantErr     error
+		wantUpdated bool
+		wantUIDs    []uint
+	}{
+		{"Cancel", true, false, true, nil, true, []uint{2, 3}},
+		{"Uncancel", true, true, false, nil, true, nil},
+		{"Already Cancelled", true, true, true, nil, false, nil},
+		{"Not Admin", false, false, true, services.ErrUnauthorized, false, nil},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := &cancelScheduleRepo{schedule: models.ClassSchedule{ID: 5, CID: 10, Title: "第1回", IsCancelled: tc.current}}
+			notifier := &recordingNotifier{}
+			service := services.NewClassScheduleService(repo, &cancelClassUserRepo{adminClassUserRepo{admin: tc.admin}}, nil, notifier, true, nil, nil, nil, nil, services.CheckInTokenConfig{}, nil)
+
+			schedule, err := service.SetClassScheduleCancelled(context.Background(), 5, 1, tc.cancelled)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tc.wantErr)
+			}
+			if repo.updated != tc.wantUpdated {
+				t.Errorf("updated = %v, want %v", repo.updated, tc.wantUpdated)
+			}
+			if tc.wantErr == nil && schedule.IsCancelled != tc.cancelled {
+				t.Errorf("is_cancelled = %v, want %v", schedule.IsCancelled, tc.cancelled)
+			}
+			if !reflect.DeepEqual(notifier.uids, tc.wantUIDs) {
+				t.Errorf("notified uids = %v, want %v", notifier.uids, tc.wantUIDs)
+			}
+		})
+	}
+}
+
+// TestCancelClassScheduleForbidden はクラス管理者でないユーザーの休講の操作に401ではなく403を返すことを確認するテストです。
+func TestCancelClassScheduleForbidden(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+
+	for _, path := range []string{"/cs/5/cancel", "/cs/5/uncancel"} {
+		t.Run(path, func(t *testing.T) {
+			repo := &cancelScheduleRepo{schedule: models.ClassSchedule{ID: 5, CID: 10}}
+			service := services.NewClassScheduleService(repo, &cancelClassUserRepo{adminClassUserRepo{admin: false}}, nil, nil, true, nil, nil, nil, nil, services.CheckInTokenConfig{}, nil)
+			controller := controllers.NewClassScheduleController(service, nil)
+
+			r := gin.New()
+			r.Use(func(c *gin.Context) { c.Set("userID", uint(2)) })
+			r.PATCH("/cs/:id/cancel", controller.CancelClassSchedule)
+			r.PATCH("/cs/:id/uncancel", controller.UncancelClassSchedule)
+
+			req, _ := http.NewRequest(http.MethodPatch, path, nil)
+			resp := httptest.NewRecorder()
+			r.ServeHTTP(resp, req)
+
+			if resp.Code != http.StatusForbidden {
+				t.Errorf("status = %d, want %d: %s", resp.Code, http.StatusForbidden, resp.Body.String())
+			}
+			if repo.updated {
+				t.Error("schedule was updated by a non-admin")
+			}
+		})
+	}
+}
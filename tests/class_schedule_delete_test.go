@@ -0,0 +1,85 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/controllers"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"github.com/gin-gonic/gin"
+)
+
+// deleteScheduleRepo は1件のスケジュールを保持し、削除を記録するClassScheduleRepositoryです。
+type deleteScheduleRepo struct {
+	cancelScheduleRepo
+	deleted bool
+}
+
+func (r *deleteScheduleRepo) DeleteClassSchedule(context.Context, uint) error {
+	r.deleted = true
+	return nil
+}
+
+// TestDeleteClassScheduleForce は開始済みのスケジュールを運用の管理者がforceを指定した場合のみ削除でき、
+// クラスの管理者には403を返し、強制削除した場合のみWARNの監査ログを出力することを確認するテストです。
+func TestDeleteClassScheduleForce(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+
+	cases := []struct {
+		name        string
+		startedAt   time.Time
+		userID      uint
+		classAdmin  bool
+		query       string
+		wantStatus  int
+		wantDeleted bool
+		wantWarn    bool
+	}{
+		{"Future Schedule", future, 2, true, "", http.StatusOK, true, false},
+		{"Future Schedule With Force", future, 2, true, "?force=true", http.StatusOK, true, false},
+		{"Started Without Force", past, 1, false, "", http.StatusUnprocessableEntity, false, false},
+		{"Started By Class Admin", past, 2, true, "?force=true", http.StatusForbidden, false, false},
+		{"Started By Super Admin", past, 1, false, "?force=true", http.StatusOK, true, true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			log.SetOutput(&logs)
+			t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+			repo := &deleteScheduleRepo{cancelScheduleRepo: cancelScheduleRepo{schedule: models.ClassSchedule{ID: 10, CID: 5, StartedAt: tc.startedAt, EndedAt: tc.startedAt.Add(time.Hour)}}}
+			classUserRepo := &cancelClassUserRepo{adminClassUserRepo{admin: tc.classAdmin}}
+			service := services.NewClassScheduleService(repo, classUserRepo, nil, nil, true, nil, nil, nil, nil, services.CheckInTokenConfig{}, []uint{1})
+			controller := controllers.NewClassScheduleController(service, services.NewJWTService("test-secret"))
+
+			r := gin.New()
+			r.Use(func(c *gin.Context) { c.Set("userID", tc.userID) })
+			r.DELETE("/cs/:id", controller.DeleteClassSchedule)
+
+			req, _ := http.NewRequest(http.MethodDelete, "/cs/10"+tc.query, nil)
+			resp := httptest.NewRecorder()
+			r.ServeHTTP(resp, req)
+
+			if resp.Code != tc.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", resp.Code, tc.wantStatus, resp.Body.String())
+			}
+			if repo.deleted != tc.wantDeleted {
+				t.Errorf("deleted = %v, want %v", repo.deleted, tc.wantDeleted)
+			}
+			if warned := strings.Contains(logs.String(), "[WARN] force-deleted class schedule"); warned != tc.wantWarn {
+				t.Errorf("warned = %v, want %v: %s", warned, tc.wantWarn, logs.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+)
+
+// TestGetClassScheduleByIDClass はスケジュールの詳細にクラスの名前と画像を含め、それ以外のクラスの項目を含めないことを確認するテストです。
+func TestGetClassScheduleByIDClass(t *testing.T) {
+	image := "https://example.com/class.png"
+	description := "非公開のメモ"
+	repo := &cancelScheduleRepo{schedule: models.ClassSchedule{
+		ID:    3,
+		Title: "第1回",
+		CID:   5,
+		Class: models.Class{ID: 5, Name: "数学", Image: &image, Description: &description},
+	}}
+	service := services.NewClassScheduleService(repo, &cancelClassUserRepo{}, nil, nil, true, nil, nil, nil, nil, services.CheckInTokenConfig{}, nil)
+
+	schedule, err := service.GetClassScheduleByID(context.Background(), 3)
+	if err != nil {
+		t.Fatalf("err = %v", err)
+	}
+	want := dto.ClassScheduleClassDTO{ID: 5, Name: "数学", ImageURL: &image}
+	if !reflect.DeepEqual(schedule.Class, want) {
+		t.Errorf("class = %+v, want %+v", schedule.Class, want)
+	}
+
+	body, err := json.Marshal(schedule)
+	if err != nil {
+		t.Fatalf("failed to encode schedule: %v", err)
+	}
+	var decoded map[string]interface{}
+	_ = json.Unmarshal(body, &decoded)
+	class, ok := decoded["class"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("response %s has no class", body)
+	}
+	if len(class) != 3 || class["image_url"] != image {
+		t.Errorf("class = %v, want id, name and image_url only", class)
+	}
+	if decoded["Title"] != "第1回" {
+		t.Errorf("Title = %v, want the model's key to be kept", decoded["Title"])
+	}
+}
@@ -0,0 +1,80 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/utils"
+)
+
+// TestUpdateClassScheduleAttendanceMode は場所の種類を兼ねる出席方式を定義済みの値のみ受け付け、
+// 以前の場所の種類の値や不正な値では更新しないことを確認するテストです。
+func TestUpdateClassScheduleAttendanceMode(t *testing.T) {
+	cases := []struct {
+		name    string
+		mode    string
+		wantErr error
+	}{
+		{"Online", "ONLINE", nil},
+		{"In Person", "IN_PERSON", nil},
+		{"Hybrid", "HYBRID", nil},
+		{"Empty", "", services.ErrInvalidAttendanceMode},
+		{"Location Type Value", "online", services.ErrInvalidAttendanceMode},
+		{"Unknown", "moon", services.ErrInvalidAttendanceMode},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := &cancelScheduleRepo{schedule: models.ClassSchedule{ID: 3, CID: 5, AttendanceMode: models.InPersonMode}}
+			service := services.NewClassScheduleService(repo, &cancelClassUserRepo{}, nil, nil, true, nil, nil, nil, nil, services.CheckInTokenConfig{}, nil)
+			location := "本館301教室"
+
+			schedule, err := service.UpdateClassSchedule(context.Background(), 3, &dto.UpdateClassScheduleDTO{Location: &location, AttendanceMode: &tc.mode})
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tc.wantErr)
+			}
+			if repo.updated != (tc.wantErr == nil) {
+				t.Errorf("updated = %v, want %v", repo.updated, tc.wantErr == nil)
+			}
+			if err == nil && (schedule.Location != location || string(schedule.AttendanceMode) != tc.mode) {
+				t.Errorf("location = %q/%q, want %q/%q", schedule.Location, schedule.AttendanceMode, location, tc.mode)
+			}
+		})
+	}
+}
+
+// TestGetClassSchedulesByDateInvalidAttendanceMode は日付での取得で不正な出席方式を指定した場合に検索せずにエラーを返すことを確認するテストです。
+func TestGetClassSchedulesByDateInvalidAttendanceMode(t *testing.T) {
+	service := services.NewClassScheduleService(&cancelScheduleRepo{}, &cancelClassUserRepo{}, nil, nil, true, nil, nil, nil, nil, services.CheckInTokenConfig{}, nil)
+
+	for _, mode := range []models.AttendanceMode{"remote", "online"} {
+		if _, err := service.GetClassSchedulesByDate(context.Background(), 5, time.Now(), mode); !errors.Is(err, services.ErrInvalidAttendanceMode) {
+			t.Errorf("mode %q: err = %v, want %v", mode, err, services.ErrInvalidAttendanceMode)
+		}
+	}
+}
+
+// TestBuildICalendarLocation はiCalendarのイベントに場所と場所の種類を出力し、未設定の場合は出力しないことを確認するテストです。
+func TestBuildICalendarLocation(t *testing.T) {
+	start := time.Date(2026, 4, 10, 9, 0, 0, 0, time.UTC)
+	calendar := utils.BuildICalendar("class_5", []utils.ICalEvent{
+		{UID: "class-schedule-1@minori", Summary: "第1回", Start: start, End: start.Add(time.Hour), Location: "本館301教室, 3階", LocationType: "IN_PERSON"},
+		{UID: "class-schedule-2@minori", Summary: "第2回", Start: start, End: start.Add(time.Hour)},
+	})
+
+	if !strings.Contains(calendar, "LOCATION:本館301教室\\, 3階\r\n") {
+		t.Errorf("calendar has no escaped LOCATION:\n%s", calendar)
+	}
+	if !strings.Contains(calendar, "X-MINORI-LOCATION-TYPE:IN_PERSON\r\n") {
+		t.Errorf("calendar has no X-MINORI-LOCATION-TYPE:\n%s", calendar)
+	}
+	if n := strings.Count(calendar, "\r\nLOCATION:"); n != 1 {
+		t.Errorf("LOCATION appears %d times, want once for the event with a location", n)
+	}
+}
@@ -0,0 +1,100 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/migration"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+)
+
+// TestClassScheduleQueriesOnDatabase はライブ中のスケジュールと日付ごとのスケジュールの取得が
+// 実際のDBで、存在する列とバインドした時刻を使って絞り込むことを確認するテストです。
+func TestClassScheduleQueriesOnDatabase(t *testing.T) {
+	db := openTestDB(t)
+	migration.Migrate(db)
+	tx := db.Begin()
+	if tx.Error != nil {
+		t.Fatalf("failed to begin transaction: %v", tx.Error)
+	}
+	t.Cleanup(func() { tx.Rollback() })
+	ctx := context.Background()
+
+	user := &models.User{Name: "山田", Image: "https://example.com/u.png", PID: "schedule-query-test"}
+	if err := tx.Create(user).Error; err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	class := &models.Class{Name: "数学", UID: user.ID}
+	other := &models.Class{Name: "英語", UID: user.ID}
+	if err := tx.Create(class).Error; err != nil {
+		t.Fatalf("failed to create class: %v", err)
+	}
+	if err := tx.Create(other).Error; err != nil {
+		t.Fatalf("failed to create class: %v", err)
+	}
+
+	// テスト用のSQLiteは時刻を文字列で比較するため、時刻は全てUTCにする
+	now := time.Now().UTC()
+	day := time.Date(2026, 4, 10, 0, 0, 0, 0, time.UTC)
+	schedules := []models.ClassSchedule{
+		{Title: "Live", CID: class.ID, IsLive: true, StartedAt: now.Add(-time.Hour), EndedAt: now.Add(time.Hour)},
+		{Title: "Live Cancelled", CID: class.ID, IsLive: true, IsCancelled: true, StartedAt: now.Add(-time.Hour), EndedAt: now.Add(time.Hour)},
+		{Title: "Live Ended", CID: class.ID, IsLive: true, StartedAt: now.Add(-2 * time.Hour), EndedAt: now.Add(-time.Hour)},
+		{Title: "Live Other Class", CID: other.ID, IsLive: true, StartedAt: now.Add(-time.Hour), EndedAt: now.Add(time.Hour)},
+		{Title: "Day Start", CID: class.ID, StartedAt: day, EndedAt: day.Add(time.Hour), AttendanceMode: models.OnlineMode},
+		{Title: "Day End", CID: class.ID, StartedAt: day.Add(23*time.Hour + 59*time.Minute), EndedAt: day.Add(24 * time.Hour), AttendanceMode: models.InPersonMode},
+		{Title: "Previous Day", CID: class.ID, StartedAt: day.Add(-time.Minute), EndedAt: day.Add(time.Hour), AttendanceMode: models.OnlineMode},
+		{Title: "Next Day", CID: class.ID, StartedAt: day.AddDate(0, 0, 1), EndedAt: day.AddDate(0, 0, 1).Add(time.Hour), AttendanceMode: models.OnlineMode},
+		{Title: "Day Other Class", CID: other.ID, StartedAt: day.Add(time.Hour), EndedAt: day.Add(2 * time.Hour), AttendanceMode: models.OnlineMode},
+	}
+	if err := tx.Create(&schedules).Error; err != nil {
+		t.Fatalf("failed to create schedules: %v", err)
+	}
+	repo := repositories.NewClassScheduleRepository(tx)
+
+	live, err := repo.FindLiveClassSchedules(ctx, class.ID)
+	if err != nil {
+		t.Fatalf("FindLiveClassSchedules() err = %v", err)
+	}
+	if got := scheduleTitles(live); len(got) != 1 || got[0] != "Live" {
+		t.Errorf("live schedules = %v, want [Live]", got)
+	}
+
+	cases := []struct {
+		name string
+		mode models.AttendanceMode
+		want []string
+	}{
+		{"All Attendance Modes", "", []string{"Day Start", "Day End"}},
+		{"Online Only", models.OnlineMode, []string{"Day Start"}},
+		{"Hybrid Only", models.HybridMode, nil},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			found, err := repo.FindClassSchedulesByDate(ctx, class.ID, day.Add(15*time.Hour), tc.mode)
+			if err != nil {
+				t.Fatalf("FindClassSchedulesByDate() err = %v", err)
+			}
+			got := scheduleTitles(found)
+			if len(got) != len(tc.want) {
+				t.Fatalf("schedules = %v, want %v", got, tc.want)
+			}
+			for i := range got {
+				if got[i] != tc.want[i] {
+					t.Errorf("schedules = %v, want %v", got, tc.want)
+				}
+			}
+		})
+	}
+}
+
+// scheduleTitles スケジュールのタイトルを順に返します。
+func scheduleTitles(schedules []models.ClassSchedule) []string {
+	var titles []string
+	for _, schedule := range schedules {
+		titles = append(titles, schedule.Title)
+	}
+	return titles
+}
@@ -0,0 +1,188 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"gorm.io/gorm"
+)
+
+// memorySemesterRepo は1つの学期と成績の基準をメモリに保持するClassSemesterRepositoryです。
+type memorySemesterRepo struct {
+	repositories.ClassSemesterRepository
+	semester   *models.ClassSemester
+	thresholds []models.ClassGradeThreshold
+	replaced   bool
+}
+
+func (r *memorySemesterRepo) FindSemester(_ context.Context, _ uint, name string) (*models.ClassSemester, error) {
+	if r.semester == nil || r.semester.Name != name {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return r.semester, nil
+}
+
+func (r *memorySemesterRepo) FindGradeScale(context.Context, uint) ([]models.ClassGradeThreshold, error) {
+	return r.thresholds, nil
+}
+
+func (r *memorySemesterRepo) ReplaceGradeScale(_ context.Context, _ uint, thresholds []models.ClassGradeThreshold) error {
+	r.replaced = true
+	r.thresholds = thresholds
+	return nil
+}
+
+// semesterAttendanceRepo は期間を記録し、固定の出席の時系列を返すAttendanceRepositoryです。
+type semesterAttendanceRepo struct {
+	repositories.AttendanceRepository
+	timeline    []repositories.AttendanceTimelineEntry
+	from, until time.Time
+}
+
+func (r *semesterAttendanceRepo) GetAttendanceTimelineBetween(_ context.Context, _, _ uint, from, until time.Time) ([]repositories.AttendanceTimelineEntry, error) {
+	r.from, r.until = from, until
+	return r.timeline, nil
+}
+
+// TestGetSemesterAttendanceGrade は学期の出席率を成績の基準で成績に変換し、遅刻と記録のないスケジュールを出席に数えないことを確認するテストです。
+func TestGetSemesterAttendanceGrade(t *testing.T) {
+	now := time.Now()
+	semester := &models.ClassSemester{CID: 10, Name: "2026-spring", StartsAt: now.AddDate(0, -3, 0), EndsAt: now.AddDate(0, -1, 0)}
+	present, tardy, absent := models.AttendanceStatus, models.TardyStatus, models.AbsenceStatus
+	customScale := []models.ClassGradeThreshold{
+		{Grade: "S", MinRate: 95, GradePoint: 4.5},
+		{Grade: "P", MinRate: 50, GradePoint: 1},
+		{Grade: "F", MinRate: 0, GradePoint: 0},
+	}
+
+	cases := []struct {
+		name       string
+		viewer     uint
+		role       string
+		semester   string
+		thresholds []models.ClassGradeThreshold
+		timeline   []repositories.AttendanceTimelineEntry
+		wantErr    error
+		wantRate   float64
+		wantGrade  string
+		wantPoint  float64
+	}{
+		{"All Present", 1, "", "2026-spring", nil, streakTimeline(now, present, present, present, present), nil, 100, "A", 4},
+		{"Tardy Is Not Attendance", 1, "", "2026-spring", nil, streakTimeline(now, present, present, present, present, tardy), nil, 80, "B", 3},
+		{"Missing Record Is Absence", 1, "", "2026-spring", nil, streakTimeline(now, present, present, "", absent), nil, 50, "F", 0},
+		{"Custom Scale", 1, "", "2026-spring", customScale, streakTimeline(now, present, absent), nil, 50, "P", 1},
+		{"No Schedules", 1, "", "2026-spring", nil, nil, nil, 0, "", 0},
+		{"Teacher Views Student", 2, "ADMIN", "2026-spring", nil, streakTimeline(now, present), nil, 100, "A", 4},
+		{"Other Student", 2, "USER", "2026-spring", nil, streakTimeline(now, present), services.ErrUnauthorized, 0, "", 0},
+		{"Unknown Semester", 1, "", "2025-fall", nil, nil, services.ErrNotFound, 0, "", 0},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			attendanceRepo := &semesterAttendanceRepo{timeline: tc.timeline}
+			service := services.NewClassSemesterService(&memorySemesterRepo{semester: semester, thresholds: tc.thresholds}, attendanceRepo, &streakClassUserRepo{role: tc.role})
+
+			grade, err := service.GetSemesterAttendanceGrade(context.Background(), tc.viewer, 1, 10, tc.semester)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tc.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if !attendanceRepo.from.Equal(semester.StartsAt) || !attendanceRepo.until.Equal(semester.EndsAt) {
+				t.Errorf("period = %v - %v, want %v - %v", attendanceRepo.from, attendanceRepo.until, semester.StartsAt, semester.EndsAt)
+			}
+			if !grade.Final {
+				t.Error("Final = false for a semester that has ended")
+			}
+			if grade.AttendanceRate != tc.wantRate {
+				t.Errorf("AttendanceRate = %v, want %v", grade.AttendanceRate, tc.wantRate)
+			}
+			if tc.wantGrade == "" {
+				if grade.Grade != nil || grade.GradePoint != nil {
+					t.Errorf("grade = %v/%v, want none", grade.Grade, grade.GradePoint)
+				}
+				return
+			}
+			if grade.Grade == nil || *grade.Grade != tc.wantGrade || *grade.GradePoint != tc.wantPoint {
+				t.Errorf("grade = %v/%v, want %s/%v", grade.Grade, grade.GradePoint, tc.wantGrade, tc.wantPoint)
+			}
+		})
+	}
+}
+
+// TestGetSemesterAttendanceGradeInProgress は学期中の場合に現在までを集計し、授業中で記録のないスケジュールを数えないことを確認するテストです。
+func TestGetSemesterAttendanceGradeInProgress(t *testing.T) {
+	now := time.Now()
+	semester := &models.ClassSemester{CID: 10, Name: "current", StartsAt: now.AddDate(0, -1, 0), EndsAt: now.AddDate(0, 2, 0)}
+	inProgress := repositories.AttendanceTimelineEntry{CSID: 99, StartedAt: now.Add(-time.Minute), EndedAt: now.Add(time.Hour)}
+	attendanceRepo := &semesterAttendanceRepo{timeline: append(streakTimeline(now, models.AttendanceStatus), inProgress)}
+	service := services.NewClassSemesterService(&memorySemesterRepo{semester: semester}, attendanceRepo, &streakClassUserRepo{})
+
+	grade, err := service.GetSemesterAttendanceGrade(context.Background(), 1, 1, 10, "current")
+	if err != nil {
+		t.Fatalf("err = %v", err)
+	}
+	if grade.Final {
+		t.Error("Final = true for a semester in progress")
+	}
+	if !attendanceRepo.until.Before(semester.EndsAt) {
+		t.Errorf("until = %v, want the current time", attendanceRepo.until)
+	}
+	if grade.TotalSessions != 1 || grade.AttendanceRate != 100 {
+		t.Errorf("sessions = %d, rate = %v, want 1, 100", grade.TotalSessions, grade.AttendanceRate)
+	}
+}
+
+// TestSetGradeScale は成績の基準の重複と出席率0%の基準の欠落を拒否し、管理者のみ設定できることを確認するテストです。
+func TestSetGradeScale(t *testing.T) {
+	cases := []struct {
+		name       string
+		admin      bool
+		thresholds []dto.GradeThresholdDTO
+		wantErr    error
+	}{
+		{"Valid", true, []dto.GradeThresholdDTO{{Grade: "P", MinRate: 60, GradePoint: 1}, {Grade: "F", MinRate: 0}}, nil},
+		{"Duplicate Grade", true, []dto.GradeThresholdDTO{{Grade: "F", MinRate: 60}, {Grade: "F", MinRate: 0}}, services.ErrInvalidGradeScale},
+		{"Duplicate Rate", true, []dto.GradeThresholdDTO{{Grade: "P", MinRate: 0}, {Grade: "F", MinRate: 0}}, services.ErrInvalidGradeScale},
+		{"Missing Zero", true, []dto.GradeThresholdDTO{{Grade: "A", MinRate: 90}, {Grade: "B", MinRate: 80}}, services.ErrInvalidGradeScale},
+		{"Not Admin", false, []dto.GradeThresholdDTO{{Grade: "F", MinRate: 0}}, services.ErrUnauthorized},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := &memorySemesterRepo{}
+			service := services.NewClassSemesterService(repo, nil, &adminClassUserRepo{admin: tc.admin})
+
+			scale, err := service.SetGradeScale(context.Background(), 1, 10, tc.thresholds)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tc.wantErr)
+			}
+			if repo.replaced != (tc.wantErr == nil) {
+				t.Errorf("replaced = %v, want %v", repo.replaced, tc.wantErr == nil)
+			}
+			if err == nil && (scale.IsDefault || len(scale.Thresholds) != len(tc.thresholds)) {
+				t.Errorf("scale = %+v, want the saved thresholds", scale)
+			}
+		})
+	}
+}
+
+// TestGetGradeScaleDefault はクラスで成績の基準を設定していない場合に既定の基準を返すことを確認するテストです。
+func TestGetGradeScaleDefault(t *testing.T) {
+	service := services.NewClassSemesterService(&memorySemesterRepo{}, nil, nil)
+
+	scale, err := service.GetGradeScale(context.Background(), 10)
+	if err != nil {
+		t.Fatalf("err = %v", err)
+	}
+	if !scale.IsDefault || len(scale.Thresholds) != len(services.DefaultGradeScale) {
+		t.Errorf("scale = %+v, want the default scale", scale)
+	}
+}
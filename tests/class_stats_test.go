@@ -0,0 +1,146 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"gorm.io/gorm"
+)
+
+// statsClassUserRepo はロールまたはロールの取得のエラーを返すClassUserRepositoryです。
+type statsClassUserRepo struct {
+	repositories.ClassUserRepository
+	role string
+	err  error
+}
+
+func (r *statsClassUserRepo) GetRole(context.Context, uint, uint) (string, error) {
+	return r.role, r.err
+}
+
+// statsClassRepo は固定の件数を返し、集計の呼び出し回数を数えるClassRepositoryです。
+type statsClassRepo struct {
+	repositories.ClassRepository
+	boardsErr error
+	calls     atomic.Int64
+}
+
+func (r *statsClassRepo) CountMembersByRole(context.Context, uint) (map[string]int64, error) {
+	r.calls.Add(1)
+	return map[string]int64{"ADMIN": 1, "ASSISTANT": 2, "USER": 20, "APPLICANT": 3, "BLACKLIST": 1}, nil
+}
+
+func (r *statsClassRepo) CountActiveMembers(_ context.Context, _ uint, since time.Time) (int64, error) {
+	if time.Since(since) < 29*24*time.Hour {
+		return 0, fmt.Errorf("since = %v, want about 30 days ago", since)
+	}
+	return 15, nil
+}
+
+func (r *statsClassRepo) CountBoards(context.Context, uint) (int64, error) {
+	return 7, r.boardsErr
+}
+
+func (r *statsClassRepo) CountSchedules(context.Context, uint, time.Time) (int64, int64, error) {
+	return 12, 4, nil
+}
+
+func (r *statsClassRepo) GetAttendanceRate(context.Context, uint) (float64, error) {
+	return 0.85, nil
+}
+
+// statsScheduleRepo はクラスのスケジュールを返すClassScheduleRepositoryです。
+type statsScheduleRepo struct {
+	repositories.ClassScheduleRepository
+	ids []uint
+}
+
+func (r *statsScheduleRepo) GetAllClassSchedules(context.Context, uint) ([]models.ClassSchedule, error) {
+	schedules := make([]models.ClassSchedule, 0, len(r.ids))
+	for _, id := range r.ids {
+		schedules = append(schedules, models.ClassSchedule{ID: id})
+	}
+	return schedules, nil
+}
+
+// TestGetClassStats はクラスの統計を管理者のみ取得でき、参加申請中とブロック済みをメンバー数に含めず、
+// いずれかの集計またはロールの取得が失敗した場合は権限のエラーにせずにそのエラーを返すことを確認するテストです。
+func TestGetClassStats(t *testing.T) {
+	boardsErr := errors.New("count boards failed")
+	roleErr := errors.New("connection refused")
+	cases := []struct {
+		name      string
+		role      string
+		roleErr   error
+		boardsErr error
+		want      *dto.ClassStatsDTO
+		wantErr   error
+	}{
+		{
+			name: "Admin",
+			role: "ADMIN",
+			want: &dto.ClassStatsDTO{
+				TotalMembers: 23, ActiveMembers: 15, AdminCount: 1, AssistantCount: 2, StudentCount: 20,
+				TotalBoards: 7, TotalSchedules: 12, UpcomingScheduleCount: 4, OverallAttendanceRate: 0.85,
+			},
+		},
+		{name: "Assistant", role: "ASSISTANT", wantErr: services.ErrUnauthorized},
+		{name: "Not Member", roleErr: gorm.ErrRecordNotFound, wantErr: services.ErrUnauthorized},
+		{name: "Role Query Failed", roleErr: roleErr, wantErr: roleErr},
+		{name: "Query Failed", role: "ADMIN", boardsErr: boardsErr, wantErr: boardsErr},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			service := services.NewCreateClassService(nil, &statsClassRepo{boardsErr: tc.boardsErr}, &statsClassUserRepo{role: tc.role, err: tc.roleErr}, nil, nil, nil, "", nil, nil)
+
+			stats, err := service.GetClassStats(context.Background(), 1, 1)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tc.wantErr)
+			}
+			if !reflect.DeepEqual(stats, tc.want) {
+				t.Errorf("stats = %+v, want %+v", stats, tc.want)
+			}
+		})
+	}
+}
+
+// TestGetClassStatsCache は発言が残っているチャットを数え、2回目以降はキャッシュした統計を返すことを確認するテストです。
+func TestGetClassStatsCache(t *testing.T) {
+	redisClient := openTestRedis(t)
+	ctx := context.Background()
+	const cid uint = 920001
+	keys := []string{fmt.Sprintf("classstats:%d", cid), "chat:920101", "chat:920102"}
+	redisClient.Del(ctx, keys...)
+	t.Cleanup(func() {
+		redisClient.Del(ctx, keys...)
+	})
+	redisClient.RPush(ctx, "chat:920101", "message")
+
+	repo := &statsClassRepo{}
+	service := services.NewCreateClassService(nil, repo, &flyerClassUserRepo{role: "ADMIN"}, nil, nil, &statsScheduleRepo{ids: []uint{920101, 920102}}, "", redisClient, nil)
+	for i := 0; i < 2; i++ {
+		stats, err := service.GetClassStats(ctx, cid, 1)
+		if err != nil {
+			t.Fatalf("err = %v", err)
+		}
+		if stats.ActiveChatRooms != 1 {
+			t.Errorf("active chat rooms = %d, want 1", stats.ActiveChatRooms)
+		}
+	}
+	if calls := repo.calls.Load(); calls != 1 {
+		t.Errorf("member counts were queried %d times, want 1", calls)
+	}
+	if ttl := redisClient.TTL(ctx, keys[0]).Val(); ttl <= 0 || ttl > 2*time.Minute {
+		t.Errorf("cache ttl = %v, want at most 2 minutes", ttl)
+	}
+}
@@ -0,0 +1,192 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/controllers"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/middlewares"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/migration"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"github.com/gin-gonic/gin"
+)
+
+// timelineClassRepo はタイムラインの取得条件を記録するClassRepositoryです。
+type timelineClassRepo struct {
+	repositories.ClassRepository
+	types  []dto.ClassTimelineEventType
+	limit  int
+	offset int
+}
+
+func (r *timelineClassRepo) FindTimelineEvents(_ context.Context, _ uint, types []dto.ClassTimelineEventType, limit int, offset int) ([]dto.ClassTimelineEventDTO, error) {
+	r.types, r.limit, r.offset = types, limit, offset
+	return []dto.ClassTimelineEventDTO{{Type: dto.TimelineBoardPosted, ResourceID: 1, Title: "お知らせ"}}, nil
+}
+
+// TestGetClassTimeline はクラスのメンバーのみタイムラインを取得でき、ページを取得位置に変換することを確認するテストです。
+func TestGetClassTimeline(t *testing.T) {
+	cases := []struct {
+		name    string
+		role    string
+		wantErr error
+	}{
+		{name: "Admin", role: "ADMIN"},
+		{name: "Assistant", role: "ASSISTANT"},
+		{name: "Student", role: "USER"},
+		{name: "Applicant", role: "APPLICANT", wantErr: services.ErrUnauthorized},
+		{name: "Blacklist", role: "BLACKLIST", wantErr: services.ErrUnauthorized},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := &timelineClassRepo{}
+			service := services.NewCreateClassService(nil, repo, &flyerClassUserRepo{role: tc.role}, nil, nil, nil, "", nil, nil)
+
+			types := []dto.ClassTimelineEventType{dto.TimelineMemberJoined}
+			events, err := service.GetClassTimeline(context.Background(), 1, 1, types, 3, 10)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tc.wantErr)
+			}
+			if tc.wantErr != nil {
+				if events != nil {
+					t.Errorf("events = %+v, want nil", events)
+				}
+				return
+			}
+			if len(events) != 1 {
+				t.Errorf("events = %+v, want 1 event", events)
+			}
+			if !reflect.DeepEqual(repo.types, types) || repo.limit != 10 || repo.offset != 20 {
+				t.Errorf("got types=%v limit=%d offset=%d, want types=%v limit=10 offset=20", repo.types, repo.limit, repo.offset, types)
+			}
+		})
+	}
+}
+
+// timelineClassService はタイムラインの取得条件を記録するClassServiceです。
+type timelineClassService struct {
+	services.ClassService
+	types []dto.ClassTimelineEventType
+	page  int
+	limit int
+}
+
+func (s *timelineClassService) GetClassTimeline(_ context.Context, _ uint, _ uint, types []dto.ClassTimelineEventType, page int, limit int) ([]dto.ClassTimelineEventDTO, error) {
+	s.types, s.page, s.limit = types, page, limit
+	return []dto.ClassTimelineEventDTO{}, nil
+}
+
+// TestGetClassTimelineFilter は出来事の種類の絞り込みを複数の指定とカンマ区切りのどちらでも受け付け、
+// 定義されていない種類を400で拒否することを確認するテストです。
+func TestGetClassTimelineFilter(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+
+	cases := []struct {
+		name       string
+		query      string
+		wantStatus int
+		wantTypes  []dto.ClassTimelineEventType
+		wantPage   int
+		wantLimit  int
+	}{
+		{"All Types", "", http.StatusOK, nil, 1, 20},
+		{"Repeated", "?type=board_posted&type=ROLE_CHANGED&page=2&limit=50", http.StatusOK,
+			[]dto.ClassTimelineEventType{dto.TimelineBoardPosted, dto.TimelineRoleChanged}, 2, 50},
+		{"Comma Separated", "?type=schedule_added,+member_joined", http.StatusOK,
+			[]dto.ClassTimelineEventType{dto.TimelineScheduleAdded, dto.TimelineMemberJoined}, 1, 20},
+		{"Unknown Type", "?type=board_posted,attendance", http.StatusBadRequest, nil, 0, 0},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			service := &timelineClassService{}
+			r := gin.New()
+			r.Use(middlewares.GlobalErrorHandler(&recordingErrorReporter{}))
+			r.GET("/api/gin/cl/:cid/timeline", controllers.NewCreateClassController(service, nil, nil).GetClassTimeline)
+
+			req, _ := http.NewRequest(http.MethodGet, "/api/gin/cl/1/timeline"+tc.query, nil)
+			resp := httptest.NewRecorder()
+			r.ServeHTTP(resp, req)
+
+			if resp.Code != tc.wantStatus {
+				t.Fatalf("status = %d, want %d", resp.Code, tc.wantStatus)
+			}
+			if !reflect.DeepEqual(service.types, tc.wantTypes) || service.page != tc.wantPage || service.limit != tc.wantLimit {
+				t.Errorf("got types=%v page=%d limit=%d, want types=%v page=%d limit=%d",
+					service.types, service.page, service.limit, tc.wantTypes, tc.wantPage, tc.wantLimit)
+			}
+		})
+	}
+}
+
+// TestFindTimelineRoleChangedOnDatabase はロールの変更に成功した監査ログをタイムラインに含め、
+// 失敗した操作とクラスから削除されたメンバーのロールの変更は含めないことを確認するテストです。
+func TestFindTimelineRoleChangedOnDatabase(t *testing.T) {
+	db := openTestDB(t)
+	migration.Migrate(db)
+	tx := db.Begin()
+	if tx.Error != nil {
+		t.Fatalf("failed to begin transaction: %v", tx.Error)
+	}
+	t.Cleanup(func() { tx.Rollback() })
+	ctx := context.Background()
+
+	users := make([]*models.User, 3)
+	for i := range users {
+		users[i] = &models.User{Name: "山田", Image: "https://example.com/u.png", PID: "timeline-role-test"}
+		if err := tx.Create(users[i]).Error; err != nil {
+			t.Fatalf("failed to create user: %v", err)
+		}
+	}
+	admin, member, removed := users[0], users[1], users[2]
+	class := &models.Class{Name: "数学", UID: admin.ID}
+	if err := tx.Create(class).Error; err != nil {
+		t.Fatalf("failed to create class: %v", err)
+	}
+	for _, classUser := range []*models.ClassUser{
+		{CID: class.ID, UID: member.ID, Nickname: "佐藤", Role: "ASSISTANT"},
+		{CID: class.ID, UID: removed.ID, Nickname: "鈴木", Role: "USER"},
+	} {
+		if err := tx.Create(classUser).Error; err != nil {
+			t.Fatalf("failed to create class user: %v", err)
+		}
+	}
+	if err := tx.Delete(&models.ClassUser{}, "cid = ? AND uid = ?", class.ID, removed.ID).Error; err != nil {
+		t.Fatalf("failed to remove class user: %v", err)
+	}
+	now := time.Now()
+	for i, log := range []struct {
+		UID        uint
+		StatusCode int
+	}{
+		{member.ID, 200},
+		{member.ID, 403},
+		{removed.ID, 200},
+	} {
+		entry := models.AuditLog{
+			ActorUID: admin.ID, Method: "PATCH", Route: "/api/gin/cu/:cid/members/:uid/role/:roleName",
+			ResourceID: fmt.Sprint(log.UID), CID: &class.ID, StatusCode: log.StatusCode, CreatedAt: now.Add(time.Duration(i) * time.Minute),
+		}
+		if err := tx.Create(&entry).Error; err != nil {
+			t.Fatalf("failed to create audit log: %v", err)
+		}
+	}
+
+	events, err := repositories.NewClassRepository(tx).FindTimelineEvents(ctx, class.ID, []dto.ClassTimelineEventType{dto.TimelineRoleChanged}, 10, 0)
+	if err != nil {
+		t.Fatalf("err = %v", err)
+	}
+	if len(events) != 1 || events[0].ResourceID != member.ID || events[0].Title != "佐藤" || events[0].ActorUID == nil || *events[0].ActorUID != admin.ID {
+		t.Errorf("events = %+v, want one role change of member %d by %d", events, member.ID, admin.ID)
+	}
+}
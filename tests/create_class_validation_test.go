@@ -0,0 +1,92 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/controllers"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/middlewares"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// createClassService はクラスの作成が呼ばれたかを記録するClassServiceです。
+type createClassService struct {
+	services.ClassService
+	called bool
+}
+
+func (s *createClassService) CreateClass(context.Context, dto.CreateClassRequest) (uint, error) {
+	s.called = true
+	return 1, nil
+}
+
+// TestCreateClassValidation は必須項目と文字数を検証し、DBに書き込む前に項目ごとのエラーメッセージを400で返すことを確認するテストです。
+func TestCreateClassValidation(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+
+	cases := []struct {
+		name        string
+		fields      map[string]string
+		wantStatus  int
+		wantDetails []string
+	}{
+		{"Valid", map[string]string{"name": "数学", "uid": "1", "description": "説明"}, http.StatusCreated, nil},
+		{"Missing Name", map[string]string{"uid": "1"}, http.StatusBadRequest, []string{"name"}},
+		{"Name Too Long", map[string]string{"name": strings.Repeat("あ", 31), "uid": "1"}, http.StatusBadRequest, []string{"name"}},
+		{"Description Too Long", map[string]string{"name": "数学", "uid": "1", "description": strings.Repeat("a", 256)}, http.StatusBadRequest, []string{"description"}},
+		{"Every Problem At Once", map[string]string{"description": strings.Repeat("a", 256)}, http.StatusBadRequest, []string{"name", "uid", "description"}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			service := &createClassService{}
+			r := gin.New()
+			r.Use(middlewares.GlobalErrorHandler(&recordingErrorReporter{}))
+			r.POST("/api/gin/cl/create", controllers.NewCreateClassController(service, nil, &recordingUploader{}).CreateClass)
+
+			body := &bytes.Buffer{}
+			writer := multipart.NewWriter(body)
+			for key, value := range tc.fields {
+				_ = writer.WriteField(key, value)
+			}
+			_ = writer.Close()
+
+			req, _ := http.NewRequest(http.MethodPost, "/api/gin/cl/create", body)
+			req.Header.Set("Content-Type", writer.FormDataContentType())
+			resp := httptest.NewRecorder()
+			r.ServeHTTP(resp, req)
+
+			if resp.Code != tc.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", resp.Code, tc.wantStatus, resp.Body.String())
+			}
+			if service.called != (tc.wantStatus == http.StatusCreated) {
+				t.Errorf("service called = %v, want %v", service.called, tc.wantStatus == http.StatusCreated)
+			}
+			if tc.wantDetails == nil {
+				return
+			}
+
+			var errResp utils.ErrorResponse
+			if err := json.Unmarshal(resp.Body.Bytes(), &errResp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if len(errResp.Details) != len(tc.wantDetails) {
+				t.Errorf("details = %v, want fields %v", errResp.Details, tc.wantDetails)
+			}
+			for _, field := range tc.wantDetails {
+				if message, ok := errResp.Details[field].(string); !ok || message == "" {
+					t.Errorf("details[%q] = %v, want message", field, errResp.Details[field])
+				}
+			}
+		})
+	}
+}
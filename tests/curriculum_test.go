@@ -0,0 +1,158 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"gorm.io/gorm"
+)
+
+// curriculumRepo はクラス1の項目1・2と、クラス2の項目3を持つCurriculumRepositoryです。
+// 生徒11は項目1・2、生徒12は項目1、退会した生徒99は項目2を完了しています。
+type curriculumRepo struct {
+	repositories.CurriculumRepository
+	saved int
+}
+
+var curriculumItems = []models.CurriculumItem{
+	{ID: 1, CID: 1, Title: "第1章", Order: 1},
+	{ID: 2, CID: 1, Title: "第2章", Order: 2},
+	{ID: 3, CID: 2, Title: "別のクラス", Order: 1},
+}
+
+var curriculumProgress = []models.UserProgress{
+	{UID: 11, ItemID: 1}, {UID: 11, ItemID: 2}, {UID: 12, ItemID: 1}, {UID: 99, ItemID: 2},
+}
+
+func (r *curriculumRepo) FindItemsByClass(_ context.Context, cid uint) ([]models.CurriculumItem, error) {
+	var items []models.CurriculumItem
+	for _, item := range curriculumItems {
+		if item.CID == cid {
+			items = append(items, item)
+		}
+	}
+	return items, nil
+}
+
+func (r *curriculumRepo) FindItemByID(_ context.Context, id uint) (*models.CurriculumItem, error) {
+	for _, item := range curriculumItems {
+		if item.ID == id {
+			return &item, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *curriculumRepo) SaveProgress(context.Context, *models.UserProgress) error {
+	r.saved++
+	return nil
+}
+
+func (r *curriculumRepo) FindProgressByUser(_ context.Context, _ uint, uid uint) ([]models.UserProgress, error) {
+	var progress []models.UserProgress
+	for _, p := range curriculumProgress {
+		if p.UID == uid {
+			progress = append(progress, p)
+		}
+	}
+	return progress, nil
+}
+
+func (r *curriculumRepo) FindProgressByClass(context.Context, uint) ([]models.UserProgress, error) {
+	return curriculumProgress, nil
+}
+
+// TestCompleteCurriculumItem は生徒のみ項目を完了でき、別のクラスの項目はErrNotFoundにすることを確認するテストです。
+func TestCompleteCurriculumItem(t *testing.T) {
+	cases := []struct {
+		name    string
+		role    string
+		itemID  uint
+		wantErr error
+	}{
+		{"Student", "USER", 1, nil},
+		{"Other Class Item", "USER", 3, services.ErrNotFound},
+		{"Missing Item", "USER", 4, services.ErrNotFound},
+		{"Assistant", "ASSISTANT", 1, services.ErrUnauthorized},
+		{"Applicant", "APPLICANT", 1, services.ErrUnauthorized},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := &curriculumRepo{}
+			service := services.NewCurriculumService(repo, &materialClassUserRepo{role: tc.role})
+
+			err := service.CompleteItem(context.Background(), 11, 1, tc.itemID)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tc.wantErr)
+			}
+			if saved := repo.saved == 1; saved != (tc.wantErr == nil) {
+				t.Errorf("saved = %v, want %v", saved, tc.wantErr == nil)
+			}
+		})
+	}
+}
+
+// TestGetUserCurriculumProgress は生徒本人と講師は進捗を取得でき、他の生徒の進捗は取得できないことを確認するテストです。
+func TestGetUserCurriculumProgress(t *testing.T) {
+	cases := []struct {
+		name    string
+		role    string
+		uid     uint
+		want    int
+		wantErr error
+	}{
+		{"Own Progress", "USER", 11, 2, nil},
+		{"Teacher", "ADMIN", 12, 1, nil},
+		{"Other Student", "USER", 12, 0, services.ErrUnauthorized},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			service := services.NewCurriculumService(&curriculumRepo{}, &materialClassUserRepo{role: tc.role})
+
+			progress, err := service.GetUserProgress(context.Background(), 11, 1, tc.uid)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tc.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if progress.CompletedCount != tc.want || progress.TotalCount != 2 || progress.ProgressRate != float64(tc.want)/2 {
+				t.Errorf("progress = %+v, want %d of 2", progress, tc.want)
+			}
+		})
+	}
+}
+
+// TestGetClassCurriculumProgress はクラスの生徒ごとの進捗率と項目ごとの完了率を返し、退会した生徒の記録を含めないことを確認するテストです。
+func TestGetClassCurriculumProgress(t *testing.T) {
+	// materialClassUserRepoはクラスの生徒として11から14の4人を返す
+	service := services.NewCurriculumService(&curriculumRepo{}, &materialClassUserRepo{role: "ADMIN"})
+
+	progress, err := service.GetClassProgress(context.Background(), 1, 1)
+	if err != nil {
+		t.Fatalf("err = %v", err)
+	}
+	if progress.TotalCount != 2 || progress.StudentCount != 4 || progress.AverageProgressRate != 0.375 {
+		t.Errorf("progress = %+v, want 2 items, 4 students and average 0.375", progress)
+	}
+	wantItems := map[uint]int{1: 2, 2: 1}
+	for _, item := range progress.Items {
+		if item.CompletedCount != wantItems[item.ItemID] {
+			t.Errorf("item %d completed by %d, want %d", item.ItemID, item.CompletedCount, wantItems[item.ItemID])
+		}
+	}
+	if progress.Students[0].UID != 11 || progress.Students[0].ProgressRate != 1 {
+		t.Errorf("students[0] = %+v, want uid 11 with all items completed", progress.Students[0])
+	}
+
+	student := services.NewCurriculumService(&curriculumRepo{}, &materialClassUserRepo{role: "USER"})
+	if _, err := student.GetClassProgress(context.Background(), 11, 1); !errors.Is(err, services.ErrUnauthorized) {
+		t.Errorf("student err = %v, want %v", err, services.ErrUnauthorized)
+	}
+}
@@ -0,0 +1,315 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"gorm.io/gorm"
+)
+
+// digestRepo はダイジェストの受信者とクラスの活動を返し、集計した期間を記録するDigestRepositoryです。
+type digestRepo struct {
+	repositories.DigestRepository
+	settings    []models.DigestSetting
+	memberships []repositories.DigestMembership
+	boards      map[uint]int64
+	changes     map[uint]int64
+	schedules   []models.ClassSchedule
+	// countRange 掲示とスケジュールの変更を集計した期間
+	countRange [2]time.Time
+	// startRange 授業を検索した期間
+	startRange [2]time.Time
+	// membershipQueries クラスの検索の回数。受信者ごとではなくまとめて検索することを確認する
+	membershipQueries int
+}
+
+func (r *digestRepo) FindDigestSetting(_ context.Context, uid uint) (*models.DigestSetting, error) {
+	for _, setting := range r.settings {
+		if setting.UID == uid {
+			return &setting, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *digestRepo) FindDigestRecipients(_ context.Context, afterUID uint, limit int) ([]models.DigestSetting, error) {
+	var recipients []models.DigestSetting
+	for _, setting := range r.settings {
+		if setting.Enabled && setting.UID > afterUID && len(recipients) < limit {
+			recipients = append(recipients, setting)
+		}
+	}
+	return recipients, nil
+}
+
+func (r *digestRepo) FindDigestMemberships(_ context.Context, uids []uint) ([]repositories.DigestMembership, error) {
+	r.membershipQueries++
+	var memberships []repositories.DigestMembership
+	for _, membership := range r.memberships {
+		for _, uid := range uids {
+			if membership.UID == uid {
+				memberships = append(memberships, membership)
+			}
+		}
+	}
+	return memberships, nil
+}
+
+func (r *digestRepo) CountBoardsCreatedBetween(_ context.Context, _ []uint, from, to time.Time) (map[uint]int64, error) {
+	r.countRange = [2]time.Time{from, to}
+	return r.boards, nil
+}
+
+func (r *digestRepo) CountSchedulesChangedBetween(_ context.Context, _ []uint, _, _ time.Time) (map[uint]int64, error) {
+	return r.changes, nil
+}
+
+func (r *digestRepo) FindSchedulesStartingBetween(_ context.Context, _ []uint, from, to time.Time) ([]models.ClassSchedule, error) {
+	r.startRange = [2]time.Time{from, to}
+	return r.schedules, nil
+}
+
+// recordingNotificationService は追加された通知を記録するNotificationServiceです。errを設定した場合は通知を追加しません。
+type recordingNotificationService struct {
+	services.NotificationService
+	published []models.Notification
+	err       error
+}
+
+func (s *recordingNotificationService) Publish(_ context.Context, notification models.Notification) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.published = append(s.published, notification)
+	return nil
+}
+
+// newDigestRepo は生徒(ID 2)が数学(ID 1)と英語(ID 3)に、生徒(ID 4)が英語のみに参加し、
+// 前日に数学の掲示が2件、スケジュールの変更が1件あり、今日の9時に数学の授業があるDigestRepositoryを生成します。
+func newDigestRepo(now time.Time) *digestRepo {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	return &digestRepo{
+		settings: []models.DigestSetting{
+			{UID: 2, Enabled: true, EmailEnabled: true, User: models.User{ID: 2, Name: "山田", Email: "yamada@example.com", EmailVerified: true, Locale: "ja"}},
+			{UID: 4, Enabled: true, User: models.User{ID: 4, Name: "鈴木"}},
+		},
+		memberships: []repositories.DigestMembership{
+			{UID: 2, CID: 1, ClassName: "数学"},
+			{UID: 2, CID: 3, ClassName: "英語"},
+			{UID: 4, CID: 3, ClassName: "英語"},
+		},
+		boards:  map[uint]int64{1: 2},
+		changes: map[uint]int64{1: 1},
+		schedules: []models.ClassSchedule{
+			{ID: 7, CID: 1, Title: "第3回", StartedAt: today.Add(9 * time.Hour), EndedAt: today.Add(10 * time.Hour)},
+		},
+	}
+}
+
+// TestDigestDryRun は前日の掲示とスケジュールの変更、今日の授業をクラスごとに集計し、活動のないクラスを載せず、
+// 通知とメールを送信しないことを確認するテストです。
+func TestDigestDryRun(t *testing.T) {
+	now := time.Date(2024, 6, 11, 8, 0, 0, 0, time.Local)
+	repo := newDigestRepo(now)
+	notifications := &recordingNotificationService{}
+	mail := &recordingMailService{}
+	service := services.NewDigestService(repo, notifications, mail, nil, services.DigestConfig{Hour: 7, Email: true})
+
+	result, err := service.DryRun(context.Background(), 2, now)
+	if err != nil {
+		t.Fatalf("err = %v", err)
+	}
+	digest := result.Digest
+	if !result.Enabled || !result.Email || result.Empty || result.AlreadySent {
+		t.Fatalf("result = %+v", result)
+	}
+	if digest.Date != "2024-06-10" || digest.Title != "6/10のクラスのまとめ" {
+		t.Fatalf("date = %q, title = %q", digest.Date, digest.Title)
+	}
+	if len(digest.Classes) != 1 || digest.Classes[0].CID != 1 || digest.Classes[0].NewBoards != 2 || digest.Classes[0].ScheduleChanges != 1 || len(digest.Classes[0].TodaySchedules) != 1 {
+		t.Fatalf("classes = %+v", digest.Classes)
+	}
+	if want := "数学: 新しい掲示 2件、スケジュールの変更 1件、今日の授業 09:00「第3回」"; digest.Body != want {
+		t.Errorf("body = %q, want %q", digest.Body, want)
+	}
+
+	yesterday, today := time.Date(2024, 6, 10, 0, 0, 0, 0, time.Local), time.Date(2024, 6, 11, 0, 0, 0, 0, time.Local)
+	if !repo.countRange[0].Equal(yesterday) || !repo.countRange[1].Equal(today) {
+		t.Errorf("count range = %v, want %v - %v", repo.countRange, yesterday, today)
+	}
+	if !repo.startRange[0].Equal(today) || !repo.startRange[1].Equal(today.AddDate(0, 0, 1)) {
+		t.Errorf("start range = %v, want the whole of %v", repo.startRange, today)
+	}
+	if len(notifications.published) != 0 || len(mail.requests) != 0 {
+		t.Errorf("published = %+v, mail = %+v, want nothing sent", notifications.published, mail.requests)
+	}
+
+	// 設定を保存していないユーザーも集計し、無効として返す
+	result, err = service.DryRun(context.Background(), 5, now)
+	if err != nil {
+		t.Fatalf("err = %v", err)
+	}
+	if result.Enabled || result.Email || !result.Empty || len(result.Digest.Classes) != 0 {
+		t.Errorf("result = %+v, want an empty disabled digest", result)
+	}
+}
+
+// TestDigestEmailRequiresServerSetting はユーザーがメールでの受け取りを設定していても、サーバーでメールのダイジェストを有効にしていない場合と、
+// メールアドレスを検証していない場合はメールを送信しないことを確認するテストです。
+func TestDigestEmailRequiresServerSetting(t *testing.T) {
+	now := time.Date(2024, 6, 11, 8, 0, 0, 0, time.Local)
+	cases := []struct {
+		name        string
+		serverEmail bool
+		verified    bool
+		wantEmail   bool
+	}{
+		{name: "Enabled", serverEmail: true, verified: true, wantEmail: true},
+		{name: "Server Disabled", serverEmail: false, verified: true},
+		{name: "Unverified Email", serverEmail: true, verified: false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := newDigestRepo(now)
+			repo.settings[0].User.EmailVerified = tc.verified
+			service := services.NewDigestService(repo, &recordingNotificationService{}, &recordingMailService{}, nil, services.DigestConfig{Hour: 7, Email: tc.serverEmail})
+
+			result, err := service.DryRun(context.Background(), 2, now)
+			if err != nil {
+				t.Fatalf("err = %v", err)
+			}
+			if result.Email != tc.wantEmail {
+				t.Errorf("email = %v, want %v", result.Email, tc.wantEmail)
+			}
+		})
+	}
+}
+
+// TestRunDailyDigest は設定した時刻から1日に1回だけダイジェストを送信し、途中で停止して送信の完了を記録しなかった場合も
+// 送信済みのユーザーに再送せず、通知を保存できなかったユーザーには次の実行で送信することを確認するテストです。
+func TestRunDailyDigest(t *testing.T) {
+	redisClient := openTestRedis(t)
+	ctx := context.Background()
+	now := time.Date(2001, 2, 3, 7, 30, 0, 0, time.Local)
+	keys := []string{"digest:lock", "digest:sent:2001-02-03", "digest:done:2001-02-03"}
+	redisClient.Del(ctx, keys...)
+	t.Cleanup(func() {
+		redisClient.Del(ctx, keys...)
+	})
+
+	repo := newDigestRepo(now)
+	notifications := &recordingNotificationService{err: errors.New("database is down")}
+	mail := &recordingMailService{}
+	service := services.NewDigestService(repo, notifications, mail, redisClient, services.DigestConfig{Hour: 7, Email: true})
+
+	run := func(at time.Time, want int) {
+		t.Helper()
+		sent, err := service.RunDailyDigest(ctx, at)
+		if err != nil {
+			t.Fatalf("err = %v", err)
+		}
+		if sent != want {
+			t.Fatalf("sent = %d, want %d", sent, want)
+		}
+	}
+
+	run(now.Add(-time.Hour), 0)
+	run(now, 0)
+	notifications.err = nil
+	run(now, 1)
+	run(now.Add(time.Hour), 0)
+	// 完了を記録する前に停止した場合
+	redisClient.Del(ctx, "digest:done:2001-02-03")
+	run(now.Add(2*time.Hour), 0)
+
+	if len(notifications.published) != 1 {
+		t.Fatalf("published = %+v, want 1 notification", notifications.published)
+	}
+	published := notifications.published[0]
+	if published.UID != 2 || published.Type != models.DigestNotification || !strings.HasPrefix(published.Body, "数学: ") {
+		t.Errorf("notification = %+v", published)
+	}
+	if len(mail.requests) != 1 || mail.requests[0].To != "yamada@example.com" || mail.requests[0].Template != services.DailyDigestMail {
+		t.Errorf("mail = %+v", mail.requests)
+	}
+	// 受信者はまとめて集計するため、実行ごとにクラスの検索は1回で済む
+	if repo.membershipQueries != 3 {
+		t.Errorf("membership queries = %d, want 3", repo.membershipQueries)
+	}
+}
+
+// TestRunDailyDigestLocked は他のインスタンスがロックを持っている間はダイジェストを送信しないことを確認するテストです。
+func TestRunDailyDigestLocked(t *testing.T) {
+	redisClient := openTestRedis(t)
+	ctx := context.Background()
+	now := time.Date(2001, 2, 4, 7, 30, 0, 0, time.Local)
+	keys := []string{"digest:lock", "digest:sent:2001-02-04", "digest:done:2001-02-04"}
+	redisClient.Del(ctx, keys...)
+	t.Cleanup(func() {
+		redisClient.Del(ctx, keys...)
+	})
+	redisClient.Set(ctx, "digest:lock", "other-instance", time.Minute)
+
+	notifications := &recordingNotificationService{}
+	service := services.NewDigestService(newDigestRepo(now), notifications, nil, redisClient, services.DigestConfig{Hour: 7})
+	sent, err := service.RunDailyDigest(ctx, now)
+	if err != nil || sent != 0 || len(notifications.published) != 0 {
+		t.Fatalf("sent = %d, err = %v, published = %+v", sent, err, notifications.published)
+	}
+	if owner := redisClient.Get(ctx, "digest:lock").Val(); owner != "other-instance" {
+		t.Errorf("lock = %q, want the other instance's lock to remain", owner)
+	}
+}
+
+// TestDigestMail はダイジェストのメールにクラスごとの活動と今日の授業を載せることを確認するテストです。
+func TestDigestMail(t *testing.T) {
+	now := time.Date(2024, 6, 11, 8, 0, 0, 0, time.Local)
+	result, err := services.NewDigestService(newDigestRepo(now), nil, nil, nil, services.DigestConfig{}).DryRun(context.Background(), 2, now)
+	if err != nil {
+		t.Fatalf("err = %v", err)
+	}
+
+	for _, tc := range []struct {
+		locale string
+		want   []string
+	}{
+		{"ja", []string{"2024-06-10のクラスのまとめ", "■ 数学", "新しい掲示: 2件", "今日の授業: 09:00 第3回"}},
+		{"en", []string{"Your class summary for 2024-06-10", "■ 数学", "New posts: 2", "Today: 09:00 第3回"}},
+	} {
+		t.Run(tc.locale, func(t *testing.T) {
+			mailer := newCapturingMailer(0, nil)
+			err := services.NewMailService(services.MailConfig{Mailer: mailer}).Send(context.Background(), services.MailRequest{
+				To:       "yamada@example.com",
+				Locale:   tc.locale,
+				Template: services.DailyDigestMail,
+				Data:     services.MailData{Name: "山田", Date: result.Digest.Date, Classes: result.Digest.Classes},
+			})
+			if err != nil {
+				t.Fatalf("err = %v", err)
+			}
+			mail, ok := mailer.receive(time.Second)
+			if !ok {
+				t.Fatal("mail was not sent")
+			}
+			text := mail.Subject + "\n" + mail.Text
+			for _, want := range tc.want {
+				if !strings.Contains(text, want) {
+					t.Errorf("mail = %q, want %q", text, want)
+				}
+			}
+			if strings.Contains(mail.Text, "英語") {
+				t.Errorf("text = %q, want classes without activity to be omitted", mail.Text)
+			}
+			if !strings.Contains(mail.HTML, "<h3>数学</h3>") {
+				t.Errorf("html = %q", mail.HTML)
+			}
+		})
+	}
+}
@@ -0,0 +1,268 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/migration"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+)
+
+// verificationUserRepo は1人のユーザーを持ち、検証したアドレスを記録するUserRepositoryです。
+// takenのアドレスは他のユーザーが検証済みとして扱い、errがある場合は検証の保存に失敗します。
+type verificationUserRepo struct {
+	repositories.UserRepository
+	user  models.User
+	taken string
+	err   error
+}
+
+func (r *verificationUserRepo) FindByID(context.Context, uint) (*models.User, error) {
+	user := r.user
+	return &user, nil
+}
+
+func (r *verificationUserRepo) IsEmailVerifiedByOther(_ context.Context, _ uint, email string) (bool, error) {
+	return r.taken != "" && strings.EqualFold(email, r.taken), nil
+}
+
+func (r *verificationUserRepo) MarkEmailVerified(ctx context.Context, uid uint, email string) (bool, error) {
+	if r.err != nil {
+		return false, r.err
+	}
+	if taken, _ := r.IsEmailVerifiedByOther(ctx, uid, email); taken {
+		return false, nil
+	}
+	r.user.Email, r.user.EmailVerified = email, true
+	return true, nil
+}
+
+// TestSendVerificationUnavailable はRedisがない場合は検証メールを送信しないことを確認するテストです。
+func TestSendVerificationUnavailable(t *testing.T) {
+	repo := &verificationUserRepo{user: models.User{ID: 2, Email: "yamada@example.com"}}
+	mail := &recordingMailService{}
+	service := services.NewEmailVerificationService(repo, mail, nil, "")
+
+	if err := service.SendVerification(context.Background(), 2, dto.EmailVerificationRequestDTO{}); !errors.Is(err, services.ErrEmailVerificationUnavailable) {
+		t.Fatalf("err = %v, want %v", err, services.ErrEmailVerificationUnavailable)
+	}
+	if _, err := service.VerifyEmail(context.Background(), "token"); !errors.Is(err, services.ErrEmailVerificationUnavailable) {
+		t.Fatalf("err = %v, want %v", err, services.ErrEmailVerificationUnavailable)
+	}
+	if len(mail.requests) != 0 {
+		t.Errorf("requests = %+v, want none", mail.requests)
+	}
+}
+
+// TestEmailVerification は検証メールのリンクのトークンで指定したアドレスを検証済みにし、トークンを1回のみ使え、
+// 再送した場合は以前のトークンを無効にし、再送の間隔と1日の送信数を制限することを確認するテストです。
+func TestEmailVerification(t *testing.T) {
+	redisClient := openTestRedis(t)
+	ctx := context.Background()
+	const uid uint = 930001
+	keys := []string{
+		fmt.Sprintf("email_verification_user:%d", uid),
+		fmt.Sprintf("email_verification_cooldown:%d", uid),
+		fmt.Sprintf("email_verification_count:%d", uid),
+	}
+	redisClient.Del(ctx, keys...)
+	t.Cleanup(func() {
+		redisClient.Del(ctx, keys...)
+	})
+
+	repo := &verificationUserRepo{user: models.User{ID: uid, Name: "山田", Email: "old@example.com", Locale: "ja"}}
+	mail := &recordingMailService{}
+	service := services.NewEmailVerificationService(repo, mail, redisClient, "https://app.example.com/verify-email")
+
+	if err := service.SendVerification(ctx, uid, dto.EmailVerificationRequestDTO{Email: "new@example.com"}); err != nil {
+		t.Fatalf("err = %v", err)
+	}
+	if err := service.SendVerification(ctx, uid, dto.EmailVerificationRequestDTO{Email: "new@example.com"}); !errors.Is(err, services.ErrEmailVerificationLimit) {
+		t.Fatalf("resend err = %v, want %v", err, services.ErrEmailVerificationLimit)
+	}
+	redisClient.Del(ctx, keys[1])
+	if err := service.SendVerification(ctx, uid, dto.EmailVerificationRequestDTO{Email: "new@example.com"}); err != nil {
+		t.Fatalf("resend after cooldown err = %v", err)
+	}
+	if len(mail.requests) != 2 {
+		t.Fatalf("requests = %+v, want 2", mail.requests)
+	}
+	request := mail.requests[1]
+	if request.To != "new@example.com" || request.Template != services.EmailVerificationMail {
+		t.Fatalf("request = %+v", request)
+	}
+	link, err := url.Parse(request.Data.VerifyURL)
+	if err != nil || link.Host != "app.example.com" || link.Query().Get("token") != request.Data.VerifyToken {
+		t.Fatalf("verify url = %q, token = %q", request.Data.VerifyURL, request.Data.VerifyToken)
+	}
+	if repo.user.Email != "old@example.com" || repo.user.EmailVerified {
+		t.Fatalf("user = %+v, want the address unchanged until verified", repo.user)
+	}
+
+	if _, err := service.VerifyEmail(ctx, mail.requests[0].Data.VerifyToken); !errors.Is(err, services.ErrInvalidEmailToken) {
+		t.Errorf("previous token err = %v, want %v", err, services.ErrInvalidEmailToken)
+	}
+	status, err := service.VerifyEmail(ctx, request.Data.VerifyToken)
+	if err != nil {
+		t.Fatalf("verify err = %v", err)
+	}
+	if status != (dto.EmailStatusDTO{Email: "new@example.com", Verified: true}) || repo.user.Email != "new@example.com" || !repo.user.EmailVerified {
+		t.Fatalf("status = %+v, user = %+v", status, repo.user)
+	}
+	if _, err := service.VerifyEmail(ctx, request.Data.VerifyToken); !errors.Is(err, services.ErrInvalidEmailToken) {
+		t.Errorf("reused token err = %v, want %v", err, services.ErrInvalidEmailToken)
+	}
+
+	redisClient.Set(ctx, keys[2], services.EmailVerificationDailyLimit, time.Hour)
+	redisClient.Del(ctx, keys[1])
+	if err := service.SendVerification(ctx, uid, dto.EmailVerificationRequestDTO{Email: "other@example.com"}); !errors.Is(err, services.ErrEmailVerificationLimit) {
+		t.Errorf("daily limit err = %v, want %v", err, services.ErrEmailVerificationLimit)
+	}
+}
+
+// TestEmailVerificationMail は確認ページのリンクがある場合はリンクを、ない場合はトークンを載せることを確認するテストです。
+func TestEmailVerificationMail(t *testing.T) {
+	cases := []struct {
+		name   string
+		locale string
+		data   services.MailData
+		want   string
+	}{
+		{"Link", "ja", services.MailData{Name: "山田", VerifyURL: "https://app.example.com/verify-email?token=abc", VerifyToken: "abc"}, "https://app.example.com/verify-email?token=abc"},
+		{"Token Only", "en", services.MailData{Name: "Yamada", VerifyToken: "abc"}, "Verification code: abc"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mailer := newCapturingMailer(0, nil)
+			err := services.NewMailService(services.MailConfig{Mailer: mailer}).Send(context.Background(), services.MailRequest{
+				To: "yamada@example.com", Locale: tc.locale, Template: services.EmailVerificationMail, Data: tc.data,
+			})
+			if err != nil {
+				t.Fatalf("err = %v", err)
+			}
+			mail, ok := mailer.receive(time.Second)
+			if !ok {
+				t.Fatal("mail was not sent")
+			}
+			if !strings.Contains(mail.Text, tc.want) {
+				t.Errorf("text = %q, want %q", mail.Text, tc.want)
+			}
+		})
+	}
+}
+
+// TestVerifyEmailKeepsToken は他のユーザーが検証済みのアドレスには検証メールを送信せず、
+// 検証の保存に失敗した場合と送信後に他のユーザーが先に検証した場合はトークンを残すことを確認するテストです。
+func TestVerifyEmailKeepsToken(t *testing.T) {
+	redisClient := openTestRedis(t)
+	ctx := context.Background()
+
+	cases := []struct {
+		name       string
+		sendTaken  string
+		taken      string
+		err        error
+		wantSend   error
+		wantVerify error
+		wantRetry  bool
+	}{
+		{"Taken Before Send", "Shared@example.com", "", nil, services.ErrEmailTaken, nil, false},
+		{"Taken Before Verify", "", "shared@example.com", nil, nil, services.ErrEmailTaken, true},
+		{"Save Failure", "", "", errors.New("connection refused"), nil, errors.New("connection refused"), true},
+	}
+	for i, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			uid := uint(930101 + i)
+			repo := &verificationUserRepo{user: models.User{ID: uid, Name: "山田", Locale: "ja"}, taken: tc.sendTaken}
+			mail := &recordingMailService{}
+			service := services.NewEmailVerificationService(repo, mail, redisClient, "")
+
+			err := service.SendVerification(ctx, uid, dto.EmailVerificationRequestDTO{Email: "shared@example.com"})
+			if !errors.Is(err, tc.wantSend) {
+				t.Fatalf("send err = %v, want %v", err, tc.wantSend)
+			}
+			if tc.wantSend != nil {
+				if len(mail.requests) != 0 {
+					t.Errorf("requests = %+v, want none", mail.requests)
+				}
+				return
+			}
+			token := mail.requests[0].Data.VerifyToken
+			repo.taken, repo.err = tc.taken, tc.err
+
+			if _, err := service.VerifyEmail(ctx, token); err == nil || err.Error() != tc.wantVerify.Error() {
+				t.Fatalf("verify err = %v, want %v", err, tc.wantVerify)
+			}
+			if repo.user.EmailVerified {
+				t.Fatalf("user = %+v, want it unverified", repo.user)
+			}
+			// 原因が解消した後は同じトークンで検証できる
+			repo.taken, repo.err = "", nil
+			if _, err := service.VerifyEmail(ctx, token); (err == nil) != tc.wantRetry {
+				t.Errorf("retry err = %v, want retry %v", err, tc.wantRetry)
+			}
+		})
+	}
+}
+
+// TestMarkEmailVerifiedOnDatabase は大文字と小文字を区別せずに他のユーザーが検証済みのアドレスを検証済みにせず、
+// 未検証のユーザーが同じアドレスを持つ場合は検証できることを確認するテストです。
+func TestMarkEmailVerifiedOnDatabase(t *testing.T) {
+	db := openTestDB(t)
+	migration.Migrate(db)
+	tx := db.Begin()
+	if tx.Error != nil {
+		t.Fatalf("failed to begin transaction: %v", tx.Error)
+	}
+	t.Cleanup(func() { tx.Rollback() })
+	ctx := context.Background()
+
+	users := []*models.User{
+		{Name: "山田", Image: "https://example.com/u.png", PID: "verified-email-test-1", Email: "shared@example.com"},
+		{Name: "佐藤", Image: "https://example.com/u.png", PID: "verified-email-test-2", Email: "shared@example.com"},
+		{Name: "鈴木", Image: "https://example.com/u.png", PID: "verified-email-test-3"},
+	}
+	for _, user := range users {
+		if err := tx.Create(user).Error; err != nil {
+			t.Fatalf("failed to create user: %v", err)
+		}
+	}
+	repo := repositories.NewUserRepository(tx)
+
+	cases := []struct {
+		name   string
+		user   *models.User
+		email  string
+		want   bool
+		wantDB string
+	}{
+		{"First", users[0], "shared@example.com", true, "shared@example.com"},
+		{"Same User", users[0], "shared@example.com", true, "shared@example.com"},
+		{"Taken", users[1], "Shared@Example.com", false, "shared@example.com"},
+		{"Other Address", users[2], "suzuki@example.com", true, "suzuki@example.com"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			verified, err := repo.MarkEmailVerified(ctx, tc.user.ID, tc.email)
+			if err != nil || verified != tc.want {
+				t.Fatalf("verified = %v, err = %v, want %v", verified, err, tc.want)
+			}
+			var saved models.User
+			if err := tx.First(&saved, tc.user.ID).Error; err != nil {
+				t.Fatalf("failed to load user: %v", err)
+			}
+			if saved.Email != tc.wantDB || saved.EmailVerified != tc.want {
+				t.Errorf("user = %q verified %v, want %q verified %v", saved.Email, saved.EmailVerified, tc.wantDB, tc.want)
+			}
+		})
+	}
+}
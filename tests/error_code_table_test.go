@@ -0,0 +1,34 @@
+package tests
+
+import (
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// errorCodeConstant constants/error_codes.goのコードと、コメントに書いたステータスを取り出す
+var errorCodeConstant = regexp.MustCompile(`(?m)^\s*ErrCode\w+\s*=\s*"([^"]+)"\s*//\s*(\d{3}|\S+)`)
+
+// TestSwaggerErrorCodeTable はconstants/error_codes.goの全てのコードが、同じステータスでSwaggerの説明のコード一覧に載っていることを確認するテストです。
+func TestSwaggerErrorCodeTable(t *testing.T) {
+	constants, err := os.ReadFile("../constants/error_codes.go")
+	if err != nil {
+		t.Fatalf("failed to read error codes: %v", err)
+	}
+	mainFile, err := os.ReadFile("../main.go")
+	if err != nil {
+		t.Fatalf("failed to read main.go: %v", err)
+	}
+
+	codes := errorCodeConstant.FindAllStringSubmatch(string(constants), -1)
+	if len(codes) == 0 {
+		t.Fatal("no error codes found")
+	}
+	for _, code := range codes {
+		row := "| " + code[1] + " | " + code[2]
+		if !strings.Contains(string(mainFile), row) {
+			t.Errorf("swagger table has no row %q", row)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/constants"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/middlewares"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"gorm.io/gorm"
+)
+
+type validationTarget struct {
+	Name string `binding:"required"`
+}
+
+// setUpErrorRouter は指定されたエラーを登録するハンドラーを持つルーターを生成します。
+func setUpErrorRouter(handler gin.HandlerFunc) *gin.Engine {
+	r := gin.New()
+	r.Use(middlewares.RequestIDMiddleware())
+	r.Use(middlewares.GlobalErrorHandler(&recordingErrorReporter{}))
+	r.GET("/test", handler)
+	return r
+}
+
+// TestGlobalErrorHandlerStatusMapping はエラーの種類ごとのステータスとコードを確認するテストです。
+func TestGlobalErrorHandlerStatusMapping(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	validationErr := binding.Validator.ValidateStruct(validationTarget{})
+
+	cases := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantCode   string
+	}{
+		{"AppError", utils.NewNotFoundError(constants.ErrCodeClassNotFound, constants.ClassNotFound), constants.StatusNotFound, constants.ErrCodeClassNotFound},
+		{"Wrapped AppError", fmt.Errorf("wrapped: %w", utils.NewConflictError(constants.ErrCodeConflict, "conflict")), constants.StatusConflict, constants.ErrCodeConflict},
+		{"Record Not Found", gorm.ErrRecordNotFound, constants.StatusNotFound, constants.ErrCodeNotFound},
+		{"Wrapped Record Not Found", fmt.Errorf("find: %w", gorm.ErrRecordNotFound), constants.StatusNotFound, constants.ErrCodeNotFound},
+		{"Validation Error", validationErr, constants.StatusUnprocessable, constants.ErrCodeValidation},
+		{"Deadline Exceeded", context.DeadlineExceeded, constants.StatusGatewayTimeout, constants.ErrCodeTimeout},
+		{"Unknown Error", errors.New("boom"), constants.StatusInternalServerError, constants.ErrCodeInternal},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := setUpErrorRouter(func(c *gin.Context) {
+				_ = c.Error(tc.err)
+			})
+
+			resp := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodGet, "/test", nil)
+			r.ServeHTTP(resp, req)
+
+			if resp.Code != tc.wantStatus {
+				t.Fatalf("status = %d, want %d", resp.Code, tc.wantStatus)
+			}
+
+			var body utils.ErrorResponse
+			if err := json.Unmarshal(resp.Body.Bytes(), &body); err != nil {
+				t.Fatalf("invalid response body: %v", err)
+			}
+			if body.Code != tc.wantCode {
+				t.Errorf("code = %q, want %q", body.Code, tc.wantCode)
+			}
+			if body.Error == "" {
+				t.Error("error message should not be empty")
+			}
+			if body.RequestID == "" {
+				t.Error("request_id should not be empty")
+			}
+		})
+	}
+}
+
+// TestGlobalErrorHandlerSuppressesDetailsInRelease はリリースモードで詳細が隠されることを確認するテストです。
+func TestGlobalErrorHandlerSuppressesDetailsInRelease(t *testing.T) {
+	cases := []struct {
+		name        string
+		mode        string
+		wantDetails bool
+	}{
+		{"Release Mode", gin.ReleaseMode, false},
+		{"Debug Mode", gin.DebugMode, true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			gin.SetMode(tc.mode)
+			defer gin.SetMode(gin.ReleaseMode)
+
+			r := setUpErrorRouter(func(c *gin.Context) {
+				_ = c.Error(errors.New("secret internal detail"))
+			})
+
+			resp := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodGet, "/test", nil)
+			r.ServeHTTP(resp, req)
+
+			var body utils.ErrorResponse
+			if err := json.Unmarshal(resp.Body.Bytes(), &body); err != nil {
+				t.Fatalf("invalid response body: %v", err)
+			}
+			if (body.Details != nil) != tc.wantDetails {
+				t.Errorf("details present = %v, want %v", body.Details != nil, tc.wantDetails)
+			}
+		})
+	}
+}
+
+// TestGlobalErrorHandlerDoesNotOverwriteResponse は既に書き込まれたレスポンスを上書きしないことを確認するテストです。
+func TestGlobalErrorHandlerDoesNotOverwriteResponse(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	r := setUpErrorRouter(func(c *gin.Context) {
+		c.JSON(constants.StatusBadRequest, gin.H{"error": constants.InvalidRequest})
+		_ = c.Error(errors.New("already handled"))
+	})
+
+	resp := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/test", nil)
+	r.ServeHTTP(resp, req)
+
+	if resp.Code != constants.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", resp.Code, constants.StatusBadRequest)
+	}
+
+	var body map[string]interface{}
+	if err := json.Unmarshal(resp.Body.Bytes(), &body); err != nil {
+		t.Fatalf("response should be a single JSON document: %v", err)
+	}
+}
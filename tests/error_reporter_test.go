@@ -0,0 +1,152 @@
+package tests
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/constants"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/middlewares"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// recordingErrorReporter は送信されたエラーを保持するErrorReporterです。
+type recordingErrorReporter struct {
+	mu     sync.Mutex
+	events []utils.ErrorEvent
+}
+
+func (r *recordingErrorReporter) Report(event utils.ErrorEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+func (r *recordingErrorReporter) captured() []utils.ErrorEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]utils.ErrorEvent(nil), r.events...)
+}
+
+// setUpReportingRouter はエラー監視のミドルウェアを適用し、ユーザーIDを設定するルーターを生成します。
+func setUpReportingRouter(reporter utils.ErrorReporter, handler gin.HandlerFunc) *gin.Engine {
+	r := gin.New()
+	r.Use(middlewares.RequestIDMiddleware())
+	r.Use(middlewares.RecoveryMiddleware(reporter))
+	r.Use(middlewares.GlobalErrorHandler(reporter))
+	r.GET("/api/gin/cs/:id", func(c *gin.Context) { c.Set("userID", uint(7)) }, handler)
+	return r
+}
+
+// TestErrorReporterCapturesUnexpectedErrors はパニックと500番台のエラーのみが送信されることを確認するテストです。
+func TestErrorReporterCapturesUnexpectedErrors(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+
+	cases := []struct {
+		name       string
+		handler    gin.HandlerFunc
+		wantStatus int
+		wantReport bool
+		wantStack  bool
+	}{
+		{"Panic", func(c *gin.Context) { panic("boom") }, constants.StatusInternalServerError, true, true},
+		{"Internal Error", func(c *gin.Context) { _ = c.Error(errors.New("boom")) }, constants.StatusInternalServerError, true, false},
+		{"Not Found", func(c *gin.Context) {
+			_ = c.Error(utils.NewNotFoundError(constants.ErrCodeClassNotFound, constants.ClassNotFound))
+		}, constants.StatusNotFound, false, false},
+		{"Success", func(c *gin.Context) { c.Status(http.StatusOK) }, constants.StatusOK, false, false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			reporter := &recordingErrorReporter{}
+			r := setUpReportingRouter(reporter, tc.handler)
+
+			resp := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodGet, "/api/gin/cs/3", nil)
+			r.ServeHTTP(resp, req)
+
+			if resp.Code != tc.wantStatus {
+				t.Fatalf("status = %d, want %d", resp.Code, tc.wantStatus)
+			}
+			events := reporter.captured()
+			if got := len(events) == 1; got != tc.wantReport {
+				t.Fatalf("reported = %v (%d events), want %v", got, len(events), tc.wantReport)
+			}
+			if !tc.wantReport {
+				return
+			}
+
+			event := events[0]
+			if event.RequestID == "" || event.RequestID != resp.Header().Get(middlewares.RequestIDHeader) {
+				t.Errorf("request id = %q, want response header %q", event.RequestID, resp.Header().Get(middlewares.RequestIDHeader))
+			}
+			if event.Route != "/api/gin/cs/:id" || event.Method != http.MethodGet || event.UID != 7 {
+				t.Errorf("event = %+v, want GET /api/gin/cs/:id by uid 7", event)
+			}
+			if got := len(event.Stack) > 0; got != tc.wantStack {
+				t.Errorf("has stack = %v, want %v", got, tc.wantStack)
+			}
+		})
+	}
+}
+
+// TestErrorReporterScrubsRequestData は送信するリクエスト情報から認証情報がマスクされることを確認するテストです。
+func TestErrorReporterScrubsRequestData(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	reporter := &recordingErrorReporter{}
+	r := setUpReportingRouter(reporter, func(c *gin.Context) { panic(errors.New("boom")) })
+
+	req, _ := http.NewRequest(http.MethodGet, "/api/gin/cs/3?cid=5&access_token=abc&password=hunter2", nil)
+	req.Header.Set("Authorization", "Bearer secret-jwt")
+	req.Header.Set("Cookie", "session=xyz")
+	req.Header.Set("Accept", "application/json")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	events := reporter.captured()
+	if len(events) != 1 {
+		t.Fatalf("events = %d, want 1", len(events))
+	}
+	event := events[0]
+
+	wantURL := "/api/gin/cs/3?cid=5&access_token=" + utils.RedactedValue + "&password=" + utils.RedactedValue
+	if event.URL != wantURL {
+		t.Errorf("url = %q, want %q", event.URL, wantURL)
+	}
+	for _, name := range []string{"Authorization", "Cookie"} {
+		if got := event.Headers[name]; got != utils.RedactedValue {
+			t.Errorf("header %s = %q, want redacted", name, got)
+		}
+	}
+	if got := event.Headers["Accept"]; got != "application/json" {
+		t.Errorf("header Accept = %q, want application/json", got)
+	}
+	for _, value := range event.Headers {
+		if strings.Contains(value, "secret-jwt") {
+			t.Error("token should not be reported")
+		}
+	}
+}
+
+// TestReportBackgroundError はバックグラウンド処理のエラーがタスク名とともに送信されることを確認するテストです。
+func TestReportBackgroundError(t *testing.T) {
+	reporter := &recordingErrorReporter{}
+	previous := utils.DefaultErrorReporter()
+	utils.SetDefaultErrorReporter(reporter)
+	defer utils.SetDefaultErrorReporter(previous)
+
+	utils.ReportBackgroundError("manage_chat_rooms", nil)
+	utils.ReportBackgroundError("manage_chat_rooms", errors.New("redis down"))
+
+	events := reporter.captured()
+	if len(events) != 1 {
+		t.Fatalf("events = %d, want 1", len(events))
+	}
+	if events[0].Tags["task"] != "manage_chat_rooms" || events[0].Err.Error() != "redis down" {
+		t.Errorf("event = %+v, want manage_chat_rooms with redis down", events[0])
+	}
+}
@@ -0,0 +1,131 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/constants"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/middlewares"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// setUpETagRouter はETagのミドルウェアを適用したルーターを生成します。
+func setUpETagRouter(config middlewares.ETagConfig, handler gin.HandlerFunc) *gin.Engine {
+	r := gin.New()
+	r.Use(middlewares.GlobalErrorHandler(&recordingErrorReporter{}))
+	r.GET("/api/gin/v2/cu/:cid/dashboard", func(c *gin.Context) {
+		c.Set("userID", uint(1))
+	}, middlewares.ETagMiddleware(config), handler)
+	return r
+}
+
+// getWithIfNoneMatch はIf-None-Matchを付けてGETリクエストを送信します。
+func getWithIfNoneMatch(r *gin.Engine, etag string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(http.MethodGet, "/api/gin/v2/cu/1/dashboard", nil)
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+	return resp
+}
+
+// TestETagMiddleware はボディのハッシュをETagとして返し、一致するIf-None-Matchには304を返すことを確認するテストです。
+func TestETagMiddleware(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+
+	body := `{"id":1}`
+	r := setUpETagRouter(middlewares.ETagConfig{CacheControl: "private, no-cache"}, func(c *gin.Context) {
+		c.Data(http.StatusOK, "application/json", []byte(body))
+	})
+
+	first := getWithIfNoneMatch(r, "")
+	etag := first.Header().Get("ETag")
+	if first.Code != http.StatusOK || first.Body.String() != body || etag == "" {
+		t.Fatalf("first = %d %q etag %q, want 200 with body and etag", first.Code, first.Body.String(), etag)
+	}
+	if cacheControl := first.Header().Get("Cache-Control"); cacheControl != "private, no-cache" {
+		t.Errorf("Cache-Control = %q, want %q", cacheControl, "private, no-cache")
+	}
+
+	cases := []struct {
+		name        string
+		ifNoneMatch string
+		body        string
+		wantStatus  int
+	}{
+		{"Matching ETag", etag, body, http.StatusNotModified},
+		{"Weak Matching ETag", `"other", W/` + etag, body, http.StatusNotModified},
+		{"Stale ETag", `"stale"`, body, http.StatusOK},
+		{"Changed Body", etag, `{"id":2}`, http.StatusOK},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			body = tc.body
+			resp := getWithIfNoneMatch(r, tc.ifNoneMatch)
+			if resp.Code != tc.wantStatus {
+				t.Fatalf("status = %d, want %d", resp.Code, tc.wantStatus)
+			}
+			if tc.wantStatus == http.StatusNotModified && resp.Body.Len() != 0 {
+				t.Errorf("304 body = %q, want empty", resp.Body.String())
+			}
+			if tc.wantStatus == http.StatusOK && resp.Body.String() != tc.body {
+				t.Errorf("body = %q, want %q", resp.Body.String(), tc.body)
+			}
+		})
+	}
+}
+
+// TestETagMiddlewareErrorResponse はエラーレスポンスにETagを付与しないことを確認するテストです。
+func TestETagMiddlewareErrorResponse(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	r := setUpETagRouter(middlewares.ETagConfig{CacheControl: "private, no-cache"}, func(c *gin.Context) {
+		_ = c.Error(utils.NewForbiddenError(constants.ErrCodeForbidden, constants.Forbidden))
+		c.Abort()
+	})
+
+	resp := getWithIfNoneMatch(r, "")
+	if resp.Code != http.StatusForbidden {
+		t.Fatalf("status = %d, want %d", resp.Code, http.StatusForbidden)
+	}
+	if etag := resp.Header().Get("ETag"); etag != "" {
+		t.Errorf("ETag = %q, want empty", etag)
+	}
+}
+
+// TestETagMiddlewareVersion はバージョンが変わらない間はハンドラーを実行せずに304を返し、変わった場合は再実行することを確認するテストです。
+func TestETagMiddlewareVersion(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+
+	version, calls := "v1", 0
+	config := middlewares.ETagConfig{Version: func(*gin.Context) (string, bool) { return version, version != "" }}
+	r := setUpETagRouter(config, func(c *gin.Context) {
+		calls++
+		c.JSON(http.StatusOK, gin.H{"calls": calls})
+	})
+
+	first := getWithIfNoneMatch(r, "")
+	etag := first.Header().Get("ETag")
+	if first.Code != http.StatusOK || etag == "" {
+		t.Fatalf("first = %d etag %q, want 200 with etag", first.Code, etag)
+	}
+
+	if resp := getWithIfNoneMatch(r, etag); resp.Code != http.StatusNotModified || calls != 1 {
+		t.Errorf("same version = %d calls %d, want 304 without calling handler", resp.Code, calls)
+	}
+
+	version = "v2"
+	if resp := getWithIfNoneMatch(r, etag); resp.Code != http.StatusOK || calls != 2 {
+		t.Errorf("bumped version = %d calls %d, want 200 with handler called", resp.Code, calls)
+	}
+
+	// バージョンを取得できない場合はボディのハッシュで判定する
+	version = ""
+	unversioned := getWithIfNoneMatch(r, "")
+	if resp := getWithIfNoneMatch(r, unversioned.Header().Get("ETag")); resp.Code != http.StatusOK || calls != 4 {
+		t.Errorf("without version = %d calls %d, want handler called for every request", resp.Code, calls)
+	}
+}
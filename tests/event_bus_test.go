@@ -0,0 +1,323 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/middlewares"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+)
+
+// publishedEvent は配信を記録した内部イベントです。
+type publishedEvent struct {
+	event   string
+	payload interface{}
+}
+
+// recordingEventPublisher は配信した内部イベントを記録するEventPublisherです。
+type recordingEventPublisher struct {
+	mu     sync.Mutex
+	events []publishedEvent
+}
+
+func (p *recordingEventPublisher) PublishEvent(_ context.Context, event string, payload interface{}) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.events = append(p.events, publishedEvent{event: event, payload: payload})
+}
+
+// TestEventPayloadContract は他のサービスが依存するペイロードのフィールド名と形式が変わっていないことを確認するテストです。
+// このテストが失敗する変更は、dto.EventEnvelopeVersionを上げて購読するサービスと調整してください。
+func TestEventPayloadContract(t *testing.T) {
+	language := "ja"
+	recordedAt := time.Date(2024, 6, 10, 9, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		event   string
+		payload interface{}
+		want    string
+	}{
+		{dto.EventClassCreated, dto.EventClassCreatedV1{CID: 1, UID: 2, Name: "数学", IsPublic: true, Language: &language},
+			`{"cid":1,"uid":2,"name":"数学","is_public":true,"language":"ja"}`},
+		{dto.EventClassCreated, dto.EventClassCreatedV1{CID: 1, UID: 2, Name: "数学"},
+			`{"cid":1,"uid":2,"name":"数学","is_public":false}`},
+		{dto.EventMemberApproved, dto.EventMemberApprovedV1{CID: 1, UID: 2, Role: "USER"},
+			`{"cid":1,"uid":2,"role":"USER"}`},
+		{dto.EventAttendanceRecorded, dto.EventAttendanceRecordedV1{ID: 5, Action: dto.AttendanceRecorded, CID: 1, CSID: 3, UID: 2, Status: "ATTENDANCE", RecordedAt: recordedAt},
+			`{"id":5,"action":"recorded","cid":1,"csid":3,"uid":2,"status":"ATTENDANCE","recorded_at":"2024-06-10T09:00:00Z"}`},
+		{dto.EventAttendanceReset, dto.EventAttendanceResetV1{CID: 1, CSID: 3, Deleted: 25},
+			`{"cid":1,"csid":3,"deleted":25}`},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.want, func(t *testing.T) {
+			envelope, err := services.NewEventEnvelope(context.Background(), tc.event, tc.payload)
+			if err != nil {
+				t.Fatalf("err = %v", err)
+			}
+			if string(envelope.Payload) != tc.want {
+				t.Fatalf("payload = %s, want %s", envelope.Payload, tc.want)
+			}
+
+			data, err := json.Marshal(envelope)
+			if err != nil {
+				t.Fatalf("err = %v", err)
+			}
+			var fields map[string]json.RawMessage
+			if err := json.Unmarshal(data, &fields); err != nil {
+				t.Fatalf("err = %v", err)
+			}
+			for _, key := range []string{"id", "event", "version", "occurred_at", "payload"} {
+				if _, ok := fields[key]; !ok {
+					t.Fatalf("envelope %s has no %q", data, key)
+				}
+			}
+			if string(fields["version"]) != "1" || string(fields["event"]) != `"`+tc.event+`"` {
+				t.Fatalf("envelope = %s", data)
+			}
+
+			// 購読するサービスと同じ手順でペイロードを読み込めること
+			decoded := reflect.New(reflect.TypeOf(tc.payload))
+			if err := services.DecodeEventPayload(envelope, decoded.Interface()); err != nil {
+				t.Fatalf("err = %v", err)
+			}
+			if !reflect.DeepEqual(decoded.Elem().Interface(), tc.payload) {
+				t.Fatalf("decoded = %+v, want %+v", decoded.Elem().Interface(), tc.payload)
+			}
+		})
+	}
+}
+
+// TestCreateClassPublishesAfterCommit はクラスの作成がコミットされた場合のみclass.createdを配信することを確認するテストです。
+func TestCreateClassPublishesAfterCommit(t *testing.T) {
+	cases := []struct {
+		name          string
+		failClassCode bool
+		wantEvents    int
+	}{
+		{"Committed", false, 1},
+		{"Rolled Back", true, 0},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := &fakeStore{failSaveClassCode: tc.failClassCode}
+			events := &recordingEventPublisher{}
+			service := services.NewCreateClassService(
+				&fakeTxManager{store: store},
+				&fakeClassRepo{store: store},
+				&fakeClassUserRepo{store: store},
+				&fakeClassCodeRepo{store: store},
+				&fakeUserRepo{},
+				nil,
+				"",
+				nil,
+				events,
+			)
+
+			cid, _ := service.CreateClass(context.Background(), dto.CreateClassRequest{Name: "数学", UID: 2, IsPublic: true})
+			if len(events.events) != tc.wantEvents {
+				t.Fatalf("events = %+v, want %d", events.events, tc.wantEvents)
+			}
+			if tc.wantEvents == 0 {
+				return
+			}
+			want := dto.EventClassCreatedV1{CID: cid, UID: 2, Name: "数学", IsPublic: true}
+			if events.events[0].event != dto.EventClassCreated || !reflect.DeepEqual(events.events[0].payload, want) {
+				t.Fatalf("event = %+v, want %+v", events.events[0], want)
+			}
+		})
+	}
+}
+
+// TestMemberApprovedEvent は参加申請をメンバーとして承認した場合のみmember.approvedを配信することを確認するテストです。
+func TestMemberApprovedEvent(t *testing.T) {
+	redisClient := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", DialTimeout: 50 * time.Millisecond, MaxRetries: -1})
+	cases := []struct {
+		name         string
+		oldRole      string
+		newRole      string
+		wantApproved bool
+	}{
+		{"Approved", "APPLICANT", "USER", true},
+		{"Approved As Assistant", "APPLICANT", "ASSISTANT", true},
+		{"Rejected", "APPLICANT", "BLACKLIST", false},
+		{"Promoted", "USER", "ASSISTANT", false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			events := &recordingEventPublisher{}
+			service := services.NewClassUserService(nil, &roleChangeClassUserRepo{role: tc.oldRole}, nil, nil, nil, nil, redisClient, 0, nil, nil, events)
+
+			if err := service.AssignRole(context.Background(), 2, 10, tc.newRole); err != nil {
+				t.Fatalf("err = %v", err)
+			}
+			if approved := len(events.events) == 1; approved != tc.wantApproved {
+				t.Fatalf("events = %+v, want approved %v", events.events, tc.wantApproved)
+			}
+			if tc.wantApproved {
+				want := dto.EventMemberApprovedV1{CID: 10, UID: 2, Role: tc.newRole}
+				if events.events[0].event != dto.EventMemberApproved || events.events[0].payload != want {
+					t.Fatalf("event = %+v, want %+v", events.events[0], want)
+				}
+			}
+		})
+	}
+}
+
+// TestAttendanceRecordedEvent は出席の記録と更新でattendance.recordedを配信することを確認するテストです。
+func TestAttendanceRecordedEvent(t *testing.T) {
+	cases := []struct {
+		name       string
+		existing   *models.Attendance
+		wantAction string
+	}{
+		{"Recorded", nil, dto.AttendanceRecorded},
+		{"Updated", &models.Attendance{ID: 7, CID: 1, UID: 2, CSID: 3}, dto.AttendanceUpdated},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			events := &recordingEventPublisher{}
+			service := services.NewAttendanceService(&timestampAttendanceRepo{existing: tc.existing}, &roleClassUserRepo{roles: map[uint]string{9: "ADMIN"}}, nil, nil, true, nil, events)
+
+			if err := service.CreateOrUpdateAttendance(context.Background(), 1, 2, 3, string(models.AttendanceStatus), nil, nil, 9, nil, 0); err != nil {
+				t.Fatalf("err = %v", err)
+			}
+			if len(events.events) != 1 || events.events[0].event != dto.EventAttendanceRecorded {
+				t.Fatalf("events = %+v", events.events)
+			}
+			payload := events.events[0].payload.(dto.EventAttendanceRecordedV1)
+			if payload.Action != tc.wantAction || payload.CID != 1 || payload.CSID != 3 || payload.UID != 2 || payload.Status != string(models.AttendanceStatus) {
+				t.Fatalf("payload = %+v, want action %s", payload, tc.wantAction)
+			}
+		})
+	}
+}
+
+// TestEventReplayErrors は配信が無効な場合と、afterがイベントのIDの形式ではない場合のエラーを確認するテストです。
+func TestEventReplayErrors(t *testing.T) {
+	cases := []struct {
+		name    string
+		enabled bool
+		after   string
+		wantErr error
+	}{
+		{"Disabled", false, "", services.ErrEventsUnavailable},
+		{"Invalid Cursor", true, "latest", services.ErrInvalidEventCursor},
+		{"Invalid Sequence", true, "1718000000000-", services.ErrInvalidEventCursor},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			bus := services.NewEventBus(nil, services.EventBusConfig{Enabled: tc.enabled})
+			if _, err := bus.Replay(context.Background(), tc.after, 0); !errors.Is(err, tc.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+// TestServiceKeyMiddleware はX-API-Keyヘッダーが設定したAPIキーと一致する場合のみ通過させ、キーが空の場合は全て拒否することを確認するテストです。
+func TestServiceKeyMiddleware(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	cases := []struct {
+		name       string
+		key        string
+		header     string
+		wantStatus int
+	}{
+		{"Valid Key", "secret", "secret", http.StatusOK},
+		{"Wrong Key", "secret", "other", http.StatusUnauthorized},
+		{"Missing Header", "secret", "", http.StatusUnauthorized},
+		{"Key Not Configured", "", "", http.StatusUnauthorized},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := gin.New()
+			r.GET("/events", middlewares.ServiceKeyMiddleware(tc.key), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+			req := httptest.NewRequest(http.MethodGet, "/events", nil)
+			if tc.header != "" {
+				req.Header.Set(middlewares.ServiceAPIKeyHeader, tc.header)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tc.wantStatus {
+				t.Fatalf("status = %d, want %d", w.Code, tc.wantStatus)
+			}
+		})
+	}
+}
+
+// TestEventBusRedis はRedisのチャンネルでイベントを受け取れ、ストリームから同じイベントを再取得できることを確認するテストです。
+func TestEventBusRedis(t *testing.T) {
+	redisClient := openTestRedis(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	redisClient.Del(ctx, services.EventStreamKey)
+	t.Cleanup(func() {
+		redisClient.Del(context.Background(), services.EventStreamKey)
+	})
+	bus := services.NewEventBus(redisClient, services.EventBusConfig{Enabled: true})
+
+	received := make(chan dto.EventEnvelope, 10)
+	go func() {
+		_ = services.ConsumeEvents(ctx, redisClient, func(envelope dto.EventEnvelope) { received <- envelope }, dto.EventMemberApproved)
+	}()
+
+	// 購読の開始を待つ手段がないため、受け取るまで配信を繰り返す
+	var first dto.EventEnvelope
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+	published := 0
+	for first.ID == "" {
+		select {
+		case first = <-received:
+		case <-ticker.C:
+			bus.PublishEvent(ctx, dto.EventMemberApproved, dto.EventMemberApprovedV1{CID: 1, UID: 2, Role: "USER"})
+			published++
+		case <-ctx.Done():
+			t.Fatal("no event was received")
+		}
+	}
+	var payload dto.EventMemberApprovedV1
+	if err := services.DecodeEventPayload(first, &payload); err != nil || payload.UID != 2 {
+		t.Fatalf("payload = %+v, err = %v", payload, err)
+	}
+	// 別のイベントは購読していないため届かない
+	bus.PublishEvent(ctx, dto.EventClassCreated, dto.EventClassCreatedV1{CID: 1})
+
+	replay, err := bus.Replay(ctx, "", 0)
+	if err != nil {
+		t.Fatalf("err = %v", err)
+	}
+	if len(replay.Events) != published+1 || replay.Next != replay.Events[len(replay.Events)-1].ID {
+		t.Fatalf("replay = %+v, want %d events", replay, published+1)
+	}
+	found := false
+	for _, envelope := range replay.Events {
+		found = found || envelope.ID == first.ID
+	}
+	if !found {
+		t.Fatalf("replay does not contain %s", first.ID)
+	}
+
+	rest, err := bus.Replay(ctx, replay.Next, 0)
+	if err != nil || len(rest.Events) != 0 || rest.Next != replay.Next {
+		t.Fatalf("rest = %+v, err = %v", rest, err)
+	}
+}
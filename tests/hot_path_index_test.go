@@ -0,0 +1,171 @@
+package tests
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/migration"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+	"gorm.io/gorm"
+)
+
+// hotPathIndexFile インデックスを追加したマイグレーションのファイル名
+const hotPathIndexFile = "../migration/sql/000005_hot_path_indexes"
+
+// seedAttendanceSQL 100クラス、クラスごとに100人のメンバーと10回のスケジュールがある10万件の出席を投入する。
+// スケジュールはseedAttendancesで投入する
+var seedAttendanceSQL = []string{
+	`INSERT INTO users (name, image, p_id, created_at)
+		WITH RECURSIVE seq(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM seq WHERE i < 1000)
+		SELECT 'user' || i, '', 'bench-' || i, CURRENT_TIMESTAMP FROM seq`,
+	`INSERT INTO classes (name, uid)
+		WITH RECURSIVE seq(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM seq WHERE i < 100)
+		SELECT 'class' || i, 1 FROM seq`,
+	`INSERT INTO class_users (cid, uid, nickname, role)
+		WITH RECURSIVE c(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM c WHERE i < 100),
+			u(i) AS (SELECT 0 UNION ALL SELECT i + 1 FROM u WHERE i < 99)
+		SELECT c.i, (c.i * 10 + u.i) % 1000 + 1, 'member', 'USER' FROM c, u`,
+}
+
+// seedAttendances 出席を投入する
+func seedAttendances(tb testing.TB, db *gorm.DB) {
+	for _, stmt := range seedAttendanceSQL {
+		if err := db.Exec(stmt).Error; err != nil {
+			tb.Fatalf("failed to seed attendances: %v", err)
+		}
+	}
+	var schedules []models.ClassSchedule
+	now := time.Now().UTC()
+	for c := uint(1); c <= 100; c++ {
+		for s := 1; s <= 10; s++ {
+			start := now.AddDate(0, 0, -s)
+			schedules = append(schedules, models.ClassSchedule{Title: "schedule", CID: c, StartedAt: start, EndedAt: start.Add(90 * time.Minute)})
+		}
+	}
+	if err := db.CreateInBatches(&schedules, 200).Error; err != nil {
+		tb.Fatalf("failed to seed schedules: %v", err)
+	}
+	for _, stmt := range []string{
+		`INSERT INTO attendances (cid, uid, csid, is_attendance)
+			SELECT cs.cid, cu.uid, cs.id, 'ATTENDANCE' FROM class_schedules cs JOIN class_users cu ON cu.cid = cs.cid`,
+		`ANALYZE`,
+	} {
+		if err := db.Exec(stmt).Error; err != nil {
+			tb.Fatalf("failed to seed attendances: %v", err)
+		}
+	}
+}
+
+// migrateWithAttendances は全てのマイグレーションを適用し、出席を投入したデータベースを返します。
+// PostgreSQLでは埋め込んだSQLのマイグレーションを、SQLiteではAutoMigrateを使います。
+func migrateWithAttendances(tb testing.TB) *gorm.DB {
+	db := openMigrationDB(tb)
+	createHotPathIndexes(tb, db)
+	seedAttendances(tb, db)
+	return db
+}
+
+// createHotPathIndexes インデックスのマイグレーションを適用します。
+// PostgreSQLではマイグレーションのSQLを実行し、SQLiteではMigrateで重複した出席を削除してからインデックスを作成します。
+func createHotPathIndexes(tb testing.TB, db *gorm.DB) {
+	tb.Helper()
+	if db.Dialector.Name() != "postgres" {
+		migration.Migrate(db)
+		return
+	}
+	if !db.Migrator().HasTable("schema_migrations") {
+		if err := migration.MigrateUp(db); err != nil {
+			tb.Fatalf("MigrateUp() = %v", err)
+		}
+		return
+	}
+	execSQLFile(tb, db, hotPathIndexFile+".up.sql")
+}
+
+// dropHotPathIndexes インデックスのマイグレーションを取り消します。後のマイグレーションは取り消しません。
+func dropHotPathIndexes(tb testing.TB, db *gorm.DB) {
+	tb.Helper()
+	execSQLFile(tb, db, hotPathIndexFile+".down.sql")
+}
+
+// execSQLFile SQLファイルを実行します。
+func execSQLFile(tb testing.TB, db *gorm.DB, name string) {
+	tb.Helper()
+	script, err := os.ReadFile(name)
+	if err != nil {
+		tb.Fatalf("failed to read %s: %v", name, err)
+	}
+	err = db.Connection(func(conn *gorm.DB) error { return conn.Exec(string(script)).Error })
+	if err != nil {
+		tb.Fatalf("failed to run %s: %v", name, err)
+	}
+}
+
+// TestHotPathIndexesRemoveDuplicateAttendances はインデックスのマイグレーションが重複した出席のうち最後に記録したものだけを残し、一意インデックスを作成することを確認するテストです。
+func TestHotPathIndexesRemoveDuplicateAttendances(t *testing.T) {
+	db := migrateWithAttendances(t)
+	dropHotPathIndexes(t, db)
+	duplicate := db.Exec(`INSERT INTO attendances (cid, uid, csid, is_attendance, recorded_at)
+		SELECT cid, uid, csid, 'TARDY', ? FROM attendances WHERE csid = 1`, time.Now().UTC().Add(time.Hour))
+	if duplicate.Error != nil {
+		t.Fatalf("failed to insert duplicates: %v", duplicate.Error)
+	}
+
+	createHotPathIndexes(t, db)
+	var total, tardy int64
+	db.Table("attendances").Count(&total)
+	db.Table("attendances").Where("csid = 1 AND is_attendance = 'TARDY'").Count(&tardy)
+	if total != 100000 || tardy != 100 {
+		t.Errorf("total = %d, tardy = %d, want 100000 rows keeping the 100 latest duplicates", total, tardy)
+	}
+	err := db.Exec(`INSERT INTO attendances (cid, uid, csid, is_attendance) SELECT cid, uid, csid, 'TARDY' FROM attendances WHERE csid = 1`).Error
+	if err == nil {
+		t.Error("duplicate attendance was inserted despite the unique index")
+	}
+}
+
+// BenchmarkAttendanceLookup は10万件の出席で、クラスごとの取得、ユーザーごとの出席履歴の取得、出席の記録時の検索を
+// インデックスの有無で比較するベンチマークです。
+func BenchmarkAttendanceLookup(b *testing.B) {
+	db := migrateWithAttendances(b)
+	repo := repositories.NewAttendanceRepository(db)
+	ctx := context.Background()
+	now := time.Now()
+
+	run := func(b *testing.B) {
+		b.Run("ByClass", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, _, err := repo.GetAllAttendancesByCID(ctx, uint(i%100+1), dto.AttendanceListFilter{}, 20, 0); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+		b.Run("TimelineByUser", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				cid := uint(i%100 + 1)
+				if _, err := repo.GetAttendanceTimeline(ctx, cid, uint(cid*10)%1000+1, now); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+		b.Run("UpsertLookup", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				cid := uint(i%100 + 1)
+				if _, err := repo.GetAttendanceByUIDAndCSID(ctx, uint(cid*10)%1000+1, (cid-1)*10+1); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+
+	b.Run("Indexed", run)
+	b.Run("Unindexed", func(b *testing.B) {
+		dropHotPathIndexes(b, db)
+		db.Exec("ANALYZE")
+		run(b)
+	})
+}
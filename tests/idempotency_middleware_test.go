@@ -0,0 +1,165 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/constants"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/middlewares"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// memoryIdempotencyStore はメモリ上でレスポンスを保持するIdempotencyStoreです。
+type memoryIdempotencyStore struct {
+	mu        sync.Mutex
+	reserved  map[string]bool
+	responses map[string]middlewares.IdempotentResponse
+}
+
+func newMemoryIdempotencyStore() *memoryIdempotencyStore {
+	return &memoryIdempotencyStore{reserved: map[string]bool{}, responses: map[string]middlewares.IdempotentResponse{}}
+}
+
+func (s *memoryIdempotencyStore) Reserve(_ context.Context, key string, _ time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.reserved[key] {
+		return false, nil
+	}
+	s.reserved[key] = true
+	return true, nil
+}
+
+func (s *memoryIdempotencyStore) Get(_ context.Context, key string) (*middlewares.IdempotentResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	response, ok := s.responses[key]
+	if !ok {
+		return nil, nil
+	}
+	return &response, nil
+}
+
+func (s *memoryIdempotencyStore) Save(_ context.Context, key string, response middlewares.IdempotentResponse, _ time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.responses[key] = response
+	return nil
+}
+
+func (s *memoryIdempotencyStore) Release(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.reserved, key)
+	delete(s.responses, key)
+	return nil
+}
+
+// setUpIdempotencyRouter は冪等キーのミドルウェアを適用し、ヘッダーのユーザーIDを設定するルーターを生成します。
+func setUpIdempotencyRouter(store middlewares.IdempotencyStore, handler gin.HandlerFunc) *gin.Engine {
+	r := gin.New()
+	r.Use(middlewares.GlobalErrorHandler(&recordingErrorReporter{}))
+	r.POST("/api/gin/cb", func(c *gin.Context) {
+		if c.GetHeader("X-Test-User") == "2" {
+			c.Set("userID", uint(2))
+		} else {
+			c.Set("userID", uint(1))
+		}
+	}, middlewares.IdempotencyMiddleware(store), handler)
+	return r
+}
+
+// postWithIdempotencyKey は冪等キーを付けてPOSTリクエストを送信します。
+func postWithIdempotencyKey(r *gin.Engine, key string, user string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(http.MethodPost, "/api/gin/cb", nil)
+	if key != "" {
+		req.Header.Set(middlewares.IdempotencyKeyHeader, key)
+	}
+	if user != "" {
+		req.Header.Set("X-Test-User", user)
+	}
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+	return resp
+}
+
+// TestIdempotencyMiddleware は同じキーの再送で保存したレスポンスを返し、保存しないレスポンスは再実行することを確認するテストです。
+func TestIdempotencyMiddleware(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+
+	cases := []struct {
+		name      string
+		status    int
+		body      string
+		appErr    bool
+		firstKey  string
+		retryKey  string
+		retryUser string
+		wantCalls int
+	}{
+		{"Replay Same Key", http.StatusCreated, `{"id":1}`, false, "abc", "abc", "", 1},
+		{"Different Key", http.StatusCreated, `{"id":1}`, false, "abc", "def", "", 2},
+		{"Different User", http.StatusCreated, `{"id":1}`, false, "abc", "abc", "2", 2},
+		{"Without Key", http.StatusCreated, `{"id":1}`, false, "", "", "", 2},
+		{"Server Error Not Cached", http.StatusInternalServerError, `{"error":"boom"}`, false, "abc", "abc", "", 2},
+		{"Registered Error Not Cached", 0, "", true, "abc", "abc", "", 2},
+		{"Large Response Not Cached", http.StatusOK, `"` + strings.Repeat("a", 128<<10) + `"`, false, "abc", "abc", "", 2},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			calls := 0
+			r := setUpIdempotencyRouter(newMemoryIdempotencyStore(), func(c *gin.Context) {
+				calls++
+				if tc.appErr {
+					_ = c.Error(utils.NewBadRequestError(constants.ErrCodeInvalidRequest, constants.InvalidRequest))
+					c.Abort()
+					return
+				}
+				c.Data(tc.status, "application/json", []byte(tc.body))
+			})
+
+			first := postWithIdempotencyKey(r, tc.firstKey, "")
+			retry := postWithIdempotencyKey(r, tc.retryKey, tc.retryUser)
+
+			if calls != tc.wantCalls {
+				t.Fatalf("handler calls = %d, want %d", calls, tc.wantCalls)
+			}
+			if retry.Code != first.Code || retry.Body.String() != first.Body.String() {
+				t.Errorf("retry = %d %q, want %d %q", retry.Code, retry.Body.String(), first.Code, first.Body.String())
+			}
+			if replayed := retry.Header().Get(middlewares.IdempotencyReplayedHeader) == "true"; replayed != (tc.wantCalls == 1) {
+				t.Errorf("replayed header = %v, want %v", replayed, tc.wantCalls == 1)
+			}
+		})
+	}
+}
+
+// TestIdempotencyMiddlewareInFlight は最初のリクエストの処理中に届いた再送に409を返すことを確認するテストです。
+func TestIdempotencyMiddlewareInFlight(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	started, release := make(chan struct{}), make(chan struct{})
+	r := setUpIdempotencyRouter(newMemoryIdempotencyStore(), func(c *gin.Context) {
+		close(started)
+		<-release
+		c.Status(http.StatusCreated)
+	})
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() { done <- postWithIdempotencyKey(r, "abc", "") }()
+	<-started
+
+	if resp := postWithIdempotencyKey(r, "abc", ""); resp.Code != http.StatusConflict {
+		t.Errorf("concurrent retry status = %d, want %d", resp.Code, http.StatusConflict)
+	}
+	close(release)
+	if resp := <-done; resp.Code != http.StatusCreated {
+		t.Errorf("first status = %d, want %d", resp.Code, http.StatusCreated)
+	}
+}
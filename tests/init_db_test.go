@@ -0,0 +1,39 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/config"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/migration"
+)
+
+// TestInitDBErrors は接続できない場合に終了せずエラーを返し、エラーメッセージにパスワードを含めないことを確認するテストです。
+func TestInitDBErrors(t *testing.T) {
+	cases := []struct {
+		name   string
+		modify func(cfg *config.DatabaseConfig)
+	}{
+		{"Unreachable Host", func(cfg *config.DatabaseConfig) { cfg.Port = 1 }},
+		{"Invalid SSL Mode", func(cfg *config.DatabaseConfig) { cfg.SSLMode = "on" }},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := config.LoadForTest().Database
+			cfg.Password = "s3cret-password"
+			tc.modify(&cfg)
+
+			db, err := migration.InitDB(cfg)
+			if err == nil {
+				t.Fatal("err = nil, want connection error")
+			}
+			if db != nil {
+				t.Errorf("db = %v, want nil", db)
+			}
+			if strings.Contains(err.Error(), cfg.Password) {
+				t.Errorf("error contains the password: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/controllers"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/middlewares"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"github.com/gin-gonic/gin"
+)
+
+// TestQualityStatsAuthorization はクラスのメンバーのみ接続品質の統計を送信でき、
+// 講師(管理者とアシスタント)以外はルームの統計を取得できないことを確認するテストです。
+func TestQualityStatsAuthorization(t *testing.T) {
+	service := services.NewLiveClassService(&speakRoleClassUserRepo{roles: speakRoles}, nil, nil)
+	ctx := context.Background()
+	report := dto.ConnectionQualityReportDTO{LatencyMs: 120}
+
+	tests := []struct {
+		name string
+		call func() error
+		want error
+	}{
+		{"生徒は送信できる", func() error {
+			return service.ReportQualityStats(ctx, 3, 1, report)
+		}, nil},
+		{"講師は送信できる", func() error {
+			return service.ReportQualityStats(ctx, 1, 1, report)
+		}, nil},
+		{"申請者は送信できない", func() error {
+			return service.ReportQualityStats(ctx, 5, 1, report)
+		}, services.ErrUnauthorized},
+		{"メンバー以外は送信できない", func() error {
+			return service.ReportQualityStats(ctx, 9, 1, report)
+		}, services.ErrUnauthorized},
+		{"講師は取得できる", func() error {
+			_, err := service.GetRoomQualityStats(ctx, 1, 1)
+			return err
+		}, nil},
+		{"アシスタントは取得できる", func() error {
+			_, err := service.GetRoomQualityStats(ctx, 2, 1)
+			return err
+		}, nil},
+		{"生徒は取得できない", func() error {
+			_, err := service.GetRoomQualityStats(ctx, 3, 1)
+			return err
+		}, services.ErrUnauthorized},
+		{"メンバー以外は取得できない", func() error {
+			_, err := service.GetRoomQualityStats(ctx, 9, 1)
+			return err
+		}, services.ErrUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.call(); !errors.Is(err, tt.want) {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+// TestReportQualityStatsReporter はリクエストの本文でuidを指定しても、ログイン中のユーザーの統計として集計し、
+// 講師以外の取得を403で拒否することを確認するテストです。
+func TestReportQualityStatsReporter(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	service := services.NewLiveClassService(&speakRoleClassUserRepo{roles: speakRoles}, nil, nil)
+	controller := controllers.NewLiveClassController(service)
+
+	request := func(method string, userID uint, body string) *httptest.ResponseRecorder {
+		r := gin.New()
+		r.Use(middlewares.GlobalErrorHandler(&recordingErrorReporter{}))
+		r.Use(func(c *gin.Context) { c.Set("userID", userID) })
+		r.POST("/live/quality/:roomID", controller.ReportQualityStats)
+		r.GET("/live/quality/:roomID", controller.GetRoomQualityStats)
+
+		req, _ := http.NewRequest(method, "/live/quality/1", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+		return resp
+	}
+
+	cases := []struct {
+		name       string
+		method     string
+		userID     uint
+		body       string
+		wantStatus int
+	}{
+		{"Student Reports For Another User", http.MethodPost, 3, `{"uid":4,"latency_ms":80}`, http.StatusOK},
+		{"Applicant Reports", http.MethodPost, 5, `{"latency_ms":80}`, http.StatusForbidden},
+		{"Student Reads", http.MethodGet, 3, "", http.StatusForbidden},
+		{"Instructor Reads", http.MethodGet, 1, "", http.StatusOK},
+	}
+	var got []dto.ViewerQualityStatsDTO
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			resp := request(tc.method, tc.userID, tc.body)
+			if resp.Code != tc.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", resp.Code, tc.wantStatus, resp.Body.String())
+			}
+			if tc.method == http.MethodGet && tc.wantStatus == http.StatusOK {
+				var body struct {
+					Data []dto.ViewerQualityStatsDTO `json:"data"`
+				}
+				if err := json.Unmarshal(resp.Body.Bytes(), &body); err != nil {
+					t.Fatalf("body = %s: %v", resp.Body.String(), err)
+				}
+				got = body.Data
+			}
+		})
+	}
+
+	if len(got) != 1 || got[0].UID != 3 || got[0].Samples != 1 || got[0].LastLatencyMs != 80 {
+		t.Errorf("stats = %+v, want one sample from uid 3", got)
+	}
+}
@@ -0,0 +1,158 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"gorm.io/gorm"
+)
+
+// speakRoleClassUserRepo はユーザーごとのロールを返すClassUserRepositoryです。ロールがないユーザーはメンバーではありません。
+type speakRoleClassUserRepo struct {
+	repositories.ClassUserRepository
+	roles map[uint]string
+}
+
+func (r *speakRoleClassUserRepo) GetRole(_ context.Context, uid uint, _ uint) (string, error) {
+	role, ok := r.roles[uid]
+	if !ok {
+		return "", gorm.ErrRecordNotFound
+	}
+	return role, nil
+}
+
+func (r *speakRoleClassUserRepo) GetClassMembers(_ context.Context, _ uint, roles ...string) ([]dto.ClassMemberDTO, error) {
+	var members []dto.ClassMemberDTO
+	for uid, role := range r.roles {
+		for _, want := range roles {
+			if role == want {
+				members = append(members, dto.ClassMemberDTO{Uid: uid, Role: role})
+			}
+		}
+	}
+	return members, nil
+}
+
+// speakRoles は講師(1)、アシスタント(2)、生徒(3, 4)、申請者(5)のロールです。
+var speakRoles = map[uint]string{1: "ADMIN", 2: "ASSISTANT", 3: "USER", 4: "USER", 5: "APPLICANT"}
+
+// TestSpeakPermissionAuthorization は講師以外が発言権を変更できず、メンバー以外は発言権を取得できず、
+// メンバーでない生徒には発言権を付与できないことを確認するテストです。
+func TestSpeakPermissionAuthorization(t *testing.T) {
+	service := services.NewLiveClassService(&speakRoleClassUserRepo{roles: speakRoles}, nil, nil)
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		call func() error
+		want error
+	}{
+		{"生徒は付与できない", func() error {
+			_, err := service.GrantSpeakPermission(ctx, 3, 1, 4)
+			return err
+		}, services.ErrUnauthorized},
+		{"申請者は剥奪できない", func() error {
+			_, err := service.RevokeSpeakPermission(ctx, 5, 1, 3)
+			return err
+		}, services.ErrUnauthorized},
+		{"メンバー以外は剥奪できない", func() error {
+			_, err := service.RevokeSpeakPermission(ctx, 9, 1, 3)
+			return err
+		}, services.ErrUnauthorized},
+		{"申請者は取得できない", func() error {
+			_, err := service.GetSpeakPermissions(ctx, 5, 1)
+			return err
+		}, services.ErrUnauthorized},
+		{"メンバーでない生徒には付与できない", func() error {
+			_, err := service.GrantSpeakPermission(ctx, 1, 1, 9)
+			return err
+		}, services.ErrNotFound},
+		{"申請者には付与できない", func() error {
+			_, err := service.GrantSpeakPermission(ctx, 2, 1, 5)
+			return err
+		}, services.ErrNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.call(); !errors.Is(err, tt.want) {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+// TestSpeakPermission は講師が付与・剥奪した発言権が保存され、変更がクラスを購読中の参加者に配信されることと、
+// 講師には発言権を保存しないことを確認するテストです。
+func TestSpeakPermission(t *testing.T) {
+	redisClient := openTestRedis(t)
+	ctx := context.Background()
+	const cid = 940001
+	key := "live_speakers:940001"
+	redisClient.Del(ctx, key)
+	t.Cleanup(func() {
+		redisClient.Del(ctx, key)
+	})
+
+	repo := &speakRoleClassUserRepo{roles: speakRoles}
+	hub := services.NewRealtimeHub(repo, nil, services.RealtimeConfig{})
+	student, err := hub.Connect(ctx, 4)
+	if err != nil {
+		t.Fatalf("err = %v", err)
+	}
+	defer student.Close()
+	if err := student.Subscribe(ctx, cid); err != nil {
+		t.Fatalf("err = %v", err)
+	}
+	service := services.NewLiveClassService(repo, redisClient, hub)
+
+	steps := []struct {
+		name    string
+		call    func() (*dto.SpeakPermissionsDTO, error)
+		uid     uint
+		granted bool
+		want    []uint
+	}{
+		{"講師が付与", func() (*dto.SpeakPermissionsDTO, error) { return service.GrantSpeakPermission(ctx, 1, cid, 4) }, 4, true, []uint{4}},
+		{"アシスタントが付与", func() (*dto.SpeakPermissionsDTO, error) { return service.GrantSpeakPermission(ctx, 2, cid, 3) }, 3, true, []uint{3, 4}},
+		{"講師には保存しない", func() (*dto.SpeakPermissionsDTO, error) { return service.GrantSpeakPermission(ctx, 1, cid, 2) }, 2, true, []uint{3, 4}},
+		{"講師が剥奪", func() (*dto.SpeakPermissionsDTO, error) { return service.RevokeSpeakPermission(ctx, 1, cid, 4) }, 4, false, []uint{3}},
+	}
+	for _, step := range steps {
+		permissions, err := step.call()
+		if err != nil {
+			t.Fatalf("%s: err = %v", step.name, err)
+		}
+		if !reflect.DeepEqual(permissions.Speakers, step.want) {
+			t.Errorf("%s: speakers = %v, want %v", step.name, permissions.Speakers, step.want)
+		}
+
+		events := student.Drain()
+		if len(events) != 1 || events[0].Type != dto.RealtimeSpeakPermission {
+			t.Fatalf("%s: events = %+v, want 1 %s event", step.name, events, dto.RealtimeSpeakPermission)
+		}
+		var changed dto.SpeakPermissionChangedDTO
+		if err := json.Unmarshal(events[0].Data, &changed); err != nil {
+			t.Fatalf("%s: err = %v", step.name, err)
+		}
+		if changed.UID != step.uid || changed.Granted != step.granted || !reflect.DeepEqual(changed.Speakers, step.want) {
+			t.Errorf("%s: event = %+v", step.name, changed)
+		}
+	}
+
+	permissions, err := service.GetSpeakPermissions(ctx, 3, cid)
+	if err != nil {
+		t.Fatalf("err = %v", err)
+	}
+	if !reflect.DeepEqual(permissions.Speakers, []uint{3}) {
+		t.Errorf("speakers = %v, want [3]", permissions.Speakers)
+	}
+	if ttl := redisClient.TTL(ctx, key).Val(); ttl <= 0 {
+		t.Errorf("ttl = %v, want positive", ttl)
+	}
+}
@@ -0,0 +1,389 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/utils"
+	"github.com/go-redis/redis/v8"
+)
+
+// capturingMailer は最初のfailures回の送信をerrで失敗させ、送信したメールをsentに流すMailerです。
+type capturingMailer struct {
+	mu       sync.Mutex
+	failures int
+	err      error
+	attempts int
+	sent     chan utils.Mail
+}
+
+func newCapturingMailer(failures int, err error) *capturingMailer {
+	return &capturingMailer{failures: failures, err: err, sent: make(chan utils.Mail, 10)}
+}
+
+func (m *capturingMailer) Send(_ context.Context, mail utils.Mail) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.attempts++
+	if m.attempts <= m.failures {
+		return m.err
+	}
+	m.sent <- mail
+	return nil
+}
+
+// receive は送信されたメールを待って返します。時間内に送信されない場合はfalseを返します。
+func (m *capturingMailer) receive(timeout time.Duration) (utils.Mail, bool) {
+	select {
+	case mail := <-m.sent:
+		return mail, true
+	case <-time.After(timeout):
+		return utils.Mail{}, false
+	}
+}
+
+// TestMailServiceLocale は宛先の言語のテンプレートでテキストとHTMLのメールを作成し、
+// テンプレートがない言語は日本語にし、HTMLではクラス名をエスケープすることを確認するテストです。
+func TestMailServiceLocale(t *testing.T) {
+	cases := []struct {
+		name        string
+		locale      string
+		wantSubject string
+	}{
+		{"Japanese", "ja", "「<数学>」への参加が承認されました"},
+		{"English Region", "en-GB", `Your request to join "<数学>" was approved`},
+		{"Unknown Locale", "fr", "「<数学>」への参加が承認されました"},
+		{"Empty Locale", "", "「<数学>」への参加が承認されました"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mailer := newCapturingMailer(0, nil)
+			service := services.NewMailService(services.MailConfig{Mailer: mailer})
+			err := service.Send(context.Background(), services.MailRequest{
+				To:       "taro@example.com",
+				Locale:   tc.locale,
+				Template: services.ApplicationApprovedMail,
+				Data:     services.MailData{Name: "山田", ClassName: "<数学>"},
+			})
+			if err != nil {
+				t.Fatalf("err = %v", err)
+			}
+
+			mail, ok := mailer.receive(time.Second)
+			if !ok {
+				t.Fatal("mail was not sent")
+			}
+			if mail.To != "taro@example.com" || mail.Subject != tc.wantSubject {
+				t.Errorf("to = %q, subject = %q, want %q", mail.To, mail.Subject, tc.wantSubject)
+			}
+			if !strings.Contains(mail.Text, "<数学>") {
+				t.Errorf("text = %q, want the class name", mail.Text)
+			}
+			if !strings.Contains(mail.HTML, "&lt;数学&gt;") || strings.Contains(mail.HTML, "<数学>") {
+				t.Errorf("html = %q, want the escaped class name", mail.HTML)
+			}
+		})
+	}
+}
+
+// TestMailServiceUnknownTemplate はテンプレートがない種類のメールの送信を予約しないことを確認するテストです。
+func TestMailServiceUnknownTemplate(t *testing.T) {
+	service := services.NewMailService(services.MailConfig{Mailer: newCapturingMailer(0, nil)})
+	err := service.Send(context.Background(), services.MailRequest{To: "taro@example.com", Template: "password_reset"})
+	if !errors.Is(err, services.ErrUnknownMailTemplate) {
+		t.Errorf("err = %v, want %v", err, services.ErrUnknownMailTemplate)
+	}
+}
+
+// TestMailServiceRetry は一時的なエラーで送信できなかったメールを再送し、上限の回数で諦め、
+// 宛先が無効なメールは再送しないことを確認するテストです。
+func TestMailServiceRetry(t *testing.T) {
+	temporary := errors.New("connection reset")
+	cases := []struct {
+		name         string
+		failures     int
+		err          error
+		wantSent     bool
+		wantAttempts int
+	}{
+		{"Recovered", 2, temporary, true, 3},
+		{"Gave Up", 3, temporary, false, 3},
+		{"Invalid Address", 1, utils.ErrInvalidNotificationTarget, false, 1},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mailer := newCapturingMailer(tc.failures, tc.err)
+			service := services.NewMailService(services.MailConfig{Mailer: mailer, RetryDelay: time.Millisecond})
+			request := services.MailRequest{To: "taro@example.com", Template: services.ApplicationApprovedMail}
+			if err := service.Send(context.Background(), request); err != nil {
+				t.Fatalf("err = %v", err)
+			}
+
+			_, sent := mailer.receive(200 * time.Millisecond)
+			if sent != tc.wantSent {
+				t.Fatalf("sent = %v, want %v", sent, tc.wantSent)
+			}
+			mailer.mu.Lock()
+			defer mailer.mu.Unlock()
+			if mailer.attempts != tc.wantAttempts {
+				t.Errorf("attempts = %d, want %d", mailer.attempts, tc.wantAttempts)
+			}
+		})
+	}
+}
+
+// recordingMailService は送信を予約されたメールを記録するMailServiceです。
+type recordingMailService struct {
+	requests []services.MailRequest
+}
+
+func (s *recordingMailService) Send(_ context.Context, request services.MailRequest) error {
+	s.requests = append(s.requests, request)
+	return nil
+}
+
+// approvalUserRepo は1件の参加申請を持つUserRepositoryです。
+type approvalUserRepo struct {
+	repositories.UserRepository
+	application models.ClassUser
+}
+
+func (r *approvalUserRepo) GetApplyingClasses(context.Context, uint) ([]models.ClassUser, error) {
+	return []models.ClassUser{r.application}, nil
+}
+
+// TestApplicationApprovedMail は申請中のユーザーを生徒にした場合のみ、ユーザーの言語で承認のメールを送信し、
+// 未検証のメールアドレスには送信しないことを確認するテストです。
+func TestApplicationApprovedMail(t *testing.T) {
+	// ロールの変更イベントは配信できなくてもよい
+	redisClient := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", DialTimeout: 50 * time.Millisecond, MaxRetries: -1})
+	cases := []struct {
+		name     string
+		oldRole  string
+		newRole  string
+		email    string
+		verified bool
+		wantSent bool
+	}{
+		{"Approved", "APPLICANT", "USER", "taro@example.com", true, true},
+		{"Rejected", "APPLICANT", "BLACKLIST", "taro@example.com", true, false},
+		{"Promoted", "USER", "ASSISTANT", "taro@example.com", true, false},
+		{"No Email", "APPLICANT", "USER", "", false, false},
+		{"Unverified Email", "APPLICANT", "USER", "taro@example.com", false, false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mail := &recordingMailService{}
+			userRepo := &approvalUserRepo{application: models.ClassUser{
+				CID: 10, UID: 2, Nickname: "山田", Role: "APPLICANT",
+				Class: models.Class{ID: 10, Name: "数学"},
+				User:  models.User{ID: 2, Email: tc.email, EmailVerified: tc.verified, Locale: "en"},
+			}}
+			service := services.NewClassUserService(nil, &roleChangeClassUserRepo{role: tc.oldRole}, nil, nil, nil, userRepo, redisClient, 0, mail, nil, nil)
+
+			if err := service.AssignRole(context.Background(), 2, 10, tc.newRole); err != nil {
+				t.Fatalf("err = %v", err)
+			}
+			if sent := len(mail.requests) == 1; sent != tc.wantSent {
+				t.Fatalf("requests = %+v, want sent %v", mail.requests, tc.wantSent)
+			}
+			if tc.wantSent {
+				want := services.MailRequest{
+					To: tc.email, Locale: "en", Template: services.ApplicationApprovedMail,
+					Data: services.MailData{Name: "山田", ClassName: "数学"},
+				}
+				if !reflect.DeepEqual(mail.requests[0], want) {
+					t.Errorf("request = %+v, want %+v", mail.requests[0], want)
+				}
+			}
+		})
+	}
+}
+
+// invitationClassUserRepo はmembersのユーザーをクラスのメンバーとするClassUserRepositoryです。
+type invitationClassUserRepo struct {
+	adminClassUserRepo
+	members []uint
+}
+
+func (r *invitationClassUserRepo) FindMembersByUIDs(_ context.Context, cid uint, uids []uint) ([]models.ClassUser, error) {
+	var members []models.ClassUser
+	for _, uid := range uids {
+		for _, member := range r.members {
+			if uid == member {
+				members = append(members, models.ClassUser{CID: cid, UID: uid})
+			}
+		}
+	}
+	return members, nil
+}
+
+// invitationUserRepo はusersのユーザーを返すUserRepositoryです。
+type invitationUserRepo struct {
+	repositories.UserRepository
+	users []models.User
+}
+
+func (r *invitationUserRepo) FindByID(_ context.Context, uid uint) (*models.User, error) {
+	for _, user := range r.users {
+		if user.ID == uid {
+			return &user, nil
+		}
+	}
+	return nil, errors.New("user not found")
+}
+
+func (r *invitationUserRepo) FindByEmails(_ context.Context, emails []string) ([]models.User, error) {
+	var users []models.User
+	for _, user := range r.users {
+		for _, email := range emails {
+			if strings.EqualFold(user.Email, email) {
+				users = append(users, user)
+			}
+		}
+	}
+	return users, nil
+}
+
+// newInvitationService はユーザー1が管理者で、ユーザー3がメンバーのクラス「数学」の招待を行うClassInvitationServiceを生成します。
+func newInvitationService(admin bool, redisClient *redis.Client, mail services.MailService) services.ClassInvitationService {
+	users := []models.User{
+		{ID: 1, Name: "佐藤", Email: "sato@example.com"},
+		{ID: 2, Name: "Taro", Email: "taro@example.com", Locale: "en-US"},
+		{ID: 3, Name: "鈴木", Email: "suzuki@example.com", Locale: "ja"},
+	}
+	return services.NewClassInvitationService(
+		&flyerClassRepo{class: &models.Class{ID: 1, Name: "数学"}},
+		&flyerClassCodeRepo{code: &models.ClassCode{CID: 1, Code: "ABC123"}},
+		&invitationClassUserRepo{adminClassUserRepo: adminClassUserRepo{admin: admin}, members: []uint{3}},
+		&invitationUserRepo{users: users},
+		mail,
+		redisClient,
+		"https://minori.example.com/join",
+	)
+}
+
+// TestInviteByEmail は重複したアドレスにまとめて送信し、既にメンバーのユーザーには送信せず、
+// アカウントがあるアドレスはユーザーの言語で、ないアドレスはリクエストの言語で送信することを確認するテストです。
+func TestInviteByEmail(t *testing.T) {
+	mail := &recordingMailService{}
+	request := dto.ClassInvitationRequest{
+		Emails: []string{"Taro@example.com", "suzuki@example.com", "new@example.com", " taro@EXAMPLE.com "},
+		Locale: "ja",
+	}
+
+	result, err := newInvitationService(true, nil, mail).InviteByEmail(context.Background(), 1, 1, request)
+	if err != nil {
+		t.Fatalf("err = %v", err)
+	}
+	if fmt.Sprint(result.Invited) != "[Taro@example.com new@example.com]" || fmt.Sprint(result.Skipped) != "[suzuki@example.com]" {
+		t.Errorf("invited = %v, skipped = %v", result.Invited, result.Skipped)
+	}
+	if result.Remaining != services.ClassInvitationDailyLimit-2 {
+		t.Errorf("remaining = %d, want %d", result.Remaining, services.ClassInvitationDailyLimit-2)
+	}
+
+	data := services.MailData{ClassName: "数学", InviterName: "佐藤", JoinCode: "ABC123", JoinURL: "https://minori.example.com/join?code=ABC123"}
+	taro := data
+	taro.Name = "Taro"
+	want := []services.MailRequest{
+		{To: "Taro@example.com", Locale: "en-US", Template: services.ClassInvitationMail, Data: taro},
+		{To: "new@example.com", Locale: "ja", Template: services.ClassInvitationMail, Data: data},
+	}
+	if fmt.Sprint(mail.requests) != fmt.Sprint(want) {
+		t.Errorf("requests = %+v, want %+v", mail.requests, want)
+	}
+}
+
+// TestInviteByEmailUnauthorized はクラスの管理者以外は招待メールを送信できないことを確認するテストです。
+func TestInviteByEmailUnauthorized(t *testing.T) {
+	mail := &recordingMailService{}
+	request := dto.ClassInvitationRequest{Emails: []string{"new@example.com"}}
+
+	if _, err := newInvitationService(false, nil, mail).InviteByEmail(context.Background(), 2, 1, request); !errors.Is(err, services.ErrUnauthorized) {
+		t.Fatalf("err = %v, want %v", err, services.ErrUnauthorized)
+	}
+	if len(mail.requests) != 0 {
+		t.Errorf("requests = %+v, want none", mail.requests)
+	}
+}
+
+// TestInviteByEmailLimit は招待したユーザーの1日の送信数の上限を超える招待を1件も送信せず、上限までの招待は送信し、
+// 上限に達したユーザーは他のクラスからも送信できないことを確認するテストです。
+func TestInviteByEmailLimit(t *testing.T) {
+	redisClient := openTestRedis(t)
+	ctx := context.Background()
+	const key = "class_invitations:user:1"
+	redisClient.Del(ctx, key)
+	t.Cleanup(func() {
+		redisClient.Del(ctx, key)
+	})
+	redisClient.Set(ctx, key, services.ClassInvitationDailyLimit-2, time.Hour)
+
+	mail := &recordingMailService{}
+	service := newInvitationService(true, redisClient, mail)
+	over := dto.ClassInvitationRequest{Emails: []string{"a@example.com", "b@example.com", "c@example.com"}}
+	if _, err := service.InviteByEmail(ctx, 1, 1, over); !errors.Is(err, services.ErrInvitationLimit) {
+		t.Fatalf("err = %v, want %v", err, services.ErrInvitationLimit)
+	}
+	if len(mail.requests) != 0 {
+		t.Fatalf("requests = %+v, want none", mail.requests)
+	}
+
+	within := dto.ClassInvitationRequest{Emails: []string{"a@example.com", "b@example.com"}}
+	result, err := service.InviteByEmail(ctx, 1, 1, within)
+	if err != nil {
+		t.Fatalf("err = %v", err)
+	}
+	if len(mail.requests) != 2 || result.Remaining != 0 {
+		t.Errorf("requests = %d, remaining = %d, want 2 and 0", len(mail.requests), result.Remaining)
+	}
+
+	other := dto.ClassInvitationRequest{Emails: []string{"d@example.com"}}
+	if _, err := service.InviteByEmail(ctx, 1, 2, other); !errors.Is(err, services.ErrInvitationLimit) {
+		t.Errorf("other class err = %v, want %v", err, services.ErrInvitationLimit)
+	}
+}
+
+// TestLogMailer は開発用のMailerが宛先とテンプレートの名前のみをログに出力し、件名と本文を出力しないことを確認するテストです。
+func TestLogMailer(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	cases := []struct {
+		name string
+		mail utils.Mail
+		want string
+	}{
+		{"Template", utils.Mail{To: "a@example.com", Subject: "メールアドレスの確認", Text: "https://minori.example.com/verify?token=secret", Template: "email_verification"}, "Mail to a@example.com (template: email_verification)"},
+		{"No Template", utils.Mail{To: "b@example.com", Subject: "休講", Text: "secret"}, "Mail to b@example.com (template: none)"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			buf.Reset()
+			if err := utils.NewLogMailer().Send(context.Background(), tc.mail); err != nil {
+				t.Fatalf("err = %v", err)
+			}
+			if got := buf.String(); !strings.Contains(got, tc.want) || strings.Contains(got, "secret") || strings.Contains(got, tc.mail.Subject) {
+				t.Errorf("log = %q, want only %q", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/middlewares"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"github.com/gin-gonic/gin"
+)
+
+// staticMaintenanceService は固定の状態を返すMaintenanceServiceです。
+type staticMaintenanceService struct {
+	services.MaintenanceService
+	state services.MaintenanceState
+}
+
+func (s *staticMaintenanceService) State() services.MaintenanceState {
+	return s.state
+}
+
+// TestMaintenanceMiddleware はread_onlyでは更新系のみ、fullでは除外したパス以外を503とRetry-Afterで拒否することを確認するテストです。
+func TestMaintenanceMiddleware(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	until := time.Now().Add(330*time.Second + 500*time.Millisecond)
+
+	cases := []struct {
+		name           string
+		state          services.MaintenanceState
+		method         string
+		path           string
+		wantStatus     int
+		wantRetryAfter string
+	}{
+		{"Off", services.MaintenanceState{Mode: services.MaintenanceOff}, http.MethodPost, "/api/gin/cl/create", http.StatusOK, ""},
+		{"Read Only Allows Reads", services.MaintenanceState{Mode: services.MaintenanceReadOnly}, http.MethodGet, "/api/gin/cl/create", http.StatusOK, ""},
+		{"Read Only Rejects Writes", services.MaintenanceState{Mode: services.MaintenanceReadOnly}, http.MethodPost, "/api/gin/cl/create", http.StatusServiceUnavailable, "60"},
+		{"Read Only Uses Deadline", services.MaintenanceState{Mode: services.MaintenanceReadOnly, Until: &until}, http.MethodDelete, "/api/gin/cl/create", http.StatusServiceUnavailable, "330"},
+		{"Full Rejects Reads", services.MaintenanceState{Mode: services.MaintenanceFull}, http.MethodGet, "/api/gin/cl/create", http.StatusServiceUnavailable, "60"},
+		{"Full Allows Token Refresh", services.MaintenanceState{Mode: services.MaintenanceFull}, http.MethodPost, "/api/gin/auth/google/refresh-token", http.StatusOK, ""},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := gin.New()
+			r.Use(middlewares.MaintenanceMiddleware(&staticMaintenanceService{state: tc.state}, "/api/gin/auth/google/refresh-token"))
+			r.Handle(tc.method, tc.path, func(c *gin.Context) { c.Status(http.StatusOK) })
+
+			req, _ := http.NewRequest(tc.method, tc.path, nil)
+			resp := httptest.NewRecorder()
+			r.ServeHTTP(resp, req)
+
+			if resp.Code != tc.wantStatus {
+				t.Fatalf("status = %d, want %d", resp.Code, tc.wantStatus)
+			}
+			if got := resp.Header().Get("Retry-After"); got != tc.wantRetryAfter {
+				t.Errorf("Retry-After = %q, want %q", got, tc.wantRetryAfter)
+			}
+		})
+	}
+}
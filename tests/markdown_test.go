@@ -0,0 +1,53 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/utils"
+)
+
+// TestRenderMarkdown はチャットのMarkdownを決まったタグのHTMLに変換し、
+// 対応する言語のコードブロックをclassで色分けし、メッセージに含まれるHTMLや危険なリンクを無害化することを確認するテストです。
+func TestRenderMarkdown(t *testing.T) {
+	cases := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"Plain", "こんにちは", "<p>こんにちは</p>"},
+		{"Line Breaks", "1行目\n2行目\n\n次の段落", "<p>1行目<br>2行目</p><p>次の段落</p>"},
+		{"Emphasis", "**太字** と *斜体* と ~~取り消し~~", "<p><strong>太字</strong> と <em>斜体</em> と <del>取り消し</del></p>"},
+		{"Inline Code", "`a *b* <c>` を使う", "<p><code>a *b* &lt;c&gt;</code> を使う</p>"},
+		{"Code Block", "```Go\nfunc main() {\n\tfmt.Println(\"<hi>\")\n}\n```",
+			"<pre class=\"chroma\"><code class=\"language-go\"><span class=\"kd\">func</span> <span class=\"nf\">main</span><span class=\"p\">()</span> <span class=\"p\">{</span>\n" +
+				"\t<span class=\"nx\">fmt</span><span class=\"p\">.</span><span class=\"nf\">Println</span><span class=\"p\">(</span><span class=\"s\">&#34;&lt;hi&gt;&#34;</span><span class=\"p\">)</span>\n" +
+				"<span class=\"p\">}</span></code></pre>"},
+		{"Code Block With HTML", "```html\n<script>alert(1)</script>\n```",
+			"<pre class=\"chroma\"><code class=\"language-html\"><span class=\"p\">&lt;</span><span class=\"nt\">script</span><span class=\"p\">&gt;</span>" +
+				"<span class=\"nx\">alert</span><span class=\"p\">(</span><span class=\"mi\">1</span><span class=\"p\">)&lt;/</span><span class=\"nt\">script</span><span class=\"p\">&gt;</span></code></pre>"},
+		{"Code Block Unknown Language", "```nosuchlang\n<b>x</b>\n```", "<pre><code class=\"language-nosuchlang\">&lt;b&gt;x&lt;/b&gt;</code></pre>"},
+		{"Code Block Too Large", "```go\n" + strings.Repeat("x", 20001) + "\n```", "<pre><code class=\"language-go\">" + strings.Repeat("x", 20001) + "</code></pre>"},
+		{"Code Block Without Language", "```\n**x**\n```", "<pre><code>**x**</code></pre>"},
+		{"Code Block Invalid Language", "```go\"onclick=alert(1)\nx\n```", "<pre><code>x</code></pre>"},
+		{"Unclosed Code Block", "```python\nprint(1)",
+			"<pre class=\"chroma\"><code class=\"language-python\"><span class=\"nb\">print</span><span class=\"p\">(</span><span class=\"mi\">1</span><span class=\"p\">)</span></code></pre>"},
+		{"Quote", "> 引用\n> **続き**\n本文", "<blockquote>引用<br><strong>続き</strong></blockquote><p>本文</p>"},
+		{"Link", "[資料](https://example.com/a?b=1&c=2)",
+			"<p><a href=\"https://example.com/a?b=1&amp;c=2\" rel=\"nofollow noopener noreferrer\" target=\"_blank\">資料</a></p>"},
+		{"Script Tag", "<script>alert(1)</script>", "<p>&lt;script&gt;alert(1)&lt;/script&gt;</p>"},
+		{"Javascript Link", "[x](javascript:alert(1))", "<p>[x](javascript:alert(1))</p>"},
+		{"Data Link", "[x](data:text/html,hi)", "<p>[x](data:text/html,hi)</p>"},
+		{"Attribute Injection", "[x](https://example.com/\"onmouseover=\"alert)",
+			"<p><a href=\"https://example.com/%22onmouseover=%22alert\" rel=\"nofollow noopener noreferrer\" target=\"_blank\">x</a></p>"},
+		{"Emphasis With HTML", "**<img src=x onerror=alert(1)>**", "<p><strong>&lt;img src=x onerror=alert(1)&gt;</strong></p>"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := utils.RenderMarkdown(tc.text); got != tc.want {
+				t.Errorf("RenderMarkdown(%q) =\n%s\nwant\n%s", tc.text, got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/migration"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+)
+
+// TestMigrateCreatesRepositoryTables はマイグレーション後に全てのテーブルが揃い、各リポジトリの基本的な読み書きができることを確認するテストです。
+func TestMigrateCreatesRepositoryTables(t *testing.T) {
+	db := openTestDB(t)
+	migration.Migrate(db)
+	if err := migration.CheckTables(db); err != nil {
+		t.Fatalf("CheckTables() = %v", err)
+	}
+
+	tx := db.Begin()
+	if tx.Error != nil {
+		t.Fatalf("failed to begin transaction: %v", tx.Error)
+	}
+	t.Cleanup(func() { tx.Rollback() })
+	ctx := context.Background()
+
+	user := &models.User{Name: "山田", Image: "https://example.com/u.png", PID: "migration-test"}
+	if err := tx.Create(user).Error; err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	if _, err := repositories.NewUserRepository(tx).FindByID(ctx, user.ID); err != nil {
+		t.Fatalf("users: %v", err)
+	}
+
+	class := &models.Class{Name: "数学", UID: user.ID}
+	classRepo := repositories.NewClassRepository(tx)
+	if err := classRepo.Create(ctx, class); err != nil {
+		t.Fatalf("classes: %v", err)
+	}
+	if _, err := classRepo.GetByID(ctx, class.ID); err != nil {
+		t.Fatalf("classes: %v", err)
+	}
+
+	classUserRepo := repositories.NewClassUserRepository(tx)
+	if err := classUserRepo.Save(ctx, &models.ClassUser{CID: class.ID, UID: user.ID, Nickname: "山田", Role: "ADMIN"}); err != nil {
+		t.Fatalf("class_users: %v", err)
+	}
+	if role, err := classUserRepo.GetRole(ctx, user.ID, class.ID); err != nil || role != "ADMIN" {
+		t.Fatalf("class_users: role = %q, err = %v", role, err)
+	}
+
+	codeRepo := repositories.NewClassCodeRepository(tx)
+	if err := codeRepo.SaveClassCode(ctx, &models.ClassCode{Code: "ABC123", CID: class.ID, UID: user.ID}); err != nil {
+		t.Fatalf("class_codes: %v", err)
+	}
+	if _, err := codeRepo.FindByClassID(ctx, class.ID); err != nil {
+		t.Fatalf("class_codes: %v", err)
+	}
+
+	boardRepo := repositories.NewClassBoardRepository(tx)
+	board, err := boardRepo.InsertClassBoard(ctx, &models.ClassBoard{Title: "お知らせ", Content: "本文", CID: class.ID, UID: user.ID})
+	if err != nil {
+		t.Fatalf("class_boards: %v", err)
+	}
+	board.Title = "更新"
+	if err := boardRepo.UpdateClassBoard(ctx, board, board.Version); err != nil {
+		t.Fatalf("class_boards: %v", err)
+	}
+	if found, err := boardRepo.FindByID(ctx, board.ID); err != nil || found.Title != "更新" {
+		t.Fatalf("class_boards: board = %+v, err = %v", found, err)
+	}
+	if err := boardRepo.DeleteClassBoard(ctx, board.ID); err != nil {
+		t.Fatalf("class_boards: %v", err)
+	}
+
+	scheduleRepo := repositories.NewClassScheduleRepository(tx)
+	schedule := &models.ClassSchedule{Title: "第1回", StartedAt: time.Now(), EndedAt: time.Now().Add(time.Hour), CID: class.ID}
+	if err := scheduleRepo.CreateClassSchedule(ctx, schedule); err != nil {
+		t.Fatalf("class_schedules: %v", err)
+	}
+	schedule.Title = "第1回 (変更)"
+	if err := scheduleRepo.UpdateClassSchedule(ctx, schedule, schedule.Version); err != nil {
+		t.Fatalf("class_schedules: %v", err)
+	}
+	if _, err := scheduleRepo.GetClassScheduleByID(ctx, schedule.ID); err != nil {
+		t.Fatalf("class_schedules: %v", err)
+	}
+
+	attendanceRepo := repositories.NewAttendanceRepository(tx)
+	if err := attendanceRepo.CreateAttendance(ctx, &models.Attendance{CID: class.ID, UID: user.ID, CSID: schedule.ID, IsAttendance: models.AttendanceStatus}); err != nil {
+		t.Fatalf("attendances: %v", err)
+	}
+	if attendances, _, err := attendanceRepo.GetAllAttendancesByCID(ctx, class.ID, dto.AttendanceListFilter{}, 20, 0); err != nil || len(attendances) != 1 {
+		t.Fatalf("attendances: %d rows, err = %v", len(attendances), err)
+	}
+	if attendances, total, err := attendanceRepo.GetAllAttendancesByCID(ctx, class.ID, dto.AttendanceListFilter{Status: models.AbsenceStatus, CSID: schedule.ID}, 20, 0); err != nil || len(attendances) != 0 || total != 0 {
+		t.Fatalf("attendances: filtered %d rows of %d, err = %v", len(attendances), total, err)
+	}
+
+	stickerRepo := repositories.NewChatStickerRepository(tx)
+	sticker := &models.ChatSticker{Name: "いいね", ImageURL: "https://example.com/s.png", CID: class.ID, UID: user.ID}
+	if err := stickerRepo.InsertChatSticker(ctx, sticker); err != nil {
+		t.Fatalf("chat_stickers: %v", err)
+	}
+	if _, err := stickerRepo.FindByID(ctx, sticker.ID); err != nil {
+		t.Fatalf("chat_stickers: %v", err)
+	}
+
+	auditRepo := repositories.NewAuditLogRepository(tx)
+	if err := auditRepo.CreateAuditLogs(ctx, []models.AuditLog{{ActorUID: user.ID, Method: "POST", Route: "/api/gin/cl/create", CID: &class.ID, StatusCode: 201}}); err != nil {
+		t.Fatalf("audit_logs: %v", err)
+	}
+	if logs, err := auditRepo.FindAuditLogsByClass(ctx, class.ID, time.Now().Add(-time.Hour), time.Now().Add(time.Hour), 1, 10); err != nil || len(logs) != 1 {
+		t.Fatalf("audit_logs: %d rows, err = %v", len(logs), err)
+	}
+
+	if err := scheduleRepo.DeleteClassSchedule(ctx, schedule.ID); err != nil {
+		t.Fatalf("class_schedules: %v", err)
+	}
+}
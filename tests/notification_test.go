@@ -0,0 +1,141 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/migration"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"gorm.io/gorm"
+)
+
+// recordingNotificationRepo は保存した通知を記録し、ID 1の通知のみ既読にできるNotificationRepositoryです。
+type recordingNotificationRepo struct {
+	repositories.NotificationRepository
+	created []models.Notification
+}
+
+func (r *recordingNotificationRepo) CreateNotification(_ context.Context, notification *models.Notification) error {
+	notification.ID = uint(len(r.created) + 1)
+	r.created = append(r.created, *notification)
+	return nil
+}
+
+func (r *recordingNotificationRepo) MarkNotificationRead(_ context.Context, _ uint, id uint, _ time.Time) error {
+	if id != 1 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// TestInAppNotifier はNotifierで送信した通知を、IDと既読の日時を引き継がずにクラスのアプリ内通知として保存することを確認するテストです。
+func TestInAppNotifier(t *testing.T) {
+	repo := &recordingNotificationRepo{}
+	notifier := services.NewInAppNotifier(services.NewNotificationService(repo, nil, nil, services.PushConfig{}, nil))
+
+	if err := notifier.Notify(context.Background(), 11, 3, "休講のお知らせ", "第3回は休講です"); err != nil {
+		t.Fatalf("err = %v", err)
+	}
+	if len(repo.created) != 1 {
+		t.Fatalf("created %d notifications, want 1", len(repo.created))
+	}
+	got := repo.created[0]
+	if got.UID != 11 || got.Type != models.ClassNotification || got.Title != "休講のお知らせ" || got.CID == nil || *got.CID != 3 {
+		t.Errorf("notification = %+v", got)
+	}
+}
+
+// TestMarkNotificationRead は存在しない通知と他のユーザーの通知の既読をErrNotFoundにすることを確認するテストです。
+func TestMarkNotificationRead(t *testing.T) {
+	cases := []struct {
+		name    string
+		id      uint
+		wantErr error
+	}{
+		{"Own Notification", 1, nil},
+		{"Not Found", 2, services.ErrNotFound},
+	}
+
+	service := services.NewNotificationService(&recordingNotificationRepo{}, nil, nil, services.PushConfig{}, nil)
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := service.MarkRead(context.Background(), 11, tc.id); !errors.Is(err, tc.wantErr) {
+				t.Errorf("err = %v, want %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+// TestNotificationRepository はユーザーの通知のみをIDの降順で取得し、既読にした通知を未読件数に含めず、
+// 保存期間を過ぎた既読の通知のみ削除することを確認するテストです。
+func TestNotificationRepository(t *testing.T) {
+	db := openTestDB(t)
+	migration.Migrate(db)
+	ctx := context.Background()
+
+	var users []*models.User
+	for i := 0; i < 2; i++ {
+		user := &models.User{Name: "山田", PID: fmt.Sprintf("notification-%d-%d", i, time.Now().UnixNano())}
+		if err := db.Create(user).Error; err != nil {
+			t.Fatalf("failed to create user: %v", err)
+		}
+		users = append(users, user)
+	}
+	t.Cleanup(func() {
+		for _, user := range users {
+			db.Where("uid = ?", user.ID).Delete(&models.Notification{})
+			db.Delete(user)
+		}
+	})
+
+	repo := repositories.NewNotificationRepository(db)
+	uid := users[0].ID
+	for _, notification := range []models.Notification{
+		{UID: uid, Type: models.ClassNotification, Title: "1"},
+		{UID: uid, Type: models.ClassNotification, Title: "2"},
+		{UID: uid, Type: models.ClassNotification, Title: "3"},
+		{UID: users[1].ID, Type: models.ClassNotification, Title: "other"},
+	} {
+		if err := repo.CreateNotification(ctx, &notification); err != nil {
+			t.Fatalf("failed to create notification: %v", err)
+		}
+	}
+
+	notifications, err := repo.FindNotificationsByUser(ctx, uid, false, 0, 2)
+	if err != nil || len(notifications) != 2 || notifications[0].Title != "3" || notifications[1].Title != "2" {
+		t.Fatalf("notifications = %+v, err = %v, want 3 and 2", notifications, err)
+	}
+	older, err := repo.FindNotificationsByUser(ctx, uid, false, notifications[1].ID, 2)
+	if err != nil || len(older) != 1 || older[0].Title != "1" {
+		t.Fatalf("older = %+v, err = %v, want 1", older, err)
+	}
+
+	if err := repo.MarkNotificationRead(ctx, users[1].ID, older[0].ID, time.Now()); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("other user's read err = %v, want %v", err, gorm.ErrRecordNotFound)
+	}
+	readAt := time.Now().Add(-services.NotificationRetention - time.Hour)
+	if err := repo.MarkNotificationRead(ctx, uid, older[0].ID, readAt); err != nil {
+		t.Fatalf("err = %v", err)
+	}
+	if count, err := repo.CountUnreadNotifications(ctx, uid); err != nil || count != 2 {
+		t.Errorf("unread = %d, err = %v, want 2", count, err)
+	}
+	if unread, err := repo.FindNotificationsByUser(ctx, uid, true, 0, 10); err != nil || len(unread) != 2 {
+		t.Errorf("unread notifications = %+v, err = %v, want 2", unread, err)
+	}
+	if updated, err := repo.MarkAllNotificationsRead(ctx, uid, time.Now()); err != nil || updated != 2 {
+		t.Errorf("updated = %d, err = %v, want 2", updated, err)
+	}
+
+	if _, err := repo.DeleteReadNotificationsBefore(ctx, time.Now().Add(-services.NotificationRetention)); err != nil {
+		t.Fatalf("err = %v", err)
+	}
+	if remaining, err := repo.FindNotificationsByUser(ctx, uid, false, 0, 10); err != nil || len(remaining) != 2 {
+		t.Errorf("remaining = %+v, err = %v, want the 2 recently read notifications", remaining, err)
+	}
+}
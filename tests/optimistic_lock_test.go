@@ -0,0 +1,204 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/migration"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+)
+
+// versionedBoardRepo はバージョン3の掲示板を持ち、異なるバージョンを指定した更新をErrStaleUpdateにするClassBoardRepositoryです。
+type versionedBoardRepo struct {
+	repositories.ClassBoardRepository
+	saved int
+}
+
+func (r *versionedBoardRepo) FindByID(_ context.Context, id uint) (*models.ClassBoard, error) {
+	return &models.ClassBoard{ID: id, Title: "最新", Content: "本文", Version: 3}, nil
+}
+
+func (r *versionedBoardRepo) UpdateClassBoard(_ context.Context, b *models.ClassBoard, expectedVersion uint) error {
+	if expectedVersion != 0 && expectedVersion != 3 {
+		return repositories.ErrStaleUpdate
+	}
+	r.saved++
+	b.Version = 4
+	return nil
+}
+
+// TestUpdateClassBoardVersion は古いバージョンの更新を最新の掲示板を持つStaleUpdateErrorにし、
+// バージョンを指定しない更新は設定で許可した場合のみ受け付けることを確認するテストです。
+func TestUpdateClassBoardVersion(t *testing.T) {
+	cases := []struct {
+		name             string
+		version          uint
+		allowUnversioned bool
+		wantErr          error
+	}{
+		{"Current Version", 3, false, nil},
+		{"Stale Version", 2, true, services.ErrStaleUpdate},
+		{"Unversioned Allowed", 0, true, nil},
+		{"Unversioned Rejected", 0, false, services.ErrVersionRequired},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := &versionedBoardRepo{}
+			service := services.NewClassBoardService(repo, &adminClassUserRepo{}, &recordingUploader{}, nil, nil, nil, tc.allowUnversioned, nil, nil, nil, nil)
+
+			board, err := service.UpdateClassBoard(context.Background(), 1, dto.ClassBoardUpdateDTO{ID: 1, Title: "変更", Version: tc.version}, "")
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tc.wantErr)
+			}
+			if saved := repo.saved == 1; saved != (tc.wantErr == nil) {
+				t.Errorf("saved = %v, want %v", saved, tc.wantErr == nil)
+			}
+			if err == nil && board.Version != 4 {
+				t.Errorf("version = %d, want 4", board.Version)
+			}
+
+			var stale *services.StaleUpdateError
+			if errors.As(err, &stale) {
+				current, ok := stale.Current.(*models.ClassBoard)
+				if !ok || current.Title != "最新" || current.Version != 3 {
+					t.Errorf("current = %+v, want the latest board", stale.Current)
+				}
+			}
+		})
+	}
+}
+
+// TestConcurrentVersionedUpdates は同じバージョンを読み込んだ2つの更新を同時に保存した場合、片方のみ保存されて
+// もう片方がErrStaleUpdateになり、バージョンを指定しない更新は後勝ちで保存されることを確認するテストです。
+func TestConcurrentVersionedUpdates(t *testing.T) {
+	db := openTestDB(t)
+	migration.Migrate(db)
+	ctx := context.Background()
+
+	user := &models.User{Name: "山田", PID: fmt.Sprintf("optimistic-lock-%d", time.Now().UnixNano())}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	class := &models.Class{Name: "数学", UID: user.ID}
+	if err := db.Create(class).Error; err != nil {
+		t.Fatalf("failed to create class: %v", err)
+	}
+	if err := db.Create(&models.ClassUser{CID: class.ID, UID: user.ID, Nickname: "山田", Role: "ADMIN"}).Error; err != nil {
+		t.Fatalf("failed to create class user: %v", err)
+	}
+	board := &models.ClassBoard{Title: "お知らせ", Content: "本文", CID: class.ID, UID: user.ID}
+	schedule := &models.ClassSchedule{Title: "第1回", StartedAt: time.Now(), EndedAt: time.Now().Add(time.Hour), CID: class.ID}
+	for _, record := range []interface{}{board, schedule} {
+		if err := db.Create(record).Error; err != nil {
+			t.Fatalf("failed to create %T: %v", record, err)
+		}
+	}
+	attendance := &models.Attendance{CID: class.ID, UID: user.ID, CSID: schedule.ID, IsAttendance: models.AbsenceStatus}
+	if err := db.Create(attendance).Error; err != nil {
+		t.Fatalf("failed to create attendance: %v", err)
+	}
+	t.Cleanup(func() {
+		db.Delete(attendance)
+		db.Unscoped().Delete(board)
+		db.Unscoped().Delete(schedule)
+		db.Exec("DELETE FROM class_users WHERE cid = ?", class.ID)
+		db.Unscoped().Delete(class)
+		db.Delete(user)
+	})
+
+	boardRepo := repositories.NewClassBoardRepository(db)
+	scheduleRepo := repositories.NewClassScheduleRepository(db)
+	attendanceRepo := repositories.NewAttendanceRepository(db)
+	cases := []struct {
+		name string
+		// update 読み込んだ時点の記録のコピーをi番目の更新として保存する
+		update  func(i int, expectedVersion uint) error
+		version func() (uint, error)
+	}{
+		{
+			"Class Board",
+			func(i int, expectedVersion uint) error {
+				b := *board
+				b.Title = fmt.Sprintf("更新%d", i)
+				return boardRepo.UpdateClassBoard(ctx, &b, expectedVersion)
+			},
+			func() (uint, error) {
+				b, err := boardRepo.FindByID(ctx, board.ID)
+				return b.Version, err
+			},
+		},
+		{
+			"Class Schedule",
+			func(i int, expectedVersion uint) error {
+				s := *schedule
+				s.Title = fmt.Sprintf("第%d回", i)
+				return scheduleRepo.UpdateClassSchedule(ctx, &s, expectedVersion)
+			},
+			func() (uint, error) {
+				s, err := scheduleRepo.GetClassScheduleByID(ctx, schedule.ID)
+				return s.Version, err
+			},
+		},
+		{
+			"Attendance",
+			func(i int, expectedVersion uint) error {
+				a := *attendance
+				a.IsAttendance = []models.AttendanceType{models.AttendanceStatus, models.TardyStatus}[i]
+				return attendanceRepo.UpdateAttendance(ctx, &a, expectedVersion)
+			},
+			func() (uint, error) {
+				a, err := attendanceRepo.GetAttendanceByUIDAndCSID(ctx, user.ID, schedule.ID)
+				return a.Version, err
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			errs := make([]error, 2)
+			var wg sync.WaitGroup
+			for i := range errs {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					errs[i] = tc.update(i, 1)
+				}(i)
+			}
+			wg.Wait()
+
+			saved, stale := 0, 0
+			for _, err := range errs {
+				switch {
+				case err == nil:
+					saved++
+				case errors.Is(err, repositories.ErrStaleUpdate):
+					stale++
+				default:
+					t.Fatalf("err = %v", err)
+				}
+			}
+			if saved != 1 || stale != 1 {
+				t.Fatalf("saved = %d, stale = %d, want 1 and 1", saved, stale)
+			}
+			if version, err := tc.version(); err != nil || version != 2 {
+				t.Fatalf("version = %d, err = %v, want 2", version, err)
+			}
+
+			// バージョンを指定しない更新は古い記録のコピーでも保存される
+			if err := tc.update(0, 0); err != nil {
+				t.Fatalf("unversioned update err = %v", err)
+			}
+			if version, err := tc.version(); err != nil || version != 3 {
+				t.Errorf("version = %d, err = %v, want 3", version, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/controllers"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/middlewares"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"github.com/gin-gonic/gin"
+)
+
+// publicClassService は公開クラスの検索条件を記録するClassServiceです。
+type publicClassService struct {
+	services.ClassService
+	query    string
+	language string
+	page     int
+	limit    int
+}
+
+func (s *publicClassService) GetPublicClasses(_ context.Context, query string, language string, page int, limit int) ([]dto.PublicClassDTO, error) {
+	s.query, s.language, s.page, s.limit = query, language, page, limit
+	return []dto.PublicClassDTO{{ID: 1, Name: "数学", MemberCount: 3, UpcomingScheduleCount: 2}}, nil
+}
+
+// TestGetPublicClasses は認証なしで検索条件をサービスに渡し、不正なページ指定を既定値に戻すことを確認するテストです。
+func TestGetPublicClasses(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+
+	cases := []struct {
+		name         string
+		query        string
+		wantQuery    string
+		wantLanguage string
+		wantPage     int
+		wantLimit    int
+	}{
+		{"Defaults", "", "", "", 1, 20},
+		{"Search", "?q=+math+&language=ja&page=2&limit=10", "math", "ja", 2, 10},
+		{"Invalid Paging", "?page=0&limit=1000", "", "", 1, 20},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			service := &publicClassService{}
+			r := gin.New()
+			r.Use(middlewares.GlobalErrorHandler(&recordingErrorReporter{}))
+			r.GET("/api/gin/cl/public", controllers.NewCreateClassController(service, nil, nil).GetPublicClasses)
+
+			req, _ := http.NewRequest(http.MethodGet, "/api/gin/cl/public"+tc.query, nil)
+			resp := httptest.NewRecorder()
+			r.ServeHTTP(resp, req)
+
+			if resp.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d", resp.Code, http.StatusOK)
+			}
+			if service.query != tc.wantQuery || service.language != tc.wantLanguage || service.page != tc.wantPage || service.limit != tc.wantLimit {
+				t.Errorf("got q=%q language=%q page=%d limit=%d, want q=%q language=%q page=%d limit=%d",
+					service.query, service.language, service.page, service.limit, tc.wantQuery, tc.wantLanguage, tc.wantPage, tc.wantLimit)
+			}
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/utils"
+)
+
+// TestFCMSender はトークンごとにFCM HTTP v1 APIのメッセージを送信し、dry-runでは検証のみを依頼して、
+// エラーのレスポンスを無効なトークン・再送できるトークン・失敗に分けることを確認するテストです。
+func TestFCMSender(t *testing.T) {
+	responses := map[string]struct {
+		status int
+		body   string
+	}{
+		"ok":         {http.StatusOK, `{"name":"projects/p/messages/1"}`},
+		"removed":    {http.StatusNotFound, `{"error":{"status":"NOT_FOUND","details":[{"errorCode":"UNREGISTERED"}]}}`},
+		"mismatch":   {http.StatusForbidden, `{"error":{"status":"PERMISSION_DENIED","details":[{"errorCode":"SENDER_ID_MISMATCH"}]}}`},
+		"overloaded": {http.StatusServiceUnavailable, `{"error":{"status":"UNAVAILABLE"}}`},
+		"malformed":  {http.StatusBadRequest, `{"error":{"status":"INVALID_ARGUMENT","details":[{"errorCode":"INVALID_ARGUMENT"}]}}`},
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			ValidateOnly bool `json:"validate_only"`
+			Message      struct {
+				Token        string            `json:"token"`
+				Notification map[string]string `json:"notification"`
+				Data         map[string]string `json:"data"`
+			} `json:"message"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+		}
+		if !request.ValidateOnly {
+			t.Errorf("validate_only = false, want true in dry-run mode")
+		}
+		if want := map[string]string{"title": "休講", "body": "第3回は休講です"}; !reflect.DeepEqual(request.Message.Notification, want) {
+			t.Errorf("notification = %v, want %v", request.Message.Notification, want)
+		}
+		if request.Message.Data["cid"] != "3" {
+			t.Errorf("data = %v, want cid 3", request.Message.Data)
+		}
+		response := responses[request.Message.Token]
+		w.WriteHeader(response.status)
+		_, _ = w.Write([]byte(response.body))
+	}))
+	defer server.Close()
+
+	sender := utils.NewFCMSender(server.URL, server.Client(), true)
+	result, err := sender.Send(context.Background(), []string{"ok", "removed", "mismatch", "overloaded", "malformed"}, utils.PushMessage{
+		Title: "休講",
+		Body:  "第3回は休講です",
+		Data:  map[string]string{"cid": "3"},
+	})
+	if err != nil {
+		t.Fatalf("err = %v", err)
+	}
+	want := &utils.PushResult{Sent: 1, InvalidTokens: []string{"removed", "mismatch"}, RetryTokens: []string{"overloaded"}, Failed: 1}
+	if !reflect.DeepEqual(result, want) {
+		t.Errorf("result = %+v, want %+v", result, want)
+	}
+}
+
+// fakePushSender は送信したトークンとメッセージを記録するPushSenderです。
+// 最初の送信ではstaleを無効なトークン、flakyを再送が必要なトークンとして返します。
+type fakePushSender struct {
+	calls chan pushCall
+	once  sync.Once
+}
+
+type pushCall struct {
+	tokens  []string
+	message utils.PushMessage
+}
+
+func (s *fakePushSender) Send(_ context.Context, tokens []string, message utils.PushMessage) (*utils.PushResult, error) {
+	result := &utils.PushResult{}
+	first := false
+	s.once.Do(func() { first = true })
+	for _, token := range tokens {
+		switch {
+		case first && token == "stale":
+			result.InvalidTokens = append(result.InvalidTokens, token)
+		case first && token == "flaky":
+			result.RetryTokens = append(result.RetryTokens, token)
+		default:
+			result.Sent++
+		}
+	}
+	s.calls <- pushCall{tokens: tokens, message: message}
+	return result, nil
+}
+
+// pushDeviceTokenRepo はユーザーの端末のトークンとして ok, stale, flaky を返し、削除したトークンを記録するDeviceTokenRepositoryです。
+type pushDeviceTokenRepo struct {
+	repositories.DeviceTokenRepository
+	deleted []string
+}
+
+func (r *pushDeviceTokenRepo) FindDeviceTokensByUser(context.Context, uint) ([]models.DeviceToken, error) {
+	return []models.DeviceToken{{Token: "ok"}, {Token: "stale"}, {Token: "flaky"}}, nil
+}
+
+func (r *pushDeviceTokenRepo) DeleteDeviceTokens(_ context.Context, tokens []string) error {
+	r.deleted = append(r.deleted, tokens...)
+	return nil
+}
+
+// pushNotificationRepo はメンションのプッシュ通知を無効にしたユーザーの設定を返すNotificationRepositoryです。
+type pushNotificationRepo struct {
+	recordingNotificationRepo
+}
+
+func (r *pushNotificationRepo) FindNotificationPreferences(context.Context, uint) ([]models.NotificationPreference, error) {
+	return []models.NotificationPreference{{Type: models.MentionNotification, PushEnabled: false}}, nil
+}
+
+// receivePush は送信されたプッシュ通知を待ち、一定時間内に送信されない場合はテストを失敗にします。
+func receivePush(t *testing.T, calls chan pushCall) pushCall {
+	t.Helper()
+	select {
+	case call := <-calls:
+		return call
+	case <-time.After(2 * time.Second):
+		t.Fatal("push notification was not sent")
+		return pushCall{}
+	}
+}
+
+// TestPushDelivery はユーザーが無効にした種類の通知をプッシュ通知せず、有効な通知はユーザーの全ての端末にまとめて送信し、
+// 無効になったトークンを削除して、一時的なエラーのトークンにのみ再送することを確認するテストです。
+func TestPushDelivery(t *testing.T) {
+	sender := &fakePushSender{calls: make(chan pushCall, 4)}
+	deviceTokens := &pushDeviceTokenRepo{}
+	service := services.NewNotificationService(&pushNotificationRepo{}, deviceTokens, nil, services.PushConfig{Sender: sender, RetryDelay: time.Millisecond}, nil)
+	ctx := context.Background()
+	cid := uint(3)
+
+	if err := service.Publish(ctx, models.Notification{UID: 11, Type: models.MentionNotification, Title: "メンション"}); err != nil {
+		t.Fatalf("err = %v", err)
+	}
+	if err := service.Publish(ctx, models.Notification{UID: 11, Type: models.ScheduleChangedNotification, Title: "休講", Body: "第3回は休講です", CID: &cid}); err != nil {
+		t.Fatalf("err = %v", err)
+	}
+
+	first := receivePush(t, sender.calls)
+	if !reflect.DeepEqual(first.tokens, []string{"ok", "stale", "flaky"}) {
+		t.Errorf("tokens = %v, want all of the user's tokens", first.tokens)
+	}
+	wantData := map[string]string{"notification_id": "2", "type": "SCHEDULE_CHANGED", "cid": "3"}
+	if first.message.Title != "休講" || first.message.Body != "第3回は休講です" || !reflect.DeepEqual(first.message.Data, wantData) {
+		t.Errorf("message = %+v, want the schedule notification with data %v", first.message, wantData)
+	}
+
+	retry := receivePush(t, sender.calls)
+	if !reflect.DeepEqual(retry.tokens, []string{"flaky"}) {
+		t.Errorf("retried tokens = %v, want [flaky]", retry.tokens)
+	}
+	if !reflect.DeepEqual(deviceTokens.deleted, []string{"stale"}) {
+		t.Errorf("deleted tokens = %v, want [stale]", deviceTokens.deleted)
+	}
+
+	select {
+	case call := <-sender.calls:
+		t.Errorf("unexpected push %+v, mentions are disabled", call.message)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -0,0 +1,94 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/utils"
+	"gorm.io/gorm"
+)
+
+// queryLogRow はクエリのログを確認するためのテーブルの行です。
+type queryLogRow struct {
+	ID    uint
+	Email string
+}
+
+// TestQueryLoggerTrace はGORMで実行したクエリについて、本番では遅いクエリとエラーのみ、デバッグでは全てのクエリを
+// リクエストID付きで出力し、バインドした値はログに出さないことを確認するテストです。
+func TestQueryLoggerTrace(t *testing.T) {
+	db := openTestDB(t)
+	if err := db.Exec("CREATE TABLE IF NOT EXISTS query_log_rows (id INTEGER PRIMARY KEY, email TEXT)").Error; err != nil {
+		t.Fatalf("failed to create table: %v", err)
+	}
+	t.Cleanup(func() { db.Exec("DROP TABLE IF EXISTS query_log_rows") })
+	const email = "taro@example.com"
+
+	cases := []struct {
+		name      string
+		logAll    bool
+		threshold time.Duration
+		query     func(db *gorm.DB) error
+		want      string
+	}{
+		// しきい値を1ナノ秒にすると全てのクエリが遅いクエリになる
+		{"Fast Query", false, time.Hour, findByEmail(email), ""},
+		{"Slow Query", false, time.Nanosecond, findByEmail(email), "[WARN] slow query"},
+		{"Failed Query", false, time.Hour, func(db *gorm.DB) error {
+			var rows []queryLogRow
+			return db.Table("missing_query_log_rows").Where("email = ?", email).Find(&rows).Error
+		}, "[ERROR] query failed"},
+		{"Record Not Found", false, time.Hour, func(db *gorm.DB) error {
+			var row queryLogRow
+			if err := db.Table("query_log_rows").Where("email = ?", email).First(&row).Error; !errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("err = %v, want gorm.ErrRecordNotFound", err)
+			}
+			return nil
+		}, ""},
+		{"Debug Fast Query", true, time.Hour, findByEmail(email), "[DEBUG] query"},
+		{"Debug Slow Query", true, time.Nanosecond, findByEmail(email), "[WARN] slow query"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var out bytes.Buffer
+			queryLogger := utils.NewQueryLogger(log.New(&out, "", 0), tc.threshold, tc.logAll)
+			ctx := utils.ContextWithRequestID(context.Background(), "req-1")
+
+			err := tc.query(db.Session(&gorm.Session{Logger: queryLogger}).WithContext(ctx))
+			if (err != nil) != strings.Contains(tc.want, "ERROR") {
+				t.Fatalf("err = %v", err)
+			}
+
+			got := out.String()
+			if tc.want == "" {
+				if got != "" {
+					t.Errorf("output = %q, want none", got)
+				}
+				return
+			}
+			for _, want := range []string{tc.want, "request_id=req-1", "rows=", "query_log_rows", "email = "} {
+				if !strings.Contains(got, want) {
+					t.Errorf("output = %q, want to contain %q", got, want)
+				}
+			}
+			if strings.Contains(got, email) {
+				t.Errorf("output = %q, want the bound value to be left out", got)
+			}
+		})
+	}
+}
+
+// findByEmail メールアドレスで行を検索するクエリを返します。
+func findByEmail(email string) func(db *gorm.DB) error {
+	return func(db *gorm.DB) error {
+		var rows []queryLogRow
+		return db.Table("query_log_rows").Where("email = ?", email).Find(&rows).Error
+	}
+}
@@ -0,0 +1,178 @@
+package tests
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// errRecordedQuery はrecordingPoolがクエリを記録した後に返すエラーです。
+var errRecordedQuery = errors.New("recorded")
+
+// recordingPool は実行されたクエリの数を数え、常にエラーを返すConnPoolです。
+type recordingPool struct {
+	queries int
+}
+
+func (p *recordingPool) PrepareContext(context.Context, string) (*sql.Stmt, error) {
+	p.queries++
+	return nil, errRecordedQuery
+}
+
+func (p *recordingPool) ExecContext(context.Context, string, ...interface{}) (sql.Result, error) {
+	p.queries++
+	return nil, errRecordedQuery
+}
+
+func (p *recordingPool) QueryContext(context.Context, string, ...interface{}) (*sql.Rows, error) {
+	p.queries++
+	return nil, errRecordedQuery
+}
+
+func (p *recordingPool) QueryRowContext(context.Context, string, ...interface{}) *sql.Row {
+	p.queries++
+	return nil
+}
+
+// recordingPrimary はトランザクションを開始できるrecordingPoolです。トランザクション内のクエリも同じ数に含めます。
+type recordingPrimary struct {
+	recordingPool
+}
+
+func (p *recordingPrimary) BeginTx(context.Context, *sql.TxOptions) (gorm.ConnPool, error) {
+	return &recordingTx{&p.recordingPool}, nil
+}
+
+// recordingTx はrecordingPrimaryのトランザクションです。
+type recordingTx struct {
+	*recordingPool
+}
+
+func (t *recordingTx) Commit() error   { return nil }
+func (t *recordingTx) Rollback() error { return nil }
+
+func (t *recordingTx) StmtContext(_ context.Context, stmt *sql.Stmt) *sql.Stmt { return stmt }
+
+// TestReadReplicaRouting は読み取り専用のクエリのみをレプリカで実行し、書き込み、トランザクション内、
+// WithPrimaryを指定した場合、レプリカが異常な場合、レプリカを設定していない場合はプライマリで実行することを確認するテストです。
+func TestReadReplicaRouting(t *testing.T) {
+	background := context.Background()
+	cases := []struct {
+		name        string
+		noReplica   bool
+		unhealthy   bool
+		ctx         context.Context
+		run         func(ctx context.Context, db *gorm.DB)
+		wantReplica bool
+	}{
+		{
+			name: "Read Only Query",
+			ctx:  background,
+			run: func(ctx context.Context, db *gorm.DB) {
+				_, _ = repositories.NewUserRepository(db).FindByName(ctx, "name")
+			},
+			wantReplica: true,
+		},
+		{
+			name: "Unmarked Query",
+			ctx:  background,
+			run:  func(ctx context.Context, db *gorm.DB) { _, _ = repositories.NewUserRepository(db).FindByID(ctx, 1) },
+		},
+		{
+			name: "Write",
+			ctx:  background,
+			run:  func(ctx context.Context, db *gorm.DB) { _ = repositories.NewUserRepository(db).DeleteUser(ctx, 1) },
+		},
+		{
+			name: "Forced Primary",
+			ctx:  repositories.WithPrimary(background),
+			run: func(ctx context.Context, db *gorm.DB) {
+				_, _ = repositories.NewUserRepository(db).FindByName(ctx, "name")
+			},
+		},
+		{
+			name: "Inside Transaction",
+			ctx:  background,
+			run: func(ctx context.Context, db *gorm.DB) {
+				_ = db.Transaction(func(tx *gorm.DB) error {
+					_, err := repositories.NewUserRepository(tx).FindByName(ctx, "name")
+					return err
+				})
+			},
+		},
+		{
+			name:      "Unhealthy Replica",
+			unhealthy: true,
+			ctx:       background,
+			run: func(ctx context.Context, db *gorm.DB) {
+				_, _ = repositories.NewUserRepository(db).FindByName(ctx, "name")
+			},
+		},
+		{
+			name:      "Replica Not Configured",
+			noReplica: true,
+			ctx:       background,
+			run: func(ctx context.Context, db *gorm.DB) {
+				_, _ = repositories.NewUserRepository(db).FindByName(ctx, "name")
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		for _, prepareStmt := range []bool{false, true} {
+			t.Run(tc.name, func(t *testing.T) {
+				primary := &recordingPrimary{}
+				replicaPool := &recordingPool{}
+				db, err := gorm.Open(postgres.New(postgres.Config{Conn: primary}), &gorm.Config{
+					Logger:               logger.Discard,
+					PrepareStmt:          prepareStmt,
+					DisableAutomaticPing: true,
+				})
+				if err != nil {
+					t.Fatalf("failed to open database: %v", err)
+				}
+				if !tc.noReplica {
+					replica := repositories.NewReadReplica(replicaPool, func(context.Context) error {
+						if tc.unhealthy {
+							return errRecordedQuery
+						}
+						return nil
+					})
+					_ = replica.CheckHealth(background)
+					if err := db.Use(replica); err != nil {
+						t.Fatalf("failed to use read replica: %v", err)
+					}
+				}
+
+				tc.run(tc.ctx, db)
+
+				if tc.wantReplica && (replicaPool.queries == 0 || primary.queries != 0) {
+					t.Errorf("prepareStmt=%t: primary ran %d queries and replica ran %d, want only the replica", prepareStmt, primary.queries, replicaPool.queries)
+				}
+				if !tc.wantReplica && (primary.queries == 0 || replicaPool.queries != 0) {
+					t.Errorf("prepareStmt=%t: primary ran %d queries and replica ran %d, want only the primary", prepareStmt, primary.queries, replicaPool.queries)
+				}
+			})
+		}
+	}
+}
+
+// TestReadReplicaHealthRecovery はヘルスチェックが成功するとレプリカへの振り分けを再開することを確認するテストです。
+func TestReadReplicaHealthRecovery(t *testing.T) {
+	var pingErr error = errRecordedQuery
+	replica := repositories.NewReadReplica(&recordingPool{}, func(context.Context) error { return pingErr })
+
+	if err := replica.CheckHealth(context.Background()); err == nil || replica.Healthy() {
+		t.Fatalf("healthy = %t, err = %v, want unhealthy", replica.Healthy(), err)
+	}
+	pingErr = nil
+	if err := replica.CheckHealth(context.Background()); err != nil || !replica.Healthy() {
+		t.Errorf("healthy = %t, err = %v, want healthy", replica.Healthy(), err)
+	}
+}
@@ -0,0 +1,433 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/controllers"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/middlewares"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/websocket"
+	"gorm.io/gorm"
+)
+
+// realtimeClassUserRepo はクラスごとのロールを返すClassUserRepositoryです。ロールがないクラスのメンバーではありません。
+// クラスのメンバーの一覧にはユーザー1のみを含めます。
+type realtimeClassUserRepo struct {
+	repositories.ClassUserRepository
+	mu    sync.Mutex
+	roles map[uint]string
+}
+
+func (r *realtimeClassUserRepo) GetRole(_ context.Context, _ uint, cid uint) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	role, ok := r.roles[cid]
+	if !ok {
+		return "", gorm.ErrRecordNotFound
+	}
+	return role, nil
+}
+
+func (r *realtimeClassUserRepo) GetClassMembers(ctx context.Context, cid uint, roles ...string) ([]dto.ClassMemberDTO, error) {
+	role, err := r.GetRole(ctx, 1, cid)
+	if err != nil {
+		return nil, nil
+	}
+	for _, want := range roles {
+		if role == want {
+			return []dto.ClassMemberDTO{{Uid: 1, Role: role}}, nil
+		}
+	}
+	return nil, nil
+}
+
+// setRole クラスのロールを変更します。ロールが空の場合はメンバーから外します。
+func (r *realtimeClassUserRepo) setRole(cid uint, role string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if role == "" {
+		delete(r.roles, cid)
+		return
+	}
+	r.roles[cid] = role
+}
+
+// drainTypes は送信を待つイベントの種類を取り出します。
+func drainTypes(conn *services.RealtimeConnection) []string {
+	var types []string
+	for _, event := range conn.Drain() {
+		types = append(types, event.Type)
+	}
+	return types
+}
+
+// TestRealtimeSendBufferOverflow は送信を待つイベントが上限を超えると古いイベントから破棄し、
+// 破棄した件数を持つresyncを先頭に送ることを確認するテストです。
+func TestRealtimeSendBufferOverflow(t *testing.T) {
+	hub := services.NewRealtimeHub(&realtimeClassUserRepo{}, nil, services.RealtimeConfig{SendBuffer: 2})
+	conn, err := hub.Connect(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("err = %v", err)
+	}
+	defer conn.Close()
+
+	for i := 1; i <= 5; i++ {
+		hub.PublishToUser(context.Background(), 1, dto.RealtimeNotification, map[string]int{"id": i})
+	}
+	hub.PublishToUser(context.Background(), 2, dto.RealtimeNotification, nil)
+
+	events := conn.Drain()
+	if len(events) != 3 || events[0].Type != dto.RealtimeResync {
+		t.Fatalf("events = %+v, want resync and the last 2 events", events)
+	}
+	var resync dto.RealtimeResyncDTO
+	if err := json.Unmarshal(events[0].Data, &resync); err != nil || resync.Dropped != 3 {
+		t.Errorf("resync = %s, want dropped 3", events[0].Data)
+	}
+	for i, want := range []string{`{"id":4}`, `{"id":5}`} {
+		if string(events[i+1].Data) != want {
+			t.Errorf("events[%d].Data = %s, want %s", i+1, events[i+1].Data, want)
+		}
+	}
+	if types := drainTypes(conn); len(types) != 0 {
+		t.Errorf("drained again = %v, want none", types)
+	}
+}
+
+// TestRealtimeSubscribe はメンバーのみクラスのイベントを購読でき、購読を解除すると配信されないことを確認するテストです。
+func TestRealtimeSubscribe(t *testing.T) {
+	repo := &realtimeClassUserRepo{roles: map[uint]string{10: "USER", 11: "APPLICANT", 12: "ADMIN"}}
+	hub := services.NewRealtimeHub(repo, nil, services.RealtimeConfig{MaxTopics: 1})
+	ctx := context.Background()
+	conn, err := hub.Connect(ctx, 1)
+	if err != nil {
+		t.Fatalf("err = %v", err)
+	}
+	defer conn.Close()
+
+	cases := []struct {
+		name    string
+		cid     uint
+		wantErr error
+	}{
+		{"Member", 10, nil},
+		{"Subscribed Again", 10, nil},
+		{"Applicant", 11, services.ErrUnauthorized},
+		{"Not Member", 13, services.ErrUnauthorized},
+		{"Topic Limit", 12, services.ErrRealtimeTopicLimit},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := conn.Subscribe(ctx, tc.cid); !errors.Is(err, tc.wantErr) {
+				t.Errorf("err = %v, want %v", err, tc.wantErr)
+			}
+		})
+	}
+
+	hub.PublishToClass(ctx, 10, dto.RealtimeBoardCreated, dto.RealtimeBoardDTO{ID: 5, CID: 10})
+	hub.PublishToClass(ctx, 11, dto.RealtimeBoardCreated, dto.RealtimeBoardDTO{ID: 6, CID: 11})
+	events := conn.Drain()
+	if len(events) != 1 || events[0].Type != dto.RealtimeBoardCreated || events[0].CID == nil || *events[0].CID != 10 {
+		t.Fatalf("events = %+v, want board_created of class 10", events)
+	}
+
+	conn.Unsubscribe(10)
+	hub.PublishToClass(ctx, 10, dto.RealtimeScheduleChanged, nil)
+	if types := drainTypes(conn); len(types) != 0 {
+		t.Errorf("events after unsubscribe = %v, want none", types)
+	}
+	if err := conn.Subscribe(ctx, 12); err != nil {
+		t.Errorf("err after unsubscribe = %v, want nil", err)
+	}
+}
+
+// TestRealtimeMembershipRevoked は購読中にクラスのメンバーでなくなった接続にはクラスのイベントを配信せず、
+// 購読を解除してunsubscribedを送ることを確認するテストです。
+func TestRealtimeMembershipRevoked(t *testing.T) {
+	for _, role := range []string{"", "BLACKLIST", "APPLICANT"} {
+		t.Run("Role "+role, func(t *testing.T) {
+			repo := &realtimeClassUserRepo{roles: map[uint]string{10: "USER"}}
+			hub := services.NewRealtimeHub(repo, nil, services.RealtimeConfig{})
+			ctx := context.Background()
+			conn, err := hub.Connect(ctx, 1)
+			if err != nil {
+				t.Fatalf("err = %v", err)
+			}
+			defer conn.Close()
+			if err := conn.Subscribe(ctx, 10); err != nil {
+				t.Fatalf("err = %v", err)
+			}
+
+			repo.setRole(10, role)
+			hub.PublishToClass(ctx, 10, dto.RealtimeBoardCreated, dto.RealtimeBoardDTO{ID: 5, CID: 10})
+			events := conn.Drain()
+			if len(events) != 1 || events[0].Type != dto.RealtimeUnsubscribed || events[0].CID == nil || *events[0].CID != 10 {
+				t.Fatalf("events = %+v, want only unsubscribed of class 10", events)
+			}
+
+			repo.setRole(10, "USER")
+			hub.PublishToClass(ctx, 10, dto.RealtimeScheduleChanged, nil)
+			if types := drainTypes(conn); len(types) != 0 {
+				t.Errorf("events after the subscription was removed = %v, want none", types)
+			}
+		})
+	}
+}
+
+// TestRealtimeBroadcastSystemEvent はシステムからの通知がクラスを購読していない接続にも届き、閉じた接続には届かないことを確認するテストです。
+func TestRealtimeBroadcastSystemEvent(t *testing.T) {
+	hub := services.NewRealtimeHub(&realtimeClassUserRepo{}, nil, services.RealtimeConfig{})
+	open, _ := hub.Connect(context.Background(), 1)
+	defer open.Close()
+	closed, _ := hub.Connect(context.Background(), 2)
+	closed.Close()
+
+	hub.BroadcastSystemEvent(services.SystemEvent{Type: "maintenance", Mode: services.MaintenanceReadOnly})
+
+	if types := drainTypes(open); len(types) != 1 || types[0] != dto.RealtimeSystem {
+		t.Errorf("open connection = %v, want [system]", types)
+	}
+	if types := drainTypes(closed); len(types) != 0 {
+		t.Errorf("closed connection = %v, want none", types)
+	}
+}
+
+// newRealtimeServer は指定した間隔でpingを送るWebSocketのサーバーを起動します。
+func newRealtimeServer(t *testing.T, hub services.RealtimeHub, jwtService services.JWTService, heartbeat time.Duration) string {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/api/gin/ws", middlewares.WebSocketTokenMiddleware(jwtService), controllers.NewRealtimeController(hub, heartbeat).Connect)
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	return "ws" + strings.TrimPrefix(server.URL, "http") + "/api/gin/ws"
+}
+
+// dialRealtime はWebSocketで接続します。authorizationが空でない場合はAuthorizationヘッダーに設定します。
+func dialRealtime(url string, authorization string) (*websocket.Conn, error) {
+	config, err := websocket.NewConfig(url, "http://localhost")
+	if err != nil {
+		return nil, err
+	}
+	if authorization != "" {
+		config.Header.Set("Authorization", authorization)
+	}
+	return websocket.DialConfig(config)
+}
+
+// receiveRealtime はイベントを1件受け取ります。
+func receiveRealtime(t *testing.T, ws *websocket.Conn) dto.RealtimeEventDTO {
+	t.Helper()
+	_ = ws.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var event dto.RealtimeEventDTO
+	if err := websocket.JSON.Receive(ws, &event); err != nil {
+		t.Fatalf("receive: %v", err)
+	}
+	return event
+}
+
+// TestRealtimeConnectionAuth はアクセストークンのない接続とリフレッシュトークン・カレンダー購読用のトークンでの接続が拒否され、
+// tokenクエリとAuthorizationヘッダーのアクセストークンで接続できることを確認するテストです。
+func TestRealtimeConnectionAuth(t *testing.T) {
+	jwtService := services.NewJWTService("realtime-test-secret")
+	url := newRealtimeServer(t, services.NewRealtimeHub(&realtimeClassUserRepo{}, nil, services.RealtimeConfig{}), jwtService, time.Minute)
+	accessToken, _ := jwtService.GenerateToken(1)
+	refreshToken, _ := jwtService.GenerateRefreshToken(1)
+	calendarToken, _ := jwtService.GenerateCalendarToken(1)
+
+	cases := []struct {
+		name          string
+		query         string
+		authorization string
+		wantConnected bool
+	}{
+		{"No Token", "", "", false},
+		{"Invalid Query Token", "?token=invalid", "", false},
+		{"Refresh Token", "?token=" + refreshToken, "", false},
+		{"Calendar Token", "?token=" + calendarToken, "", false},
+		{"Refresh Token Header", "", "Bearer " + refreshToken, false},
+		{"Query Token", "?token=" + accessToken, "", true},
+		{"Authorization Header", "", "Bearer " + accessToken, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ws, err := dialRealtime(url+tc.query, tc.authorization)
+			if (err == nil) != tc.wantConnected {
+				t.Fatalf("err = %v, want connected %v", err, tc.wantConnected)
+			}
+			if ws != nil {
+				_ = ws.Close()
+			}
+		})
+	}
+}
+
+// TestRealtimeProtocol はクラスの購読の結果とクラス・ユーザー宛てのイベントが1つの接続で届くことを確認するテストです。
+func TestRealtimeProtocol(t *testing.T) {
+	jwtService := services.NewJWTService("realtime-test-secret")
+	hub := services.NewRealtimeHub(&realtimeClassUserRepo{roles: map[uint]string{10: "USER"}}, nil, services.RealtimeConfig{})
+	url := newRealtimeServer(t, hub, jwtService, time.Minute)
+	token, _ := jwtService.GenerateToken(1)
+	ws, err := dialRealtime(url+"?token="+token, "")
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer ws.Close()
+
+	messages := []dto.RealtimeClientMessage{
+		{Type: dto.RealtimeSubscribe, CID: 10},
+		{Type: dto.RealtimeSubscribe, CID: 11},
+		{Type: "unknown"},
+	}
+	for _, message := range messages {
+		if err := websocket.JSON.Send(ws, message); err != nil {
+			t.Fatalf("send: %v", err)
+		}
+	}
+	wantTypes := []string{dto.RealtimeSubscribed, dto.RealtimeError, dto.RealtimeError}
+	for i, want := range wantTypes {
+		if event := receiveRealtime(t, ws); event.Type != want {
+			t.Fatalf("reply %d = %+v, want %s", i, event, want)
+		}
+	}
+
+	hub.PublishToClass(context.Background(), 10, dto.RealtimeBoardCreated, dto.RealtimeBoardDTO{ID: 5, CID: 10})
+	hub.PublishToUser(context.Background(), 1, dto.RealtimeDirectMessage, dto.RealtimeDirectMessageDTO{SenderID: "2", Text: "hi"})
+	for _, want := range []string{dto.RealtimeBoardCreated, dto.RealtimeDirectMessage} {
+		if event := receiveRealtime(t, ws); event.Type != want {
+			t.Fatalf("event = %+v, want %s", event, want)
+		}
+	}
+}
+
+// TestRealtimeHeartbeat はサーバーがpingを送り、pongを返すクライアントは接続を維持し、
+// 応答しないクライアントは切断されることを確認するテストです。
+func TestRealtimeHeartbeat(t *testing.T) {
+	const heartbeat = 50 * time.Millisecond
+	jwtService := services.NewJWTService("realtime-test-secret")
+	url := newRealtimeServer(t, services.NewRealtimeHub(&realtimeClassUserRepo{}, nil, services.RealtimeConfig{}), jwtService, heartbeat)
+	token, _ := jwtService.GenerateToken(1)
+	ws, err := dialRealtime(url+"?token="+token, "")
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer ws.Close()
+
+	// 切断までの時間を超えてもpongを返していれば接続を維持する
+	deadline := time.Now().Add(4 * heartbeat)
+	for time.Now().Before(deadline) {
+		if event := receiveRealtime(t, ws); event.Type != dto.RealtimePing {
+			t.Fatalf("event = %+v, want ping", event)
+		}
+		if err := websocket.JSON.Send(ws, dto.RealtimeClientMessage{Type: dto.RealtimePong}); err != nil {
+			t.Fatalf("send pong: %v", err)
+		}
+	}
+
+	_ = ws.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for {
+		var event dto.RealtimeEventDTO
+		if err := websocket.JSON.Receive(ws, &event); err != nil {
+			if netErr, ok := err.(interface{ Timeout() bool }); ok && netErr.Timeout() {
+				t.Fatal("connection was not closed after missing heartbeats")
+			}
+			return
+		}
+		if event.Type != dto.RealtimePing {
+			t.Fatalf("event = %+v, want ping", event)
+		}
+	}
+}
+
+// TestRealtimeAcrossInstances は別のインスタンスで配信したイベントがRedisを経由して届くことを確認するテストです。
+func TestRealtimeAcrossInstances(t *testing.T) {
+	redisClient := openTestRedis(t)
+	repo := &realtimeClassUserRepo{roles: map[uint]string{10: "USER"}}
+	receiver := services.NewRealtimeHub(repo, redisClient, services.RealtimeConfig{})
+	publisher := services.NewRealtimeHub(repo, redisClient, services.RealtimeConfig{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, err := receiver.Connect(ctx, 1)
+	if err != nil {
+		t.Fatalf("err = %v", err)
+	}
+	defer conn.Close()
+	if err := conn.Subscribe(ctx, 10); err != nil {
+		t.Fatalf("err = %v", err)
+	}
+
+	seen := map[string]bool{}
+	for !seen[dto.RealtimeNotification] || !seen[dto.RealtimeScheduleChanged] {
+		// 購読の完了を待たずに配信したイベントは届かないため、届くまで配信し直す
+		publisher.PublishToUser(ctx, 1, dto.RealtimeNotification, nil)
+		publisher.PublishToClass(ctx, 10, dto.RealtimeScheduleChanged, nil)
+		select {
+		case <-conn.Ready():
+			for _, eventType := range drainTypes(conn) {
+				seen[eventType] = true
+			}
+		case <-time.After(100 * time.Millisecond):
+		case <-ctx.Done():
+			t.Fatalf("received = %v, want notification and schedule_changed", seen)
+		}
+	}
+}
+
+// TestSendDirectMessageSender は認証済みのユーザーと異なる送信者IDでのダイレクトメッセージの送信を403で拒否することを確認するテストです。
+func TestSendDirectMessageSender(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	redisClient := openTestRedis(t)
+	hub := services.NewRealtimeHub(&realtimeClassUserRepo{}, nil, services.RealtimeConfig{})
+	receiver, err := hub.Connect(context.Background(), 2)
+	if err != nil {
+		t.Fatalf("err = %v", err)
+	}
+	defer receiver.Close()
+
+	controller := controllers.NewChatController(services.NewRoomManager(redisClient, hub), nil, nil, nil, nil, nil, 0)
+	router := gin.New()
+	router.Use(func(c *gin.Context) { c.Set("userID", uint(1)) })
+	router.POST("/chat/dm/:senderId/:receiverId", controller.SendDirectMessage)
+
+	cases := []struct {
+		name       string
+		senderID   string
+		wantStatus int
+		wantEvents int
+	}{
+		{"Other Sender", "3", http.StatusForbidden, 0},
+		{"Own Sender", "1", http.StatusOK, 1},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/chat/dm/"+tc.senderID+"/2", strings.NewReader(url.Values{"message": {"hello"}}.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+
+			if resp.Code != tc.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", resp.Code, tc.wantStatus, resp.Body.String())
+			}
+			events := receiver.Drain()
+			if len(events) != tc.wantEvents {
+				t.Fatalf("events = %+v, want %d", events, tc.wantEvents)
+			}
+			var dm dto.RealtimeDirectMessageDTO
+			if tc.wantEvents > 0 && (json.Unmarshal(events[0].Data, &dm) != nil || dm.SenderID != "1") {
+				t.Errorf("dm = %s, want sender 1", events[0].Data)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/constants"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/controllers"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/gin-gonic/gin"
+)
+
+// recordingJWTService はリフレッシュに使われたトークンを記録するJWTServiceです。
+type recordingJWTService struct {
+	services.JWTService
+	refreshed []string
+}
+
+func (s *recordingJWTService) RefreshAccessToken(refreshToken string) (*jwt.Token, error) {
+	s.refreshed = append(s.refreshed, refreshToken)
+	return &jwt.Token{Raw: "new-access-token"}, nil
+}
+
+// TestRefreshAccessTokenHandlerValidation はJSON以外のリクエストを415で、リフレッシュトークンのないリクエストを400で拒否し、サービスを呼ばないことを確認するテストです。
+func TestRefreshAccessTokenHandlerValidation(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	cases := []struct {
+		name        string
+		contentType string
+		body        string
+		wantStatus  int
+		wantError   string
+		wantToken   string
+	}{
+		{"Valid", "application/json", `{"refresh_token":"Bearer token-1"}`, constants.StatusOK, "", "token-1"},
+		{"JSON With Charset", "application/json; charset=utf-8", `{"refresh_token":"token-1"}`, constants.StatusOK, "", "token-1"},
+		{"No Content Type", "", `{"refresh_token":"token-1"}`, constants.StatusUnsupportedMedia, constants.ContentTypeNotJSON, ""},
+		{"Form", "application/x-www-form-urlencoded", "refresh_token=token-1", constants.StatusUnsupportedMedia, constants.ContentTypeNotJSON, ""},
+		{"Invalid JSON", "application/json", `{"refresh_token":`, constants.StatusBadRequest, constants.InvalidRequest, ""},
+		{"Missing Token", "application/json", `{}`, constants.StatusBadRequest, constants.RefreshTokenRequired, ""},
+		{"Blank Token", "application/json", `{"refresh_token":"Bearer  "}`, constants.StatusBadRequest, constants.RefreshTokenRequired, ""},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			jwtService := &recordingJWTService{}
+			controller := controllers.NewGoogleAuthController(nil, jwtService, nil)
+			r := gin.New()
+			r.POST("/refresh-token", controller.RefreshAccessTokenHandler)
+
+			resp := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodPost, "/refresh-token", strings.NewReader(tc.body))
+			if tc.contentType != "" {
+				req.Header.Set("Content-Type", tc.contentType)
+			}
+			r.ServeHTTP(resp, req)
+
+			if resp.Code != tc.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", resp.Code, tc.wantStatus, resp.Body.String())
+			}
+			if tc.wantError != "" {
+				var body map[string]string
+				if err := json.Unmarshal(resp.Body.Bytes(), &body); err != nil || body["error"] != tc.wantError {
+					t.Errorf("body = %s, want error %q", resp.Body.String(), tc.wantError)
+				}
+			}
+			if tc.wantToken == "" && len(jwtService.refreshed) > 0 {
+				t.Errorf("refreshed %v, want no call", jwtService.refreshed)
+			}
+			if tc.wantToken != "" && (len(jwtService.refreshed) != 1 || jwtService.refreshed[0] != tc.wantToken) {
+				t.Errorf("refreshed %v, want [%s]", jwtService.refreshed, tc.wantToken)
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/constants"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/middlewares"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// setUpTimeoutRouter はタイムアウトミドルウェアを適用したルーターを生成します。
+func setUpTimeoutRouter(config middlewares.TimeoutConfig, handler gin.HandlerFunc) *gin.Engine {
+	r := gin.New()
+	r.Use(middlewares.RequestIDMiddleware())
+	r.Use(middlewares.GlobalErrorHandler(&recordingErrorReporter{}))
+	r.Use(middlewares.TimeoutMiddleware(config))
+	r.GET("/slow", handler)
+	return r
+}
+
+// TestTimeoutMiddlewareReturnsGatewayTimeout は期限切れのリクエストが504になることを確認するテストです。
+func TestTimeoutMiddlewareReturnsGatewayTimeout(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	r := setUpTimeoutRouter(middlewares.TimeoutConfig{Default: 20 * time.Millisecond}, func(c *gin.Context) {
+		<-c.Request.Context().Done()
+	})
+
+	resp := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/slow", nil)
+	r.ServeHTTP(resp, req)
+
+	if resp.Code != constants.StatusGatewayTimeout {
+		t.Fatalf("status = %d, want %d", resp.Code, constants.StatusGatewayTimeout)
+	}
+	var body utils.ErrorResponse
+	if err := json.Unmarshal(resp.Body.Bytes(), &body); err != nil {
+		t.Fatalf("invalid response body: %v", err)
+	}
+	if body.Code != constants.ErrCodeTimeout {
+		t.Errorf("code = %q, want %q", body.Code, constants.ErrCodeTimeout)
+	}
+}
+
+// TestTimeoutMiddlewareOverrides はルートごとの期限の上書きを確認するテストです。
+func TestTimeoutMiddlewareOverrides(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+
+	cases := []struct {
+		name         string
+		override     time.Duration
+		wantDeadline bool
+	}{
+		{"Longer Deadline", time.Minute, true},
+		{"No Deadline", 0, false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := middlewares.TimeoutConfig{
+				Default:   time.Millisecond,
+				Overrides: map[string]time.Duration{"GET /slow": tc.override},
+			}
+
+			var hasDeadline bool
+			var remaining time.Duration
+			r := setUpTimeoutRouter(config, func(c *gin.Context) {
+				var deadline time.Time
+				deadline, hasDeadline = c.Request.Context().Deadline()
+				remaining = time.Until(deadline)
+				c.Status(constants.StatusOK)
+			})
+
+			resp := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodGet, "/slow", nil)
+			r.ServeHTTP(resp, req)
+
+			if hasDeadline != tc.wantDeadline {
+				t.Fatalf("has deadline = %v, want %v", hasDeadline, tc.wantDeadline)
+			}
+			if tc.wantDeadline && remaining < time.Second {
+				t.Errorf("remaining = %s, want the overridden deadline", remaining)
+			}
+			if resp.Code != constants.StatusOK {
+				t.Errorf("status = %d, want %d", resp.Code, constants.StatusOK)
+			}
+		})
+	}
+}
+
+// TestQueryCancelledByDeadline は期限切れで遅いクエリが中断されることを確認するテストです。
+func TestQueryCancelledByDeadline(t *testing.T) {
+	db := openTestDB(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := db.WithContext(ctx).Exec(slowQuery(db)).Error
+	if err == nil {
+		t.Fatal("expected the slow query to be cancelled")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("query was not aborted in time: %s", elapsed)
+	}
+	if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		t.Errorf("ctx.Err() = %v, want %v", ctx.Err(), context.DeadlineExceeded)
+	}
+}
+
+// TestRepositoryUsesRequestContext はリポジトリがキャンセル済みのコンテキストでクエリを実行しないことを確認するテストです。
+func TestRepositoryUsesRequestContext(t *testing.T) {
+	db := openTestDB(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := repositories.NewClassUserRepository(db).GetRole(ctx, 1, 1)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want %v", err, context.Canceled)
+	}
+}
@@ -0,0 +1,259 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/migration"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+)
+
+// materialRepo はスケジュール1の予習資料(ID 1)と振り返りアンケート(ID 2)を持ち、回答と通知を記録するScheduleMaterialRepositoryです。
+type materialRepo struct {
+	repositories.ScheduleMaterialRepository
+	pending   []repositories.PendingSurvey
+	counts    map[int]int64
+	responses []models.ScheduleSurveyResponse
+	notified  []uint
+}
+
+func (r *materialRepo) materials() []models.ScheduleMaterial {
+	return []models.ScheduleMaterial{
+		{ID: 1, CSID: 1, Phase: models.PreClassPhase, Title: "予習資料"},
+		{ID: 2, CSID: 1, Phase: models.PostClassPhase, Title: "振り返り"},
+	}
+}
+
+func (r *materialRepo) FindBySchedule(context.Context, uint) ([]models.ScheduleMaterial, error) {
+	return r.materials(), nil
+}
+
+func (r *materialRepo) FindByID(_ context.Context, id uint) (*models.ScheduleMaterial, error) {
+	for _, material := range r.materials() {
+		if material.ID == id {
+			return &material, nil
+		}
+	}
+	return &models.ScheduleMaterial{ID: id, CSID: 99, Phase: models.PostClassPhase}, nil
+}
+
+func (r *materialRepo) SaveResponse(_ context.Context, response *models.ScheduleSurveyResponse) error {
+	r.responses = append(r.responses, *response)
+	return nil
+}
+
+func (r *materialRepo) CountResponsesByUnderstanding(context.Context, uint) (map[int]int64, error) {
+	return r.counts, nil
+}
+
+func (r *materialRepo) FindReflections(context.Context, uint) ([]repositories.SurveyReflectionRow, error) {
+	return []repositories.SurveyReflectionRow{{UID: 11, Nickname: "たろう", Understanding: 2, Reflection: "例題が難しかった"}}, nil
+}
+
+func (r *materialRepo) FindPendingSurveys(context.Context, time.Time) ([]repositories.PendingSurvey, error) {
+	return r.pending, nil
+}
+
+// ClaimSurveyNotification は通知済みのアンケートを記録せずにfalseを返します。
+func (r *materialRepo) ClaimSurveyNotification(_ context.Context, id uint, _ time.Time) (bool, error) {
+	for _, notified := range r.notified {
+		if notified == id {
+			return false, nil
+		}
+	}
+	r.notified = append(r.notified, id)
+	return true, nil
+}
+
+// materialScheduleRepo はクラス1のスケジュールを返すClassScheduleRepositoryです。
+type materialScheduleRepo struct {
+	repositories.ClassScheduleRepository
+	schedule models.ClassSchedule
+}
+
+func (r *materialScheduleRepo) GetClassScheduleByID(context.Context, uint) (*models.ClassSchedule, error) {
+	schedule := r.schedule
+	return &schedule, nil
+}
+
+// materialClassUserRepo は固定のロールと、クラスの生徒を4人返すClassUserRepositoryです。
+type materialClassUserRepo struct {
+	repositories.ClassUserRepository
+	role string
+}
+
+func (r *materialClassUserRepo) GetRole(context.Context, uint, uint) (string, error) {
+	return r.role, nil
+}
+
+func (r *materialClassUserRepo) GetClassMembers(context.Context, uint, ...string) ([]dto.ClassMemberDTO, error) {
+	return []dto.ClassMemberDTO{{Uid: 11}, {Uid: 12}, {Uid: 13}, {Uid: 14}}, nil
+}
+
+// newMaterialService はendedAtに終了するスケジュールの資料を扱うScheduleMaterialServiceを生成します。
+func newMaterialService(repo *materialRepo, role string, endedAt time.Time, cancelled bool, notifier services.Notifier) services.ScheduleMaterialService {
+	schedule := models.ClassSchedule{ID: 1, CID: 1, Title: "第3回", EndedAt: endedAt, IsCancelled: cancelled}
+	return services.NewScheduleMaterialService(repo, &materialScheduleRepo{schedule: schedule}, &materialClassUserRepo{role: role}, notifier)
+}
+
+// TestGetScheduleMaterials は生徒には授業が終了するまで振り返りアンケートを表示せず、メンバー以外は取得できないことを確認するテストです。
+func TestGetScheduleMaterials(t *testing.T) {
+	now := time.Now()
+	cases := []struct {
+		name    string
+		role    string
+		endedAt time.Time
+		wantIDs []uint
+		wantErr error
+	}{
+		{"Student Before Class End", "USER", now.Add(time.Hour), []uint{1}, nil},
+		{"Student After Class End", "USER", now.Add(-time.Hour), []uint{1, 2}, nil},
+		{"Assistant Before Class End", "ASSISTANT", now.Add(time.Hour), []uint{1, 2}, nil},
+		{"Applicant", "APPLICANT", now.Add(-time.Hour), nil, services.ErrUnauthorized},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			service := newMaterialService(&materialRepo{}, tc.role, tc.endedAt, false, nil)
+
+			materials, err := service.GetMaterials(context.Background(), 11, 1)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tc.wantErr)
+			}
+			var ids []uint
+			for _, material := range materials {
+				ids = append(ids, material.ID)
+			}
+			if !reflect.DeepEqual(ids, tc.wantIDs) {
+				t.Errorf("ids = %v, want %v", ids, tc.wantIDs)
+			}
+		})
+	}
+}
+
+// TestSubmitSurveyResponse は生徒のみ、授業が終了した休講でないスケジュールの振り返りアンケートに回答できることを確認するテストです。
+func TestSubmitSurveyResponse(t *testing.T) {
+	now := time.Now()
+	cases := []struct {
+		name       string
+		role       string
+		materialID uint
+		endedAt    time.Time
+		cancelled  bool
+		wantErr    error
+	}{
+		{"Student", "USER", 2, now.Add(-time.Hour), false, nil},
+		{"Before Class End", "USER", 2, now.Add(time.Hour), false, services.ErrSurveyNotOpen},
+		{"Cancelled", "USER", 2, now.Add(-time.Hour), true, services.ErrSurveyNotOpen},
+		{"Pre Class Material", "USER", 1, now.Add(-time.Hour), false, services.ErrNotFound},
+		{"Other Schedule", "USER", 3, now.Add(-time.Hour), false, services.ErrNotFound},
+		{"Admin", "ADMIN", 2, now.Add(-time.Hour), false, services.ErrUnauthorized},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := &materialRepo{}
+			service := newMaterialService(repo, tc.role, tc.endedAt, tc.cancelled, nil)
+
+			err := service.SubmitSurveyResponse(context.Background(), 11, 1, tc.materialID, dto.SurveyResponseRequest{Understanding: 4})
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tc.wantErr)
+			}
+			if saved := len(repo.responses) == 1; saved != (tc.wantErr == nil) {
+				t.Errorf("saved = %v, want %v", saved, tc.wantErr == nil)
+			}
+		})
+	}
+}
+
+// TestGetSurveySummary は理解度の平均と分布、クラスの生徒数に対する回答率を集計し、生徒は集計を取得できないことを確認するテストです。
+func TestGetSurveySummary(t *testing.T) {
+	repo := &materialRepo{counts: map[int]int64{2: 1, 4: 2}}
+	summary, err := newMaterialService(repo, "ASSISTANT", time.Now(), false, nil).GetSurveySummary(context.Background(), 1, 1, 2)
+	if err != nil {
+		t.Fatalf("err = %v", err)
+	}
+	if summary.ResponseCount != 3 || summary.StudentCount != 4 || summary.ResponseRate != 0.75 {
+		t.Errorf("responses = %d/%d (%v), want 3/4 (0.75)", summary.ResponseCount, summary.StudentCount, summary.ResponseRate)
+	}
+	if summary.AverageUnderstanding != 10.0/3 {
+		t.Errorf("average = %v, want %v", summary.AverageUnderstanding, 10.0/3)
+	}
+	if want := map[int]int64{1: 0, 2: 1, 3: 0, 4: 2, 5: 0}; !reflect.DeepEqual(summary.UnderstandingCounts, want) {
+		t.Errorf("counts = %v, want %v", summary.UnderstandingCounts, want)
+	}
+	if len(summary.Reflections) != 1 || summary.Reflections[0].Nickname != "たろう" {
+		t.Errorf("reflections = %+v", summary.Reflections)
+	}
+
+	if _, err := newMaterialService(repo, "USER", time.Now(), false, nil).GetSurveySummary(context.Background(), 11, 1, 2); !errors.Is(err, services.ErrUnauthorized) {
+		t.Errorf("student err = %v, want %v", err, services.ErrUnauthorized)
+	}
+}
+
+// TestNotifyEndedSurveys は授業が終了したスケジュールの振り返りアンケートをクラスの生徒全員に通知し、通知済みとして記録することを確認するテストです。
+func TestNotifyEndedSurveys(t *testing.T) {
+	repo := &materialRepo{pending: []repositories.PendingSurvey{{MaterialID: 2, CID: 1, Title: "振り返り", ScheduleTitle: "第3回"}}}
+	notifier := &recordingNotifier{}
+	service := newMaterialService(repo, "ADMIN", time.Now(), false, notifier)
+
+	notified, err := service.NotifyEndedSurveys(context.Background())
+	if err != nil {
+		t.Fatalf("err = %v", err)
+	}
+	if notified != 1 || !reflect.DeepEqual(repo.notified, []uint{2}) {
+		t.Errorf("notified = %d %v, want 1 [2]", notified, repo.notified)
+	}
+	if want := []uint{11, 12, 13, 14}; !reflect.DeepEqual(notifier.uids, want) {
+		t.Errorf("notified uids = %v, want %v", notifier.uids, want)
+	}
+
+	// 通知前に取得した未通知のアンケートを、他のインスタンスが通知済みの場合は通知しない
+	notified, err = service.NotifyEndedSurveys(context.Background())
+	if err != nil || notified != 0 || len(notifier.uids) != 4 {
+		t.Errorf("second run notified = %d, uids = %v, err = %v, want 0 and no new notifications", notified, notifier.uids, err)
+	}
+}
+
+// TestClaimSurveyNotificationOnDatabase はアンケートの通知済みの記録が実際のDBで未通知の場合のみ成功することを確認するテストです。
+func TestClaimSurveyNotificationOnDatabase(t *testing.T) {
+	db := openTestDB(t)
+	migration.Migrate(db)
+	tx := db.Begin()
+	if tx.Error != nil {
+		t.Fatalf("failed to begin transaction: %v", tx.Error)
+	}
+	t.Cleanup(func() { tx.Rollback() })
+	ctx := context.Background()
+
+	user := &models.User{Name: "山田", Image: "https://example.com/u.png", PID: "survey-claim-test"}
+	if err := tx.Create(user).Error; err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	class := &models.Class{Name: "数学", UID: user.ID}
+	if err := tx.Create(class).Error; err != nil {
+		t.Fatalf("failed to create class: %v", err)
+	}
+	schedule := &models.ClassSchedule{Title: "第3回", CID: class.ID, StartedAt: time.Now().Add(-2 * time.Hour), EndedAt: time.Now().Add(-time.Hour)}
+	if err := tx.Create(schedule).Error; err != nil {
+		t.Fatalf("failed to create schedule: %v", err)
+	}
+	material := &models.ScheduleMaterial{CSID: schedule.ID, UID: user.ID, Phase: models.PostClassPhase, Title: "振り返り"}
+	if err := tx.Create(material).Error; err != nil {
+		t.Fatalf("failed to create material: %v", err)
+	}
+	repo := repositories.NewScheduleMaterialRepository(tx)
+
+	for i, want := range []bool{true, false} {
+		claimed, err := repo.ClaimSurveyNotification(ctx, material.ID, time.Now())
+		if err != nil || claimed != want {
+			t.Errorf("claim %d = %v, %v, want %v", i+1, claimed, err, want)
+		}
+	}
+}
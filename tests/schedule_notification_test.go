@@ -0,0 +1,143 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/migration"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+)
+
+// notifiedClassUserRepo は固定のロールと、指定したユーザーをクラスのメンバーとして返すClassUserRepositoryです。
+type notifiedClassUserRepo struct {
+	repositories.ClassUserRepository
+	role    string
+	members []uint
+}
+
+func (r *notifiedClassUserRepo) GetRole(context.Context, uint, uint) (string, error) {
+	return r.role, nil
+}
+
+func (r *notifiedClassUserRepo) GetClassMembers(context.Context, uint, ...string) ([]dto.ClassMemberDTO, error) {
+	members := make([]dto.ClassMemberDTO, 0, len(r.members))
+	for _, uid := range r.members {
+		members = append(members, dto.ClassMemberDTO{Uid: uid})
+	}
+	return members, nil
+}
+
+// TestScheduleNotificationStats はスケジュールの通知を受け取った人数と読んだ人数を集計し、
+// 未読のメンバーのうちクラスに残っているメンバーのみに再通知して、続けての再通知を拒否することを確認するテストです。
+func TestScheduleNotificationStats(t *testing.T) {
+	db := openTestDB(t)
+	migration.Migrate(db)
+	ctx := context.Background()
+
+	var uids []uint
+	for i := 0; i < 3; i++ {
+		user := &models.User{Name: "山田", PID: fmt.Sprintf("schedule-notification-%d-%d", i, time.Now().UnixNano())}
+		if err := db.Create(user).Error; err != nil {
+			t.Fatalf("failed to create user: %v", err)
+		}
+		uids = append(uids, user.ID)
+	}
+	t.Cleanup(func() {
+		for _, uid := range uids {
+			db.Where("uid = ?", uid).Delete(&models.Notification{})
+			db.Delete(&models.User{}, uid)
+		}
+	})
+
+	repo := repositories.NewNotificationRepository(db)
+	notifications := services.NewNotificationService(repo, nil, nil, services.PushConfig{}, nil)
+	// 3人目のメンバーはクラスを退出している
+	classUserRepo := &notifiedClassUserRepo{role: "ADMIN", members: uids[:2]}
+	scheduleRepo := &materialScheduleRepo{schedule: models.ClassSchedule{ID: 5, CID: 10, Title: "第1回"}}
+	service := services.NewScheduleNotificationService(notifications, repo, scheduleRepo, classUserRepo, nil, openTestRedis(t))
+
+	schedule := &scheduleRepo.schedule
+	for _, uid := range uids {
+		if err := service.NotifySchedule(ctx, uid, schedule, "休講のお知らせ", "第1回は休講です"); err != nil {
+			t.Fatalf("err = %v", err)
+		}
+	}
+	// 他のスケジュールの通知は集計しない
+	if err := service.NotifySchedule(ctx, uids[0], &models.ClassSchedule{ID: 6, CID: 10}, "休講のお知らせ", "第2回は休講です"); err != nil {
+		t.Fatalf("err = %v", err)
+	}
+	read, err := repo.FindNotificationsByUser(ctx, uids[0], false, 0, 10)
+	if err != nil || len(read) != 2 {
+		t.Fatalf("notifications = %+v, err = %v", read, err)
+	}
+	for _, notification := range read {
+		if notification.Type != models.ScheduleChangedNotification || notification.ResourceType != "class_schedule" {
+			t.Errorf("notification = %+v, want a schedule notification", notification)
+		}
+		if *notification.ResourceID == 5 {
+			if err := repo.MarkNotificationRead(ctx, uids[0], notification.ID, time.Now()); err != nil {
+				t.Fatalf("err = %v", err)
+			}
+		}
+	}
+
+	stats, err := service.GetScheduleNotificationStats(ctx, 1, 5)
+	if err != nil {
+		t.Fatalf("err = %v", err)
+	}
+	want := dto.ScheduleNotificationStatsDTO{CSID: 5, Delivered: 3, Read: 1, Unread: 2, OpenRate: 1.0 / 3}
+	if *stats != want {
+		t.Errorf("stats = %+v, want %+v", *stats, want)
+	}
+
+	result, err := service.RemindUnread(ctx, 1, 5)
+	if err != nil || result.Reminded != 1 {
+		t.Fatalf("result = %+v, err = %v, want 1 reminder", result, err)
+	}
+	if unread, err := repo.FindNotificationsByUser(ctx, uids[1], true, 0, 10); err != nil || len(unread) != 2 {
+		t.Errorf("unread = %+v, err = %v, want the notification and the reminder", unread, err)
+	}
+	if unread, err := repo.FindNotificationsByUser(ctx, uids[2], true, 0, 10); err != nil || len(unread) != 1 {
+		t.Errorf("unread = %+v, err = %v, want no reminder for a former member", unread, err)
+	}
+	if _, err := service.RemindUnread(ctx, 1, 5); !errors.Is(err, services.ErrReminderCooldown) {
+		t.Errorf("err = %v, want %v", err, services.ErrReminderCooldown)
+	}
+}
+
+// TestScheduleNotificationPermission はスケジュールの通知の既読状況の取得と再通知を、クラスの講師・アシスタントのみができることを確認するテストです。
+func TestScheduleNotificationPermission(t *testing.T) {
+	cases := []struct {
+		name    string
+		role    string
+		wantErr error
+	}{
+		{"Admin", "ADMIN", nil},
+		{"Assistant", "ASSISTANT", nil},
+		{"Student", "USER", services.ErrUnauthorized},
+		{"Applicant", "APPLICANT", services.ErrUnauthorized},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			db := openTestDB(t)
+			migration.Migrate(db)
+			repo := repositories.NewNotificationRepository(db)
+			scheduleRepo := &materialScheduleRepo{schedule: models.ClassSchedule{ID: 5, CID: 10}}
+			service := services.NewScheduleNotificationService(services.NewNotificationService(repo, nil, nil, services.PushConfig{}, nil), repo, scheduleRepo, &notifiedClassUserRepo{role: tc.role}, nil, nil)
+
+			if _, err := service.GetScheduleNotificationStats(context.Background(), 1, 5); !errors.Is(err, tc.wantErr) {
+				t.Errorf("stats err = %v, want %v", err, tc.wantErr)
+			}
+			if _, err := service.RemindUnread(context.Background(), 1, 5); !errors.Is(err, tc.wantErr) {
+				t.Errorf("remind err = %v, want %v", err, tc.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package tests
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/migration"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// seededTables は初期データの投入で行が増えるテーブルです。
+var seededTables = []string{"users", "classes", "class_codes", "class_users", "class_schedules", "class_boards"}
+
+// countRows はテーブルごとの行数を返します。
+func countRows(t *testing.T, db *gorm.DB) map[string]int64 {
+	counts := make(map[string]int64, len(seededTables))
+	for _, table := range seededTables {
+		var count int64
+		if err := db.Table(table).Count(&count).Error; err != nil {
+			t.Fatalf("failed to count %s: %v", table, err)
+		}
+		counts[table] = count
+	}
+	return counts
+}
+
+// TestSeedIdempotent は初期データを2回投入しても行が増えず、全てのロールが揃うことを確認するテストです。
+func TestSeedIdempotent(t *testing.T) {
+	db := openEmptySchema(t)
+	migration.Migrate(db)
+	opts := migration.SeedOptions{Demo: true, GinMode: gin.DebugMode}
+
+	if err := migration.Seed(db, opts); err != nil {
+		t.Fatalf("first Seed() = %v", err)
+	}
+	first := countRows(t, db)
+	for _, table := range seededTables {
+		if first[table] == 0 {
+			t.Errorf("%s has no demo rows", table)
+		}
+	}
+
+	if err := migration.Seed(db, opts); err != nil {
+		t.Fatalf("second Seed() = %v", err)
+	}
+	second := countRows(t, db)
+	for _, table := range seededTables {
+		if first[table] != second[table] {
+			t.Errorf("%s rows = %d after the second run, want %d", table, second[table], first[table])
+		}
+	}
+
+	var roles []string
+	if err := db.Raw("SELECT unnest(enum_range(NULL::role))::text").Scan(&roles).Error; err != nil {
+		t.Fatalf("failed to read roles: %v", err)
+	}
+	if len(roles) != len(migration.Roles) {
+		t.Errorf("roles = %v, want %v", roles, migration.Roles)
+	}
+}
+
+// TestSeedDemoInRelease はリリースモードではデモデータを投入せず、データベースに接続する前にエラーを返すことを確認するテストです。
+func TestSeedDemoInRelease(t *testing.T) {
+	err := migration.Seed(nil, migration.SeedOptions{Demo: true, GinMode: gin.ReleaseMode})
+	if !errors.Is(err, migration.ErrDemoSeedInRelease) {
+		t.Errorf("Seed() = %v, want %v", err, migration.ErrDemoSeedInRelease)
+	}
+}
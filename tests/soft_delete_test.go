@@ -0,0 +1,113 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"gorm.io/gorm"
+)
+
+// restoreClassRepo は論理削除したクラスを保持するClassRepositoryです。
+type restoreClassRepo struct {
+	repositories.ClassRepository
+	deleted  map[uint]bool
+	restored []uint
+}
+
+func (r *restoreClassRepo) Restore(_ context.Context, classID uint) error {
+	if !r.deleted[classID] {
+		return gorm.ErrRecordNotFound
+	}
+	r.restored = append(r.restored, classID)
+	return nil
+}
+
+// restoreScheduleRepo は論理削除したスケジュールを保持するClassScheduleRepositoryです。
+type restoreScheduleRepo struct {
+	repositories.ClassScheduleRepository
+	deleted map[uint]models.ClassSchedule
+	// classDeleted スケジュールのクラスも削除されている
+	classDeleted bool
+	restored     []uint
+}
+
+func (r *restoreScheduleRepo) FindDeletedClassSchedule(_ context.Context, id uint) (*models.ClassSchedule, error) {
+	schedule, ok := r.deleted[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &schedule, nil
+}
+
+func (r *restoreScheduleRepo) RestoreClassSchedule(_ context.Context, id uint) error {
+	if r.classDeleted {
+		return gorm.ErrRecordNotFound
+	}
+	r.restored = append(r.restored, id)
+	return nil
+}
+
+// TestRestoreClass はクラスの管理者のみが削除したクラスを復元でき、削除されていないクラスはErrNotFoundになることを確認するテストです。
+func TestRestoreClass(t *testing.T) {
+	cases := []struct {
+		name    string
+		role    string
+		cid     uint
+		wantErr error
+	}{
+		{"Admin", "ADMIN", 1, nil},
+		{"Assistant", "ASSISTANT", 1, services.ErrUnauthorized},
+		{"Member", "USER", 1, services.ErrUnauthorized},
+		{"Not Deleted", "ADMIN", 2, services.ErrNotFound},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := &restoreClassRepo{deleted: map[uint]bool{1: true}}
+			service := services.NewCreateClassService(nil, repo, &flyerClassUserRepo{role: tc.role}, nil, nil, nil, "", nil, nil)
+
+			if err := service.RestoreClass(context.Background(), tc.cid, 7); !errors.Is(err, tc.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tc.wantErr)
+			}
+			if restored := len(repo.restored) == 1; restored != (tc.wantErr == nil) {
+				t.Errorf("restored = %v, want %v", repo.restored, tc.wantErr == nil)
+			}
+		})
+	}
+}
+
+// TestRestoreClassSchedule は管理者が指定したクラスのスケジュールのみ復元でき、クラスが削除されている場合は復元しないことを確認するテストです。
+func TestRestoreClassSchedule(t *testing.T) {
+	cases := []struct {
+		name         string
+		admin        bool
+		cid          uint
+		id           uint
+		classDeleted bool
+		wantErr      error
+	}{
+		{"Admin", true, 5, 10, false, nil},
+		{"Not Admin", false, 5, 10, false, services.ErrUnauthorized},
+		{"Other Class", true, 6, 10, false, services.ErrNotFound},
+		{"Not Deleted", true, 5, 11, false, services.ErrNotFound},
+		{"Class Deleted", true, 5, 10, true, services.ErrNotFound},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := &restoreScheduleRepo{deleted: map[uint]models.ClassSchedule{10: {ID: 10, CID: 5}}, classDeleted: tc.classDeleted}
+			service := services.NewClassScheduleService(repo, &adminClassUserRepo{admin: tc.admin}, nil, nil, true, nil, nil, nil, nil, services.CheckInTokenConfig{}, nil)
+
+			if err := service.RestoreClassSchedule(context.Background(), tc.cid, tc.id, 7); !errors.Is(err, tc.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tc.wantErr)
+			}
+			if restored := len(repo.restored) == 1; restored != (tc.wantErr == nil) {
+				t.Errorf("restored = %v, want %v", repo.restored, tc.wantErr == nil)
+			}
+		})
+	}
+}
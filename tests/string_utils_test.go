@@ -0,0 +1,42 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/utils"
+)
+
+// TestContainsPattern は検索語の%と_とエスケープ文字をワイルドカードとして扱わず、文字として部分一致させることを確認するテストです。
+func TestContainsPattern(t *testing.T) {
+	db := openTestDB(t)
+
+	cases := []struct {
+		name      string
+		value     string
+		query     string
+		want      string
+		wantMatch bool
+	}{
+		{"Plain", "数学入門", "数学", "%数学%", true},
+		{"Percent Literal", "100% English", "100%", `%100\%%`, true},
+		{"Percent Not Wildcard", "1000 English", "10%E", `%10\%E%`, false},
+		{"Underscore Literal", "math_101", "h_1", `%h\_1%`, true},
+		{"Underscore Not Wildcard", "math-101", "h_1", `%h\_1%`, false},
+		{"Backslash", `C:\class`, `:\c`, `%:\\c%`, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			pattern := utils.ContainsPattern(tc.query)
+			if pattern != tc.want {
+				t.Errorf("pattern = %q, want %q", pattern, tc.want)
+			}
+			var match bool
+			if err := db.Raw(`SELECT ? LIKE ? ESCAPE '\'`, tc.value, pattern).Scan(&match).Error; err != nil {
+				t.Fatalf("err = %v", err)
+			}
+			if match != tc.wantMatch {
+				t.Errorf("%q LIKE %q = %v, want %v", tc.value, pattern, match, tc.wantMatch)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package tests
+
+import (
+	"fmt"
+	"os"
+	"sync/atomic"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// テストで使うデータベースとRedisです。
+// TEST_DATABASE_DSNとTEST_REDIS_ADDRが設定されていない場合は、外部のサービスを使わずにSQLiteのインメモリDBとminiredisで実行します。
+
+// sqliteSeq はテストごとに別のインメモリDBを開くための連番です。
+var sqliteSeq int64
+
+// openTestDB はTEST_DATABASE_DSNが設定されている場合はそのPostgreSQLに、設定されていない場合はテストごとに新しいSQLiteのインメモリDBに接続します。
+func openTestDB(t testing.TB) *gorm.DB {
+	t.Helper()
+	if dsn := os.Getenv("TEST_DATABASE_DSN"); dsn != "" {
+		db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+		if err != nil {
+			t.Fatalf("failed to connect to database: %v", err)
+		}
+		return db
+	}
+
+	// 接続ごとに別のDBにならないよう共有キャッシュにし、接続を1本にしてロックの競合を避ける
+	name := fmt.Sprintf("file:test%d?mode=memory&cache=shared&_busy_timeout=5000", atomic.AddInt64(&sqliteSeq, 1))
+	db, err := gorm.Open(sqlite.Open(name), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("failed to get generic database: %v", err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	return db
+}
+
+// slowQuery 数秒以上かかるクエリを返します。SQLiteでは中断されるまで終わらない再帰クエリにします。
+func slowQuery(db *gorm.DB) string {
+	if db.Dialector.Name() == "postgres" {
+		return "SELECT pg_sleep(5)"
+	}
+	return "WITH RECURSIVE r(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM r) SELECT count(*) FROM r"
+}
+
+// requirePostgres PostgreSQL固有のSQLを確認するテストで、TEST_DATABASE_DSNが設定されていない場合はスキップします。
+// CIではPostgreSQLのサービスを起動してTEST_DATABASE_DSNを設定するため、これらのテストも実行されます。
+func requirePostgres(t testing.TB) {
+	t.Helper()
+	if os.Getenv("TEST_DATABASE_DSN") == "" {
+		t.Skip("TEST_DATABASE_DSN is not set (this test uses PostgreSQL-specific SQL)")
+	}
+}
+
+// openTestRedis はTEST_REDIS_ADDRが設定されている場合はそのRedisに、設定されていない場合はテストごとに起動したminiredisに接続します。
+func openTestRedis(t testing.TB) *redis.Client {
+	t.Helper()
+	addr := os.Getenv("TEST_REDIS_ADDR")
+	if addr == "" {
+		server, err := miniredis.Run()
+		if err != nil {
+			t.Fatalf("failed to start miniredis: %v", err)
+		}
+		t.Cleanup(server.Close)
+		addr = server.Addr()
+	}
+	client := redis.NewClient(&redis.Options{Addr: addr})
+	t.Cleanup(func() { _ = client.Close() })
+	return client
+}
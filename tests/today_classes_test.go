@@ -0,0 +1,64 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/controllers"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"github.com/gin-gonic/gin"
+)
+
+// todayScheduleRepo は今日のスケジュールを検索したユーザーIDを記録するClassScheduleRepositoryです。
+type todayScheduleRepo struct {
+	repositories.ClassScheduleRepository
+	uid uint
+}
+
+func (r *todayScheduleRepo) FindClassSchedulesByUserBetween(_ context.Context, uid uint, _, _ time.Time) ([]models.ClassSchedule, error) {
+	r.uid = uid
+	return nil, nil
+}
+
+// TestGetTodayClassesUser はクエリのuidを無視してログイン中のユーザーの今日のクラスを取得することを確認するテストです。
+func TestGetTodayClassesUser(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+
+	cases := []struct {
+		name       string
+		path       string
+		wantStatus int
+		wantUID    uint
+	}{
+		{"Own Classes", "/cl/today", http.StatusOK, 7},
+		{"Other User In Query", "/cl/today?uid=8", http.StatusOK, 7},
+		{"Invalid Time Zone", "/cl/today?tz=Mars/Olympus", http.StatusBadRequest, 0},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := &todayScheduleRepo{}
+			service := services.NewClassScheduleService(repo, nil, nil, nil, true, nil, nil, nil, nil, services.CheckInTokenConfig{}, nil)
+			controller := controllers.NewCreateClassController(nil, service, nil)
+
+			r := gin.New()
+			r.Use(func(c *gin.Context) { c.Set("userID", uint(7)) })
+			r.GET("/cl/today", controller.GetTodayClasses)
+
+			req, _ := http.NewRequest(http.MethodGet, tc.path, nil)
+			resp := httptest.NewRecorder()
+			r.ServeHTTP(resp, req)
+
+			if resp.Code != tc.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", resp.Code, tc.wantStatus, resp.Body.String())
+			}
+			if repo.uid != tc.wantUID {
+				t.Errorf("uid = %d, want %d", repo.uid, tc.wantUID)
+			}
+		})
+	}
+}
@@ -0,0 +1,240 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/migration"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+)
+
+var errInduced = errors.New("induced failure")
+
+// fakeStore はコミット済みのデータとトランザクション中の書き込みを保持する偽のストアです。
+type fakeStore struct {
+	classes    []models.Class
+	classUsers []models.ClassUser
+	classCodes []models.ClassCode
+
+	pendingClasses    []models.Class
+	pendingClassUsers []models.ClassUser
+	pendingClassCodes []models.ClassCode
+
+	failSaveClassCode bool
+}
+
+func (s *fakeStore) commit() {
+	s.classes = append(s.classes, s.pendingClasses...)
+	s.classUsers = append(s.classUsers, s.pendingClassUsers...)
+	s.classCodes = append(s.classCodes, s.pendingClassCodes...)
+	s.rollback()
+}
+
+func (s *fakeStore) rollback() {
+	s.pendingClasses = nil
+	s.pendingClassUsers = nil
+	s.pendingClassCodes = nil
+}
+
+type fakeUserRepo struct {
+	repositories.UserRepository
+}
+
+func (r *fakeUserRepo) FindByID(_ context.Context, userID uint) (*models.User, error) {
+	return &models.User{ID: userID, Name: "tester"}, nil
+}
+
+type fakeClassRepo struct {
+	repositories.ClassRepository
+	store *fakeStore
+}
+
+func (r *fakeClassRepo) Save(_ context.Context, class *models.Class) (uint, error) {
+	class.ID = uint(len(r.store.classes) + len(r.store.pendingClasses) + 1)
+	r.store.pendingClasses = append(r.store.pendingClasses, *class)
+	return class.ID, nil
+}
+
+type fakeClassUserRepo struct {
+	repositories.ClassUserRepository
+	store *fakeStore
+}
+
+func (r *fakeClassUserRepo) Save(_ context.Context, classUser *models.ClassUser) error {
+	r.store.pendingClassUsers = append(r.store.pendingClassUsers, *classUser)
+	return nil
+}
+
+type fakeClassCodeRepo struct {
+	repositories.ClassCodeRepository
+	store *fakeStore
+}
+
+func (r *fakeClassCodeRepo) FindByCode(_ context.Context, _ string) (*models.ClassCode, error) {
+	return nil, nil
+}
+
+func (r *fakeClassCodeRepo) SaveClassCode(_ context.Context, classCode *models.ClassCode) error {
+	if r.store.failSaveClassCode {
+		return errInduced
+	}
+	r.store.pendingClassCodes = append(r.store.pendingClassCodes, *classCode)
+	return nil
+}
+
+// fakeTxManager はfnの結果に応じて偽のストアをコミット・ロールバックするTxManagerです。
+type fakeTxManager struct {
+	store *fakeStore
+}
+
+func (m *fakeTxManager) WithinTransaction(_ context.Context, fn func(repos repositories.RepositorySet) error) error {
+	var committed []func()
+	repos := repositories.RepositorySet{
+		Class:       &fakeClassRepo{store: m.store},
+		ClassCode:   &fakeClassCodeRepo{store: m.store},
+		ClassUser:   &fakeClassUserRepo{store: m.store},
+		User:        &fakeUserRepo{},
+		TxManager:   m,
+		AfterCommit: func(hook func()) { committed = append(committed, hook) },
+	}
+	if err := fn(repos); err != nil {
+		m.store.rollback()
+		return err
+	}
+	m.store.commit()
+	for _, hook := range committed {
+		hook()
+	}
+	return nil
+}
+
+// newFakeClassService は偽のストアを使うClassServiceを生成します。
+func newFakeClassService(store *fakeStore) services.ClassService {
+	return services.NewCreateClassService(
+		&fakeTxManager{store: store},
+		&fakeClassRepo{store: store},
+		&fakeClassUserRepo{store: store},
+		&fakeClassCodeRepo{store: store},
+		&fakeUserRepo{},
+		nil,
+		"",
+		nil,
+		nil,
+	)
+}
+
+// TestCreateClassTransaction はクラス作成が全て成功した場合のみコミットされることを確認するテストです。
+func TestCreateClassTransaction(t *testing.T) {
+	tests := []struct {
+		name          string
+		failClassCode bool
+		wantErr       error
+		wantCommitted int
+	}{
+		{name: "commits all records", failClassCode: false, wantErr: nil, wantCommitted: 1},
+		{name: "rolls back when class code fails", failClassCode: true, wantErr: errInduced, wantCommitted: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &fakeStore{failSaveClassCode: tt.failClassCode}
+			service := newFakeClassService(store)
+
+			_, err := service.CreateClass(context.Background(), dto.CreateClassRequest{Name: "class", UID: 1})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CreateClass() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if len(store.classes) != tt.wantCommitted {
+				t.Errorf("committed classes = %d, want %d", len(store.classes), tt.wantCommitted)
+			}
+			if len(store.classUsers) != tt.wantCommitted {
+				t.Errorf("committed class users = %d, want %d", len(store.classUsers), tt.wantCommitted)
+			}
+			if len(store.classCodes) != tt.wantCommitted {
+				t.Errorf("committed class codes = %d, want %d", len(store.classCodes), tt.wantCommitted)
+			}
+		})
+	}
+}
+
+// TestTxManagerRollsBackOnError はfnがエラーを返した場合に全ての書き込みがロールバックされることを確認するテストです。
+func TestTxManagerRollsBackOnError(t *testing.T) {
+	db := openTestDB(t)
+	migration.Migrate(db)
+	txManager := repositories.NewTxManager(db)
+	ctx := context.Background()
+
+	user := models.User{PID: "tx-manager-test", Name: "tx-manager-test"}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	defer db.Unscoped().Delete(&user)
+
+	var classID uint
+	err := txManager.WithinTransaction(ctx, func(repos repositories.RepositorySet) error {
+		var err error
+		classID, err = repos.Class.Save(ctx, &models.Class{Name: "tx-manager-test", UID: user.ID})
+		if err != nil {
+			return err
+		}
+		if err := repos.ClassUser.Save(ctx, &models.ClassUser{CID: classID, UID: user.ID, Nickname: user.Name, Role: "ADMIN"}); err != nil {
+			return err
+		}
+		return errInduced
+	})
+	if !errors.Is(err, errInduced) {
+		t.Fatalf("WithinTransaction() error = %v, want %v", err, errInduced)
+	}
+
+	var count int64
+	db.Model(&models.Class{}).Where("id = ?", classID).Count(&count)
+	if count != 0 {
+		t.Errorf("class %d was committed despite rollback", classID)
+	}
+	db.Unscoped().Model(&models.ClassUser{}).Where("cid = ?", classID).Count(&count)
+	if count != 0 {
+		t.Errorf("class user for class %d was committed despite rollback", classID)
+	}
+}
+
+// TestTxManagerNestedReusesOuterTransaction は入れ子の呼び出しが外側のトランザクションで実行されることを確認するテストです。
+func TestTxManagerNestedReusesOuterTransaction(t *testing.T) {
+	db := openTestDB(t)
+	migration.Migrate(db)
+	txManager := repositories.NewTxManager(db)
+	ctx := context.Background()
+
+	user := models.User{PID: "tx-manager-nested-test", Name: "tx-manager-nested-test"}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	defer db.Unscoped().Delete(&user)
+
+	var classID uint
+	err := txManager.WithinTransaction(ctx, func(repos repositories.RepositorySet) error {
+		err := repos.TxManager.WithinTransaction(ctx, func(inner repositories.RepositorySet) error {
+			var err error
+			classID, err = inner.Class.Save(ctx, &models.Class{Name: "tx-manager-nested-test", UID: user.ID})
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		// 入れ子の処理が成功しても、外側が失敗すれば全体がロールバックされる
+		return errInduced
+	})
+	if !errors.Is(err, errInduced) {
+		t.Fatalf("WithinTransaction() error = %v, want %v", err, errInduced)
+	}
+
+	var count int64
+	db.Model(&models.Class{}).Where("id = ?", classID).Count(&count)
+	if count != 0 {
+		t.Errorf("class %d from nested call was committed despite outer rollback", classID)
+	}
+}
@@ -0,0 +1,132 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+)
+
+// 他のテストのキーと重ならないユーザーID
+const (
+	unreadAuthor uint = 910001 + iota
+	unreadMember
+	unreadMentioned
+)
+
+// unreadClassUserRepo はクラス10と11のメンバーと、unreadMentionedの参加クラスを返すClassUserRepositoryです。
+type unreadClassUserRepo struct {
+	repositories.ClassUserRepository
+}
+
+func (r *unreadClassUserRepo) GetUserClasses(context.Context, uint, int, int, repositories.ClassUserQueryOptions) ([]dto.UserClassInfoDTO, error) {
+	return []dto.UserClassInfoDTO{
+		{ID: 10, Name: "数学", Role: "USER"},
+		{ID: 11, Name: "英語", Role: "ASSISTANT"},
+		{ID: 12, Name: "申請中", Role: "APPLICANT"},
+	}, nil
+}
+
+func (r *unreadClassUserRepo) GetClassMembers(context.Context, uint, ...string) ([]dto.ClassMemberDTO, error) {
+	return []dto.ClassMemberDTO{
+		{Uid: unreadAuthor, Nickname: "たろう"},
+		{Uid: unreadMember, Nickname: "はなこ"},
+		{Uid: unreadMentioned, Nickname: "さくら"},
+	}, nil
+}
+
+// unreadScheduleRepo はクラス10のスケジュールを返すClassScheduleRepositoryです。
+type unreadScheduleRepo struct {
+	repositories.ClassScheduleRepository
+}
+
+func (r *unreadScheduleRepo) GetClassScheduleByID(_ context.Context, id uint) (*models.ClassSchedule, error) {
+	return &models.ClassSchedule{ID: id, CID: 10}, nil
+}
+
+// TestUnreadSummary は掲示、チャット、メンション、通知の未読件数をクラスごとと合計で返し、
+// 閲覧と既読で件数が減り、参加申請中のクラスを含めないことを確認するテストです。
+func TestUnreadSummary(t *testing.T) {
+	redisClient := openTestRedis(t)
+	ctx := context.Background()
+	cleanup := func() {
+		for _, uid := range []uint{unreadAuthor, unreadMember, unreadMentioned} {
+			redisClient.Del(ctx, fmt.Sprintf("unread:%d", uid))
+		}
+	}
+	cleanup()
+	t.Cleanup(func() {
+		cleanup()
+	})
+	service := services.NewUnreadService(&unreadClassUserRepo{}, &unreadScheduleRepo{}, redisClient)
+
+	steps := []func() error{
+		func() error { return service.RecordBoardPost(ctx, models.ClassBoard{CID: 10, UID: unreadAuthor}) },
+		func() error {
+			return service.RecordChatMessage(ctx, 100, unreadMember, "@さくら 資料はどこですか")
+		},
+		func() error { return service.RecordNotification(ctx, unreadMentioned, 11) },
+		func() error { return service.RecordNotification(ctx, unreadMentioned, 12) },
+		// 未読が1件のため、2回目の閲覧で負の件数にならない
+		func() error { return service.RecordBoardRead(ctx, unreadMentioned, 10) },
+		func() error { return service.RecordBoardRead(ctx, unreadMentioned, 10) },
+		func() error { return service.RecordBoardPost(ctx, models.ClassBoard{CID: 10, UID: unreadAuthor}) },
+		func() error { return service.RecordBoardPost(ctx, models.ClassBoard{CID: 10, UID: unreadAuthor}) },
+	}
+	for i, step := range steps {
+		if err := step(); err != nil {
+			t.Fatalf("step %d: err = %v", i, err)
+		}
+	}
+
+	summary, err := service.GetUnreadSummary(ctx, unreadMentioned)
+	if err != nil {
+		t.Fatalf("err = %v", err)
+	}
+	want := dto.UnreadSummaryDTO{
+		Classes: []dto.ClassUnreadDTO{
+			{CID: 10, Name: "数学", Counts: dto.UnreadCountsDTO{Boards: 2, Chat: 1, Mentions: 1, Total: 4}},
+			{CID: 11, Name: "英語", Counts: dto.UnreadCountsDTO{Notifications: 1, Total: 1}},
+		},
+		Total: dto.UnreadCountsDTO{Boards: 2, Chat: 1, Notifications: 1, Mentions: 1, Total: 5},
+	}
+	if !reflect.DeepEqual(*summary, want) {
+		t.Fatalf("summary = %+v, want %+v", *summary, want)
+	}
+
+	// 投稿者は自分の掲示とチャットを未読にしない
+	summary, err = service.GetUnreadSummary(ctx, unreadMember)
+	if err != nil {
+		t.Fatalf("err = %v", err)
+	}
+	if summary.Total.Boards != 3 || summary.Total.Chat != 0 {
+		t.Errorf("member total = %+v, want 3 boards and no chat", summary.Total)
+	}
+
+	if err := service.MarkRead(ctx, unreadMentioned, 10, services.UnreadChat); err != nil {
+		t.Fatalf("err = %v", err)
+	}
+	summary, err = service.GetUnreadSummary(ctx, unreadMentioned)
+	if err != nil {
+		t.Fatalf("err = %v", err)
+	}
+	if counts := summary.Classes[0].Counts; counts.Chat != 0 || counts.Mentions != 1 || counts.Total != 3 {
+		t.Errorf("counts after marking chat read = %+v, want mentions and boards left", counts)
+	}
+
+	if err := service.MarkRead(ctx, unreadMentioned, 10); err != nil {
+		t.Fatalf("err = %v", err)
+	}
+	summary, err = service.GetUnreadSummary(ctx, unreadMentioned)
+	if err != nil {
+		t.Fatalf("err = %v", err)
+	}
+	if summary.Total.Total != 1 {
+		t.Errorf("total after marking class read = %d, want 1", summary.Total.Total)
+	}
+}
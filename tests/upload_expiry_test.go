@@ -0,0 +1,92 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/utils"
+)
+
+// abortRecordingUploader はS3のアップロードIDを連番で払い出し、中止したアップロードIDを記録するMultipartUploaderです。
+// failがtrueの場合は中止に失敗します。
+type abortRecordingUploader struct {
+	utils.MultipartUploader
+	created int
+	aborted []string
+	fail    bool
+}
+
+func (u *abortRecordingUploader) CreateMultipartUpload(context.Context, string, string) (string, error) {
+	u.created++
+	return fmt.Sprintf("s3-upload-%d", u.created), nil
+}
+
+func (u *abortRecordingUploader) AbortMultipartUpload(_ context.Context, _ string, uploadID string) error {
+	if u.fail {
+		return errors.New("s3 unavailable")
+	}
+	u.aborted = append(u.aborted, uploadID)
+	return nil
+}
+
+// TestAbortExpiredUploads はアップロードの状態の有効期限を過ぎたアップロードのみをS3で中止し、
+// 中止済みのアップロードを再び中止せず、中止に失敗したアップロードは次回に中止し直すことを確認するテストです。
+func TestAbortExpiredUploads(t *testing.T) {
+	ctx := context.Background()
+	uploader := &abortRecordingUploader{}
+	service := services.NewUploadService(uploader, &materialClassUserRepo{role: "ADMIN"}, openTestRedis(t))
+
+	request := dto.StartUploadRequest{CID: 1, FileName: "lecture.mp4", Size: 10}
+	first, err := service.StartUpload(ctx, 1, request)
+	if err != nil {
+		t.Fatalf("err = %v", err)
+	}
+	if _, err := service.StartUpload(ctx, 1, request); err != nil {
+		t.Fatalf("err = %v", err)
+	}
+	// 中止したアップロードは期限切れの対象にしない
+	if err := service.AbortUpload(ctx, 1, first.UploadID); err != nil {
+		t.Fatalf("err = %v", err)
+	}
+	if _, err := service.StartUpload(ctx, 1, request); err != nil {
+		t.Fatalf("err = %v", err)
+	}
+	uploader.aborted = nil
+	expired := time.Now().Add(25 * time.Hour)
+
+	cases := []struct {
+		name        string
+		now         time.Time
+		fail        bool
+		wantAborted int
+		wantErr     bool
+		wantS3IDs   []string
+	}{
+		{"Not Expired", time.Now(), false, 0, false, nil},
+		{"S3 Failure", expired, true, 0, true, nil},
+		{"Expired", expired, false, 2, false, []string{"s3-upload-2", "s3-upload-3"}},
+		{"Already Aborted", expired, false, 0, false, []string{"s3-upload-2", "s3-upload-3"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			uploader.fail = tc.fail
+			aborted, err := service.AbortExpiredUploads(ctx, tc.now)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tc.wantErr)
+			}
+			if aborted != tc.wantAborted {
+				t.Errorf("aborted = %d, want %d", aborted, tc.wantAborted)
+			}
+			sort.Strings(uploader.aborted)
+			if fmt.Sprint(uploader.aborted) != fmt.Sprint(tc.wantS3IDs) {
+				t.Errorf("S3 aborts = %v, want %v", uploader.aborted, tc.wantS3IDs)
+			}
+		})
+	}
+}
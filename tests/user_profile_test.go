@@ -0,0 +1,61 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"github.com/go-redis/redis/v8"
+)
+
+// profileUserRepo は保持しているユーザーのみを返し、問い合わせたIDを記録するUserRepositoryです。
+type profileUserRepo struct {
+	repositories.UserRepository
+	users     map[uint]models.User
+	requested [][]uint
+}
+
+func (r *profileUserRepo) GetUsersByIDs(_ context.Context, userIDs []uint) ([]models.User, error) {
+	r.requested = append(r.requested, userIDs)
+	var users []models.User
+	for _, uid := range userIDs {
+		if user, ok := r.users[uid]; ok {
+			users = append(users, user)
+		}
+	}
+	return users, nil
+}
+
+// TestGetUserProfiles はRedisに接続できない場合もDBからまとめて取得し、削除されたユーザーをDeleted Userとして返すことを確認するテストです。
+func TestGetUserProfiles(t *testing.T) {
+	redisClient := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", DialTimeout: 50 * time.Millisecond, MaxRetries: -1})
+	t.Cleanup(func() { _ = redisClient.Close() })
+	repo := &profileUserRepo{users: map[uint]models.User{
+		1: {ID: 1, Name: "山田", Image: "https://example.com/1.png", Email: "yamada@example.com"},
+	}}
+	service := services.NewCreateUserService(repo, redisClient)
+
+	profiles, err := service.GetUserProfiles(context.Background(), []uint{1, 2})
+	if err != nil {
+		t.Fatalf("err = %v", err)
+	}
+	if len(repo.requested) != 1 || len(repo.requested[0]) != 2 {
+		t.Errorf("requested = %v, want a single batch of both users", repo.requested)
+	}
+
+	yamada := profiles[1]
+	if yamada.ID != 1 || yamada.Name != "山田" || yamada.AvatarURL == nil || *yamada.AvatarURL != "https://example.com/1.png" {
+		t.Errorf("profile = %+v", yamada)
+	}
+	if deleted := profiles[2]; deleted != (dto.UserProfileDTO{ID: 2, Name: services.DeletedUserName}) {
+		t.Errorf("deleted profile = %+v, want %q without avatar", deleted, services.DeletedUserName)
+	}
+
+	if _, err := service.GetUserProfiles(context.Background(), nil); err != nil || len(repo.requested) != 1 {
+		t.Errorf("no senders: err = %v, requested = %v", err, repo.requested)
+	}
+}
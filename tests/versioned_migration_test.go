@@ -0,0 +1,258 @@
+package tests
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/migration"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// openEmptySchema はPostgreSQLにテスト用の空のスキーマを作成し、そのスキーマを使う接続を返します。スキーマはテストの終了時に削除します。
+// SQLのマイグレーションはPostgreSQL向けのため、TEST_DATABASE_DSNが設定されていない場合はスキップします。
+func openEmptySchema(t testing.TB) *gorm.DB {
+	requirePostgres(t)
+	admin := openTestDB(t)
+	schema := fmt.Sprintf("migration_test_%d", time.Now().UnixNano())
+	if err := admin.Exec("CREATE SCHEMA " + schema).Error; err != nil {
+		t.Fatalf("failed to create schema: %v", err)
+	}
+	t.Cleanup(func() { admin.Exec("DROP SCHEMA " + schema + " CASCADE") })
+
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	switch {
+	case !strings.Contains(dsn, "://"):
+		dsn += " search_path=" + schema
+	case strings.Contains(dsn, "?"):
+		dsn += "&search_path=" + schema
+	default:
+		dsn += "?search_path=" + schema
+	}
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to connect to database: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			_ = sqlDB.Close()
+		}
+	})
+	return db
+}
+
+// openMigrationDB はマイグレーションを1つも適用していないDBに接続します。
+// TEST_DATABASE_DSNが設定されている場合は空のスキーマを作成し、設定されていない場合は新しいSQLiteのインメモリDBを使います。
+func openMigrationDB(t testing.TB) *gorm.DB {
+	if os.Getenv("TEST_DATABASE_DSN") != "" {
+		return openEmptySchema(t)
+	}
+	return openTestDB(t)
+}
+
+// testMigrations はPostgreSQLとSQLiteのどちらでも実行できる、Runnerを確認するためのマイグレーションです。
+var testMigrations = fstest.MapFS{
+	"sql/000001_baseline.up.sql":      {Data: []byte("CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT NOT NULL);")},
+	"sql/000001_baseline.down.sql":    {Data: []byte("DROP TABLE users;")},
+	"sql/000002_add_classes.up.sql":   {Data: []byte("CREATE TABLE classes (id INTEGER PRIMARY KEY, uid INTEGER NOT NULL);\nCREATE INDEX idx_classes_uid ON classes (uid);")},
+	"sql/000002_add_classes.down.sql": {Data: []byte("DROP INDEX idx_classes_uid;\nDROP TABLE classes;")},
+}
+
+// brokenMigrations はtestMigrationsに、途中の文が失敗するマイグレーションを加えたものです。
+func brokenMigrations() fstest.MapFS {
+	migrations := fstest.MapFS{
+		"sql/000003_broken.up.sql":   {Data: []byte("CREATE TABLE boards (id INTEGER PRIMARY KEY);\nINSERT INTO missing_table (id) VALUES (1);")},
+		"sql/000003_broken.down.sql": {Data: []byte("DROP TABLE boards;")},
+	}
+	for name, file := range testMigrations {
+		migrations[name] = file
+	}
+	return migrations
+}
+
+// TestRunnerUpDown はマイグレーションを順に適用・取り消し、適用済みのバージョンを記録することを確認するテストです。
+func TestRunnerUpDown(t *testing.T) {
+	db := openMigrationDB(t)
+	runner := migration.NewRunner(testMigrations)
+	if latest, err := runner.LatestVersion(); err != nil || latest != 2 {
+		t.Fatalf("LatestVersion() = %d, %v, want 2", latest, err)
+	}
+
+	// 2回目の適用では何もしない
+	for i := 0; i < 2; i++ {
+		if err := runner.Up(db); err != nil {
+			t.Fatalf("Up() = %v", err)
+		}
+	}
+	if version, dirty, err := migration.Version(db); err != nil || version != 2 || dirty {
+		t.Fatalf("version = %d, dirty = %v, err = %v, want 2", version, dirty, err)
+	}
+	if !db.Migrator().HasTable("users") || !db.Migrator().HasTable("classes") {
+		t.Fatal("tables are missing after Up()")
+	}
+
+	if err := runner.Down(db, 1); err != nil {
+		t.Fatalf("Down(1) = %v", err)
+	}
+	if version, _, err := migration.Version(db); err != nil || version != 1 {
+		t.Fatalf("version = %d, err = %v, want 1", version, err)
+	}
+	if db.Migrator().HasTable("classes") || !db.Migrator().HasTable("users") {
+		t.Fatal("Down(1) must drop only the latest migration")
+	}
+
+	if err := runner.Down(db, 5); err != nil {
+		t.Fatalf("Down(5) = %v", err)
+	}
+	if version, _, err := migration.Version(db); err != nil || version != 0 {
+		t.Fatalf("version = %d, err = %v, want 0", version, err)
+	}
+	if db.Migrator().HasTable("users") {
+		t.Fatal("users table remains after rolling back every migration")
+	}
+}
+
+// TestRunnerBaseline は既存のテーブルがあるDBではベースラインを記録するまで適用を拒否し、記録後はベースラインより後のマイグレーションのみ適用することを確認するテストです。
+func TestRunnerBaseline(t *testing.T) {
+	db := openMigrationDB(t)
+	if err := db.Exec("CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT NOT NULL)").Error; err != nil {
+		t.Fatalf("failed to create users: %v", err)
+	}
+	runner := migration.NewRunner(testMigrations)
+
+	if err := runner.Up(db); !errors.Is(err, migration.ErrNoBaseline) {
+		t.Fatalf("Up() = %v, want %v", err, migration.ErrNoBaseline)
+	}
+	if err := migration.Baseline(db); err != nil {
+		t.Fatalf("Baseline() = %v", err)
+	}
+	if err := migration.Baseline(db); err == nil {
+		t.Fatal("second Baseline() = nil, want an error")
+	}
+	if err := runner.Up(db); err != nil {
+		t.Fatalf("Up() = %v", err)
+	}
+	if version, dirty, err := migration.Version(db); err != nil || version != 2 || dirty || !db.Migrator().HasTable("classes") {
+		t.Fatalf("version = %d, dirty = %v, err = %v, want 2 with classes", version, dirty, err)
+	}
+}
+
+// TestVersionedMigrations は空のデータベースに埋め込んだ全てのマイグレーションを適用・取り消しできることを確認するテストです。
+// 埋め込んだマイグレーションはPostgreSQL向けのため、TEST_DATABASE_DSNが設定されている場合のみ実行します。
+func TestVersionedMigrations(t *testing.T) {
+	db := openEmptySchema(t)
+	latest, err := migration.LatestVersion()
+	if err != nil {
+		t.Fatalf("LatestVersion() = %v", err)
+	}
+
+	for round := 0; round < 2; round++ {
+		if err := migration.MigrateUp(db); err != nil {
+			t.Fatalf("MigrateUp() = %v", err)
+		}
+		if version, dirty, err := migration.Version(db); err != nil || version != latest || dirty {
+			t.Fatalf("version = %d, dirty = %v, err = %v, want %d", version, dirty, err, latest)
+		}
+		if err := migration.CheckTables(db); err != nil {
+			t.Fatalf("CheckTables() = %v", err)
+		}
+
+		if err := migration.MigrateDown(db, int(latest)); err != nil {
+			t.Fatalf("MigrateDown() = %v", err)
+		}
+		if version, _, err := migration.Version(db); err != nil || version != 0 {
+			t.Fatalf("version = %d, err = %v, want 0", version, err)
+		}
+		if db.Migrator().HasTable("users") {
+			t.Fatal("users table remains after rolling back every migration")
+		}
+	}
+}
+
+// TestBaselineExistingSchema はAutoMigrateで作成したデータベースにベースラインを記録してから、埋め込んだマイグレーションを適用できることを確認するテストです。
+// TestVersionedMigrationsと同じく、TEST_DATABASE_DSNが設定されている場合のみ実行します。
+func TestBaselineExistingSchema(t *testing.T) {
+	db := openEmptySchema(t)
+	migration.Migrate(db)
+
+	if err := migration.MigrateUp(db); !errors.Is(err, migration.ErrNoBaseline) {
+		t.Fatalf("MigrateUp() = %v, want %v", err, migration.ErrNoBaseline)
+	}
+	if err := migration.RunCommand(db, []string{"baseline"}, io.Discard); err != nil {
+		t.Fatalf("baseline: %v", err)
+	}
+	if err := migration.MigrateUp(db); err != nil {
+		t.Fatalf("MigrateUp() = %v", err)
+	}
+	if err := migration.CheckVersion(db); err != nil {
+		t.Fatalf("CheckVersion() = %v", err)
+	}
+}
+
+// TestDirtyMigrationBlocksStartup は途中で失敗したマイグレーションが取り消され、dirtyとして記録されるため起動時の確認が失敗し、
+// スキーマを直した後にforceで解除できることを確認するテストです。
+func TestDirtyMigrationBlocksStartup(t *testing.T) {
+	db := openMigrationDB(t)
+	runner := migration.NewRunner(brokenMigrations())
+	if err := runner.Up(db); err == nil {
+		t.Fatal("Up() = nil, want the broken migration to fail")
+	}
+	if db.Migrator().HasTable("boards") {
+		t.Error("the failed migration was not rolled back")
+	}
+
+	var dirtyErr *migration.DirtyError
+	if err := migration.CheckVersion(db); !errors.As(err, &dirtyErr) || dirtyErr.Version != 3 {
+		t.Fatalf("CheckVersion() = %v, want DirtyError for version 3", err)
+	}
+	if err := runner.Up(db); !errors.As(err, &dirtyErr) {
+		t.Fatalf("Up() = %v, want DirtyError", err)
+	}
+
+	if err := migration.RunCommand(db, []string{"force", "2"}, io.Discard); err != nil {
+		t.Fatalf("force: %v", err)
+	}
+	if err := migration.CheckVersion(db); err != nil {
+		t.Fatalf("CheckVersion() = %v after force", err)
+	}
+	if version, _, err := migration.Version(db); err != nil || version != 2 {
+		t.Fatalf("version = %d, err = %v, want 2", version, err)
+	}
+}
+
+// TestMigrateCommandArguments はmigrateサブコマンドの不正な引数をデータベースに接続する前に拒否することを確認するテストです。
+func TestMigrateCommandArguments(t *testing.T) {
+	cases := [][]string{
+		nil,
+		{"sideways"},
+		{"down", "0"},
+		{"down", "abc"},
+		{"force"},
+		{"force", "-1"},
+	}
+
+	for _, args := range cases {
+		t.Run(strings.Join(args, " "), func(t *testing.T) {
+			if err := migration.RunCommand(nil, args, io.Discard); err == nil {
+				t.Errorf("RunCommand(%q) = nil, want an error", args)
+			}
+		})
+	}
+}
+
+// TestEmbeddedMigrations は埋め込んだSQLファイルがup/downの組で揃い、ベースラインを含むことを確認するテストです。
+func TestEmbeddedMigrations(t *testing.T) {
+	latest, err := migration.LatestVersion()
+	if err != nil {
+		t.Fatalf("LatestVersion() = %v", err)
+	}
+	if latest < 1 {
+		t.Errorf("latest version = %d, want the baseline", latest)
+	}
+}
@@ -0,0 +1,416 @@
+package tests
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/dto"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/models"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/repositories"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/services"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/utils"
+	"gorm.io/gorm"
+)
+
+// memoryWebhookRepo はWebhookと送信の記録をメモリに保存するWebhookRepositoryです。
+type memoryWebhookRepo struct {
+	repositories.WebhookRepository
+	mu         sync.Mutex
+	webhooks   map[uint]*models.Webhook
+	deliveries []models.WebhookDelivery
+}
+
+func newMemoryWebhookRepo(webhooks ...models.Webhook) *memoryWebhookRepo {
+	r := &memoryWebhookRepo{webhooks: make(map[uint]*models.Webhook)}
+	for i := range webhooks {
+		webhook := webhooks[i]
+		r.webhooks[webhook.ID] = &webhook
+	}
+	return r
+}
+
+func (r *memoryWebhookRepo) CreateWebhook(_ context.Context, webhook *models.Webhook) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	webhook.ID = uint(len(r.webhooks) + 1)
+	stored := *webhook
+	r.webhooks[webhook.ID] = &stored
+	return nil
+}
+
+func (r *memoryWebhookRepo) FindWebhook(_ context.Context, id uint) (*models.Webhook, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	webhook, ok := r.webhooks[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	found := *webhook
+	return &found, nil
+}
+
+func (r *memoryWebhookRepo) FindWebhooksByClass(_ context.Context, cid uint) ([]models.Webhook, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var webhooks []models.Webhook
+	for _, webhook := range r.webhooks {
+		if webhook.CID == cid {
+			webhooks = append(webhooks, *webhook)
+		}
+	}
+	return webhooks, nil
+}
+
+func (r *memoryWebhookRepo) FindEnabledWebhooks(_ context.Context, cid uint) ([]models.Webhook, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var webhooks []models.Webhook
+	for _, webhook := range r.webhooks {
+		if webhook.CID == cid && webhook.Enabled {
+			webhooks = append(webhooks, *webhook)
+		}
+	}
+	return webhooks, nil
+}
+
+func (r *memoryWebhookRepo) ResetWebhookFailures(_ context.Context, id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if webhook, ok := r.webhooks[id]; ok {
+		webhook.ConsecutiveFailures = 0
+	}
+	return nil
+}
+
+func (r *memoryWebhookRepo) IncrementWebhookFailures(_ context.Context, id uint) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	webhook, ok := r.webhooks[id]
+	if !ok {
+		return 0, gorm.ErrRecordNotFound
+	}
+	webhook.ConsecutiveFailures++
+	return webhook.ConsecutiveFailures, nil
+}
+
+func (r *memoryWebhookRepo) DisableWebhook(_ context.Context, id uint, at time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	webhook, ok := r.webhooks[id]
+	if !ok || !webhook.Enabled {
+		return false, nil
+	}
+	webhook.Enabled = false
+	webhook.DisabledAt = &at
+	return true, nil
+}
+
+func (r *memoryWebhookRepo) CreateDelivery(_ context.Context, delivery *models.WebhookDelivery, _ int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delivery.ID = uint(len(r.deliveries) + 1)
+	r.deliveries = append(r.deliveries, *delivery)
+	return nil
+}
+
+func (r *memoryWebhookRepo) snapshot() ([]models.WebhookDelivery, map[uint]models.Webhook) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	webhooks := make(map[uint]models.Webhook, len(r.webhooks))
+	for id, webhook := range r.webhooks {
+		webhooks[id] = *webhook
+	}
+	return append([]models.WebhookDelivery(nil), r.deliveries...), webhooks
+}
+
+// webhookClassUserRepo は指定したユーザーのみをクラスの管理者とするClassUserRepositoryです。
+type webhookClassUserRepo struct {
+	repositories.ClassUserRepository
+	admins map[uint]bool
+}
+
+func (r *webhookClassUserRepo) IsAdmin(_ context.Context, uid uint, _ uint) (bool, error) {
+	return r.admins[uid], nil
+}
+
+func (r *webhookClassUserRepo) GetClassMembers(_ context.Context, _ uint, _ ...string) ([]dto.ClassMemberDTO, error) {
+	var members []dto.ClassMemberDTO
+	for uid := range r.admins {
+		members = append(members, dto.ClassMemberDTO{Uid: uid, Role: "ADMIN"})
+	}
+	return members, nil
+}
+
+// scriptedWebhookSender は指定した回数だけ失敗した後に成功するWebhookSenderです。failuresが負の場合は常に失敗します。
+type scriptedWebhookSender struct {
+	mu       sync.Mutex
+	failures int
+	messages []utils.WebhookMessage
+}
+
+func (s *scriptedWebhookSender) Send(_ context.Context, message utils.WebhookMessage) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messages = append(s.messages, message)
+	if s.failures != 0 {
+		s.failures--
+		return http.StatusInternalServerError, errors.New("unexpected status 500")
+	}
+	return http.StatusOK, nil
+}
+
+func (s *scriptedWebhookSender) sent() []utils.WebhookMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]utils.WebhookMessage(nil), s.messages...)
+}
+
+// lockedNotifier は通知先のユーザーIDを記録する、複数のゴルーチンから呼び出せるNotifierです。
+type lockedNotifier struct {
+	mu   sync.Mutex
+	uids []uint
+}
+
+func (n *lockedNotifier) Notify(_ context.Context, uid uint, _ uint, _ string, _ string) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.uids = append(n.uids, uid)
+	return nil
+}
+
+func (n *lockedNotifier) notified() []uint {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return append([]uint(nil), n.uids...)
+}
+
+// waitFor は条件を満たすまで待ち、時間内に満たさない場合はテストを失敗させます。
+func waitFor(t *testing.T, what string, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// TestSignWebhook は署名がタイムスタンプと本文のHMAC-SHA256であることを確認するテストです。
+func TestSignWebhook(t *testing.T) {
+	body := []byte(`{"event":"board.created"}`)
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte("1700000000." + string(body)))
+	want := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	if got := utils.SignWebhook("secret", 1700000000, body); got != want {
+		t.Errorf("SignWebhook() = %q, want %q", got, want)
+	}
+	if got := utils.SignWebhook("other", 1700000000, body); got == want {
+		t.Error("SignWebhook() with another secret returned the same signature")
+	}
+}
+
+// TestHTTPWebhookSender は送信先にイベントの種類・ID・バージョンと検証できる署名が届き、2xx以外の応答が失敗になることを確認するテストです。
+func TestHTTPWebhookSender(t *testing.T) {
+	cases := []struct {
+		name    string
+		status  int
+		wantErr bool
+	}{
+		{"Success", http.StatusNoContent, false},
+		{"Server error", http.StatusInternalServerError, true},
+		{"Redirect is not followed", http.StatusFound, true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var header http.Header
+			var body []byte
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				header = r.Header.Clone()
+				body, _ = io.ReadAll(r.Body)
+				if tc.status == http.StatusFound {
+					w.Header().Set("Location", "/elsewhere")
+				}
+				w.WriteHeader(tc.status)
+			}))
+			defer server.Close()
+
+			message := utils.WebhookMessage{
+				URL:        server.URL,
+				Secret:     "0123456789abcdef",
+				Event:      "board.created",
+				DeliveryID: "delivery-1",
+				Version:    1,
+				Body:       []byte(`{"id":"delivery-1"}`),
+			}
+			status, err := utils.NewHTTPWebhookSender(true).Send(context.Background(), message)
+			if status != tc.status {
+				t.Errorf("status = %d, want %d", status, tc.status)
+			}
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Send() error = %v, wantErr %v", err, tc.wantErr)
+			}
+
+			if got := header.Get("X-Minori-Event"); got != "board.created" {
+				t.Errorf("X-Minori-Event = %q", got)
+			}
+			if got := header.Get("X-Minori-Delivery"); got != "delivery-1" {
+				t.Errorf("X-Minori-Delivery = %q", got)
+			}
+			if got := header.Get("X-Minori-Webhook-Version"); got != "1" {
+				t.Errorf("X-Minori-Webhook-Version = %q", got)
+			}
+			timestamp, err := strconv.ParseInt(header.Get("X-Minori-Timestamp"), 10, 64)
+			if err != nil {
+				t.Fatalf("X-Minori-Timestamp = %q", header.Get("X-Minori-Timestamp"))
+			}
+			if got, want := header.Get("X-Minori-Signature"), utils.SignWebhook(message.Secret, timestamp, body); got != want {
+				t.Errorf("X-Minori-Signature = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+// TestHTTPWebhookSenderRejectsPrivateAddress は既定ではループバックのアドレスに送信しないことを確認するテストです。
+func TestHTTPWebhookSenderRejectsPrivateAddress(t *testing.T) {
+	called := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer server.Close()
+
+	_, err := utils.NewHTTPWebhookSender(false).Send(context.Background(), utils.WebhookMessage{URL: server.URL, Body: []byte("{}")})
+	if !errors.Is(err, utils.ErrPrivateAddress) {
+		t.Errorf("Send() error = %v, want ErrPrivateAddress", err)
+	}
+	if called {
+		t.Error("request reached a loopback address")
+	}
+}
+
+// TestWebhookManagement はWebhookの管理がクラスの管理者に限られ、秘密鍵が登録時のみ返されることを確認するテストです。
+func TestWebhookManagement(t *testing.T) {
+	ctx := context.Background()
+	repo := newMemoryWebhookRepo(models.Webhook{ID: 1, CID: 2, URL: "https://example.com/other", Secret: "secret", Events: "board.created", Enabled: true})
+	service := services.NewWebhookService(repo, &webhookClassUserRepo{admins: map[uint]bool{1: true}}, nil, services.WebhookConfig{})
+	request := dto.WebhookRequest{URL: "https://example.com/hooks", Events: []string{"board.created", "member.joined", "board.created"}}
+
+	if _, err := service.CreateWebhook(ctx, 2, 1, request); !errors.Is(err, services.ErrUnauthorized) {
+		t.Errorf("CreateWebhook() by a non-admin error = %v, want ErrUnauthorized", err)
+	}
+	invalid := request
+	invalid.URL = "ftp://example.com/hooks"
+	if _, err := service.CreateWebhook(ctx, 1, 1, invalid); !errors.Is(err, services.ErrInvalidWebhookURL) {
+		t.Errorf("CreateWebhook() with ftp error = %v, want ErrInvalidWebhookURL", err)
+	}
+
+	created, err := service.CreateWebhook(ctx, 1, 1, request)
+	if err != nil {
+		t.Fatalf("CreateWebhook() error = %v", err)
+	}
+	if len(created.Secret) < 16 {
+		t.Errorf("generated secret = %q, want at least 16 characters", created.Secret)
+	}
+	if len(created.Events) != 2 || !created.Enabled {
+		t.Errorf("created = %+v, want 2 unique events and enabled", created)
+	}
+
+	webhooks, err := service.GetWebhooks(ctx, 1, 1)
+	if err != nil {
+		t.Fatalf("GetWebhooks() error = %v", err)
+	}
+	if len(webhooks) != 1 || webhooks[0].Secret != "" {
+		t.Errorf("GetWebhooks() = %+v, want the class's webhook without its secret", webhooks)
+	}
+
+	if err := service.DeleteWebhook(ctx, 1, 1, 1); !errors.Is(err, services.ErrNotFound) {
+		t.Errorf("DeleteWebhook() of another class's webhook error = %v, want ErrNotFound", err)
+	}
+	if _, err := service.GetDeliveries(ctx, 2, 1, created.ID); !errors.Is(err, services.ErrUnauthorized) {
+		t.Errorf("GetDeliveries() by a non-admin error = %v, want ErrUnauthorized", err)
+	}
+}
+
+// TestWebhookDelivery は失敗した送信を同じイベントのIDで再送し、全ての送信を記録することを確認するテストです。
+func TestWebhookDelivery(t *testing.T) {
+	repo := newMemoryWebhookRepo(models.Webhook{ID: 1, CID: 1, URL: "https://example.com/hooks", Secret: "secret", Events: "board.created", Enabled: true, ConsecutiveFailures: 3})
+	sender := &scriptedWebhookSender{failures: 2}
+	service := services.NewWebhookService(repo, &webhookClassUserRepo{}, nil, services.WebhookConfig{Sender: sender, RetryDelay: time.Millisecond})
+
+	service.PublishWebhook(context.Background(), 1, models.MemberJoinedWebhook, dto.WebhookPayloadDTO{Member: &dto.WebhookMemberV1{UID: 2, Role: "USER"}})
+	service.PublishWebhook(context.Background(), 1, models.BoardCreatedWebhook, dto.WebhookPayloadDTO{Board: &dto.WebhookBoardV1{ID: 5, Title: "課題"}})
+
+	waitFor(t, "3 deliveries", func() bool {
+		deliveries, _ := repo.snapshot()
+		return len(deliveries) == 3
+	})
+	deliveries, webhooks := repo.snapshot()
+	for i, delivery := range deliveries {
+		if delivery.Attempt != i+1 || delivery.Event != models.BoardCreatedWebhook || delivery.DeliveryID != deliveries[0].DeliveryID {
+			t.Errorf("delivery %d = %+v, want attempt %d of the same board.created event", i, delivery, i+1)
+		}
+	}
+	if deliveries[0].StatusCode != http.StatusInternalServerError || deliveries[0].Error == "" || deliveries[2].StatusCode != http.StatusOK {
+		t.Errorf("deliveries = %+v, want two failures followed by a success", deliveries)
+	}
+	if webhooks[1].ConsecutiveFailures != 0 {
+		t.Errorf("ConsecutiveFailures = %d, want reset to 0 after a success", webhooks[1].ConsecutiveFailures)
+	}
+
+	var payload dto.WebhookPayloadDTO
+	if err := json.Unmarshal(sender.sent()[0].Body, &payload); err != nil {
+		t.Fatalf("payload is not JSON: %v", err)
+	}
+	if payload.Event != "board.created" || payload.Version != dto.WebhookPayloadVersion || payload.CID != 1 || payload.ID != deliveries[0].DeliveryID || payload.Board == nil || payload.Member != nil {
+		t.Errorf("payload = %+v", payload)
+	}
+}
+
+// TestWebhookAutoDisable は再送しても配信できないイベントが上限まで続くとWebhookを無効にし、管理者に通知することを確認するテストです。
+func TestWebhookAutoDisable(t *testing.T) {
+	repo := newMemoryWebhookRepo(models.Webhook{ID: 1, CID: 1, URL: "https://example.com/hooks", Secret: "secret", Events: "attendance.changed", Enabled: true})
+	sender := &scriptedWebhookSender{failures: -1}
+	notifier := &lockedNotifier{}
+	service := services.NewWebhookService(repo, &webhookClassUserRepo{admins: map[uint]bool{7: true}}, notifier, services.WebhookConfig{Sender: sender, RetryDelay: time.Millisecond, FailureLimit: 2})
+
+	for i := 0; i < 2; i++ {
+		service.PublishWebhook(context.Background(), 1, models.AttendanceChangedWebhook, dto.WebhookPayloadDTO{Attendance: &dto.WebhookAttendanceV1{Action: dto.AttendanceReset, CSID: 3}})
+	}
+
+	waitFor(t, "the webhook to be disabled", func() bool {
+		_, webhooks := repo.snapshot()
+		return !webhooks[1].Enabled
+	})
+	waitFor(t, "the admin to be notified", func() bool {
+		return len(notifier.notified()) == 1
+	})
+	_, webhooks := repo.snapshot()
+	if webhooks[1].DisabledAt == nil || webhooks[1].ConsecutiveFailures != 2 {
+		t.Errorf("webhook = %+v, want disabled after 2 failed events", webhooks[1])
+	}
+	if got := notifier.notified(); got[0] != 7 {
+		t.Errorf("notified = %v, want [7]", got)
+	}
+	if got := len(sender.sent()); got != 10 {
+		t.Errorf("sent %d requests, want 5 attempts for each of 2 events", got)
+	}
+
+	service.PublishWebhook(context.Background(), 1, models.AttendanceChangedWebhook, dto.WebhookPayloadDTO{Attendance: &dto.WebhookAttendanceV1{Action: dto.AttendanceReset, CSID: 3}})
+	time.Sleep(20 * time.Millisecond)
+	if got := len(sender.sent()); got != 10 {
+		t.Errorf("sent %d requests after disabling, want 10", got)
+	}
+}
@@ -0,0 +1,100 @@
+package utils
+
+import (
+	"fmt"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/constants"
+)
+
+// AppError HTTPステータスと機械判読用コードを持つアプリケーションエラー
+type AppError struct {
+	Status  int
+	Code    string
+	Message string
+	Details map[string]interface{}
+	Err     error
+}
+
+// ErrorResponse エラーレスポンスのJSON形式
+type ErrorResponse struct {
+	Error     string                 `json:"error"`
+	Code      string                 `json:"code"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+	RequestID string                 `json:"request_id,omitempty"`
+}
+
+// NewAppError AppErrorを生成
+func NewAppError(status int, code string, message string) *AppError {
+	return &AppError{
+		Status:  status,
+		Code:    code,
+		Message: message,
+	}
+}
+
+// Error エラーメッセージを返す
+func (e *AppError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %s: %v", e.Code, e.Message, e.Err)
+	}
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+// Unwrap ラップされた元のエラーを返す
+func (e *AppError) Unwrap() error {
+	return e.Err
+}
+
+// WithDetails 詳細情報を付与したAppErrorを返す
+func (e *AppError) WithDetails(details map[string]interface{}) *AppError {
+	copied := *e
+	copied.Details = details
+	return &copied
+}
+
+// Wrap 元のエラーをラップしたAppErrorを返す
+func (e *AppError) Wrap(err error) *AppError {
+	copied := *e
+	copied.Err = err
+	return &copied
+}
+
+// Response レスポンス用のボディを生成
+func (e *AppError) Response(requestID string) ErrorResponse {
+	return ErrorResponse{
+		Error:     e.Message,
+		Code:      e.Code,
+		Details:   e.Details,
+		RequestID: requestID,
+	}
+}
+
+// NewBadRequestError 400エラーを生成
+func NewBadRequestError(code string, message string) *AppError {
+	return NewAppError(constants.StatusBadRequest, code, message)
+}
+
+// NewUnauthorizedError 401エラーを生成
+func NewUnauthorizedError(code string, message string) *AppError {
+	return NewAppError(constants.StatusUnauthorized, code, message)
+}
+
+// NewForbiddenError 403エラーを生成
+func NewForbiddenError(code string, message string) *AppError {
+	return NewAppError(constants.StatusForbidden, code, message)
+}
+
+// NewNotFoundError 404エラーを生成
+func NewNotFoundError(code string, message string) *AppError {
+	return NewAppError(constants.StatusNotFound, code, message)
+}
+
+// NewConflictError 409エラーを生成
+func NewConflictError(code string, message string) *AppError {
+	return NewAppError(constants.StatusConflict, code, message)
+}
+
+// NewInternalError 500エラーを生成
+func NewInternalError(err error) *AppError {
+	return NewAppError(constants.StatusInternalServerError, constants.ErrCodeInternal, constants.InternalServerError).Wrap(err)
+}
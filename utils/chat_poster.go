@@ -0,0 +1,121 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	chatPostTimeout = 10 * time.Second
+	// chatBodyLimit 投稿する本文の最大文字数。見出しとリンクを加えてもDiscordの上限の2000文字に収まるようにする
+	chatBodyLimit = 1000
+)
+
+// ChatMessage チャットサービスに投稿する1つのメッセージ
+type ChatMessage struct {
+	Title string
+	// Author 投稿者のクラスでのニックネーム。空の場合は表示しない
+	Author string
+	Body   string
+	// Link アプリで詳細を表示するURL。空の場合は表示しない
+	Link string
+}
+
+// ChatPoster チャットサービスのWebhookにメッセージを投稿し、投稿先が返したステータスコードを返す。応答がなかった場合は0を返す
+type ChatPoster interface {
+	Post(ctx context.Context, webhookURL string, message ChatMessage) (int, error)
+}
+
+// chatPoster チャットサービスごとの形式に変換したメッセージをJSONのPOSTで投稿するChatPoster
+type chatPoster struct {
+	client *http.Client
+	encode func(message ChatMessage) interface{}
+}
+
+// NewSlackPoster SlackのIncoming Webhookに投稿するChatPosterを生成する。10秒でタイムアウトし、リダイレクトは追わない。
+// allowPrivateNetworksがfalseの場合、ループバックやプライベートネットワークのアドレスには接続しない
+func NewSlackPoster(allowPrivateNetworks bool) ChatPoster {
+	return &chatPoster{client: newOutboundHTTPClient(chatPostTimeout, allowPrivateNetworks), encode: encodeSlackMessage}
+}
+
+// NewDiscordPoster DiscordのWebhookに投稿するChatPosterを生成する。タイムアウトと接続先の制限はNewSlackPosterと同じ
+func NewDiscordPoster(allowPrivateNetworks bool) ChatPoster {
+	return &chatPoster{client: newOutboundHTTPClient(chatPostTimeout, allowPrivateNetworks), encode: encodeDiscordMessage}
+}
+
+// Post メッセージを投稿する。2xx以外のステータスコードはエラーにする
+func (p *chatPoster) Post(ctx context.Context, webhookURL string, message ChatMessage) (int, error) {
+	body, err := json.Marshal(p.encode(message))
+	if err != nil {
+		return 0, err
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	response, err := p.client.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+	reply, _ := io.ReadAll(io.LimitReader(response.Body, 512))
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return response.StatusCode, fmt.Errorf("unexpected status %d: %s", response.StatusCode, strings.TrimSpace(string(reply)))
+	}
+	return response.StatusCode, nil
+}
+
+// encodeSlackMessage 見出しを太字にし、リンクを付けたmrkdwnのテキストに変換する
+func encodeSlackMessage(message ChatMessage) interface{} {
+	lines := []string{"*" + escapeSlackText(message.Title) + "*"}
+	if message.Author != "" {
+		lines = append(lines, "投稿者: "+escapeSlackText(message.Author))
+	}
+	if message.Body != "" {
+		lines = append(lines, escapeSlackText(truncateRunes(message.Body, chatBodyLimit)))
+	}
+	if message.Link != "" {
+		lines = append(lines, "<"+message.Link+"|アプリで開く>")
+	}
+	return map[string]string{"text": strings.Join(lines, "\n")}
+}
+
+// escapeSlackText mrkdwnで制御文字として扱われる文字をエスケープする
+func escapeSlackText(text string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(text)
+}
+
+// encodeDiscordMessage 見出しを太字にし、リンクを付けたMarkdownのテキストに変換する。本文中のメンションは通知しない
+func encodeDiscordMessage(message ChatMessage) interface{} {
+	lines := []string{"**" + message.Title + "**"}
+	if message.Author != "" {
+		lines = append(lines, "投稿者: "+message.Author)
+	}
+	if message.Body != "" {
+		lines = append(lines, truncateRunes(message.Body, chatBodyLimit))
+	}
+	if message.Link != "" {
+		lines = append(lines, "<"+message.Link+">")
+	}
+	return map[string]interface{}{
+		"content":          strings.Join(lines, "\n"),
+		"allowed_mentions": map[string][]string{"parse": {}},
+	}
+}
+
+// truncateRunes 文字数がlimitを超える場合は末尾を「…」にして切り詰める
+func truncateRunes(text string, limit int) string {
+	runes := []rune(text)
+	if len(runes) <= limit {
+		return text
+	}
+	return string(append(runes[:limit-1], '…'))
+}
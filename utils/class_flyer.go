@@ -0,0 +1,176 @@
+package utils
+
+import (
+	_ "embed"
+	"fmt"
+	"strings"
+
+	"github.com/signintech/gopdf"
+)
+
+// 配布用PDFの寸法(ポイント)。用紙はA4
+const (
+	flyerPageWidth = 595.0
+	flyerMargin    = 60.0
+	// flyerQRSize 参加用QRコードの一辺の長さ。教室の後ろの席からでも読み取れる大きさにする
+	flyerQRSize = 220.0
+	// flyerQuietZone QRコードの周囲に空けるモジュール数
+	flyerQuietZone = 4
+	// flyerMaxDescriptionLines 説明文の最大行数。超えた分は省略する
+	flyerMaxDescriptionLines = 8
+	// flyerFontFamily 埋め込むフォントのgopdfでの名前
+	flyerFontFamily = "mplus-1p"
+)
+
+// flyerFont 配布用PDFに埋め込む日本語フォント(M+ 1p)。使った文字のみをサブセットにして埋め込む。ライセンスはfonts/LICENSEを参照
+//
+//go:embed fonts/mplus-1p-regular.ttf
+var flyerFont []byte
+
+// ClassFlyer 配布用PDFに差し込むクラスの情報
+type ClassFlyer struct {
+	ClassName   string
+	Description string
+	// JoinCode 参加用のクラスコード
+	JoinCode string
+	// JoinURL QRコードに格納する招待リンク。空の場合はクラスコードを格納する
+	JoinURL string
+	// SecretRequired 参加にシークレットが必要か。シークレット自体は印刷しない
+	SecretRequired bool
+}
+
+// BuildClassFlyerPDF クラス名・説明・参加用QRコードを載せたA4の配布用PDFを生成する。
+// 日本語はPDFビューアのフォントに頼らず、埋め込んだM+ 1pで表示する
+func BuildClassFlyerPDF(flyer ClassFlyer) ([]byte, error) {
+	qrData := flyer.JoinURL
+	if qrData == "" {
+		qrData = flyer.JoinCode
+	}
+	qr, err := EncodeQRCode(qrData)
+	if err != nil {
+		return nil, err
+	}
+
+	pdf := &gopdf.GoPdf{}
+	pdf.Start(gopdf.Config{PageSize: *gopdf.PageSizeA4, Unit: gopdf.UnitPT})
+	pdf.AddPage()
+	option := gopdf.TtfOption{OnGlyphNotFoundSubstitute: func(rune) rune { return '〓' }}
+	if err := pdf.AddTTFFontDataWithOption(flyerFontFamily, flyerFont, option); err != nil {
+		return nil, fmt.Errorf("loading flyer font: %w", err)
+	}
+
+	w := &flyerWriter{pdf: pdf}
+	y := flyerMargin + 20
+	w.textCentered("クラス参加のご案内", 16, y)
+	y += 56
+	for _, line := range w.wrap(flyer.ClassName, 28, flyerPageWidth-flyerMargin*2, 2) {
+		w.textCentered(line, 28, y)
+		y += 36
+	}
+	y += 4
+	for _, line := range w.wrap(flyer.Description, 12, flyerPageWidth-flyerMargin*2, flyerMaxDescriptionLines) {
+		w.textCentered(line, 12, y)
+		y += 18
+	}
+
+	y += 20
+	writeFlyerQRCode(pdf, qr, (flyerPageWidth-flyerQRSize)/2, y, flyerQRSize)
+
+	y += flyerQRSize + 40
+	w.textCentered("クラスコード: "+flyer.JoinCode, 20, y)
+	y += 30
+	w.textCentered("スマートフォンでQRコードを読み取るか、クラスコードを入力して参加してください。", 11, y)
+	if flyer.SecretRequired {
+		y += 18
+		w.textCentered("参加にはシークレットが必要です。講師からお知らせします。", 11, y)
+	}
+	if w.err != nil {
+		return nil, w.err
+	}
+
+	return pdf.GetBytesPdfReturnErr()
+}
+
+// flyerWriter 配布用PDFに文字列を書く。最初に起きたエラーを保持し、以降は何も書かない
+type flyerWriter struct {
+	pdf *gopdf.GoPdf
+	err error
+}
+
+// textCentered 上端からyの高さに文字列を中央揃えで書く。yは文字列のベースライン
+func (w *flyerWriter) textCentered(text string, size float64, y float64) {
+	if w.err != nil {
+		return
+	}
+	width := w.width(text, size)
+	if w.err != nil {
+		return
+	}
+	w.pdf.SetXY((flyerPageWidth-width)/2, y)
+	w.err = w.pdf.Text(text)
+}
+
+// width フォントの大きさsizeでの文字列の幅を返す
+func (w *flyerWriter) width(text string, size float64) float64 {
+	if w.err != nil {
+		return 0
+	}
+	if w.err = w.pdf.SetFont(flyerFontFamily, "", size); w.err != nil {
+		return 0
+	}
+	width, err := w.pdf.MeasureTextWidth(text)
+	if err != nil {
+		w.err = err
+	}
+	return width
+}
+
+// wrap フォントの大きさsizeで幅maxWidthに収まるように文字列を折り返す
+func (w *flyerWriter) wrap(text string, size float64, maxWidth float64, maxLines int) []string {
+	return wrapFlyerText(text, func(s string) float64 { return w.width(s, size) }, maxWidth, maxLines)
+}
+
+// writeFlyerQRCode 左上(x, y)から一辺sizeの正方形にQRコードを描く
+func writeFlyerQRCode(pdf *gopdf.GoPdf, qr *QRCode, x, y, size float64) {
+	module := size / float64(qr.Size+flyerQuietZone*2)
+	pdf.SetFillColor(0, 0, 0)
+	for row := 0; row < qr.Size; row++ {
+		for col := 0; col < qr.Size; col++ {
+			if !qr.Modules[row][col] {
+				continue
+			}
+			left := x + float64(col+flyerQuietZone)*module
+			top := y + float64(row+flyerQuietZone)*module
+			pdf.RectFromUpperLeftWithStyle(left, top, module, module, "F")
+		}
+	}
+}
+
+// wrapFlyerText widthで測った幅がmaxWidthに収まるように文字列を折り返す。maxLinesを超える場合は最後の行を…で省略する
+func wrapFlyerText(text string, width func(string) float64, maxWidth float64, maxLines int) []string {
+	var lines []string
+	for _, paragraph := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		var line []rune
+		for _, r := range strings.TrimSpace(paragraph) {
+			if width(string(append(line, r))) > maxWidth {
+				lines = append(lines, string(line))
+				line = nil
+			}
+			line = append(line, r)
+		}
+		lines = append(lines, string(line))
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	if len(lines) > maxLines {
+		lines = lines[:maxLines]
+		last := []rune(lines[maxLines-1])
+		for len(last) > 0 && width(string(last)+"…") > maxWidth {
+			last = last[:len(last)-1]
+		}
+		lines[maxLines-1] = string(last) + "…"
+	}
+	return lines
+}
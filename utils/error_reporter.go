@@ -0,0 +1,71 @@
+package utils
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// ErrorEvent 外部のエラー監視サービスに送信するエラーの情報
+type ErrorEvent struct {
+	Err       error
+	Stack     []byte
+	RequestID string
+	Method    string
+	Route     string
+	URL       string
+	UID       uint
+	Headers   map[string]string
+	Tags      map[string]string
+	Timestamp time.Time
+}
+
+// ErrorReporter パニックや想定外のエラーを外部のエラー監視サービスに送信する
+type ErrorReporter interface {
+	Report(event ErrorEvent)
+}
+
+// noopErrorReporter DSNが設定されていない場合に使用する何もしないErrorReporter
+type noopErrorReporter struct{}
+
+func (noopErrorReporter) Report(ErrorEvent) {}
+
+var (
+	defaultErrorReporter   ErrorReporter = noopErrorReporter{}
+	defaultErrorReporterMu sync.RWMutex
+)
+
+// NewErrorReporter DSNからErrorReporterを生成する。DSNが空の場合は何もしないErrorReporterを返す
+func NewErrorReporter(dsn string, environment string) (ErrorReporter, error) {
+	if dsn == "" {
+		return noopErrorReporter{}, nil
+	}
+	return newSentryReporter(dsn, environment)
+}
+
+// SetDefaultErrorReporter バックグラウンド処理のエラー送信に使うErrorReporterを設定する
+func SetDefaultErrorReporter(reporter ErrorReporter) {
+	defaultErrorReporterMu.Lock()
+	defer defaultErrorReporterMu.Unlock()
+	defaultErrorReporter = reporter
+}
+
+// DefaultErrorReporter 設定されたErrorReporterを返す
+func DefaultErrorReporter() ErrorReporter {
+	defaultErrorReporterMu.RLock()
+	defer defaultErrorReporterMu.RUnlock()
+	return defaultErrorReporter
+}
+
+// ReportBackgroundError リクエストに紐付かないゴルーチンのエラーをログに出力し、エラー監視サービスに送信する
+func ReportBackgroundError(task string, err error) {
+	if err == nil {
+		return
+	}
+	log.Printf("Background task %s failed: %v", task, err)
+	DefaultErrorReporter().Report(ErrorEvent{
+		Err:       err,
+		Tags:      map[string]string{"task": task},
+		Timestamp: time.Now(),
+	})
+}
@@ -0,0 +1,179 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+const (
+	// GoogleCalendarEventsEndpoint ユーザーのメインのカレンダーの予定を操作するGoogle Calendar APIのURL
+	GoogleCalendarEventsEndpoint = "https://www.googleapis.com/calendar/v3/calendars/primary/events"
+	// GoogleCalendarEventsScope カレンダーの予定を作成・変更するためのOAuth 2.0のスコープ
+	GoogleCalendarEventsScope = "https://www.googleapis.com/auth/calendar.events"
+	googleCalendarTimeout     = 15 * time.Second
+)
+
+// CalendarEvent Googleカレンダーに作成する予定
+type CalendarEvent struct {
+	Summary     string
+	Description string
+	Location    string
+	Start       time.Time
+	End         time.Time
+	// Transparent 予定の時間を空き時間として扱う。休講の予定に使う
+	Transparent bool
+}
+
+// CalendarAPIError Google Calendar APIが2xx以外を返したエラー
+type CalendarAPIError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *CalendarAPIError) Error() string {
+	return fmt.Sprintf("google calendar returned %d: %s", e.StatusCode, e.Body)
+}
+
+// Retryable 時間を置いて再送すれば成功する可能性のあるエラーか
+func (e *CalendarAPIError) Retryable() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+// GoogleCalendarClient ユーザーのGoogleカレンダーの予定を作成・変更・削除する。clientはユーザーのトークンで認証するHTTPクライアント
+type GoogleCalendarClient interface {
+	// UpsertEvent eventIDの予定を変更し、予定のIDを返す。eventIDが空の場合と、ユーザーが予定を削除していた場合は作成する
+	UpsertEvent(ctx context.Context, client *http.Client, eventID string, event CalendarEvent) (string, error)
+	// DeleteEvent 予定を削除する。削除済みの場合は何もしない
+	DeleteEvent(ctx context.Context, client *http.Client, eventID string) error
+}
+
+// googleCalendarClient Google Calendar API v3のGoogleCalendarClient
+type googleCalendarClient struct {
+	endpoint string
+}
+
+// NewGoogleCalendarClient endpointの予定のAPIを呼ぶGoogleCalendarClientを生成する。通常はGoogleCalendarEventsEndpointを指定する
+func NewGoogleCalendarClient(endpoint string) GoogleCalendarClient {
+	return &googleCalendarClient{endpoint: strings.TrimRight(endpoint, "/")}
+}
+
+// HasCalendarScope トークンが予定を操作する権限を許可されているか
+func HasCalendarScope(token *oauth2.Token) bool {
+	if token == nil {
+		return false
+	}
+	scope, _ := token.Extra("scope").(string)
+	for _, granted := range strings.Fields(scope) {
+		if granted == GoogleCalendarEventsScope {
+			return true
+		}
+	}
+	return false
+}
+
+// calendarEventTime Google Calendar APIの予定の日時
+type calendarEventTime struct {
+	DateTime string `json:"dateTime"`
+}
+
+// calendarEventRequest Google Calendar APIの予定
+type calendarEventRequest struct {
+	Summary      string            `json:"summary"`
+	Description  string            `json:"description"`
+	Location     string            `json:"location"`
+	Start        calendarEventTime `json:"start"`
+	End          calendarEventTime `json:"end"`
+	Transparency string            `json:"transparency"`
+	Status       string            `json:"status"`
+}
+
+// calendarEventResponse Google Calendar APIが返す予定
+type calendarEventResponse struct {
+	ID string `json:"id"`
+}
+
+// UpsertEvent eventIDの予定を変更し、予定のIDを返す。eventIDが空の場合と、予定が見つからない場合は作成する
+func (c *googleCalendarClient) UpsertEvent(ctx context.Context, client *http.Client, eventID string, event CalendarEvent) (string, error) {
+	transparency := "opaque"
+	if event.Transparent {
+		transparency = "transparent"
+	}
+	body, err := json.Marshal(calendarEventRequest{
+		Summary:      event.Summary,
+		Description:  event.Description,
+		Location:     event.Location,
+		Start:        calendarEventTime{DateTime: event.Start.Format(time.RFC3339)},
+		End:          calendarEventTime{DateTime: event.End.Format(time.RFC3339)},
+		Transparency: transparency,
+		// ユーザーがカレンダーから削除した予定も、PATCHで元に戻す
+		Status: "confirmed",
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if eventID != "" {
+		var updated calendarEventResponse
+		err := c.do(ctx, client, http.MethodPatch, c.endpoint+"/"+url.PathEscape(eventID), body, &updated)
+		var apiErr *CalendarAPIError
+		if err == nil || !errors.As(err, &apiErr) || (apiErr.StatusCode != http.StatusNotFound && apiErr.StatusCode != http.StatusGone) {
+			return updated.ID, err
+		}
+	}
+	var created calendarEventResponse
+	if err := c.do(ctx, client, http.MethodPost, c.endpoint, body, &created); err != nil {
+		return "", err
+	}
+	return created.ID, nil
+}
+
+// DeleteEvent 予定を削除する。見つからない場合は削除済みとして扱う
+func (c *googleCalendarClient) DeleteEvent(ctx context.Context, client *http.Client, eventID string) error {
+	err := c.do(ctx, client, http.MethodDelete, c.endpoint+"/"+url.PathEscape(eventID), nil, nil)
+	var apiErr *CalendarAPIError
+	if errors.As(err, &apiErr) && (apiErr.StatusCode == http.StatusNotFound || apiErr.StatusCode == http.StatusGone) {
+		return nil
+	}
+	return err
+}
+
+// do APIを呼び、2xxの場合は応答をresultに読み込む
+func (c *googleCalendarClient) do(ctx context.Context, client *http.Client, method string, endpoint string, body []byte, result interface{}) error {
+	ctx, cancel := context.WithTimeout(ctx, googleCalendarTimeout)
+	defer cancel()
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return &CalendarAPIError{StatusCode: resp.StatusCode, Body: strings.TrimSpace(string(snippet))}
+	}
+	if result == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
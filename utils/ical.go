@@ -0,0 +1,78 @@
+package utils
+
+import (
+	"strings"
+	"time"
+)
+
+const icalTimeFormat = "20060102T150405Z"
+
+// ICalEvent iCalendarのイベント
+type ICalEvent struct {
+	UID      string
+	Summary  string
+	Start    time.Time
+	End      time.Time
+	Location string
+	// LocationType X-MINORI-LOCATION-TYPEとして出力する場所の種類。スケジュールの出席方式を指定する
+	LocationType string
+}
+
+// BuildICalendar イベントからiCalendar(RFC 5545)形式の文字列を生成
+func BuildICalendar(calendarName string, events []ICalEvent) string {
+	var b strings.Builder
+	writeICalLine(&b, "BEGIN:VCALENDAR")
+	writeICalLine(&b, "VERSION:2.0")
+	writeICalLine(&b, "PRODID:-//minori//class schedule//JA")
+	writeICalLine(&b, "CALSCALE:GREGORIAN")
+	writeICalLine(&b, "METHOD:PUBLISH")
+	writeICalLine(&b, "X-WR-CALNAME:"+escapeICalText(calendarName))
+
+	stamp := time.Now().UTC().Format(icalTimeFormat)
+	for _, event := range events {
+		writeICalLine(&b, "BEGIN:VEVENT")
+		writeICalLine(&b, "UID:"+event.UID)
+		writeICalLine(&b, "DTSTAMP:"+stamp)
+		writeICalLine(&b, "DTSTART:"+event.Start.UTC().Format(icalTimeFormat))
+		writeICalLine(&b, "DTEND:"+event.End.UTC().Format(icalTimeFormat))
+		writeICalLine(&b, "SUMMARY:"+escapeICalText(event.Summary))
+		if event.Location != "" {
+			writeICalLine(&b, "LOCATION:"+escapeICalText(event.Location))
+		}
+		if event.LocationType != "" {
+			writeICalLine(&b, "X-MINORI-LOCATION-TYPE:"+escapeICalText(event.LocationType))
+		}
+		writeICalLine(&b, "END:VEVENT")
+	}
+
+	writeICalLine(&b, "END:VCALENDAR")
+	return b.String()
+}
+
+// escapeICalText TEXT値の特殊文字をエスケープ
+func escapeICalText(s string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+	return replacer.Replace(s)
+}
+
+// writeICalLine 75オクテットを超える行を折り返してCRLFで書き込む
+func writeICalLine(b *strings.Builder, line string) {
+	const limit = 75
+	for len(line) > limit {
+		cut := limit
+		// マルチバイト文字の途中で折り返さない
+		for cut > 0 && !isRuneStart(line[cut]) {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+	}
+	b.WriteString(line)
+	b.WriteString("\r\n")
+}
+
+// isRuneStart UTF-8の文字の先頭バイトかどうか
+func isRuneStart(c byte) bool {
+	return c&0xC0 != 0x80
+}
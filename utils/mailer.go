@@ -0,0 +1,195 @@
+package utils
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"log"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// smtpSendTimeout 1通のメールの接続から送信までの上限時間。ctxの期限の方が早い場合はctxの期限に従う
+const smtpSendTimeout = 30 * time.Second
+
+// Mail 送信するメール。HTMLが空の場合はテキストのみのメールにする
+type Mail struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+	// Template メールを作成したテンプレートの名前。テンプレートを使わないメールは空
+	Template string
+}
+
+// Mailer メールを送信する。送信先のアドレスが無効な場合はErrInvalidNotificationTargetを返す
+type Mailer interface {
+	Send(ctx context.Context, mail Mail) error
+}
+
+// smtpMailer SMTPサーバーからメールを送信するMailer
+type smtpMailer struct {
+	host string
+	addr string
+	auth smtp.Auth
+	from string
+}
+
+// NewSMTPMailer SMTPサーバーからメールを送信するMailerを生成する。userが空の場合は認証しない
+func NewSMTPMailer(host string, port int, user, password, from string) Mailer {
+	var auth smtp.Auth
+	if user != "" {
+		auth = smtp.PlainAuth("", user, password, host)
+	}
+	return &smtpMailer{host: host, addr: net.JoinHostPort(host, strconv.Itoa(port)), auth: auth, from: from}
+}
+
+// Send メールをUTF-8で送信する。HTMLがある場合はテキストとHTMLのmultipart/alternativeにする
+func (m *smtpMailer) Send(ctx context.Context, mail Mail) error {
+	// 改行を含むアドレスでヘッダーを追加されないようにする
+	if strings.ContainsAny(mail.To, "\r\n") {
+		return ErrInvalidNotificationTarget
+	}
+	headers := []string{
+		"From: " + m.from,
+		"To: " + mail.To,
+		"Subject: " + mime.BEncoding.Encode("UTF-8", mail.Subject),
+		"MIME-Version: 1.0",
+	}
+
+	var body []string
+	if mail.HTML == "" {
+		headers = append(headers, "Content-Type: text/plain; charset=UTF-8", "Content-Transfer-Encoding: 8bit")
+		body = []string{mail.Text}
+	} else {
+		boundary, err := mailBoundary()
+		if err != nil {
+			return err
+		}
+		headers = append(headers, `Content-Type: multipart/alternative; boundary="`+boundary+`"`)
+		body = []string{
+			"--" + boundary,
+			"Content-Type: text/plain; charset=UTF-8",
+			"Content-Transfer-Encoding: 8bit",
+			"",
+			mail.Text,
+			"--" + boundary,
+			"Content-Type: text/html; charset=UTF-8",
+			"Content-Transfer-Encoding: 8bit",
+			"",
+			mail.HTML,
+			"--" + boundary + "--",
+		}
+	}
+
+	message := strings.Join(append(append(headers, ""), body...), "\r\n")
+	return m.send(ctx, mail.To, []byte(message))
+}
+
+// send smtp.SendMailと同じ手順で送信する。SMTPサーバーが応答しない場合に処理が止まらないよう、
+// 接続と送受信にctxの期限とsmtpSendTimeoutを適用し、ctxがキャンセルされた場合は接続を閉じて中断する
+func (m *smtpMailer) send(ctx context.Context, to string, message []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, smtpSendTimeout)
+	defer cancel()
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", m.addr)
+	if err != nil {
+		return contextError(ctx, err)
+	}
+	deadline, _ := ctx.Deadline()
+	if err := conn.SetDeadline(deadline); err != nil {
+		conn.Close()
+		return err
+	}
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	client, err := smtp.NewClient(conn, m.host)
+	if err != nil {
+		conn.Close()
+		return contextError(ctx, err)
+	}
+	defer client.Close()
+	return contextError(ctx, m.transmit(client, to, message))
+}
+
+// transmit 接続したSMTPサーバーに認証してメールを送信する
+func (m *smtpMailer) transmit(client *smtp.Client, to string, message []byte) error {
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: m.host}); err != nil {
+			return err
+		}
+	}
+	if m.auth != nil {
+		if ok, _ := client.Extension("AUTH"); ok {
+			if err := client.Auth(m.auth); err != nil {
+				return err
+			}
+		}
+	}
+	if err := client.Mail(m.from); err != nil {
+		return err
+	}
+	if err := client.Rcpt(to); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(message); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// contextError ctxの期限切れやキャンセルで接続を閉じたために失敗した場合は、接続のエラーの代わりにctxのエラーを返す
+func contextError(ctx context.Context, err error) error {
+	if err != nil && ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+// mailBoundary 本文と重ならないmultipartの境界の文字列を生成する
+func mailBoundary() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// logMailer メールを送信せずにログに出力するMailer。SMTPサーバーを用意しない開発環境で使う
+type logMailer struct{}
+
+// NewLogMailer logMailerを生成
+func NewLogMailer() Mailer {
+	return logMailer{}
+}
+
+// Send 宛先とテンプレートの名前のみをログに出力する。件名と本文には検証用のリンクなどが含まれるため出力しない
+func (logMailer) Send(_ context.Context, mail Mail) error {
+	template := mail.Template
+	if template == "" {
+		template = "none"
+	}
+	log.Printf("Mail to %s (template: %s)", mail.To, template)
+	return nil
+}
@@ -0,0 +1,168 @@
+package utils
+
+import (
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2"
+	chromahtml "github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+)
+
+// markdownHighlightLimit シンタックスハイライトを行うコードブロックの最大のバイト数。大きなコードブロックはハイライトせずに出力する
+const markdownHighlightLimit = 20000
+
+var (
+	markdownFence      = regexp.MustCompile("^```\\s*([^`\\s]*)\\s*$")
+	markdownCodeLang   = regexp.MustCompile(`^[a-z0-9_+#.-]{1,20}$`)
+	markdownInlineCode = regexp.MustCompile("`([^`\n]+)`")
+	markdownLink       = regexp.MustCompile(`\[([^\[\]\n]+)\]\(([^()\s]+)\)`)
+	markdownBold       = regexp.MustCompile(`\*\*([^*\n]+?)\*\*`)
+	markdownItalic     = regexp.MustCompile(`\*([^*\n]+?)\*`)
+	markdownStrike     = regexp.MustCompile(`~~([^~\n]+?)~~`)
+
+	// markdownHighlighter コードをstyle属性ではなくclassで色分けしたspanに変換する。pre要素は出力しない
+	markdownHighlighter = chromahtml.New(chromahtml.WithClasses(true), chromahtml.PreventSurroundingPre(true))
+)
+
+// RenderMarkdown チャットのメッセージのMarkdownをHTMLに変換する。
+// 対応するのはコードブロック、引用、インラインコード、太字、斜体、取り消し線、http・httpsのリンクのみ。
+// 本文はすべてエスケープしてから決まったタグだけを出力するため、メッセージに含まれるHTMLやスクリプトはそのまま表示される。
+// コードブロックの言語はclassに"language-言語"として出力し、対応する言語のコードはサーバーでシンタックスハイライトする。
+// ハイライトしたコードブロックはpreにclass="chroma"を付け、トークンをPygments互換のclassを持つspanで囲むため、クライアントはPygments・Chromaのテーマのスタイルシートで色を付ける
+func RenderMarkdown(text string) string {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	var b strings.Builder
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		switch {
+		case markdownFence.MatchString(line):
+			lang := strings.ToLower(markdownFence.FindStringSubmatch(line)[1])
+			end := i + 1
+			for end < len(lines) && strings.TrimSpace(lines[end]) != "```" {
+				end++
+			}
+			writeMarkdownCodeBlock(&b, lang, lines[i+1:end])
+			i = end + 1
+		case isMarkdownQuote(line):
+			var quoted []string
+			for ; i < len(lines) && isMarkdownQuote(lines[i]); i++ {
+				quoted = append(quoted, strings.TrimPrefix(strings.TrimPrefix(lines[i], ">"), " "))
+			}
+			b.WriteString("<blockquote>")
+			writeMarkdownLines(&b, quoted)
+			b.WriteString("</blockquote>")
+		case strings.TrimSpace(line) == "":
+			i++
+		default:
+			var paragraph []string
+			for ; i < len(lines) && strings.TrimSpace(lines[i]) != "" && !isMarkdownQuote(lines[i]) && !markdownFence.MatchString(lines[i]); i++ {
+				paragraph = append(paragraph, lines[i])
+			}
+			b.WriteString("<p>")
+			writeMarkdownLines(&b, paragraph)
+			b.WriteString("</p>")
+		}
+	}
+	return b.String()
+}
+
+// isMarkdownQuote 引用の行か判定する
+func isMarkdownQuote(line string) bool {
+	return strings.HasPrefix(line, ">")
+}
+
+// writeMarkdownCodeBlock コードブロックを書き込む。言語名に使えない文字を含む場合は言語を指定しない。
+// 対応していない言語とハイライトに失敗したコードはエスケープのみ行う
+func writeMarkdownCodeBlock(b *strings.Builder, lang string, lines []string) {
+	code := strings.Join(lines, "\n")
+	if !markdownCodeLang.MatchString(lang) {
+		b.WriteString("<pre><code>" + html.EscapeString(code) + "</code></pre>")
+		return
+	}
+	class := ` class="language-` + html.EscapeString(lang) + `"`
+	if highlighted, ok := highlightMarkdownCode(lang, code); ok {
+		b.WriteString(`<pre class="chroma"><code` + class + ">" + highlighted + "</code></pre>")
+		return
+	}
+	b.WriteString("<pre><code" + class + ">" + html.EscapeString(code) + "</code></pre>")
+}
+
+// highlightMarkdownCode コードを言語の字句解析器でトークンに分け、classで色分けしたHTMLを返す。トークンの文字列はエスケープして出力される
+func highlightMarkdownCode(lang string, code string) (string, bool) {
+	lexer := lexers.Get(lang)
+	if lexer == nil || len(code) > markdownHighlightLimit {
+		return "", false
+	}
+	tokens, err := chroma.Coalesce(lexer).Tokenise(nil, code)
+	if err != nil {
+		return "", false
+	}
+	var b strings.Builder
+	if err := markdownHighlighter.Format(&b, styles.Fallback, tokens); err != nil {
+		return "", false
+	}
+	return b.String(), true
+}
+
+// writeMarkdownLines 行ごとにインラインの書式を変換し、改行で区切って書き込む
+func writeMarkdownLines(b *strings.Builder, lines []string) {
+	for i, line := range lines {
+		if i > 0 {
+			b.WriteString("<br>")
+		}
+		b.WriteString(renderMarkdownInline(line))
+	}
+}
+
+// renderMarkdownInline インラインコードの中は書式を変換せずにエスケープする
+func renderMarkdownInline(line string) string {
+	var b strings.Builder
+	last := 0
+	for _, m := range markdownInlineCode.FindAllStringSubmatchIndex(line, -1) {
+		b.WriteString(renderMarkdownLinks(line[last:m[0]]))
+		b.WriteString("<code>" + html.EscapeString(line[m[2]:m[3]]) + "</code>")
+		last = m[1]
+	}
+	b.WriteString(renderMarkdownLinks(line[last:]))
+	return b.String()
+}
+
+// renderMarkdownLinks リンクを変換する。http・https以外のURLはリンクにせず、そのまま表示する
+func renderMarkdownLinks(text string) string {
+	var b strings.Builder
+	last := 0
+	for _, m := range markdownLink.FindAllStringSubmatchIndex(text, -1) {
+		href, ok := safeMarkdownURL(text[m[4]:m[5]])
+		if !ok {
+			continue
+		}
+		b.WriteString(renderMarkdownEmphasis(text[last:m[0]]))
+		b.WriteString(`<a href="` + html.EscapeString(href) + `" rel="nofollow noopener noreferrer" target="_blank">`)
+		b.WriteString(renderMarkdownEmphasis(text[m[2]:m[3]]))
+		b.WriteString("</a>")
+		last = m[1]
+	}
+	b.WriteString(renderMarkdownEmphasis(text[last:]))
+	return b.String()
+}
+
+// safeMarkdownURL リンク先がホストを持つhttp・httpsのURLの場合のみ、正規化したURLを返す
+func safeMarkdownURL(raw string) (string, bool) {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", false
+	}
+	return u.String(), true
+}
+
+// renderMarkdownEmphasis エスケープしてから太字、取り消し線、斜体を変換する
+func renderMarkdownEmphasis(text string) string {
+	escaped := html.EscapeString(text)
+	escaped = markdownBold.ReplaceAllString(escaped, "<strong>$1</strong>")
+	escaped = markdownStrike.ReplaceAllString(escaped, "<del>$1</del>")
+	return markdownItalic.ReplaceAllString(escaped, "<em>$1</em>")
+}
@@ -0,0 +1,136 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/config"
+	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/constants"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// MultipartUploader 大容量ファイルをS3のマルチパートアップロードでパートごとにアップロードする
+type MultipartUploader interface {
+	CreateMultipartUpload(ctx context.Context, key string, contentType string) (string, error)
+	UploadPart(ctx context.Context, key string, uploadID string, partNumber int32, body io.Reader, size int64) (string, error)
+	CompleteMultipartUpload(ctx context.Context, key string, uploadID string, parts []UploadedPart) (string, error)
+	AbortMultipartUpload(ctx context.Context, key string, uploadID string) error
+}
+
+// UploadedPart アップロード済みのパート
+type UploadedPart struct {
+	PartNumber int32  `json:"part_number"`
+	ETag       string `json:"etag"`
+	Size       int64  `json:"size"`
+}
+
+// NewAwsMultipartUploader S3を使うMultipartUploaderを生成
+func NewAwsMultipartUploader(cfg config.AWSConfig) MultipartUploader {
+	return &awsUploader{cfg: cfg}
+}
+
+// CreateMultipartUpload マルチパートアップロードを開始し、S3のアップロードIDを返す
+func (u *awsUploader) CreateMultipartUpload(ctx context.Context, key string, contentType string) (string, error) {
+	s3Client, bucketName, err := u.s3ClientAndBucket()
+	if err != nil {
+		return "", err
+	}
+
+	input := &s3.CreateMultipartUploadInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(key),
+	}
+	if contentType != "" {
+		input.ContentType = aws.String(contentType)
+	}
+
+	output, err := s3Client.CreateMultipartUpload(ctx, input)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", constants.ErrUploadToS3JP, err)
+	}
+	return aws.ToString(output.UploadId), nil
+}
+
+// UploadPart パートをアップロードし、ETagを返す
+func (u *awsUploader) UploadPart(ctx context.Context, key string, uploadID string, partNumber int32, body io.Reader, size int64) (string, error) {
+	s3Client, bucketName, err := u.s3ClientAndBucket()
+	if err != nil {
+		return "", err
+	}
+
+	output, err := s3Client.UploadPart(ctx, &s3.UploadPartInput{
+		Bucket:        aws.String(bucketName),
+		Key:           aws.String(key),
+		UploadId:      aws.String(uploadID),
+		PartNumber:    aws.Int32(partNumber),
+		Body:          body,
+		ContentLength: aws.Int64(size),
+	})
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", constants.ErrUploadToS3JP, err)
+	}
+	return aws.ToString(output.ETag), nil
+}
+
+// CompleteMultipartUpload パートを結合してアップロードを完了し、ファイルのURLを返す。partsはパート番号順であること
+func (u *awsUploader) CompleteMultipartUpload(ctx context.Context, key string, uploadID string, parts []UploadedPart) (string, error) {
+	s3Client, bucketName, err := u.s3ClientAndBucket()
+	if err != nil {
+		return "", err
+	}
+
+	completedParts := make([]types.CompletedPart, 0, len(parts))
+	for _, part := range parts {
+		completedParts = append(completedParts, types.CompletedPart{
+			ETag:       aws.String(part.ETag),
+			PartNumber: aws.Int32(part.PartNumber),
+		})
+	}
+
+	_, err = s3Client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(bucketName),
+		Key:             aws.String(key),
+		UploadId:        aws.String(uploadID),
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: completedParts},
+	})
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", constants.ErrUploadToS3JP, err)
+	}
+
+	cloudFrontURL := u.cfg.CloudFrontURL
+	if cloudFrontURL == "" {
+		return "", fmt.Errorf(constants.ErrCloudFrontURLNotSetJP)
+	}
+	return fmt.Sprintf("%s/%s", cloudFrontURL, key), nil
+}
+
+// AbortMultipartUpload マルチパートアップロードを中止し、アップロード済みのパートを破棄する
+func (u *awsUploader) AbortMultipartUpload(ctx context.Context, key string, uploadID string) error {
+	s3Client, bucketName, err := u.s3ClientAndBucket()
+	if err != nil {
+		return err
+	}
+
+	_, err = s3Client.AbortMultipartUpload(ctx, &s3.AbortMultipartUploadInput{
+		Bucket:   aws.String(bucketName),
+		Key:      aws.String(key),
+		UploadId: aws.String(uploadID),
+	})
+	return err
+}
+
+// s3ClientAndBucket S3クライアントとバケット名を取得
+func (u *awsUploader) s3ClientAndBucket() (*s3.Client, string, error) {
+	bucketName := u.cfg.BucketName
+	if bucketName == "" {
+		return nil, "", fmt.Errorf(constants.ErrLoadAWSConfigJP)
+	}
+	s3Client, err := u.initializeS3Client()
+	if err != nil {
+		return nil, "", err
+	}
+	return s3Client, bucketName, nil
+}
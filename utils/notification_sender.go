@@ -0,0 +1,29 @@
+package utils
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrInvalidNotificationTarget 送信先のアドレスが無効になっており、再送しても届かない
+var ErrInvalidNotificationTarget = errors.New("notification target is no longer valid")
+
+// NotificationSender 外部の通知チャネルにメッセージを送信する。targetはチャネルごとの送信先
+type NotificationSender interface {
+	Send(ctx context.Context, target string, subject string, body string) error
+}
+
+// smtpMailSender メールアドレスを送信先とするNotificationSender
+type smtpMailSender struct {
+	mailer Mailer
+}
+
+// NewSMTPMailSender SMTPサーバーからメールを送信するNotificationSenderを生成する。userが空の場合は認証しない
+func NewSMTPMailSender(host string, port int, user, password, from string) NotificationSender {
+	return &smtpMailSender{mailer: NewSMTPMailer(host, port, user, password, from)}
+}
+
+// Send 件名と本文をUTF-8のテキストメールとして送信する
+func (s *smtpMailSender) Send(ctx context.Context, to string, subject string, body string) error {
+	return s.mailer.Send(ctx, Mail{To: to, Subject: subject, Text: body})
+}
@@ -0,0 +1,46 @@
+package utils
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// ErrPrivateAddress 送信先がループバックやプライベートネットワークのアドレスに解決された
+var ErrPrivateAddress = errors.New("destination resolves to a private address")
+
+// newOutboundHTTPClient クラスの管理者が登録した外部のURLに送信するHTTPクライアントを生成する。
+// 接続と応答をtimeoutで打ち切り、リダイレクトは追わない。allowPrivateNetworksがfalseの場合、ループバックやプライベートネットワークのアドレスには接続しない
+func newOutboundHTTPClient(timeout time.Duration, allowPrivateNetworks bool) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout}
+	if !allowPrivateNetworks {
+		dialer.Control = rejectPrivateAddress
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// rejectPrivateAddress 名前解決した後の接続先がインターネット上のアドレスでない場合は接続しない。
+// 管理者が登録したURLから内部のサービスに送信されることを防ぐ
+func rejectPrivateAddress(_ string, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return ErrPrivateAddress
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+const (
+	pushSendTimeout = 10 * time.Second
+	// fcmEndpoint FCM HTTP v1 APIの送信先。%sはFirebaseのプロジェクトID
+	fcmEndpoint = "https://fcm.googleapis.com/v1/projects/%s/messages:send"
+	// fcmScope FCMで送信するためのOAuth 2.0のスコープ
+	fcmScope = "https://www.googleapis.com/auth/firebase.messaging"
+)
+
+// PushMessage 端末に送信するプッシュ通知。Dataはアプリが遷移先の判定に使う
+type PushMessage struct {
+	Title string
+	Body  string
+	Data  map[string]string
+}
+
+// PushResult 1つのプッシュ通知を複数の端末に送信した結果
+type PushResult struct {
+	Sent int
+	// InvalidTokens アプリの削除などで無効になり、再送しても届かないトークン
+	InvalidTokens []string
+	// RetryTokens 一時的なエラーで送信できず、時間を置いて再送できるトークン
+	RetryTokens []string
+	// Failed 再送しても成功しないエラーで送信できなかったトークンの数
+	Failed int
+}
+
+// PushSender 端末のトークンにプッシュ通知を送信する
+type PushSender interface {
+	Send(ctx context.Context, tokens []string, message PushMessage) (*PushResult, error)
+}
+
+// fcmSender Firebase Cloud MessagingのHTTP v1 APIで送信するPushSender
+type fcmSender struct {
+	endpoint string
+	client   *http.Client
+	dryRun   bool
+}
+
+// NewFCMSender endpointのFCM HTTP v1 APIに送信するPushSenderを生成する。clientは認証済みのリクエストを送るクライアント。
+// dryRunの場合はFCMにメッセージの検証のみを依頼し、端末には届けない
+func NewFCMSender(endpoint string, client *http.Client, dryRun bool) PushSender {
+	return &fcmSender{endpoint: endpoint, client: client, dryRun: dryRun}
+}
+
+// NewFCMClient サービスアカウントの認証情報からFCMに送信するHTTPクライアントと送信先を生成する。
+// projectIDが空の場合は認証情報のプロジェクトIDを使う
+func NewFCMClient(ctx context.Context, credentialsJSON []byte, projectID string) (*http.Client, string, error) {
+	credentials, err := google.CredentialsFromJSON(ctx, credentialsJSON, fcmScope)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to load fcm credentials: %w", err)
+	}
+	if projectID == "" {
+		projectID = credentials.ProjectID
+	}
+	if projectID == "" {
+		return nil, "", fmt.Errorf("fcm project id is not set")
+	}
+	client := oauth2.NewClient(ctx, credentials.TokenSource)
+	client.Timeout = pushSendTimeout
+	return client, fmt.Sprintf(fcmEndpoint, projectID), nil
+}
+
+// fcmRequest FCM HTTP v1 APIのリクエスト
+type fcmRequest struct {
+	ValidateOnly bool       `json:"validate_only,omitempty"`
+	Message      fcmMessage `json:"message"`
+}
+
+type fcmMessage struct {
+	Token        string            `json:"token"`
+	Notification fcmNotification   `json:"notification"`
+	Data         map[string]string `json:"data,omitempty"`
+}
+
+type fcmNotification struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+}
+
+// fcmErrorResponse FCM HTTP v1 APIのエラーレスポンス
+type fcmErrorResponse struct {
+	Error struct {
+		Status  string `json:"status"`
+		Details []struct {
+			ErrorCode string `json:"errorCode"`
+		} `json:"details"`
+	} `json:"error"`
+}
+
+// Send メッセージを各トークンに送信する。HTTP v1 APIは1リクエストで1つのトークンにのみ送信できるため、トークンごとに送信する。
+// コンテキストがキャンセルされた場合は送信していないトークンを再送の対象にする
+func (s *fcmSender) Send(ctx context.Context, tokens []string, message PushMessage) (*PushResult, error) {
+	result := &PushResult{}
+	for i, token := range tokens {
+		if ctx.Err() != nil {
+			result.RetryTokens = append(result.RetryTokens, tokens[i:]...)
+			return result, ctx.Err()
+		}
+		switch outcome := s.send(ctx, token, message); outcome {
+		case fcmSent:
+			result.Sent++
+		case fcmInvalidToken:
+			result.InvalidTokens = append(result.InvalidTokens, token)
+		case fcmRetry:
+			result.RetryTokens = append(result.RetryTokens, token)
+		default:
+			result.Failed++
+		}
+	}
+	return result, nil
+}
+
+// fcmOutcome 1つのトークンへの送信の結果
+type fcmOutcome int
+
+const (
+	fcmSent fcmOutcome = iota
+	fcmInvalidToken
+	fcmRetry
+	fcmFailed
+)
+
+// send 1つのトークンに送信する
+func (s *fcmSender) send(ctx context.Context, token string, message PushMessage) fcmOutcome {
+	payload, err := json.Marshal(fcmRequest{
+		ValidateOnly: s.dryRun,
+		Message: fcmMessage{
+			Token:        token,
+			Notification: fcmNotification{Title: message.Title, Body: message.Body},
+			Data:         message.Data,
+		},
+	})
+	if err != nil {
+		return fcmFailed
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return fcmFailed
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fcmRetry
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 300 {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return fcmSent
+	}
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return fcmRetry
+	}
+
+	var body fcmErrorResponse
+	_ = json.NewDecoder(resp.Body).Decode(&body)
+	if body.Error.Status == "NOT_FOUND" {
+		return fcmInvalidToken
+	}
+	for _, detail := range body.Error.Details {
+		// UNREGISTEREDはアプリの削除など、SENDER_ID_MISMATCHは別のプロジェクトで発行されたトークン
+		if detail.ErrorCode == "UNREGISTERED" || detail.ErrorCode == "SENDER_ID_MISMATCH" {
+			return fcmInvalidToken
+		}
+	}
+	return fcmFailed
+}
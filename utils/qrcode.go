@@ -0,0 +1,461 @@
+package utils
+
+import "errors"
+
+// ErrQRCodeTooLong QRコードに格納できる長さを超えている
+var ErrQRCodeTooLong = errors.New("data is too long for a QR code")
+
+// QRCode 誤り訂正レベルMで生成したQRコード。Modules[y][x]がtrueの場合は黒のモジュールを表す
+type QRCode struct {
+	Size    int
+	Modules [][]bool
+}
+
+// qrVersion 誤り訂正レベルMでの型番ごとのブロック構成
+type qrVersion struct {
+	ecPerBlock int
+	// blocks ブロックごとのデータコード語数
+	blocks []int
+	// alignments 位置合わせパターンの中心座標
+	alignments []int
+}
+
+// qrVersions 型番1〜10の構成。招待リンク程度の長さ(最大213バイト)を想定する
+var qrVersions = []qrVersion{
+	1:  {10, []int{16}, nil},
+	2:  {16, []int{28}, []int{6, 18}},
+	3:  {26, []int{44}, []int{6, 22}},
+	4:  {18, []int{32, 32}, []int{6, 26}},
+	5:  {24, []int{43, 43}, []int{6, 30}},
+	6:  {16, []int{27, 27, 27, 27}, []int{6, 34}},
+	7:  {18, []int{31, 31, 31, 31}, []int{6, 22, 38}},
+	8:  {22, []int{38, 38, 39, 39}, []int{6, 24, 42}},
+	9:  {22, []int{36, 36, 36, 37, 37}, []int{6, 26, 46}},
+	10: {26, []int{43, 43, 43, 43, 44}, []int{6, 28, 50}},
+}
+
+// dataCodewords データコード語の総数を返す
+func (v qrVersion) dataCodewords() int {
+	total := 0
+	for _, n := range v.blocks {
+		total += n
+	}
+	return total
+}
+
+// EncodeQRCode データをバイトモードでQRコードにする。型番は格納できる最小のものを選ぶ
+func EncodeQRCode(data string) (*QRCode, error) {
+	payload := []byte(data)
+	version := 0
+	for v := 1; v < len(qrVersions); v++ {
+		countBits := 8
+		if v >= 10 {
+			countBits = 16
+		}
+		if 4+countBits+len(payload)*8 <= qrVersions[v].dataCodewords()*8 {
+			version = v
+			break
+		}
+	}
+	if version == 0 {
+		return nil, ErrQRCodeTooLong
+	}
+
+	codewords := qrInterleave(qrVersions[version], qrDataCodewords(version, payload))
+	q := newQRMatrix(version)
+	q.drawFunctionPatterns()
+	q.drawCodewords(codewords)
+
+	// 減点の最も少ないマスクを選ぶ
+	best, bestPenalty := -1, 0
+	for mask := 0; mask < 8; mask++ {
+		q.applyMask(mask)
+		q.drawFormatBits(mask)
+		if penalty := q.penalty(); best < 0 || penalty < bestPenalty {
+			best, bestPenalty = mask, penalty
+		}
+		q.applyMask(mask)
+	}
+	q.applyMask(best)
+	q.drawFormatBits(best)
+
+	return &QRCode{Size: q.size, Modules: q.modules}, nil
+}
+
+// qrDataCodewords モード指示子・文字数・データ・終端パターン・埋め草からデータコード語を組み立てる
+func qrDataCodewords(version int, payload []byte) []byte {
+	capacity := qrVersions[version].dataCodewords()
+	var bits qrBitBuffer
+	bits.append(0x4, 4)
+	if version >= 10 {
+		bits.append(len(payload), 16)
+	} else {
+		bits.append(len(payload), 8)
+	}
+	for _, b := range payload {
+		bits.append(int(b), 8)
+	}
+
+	terminator := capacity*8 - len(bits)
+	if terminator > 4 {
+		terminator = 4
+	}
+	bits.append(0, terminator)
+	bits.append(0, (8-len(bits)%8)%8)
+
+	codewords := make([]byte, 0, capacity)
+	for i := 0; i < len(bits); i += 8 {
+		var b byte
+		for j := 0; j < 8; j++ {
+			if bits[i+j] {
+				b |= 1 << (7 - j)
+			}
+		}
+		codewords = append(codewords, b)
+	}
+	for pad := byte(0xEC); len(codewords) < capacity; pad ^= 0xEC ^ 0x11 {
+		codewords = append(codewords, pad)
+	}
+	return codewords
+}
+
+// qrInterleave データをブロックに分けて誤り訂正コード語を付け、ブロックを交互に並べる
+func qrInterleave(version qrVersion, data []byte) []byte {
+	divisor := reedSolomonDivisor(version.ecPerBlock)
+	dataBlocks := make([][]byte, len(version.blocks))
+	ecBlocks := make([][]byte, len(version.blocks))
+	offset := 0
+	for i, n := range version.blocks {
+		dataBlocks[i] = data[offset : offset+n]
+		ecBlocks[i] = reedSolomonRemainder(dataBlocks[i], divisor)
+		offset += n
+	}
+
+	longest := version.blocks[len(version.blocks)-1]
+	result := make([]byte, 0, len(data)+version.ecPerBlock*len(version.blocks))
+	for i := 0; i < longest; i++ {
+		for _, block := range dataBlocks {
+			if i < len(block) {
+				result = append(result, block[i])
+			}
+		}
+	}
+	for i := 0; i < version.ecPerBlock; i++ {
+		for _, block := range ecBlocks {
+			result = append(result, block[i])
+		}
+	}
+	return result
+}
+
+// gfMultiply 原始多項式x^8+x^4+x^3+x^2+1のガロア体GF(256)での積を返す
+func gfMultiply(x, y byte) byte {
+	z := 0
+	for i := 7; i >= 0; i-- {
+		z = (z << 1) ^ ((z >> 7) * 0x11D)
+		z ^= ((int(y) >> i) & 1) * int(x)
+	}
+	return byte(z)
+}
+
+// reedSolomonDivisor 次数degreeの生成多項式の係数(最高次の1を除く)を返す
+func reedSolomonDivisor(degree int) []byte {
+	result := make([]byte, degree)
+	result[degree-1] = 1
+	root := byte(1)
+	for i := 0; i < degree; i++ {
+		for j := range result {
+			result[j] = gfMultiply(result[j], root)
+			if j+1 < len(result) {
+				result[j] ^= result[j+1]
+			}
+		}
+		root = gfMultiply(root, 0x02)
+	}
+	return result
+}
+
+// reedSolomonRemainder データを生成多項式で割った余り(誤り訂正コード語)を返す
+func reedSolomonRemainder(data []byte, divisor []byte) []byte {
+	result := make([]byte, len(divisor))
+	for _, b := range data {
+		factor := b ^ result[0]
+		copy(result, result[1:])
+		result[len(result)-1] = 0
+		for i, coefficient := range divisor {
+			result[i] ^= gfMultiply(coefficient, factor)
+		}
+	}
+	return result
+}
+
+// qrBitBuffer ビット列
+type qrBitBuffer []bool
+
+func (b *qrBitBuffer) append(value int, length int) {
+	for i := length - 1; i >= 0; i-- {
+		*b = append(*b, (value>>i)&1 == 1)
+	}
+}
+
+// qrMatrix 生成中のQRコード。isFunctionはデータを配置しない機能パターンのモジュールを表す
+type qrMatrix struct {
+	version    int
+	size       int
+	modules    [][]bool
+	isFunction [][]bool
+}
+
+func newQRMatrix(version int) *qrMatrix {
+	size := version*4 + 17
+	q := &qrMatrix{version: version, size: size, modules: make([][]bool, size), isFunction: make([][]bool, size)}
+	for y := 0; y < size; y++ {
+		q.modules[y] = make([]bool, size)
+		q.isFunction[y] = make([]bool, size)
+	}
+	return q
+}
+
+func (q *qrMatrix) setFunction(x, y int, dark bool) {
+	q.modules[y][x] = dark
+	q.isFunction[y][x] = true
+}
+
+// drawFunctionPatterns 位置検出・タイミング・位置合わせパターンと型番情報を描き、形式情報の領域を確保する
+func (q *qrMatrix) drawFunctionPatterns() {
+	for i := 0; i < q.size; i++ {
+		q.setFunction(6, i, i%2 == 0)
+		q.setFunction(i, 6, i%2 == 0)
+	}
+
+	q.drawFinderPattern(3, 3)
+	q.drawFinderPattern(q.size-4, 3)
+	q.drawFinderPattern(3, q.size-4)
+
+	alignments := qrVersions[q.version].alignments
+	last := len(alignments) - 1
+	for i, x := range alignments {
+		for j, y := range alignments {
+			// 位置検出パターンと重なる角は描かない
+			if (i == 0 && j == 0) || (i == 0 && j == last) || (i == last && j == 0) {
+				continue
+			}
+			q.drawAlignmentPattern(x, y)
+		}
+	}
+
+	q.drawFormatBits(0)
+	q.drawVersionBits()
+}
+
+// drawFinderPattern 中心(x, y)の位置検出パターンと分離パターンを描く
+func (q *qrMatrix) drawFinderPattern(x, y int) {
+	for dy := -4; dy <= 4; dy++ {
+		for dx := -4; dx <= 4; dx++ {
+			xx, yy := x+dx, y+dy
+			if xx < 0 || xx >= q.size || yy < 0 || yy >= q.size {
+				continue
+			}
+			distance := qrMax(qrAbs(dx), qrAbs(dy))
+			q.setFunction(xx, yy, distance != 2 && distance != 4)
+		}
+	}
+}
+
+// drawAlignmentPattern 中心(x, y)の位置合わせパターンを描く
+func (q *qrMatrix) drawAlignmentPattern(x, y int) {
+	for dy := -2; dy <= 2; dy++ {
+		for dx := -2; dx <= 2; dx++ {
+			q.setFunction(x+dx, y+dy, qrMax(qrAbs(dx), qrAbs(dy)) != 1)
+		}
+	}
+}
+
+// drawFormatBits 誤り訂正レベルMとマスクの形式情報を2か所に描く
+func (q *qrMatrix) drawFormatBits(mask int) {
+	data := mask // 誤り訂正レベルMの指示子は00
+	remainder := data
+	for i := 0; i < 10; i++ {
+		remainder = (remainder << 1) ^ ((remainder >> 9) * 0x537)
+	}
+	bits := (data<<10 | remainder) ^ 0x5412
+	bit := func(i int) bool { return (bits>>i)&1 == 1 }
+
+	for i := 0; i <= 5; i++ {
+		q.setFunction(8, i, bit(i))
+	}
+	q.setFunction(8, 7, bit(6))
+	q.setFunction(8, 8, bit(7))
+	q.setFunction(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		q.setFunction(14-i, 8, bit(i))
+	}
+
+	for i := 0; i < 8; i++ {
+		q.setFunction(q.size-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		q.setFunction(8, q.size-15+i, bit(i))
+	}
+	q.setFunction(8, q.size-8, true)
+}
+
+// drawVersionBits 型番7以上の場合に型番情報を2か所に描く
+func (q *qrMatrix) drawVersionBits() {
+	if q.version < 7 {
+		return
+	}
+	remainder := q.version
+	for i := 0; i < 12; i++ {
+		remainder = (remainder << 1) ^ ((remainder >> 11) * 0x1F25)
+	}
+	bits := q.version<<12 | remainder
+	for i := 0; i < 18; i++ {
+		dark := (bits>>i)&1 == 1
+		a, b := q.size-11+i%3, i/3
+		q.setFunction(a, b, dark)
+		q.setFunction(b, a, dark)
+	}
+}
+
+// drawCodewords コード語を右下から2列ずつジグザグに配置する
+func (q *qrMatrix) drawCodewords(codewords []byte) {
+	i := 0
+	for right := q.size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		for vert := 0; vert < q.size; vert++ {
+			for j := 0; j < 2; j++ {
+				x := right - j
+				y := vert
+				if (right+1)&2 == 0 {
+					y = q.size - 1 - vert
+				}
+				if !q.isFunction[y][x] && i < len(codewords)*8 {
+					q.modules[y][x] = (codewords[i>>3]>>(7-(i&7)))&1 == 1
+					i++
+				}
+			}
+		}
+	}
+}
+
+// applyMask データ領域をマスクパターンで反転する。同じマスクを2回適用すると元に戻る
+func (q *qrMatrix) applyMask(mask int) {
+	for y := 0; y < q.size; y++ {
+		for x := 0; x < q.size; x++ {
+			if q.isFunction[y][x] {
+				continue
+			}
+			var invert bool
+			switch mask {
+			case 0:
+				invert = (x+y)%2 == 0
+			case 1:
+				invert = y%2 == 0
+			case 2:
+				invert = x%3 == 0
+			case 3:
+				invert = (x+y)%3 == 0
+			case 4:
+				invert = (x/3+y/2)%2 == 0
+			case 5:
+				invert = x*y%2+x*y%3 == 0
+			case 6:
+				invert = (x*y%2+x*y%3)%2 == 0
+			case 7:
+				invert = ((x+y)%2+x*y%3)%2 == 0
+			}
+			if invert {
+				q.modules[y][x] = !q.modules[y][x]
+			}
+		}
+	}
+}
+
+// penalty JIS X 0510の4つの規則による減点を返す
+func (q *qrMatrix) penalty() int {
+	penalty := 0
+	lines := make([][]bool, 0, q.size*2)
+	for y := 0; y < q.size; y++ {
+		lines = append(lines, q.modules[y])
+	}
+	for x := 0; x < q.size; x++ {
+		column := make([]bool, q.size)
+		for y := 0; y < q.size; y++ {
+			column[y] = q.modules[y][x]
+		}
+		lines = append(lines, column)
+	}
+
+	finderLike := [][]bool{
+		{true, false, true, true, true, false, true, false, false, false, false},
+		{false, false, false, false, true, false, true, true, true, false, true},
+	}
+	for _, line := range lines {
+		// 同色のモジュールが5つ以上連続する
+		run := 1
+		for i := 1; i <= len(line); i++ {
+			if i < len(line) && line[i] == line[i-1] {
+				run++
+				continue
+			}
+			if run >= 5 {
+				penalty += run - 2
+			}
+			run = 1
+		}
+		// 位置検出パターンに似た1:1:3:1:1の並び
+		for i := 0; i+11 <= len(line); i++ {
+			for _, pattern := range finderLike {
+				if qrMatches(line[i:i+11], pattern) {
+					penalty += 40
+				}
+			}
+		}
+	}
+
+	dark := 0
+	for y := 0; y < q.size; y++ {
+		for x := 0; x < q.size; x++ {
+			if q.modules[y][x] {
+				dark++
+			}
+			// 同色の2x2のブロック
+			if x+1 < q.size && y+1 < q.size {
+				c := q.modules[y][x]
+				if c == q.modules[y][x+1] && c == q.modules[y+1][x] && c == q.modules[y+1][x+1] {
+					penalty += 3
+				}
+			}
+		}
+	}
+	// 黒のモジュールの割合が50%から離れている
+	total := q.size * q.size
+	penalty += qrAbs(dark*20-total*10) / total * 10
+	return penalty
+}
+
+func qrMatches(line []bool, pattern []bool) bool {
+	for i := range pattern {
+		if line[i] != pattern[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func qrAbs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+func qrMax(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,95 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// DefaultSlowQueryThreshold 遅いクエリとして記録する実行時間の既定値
+const DefaultSlowQueryThreshold = 200 * time.Millisecond
+
+// QueryLogger GORMのクエリをアプリケーションのログに出力するロガー。
+// logAllがfalseの場合は遅いクエリとエラーのみを出力する。
+// バインドした値には個人情報が含まれるため、SQLはプレースホルダーのまま出力する
+type QueryLogger struct {
+	out           *log.Logger
+	slowThreshold time.Duration
+	level         logger.LogLevel
+}
+
+// NewQueryLogger QueryLoggerを生成する。slowThresholdが0以下の場合は既定値を使用する
+func NewQueryLogger(out *log.Logger, slowThreshold time.Duration, logAll bool) *QueryLogger {
+	if out == nil {
+		out = log.Default()
+	}
+	if slowThreshold <= 0 {
+		slowThreshold = DefaultSlowQueryThreshold
+	}
+	level := logger.Warn
+	if logAll {
+		level = logger.Info
+	}
+	return &QueryLogger{out: out, slowThreshold: slowThreshold, level: level}
+}
+
+// LogMode ログレベルを変更したロガーを返す
+func (l *QueryLogger) LogMode(level logger.LogLevel) logger.Interface {
+	copied := *l
+	copied.level = level
+	return &copied
+}
+
+// ParamsFilter ログに出力するSQLからバインドした値を除き、プレースホルダーのまま残す
+func (l *QueryLogger) ParamsFilter(_ context.Context, sql string, _ ...interface{}) (string, []interface{}) {
+	return sql, nil
+}
+
+// Info 情報メッセージを出力する
+func (l *QueryLogger) Info(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= logger.Info {
+		l.out.Printf("[INFO] gorm: request_id=%s %s", RequestIDFromContext(ctx), fmt.Sprintf(msg, args...))
+	}
+}
+
+// Warn 警告メッセージを出力する
+func (l *QueryLogger) Warn(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= logger.Warn {
+		l.out.Printf("[WARN] gorm: request_id=%s %s", RequestIDFromContext(ctx), fmt.Sprintf(msg, args...))
+	}
+}
+
+// Error エラーメッセージを出力する
+func (l *QueryLogger) Error(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= logger.Error {
+		l.out.Printf("[ERROR] gorm: request_id=%s %s", RequestIDFromContext(ctx), fmt.Sprintf(msg, args...))
+	}
+}
+
+// Trace クエリの実行結果を出力する。レコードが見つからないエラーは通常の結果として扱う
+func (l *QueryLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	if l.level <= logger.Silent {
+		return
+	}
+
+	elapsed := time.Since(begin)
+	switch {
+	case err != nil && !errors.Is(err, gorm.ErrRecordNotFound) && l.level >= logger.Error:
+		sql, rows := fc()
+		l.out.Printf("[ERROR] query failed: request_id=%s duration=%s rows=%d sql=%q err=%v",
+			RequestIDFromContext(ctx), elapsed, rows, sql, err)
+	case elapsed >= l.slowThreshold && l.level >= logger.Warn:
+		sql, rows := fc()
+		l.out.Printf("[WARN] slow query: request_id=%s duration=%s threshold=%s rows=%d sql=%q",
+			RequestIDFromContext(ctx), elapsed, l.slowThreshold, rows, sql)
+	case l.level >= logger.Info:
+		sql, rows := fc()
+		l.out.Printf("[DEBUG] query: request_id=%s duration=%s rows=%d sql=%q",
+			RequestIDFromContext(ctx), elapsed, rows, sql)
+	}
+}
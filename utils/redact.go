@@ -0,0 +1,27 @@
+package utils
+
+import "regexp"
+
+// RedactedValue マスクした値の代わりに記録する文字列
+const RedactedValue = "[REDACTED]"
+
+var (
+	sensitiveJSONField = regexp.MustCompile(`(?i)("[^"]*(password|secret|token|authcode)[^"]*"\s*:\s*)("(\\.|[^"\\])*"?|[^,}\s]+)`)
+	sensitiveFormField = regexp.MustCompile(`(?i)((^|&)[^=&]*(password|secret|token|authcode)[^=&]*=)[^&]*`)
+	sensitiveHeader    = regexp.MustCompile(`(?i)^(authorization|cookie|set-cookie|.*token.*|.*secret.*)$`)
+)
+
+// RedactJSON JSONのパスワードやトークンなどの値をマスクする。途中で切れたJSONにも使える
+func RedactJSON(body string) string {
+	return sensitiveJSONField.ReplaceAllString(body, `$1"`+RedactedValue+`"`)
+}
+
+// RedactForm フォームやクエリ文字列のパスワードやトークンなどの値をマスクする
+func RedactForm(body string) string {
+	return sensitiveFormField.ReplaceAllString(body, `${1}`+RedactedValue)
+}
+
+// IsSensitiveHeader 認証情報を含むヘッダーか判定する
+func IsSensitiveHeader(name string) bool {
+	return sensitiveHeader.MatchString(name)
+}
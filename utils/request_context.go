@@ -0,0 +1,19 @@
+package utils
+
+import "context"
+
+type requestIDContextKey struct{}
+
+// ContextWithRequestID リクエストIDを持つcontextを返す
+func ContextWithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDContextKey{}, requestID)
+}
+
+// RequestIDFromContext contextからリクエストIDを取得する。設定されていない場合は空文字を返す
+func RequestIDFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	requestID, _ := ctx.Value(requestIDContextKey{}).(string)
+	return requestID
+}
@@ -0,0 +1,152 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	sentryBufferSize  = 100
+	sentrySendTimeout = 5 * time.Second
+	sentryClientName  = "minori-gin/1.0"
+)
+
+// sentryReporter SentryのストアAPIにエラーを送信するErrorReporter
+// 送信はバックグラウンドで行い、リクエストやジョブを待たせない
+type sentryReporter struct {
+	endpoint    string
+	auth        string
+	environment string
+	client      *http.Client
+	events      chan ErrorEvent
+}
+
+// newSentryReporter DSN(https://<key>@<host>/<project>)を解析してsentryReporterを生成する
+func newSentryReporter(dsn string, environment string) (*sentryReporter, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("invalid error reporter dsn: %w", err)
+	}
+	if u.User == nil || u.User.Username() == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid error reporter dsn: public key and host are required")
+	}
+
+	path := strings.TrimSuffix(u.Path, "/")
+	slash := strings.LastIndex(path, "/")
+	projectID := path[slash+1:]
+	if projectID == "" {
+		return nil, fmt.Errorf("invalid error reporter dsn: project id is required")
+	}
+
+	r := &sentryReporter{
+		endpoint:    fmt.Sprintf("%s://%s%s/api/%s/store/", u.Scheme, u.Host, path[:slash], projectID),
+		auth:        fmt.Sprintf("Sentry sentry_version=7, sentry_client=%s, sentry_key=%s", sentryClientName, u.User.Username()),
+		environment: environment,
+		client:      &http.Client{Timeout: sentrySendTimeout},
+		events:      make(chan ErrorEvent, sentryBufferSize),
+	}
+	go r.run()
+	return r, nil
+}
+
+// Report エラーを送信キューに追加する。キューが一杯の場合は破棄する
+func (r *sentryReporter) Report(event ErrorEvent) {
+	select {
+	case r.events <- event:
+	default:
+		log.Printf("Error reporter queue is full. Dropped error: %v", event.Err)
+	}
+}
+
+// run キューのエラーを順に送信する
+func (r *sentryReporter) run() {
+	for event := range r.events {
+		if err := r.send(event); err != nil {
+			log.Printf("Failed to send error report: %v", err)
+		}
+	}
+}
+
+// send エラーをSentryのイベント形式に変換して送信する
+func (r *sentryReporter) send(event ErrorEvent) error {
+	payload, err := json.Marshal(r.toPayload(event))
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, r.endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Sentry-Auth", r.auth)
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("error reporter responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// toPayload SentryのストアAPIのイベントを生成する
+func (r *sentryReporter) toPayload(event ErrorEvent) map[string]interface{} {
+	tags := map[string]string{}
+	for k, v := range event.Tags {
+		tags[k] = v
+	}
+	if event.RequestID != "" {
+		tags["request_id"] = event.RequestID
+	}
+	if event.Route != "" {
+		tags["route"] = event.Route
+	}
+
+	message := "unknown error"
+	errorType := "error"
+	if event.Err != nil {
+		message = event.Err.Error()
+		errorType = fmt.Sprintf("%T", event.Err)
+	}
+
+	payload := map[string]interface{}{
+		"event_id":    newEventID(),
+		"timestamp":   event.Timestamp.UTC().Format(time.RFC3339),
+		"level":       "error",
+		"platform":    "go",
+		"environment": r.environment,
+		"message":     message,
+		"exception":   map[string]interface{}{"values": []map[string]string{{"type": errorType, "value": message}}},
+		"tags":        tags,
+	}
+	if event.UID != 0 {
+		payload["user"] = map[string]string{"id": fmt.Sprint(event.UID)}
+	}
+	if event.Method != "" {
+		payload["request"] = map[string]interface{}{"method": event.Method, "url": event.URL, "headers": event.Headers}
+	}
+	if len(event.Stack) > 0 {
+		payload["extra"] = map[string]string{"stack": string(event.Stack)}
+	}
+	return payload
+}
+
+// newEventID 32桁の16進数のイベントIDを生成する
+func newEventID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%032x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
@@ -1,3 +1,14 @@
 package utils
 
+import "strings"
+
 // 문자열 처리 관련 유틸리티 함수를 포함
+
+// likeEscaper LIKEのワイルドカードとエスケープ文字をエスケープする
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// ContainsPattern sを部分一致で検索するLIKEのパターンを返す。sの%と_はワイルドカードとして扱わない。
+// SQLiteにはデフォルトのエスケープ文字がないため、LIKE ? ESCAPE '\'と組み合わせて使う
+func ContainsPattern(s string) string {
+	return "%" + likeEscaper.Replace(s) + "%"
+}
@@ -0,0 +1,72 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const translateTimeout = 10 * time.Second
+
+// Translation 翻訳の結果。SourceLangは翻訳APIが判定した原文の言語
+type Translation struct {
+	Text       string
+	SourceLang string
+}
+
+// Translator 外部の翻訳APIでテキストを翻訳する。targetLangはDeepLの言語コード (JA, EN-US など)
+type Translator interface {
+	Translate(ctx context.Context, text string, targetLang string) (*Translation, error)
+}
+
+// deepLTranslator DeepLのテキスト翻訳APIを使うTranslator
+type deepLTranslator struct {
+	endpoint string
+	apiKey   string
+	client   *http.Client
+}
+
+// NewDeepLTranslator DeepL互換の翻訳APIに送信するTranslatorを生成する
+func NewDeepLTranslator(endpoint, apiKey string) Translator {
+	return &deepLTranslator{endpoint: endpoint, apiKey: apiKey, client: &http.Client{Timeout: translateTimeout}}
+}
+
+// Translate テキストをtargetLangに翻訳する
+func (t *deepLTranslator) Translate(ctx context.Context, text string, targetLang string) (*Translation, error) {
+	form := url.Values{"text": {text}, "target_lang": {targetLang}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Authorization", "DeepL-Auth-Key "+t.apiKey)
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil, fmt.Errorf("translation api responded with status %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Translations []struct {
+			DetectedSourceLanguage string `json:"detected_source_language"`
+			Text                   string `json:"text"`
+		} `json:"translations"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to decode translation: %w", err)
+	}
+	if len(body.Translations) == 0 {
+		return nil, fmt.Errorf("translation api returned no translations")
+	}
+	return &Translation{Text: body.Translations[0].Text, SourceLang: body.Translations[0].DetectedSourceLanguage}, nil
+}
@@ -4,16 +4,17 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	appconfig "github.com/YJU-OKURA/project_minori-gin-deployment-repo/config"
 	"github.com/YJU-OKURA/project_minori-gin-deployment-repo/constants"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 	"io"
 	"log"
 	"mime/multipart"
-	"os"
 	"path/filepath"
 	"strings"
 	"time"
@@ -21,24 +22,28 @@ import (
 
 type Uploader interface {
 	UploadImage(file *multipart.FileHeader, classID uint, isLogo bool) (string, error)
+	DeleteObjects(ctx context.Context, urls []string) error
+	OpenObject(ctx context.Context, url string) (io.ReadCloser, error)
 }
 
+// deleteObjectsBatchSize S3のDeleteObjectsで一度に削除できるオブジェクトの上限
+const deleteObjectsBatchSize = 1000
+
 type awsUploader struct {
+	cfg appconfig.AWSConfig
 }
 
-func NewAwsUploader() Uploader {
-	return &awsUploader{}
+func NewAwsUploader(cfg appconfig.AWSConfig) Uploader {
+	return &awsUploader{cfg: cfg}
 }
 
 // initializeS3Client S3クライアントを初期化
-func initializeS3Client() (*s3.Client, error) {
-	awsRegion := os.Getenv("AWS_REGION")
-
+func (u *awsUploader) initializeS3Client() (*s3.Client, error) {
 	cfg, err := config.LoadDefaultConfig(context.TODO(),
-		config.WithRegion(awsRegion),
+		config.WithRegion(u.cfg.Region),
 		config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(
-			os.Getenv("AWS_S3_ACCESS_KEY"),
-			os.Getenv("AWS_S3_SECRET_ACCESS_KEY"),
+			u.cfg.AccessKey,
+			u.cfg.SecretAccessKey,
 			"",
 		)),
 	)
@@ -81,7 +86,7 @@ func (u *awsUploader) UploadImage(fileHeader *multipart.FileHeader, classID uint
 		return "", fmt.Errorf("%s: %w", constants.ErrReadFileDataJP, err)
 	}
 
-	s3Client, err := initializeS3Client()
+	s3Client, err := u.initializeS3Client()
 	if err != nil {
 		return "", err
 	}
@@ -99,7 +104,7 @@ func (u *awsUploader) UploadImage(fileHeader *multipart.FileHeader, classID uint
 		uniqueFileName = fmt.Sprintf("images/%d/%s-%d%s", classID, strings.TrimSuffix(fileHeader.Filename, extension), time.Now().Unix(), extension)
 	}
 
-	bucketName := os.Getenv("AWS_S3_BUCKET_NAME")
+	bucketName := u.cfg.BucketName
 	if bucketName == "" {
 		return "", fmt.Errorf(constants.ErrLoadAWSConfigJP)
 	}
@@ -116,7 +121,7 @@ func (u *awsUploader) UploadImage(fileHeader *multipart.FileHeader, classID uint
 		return "", fmt.Errorf("%s: %w", constants.ErrUploadToS3JP, err)
 	}
 
-	cloudFrontURL := os.Getenv("AWS_CLOUDFRONT")
+	cloudFrontURL := u.cfg.CloudFrontURL
 	if cloudFrontURL == "" {
 		return "", fmt.Errorf(constants.ErrCloudFrontURLNotSetJP)
 	}
@@ -125,3 +130,63 @@ func (u *awsUploader) UploadImage(fileHeader *multipart.FileHeader, classID uint
 	log.Printf("Final URL: %s", finalURL)
 	return finalURL, nil
 }
+
+// DeleteObjects UploadImageが返したURLのオブジェクトをS3から削除する
+func (u *awsUploader) DeleteObjects(ctx context.Context, urls []string) error {
+	objects := make([]types.ObjectIdentifier, 0, len(urls))
+	for _, url := range urls {
+		if url == "" {
+			continue
+		}
+		objects = append(objects, types.ObjectIdentifier{Key: aws.String(u.objectKey(url))})
+	}
+	if len(objects) == 0 {
+		return nil
+	}
+
+	s3Client, bucketName, err := u.s3ClientAndBucket()
+	if err != nil {
+		return err
+	}
+
+	for start := 0; start < len(objects); start += deleteObjectsBatchSize {
+		end := start + deleteObjectsBatchSize
+		if end > len(objects) {
+			end = len(objects)
+		}
+		output, err := s3Client.DeleteObjects(ctx, &s3.DeleteObjectsInput{
+			Bucket: aws.String(bucketName),
+			Delete: &types.Delete{Objects: objects[start:end], Quiet: aws.Bool(true)},
+		})
+		if err != nil {
+			return fmt.Errorf("%s: %w", constants.ErrDeleteFromS3JP, err)
+		}
+		if len(output.Errors) > 0 {
+			first := output.Errors[0]
+			return fmt.Errorf("%s: %d objects failed, first %s: %s", constants.ErrDeleteFromS3JP, len(output.Errors), aws.ToString(first.Key), aws.ToString(first.Message))
+		}
+	}
+	return nil
+}
+
+// OpenObject UploadImageが返したURLのオブジェクトをS3から読み込む。CloudFrontのキャッシュを経由しない
+func (u *awsUploader) OpenObject(ctx context.Context, url string) (io.ReadCloser, error) {
+	s3Client, bucketName, err := u.s3ClientAndBucket()
+	if err != nil {
+		return nil, err
+	}
+	output, err := s3Client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(u.objectKey(url)),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return output.Body, nil
+}
+
+// objectKey CloudFrontのURLをS3のオブジェクトのキーに変換する
+func (u *awsUploader) objectKey(url string) string {
+	cloudFrontURL := strings.TrimSuffix(u.cfg.CloudFrontURL, "/")
+	return strings.TrimPrefix(strings.TrimPrefix(url, cloudFrontURL), "/")
+}
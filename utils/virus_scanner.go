@@ -0,0 +1,104 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+const (
+	clamdTimeout   = 2 * time.Minute
+	clamdChunkSize = 64 << 10
+)
+
+// ScanResult ウイルススキャンの結果
+type ScanResult struct {
+	Infected bool
+	// Signature 検出したウイルスの名前。検出しなかった場合は空
+	Signature string
+}
+
+// VirusScanner ファイルの内容のウイルススキャンを行う
+type VirusScanner interface {
+	Scan(ctx context.Context, content io.Reader) (ScanResult, error)
+}
+
+// clamAVScanner clamdのINSTREAMコマンドでスキャンするVirusScanner
+type clamAVScanner struct {
+	address string
+}
+
+// NewClamAVScanner addressのclamdにTCPで接続してスキャンするVirusScannerを生成する。addressは「host:port」の形式
+func NewClamAVScanner(address string) VirusScanner {
+	return &clamAVScanner{address: address}
+}
+
+// Scan ファイルの内容をclamdに送信し、結果を返す。clamdがエラーを返した場合とサイズの上限を超えた場合はエラーにする
+func (s *clamAVScanner) Scan(ctx context.Context, content io.Reader) (ScanResult, error) {
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	conn, err := dialer.DialContext(ctx, "tcp", s.address)
+	if err != nil {
+		return ScanResult{}, err
+	}
+	defer conn.Close()
+	deadline := time.Now().Add(clamdTimeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		return ScanResult{}, err
+	}
+
+	if _, err := conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return ScanResult{}, err
+	}
+	chunk := make([]byte, clamdChunkSize)
+	size := make([]byte, 4)
+	for {
+		n, readErr := content.Read(chunk)
+		if n > 0 {
+			binary.BigEndian.PutUint32(size, uint32(n))
+			if _, err := conn.Write(size); err != nil {
+				return ScanResult{}, err
+			}
+			if _, err := conn.Write(chunk[:n]); err != nil {
+				return ScanResult{}, err
+			}
+		}
+		if errors.Is(readErr, io.EOF) {
+			break
+		}
+		if readErr != nil {
+			return ScanResult{}, readErr
+		}
+	}
+	binary.BigEndian.PutUint32(size, 0)
+	if _, err := conn.Write(size); err != nil {
+		return ScanResult{}, err
+	}
+
+	reply, err := io.ReadAll(io.LimitReader(conn, 4<<10))
+	if err != nil {
+		return ScanResult{}, err
+	}
+	return parseClamdReply(string(bytes.TrimRight(reply, "\x00\n")))
+}
+
+// parseClamdReply 「stream: OK」または「stream: {ウイルス名} FOUND」の応答を解析する
+func parseClamdReply(reply string) (ScanResult, error) {
+	result := strings.TrimSpace(strings.TrimPrefix(reply, "stream:"))
+	switch {
+	case result == "OK":
+		return ScanResult{}, nil
+	case strings.HasSuffix(result, " FOUND"):
+		return ScanResult{Infected: true, Signature: strings.TrimSuffix(result, " FOUND")}, nil
+	default:
+		return ScanResult{}, fmt.Errorf("clamd returned %q", reply)
+	}
+}
@@ -0,0 +1,83 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	webhookSendTimeout = 10 * time.Second
+	webhookUserAgent   = "Minori-Webhook/1"
+)
+
+// WebhookMessage Webhookで送信する1つのリクエスト
+type WebhookMessage struct {
+	URL    string
+	Secret string
+	Event  string
+	// DeliveryID イベントのID。再送でも同じIDを送る
+	DeliveryID string
+	Version    int
+	Body       []byte
+}
+
+// WebhookSender Webhookを送信し、送信先が返したステータスコードを返す。応答がなかった場合は0を返す
+type WebhookSender interface {
+	Send(ctx context.Context, message WebhookMessage) (int, error)
+}
+
+// httpWebhookSender HTTPのPOSTでWebhookを送信するWebhookSender
+type httpWebhookSender struct {
+	client *http.Client
+}
+
+// NewHTTPWebhookSender 10秒でタイムアウトするHTTPクライアントで送信するWebhookSenderを生成する。リダイレクトは追わない。
+// allowPrivateNetworksがfalseの場合、ループバックやプライベートネットワークのアドレスには接続しない
+func NewHTTPWebhookSender(allowPrivateNetworks bool) WebhookSender {
+	return &httpWebhookSender{client: newOutboundHTTPClient(webhookSendTimeout, allowPrivateNetworks)}
+}
+
+// Send 署名を付けてペイロードを送信する。2xx以外のステータスコードはエラーにする
+func (s *httpWebhookSender) Send(ctx context.Context, message WebhookMessage) (int, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, message.URL, bytes.NewReader(message.Body))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := time.Now().Unix()
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("User-Agent", webhookUserAgent)
+	request.Header.Set("X-Minori-Event", message.Event)
+	request.Header.Set("X-Minori-Delivery", message.DeliveryID)
+	request.Header.Set("X-Minori-Webhook-Version", strconv.Itoa(message.Version))
+	request.Header.Set("X-Minori-Timestamp", strconv.FormatInt(timestamp, 10))
+	request.Header.Set("X-Minori-Signature", SignWebhook(message.Secret, timestamp, message.Body))
+
+	response, err := s.client.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(response.Body, 64<<10))
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return response.StatusCode, fmt.Errorf("unexpected status %d", response.StatusCode)
+	}
+	return response.StatusCode, nil
+}
+
+// SignWebhook X-Minori-Signatureヘッダーの値を返す。「{X-Minori-Timestamp}.{本文}」のHMAC-SHA256を秘密鍵で計算し、
+// 「sha256=」に続けて16進数で表す。受信側は同じ値を計算し、古いタイムスタンプのリクエストを拒否して再送攻撃を防ぐ
+func SignWebhook(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}